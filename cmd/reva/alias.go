@@ -0,0 +1,144 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	gouser "os/user"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+func aliasCommand() *command {
+	cmd := newCommand("alias")
+	cmd.Description = func() string { return "list, define or remove command aliases" }
+	cmd.Usage = func() string { return "Usage: alias [-d] [<name> [<command>...]]" }
+	deleteFlag := cmd.Bool("d", false, "removes the alias")
+
+	cmd.ResetFlags = func() {
+		*deleteFlag = false
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		aliases, err := readAliases()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case cmd.NArg() == 0:
+			names := make([]string, 0, len(aliases))
+			for name := range aliases {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Printf("%s = %s\n", name, aliases[name])
+			}
+			return nil
+
+		case *deleteFlag:
+			name := cmd.Args()[0]
+			if _, ok := aliases[name]; !ok {
+				return errors.New("no alias named " + name)
+			}
+			delete(aliases, name)
+
+		case cmd.NArg() == 1:
+			name := cmd.Args()[0]
+			value, ok := aliases[name]
+			if !ok {
+				return errors.New("no alias named " + name)
+			}
+			fmt.Printf("%s = %s\n", name, value)
+			return nil
+
+		default:
+			aliases[cmd.Args()[0]] = strings.Join(cmd.Args()[1:], " ")
+		}
+
+		return writeAliases(aliases)
+	}
+	return cmd
+}
+
+func getAliasesFile() string {
+	user, err := gouser.Current()
+	if err != nil {
+		panic(err)
+	}
+
+	return path.Join(user.HomeDir, ".reva-aliases")
+}
+
+func readAliases() (map[string]string, error) {
+	aliases := map[string]string{}
+
+	data, err := ioutil.ReadFile(getAliasesFile())
+	if err != nil {
+		// No aliases have been defined yet
+		return aliases, nil
+	}
+
+	if err := json.Unmarshal(data, &aliases); err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+func writeAliases(aliases map[string]string) error {
+	data, err := json.Marshal(aliases)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(getAliasesFile(), data, 0600)
+}
+
+// expandAlias replaces the first word of the given command line if it is an alias; aliases never take precedence over commands.
+func expandAlias(s string) string {
+	aliases, err := readAliases()
+	if err != nil {
+		return s
+	}
+	return applyAliases(s, aliases)
+}
+
+func applyAliases(s string, aliases map[string]string) string {
+	args := strings.SplitN(s, " ", 2)
+	value, ok := aliases[args[0]]
+	if !ok {
+		return s
+	}
+	for _, cmd := range commands {
+		if cmd.Name == args[0] {
+			return s
+		}
+	}
+
+	if len(args) == 2 {
+		return value + " " + args[1]
+	}
+	return value
+}
@@ -22,10 +22,13 @@ import (
 	"bytes"
 	"encoding/gob"
 	"errors"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/c-bata/go-prompt"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
 	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
@@ -61,21 +64,22 @@ func (c *Completer) loginArgumentCompleter() []prompt.Suggest {
 	return suggests
 }
 
-func (c *Completer) lsArgumentCompleter(onlyDirs bool) []prompt.Suggest {
-	if onlyDirs {
-		if s, ok := checkCache(c.lsDirArguments); ok {
-			return s
-		}
-	} else {
-		if s, ok := checkCache(c.lsArguments); ok {
-			return s
-		}
+func (c *Completer) pathArgumentCompleter(word string, onlyDirs bool) []prompt.Suggest {
+	// List the container the typed path currently points into, so that completion follows the path
+	dir := "/home"
+	if i := strings.LastIndex(word, "/"); i > 0 {
+		dir = word[:i]
+	}
+
+	a := c.getArgumentCompleter(c.pathArguments, fmt.Sprintf("%v:%s", onlyDirs, dir))
+	if s, ok := checkCache(a); ok {
+		return s
 	}
 
 	info := []*provider.ResourceInfo{}
 	suggests := []prompt.Suggest{}
 
-	b, err := executeCommand(lsCommand(), "/home")
+	b, err := executeCommand(lsCommand(), dir)
 	if err == nil {
 		dec := gob.NewDecoder(&b)
 		if err := dec.Decode(&info); err != nil {
@@ -90,11 +94,37 @@ func (c *Completer) lsArgumentCompleter(onlyDirs bool) []prompt.Suggest {
 		}
 	}
 
-	if onlyDirs {
-		cacheSuggestions(c.lsDirArguments, suggests)
-	} else {
-		cacheSuggestions(c.lsArguments, suggests)
+	cacheSuggestions(a, suggests)
+	return suggests
+}
+
+func (c *Completer) userArgumentCompleter(query string) []prompt.Suggest {
+	// Avoid querying all users of the system
+	if len(query) < 2 {
+		return []prompt.Suggest{}
+	}
+
+	a := c.getArgumentCompleter(c.userArguments, query)
+	if s, ok := checkCache(a); ok {
+		return s
+	}
+
+	users := []*userpb.User{}
+	suggests := []prompt.Suggest{}
+
+	b, err := executeCommand(userFindCommand(), query)
+	if err == nil {
+		dec := gob.NewDecoder(&b)
+		if err := dec.Decode(&users); err != nil {
+			return []prompt.Suggest{}
+		}
+
+		for _, u := range users {
+			suggests = append(suggests, prompt.Suggest{Text: u.Id.OpaqueId, Description: fmt.Sprintf("%s (%s)", u.DisplayName, u.Username)})
+		}
 	}
+
+	cacheSuggestions(a, suggests)
 	return suggests
 }
 
@@ -224,6 +254,18 @@ func executeCommand(cmd *command, args ...string) (bytes.Buffer, error) {
 	return b, nil
 }
 
+func (c *Completer) getArgumentCompleter(completers map[string]*argumentCompleter, key string) *argumentCompleter {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	a, ok := completers[key]
+	if !ok {
+		a = new(argumentCompleter)
+		completers[key] = a
+	}
+	return a
+}
+
 func checkCache(a *argumentCompleter) ([]prompt.Suggest, bool) {
 	a.RLock()
 	defer a.RUnlock()
@@ -20,7 +20,9 @@ package main
 
 import (
 	"flag"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/c-bata/go-prompt"
 )
@@ -30,19 +32,20 @@ type Completer struct {
 	Commands                  []*command
 	DisableArgPrompt          bool
 	loginArguments            *argumentCompleter
-	lsArguments               *argumentCompleter
-	lsDirArguments            *argumentCompleter
+	pathArguments             map[string]*argumentCompleter
+	userArguments             map[string]*argumentCompleter
 	ocmShareArguments         *argumentCompleter
 	ocmShareReceivedArguments *argumentCompleter
 	shareArguments            *argumentCompleter
 	shareReceivedArguments    *argumentCompleter
+	mutex                     sync.Mutex
 }
 
 func (c *Completer) init() {
-	c.loginArguments, c.lsArguments = new(argumentCompleter), new(argumentCompleter)
+	c.loginArguments = new(argumentCompleter)
 	c.ocmShareArguments, c.ocmShareReceivedArguments = new(argumentCompleter), new(argumentCompleter)
 	c.shareArguments, c.shareReceivedArguments = new(argumentCompleter), new(argumentCompleter)
-	c.lsDirArguments = new(argumentCompleter)
+	c.pathArguments, c.userArguments = make(map[string]*argumentCompleter), make(map[string]*argumentCompleter)
 }
 
 // Complete provides completion to prompt
@@ -50,7 +53,13 @@ func (c *Completer) Complete(d prompt.Document) []prompt.Suggest {
 	if d.TextBeforeCursor() == "" {
 		return []prompt.Suggest{}
 	}
-	args := strings.Split(d.TextBeforeCursor(), " ")
+
+	// Once the first word is complete, complete the arguments of the command an alias stands for
+	text := d.TextBeforeCursor()
+	if strings.Contains(text, " ") {
+		text = expandAlias(text)
+	}
+	args := strings.Split(text, " ")
 
 	w := d.GetWordBeforeCursor()
 
@@ -88,24 +97,24 @@ func (c *Completer) argumentCompleter(args ...string) []prompt.Suggest {
 
 	case "ls", "mkdir":
 		if len(args) == 2 {
-			return prompt.FilterHasPrefix(c.lsArgumentCompleter(true), args[1], true)
+			return prompt.FilterHasPrefix(c.pathArgumentCompleter(args[1], true), args[1], true)
 		}
 
 	case "mv":
 		if len(args) == 2 {
-			return prompt.FilterHasPrefix(c.lsArgumentCompleter(false), args[1], true)
+			return prompt.FilterHasPrefix(c.pathArgumentCompleter(args[1], false), args[1], true)
 		} else if len(args) == 3 {
-			return prompt.FilterHasPrefix(c.lsArgumentCompleter(false), args[2], true)
+			return prompt.FilterHasPrefix(c.pathArgumentCompleter(args[2], false), args[2], true)
 		}
 
 	case "rm", "stat", "share-create", "ocm-share-create", "public-share-create", "open-in-app", "open-file-in-app-provider", "download":
 		if len(args) == 2 {
-			return prompt.FilterHasPrefix(c.lsArgumentCompleter(false), args[1], true)
+			return prompt.FilterHasPrefix(c.pathArgumentCompleter(args[1], false), args[1], true)
 		}
 
 	case "upload":
 		if len(args) == 3 {
-			return prompt.FilterHasPrefix(c.lsArgumentCompleter(false), args[2], true)
+			return prompt.FilterHasPrefix(c.pathArgumentCompleter(args[2], false), args[2], true)
 		}
 
 	case "ocm-share-remove", "ocm-share-update":
@@ -177,7 +186,10 @@ func (c *Completer) completeOptionArguments(d prompt.Document) ([]prompt.Suggest
 	case "-viewmode":
 		suggests = []prompt.Suggest{{Text: "view"}, {Text: "read"}, {Text: "write"}}
 		match = true
-	case "-c", "-grantee", "-idp", "-by-resource-id", "-xs", "-token":
+	case "-grantee":
+		// Users are looked up by name, so the suggestions mustn't be filtered by prefix
+		return c.userArgumentCompleter(d.GetWordBeforeCursor()), true
+	case "-c", "-idp", "-by-resource-id", "-xs", "-token":
 		match = true
 	}
 	return prompt.FilterHasPrefix(suggests, d.GetWordBeforeCursor(), true), match
@@ -233,7 +245,20 @@ func excludeOptions(args []string) ([]string, bool) {
 }
 
 func (c *Completer) getAllSuggests() []prompt.Suggest {
-	return convertCmdToSuggests(commands)
+	suggests := convertCmdToSuggests(commands)
+
+	if aliases, err := readAliases(); err == nil {
+		names := make([]string, 0, len(aliases))
+		for name := range aliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			suggests = append(suggests, prompt.Suggest{Text: name, Description: "alias for " + aliases[name]})
+		}
+	}
+
+	return suggests
 }
 
 func convertCmdToSuggests(cmds []*command) []prompt.Suggest {
@@ -0,0 +1,87 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// checkDryRunSupport makes sure that the gateway evaluates the dry-run requests, as an
+// older gateway ignores the flag and executes the operation. A supporting gateway
+// acknowledges the flag in the header of its responses, which is checked on a stat.
+func checkDryRunSupport(ctx context.Context, client gateway.GatewayAPIClient) error {
+	var header metadata.MD
+	_, err := client.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{Path: "/"}}, grpc.Header(&header))
+	if err != nil {
+		return err
+	}
+	if len(header.Get(ctxpkg.DryRunSupportedHeader)) == 0 {
+		return errors.New("dry-run: the gateway does not support dry-runs, the operation was not executed")
+	}
+	return nil
+}
+
+// printDryRunReport prints what a destructive operation would do, as reported by the gateway.
+func printDryRunReport(opaque *types.Opaque) {
+	entry, ok := opaque.GetMap()["dry_run_report"]
+	if !ok {
+		fmt.Println("dry-run: the gateway did not report the effects of the operation")
+		return
+	}
+
+	report := struct {
+		AffectedItems int             `json:"affected_items"`
+		Truncated     bool            `json:"truncated"`
+		Permissions   map[string]bool `json:"permissions"`
+	}{}
+	if err := json.Unmarshal(entry.Value, &report); err != nil {
+		fmt.Println("dry-run: invalid report:", err)
+		return
+	}
+
+	items := fmt.Sprintf("%d", report.AffectedItems)
+	if report.Truncated {
+		items += "+"
+	}
+	fmt.Printf("dry-run: %s item(s) affected\n", items)
+
+	perms := make([]string, 0, len(report.Permissions))
+	for perm := range report.Permissions {
+		perms = append(perms, perm)
+	}
+	sort.Strings(perms)
+	for _, perm := range perms {
+		granted := "granted"
+		if !report.Permissions[perm] {
+			granted = "denied"
+		}
+		fmt.Printf("  %s: %s\n", perm, granted)
+	}
+}
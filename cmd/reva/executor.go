@@ -43,7 +43,7 @@ func (e *Executor) Execute(s string) {
 		os.Exit(0)
 	}
 
-	args := strings.Split(s, " ")
+	args := strings.Split(expandAlias(s), " ")
 
 	// Verify that the configuration is set, either in memory or in a file.
 	if conf == nil || conf.Host == "" {
@@ -0,0 +1,89 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	gouser "os/user"
+	"path"
+	"strings"
+)
+
+// maxHistoryEntries is the number of commands kept in the history file.
+const maxHistoryEntries = 1000
+
+func getHistoryFile() string {
+	user, err := gouser.Current()
+	if err != nil {
+		panic(err)
+	}
+
+	return path.Join(user.HomeDir, ".reva-history")
+}
+
+func readHistory() []string {
+	f, err := os.Open(getHistoryFile())
+	if err != nil {
+		return []string{}
+	}
+	defer f.Close()
+
+	return parseHistory(f)
+}
+
+// parseHistory reads the non-empty lines of a history, keeping the most recent entries only.
+func parseHistory(r io.Reader) []string {
+	history := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			history = append(history, line)
+		}
+	}
+
+	if len(history) > maxHistoryEntries {
+		history = history[len(history)-maxHistoryEntries:]
+	}
+	return history
+}
+
+func appendHistory(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+
+	f, err := os.OpenFile(getHistoryFile(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	_, _ = f.WriteString(line + "\n")
+}
+
+func writeHistory(history []string) {
+	if len(history) == 0 {
+		return
+	}
+	_ = ioutil.WriteFile(getHistoryFile(), []byte(strings.Join(history, "\n")+"\n"), 0600)
+}
@@ -47,6 +47,7 @@ var (
 		configureCommand(),
 		loginCommand(),
 		whoamiCommand(),
+		userFindCommand(),
 		importCommand(),
 		lsCommand(),
 		statCommand(),
@@ -55,6 +56,7 @@ var (
 		rmCommand(),
 		moveCommand(),
 		mkdirCommand(),
+		mountCommand(),
 		ocmFindAcceptedUsersCommand(),
 		ocmInviteGenerateCommand(),
 		ocmInviteForwardCommand(),
@@ -64,8 +66,10 @@ var (
 		ocmShareUpdateCommand(),
 		ocmShareListReceivedCommand(),
 		ocmShareUpdateReceivedCommand(),
+		ocmCachePurgeCommand(),
 		openInAppCommand(),
 		preferencesCommand(),
+		publicShareAnalyticsCommand(),
 		publicShareCreateCommand(),
 		publicShareListCommand(),
 		publicShareRemoveCommand(),
@@ -79,6 +83,13 @@ var (
 		shareUpdateCommand(),
 		shareListReceivedCommand(),
 		shareUpdateReceivedCommand(),
+		shareExportCommand(),
+		shareImportCommand(),
+		storageBackupMetadataCommand(),
+		storageRestoreMetadataCommand(),
+		storageRepairMovesCommand(),
+		storageFsckCommand(),
+		storageVerifyCommand(),
 		transferCreateCommand(),
 		transferGetStatusCommand(),
 		transferCancelCommand(),
@@ -90,6 +101,7 @@ var (
 		setlockCommand(),
 		getlockCommand(),
 		unlockCommand(),
+		aliasCommand(),
 		helpCommand(),
 	}
 )
@@ -142,11 +154,19 @@ func main() {
 	fmt.Printf("reva-cli %s (rev-%s)\n", version, gitCommit)
 	fmt.Println("Please use `exit` or `Ctrl-D` to exit this program.")
 
+	// Restore the history of previous sessions and record all commands entered in this one
+	history := readHistory()
+	writeHistory(history)
+
 	p := prompt.New(
-		executor.Execute,
+		func(s string) {
+			appendHistory(s)
+			executor.Execute(s)
+		},
 		completer.Complete,
 		prompt.OptionTitle("reva-cli"),
 		prompt.OptionPrefix(">> "),
+		prompt.OptionHistory(history),
 	)
 	p.Run()
 }
@@ -0,0 +1,987 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/fuse"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	gstatus "google.golang.org/grpc/status"
+)
+
+func mountCommand() *command {
+	cmd := newCommand("mount")
+	cmd.Description = func() string { return "mount a remote folder on the local filesystem" }
+	cmd.Usage = func() string { return "Usage: mount [-flags] <remote_folder> <mountpoint>" }
+	attrTTLFlag := cmd.Duration("attr-ttl", time.Second, "how long file attributes are cached")
+	allowOtherFlag := cmd.Bool("allow-other", false, "allow other users to access the mount")
+	usernameFlag := cmd.String("username", "", "the user id to authenticate as with machine auth when the token expires")
+	apiKeyFlag := cmd.String("api-key", "", "the machine auth api key used when the token expires")
+
+	cmd.ResetFlags = func() {
+		*attrTTLFlag, *allowOtherFlag, *usernameFlag, *apiKeyFlag = time.Second, false, "", ""
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 2 {
+			return errors.New("Invalid arguments: " + cmd.Usage())
+		}
+
+		remote := path.Clean(cmd.Args()[0])
+		mountpoint, err := utils.ResolvePath(cmd.Args()[1])
+		if err != nil {
+			return err
+		}
+
+		gwc, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		auth := &mountAuth{gwc: gwc, username: *usernameFlag, apiKey: *apiKeyFlag}
+		if auth.token, err = readToken(); err != nil && auth.username == "" {
+			return errors.Wrap(err, "error reading the token, please login first")
+		}
+
+		fs := newRemoteFS(gwc, auth, remote, *attrTTLFlag)
+		info, err := fs.stat(context.Background(), remote)
+		if err != nil {
+			return errors.Wrap(err, "error stating "+remote)
+		}
+		if info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			return errors.New(remote + " is not a folder")
+		}
+
+		// the executor gives up on a command when interrupted without waiting for it,
+		// so the signal is taken over to release the mount point before exiting
+		signal.Reset(os.Interrupt, syscall.SIGTERM)
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sigs)
+
+		conn, err := fuse.Mount(mountpoint, fuse.MountOptions{AllowOther: *allowOtherFlag})
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			for {
+				select {
+				case <-sigs:
+					if err := fuse.Unmount(mountpoint); err != nil {
+						fmt.Println(err)
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		fmt.Printf("Mounted %s on %s, press Ctrl-C to unmount\n", remote, mountpoint)
+		return fuse.NewServer(conn, fs).Serve()
+	}
+	return cmd
+}
+
+// mountAuth holds the token of a mount and renews it when it expires
+type mountAuth struct {
+	gwc      gateway.GatewayAPIClient
+	username string
+	apiKey   string
+
+	mu    sync.Mutex
+	token string
+}
+
+func (a *mountAuth) current() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.token
+}
+
+func (a *mountAuth) context(ctx context.Context, token string) context.Context {
+	ctx = ctxpkg.ContextSetToken(ctx, token)
+	return metadata.AppendToOutgoingContext(ctx, ctxpkg.TokenHeader, token)
+}
+
+// refresh replaces the expired token, unless another request already did
+func (a *mountAuth) refresh(ctx context.Context, expired string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != expired {
+		return nil
+	}
+
+	if a.username == "" {
+		// pick up the token of a login done in the meantime
+		t, err := readToken()
+		if err != nil {
+			return err
+		}
+		if t == expired {
+			return errors.New("the token has expired, please login again")
+		}
+		a.token = t
+		return nil
+	}
+
+	res, err := a.gwc.Authenticate(ctx, &gateway.AuthenticateRequest{
+		Type:         "machine",
+		ClientId:     "userid:" + a.username,
+		ClientSecret: a.apiKey,
+	})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return formatError(res.Status)
+	}
+	a.token = res.Token
+	return nil
+}
+
+type cachedInfo struct {
+	info    *provider.ResourceInfo
+	expires time.Time
+}
+
+// mountHandle is an open file; files opened for writing are staged in a
+// temporary file which is uploaded when it is closed
+type mountHandle struct {
+	mu   sync.Mutex
+	node uint64
+	size uint64
+
+	// download endpoint of read only handles
+	endpoint string
+	token    string
+
+	tmp   *os.File
+	dirty bool
+}
+
+// remoteFS exposes a remote folder over FUSE, addressing nodes by path
+type remoteFS struct {
+	gwc  gateway.GatewayAPIClient
+	auth *mountAuth
+	ttl  time.Duration
+	uid  uint32
+	gid  uint32
+
+	mu      sync.Mutex
+	paths   map[uint64]string
+	inos    map[string]uint64
+	lookups map[uint64]uint64
+	nextIno uint64
+	infos   map[string]cachedInfo
+	handles map[uint64]*mountHandle
+	nextFh  uint64
+}
+
+func newRemoteFS(gwc gateway.GatewayAPIClient, auth *mountAuth, root string, ttl time.Duration) *remoteFS {
+	return &remoteFS{
+		gwc:     gwc,
+		auth:    auth,
+		ttl:     ttl,
+		uid:     uint32(os.Getuid()),
+		gid:     uint32(os.Getgid()),
+		paths:   map[uint64]string{fuse.RootID: root},
+		inos:    map[string]uint64{root: fuse.RootID},
+		lookups: map[uint64]uint64{},
+		nextIno: fuse.RootID,
+		infos:   map[string]cachedInfo{},
+		handles: map[uint64]*mountHandle{},
+	}
+}
+
+// call runs a gateway request, renewing the token and retrying once when it has expired
+func (fs *remoteFS) call(ctx context.Context, f func(ctx context.Context) (*rpc.Status, error)) error {
+	for retried := false; ; retried = true {
+		token := fs.auth.current()
+		st, err := f(fs.auth.context(ctx, token))
+		expired := gstatus.Code(err) == codes.Unauthenticated || (err == nil && st.Code == rpc.Code_CODE_UNAUTHENTICATED)
+		if expired && !retried {
+			if err := fs.auth.refresh(ctx, token); err != nil {
+				log.Println("mount: error refreshing the token:", err)
+				return syscall.EACCES
+			}
+			continue
+		}
+		if err != nil {
+			log.Println("mount:", err)
+			return syscall.EIO
+		}
+		return toErrno(st)
+	}
+}
+
+func toErrno(st *rpc.Status) error {
+	switch st.Code {
+	case rpc.Code_CODE_OK:
+		return nil
+	case rpc.Code_CODE_NOT_FOUND:
+		return syscall.ENOENT
+	case rpc.Code_CODE_PERMISSION_DENIED, rpc.Code_CODE_UNAUTHENTICATED:
+		return syscall.EACCES
+	case rpc.Code_CODE_ALREADY_EXISTS:
+		return syscall.EEXIST
+	case rpc.Code_CODE_INSUFFICIENT_STORAGE:
+		return syscall.ENOSPC
+	case rpc.Code_CODE_INVALID_ARGUMENT:
+		return syscall.EINVAL
+	case rpc.Code_CODE_FAILED_PRECONDITION, rpc.Code_CODE_ABORTED:
+		return syscall.EBUSY
+	case rpc.Code_CODE_UNIMPLEMENTED:
+		return syscall.ENOSYS
+	}
+	log.Println("mount:", formatError(st))
+	return syscall.EIO
+}
+
+func (fs *remoteFS) path(id uint64) (string, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	p, ok := fs.paths[id]
+	if !ok {
+		return "", syscall.ESTALE
+	}
+	return p, nil
+}
+
+func (fs *remoteFS) child(parent uint64, name string) (string, error) {
+	p, err := fs.path(parent)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(p, name), nil
+}
+
+// node returns the id of a path, counting the lookup the kernel will remember
+func (fs *remoteFS) node(p string) uint64 {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	id, ok := fs.inos[p]
+	if !ok {
+		fs.nextIno++
+		id = fs.nextIno
+		fs.inos[p] = id
+		fs.paths[id] = p
+	}
+	fs.lookups[id]++
+	return id
+}
+
+func (fs *remoteFS) invalidate(paths ...string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for _, p := range paths {
+		delete(fs.infos, p)
+		delete(fs.infos, path.Dir(p))
+	}
+}
+
+func (fs *remoteFS) cache(p string, info *provider.ResourceInfo) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.infos[p] = cachedInfo{info: info, expires: time.Now().Add(fs.ttl)}
+}
+
+func (fs *remoteFS) stat(ctx context.Context, p string) (*provider.ResourceInfo, error) {
+	fs.mu.Lock()
+	c, ok := fs.infos[p]
+	fs.mu.Unlock()
+	if ok && time.Now().Before(c.expires) {
+		return c.info, nil
+	}
+
+	var res *provider.StatResponse
+	err := fs.call(ctx, func(ctx context.Context) (*rpc.Status, error) {
+		var err error
+		if res, err = fs.gwc.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{Path: p}}); err != nil {
+			return nil, err
+		}
+		return res.Status, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	fs.cache(p, res.Info)
+	return res.Info, nil
+}
+
+func (fs *remoteFS) attr(id uint64, info *provider.ResourceInfo) *fuse.Attr {
+	a := &fuse.Attr{
+		Ino:   id,
+		Size:  info.Size,
+		Mode:  0644,
+		Nlink: 1,
+		UID:   fs.uid,
+		GID:   fs.gid,
+		Valid: fs.ttl,
+	}
+	if info.Mtime != nil {
+		a.Mtime = utils.TSToTime(info.Mtime)
+	}
+	if info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		a.Mode = os.ModeDir | 0755
+		a.Nlink = 2
+	} else if info.PermissionSet != nil && !info.PermissionSet.InitiateFileUpload {
+		a.Mode = 0444
+	}
+
+	// files being written report their local size until they are uploaded
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for _, h := range fs.handles {
+		if h.node == id && h.tmp != nil {
+			if st, err := h.tmp.Stat(); err == nil {
+				a.Size = uint64(st.Size())
+			}
+		}
+	}
+	return a
+}
+
+func (fs *remoteFS) Lookup(ctx context.Context, parent uint64, name string) (*fuse.Attr, error) {
+	p, err := fs.child(parent, name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := fs.stat(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return fs.attr(fs.node(p), info), nil
+}
+
+func (fs *remoteFS) Forget(id uint64, n uint64) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if id == fuse.RootID {
+		return
+	}
+	if fs.lookups[id] > n {
+		fs.lookups[id] -= n
+		return
+	}
+	delete(fs.lookups, id)
+	if p, ok := fs.paths[id]; ok {
+		delete(fs.paths, id)
+		if fs.inos[p] == id {
+			delete(fs.inos, p)
+		}
+	}
+}
+
+func (fs *remoteFS) GetAttr(ctx context.Context, id uint64) (*fuse.Attr, error) {
+	p, err := fs.path(id)
+	if err != nil {
+		return nil, err
+	}
+	info, err := fs.stat(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return fs.attr(id, info), nil
+}
+
+func (fs *remoteFS) Truncate(ctx context.Context, id uint64, fh uint64, size uint64) (*fuse.Attr, error) {
+	if h, err := fs.handle(fh); err == nil && h.tmp != nil {
+		if err := h.truncate(size); err != nil {
+			return nil, err
+		}
+		return fs.GetAttr(ctx, id)
+	}
+
+	// truncating a file which is not open for writing uploads it right away
+	flags := syscall.O_WRONLY
+	if size == 0 {
+		flags |= syscall.O_TRUNC
+	}
+	fh, err := fs.Open(ctx, id, flags)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = fs.Release(ctx, id, fh) }()
+	h, err := fs.handle(fh)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.truncate(size); err != nil {
+		return nil, err
+	}
+	if err := fs.Flush(ctx, id, fh); err != nil {
+		return nil, err
+	}
+	return fs.GetAttr(ctx, id)
+}
+
+func (fs *remoteFS) Mkdir(ctx context.Context, parent uint64, name string) (*fuse.Attr, error) {
+	p, err := fs.child(parent, name)
+	if err != nil {
+		return nil, err
+	}
+	err = fs.call(ctx, func(ctx context.Context) (*rpc.Status, error) {
+		res, err := fs.gwc.CreateContainer(ctx, &provider.CreateContainerRequest{Ref: &provider.Reference{Path: p}})
+		if err != nil {
+			return nil, err
+		}
+		return res.Status, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	fs.invalidate(p)
+	return fs.Lookup(ctx, parent, name)
+}
+
+func (fs *remoteFS) remove(ctx context.Context, p string) error {
+	err := fs.call(ctx, func(ctx context.Context) (*rpc.Status, error) {
+		res, err := fs.gwc.Delete(ctx, &provider.DeleteRequest{Ref: &provider.Reference{Path: p}})
+		if err != nil {
+			return nil, err
+		}
+		return res.Status, nil
+	})
+	fs.invalidate(p)
+	return err
+}
+
+func (fs *remoteFS) Unlink(ctx context.Context, parent uint64, name string) error {
+	p, err := fs.child(parent, name)
+	if err != nil {
+		return err
+	}
+	return fs.remove(ctx, p)
+}
+
+func (fs *remoteFS) Rmdir(ctx context.Context, parent uint64, name string) error {
+	p, err := fs.child(parent, name)
+	if err != nil {
+		return err
+	}
+	infos, err := fs.list(ctx, p)
+	if err != nil {
+		return err
+	}
+	if len(infos) > 0 {
+		return syscall.ENOTEMPTY
+	}
+	return fs.remove(ctx, p)
+}
+
+func (fs *remoteFS) Rename(ctx context.Context, parent uint64, name string, newParent uint64, newName string) error {
+	src, err := fs.child(parent, name)
+	if err != nil {
+		return err
+	}
+	dst, err := fs.child(newParent, newName)
+	if err != nil {
+		return err
+	}
+
+	// a rename replaces an existing file, as editors saving through a temporary file expect
+	fs.invalidate(dst)
+	if info, err := fs.stat(ctx, dst); err == nil {
+		if info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			return syscall.EEXIST
+		}
+		if err := fs.remove(ctx, dst); err != nil {
+			return err
+		}
+	}
+
+	err = fs.call(ctx, func(ctx context.Context) (*rpc.Status, error) {
+		res, err := fs.gwc.Move(ctx, &provider.MoveRequest{
+			Source:      &provider.Reference{Path: src},
+			Destination: &provider.Reference{Path: dst},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return res.Status, nil
+	})
+	fs.invalidate(src, dst)
+	if err != nil {
+		return err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	delete(fs.inos, dst)
+	for p, id := range fs.inos {
+		if p == src || strings.HasPrefix(p, src+"/") {
+			np := dst + strings.TrimPrefix(p, src)
+			delete(fs.inos, p)
+			delete(fs.infos, p)
+			fs.inos[np] = id
+			fs.paths[id] = np
+		}
+	}
+	return nil
+}
+
+func (fs *remoteFS) list(ctx context.Context, p string) ([]*provider.ResourceInfo, error) {
+	var res *provider.ListContainerResponse
+	err := fs.call(ctx, func(ctx context.Context) (*rpc.Status, error) {
+		var err error
+		if res, err = fs.gwc.ListContainer(ctx, &provider.ListContainerRequest{Ref: &provider.Reference{Path: p}}); err != nil {
+			return nil, err
+		}
+		return res.Status, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Infos, nil
+}
+
+func (fs *remoteFS) ReadDir(ctx context.Context, id uint64) ([]fuse.Dirent, error) {
+	p, err := fs.path(id)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := fs.list(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]fuse.Dirent, 0, len(infos))
+	for _, info := range infos {
+		name := path.Base(info.Path)
+		cp := path.Join(p, name)
+		fs.cache(cp, info)
+		fs.mu.Lock()
+		ino := fs.inos[cp]
+		fs.mu.Unlock()
+		entries = append(entries, fuse.Dirent{
+			Ino:  ino,
+			Name: name,
+			Dir:  info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER,
+		})
+	}
+	return entries, nil
+}
+
+func (fs *remoteFS) Open(ctx context.Context, id uint64, flags int) (uint64, error) {
+	p, err := fs.path(id)
+	if err != nil {
+		return 0, err
+	}
+	fs.invalidate(p)
+	info, err := fs.stat(ctx, p)
+	if err != nil {
+		return 0, err
+	}
+	if info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		return 0, syscall.EISDIR
+	}
+
+	h := &mountHandle{node: id, size: info.Size}
+	if flags&syscall.O_ACCMODE != syscall.O_RDONLY {
+		if h.tmp, err = ioutil.TempFile("", "reva-mount-"); err != nil {
+			return 0, err
+		}
+		if flags&syscall.O_TRUNC != 0 {
+			h.dirty = true
+		} else if info.Size > 0 {
+			if err := fs.download(ctx, p, h.tmp); err != nil {
+				h.close()
+				return 0, err
+			}
+		}
+	}
+	return fs.register(h), nil
+}
+
+func (fs *remoteFS) Create(ctx context.Context, parent uint64, name string, flags int) (*fuse.Attr, uint64, error) {
+	p, err := fs.child(parent, name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// the file is made visible right away, its content is uploaded when it is closed
+	err = fs.call(ctx, func(ctx context.Context) (*rpc.Status, error) {
+		res, err := fs.gwc.TouchFile(ctx, &provider.TouchFileRequest{Ref: &provider.Reference{Path: p}})
+		if err != nil {
+			return nil, err
+		}
+		return res.Status, nil
+	})
+	fs.invalidate(p)
+	switch {
+	case err == syscall.EEXIST && flags&syscall.O_EXCL == 0, err == syscall.ENOSYS:
+	case err != nil:
+		return nil, 0, err
+	}
+
+	h := &mountHandle{dirty: true}
+	if h.tmp, err = ioutil.TempFile("", "reva-mount-"); err != nil {
+		return nil, 0, err
+	}
+	h.node = fs.node(p)
+	fh := fs.register(h)
+
+	info, err := fs.stat(ctx, p)
+	if err != nil {
+		// the storage does not support touching files
+		now := time.Now()
+		info = &provider.ResourceInfo{
+			Type:  provider.ResourceType_RESOURCE_TYPE_FILE,
+			Mtime: &typespb.Timestamp{Seconds: uint64(now.Unix()), Nanos: uint32(now.Nanosecond())},
+		}
+	}
+	return fs.attr(h.node, info), fh, nil
+}
+
+func (fs *remoteFS) register(h *mountHandle) uint64 {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.nextFh++
+	fs.handles[fs.nextFh] = h
+	return fs.nextFh
+}
+
+func (fs *remoteFS) handle(fh uint64) (*mountHandle, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	h, ok := fs.handles[fh]
+	if !ok {
+		return nil, syscall.EBADF
+	}
+	return h, nil
+}
+
+func (fs *remoteFS) Read(ctx context.Context, id uint64, fh uint64, off int64, size int) ([]byte, error) {
+	h, err := fs.handle(fh)
+	if err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	buf := make([]byte, size)
+	if h.tmp != nil {
+		n, err := h.tmp.ReadAt(buf, off)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+
+	if uint64(off) >= h.size {
+		return nil, nil
+	}
+	p, err := fs.path(id)
+	if err != nil {
+		return nil, err
+	}
+	n, err := fs.readRange(ctx, h, p, buf, off)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// readRange fetches a range of a file, renewing the download endpoint when its token has expired
+func (fs *remoteFS) readRange(ctx context.Context, h *mountHandle, p string, buf []byte, off int64) (int, error) {
+	for retried := false; ; retried = true {
+		if h.endpoint == "" {
+			endpoint, token, err := fs.initiateDownload(ctx, p)
+			if err != nil {
+				return 0, err
+			}
+			h.endpoint, h.token = endpoint, token
+		}
+
+		httpReq, err := rhttp.NewRequest(ctx, http.MethodGet, h.endpoint, nil)
+		if err != nil {
+			return 0, err
+		}
+		httpReq.Header.Set(datagateway.TokenTransportHeader, h.token)
+		httpReq.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(len(buf))-1))
+
+		httpRes, err := client.Do(httpReq)
+		if err != nil {
+			log.Println("mount:", err)
+			return 0, syscall.EIO
+		}
+		defer httpRes.Body.Close()
+
+		switch httpRes.StatusCode {
+		case http.StatusOK:
+			// the range was ignored
+			if _, err := io.CopyN(ioutil.Discard, httpRes.Body, off); err != nil {
+				return 0, nil
+			}
+		case http.StatusPartialContent:
+		case http.StatusRequestedRangeNotSatisfiable:
+			return 0, nil
+		case http.StatusUnauthorized, http.StatusForbidden:
+			if !retried {
+				h.endpoint = ""
+				continue
+			}
+			return 0, syscall.EACCES
+		default:
+			log.Println("mount: GET request returned " + httpRes.Status)
+			return 0, syscall.EIO
+		}
+
+		n, err := io.ReadFull(httpRes.Body, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, syscall.EIO
+		}
+		return n, nil
+	}
+}
+
+func (fs *remoteFS) initiateDownload(ctx context.Context, p string) (string, string, error) {
+	var res *gateway.InitiateFileDownloadResponse
+	err := fs.call(ctx, func(ctx context.Context) (*rpc.Status, error) {
+		var err error
+		if res, err = fs.gwc.InitiateFileDownload(ctx, &provider.InitiateFileDownloadRequest{Ref: &provider.Reference{Path: p}}); err != nil {
+			return nil, err
+		}
+		return res.Status, nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+	protocol, err := getDownloadProtocolInfo(res.Protocols, "simple")
+	if err != nil {
+		return "", "", syscall.EIO
+	}
+	return protocol.DownloadEndpoint, protocol.Token, nil
+}
+
+func (fs *remoteFS) download(ctx context.Context, p string, f *os.File) error {
+	endpoint, token, err := fs.initiateDownload(ctx, p)
+	if err != nil {
+		return err
+	}
+	httpReq, err := rhttp.NewRequest(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set(datagateway.TokenTransportHeader, token)
+	httpRes, err := client.Do(httpReq)
+	if err != nil {
+		log.Println("mount:", err)
+		return syscall.EIO
+	}
+	defer httpRes.Body.Close()
+	if httpRes.StatusCode != http.StatusOK {
+		log.Println("mount: GET request returned " + httpRes.Status)
+		return syscall.EIO
+	}
+	if _, err := io.Copy(f, httpRes.Body); err != nil {
+		return syscall.EIO
+	}
+	return nil
+}
+
+func (fs *remoteFS) upload(ctx context.Context, p string, f *os.File) error {
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	var res *gateway.InitiateFileUploadResponse
+	err = fs.call(ctx, func(ctx context.Context) (*rpc.Status, error) {
+		var err error
+		res, err = fs.gwc.InitiateFileUpload(ctx, &provider.InitiateFileUploadRequest{
+			Ref: &provider.Reference{Path: p},
+			Opaque: &typespb.Opaque{
+				Map: map[string]*typespb.OpaqueEntry{
+					"Upload-Length": {
+						Decoder: "plain",
+						Value:   []byte(strconv.FormatInt(st.Size(), 10)),
+					},
+				},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return res.Status, nil
+	})
+	if err != nil {
+		return err
+	}
+	protocol, err := getUploadProtocolInfo(res.Protocols, "simple")
+	if err != nil {
+		return syscall.EIO
+	}
+
+	httpReq, err := rhttp.NewRequest(ctx, http.MethodPut, protocol.UploadEndpoint, io.NewSectionReader(f, 0, st.Size()))
+	if err != nil {
+		return err
+	}
+	httpReq.ContentLength = st.Size()
+	httpReq.Header.Set(datagateway.TokenTransportHeader, protocol.Token)
+	httpRes, err := client.Do(httpReq)
+	if err != nil {
+		log.Println("mount:", err)
+		return syscall.EIO
+	}
+	defer httpRes.Body.Close()
+	switch httpRes.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	case http.StatusInsufficientStorage:
+		return syscall.ENOSPC
+	case http.StatusForbidden:
+		return syscall.EACCES
+	}
+	log.Println("mount: PUT request returned " + httpRes.Status)
+	return syscall.EIO
+}
+
+func (fs *remoteFS) Write(ctx context.Context, id uint64, fh uint64, off int64, data []byte) (int, error) {
+	h, err := fs.handle(fh)
+	if err != nil {
+		return 0, err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.tmp == nil {
+		return 0, syscall.EBADF
+	}
+	n, err := h.tmp.WriteAt(data, off)
+	if n > 0 {
+		h.dirty = true
+	}
+	return n, err
+}
+
+func (fs *remoteFS) Flush(ctx context.Context, id uint64, fh uint64) error {
+	h, err := fs.handle(fh)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.dirty {
+		return nil
+	}
+	p, err := fs.path(id)
+	if err != nil {
+		return err
+	}
+	err = fs.upload(ctx, p, h.tmp)
+	fs.invalidate(p)
+	if err != nil {
+		return err
+	}
+	h.dirty = false
+	return nil
+}
+
+func (fs *remoteFS) Release(ctx context.Context, id uint64, fh uint64) error {
+	// close(2) has already flushed, this only catches writes through duplicated descriptors
+	err := fs.Flush(ctx, id, fh)
+
+	fs.mu.Lock()
+	h, ok := fs.handles[fh]
+	delete(fs.handles, fh)
+	fs.mu.Unlock()
+	if ok {
+		h.close()
+	}
+	return err
+}
+
+func (h *mountHandle) truncate(size uint64) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.tmp.Truncate(int64(size)); err != nil {
+		return err
+	}
+	h.dirty = true
+	return nil
+}
+
+func (h *mountHandle) close() {
+	if h.tmp != nil {
+		h.tmp.Close()
+		os.Remove(h.tmp.Name())
+	}
+}
+
+func (fs *remoteFS) StatFS(ctx context.Context) (*fuse.StatFS, error) {
+	root, err := fs.path(fuse.RootID)
+	if err != nil {
+		return nil, err
+	}
+
+	const bsize = 4096
+	var res *provider.GetQuotaResponse
+	err = fs.call(ctx, func(ctx context.Context) (*rpc.Status, error) {
+		var err error
+		if res, err = fs.gwc.GetQuota(ctx, &gateway.GetQuotaRequest{Ref: &provider.Reference{Path: root}}); err != nil {
+			return nil, err
+		}
+		return res.Status, nil
+	})
+	total, used := uint64(1<<50), uint64(0)
+	switch {
+	case err == syscall.ENOSYS:
+		// no quota, report a large file system
+	case err != nil:
+		return nil, err
+	case res.TotalBytes > 0:
+		total, used = res.TotalBytes, res.UsedBytes
+	}
+	free := uint64(0)
+	if total > used {
+		free = total - used
+	}
+	return &fuse.StatFS{Blocks: total / bsize, Bfree: free / bsize, Bavail: free / bsize, Bsize: bsize}, nil
+}
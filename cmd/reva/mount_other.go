@@ -0,0 +1,38 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+func mountCommand() *command {
+	cmd := newCommand("mount")
+	cmd.Description = func() string { return "mount a remote folder on the local filesystem" }
+	cmd.Usage = func() string { return "Usage: mount [-flags] <remote_folder> <mountpoint>" }
+	cmd.Action = func(w ...io.Writer) error {
+		return errors.New("mount is only supported on linux")
+	}
+	return cmd
+}
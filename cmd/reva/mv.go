@@ -23,6 +23,7 @@ import (
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/pkg/errors"
 )
 
@@ -30,6 +31,12 @@ func moveCommand() *command {
 	cmd := newCommand("mv")
 	cmd.Description = func() string { return "moves/rename a file/folder" }
 	cmd.Usage = func() string { return "Usage: mv [-flags] <source> <destination>" }
+	dryRunFlag := cmd.Bool("dry-run", false, "only report what would be done, without doing it")
+
+	cmd.ResetFlags = func() {
+		*dryRunFlag = false
+	}
+
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 2 {
 			return errors.New("Invalid arguments: " + cmd.Usage())
@@ -43,6 +50,12 @@ func moveCommand() *command {
 		if err != nil {
 			return err
 		}
+		if *dryRunFlag {
+			ctx = ctxpkg.ContextSetDryRun(ctx)
+			if err := checkDryRunSupport(ctx, client); err != nil {
+				return err
+			}
+		}
 
 		sourceRef := &provider.Reference{Path: src}
 		targetRef := &provider.Reference{Path: dst}
@@ -52,6 +65,10 @@ func moveCommand() *command {
 			return err
 		}
 
+		if *dryRunFlag {
+			printDryRunReport(res.Opaque)
+		}
+
 		if res.Status.Code != rpc.Code_CODE_OK {
 			return formatError(res.Status)
 		}
@@ -0,0 +1,58 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	ocmcachepb "github.com/cs3org/reva/pkg/ocm/cache/proto"
+)
+
+func ocmCachePurgeCommand() *command {
+	cmd := newCommand("ocm-cache-purge")
+	cmd.Description = func() string { return "purge the cache of the resources received through ocm shares" }
+	cmd.Usage = func() string { return "Usage: ocm-cache-purge [-flags]" }
+	domain := cmd.String("domain", "", "only purge the resources of the provider with this domain")
+
+	cmd.ResetFlags = func() {
+		*domain = ""
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		conn, err := getConn()
+		if err != nil {
+			return err
+		}
+		client := ocmcachepb.NewOCMCacheServiceClient(conn)
+
+		res, err := client.PurgeOCMCache(getAuthContext(), &ocmcachepb.PurgeOCMCacheRequest{Domain: *domain})
+		if err != nil {
+			return err
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return formatError(res.Status)
+		}
+
+		fmt.Printf("purged %d cached entries, freed %d bytes\n", res.Entries, res.Bytes)
+		return nil
+	}
+	return cmd
+}
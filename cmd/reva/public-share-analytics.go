@@ -0,0 +1,121 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	"github.com/cs3org/reva/pkg/publicshare/analytics"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/pkg/errors"
+)
+
+func publicShareAnalyticsCommand() *command {
+	cmd := newCommand("public-share-analytics")
+	cmd.Description = func() string { return "show the views and downloads of a public share" }
+	cmd.Usage = func() string { return "Usage: public-share-analytics [-flags] <share_id>" }
+	since := cmd.String("since", "", "start of the period (YYYY-MM-DD)")
+	until := cmd.String("until", "", "end of the period (YYYY-MM-DD)")
+	top := cmd.Int("top", 10, "number of most downloaded files to show")
+
+	cmd.ResetFlags = func() {
+		*since, *until, *top = "", "", 10
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return errors.New("Invalid arguments: " + cmd.Usage())
+		}
+
+		q := &analytics.Query{Top: *top}
+		var err error
+		if *since != "" {
+			if q.Since, err = time.Parse("2006-01-02", *since); err != nil {
+				return err
+			}
+		}
+		if *until != "" {
+			if q.Until, err = time.Parse("2006-01-02", *until); err != nil {
+				return err
+			}
+		}
+		o, err := analytics.AddQueryToOpaque(nil, q)
+		if err != nil {
+			return err
+		}
+
+		ctx := getAuthContext()
+		shareClient, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		shareRes, err := shareClient.GetPublicShare(ctx, &link.GetPublicShareRequest{
+			Opaque: o,
+			Ref: &link.PublicShareReference{
+				Spec: &link.PublicShareReference_Id{
+					Id: &link.PublicShareId{
+						OpaqueId: cmd.Args()[0],
+					},
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		if shareRes.Status.Code != rpc.Code_CODE_OK {
+			return formatError(shareRes.Status)
+		}
+
+		report, ok, err := analytics.ReportFromOpaque(shareRes.Opaque)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("no analytics in the response")
+		}
+
+		fmt.Printf("Period: %s - %s\n", report.Since.Format(time.RFC3339), report.Until.Format(time.RFC3339))
+		fmt.Printf("Views: %d, Downloads: %d, Unique visitors: ~%d\n", report.Views, report.Downloads, report.UniqueVisitors)
+
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"Time", "Views", "Downloads"})
+		for _, b := range report.Timeline {
+			t.AppendRow(table.Row{b.Time.Format(time.RFC3339), b.Views, b.Downloads})
+		}
+		t.Render()
+
+		t = table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"Path", "Downloads"})
+		for _, f := range report.Files {
+			t.AppendRow(table.Row{f.Path, f.Downloads})
+		}
+		t.Render()
+		return nil
+	}
+	return cmd
+}
@@ -24,6 +24,7 @@ import (
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/pkg/errors"
 )
 
@@ -31,6 +32,12 @@ func publicShareRemoveCommand() *command {
 	cmd := newCommand("public-share-remove")
 	cmd.Description = func() string { return "remove a public share" }
 	cmd.Usage = func() string { return "Usage: public-share-remove [-flags] <share_id>" }
+	dryRunFlag := cmd.Bool("dry-run", false, "only report what would be done, without doing it")
+
+	cmd.ResetFlags = func() {
+		*dryRunFlag = false
+	}
+
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
 			return errors.New("Invalid arguments: " + cmd.Usage())
@@ -43,6 +50,12 @@ func publicShareRemoveCommand() *command {
 		if err != nil {
 			return err
 		}
+		if *dryRunFlag {
+			ctx = ctxpkg.ContextSetDryRun(ctx)
+			if err := checkDryRunSupport(ctx, shareClient); err != nil {
+				return err
+			}
+		}
 
 		shareRequest := &link.RemovePublicShareRequest{
 			Ref: &link.PublicShareReference{
@@ -59,6 +72,10 @@ func publicShareRemoveCommand() *command {
 			return err
 		}
 
+		if *dryRunFlag {
+			printDryRunReport(shareRes.Opaque)
+		}
+
 		if shareRes.Status.Code != rpc.Code_CODE_OK {
 			return formatError(shareRes.Status)
 		}
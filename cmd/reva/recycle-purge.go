@@ -23,12 +23,18 @@ import (
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 )
 
 func recyclePurgeCommand() *command {
 	cmd := newCommand("recycle-purge")
 	cmd.Description = func() string { return "purge a recycle bin" }
 	cmd.Usage = func() string { return "Usage: recycle-purge [-flags] " }
+	dryRunFlag := cmd.Bool("dry-run", false, "only report what would be done, without doing it")
+
+	cmd.ResetFlags = func() {
+		*dryRunFlag = false
+	}
 
 	cmd.Action = func(w ...io.Writer) error {
 		client, err := getClient()
@@ -37,6 +43,12 @@ func recyclePurgeCommand() *command {
 		}
 
 		ctx := getAuthContext()
+		if *dryRunFlag {
+			ctx = ctxpkg.ContextSetDryRun(ctx)
+			if err := checkDryRunSupport(ctx, client); err != nil {
+				return err
+			}
+		}
 
 		getHomeRes, err := client.GetHome(ctx, &provider.GetHomeRequest{})
 		if err != nil {
@@ -54,6 +66,10 @@ func recyclePurgeCommand() *command {
 			return err
 		}
 
+		if *dryRunFlag {
+			printDryRunReport(res.Opaque)
+		}
+
 		if res.Status.Code != rpc.Code_CODE_OK {
 			return formatError(res.Status)
 		}
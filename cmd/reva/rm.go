@@ -23,6 +23,7 @@ import (
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	storageproviderv1beta1pb "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/pkg/errors"
 )
 
@@ -30,6 +31,12 @@ func rmCommand() *command {
 	cmd := newCommand("rm")
 	cmd.Description = func() string { return "removes a file or folder" }
 	cmd.Usage = func() string { return "Usage: rm [-flags] <file_name>" }
+	dryRunFlag := cmd.Bool("dry-run", false, "only report what would be done, without doing it")
+
+	cmd.ResetFlags = func() {
+		*dryRunFlag = false
+	}
+
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
 			return errors.New("Invalid arguments: " + cmd.Usage())
@@ -41,6 +48,12 @@ func rmCommand() *command {
 		if err != nil {
 			return err
 		}
+		if *dryRunFlag {
+			ctx = ctxpkg.ContextSetDryRun(ctx)
+			if err := checkDryRunSupport(ctx, client); err != nil {
+				return err
+			}
+		}
 
 		ref := &storageproviderv1beta1pb.Reference{Path: fn}
 		req := &storageproviderv1beta1pb.DeleteRequest{Ref: ref}
@@ -49,6 +62,10 @@ func rmCommand() *command {
 			return err
 		}
 
+		if *dryRunFlag {
+			printDryRunReport(res.Opaque)
+		}
+
 		if res.Status.Code != rpc.Code_CODE_OK {
 			return formatError(res.Status)
 		}
@@ -0,0 +1,125 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"io"
+	"os"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/share/portable"
+)
+
+func shareExportCommand() *command {
+	cmd := newCommand("share-export")
+	cmd.Description = func() string { return "export the shares and public links you manage to a portable JSON document" }
+	cmd.Usage = func() string { return "Usage: share-export [-flags]" }
+	storageID := cmd.String("storage", "", "only export shares of resources in this storage")
+	output := cmd.String("o", "", "the file to write the document to, defaults to stdout")
+	links := cmd.Bool("links", true, "also export public links")
+
+	cmd.ResetFlags = func() {
+		*storageID, *output, *links = "", "", true
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		ctx := getAuthContext()
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		// resolve the path of every shared resource once, to find it again
+		// on import if its id is not mapped
+		paths := map[string]string{}
+		getPath := func(id *provider.ResourceId) (string, error) {
+			key := id.StorageId + ":" + id.OpaqueId
+			if p, ok := paths[key]; ok {
+				return p, nil
+			}
+			res, err := client.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{ResourceId: id}})
+			if err != nil {
+				return "", err
+			}
+			if res.Status.Code != rpc.Code_CODE_OK {
+				return "", formatError(res.Status)
+			}
+			paths[key] = res.Info.Path
+			return res.Info.Path, nil
+		}
+
+		doc := &portable.Document{Shares: []*portable.Share{}, PublicLinks: []*portable.PublicLink{}}
+
+		sharesRes, err := client.ListShares(ctx, &collaboration.ListSharesRequest{})
+		if err != nil {
+			return err
+		}
+		if sharesRes.Status.Code != rpc.Code_CODE_OK {
+			return formatError(sharesRes.Status)
+		}
+		for _, s := range sharesRes.Shares {
+			if *storageID != "" && s.ResourceId.GetStorageId() != *storageID {
+				continue
+			}
+			p, err := getPath(s.ResourceId)
+			if err != nil {
+				return err
+			}
+			doc.Shares = append(doc.Shares, portable.FromShare(s, p))
+		}
+
+		if *links {
+			linksRes, err := client.ListPublicShares(ctx, &link.ListPublicSharesRequest{})
+			if err != nil {
+				return err
+			}
+			if linksRes.Status.Code != rpc.Code_CODE_OK {
+				return formatError(linksRes.Status)
+			}
+			for _, l := range linksRes.Share {
+				if *storageID != "" && l.ResourceId.GetStorageId() != *storageID {
+					continue
+				}
+				p, err := getPath(l.ResourceId)
+				if err != nil {
+					return err
+				}
+				doc.PublicLinks = append(doc.PublicLinks, portable.FromPublicShare(l, p))
+			}
+		}
+
+		out := io.Writer(os.Stdout)
+		if len(w) > 0 {
+			out = w[0]
+		}
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		return doc.Encode(out)
+	}
+	return cmd
+}
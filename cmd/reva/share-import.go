@@ -0,0 +1,151 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/share/portable"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/pkg/errors"
+)
+
+func shareImportCommand() *command {
+	cmd := newCommand("share-import")
+	cmd.Description = func() string { return "re-create the shares and public links of a document written by share-export" }
+	cmd.Usage = func() string { return "Usage: share-import [-flags] <file>" }
+	mapping := cmd.String("mapping", "", "JSON file mapping the users, groups, resources and paths of the source deployment")
+	password := cmd.String("link-password", "", "the password of re-created password protected public links, which are skipped if empty")
+	dryRun := cmd.Bool("dry-run", false, "only check that all resources can be found")
+
+	cmd.ResetFlags = func() {
+		*mapping, *password, *dryRun = "", "", false
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return errors.New("Invalid arguments: " + cmd.Usage())
+		}
+
+		f, err := os.Open(cmd.Args()[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		doc, err := portable.Decode(f)
+		if err != nil {
+			return err
+		}
+		m, err := portable.ReadMapping(*mapping)
+		if err != nil {
+			return err
+		}
+
+		ctx := getAuthContext()
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		stat := func(r portable.Resource) (*provider.ResourceInfo, error) {
+			ref, err := m.Reference(r)
+			if err != nil {
+				return nil, err
+			}
+			res, err := client.Stat(ctx, &provider.StatRequest{Ref: ref})
+			if err != nil {
+				return nil, err
+			}
+			if res.Status.Code != rpc.Code_CODE_OK {
+				return nil, formatError(res.Status)
+			}
+			return res.Info, nil
+		}
+
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"Type", "Path", "Grantee", "Result"})
+		var failed int
+		report := func(typ, path, grantee string, err error) {
+			result := "ok"
+			if err != nil {
+				failed++
+				result = err.Error()
+			}
+			t.AppendRow(table.Row{typ, path, grantee, result})
+		}
+
+		for _, s := range doc.Shares {
+			grantee := s.Grantee.Idp + "/" + s.Grantee.OpaqueID
+			info, err := stat(s.Resource)
+			if err != nil {
+				report(s.GranteeType, s.Resource.Path, grantee, err)
+				continue
+			}
+			grant, err := m.Grant(s)
+			if err != nil || *dryRun {
+				report(s.GranteeType, info.Path, grantee, err)
+				continue
+			}
+			res, err := client.CreateShare(ctx, &collaboration.CreateShareRequest{ResourceInfo: info, Grant: grant})
+			if err == nil && res.Status.Code != rpc.Code_CODE_OK {
+				err = formatError(res.Status)
+			}
+			report(s.GranteeType, info.Path, grantee, err)
+		}
+
+		for _, l := range doc.PublicLinks {
+			if l.PasswordProtected && *password == "" {
+				report("link", l.Resource.Path, l.Token, errors.New("skipped password protected link, use -link-password"))
+				continue
+			}
+			info, err := stat(l.Resource)
+			if err != nil || *dryRun {
+				report("link", l.Resource.Path, l.Token, err)
+				continue
+			}
+			res, err := client.CreatePublicShare(ctx, &link.CreatePublicShareRequest{
+				ResourceInfo: info,
+				Grant:        portable.PublicGrant(l, *password),
+			})
+			if err == nil && res.Status.Code != rpc.Code_CODE_OK {
+				err = formatError(res.Status)
+			}
+			if err != nil {
+				report("link", info.Path, l.Token, err)
+				continue
+			}
+			// public link tokens cannot be chosen, report the new one
+			report("link", info.Path, l.Token+" -> "+res.Share.Token, nil)
+		}
+
+		t.Render()
+		if failed > 0 {
+			return fmt.Errorf("%d of %d shares and links could not be imported", failed, len(doc.Shares)+len(doc.PublicLinks))
+		}
+		return nil
+	}
+	return cmd
+}
@@ -24,6 +24,7 @@ import (
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/pkg/errors"
 )
 
@@ -31,6 +32,12 @@ func shareRemoveCommand() *command {
 	cmd := newCommand("share-remove")
 	cmd.Description = func() string { return "remove a share" }
 	cmd.Usage = func() string { return "Usage: share-remove [-flags] <share_id>" }
+	dryRunFlag := cmd.Bool("dry-run", false, "only report what would be done, without doing it")
+
+	cmd.ResetFlags = func() {
+		*dryRunFlag = false
+	}
+
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
 			return errors.New("Invalid arguments: " + cmd.Usage())
@@ -43,6 +50,12 @@ func shareRemoveCommand() *command {
 		if err != nil {
 			return err
 		}
+		if *dryRunFlag {
+			ctx = ctxpkg.ContextSetDryRun(ctx)
+			if err := checkDryRunSupport(ctx, shareClient); err != nil {
+				return err
+			}
+		}
 
 		shareRequest := &collaboration.RemoveShareRequest{
 			Ref: &collaboration.ShareReference{
@@ -59,6 +72,10 @@ func shareRemoveCommand() *command {
 			return err
 		}
 
+		if *dryRunFlag {
+			printDryRunReport(shareRes.Opaque)
+		}
+
 		if shareRes.Status.Code != rpc.Code_CODE_OK {
 			return formatError(shareRes.Status)
 		}
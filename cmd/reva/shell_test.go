@@ -0,0 +1,86 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestApplyAliases(t *testing.T) {
+	aliases := map[string]string{
+		"ll": "ls -l",
+		"ls": "stat",
+	}
+
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{"ll", "ls -l"},
+		{"ll /home/docs", "ls -l /home/docs"},
+		{"llx /home", "llx /home"},
+		{"whoami", "whoami"},
+		// Aliases never shadow commands
+		{"ls /home", "ls /home"},
+	}
+
+	for _, tt := range tests {
+		if got := applyAliases(tt.line, aliases); got != tt.expected {
+			t.Errorf("applyAliases(%q): expected %q, got %q", tt.line, tt.expected, got)
+		}
+	}
+}
+
+func TestParseHistory(t *testing.T) {
+	history := parseHistory(strings.NewReader("ls /home\n\n  whoami  \nstat /home\n"))
+	expected := []string{"ls /home", "whoami", "stat /home"}
+	if fmt.Sprint(history) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, history)
+	}
+
+	var b strings.Builder
+	for i := 0; i < maxHistoryEntries+5; i++ {
+		fmt.Fprintf(&b, "cmd %d\n", i)
+	}
+	history = parseHistory(strings.NewReader(b.String()))
+	if len(history) != maxHistoryEntries {
+		t.Fatalf("expected %d entries, got %d", maxHistoryEntries, len(history))
+	}
+	if history[0] != "cmd 5" || history[len(history)-1] != fmt.Sprintf("cmd %d", maxHistoryEntries+4) {
+		t.Errorf("expected the most recent entries to be kept, got %q ... %q", history[0], history[len(history)-1])
+	}
+}
+
+func TestGetArgumentCompleter(t *testing.T) {
+	c := &Completer{}
+	c.init()
+
+	home := c.getArgumentCompleter(c.pathArguments, "false:/home")
+	if c.getArgumentCompleter(c.pathArguments, "false:/home") != home {
+		t.Error("expected the completer of a path to be reused")
+	}
+	if c.getArgumentCompleter(c.pathArguments, "false:/home/docs") == home {
+		t.Error("expected every path to have its own completer")
+	}
+	if c.getArgumentCompleter(c.userArguments, "false:/home") == home {
+		t.Error("expected paths and users to be cached separately")
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/storage/utils/mdbackup"
+	"github.com/pkg/errors"
+)
+
+func storageBackupMetadataCommand() *command {
+	cmd := newCommand("storage-backup-metadata")
+	cmd.Description = func() string {
+		return "export the grants and arbitrary metadata of a tree, and optionally the storage spaces, to an archive"
+	}
+	cmd.Usage = func() string { return "Usage: storage-backup-metadata [-flags] <path>" }
+	output := cmd.String("o", "", "the file to write the archive to, defaults to stdout")
+	spaces := cmd.Bool("spaces", false, "also export the definitions of the storage spaces")
+
+	cmd.ResetFlags = func() {
+		*output, *spaces = "", false
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return errors.New("Invalid arguments: " + cmd.Usage())
+		}
+		root := cmd.Args()[0]
+
+		ctx := getAuthContext()
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		out := io.Writer(os.Stdout)
+		if len(w) > 0 {
+			out = w[0]
+		}
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+
+		aw, err := mdbackup.NewWriter(out, root)
+		if err != nil {
+			return err
+		}
+
+		// spaces come first so that a restore re-creates them before
+		// touching the resources they contain
+		if *spaces {
+			res, err := client.ListStorageSpaces(ctx, &provider.ListStorageSpacesRequest{})
+			if err != nil {
+				return err
+			}
+			if res.Status.Code != rpc.Code_CODE_OK {
+				return formatError(res.Status)
+			}
+			for _, s := range res.StorageSpaces {
+				if err := aw.WriteSpace(s); err != nil {
+					return err
+				}
+			}
+		}
+
+		backup := func(info *provider.ResourceInfo) error {
+			ref := &provider.Reference{Path: info.Path}
+			res, err := client.ListGrants(ctx, &provider.ListGrantsRequest{Ref: ref})
+			if err != nil {
+				return err
+			}
+			if res.Status.Code != rpc.Code_CODE_OK {
+				return errors.Wrap(formatError(res.Status), info.Path)
+			}
+			return aw.WriteResource(&mdbackup.Resource{
+				Path:     info.Path,
+				Metadata: info.GetArbitraryMetadata().GetMetadata(),
+				Grants:   res.Grants,
+			})
+		}
+
+		mdKeys := []string{"*"}
+		statRes, err := client.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{Path: root}, ArbitraryMetadataKeys: mdKeys})
+		if err != nil {
+			return err
+		}
+		if statRes.Status.Code != rpc.Code_CODE_OK {
+			return formatError(statRes.Status)
+		}
+
+		var count int
+		var walk func(info *provider.ResourceInfo) error
+		walk = func(info *provider.ResourceInfo) error {
+			if err := backup(info); err != nil {
+				return err
+			}
+			count++
+			if info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+				return nil
+			}
+			res, err := client.ListContainer(ctx, &provider.ListContainerRequest{Ref: &provider.Reference{Path: info.Path}, ArbitraryMetadataKeys: mdKeys})
+			if err != nil {
+				return err
+			}
+			if res.Status.Code != rpc.Code_CODE_OK {
+				return errors.Wrap(formatError(res.Status), info.Path)
+			}
+			for _, child := range res.Infos {
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if err := walk(statRes.Info); err != nil {
+			return err
+		}
+
+		if err := aw.Close(); err != nil {
+			return err
+		}
+		if *output != "" {
+			fmt.Printf("exported the metadata of %d resources to %s\n", count, *output)
+		}
+		return nil
+	}
+	return cmd
+}
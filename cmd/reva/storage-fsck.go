@@ -0,0 +1,134 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	fsckpb "github.com/cs3org/reva/pkg/storage/utils/fsck/proto"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/pkg/errors"
+)
+
+func storageFsckCommand() *command {
+	cmd := newCommand("storage-fsck")
+	cmd.Description = func() string {
+		return "check the consistency of the metadata of the storage holding a path with its content"
+	}
+	cmd.Usage = func() string { return "Usage: storage-fsck [-flags] <path>" }
+	start := cmd.Bool("start", false, "start a check of the path instead of printing the report of the last check")
+	checks := cmd.String("checks", "", "comma separated kinds of inconsistencies to look for: size, checksum, grant, artifact")
+	repair := cmd.String("repair", "", "comma separated kinds of inconsistencies to repair")
+	cancel := cmd.Bool("cancel", false, "cancel the running check")
+
+	cmd.ResetFlags = func() {
+		*start, *checks, *repair, *cancel = false, "", "", false
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return errors.New("Invalid arguments: " + cmd.Usage())
+		}
+		ref := &provider.Reference{Path: cmd.Args()[0]}
+
+		conn, err := getConn()
+		if err != nil {
+			return err
+		}
+		client := fsckpb.NewFsckServiceClient(conn)
+		ctx := getAuthContext()
+
+		switch {
+		case *cancel:
+			res, err := client.CancelCheck(ctx, &fsckpb.CancelCheckRequest{Ref: ref})
+			if err != nil {
+				return err
+			}
+			if res.Status.Code != rpc.Code_CODE_OK {
+				return formatError(res.Status)
+			}
+			fmt.Println("check cancelled")
+			return nil
+
+		case *start:
+			res, err := client.StartCheck(ctx, &fsckpb.StartCheckRequest{Ref: ref, Checks: splitKinds(*checks), Repair: splitKinds(*repair)})
+			if err != nil {
+				return err
+			}
+			if res.Status.Code != rpc.Code_CODE_OK {
+				return formatError(res.Status)
+			}
+			fmt.Printf("check %s of %s started\n", res.Report.Id, res.Report.Root)
+			return nil
+		}
+
+		res, err := client.GetCheckReport(ctx, &fsckpb.GetCheckReportRequest{Ref: ref})
+		if err != nil {
+			return err
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return formatError(res.Status)
+		}
+		printCheckReport(res.Report)
+		return nil
+	}
+	return cmd
+}
+
+func printCheckReport(r *fsckpb.CheckReport) {
+	fmt.Printf("check %s of %s: %s\n", r.Id, r.Root, r.State)
+	fmt.Printf("started: %s\n", time.Unix(int64(r.Started), 0).Format(time.RFC3339))
+	if r.Finished != 0 {
+		fmt.Printf("finished: %s\n", time.Unix(int64(r.Finished), 0).Format(time.RFC3339))
+	}
+	fmt.Printf("checked: %d resources, %d bytes, %d failures\n", r.Checked, r.Bytes, r.Failed)
+	if r.Error != "" {
+		fmt.Printf("error: %s\n", r.Error)
+	}
+	if len(r.Issues) == 0 {
+		return
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Kind", "Path", "Detail", "Repairable", "Repaired", "Error"})
+	for _, i := range r.Issues {
+		t.AppendRow(table.Row{i.Kind, i.Path, i.Detail, i.Repairable, i.Repaired, i.Error})
+	}
+	t.Render()
+	if r.Truncated {
+		fmt.Println("the report is truncated, more inconsistencies were found")
+	}
+}
+
+func splitKinds(s string) []string {
+	var kinds []string
+	for _, k := range strings.Split(s, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			kinds = append(kinds, k)
+		}
+	}
+	return kinds
+}
@@ -0,0 +1,107 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	movejournalpb "github.com/cs3org/reva/pkg/storage/utils/movejournal/proto"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/pkg/errors"
+)
+
+func storageRepairMovesCommand() *command {
+	cmd := newCommand("storage-repair-moves")
+	cmd.Description = func() string {
+		return "list the interrupted moves of the storage holding a path, and resume or roll them back"
+	}
+	cmd.Usage = func() string { return "Usage: storage-repair-moves [-flags] <path>" }
+	id := cmd.String("id", "", "the move to repair")
+	all := cmd.Bool("all", false, "repair all the interrupted moves")
+	rollback := cmd.Bool("rollback", false, "move the objects back to the source instead of completing the moves")
+
+	cmd.ResetFlags = func() {
+		*id, *all, *rollback = "", false, false
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return errors.New("Invalid arguments: " + cmd.Usage())
+		}
+		ref := &provider.Reference{Path: cmd.Args()[0]}
+
+		conn, err := getConn()
+		if err != nil {
+			return err
+		}
+		client := movejournalpb.NewMoveJournalServiceClient(conn)
+		ctx := getAuthContext()
+
+		res, err := client.ListInterruptedMoves(ctx, &movejournalpb.ListInterruptedMovesRequest{Ref: ref})
+		if err != nil {
+			return err
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return formatError(res.Status)
+		}
+
+		if *id == "" && !*all {
+			t := table.NewWriter()
+			t.SetOutputMirror(os.Stdout)
+			t.AppendHeader(table.Row{"ID", "Source", "Target", "Started", "Last update", "Objects moved"})
+			for _, m := range res.Moves {
+				t.AppendRow(table.Row{m.Id, m.Source, m.Target,
+					time.Unix(int64(m.Started), 0).Format(time.RFC3339), time.Unix(int64(m.Updated), 0).Format(time.RFC3339), m.Moved})
+			}
+			t.Render()
+			return nil
+		}
+
+		action := "resumed"
+		if *rollback {
+			action = "rolled back"
+		}
+		for _, m := range res.Moves {
+			if !*all && m.Id != *id {
+				continue
+			}
+			repairRes, err := client.RepairMove(ctx, &movejournalpb.RepairMoveRequest{Ref: ref, Id: m.Id, Rollback: *rollback})
+			if err != nil {
+				return err
+			}
+			if repairRes.Status.Code != rpc.Code_CODE_OK {
+				return errors.Wrap(formatError(repairRes.Status), m.Id)
+			}
+			fmt.Printf("%s the move of %s to %s\n", action, m.Source, m.Target)
+			if !*all {
+				return nil
+			}
+		}
+		if !*all {
+			return errors.New("no interrupted move with id " + *id)
+		}
+		return nil
+	}
+	return cmd
+}
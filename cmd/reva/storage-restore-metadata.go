@@ -0,0 +1,189 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/storage/utils/mdbackup"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/pkg/errors"
+)
+
+func storageRestoreMetadataCommand() *command {
+	cmd := newCommand("storage-restore-metadata")
+	cmd.Description = func() string {
+		return "restore the grants, arbitrary metadata and storage spaces of an archive written by storage-backup-metadata"
+	}
+	cmd.Usage = func() string { return "Usage: storage-restore-metadata [-flags] <file>" }
+	target := cmd.String("target", "", "the path to restore the tree to, defaults to the path it was exported from")
+	dryRun := cmd.Bool("dry-run", false, "only check that all resources can be found")
+
+	cmd.ResetFlags = func() {
+		*target, *dryRun = "", false
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return errors.New("Invalid arguments: " + cmd.Usage())
+		}
+
+		f, err := os.Open(cmd.Args()[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		ar, err := mdbackup.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer ar.Close()
+
+		ctx := getAuthContext()
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"Type", "Path", "Result"})
+		var total, failed int
+		report := func(typ, p string, err error) {
+			total++
+			result := "ok"
+			if err != nil {
+				failed++
+				result = err.Error()
+			}
+			t.AppendRow(table.Row{typ, p, result})
+		}
+		check := func(s *rpc.Status, err error) error {
+			if err != nil {
+				return err
+			}
+			if s.Code != rpc.Code_CODE_OK {
+				return formatError(s)
+			}
+			return nil
+		}
+
+		restoreSpace := func(s *provider.StorageSpace) (string, error) {
+			listRes, err := client.ListStorageSpaces(ctx, &provider.ListStorageSpacesRequest{
+				Filters: []*provider.ListStorageSpacesRequest_Filter{
+					{
+						Type: provider.ListStorageSpacesRequest_Filter_TYPE_ID,
+						Term: &provider.ListStorageSpacesRequest_Filter_Id{Id: s.Id},
+					},
+				},
+			})
+			if err := check(listRes.GetStatus(), err); err != nil {
+				return "", err
+			}
+			if *dryRun {
+				return "", nil
+			}
+			if len(listRes.StorageSpaces) > 0 {
+				res, err := client.UpdateStorageSpace(ctx, &provider.UpdateStorageSpaceRequest{StorageSpace: s})
+				return "", check(res.GetStatus(), err)
+			}
+			// space ids cannot be chosen, report the new one
+			res, err := client.CreateStorageSpace(ctx, &provider.CreateStorageSpaceRequest{
+				Owner: s.Owner,
+				Type:  s.SpaceType,
+				Name:  s.Name,
+				Quota: s.Quota,
+			})
+			if err := check(res.GetStatus(), err); err != nil {
+				return "", err
+			}
+			return res.StorageSpace.GetId().GetOpaqueId(), nil
+		}
+
+		restoreResource := func(r *mdbackup.Resource, p string) error {
+			ref := &provider.Reference{Path: p}
+			statRes, err := client.Stat(ctx, &provider.StatRequest{Ref: ref})
+			if err := check(statRes.GetStatus(), err); err != nil || *dryRun {
+				return err
+			}
+			if len(r.Metadata) > 0 {
+				res, err := client.SetArbitraryMetadata(ctx, &provider.SetArbitraryMetadataRequest{
+					Ref:               ref,
+					ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: r.Metadata},
+				})
+				if err := check(res.GetStatus(), err); err != nil {
+					return errors.Wrap(err, "error setting metadata")
+				}
+			}
+			for _, g := range r.Grants {
+				res, err := client.AddGrant(ctx, &provider.AddGrantRequest{Ref: ref, Grant: g})
+				if err == nil && res.Status.Code == rpc.Code_CODE_ALREADY_EXISTS {
+					updateRes, err := client.UpdateGrant(ctx, &provider.UpdateGrantRequest{Ref: ref, Grant: g})
+					if err := check(updateRes.GetStatus(), err); err != nil {
+						return errors.Wrap(err, "error updating grant")
+					}
+					continue
+				}
+				if err := check(res.GetStatus(), err); err != nil {
+					return errors.Wrap(err, "error adding grant")
+				}
+			}
+			return nil
+		}
+
+		for {
+			e, err := ar.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case e.Space != nil:
+				id, err := restoreSpace(e.Space)
+				name := e.Space.Name
+				if id != "" {
+					name += " -> " + id
+				}
+				report("space", name, err)
+			case e.Resource != nil:
+				p := e.Resource.Path
+				if *target != "" {
+					p = path.Join(*target, strings.TrimPrefix(p, ar.Root()))
+				}
+				report("resource", p, restoreResource(e.Resource, p))
+			}
+		}
+
+		t.Render()
+		if failed > 0 {
+			return fmt.Errorf("%d of %d entries could not be restored", failed, total)
+		}
+		return nil
+	}
+	return cmd
+}
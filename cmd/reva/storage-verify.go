@@ -0,0 +1,105 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/storage/utils/verify"
+	verifypb "github.com/cs3org/reva/pkg/storage/utils/verify/proto"
+	"github.com/pkg/errors"
+)
+
+func storageVerifyCommand() *command {
+	cmd := newCommand("storage-verify")
+	cmd.Description = func() string {
+		return "recompute the checksum of a file and compare it to the stored one"
+	}
+	cmd.Usage = func() string { return "Usage: storage-verify [-flags] <path>" }
+	algorithm := cmd.String("algorithm", "", "algorithm of the recomputed checksum: md5, sha1 or adler32, the one of the stored checksum if empty")
+	expected := cmd.String("expected", "", "checksum the content is compared to as well, optionally prefixed with its algorithm as in sha1:<checksum>")
+	quiet := cmd.Bool("quiet", false, "don't print the progress of the verification")
+
+	cmd.ResetFlags = func() {
+		*algorithm, *expected, *quiet = "", "", false
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return errors.New("Invalid arguments: " + cmd.Usage())
+		}
+		ref := &provider.Reference{Path: cmd.Args()[0]}
+
+		conn, err := getConn()
+		if err != nil {
+			return err
+		}
+		client := verifypb.NewVerifyServiceClient(conn)
+		ctx := getAuthContext()
+
+		stream, err := client.VerifyChecksum(ctx, &verifypb.VerifyChecksumRequest{Ref: ref, Algorithm: *algorithm, Expected: *expected})
+		if err != nil {
+			return err
+		}
+		for {
+			res, err := stream.Recv()
+			if err == io.EOF {
+				return errors.New("error: the verification ended without a result")
+			}
+			if err != nil {
+				return err
+			}
+			if res.Status.Code != rpc.Code_CODE_OK {
+				return formatError(res.Status)
+			}
+			if p := res.Progress; p != nil {
+				if !*quiet {
+					fmt.Fprintf(os.Stderr, "read %d of %d bytes\n", p.Read, p.Size)
+				}
+				continue
+			}
+			if res.Result != nil {
+				return printVerifyResult(res.Result)
+			}
+		}
+	}
+	return cmd
+}
+
+func printVerifyResult(r *verifypb.VerifyResult) error {
+	fmt.Printf("%s: %s\n", r.Path, r.Verdict)
+	fmt.Printf("read: %d bytes\n", r.Bytes)
+	fmt.Printf("computed %s checksum: %s\n", r.Algorithm, r.Computed)
+	if r.Stored != "" {
+		fmt.Printf("stored checksum: %s (match: %t)\n", r.Stored, r.StoredMatch)
+	} else {
+		fmt.Printf("no %s checksum is stored\n", r.Algorithm)
+	}
+	if r.Expected != "" {
+		fmt.Printf("expected checksum: %s (match: %t)\n", r.Expected, r.ExpectedMatch)
+	}
+	if r.Verdict == verify.VerdictMismatch {
+		return errors.New("error: the content doesn't match its checksum")
+	}
+	return nil
+}
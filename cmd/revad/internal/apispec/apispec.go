@@ -0,0 +1,91 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package apispec implements the revad apispec mode, which writes the
+// protobuf descriptors of the gRPC services and the OpenAPI document of the
+// HTTP services compiled into the binary, to generate clients matching it.
+package apispec
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/apispec"
+)
+
+// Main runs the apispec mode with the given command line arguments and the
+// version of the binary.
+func Main(args []string, version string) {
+	fs := flag.NewFlagSet("apispec", flag.ExitOnError)
+	outFlag := fs.String("o", ".", "the directory to write the descriptors.pb and openapi.json files to")
+	grpcFlag := fs.String("grpc", "", "comma separated full names of the gRPC services to include, e.g. cs3.gateway.v1beta1.GatewayAPI; all of them if empty")
+	httpFlag := fs.String("http", "", "comma separated names of the HTTP services to include, e.g. archiver,siteacc; all of them if empty")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: revad apispec [-flags]\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	services := apispec.GRPCServices()
+	if *grpcFlag != "" {
+		services = strings.Split(*grpcFlag, ",")
+	}
+	descriptors, missing, err := apispec.Descriptors(services)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "unknown gRPC services: %s\n", strings.Join(missing, ", "))
+		os.Exit(1)
+	}
+
+	// the HTTP services are described at their default prefixes
+	prefixes := apispec.Registered()
+	if *httpFlag != "" {
+		selected := map[string]string{}
+		for _, name := range strings.Split(*httpFlag, ",") {
+			prefix, ok := prefixes[name]
+			if !ok {
+				fmt.Fprintf(os.Stderr, "unknown or undescribed HTTP service: %s\n", name)
+				os.Exit(1)
+			}
+			selected[name] = prefix
+		}
+		prefixes = selected
+	}
+	document, err := apispec.OpenAPI(version, prefixes).JSON()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	for name, data := range map[string][]byte{"descriptors.pb": descriptors, "openapi.json": document} {
+		file := filepath.Join(*outFlag, name)
+		if err := ioutil.WriteFile(file, data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stdout, "%s written\n", file)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,218 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package e2e implements the revad e2e mode, which runs scripted end-to-end
+// scenarios against freshly started revad instances, once per driver of a
+// matrix, and reports the results in the JUnit format.
+//
+// The instances are started by executing the revad binary itself, one
+// process per instance, as the shared configuration of revad is global to
+// the process.
+package e2e
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/pkg/errors"
+)
+
+// result is the outcome of a scenario run against a driver.
+type result struct {
+	driver   string
+	scenario string
+	duration time.Duration
+	skipped  bool
+	err      error
+	output   []string
+}
+
+type runner struct {
+	matrix *Matrix
+	revad  string
+	keep   bool
+	filter *regexp.Regexp
+}
+
+// Main runs the e2e mode with the given command line arguments.
+func Main(args []string) {
+	fs := flag.NewFlagSet("e2e", flag.ExitOnError)
+	matrixFlag := fs.String("m", "matrix.yaml", "the YAML file describing the instances, drivers and scenarios")
+	junitFlag := fs.String("junit", "", "write a JUnit report to the given file")
+	runFlag := fs.String("run", "", "only run the scenarios whose driver/scenario name matches the regular expression")
+	revadFlag := fs.String("revad", "", "the revad binary running the instances. Defaults to the running binary")
+	keepFlag := fs.Bool("keep", false, "keep the roots and logs of the instances of failed scenarios")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: revad e2e [-flags]\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	m, err := LoadMatrix(*matrixFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	r := &runner{matrix: m, revad: *revadFlag, keep: *keepFlag}
+	if r.revad == "" {
+		if r.revad, err = os.Executable(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+	if *runFlag != "" {
+		if r.filter, err = regexp.Compile(*runFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -run expression: %s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	results := r.run()
+	if *junitFlag != "" {
+		if err := writeJUnit(*junitFlag, results); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	failed := 0
+	for _, res := range results {
+		if res.err != nil {
+			failed++
+		}
+	}
+	fmt.Fprintf(os.Stdout, "%d scenarios run, %d failed\n", len(results), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func (r *runner) run() []*result {
+	var results []*result
+	for _, d := range r.matrix.Drivers {
+		for _, s := range r.matrix.scenarios {
+			name := d.Name + "/" + s.Name
+			if r.filter != nil && !r.filter.MatchString(name) {
+				continue
+			}
+			if contains(d.Skip, s.Name) {
+				results = append(results, &result{driver: d.Name, scenario: s.Name, skipped: true})
+				fmt.Fprintf(os.Stdout, "SKIP %s\n", name)
+				continue
+			}
+
+			res := r.runScenario(d, s)
+			results = append(results, res)
+			if res.err != nil {
+				fmt.Fprintf(os.Stdout, "FAIL %s (%s): %s\n", name, res.duration.Round(time.Millisecond), res.err.Error())
+			} else {
+				fmt.Fprintf(os.Stdout, "PASS %s (%s)\n", name, res.duration.Round(time.Millisecond))
+			}
+		}
+	}
+	return results
+}
+
+func (r *runner) runScenario(d *Driver, s *Scenario) *result {
+	res := &result{driver: d.Name, scenario: s.Name}
+	start := time.Now()
+	defer func() { res.duration = time.Since(start) }()
+
+	c, err := r.startCluster(d, s)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	defer func() {
+		keep := r.keep && res.err != nil
+		if keep {
+			res.output = append(res.output, "kept the roots "+strings.Join(c.roots(), ", "))
+		}
+		c.stop(keep)
+	}()
+
+	ctx := context.Background()
+	vars := map[string]string{}
+	for k, v := range c.vars {
+		vars[k] = v
+	}
+	sessions := map[string]*session{}
+	for n, st := range s.Steps {
+		st = st.expand(vars)
+		res.output = append(res.output, fmt.Sprintf("%d: %s %s as %s on %s", n+1, st.Action, st.Path, st.User, st.Instance))
+
+		out, err := r.runStep(ctx, c, sessions, st)
+		if err != nil {
+			res.err = errors.Wrapf(err, "step %d (%s)", n+1, st.Action)
+			return res
+		}
+		if st.Save != "" {
+			vars[st.Save] = out
+		}
+	}
+	return res
+}
+
+func (r *runner) runStep(ctx context.Context, c *cluster, sessions map[string]*session, st *Step) (string, error) {
+	want, err := expectedCode(st.Expect)
+	if err != nil {
+		return "", err
+	}
+
+	key := st.Instance + "/" + st.User
+	s, ok := sessions[key]
+	if !ok {
+		client, err := pool.GetGatewayServiceClient(pool.Endpoint(c.processes[st.Instance].grpcAddress))
+		if err != nil {
+			return "", errors.Wrap(err, "error getting gateway client")
+		}
+		if s, err = authenticate(ctx, client, st.User, r.matrix.Users[st.User]); err != nil {
+			return "", err
+		}
+		sessions[key] = s
+	}
+
+	out, err := actions[st.Action](s, st)
+	if want == rpc.Code_CODE_OK {
+		return out, err
+	}
+	if err == nil {
+		return "", errors.Errorf("expected %s, got ok", st.Expect)
+	}
+	if se, ok := err.(*statusError); !ok || se.status.Code != want {
+		return "", errors.Wrapf(err, "expected %s", st.Expect)
+	}
+	return "", nil
+}
+
+// expand returns a copy of the step with the variables substituted.
+func (st *Step) expand(vars map[string]string) *Step {
+	e := *st
+	for _, f := range []*string{&e.Path, &e.Target, &e.Content, &e.Grantee, &e.Role, &e.Domain, &e.Idp, &e.Token} {
+		*f = expand(*f, vars)
+	}
+	return &e
+}
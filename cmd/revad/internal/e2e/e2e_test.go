@@ -0,0 +1,169 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package e2e
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/pkg/errors"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadRepoMatrix(t *testing.T) {
+	m, err := LoadMatrix("../../../../tests/e2e/matrix.yaml")
+	if err != nil {
+		t.Fatalf("the shipped matrix is invalid: %v", err)
+	}
+	if len(m.scenarios) != len(m.Scenarios) {
+		t.Errorf("expected %d scenarios, got %d", len(m.Scenarios), len(m.scenarios))
+	}
+}
+
+const testMatrix = `
+users:
+  einstein: relativity
+instances:
+  local:
+    config: revad.toml
+    files: [users.json]
+drivers:
+  - name: localhome
+scenarios:
+  - scenario.yaml
+`
+
+func TestLoadMatrix(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"matrix.yaml": testMatrix,
+		"scenario.yaml": `
+name: upload
+steps:
+  - {action: upload, instance: local, user: einstein, path: /home/file.txt, content: hello}
+`,
+	})
+
+	m, err := LoadMatrix(filepath.Join(dir, "matrix.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if i := m.Instances["local"]; i.Config != filepath.Join(dir, "revad.toml") || i.Files[0] != filepath.Join(dir, "users.json") {
+		t.Errorf("expected the templates to be resolved relative to the matrix, got %+v", i)
+	}
+	if s := m.scenarios[0]; len(s.Instances) != 1 || s.Instances[0] != "local" {
+		t.Errorf("expected the scenario to default to all instances, got %v", s.Instances)
+	}
+}
+
+func TestLoadInvalidMatrix(t *testing.T) {
+	tests := map[string]string{
+		"unknown action":   `{action: fly, instance: local, user: einstein}`,
+		"unknown instance": `{action: mkdir, instance: mesh, user: einstein}`,
+		"unknown user":     `{action: mkdir, instance: local, user: marie}`,
+		"invalid variable": `{action: mkdir, instance: local, user: einstein, save: "a-b"}`,
+		"unknown field":    `{action: mkdir, instance: local, user: einstein, colour: red}`,
+	}
+
+	for name, step := range tests {
+		dir := writeFiles(t, map[string]string{
+			"matrix.yaml":   testMatrix,
+			"scenario.yaml": "name: broken\nsteps:\n  - " + step + "\n",
+		})
+		if _, err := LoadMatrix(filepath.Join(dir, "matrix.yaml")); err == nil {
+			t.Errorf("%s: expected the matrix to be rejected", name)
+		}
+	}
+}
+
+func TestStepExpand(t *testing.T) {
+	st := &Step{Action: "ocm-accept-invite", Path: "/home/{{dir}}", Token: "{{token}}", Content: "{{.Username}}"}
+	e := st.expand(map[string]string{"dir": "docs", "token": "abc"})
+
+	if e.Path != "/home/docs" || e.Token != "abc" {
+		t.Errorf("expected the variables to be substituted, got %+v", e)
+	}
+	if e.Content != "{{.Username}}" {
+		t.Errorf("expected unknown placeholders to be kept, got %q", e.Content)
+	}
+	if st.Path != "/home/{{dir}}" {
+		t.Error("expected the original step to be unchanged")
+	}
+}
+
+func TestExpectedCode(t *testing.T) {
+	if c, err := expectedCode(""); err != nil || c != rpc.Code_CODE_OK {
+		t.Errorf("expected ok by default, got %v (%v)", c, err)
+	}
+	if c, err := expectedCode("not_found"); err != nil || c != rpc.Code_CODE_NOT_FOUND {
+		t.Errorf("expected not_found, got %v (%v)", c, err)
+	}
+	if _, err := expectedCode("nope"); err == nil {
+		t.Error("expected an unknown code to be rejected")
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "e2e.xml")
+	results := []*result{
+		{driver: "localhome", scenario: "upload", duration: time.Second},
+		{driver: "localhome", scenario: "share", duration: time.Second, err: errors.New("boom")},
+		{driver: "ocis", scenario: "upload", skipped: true},
+	}
+	if err := writeJUnit(file, results); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := junitSuites{}
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Fatalf("invalid report: %v", err)
+	}
+
+	if len(report.Suites) != 2 {
+		t.Fatalf("expected a suite per driver, got %d", len(report.Suites))
+	}
+	if s := report.Suites[0]; s.Name != "localhome" || s.Tests != 2 || s.Failures != 1 || s.Time != 2 {
+		t.Errorf("unexpected suite %+v", s)
+	}
+	if c := report.Suites[0].Cases[1]; c.Failure == nil || !strings.Contains(c.Failure.Message, "boom") {
+		t.Errorf("expected the failure to be reported, got %+v", c)
+	}
+	if s := report.Suites[1]; s.Skipped != 1 || s.Cases[0].Skipped == nil {
+		t.Errorf("expected the skipped scenario to be reported, got %+v", s)
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package e2e
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const startTimeout = 30 * time.Second
+
+// process is a running revad instance.
+type process struct {
+	name        string
+	root        string
+	grpcAddress string
+	httpAddress string
+	cmd         *exec.Cmd
+	exited      chan struct{}
+}
+
+// cluster are the instances started for a scenario.
+type cluster struct {
+	processes map[string]*process
+	// vars are the variables available to the templates and the steps.
+	vars map[string]string
+}
+
+// startCluster starts the instances used by the scenario, configured for the driver.
+// Every instance gets its own root directory and its own addresses, which the
+// instances and the steps refer to as {{<instance>_grpc_address}} and
+// {{<instance>_http_address}}.
+func (r *runner) startCluster(d *Driver, s *Scenario) (*cluster, error) {
+	c := &cluster{
+		processes: map[string]*process{},
+		vars:      map[string]string{},
+	}
+	for k, v := range d.Variables {
+		c.vars[k] = v
+	}
+	for _, name := range s.Instances {
+		root, err := ioutil.TempDir("", "reva-e2e-"+name+"-")
+		if err != nil {
+			return nil, errors.Wrap(err, "e2e: error creating root")
+		}
+		p := &process{name: name, root: root}
+		c.processes[name] = p
+		if p.grpcAddress, err = freeAddress(); err != nil {
+			c.stop(false)
+			return nil, err
+		}
+		if p.httpAddress, err = freeAddress(); err != nil {
+			c.stop(false)
+			return nil, err
+		}
+		c.vars[name+"_root"] = p.root
+		c.vars[name+"_grpc_address"] = p.grpcAddress
+		c.vars[name+"_http_address"] = p.httpAddress
+	}
+
+	for _, name := range s.Instances {
+		if err := r.startProcess(c, c.processes[name], r.matrix.Instances[name]); err != nil {
+			c.stop(true)
+			return nil, errors.Wrapf(err, "e2e: error starting instance %s", name)
+		}
+	}
+	return c, nil
+}
+
+func (r *runner) startProcess(c *cluster, p *process, i *Instance) error {
+	vars := map[string]string{
+		"name":         p.name,
+		"root":         p.root,
+		"grpc_address": p.grpcAddress,
+		"http_address": p.httpAddress,
+	}
+	for k, v := range c.vars {
+		vars[k] = v
+	}
+
+	config := filepath.Join(p.root, "revad.toml")
+	if err := writeTemplate(i.Config, config, vars); err != nil {
+		return err
+	}
+	for _, f := range i.Files {
+		if err := writeTemplate(f, filepath.Join(p.root, filepath.Base(f)), vars); err != nil {
+			return err
+		}
+	}
+
+	out, err := os.Create(filepath.Join(p.root, "revad.log"))
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	p.cmd = exec.Command(r.revad, "-c", config)
+	p.cmd.Dir = p.root
+	p.cmd.Stdout = out
+	p.cmd.Stderr = out
+	if err := p.cmd.Start(); err != nil {
+		return err
+	}
+	p.exited = make(chan struct{})
+	go func() {
+		_ = p.cmd.Wait()
+		close(p.exited)
+	}()
+
+	addresses := []string{p.grpcAddress}
+	if tmpl, err := ioutil.ReadFile(i.Config); err == nil && strings.Contains(string(tmpl), "{{http_address}}") {
+		addresses = append(addresses, p.httpAddress)
+	}
+	for _, a := range addresses {
+		if err := p.waitFor(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitFor waits until the process listens on the address.
+func (p *process) waitFor(address string) error {
+	deadline := time.Now().Add(startTimeout)
+	for time.Now().Before(deadline) {
+		select {
+		case <-p.exited:
+			return fmt.Errorf("revad exited, see %s", filepath.Join(p.root, "revad.log"))
+		default:
+		}
+		conn, err := net.Dial("tcp", address)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return fmt.Errorf("revad did not listen on %s within %s", address, startTimeout)
+}
+
+// stop kills the instances and removes their roots, unless they are kept for debugging.
+func (c *cluster) stop(keep bool) {
+	for _, p := range c.processes {
+		if p.cmd != nil && p.cmd.Process != nil {
+			_ = p.cmd.Process.Kill()
+			<-p.exited
+		}
+		if !keep {
+			os.RemoveAll(p.root)
+		}
+	}
+}
+
+// roots returns the root directories of the instances.
+func (c *cluster) roots() []string {
+	roots := make([]string, 0, len(c.processes))
+	for _, p := range c.processes {
+		roots = append(roots, p.root)
+	}
+	return roots
+}
+
+func freeAddress() (string, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return "", errors.Wrap(err, "e2e: error finding a free port")
+	}
+	defer l.Close()
+	return l.Addr().String(), nil
+}
+
+func writeTemplate(src, dst string, vars map[string]string) error {
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return errors.Wrap(err, "e2e: error reading template")
+	}
+	if err := ioutil.WriteFile(dst, []byte(expand(string(data), vars)), 0600); err != nil {
+		return errors.Wrap(err, "e2e: error writing "+dst)
+	}
+	return nil
+}
+
+// expand replaces the {{variable}} placeholders of s. Unknown placeholders,
+// like the {{.Username}} of the user layouts, are left untouched.
+func expand(s string, vars map[string]string) string {
+	for k, v := range vars {
+		s = strings.ReplaceAll(s, "{{"+k+"}}", v)
+	}
+	return s
+}
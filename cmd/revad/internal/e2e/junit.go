@@ -0,0 +1,100 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package e2e
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The JUnit report has a test suite per driver and a test case per scenario,
+// which is the layout understood by the CI systems.
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     float64     `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func writeJUnit(file string, results []*result) error {
+	report := junitSuites{}
+	index := map[string]int{}
+	for _, res := range results {
+		i, ok := index[res.driver]
+		if !ok {
+			i = len(report.Suites)
+			index[res.driver] = i
+			report.Suites = append(report.Suites, junitSuite{Name: res.driver})
+		}
+		suite := &report.Suites[i]
+
+		c := junitCase{
+			Name:      res.scenario,
+			Classname: res.driver,
+			Time:      res.duration.Seconds(),
+			SystemOut: strings.Join(res.output, "\n"),
+		}
+		switch {
+		case res.skipped:
+			c.Skipped = &struct{}{}
+			suite.Skipped++
+		case res.err != nil:
+			c.Failure = &junitFailure{Message: res.err.Error(), Text: res.err.Error()}
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.Time += c.Time
+		suite.Cases = append(suite.Cases, c)
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "e2e: error encoding the junit report")
+	}
+	data = append([]byte(xml.Header), data...)
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		return errors.Wrap(err, "e2e: error writing the junit report")
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package e2e
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Matrix describes the instances to start and the drivers and scenarios to run against them.
+type Matrix struct {
+	// Users maps the usernames used in the scenarios to their passwords.
+	Users map[string]string `yaml:"users"`
+	// Instances are the revad instances of a run, by name.
+	Instances map[string]*Instance `yaml:"instances"`
+	// Drivers are the entries of the matrix. Every scenario runs once per driver.
+	Drivers []*Driver `yaml:"drivers"`
+	// Scenarios are the files of the scenarios, relative to the matrix file.
+	Scenarios []string `yaml:"scenarios"`
+
+	scenarios []*Scenario
+}
+
+// Instance is a revad instance, configured by a template.
+type Instance struct {
+	// Config is the template of the revad configuration file.
+	Config string `yaml:"config"`
+	// Files are further templates, like the users or the providers of the
+	// mesh, written next to the configuration file.
+	Files []string `yaml:"files"`
+}
+
+// Driver is an entry of the matrix, providing the variables substituted in the templates.
+type Driver struct {
+	Name      string            `yaml:"name"`
+	Variables map[string]string `yaml:"variables"`
+	// Skip lists the scenarios the driver does not support.
+	Skip []string `yaml:"skip"`
+}
+
+// Scenario is a scripted sequence of steps.
+type Scenario struct {
+	Name string `yaml:"name"`
+	// Instances are the instances the scenario needs. Defaults to all of them.
+	Instances []string `yaml:"instances"`
+	Steps     []*Step  `yaml:"steps"`
+}
+
+// Step is a single action performed by a user against an instance.
+// Which of the fields are used depends on the action.
+type Step struct {
+	Action   string `yaml:"action"`
+	Instance string `yaml:"instance"`
+	User     string `yaml:"user"`
+	Path     string `yaml:"path"`
+	Target   string `yaml:"target"`
+	Content  string `yaml:"content"`
+	Grantee  string `yaml:"grantee"`
+	Role     string `yaml:"role"`
+	Domain   string `yaml:"domain"`
+	Idp      string `yaml:"idp"`
+	Token    string `yaml:"token"`
+	// Expect is the expected status code, e.g. not_found. Defaults to ok.
+	Expect string `yaml:"expect"`
+	// Save names the variable in which the result of the step, like an
+	// invite token, is stored for the following steps.
+	Save string `yaml:"save"`
+}
+
+var variableName = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// LoadMatrix reads and validates the matrix file and the scenarios it refers to.
+func LoadMatrix(file string) (*Matrix, error) {
+	m := &Matrix{}
+	if err := readYAML(file, m); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(file)
+	for _, i := range m.Instances {
+		i.Config = resolve(dir, i.Config)
+		for n, f := range i.Files {
+			i.Files[n] = resolve(dir, f)
+		}
+	}
+	for _, f := range m.Scenarios {
+		s := &Scenario{}
+		if err := readYAML(resolve(dir, f), s); err != nil {
+			return nil, err
+		}
+		m.scenarios = append(m.scenarios, s)
+	}
+
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func readYAML(file string, v interface{}) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return errors.Wrap(err, "e2e: error reading "+file)
+	}
+	if err := yaml.UnmarshalStrict(data, v); err != nil {
+		return errors.Wrap(err, "e2e: error parsing "+file)
+	}
+	return nil
+}
+
+func resolve(dir, file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(dir, file)
+}
+
+func (m *Matrix) validate() error {
+	if len(m.Instances) == 0 {
+		return errors.New("e2e: the matrix has no instances")
+	}
+	for name, i := range m.Instances {
+		if !variableName.MatchString(name) {
+			return errors.Errorf("e2e: invalid instance name %q", name)
+		}
+		if i.Config == "" {
+			return errors.Errorf("e2e: instance %s has no config", name)
+		}
+	}
+	if len(m.Drivers) == 0 {
+		return errors.New("e2e: the matrix has no drivers")
+	}
+	for _, d := range m.Drivers {
+		if d.Name == "" {
+			return errors.New("e2e: drivers need a name")
+		}
+	}
+
+	for _, s := range m.scenarios {
+		if s.Name == "" {
+			return errors.New("e2e: scenarios need a name")
+		}
+		if len(s.Instances) == 0 {
+			for name := range m.Instances {
+				s.Instances = append(s.Instances, name)
+			}
+			sort.Strings(s.Instances)
+		}
+		for _, i := range s.Instances {
+			if m.Instances[i] == nil {
+				return errors.Errorf("e2e: scenario %s uses unknown instance %s", s.Name, i)
+			}
+		}
+		for n, st := range s.Steps {
+			if err := m.validateStep(s, st); err != nil {
+				return errors.Wrapf(err, "e2e: step %d of scenario %s", n+1, s.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Matrix) validateStep(s *Scenario, st *Step) error {
+	if actions[st.Action] == nil {
+		return errors.Errorf("unknown action %q", st.Action)
+	}
+	if !contains(s.Instances, st.Instance) {
+		return errors.Errorf("instance %q is not used by the scenario", st.Instance)
+	}
+	if _, ok := m.Users[st.User]; !ok {
+		return errors.Errorf("unknown user %q", st.User)
+	}
+	if st.Save != "" && !variableName.MatchString(st.Save) {
+		return errors.Errorf("invalid variable name %q", st.Save)
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
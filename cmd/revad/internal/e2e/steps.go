@@ -0,0 +1,426 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	invitepb "github.com/cs3org/go-cs3apis/cs3/ocm/invite/v1beta1"
+	ocmprovider "github.com/cs3org/go-cs3apis/cs3/ocm/provider/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// session is a user authenticated against an instance.
+type session struct {
+	ctx    context.Context
+	client gateway.GatewayAPIClient
+}
+
+// action performs a step. The returned string is stored in the variable
+// named by the Save field of the step.
+type action func(s *session, st *Step) (string, error)
+
+var actions map[string]action
+
+func init() {
+	actions = map[string]action{
+		"mkdir":             mkdir,
+		"upload":            upload,
+		"download":          download,
+		"stat":              stat,
+		"move":              move,
+		"delete":            remove,
+		"restore":           restore,
+		"share":             share,
+		"accept-shares":     acceptShares,
+		"ocm-invite":        ocmInvite,
+		"ocm-accept-invite": ocmAcceptInvite,
+		"ocm-share":         ocmShare,
+		"accept-ocm-shares": acceptOCMShares,
+	}
+}
+
+// statusError is returned when a call succeeds with a status other than ok,
+// which is what the steps expecting a failure check for.
+type statusError struct {
+	msg    string
+	status *rpc.Status
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s: code=%s msg=%q", e.msg, e.status.Code, e.status.Message)
+}
+
+func checkStatus(st *rpc.Status, err error, msg string) error {
+	if err != nil {
+		return errors.Wrap(err, msg)
+	}
+	if st.Code != rpc.Code_CODE_OK {
+		return &statusError{msg: msg, status: st}
+	}
+	return nil
+}
+
+// expectedCode parses the expect field of a step, e.g. not_found.
+func expectedCode(expect string) (rpc.Code, error) {
+	if expect == "" {
+		return rpc.Code_CODE_OK, nil
+	}
+	c, ok := rpc.Code_value["CODE_"+strings.ToUpper(expect)]
+	if !ok {
+		return 0, errors.Errorf("unknown status code %q", expect)
+	}
+	return rpc.Code(c), nil
+}
+
+func authenticate(ctx context.Context, client gateway.GatewayAPIClient, username, password string) (*session, error) {
+	res, err := client.Authenticate(ctx, &gateway.AuthenticateRequest{
+		Type:         "basic",
+		ClientId:     username,
+		ClientSecret: password,
+	})
+	if err := checkStatus(res.GetStatus(), err, "error authenticating "+username); err != nil {
+		return nil, err
+	}
+	ctx = ctxpkg.ContextSetToken(ctx, res.Token)
+	ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.TokenHeader, res.Token)
+	return &session{ctx: ctx, client: client}, nil
+}
+
+func ref(p string) *provider.Reference {
+	return &provider.Reference{Path: p}
+}
+
+func mkdir(s *session, st *Step) (string, error) {
+	res, err := s.client.CreateContainer(s.ctx, &provider.CreateContainerRequest{Ref: ref(st.Path)})
+	return "", checkStatus(res.GetStatus(), err, "error creating folder "+st.Path)
+}
+
+func upload(s *session, st *Step) (string, error) {
+	res, err := s.client.InitiateFileUpload(s.ctx, &provider.InitiateFileUploadRequest{
+		Ref: ref(st.Path),
+		Opaque: &types.Opaque{
+			Map: map[string]*types.OpaqueEntry{
+				"Upload-Length": {
+					Decoder: "plain",
+					Value:   []byte(strconv.Itoa(len(st.Content))),
+				},
+			},
+		},
+	})
+	if err := checkStatus(res.GetStatus(), err, "error initiating upload of "+st.Path); err != nil {
+		return "", err
+	}
+
+	var endpoint, token string
+	for _, p := range res.Protocols {
+		if p.Protocol == "simple" {
+			endpoint, token = p.UploadEndpoint, p.Token
+		}
+	}
+	if endpoint == "" {
+		return "", errors.New("no simple upload protocol available for " + st.Path)
+	}
+
+	httpRes, err := s.transfer(http.MethodPut, endpoint, token, st.Content)
+	if err != nil {
+		return "", errors.Wrap(err, "error uploading "+st.Path)
+	}
+	defer httpRes.Body.Close()
+	if httpRes.StatusCode != http.StatusOK && httpRes.StatusCode != http.StatusCreated && httpRes.StatusCode != http.StatusNoContent {
+		return "", errors.New("upload of " + st.Path + " returned " + httpRes.Status)
+	}
+	return "", nil
+}
+
+func download(s *session, st *Step) (string, error) {
+	res, err := s.client.InitiateFileDownload(s.ctx, &provider.InitiateFileDownloadRequest{Ref: ref(st.Path)})
+	if err := checkStatus(res.GetStatus(), err, "error initiating download of "+st.Path); err != nil {
+		return "", err
+	}
+
+	var endpoint, token string
+	for _, p := range res.Protocols {
+		if p.Protocol == "simple" || endpoint == "" {
+			endpoint, token = p.DownloadEndpoint, p.Token
+		}
+	}
+	if endpoint == "" {
+		return "", errors.New("no download protocol available for " + st.Path)
+	}
+
+	httpRes, err := s.transfer(http.MethodGet, endpoint, token, "")
+	if err != nil {
+		return "", errors.Wrap(err, "error downloading "+st.Path)
+	}
+	defer httpRes.Body.Close()
+	if httpRes.StatusCode != http.StatusOK {
+		return "", errors.New("download of " + st.Path + " returned " + httpRes.Status)
+	}
+	data, err := ioutil.ReadAll(httpRes.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "error downloading "+st.Path)
+	}
+	if st.Content != "" && string(data) != st.Content {
+		return "", fmt.Errorf("%s has content %q, expected %q", st.Path, data, st.Content)
+	}
+	return string(data), nil
+}
+
+func (s *session) transfer(method, endpoint, token, body string) (*http.Response, error) {
+	req, err := rhttp.NewRequest(s.ctx, method, endpoint, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(datagateway.TokenTransportHeader, token)
+	req.Header.Set(ctxpkg.TokenHeader, ctxpkg.ContextMustGetToken(s.ctx))
+	return rhttp.GetHTTPClient(rhttp.Insecure(true)).Do(req)
+}
+
+func stat(s *session, st *Step) (string, error) {
+	res, err := s.client.Stat(s.ctx, &provider.StatRequest{Ref: ref(st.Path)})
+	if err := checkStatus(res.GetStatus(), err, "error stating "+st.Path); err != nil {
+		return "", err
+	}
+	return res.Info.Etag, nil
+}
+
+func move(s *session, st *Step) (string, error) {
+	res, err := s.client.Move(s.ctx, &provider.MoveRequest{Source: ref(st.Path), Destination: ref(st.Target)})
+	return "", checkStatus(res.GetStatus(), err, "error moving "+st.Path)
+}
+
+func remove(s *session, st *Step) (string, error) {
+	res, err := s.client.Delete(s.ctx, &provider.DeleteRequest{Ref: ref(st.Path)})
+	return "", checkStatus(res.GetStatus(), err, "error deleting "+st.Path)
+}
+
+// restore restores the most recently deleted item of the home with the
+// name of the path to its original location.
+func restore(s *session, st *Step) (string, error) {
+	home, err := s.client.GetHome(s.ctx, &provider.GetHomeRequest{})
+	if err := checkStatus(home.GetStatus(), err, "error getting home"); err != nil {
+		return "", err
+	}
+	res, err := s.client.ListRecycle(s.ctx, &provider.ListRecycleRequest{Ref: ref(home.Path)})
+	if err := checkStatus(res.GetStatus(), err, "error listing the recycle bin"); err != nil {
+		return "", err
+	}
+
+	var item *provider.RecycleItem
+	for _, i := range res.RecycleItems {
+		if path.Base(i.Ref.GetPath()) != path.Base(st.Path) {
+			continue
+		}
+		if item == nil || i.DeletionTime.GetSeconds() > item.DeletionTime.GetSeconds() {
+			item = i
+		}
+	}
+	if item == nil {
+		return "", &statusError{
+			msg:    "error restoring " + st.Path,
+			status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND, Message: "not in the recycle bin"},
+		}
+	}
+
+	restoreRes, err := s.client.RestoreRecycleItem(s.ctx, &provider.RestoreRecycleItemRequest{Ref: ref(home.Path), Key: item.Key})
+	return "", checkStatus(restoreRes.GetStatus(), err, "error restoring "+st.Path)
+}
+
+func rolePermissions(name string) (*provider.ResourcePermissions, error) {
+	if name == "" {
+		name = conversions.RoleViewer
+	}
+	role := conversions.RoleFromName(name)
+	if role.Name == conversions.RoleUnknown {
+		return nil, errors.New("unknown role " + name)
+	}
+	return role.CS3ResourcePermissions(), nil
+}
+
+func share(s *session, st *Step) (string, error) {
+	info, err := s.client.Stat(s.ctx, &provider.StatRequest{Ref: ref(st.Path)})
+	if err := checkStatus(info.GetStatus(), err, "error stating "+st.Path); err != nil {
+		return "", err
+	}
+	grantee, err := s.client.GetUserByClaim(s.ctx, &userpb.GetUserByClaimRequest{Claim: "username", Value: st.Grantee})
+	if err := checkStatus(grantee.GetStatus(), err, "error getting user "+st.Grantee); err != nil {
+		return "", err
+	}
+	perms, err := rolePermissions(st.Role)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := s.client.CreateShare(s.ctx, &collaboration.CreateShareRequest{
+		ResourceInfo: info.Info,
+		Grant: &collaboration.ShareGrant{
+			Permissions: &collaboration.SharePermissions{Permissions: perms},
+			Grantee: &provider.Grantee{
+				Type: provider.GranteeType_GRANTEE_TYPE_USER,
+				Id:   &provider.Grantee_UserId{UserId: grantee.User.Id},
+			},
+		},
+	})
+	if err := checkStatus(res.GetStatus(), err, "error sharing "+st.Path); err != nil {
+		return "", err
+	}
+	return res.Share.Id.OpaqueId, nil
+}
+
+// acceptShares accepts all the pending shares received by the user.
+func acceptShares(s *session, st *Step) (string, error) {
+	res, err := s.client.ListReceivedShares(s.ctx, &collaboration.ListReceivedSharesRequest{})
+	if err := checkStatus(res.GetStatus(), err, "error listing received shares"); err != nil {
+		return "", err
+	}
+	accepted := 0
+	for _, rs := range res.Shares {
+		if rs.State != collaboration.ShareState_SHARE_STATE_PENDING {
+			continue
+		}
+		rs.State = collaboration.ShareState_SHARE_STATE_ACCEPTED
+		updateRes, err := s.client.UpdateReceivedShare(s.ctx, &collaboration.UpdateReceivedShareRequest{
+			Share:      rs,
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"state"}},
+		})
+		if err := checkStatus(updateRes.GetStatus(), err, "error accepting share "+rs.Share.Id.OpaqueId); err != nil {
+			return "", err
+		}
+		accepted++
+	}
+	return strconv.Itoa(accepted), nil
+}
+
+func ocmInvite(s *session, st *Step) (string, error) {
+	res, err := s.client.GenerateInviteToken(s.ctx, &invitepb.GenerateInviteTokenRequest{})
+	if err := checkStatus(res.GetStatus(), err, "error generating invite token"); err != nil {
+		return "", err
+	}
+	return res.InviteToken.Token, nil
+}
+
+func (s *session) meshProvider(domain string) (*ocmprovider.ProviderInfo, error) {
+	res, err := s.client.GetInfoByDomain(s.ctx, &ocmprovider.GetInfoByDomainRequest{Domain: domain})
+	if err := checkStatus(res.GetStatus(), err, "error getting provider "+domain); err != nil {
+		return nil, err
+	}
+	return res.ProviderInfo, nil
+}
+
+// ocmAcceptInvite accepts the invite token generated by a user of the provider with the domain.
+func ocmAcceptInvite(s *session, st *Step) (string, error) {
+	p, err := s.meshProvider(st.Domain)
+	if err != nil {
+		return "", err
+	}
+	res, err := s.client.ForwardInvite(s.ctx, &invitepb.ForwardInviteRequest{
+		InviteToken:          &invitepb.InviteToken{Token: st.Token},
+		OriginSystemProvider: p,
+	})
+	return "", checkStatus(res.GetStatus(), err, "error accepting invite")
+}
+
+// ocmShare shares the path with the remote user of the provider with the
+// domain, whose id and idp are given by the grantee and idp fields.
+func ocmShare(s *session, st *Step) (string, error) {
+	p, err := s.meshProvider(st.Domain)
+	if err != nil {
+		return "", err
+	}
+	remote, err := s.client.GetAcceptedUser(s.ctx, &invitepb.GetAcceptedUserRequest{
+		RemoteUserId: &userpb.UserId{OpaqueId: st.Grantee, Idp: st.Idp, Type: userpb.UserType_USER_TYPE_PRIMARY},
+	})
+	if err := checkStatus(remote.GetStatus(), err, "error getting accepted user "+st.Grantee); err != nil {
+		return "", err
+	}
+	info, err := s.client.Stat(s.ctx, &provider.StatRequest{Ref: ref(st.Path)})
+	if err := checkStatus(info.GetStatus(), err, "error stating "+st.Path); err != nil {
+		return "", err
+	}
+
+	perms, permissions := conversions.NewViewerRole().CS3ResourcePermissions(), 1
+	if st.Role == conversions.RoleEditor {
+		perms, permissions = conversions.NewEditorRole().CS3ResourcePermissions(), 15
+	}
+	res, err := s.client.CreateOCMShare(s.ctx, &ocm.CreateOCMShareRequest{
+		Opaque: &types.Opaque{
+			Map: map[string]*types.OpaqueEntry{
+				"permissions": {Decoder: "plain", Value: []byte(strconv.Itoa(permissions))},
+				"name":        {Decoder: "plain", Value: []byte(info.Info.Path)},
+			},
+		},
+		ResourceId: info.Info.Id,
+		Grant: &ocm.ShareGrant{
+			Permissions: &ocm.SharePermissions{Permissions: perms},
+			Grantee: &provider.Grantee{
+				Type: provider.GranteeType_GRANTEE_TYPE_USER,
+				Id:   &provider.Grantee_UserId{UserId: remote.RemoteUser.Id},
+			},
+		},
+		RecipientMeshProvider: p,
+	})
+	if err := checkStatus(res.GetStatus(), err, "error creating OCM share of "+st.Path); err != nil {
+		return "", err
+	}
+	return res.Share.Id.OpaqueId, nil
+}
+
+// acceptOCMShares accepts all the pending OCM shares received by the user.
+func acceptOCMShares(s *session, st *Step) (string, error) {
+	res, err := s.client.ListReceivedOCMShares(s.ctx, &ocm.ListReceivedOCMSharesRequest{})
+	if err := checkStatus(res.GetStatus(), err, "error listing received OCM shares"); err != nil {
+		return "", err
+	}
+	accepted := 0
+	for _, rs := range res.Shares {
+		if rs.State != ocm.ShareState_SHARE_STATE_PENDING {
+			continue
+		}
+		rs.State = ocm.ShareState_SHARE_STATE_ACCEPTED
+		updateRes, err := s.client.UpdateReceivedOCMShare(s.ctx, &ocm.UpdateReceivedOCMShareRequest{
+			Share:      rs,
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"state"}},
+		})
+		if err := checkStatus(updateRes.GetStatus(), err, "error accepting OCM share "+rs.Share.Id.OpaqueId); err != nil {
+			return "", err
+		}
+		accepted++
+	}
+	return strconv.Itoa(accepted), nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package seed
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// account is the format shared by the json user and auth drivers.
+type account struct {
+	ID          *userpb.UserId `json:"id"`
+	Username    string         `json:"username"`
+	Secret      string         `json:"secret"`
+	Mail        string         `json:"mail"`
+	DisplayName string         `json:"display_name"`
+	Groups      []string       `json:"groups"`
+	UIDNumber   int64          `json:"uid_number,omitempty"`
+	GIDNumber   int64          `json:"gid_number,omitempty"`
+}
+
+// userID returns the id of the user. Unless set in the fixture, it is derived
+// from the username so that seeding again results in the same ids.
+func (f *Fixture) userID(u *User) *userpb.UserId {
+	id := u.ID
+	if id == "" {
+		id = uuid.NewSHA1(uuid.NameSpaceURL, []byte(f.Idp+"/"+u.Username)).String()
+	}
+	return &userpb.UserId{
+		OpaqueId: id,
+		Idp:      f.Idp,
+		Type:     userpb.UserType_USER_TYPE_PRIMARY,
+	}
+}
+
+func (f *Fixture) groupID(g *Group) *grouppb.GroupId {
+	return &grouppb.GroupId{
+		OpaqueId: g.Name,
+		Idp:      f.Idp,
+	}
+}
+
+// WriteAccounts writes the users and groups to the files read by the json drivers.
+func (f *Fixture) WriteAccounts() error {
+	if f.UsersFile != "" {
+		accounts := make([]*account, 0, len(f.Users))
+		for _, u := range f.Users {
+			accounts = append(accounts, &account{
+				ID:          f.userID(u),
+				Username:    u.Username,
+				Secret:      u.Password,
+				Mail:        u.Mail,
+				DisplayName: u.DisplayName,
+				Groups:      u.Groups,
+				UIDNumber:   u.UIDNumber,
+				GIDNumber:   u.GIDNumber,
+			})
+		}
+		if err := writeJSON(f.UsersFile, accounts); err != nil {
+			return errors.Wrap(err, "seed: error writing users file")
+		}
+	}
+
+	if f.GroupsFile != "" {
+		groups := make([]*grouppb.Group, 0, len(f.Groups))
+		for _, g := range f.Groups {
+			group := &grouppb.Group{
+				Id:          f.groupID(g),
+				GroupName:   g.Name,
+				Mail:        g.Mail,
+				DisplayName: g.DisplayName,
+				GidNumber:   g.GIDNumber,
+			}
+			for _, u := range f.Users {
+				for _, name := range u.Groups {
+					if name == g.Name {
+						group.Members = append(group.Members, f.userID(u))
+					}
+				}
+			}
+			groups = append(groups, group)
+		}
+		if err := writeJSON(f.GroupsFile, groups); err != nil {
+			return errors.Wrap(err, "seed: error writing groups file")
+		}
+	}
+	return nil
+}
+
+func writeJSON(file string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0600)
+}
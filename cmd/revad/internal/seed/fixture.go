@@ -0,0 +1,172 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package seed
+
+import (
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Fixture describes the demo data to be provisioned.
+type Fixture struct {
+	// Gateway is the address of the gateway used to provision the data.
+	Gateway string `yaml:"gateway"`
+	// Insecure skips the certificate checks when uploading the sample content.
+	Insecure bool `yaml:"insecure"`
+	// Idp is the identity provider of the seeded users and groups.
+	Idp string `yaml:"idp"`
+
+	// UsersFile and GroupsFile are the json files of the json user, group and auth drivers
+	// into which the accounts are written.
+	UsersFile  string `yaml:"users_file"`
+	GroupsFile string `yaml:"groups_file"`
+
+	Users       []*User       `yaml:"users"`
+	Groups      []*Group      `yaml:"groups"`
+	Shares      []*Share      `yaml:"shares"`
+	PublicLinks []*PublicLink `yaml:"public_links"`
+}
+
+// User is a demo account, provisioned with a home and sample content.
+type User struct {
+	ID          string   `yaml:"id"`
+	Username    string   `yaml:"username"`
+	Password    string   `yaml:"password"`
+	DisplayName string   `yaml:"display_name"`
+	Mail        string   `yaml:"mail"`
+	UIDNumber   int64    `yaml:"uid_number"`
+	GIDNumber   int64    `yaml:"gid_number"`
+	Groups      []string `yaml:"groups"`
+	Files       []*File  `yaml:"files"`
+}
+
+// File is a file or, if it has no content, a folder created in the home of a user.
+type File struct {
+	Path    string `yaml:"path"`
+	Content string `yaml:"content"`
+	Folder  bool   `yaml:"folder"`
+}
+
+// Group is a demo group. Its members are the users listing it.
+type Group struct {
+	Name        string `yaml:"name"`
+	DisplayName string `yaml:"display_name"`
+	Mail        string `yaml:"mail"`
+	GIDNumber   int64  `yaml:"gid_number"`
+}
+
+// Share is a share of a resource of a user with another user or a group.
+type Share struct {
+	Owner string `yaml:"owner"`
+	Path  string `yaml:"path"`
+	User  string `yaml:"user"`
+	Group string `yaml:"group"`
+	Role  string `yaml:"role"`
+}
+
+// PublicLink is a public link to a resource of a user.
+type PublicLink struct {
+	Owner      string     `yaml:"owner"`
+	Path       string     `yaml:"path"`
+	Role       string     `yaml:"role"`
+	Password   string     `yaml:"password"`
+	Expiration *time.Time `yaml:"expiration"`
+}
+
+// LoadFixture reads and validates the fixture file.
+func LoadFixture(file string) (*Fixture, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "seed: error reading fixture file")
+	}
+	f := &Fixture{}
+	if err := yaml.UnmarshalStrict(data, f); err != nil {
+		return nil, errors.Wrap(err, "seed: error parsing fixture file")
+	}
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *Fixture) user(username string) *User {
+	for _, u := range f.Users {
+		if u.Username == username {
+			return u
+		}
+	}
+	return nil
+}
+
+func (f *Fixture) group(name string) *Group {
+	for _, g := range f.Groups {
+		if g.Name == name {
+			return g
+		}
+	}
+	return nil
+}
+
+func (f *Fixture) validate() error {
+	if f.Gateway == "" {
+		f.Gateway = "localhost:19000"
+	}
+	if f.Idp == "" {
+		f.Idp = "http://localhost:20080"
+	}
+
+	for _, u := range f.Users {
+		if u.Username == "" || u.Password == "" {
+			return errors.New("seed: users need a username and a password")
+		}
+		for _, g := range u.Groups {
+			if f.group(g) == nil {
+				return errors.Errorf("seed: user %s is member of unknown group %s", u.Username, g)
+			}
+		}
+	}
+	for _, g := range f.Groups {
+		if g.Name == "" {
+			return errors.New("seed: groups need a name")
+		}
+	}
+	for _, s := range f.Shares {
+		if f.user(s.Owner) == nil {
+			return errors.Errorf("seed: share of %s owned by unknown user %s", s.Path, s.Owner)
+		}
+		if (s.User == "") == (s.Group == "") {
+			return errors.Errorf("seed: share of %s needs either a user or a group", s.Path)
+		}
+		if s.User != "" && f.user(s.User) == nil {
+			return errors.Errorf("seed: share of %s with unknown user %s", s.Path, s.User)
+		}
+		if s.Group != "" && f.group(s.Group) == nil {
+			return errors.Errorf("seed: share of %s with unknown group %s", s.Path, s.Group)
+		}
+	}
+	for _, l := range f.PublicLinks {
+		if f.user(l.Owner) == nil {
+			return errors.Errorf("seed: public link to %s owned by unknown user %s", l.Path, l.Owner)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package seed
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+)
+
+const fixture = `
+users_file: %s/users.json
+groups_file: %s/groups.json
+users:
+  - username: einstein
+    password: relativity
+    groups: [physics]
+  - username: marie
+    password: radioactivity
+    groups: [physics]
+groups:
+  - name: physics
+shares:
+  - owner: einstein
+    path: /notes
+    group: physics
+    role: viewer
+public_links:
+  - owner: marie
+    path: /paper.pdf
+`
+
+func writeFixture(t *testing.T, content string) string {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "fixture.yaml")
+	if err := ioutil.WriteFile(file, []byte(strings.ReplaceAll(content, "%s", dir)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLoadFixture(t *testing.T) {
+	f, err := LoadFixture(writeFixture(t, fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Gateway != "localhost:19000" || f.Idp != "http://localhost:20080" {
+		t.Errorf("expected the default gateway and idp, got %s and %s", f.Gateway, f.Idp)
+	}
+	if len(f.Users) != 2 || len(f.Shares) != 1 || len(f.PublicLinks) != 1 {
+		t.Errorf("unexpected fixture %+v", f)
+	}
+}
+
+func TestLoadInvalidFixture(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":        "unknown: true\n",
+		"missing password":     "users:\n  - username: einstein\n",
+		"unknown member group": "users:\n  - username: einstein\n    password: x\n    groups: [chemistry]\n",
+		"unknown share owner":  "shares:\n  - owner: einstein\n    path: /notes\n    user: marie\n",
+		"user and group":       "users:\n  - username: einstein\n    password: x\ngroups:\n  - name: physics\nshares:\n  - owner: einstein\n    path: /notes\n    user: einstein\n    group: physics\n",
+		"unknown link owner":   "public_links:\n  - owner: einstein\n    path: /notes\n",
+	}
+	for name, content := range tests {
+		if _, err := LoadFixture(writeFixture(t, content)); err == nil {
+			t.Errorf("%s: expected the fixture to be rejected", name)
+		}
+	}
+}
+
+func TestWriteAccounts(t *testing.T) {
+	f, err := LoadFixture(writeFixture(t, fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.WriteAccounts(); err != nil {
+		t.Fatal(err)
+	}
+
+	var accounts []*account
+	readJSON(t, f.UsersFile, &accounts)
+	if len(accounts) != 2 || accounts[0].Username != "einstein" || accounts[0].Secret != "relativity" {
+		t.Fatalf("unexpected accounts %+v", accounts)
+	}
+	// seeding again results in the same ids
+	if id := f.userID(f.Users[0]); id.OpaqueId != accounts[0].ID.OpaqueId {
+		t.Errorf("expected the id to be derived from the username, got %s and %s", id.OpaqueId, accounts[0].ID.OpaqueId)
+	}
+
+	var groups []*grouppb.Group
+	readJSON(t, f.GroupsFile, &groups)
+	if len(groups) != 1 || len(groups[0].Members) != 2 {
+		t.Fatalf("expected both users to be members of the group, got %+v", groups)
+	}
+}
+
+func readJSON(t *testing.T, file string, v interface{}) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package seed
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+type seeder struct {
+	fixture *Fixture
+	client  gateway.GatewayAPIClient
+	http    *http.Client
+	log     func(format string, args ...interface{})
+}
+
+// Provision creates the homes, sample content, shares and public links of the fixture.
+// The users must already be known to the running reva instance.
+func (f *Fixture) Provision(ctx context.Context, log func(format string, args ...interface{})) error {
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(f.Gateway))
+	if err != nil {
+		return errors.Wrap(err, "seed: error getting gateway client")
+	}
+	s := &seeder{
+		fixture: f,
+		client:  client,
+		http:    rhttp.GetHTTPClient(rhttp.Insecure(f.Insecure)),
+		log:     log,
+	}
+
+	for _, u := range f.Users {
+		uctx, err := s.authenticate(ctx, u)
+		if err != nil {
+			return err
+		}
+		if err := s.provisionHome(uctx, u); err != nil {
+			return err
+		}
+	}
+	for _, sh := range f.Shares {
+		if err := s.createShare(ctx, sh); err != nil {
+			return err
+		}
+	}
+	for _, l := range f.PublicLinks {
+		if err := s.createPublicLink(ctx, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkStatus(st *rpc.Status, err error, msg string) error {
+	if err != nil {
+		return errors.Wrap(err, "seed: "+msg)
+	}
+	if st.Code != rpc.Code_CODE_OK {
+		return fmt.Errorf("seed: %s: code=%s msg=%q", msg, st.Code, st.Message)
+	}
+	return nil
+}
+
+func (s *seeder) authenticate(ctx context.Context, u *User) (context.Context, error) {
+	res, err := s.client.Authenticate(ctx, &gateway.AuthenticateRequest{
+		Type:         "basic",
+		ClientId:     u.Username,
+		ClientSecret: u.Password,
+	})
+	if err := checkStatus(res.GetStatus(), err, "error authenticating "+u.Username); err != nil {
+		return nil, err
+	}
+	ctx = ctxpkg.ContextSetToken(ctx, res.Token)
+	ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.TokenHeader, res.Token)
+	return ctx, nil
+}
+
+func (s *seeder) provisionHome(ctx context.Context, u *User) error {
+	res, err := s.client.CreateHome(ctx, &provider.CreateHomeRequest{})
+	if err == nil && res.Status.Code == rpc.Code_CODE_ALREADY_EXISTS {
+		res.Status.Code = rpc.Code_CODE_OK
+	}
+	if err := checkStatus(res.GetStatus(), err, "error creating home of "+u.Username); err != nil {
+		return err
+	}
+	s.log("created home of %s", u.Username)
+
+	for _, f := range u.Files {
+		if f.Folder {
+			err = s.mkdirAll(ctx, f.Path)
+		} else {
+			err = s.upload(ctx, f)
+		}
+		if err != nil {
+			return err
+		}
+		s.log("created %s", f.Path)
+	}
+	return nil
+}
+
+// mkdirAll creates the folder and all missing parents.
+func (s *seeder) mkdirAll(ctx context.Context, p string) error {
+	current := "/"
+	for _, name := range strings.Split(strings.Trim(path.Clean(p), "/"), "/") {
+		if name == "" {
+			continue
+		}
+		current = path.Join(current, name)
+		ref := &provider.Reference{Path: current}
+
+		stat, err := s.client.Stat(ctx, &provider.StatRequest{Ref: ref})
+		if err == nil && stat.Status.Code == rpc.Code_CODE_OK {
+			continue
+		}
+		res, err := s.client.CreateContainer(ctx, &provider.CreateContainerRequest{Ref: ref})
+		if err == nil && res.Status.Code == rpc.Code_CODE_ALREADY_EXISTS {
+			continue
+		}
+		if err := checkStatus(res.GetStatus(), err, "error creating folder "+current); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *seeder) upload(ctx context.Context, f *File) error {
+	if err := s.mkdirAll(ctx, path.Dir(f.Path)); err != nil {
+		return err
+	}
+
+	res, err := s.client.InitiateFileUpload(ctx, &provider.InitiateFileUploadRequest{
+		Ref: &provider.Reference{Path: f.Path},
+		Opaque: &types.Opaque{
+			Map: map[string]*types.OpaqueEntry{
+				"Upload-Length": {
+					Decoder: "plain",
+					Value:   []byte(fmt.Sprintf("%d", len(f.Content))),
+				},
+			},
+		},
+	})
+	if err := checkStatus(res.GetStatus(), err, "error initiating upload of "+f.Path); err != nil {
+		return err
+	}
+
+	var endpoint, token string
+	for _, p := range res.Protocols {
+		if p.Protocol == "simple" {
+			endpoint, token = p.UploadEndpoint, p.Token
+		}
+	}
+	if endpoint == "" {
+		return errors.New("seed: no simple upload protocol available for " + f.Path)
+	}
+
+	req, err := rhttp.NewRequest(ctx, http.MethodPut, endpoint, strings.NewReader(f.Content))
+	if err != nil {
+		return errors.Wrap(err, "seed: error creating upload request")
+	}
+	req.Header.Set(datagateway.TokenTransportHeader, token)
+	req.Header.Set(ctxpkg.TokenHeader, ctxpkg.ContextMustGetToken(ctx))
+
+	httpRes, err := s.http.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "seed: error uploading "+f.Path)
+	}
+	defer httpRes.Body.Close()
+	if httpRes.StatusCode != http.StatusOK && httpRes.StatusCode != http.StatusCreated && httpRes.StatusCode != http.StatusNoContent {
+		return errors.New("seed: upload of " + f.Path + " returned " + httpRes.Status)
+	}
+	return nil
+}
+
+func (s *seeder) stat(ctx context.Context, p string) (*provider.ResourceInfo, error) {
+	res, err := s.client.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{Path: p}})
+	if err := checkStatus(res.GetStatus(), err, "error stating "+p); err != nil {
+		return nil, err
+	}
+	return res.Info, nil
+}
+
+func rolePermissions(name string) (*provider.ResourcePermissions, error) {
+	if name == "" {
+		name = conversions.RoleViewer
+	}
+	role := conversions.RoleFromName(name)
+	if role.Name == conversions.RoleUnknown {
+		return nil, errors.New("seed: unknown role " + name)
+	}
+	return role.CS3ResourcePermissions(), nil
+}
+
+func (s *seeder) createShare(ctx context.Context, sh *Share) error {
+	ctx, err := s.authenticate(ctx, s.fixture.user(sh.Owner))
+	if err != nil {
+		return err
+	}
+	info, err := s.stat(ctx, sh.Path)
+	if err != nil {
+		return err
+	}
+	perms, err := rolePermissions(sh.Role)
+	if err != nil {
+		return err
+	}
+
+	grantee := &provider.Grantee{}
+	if sh.User != "" {
+		grantee.Type = provider.GranteeType_GRANTEE_TYPE_USER
+		grantee.Id = &provider.Grantee_UserId{UserId: s.fixture.userID(s.fixture.user(sh.User))}
+	} else {
+		grantee.Type = provider.GranteeType_GRANTEE_TYPE_GROUP
+		grantee.Id = &provider.Grantee_GroupId{GroupId: s.fixture.groupID(s.fixture.group(sh.Group))}
+	}
+
+	res, err := s.client.CreateShare(ctx, &collaboration.CreateShareRequest{
+		ResourceInfo: info,
+		Grant: &collaboration.ShareGrant{
+			Permissions: &collaboration.SharePermissions{Permissions: perms},
+			Grantee:     grantee,
+		},
+	})
+	if err == nil && res.Status.Code == rpc.Code_CODE_ALREADY_EXISTS {
+		s.log("share of %s already exists", sh.Path)
+		return nil
+	}
+	if err := checkStatus(res.GetStatus(), err, "error sharing "+sh.Path); err != nil {
+		return err
+	}
+	s.log("shared %s of %s with %s%s", sh.Path, sh.Owner, sh.User, sh.Group)
+	return nil
+}
+
+func (s *seeder) createPublicLink(ctx context.Context, l *PublicLink) error {
+	ctx, err := s.authenticate(ctx, s.fixture.user(l.Owner))
+	if err != nil {
+		return err
+	}
+	info, err := s.stat(ctx, l.Path)
+	if err != nil {
+		return err
+	}
+	perms, err := rolePermissions(l.Role)
+	if err != nil {
+		return err
+	}
+
+	grant := &link.Grant{
+		Permissions: &link.PublicSharePermissions{Permissions: perms},
+		Password:    l.Password,
+	}
+	if l.Expiration != nil {
+		grant.Expiration = &types.Timestamp{Seconds: uint64(l.Expiration.Unix())}
+	}
+
+	res, err := s.client.CreatePublicShare(ctx, &link.CreatePublicShareRequest{
+		ResourceInfo: info,
+		Grant:        grant,
+	})
+	if err := checkStatus(res.GetStatus(), err, "error creating public link to "+l.Path); err != nil {
+		return err
+	}
+	s.log("created public link to %s of %s with token %s", l.Path, l.Owner, res.Share.Token)
+	return nil
+}
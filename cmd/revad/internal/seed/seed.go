@@ -0,0 +1,66 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package seed provisions demo users, groups, homes with sample content,
+// shares and public links from a YAML fixture file.
+package seed
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// Main runs the seed mode with the given command line arguments.
+func Main(args []string) {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	fixtureFlag := fs.String("f", "fixture.yaml", "the YAML fixture file describing the demo data")
+	accountsOnly := fs.Bool("accounts-only", false, "only write the users and groups files, which need to be in place before starting revad")
+	skipAccounts := fs.Bool("skip-accounts", false, "do not write the users and groups files")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: revad seed [-flags]\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	f, err := LoadFixture(*fixtureFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if !*skipAccounts {
+		if err := f.WriteAccounts(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+	if *accountsOnly {
+		os.Exit(0)
+	}
+
+	logf := func(format string, args ...interface{}) {
+		fmt.Fprintf(os.Stdout, format+"\n", args...)
+	}
+	if err := f.Provision(context.Background(), logf); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -28,8 +28,12 @@ import (
 	"sync"
 	"syscall"
 
+	"github.com/cs3org/reva/cmd/revad/internal/apispec"
 	"github.com/cs3org/reva/cmd/revad/internal/config"
+	"github.com/cs3org/reva/cmd/revad/internal/e2e"
 	"github.com/cs3org/reva/cmd/revad/internal/grace"
+	"github.com/cs3org/reva/cmd/revad/internal/migrate"
+	"github.com/cs3org/reva/cmd/revad/internal/seed"
 	"github.com/cs3org/reva/cmd/revad/runtime"
 	"github.com/cs3org/reva/pkg/sysinfo"
 
@@ -50,6 +54,23 @@ var (
 )
 
 func main() {
+	// the seed mode provisions demo data instead of running the daemon
+	if len(os.Args) > 1 && os.Args[1] == "seed" {
+		seed.Main(os.Args[2:])
+	}
+	// the migrate mode applies the schema migrations of the sql drivers
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migrate.Main(os.Args[2:])
+	}
+	// the e2e mode runs end-to-end scenarios against instances of this binary
+	if len(os.Args) > 1 && os.Args[1] == "e2e" {
+		e2e.Main(os.Args[2:])
+	}
+	// the apispec mode exports the descriptions of the APIs for the generation of clients
+	if len(os.Args) > 1 && os.Args[1] == "apispec" {
+		apispec.Main(os.Args[2:], version)
+	}
+
 	flag.Parse()
 
 	// initialize the global system information
@@ -27,22 +27,31 @@ import (
 	_ "github.com/cs3org/reva/internal/http/interceptors/auth/tokenwriter/loader"
 	_ "github.com/cs3org/reva/internal/http/interceptors/loader"
 	_ "github.com/cs3org/reva/internal/http/services/loader"
+	_ "github.com/cs3org/reva/pkg/announcements/loader"
 	_ "github.com/cs3org/reva/pkg/app/provider/loader"
 	_ "github.com/cs3org/reva/pkg/app/registry/loader"
 	_ "github.com/cs3org/reva/pkg/appauth/manager/loader"
 	_ "github.com/cs3org/reva/pkg/auth/manager/loader"
 	_ "github.com/cs3org/reva/pkg/auth/registry/loader"
 	_ "github.com/cs3org/reva/pkg/cbox/loader"
+	_ "github.com/cs3org/reva/pkg/comments/loader"
 	_ "github.com/cs3org/reva/pkg/datatx/manager/loader"
+	_ "github.com/cs3org/reva/pkg/devices/loader"
 	_ "github.com/cs3org/reva/pkg/group/manager/loader"
+	_ "github.com/cs3org/reva/pkg/kvcache/loader"
+	_ "github.com/cs3org/reva/pkg/lease/loader"
 	_ "github.com/cs3org/reva/pkg/metrics/driver/loader"
 	_ "github.com/cs3org/reva/pkg/ocm/invite/manager/loader"
 	_ "github.com/cs3org/reva/pkg/ocm/provider/authorizer/loader"
 	_ "github.com/cs3org/reva/pkg/ocm/share/manager/loader"
 	_ "github.com/cs3org/reva/pkg/permission/manager/loader"
+	_ "github.com/cs3org/reva/pkg/pid/loader"
 	_ "github.com/cs3org/reva/pkg/preferences/loader"
+	_ "github.com/cs3org/reva/pkg/preview/cache/loader"
+	_ "github.com/cs3org/reva/pkg/publicshare/analytics/loader"
 	_ "github.com/cs3org/reva/pkg/publicshare/manager/loader"
 	_ "github.com/cs3org/reva/pkg/rhttp/datatx/manager/loader"
+	_ "github.com/cs3org/reva/pkg/rhttp/datatx/utils/downloadtoken/loader"
 	_ "github.com/cs3org/reva/pkg/share/cache/loader"
 	_ "github.com/cs3org/reva/pkg/share/cache/warmup/loader"
 	_ "github.com/cs3org/reva/pkg/share/manager/loader"
@@ -50,5 +59,6 @@ import (
 	_ "github.com/cs3org/reva/pkg/storage/fs/loader"
 	_ "github.com/cs3org/reva/pkg/storage/registry/loader"
 	_ "github.com/cs3org/reva/pkg/token/manager/loader"
+	_ "github.com/cs3org/reva/pkg/token/revocation/loader"
 	_ "github.com/cs3org/reva/pkg/user/manager/loader"
 )
@@ -29,11 +29,19 @@ import (
 	"strings"
 
 	"github.com/cs3org/reva/cmd/revad/internal/grace"
+	"github.com/cs3org/reva/pkg/cost"
+	"github.com/cs3org/reva/pkg/kvcache"
+	kvcacheregistry "github.com/cs3org/reva/pkg/kvcache/registry"
+	"github.com/cs3org/reva/pkg/lease"
+	leaseregistry "github.com/cs3org/reva/pkg/lease/registry"
 	"github.com/cs3org/reva/pkg/logger"
+	"github.com/cs3org/reva/pkg/profiling"
 	"github.com/cs3org/reva/pkg/registry/memory"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rhttp"
 	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/slo"
+	"github.com/cs3org/reva/pkg/sysinfo"
 	rtrace "github.com/cs3org/reva/pkg/trace"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/mitchellh/mapstructure"
@@ -78,9 +86,22 @@ type coreConf struct {
 	TracingEndpoint    string `mapstructure:"tracing_endpoint"`
 	TracingCollector   string `mapstructure:"tracing_collector"`
 	TracingServiceName string `mapstructure:"tracing_service_name"`
+	// TracingExporter is either jaeger or otlp. The otlp exporter sends the spans
+	// to the tracing_collector with the tracing_headers, e.g. to authenticate.
+	TracingExporter string            `mapstructure:"tracing_exporter"`
+	TracingHeaders  map[string]string `mapstructure:"tracing_headers"`
 
 	// TracingService specifies the service. i.e OpenCensus, OpenTelemetry, OpenTracing...
 	TracingService string `mapstructure:"tracing_service"`
+
+	// Profiling configures the continuous profiling agent.
+	Profiling profiling.Config `mapstructure:"profiling"`
+
+	// SLO configures the service level objectives tracked by the servers.
+	SLO slo.Config `mapstructure:"slo"`
+
+	// Costs configures the accounting of the resources consumed by the requests.
+	Costs cost.Config `mapstructure:"costs"`
 }
 
 func run(mainConf map[string]interface{}, coreConf *coreConf, logger *zerolog.Logger, filename string) {
@@ -88,9 +109,21 @@ func run(mainConf map[string]interface{}, coreConf *coreConf, logger *zerolog.Lo
 	logger.Info().Msgf("host info: %s", host)
 
 	if coreConf.TracingEnabled {
-		initTracing(coreConf)
+		initTracing(coreConf, logger)
 	}
 	initCPUCount(coreConf, logger)
+	if coreConf.Profiling.Enabled() {
+		initProfiling(coreConf, logger)
+	}
+	if coreConf.SLO.Enabled() {
+		initSLO(coreConf, logger)
+	}
+	if coreConf.Costs.Enabled {
+		initCosts(coreConf, logger)
+	}
+	sysinfo.SetConfig(mainConf)
+	initCache(logger)
+	initLease(logger)
 
 	servers := initServers(mainConf, logger)
 	watcher, err := initWatcher(logger, filename)
@@ -148,8 +181,85 @@ func initServers(mainConf map[string]interface{}, log *zerolog.Logger) map[strin
 	return servers
 }
 
-func initTracing(conf *coreConf) {
-	rtrace.SetTraceProvider(conf.TracingCollector, conf.TracingEndpoint, conf.TracingServiceName)
+func initTracing(conf *coreConf, log *zerolog.Logger) {
+	switch conf.TracingExporter {
+	case "", rtrace.ExporterJaeger:
+		rtrace.SetTraceProvider(conf.TracingCollector, conf.TracingEndpoint, conf.TracingServiceName)
+	case rtrace.ExporterOTLP:
+		if err := rtrace.SetOTLPTraceProvider(conf.TracingCollector, conf.TracingHeaders, conf.TracingServiceName); err != nil {
+			log.Error().Err(err).Msg("error creating otlp trace exporter")
+			os.Exit(1)
+		}
+	default:
+		log.Error().Str("exporter", conf.TracingExporter).Msg("unknown tracing exporter")
+		os.Exit(1)
+	}
+}
+
+func initProfiling(conf *coreConf, log *zerolog.Logger) {
+	agent, err := profiling.New(&conf.Profiling, log)
+	if err != nil {
+		log.Error().Err(err).Msg("error creating profiling agent")
+		os.Exit(1)
+	}
+	agent.Start()
+	log.Info().Int("interval", conf.Profiling.Interval).Msg("continuous profiling enabled")
+}
+
+func initSLO(conf *coreConf, log *zerolog.Logger) {
+	if err := slo.Configure(&conf.SLO); err != nil {
+		log.Error().Err(err).Msg("error configuring service level objectives")
+		os.Exit(1)
+	}
+	log.Info().Int("objectives", len(conf.SLO.Objectives)).Msg("service level objectives tracked")
+}
+
+func initCosts(conf *coreConf, log *zerolog.Logger) {
+	if err := cost.Configure(&conf.Costs, log); err != nil {
+		log.Error().Err(err).Msg("error configuring cost accounting")
+		os.Exit(1)
+	}
+	log.Info().Int("report_interval", conf.Costs.ReportInterval).Str("rank_by", conf.Costs.RankBy).Msg("cost accounting enabled")
+}
+
+func initCache(log *zerolog.Logger) {
+	conf, err := kvcache.ParseConfig(sharedconf.GetCache())
+	if err != nil {
+		log.Error().Err(err).Msg("error parsing cache configuration")
+		os.Exit(1)
+	}
+	f, ok := kvcacheregistry.NewFuncs[conf.Driver]
+	if !ok {
+		log.Error().Msgf("cache driver not found: %s", conf.Driver)
+		os.Exit(1)
+	}
+	store, err := f(conf.Drivers[conf.Driver])
+	if err != nil {
+		log.Error().Err(err).Msg("error creating cache store")
+		os.Exit(1)
+	}
+	kvcache.Configure(store, conf)
+	log.Info().Str("driver", conf.Driver).Msg("key-value cache enabled")
+}
+
+func initLease(log *zerolog.Logger) {
+	conf, err := lease.ParseConfig(sharedconf.GetLease())
+	if err != nil {
+		log.Error().Err(err).Msg("error parsing lease configuration")
+		os.Exit(1)
+	}
+	f, ok := leaseregistry.NewFuncs[conf.Driver]
+	if !ok {
+		log.Error().Msgf("lease driver not found: %s", conf.Driver)
+		os.Exit(1)
+	}
+	locker, err := f(conf.Drivers[conf.Driver])
+	if err != nil {
+		log.Error().Err(err).Msg("error creating lease locker")
+		os.Exit(1)
+	}
+	lease.Configure(locker, conf)
+	log.Info().Str("driver", conf.Driver).Msg("leases enabled")
 }
 
 func initCPUCount(conf *coreConf, log *zerolog.Logger) {
@@ -0,0 +1,129 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package accesslog
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/accesslog"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// NewUnary returns a new unary interceptor that writes the access log of the
+// calls. services maps the gRPC services to the names of the services
+// implementing them, it can be filled once the services have been registered.
+func NewUnary(l *accesslog.Logger, services map[string]string) grpc.UnaryServerInterceptor {
+	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		e := newEntry(ctx, info.FullMethod, services)
+		if !l.Enabled(e.Service) {
+			return handler(ctx, req)
+		}
+
+		res, err := handler(accesslog.ContextSetEntry(ctx, e), req)
+		if m, ok := res.(proto.Message); ok {
+			e.Bytes = int64(proto.Size(m))
+		}
+		finish(l, e, err)
+		return res, err
+	}
+	return interceptor
+}
+
+// NewUserUnary returns a new unary interceptor that records the user the call
+// has been authenticated as in its access log entry. It has to run after the
+// authentication.
+func NewUserUnary() grpc.UnaryServerInterceptor {
+	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if u, ok := ctxpkg.ContextGetUser(ctx); ok {
+			accesslog.SetUser(ctx, u)
+		}
+		return handler(ctx, req)
+	}
+	return interceptor
+}
+
+// NewStream returns a new server stream interceptor that writes the access log
+// of the calls. The streams are authenticated before, so the user is known.
+func NewStream(l *accesslog.Logger, services map[string]string) grpc.StreamServerInterceptor {
+	interceptor := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		e := newEntry(ctx, info.FullMethod, services)
+		if !l.Enabled(e.Service) {
+			return handler(srv, ss)
+		}
+		if u, ok := ctxpkg.ContextGetUser(ctx); ok {
+			e.SetUser(u)
+		}
+
+		err := handler(srv, &countingServerStream{ServerStream: ss, entry: e})
+		finish(l, e, err)
+		return err
+	}
+	return interceptor
+}
+
+func newEntry(ctx context.Context, fullMethod string, services map[string]string) *accesslog.Entry {
+	e := &accesslog.Entry{
+		Time:     time.Now(),
+		Protocol: "grpc",
+		Route:    fullMethod,
+	}
+	// the full method is /package.Service/Method
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		e.Method = fullMethod[i+1:]
+		svc := strings.TrimPrefix(fullMethod[:i], "/")
+		if e.Service = services[svc]; e.Service == "" {
+			e.Service = svc
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		e.Remote = p.Addr.String()
+	}
+	e.UserAgent, _ = ctxpkg.ContextGetUserAgentString(ctx)
+	return e
+}
+
+func finish(l *accesslog.Logger, e *accesslog.Entry, err error) {
+	code := status.Code(err)
+	e.Duration = time.Since(e.Time)
+	e.Status = code.String()
+	e.Failed = code != codes.OK
+	l.Log(e)
+}
+
+// countingServerStream keeps track of the size of the messages sent.
+type countingServerStream struct {
+	grpc.ServerStream
+	entry *accesslog.Entry
+}
+
+func (ss *countingServerStream) SendMsg(m interface{}) error {
+	err := ss.ServerStream.SendMsg(m)
+	if msg, ok := m.(proto.Message); ok && err == nil {
+		ss.entry.Bytes += int64(proto.Size(msg))
+	}
+	return err
+}
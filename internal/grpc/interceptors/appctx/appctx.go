@@ -22,7 +22,9 @@ import (
 	"context"
 
 	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	rtrace "github.com/cs3org/reva/pkg/trace"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
@@ -31,13 +33,17 @@ import (
 // NewUnary returns a new unary interceptor that creates the application context.
 func NewUnary(log zerolog.Logger) grpc.UnaryServerInterceptor {
 	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		// the span is usually started by the tracing interceptor
 		span := trace.SpanFromContext(ctx)
-		defer span.End()
 		if !span.SpanContext().HasTraceID() {
 			ctx, span = rtrace.Provider.Tracer("grpc").Start(ctx, "grpc unary")
+			defer span.End()
 		}
 
-		sub := log.With().Str("traceid", span.SpanContext().TraceID().String()).Logger()
+		reqID := requestID(ctx)
+		ctx = ctxpkg.ContextSetRequestID(ctx, reqID)
+
+		sub := log.With().Str("traceid", span.SpanContext().TraceID().String()).Str("requestid", reqID).Logger()
 		ctx = appctx.WithLogger(ctx, &sub)
 		res, err := handler(ctx, req)
 		return res, err
@@ -51,13 +57,15 @@ func NewStream(log zerolog.Logger) grpc.StreamServerInterceptor {
 	interceptor := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		ctx := ss.Context()
 		span := trace.SpanFromContext(ctx)
-		defer span.End()
-
 		if !span.SpanContext().HasTraceID() {
 			ctx, span = rtrace.Provider.Tracer("grpc").Start(ctx, "grpc stream")
+			defer span.End()
 		}
 
-		sub := log.With().Str("traceid", span.SpanContext().TraceID().String()).Logger()
+		reqID := requestID(ctx)
+		ctx = ctxpkg.ContextSetRequestID(ctx, reqID)
+
+		sub := log.With().Str("traceid", span.SpanContext().TraceID().String()).Str("requestid", reqID).Logger()
 		ctx = appctx.WithLogger(ctx, &sub)
 
 		wrapped := newWrappedServerStream(ctx, ss)
@@ -67,6 +75,15 @@ func NewStream(log zerolog.Logger) grpc.StreamServerInterceptor {
 	return interceptor
 }
 
+// requestID returns the request ID forwarded by the caller, or a new one if the request originates
+// here or the forwarded one is not sane, see ctxpkg.IsValidRequestID.
+func requestID(ctx context.Context) string {
+	if id, ok := ctxpkg.ContextGetRequestID(ctx); ok {
+		return id
+	}
+	return uuid.NewString()
+}
+
 func newWrappedServerStream(ctx context.Context, ss grpc.ServerStream) *wrappedServerStream {
 	return &wrappedServerStream{ServerStream: ss, newCtx: ctx}
 }
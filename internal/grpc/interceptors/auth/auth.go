@@ -20,6 +20,7 @@ package auth
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/bluele/gcache"
@@ -33,6 +34,8 @@ import (
 	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/cs3org/reva/pkg/token"
 	tokenmgr "github.com/cs3org/reva/pkg/token/manager/registry"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	revocationregistry "github.com/cs3org/reva/pkg/token/revocation/registry"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
@@ -50,6 +53,10 @@ type config struct {
 	TokenManager  string                            `mapstructure:"token_manager"`
 	TokenManagers map[string]map[string]interface{} `mapstructure:"token_managers"`
 	GatewayAddr   string                            `mapstructure:"gateway_addr"`
+	// RevocationStore is the store checked for revoked tokens. Revocation checks are disabled if empty.
+	RevocationStore    string                            `mapstructure:"revocation_store"`
+	RevocationStores   map[string]map[string]interface{} `mapstructure:"revocation_stores"`
+	RevocationCacheTTL int                               `mapstructure:"revocation_cache_ttl" docs:"30;Time in seconds the revocation lookups are cached for."`
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -58,6 +65,9 @@ func parseConfig(m map[string]interface{}) (*config, error) {
 		err = errors.Wrap(err, "auth: error decoding conf")
 		return nil, err
 	}
+	if c.RevocationCacheTTL == 0 {
+		c.RevocationCacheTTL = 30
+	}
 	return c, nil
 }
 
@@ -88,6 +98,11 @@ func NewUnary(m map[string]interface{}, unprotected []string) (grpc.UnaryServerI
 		return nil, errors.Wrap(err, "auth: error creating token manager")
 	}
 
+	checker, err := revocationregistry.NewChecker(conf.RevocationStore, conf.RevocationStores, time.Duration(conf.RevocationCacheTTL)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
 	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		log := appctx.GetLogger(ctx)
 
@@ -98,7 +113,7 @@ func NewUnary(m map[string]interface{}, unprotected []string) (grpc.UnaryServerI
 			// to decide the storage provider.
 			tkn, ok := ctxpkg.ContextGetToken(ctx)
 			if ok {
-				u, err := dismantleToken(ctx, tkn, req, tokenManager, conf.GatewayAddr, true)
+				u, err := dismantleToken(ctx, tkn, req, tokenManager, checker, conf.GatewayAddr, true)
 				if err == nil {
 					ctx = ctxpkg.ContextSetUser(ctx, u)
 				}
@@ -114,7 +129,7 @@ func NewUnary(m map[string]interface{}, unprotected []string) (grpc.UnaryServerI
 		}
 
 		// validate the token and ensure access to the resource is allowed
-		u, err := dismantleToken(ctx, tkn, req, tokenManager, conf.GatewayAddr, false)
+		u, err := dismantleToken(ctx, tkn, req, tokenManager, checker, conf.GatewayAddr, false)
 		if err != nil {
 			log.Warn().Err(err).Msg("access token is invalid")
 			return nil, status.Errorf(codes.PermissionDenied, "auth: core access token is invalid")
@@ -151,6 +166,11 @@ func NewStream(m map[string]interface{}, unprotected []string) (grpc.StreamServe
 		return nil, errtypes.NotFound("auth: token manager not found: " + conf.TokenManager)
 	}
 
+	checker, err := revocationregistry.NewChecker(conf.RevocationStore, conf.RevocationStores, time.Duration(conf.RevocationCacheTTL)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
 	interceptor := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		ctx := ss.Context()
 		log := appctx.GetLogger(ctx)
@@ -162,7 +182,7 @@ func NewStream(m map[string]interface{}, unprotected []string) (grpc.StreamServe
 			// to decide the storage provider.
 			tkn, ok := ctxpkg.ContextGetToken(ctx)
 			if ok {
-				u, err := dismantleToken(ctx, tkn, ss, tokenManager, conf.GatewayAddr, true)
+				u, err := dismantleToken(ctx, tkn, ss, tokenManager, checker, conf.GatewayAddr, true)
 				if err == nil {
 					ctx = ctxpkg.ContextSetUser(ctx, u)
 					ss = newWrappedServerStream(ctx, ss)
@@ -180,7 +200,7 @@ func NewStream(m map[string]interface{}, unprotected []string) (grpc.StreamServe
 		}
 
 		// validate the token and ensure access to the resource is allowed
-		u, err := dismantleToken(ctx, tkn, ss, tokenManager, conf.GatewayAddr, false)
+		u, err := dismantleToken(ctx, tkn, ss, tokenManager, checker, conf.GatewayAddr, false)
 		if err != nil {
 			log.Warn().Err(err).Msg("access token is invalid")
 			return status.Errorf(codes.PermissionDenied, "auth: core access token is invalid")
@@ -207,12 +227,22 @@ func (ss *wrappedServerStream) Context() context.Context {
 	return ss.newCtx
 }
 
-func dismantleToken(ctx context.Context, tkn string, req interface{}, mgr token.Manager, gatewayAddr string, unprotected bool) (*userpb.User, error) {
+func dismantleToken(ctx context.Context, tkn string, req interface{}, mgr token.Manager, checker *revocation.Checker, gatewayAddr string, unprotected bool) (*userpb.User, error) {
 	u, tokenScope, err := mgr.DismantleToken(ctx, tkn)
 	if err != nil {
 		return nil, err
 	}
 
+	if checker != nil {
+		revoked, err := checker.IsRevoked(ctx, mgr, tkn, u)
+		if err != nil {
+			return nil, errors.Wrap(err, "auth: error checking token revocation")
+		}
+		if revoked {
+			return nil, errtypes.PermissionDenied("auth: token has been revoked")
+		}
+	}
+
 	if unprotected {
 		return u, nil
 	}
@@ -229,6 +259,12 @@ func dismantleToken(ctx context.Context, tkn string, req interface{}, mgr token.
 		u.Groups = groups
 	}
 
+	// actions taken by admins on behalf of users are marked with both identities
+	if access, ok := scope.GetSupportAccess(tokenScope); ok {
+		appctx.GetLogger(ctx).Info().Str("impersonated_user", u.Id.OpaqueId).Str("impersonator", access.Admin.GetOpaqueId()).
+			Str("support_request", access.RequestID).Str("request", fmt.Sprintf("%T", req)).Msg("audit: request on behalf of user with support access")
+	}
+
 	// Check if access to the resource is in the scope of the token
 	ok, err := scope.VerifyScope(ctx, tokenScope, req)
 	if err != nil {
@@ -0,0 +1,116 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/token/manager/jwt"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	_ "github.com/cs3org/reva/pkg/token/revocation/memory"
+	"github.com/cs3org/reva/pkg/token/revocation/registry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestUnaryRevokedTokens revokes a token in a memory store of its own and checks that the
+// interceptor of the same process rejects it.
+func TestUnaryRevokedTokens(t *testing.T) {
+	const secret = "jwt-secret"
+
+	conf := map[string]interface{}{
+		"gateway_addr":     "127.0.0.1:1",
+		"token_managers":   map[string]interface{}{"jwt": map[string]interface{}{"secret": secret}},
+		"revocation_store": "memory",
+	}
+	unary, err := NewUnary(conf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := NewStream(conf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mgr, err := jwt.New(map[string]interface{}{"secret": secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scopes, err := scope.AddOwnerScope(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mint := func(username string) string {
+		u := &userpb.User{Id: &userpb.UserId{Idp: "https://idp.example.org", OpaqueId: username}, Username: username}
+		tkn, err := mgr.MintToken(context.Background(), u, scopes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tkn
+	}
+	revoked, valid := mint("einstein"), mint("richard")
+
+	store, err := registry.NewStore("memory", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RevokeToken(context.Background(), revocation.TokenID(revoked), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/cs3.gateway.v1beta1.GatewayAPI/Stat"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	req := &provider.StatRequest{Ref: &provider.Reference{Path: "/home"}}
+
+	if _, err := unary(ctxpkg.ContextSetToken(context.Background(), revoked), req, info, handler); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected the revoked token to be rejected, got %v", err)
+	}
+	if res, err := unary(ctxpkg.ContextSetToken(context.Background(), valid), req, info, handler); err != nil || res != "ok" {
+		t.Errorf("expected the token to be accepted, got %v", err)
+	}
+
+	streamInfo := &grpc.StreamServerInfo{FullMethod: "/cs3.gateway.v1beta1.GatewayAPI/Stat"}
+	called := false
+	streamHandler := func(srv interface{}, ss grpc.ServerStream) error {
+		called = true
+		return nil
+	}
+	ss := &fakeServerStream{ctx: ctxpkg.ContextSetToken(context.Background(), revoked)}
+	if err := stream(nil, ss, streamInfo, streamHandler); status.Code(err) != codes.PermissionDenied || called {
+		t.Errorf("expected the revoked token to be rejected by the stream interceptor, got %v", err)
+	}
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ss *fakeServerStream) Context() context.Context {
+	return ss.ctx
+}
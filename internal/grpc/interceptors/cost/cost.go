@@ -0,0 +1,71 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package cost
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/cost"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// storageProviderAPI is the prefix of the methods of the storage providers,
+// each call is counted as a storage operation.
+const storageProviderAPI = "/cs3.storage.provider.v1beta1.ProviderAPI/"
+
+// NewUnary returns a new unary interceptor accounting the resources consumed
+// by the calls, including the size of their messages, to their users and client
+// applications.
+func NewUnary() grpc.UnaryServerInterceptor {
+	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		rec := cost.NewRecord()
+		if strings.HasPrefix(info.FullMethod, storageProviderAPI) {
+			rec.AddStorageOps(1)
+		}
+		if m, ok := req.(proto.Message); ok {
+			rec.AddBytes(int64(proto.Size(m)))
+		}
+
+		start := time.Now()
+		res, err := handler(cost.ContextSetRecord(ctx, rec), req)
+		if m, ok := res.(proto.Message); ok {
+			rec.AddBytes(int64(proto.Size(m)))
+		}
+		ua, _ := ctxpkg.ContextGetUserAgentString(ctx)
+		cost.Account(rec, cost.ClientApp(ua), time.Since(start))
+		return res, err
+	}
+	return interceptor
+}
+
+// NewUserUnary returns a new unary interceptor setting the user the call has
+// been authenticated as in its record. It has to run after the authentication.
+func NewUserUnary() grpc.UnaryServerInterceptor {
+	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if u, ok := ctxpkg.ContextGetUser(ctx); ok {
+			cost.SetUser(ctx, u.Username)
+		}
+		return handler(ctx, req)
+	}
+	return interceptor
+}
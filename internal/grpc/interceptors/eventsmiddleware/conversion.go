@@ -19,13 +19,21 @@
 package eventsmiddleware
 
 import (
+	"context"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/events"
 )
 
 // ShareCreated converts response to event
 func ShareCreated(r *collaboration.CreateShareResponse) events.ShareCreated {
 	e := events.ShareCreated{
+		ShareID:        r.Share.Id,
 		Sharer:         r.Share.Creator,
 		GranteeUserID:  r.Share.GetGrantee().GetUserId(),
 		GranteeGroupID: r.Share.GetGrantee().GetGroupId(),
@@ -35,3 +43,122 @@ func ShareCreated(r *collaboration.CreateShareResponse) events.ShareCreated {
 
 	return e
 }
+
+// ShareRemoved converts request to event
+func ShareRemoved(ctx context.Context, r *collaboration.RemoveShareRequest) events.ShareRemoved {
+	return events.ShareRemoved{
+		Executant: executant(ctx),
+		ShareID:   r.GetRef().GetId(),
+		ShareKey:  r.GetRef().GetKey(),
+	}
+}
+
+// ReceivedShareUpdated converts request to event
+func ReceivedShareUpdated(ctx context.Context, r *collaboration.UpdateReceivedShareRequest) events.ReceivedShareUpdated {
+	return events.ReceivedShareUpdated{
+		Executant: executant(ctx),
+		ShareID:   r.GetShare().GetShare().GetId(),
+		State:     r.GetShare().GetState(),
+	}
+}
+
+// ReceivedOCMShareUpdated converts request to event
+func ReceivedOCMShareUpdated(ctx context.Context, r *ocm.UpdateReceivedOCMShareRequest) events.ReceivedOCMShareUpdated {
+	return events.ReceivedOCMShareUpdated{
+		Executant: executant(ctx),
+		ShareID:   r.GetShare().GetShare().GetId(),
+		State:     r.GetShare().GetState(),
+	}
+}
+
+// SpaceCreated converts response to event
+func SpaceCreated(ctx context.Context, r *provider.CreateStorageSpaceResponse) events.SpaceCreated {
+	return events.SpaceCreated{
+		Executant: executant(ctx),
+		ID:        r.GetStorageSpace().GetId(),
+		Type:      r.GetStorageSpace().GetSpaceType(),
+		Name:      r.GetStorageSpace().GetName(),
+	}
+}
+
+// ContainerCreated converts request to event
+func ContainerCreated(ctx context.Context, r *provider.CreateContainerRequest) events.ContainerCreated {
+	return events.ContainerCreated{
+		Executant: executant(ctx),
+		Ref:       r.Ref,
+	}
+}
+
+// FileTouched converts request to event
+func FileTouched(ctx context.Context, r *provider.TouchFileRequest) events.FileTouched {
+	return events.FileTouched{
+		Executant: executant(ctx),
+		Ref:       r.Ref,
+	}
+}
+
+// ItemTrashed converts request to event
+func ItemTrashed(ctx context.Context, r *provider.DeleteRequest) events.ItemTrashed {
+	return events.ItemTrashed{
+		Executant: executant(ctx),
+		Ref:       r.Ref,
+	}
+}
+
+// ItemMoved converts request to event
+func ItemMoved(ctx context.Context, r *provider.MoveRequest) events.ItemMoved {
+	return events.ItemMoved{
+		Executant:    executant(ctx),
+		Ref:          r.Destination,
+		OldReference: r.Source,
+	}
+}
+
+// ItemPurged converts request to event
+func ItemPurged(ctx context.Context, r *provider.PurgeRecycleRequest) events.ItemPurged {
+	return events.ItemPurged{
+		Executant: executant(ctx),
+		Ref:       r.Ref,
+		Key:       r.Key,
+	}
+}
+
+// LinkCreated converts response to event
+func LinkCreated(r *link.CreatePublicShareResponse) events.LinkCreated {
+	return events.LinkCreated{
+		ShareID:           r.GetShare().GetId(),
+		Sharer:            r.GetShare().GetCreator(),
+		ItemID:            r.GetShare().GetResourceId(),
+		Permissions:       r.GetShare().GetPermissions(),
+		DisplayName:       r.GetShare().GetDisplayName(),
+		Expiration:        r.GetShare().GetExpiration(),
+		PasswordProtected: r.GetShare().GetPasswordProtected(),
+		CTime:             r.GetShare().GetCtime(),
+	}
+}
+
+// LinkRemoved converts request to event
+func LinkRemoved(ctx context.Context, r *link.RemovePublicShareRequest) events.LinkRemoved {
+	return events.LinkRemoved{
+		Executant:  executant(ctx),
+		ShareID:    r.GetRef().GetId(),
+		ShareToken: r.GetRef().GetToken(),
+	}
+}
+
+// LinkAccessed converts response to event
+func LinkAccessed(r *link.GetPublicShareByTokenResponse) events.LinkAccessed {
+	return events.LinkAccessed{
+		ShareID:    r.GetShare().GetId(),
+		Sharer:     r.GetShare().GetCreator(),
+		ItemID:     r.GetShare().GetResourceId(),
+		ShareToken: r.GetShare().GetToken(),
+	}
+}
+
+func executant(ctx context.Context) *user.UserId {
+	if u, ok := ctxpkg.ContextGetUser(ctx); ok {
+		return u.Id
+	}
+	return nil
+}
@@ -20,13 +20,17 @@ package eventsmiddleware
 
 import (
 	"context"
-	"fmt"
 
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
 	"go-micro.dev/v4/util/log"
 	"google.golang.org/grpc"
 
-	"github.com/asim/go-micro/plugins/events/nats/v4"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/events"
 	"github.com/cs3org/reva/pkg/events/server"
 	"github.com/cs3org/reva/pkg/rgrpc"
@@ -59,6 +63,54 @@ func NewUnary(m map[string]interface{}) (grpc.UnaryServerInterceptor, int, error
 		switch v := res.(type) {
 		case *collaboration.CreateShareResponse:
 			ev = ShareCreated(v)
+		case *collaboration.RemoveShareResponse:
+			if isSuccess(v) {
+				ev = ShareRemoved(ctx, req.(*collaboration.RemoveShareRequest))
+			}
+		case *collaboration.UpdateReceivedShareResponse:
+			if isSuccess(v) {
+				ev = ReceivedShareUpdated(ctx, req.(*collaboration.UpdateReceivedShareRequest))
+			}
+		case *ocm.UpdateReceivedOCMShareResponse:
+			if isSuccess(v) {
+				ev = ReceivedOCMShareUpdated(ctx, req.(*ocm.UpdateReceivedOCMShareRequest))
+			}
+		case *provider.CreateStorageSpaceResponse:
+			if isSuccess(v) {
+				ev = SpaceCreated(ctx, v)
+			}
+		case *provider.CreateContainerResponse:
+			if isSuccess(v) {
+				ev = ContainerCreated(ctx, req.(*provider.CreateContainerRequest))
+			}
+		case *provider.TouchFileResponse:
+			if isSuccess(v) {
+				ev = FileTouched(ctx, req.(*provider.TouchFileRequest))
+			}
+		case *provider.DeleteResponse:
+			if isSuccess(v) {
+				ev = ItemTrashed(ctx, req.(*provider.DeleteRequest))
+			}
+		case *provider.MoveResponse:
+			if isSuccess(v) {
+				ev = ItemMoved(ctx, req.(*provider.MoveRequest))
+			}
+		case *provider.PurgeRecycleResponse:
+			if isSuccess(v) {
+				ev = ItemPurged(ctx, req.(*provider.PurgeRecycleRequest))
+			}
+		case *link.CreatePublicShareResponse:
+			if isSuccess(v) {
+				ev = LinkCreated(v)
+			}
+		case *link.RemovePublicShareResponse:
+			if isSuccess(v) {
+				ev = LinkRemoved(ctx, req.(*link.RemovePublicShareRequest))
+			}
+		case *link.GetPublicShareByTokenResponse:
+			if isSuccess(v) {
+				ev = LinkAccessed(v)
+			}
 		}
 
 		if ev != nil {
@@ -72,6 +124,14 @@ func NewUnary(m map[string]interface{}) (grpc.UnaryServerInterceptor, int, error
 	return interceptor, defaultPriority, nil
 }
 
+type su interface {
+	GetStatus() *rpc.Status
+}
+
+func isSuccess(res su) bool {
+	return res.GetStatus().Code == rpc.Code_CODE_OK
+}
+
 // NewStream returns a new server stream interceptor
 // that creates the application context.
 func NewStream() grpc.StreamServerInterceptor {
@@ -83,13 +143,13 @@ func NewStream() grpc.StreamServerInterceptor {
 }
 
 func publisherFromConfig(m map[string]interface{}) (events.Publisher, error) {
-	typ := m["type"].(string)
-	switch typ {
-	default:
-		return nil, fmt.Errorf("stream type '%s' not supported", typ)
-	case "nats":
-		address := m["address"].(string)
-		cid := m["clusterID"].(string)
-		return server.NewNatsStream(nats.Address(address), nats.ClusterID(cid))
+	c := &server.Config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "eventsmiddleware: error decoding config")
+	}
+	// clusterID was the name of the setting before the broker became pluggable
+	if cid, ok := m["clusterID"].(string); ok && c.ClusterID == "" {
+		c.ClusterID = cid
 	}
+	return server.NewStream(c)
 }
@@ -0,0 +1,85 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package profiling
+
+import (
+	"context"
+
+	"github.com/cs3org/reva/pkg/profiling"
+	"github.com/cs3org/reva/pkg/rgrpc"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+const (
+	// the labels have to be set before the other interceptors run to
+	// attribute their work to the methods as well
+	defaultPriority = 100
+)
+
+func init() {
+	rgrpc.RegisterUnaryInterceptor("profiling", NewUnary)
+	rgrpc.RegisterStreamInterceptor("profiling", NewStream)
+}
+
+type config struct {
+	Priority int `mapstructure:"priority"`
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, errors.Wrap(err, "profiling: error decoding configuration")
+	}
+	if conf.Priority == 0 {
+		conf.Priority = defaultPriority
+	}
+	return conf, nil
+}
+
+// NewUnary returns a new unary interceptor labelling the profile samples
+// with the method being served.
+func NewUnary(m map[string]interface{}) (grpc.UnaryServerInterceptor, int, error) {
+	conf, err := parseConfig(m)
+	if err != nil {
+		return nil, 0, err
+	}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res interface{}, err error) {
+		profiling.Do(ctx, info.FullMethod, func(ctx context.Context) {
+			res, err = handler(ctx, req)
+		})
+		return res, err
+	}, conf.Priority, nil
+}
+
+// NewStream returns a new stream interceptor labelling the profile samples
+// with the method being served.
+func NewStream(m map[string]interface{}) (grpc.StreamServerInterceptor, int, error) {
+	conf, err := parseConfig(m)
+	if err != nil {
+		return nil, 0, err
+	}
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		profiling.Do(ss.Context(), info.FullMethod, func(context.Context) {
+			err = handler(srv, ss)
+		})
+		return err
+	}, conf.Priority, nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package slo
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/slo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type statusResponse interface {
+	GetStatus() *rpc.Status
+}
+
+// NewUnary returns a new unary interceptor counting the calls in the service
+// level indicators of the services serving them. services maps the gRPC
+// services to the names of the services implementing them, it can be filled
+// once the services have been registered. Streams are not counted, as their
+// duration says nothing about the latency.
+func NewUnary(services map[string]string) grpc.UnaryServerInterceptor {
+	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		res, err := handler(ctx, req)
+		slo.Record(service(info.FullMethod, services), failed(res, err), time.Since(start))
+		return res, err
+	}
+	return interceptor
+}
+
+func service(fullMethod string, services map[string]string) string {
+	// the full method is /package.Service/Method
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return services[strings.TrimPrefix(fullMethod[:i], "/")]
+	}
+	return ""
+}
+
+// failed returns whether the call failed because of the server, either with a
+// gRPC error or with a CS3 status in the response.
+func failed(res interface{}, err error) bool {
+	if err != nil {
+		switch status.Code(err) {
+		case codes.Unknown, codes.DeadlineExceeded, codes.Internal, codes.Unavailable, codes.DataLoss:
+			return true
+		}
+		return false
+	}
+	if r, ok := res.(statusResponse); ok && r.GetStatus() != nil {
+		switch r.GetStatus().Code {
+		case rpc.Code_CODE_INTERNAL, rpc.Code_CODE_UNAVAILABLE:
+			return true
+		}
+	}
+	return false
+}
@@ -39,6 +39,15 @@ func NewUnary() grpc.UnaryServerInterceptor {
 					ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.TokenHeader, tkn)
 				}
 			}
+			// the gateway forwards the folder password of public links to
+			// the public storage provider
+			if val, ok := md[ctxpkg.FolderPasswordHeader]; ok && len(val) > 0 {
+				ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.FolderPasswordHeader, val[0])
+			}
+			// as well as the lock id to the storage provider enforcing the lock
+			if val, ok := md[ctxpkg.LockIDHeader]; ok && len(val) > 0 {
+				ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.LockIDHeader, val[0])
+			}
 		}
 
 		return handler(ctx, req)
@@ -61,6 +70,15 @@ func NewStream() grpc.StreamServerInterceptor {
 					ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.TokenHeader, tkn)
 				}
 			}
+			// the gateway forwards the folder password of public links to
+			// the public storage provider
+			if val, ok := md[ctxpkg.FolderPasswordHeader]; ok && len(val) > 0 {
+				ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.FolderPasswordHeader, val[0])
+			}
+			// as well as the lock id to the storage provider enforcing the lock
+			if val, ok := md[ctxpkg.LockIDHeader]; ok && len(val) > 0 {
+				ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.LockIDHeader, val[0])
+			}
 		}
 
 		wrapped := newWrappedServerStream(ctx, ss)
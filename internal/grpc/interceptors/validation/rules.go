@@ -0,0 +1,198 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package validation
+
+import (
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	referenceType   protoreflect.FullName = "cs3.storage.provider.v1beta1.Reference"
+	permissionsType protoreflect.FullName = "cs3.storage.provider.v1beta1.ResourcePermissions"
+)
+
+// requiredReferences are the request fields which must always hold a reference if present in a request.
+var requiredReferences = []protoreflect.Name{"ref", "source", "destination"}
+
+type validator struct {
+	checkPermissions bool
+	violations       []*errdetails.BadRequest_FieldViolation
+}
+
+func (v *validator) addViolation(field, description string) {
+	v.violations = append(v.violations, &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: description,
+	})
+}
+
+func (v *validator) validateRequest(msg proto.Message) {
+	m := msg.ProtoReflect()
+
+	fields := m.Descriptor().Fields()
+	for _, name := range requiredReferences {
+		fd := fields.ByName(name)
+		if fd != nil && fd.Message() != nil && fd.Message().FullName() == referenceType && !m.Has(fd) {
+			v.addViolation(string(name), "a reference is required")
+		}
+	}
+
+	v.walk(m, "")
+}
+
+// walk validates all populated fields of the message recursively.
+func (v *validator) walk(m protoreflect.Message, prefix string) {
+	switch m.Descriptor().FullName() {
+	case referenceType:
+		v.validateReference(m, prefix)
+	case permissionsType:
+		if v.checkPermissions && strings.HasSuffix(prefix, "permissions") {
+			v.validatePermissions(m, prefix)
+		}
+	}
+
+	m.Range(func(fd protoreflect.FieldDescriptor, val protoreflect.Value) bool {
+		field := fieldPath(prefix, string(fd.Name()))
+		switch {
+		case fd.IsList():
+			list := val.List()
+			for i := 0; i < list.Len(); i++ {
+				v.walkValue(fd, list.Get(i), field)
+			}
+		case fd.IsMap():
+			val.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+				if s, ok := k.Interface().(string); ok && !utf8.ValidString(s) {
+					v.addViolation(field, "map key is not valid UTF-8")
+				}
+				v.walkValue(fd.MapValue(), mv, field)
+				return true
+			})
+		default:
+			v.walkValue(fd, val, field)
+		}
+		return true
+	})
+}
+
+func (v *validator) walkValue(fd protoreflect.FieldDescriptor, val protoreflect.Value, field string) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		if !utf8.ValidString(val.String()) {
+			v.addViolation(field, "value is not valid UTF-8")
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		v.walk(val.Message(), field)
+	}
+}
+
+func (v *validator) validateReference(m protoreflect.Message, field string) {
+	fields := m.Descriptor().Fields()
+	pathField := fields.ByName("path")
+	idField := fields.ByName("resource_id")
+
+	hasID := idField != nil && m.Has(idField) && hasNonEmptyString(m.Get(idField).Message())
+	p := ""
+	if pathField != nil {
+		p = m.Get(pathField).String()
+	}
+
+	if !hasID && p == "" {
+		v.addViolation(field, "the reference must contain a resource id or a path")
+		return
+	}
+	if p == "" {
+		return
+	}
+
+	if strings.ContainsRune(p, 0) {
+		v.addViolation(fieldPath(field, "path"), "the path must not contain NUL characters")
+		return
+	}
+	for _, segment := range strings.Split(p, "/") {
+		if segment == ".." {
+			v.addViolation(fieldPath(field, "path"), "path traversal is not allowed")
+			return
+		}
+	}
+
+	if normalized := normalizePath(p); normalized != p {
+		m.Set(pathField, protoreflect.ValueOfString(normalized))
+	}
+}
+
+// validatePermissions rejects permission combinations which can't be used in a meaningful way.
+func (v *validator) validatePermissions(m protoreflect.Message, field string) {
+	has := func(name protoreflect.Name) bool {
+		fd := m.Descriptor().Fields().ByName(name)
+		return fd != nil && m.Get(fd).Bool()
+	}
+
+	requirements := []struct {
+		permissions []protoreflect.Name
+		requires    protoreflect.Name
+	}{
+		{[]protoreflect.Name{"add_grant", "update_grant", "remove_grant"}, "list_grants"},
+		{[]protoreflect.Name{"restore_file_version"}, "list_file_versions"},
+		{[]protoreflect.Name{"restore_recycle_item", "purge_recycle"}, "list_recycle"},
+	}
+	for _, r := range requirements {
+		if has(r.requires) {
+			continue
+		}
+		for _, p := range r.permissions {
+			if has(p) {
+				v.addViolation(field, string(p)+" requires "+string(r.requires))
+			}
+		}
+	}
+}
+
+// normalizePath cleans the path while keeping the ./ prefix of relative references.
+func normalizePath(p string) string {
+	cleaned := path.Clean(p)
+	if strings.HasPrefix(p, "./") && cleaned != "." && !strings.HasPrefix(cleaned, "./") {
+		cleaned = "./" + cleaned
+	}
+	return cleaned
+}
+
+func hasNonEmptyString(m protoreflect.Message) bool {
+	found := false
+	m.Range(func(fd protoreflect.FieldDescriptor, val protoreflect.Value) bool {
+		if fd.Kind() == protoreflect.StringKind && val.String() != "" {
+			found = true
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+func fieldPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,130 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package validation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rgrpc"
+	rstatus "github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const (
+	defaultPriority = 200
+)
+
+func init() {
+	rgrpc.RegisterUnaryInterceptor("validation", NewUnary)
+}
+
+type config struct {
+	Priority int `mapstructure:"priority"`
+	// SkipPermissionChecks disables checking the permission combinations of grants.
+	SkipPermissionChecks bool `mapstructure:"skip_permission_checks"`
+}
+
+// NewUnary returns a new unary interceptor that validates the incoming CS3 requests
+// and rejects malformed ones before they reach the service handlers.
+func NewUnary(m map[string]interface{}) (grpc.UnaryServerInterceptor, int, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, 0, errors.Wrap(err, "validation: error decoding configuration")
+	}
+	if conf.Priority == 0 {
+		conf.Priority = defaultPriority
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		msg, ok := req.(proto.Message)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		v := &validator{checkPermissions: !conf.SkipPermissionChecks}
+		v.validateRequest(msg)
+		if len(v.violations) == 0 {
+			return handler(ctx, req)
+		}
+
+		appctx.GetLogger(ctx).Debug().Str("method", info.FullMethod).Interface("violations", v.violations).Msg("validation: rejecting invalid request")
+		return invalidResponse(ctx, info.FullMethod, v.violations)
+	}, conf.Priority, nil
+}
+
+// invalidResponse creates the response message of the called method with an invalid argument status.
+// If the method does not return a CS3 status, a gRPC error carrying the violations is returned instead.
+func invalidResponse(ctx context.Context, fullMethod string, violations []*errdetails.BadRequest_FieldViolation) (interface{}, error) {
+	msgs := make([]string, 0, len(violations))
+	for _, v := range violations {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", v.Field, v.Description))
+	}
+	msg := "invalid argument: " + strings.Join(msgs, "; ")
+
+	if res := newResponse(fullMethod); res != nil {
+		fd := res.Descriptor().Fields().ByName("status")
+		if fd != nil && fd.Message() != nil && fd.Message().FullName() == (&rpc.Status{}).ProtoReflect().Descriptor().FullName() {
+			res.Set(fd, protoreflect.ValueOfMessage(rstatus.NewInvalid(ctx, msg).ProtoReflect()))
+			return res.Interface(), nil
+		}
+	}
+
+	st, err := status.New(codes.InvalidArgument, msg).WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, msg)
+	}
+	return nil, st.Err()
+}
+
+func newResponse(fullMethod string) protoreflect.Message {
+	// The full method has the form /package.Service/Method
+	parts := strings.Split(strings.TrimPrefix(fullMethod, "/"), "/")
+	if len(parts) != 2 {
+		return nil
+	}
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(parts[0]))
+	if err != nil {
+		return nil
+	}
+	svc, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil
+	}
+	method := svc.Methods().ByName(protoreflect.Name(parts[1]))
+	if method == nil {
+		return nil
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(method.Output().FullName())
+	if err != nil {
+		return nil
+	}
+	return mt.New()
+}
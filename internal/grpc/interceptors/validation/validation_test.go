@@ -0,0 +1,92 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package validation
+
+import (
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+func TestValidateReferences(t *testing.T) {
+	tests := map[string]struct {
+		req          *provider.StatRequest
+		violations   int
+		expectedPath string
+	}{
+		"missing_ref":     {req: &provider.StatRequest{}, violations: 1},
+		"empty_ref":       {req: &provider.StatRequest{Ref: &provider.Reference{}}, violations: 1},
+		"resource_id":     {req: &provider.StatRequest{Ref: &provider.Reference{ResourceId: &provider.ResourceId{StorageId: "s", OpaqueId: "o"}}}},
+		"absolute_path":   {req: &provider.StatRequest{Ref: &provider.Reference{Path: "/home//a/./b/"}}, expectedPath: "/home/a/b"},
+		"relative_path":   {req: &provider.StatRequest{Ref: &provider.Reference{ResourceId: &provider.ResourceId{StorageId: "s"}, Path: "./a//b"}}, expectedPath: "./a/b"},
+		"relative_root":   {req: &provider.StatRequest{Ref: &provider.Reference{ResourceId: &provider.ResourceId{StorageId: "s"}, Path: "."}}, expectedPath: "."},
+		"path_traversal":  {req: &provider.StatRequest{Ref: &provider.Reference{Path: "/home/../etc"}}, violations: 1, expectedPath: "/home/../etc"},
+		"nul_character":   {req: &provider.StatRequest{Ref: &provider.Reference{Path: "/home/a\x00b"}}, violations: 1, expectedPath: "/home/a\x00b"},
+		"invalid_utf8":    {req: &provider.StatRequest{Ref: &provider.Reference{Path: "/home/\xff"}}, violations: 1, expectedPath: "/home/\xff"},
+		"arbitrary_field": {req: &provider.StatRequest{Ref: &provider.Reference{Path: "/home"}, ArbitraryMetadataKeys: []string{"\xff"}}, violations: 1, expectedPath: "/home"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := &validator{checkPermissions: true}
+			v.validateRequest(tt.req)
+			if len(v.violations) != tt.violations {
+				t.Fatalf("expected %d violations, got %v", tt.violations, v.violations)
+			}
+			if tt.expectedPath != "" && tt.req.Ref.Path != tt.expectedPath {
+				t.Errorf("expected path %q, got %q", tt.expectedPath, tt.req.Ref.Path)
+			}
+		})
+	}
+}
+
+func TestValidatePermissions(t *testing.T) {
+	valid := &provider.AddGrantRequest{
+		Ref: &provider.Reference{Path: "/home/a"},
+		Grant: &provider.Grant{Permissions: &provider.ResourcePermissions{
+			Stat: true, ListGrants: true, AddGrant: true, UpdateGrant: true, RemoveGrant: true,
+		}},
+	}
+	v := &validator{checkPermissions: true}
+	v.validateRequest(valid)
+	if len(v.violations) != 0 {
+		t.Fatalf("expected no violations, got %v", v.violations)
+	}
+
+	invalid := &provider.AddGrantRequest{
+		Ref: &provider.Reference{Path: "/home/a"},
+		Grant: &provider.Grant{Permissions: &provider.ResourcePermissions{
+			Stat: true, AddGrant: true, PurgeRecycle: true,
+		}},
+	}
+	v = &validator{checkPermissions: true}
+	v.validateRequest(invalid)
+	if len(v.violations) != 2 {
+		t.Fatalf("expected 2 violations, got %v", v.violations)
+	}
+	if v.violations[0].Field != "grant.permissions" {
+		t.Errorf("unexpected field %q", v.violations[0].Field)
+	}
+
+	v = &validator{checkPermissions: false}
+	v.validateRequest(invalid)
+	if len(v.violations) != 0 {
+		t.Fatalf("expected permission checks to be skipped, got %v", v.violations)
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/cs3org/reva/pkg/auth/manager/registry"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/plugin"
+	"github.com/cs3org/reva/pkg/readiness"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/mitchellh/mapstructure"
@@ -43,6 +44,7 @@ func init() {
 type config struct {
 	AuthManager  string                            `mapstructure:"auth_manager"`
 	AuthManagers map[string]map[string]interface{} `mapstructure:"auth_managers"`
+	Warmup       readiness.Config                  `mapstructure:"warmup" docs:"url:pkg/readiness/readiness.go"`
 }
 
 func (c *config) init() {
@@ -55,6 +57,7 @@ type service struct {
 	authmgr auth.Manager
 	conf    *config
 	plugin  *plugin.RevaPlugin
+	warmup  *readiness.Initializer
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -112,10 +115,23 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 		plugin:  plug,
 	}
 
+	if c.Warmup.Enabled() {
+		if svc.warmup, err = readiness.NewInitializer("authprovider:"+c.AuthManager, &c.Warmup, readiness.Check(authManager)); err != nil {
+			return nil, err
+		}
+		if err := svc.warmup.Start(); err != nil {
+			svc.warmup.Close()
+			return nil, err
+		}
+	}
+
 	return svc, nil
 }
 
 func (s *service) Close() error {
+	if s.warmup != nil {
+		s.warmup.Close()
+	}
 	if s.plugin != nil {
 		s.plugin.Kill()
 	}
@@ -135,6 +151,14 @@ func (s *service) Authenticate(ctx context.Context, req *provider.AuthenticateRe
 	username := req.ClientId
 	password := req.ClientSecret
 
+	if s.warmup != nil {
+		if err := s.warmup.Ensure(ctx); err != nil {
+			return &provider.AuthenticateResponse{
+				Status: status.NewUnavailable(ctx, err.Error()),
+			}, nil
+		}
+	}
+
 	u, scope, err := s.authmgr.Authenticate(ctx, username, password)
 	switch v := err.(type) {
 	case nil:
@@ -32,7 +32,10 @@ import (
 	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	txdriver "github.com/cs3org/reva/pkg/datatx"
 	txregistry "github.com/cs3org/reva/pkg/datatx/manager/registry"
+	"github.com/cs3org/reva/pkg/datatx/scheduler"
+	schedulerpb "github.com/cs3org/reva/pkg/datatx/scheduler/proto"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/logger"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/mitchellh/mapstructure"
@@ -53,12 +56,15 @@ type config struct {
 	StorageDrivers      map[string]map[string]interface{} `mapstructure:"storage_drivers"`
 	TxSharesFile        string                            `mapstructure:"tx_shares_file"`
 	DataTransfersFolder string                            `mapstructure:"data_transfers_folder"`
+	// scheduling of the transfers with the remote providers
+	Scheduler scheduler.Config `mapstructure:"scheduler" docs:"url:pkg/datatx/scheduler/scheduler.go"`
 }
 
 type service struct {
 	conf          *config
 	txManager     txdriver.Manager
 	txShareDriver *txShareDriver
+	scheduler     *scheduler.Scheduler
 }
 
 type txShareDriver struct {
@@ -98,6 +104,7 @@ func (c *config) init() {
 
 func (s *service) Register(ss *grpc.Server) {
 	datatx.RegisterTxAPIServer(ss, s)
+	schedulerpb.RegisterTransferQueueServiceServer(ss, s)
 }
 
 func getDatatxManager(c *config) (txdriver.Manager, error) {
@@ -145,10 +152,23 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 		txShareDriver: txShareDriver,
 	}
 
+	if c.Scheduler.Enabled() {
+		log := logger.New().With().Str("service", "datatx").Logger()
+		sched, err := scheduler.New(txManager, &c.Scheduler, &log)
+		if err != nil {
+			return nil, errors.Wrap(err, "datatx service: error creating the transfer scheduler")
+		}
+		service.scheduler = sched
+		service.txManager = sched
+	}
+
 	return service, nil
 }
 
 func (s *service) Close() error {
+	if s.scheduler != nil {
+		s.scheduler.Close()
+	}
 	return nil
 }
 
@@ -308,6 +328,21 @@ func (s *service) RetryTransfer(ctx context.Context, req *datatx.RetryTransferRe
 	}, nil
 }
 
+func (s *service) GetTransferQueue(ctx context.Context, req *schedulerpb.GetTransferQueueRequest) (*schedulerpb.GetTransferQueueResponse, error) {
+	if s.scheduler == nil {
+		return &schedulerpb.GetTransferQueueResponse{
+			Status: status.NewUnimplemented(ctx, nil, "datatx service: transfer scheduling is not enabled"),
+		}, nil
+	}
+
+	remotes, transfers := s.scheduler.Queue(req.Remote)
+	return &schedulerpb.GetTransferQueueResponse{
+		Status:    status.NewOK(ctx),
+		Remotes:   remotes,
+		Transfers: transfers,
+	}, nil
+}
+
 func (s *service) extractEndpointInfo(ctx context.Context, targetURL string) (*webdavEndpoint, error) {
 	if targetURL == "" {
 		return nil, errtypes.BadRequest("datatx service: ref target is an empty uri")
@@ -29,6 +29,7 @@ import (
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/auth/scope"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
@@ -126,6 +127,13 @@ func (s *svc) Authenticate(ctx context.Context, req *gateway.AuthenticateRequest
 		}, nil
 	}
 	*/
+	// the endpoints only accepting app passwords tell the sessions started with them apart by this
+	res.TokenScope, err = scope.AddCredentialScope(req.Type, res.TokenScope)
+	if err != nil {
+		return &gateway.AuthenticateResponse{
+			Status: status.NewInternal(ctx, err, "error adding the credential scope"),
+		}, nil
+	}
 	scope := res.TokenScope
 
 	token, err = s.tokenmgr.MintToken(ctx, &u, scope)
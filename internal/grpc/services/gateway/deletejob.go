@@ -0,0 +1,86 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+	"strings"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	deletejobpb "github.com/cs3org/reva/pkg/deletejob/proto"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/pkg/errors"
+)
+
+// GetDeleteJob returns the progress of a background deletion.
+func (s *svc) GetDeleteJob(ctx context.Context, req *deletejobpb.GetDeleteJobRequest) (*deletejobpb.GetDeleteJobResponse, error) {
+	c, err := s.findDeleteJobService(ctx, req.Id)
+	if err != nil {
+		return &deletejobpb.GetDeleteJobResponse{
+			Status: status.NewStatusFromErrType(ctx, "GetDeleteJob id="+req.Id, err),
+		}, nil
+	}
+
+	res, err := c.GetDeleteJob(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error calling GetDeleteJob")
+	}
+	return res, nil
+}
+
+// CancelDeleteJob stops a background deletion.
+func (s *svc) CancelDeleteJob(ctx context.Context, req *deletejobpb.CancelDeleteJobRequest) (*deletejobpb.CancelDeleteJobResponse, error) {
+	c, err := s.findDeleteJobService(ctx, req.Id)
+	if err != nil {
+		return &deletejobpb.CancelDeleteJobResponse{
+			Status: status.NewStatusFromErrType(ctx, "CancelDeleteJob id="+req.Id, err),
+		}, nil
+	}
+
+	res, err := c.CancelDeleteJob(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error calling CancelDeleteJob")
+	}
+	return res, nil
+}
+
+// findDeleteJobService returns the storage provider running a job, whose id
+// is prefixed with the id of the storage the deleted tree belongs to.
+func (s *svc) findDeleteJobService(ctx context.Context, id string) (deletejobpb.DeleteJobServiceClient, error) {
+	i := strings.LastIndex(id, ":")
+	if i < 0 {
+		return nil, errtypes.NotFound("delete job " + id)
+	}
+	storageID := id[:i]
+
+	providers, err := s.findProviders(ctx, &provider.Reference{
+		ResourceId: &provider.ResourceId{StorageId: storageID, OpaqueId: storageID},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := pool.GetDeleteJobServiceClient(pool.Endpoint(providers[0].Address))
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error getting a delete job service client")
+	}
+	return c, nil
+}
@@ -0,0 +1,340 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// dryRunMaxItems limits the number of items counted for a dry-run, so that
+// evaluating an operation on a large tree stays cheap.
+const dryRunMaxItems = 10000
+
+// dryRunReport describes what a destructive operation would do if it was executed.
+// It is returned in the opaque of the response to a dry-run request.
+type dryRunReport struct {
+	AffectedItems int             `json:"affected_items"`
+	Truncated     bool            `json:"truncated,omitempty"`
+	Permissions   map[string]bool `json:"permissions"`
+}
+
+func newDryRunReport() *dryRunReport {
+	return &dryRunReport{Permissions: map[string]bool{}}
+}
+
+func (r *dryRunReport) status(ctx context.Context, op string) *rpc.Status {
+	missing := []string{}
+	for perm, granted := range r.Permissions {
+		if !granted {
+			missing = append(missing, perm)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return status.NewPermissionDenied(ctx, nil, "dry-run: "+op+" would be denied, missing permissions: "+strings.Join(missing, ", "))
+	}
+	return status.NewOK(ctx)
+}
+
+func (r *dryRunReport) opaque() *types.Opaque {
+	data, _ := json.Marshal(r)
+	return &types.Opaque{
+		Map: map[string]*types.OpaqueEntry{
+			"dry_run_report": {
+				Decoder: "json",
+				Value:   data,
+			},
+		},
+	}
+}
+
+// markDryRun flags the opaque of the response to a dry-run request, failed or not, so
+// that the clients can tell that the gateway only evaluated the operation.
+func markDryRun(o *types.Opaque) *types.Opaque {
+	if o == nil {
+		o = &types.Opaque{}
+	}
+	if o.Map == nil {
+		o.Map = map[string]*types.OpaqueEntry{}
+	}
+	o.Map["dry_run"] = &types.OpaqueEntry{
+		Decoder: "plain",
+		Value:   []byte("true"),
+	}
+	return o
+}
+
+// acknowledgeDryRun lets the client of a dry-run request know that the gateway evaluates
+// such requests, so that it can make sure of it with a request which has no effect before
+// sending a destructive one: older gateways ignore the flag and execute the operation.
+func acknowledgeDryRun(ctx context.Context) {
+	if ctxpkg.ContextGetDryRun(ctx) {
+		_ = grpc.SetHeader(ctx, metadata.Pairs(ctxpkg.DryRunSupportedHeader, "true"))
+	}
+}
+
+// countItems counts the resource and, if it is a container, all of its descendants.
+func (s *svc) countItems(ctx context.Context, info *provider.ResourceInfo, report *dryRunReport) *rpc.Status {
+	report.AffectedItems++
+
+	pending := []string{}
+	if info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		pending = append(pending, info.Path)
+	}
+
+	for len(pending) > 0 {
+		p := pending[0]
+		pending = pending[1:]
+
+		res, err := s.ListContainer(ctx, &provider.ListContainerRequest{Ref: &provider.Reference{Path: p}})
+		if err != nil {
+			return status.NewInternal(ctx, err, "gateway: error listing container "+p)
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return res.Status
+		}
+
+		for _, child := range res.Infos {
+			if report.AffectedItems >= dryRunMaxItems {
+				report.Truncated = true
+				return nil
+			}
+			report.AffectedItems++
+
+			if child.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+				pending = append(pending, child.Path)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *svc) dryRunDelete(ctx context.Context, req *provider.DeleteRequest) (res *provider.DeleteResponse, err error) {
+	defer func() {
+		if res != nil {
+			res.Opaque = markDryRun(res.Opaque)
+		}
+	}()
+
+	statRes, err := s.Stat(ctx, &provider.StatRequest{Ref: req.Ref})
+	if err != nil {
+		return nil, err
+	}
+	if statRes.Status.Code != rpc.Code_CODE_OK {
+		return &provider.DeleteResponse{Status: statRes.Status}, nil
+	}
+
+	report := newDryRunReport()
+	report.Permissions["delete"] = statRes.Info.PermissionSet.GetDelete()
+	if st := s.countItems(ctx, statRes.Info, report); st != nil {
+		return &provider.DeleteResponse{Status: st}, nil
+	}
+
+	return &provider.DeleteResponse{
+		Status: report.status(ctx, "delete"),
+		Opaque: report.opaque(),
+	}, nil
+}
+
+func (s *svc) dryRunMove(ctx context.Context, req *provider.MoveRequest) (res *provider.MoveResponse, err error) {
+	defer func() {
+		if res != nil {
+			res.Opaque = markDryRun(res.Opaque)
+		}
+	}()
+
+	srcStatRes, err := s.Stat(ctx, &provider.StatRequest{Ref: req.Source})
+	if err != nil {
+		return nil, err
+	}
+	if srcStatRes.Status.Code != rpc.Code_CODE_OK {
+		return &provider.MoveResponse{Status: srcStatRes.Status}, nil
+	}
+
+	report := newDryRunReport()
+	report.Permissions["move"] = srcStatRes.Info.PermissionSet.GetMove()
+
+	// the destination must not exist yet
+	dstStatRes, err := s.Stat(ctx, &provider.StatRequest{Ref: req.Destination})
+	if err != nil {
+		return nil, err
+	}
+	if dstStatRes.Status.Code == rpc.Code_CODE_OK {
+		return &provider.MoveResponse{
+			Status: status.NewAlreadyExists(ctx, nil, "dry-run: destination already exists"),
+			Opaque: report.opaque(),
+		}, nil
+	}
+
+	// and the user must be allowed to create the resource in the parent of the destination
+	if dp := req.Destination.GetPath(); dp != "" {
+		parentStatRes, err := s.Stat(ctx, &provider.StatRequest{
+			Ref: &provider.Reference{ResourceId: req.Destination.GetResourceId(), Path: path.Dir(dp)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if parentStatRes.Status.Code != rpc.Code_CODE_OK {
+			return &provider.MoveResponse{Status: parentStatRes.Status}, nil
+		}
+
+		perms := parentStatRes.Info.PermissionSet
+		if srcStatRes.Info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			report.Permissions["create_container"] = perms.GetCreateContainer()
+		} else {
+			report.Permissions["initiate_file_upload"] = perms.GetInitiateFileUpload()
+		}
+	}
+
+	if st := s.countItems(ctx, srcStatRes.Info, report); st != nil {
+		return &provider.MoveResponse{Status: st}, nil
+	}
+
+	return &provider.MoveResponse{
+		Status: report.status(ctx, "move"),
+		Opaque: report.opaque(),
+	}, nil
+}
+
+func (s *svc) dryRunPurgeRecycle(ctx context.Context, req *provider.PurgeRecycleRequest) (res *provider.PurgeRecycleResponse, err error) {
+	defer func() {
+		if res != nil {
+			res.Opaque = markDryRun(res.Opaque)
+		}
+	}()
+
+	statRes, err := s.Stat(ctx, &provider.StatRequest{Ref: req.Ref})
+	if err != nil {
+		return nil, err
+	}
+	if statRes.Status.Code != rpc.Code_CODE_OK {
+		return &provider.PurgeRecycleResponse{Status: statRes.Status}, nil
+	}
+
+	report := newDryRunReport()
+	report.Permissions["purge_recycle"] = statRes.Info.PermissionSet.GetPurgeRecycle()
+
+	if req.Key != "" {
+		// a single item (with all of its contents) is purged
+		report.AffectedItems = 1
+	} else {
+		listRes, err := s.ListRecycle(ctx, &provider.ListRecycleRequest{Ref: req.Ref})
+		if err != nil {
+			return nil, err
+		}
+		if listRes.Status.Code != rpc.Code_CODE_OK {
+			return &provider.PurgeRecycleResponse{Status: listRes.Status}, nil
+		}
+		report.AffectedItems = len(listRes.RecycleItems)
+	}
+
+	return &provider.PurgeRecycleResponse{
+		Status: report.status(ctx, "purge recycle"),
+		Opaque: report.opaque(),
+	}, nil
+}
+
+func (s *svc) dryRunRemoveShare(ctx context.Context, req *collaboration.RemoveShareRequest) (res *collaboration.RemoveShareResponse, err error) {
+	defer func() {
+		if res != nil {
+			res.Opaque = markDryRun(res.Opaque)
+		}
+	}()
+
+	c, err := pool.GetUserShareProviderClient(pool.Endpoint(s.c.UserShareProviderEndpoint))
+	if err != nil {
+		return &collaboration.RemoveShareResponse{
+			Status: status.NewInternal(ctx, err, "error getting user share provider client"),
+		}, nil
+	}
+
+	getShareRes, err := c.GetShare(ctx, &collaboration.GetShareRequest{Ref: req.Ref})
+	if err != nil {
+		return nil, err
+	}
+	if getShareRes.Status.Code != rpc.Code_CODE_OK {
+		return &collaboration.RemoveShareResponse{Status: getShareRes.Status}, nil
+	}
+
+	// only the owner or the creator of a share may remove it
+	u := ctxpkg.ContextMustGetUser(ctx)
+	share := getShareRes.Share
+
+	report := newDryRunReport()
+	report.AffectedItems = 1
+	report.Permissions["remove_share"] = utils.UserEqual(u.Id, share.Owner) || utils.UserEqual(u.Id, share.Creator)
+
+	return &collaboration.RemoveShareResponse{
+		Status: report.status(ctx, "remove share"),
+		Opaque: report.opaque(),
+	}, nil
+}
+
+func (s *svc) dryRunRemovePublicShare(ctx context.Context, req *link.RemovePublicShareRequest) (res *link.RemovePublicShareResponse, err error) {
+	defer func() {
+		if res != nil {
+			res.Opaque = markDryRun(res.Opaque)
+		}
+	}()
+
+	c, err := pool.GetPublicShareProviderClient(pool.Endpoint(s.c.PublicShareProviderEndpoint))
+	if err != nil {
+		return &link.RemovePublicShareResponse{
+			Status: status.NewInternal(ctx, err, "error getting public share provider client"),
+		}, nil
+	}
+
+	getShareRes, err := c.GetPublicShare(ctx, &link.GetPublicShareRequest{Ref: req.Ref})
+	if err != nil {
+		return nil, err
+	}
+	if getShareRes.Status.Code != rpc.Code_CODE_OK {
+		return &link.RemovePublicShareResponse{Status: getShareRes.Status}, nil
+	}
+
+	// only the owner or the creator of a public share may remove it
+	u := ctxpkg.ContextMustGetUser(ctx)
+	share := getShareRes.Share
+
+	report := newDryRunReport()
+	report.AffectedItems = 1
+	report.Permissions["remove_public_share"] = utils.UserEqual(u.Id, share.Owner) || utils.UserEqual(u.Id, share.Creator)
+
+	return &link.RemovePublicShareResponse{
+		Status: report.status(ctx, "remove public share"),
+		Opaque: report.opaque(),
+	}, nil
+}
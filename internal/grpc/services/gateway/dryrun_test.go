@@ -0,0 +1,478 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/ReneKroon/ttlcache/v2"
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	registry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/storage/utils/namepolicy"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+var (
+	einstein = &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "einstein"}}
+	marie    = &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "marie"}}
+
+	granted = &provider.ResourcePermissions{Delete: true, Move: true, PurgeRecycle: true, CreateContainer: true, InitiateFileUpload: true}
+	denied  = &provider.ResourcePermissions{}
+)
+
+// mutations records the mutating calls received by the fake services, which a dry-run must not send.
+type mutations struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (m *mutations) record(call string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, call)
+}
+
+func (m *mutations) list() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.calls...)
+}
+
+func newInfo(p string, t provider.ResourceType, perms *provider.ResourcePermissions) *provider.ResourceInfo {
+	return &provider.ResourceInfo{
+		Id:            &provider.ResourceId{StorageId: "data", OpaqueId: p},
+		Path:          p,
+		Type:          t,
+		PermissionSet: perms,
+		Owner:         einstein.Id,
+	}
+}
+
+// fakeStorage serves a tree of resources; the container /data/big has more children than a dry-run counts.
+type fakeStorage struct {
+	provider.ProviderAPIServer
+	*mutations
+	infos map[string]*provider.ResourceInfo
+}
+
+func newFakeStorage(m *mutations) *fakeStorage {
+	s := &fakeStorage{mutations: m, infos: map[string]*provider.ResourceInfo{}}
+	for _, info := range []*provider.ResourceInfo{
+		newInfo("/data", provider.ResourceType_RESOURCE_TYPE_CONTAINER, granted),
+		newInfo("/data/docs", provider.ResourceType_RESOURCE_TYPE_CONTAINER, granted),
+		newInfo("/data/docs/a.txt", provider.ResourceType_RESOURCE_TYPE_FILE, granted),
+		newInfo("/data/docs/sub", provider.ResourceType_RESOURCE_TYPE_CONTAINER, granted),
+		newInfo("/data/docs/sub/b.txt", provider.ResourceType_RESOURCE_TYPE_FILE, granted),
+		newInfo("/data/archive", provider.ResourceType_RESOURCE_TYPE_CONTAINER, granted),
+		newInfo("/data/readonly", provider.ResourceType_RESOURCE_TYPE_CONTAINER, denied),
+		newInfo("/data/locked.txt", provider.ResourceType_RESOURCE_TYPE_FILE, denied),
+		newInfo("/data/big", provider.ResourceType_RESOURCE_TYPE_CONTAINER, granted),
+	} {
+		s.infos[info.Path] = info
+	}
+	return s
+}
+
+func (s *fakeStorage) Stat(ctx context.Context, req *provider.StatRequest) (*provider.StatResponse, error) {
+	info, ok := s.infos[req.Ref.Path]
+	if !ok {
+		return &provider.StatResponse{Status: status.NewNotFound(ctx, "not found")}, nil
+	}
+	return &provider.StatResponse{Status: status.NewOK(ctx), Info: info}, nil
+}
+
+func (s *fakeStorage) ListContainer(ctx context.Context, req *provider.ListContainerRequest) (*provider.ListContainerResponse, error) {
+	res := &provider.ListContainerResponse{Status: status.NewOK(ctx)}
+	if req.Ref.Path == "/data/big" {
+		for i := 0; i <= dryRunMaxItems; i++ {
+			res.Infos = append(res.Infos, newInfo(fmt.Sprintf("/data/big/%d.txt", i), provider.ResourceType_RESOURCE_TYPE_FILE, granted))
+		}
+		return res, nil
+	}
+	for _, info := range s.infos {
+		if path.Dir(info.Path) == req.Ref.Path && info.Path != req.Ref.Path {
+			res.Infos = append(res.Infos, info)
+		}
+	}
+	return res, nil
+}
+
+func (s *fakeStorage) ListRecycle(ctx context.Context, req *provider.ListRecycleRequest) (*provider.ListRecycleResponse, error) {
+	return &provider.ListRecycleResponse{
+		Status:       status.NewOK(ctx),
+		RecycleItems: []*provider.RecycleItem{{Key: "1"}, {Key: "2"}, {Key: "3"}},
+	}, nil
+}
+
+func (s *fakeStorage) Delete(ctx context.Context, req *provider.DeleteRequest) (*provider.DeleteResponse, error) {
+	s.record("Delete")
+	return &provider.DeleteResponse{Status: status.NewOK(ctx)}, nil
+}
+
+func (s *fakeStorage) Move(ctx context.Context, req *provider.MoveRequest) (*provider.MoveResponse, error) {
+	s.record("Move")
+	return &provider.MoveResponse{Status: status.NewOK(ctx)}, nil
+}
+
+func (s *fakeStorage) PurgeRecycle(ctx context.Context, req *provider.PurgeRecycleRequest) (*provider.PurgeRecycleResponse, error) {
+	s.record("PurgeRecycle")
+	return &provider.PurgeRecycleResponse{Status: status.NewOK(ctx)}, nil
+}
+
+// fakeRegistry resolves all the references to the storage served at addr.
+type fakeRegistry struct {
+	registry.RegistryAPIServer
+	addr string
+}
+
+func (r *fakeRegistry) GetStorageProviders(ctx context.Context, req *registry.GetStorageProvidersRequest) (*registry.GetStorageProvidersResponse, error) {
+	return &registry.GetStorageProvidersResponse{
+		Status:    status.NewOK(ctx),
+		Providers: []*registry.ProviderInfo{{Address: r.addr, ProviderPath: "/"}},
+	}, nil
+}
+
+// fakeShares serves a share and a public share owned by einstein.
+type fakeShares struct {
+	collaboration.CollaborationAPIServer
+	*mutations
+}
+
+func (s *fakeShares) GetShare(ctx context.Context, req *collaboration.GetShareRequest) (*collaboration.GetShareResponse, error) {
+	return &collaboration.GetShareResponse{
+		Status: status.NewOK(ctx),
+		Share:  &collaboration.Share{Id: req.Ref.GetId(), Owner: einstein.Id, Creator: einstein.Id},
+	}, nil
+}
+
+func (s *fakeShares) RemoveShare(ctx context.Context, req *collaboration.RemoveShareRequest) (*collaboration.RemoveShareResponse, error) {
+	s.record("RemoveShare")
+	return &collaboration.RemoveShareResponse{Status: status.NewOK(ctx)}, nil
+}
+
+type fakeLinks struct {
+	link.LinkAPIServer
+	*mutations
+}
+
+func (s *fakeLinks) GetPublicShare(ctx context.Context, req *link.GetPublicShareRequest) (*link.GetPublicShareResponse, error) {
+	return &link.GetPublicShareResponse{
+		Status: status.NewOK(ctx),
+		Share:  &link.PublicShare{Id: req.Ref.GetId(), Owner: einstein.Id, Creator: einstein.Id},
+	}, nil
+}
+
+func (s *fakeLinks) RemovePublicShare(ctx context.Context, req *link.RemovePublicShareRequest) (*link.RemovePublicShareResponse, error) {
+	s.record("RemovePublicShare")
+	return &link.RemovePublicShareResponse{Status: status.NewOK(ctx)}, nil
+}
+
+// newDryRunService returns a gateway whose storage registry, storage and share providers are
+// fakes served by a single grpc server, and the mutating calls these received.
+func newDryRunService(t *testing.T) (*svc, *mutations) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	m := &mutations{}
+	srv := grpc.NewServer()
+	registry.RegisterRegistryAPIServer(srv, &fakeRegistry{addr: addr})
+	provider.RegisterProviderAPIServer(srv, newFakeStorage(m))
+	collaboration.RegisterCollaborationAPIServer(srv, &fakeShares{mutations: m})
+	link.RegisterLinkAPIServer(srv, &fakeLinks{mutations: m})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	c := &config{
+		StorageRegistryEndpoint:     addr,
+		UserShareProviderEndpoint:   addr,
+		PublicShareProviderEndpoint: addr,
+	}
+	c.init()
+	s := &svc{
+		c:               c,
+		etagCache:       ttlcache.NewCache(),
+		createHomeCache: ttlcache.NewCache(),
+		filenamePolicy:  namepolicy.New(nil),
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s, m
+}
+
+func dryRunContext(u *userpb.User) context.Context {
+	return ctxpkg.ContextSetDryRun(ctxpkg.ContextSetUser(context.Background(), u))
+}
+
+// dryRunReportOf checks that the response is marked as a dry-run and returns its report.
+func dryRunReportOf(t *testing.T, opaque *types.Opaque) *dryRunReport {
+	t.Helper()
+
+	if e, ok := opaque.GetMap()["dry_run"]; !ok || string(e.Value) != "true" {
+		t.Fatalf("expected the response to be marked as a dry-run, got %v", opaque)
+	}
+	e, ok := opaque.GetMap()["dry_run_report"]
+	if !ok {
+		t.Fatalf("expected a dry-run report, got %v", opaque)
+	}
+	report := &dryRunReport{}
+	if err := json.Unmarshal(e.Value, report); err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func checkNoMutation(t *testing.T, m *mutations) {
+	t.Helper()
+	if calls := m.list(); len(calls) != 0 {
+		t.Errorf("expected no mutating call, got %v", calls)
+	}
+}
+
+func TestDryRunDelete(t *testing.T) {
+	s, m := newDryRunService(t)
+	ctx := dryRunContext(einstein)
+
+	tests := []struct {
+		path        string
+		code        rpc.Code
+		items       int
+		truncated   bool
+		permissions map[string]bool
+	}{
+		{path: "/data/docs/a.txt", code: rpc.Code_CODE_OK, items: 1, permissions: map[string]bool{"delete": true}},
+		{path: "/data/docs", code: rpc.Code_CODE_OK, items: 4, permissions: map[string]bool{"delete": true}},
+		{path: "/data/locked.txt", code: rpc.Code_CODE_PERMISSION_DENIED, items: 1, permissions: map[string]bool{"delete": false}},
+		{path: "/data/big", code: rpc.Code_CODE_OK, items: dryRunMaxItems, truncated: true, permissions: map[string]bool{"delete": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			res, err := s.Delete(ctx, &provider.DeleteRequest{Ref: &provider.Reference{Path: tt.path}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Status.Code != tt.code {
+				t.Errorf("expected status %v, got %v", tt.code, res.Status.Code)
+			}
+			report := dryRunReportOf(t, res.Opaque)
+			if report.AffectedItems != tt.items || report.Truncated != tt.truncated {
+				t.Errorf("expected %d affected items (truncated: %v), got %d (truncated: %v)", tt.items, tt.truncated, report.AffectedItems, report.Truncated)
+			}
+			checkPermissions(t, tt.permissions, report.Permissions)
+		})
+	}
+
+	res, err := s.Delete(ctx, &provider.DeleteRequest{Ref: &provider.Reference{Path: "/data/missing"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status.Code != rpc.Code_CODE_NOT_FOUND {
+		t.Errorf("expected status %v, got %v", rpc.Code_CODE_NOT_FOUND, res.Status.Code)
+	}
+	if _, ok := res.Opaque.GetMap()["dry_run"]; !ok {
+		t.Error("expected the failed response to be marked as a dry-run")
+	}
+
+	checkNoMutation(t, m)
+}
+
+func TestDryRunMove(t *testing.T) {
+	s, m := newDryRunService(t)
+	ctx := dryRunContext(einstein)
+
+	tests := []struct {
+		name        string
+		src, dst    string
+		code        rpc.Code
+		items       int
+		permissions map[string]bool
+	}{
+		{name: "container", src: "/data/docs", dst: "/data/archive/docs", code: rpc.Code_CODE_OK, items: 4, permissions: map[string]bool{"move": true, "create_container": true}},
+		{name: "file", src: "/data/docs/a.txt", dst: "/data/archive/a.txt", code: rpc.Code_CODE_OK, items: 1, permissions: map[string]bool{"move": true, "initiate_file_upload": true}},
+		{name: "read-only destination", src: "/data/docs", dst: "/data/readonly/docs", code: rpc.Code_CODE_PERMISSION_DENIED, items: 4, permissions: map[string]bool{"move": true, "create_container": false}},
+		{name: "locked source", src: "/data/locked.txt", dst: "/data/archive/locked.txt", code: rpc.Code_CODE_PERMISSION_DENIED, items: 1, permissions: map[string]bool{"move": false, "initiate_file_upload": true}},
+		{name: "existing destination", src: "/data/docs/a.txt", dst: "/data/locked.txt", code: rpc.Code_CODE_ALREADY_EXISTS, permissions: map[string]bool{"move": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.Move(ctx, &provider.MoveRequest{
+				Source:      &provider.Reference{Path: tt.src},
+				Destination: &provider.Reference{Path: tt.dst},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Status.Code != tt.code {
+				t.Errorf("expected status %v, got %v", tt.code, res.Status.Code)
+			}
+			report := dryRunReportOf(t, res.Opaque)
+			if report.AffectedItems != tt.items {
+				t.Errorf("expected %d affected items, got %d", tt.items, report.AffectedItems)
+			}
+			checkPermissions(t, tt.permissions, report.Permissions)
+		})
+	}
+
+	checkNoMutation(t, m)
+}
+
+func TestDryRunPurgeRecycle(t *testing.T) {
+	s, m := newDryRunService(t)
+	ctx := dryRunContext(einstein)
+
+	tests := []struct {
+		name  string
+		path  string
+		key   string
+		code  rpc.Code
+		items int
+	}{
+		{name: "whole recycle bin", path: "/data", code: rpc.Code_CODE_OK, items: 3},
+		{name: "single item", path: "/data", key: "2", code: rpc.Code_CODE_OK, items: 1},
+		{name: "denied", path: "/data/locked.txt", code: rpc.Code_CODE_PERMISSION_DENIED, items: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.PurgeRecycle(ctx, &provider.PurgeRecycleRequest{Ref: &provider.Reference{Path: tt.path}, Key: tt.key})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Status.Code != tt.code {
+				t.Errorf("expected status %v, got %v", tt.code, res.Status.Code)
+			}
+			report := dryRunReportOf(t, res.Opaque)
+			if report.AffectedItems != tt.items {
+				t.Errorf("expected %d affected items, got %d", tt.items, report.AffectedItems)
+			}
+			checkPermissions(t, map[string]bool{"purge_recycle": tt.code == rpc.Code_CODE_OK}, report.Permissions)
+		})
+	}
+
+	checkNoMutation(t, m)
+}
+
+func TestDryRunRemoveShares(t *testing.T) {
+	s, m := newDryRunService(t)
+
+	for _, tt := range []struct {
+		user *userpb.User
+		code rpc.Code
+	}{
+		{user: einstein, code: rpc.Code_CODE_OK},
+		{user: marie, code: rpc.Code_CODE_PERMISSION_DENIED},
+	} {
+		ctx := dryRunContext(tt.user)
+
+		res, err := s.RemoveShare(ctx, &collaboration.RemoveShareRequest{
+			Ref: &collaboration.ShareReference{Spec: &collaboration.ShareReference_Id{Id: &collaboration.ShareId{OpaqueId: "share"}}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Status.Code != tt.code {
+			t.Errorf("expected status %v removing the share as %s, got %v", tt.code, tt.user.Id.OpaqueId, res.Status.Code)
+		}
+		report := dryRunReportOf(t, res.Opaque)
+		if report.AffectedItems != 1 {
+			t.Errorf("expected 1 affected item, got %d", report.AffectedItems)
+		}
+		checkPermissions(t, map[string]bool{"remove_share": tt.code == rpc.Code_CODE_OK}, report.Permissions)
+
+		linkRes, err := s.RemovePublicShare(ctx, &link.RemovePublicShareRequest{
+			Ref: &link.PublicShareReference{Spec: &link.PublicShareReference_Id{Id: &link.PublicShareId{OpaqueId: "link"}}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if linkRes.Status.Code != tt.code {
+			t.Errorf("expected status %v removing the public share as %s, got %v", tt.code, tt.user.Id.OpaqueId, linkRes.Status.Code)
+		}
+		report = dryRunReportOf(t, linkRes.Opaque)
+		if report.AffectedItems != 1 {
+			t.Errorf("expected 1 affected item, got %d", report.AffectedItems)
+		}
+		checkPermissions(t, map[string]bool{"remove_public_share": tt.code == rpc.Code_CODE_OK}, report.Permissions)
+	}
+
+	checkNoMutation(t, m)
+}
+
+// TestDryRunAcknowledged checks that the gateway acknowledges the dry-run flag in the header
+// of the response to a stat, which the clients check before sending a dry-run request.
+func TestDryRunAcknowledged(t *testing.T) {
+	s, _ := newDryRunService(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	gateway.RegisterGatewayAPIServer(srv, s)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(lis.Addr().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ctx, expected := range map[context.Context]bool{
+		dryRunContext(einstein):                               true,
+		ctxpkg.ContextSetUser(context.Background(), einstein): false,
+	} {
+		var header metadata.MD
+		if _, err := client.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{Path: "/data"}}, grpc.Header(&header)); err != nil {
+			t.Fatal(err)
+		}
+		if acknowledged := len(header.Get(ctxpkg.DryRunSupportedHeader)) != 0; acknowledged != expected {
+			t.Errorf("expected the dry-run to be acknowledged: %v, got %v", expected, acknowledged)
+		}
+	}
+}
+
+func checkPermissions(t *testing.T, expected, got map[string]bool) {
+	t.Helper()
+	if len(got) != len(expected) {
+		t.Errorf("expected permissions %v, got %v", expected, got)
+		return
+	}
+	for perm, granted := range expected {
+		if g, ok := got[perm]; !ok || g != granted {
+			t.Errorf("expected permissions %v, got %v", expected, got)
+			return
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	fsckpb "github.com/cs3org/reva/pkg/storage/utils/fsck/proto"
+	"github.com/pkg/errors"
+)
+
+// StartCheck starts a consistency check of the storage holding the reference.
+func (s *svc) StartCheck(ctx context.Context, req *fsckpb.StartCheckRequest) (*fsckpb.StartCheckResponse, error) {
+	c, err := s.findFsckService(ctx, req.Ref)
+	if err != nil {
+		return &fsckpb.StartCheckResponse{
+			Status: status.NewStatusFromErrType(ctx, "StartCheck ref="+req.Ref.String(), err),
+		}, nil
+	}
+
+	res, err := c.StartCheck(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error calling StartCheck")
+	}
+	return res, nil
+}
+
+// GetCheckReport returns the report of the last check of the storage holding the reference.
+func (s *svc) GetCheckReport(ctx context.Context, req *fsckpb.GetCheckReportRequest) (*fsckpb.GetCheckReportResponse, error) {
+	c, err := s.findFsckService(ctx, req.Ref)
+	if err != nil {
+		return &fsckpb.GetCheckReportResponse{
+			Status: status.NewStatusFromErrType(ctx, "GetCheckReport ref="+req.Ref.String(), err),
+		}, nil
+	}
+
+	res, err := c.GetCheckReport(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error calling GetCheckReport")
+	}
+	return res, nil
+}
+
+// CancelCheck cancels the running check of the storage holding the reference.
+func (s *svc) CancelCheck(ctx context.Context, req *fsckpb.CancelCheckRequest) (*fsckpb.CancelCheckResponse, error) {
+	c, err := s.findFsckService(ctx, req.Ref)
+	if err != nil {
+		return &fsckpb.CancelCheckResponse{
+			Status: status.NewStatusFromErrType(ctx, "CancelCheck ref="+req.Ref.String(), err),
+		}, nil
+	}
+
+	res, err := c.CancelCheck(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error calling CancelCheck")
+	}
+	return res, nil
+}
+
+func (s *svc) findFsckService(ctx context.Context, ref *provider.Reference) (fsckpb.FsckServiceClient, error) {
+	providers, err := s.findProviders(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := pool.GetFsckServiceClient(pool.Endpoint(providers[0].Address))
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error getting a fsck service client")
+	}
+	return c, nil
+}
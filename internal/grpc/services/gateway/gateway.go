@@ -27,11 +27,23 @@ import (
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 
 	"github.com/ReneKroon/ttlcache/v2"
+	deletejobpb "github.com/cs3org/reva/pkg/deletejob/proto"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/idempotency"
+	ocmcache "github.com/cs3org/reva/pkg/ocm/cache"
+	ocmcachepb "github.com/cs3org/reva/pkg/ocm/cache/proto"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/sharedconf"
+	sharedwithmepb "github.com/cs3org/reva/pkg/sharedwithme/proto"
+	fsckpb "github.com/cs3org/reva/pkg/storage/utils/fsck/proto"
+	movejournalpb "github.com/cs3org/reva/pkg/storage/utils/movejournal/proto"
+	"github.com/cs3org/reva/pkg/storage/utils/namepolicy"
+	verifypb "github.com/cs3org/reva/pkg/storage/utils/verify/proto"
+	"github.com/cs3org/reva/pkg/tenant"
 	"github.com/cs3org/reva/pkg/token"
 	"github.com/cs3org/reva/pkg/token/manager/registry"
+	"github.com/cs3org/reva/pkg/watch"
+	watchpb "github.com/cs3org/reva/pkg/watch/proto"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
@@ -63,7 +75,13 @@ type config struct {
 	DisableHomeCreationOnLogin    bool   `mapstructure:"disable_home_creation_on_login"`
 	TransferSharedSecret          string `mapstructure:"transfer_shared_secret"`
 	TransferExpires               int64  `mapstructure:"transfer_expires"`
-	TokenManager                  string `mapstructure:"token_manager"`
+	// UploadTransferExpires is the lifetime in seconds of the transfer tokens of
+	// resumable uploads, which are bound to the upload and renewed while it goes on.
+	UploadTransferExpires int64 `mapstructure:"upload_transfer_expires"`
+	// UploadTransferMaxLifetime is the time in seconds during which the transfer
+	// token of a resumable upload can be renewed.
+	UploadTransferMaxLifetime int64  `mapstructure:"upload_transfer_max_lifetime"`
+	TokenManager              string `mapstructure:"token_manager"`
 	// ShareFolder is the location where to create shares in the recipient's storage provider.
 	ShareFolder         string                            `mapstructure:"share_folder"`
 	DataTransfersFolder string                            `mapstructure:"data_transfers_folder"`
@@ -72,6 +90,28 @@ type config struct {
 	EtagCacheTTL        int                               `mapstructure:"etag_cache_ttl"`
 	AllowedUserAgents   map[string][]string               `mapstructure:"allowed_user_agents"` // map[path][]user-agent
 	CreateHomeCacheTTL  int                               `mapstructure:"create_home_cache_ttl"`
+	// FilenamePolicy restricts the names of newly created or renamed resources.
+	FilenamePolicy namepolicy.Config `mapstructure:"filename_policy"`
+	// WatchNatsAddress is the event stream the changes sent to the watching clients are consumed from.
+	// Watching resources is disabled if it is not set.
+	WatchNatsAddress   string `mapstructure:"watch_nats_address"`
+	WatchNatsClusterID string `mapstructure:"watch_nats_clusterid"`
+	WatchBufferSize    int    `mapstructure:"watch_buffer_size" docs:"64;Number of changes buffered per watching client before dropping new ones."`
+	// GroupsNatsAddress is the event stream the group membership changes are consumed from, to
+	// invalidate the cached etags of the users whose group shares have changed.
+	GroupsNatsAddress   string `mapstructure:"groups_nats_address"`
+	GroupsNatsClusterID string `mapstructure:"groups_nats_clusterid"`
+	// SharedWithMeWorkers bounds the number of shared resources stat'ed concurrently by ListSharedWithMe.
+	SharedWithMeWorkers int `mapstructure:"shared_with_me_workers" docs:"10;Number of shared resources stat'ed concurrently when listing the shares with their resources."`
+	// IdempotencyTTL is the time in seconds a retried mutating request is answered from the first response.
+	IdempotencyTTL int `mapstructure:"idempotency_ttl" docs:"3600;Seconds the response to a request carrying an idempotency key is kept to answer its retries."`
+	// LocaleSorting orders the listed resources following the collation rules of the locale of the user.
+	LocaleSorting bool `mapstructure:"locale_sorting" docs:"false;Order the resources of ListContainer by their names in the locale of the user."`
+	// DefaultLocale is the locale of the users who have not chosen one.
+	DefaultLocale string `mapstructure:"default_locale" docs:"en;Locale used to order the listings of the users without a locale preference."`
+	// OCMCache caches the resources received through OCM shares, to browse the shares of slow providers.
+	// The content is cached by the datagateway, which has to be configured with the same directory.
+	OCMCache ocmcache.Config `mapstructure:"ocm_cache"`
 }
 
 // sets defaults
@@ -90,6 +130,10 @@ func (c *config) init() {
 		c.TokenManager = "jwt"
 	}
 
+	if c.DefaultLocale == "" {
+		c.DefaultLocale = "en"
+	}
+
 	// if services address are not specified we used the shared conf
 	// for the gatewaysvc to have dev setups very quickly.
 	c.AuthRegistryEndpoint = sharedconf.GetGatewaySVC(c.AuthRegistryEndpoint)
@@ -116,6 +160,28 @@ func (c *config) init() {
 	if c.TransferExpires == 0 {
 		c.TransferExpires = 100 * 60 // seconds
 	}
+
+	if c.UploadTransferExpires == 0 {
+		c.UploadTransferExpires = 6 * 60 * 60 // seconds
+	}
+	if c.UploadTransferMaxLifetime == 0 {
+		c.UploadTransferMaxLifetime = 7 * 24 * 60 * 60 // seconds
+	}
+	if c.UploadTransferMaxLifetime < c.UploadTransferExpires {
+		c.UploadTransferMaxLifetime = c.UploadTransferExpires
+	}
+
+	if c.WatchBufferSize <= 0 {
+		c.WatchBufferSize = 64
+	}
+
+	if c.SharedWithMeWorkers <= 0 {
+		c.SharedWithMeWorkers = 10
+	}
+
+	if c.IdempotencyTTL <= 0 {
+		c.IdempotencyTTL = 3600
+	}
 }
 
 type svc struct {
@@ -124,6 +190,11 @@ type svc struct {
 	tokenmgr        token.Manager
 	etagCache       *ttlcache.Cache `mapstructure:"etag_cache"`
 	createHomeCache *ttlcache.Cache `mapstructure:"create_home_cache"`
+	filenamePolicy  *namepolicy.Policy
+	watchHub        *watch.Hub
+	tenants         *tenant.Manager
+	idempotency     *idempotency.Cache
+	ocmCache        *ocmcache.Cache
 }
 
 // New creates a new gateway svc that acts as a proxy for any grpc operation.
@@ -162,6 +233,30 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 		tokenmgr:        tokenManager,
 		etagCache:       etagCache,
 		createHomeCache: createHomeCache,
+		filenamePolicy:  namepolicy.New(&c.FilenamePolicy),
+		idempotency:     idempotency.New(time.Duration(c.IdempotencyTTL) * time.Second),
+	}
+
+	if s.tenants, err = tenant.New(sharedconf.GetTenancy()); err != nil {
+		return nil, err
+	}
+
+	if c.OCMCache.Enabled {
+		if s.ocmCache, err = ocmcache.New(&c.OCMCache); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.WatchNatsAddress != "" {
+		if s.watchHub, err = newWatchHub(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.GroupsNatsAddress != "" {
+		if err = s.consumeMembershipChanges(); err != nil {
+			return nil, err
+		}
 	}
 
 	return s, nil
@@ -169,6 +264,13 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 
 func (s *svc) Register(ss *grpc.Server) {
 	gateway.RegisterGatewayAPIServer(ss, s)
+	watchpb.RegisterWatchServiceServer(ss, s)
+	sharedwithmepb.RegisterSharedWithMeServiceServer(ss, s)
+	deletejobpb.RegisterDeleteJobServiceServer(ss, s)
+	ocmcachepb.RegisterOCMCacheServiceServer(ss, s)
+	movejournalpb.RegisterMoveJournalServiceServer(ss, s)
+	fsckpb.RegisterFsckServiceServer(ss, s)
+	verifypb.RegisterVerifyServiceServer(ss, s)
 }
 
 func (s *svc) Close() error {
@@ -0,0 +1,90 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/idempotency"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// idempotent runs fn once per idempotency key of the user, if the request
+// carries one. The key is scoped to the user and the method, the fingerprint
+// identifies the payload of the request.
+func (s *svc) idempotent(ctx context.Context, method string, fn func() (idempotency.Response, error), fingerprint ...proto.Message) (idempotency.Response, error) {
+	key, ok := ctxpkg.ContextGetIdempotencyKey(ctx)
+	if !ok {
+		return fn()
+	}
+	u := ctxpkg.ContextMustGetUser(ctx)
+	key = u.Id.Idp + "!" + u.Id.OpaqueId + "!" + method + "!" + key
+	return s.idempotency.Do(key, idempotency.Fingerprint(fingerprint...), fn)
+}
+
+// CreateShare creates a share, retries with the same idempotency key return the first share.
+func (s *svc) CreateShare(ctx context.Context, req *collaboration.CreateShareRequest) (*collaboration.CreateShareResponse, error) {
+	res, err := s.idempotent(ctx, "CreateShare", func() (idempotency.Response, error) {
+		return s.createShare(ctx, req)
+	}, req.GetResourceInfo().GetId(), req.GetGrant())
+	switch err.(type) {
+	case nil:
+		return res.(*collaboration.CreateShareResponse), nil
+	case errtypes.BadRequest:
+		return &collaboration.CreateShareResponse{Status: status.NewInvalidArg(ctx, err.Error())}, nil
+	default:
+		return nil, err
+	}
+}
+
+// InitiateFileUpload initiates an upload, retries with the same idempotency key return the first upload endpoints.
+func (s *svc) InitiateFileUpload(ctx context.Context, req *provider.InitiateFileUploadRequest) (*gateway.InitiateFileUploadResponse, error) {
+	res, err := s.idempotent(ctx, "InitiateFileUpload", func() (idempotency.Response, error) {
+		return s.initiateUpload(ctx, req)
+	}, req)
+	switch err.(type) {
+	case nil:
+		return res.(*gateway.InitiateFileUploadResponse), nil
+	case errtypes.BadRequest:
+		return &gateway.InitiateFileUploadResponse{Status: status.NewInvalidArg(ctx, err.Error())}, nil
+	default:
+		return nil, err
+	}
+}
+
+// CreateStorageSpace creates a space, retries with the same idempotency key return the first space.
+func (s *svc) CreateStorageSpace(ctx context.Context, req *provider.CreateStorageSpaceRequest) (*provider.CreateStorageSpaceResponse, error) {
+	res, err := s.idempotent(ctx, "CreateStorageSpace", func() (idempotency.Response, error) {
+		return s.createStorageSpace(ctx, req)
+	}, req)
+	switch err.(type) {
+	case nil:
+		return res.(*provider.CreateStorageSpaceResponse), nil
+	case errtypes.BadRequest:
+		return &provider.CreateStorageSpaceResponse{Status: status.NewInvalidArg(ctx, err.Error())}, nil
+	default:
+		return nil, err
+	}
+}
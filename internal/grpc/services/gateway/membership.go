@@ -0,0 +1,65 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"strings"
+
+	"github.com/asim/go-micro/plugins/events/nats/v4"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/events/server"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// consumeMembershipChanges invalidates the cached etags of the home and
+// shares folders of the users whose groups have changed, so that the clients
+// notice the group shares which appeared or disappeared.
+func (s *svc) consumeMembershipChanges() error {
+	stream, err := server.NewNatsStream(nats.Address(s.c.GroupsNatsAddress), nats.ClusterID(s.c.GroupsNatsClusterID))
+	if err != nil {
+		return errors.Wrap(err, "gateway: error connecting to the event stream")
+	}
+
+	// every gateway instance has its own etag cache, so each needs to see all events
+	evs, err := events.Consume(stream, "gateway-groups-"+uuid.NewString(), events.GroupMembershipChanged{})
+	if err != nil {
+		return errors.Wrap(err, "gateway: error consuming events")
+	}
+
+	go func() {
+		for ev := range evs {
+			if e, ok := ev.(events.GroupMembershipChanged); ok && e.UserID != nil {
+				s.invalidateEtags(e.UserID.OpaqueId)
+			}
+		}
+	}()
+	return nil
+}
+
+// invalidateEtags removes the cached etags of the folders of a user, which
+// are keyed by the id of the user and the path of the folder.
+func (s *svc) invalidateEtags(userID string) {
+	prefix := userID + ":"
+	for _, k := range s.etagCache.GetKeys() {
+		if strings.HasPrefix(k, prefix) {
+			_ = s.etagCache.Remove(k)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	movejournalpb "github.com/cs3org/reva/pkg/storage/utils/movejournal/proto"
+	"github.com/pkg/errors"
+)
+
+// ListInterruptedMoves returns the interrupted moves of the storage holding the reference.
+func (s *svc) ListInterruptedMoves(ctx context.Context, req *movejournalpb.ListInterruptedMovesRequest) (*movejournalpb.ListInterruptedMovesResponse, error) {
+	c, err := s.findMoveJournalService(ctx, req.Ref)
+	if err != nil {
+		return &movejournalpb.ListInterruptedMovesResponse{
+			Status: status.NewStatusFromErrType(ctx, "ListInterruptedMoves ref="+req.Ref.String(), err),
+		}, nil
+	}
+
+	res, err := c.ListInterruptedMoves(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error calling ListInterruptedMoves")
+	}
+	return res, nil
+}
+
+// RepairMove resumes or rolls back an interrupted move of the storage holding the reference.
+func (s *svc) RepairMove(ctx context.Context, req *movejournalpb.RepairMoveRequest) (*movejournalpb.RepairMoveResponse, error) {
+	c, err := s.findMoveJournalService(ctx, req.Ref)
+	if err != nil {
+		return &movejournalpb.RepairMoveResponse{
+			Status: status.NewStatusFromErrType(ctx, "RepairMove id="+req.Id, err),
+		}, nil
+	}
+
+	res, err := c.RepairMove(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error calling RepairMove")
+	}
+	return res, nil
+}
+
+func (s *svc) findMoveJournalService(ctx context.Context, ref *provider.Reference) (movejournalpb.MoveJournalServiceClient, error) {
+	providers, err := s.findProviders(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := pool.GetMoveJournalServiceClient(pool.Endpoint(providers[0].Address))
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error getting a move journal service client")
+	}
+	return c, nil
+}
@@ -0,0 +1,46 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+
+	ocmcachepb "github.com/cs3org/reva/pkg/ocm/cache/proto"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+)
+
+func (s *svc) PurgeOCMCache(ctx context.Context, req *ocmcachepb.PurgeOCMCacheRequest) (*ocmcachepb.PurgeOCMCacheResponse, error) {
+	if s.ocmCache == nil {
+		return &ocmcachepb.PurgeOCMCacheResponse{
+			Status: status.NewUnimplemented(ctx, nil, "gateway: the ocm cache is not enabled"),
+		}, nil
+	}
+
+	entries, freed, err := s.ocmCache.Purge(req.Domain)
+	if err != nil {
+		return &ocmcachepb.PurgeOCMCacheResponse{
+			Status: status.NewInternal(ctx, err, "gateway: error purging the ocm cache"),
+		}, nil
+	}
+	return &ocmcachepb.PurgeOCMCacheResponse{
+		Status:  status.NewOK(ctx),
+		Entries: uint64(entries),
+		Bytes:   uint64(freed),
+	}, nil
+}
@@ -34,6 +34,7 @@ import (
 	"github.com/cs3org/reva/pkg/appctx"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/ocm/share/sender"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/pkg/errors"
@@ -224,6 +225,9 @@ func (s *svc) UpdateReceivedOCMShare(ctx context.Context, req *ocm.UpdateReceive
 			},
 		}, nil
 	}
+	if res.Status.Code == rpc.Code_CODE_OK {
+		s.notifyOCMShareState(ctx, req)
+	}
 
 	// if we don't need to create/delete references then we return early.
 	if !s.c.CommitShareToStorageGrant && !s.c.CommitShareToStorageRef {
@@ -529,3 +533,65 @@ func (s *svc) createOCMReference(ctx context.Context, share *ocm.Share) (*rpc.St
 
 	return status.NewOK(ctx), nil
 }
+
+// notifyOCMShareState lets the provider of the owner of a received share know
+// that its recipient accepted or declined it. The notification is best effort,
+// as not every provider handles them.
+func (s *svc) notifyOCMShareState(ctx context.Context, req *ocm.UpdateReceivedOCMShareRequest) {
+	log := appctx.GetLogger(ctx)
+
+	var notificationType string
+	for _, p := range req.GetUpdateMask().GetPaths() {
+		if p != "state" {
+			continue
+		}
+		switch req.GetShare().GetState() {
+		case ocm.ShareState_SHARE_STATE_ACCEPTED:
+			notificationType = "SHARE_ACCEPTED"
+		case ocm.ShareState_SHARE_STATE_REJECTED:
+			notificationType = "SHARE_DECLINED"
+		}
+	}
+	if notificationType == "" {
+		return
+	}
+
+	getShareRes, err := s.GetReceivedOCMShare(ctx, &ocm.GetReceivedOCMShareRequest{
+		Ref: &ocm.ShareReference{
+			Spec: &ocm.ShareReference_Id{Id: req.GetShare().GetShare().GetId()},
+		},
+	})
+	if err != nil || getShareRes.Status.Code != rpc.Code_CODE_OK {
+		log.Error().Err(err).Msg("gateway: error getting received ocm share to notify its owner")
+		return
+	}
+	share := getShareRes.Share.GetShare()
+	opaque := share.GetGrantee().GetOpaque().GetMap()
+	remoteShareID := string(opaque["remoteShareId"].GetValue())
+	if remoteShareID == "" {
+		return
+	}
+
+	meshProvider, err := s.GetInfoByDomain(ctx, &ocmprovider.GetInfoByDomainRequest{
+		Domain: share.GetOwner().GetIdp(),
+	})
+	if err != nil || meshProvider.Status.Code != rpc.Code_CODE_OK {
+		log.Error().Err(err).Str("domain", share.GetOwner().GetIdp()).Msg("gateway: error getting provider info to notify the owner of an ocm share")
+		return
+	}
+
+	notification := map[string]interface{}{
+		"notificationType": notificationType,
+		"resourceType":     "file",
+		"providerId":       remoteShareID,
+		"notification": map[string]interface{}{
+			"sharedSecret": string(opaque["token"].GetValue()),
+			"message":      fmt.Sprintf("the share %s was %s", share.GetName(), strings.ToLower(strings.TrimPrefix(notificationType, "SHARE_"))),
+		},
+	}
+	go func() {
+		if err := sender.SendNotification(notification, meshProvider.ProviderInfo); err != nil {
+			log.Error().Err(err).Str("domain", share.GetOwner().GetIdp()).Msg("gateway: error notifying the owner of an ocm share")
+		}
+	}()
+}
@@ -24,6 +24,7 @@ import (
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/pkg/errors"
@@ -37,6 +38,12 @@ func (s *svc) CreatePublicShare(ctx context.Context, req *link.CreatePublicShare
 	log := appctx.GetLogger(ctx)
 	log.Info().Msg("create public share")
 
+	if st := s.setSharePolicy(ctx, req.ResourceInfo); st != nil {
+		return &link.CreatePublicShareResponse{
+			Status: st,
+		}, nil
+	}
+
 	c, err := pool.GetPublicShareProviderClient(pool.Endpoint(s.c.PublicShareProviderEndpoint))
 	if err != nil {
 		return nil, err
@@ -51,6 +58,10 @@ func (s *svc) CreatePublicShare(ctx context.Context, req *link.CreatePublicShare
 }
 
 func (s *svc) RemovePublicShare(ctx context.Context, req *link.RemovePublicShareRequest) (*link.RemovePublicShareResponse, error) {
+	if ctxpkg.ContextGetDryRun(ctx) {
+		return s.dryRunRemovePublicShare(ctx, req)
+	}
+
 	log := appctx.GetLogger(ctx)
 	log.Info().Msg("remove public share")
 
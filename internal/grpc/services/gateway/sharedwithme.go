@@ -0,0 +1,59 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/sharedwithme"
+	sharedwithmepb "github.com/cs3org/reva/pkg/sharedwithme/proto"
+	"github.com/pkg/errors"
+)
+
+// ListSharedWithMe lists the received shares together with the information
+// of the shared resources, which are stat'ed concurrently.
+func (s *svc) ListSharedWithMe(ctx context.Context, req *sharedwithmepb.ListSharedWithMeRequest) (*sharedwithmepb.ListSharedWithMeResponse, error) {
+	res, err := s.ListReceivedShares(ctx, &collaboration.ListReceivedSharesRequest{Filters: req.Filters})
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error calling ListReceivedShares")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return &sharedwithmepb.ListSharedWithMeResponse{Status: res.Status}, nil
+	}
+
+	stat := func(ctx context.Context, id *provider.ResourceId) (*provider.ResourceInfo, *rpc.Status) {
+		statRes, err := s.Stat(ctx, &provider.StatRequest{
+			Ref:                   &provider.Reference{ResourceId: id},
+			ArbitraryMetadataKeys: req.ArbitraryMetadataKeys,
+		})
+		if err != nil {
+			return nil, status.NewInternal(ctx, err, "error statting shared resource")
+		}
+		return statRes.Info, statRes.Status
+	}
+
+	return &sharedwithmepb.ListSharedWithMeResponse{
+		Status: status.NewOK(ctx),
+		Shares: sharedwithme.Enrich(ctx, res.Shares, s.c.SharedWithMeWorkers, stat),
+	}, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/share/policy"
+)
+
+// setSharePolicy replaces the share policy in the resource info of a share request with the policy of the
+// space containing the resource, so that the share providers enforce the policy set by the space managers
+// and not one sent by the client.
+func (s *svc) setSharePolicy(ctx context.Context, ri *provider.ResourceInfo) *rpc.Status {
+	if ri.GetId() == nil {
+		return nil
+	}
+	res, err := s.stat(ctx, &provider.StatRequest{
+		Ref:                   &provider.Reference{ResourceId: ri.Id},
+		ArbitraryMetadataKeys: []string{policy.OpaqueKey},
+	})
+	if err != nil {
+		return status.NewInternal(ctx, err, "gateway: error getting the share policy")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return res.Status
+	}
+
+	if ri.Opaque != nil {
+		delete(ri.Opaque.Map, policy.OpaqueKey)
+	}
+	if e, ok := res.Info.GetOpaque().GetMap()[policy.OpaqueKey]; ok {
+		ri.Opaque = policy.AddToOpaque(ri.Opaque, e.Value)
+	}
+	return nil
+}
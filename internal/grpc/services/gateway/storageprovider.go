@@ -30,6 +30,7 @@ import (
 	"google.golang.org/protobuf/types/known/fieldmaskpb"
 
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	preferences "github.com/cs3org/go-cs3apis/cs3/preferences/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
@@ -40,13 +41,17 @@ import (
 
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/locale"
+	ocmcache "github.com/cs3org/reva/pkg/ocm/cache"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/uploadtoken"
 	"github.com/cs3org/reva/pkg/storage/utils/etag"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"golang.org/x/text/language"
 
 	"google.golang.org/grpc/codes"
 	gstatus "google.golang.org/grpc/status"
@@ -56,9 +61,53 @@ import (
 type transferClaims struct {
 	jwt.StandardClaims
 	Target string `json:"target"`
+	// OCM is set for the downloads from the remote providers of OCM shares to be cached.
+	OCM *ocmcache.Transfer `json:"ocm,omitempty"`
+	// Scope binds the token to a resumable upload.
+	uploadtoken.Scope
 }
 
-func (s *svc) sign(_ context.Context, target string) (string, error) {
+// transferSubject identifies the user a transfer token is minted for, the datagateway
+// limits the concurrent transfers of every user with it.
+func transferSubject(ctx context.Context) string {
+	if u, ok := ctxpkg.ContextGetUser(ctx); ok && u.Id != nil {
+		return u.Id.Idp + "!" + u.Id.OpaqueId
+	}
+	return ""
+}
+
+func (s *svc) sign(ctx context.Context, target string) (string, error) {
+	return s.signOCM(ctx, target, nil)
+}
+
+// signUpload signs the transfer of a resumable upload. The token is bound to
+// the upload and has its own lifetime, so that uploads taking longer than the
+// access token of the user or a download token can finish.
+func (s *svc) signUpload(ctx context.Context, target string) (string, error) {
+	now := time.Now()
+	claims := transferClaims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: now.Add(time.Duration(s.c.UploadTransferExpires) * time.Second).Unix(),
+			Audience:  "reva",
+			IssuedAt:  now.Unix(),
+			Subject:   transferSubject(ctx),
+		},
+		Target: target,
+		Scope:  uploadtoken.NewScope(target, time.Duration(s.c.UploadTransferMaxLifetime)*time.Second, now),
+	}
+
+	t := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), claims)
+
+	tkn, err := t.SignedString([]byte(s.c.TransferSharedSecret))
+	if err != nil {
+		return "", errors.Wrapf(err, "error signing token with claims %+v", claims)
+	}
+
+	return tkn, nil
+}
+
+// signOCM signs the transfer of a remote file of an OCM share to be cached by the datagateway.
+func (s *svc) signOCM(ctx context.Context, target string, ocm *ocmcache.Transfer) (string, error) {
 	// Tus sends a separate request to the datagateway service for every chunk.
 	// For large files, this can take a long time, so we extend the expiration
 	ttl := time.Duration(s.c.TransferExpires) * time.Second
@@ -67,8 +116,10 @@ func (s *svc) sign(_ context.Context, target string) (string, error) {
 			ExpiresAt: time.Now().Add(ttl).Unix(),
 			Audience:  "reva",
 			IssuedAt:  time.Now().Unix(),
+			Subject:   transferSubject(ctx),
 		},
 		Target: target,
+		OCM:    ocm,
 	}
 
 	t := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), claims)
@@ -102,7 +153,7 @@ func (s *svc) CreateHome(ctx context.Context, req *provider.CreateHomeRequest) (
 	return res, nil
 }
 
-func (s *svc) CreateStorageSpace(ctx context.Context, req *provider.CreateStorageSpaceRequest) (*provider.CreateStorageSpaceResponse, error) {
+func (s *svc) createStorageSpace(ctx context.Context, req *provider.CreateStorageSpaceRequest) (*provider.CreateStorageSpaceResponse, error) {
 	log := appctx.GetLogger(ctx)
 	// TODO: needs to be fixed
 	c, err := s.findByPath(ctx, "/users")
@@ -380,6 +431,19 @@ func (s *svc) InitiateFileDownload(ctx context.Context, req *provider.InitiateFi
 			}, nil
 		}
 
+		if protocol == "webdav" && s.ocmCache != nil && s.ocmCache.CachesContent() {
+			dp, err := s.webdavRefCachedDownload(ctx, statRes.Info.Target)
+			if err != nil {
+				return &gateway.InitiateFileDownloadResponse{
+					Status: status.NewInternal(ctx, err, "gateway: error downloading from webdav host: "+p),
+				}, nil
+			}
+			return &gateway.InitiateFileDownloadResponse{
+				Status:    status.NewOK(ctx),
+				Protocols: []*gateway.FileDownloadProtocol{dp},
+			}, nil
+		}
+
 		if protocol == "webdav" {
 			// TODO(ishank011): pass this through the datagateway service
 			// For now, we just expose the file server to the user
@@ -444,6 +508,19 @@ func (s *svc) InitiateFileDownload(ctx context.Context, req *provider.InitiateFi
 			}, nil
 		}
 
+		if protocol == "webdav" && s.ocmCache != nil && s.ocmCache.CachesContent() {
+			dp, err := s.webdavRefCachedDownload(ctx, statRes.Info.Target, shareChild)
+			if err != nil {
+				return &gateway.InitiateFileDownloadResponse{
+					Status: status.NewInternal(ctx, err, "gateway: error downloading from webdav host: "+p),
+				}, nil
+			}
+			return &gateway.InitiateFileDownloadResponse{
+				Status:    status.NewOK(ctx),
+				Protocols: []*gateway.FileDownloadProtocol{dp},
+			}, nil
+		}
+
 		if protocol == "webdav" {
 			// TODO(ishank011): pass this through the datagateway service
 			// For now, we just expose the file server to the user
@@ -510,7 +587,11 @@ func (s *svc) initiateFileDownload(ctx context.Context, req *provider.InitiateFi
 
 			// TODO(labkode): calculate signature of the whole request? we only sign the URI now. Maybe worth https://tools.ietf.org/html/draft-cavage-http-signatures-11
 			target := u.String()
-			token, err := s.sign(ctx, target)
+			sign := s.sign
+			if protocols[p].Protocol == "tus" {
+				sign = s.signUpload
+			}
+			token, err := sign(ctx, target)
 			if err != nil {
 				return &gateway.InitiateFileDownloadResponse{
 					Status: status.NewInternal(ctx, err, "error creating signature for download"),
@@ -529,7 +610,37 @@ func (s *svc) initiateFileDownload(ctx context.Context, req *provider.InitiateFi
 	}, nil
 }
 
-func (s *svc) InitiateFileUpload(ctx context.Context, req *provider.InitiateFileUploadRequest) (*gateway.InitiateFileUploadResponse, error) {
+// checkFilename validates the name of the resource a request is going to create
+// against the filename policy. References by id only are not checked as they
+// point to existing resources. Paths relative to a resource are resolved first
+// if the policy depends on the full path, the request is rejected if that fails.
+func (s *svc) checkFilename(ctx context.Context, ref *provider.Reference) *rpc.Status {
+	if ref == nil || ref.Path == "" {
+		return nil
+	}
+	fn := ref.Path
+	if !path.IsAbs(fn) && s.filenamePolicy.PathDependent() {
+		if ref.ResourceId == nil {
+			return status.NewInvalidArg(ctx, "relative path without a resource id")
+		}
+		parent, st := s.getPath(ctx, &provider.Reference{ResourceId: ref.ResourceId})
+		if st.Code != rpc.Code_CODE_OK {
+			return st
+		}
+		fn = path.Join(parent, fn)
+	}
+	if err := s.filenamePolicy.Check(fn); err != nil {
+		return status.NewInvalidArg(ctx, err.Error())
+	}
+	return nil
+}
+
+func (s *svc) initiateUpload(ctx context.Context, req *provider.InitiateFileUploadRequest) (*gateway.InitiateFileUploadResponse, error) {
+	if st := s.checkFilename(ctx, req.Ref); st != nil {
+		return &gateway.InitiateFileUploadResponse{
+			Status: st,
+		}, nil
+	}
 	log := appctx.GetLogger(ctx)
 	if utils.IsRelativeReference(req.Ref) {
 		return s.initiateFileUpload(ctx, req)
@@ -718,7 +829,11 @@ func (s *svc) initiateFileUpload(ctx context.Context, req *provider.InitiateFile
 
 			// TODO(labkode): calculate signature of the whole request? we only sign the URI now. Maybe worth https://tools.ietf.org/html/draft-cavage-http-signatures-11
 			target := u.String()
-			token, err := s.sign(ctx, target)
+			sign := s.sign
+			if protocols[p].Protocol == "tus" {
+				sign = s.signUpload
+			}
+			token, err := sign(ctx, target)
 			if err != nil {
 				return &gateway.InitiateFileUploadResponse{
 					Status: status.NewInternal(ctx, err, "error creating signature for upload"),
@@ -758,6 +873,11 @@ func (s *svc) GetPath(ctx context.Context, req *provider.GetPathRequest) (*provi
 }
 
 func (s *svc) CreateContainer(ctx context.Context, req *provider.CreateContainerRequest) (*provider.CreateContainerResponse, error) {
+	if st := s.checkFilename(ctx, req.Ref); st != nil {
+		return &provider.CreateContainerResponse{
+			Status: st,
+		}, nil
+	}
 	log := appctx.GetLogger(ctx)
 
 	if utils.IsRelativeReference(req.Ref) {
@@ -850,6 +970,11 @@ func (s *svc) createContainer(ctx context.Context, req *provider.CreateContainer
 }
 
 func (s *svc) TouchFile(ctx context.Context, req *provider.TouchFileRequest) (*provider.TouchFileResponse, error) {
+	if st := s.checkFilename(ctx, req.Ref); st != nil {
+		return &provider.TouchFileResponse{
+			Status: st,
+		}, nil
+	}
 	c, err := s.find(ctx, req.Ref)
 	if err != nil {
 		return &provider.TouchFileResponse{
@@ -875,6 +1000,10 @@ func (s *svc) inSharedFolder(ctx context.Context, p string) bool {
 }
 
 func (s *svc) Delete(ctx context.Context, req *provider.DeleteRequest) (*provider.DeleteResponse, error) {
+	if ctxpkg.ContextGetDryRun(ctx) {
+		return s.dryRunDelete(ctx, req)
+	}
+
 	log := appctx.GetLogger(ctx)
 	p, st := s.getPath(ctx, req.Ref)
 	if st.Code != rpc.Code_CODE_OK {
@@ -1028,6 +1157,14 @@ func (s *svc) delete(ctx context.Context, req *provider.DeleteRequest) (*provide
 }
 
 func (s *svc) Move(ctx context.Context, req *provider.MoveRequest) (*provider.MoveResponse, error) {
+	if st := s.checkFilename(ctx, req.Destination); st != nil {
+		return &provider.MoveResponse{
+			Status: st,
+		}, nil
+	}
+	if ctxpkg.ContextGetDryRun(ctx) {
+		return s.dryRunMove(ctx, req)
+	}
 	log := appctx.GetLogger(ctx)
 	p, st := s.getPath(ctx, req.Source)
 	if st.Code != rpc.Code_CODE_OK {
@@ -1473,6 +1610,8 @@ func (s *svc) statAcrossProviders(ctx context.Context, req *provider.StatRequest
 }
 
 func (s *svc) Stat(ctx context.Context, req *provider.StatRequest) (*provider.StatResponse, error) {
+	acknowledgeDryRun(ctx)
+
 	if utils.IsRelativeReference(req.Ref) {
 		return s.stat(ctx, req)
 	}
@@ -1892,6 +2031,41 @@ func (s *svc) listContainerAcrossProviders(ctx context.Context, req *provider.Li
 }
 
 func (s *svc) ListContainer(ctx context.Context, req *provider.ListContainerRequest) (*provider.ListContainerResponse, error) {
+	res, err := s.listContainerByRef(ctx, req)
+	if err != nil || !s.c.LocaleSorting || res.Status.Code != rpc.Code_CODE_OK {
+		return res, err
+	}
+	locale.Sort(res.Infos, s.userLocale(ctx))
+	return res, nil
+}
+
+// userLocale returns the locale preferred by the user of the context, falling
+// back to the default one.
+func (s *svc) userLocale(ctx context.Context) language.Tag {
+	log := appctx.GetLogger(ctx)
+	fallback, err := locale.Parse(s.c.DefaultLocale)
+	if err != nil {
+		fallback = language.English
+	}
+	res, err := s.GetKey(ctx, &preferences.GetKeyRequest{
+		Key: &preferences.PreferenceKey{Namespace: locale.PreferenceNamespace, Key: locale.PreferenceKey},
+	})
+	switch {
+	case err != nil:
+		log.Warn().Err(err).Msg("gateway: error getting the locale of the user")
+		return fallback
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return fallback
+	}
+	t, err := locale.Parse(res.Val)
+	if err != nil {
+		log.Warn().Err(err).Msg("gateway: user has an invalid locale")
+		return fallback
+	}
+	return t
+}
+
+func (s *svc) listContainerByRef(ctx context.Context, req *provider.ListContainerRequest) (*provider.ListContainerResponse, error) {
 	log := appctx.GetLogger(ctx)
 
 	if utils.IsRelativeReference(req.Ref) {
@@ -2240,6 +2414,10 @@ func (s *svc) RestoreRecycleItem(ctx context.Context, req *provider.RestoreRecyc
 }
 
 func (s *svc) PurgeRecycle(ctx context.Context, req *provider.PurgeRecycleRequest) (*provider.PurgeRecycleResponse, error) {
+	if ctxpkg.ContextGetDryRun(ctx) {
+		return s.dryRunPurgeRecycle(ctx, req)
+	}
+
 	c, err := s.find(ctx, req.Ref)
 	if err != nil {
 		return &provider.PurgeRecycleResponse{
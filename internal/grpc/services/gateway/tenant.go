@@ -0,0 +1,103 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/pkg/errors"
+)
+
+// checkShareTenant returns a PERMISSION_DENIED status if the current user is
+// not allowed to share with the tenant of the grantee.
+func (s *svc) checkShareTenant(ctx context.Context, g *provider.Grantee) *rpc.Status {
+	if !s.tenants.Enabled() {
+		return nil
+	}
+
+	var to string
+	switch g.GetType() {
+	case provider.GranteeType_GRANTEE_TYPE_USER:
+		res, err := s.GetUser(ctx, &userpb.GetUserRequest{UserId: g.GetUserId(), SkipFetchingUserGroups: true})
+		if err != nil {
+			return status.NewInternal(ctx, err, "error getting grantee")
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return res.Status
+		}
+		to = s.tenants.Of(res.User)
+	case provider.GranteeType_GRANTEE_TYPE_GROUP:
+		res, err := s.GetGroup(ctx, &grouppb.GetGroupRequest{GroupId: g.GetGroupId(), SkipFetchingMembers: true})
+		if err != nil {
+			return status.NewInternal(ctx, err, "error getting grantee")
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return res.Status
+		}
+		to = s.tenants.OfGroup(res.Group)
+	default:
+		return nil
+	}
+
+	u, _ := ctxpkg.ContextGetUser(ctx)
+	if from := s.tenants.Of(u); !s.tenants.CanShare(from, to) {
+		return status.NewPermissionDenied(ctx, errors.New("cross tenant sharing"), "sharing with tenant "+to+" is not allowed for tenant "+from)
+	}
+	return nil
+}
+
+// filterReceivedShares hides the shares created by users of tenants the
+// current user is not allowed to interact with.
+func (s *svc) filterReceivedShares(ctx context.Context, shares []*collaboration.ReceivedShare) []*collaboration.ReceivedShare {
+	if !s.tenants.Enabled() {
+		return shares
+	}
+
+	u, _ := ctxpkg.ContextGetUser(ctx)
+	current := s.tenants.Of(u)
+	tenants := map[string]string{}
+	filtered := make([]*collaboration.ReceivedShare, 0, len(shares))
+	for _, rs := range shares {
+		creator := rs.GetShare().GetCreator()
+		key := creator.GetIdp() + "!" + creator.GetOpaqueId()
+		t, ok := tenants[key]
+		if !ok {
+			res, err := s.GetUser(ctx, &userpb.GetUserRequest{UserId: creator, SkipFetchingUserGroups: true})
+			if err != nil || res.Status.Code != rpc.Code_CODE_OK {
+				appctx.GetLogger(ctx).Debug().Interface("creator", creator).Msg("could not look up share creator, hiding share")
+				continue
+			}
+			t = s.tenants.Of(res.User)
+			tenants[key] = t
+		}
+		// the creator must have been allowed to share with the current user
+		if s.tenants.CanShare(t, current) {
+			filtered = append(filtered, rs)
+		}
+	}
+	return filtered
+}
@@ -38,12 +38,24 @@ import (
 )
 
 // TODO(labkode): add multi-phase commit logic when commit share or commit ref is enabled.
-func (s *svc) CreateShare(ctx context.Context, req *collaboration.CreateShareRequest) (*collaboration.CreateShareResponse, error) {
+func (s *svc) createShare(ctx context.Context, req *collaboration.CreateShareRequest) (*collaboration.CreateShareResponse, error) {
 
 	if s.isSharedFolder(ctx, req.ResourceInfo.GetPath()) {
 		return nil, errtypes.AlreadyExists("gateway: can't share the share folder itself")
 	}
 
+	if st := s.checkShareTenant(ctx, req.GetGrant().GetGrantee()); st != nil {
+		return &collaboration.CreateShareResponse{
+			Status: st,
+		}, nil
+	}
+
+	if st := s.setSharePolicy(ctx, req.ResourceInfo); st != nil {
+		return &collaboration.CreateShareResponse{
+			Status: st,
+		}, nil
+	}
+
 	c, err := pool.GetUserShareProviderClient(pool.Endpoint(s.c.UserShareProviderEndpoint))
 	if err != nil {
 		return &collaboration.CreateShareResponse{
@@ -97,6 +109,10 @@ func (s *svc) CreateShare(ctx context.Context, req *collaboration.CreateShareReq
 }
 
 func (s *svc) RemoveShare(ctx context.Context, req *collaboration.RemoveShareRequest) (*collaboration.RemoveShareResponse, error) {
+	if ctxpkg.ContextGetDryRun(ctx) {
+		return s.dryRunRemoveShare(ctx, req)
+	}
+
 	c, err := pool.GetUserShareProviderClient(pool.Endpoint(s.c.UserShareProviderEndpoint))
 	if err != nil {
 		return &collaboration.RemoveShareResponse{
@@ -252,6 +268,9 @@ func (s *svc) ListReceivedShares(ctx context.Context, req *collaboration.ListRec
 	if err != nil {
 		return nil, errors.Wrap(err, "gateway: error calling ListReceivedShares")
 	}
+	if res.Status.Code == rpc.Code_CODE_OK {
+		res.Shares = s.filterReceivedShares(ctx, res.Shares)
+	}
 	return res, nil
 }
 
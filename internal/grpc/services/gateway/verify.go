@@ -0,0 +1,61 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"io"
+
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	verifypb "github.com/cs3org/reva/pkg/storage/utils/verify/proto"
+	"github.com/pkg/errors"
+)
+
+// VerifyChecksum forwards the verification of a file to the storage provider
+// holding it and relays its progress to the client.
+func (s *svc) VerifyChecksum(req *verifypb.VerifyChecksumRequest, stream verifypb.VerifyService_VerifyChecksumServer) error {
+	ctx := stream.Context()
+	providers, err := s.findProviders(ctx, req.Ref)
+	if err != nil {
+		return stream.Send(&verifypb.VerifyChecksumResponse{
+			Status: status.NewStatusFromErrType(ctx, "VerifyChecksum ref="+req.Ref.String(), err),
+		})
+	}
+
+	c, err := pool.GetVerifyServiceClient(pool.Endpoint(providers[0].Address))
+	if err != nil {
+		return errors.Wrap(err, "gateway: error getting a verify service client")
+	}
+	res, err := c.VerifyChecksum(ctx, req)
+	if err != nil {
+		return errors.Wrap(err, "gateway: error calling VerifyChecksum")
+	}
+	for {
+		msg, err := res.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "gateway: error receiving the verification progress")
+		}
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+	}
+}
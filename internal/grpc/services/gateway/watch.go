@@ -0,0 +1,106 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"github.com/asim/go-micro/plugins/events/nats/v4"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/events/server"
+	"github.com/cs3org/reva/pkg/watch"
+	watchpb "github.com/cs3org/reva/pkg/watch/proto"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+)
+
+func newWatchHub(c *config) (*watch.Hub, error) {
+	stream, err := server.NewNatsStream(nats.Address(c.WatchNatsAddress), nats.ClusterID(c.WatchNatsClusterID))
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error connecting to the event stream")
+	}
+
+	// every gateway instance serves its own watchers, so each needs to see all events
+	evs, err := events.Consume(stream, "gateway-watch-"+uuid.NewString(), watch.Events...)
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error consuming events")
+	}
+
+	hub := watch.NewHub()
+	go func() {
+		for ev := range evs {
+			hub.Dispatch(ev)
+		}
+	}()
+	return hub, nil
+}
+
+// Watch streams the changes to the referenced resource and its descendants
+// until the client cancels the call.
+func (s *svc) Watch(req *watchpb.WatchRequest, stream watchpb.WatchService_WatchServer) error {
+	ctx := stream.Context()
+	log := appctx.GetLogger(ctx)
+
+	if s.watchHub == nil {
+		return gstatus.Error(codes.Unimplemented, "gateway: watching resources is not enabled")
+	}
+
+	// the stat ensures the user has access to the watched resource
+	res, err := s.Stat(ctx, &provider.StatRequest{Ref: req.Ref})
+	if err != nil {
+		return errors.Wrap(err, "gateway: error calling Stat")
+	}
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+	case rpc.Code_CODE_NOT_FOUND:
+		return gstatus.Error(codes.NotFound, res.Status.Message)
+	case rpc.Code_CODE_PERMISSION_DENIED:
+		return gstatus.Error(codes.PermissionDenied, res.Status.Message)
+	default:
+		return gstatus.Error(codes.Internal, res.Status.Message)
+	}
+
+	root := watch.Root{Path: res.Info.Path, ID: res.Info.Id}
+	// the uploads are reported with the path inside the storage, they can be matched
+	// if the whole subtree is on the storage of the root
+	if providers, err := s.findProviders(ctx, &provider.Reference{Path: res.Info.Path}); err == nil && len(providers) == 1 {
+		root.MountPath = providers[0].ProviderPath
+	}
+
+	sub := s.watchHub.Subscribe(root, s.c.WatchBufferSize)
+	defer s.watchHub.Unsubscribe(sub)
+	log.Debug().Str("path", res.Info.Path).Msg("gateway: client started watching")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case change, ok := <-sub.C():
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(change); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -25,13 +25,16 @@ import (
 	"path"
 	"strings"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	ocmprovider "github.com/cs3org/go-cs3apis/cs3/ocm/provider/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	ocmcache "github.com/cs3org/reva/pkg/ocm/cache"
 	"github.com/pkg/errors"
 	"github.com/studio-b12/gowebdav"
+	"google.golang.org/protobuf/proto"
 )
 
 type webdavEndpoint struct {
@@ -50,6 +53,12 @@ func (s *svc) webdavRefStat(ctx context.Context, targetURL string, nameQueries .
 	if err != nil {
 		return nil, err
 	}
+	if s.ocmCache != nil {
+		if e, ok := s.ocmCache.Get(ocmcache.Stat, ep.cacheKey()); ok && s.ocmCache.Fresh(e.Fetched) {
+			return proto.Clone(e.Value.(*provider.ResourceInfo)).(*provider.ResourceInfo), nil
+		}
+	}
+
 	webdavEP, err := s.getWebdavEndpoint(ctx, ep.endpoint)
 	if err != nil {
 		return nil, err
@@ -64,7 +73,11 @@ func (s *svc) webdavRefStat(ctx context.Context, targetURL string, nameQueries .
 	if err != nil {
 		return nil, errors.Wrap(err, fmt.Sprintf("gateway: error statting %s at the webdav endpoint: %s", ep.filePath, webdavEP))
 	}
-	return normalize(info.(*gowebdav.File)), nil
+	md := normalize(info.(*gowebdav.File))
+	if s.ocmCache != nil {
+		s.ocmCache.Set(ocmcache.Stat, ep.cacheKey(), md.Etag, proto.Clone(md))
+	}
+	return md, nil
 }
 
 func (s *svc) webdavRefLs(ctx context.Context, targetURL string, nameQueries ...string) ([]*provider.ResourceInfo, error) {
@@ -85,6 +98,10 @@ func (s *svc) webdavRefLs(ctx context.Context, targetURL string, nameQueries ...
 	c := gowebdav.NewClient(webdavEP, "", "")
 	c.SetHeader(ctxpkg.TokenHeader, ep.token)
 
+	if s.ocmCache != nil {
+		return s.webdavRefCachedLs(c, ep, webdavEP)
+	}
+
 	// TODO(ishank011): We need to call PROPFIND ourselves as we need to retrieve
 	// ownloud-specific fields to get the resource ID and permissions.
 	infos, err := c.ReadDir(ep.filePath)
@@ -100,6 +117,47 @@ func (s *svc) webdavRefLs(ctx context.Context, targetURL string, nameQueries ...
 	return mds, nil
 }
 
+// webdavRefCachedLs lists a remote container using the cache of the OCM
+// resources. A cached listing is used as long as the ETag of the container
+// does not change, which only costs a stat instead of listing the container.
+func (s *svc) webdavRefCachedLs(c *gowebdav.Client, ep *webdavEndpoint, webdavEP string) ([]*provider.ResourceInfo, error) {
+	key := ep.cacheKey()
+	cached, ok := s.ocmCache.Get(ocmcache.Listing, key)
+	if ok && s.ocmCache.Fresh(cached.Fetched) {
+		return cloneInfos(cached.Value.([]*provider.ResourceInfo)), nil
+	}
+
+	info, err := c.Stat(ep.filePath)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("gateway: error statting %s at the webdav endpoint: %s", ep.filePath, webdavEP))
+	}
+	md := normalize(info.(*gowebdav.File))
+	s.ocmCache.Set(ocmcache.Stat, key, md.Etag, proto.Clone(md))
+	if ok && md.Etag != "" && md.Etag == cached.ETag {
+		s.ocmCache.Revalidated(ocmcache.Listing, key)
+		return cloneInfos(cached.Value.([]*provider.ResourceInfo)), nil
+	}
+
+	infos, err := c.ReadDir(ep.filePath)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("gateway: error listing %s at the webdav endpoint: %s", ep.filePath, webdavEP))
+	}
+
+	mds := []*provider.ResourceInfo{}
+	for _, fi := range infos {
+		info := fi.(gowebdav.File)
+		child := normalize(&info)
+		mds = append(mds, child)
+
+		// the clients usually stat the listed resources next
+		childKey := key
+		childKey.Path = path.Join(ep.filePath, info.Name())
+		s.ocmCache.Set(ocmcache.Stat, childKey, child.Etag, proto.Clone(child))
+	}
+	s.ocmCache.Set(ocmcache.Listing, key, md.Etag, cloneInfos(mds))
+	return mds, nil
+}
+
 func (s *svc) webdavRefMkdir(ctx context.Context, targetURL string, nameQueries ...string) error {
 	targetURL, err := appendNameQuery(targetURL, nameQueries...)
 	if err != nil {
@@ -118,6 +176,7 @@ func (s *svc) webdavRefMkdir(ctx context.Context, targetURL string, nameQueries
 	c := gowebdav.NewClient(webdavEP, "", "")
 	c.SetHeader(ctxpkg.TokenHeader, ep.token)
 
+	s.invalidateOCMCache(ep)
 	err = c.Mkdir(ep.filePath, 0700)
 	if err != nil {
 		return errors.Wrap(err, fmt.Sprintf("gateway: error creating dir %s at the webdav endpoint: %s", ep.filePath, webdavEP))
@@ -151,6 +210,8 @@ func (s *svc) webdavRefMove(ctx context.Context, targetURL, src, destination str
 	c := gowebdav.NewClient(srcWebdavEP, "", "")
 	c.SetHeader(ctxpkg.TokenHeader, srcEP.token)
 
+	s.invalidateOCMCache(srcEP)
+	s.invalidateOCMCache(destEP)
 	err = c.Rename(srcEP.filePath, destEP.filePath, true)
 	if err != nil {
 		return errors.Wrap(err, fmt.Sprintf("gateway: error renaming %s to %s at the webdav endpoint: %s", srcEP.filePath, destEP.filePath, srcWebdavEP))
@@ -176,6 +237,7 @@ func (s *svc) webdavRefDelete(ctx context.Context, targetURL string, nameQueries
 	c := gowebdav.NewClient(webdavEP, "", "")
 	c.SetHeader(ctxpkg.TokenHeader, ep.token)
 
+	s.invalidateOCMCache(ep)
 	err = c.Remove(ep.filePath)
 	if err != nil {
 		return errors.Wrap(err, fmt.Sprintf("gateway: error removing %s at the webdav endpoint: %s", ep.filePath, webdavEP))
@@ -212,6 +274,63 @@ func (s *svc) webdavRefTransferEndpoint(ctx context.Context, targetURL string, n
 	}, nil
 }
 
+// webdavRefCachedDownload passes the download of a remote file through the
+// datagateway, which serves the content from the cache of the OCM resources
+// as long as its ETag does not change.
+func (s *svc) webdavRefCachedDownload(ctx context.Context, targetURL string, nameQueries ...string) (*gateway.FileDownloadProtocol, error) {
+	md, err := s.webdavRefStat(ctx, targetURL, nameQueries...)
+	if err != nil {
+		return nil, err
+	}
+
+	targetURL, err = appendNameQuery(targetURL, nameQueries...)
+	if err != nil {
+		return nil, err
+	}
+	ep, err := s.extractEndpointInfo(ctx, targetURL)
+	if err != nil {
+		return nil, err
+	}
+	webdavEP, err := s.getWebdavEndpoint(ctx, ep.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(webdavEP)
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error parsing webdav endpoint: "+webdavEP)
+	}
+	u.Path = path.Join(u.Path, ep.filePath)
+
+	token, err := s.signOCM(ctx, u.String(), &ocmcache.Transfer{Key: ep.cacheKey(), ETag: md.Etag})
+	if err != nil {
+		return nil, err
+	}
+	return &gateway.FileDownloadProtocol{
+		Protocol:         "simple",
+		DownloadEndpoint: s.c.DataGatewayEndpoint,
+		Token:            token,
+	}, nil
+}
+
+// invalidateOCMCache drops the cached metadata of a remote resource changed through the gateway.
+func (s *svc) invalidateOCMCache(ep *webdavEndpoint) {
+	if s.ocmCache != nil {
+		s.ocmCache.Invalidate(ep.cacheKey())
+	}
+}
+
+func (ep *webdavEndpoint) cacheKey() ocmcache.Key {
+	return ocmcache.Key{Domain: ep.endpoint, Token: ep.token, Path: ep.filePath}
+}
+
+func cloneInfos(infos []*provider.ResourceInfo) []*provider.ResourceInfo {
+	clones := make([]*provider.ResourceInfo, 0, len(infos))
+	for _, info := range infos {
+		clones = append(clones, proto.Clone(info).(*provider.ResourceInfo))
+	}
+	return clones
+}
+
 func (s *svc) extractEndpointInfo(ctx context.Context, targetURL string) (*webdavEndpoint, error) {
 	if targetURL == "" {
 		return nil, errtypes.BadRequest("gateway: ref target is an empty uri")
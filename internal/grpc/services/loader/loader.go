@@ -37,6 +37,8 @@ import (
 	_ "github.com/cs3org/reva/internal/grpc/services/preferences"
 	_ "github.com/cs3org/reva/internal/grpc/services/publicshareprovider"
 	_ "github.com/cs3org/reva/internal/grpc/services/publicstorageprovider"
+	_ "github.com/cs3org/reva/internal/grpc/services/search"
+	_ "github.com/cs3org/reva/internal/grpc/services/spacesregistry"
 	_ "github.com/cs3org/reva/internal/grpc/services/storageprovider"
 	_ "github.com/cs3org/reva/internal/grpc/services/storageregistry"
 	_ "github.com/cs3org/reva/internal/grpc/services/userprovider"
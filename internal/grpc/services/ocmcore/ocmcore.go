@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
 	ocmcore "github.com/cs3org/go-cs3apis/cs3/ocm/core/v1beta1"
 	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
@@ -153,22 +154,33 @@ func (s *service) CreateOCMCoreShare(ctx context.Context, req *ocmcore.CreateOCM
 		}, nil
 	}
 
-	grant := &ocm.ShareGrant{
-		Grantee: &provider.Grantee{
-			Type: provider.GranteeType_GRANTEE_TYPE_USER,
-			// For now, we only support user shares.
-			// TODO (ishank011): To be updated once this is decided.
-			Id: &provider.Grantee_UserId{UserId: req.ShareWith},
-			// passing this in grant.Grantee.Opaque because ShareGrant itself doesn't have a root opaque.
-			Opaque: &typespb.Opaque{
-				Map: map[string]*typespb.OpaqueEntry{
-					"remoteShareId": {
-						Decoder: "plain",
-						Value:   []byte(req.ProviderId),
-					},
+	grantee := &provider.Grantee{
+		Type: provider.GranteeType_GRANTEE_TYPE_USER,
+		Id:   &provider.Grantee_UserId{UserId: req.ShareWith},
+		// passing this in grant.Grantee.Opaque because ShareGrant itself doesn't have a root opaque.
+		Opaque: &typespb.Opaque{
+			Map: map[string]*typespb.OpaqueEntry{
+				"remoteShareId": {
+					Decoder: "plain",
+					Value:   []byte(req.ProviderId),
 				},
 			},
 		},
+	}
+	// shares sent to remote groups have already been mapped to a local group
+	if groupOpaque, ok := req.Protocol.Opaque.Map["groupId"]; ok {
+		if groupOpaque.Decoder != "plain" {
+			err := errtypes.NotSupported("opaque entry decoder not recognized: " + groupOpaque.Decoder)
+			return &ocmcore.CreateOCMCoreShareResponse{
+				Status: status.NewInternal(ctx, err, "invalid opaque entry decoder"),
+			}, nil
+		}
+		grantee.Type = provider.GranteeType_GRANTEE_TYPE_GROUP
+		grantee.Id = &provider.Grantee_GroupId{GroupId: &grouppb.GroupId{OpaqueId: string(groupOpaque.Value)}}
+	}
+
+	grant := &ocm.ShareGrant{
+		Grantee: grantee,
 		Permissions: &ocm.SharePermissions{
 			Permissions: resourcePermissions,
 		},
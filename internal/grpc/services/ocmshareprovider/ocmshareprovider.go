@@ -266,7 +266,7 @@ func (s *service) UpdateReceivedOCMShare(ctx context.Context, req *ocm.UpdateRec
 	_, err := s.sm.UpdateReceivedShare(ctx, req.Share, req.UpdateMask) // TODO(labkode): check what to update
 	if err != nil {
 		return &ocm.UpdateReceivedOCMShareResponse{
-			Status: status.NewInternal(ctx, err, "error updating received share"),
+			Status: status.NewStatusFromErrType(ctx, "error updating received share", err),
 		}, nil
 	}
 
@@ -280,7 +280,7 @@ func (s *service) GetReceivedOCMShare(ctx context.Context, req *ocm.GetReceivedO
 	share, err := s.sm.GetReceivedShare(ctx, req.Ref)
 	if err != nil {
 		return &ocm.GetReceivedOCMShareResponse{
-			Status: status.NewInternal(ctx, err, "error getting received share"),
+			Status: status.NewStatusFromErrType(ctx, "error getting received share", err),
 		}, nil
 	}
 
@@ -21,16 +21,24 @@ package publicshareprovider
 import (
 	"context"
 	"regexp"
+	"time"
 
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/publicshare"
+	"github.com/cs3org/reva/pkg/publicshare/analytics"
+	analyticsregistry "github.com/cs3org/reva/pkg/publicshare/analytics/registry"
 	"github.com/cs3org/reva/pkg/publicshare/manager/registry"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/share/policy"
+	"github.com/cs3org/reva/pkg/utils"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
@@ -44,6 +52,8 @@ type config struct {
 	Driver                string                            `mapstructure:"driver"`
 	Drivers               map[string]map[string]interface{} `mapstructure:"drivers"`
 	AllowedPathsForShares []string                          `mapstructure:"allowed_paths_for_shares"`
+	// Analytics configures the statistics reported to the owners of the links.
+	Analytics analytics.Config `mapstructure:"analytics"`
 }
 
 func (c *config) init() {
@@ -56,6 +66,7 @@ type service struct {
 	conf                  *config
 	sm                    publicshare.Manager
 	allowedPathsForShares []*regexp.Regexp
+	analytics             *analytics.Analytics // nil if not configured
 }
 
 func getShareManager(c *config) (publicshare.Manager, error) {
@@ -65,6 +76,18 @@ func getShareManager(c *config) (publicshare.Manager, error) {
 	return nil, errtypes.NotFound("driver not found: " + c.Driver)
 }
 
+func getAnalytics(c *analytics.Config) (*analytics.Analytics, error) {
+	f, ok := analyticsregistry.NewFuncs[c.Driver]
+	if !ok {
+		return nil, errtypes.NotFound("analytics driver not found: " + c.Driver)
+	}
+	m, err := f(c.Drivers[c.Driver])
+	if err != nil {
+		return nil, err
+	}
+	return analytics.New(c, m)
+}
+
 // TODO(labkode): add ctx to Close.
 func (s *service) Close() error {
 	return nil
@@ -115,6 +138,11 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 		sm:                    sm,
 		allowedPathsForShares: allowedPathsForShares,
 	}
+	if c.Analytics.Enabled() {
+		if service.analytics, err = getAnalytics(&c.Analytics); err != nil {
+			return nil, err
+		}
+	}
 
 	return service, nil
 }
@@ -146,6 +174,16 @@ func (s *service) CreatePublicShare(ctx context.Context, req *link.CreatePublicS
 		log.Error().Msg("error getting user from context")
 	}
 
+	p, err := policy.FromOpaque(req.ResourceInfo.GetOpaque())
+	if err == nil {
+		err = p.CheckPublicShare(req.Grant, u, time.Now())
+	}
+	if err != nil {
+		return &link.CreatePublicShareResponse{
+			Status: status.NewStatusFromErrType(ctx, "public link creation is not allowed by the share policy of the space", err),
+		}, nil
+	}
+
 	share, err := s.sm.CreatePublicShare(ctx, u, req.ResourceInfo, req.Grant)
 	if err != nil {
 		log.Debug().Err(err).Str("createShare", "shares").Msg("error connecting to storage provider")
@@ -182,7 +220,14 @@ func (s *service) GetPublicShareByToken(ctx context.Context, req *link.GetPublic
 	found, err := s.sm.GetPublicShareByToken(ctx, req.GetToken(), req.GetAuthentication(), req.GetSign())
 	switch v := err.(type) {
 	case nil:
+		o, err := s.accessRulesOpaque(ctx, found)
+		if err != nil {
+			return &link.GetPublicShareByTokenResponse{
+				Status: status.NewInternal(ctx, err, "error getting access rules"),
+			}, nil
+		}
 		return &link.GetPublicShareByTokenResponse{
+			Opaque: o,
 			Status: status.NewOK(ctx),
 			Share:  found,
 		}, nil
@@ -215,17 +260,75 @@ func (s *service) GetPublicShare(ctx context.Context, req *link.GetPublicShareRe
 		return nil, err
 	}
 
+	o, err := s.accessRulesOpaque(ctx, found)
+	if err != nil {
+		return &link.GetPublicShareResponse{
+			Status: status.NewInternal(ctx, err, "error getting access rules"),
+		}, nil
+	}
+	if q, ok, err := analytics.QueryFromOpaque(req.Opaque); ok {
+		if err != nil {
+			return &link.GetPublicShareResponse{
+				Status: status.NewInvalidArg(ctx, "invalid analytics query"),
+			}, nil
+		}
+		var st *rpc.Status
+		if o, st = s.addAnalytics(ctx, u, found, q, o); st != nil {
+			return &link.GetPublicShareResponse{Status: st}, nil
+		}
+	}
 	return &link.GetPublicShareResponse{
+		Opaque: o,
 		Status: status.NewOK(ctx),
 		Share:  found,
 	}, nil
 }
 
+// accessRulesOpaque returns an opaque carrying the access rules of the share,
+// with the hashes of their passwords, so that the public storage provider can
+// enforce them. It is nil if the share has no rules.
+func (s *service) accessRulesOpaque(ctx context.Context, share *link.PublicShare) (*typesv1beta1.Opaque, error) {
+	m, ok := s.sm.(publicshare.AccessRulesManager)
+	if !ok || share == nil {
+		return nil, nil
+	}
+	rules, err := m.GetAccessRules(ctx, share.Id)
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+	return publicshare.AddAccessRulesToOpaque(nil, rules)
+}
+
+// addAnalytics adds the analytics report of a share to the given opaque,
+// only the owner and the creator of the share may read it.
+func (s *service) addAnalytics(ctx context.Context, u *userpb.User, share *link.PublicShare, q *analytics.Query, o *typesv1beta1.Opaque) (*typesv1beta1.Opaque, *rpc.Status) {
+	if s.analytics == nil {
+		return nil, status.NewUnimplemented(ctx, nil, "public link analytics are not enabled")
+	}
+	if !utils.UserEqual(share.Owner, u.GetId()) && !utils.UserEqual(share.Creator, u.GetId()) {
+		return nil, status.NewPermissionDenied(ctx, nil, "only the owner of the public share may read its analytics")
+	}
+	r, err := s.analytics.Report(ctx, share.Token, q)
+	if err != nil {
+		return nil, status.NewInternal(ctx, err, "error getting the analytics report")
+	}
+	if o, err = analytics.AddReportToOpaque(o, r); err != nil {
+		return nil, status.NewInternal(ctx, err, "error encoding the analytics report")
+	}
+	return o, nil
+}
+
 func (s *service) ListPublicShares(ctx context.Context, req *link.ListPublicSharesRequest) (*link.ListPublicSharesResponse, error) {
 	log := appctx.GetLogger(ctx)
 	log.Info().Str("publicshareprovider", "list").Msg("list public share")
 	user, _ := ctxpkg.ContextGetUser(ctx)
 
+	if _, ok := req.GetOpaque().GetMap()[publicshare.OpaqueResourceIDsOnly]; ok {
+		if ids, ok := publicshare.ResourceIDsOf(req.Filters); ok {
+			return s.listSharedResources(ctx, user, ids)
+		}
+	}
+
 	shares, err := s.sm.ListPublicShares(ctx, user, req.Filters, &provider.ResourceInfo{}, req.GetSign())
 	if err != nil {
 		log.Err(err).Msg("error listing shares")
@@ -241,6 +344,26 @@ func (s *service) ListPublicShares(ctx context.Context, req *link.ListPublicShar
 	return res, nil
 }
 
+// listSharedResources answers the listings which only need to know which
+// resources have public links with links holding only the resource id.
+func (s *service) listSharedResources(ctx context.Context, u *userpb.User, ids []*provider.ResourceId) (*link.ListPublicSharesResponse, error) {
+	shared, err := publicshare.ListSharedResources(ctx, s.sm, u, ids)
+	if err != nil {
+		return &link.ListPublicSharesResponse{
+			Status: status.NewInternal(ctx, err, "error listing shared resources"),
+		}, nil
+	}
+
+	shares := make([]*link.PublicShare, 0, len(shared))
+	for _, id := range shared {
+		shares = append(shares, &link.PublicShare{ResourceId: id})
+	}
+	return &link.ListPublicSharesResponse{
+		Status: status.NewOK(ctx),
+		Share:  shares,
+	}, nil
+}
+
 func (s *service) UpdatePublicShare(ctx context.Context, req *link.UpdatePublicShareRequest) (*link.UpdatePublicShareResponse, error) {
 	log := appctx.GetLogger(ctx)
 	log.Info().Str("publicshareprovider", "update").Msg("update public share")
@@ -250,14 +373,59 @@ func (s *service) UpdatePublicShare(ctx context.Context, req *link.UpdatePublicS
 		log.Error().Msg("error getting user from context")
 	}
 
-	updateR, err := s.sm.UpdatePublicShare(ctx, u, req, nil)
+	if rules, ok, err := publicshare.AccessRulesFromOpaque(req.GetOpaque()); ok {
+		if err != nil {
+			return &link.UpdatePublicShareResponse{
+				Status: status.NewInvalidArg(ctx, err.Error()),
+			}, nil
+		}
+		if st := s.setAccessRules(ctx, u, req.Ref, rules); st != nil {
+			return &link.UpdatePublicShareResponse{
+				Status: st,
+			}, nil
+		}
+	}
+
+	var updateR *link.PublicShare
+	var err error
+	if req.GetUpdate() != nil {
+		updateR, err = s.sm.UpdatePublicShare(ctx, u, req, nil)
+	} else {
+		// only the access rules were updated
+		updateR, err = s.sm.GetPublicShare(ctx, u, req.Ref, false)
+	}
 	if err != nil {
 		log.Err(err).Msgf("error updating public shares: %v", err)
 	}
 
+	o, err := s.accessRulesOpaque(ctx, updateR)
+	if err != nil {
+		log.Err(err).Msg("error getting access rules")
+	}
 	res := &link.UpdatePublicShareResponse{
+		Opaque: o,
 		Status: status.NewOK(ctx),
 		Share:  updateR,
 	}
 	return res, nil
 }
+
+// setAccessRules replaces the access rules of a share, which only its owner
+// and its creator may change.
+func (s *service) setAccessRules(ctx context.Context, u *userpb.User, ref *link.PublicShareReference, rules publicshare.AccessRules) *rpc.Status {
+	m, ok := s.sm.(publicshare.AccessRulesManager)
+	if !ok {
+		return status.NewUnimplemented(ctx, nil, "the public share manager does not support access rules")
+	}
+	share, err := s.sm.GetPublicShare(ctx, u, ref, false)
+	if err != nil {
+		return status.NewNotFound(ctx, "public share not found")
+	}
+	if !utils.UserEqual(share.Owner, u.GetId()) && !utils.UserEqual(share.Creator, u.GetId()) {
+		return status.NewPermissionDenied(ctx, nil, "only the owner of the public share may change its access rules")
+	}
+	if err := m.SetAccessRules(ctx, u, ref, rules); err != nil {
+		return status.NewInternal(ctx, err, "error setting access rules")
+	}
+	return nil
+}
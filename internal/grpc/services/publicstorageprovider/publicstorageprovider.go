@@ -30,7 +30,9 @@ import (
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/publicshare"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
@@ -108,7 +110,7 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 }
 
 func (s *service) SetArbitraryMetadata(ctx context.Context, req *provider.SetArbitraryMetadataRequest) (*provider.SetArbitraryMetadataResponse, error) {
-	ref, _, _, st, err := s.translatePublicRefToCS3Ref(ctx, req.Ref)
+	ref, _, _, st, err := s.translatePublicRefToCS3Ref(ctx, req.Ref, true)
 	switch {
 	case err != nil:
 		return nil, err
@@ -168,26 +170,28 @@ func (s *service) InitiateFileDownload(ctx context.Context, req *provider.Initia
 	return s.initiateFileDownload(ctx, req)
 }
 
-func (s *service) translatePublicRefToCS3Ref(ctx context.Context, ref *provider.Reference) (*provider.Reference, string, *link.PublicShare, *rpc.Status, error) {
+// translatePublicRefToCS3Ref resolves a reference below a public link. The
+// access rules of the link are checked for a read or, if write is set, for a
+// change of the referenced resource.
+func (s *service) translatePublicRefToCS3Ref(ctx context.Context, ref *provider.Reference, write bool) (*provider.Reference, string, *link.PublicShare, *rpc.Status, error) {
 	log := appctx.GetLogger(ctx)
 	tkn, relativePath, err := s.unwrap(ctx, ref)
 	if err != nil {
 		return nil, "", nil, nil, err
 	}
 
-	ls, shareInfo, st, err := s.resolveToken(ctx, tkn)
+	ls, shareInfo, rules, st, err := s.resolveToken(ctx, tkn)
 	switch {
 	case err != nil:
 		return nil, "", nil, nil, err
 	case st != nil:
 		return nil, "", nil, st, nil
 	}
-
-	p := shareInfo.Path
-	if shareInfo.Type != provider.ResourceType_RESOURCE_TYPE_FILE {
-		p = path.Join("/", shareInfo.Path, relativePath)
+	if st := checkAccess(ctx, rules, relativePath, write); st != nil {
+		return nil, "", nil, st, nil
 	}
-	cs3Ref := &provider.Reference{Path: p}
+
+	cs3Ref := shareRef(ls, shareInfo, relativePath)
 
 	log.Debug().
 		Interface("sourceRef", ref).
@@ -200,6 +204,16 @@ func (s *service) translatePublicRefToCS3Ref(ctx context.Context, ref *provider.
 	return cs3Ref, tkn, ls, nil, nil
 }
 
+// shareRef returns a reference to the given path below the shared resource.
+// References are relative to the shared resource id, so that links on spaces,
+// which are not mounted at a global path, resolve like links on a folder.
+func shareRef(ls *link.PublicShare, shareInfo *provider.ResourceInfo, relativePath string) *provider.Reference {
+	if shareInfo.Type == provider.ResourceType_RESOURCE_TYPE_FILE {
+		return &provider.Reference{ResourceId: ls.ResourceId}
+	}
+	return &provider.Reference{ResourceId: ls.ResourceId, Path: utils.MakeRelativePath(relativePath)}
+}
+
 // Both, t.dir and tokenPath paths need to be merged:
 // tokenPath   = /oc/einstein/public-links
 // t.dir       = /public/ausGxuUePCOi/foldera/folderb/
@@ -208,7 +222,7 @@ func (s *service) translatePublicRefToCS3Ref(ctx context.Context, ref *provider.
 // end         = /einstein/files/public-links/foldera/folderb/
 
 func (s *service) initiateFileDownload(ctx context.Context, req *provider.InitiateFileDownloadRequest) (*provider.InitiateFileDownloadResponse, error) {
-	cs3Ref, _, ls, st, err := s.translatePublicRefToCS3Ref(ctx, req.Ref)
+	cs3Ref, _, ls, st, err := s.translatePublicRefToCS3Ref(ctx, req.Ref, false)
 	switch {
 	case err != nil:
 		return nil, err
@@ -260,7 +274,7 @@ func (s *service) initiateFileDownload(ctx context.Context, req *provider.Initia
 }
 
 func (s *service) InitiateFileUpload(ctx context.Context, req *provider.InitiateFileUploadRequest) (*provider.InitiateFileUploadResponse, error) {
-	cs3Ref, _, ls, st, err := s.translatePublicRefToCS3Ref(ctx, req.Ref)
+	cs3Ref, _, ls, st, err := s.translatePublicRefToCS3Ref(ctx, req.Ref, true)
 	switch {
 	case err != nil:
 		return nil, err
@@ -352,7 +366,7 @@ func (s *service) CreateContainer(ctx context.Context, req *provider.CreateConta
 		Value: attribute.StringValue(req.Ref.String()),
 	})
 
-	cs3Ref, _, ls, st, err := s.translatePublicRefToCS3Ref(ctx, req.Ref)
+	cs3Ref, _, ls, st, err := s.translatePublicRefToCS3Ref(ctx, req.Ref, true)
 	switch {
 	case err != nil:
 		return nil, err
@@ -384,7 +398,7 @@ func (s *service) CreateContainer(ctx context.Context, req *provider.CreateConta
 }
 
 func (s *service) TouchFile(ctx context.Context, req *provider.TouchFileRequest) (*provider.TouchFileResponse, error) {
-	ref, _, _, st, err := s.translatePublicRefToCS3Ref(ctx, req.Ref)
+	ref, _, _, st, err := s.translatePublicRefToCS3Ref(ctx, req.Ref, true)
 	switch {
 	case err != nil:
 		return nil, err
@@ -405,7 +419,7 @@ func (s *service) Delete(ctx context.Context, req *provider.DeleteRequest) (*pro
 		Value: attribute.StringValue(req.Ref.String()),
 	})
 
-	cs3Ref, _, ls, st, err := s.translatePublicRefToCS3Ref(ctx, req.Ref)
+	cs3Ref, _, ls, st, err := s.translatePublicRefToCS3Ref(ctx, req.Ref, true)
 	switch {
 	case err != nil:
 		return nil, err
@@ -451,7 +465,7 @@ func (s *service) Move(ctx context.Context, req *provider.MoveRequest) (*provide
 		},
 	)
 
-	cs3RefSource, tknSource, ls, st, err := s.translatePublicRefToCS3Ref(ctx, req.Source)
+	cs3RefSource, tknSource, ls, st, err := s.translatePublicRefToCS3Ref(ctx, req.Source, true)
 	switch {
 	case err != nil:
 		return nil, err
@@ -465,7 +479,7 @@ func (s *service) Move(ctx context.Context, req *provider.MoveRequest) (*provide
 		}, nil
 	}
 	// FIXME: maybe there's a shortcut possible here using the source path
-	cs3RefDestination, tknDest, _, st, err := s.translatePublicRefToCS3Ref(ctx, req.Destination)
+	cs3RefDestination, tknDest, _, st, err := s.translatePublicRefToCS3Ref(ctx, req.Destination, true)
 	switch {
 	case err != nil:
 		return nil, err
@@ -529,7 +543,7 @@ func (s *service) Stat(ctx context.Context, req *provider.StatRequest) (*provide
 		}
 	}
 
-	share, shareInfo, st, err := s.resolveToken(ctx, tkn)
+	share, shareInfo, rules, st, err := s.resolveToken(ctx, tkn)
 	switch {
 	case err != nil:
 		return nil, err
@@ -543,12 +557,18 @@ func (s *service) Stat(ctx context.Context, req *provider.StatRequest) (*provide
 		}, nil
 	}
 
+	if st := checkAccess(ctx, rules, relativePath, false); st != nil {
+		return &provider.StatResponse{
+			Status: st,
+		}, nil
+	}
+
 	if shareInfo.Type == provider.ResourceType_RESOURCE_TYPE_FILE || (relativePath == "" && nodeID == "") || shareInfo.Id.OpaqueId == nodeID {
 		res := &provider.StatResponse{
 			Status: status.NewOK(ctx),
 			Info:   shareInfo,
 		}
-		s.augmentStatResponse(ctx, res, shareInfo, share, tkn)
+		s.augmentStatResponse(ctx, res, shareInfo, share, rules, tkn, "")
 		return res, nil
 	}
 
@@ -559,8 +579,7 @@ func (s *service) Stat(ctx context.Context, req *provider.StatRequest) (*provide
 			OpaqueId:  nodeID,
 		}}
 	} else if req.Ref.Path != "" {
-		p := path.Join("/", shareInfo.Path, relativePath)
-		ref = &provider.Reference{Path: p}
+		ref = shareRef(share, shareInfo, relativePath)
 	}
 
 	statResponse, err := s.gateway.Stat(ctx, &provider.StatRequest{Ref: ref})
@@ -570,28 +589,38 @@ func (s *service) Stat(ctx context.Context, req *provider.StatRequest) (*provide
 		}, nil
 	}
 
-	s.augmentStatResponse(ctx, statResponse, shareInfo, share, tkn)
+	if req.Ref.ResourceId != nil && statResponse.Info != nil {
+		// both paths were resolved by id, so they share the same root
+		relativePath = strings.TrimPrefix(statResponse.Info.Path, shareInfo.Path)
+		if st := checkAccess(ctx, rules, relativePath, false); st != nil {
+			return &provider.StatResponse{
+				Status: st,
+			}, nil
+		}
+	}
+	s.augmentStatResponse(ctx, statResponse, shareInfo, share, rules, tkn, relativePath)
 
 	return statResponse, nil
 }
 
-func (s *service) augmentStatResponse(ctx context.Context, res *provider.StatResponse, shareInfo *provider.ResourceInfo, share *link.PublicShare, tkn string) {
+func (s *service) augmentStatResponse(ctx context.Context, res *provider.StatResponse, shareInfo *provider.ResourceInfo, share *link.PublicShare, rules publicshare.AccessRules, tkn, relativePath string) {
 	// prevent leaking internal paths
 	if res.Info != nil {
 		if err := addShare(res.Info, share); err != nil {
 			appctx.GetLogger(ctx).Error().Err(err).Interface("share", share).Interface("info", res.Info).Msg("error when adding share")
 		}
 
-		var sharePath string
+		sharePath := relativePath
 		if shareInfo.Type == provider.ResourceType_RESOURCE_TYPE_FILE {
 			sharePath = path.Base(shareInfo.Path)
-		} else {
-			sharePath = strings.TrimPrefix(res.Info.Path, shareInfo.Path)
 		}
 
 		res.Info.Path = path.Join(s.mountPath, "/", tkn, sharePath)
 		s.setPublicStorageID(res.Info, tkn)
 		filterPermissions(res.Info.PermissionSet, share.GetPermissions().Permissions)
+		if rules.Evaluate(relativePath, "").ReadOnly {
+			filterPermissions(res.Info.PermissionSet, readOnlyPermissions)
+		}
 	}
 }
 
@@ -627,7 +656,7 @@ func (s *service) ListContainer(ctx context.Context, req *provider.ListContainer
 		return nil, err
 	}
 
-	share, shareInfo, st, err := s.resolveToken(ctx, tkn)
+	share, shareInfo, rules, st, err := s.resolveToken(ctx, tkn)
 	switch {
 	case err != nil:
 		return nil, err
@@ -636,15 +665,26 @@ func (s *service) ListContainer(ctx context.Context, req *provider.ListContainer
 			Status: st,
 		}, nil
 	}
+	if st := checkAccess(ctx, rules, relativePath, false); st != nil {
+		return &provider.ListContainerResponse{
+			Status: st,
+		}, nil
+	}
 	if share.GetPermissions() == nil || !share.GetPermissions().Permissions.ListContainer {
 		return &provider.ListContainerResponse{
 			Status: status.NewPermissionDenied(ctx, nil, "share does not grant ListContainer permission"),
 		}, nil
 	}
+	if publicshare.IsUploadOnly(share.GetPermissions().Permissions) {
+		// the contents of a file drop are not revealed to the recipients
+		return &provider.ListContainerResponse{
+			Status: status.NewPermissionDenied(ctx, nil, "share is upload only"),
+		}, nil
+	}
 
 	listContainerR, err := s.gateway.ListContainer(
 		ctx,
-		&provider.ListContainerRequest{Ref: &provider.Reference{Path: path.Join("/", shareInfo.Path, relativePath)}},
+		&provider.ListContainerRequest{Ref: shareRef(share, shareInfo, relativePath)},
 	)
 	if err != nil {
 		return &provider.ListContainerResponse{
@@ -652,14 +692,25 @@ func (s *service) ListContainer(ctx context.Context, req *provider.ListContainer
 		}, nil
 	}
 
-	for i := range listContainerR.Infos {
-		filterPermissions(listContainerR.Infos[i].PermissionSet, share.GetPermissions().Permissions)
-		listContainerR.Infos[i].Path = path.Join(s.mountPath, "/", tkn, relativePath, path.Base(listContainerR.Infos[i].Path))
-		s.setPublicStorageID(listContainerR.Infos[i], tkn)
-		if err := addShare(listContainerR.Infos[i], share); err != nil {
-			appctx.GetLogger(ctx).Error().Err(err).Interface("share", share).Interface("info", listContainerR.Infos[i]).Msg("error when adding share")
+	infos := listContainerR.Infos[:0]
+	for _, info := range listContainerR.Infos {
+		childPath := path.Join(relativePath, path.Base(info.Path))
+		access := rules.Evaluate(childPath, "")
+		if access.Hidden {
+			continue
 		}
+		filterPermissions(info.PermissionSet, share.GetPermissions().Permissions)
+		if access.ReadOnly {
+			filterPermissions(info.PermissionSet, readOnlyPermissions)
+		}
+		info.Path = path.Join(s.mountPath, "/", tkn, childPath)
+		s.setPublicStorageID(info, tkn)
+		if err := addShare(info, share); err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Interface("share", share).Interface("info", info).Msg("error when adding share")
+		}
+		infos = append(infos, info)
 	}
+	listContainerR.Infos = infos
 
 	return listContainerR, nil
 }
@@ -775,11 +826,12 @@ func (s *service) trimMountPrefix(fn string) (string, error) {
 	return "", errors.Errorf("path=%q does not belong to this storage provider mount path=%q"+fn, s.mountPath)
 }
 
-// resolveToken returns the path and share for the publicly shared resource.
-func (s *service) resolveToken(ctx context.Context, token string) (*link.PublicShare, *provider.ResourceInfo, *rpc.Status, error) {
+// resolveToken returns the path, share and access rules for the publicly
+// shared resource.
+func (s *service) resolveToken(ctx context.Context, token string) (*link.PublicShare, *provider.ResourceInfo, publicshare.AccessRules, *rpc.Status, error) {
 	driver, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewayAddr))
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	}
 
 	publicShareResponse, err := driver.GetPublicShare(
@@ -795,9 +847,13 @@ func (s *service) resolveToken(ctx context.Context, token string) (*link.PublicS
 	)
 	switch {
 	case err != nil:
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	case publicShareResponse.Status.Code != rpc.Code_CODE_OK:
-		return nil, nil, publicShareResponse.Status, nil
+		return nil, nil, nil, publicShareResponse.Status, nil
+	}
+	rules, _, err := publicshare.AccessRulesFromOpaque(publicShareResponse.Opaque)
+	if err != nil {
+		return nil, nil, nil, nil, err
 	}
 
 	sRes, err := s.gateway.Stat(ctx, &provider.StatRequest{
@@ -807,9 +863,42 @@ func (s *service) resolveToken(ctx context.Context, token string) (*link.PublicS
 	})
 	switch {
 	case err != nil:
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	case sRes.Status.Code != rpc.Code_CODE_OK:
-		return nil, nil, sRes.Status, nil
+		return nil, nil, nil, sRes.Status, nil
 	}
-	return publicShareResponse.GetShare(), sRes.Info, nil, nil
+	return publicShareResponse.GetShare(), sRes.Info, rules, nil, nil
+}
+
+// readOnlyPermissions are the permissions left on the read-only subtrees of a
+// public link.
+var readOnlyPermissions = &provider.ResourcePermissions{
+	GetPath:              true,
+	GetQuota:             true,
+	InitiateFileDownload: true,
+	ListContainer:        true,
+	ListFileVersions:     true,
+	ListGrants:           true,
+	ListRecycle:          true,
+	Stat:                 true,
+}
+
+// checkAccess evaluates the access rules of a public link on a path below the
+// shared folder. Hidden paths are not found, password protected paths need
+// the folder password of the request, and read-only paths can't be changed.
+func checkAccess(ctx context.Context, rules publicshare.AccessRules, relativePath string, write bool) *rpc.Status {
+	if len(rules) == 0 {
+		return nil
+	}
+	pw, _ := ctxpkg.ContextGetFolderPassword(ctx)
+	access := rules.Evaluate(relativePath, pw)
+	switch {
+	case access.Hidden:
+		return status.NewNotFound(ctx, "file not found")
+	case access.Locked:
+		return status.NewPermissionDenied(ctx, nil, "folder is password protected")
+	case write && access.ReadOnly:
+		return status.NewPermissionDenied(ctx, nil, "folder is read only")
+	}
+	return nil
 }
@@ -0,0 +1,187 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package search
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/search"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/metadata"
+)
+
+// indexedEvents are the events changing the indexed resources.
+var indexedEvents = []events.Unmarshaller{
+	events.ContainerCreated{},
+	events.FileTouched{},
+	events.FileUploaded{},
+	events.ItemMoved{},
+	events.ItemTrashed{},
+}
+
+// space is a space a user searched in, which is crawled on their behalf.
+type space struct {
+	user *userpb.UserId
+	root *provider.ResourceId
+}
+
+// track remembers the space of the user for the periodic crawls and crawls it
+// right away if it has not been indexed yet.
+func (s *service) track(client gateway.GatewayAPIClient, user *userpb.UserId, root *provider.ResourceId) {
+	id := search.ID(root)
+	s.mu.Lock()
+	_, known := s.spaces[id]
+	s.spaces[id] = &space{user: user, root: root}
+	s.mu.Unlock()
+	if known {
+		return
+	}
+
+	if _, err := s.index.Get(context.Background(), id); err != nil {
+		go s.crawl(client, &space{user: user, root: root})
+	}
+}
+
+// crawl indexes the space again, unless it is being crawled already.
+func (s *service) crawl(client gateway.GatewayAPIClient, sp *space) {
+	id := search.ID(sp.root)
+	s.mu.Lock()
+	if s.crawling[id] {
+		s.mu.Unlock()
+		return
+	}
+	s.crawling[id] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.crawling, id)
+		s.mu.Unlock()
+	}()
+
+	ctx, err := s.impersonate(client, sp.user)
+	if err == nil {
+		err = s.crawler.Crawl(ctx, &provider.Reference{ResourceId: sp.root})
+	}
+	if err != nil {
+		log.Error().Err(err).Str("space", id).Str("user", sp.user.OpaqueId).Msg("search: error crawling space")
+	}
+}
+
+// crawlPeriodically crawls the tracked spaces one after the other.
+func (s *service) crawlPeriodically(client gateway.GatewayAPIClient, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			spaces := make([]*space, 0, len(s.spaces))
+			for _, sp := range s.spaces {
+				spaces = append(spaces, sp)
+			}
+			s.mu.Unlock()
+
+			for _, sp := range spaces {
+				s.crawl(client, sp)
+			}
+		}
+	}
+}
+
+// handleEvent indexes the resource changed by the event on behalf of the user
+// who changed it.
+func (s *service) handleEvent(client gateway.GatewayAPIClient, ev interface{}) {
+	var executant *userpb.UserId
+	var ref *provider.Reference
+	var removed bool
+	switch e := ev.(type) {
+	case events.ContainerCreated:
+		executant, ref = e.Executant, e.Ref
+	case events.FileTouched:
+		executant, ref = e.Executant, e.Ref
+	case events.FileUploaded:
+		executant, ref = e.Executant, e.Ref
+	case events.ItemMoved:
+		// the resources keep their ids, so their entries are replaced
+		executant, ref = e.Executant, e.Ref
+	case events.ItemTrashed:
+		executant, ref, removed = e.Executant, e.Ref, true
+	}
+	if executant == nil || ref == nil {
+		return
+	}
+
+	ctx, err := s.impersonate(client, executant)
+	switch {
+	case err != nil:
+	case !removed:
+		err = s.crawler.Crawl(ctx, ref)
+	case ref.Path == "" || ref.Path == ".":
+		err = s.index.Delete(ctx, search.ID(ref.ResourceId))
+	default:
+		// the trashed resource cannot be looked up anymore, its parent is indexed again
+		err = s.crawler.Crawl(ctx, parent(ref))
+	}
+	if err != nil {
+		log.Error().Err(err).Interface("ref", ref).Str("user", executant.OpaqueId).Msg("search: error indexing changed resource")
+	}
+}
+
+// parent returns the reference of the folder holding the referenced resource.
+func parent(ref *provider.Reference) *provider.Reference {
+	dir := path.Dir(ref.Path)
+	if ref.ResourceId != nil && !strings.HasPrefix(dir, "/") && dir != "." {
+		dir = "./" + dir
+	}
+	return &provider.Reference{ResourceId: ref.ResourceId, Path: dir}
+}
+
+// impersonate returns a context authenticated as the given user.
+func (s *service) impersonate(client gateway.GatewayAPIClient, id *userpb.UserId) (context.Context, error) {
+	res, err := client.Authenticate(context.Background(), &gateway.AuthenticateRequest{
+		Type:         "machine",
+		ClientId:     "userid:" + id.OpaqueId,
+		ClientSecret: s.conf.MachineAuthAPIKey,
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, errtypes.InternalError(fmt.Sprintf("error authenticating as %s: %s", id.OpaqueId, res.Status.Message))
+	}
+
+	ctx := ctxpkg.ContextSetToken(context.Background(), res.Token)
+	ctx = ctxpkg.ContextSetUser(ctx, res.User)
+	ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.TokenHeader, res.Token)
+	return ctx, nil
+}
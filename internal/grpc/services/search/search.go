@@ -0,0 +1,267 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package search
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/asim/go-micro/plugins/events/nats/v4"
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/events/server"
+	"github.com/cs3org/reva/pkg/rgrpc"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/search"
+	_ "github.com/cs3org/reva/pkg/search/index/loader"
+	"github.com/cs3org/reva/pkg/search/index/registry"
+	"github.com/cs3org/reva/pkg/search/proto"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+func init() {
+	rgrpc.Register("search", New)
+}
+
+type config struct {
+	GatewaySvc string                            `mapstructure:"gatewaysvc"`
+	Index      string                            `mapstructure:"index" docs:"bolt;The index storing the metadata of the resources."`
+	Indexes    map[string]map[string]interface{} `mapstructure:"indexes"`
+	// MachineAuthAPIKey is the key of the machine auth provider, used to index the resources on behalf of the users.
+	MachineAuthAPIKey string `mapstructure:"machine_auth_apikey"`
+	CrawlInterval     int    `mapstructure:"crawl_interval" docs:"60;Minutes between two crawls of the spaces the users searched in, a negative value disables them."`
+	Limit             int    `mapstructure:"limit" docs:"100;Maximum number of matches returned at once."`
+	// NatsAddress is the event stream the changes to the resources are consumed from, so that
+	// they are indexed right away. Otherwise they are only indexed by the next crawl.
+	NatsAddress   string `mapstructure:"nats_address"`
+	NatsClusterID string `mapstructure:"nats_clusterid"`
+}
+
+func (c *config) init() {
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+	if c.Index == "" {
+		c.Index = "bolt"
+	}
+	if c.CrawlInterval == 0 {
+		c.CrawlInterval = 60
+	}
+	if c.Limit <= 0 {
+		c.Limit = 100
+	}
+}
+
+type service struct {
+	conf    *config
+	index   search.Index
+	crawler *search.Crawler
+
+	mu       sync.Mutex
+	spaces   map[string]*space // the spaces the users searched in, by id of their root
+	crawling map[string]bool
+	done     chan struct{}
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		err = errors.Wrap(err, "error decoding conf")
+		return nil, err
+	}
+	return c, nil
+}
+
+// New returns a new SearchServiceServer, which indexes the spaces of the users
+// when they first search and crawls them periodically afterwards. The changes
+// published on the event stream are indexed as they happen.
+func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
+	c, err := parseConfig(m)
+	if err != nil {
+		return nil, err
+	}
+	c.init()
+
+	if c.MachineAuthAPIKey == "" {
+		return nil, errors.New("search: no machine auth api key configured")
+	}
+	f, ok := registry.NewFuncs[c.Index]
+	if !ok {
+		return nil, errtypes.NotFound("search: index not found: " + c.Index)
+	}
+	index, err := f(c.Indexes[c.Index])
+	if err != nil {
+		return nil, errors.Wrap(err, "search: error creating the index")
+	}
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(c.GatewaySvc))
+	if err != nil {
+		return nil, errors.Wrap(err, "search: error getting gateway client")
+	}
+
+	s := &service{
+		conf:     c,
+		index:    index,
+		crawler:  search.NewCrawler(index, client),
+		spaces:   map[string]*space{},
+		crawling: map[string]bool{},
+		done:     make(chan struct{}),
+	}
+
+	if c.NatsAddress != "" {
+		stream, err := server.NewNatsStream(nats.Address(c.NatsAddress), nats.ClusterID(c.NatsClusterID))
+		if err != nil {
+			return nil, errors.Wrap(err, "search: error connecting to the event stream")
+		}
+		// all the instances share the index, so every change is indexed once
+		evs, err := events.Consume(stream, "search", indexedEvents...)
+		if err != nil {
+			return nil, errors.Wrap(err, "search: error consuming events")
+		}
+		go func() {
+			for ev := range evs {
+				s.handleEvent(client, ev)
+			}
+		}()
+	}
+	if c.CrawlInterval > 0 {
+		go s.crawlPeriodically(client, time.Duration(c.CrawlInterval)*time.Minute)
+	}
+
+	return s, nil
+}
+
+func (s *service) Close() error {
+	close(s.done)
+	return s.index.Close()
+}
+
+func (s *service) UnprotectedEndpoints() []string {
+	return []string{}
+}
+
+func (s *service) Register(ss *grpc.Server) {
+	proto.RegisterSearchServiceServer(ss, s)
+}
+
+func (s *service) Search(ctx context.Context, req *proto.SearchRequest) (*proto.SearchResponse, error) {
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		return &proto.SearchResponse{
+			Status: status.NewUnauthenticated(ctx, errtypes.UserRequired("user not found in context"), "search: error getting user"),
+		}, nil
+	}
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		return &proto.SearchResponse{
+			Status: status.NewInternal(ctx, err, "search: error getting gateway client"),
+		}, nil
+	}
+
+	roots, err := s.roots(ctx, client)
+	if err != nil {
+		return &proto.SearchResponse{
+			Status: status.NewInternal(ctx, err, "search: error getting the spaces of the user"),
+		}, nil
+	}
+	// the spaces which have not been indexed yet are crawled in the
+	// background, their resources are found by the next searches
+	scopes := make([]string, 0, len(roots))
+	for _, r := range roots {
+		scopes = append(scopes, search.ID(r))
+		s.track(client, u.Id, r)
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 || limit > s.conf.Limit {
+		limit = s.conf.Limit
+	}
+	entries, err := s.index.Search(ctx, &search.Query{
+		Term:     req.Query,
+		MimeType: req.MimeType,
+		Owner:    u.Id.OpaqueId,
+		Scopes:   scopes,
+		Limit:    limit,
+		Offset:   int(req.Offset),
+	})
+	if err != nil {
+		return &proto.SearchResponse{
+			Status: status.NewInternal(ctx, err, "search: error searching the index"),
+		}, nil
+	}
+
+	matches := make([]*proto.Match, 0, len(entries))
+	for _, e := range entries {
+		matches = append(matches, &proto.Match{
+			Id:       search.ResourceID(e.ID),
+			Name:     e.Name,
+			Path:     e.Path,
+			Type:     e.Type,
+			Size:     e.Size,
+			Mtime:    e.Mtime,
+			MimeType: e.MimeType,
+		})
+	}
+	return &proto.SearchResponse{
+		Status:  status.NewOK(ctx),
+		Matches: matches,
+	}, nil
+}
+
+// roots returns the roots of the spaces the user of the context searches in:
+// their home first, followed by the resources of the accepted shares.
+func (s *service) roots(ctx context.Context, client gateway.GatewayAPIClient) ([]*provider.ResourceId, error) {
+	homeRes, err := client.GetHome(ctx, &provider.GetHomeRequest{})
+	switch {
+	case err != nil:
+		return nil, err
+	case homeRes.Status.Code != rpc.Code_CODE_OK:
+		return nil, errtypes.InternalError(homeRes.Status.Message)
+	}
+	statRes, err := client.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{Path: homeRes.Path}})
+	switch {
+	case err != nil:
+		return nil, err
+	case statRes.Status.Code != rpc.Code_CODE_OK:
+		return nil, errtypes.InternalError(statRes.Status.Message)
+	}
+
+	sharesRes, err := client.ListReceivedShares(ctx, &collaboration.ListReceivedSharesRequest{})
+	switch {
+	case err != nil:
+		return nil, err
+	case sharesRes.Status.Code != rpc.Code_CODE_OK:
+		return nil, errtypes.InternalError(sharesRes.Status.Message)
+	}
+
+	roots := []*provider.ResourceId{statRes.Info.Id}
+	for _, rs := range sharesRes.Shares {
+		if rs.State == collaboration.ShareState_SHARE_STATE_ACCEPTED {
+			roots = append(roots, rs.Share.ResourceId)
+		}
+	}
+	return roots, nil
+}
@@ -0,0 +1,204 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package spacesregistry
+
+import (
+	"context"
+	"path"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/spacesregistry/proto"
+	"github.com/pkg/errors"
+)
+
+func (s *service) getGateway() (gateway.GatewayAPIClient, error) {
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting gateway client")
+	}
+	return client, nil
+}
+
+func getHome(ctx context.Context, client gateway.GatewayAPIClient) (string, error) {
+	res, err := client.GetHome(ctx, &provider.GetHomeRequest{})
+	if err != nil {
+		return "", errors.Wrap(err, "error calling GetHome")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return "", status.NewErrorFromCode(res.Status.Code, "spacesregistry")
+	}
+	return res.Path, nil
+}
+
+func stat(ctx context.Context, client gateway.GatewayAPIClient, ref *provider.Reference) (*provider.ResourceInfo, error) {
+	res, err := client.Stat(ctx, &provider.StatRequest{Ref: ref})
+	if err != nil {
+		return nil, errors.Wrap(err, "error calling Stat")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, status.NewErrorFromCode(res.Status.Code, "spacesregistry")
+	}
+	return res.Info, nil
+}
+
+// personalSpaces returns the home of the user.
+func (s *service) personalSpaces(ctx context.Context) ([]*proto.Space, error) {
+	client, err := s.getGateway()
+	if err != nil {
+		return nil, err
+	}
+	home, err := getHome(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	info, err := stat(ctx, client, &provider.Reference{Path: home})
+	if err != nil {
+		return nil, err
+	}
+	return []*proto.Space{{
+		Id:   info.Id.StorageId + "!" + info.Id.OpaqueId,
+		Name: path.Base(home),
+		Path: home,
+		Root: info.Id,
+	}}, nil
+}
+
+// shareSpaces returns the accepted shares, mounted in the share folder of the
+// home of the user.
+func (s *service) shareSpaces(ctx context.Context) ([]*proto.Space, error) {
+	client, err := s.getGateway()
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.ListReceivedShares(ctx, &collaboration.ListReceivedSharesRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "error calling ListReceivedShares")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, status.NewErrorFromCode(res.Status.Code, "spacesregistry")
+	}
+
+	var home string
+	spaces := []*proto.Space{}
+	for _, rs := range res.Shares {
+		if rs.State != collaboration.ShareState_SHARE_STATE_ACCEPTED {
+			continue
+		}
+		if home == "" {
+			if home, err = getHome(ctx, client); err != nil {
+				return nil, err
+			}
+		}
+
+		name := path.Base(rs.GetMountPoint().GetPath())
+		if rs.GetMountPoint().GetPath() == "" {
+			info, err := stat(ctx, client, &provider.Reference{ResourceId: rs.Share.ResourceId})
+			if err != nil {
+				// the shared resource may have been removed in the meantime
+				continue
+			}
+			name = path.Base(info.Path)
+		}
+		spaces = append(spaces, &proto.Space{
+			Id:   rs.Share.Id.OpaqueId,
+			Name: name,
+			Path: path.Join(home, s.conf.ShareFolder, name),
+			Root: rs.Share.ResourceId,
+		})
+	}
+	return spaces, nil
+}
+
+// projectSpaces returns the project spaces the user is a member of.
+func (s *service) projectSpaces(ctx context.Context) ([]*proto.Space, error) {
+	client, err := s.getGateway()
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.ListStorageSpaces(ctx, &provider.ListStorageSpacesRequest{
+		Filters: []*provider.ListStorageSpacesRequest_Filter{{
+			Type: provider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE,
+			Term: &provider.ListStorageSpacesRequest_Filter_SpaceType{SpaceType: "project"},
+		}},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error calling ListStorageSpaces")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK && res.Status.Code != rpc.Code_CODE_NOT_FOUND {
+		return nil, status.NewErrorFromCode(res.Status.Code, "spacesregistry")
+	}
+
+	spaces := []*proto.Space{}
+	for _, sp := range res.StorageSpaces {
+		space := &proto.Space{
+			Id:   sp.GetId().GetOpaqueId(),
+			Name: sp.Name,
+			Root: sp.Root,
+		}
+		if info, err := stat(ctx, client, &provider.Reference{ResourceId: sp.Root}); err == nil {
+			space.Path = info.Path
+		}
+		spaces = append(spaces, space)
+	}
+	return spaces, nil
+}
+
+// ocmSpaces returns the accepted OCM shares, mounted in the share folder of the
+// home of the user. The shares transferring the data are not browsable.
+func (s *service) ocmSpaces(ctx context.Context) ([]*proto.Space, error) {
+	client, err := s.getGateway()
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.ListReceivedOCMShares(ctx, &ocm.ListReceivedOCMSharesRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "error calling ListReceivedOCMShares")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, status.NewErrorFromCode(res.Status.Code, "spacesregistry")
+	}
+
+	var home string
+	spaces := []*proto.Space{}
+	for _, rs := range res.Shares {
+		if rs.State != ocm.ShareState_SHARE_STATE_ACCEPTED || rs.Share.ShareType == ocm.Share_SHARE_TYPE_TRANSFER {
+			continue
+		}
+		if home == "" {
+			if home, err = getHome(ctx, client); err != nil {
+				return nil, err
+			}
+		}
+
+		name := path.Base(rs.Share.Name)
+		spaces = append(spaces, &proto.Space{
+			Id:   rs.Share.Id.OpaqueId,
+			Name: name,
+			Path: path.Join(home, s.conf.ShareFolder, name),
+			Root: rs.Share.ResourceId,
+		})
+	}
+	return spaces, nil
+}
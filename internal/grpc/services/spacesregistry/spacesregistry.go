@@ -0,0 +1,152 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package spacesregistry
+
+import (
+	"context"
+	"time"
+
+	"github.com/asim/go-micro/plugins/events/nats/v4"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/events/server"
+	"github.com/cs3org/reva/pkg/rgrpc"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/spacesregistry"
+	"github.com/cs3org/reva/pkg/spacesregistry/proto"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+func init() {
+	rgrpc.Register("spacesregistry", New)
+}
+
+type config struct {
+	GatewaySvc string `mapstructure:"gatewaysvc"`
+	// SpaceTypes are the types of the spaces composed into the views of the users, in this order.
+	SpaceTypes  []string `mapstructure:"space_types" docs:"[personal,share,project,ocm];The types of the spaces listed to the users, in this order."`
+	ShareFolder string   `mapstructure:"share_folder" docs:"MyShares;The folder the received shares are mounted in, it has to match the one of the gateway."`
+	CacheTTL    int      `mapstructure:"cache_ttl" docs:"300;Seconds the spaces of a user are cached if no event changes them."`
+	// NatsAddress is the event stream the changes to the spaces of the users are consumed from.
+	// The views of the users are only dropped when they expire if it is not set.
+	NatsAddress   string `mapstructure:"nats_address"`
+	NatsClusterID string `mapstructure:"nats_clusterid"`
+}
+
+func (c *config) init() {
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+	if len(c.SpaceTypes) == 0 {
+		c.SpaceTypes = []string{spacesregistry.TypePersonal, spacesregistry.TypeShare, spacesregistry.TypeProject, spacesregistry.TypeOCM}
+	}
+	if c.ShareFolder == "" {
+		c.ShareFolder = "MyShares"
+	}
+	if c.CacheTTL <= 0 {
+		c.CacheTTL = 300
+	}
+}
+
+type service struct {
+	conf     *config
+	registry *spacesregistry.Registry
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		err = errors.Wrap(err, "error decoding conf")
+		return nil, err
+	}
+	return c, nil
+}
+
+// New returns a new SpacesRegistryServiceServer.
+func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
+	c, err := parseConfig(m)
+	if err != nil {
+		return nil, err
+	}
+	c.init()
+
+	s := &service{conf: c}
+	sources := map[string]spacesregistry.Source{
+		spacesregistry.TypePersonal: s.personalSpaces,
+		spacesregistry.TypeShare:    s.shareSpaces,
+		spacesregistry.TypeProject:  s.projectSpaces,
+		spacesregistry.TypeOCM:      s.ocmSpaces,
+	}
+	s.registry, err = spacesregistry.New(sources, c.SpaceTypes, time.Duration(c.CacheTTL)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.NatsAddress != "" {
+		stream, err := server.NewNatsStream(nats.Address(c.NatsAddress), nats.ClusterID(c.NatsClusterID))
+		if err != nil {
+			return nil, errors.Wrap(err, "spacesregistry: error connecting to the event stream")
+		}
+		evs, err := events.Consume(stream, "spacesregistry", spacesregistry.Events...)
+		if err != nil {
+			return nil, errors.Wrap(err, "spacesregistry: error consuming events")
+		}
+		go func() {
+			for ev := range evs {
+				s.registry.HandleEvent(ev)
+			}
+		}()
+	}
+
+	return s, nil
+}
+
+func (s *service) Close() error {
+	return nil
+}
+
+func (s *service) UnprotectedEndpoints() []string {
+	return []string{}
+}
+
+func (s *service) Register(ss *grpc.Server) {
+	proto.RegisterSpacesRegistryServiceServer(ss, s)
+}
+
+func (s *service) ListMySpaces(ctx context.Context, req *proto.ListMySpacesRequest) (*proto.ListMySpacesResponse, error) {
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		return &proto.ListMySpacesResponse{
+			Status: status.NewUnauthenticated(ctx, errtypes.UserRequired("user not found in context"), "spacesregistry: error getting user"),
+		}, nil
+	}
+
+	spaces, err := s.registry.List(ctx, spacesregistry.UserKey(u.Id), req.Types, req.Refresh)
+	if err != nil {
+		return &proto.ListMySpacesResponse{
+			Status: status.NewInternal(ctx, err, "spacesregistry: error listing the spaces"),
+		}, nil
+	}
+	return &proto.ListMySpacesResponse{
+		Status: status.NewOK(ctx),
+		Spaces: spaces,
+	}, nil
+}
@@ -0,0 +1,167 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storageprovider
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/storage/utils/fsck"
+	fsckpb "github.com/cs3org/reva/pkg/storage/utils/fsck/proto"
+)
+
+// newGranteeResolver returns the resolver looking the grantees of the grants
+// up through the gateway. The grants found by the background checks, which
+// run without a user, are assumed to be valid.
+func newGranteeResolver(c *config) fsck.GranteeResolver {
+	return func(ctx context.Context, g *provider.Grantee) (bool, error) {
+		if _, ok := ctxpkg.ContextGetToken(ctx); !ok {
+			return true, nil
+		}
+		client, err := pool.GetGatewayServiceClient(pool.Endpoint(c.GatewayAddr))
+		if err != nil {
+			return false, err
+		}
+		var st *rpc.Status
+		switch g.Type {
+		case provider.GranteeType_GRANTEE_TYPE_USER:
+			res, err := client.GetUser(ctx, &userpb.GetUserRequest{UserId: g.GetUserId(), SkipFetchingUserGroups: true})
+			if err != nil {
+				return false, err
+			}
+			st = res.Status
+		case provider.GranteeType_GRANTEE_TYPE_GROUP:
+			res, err := client.GetGroup(ctx, &grouppb.GetGroupRequest{GroupId: g.GetGroupId(), SkipFetchingMembers: true})
+			if err != nil {
+				return false, err
+			}
+			st = res.Status
+		default:
+			return true, nil
+		}
+		switch st.Code {
+		case rpc.Code_CODE_OK:
+			return true, nil
+		case rpc.Code_CODE_NOT_FOUND:
+			return false, nil
+		}
+		return false, errtypes.InternalError(st.Message)
+	}
+}
+
+// StartCheck starts a consistency check of a folder of the storage.
+func (s *service) StartCheck(ctx context.Context, req *fsckpb.StartCheckRequest) (*fsckpb.StartCheckResponse, error) {
+	ref, err := s.unwrap(ctx, req.Ref)
+	if err != nil {
+		return &fsckpb.StartCheckResponse{
+			Status: status.NewInvalidArg(ctx, err.Error()),
+		}, nil
+	}
+
+	r, err := s.checker.Start(ctx, ref, req.Checks, req.Repair)
+	if err != nil {
+		var st *rpc.Status
+		switch err.(type) {
+		case errtypes.AlreadyExists:
+			st = status.NewAlreadyExists(ctx, err, "a check is already running")
+		case errtypes.BadRequest:
+			st = status.NewInvalidArg(ctx, err.Error())
+		default:
+			st = status.NewInternal(ctx, err, "error starting the check")
+		}
+		return &fsckpb.StartCheckResponse{Status: st}, nil
+	}
+	return &fsckpb.StartCheckResponse{
+		Status: status.NewOK(ctx),
+		Report: s.checkReport(r),
+	}, nil
+}
+
+// GetCheckReport returns the report of the running check or of the last one.
+func (s *service) GetCheckReport(ctx context.Context, req *fsckpb.GetCheckReportRequest) (*fsckpb.GetCheckReportResponse, error) {
+	r := s.checker.Report()
+	if r == nil {
+		return &fsckpb.GetCheckReportResponse{
+			Status: status.NewNotFound(ctx, "the storage has not been checked yet"),
+		}, nil
+	}
+	return &fsckpb.GetCheckReportResponse{
+		Status: status.NewOK(ctx),
+		Report: s.checkReport(r),
+	}, nil
+}
+
+// CancelCheck cancels the running check.
+func (s *service) CancelCheck(ctx context.Context, req *fsckpb.CancelCheckRequest) (*fsckpb.CancelCheckResponse, error) {
+	if !s.checker.Cancel() {
+		return &fsckpb.CancelCheckResponse{
+			Status: status.NewNotFound(ctx, "no check is running"),
+		}, nil
+	}
+	return &fsckpb.CancelCheckResponse{Status: status.NewOK(ctx)}, nil
+}
+
+// checkReport converts a report, prefixing the paths of the resources with
+// the mount path.
+func (s *service) checkReport(r *fsck.Report) *fsckpb.CheckReport {
+	issues := make([]*fsckpb.Issue, 0, len(r.Issues))
+	for _, i := range r.Issues {
+		p := i.Path
+		if i.Kind != fsck.KindArtifact && strings.HasPrefix(p, "/") {
+			p = path.Join(s.mountPath, p)
+		}
+		issues = append(issues, &fsckpb.Issue{
+			Kind:       i.Kind,
+			Path:       p,
+			Detail:     i.Detail,
+			Repairable: i.Repairable,
+			Repaired:   i.Repaired,
+			Error:      i.Error,
+		})
+	}
+	root := r.Root
+	if strings.HasPrefix(root, "/") {
+		root = path.Join(s.mountPath, root)
+	}
+	pr := &fsckpb.CheckReport{
+		Id:        r.ID,
+		Root:      root,
+		State:     r.State,
+		Started:   uint64(r.Started.Unix()),
+		Checked:   r.Checked,
+		Bytes:     r.Bytes,
+		Failed:    r.Failed,
+		Issues:    issues,
+		Truncated: r.Truncated,
+		Error:     r.Error,
+	}
+	if !r.Finished.IsZero() {
+		pr.Finished = uint64(r.Finished.Unix())
+	}
+	return pr
+}
@@ -0,0 +1,58 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storageprovider
+
+import (
+	"context"
+	"time"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+)
+
+// lockStatus returns the status a write to the given reference is rejected with when the
+// resource carries a lock the caller does not hold, or nil if the write is allowed.
+// The lock is held when the request carries its id. Enforcing the lock here rather than
+// in the drivers makes it effective for every storage driver supporting locks.
+func (s *service) lockStatus(ctx context.Context, ref *provider.Reference) *rpc.Status {
+	lock, err := s.storage.GetLock(ctx, ref)
+	if err != nil {
+		switch err.(type) {
+		case errtypes.IsNotFound, errtypes.IsNotSupported:
+		default:
+			appctx.GetLogger(ctx).Warn().Err(err).Interface("ref", ref).Msg("storageprovider: error getting lock, ignoring")
+		}
+		return nil
+	}
+	if lock == nil || lockExpired(lock) {
+		return nil
+	}
+	if id, ok := ctxpkg.ContextGetLockID(ctx); ok && id == lock.LockId {
+		return nil
+	}
+	return status.NewLocked(ctx)
+}
+
+func lockExpired(l *provider.Lock) bool {
+	return l.Expiration != nil && time.Unix(int64(l.Expiration.Seconds), 0).Before(time.Now())
+}
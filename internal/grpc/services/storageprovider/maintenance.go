@@ -0,0 +1,69 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storageprovider
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+)
+
+// maintenanceFileCheckInterval is how long the presence of the maintenance file is cached,
+// sparing a stat on every write.
+const maintenanceFileCheckInterval = 5 * time.Second
+
+// maintenanceFile caches whether the maintenance file exists.
+type maintenanceFile struct {
+	mu      sync.Mutex
+	checked time.Time
+	exists  bool
+}
+
+// inMaintenance checks whether the storage is in maintenance mode, either because it is configured
+// statically or because the maintenance file exists. The latter allows toggling the mode at runtime.
+func (s *service) inMaintenance() bool {
+	if s.conf.Maintenance {
+		return true
+	}
+	if s.conf.MaintenanceFile == "" {
+		return false
+	}
+
+	s.maintenance.mu.Lock()
+	defer s.maintenance.mu.Unlock()
+	if now := time.Now(); now.Sub(s.maintenance.checked) >= maintenanceFileCheckInterval {
+		_, err := os.Stat(s.conf.MaintenanceFile)
+		s.maintenance.exists = err == nil
+		s.maintenance.checked = now
+	}
+	return s.maintenance.exists
+}
+
+// maintenanceStatus returns the status writes are rejected with while the storage is in maintenance
+// mode, or nil if writes are allowed. Reads are always served.
+func (s *service) maintenanceStatus(ctx context.Context) *rpc.Status {
+	if !s.inMaintenance() {
+		return nil
+	}
+	return status.NewMaintenance(ctx)
+}
@@ -0,0 +1,58 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storageprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+)
+
+func TestMaintenanceFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "maintenance")
+	s := &service{conf: &config{MaintenanceFile: file}}
+
+	if st := s.maintenanceStatus(context.Background()); st != nil {
+		t.Fatalf("expected writes to be allowed without the maintenance file, got %v", st)
+	}
+
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if s.inMaintenance() {
+		t.Error("expected the absence of the maintenance file to be cached")
+	}
+
+	s.maintenance.checked = time.Now().Add(-maintenanceFileCheckInterval)
+	st := s.maintenanceStatus(context.Background())
+	if !status.IsMaintenance(st) {
+		t.Fatalf("expected a maintenance status once the cache expired, got %v", st)
+	}
+}
+
+func TestMaintenanceStatic(t *testing.T) {
+	s := &service{conf: &config{Maintenance: true}}
+	if !status.IsMaintenance(s.maintenanceStatus(context.Background())) {
+		t.Error("expected a maintenance status")
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storageprovider
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+// checkQuota rejects uploads of the given size which would exceed the quota of the storage holding the
+// reference. Drivers enforcing their quota themselves are left to do so, and failures to determine the
+// quota don't block the upload, as the driver will still refuse writes it can't store.
+func (s *service) checkQuota(ctx context.Context, ref *provider.Reference, size uint64) error {
+	// the quota is taken from the parent, as the file being uploaded may not exist yet
+	parent := ref
+	if ref.Path != "" {
+		parent = &provider.Reference{ResourceId: ref.ResourceId, Path: path.Dir(ref.Path)}
+	}
+	quota, err := storage.QuotaOf(ctx, s.storage, parent)
+	if err != nil {
+		appctx.GetLogger(ctx).Debug().Err(err).Interface("ref", ref).Msg("storageprovider: could not determine quota, skipping check")
+		return nil
+	}
+	if quota.Enforced || !quota.IsKnown() {
+		return nil
+	}
+
+	// an upload replacing an existing file only needs the space it adds
+	if md, err := s.storage.GetMD(ctx, ref, nil); err == nil && md.Type == provider.ResourceType_RESOURCE_TYPE_FILE {
+		if md.Size >= size {
+			return nil
+		}
+		size -= md.Size
+	}
+
+	if !quota.Allows(size) {
+		return errtypes.InsufficientStorage(fmt.Sprintf("upload of %d bytes exceeds the %d bytes left in the quota", size, quota.Free()))
+	}
+	return nil
+}
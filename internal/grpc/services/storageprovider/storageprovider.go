@@ -33,14 +33,34 @@ import (
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/deletejob"
+	deletejobpb "github.com/cs3org/reva/pkg/deletejob/proto"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/mime"
+	"github.com/cs3org/reva/pkg/readiness"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/cs3org/reva/pkg/storage/utils/checksums"
+	"github.com/cs3org/reva/pkg/storage/utils/fsck"
+	fsckpb "github.com/cs3org/reva/pkg/storage/utils/fsck/proto"
+	"github.com/cs3org/reva/pkg/storage/utils/mimetypes"
+	"github.com/cs3org/reva/pkg/storage/utils/movejournal"
+	movejournalpb "github.com/cs3org/reva/pkg/storage/utils/movejournal/proto"
+	"github.com/cs3org/reva/pkg/storage/utils/normalize"
+	"github.com/cs3org/reva/pkg/storage/utils/protect"
+	"github.com/cs3org/reva/pkg/storage/utils/quarantine"
+	"github.com/cs3org/reva/pkg/storage/utils/retention"
+	"github.com/cs3org/reva/pkg/storage/utils/slowlog"
+	"github.com/cs3org/reva/pkg/storage/utils/throttle"
+	verifypb "github.com/cs3org/reva/pkg/storage/utils/verify/proto"
+	"github.com/cs3org/reva/pkg/storage/utils/warmup"
+	"github.com/cs3org/reva/pkg/storage/utils/worm"
 	rtrace "github.com/cs3org/reva/pkg/trace"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/google/uuid"
@@ -64,6 +84,22 @@ type config struct {
 	ExposeDataServer    bool                              `mapstructure:"expose_data_server" docs:"false;Whether to expose data server."` // if true the client will be able to upload/download directly to it
 	AvailableXS         map[string]uint32                 `mapstructure:"available_checksums" docs:"nil;List of available checksums."`
 	CustomMimeTypesJSON string                            `mapstructure:"custom_mime_types_json" docs:"nil;An optional mapping file with the list of supported custom file extensions and corresponding mime types."`
+	Maintenance         bool                              `mapstructure:"maintenance" docs:"false;Whether the storage is in maintenance mode, rejecting all writes."`
+	MaintenanceFile     string                            `mapstructure:"maintenance_file" docs:";The storage is in maintenance mode while this file exists."`
+	Filenames           normalize.Config                  `mapstructure:"filenames" docs:"url:pkg/storage/utils/normalize/normalize.go"`
+	LegalHold           worm.Config                       `mapstructure:"legal_hold" docs:"url:pkg/storage/utils/worm/worm.go"`
+	Protection          protect.Config                    `mapstructure:"protection" docs:"url:pkg/storage/utils/protect/protect.go"`
+	Retention           retention.Config                  `mapstructure:"retention" docs:"url:pkg/storage/utils/retention/retention.go"`
+	Quarantine          quarantine.Config                 `mapstructure:"quarantine" docs:"url:pkg/storage/utils/quarantine/quarantine.go"`
+	Checksums           checksums.Config                  `mapstructure:"checksums" docs:"url:pkg/storage/utils/checksums/checksums.go"`
+	MimeTypes           mimetypes.Config                  `mapstructure:"mime_types" docs:"url:pkg/storage/utils/mimetypes/mimetypes.go"`
+	AsyncDelete         deletejob.Config                  `mapstructure:"async_delete" docs:"url:pkg/deletejob/deletejob.go"`
+	SlowLog             slowlog.Config                    `mapstructure:"slow_log" docs:"url:pkg/storage/utils/slowlog/slowlog.go"`
+	Throttle            throttle.Config                   `mapstructure:"throttle" docs:"url:pkg/storage/utils/throttle/throttle.go"`
+	Warmup              readiness.Config                  `mapstructure:"warmup" docs:"url:pkg/readiness/readiness.go"`
+	DisableQuotaCheck   bool                              `mapstructure:"disable_quota_check" docs:"false;Whether to skip checking uploads against the quota of drivers which don't enforce it themselves."`
+	Fsck                fsck.Config                       `mapstructure:"fsck" docs:"url:pkg/storage/utils/fsck/fsck.go"`
+	GatewayAddr         string                            `mapstructure:"gateway_addr" docs:";The gateway the grantees of the grants are looked up with by the consistency checks."`
 }
 
 func (c *config) init() {
@@ -79,6 +115,8 @@ func (c *config) init() {
 		c.MountID = "00000000-0000-0000-0000-000000000000"
 	}
 
+	c.GatewayAddr = sharedconf.GetGatewaySVC(c.GatewayAddr)
+
 	if c.TmpFolder == "" {
 		c.TmpFolder = "/var/tmp/reva/tmp"
 	}
@@ -105,9 +143,13 @@ type service struct {
 	tmpFolder          string
 	dataServerURL      *url.URL
 	availableXS        []*provider.ResourceChecksumPriority
+	deleteJobs         *deletejob.Manager
+	checker            *fsck.Checker
+	maintenance        maintenanceFile
 }
 
 func (s *service) Close() error {
+	s.checker.Close()
 	return s.storage.Shutdown(context.Background())
 }
 
@@ -115,6 +157,10 @@ func (s *service) UnprotectedEndpoints() []string { return []string{} }
 
 func (s *service) Register(ss *grpc.Server) {
 	provider.RegisterProviderAPIServer(ss, s)
+	deletejobpb.RegisterDeleteJobServiceServer(ss, s)
+	movejournalpb.RegisterMoveJournalServiceServer(ss, s)
+	fsckpb.RegisterFsckServiceServer(ss, s)
+	verifypb.RegisterVerifyServiceServer(ss, s)
 }
 
 func parseXSTypes(xsTypes map[string]uint32) ([]*provider.ResourceChecksumPriority, error) {
@@ -182,6 +228,57 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 	if err != nil {
 		return nil, err
 	}
+	driver := fs
+	// the slow log wraps the driver directly so that it only measures the driver itself
+	if c.SlowLog.Enabled() {
+		if fs, err = slowlog.New(fs, &c.SlowLog); err != nil {
+			return nil, err
+		}
+	}
+	// the calls made by the other wrappers to the driver are throttled as well
+	if c.Throttle.Enabled() {
+		if c.Throttle.Mount == "" {
+			c.Throttle.Mount = mountID
+		}
+		if fs, err = throttle.New(fs, &c.Throttle); err != nil {
+			return nil, err
+		}
+	}
+	if c.Filenames.Enabled() {
+		if fs, err = normalize.New(fs, &c.Filenames); err != nil {
+			return nil, err
+		}
+	}
+	if c.LegalHold.Enabled() {
+		if fs, err = worm.New(fs, &c.LegalHold); err != nil {
+			return nil, err
+		}
+	}
+	if c.Protection.Enabled() {
+		if fs, err = protect.New(fs, &c.Protection); err != nil {
+			return nil, err
+		}
+	}
+	if c.Retention.Enabled() {
+		if fs, err = retention.New(fs, &c.Retention); err != nil {
+			return nil, err
+		}
+	}
+	if c.Quarantine.Enabled() {
+		if fs, err = quarantine.New(fs, &c.Quarantine); err != nil {
+			return nil, err
+		}
+	}
+	if c.Checksums.Enabled() {
+		if fs, err = checksums.New(fs, &c.Checksums); err != nil {
+			return nil, err
+		}
+	}
+	if c.MimeTypes.Enabled() {
+		if fs, err = mimetypes.New(fs, &c.MimeTypes); err != nil {
+			return nil, err
+		}
+	}
 
 	// parse data server url
 	u, err := url.Parse(c.DataServerURL)
@@ -213,12 +310,18 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 		mountID:       mountID,
 		dataServerURL: u,
 		availableXS:   xsTypes,
+		deleteJobs:    deletejob.NewManager(&c.AsyncDelete),
+		checker:       fsck.NewChecker(fs, driver, &c.Fsck, newGranteeResolver(c)),
 	}
 
 	return service, nil
 }
 
 func (s *service) SetArbitraryMetadata(ctx context.Context, req *provider.SetArbitraryMetadataRequest) (*provider.SetArbitraryMetadataResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.SetArbitraryMetadataResponse{Status: st}, nil
+	}
+
 	newRef, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		err := errors.Wrap(err, "storageprovidersvc: error unwrapping path")
@@ -234,6 +337,8 @@ func (s *service) SetArbitraryMetadata(ctx context.Context, req *provider.SetArb
 			st = status.NewNotFound(ctx, "path not found when setting arbitrary metadata")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.BadRequest:
+			st = status.NewInvalidArg(ctx, err.Error())
 		default:
 			st = status.NewInternal(ctx, err, "error setting arbitrary metadata: "+req.Ref.String())
 		}
@@ -249,6 +354,10 @@ func (s *service) SetArbitraryMetadata(ctx context.Context, req *provider.SetArb
 }
 
 func (s *service) UnsetArbitraryMetadata(ctx context.Context, req *provider.UnsetArbitraryMetadataRequest) (*provider.UnsetArbitraryMetadataResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.UnsetArbitraryMetadataResponse{Status: st}, nil
+	}
+
 	newRef, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		err := errors.Wrap(err, "storageprovidersvc: error unwrapping path")
@@ -280,6 +389,10 @@ func (s *service) UnsetArbitraryMetadata(ctx context.Context, req *provider.Unse
 
 // SetLock puts a lock on the given reference
 func (s *service) SetLock(ctx context.Context, req *provider.SetLockRequest) (*provider.SetLockResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.SetLockResponse{Status: st}, nil
+	}
+
 	newRef, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		err := errors.Wrap(err, "storageprovidersvc: error unwrapping path")
@@ -346,6 +459,10 @@ func (s *service) GetLock(ctx context.Context, req *provider.GetLockRequest) (*p
 
 // RefreshLock refreshes an existing lock on the given reference
 func (s *service) RefreshLock(ctx context.Context, req *provider.RefreshLockRequest) (*provider.RefreshLockResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.RefreshLockResponse{Status: st}, nil
+	}
+
 	newRef, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		err := errors.Wrap(err, "storageprovidersvc: error unwrapping path")
@@ -379,6 +496,10 @@ func (s *service) RefreshLock(ctx context.Context, req *provider.RefreshLockRequ
 
 // Unlock removes an existing lock from the given reference
 func (s *service) Unlock(ctx context.Context, req *provider.UnlockRequest) (*provider.UnlockResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.UnlockResponse{Status: st}, nil
+	}
+
 	newRef, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		err := errors.Wrap(err, "storageprovidersvc: error unwrapping path")
@@ -447,6 +568,10 @@ func (s *service) InitiateFileDownload(ctx context.Context, req *provider.Initia
 }
 
 func (s *service) InitiateFileUpload(ctx context.Context, req *provider.InitiateFileUploadRequest) (*provider.InitiateFileUploadResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.InitiateFileUploadResponse{Status: st}, nil
+	}
+
 	// TODO(labkode): same considerations as download
 	log := appctx.GetLogger(ctx)
 	newRef, err := s.unwrap(ctx, req.Ref)
@@ -460,6 +585,9 @@ func (s *service) InitiateFileUpload(ctx context.Context, req *provider.Initiate
 			Status: status.NewInternal(ctx, errtypes.BadRequest("can't upload to mount path"), "can't upload to mount path"),
 		}, nil
 	}
+	if st := s.lockStatus(ctx, newRef); st != nil {
+		return &provider.InitiateFileUploadResponse{Status: st}, nil
+	}
 
 	metadata := map[string]string{}
 	var uploadLength int64
@@ -482,6 +610,13 @@ func (s *service) InitiateFileUpload(ctx context.Context, req *provider.Initiate
 			metadata["mtime"] = string(req.Opaque.Map["X-OC-Mtime"].Value)
 		}
 	}
+	if uploadLength > 0 && !s.conf.DisableQuotaCheck {
+		if err := s.checkQuota(ctx, newRef, uint64(uploadLength)); err != nil {
+			return &provider.InitiateFileUploadResponse{
+				Status: status.NewInsufficientStorage(ctx, err, "insufficient storage"),
+			}, nil
+		}
+	}
 	uploadIDs, err := s.storage.InitiateUpload(ctx, newRef, uploadLength, metadata)
 	if err != nil {
 		var st *rpc.Status
@@ -565,6 +700,10 @@ func (s *service) GetHome(ctx context.Context, req *provider.GetHomeRequest) (*p
 }
 
 func (s *service) CreateHome(ctx context.Context, req *provider.CreateHomeRequest) (*provider.CreateHomeResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.CreateHomeResponse{Status: st}, nil
+	}
+
 	log := appctx.GetLogger(ctx)
 	if err := s.storage.CreateHome(ctx); err != nil {
 		st := status.NewInternal(ctx, err, "error creating home")
@@ -582,6 +721,10 @@ func (s *service) CreateHome(ctx context.Context, req *provider.CreateHomeReques
 
 // CreateStorageSpace creates a storage space
 func (s *service) CreateStorageSpace(ctx context.Context, req *provider.CreateStorageSpaceRequest) (*provider.CreateStorageSpaceResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.CreateStorageSpaceResponse{Status: st}, nil
+	}
+
 	resp, err := s.storage.CreateStorageSpace(ctx, req)
 	if err != nil {
 		return nil, err
@@ -639,16 +782,28 @@ func (s *service) ListStorageSpaces(ctx context.Context, req *provider.ListStora
 }
 
 func (s *service) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorageSpaceRequest) (*provider.UpdateStorageSpaceResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.UpdateStorageSpaceResponse{Status: st}, nil
+	}
+
 	return s.storage.UpdateStorageSpace(ctx, req)
 }
 
 func (s *service) DeleteStorageSpace(ctx context.Context, req *provider.DeleteStorageSpaceRequest) (*provider.DeleteStorageSpaceResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.DeleteStorageSpaceResponse{Status: st}, nil
+	}
+
 	return &provider.DeleteStorageSpaceResponse{
 		Status: status.NewUnimplemented(ctx, errtypes.NotSupported("DeleteStorageSpace not implemented"), "DeleteStorageSpace not implemented"),
 	}, nil
 }
 
 func (s *service) CreateContainer(ctx context.Context, req *provider.CreateContainerRequest) (*provider.CreateContainerResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.CreateContainerResponse{Status: st}, nil
+	}
+
 	newRef, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		return &provider.CreateContainerResponse{
@@ -679,6 +834,10 @@ func (s *service) CreateContainer(ctx context.Context, req *provider.CreateConta
 }
 
 func (s *service) TouchFile(ctx context.Context, req *provider.TouchFileRequest) (*provider.TouchFileResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.TouchFileResponse{Status: st}, nil
+	}
+
 	newRef, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		return &provider.TouchFileResponse{
@@ -709,6 +868,10 @@ func (s *service) TouchFile(ctx context.Context, req *provider.TouchFileRequest)
 }
 
 func (s *service) Delete(ctx context.Context, req *provider.DeleteRequest) (*provider.DeleteResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.DeleteResponse{Status: st}, nil
+	}
+
 	newRef, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		return &provider.DeleteResponse{
@@ -720,6 +883,9 @@ func (s *service) Delete(ctx context.Context, req *provider.DeleteRequest) (*pro
 			Status: status.NewInternal(ctx, errtypes.BadRequest("can't delete mount path"), "can't delete mount path"),
 		}, nil
 	}
+	if st := s.lockStatus(ctx, newRef); st != nil {
+		return &provider.DeleteResponse{Status: st}, nil
+	}
 
 	// check DeleteRequest for any known opaque properties.
 	if req.Opaque != nil {
@@ -728,6 +894,11 @@ func (s *service) Delete(ctx context.Context, req *provider.DeleteRequest) (*pro
 			// it is a binary key; its existence signals true. Although, do not assume.
 			ctx = context.WithValue(ctx, appctx.DeletingSharedResource, true)
 		}
+		if _, ok := req.Opaque.Map["async"]; ok {
+			if res := s.deleteAsync(ctx, newRef); res != nil {
+				return res, nil
+			}
+		}
 	}
 
 	if err := s.storage.Delete(ctx, newRef); err != nil {
@@ -751,7 +922,131 @@ func (s *service) Delete(ctx context.Context, req *provider.DeleteRequest) (*pro
 	return res, nil
 }
 
+// deleteAsync starts a job deleting the folder in the background, it returns
+// nil for files, which are deleted right away.
+func (s *service) deleteAsync(ctx context.Context, ref *provider.Reference) *provider.DeleteResponse {
+	md, err := s.storage.GetMD(ctx, ref, nil)
+	if err != nil {
+		var st *rpc.Status
+		switch err.(type) {
+		case errtypes.IsNotFound:
+			st = status.NewNotFound(ctx, "path not found when deleting")
+		case errtypes.PermissionDenied:
+			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		default:
+			st = status.NewInternal(ctx, err, "error statting resource to delete: "+ref.String())
+		}
+		return &provider.DeleteResponse{Status: st}
+	}
+	if md.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		return nil
+	}
+
+	// the storage id tells the gateway which provider runs the job
+	job := s.deleteJobs.Start(ctx, md.GetId().GetStorageId()+":"+uuid.New().String(), s.storage, ref)
+	return &provider.DeleteResponse{
+		Status: status.NewOK(ctx),
+		Opaque: &typespb.Opaque{
+			Map: map[string]*typespb.OpaqueEntry{
+				"job_id": {
+					Decoder: "plain",
+					Value:   []byte(job.Id),
+				},
+			},
+		},
+	}
+}
+
+// GetDeleteJob returns the progress of a background deletion.
+func (s *service) GetDeleteJob(ctx context.Context, req *deletejobpb.GetDeleteJobRequest) (*deletejobpb.GetDeleteJobResponse, error) {
+	job, err := s.deleteJobs.Get(ctx, req.Id)
+	if err != nil {
+		return &deletejobpb.GetDeleteJobResponse{
+			Status: status.NewNotFound(ctx, "delete job not found"),
+		}, nil
+	}
+	return &deletejobpb.GetDeleteJobResponse{
+		Status: status.NewOK(ctx),
+		Job:    job,
+	}, nil
+}
+
+// CancelDeleteJob stops a background deletion.
+func (s *service) CancelDeleteJob(ctx context.Context, req *deletejobpb.CancelDeleteJobRequest) (*deletejobpb.CancelDeleteJobResponse, error) {
+	job, err := s.deleteJobs.Cancel(ctx, req.Id)
+	if err != nil {
+		return &deletejobpb.CancelDeleteJobResponse{
+			Status: status.NewNotFound(ctx, "delete job not found"),
+		}, nil
+	}
+	return &deletejobpb.CancelDeleteJobResponse{
+		Status: status.NewOK(ctx),
+		Job:    job,
+	}, nil
+}
+
+// ListInterruptedMoves returns the interrupted moves of the drivers
+// journaling their moves.
+func (s *service) ListInterruptedMoves(ctx context.Context, req *movejournalpb.ListInterruptedMovesRequest) (*movejournalpb.ListInterruptedMovesResponse, error) {
+	r, ok := s.storage.(movejournal.Repairer)
+	if !ok {
+		return &movejournalpb.ListInterruptedMovesResponse{
+			Status: status.NewUnimplemented(ctx, nil, "the storage doesn't journal its moves"),
+		}, nil
+	}
+
+	entries, err := r.ListInterruptedMoves(ctx)
+	if err != nil {
+		return &movejournalpb.ListInterruptedMovesResponse{
+			Status: status.NewInternal(ctx, err, "error listing the interrupted moves"),
+		}, nil
+	}
+	moves := make([]*movejournalpb.InterruptedMove, 0, len(entries))
+	for _, e := range entries {
+		moves = append(moves, &movejournalpb.InterruptedMove{
+			Id:      e.ID,
+			Source:  e.Source,
+			Target:  e.Target,
+			Started: uint64(e.Started.Unix()),
+			Updated: uint64(e.Updated.Unix()),
+			Moved:   uint64(len(e.Moved)),
+		})
+	}
+	return &movejournalpb.ListInterruptedMovesResponse{
+		Status: status.NewOK(ctx),
+		Moves:  moves,
+	}, nil
+}
+
+// RepairMove resumes or rolls back an interrupted move.
+func (s *service) RepairMove(ctx context.Context, req *movejournalpb.RepairMoveRequest) (*movejournalpb.RepairMoveResponse, error) {
+	r, ok := s.storage.(movejournal.Repairer)
+	if !ok {
+		return &movejournalpb.RepairMoveResponse{
+			Status: status.NewUnimplemented(ctx, nil, "the storage doesn't journal its moves"),
+		}, nil
+	}
+
+	if err := r.RepairMove(ctx, req.Id, req.Rollback); err != nil {
+		var st *rpc.Status
+		switch err.(type) {
+		case errtypes.IsNotFound:
+			st = status.NewNotFound(ctx, "interrupted move not found")
+		case errtypes.BadRequest:
+			st = status.NewInvalidArg(ctx, err.Error())
+		default:
+			st = status.NewInternal(ctx, err, "error repairing the move")
+		}
+		return &movejournalpb.RepairMoveResponse{Status: st}, nil
+	}
+	return &movejournalpb.RepairMoveResponse{Status: status.NewOK(ctx)}, nil
+}
+
 func (s *service) Move(ctx context.Context, req *provider.MoveRequest) (*provider.MoveResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.MoveResponse{Status: st}, nil
+	}
+
 	sourceRef, err := s.unwrap(ctx, req.Source)
 	if err != nil {
 		return &provider.MoveResponse{
@@ -764,6 +1059,11 @@ func (s *service) Move(ctx context.Context, req *provider.MoveRequest) (*provide
 			Status: status.NewInternal(ctx, err, "error unwrapping destination path"),
 		}, nil
 	}
+	for _, ref := range []*provider.Reference{sourceRef, targetRef} {
+		if st := s.lockStatus(ctx, ref); st != nil {
+			return &provider.MoveResponse{Status: st}, nil
+		}
+	}
 
 	if err := s.storage.Move(ctx, sourceRef, targetRef); err != nil {
 		var st *rpc.Status
@@ -1082,6 +1382,10 @@ func (s *service) ListFileVersions(ctx context.Context, req *provider.ListFileVe
 }
 
 func (s *service) RestoreFileVersion(ctx context.Context, req *provider.RestoreFileVersionRequest) (*provider.RestoreFileVersionResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.RestoreFileVersionResponse{Status: st}, nil
+	}
+
 	newRef, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		return &provider.RestoreFileVersionResponse{
@@ -1119,6 +1423,7 @@ func (s *service) ListRecycleStream(req *provider.ListRecycleStreamRequest, ss p
 		return err
 	}
 
+	ctx = recycleContext(ctx, ref)
 	key, itemPath := router.ShiftPath(req.Key)
 	items, err := s.storage.ListRecycle(ctx, ref.GetPath(), key, itemPath)
 	if err != nil {
@@ -1160,6 +1465,7 @@ func (s *service) ListRecycle(ctx context.Context, req *provider.ListRecycleRequ
 	if err != nil {
 		return nil, err
 	}
+	ctx = recycleContext(ctx, ref)
 	key, itemPath := router.ShiftPath(req.Key)
 	items, err := s.storage.ListRecycle(ctx, ref.GetPath(), key, itemPath)
 	// TODO(labkode): CRITICAL: fill recycle info with storage provider.
@@ -1195,11 +1501,16 @@ func (s *service) ListRecycle(ctx context.Context, req *provider.ListRecycleRequ
 }
 
 func (s *service) RestoreRecycleItem(ctx context.Context, req *provider.RestoreRecycleItemRequest) (*provider.RestoreRecycleItemResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.RestoreRecycleItemResponse{Status: st}, nil
+	}
+
 	// TODO(labkode): CRITICAL: fill recycle info with storage provider.
 	ref, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		return nil, err
 	}
+	ctx = recycleContext(ctx, ref)
 	key, itemPath := router.ShiftPath(req.Key)
 	if err := s.storage.RestoreRecycleItem(ctx, ref.GetPath(), key, itemPath, req.RestoreRef); err != nil {
 		var st *rpc.Status
@@ -1223,10 +1534,15 @@ func (s *service) RestoreRecycleItem(ctx context.Context, req *provider.RestoreR
 }
 
 func (s *service) PurgeRecycle(ctx context.Context, req *provider.PurgeRecycleRequest) (*provider.PurgeRecycleResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.PurgeRecycleResponse{Status: st}, nil
+	}
+
 	ref, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		return nil, err
 	}
+	ctx = recycleContext(ctx, ref)
 	// if a key was sent as opaque id purge only that item
 	key, itemPath := router.ShiftPath(req.Key)
 	if key != "" {
@@ -1298,6 +1614,10 @@ func (s *service) ListGrants(ctx context.Context, req *provider.ListGrantsReques
 }
 
 func (s *service) DenyGrant(ctx context.Context, req *provider.DenyGrantRequest) (*provider.DenyGrantResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.DenyGrantResponse{Status: st}, nil
+	}
+
 	newRef, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		return &provider.DenyGrantResponse{
@@ -1335,6 +1655,10 @@ func (s *service) DenyGrant(ctx context.Context, req *provider.DenyGrantRequest)
 }
 
 func (s *service) AddGrant(ctx context.Context, req *provider.AddGrantRequest) (*provider.AddGrantResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.AddGrantResponse{Status: st}, nil
+	}
+
 	newRef, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		return &provider.AddGrantResponse{
@@ -1372,6 +1696,10 @@ func (s *service) AddGrant(ctx context.Context, req *provider.AddGrantRequest) (
 }
 
 func (s *service) UpdateGrant(ctx context.Context, req *provider.UpdateGrantRequest) (*provider.UpdateGrantResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.UpdateGrantResponse{Status: st}, nil
+	}
+
 	// check grantee type is valid
 	if req.Grant.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_INVALID {
 		return &provider.UpdateGrantResponse{
@@ -1408,6 +1736,10 @@ func (s *service) UpdateGrant(ctx context.Context, req *provider.UpdateGrantRequ
 }
 
 func (s *service) RemoveGrant(ctx context.Context, req *provider.RemoveGrantRequest) (*provider.RemoveGrantResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.RemoveGrantResponse{Status: st}, nil
+	}
+
 	// check targetType is valid
 	if req.Grant.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_INVALID {
 		return &provider.RemoveGrantResponse{
@@ -1444,6 +1776,10 @@ func (s *service) RemoveGrant(ctx context.Context, req *provider.RemoveGrantRequ
 }
 
 func (s *service) CreateReference(ctx context.Context, req *provider.CreateReferenceRequest) (*provider.CreateReferenceResponse, error) {
+	if st := s.maintenanceStatus(ctx); st != nil {
+		return &provider.CreateReferenceResponse{Status: st}, nil
+	}
+
 	log := appctx.GetLogger(ctx)
 
 	// parse uri is valid
@@ -1521,10 +1857,25 @@ func (s *service) GetQuota(ctx context.Context, req *provider.GetQuotaRequest) (
 }
 
 func getFS(c *config) (storage.FS, error) {
-	if f, ok := registry.NewFuncs[c.Driver]; ok {
-		return f(c.Drivers[c.Driver])
+	f, ok := registry.NewFuncs[c.Driver]
+	if !ok {
+		return nil, errtypes.NotFound("driver not found: " + c.Driver)
+	}
+	if c.Warmup.Enabled() {
+		return warmup.New("storageprovider:"+c.MountPath, func() (storage.FS, error) {
+			return f(c.Drivers[c.Driver])
+		}, &c.Warmup)
+	}
+	return f(c.Drivers[c.Driver])
+}
+
+// recycleContext flags the trash of a space to the storage when the space is
+// referenced by the id of its root.
+func recycleContext(ctx context.Context, ref *provider.Reference) context.Context {
+	if ref.GetResourceId() != nil {
+		return appctx.ContextSetRecycleSpace(ctx, ref.GetResourceId())
 	}
-	return nil, errtypes.NotFound("driver not found: " + c.Driver)
+	return ctx
 }
 
 func (s *service) unwrap(ctx context.Context, ref *provider.Reference) (*provider.Reference, error) {
@@ -0,0 +1,82 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storageprovider
+
+import (
+	"path"
+	"strings"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/storage/utils/verify"
+	verifypb "github.com/cs3org/reva/pkg/storage/utils/verify/proto"
+)
+
+// VerifyChecksum recomputes the checksum of a file, sending the progress of
+// the reading to the client, and compares it to the stored and expected ones.
+func (s *service) VerifyChecksum(req *verifypb.VerifyChecksumRequest, stream verifypb.VerifyService_VerifyChecksumServer) error {
+	ctx := stream.Context()
+	ref, err := s.unwrap(ctx, req.Ref)
+	if err != nil {
+		return stream.Send(&verifypb.VerifyChecksumResponse{
+			Status: status.NewInvalidArg(ctx, err.Error()),
+		})
+	}
+
+	r, err := verify.Verify(ctx, s.storage, ref, req.Algorithm, req.Expected, func(read, size uint64) error {
+		return stream.Send(&verifypb.VerifyChecksumResponse{
+			Status:   status.NewOK(ctx),
+			Progress: &verifypb.Progress{Read: read, Size: size},
+		})
+	})
+	if err != nil {
+		var st *rpc.Status
+		switch err.(type) {
+		case errtypes.IsNotFound:
+			st = status.NewNotFound(ctx, "file not found when verifying its checksum")
+		case errtypes.PermissionDenied:
+			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.BadRequest:
+			st = status.NewInvalidArg(ctx, err.Error())
+		default:
+			st = status.NewInternal(ctx, err, "error verifying the checksum: "+req.Ref.String())
+		}
+		return stream.Send(&verifypb.VerifyChecksumResponse{Status: st})
+	}
+
+	p := r.Path
+	if strings.HasPrefix(p, "/") {
+		p = path.Join(s.mountPath, p)
+	}
+	return stream.Send(&verifypb.VerifyChecksumResponse{
+		Status: status.NewOK(ctx),
+		Result: &verifypb.VerifyResult{
+			Path:          p,
+			Algorithm:     r.Algorithm,
+			Computed:      r.Computed,
+			Stored:        r.Stored,
+			Expected:      r.Expected,
+			StoredMatch:   r.StoredMatch,
+			ExpectedMatch: r.ExpectedMatch,
+			Verdict:       r.Verdict,
+			Bytes:         r.Bytes,
+		},
+	})
+}
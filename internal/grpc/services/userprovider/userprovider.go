@@ -25,8 +25,10 @@ import (
 	"sort"
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/plugin"
+	"github.com/cs3org/reva/pkg/readiness"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/user"
@@ -43,6 +45,7 @@ func init() {
 type config struct {
 	Driver  string                            `mapstructure:"driver"`
 	Drivers map[string]map[string]interface{} `mapstructure:"drivers"`
+	Warmup  readiness.Config                  `mapstructure:"warmup" docs:"url:pkg/readiness/readiness.go"`
 }
 
 func (c *config) init() {
@@ -101,15 +104,41 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 		plugin:  plug,
 	}
 
+	if c.Warmup.Enabled() {
+		if svc.warmup, err = readiness.NewInitializer("userprovider:"+c.Driver, &c.Warmup, readiness.Check(userManager)); err != nil {
+			return nil, err
+		}
+		if err := svc.warmup.Start(); err != nil {
+			svc.warmup.Close()
+			return nil, err
+		}
+	}
+
 	return svc, nil
 }
 
 type service struct {
 	usermgr user.Manager
 	plugin  *plugin.RevaPlugin
+	warmup  *readiness.Initializer
+}
+
+// notReady returns the status to answer with while the warmup of the driver
+// has not succeeded.
+func (s *service) notReady(ctx context.Context) *rpc.Status {
+	if s.warmup == nil {
+		return nil
+	}
+	if err := s.warmup.Ensure(ctx); err != nil {
+		return status.NewUnavailable(ctx, err.Error())
+	}
+	return nil
 }
 
 func (s *service) Close() error {
+	if s.warmup != nil {
+		s.warmup.Close()
+	}
 	if s.plugin != nil {
 		s.plugin.Kill()
 	}
@@ -125,6 +154,10 @@ func (s *service) Register(ss *grpc.Server) {
 }
 
 func (s *service) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*userpb.GetUserResponse, error) {
+	if st := s.notReady(ctx); st != nil {
+		return &userpb.GetUserResponse{Status: st}, nil
+	}
+
 	user, err := s.usermgr.GetUser(ctx, req.UserId, req.SkipFetchingUserGroups)
 	if err != nil {
 		res := &userpb.GetUserResponse{}
@@ -145,6 +178,10 @@ func (s *service) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*use
 }
 
 func (s *service) GetUserByClaim(ctx context.Context, req *userpb.GetUserByClaimRequest) (*userpb.GetUserByClaimResponse, error) {
+	if st := s.notReady(ctx); st != nil {
+		return &userpb.GetUserByClaimResponse{Status: st}, nil
+	}
+
 	user, err := s.usermgr.GetUserByClaim(ctx, req.Claim, req.Value, req.SkipFetchingUserGroups)
 	if err != nil {
 		res := &userpb.GetUserByClaimResponse{}
@@ -165,6 +202,10 @@ func (s *service) GetUserByClaim(ctx context.Context, req *userpb.GetUserByClaim
 }
 
 func (s *service) FindUsers(ctx context.Context, req *userpb.FindUsersRequest) (*userpb.FindUsersResponse, error) {
+	if st := s.notReady(ctx); st != nil {
+		return &userpb.FindUsersResponse{Status: st}, nil
+	}
+
 	users, err := s.usermgr.FindUsers(ctx, req.Filter, req.SkipFetchingUserGroups)
 	if err != nil {
 		err = errors.Wrap(err, "userprovidersvc: error finding users")
@@ -187,6 +228,10 @@ func (s *service) FindUsers(ctx context.Context, req *userpb.FindUsersRequest) (
 }
 
 func (s *service) GetUserGroups(ctx context.Context, req *userpb.GetUserGroupsRequest) (*userpb.GetUserGroupsResponse, error) {
+	if st := s.notReady(ctx); st != nil {
+		return &userpb.GetUserGroupsResponse{Status: st}, nil
+	}
+
 	groups, err := s.usermgr.GetUserGroups(ctx, req.UserId)
 	if err != nil {
 		err = errors.Wrap(err, "userprovidersvc: error getting user groups")
@@ -0,0 +1,87 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package usershareprovider
+
+import (
+	"context"
+	"time"
+
+	"github.com/asim/go-micro/plugins/events/nats/v4"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/events/server"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/share/membership"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// newGroupResolver returns the resolver of the current groups of the
+// recipients, publishing their membership changes if an event stream is configured.
+func newGroupResolver(c *config) (*membership.Resolver, error) {
+	var onChange membership.ChangeFunc
+	if c.NatsAddress != "" {
+		stream, err := server.NewNatsStream(nats.Address(c.NatsAddress), nats.ClusterID(c.NatsClusterID))
+		if err != nil {
+			return nil, errors.Wrap(err, "usershareprovider: error connecting to the event stream")
+		}
+		onChange = func(uid *userpb.UserId, added, removed []string) {
+			ev := events.GroupMembershipChanged{UserID: uid, Added: added, Removed: removed}
+			if err := events.Publish(stream, ev); err != nil {
+				log.Error().Err(err).Str("user", uid.OpaqueId).Msg("usershareprovider: error publishing group membership change")
+			}
+		}
+	}
+
+	fetch := func(ctx context.Context, uid *userpb.UserId) ([]string, error) {
+		client, err := pool.GetGatewayServiceClient(pool.Endpoint(c.GatewayAddr))
+		if err != nil {
+			return nil, err
+		}
+		res, err := client.GetUserGroups(ctx, &userpb.GetUserGroupsRequest{UserId: uid})
+		if err != nil {
+			return nil, err
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return nil, errtypes.InternalError(res.Status.Message)
+		}
+		return res.Groups, nil
+	}
+
+	return membership.NewResolver(time.Duration(c.GroupRefreshInterval)*time.Second, fetch, onChange), nil
+}
+
+// withCurrentGroups replaces the groups of the user of the context, which
+// might date back to the login, with the current ones so that the group
+// shares follow the membership changes.
+func (s *service) withCurrentGroups(ctx context.Context) context.Context {
+	if s.groups == nil {
+		return ctx
+	}
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		return ctx
+	}
+	current := *u
+	current.Groups = s.groups.Groups(ctx, u)
+	return ctxpkg.ContextSetUser(ctx, &current)
+}
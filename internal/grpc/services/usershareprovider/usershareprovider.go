@@ -32,6 +32,9 @@ import (
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/share"
 	"github.com/cs3org/reva/pkg/share/manager/registry"
+	"github.com/cs3org/reva/pkg/share/membership"
+	"github.com/cs3org/reva/pkg/share/policy"
+	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
@@ -45,18 +48,30 @@ type config struct {
 	Driver                string                            `mapstructure:"driver"`
 	Drivers               map[string]map[string]interface{} `mapstructure:"drivers"`
 	AllowedPathsForShares []string                          `mapstructure:"allowed_paths_for_shares"`
+	// GroupRefreshInterval is the number of seconds after which the groups of the recipients are fetched
+	// again from the user provider, so that the group membership changes apply to the group shares.
+	// The groups the users logged in with are used if it is 0.
+	GroupRefreshInterval int    `mapstructure:"group_refresh_interval"`
+	GatewayAddr          string `mapstructure:"gateway_addr"`
+	// NatsAddress is the event stream the group membership changes are published to,
+	// for the gateways to invalidate the etags of the shares folders of the users.
+	NatsAddress   string `mapstructure:"nats_address"`
+	NatsClusterID string `mapstructure:"nats_clusterid"`
 }
 
 func (c *config) init() {
 	if c.Driver == "" {
 		c.Driver = "json"
 	}
+	c.GatewayAddr = sharedconf.GetGatewaySVC(c.GatewayAddr)
 }
 
 type service struct {
 	conf                  *config
 	sm                    share.Manager
 	allowedPathsForShares []*regexp.Regexp
+	groups                *membership.Resolver // nil if the groups of the users are taken from their tokens
+	stop                  context.CancelFunc
 }
 
 func getShareManager(c *config) (share.Manager, error) {
@@ -68,6 +83,9 @@ func getShareManager(c *config) (share.Manager, error) {
 
 // TODO(labkode): add ctx to Close.
 func (s *service) Close() error {
+	if s.stop != nil {
+		s.stop()
+	}
 	return nil
 }
 
@@ -118,6 +136,15 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 		allowedPathsForShares: allowedPathsForShares,
 	}
 
+	if c.GroupRefreshInterval > 0 {
+		if service.groups, err = newGroupResolver(c); err != nil {
+			return nil, err
+		}
+		var ctx context.Context
+		ctx, service.stop = context.WithCancel(context.Background())
+		go service.groups.Run(ctx)
+	}
+
 	return service, nil
 }
 
@@ -147,6 +174,16 @@ func (s *service) CreateShare(ctx context.Context, req *collaboration.CreateShar
 		}, nil
 	}
 
+	p, err := policy.FromOpaque(req.ResourceInfo.GetOpaque())
+	if err == nil {
+		err = p.CheckShare(req.Grant, u)
+	}
+	if err != nil {
+		return &collaboration.CreateShareResponse{
+			Status: status.NewStatusFromErrType(ctx, "share creation is not allowed by the share policy of the space", err),
+		}, nil
+	}
+
 	share, err := s.sm.Share(ctx, req.ResourceInfo, req.Grant)
 	if err != nil {
 		return &collaboration.CreateShareResponse{
@@ -175,6 +212,7 @@ func (s *service) RemoveShare(ctx context.Context, req *collaboration.RemoveShar
 }
 
 func (s *service) GetShare(ctx context.Context, req *collaboration.GetShareRequest) (*collaboration.GetShareResponse, error) {
+	ctx = s.withCurrentGroups(ctx)
 	share, err := s.sm.GetShare(ctx, req.Ref)
 	if err != nil {
 		return &collaboration.GetShareResponse{
@@ -189,6 +227,12 @@ func (s *service) GetShare(ctx context.Context, req *collaboration.GetShareReque
 }
 
 func (s *service) ListShares(ctx context.Context, req *collaboration.ListSharesRequest) (*collaboration.ListSharesResponse, error) {
+	if _, ok := req.GetOpaque().GetMap()[share.OpaqueResourceIDsOnly]; ok {
+		if ids, ok := share.ResourceIDsOf(req.Filters); ok {
+			return s.listSharedResources(ctx, ids)
+		}
+	}
+
 	shares, err := s.sm.ListShares(ctx, req.Filters) // TODO(labkode): add filter to share manager
 	if err != nil {
 		return &collaboration.ListSharesResponse{
@@ -203,6 +247,26 @@ func (s *service) ListShares(ctx context.Context, req *collaboration.ListSharesR
 	return res, nil
 }
 
+// listSharedResources answers the listings which only need to know which
+// resources are shared with the shares of the resources, holding only their id.
+func (s *service) listSharedResources(ctx context.Context, ids []*provider.ResourceId) (*collaboration.ListSharesResponse, error) {
+	shared, err := share.ListSharedResources(ctx, s.sm, ids)
+	if err != nil {
+		return &collaboration.ListSharesResponse{
+			Status: status.NewInternal(ctx, err, "error listing shared resources"),
+		}, nil
+	}
+
+	shares := make([]*collaboration.Share, 0, len(shared))
+	for _, id := range shared {
+		shares = append(shares, &collaboration.Share{ResourceId: id})
+	}
+	return &collaboration.ListSharesResponse{
+		Status: status.NewOK(ctx),
+		Shares: shares,
+	}, nil
+}
+
 func (s *service) UpdateShare(ctx context.Context, req *collaboration.UpdateShareRequest) (*collaboration.UpdateShareResponse, error) {
 	share, err := s.sm.UpdateShare(ctx, req.Ref, req.Field.GetPermissions()) // TODO(labkode): check what to update
 	if err != nil {
@@ -219,6 +283,7 @@ func (s *service) UpdateShare(ctx context.Context, req *collaboration.UpdateShar
 }
 
 func (s *service) ListReceivedShares(ctx context.Context, req *collaboration.ListReceivedSharesRequest) (*collaboration.ListReceivedSharesResponse, error) {
+	ctx = s.withCurrentGroups(ctx)
 	// For the UI add a filter to not display the denial shares
 	foundExclude := false
 	for _, f := range req.Filters {
@@ -245,6 +310,7 @@ func (s *service) ListReceivedShares(ctx context.Context, req *collaboration.Lis
 }
 
 func (s *service) GetReceivedShare(ctx context.Context, req *collaboration.GetReceivedShareRequest) (*collaboration.GetReceivedShareResponse, error) {
+	ctx = s.withCurrentGroups(ctx)
 	log := appctx.GetLogger(ctx)
 
 	share, err := s.sm.GetReceivedShare(ctx, req.Ref)
@@ -263,6 +329,7 @@ func (s *service) GetReceivedShare(ctx context.Context, req *collaboration.GetRe
 }
 
 func (s *service) UpdateReceivedShare(ctx context.Context, req *collaboration.UpdateReceivedShareRequest) (*collaboration.UpdateReceivedShareResponse, error) {
+	ctx = s.withCurrentGroups(ctx)
 	share, err := s.sm.UpdateReceivedShare(ctx, req.Share, req.UpdateMask) // TODO(labkode): check what to update
 	if err != nil {
 		return &collaboration.UpdateReceivedShareResponse{
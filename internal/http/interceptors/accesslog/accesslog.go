@@ -0,0 +1,117 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package accesslog
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cs3org/reva/pkg/accesslog"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+)
+
+// New returns a new HTTP middleware that writes the access log of the requests.
+// service returns the name of the service serving the path of a request.
+func New(l *accesslog.Logger, service func(path string) string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			e := &accesslog.Entry{
+				Time:      time.Now(),
+				Protocol:  "http",
+				Service:   service(r.URL.Path),
+				Method:    r.Method,
+				Route:     r.URL.Path,
+				Remote:    remote(r),
+				UserAgent: r.UserAgent(),
+			}
+			if !l.Enabled(e.Service) {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			h.ServeHTTP(rw, r.WithContext(accesslog.ContextSetEntry(r.Context(), e)))
+
+			e.Duration = time.Since(e.Time)
+			e.Status = strconv.Itoa(rw.status)
+			e.Failed = rw.status >= http.StatusInternalServerError
+			e.Bytes = rw.size
+			l.Log(e)
+		})
+	}
+}
+
+// User returns a new HTTP middleware that records the user the request has
+// been authenticated as in its access log entry. It has to run after the
+// authentication.
+func User(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, ok := ctxpkg.ContextGetUser(r.Context()); ok {
+			accesslog.SetUser(r.Context(), u)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func remote(r *http.Request) string {
+	if ip, ok := ctxpkg.ContextGetClientIP(r.Context()); ok {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// responseWriter keeps track of the status code and of the size of the response.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	w.status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
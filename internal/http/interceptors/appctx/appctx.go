@@ -25,7 +25,9 @@ import (
 	"net/http"
 
 	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	rtrace "github.com/cs3org/reva/pkg/trace"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -49,9 +51,21 @@ func handler(log zerolog.Logger, h http.Handler) http.Handler {
 			ctx, span = rtrace.Provider.Tracer("http").Start(ctx, "http interceptor")
 		}
 
-		sub := log.With().Str("traceid", span.SpanContext().TraceID().String()).Logger()
+		reqID := getRequestID(r)
+		w.Header().Set(ctxpkg.RequestIDHeader, reqID)
+		ctx = ctxpkg.ContextSetRequestID(ctx, reqID)
+
+		sub := log.With().Str("traceid", span.SpanContext().TraceID().String()).Str("requestid", reqID).Logger()
 		ctx = appctx.WithLogger(ctx, &sub)
 		r = r.WithContext(ctx)
 		h.ServeHTTP(w, r)
 	})
 }
+
+// getRequestID honors the request ID sent by the client if it is sane, otherwise a new one is generated.
+func getRequestID(r *http.Request) string {
+	if id := r.Header.Get(ctxpkg.RequestIDHeader); ctxpkg.IsValidRequestID(id) {
+		return id
+	}
+	return uuid.NewString()
+}
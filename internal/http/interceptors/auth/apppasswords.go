@@ -0,0 +1,123 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package auth
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/auth/scope"
+)
+
+// appPasswordsConfig configures the enforcement of app passwords on the WebDAV endpoints.
+type appPasswordsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Paths are the path prefixes the policy is enforced on.
+	Paths []string `mapstructure:"paths"`
+	// CredentialType is the auth type basic credentials are authenticated against, so that only
+	// app passwords and not the actual passwords of the users are accepted.
+	CredentialType string `mapstructure:"credential_type"`
+	// UserAgentExceptions lists user agents (matched as substrings) the policy is not enforced for.
+	UserAgentExceptions []string `mapstructure:"user_agent_exceptions"`
+	// HelpURL points to the page where users can generate app passwords.
+	HelpURL string `mapstructure:"help_url"`
+}
+
+func (c *appPasswordsConfig) init() {
+	if len(c.Paths) == 0 {
+		c.Paths = []string{"/remote.php/webdav", "/remote.php/dav", "/webdav", "/dav"}
+	}
+	if c.CredentialType == "" {
+		c.CredentialType = "appauth"
+	}
+}
+
+// appPasswordsPolicy rejects interactive sessions (e.g. OIDC) on the WebDAV endpoints, so that sync
+// clients can only authenticate with app passwords. The tokens minted for app passwords are accepted.
+type appPasswordsPolicy struct {
+	conf *appPasswordsConfig
+}
+
+func newAppPasswordsPolicy(c *appPasswordsConfig) *appPasswordsPolicy {
+	c.init()
+	return &appPasswordsPolicy{conf: c}
+}
+
+// applies checks whether the policy has to be enforced for the given request.
+func (p *appPasswordsPolicy) applies(r *http.Request) bool {
+	matched := false
+	for _, prefix := range p.conf.Paths {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if r.URL.Path == prefix || strings.HasPrefix(r.URL.Path, prefix+"/") {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false
+	}
+
+	ua := r.UserAgent()
+	for _, e := range p.conf.UserAgentExceptions {
+		if e != "" && strings.Contains(ua, e) {
+			return false
+		}
+	}
+	return true
+}
+
+// acceptsToken checks whether a token was minted for an app password. The
+// tokens of interactive sessions, and the ones not recording their
+// credentials, are refused.
+func (p *appPasswordsPolicy) acceptsToken(tokenScope map[string]*authpb.Scope) bool {
+	authType, ok := scope.GetCredentialType(tokenScope)
+	return ok && (authType == p.conf.CredentialType || authType == "appauth")
+}
+
+// reject replies with an authentication challenge and an error explaining how to obtain an app password.
+func (p *appPasswordsPolicy) reject(w http.ResponseWriter, r *http.Request, realm string) {
+	if realm == "" {
+		realm = r.Host
+	}
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm="%s"`, realm))
+
+	msg := "This endpoint only accepts app passwords. Please generate an app password and use it to configure your client."
+	if p.conf.HelpURL != "" {
+		msg += " App passwords can be generated at " + p.conf.HelpURL
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusUnauthorized)
+		link := ""
+		if p.conf.HelpURL != "" {
+			u := html.EscapeString(p.conf.HelpURL)
+			link = fmt.Sprintf(`<p><a href="%s">Generate an app password</a></p>`, u)
+		}
+		_, _ = fmt.Fprintf(w, `<!DOCTYPE html><html><head><title>App password required</title></head><body><h1>App password required</h1><p>%s</p>%s</body></html>`, html.EscapeString(msg), link)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusUnauthorized)
+	_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?><d:error xmlns:d="DAV:" xmlns:s="http://sabredav.org/ns"><s:exception>Sabre\DAV\Exception\NotAuthenticated</s:exception><s:message>%s</s:message></d:error>`, html.EscapeString(msg))
+}
@@ -0,0 +1,94 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/auth/scope"
+)
+
+func TestAppPasswordsPolicyApplies(t *testing.T) {
+	p := newAppPasswordsPolicy(&appPasswordsConfig{
+		Enabled:             true,
+		UserAgentExceptions: []string{"Mozilla"},
+	})
+
+	tests := []struct {
+		path      string
+		userAgent string
+		expected  bool
+	}{
+		{"/remote.php/webdav/file.txt", "mirall/2.9.0", true},
+		{"/remote.php/dav/files/einstein", "mirall/2.9.0", true},
+		{"/dav", "mirall/2.9.0", true},
+		{"/davx/file.txt", "mirall/2.9.0", false},
+		{"/ocs/v1.php/cloud/user", "mirall/2.9.0", false},
+		{"/remote.php/webdav/file.txt", "Mozilla/5.0 (X11; Linux x86_64)", false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("PROPFIND", tt.path, nil)
+		r.Header.Set("User-Agent", tt.userAgent)
+		if res := p.applies(r); res != tt.expected {
+			t.Errorf("applies(%s, %s): expected %v, got %v", tt.path, tt.userAgent, tt.expected, res)
+		}
+	}
+}
+
+func TestAppPasswordsPolicyAcceptsToken(t *testing.T) {
+	p := newAppPasswordsPolicy(&appPasswordsConfig{Enabled: true, CredentialType: "apppasswords"})
+
+	minted := func(authType string) map[string]*authpb.Scope {
+		scopes, err := scope.AddOwnerScope(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if authType == "" {
+			return scopes
+		}
+		scopes, err = scope.AddCredentialScope(authType, scopes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return scopes
+	}
+
+	tests := []struct {
+		authType string
+		expected bool
+	}{
+		// the session a sync client started with its app password
+		{"apppasswords", true},
+		{"appauth", true},
+		// interactive sessions
+		{"oidc", false},
+		{"basic", false},
+		// tokens not recording their credentials
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if res := p.acceptsToken(minted(tt.authType)); res != tt.expected {
+			t.Errorf("acceptsToken(%q): expected %v, got %v", tt.authType, tt.expected, res)
+		}
+	}
+}
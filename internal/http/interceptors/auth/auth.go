@@ -21,7 +21,9 @@ package auth
 import (
 	"context"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -34,8 +36,11 @@ import (
 	tokenwriterregistry "github.com/cs3org/reva/internal/http/interceptors/auth/tokenwriter/registry"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/auth"
+	"github.com/cs3org/reva/pkg/auth/loginguard"
 	"github.com/cs3org/reva/pkg/auth/scope"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/devices"
+	deviceregistry "github.com/cs3org/reva/pkg/devices/registry"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
@@ -43,6 +48,8 @@ import (
 	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/cs3org/reva/pkg/token"
 	tokenmgr "github.com/cs3org/reva/pkg/token/manager/registry"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	revocationregistry "github.com/cs3org/reva/pkg/token/revocation/registry"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
@@ -66,6 +73,21 @@ type config struct {
 	TokenManagers          map[string]map[string]interface{} `mapstructure:"token_managers"`
 	TokenWriter            string                            `mapstructure:"token_writer"`
 	TokenWriters           map[string]map[string]interface{} `mapstructure:"token_writers"`
+	// PublicShareGuard protects public links against brute-force attacks.
+	PublicShareGuard loginguard.Config `mapstructure:"public_share_guard"`
+	// BasicAuthGuard protects the passwords of the users against brute-force attacks.
+	BasicAuthGuard loginguard.Config `mapstructure:"basic_auth_guard"`
+	// AppPasswordsOnly restricts the WebDAV endpoints to app passwords.
+	AppPasswordsOnly appPasswordsConfig `mapstructure:"app_passwords_only"`
+	// RevocationStore is the store checked for revoked tokens. Revocation checks are disabled if empty.
+	RevocationStore    string                            `mapstructure:"revocation_store"`
+	RevocationStores   map[string]map[string]interface{} `mapstructure:"revocation_stores"`
+	RevocationCacheTTL int                               `mapstructure:"revocation_cache_ttl" docs:"30;Time in seconds the revocation lookups are cached for."`
+	// DeviceRegistry is the registry the clients of the users are tracked in. Devices are not tracked if empty.
+	DeviceRegistry         string                            `mapstructure:"device_registry"`
+	DeviceRegistries       map[string]map[string]interface{} `mapstructure:"device_registries"`
+	DeviceActivityInterval int                               `mapstructure:"device_activity_interval" docs:"300;Time in seconds after which the activity of a device is written to the registry again."`
+	DeviceTokenLifetime    int                               `mapstructure:"device_token_lifetime" docs:"86400;Time in seconds the tokens used by a device are revoked for when the device is revoked. Should match the expiration of the tokens."`
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -153,6 +175,52 @@ func New(m map[string]interface{}, unprotected []string) (global.Middleware, err
 		return nil, err
 	}
 
+	guards := map[string]*loginGuard{}
+	for credType, c := range map[string]*loginguard.Config{"basic": &conf.BasicAuthGuard, "publicshares": &conf.PublicShareGuard} {
+		if !c.Enabled {
+			continue
+		}
+		if guards[credType], err = newLoginGuard(credType, c); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.RevocationCacheTTL == 0 {
+		conf.RevocationCacheTTL = 30
+	}
+	checker, err := revocationregistry.NewChecker(conf.RevocationStore, conf.RevocationStores, time.Duration(conf.RevocationCacheTTL)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	var store revocation.Store
+	if checker != nil {
+		store = checker.Store()
+	}
+
+	var tracker *devices.Tracker
+	if conf.DeviceRegistry != "" {
+		f, ok := deviceregistry.NewFuncs[conf.DeviceRegistry]
+		if !ok {
+			return nil, fmt.Errorf("device registry not found: %s", conf.DeviceRegistry)
+		}
+		reg, err := f(conf.DeviceRegistries[conf.DeviceRegistry])
+		if err != nil {
+			return nil, err
+		}
+		if conf.DeviceActivityInterval == 0 {
+			conf.DeviceActivityInterval = 300
+		}
+		if conf.DeviceTokenLifetime == 0 {
+			conf.DeviceTokenLifetime = 86400
+		}
+		tracker = devices.NewTracker(reg, store, time.Duration(conf.DeviceTokenLifetime)*time.Second, time.Duration(conf.DeviceActivityInterval)*time.Second)
+	}
+
+	var appPasswords *appPasswordsPolicy
+	if conf.AppPasswordsOnly.Enabled {
+		appPasswords = newAppPasswordsPolicy(&conf.AppPasswordsOnly)
+	}
+
 	chain := func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// OPTION requests need to pass for preflight requests
@@ -173,7 +241,7 @@ func New(m map[string]interface{}, unprotected []string) (global.Middleware, err
 				isUnprotectedEndpoint = true
 			}
 
-			ctx, err := authenticateUser(w, r, conf, tokenStrategy, tokenManager, tokenWriter, credChain, isUnprotectedEndpoint)
+			ctx, err := authenticateUser(w, r, conf, tokenStrategy, tokenManager, tokenWriter, credChain, guards, appPasswords, checker, tracker, isUnprotectedEndpoint)
 			if err != nil {
 				if !isUnprotectedEndpoint {
 					return
@@ -187,7 +255,7 @@ func New(m map[string]interface{}, unprotected []string) (global.Middleware, err
 	return chain, nil
 }
 
-func authenticateUser(w http.ResponseWriter, r *http.Request, conf *config, tokenStrategy auth.TokenStrategy, tokenManager token.Manager, tokenWriter auth.TokenWriter, credChain map[string]auth.CredentialStrategy, isUnprotectedEndpoint bool) (context.Context, error) {
+func authenticateUser(w http.ResponseWriter, r *http.Request, conf *config, tokenStrategy auth.TokenStrategy, tokenManager token.Manager, tokenWriter auth.TokenWriter, credChain map[string]auth.CredentialStrategy, guards map[string]*loginGuard, appPasswords *appPasswordsPolicy, checker *revocation.Checker, tracker *devices.Tracker, isUnprotectedEndpoint bool) (context.Context, error) {
 	ctx := r.Context()
 	log := appctx.GetLogger(ctx)
 
@@ -200,7 +268,14 @@ func authenticateUser(w http.ResponseWriter, r *http.Request, conf *config, toke
 		return nil, err
 	}
 
+	// interactive sessions are not accepted on the endpoints restricted to app passwords
+	enforceAppPasswords := appPasswords != nil && appPasswords.applies(r)
+
+	device := &devices.Client{UserAgent: r.UserAgent()}
+
 	tkn := tokenStrategy.GetToken(r)
+	tokenProvided := tkn != ""
+
 	if tkn == "" {
 		log.Warn().Msg("core access token not set")
 
@@ -241,8 +316,34 @@ func authenticateUser(w http.ResponseWriter, r *http.Request, conf *config, toke
 			ClientSecret: creds.ClientSecret,
 		}
 
+		if enforceAppPasswords && creds.Type != "publicshares" {
+			if creds.Type != "basic" {
+				err := errtypes.PermissionDenied("only app passwords are accepted")
+				if !isUnprotectedEndpoint {
+					log.Warn().Str("type", creds.Type).Str("user-agent", r.UserAgent()).Msg("credentials rejected, only app passwords are accepted")
+					appPasswords.reject(w, r, conf.Realm)
+				}
+				return nil, err
+			}
+			req.Type = appPasswords.conf.CredentialType
+		}
+
 		log.Debug().Msgf("AuthenticateRequest: type: %s, client_id: %s against %s", req.Type, req.ClientId, conf.GatewaySvc)
 
+		// attempts with passwords and to access public links are tracked to slow down and block brute-force attacks
+		guard, guarded := guards[creds.Type]
+		var clientIP string
+		if guarded {
+			clientIP, _ = utils.GetClientIP(r)
+			if retryAfter, blocked := guard.blocked(clientIP, creds.ClientID); blocked {
+				err := errtypes.PermissionDenied("too many failed attempts")
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				logError(isUnprotectedEndpoint, log, err, "authentication blocked", http.StatusTooManyRequests, w)
+				return nil, err
+			}
+			time.Sleep(guard.Delay(clientIP, creds.ClientID))
+		}
+
 		res, err := client.Authenticate(ctx, req)
 		if err != nil {
 			logError(isUnprotectedEndpoint, log, err, "error calling Authenticate", http.StatusUnauthorized, w)
@@ -250,10 +351,28 @@ func authenticateUser(w http.ResponseWriter, r *http.Request, conf *config, toke
 		}
 
 		if res.Status.Code != rpc.Code_CODE_OK {
+			if guarded && res.Status.Code != rpc.Code_CODE_INTERNAL {
+				guard.fail(clientIP, creds.ClientID)
+			}
 			err := status.NewErrorFromCode(res.Status.Code, "auth")
+			if enforceAppPasswords && creds.Type == "basic" && !isUnprotectedEndpoint {
+				// most likely the actual password of the user was provided instead of an app password
+				log.Error().Err(err).Msg("error generating access token from app password")
+				appPasswords.reject(w, r, conf.Realm)
+				return nil, err
+			}
 			logError(isUnprotectedEndpoint, log, err, "error generating access token from credentials", http.StatusUnauthorized, w)
 			return nil, err
 		}
+		if guarded {
+			guard.succeed(creds.ClientID)
+		}
+
+		device.CredentialType = req.Type
+		if req.Type == "appauth" || (appPasswords != nil && req.Type == appPasswords.conf.CredentialType) {
+			device.AppPassword = req.ClientSecret
+		}
+		device.Issued = true
 
 		log.Info().Msg("core access token generated")
 		// write token to response
@@ -270,6 +389,42 @@ func authenticateUser(w http.ResponseWriter, r *http.Request, conf *config, toke
 		return nil, err
 	}
 
+	// the sessions started with app passwords go on with their token, the interactive ones are rejected
+	if tokenProvided && enforceAppPasswords && !appPasswords.acceptsToken(tokenScope) {
+		err := errtypes.PermissionDenied("only app passwords are accepted")
+		if !isUnprotectedEndpoint {
+			log.Warn().Str("user-agent", r.UserAgent()).Msg("access token rejected, only app passwords are accepted")
+			appPasswords.reject(w, r, conf.Realm)
+		}
+		return nil, err
+	}
+
+	if checker != nil {
+		revoked, err := checker.IsRevoked(ctx, tokenManager, tkn, u)
+		if err != nil {
+			logError(isUnprotectedEndpoint, log, err, "error checking token revocation", http.StatusInternalServerError, w)
+			return nil, err
+		}
+		if revoked {
+			err := errtypes.PermissionDenied("token has been revoked")
+			logError(isUnprotectedEndpoint, log, err, "token has been revoked", http.StatusUnauthorized, w)
+			return nil, err
+		}
+	}
+
+	// the clients of the users are tracked, so that they can review and cut off their devices
+	if tracker != nil && u.Id.Type != userpb.UserType_USER_TYPE_LIGHTWEIGHT {
+		device.Token = tkn
+		device.IP, _ = utils.GetClientIP(r)
+		if _, err := tracker.Track(ctx, u.Id, device); err != nil {
+			if _, ok := err.(errtypes.IsPermissionDenied); ok {
+				logError(isUnprotectedEndpoint, log, err, "device has been revoked", http.StatusUnauthorized, w)
+				return nil, err
+			}
+			log.Error().Err(err).Msg("error tracking the device")
+		}
+	}
+
 	if sharedconf.SkipUserGroupsInToken() {
 		var groups []string
 		if groupsIf, err := userGroupsCache.Get(u.Id.OpaqueId); err == nil {
@@ -298,6 +453,13 @@ func authenticateUser(w http.ResponseWriter, r *http.Request, conf *config, toke
 		return nil, err
 	}
 
+	// actions taken by admins on behalf of users are marked with both identities
+	if access, ok := scope.GetSupportAccess(tokenScope); ok {
+		sublog := log.With().Str("impersonated_user", u.Id.OpaqueId).Str("impersonator", access.Admin.GetOpaqueId()).Str("support_request", access.RequestID).Logger()
+		sublog.Info().Str("method", r.Method).Str("path", r.URL.Path).Msg("audit: request on behalf of user with support access")
+		ctx = appctx.WithLogger(ctx, &sublog)
+	}
+
 	// store user and core access token in context.
 	ctx = ctxpkg.ContextSetUser(ctx, u)
 	ctx = ctxpkg.ContextSetToken(ctx, tkn)
@@ -305,6 +467,11 @@ func authenticateUser(w http.ResponseWriter, r *http.Request, conf *config, toke
 
 	ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.UserAgentHeader, r.UserAgent())
 
+	// retries of mutating requests are deduplicated by the gateway
+	if key := r.Header.Get(ctxpkg.IdempotencyKeyHeader); key != "" {
+		ctx = ctxpkg.ContextSetIdempotencyKey(ctx, key)
+	}
+
 	return ctx, nil
 }
 
@@ -0,0 +1,100 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/auth/loginguard"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+const (
+	guardResultSucceeded = "succeeded"
+	guardResultFailed    = "failed"
+	guardResultBlocked   = "blocked"
+)
+
+var (
+	guardAttempts = map[string]*stats.Int64Measure{
+		"basic":        stats.Int64("basic_auth_attempts", "The number of authentication attempts with basic auth", stats.UnitDimensionless),
+		"publicshares": stats.Int64("public_share_auth_attempts", "The number of authentication attempts against password-protected public links", stats.UnitDimensionless),
+	}
+	guardResultKey     = tag.MustNewKey("result")
+	registerGuardViews sync.Once
+)
+
+// loginGuard slows down and blocks brute-force attacks against the credentials of a type,
+// tracking the failed attempts per client IP and per account, i.e. username or public link token.
+// The underlying guard is shared within the process, so that admins can inspect and clear its blocks.
+type loginGuard struct {
+	*loginguard.Guard
+	attempts *stats.Int64Measure
+}
+
+func newLoginGuard(credType string, c *loginguard.Config) (*loginGuard, error) {
+	registerGuardViews.Do(func() {
+		for _, m := range guardAttempts {
+			_ = view.Register(&view.View{
+				Name:        m.Name(),
+				Description: m.Description(),
+				Measure:     m,
+				TagKeys:     []tag.Key{guardResultKey},
+				Aggregation: view.Count(),
+			})
+		}
+	})
+	g, err := loginguard.Shared(credType, c)
+	if err != nil {
+		return nil, err
+	}
+	return &loginGuard{Guard: g, attempts: guardAttempts[credType]}, nil
+}
+
+// blocked checks whether attempts from the IP or for the account are currently blocked, and for how long.
+func (g *loginGuard) blocked(ip, account string) (time.Duration, bool) {
+	d, blocked := g.Blocked(ip, account)
+	if blocked {
+		g.record(guardResultBlocked)
+	}
+	return d, blocked
+}
+
+// fail registers a failed attempt and blocks the IP or account if too many attempts failed.
+func (g *loginGuard) fail(ip, account string) {
+	g.record(guardResultFailed)
+	g.Fail(ip, account)
+}
+
+// succeed resets the failed attempts of the account; those of the IP are kept,
+// so that guessing the passwords of several accounts is still slowed down.
+func (g *loginGuard) succeed(account string) {
+	g.record(guardResultSucceeded)
+	g.Succeed(account)
+}
+
+func (g *loginGuard) record(result string) {
+	if ctx, err := tag.New(context.Background(), tag.Insert(guardResultKey, result)); err == nil {
+		stats.Record(ctx, g.attempts.M(1))
+	}
+}
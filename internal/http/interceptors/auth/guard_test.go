@@ -0,0 +1,79 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/auth/loginguard"
+)
+
+func newTestGuard(t *testing.T, c *loginguard.Config) *loginGuard {
+	g, err := loginguard.New("publicshares", c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &loginGuard{Guard: g, attempts: guardAttempts["publicshares"]}
+}
+
+func TestPublicShareGuard(t *testing.T) {
+	g := newTestGuard(t, &loginguard.Config{
+		MaxAttempts: 3,
+		DelayStep:   100,
+		MaxDelay:    150,
+	})
+
+	if d := g.Delay("1.2.3.4", "token"); d != 0 {
+		t.Fatalf("expected no delay without failed attempts, got %v", d)
+	}
+
+	g.fail("1.2.3.4", "token")
+	if d := g.Delay("1.2.3.4", "token"); d != 100*time.Millisecond {
+		t.Fatalf("expected a delay of 100ms, got %v", d)
+	}
+	g.fail("1.2.3.4", "token")
+	if d := g.Delay("5.6.7.8", "token"); d != 150*time.Millisecond {
+		t.Fatalf("expected the delay to be capped at 150ms, got %v", d)
+	}
+	if _, blocked := g.blocked("1.2.3.4", "token"); blocked {
+		t.Fatal("expected attempts not to be blocked yet")
+	}
+
+	g.fail("1.2.3.4", "token")
+	if _, blocked := g.blocked("1.2.3.4", "other-token"); !blocked {
+		t.Fatal("expected the IP to be blocked")
+	}
+	if _, blocked := g.blocked("5.6.7.8", "token"); !blocked {
+		t.Fatal("expected the token to be blocked")
+	}
+	if _, blocked := g.blocked("5.6.7.8", "other-token"); blocked {
+		t.Fatal("expected other IPs and tokens not to be blocked")
+	}
+}
+
+func TestPublicShareGuardSuccess(t *testing.T) {
+	g := newTestGuard(t, &loginguard.Config{MaxAttempts: 3})
+
+	g.fail("1.2.3.4", "token")
+	g.succeed("token")
+	if d := g.Delay("5.6.7.8", "token"); d != 0 {
+		t.Fatalf("expected the failed attempts of the token to be reset, got a delay of %v", d)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	_ "github.com/cs3org/reva/internal/http/interceptors/auth/credential/loader"
+	_ "github.com/cs3org/reva/internal/http/interceptors/auth/token/loader"
+	_ "github.com/cs3org/reva/internal/http/interceptors/auth/tokenwriter/loader"
+	revocationsvc "github.com/cs3org/reva/internal/http/services/revocation"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/token/manager/jwt"
+	_ "github.com/cs3org/reva/pkg/token/revocation/memory"
+	"github.com/rs/zerolog"
+)
+
+// TestRevokedTokens revokes tokens through the revocation service and checks that the
+// middleware of the same process rejects them, both configured with their own memory store.
+func TestRevokedTokens(t *testing.T) {
+	const secret = "jwt-secret"
+
+	log := zerolog.Nop()
+	svc, err := revocationsvc.New(map[string]interface{}{
+		"admin_secret":     "admin",
+		"revocation_store": "memory",
+	}, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	middleware, err := New(map[string]interface{}{
+		"gatewaysvc":       "127.0.0.1:1",
+		"token_managers":   map[string]interface{}{"jwt": map[string]interface{}{"secret": secret}},
+		"revocation_store": "memory",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	mgr, err := jwt.New(map[string]interface{}{"secret": secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scopes, err := scope.AddOwnerScope(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := map[string]string{}
+	for _, username := range []string{"einstein", "marie", "richard"} {
+		u := &userpb.User{Id: &userpb.UserId{Idp: "https://idp.example.org", OpaqueId: username}, Username: username}
+		if tokens[username], err = mgr.MintToken(context.Background(), u, scopes); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	revoke := func(kind string, form url.Values) {
+		r := httptest.NewRequest(http.MethodPost, "/"+kind, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("X-Admin-Secret", "admin")
+		w := httptest.NewRecorder()
+		svc.Handler().ServeHTTP(w, r)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("expected the revocation to be stored, got %d", w.Code)
+		}
+	}
+	revoke("token", url.Values{"token": {tokens["einstein"]}})
+	revoke("user", url.Values{"idp": {"https://idp.example.org"}, "opaque_id": {"marie"}})
+
+	tests := map[string]int{
+		"einstein": http.StatusUnauthorized,
+		"marie":    http.StatusUnauthorized,
+		"richard":  http.StatusOK,
+	}
+	for username, expected := range tests {
+		r := httptest.NewRequest("PROPFIND", "/remote.php/webdav/", nil)
+		r.Header.Set(ctxpkg.TokenHeader, tokens[username])
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != expected {
+			t.Errorf("%s: expected status %d, got %d", username, expected, w.Code)
+		}
+	}
+}
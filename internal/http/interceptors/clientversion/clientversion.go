@@ -0,0 +1,245 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package clientversion implements a middleware inspecting the versions of
+// the ownCloud sync clients, which warns outdated clients and blocks the ones
+// below the minimum versions supported by the deployment.
+package clientversion
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	defaultPriority = 150
+)
+
+// The platforms of the sync clients.
+const (
+	PlatformDesktop = "desktop"
+	PlatformAndroid = "android"
+	PlatformIOS     = "ios"
+)
+
+// the products of the sync clients in their user agents, e.g.
+// "Mozilla/5.0 (Linux) mirall/2.10.1 (build 6389) (ownCloud, ubuntu-5.4 ...)"
+var clientRegex = regexp.MustCompile(`(mirall|ownCloud-android|ownCloudApp|ownCloud-iOS)/([0-9]+(?:\.[0-9]+)*)`)
+
+var platforms = map[string]string{
+	"mirall":           PlatformDesktop,
+	"ownCloud-android": PlatformAndroid,
+	"ownCloudApp":      PlatformIOS,
+	"ownCloud-iOS":     PlatformIOS,
+}
+
+func init() {
+	global.RegisterMiddleware("clientversion", New)
+}
+
+type config struct {
+	Priority int `mapstructure:"priority"`
+	// Prefixes are the paths of the webdav and OCS endpoints the policy applies to.
+	Prefixes []string `mapstructure:"prefixes"`
+	// MinVersions are the oldest versions allowed per platform, older clients are blocked.
+	MinVersions map[string]string `mapstructure:"min_versions"`
+	// RecommendedVersions are the versions below which clients are warned.
+	RecommendedVersions map[string]string `mapstructure:"recommended_versions"`
+	// UpgradeURL is where the users find the current clients.
+	UpgradeURL string `mapstructure:"upgrade_url"`
+}
+
+func (c *config) init() {
+	if c.Priority == 0 {
+		c.Priority = defaultPriority
+	}
+	if len(c.Prefixes) == 0 {
+		c.Prefixes = []string{"/remote.php", "/dav", "/webdav", "/ocs"}
+	}
+}
+
+// Verdict is the outcome of checking a client version against the policy.
+type Verdict int
+
+const (
+	// Allowed clients are up to date or unknown.
+	Allowed Verdict = iota
+	// Outdated clients are older than the recommended version.
+	Outdated
+	// Blocked clients are older than the minimum version.
+	Blocked
+)
+
+// Client is a sync client identified from its user agent.
+type Client struct {
+	Platform string
+	Version  string
+}
+
+// ParseClient returns the sync client sending the given user agent. The
+// second return value is false for browsers and other clients.
+func ParseClient(userAgent string) (Client, bool) {
+	m := clientRegex.FindStringSubmatch(userAgent)
+	if m == nil {
+		return Client{}, false
+	}
+	return Client{Platform: platforms[m[1]], Version: m[2]}, true
+}
+
+// CompareVersions compares two dotted version numbers, missing parts count as
+// zero. It returns -1, 0 or 1 if a is older, equal or newer than b.
+func CompareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+type policy struct {
+	c *config
+}
+
+// Check returns the verdict of the policy on a client and the version it
+// should be upgraded to.
+func (p *policy) Check(c Client) (Verdict, string) {
+	if min := p.c.MinVersions[c.Platform]; min != "" && CompareVersions(c.Version, min) < 0 {
+		return Blocked, min
+	}
+	if rec := p.c.RecommendedVersions[c.Platform]; rec != "" && CompareVersions(c.Version, rec) < 0 {
+		return Outdated, rec
+	}
+	return Allowed, ""
+}
+
+func (p *policy) applies(path string) bool {
+	for _, prefix := range p.c.Prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *policy) hint(c Client, version string) string {
+	msg := fmt.Sprintf("Your %s client %s is outdated, please upgrade to version %s or later", c.Platform, c.Version, version)
+	if p.c.UpgradeURL != "" {
+		msg += ": " + p.c.UpgradeURL
+	}
+	return msg
+}
+
+// New returns a middleware enforcing the minimum client versions.
+func New(m map[string]interface{}) (global.Middleware, int, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, 0, err
+	}
+	conf.init()
+	for _, versions := range []map[string]string{conf.MinVersions, conf.RecommendedVersions} {
+		for platform := range versions {
+			switch platform {
+			case PlatformDesktop, PlatformAndroid, PlatformIOS:
+			default:
+				return nil, 0, fmt.Errorf("clientversion: unknown platform %s", platform)
+			}
+		}
+	}
+	p := &policy{c: conf}
+
+	handler := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !p.applies(r.URL.Path) {
+				h.ServeHTTP(w, r)
+				return
+			}
+			client, ok := ParseClient(r.UserAgent())
+			if !ok {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			verdict, version := p.Check(client)
+			switch verdict {
+			case Blocked:
+				appctx.GetLogger(r.Context()).Debug().Str("platform", client.Platform).Str("version", client.Version).Msg("blocking outdated client")
+				p.block(w, r, client, version)
+				return
+			case Outdated:
+				w.Header().Set("Warning", fmt.Sprintf("299 reva %q", p.hint(client, version)))
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+	return handler, conf.Priority, nil
+}
+
+// block answers like ownCloud does to legacy clients: webdav requests are
+// forbidden with an "Unsupported client version." exception, which the
+// clients show to the users, OCS requests require an upgrade.
+func (p *policy) block(w http.ResponseWriter, r *http.Request, c Client, version string) {
+	if strings.HasPrefix(r.URL.Path, "/ocs") {
+		response.WriteOCSError(w, r, http.StatusUpgradeRequired, p.hint(c, version), nil)
+		return
+	}
+
+	b, err := xml.Marshal(&errorXML{
+		Xmlnsd:    "DAV",
+		Xmlnss:    "http://sabredav.org/ns",
+		Exception: "Sabre\\DAV\\Exception\\Forbidden",
+		Message:   "Unsupported client version.",
+		Hint:      p.hint(c, version),
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusForbidden)
+	_, _ = w.Write([]byte(xml.Header + string(b)))
+}
+
+type errorXML struct {
+	XMLName   xml.Name `xml:"d:error"`
+	Xmlnsd    string   `xml:"xmlns:d,attr"`
+	Xmlnss    string   `xml:"xmlns:s,attr"`
+	Exception string   `xml:"s:exception"`
+	Message   string   `xml:"s:message"`
+	Hint      string   `xml:"s:hint,omitempty"`
+}
@@ -0,0 +1,104 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package clientversion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseClient(t *testing.T) {
+	tests := []struct {
+		ua       string
+		platform string
+		version  string
+		ok       bool
+	}{
+		{"Mozilla/5.0 (Linux) mirall/2.10.1 (build 6389) (ownCloud, ubuntu-5.4 ClientArchitecture: x86_64 OsArchitecture: x86_64)", PlatformDesktop, "2.10.1", true},
+		{"Mozilla/5.0 (Android) ownCloud-android/2.19.0", PlatformAndroid, "2.19.0", true},
+		{"ownCloudApp/11.8.1 (App/192; iOS/15.4; iPhone)", PlatformIOS, "11.8.1", true},
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:99.0) Gecko/20100101 Firefox/99.0", "", "", false},
+	}
+	for _, tt := range tests {
+		c, ok := ParseClient(tt.ua)
+		if ok != tt.ok || c.Platform != tt.platform || c.Version != tt.version {
+			t.Errorf("ParseClient(%q) = %+v, %v, want %s %s, %v", tt.ua, c, ok, tt.platform, tt.version, tt.ok)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2.10.1", "2.9.0", 1},
+		{"2.9", "2.9.0", 0},
+		{"2.9.0", "2.10", -1},
+		{"3", "2.99.99", 1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	mw, _, err := New(map[string]interface{}{
+		"min_versions":         map[string]string{"desktop": "2.9.0"},
+		"recommended_versions": map[string]string{"desktop": "2.11.0"},
+		"upgrade_url":          "https://owncloud.com/desktop-app/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		path    string
+		ua      string
+		status  int
+		warning bool
+	}{
+		{"/remote.php/dav/files/einstein", "mirall/2.8.2", http.StatusForbidden, false},
+		{"/remote.php/dav/files/einstein", "mirall/2.10.0", http.StatusOK, true},
+		{"/remote.php/dav/files/einstein", "mirall/2.11.0", http.StatusOK, false},
+		{"/ocs/v2.php/cloud/capabilities", "mirall/2.8.2", http.StatusUpgradeRequired, false},
+		{"/app/list", "mirall/2.8.2", http.StatusOK, false},
+		{"/remote.php/dav/files/einstein", "curl/7.81.0", http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("PROPFIND", tt.path, nil)
+		r.Header.Set("User-Agent", tt.ua)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s %s: got status %d, want %d", tt.path, tt.ua, w.Code, tt.status)
+		}
+		if warning := w.Header().Get("Warning") != ""; warning != tt.warning {
+			t.Errorf("%s %s: got warning %v, want %v", tt.path, tt.ua, warning, tt.warning)
+		}
+		if tt.status == http.StatusForbidden && !strings.Contains(w.Body.String(), "Unsupported client version.") {
+			t.Errorf("%s %s: unexpected body %s", tt.path, tt.ua, w.Body.String())
+		}
+	}
+}
@@ -19,9 +19,9 @@
 package cors
 
 import (
+	"github.com/cs3org/reva/pkg/rhttp/cors"
 	"github.com/cs3org/reva/pkg/rhttp/global"
 	"github.com/mitchellh/mapstructure"
-	"github.com/rs/cors"
 )
 
 const (
@@ -33,18 +33,12 @@ func init() {
 }
 
 type config struct {
-	AllowCredentials   bool     `mapstructure:"allow_credentials"`
-	OptionsPassthrough bool     `mapstructure:"options_passthrough"`
-	Debug              bool     `mapstructure:"debug"`
-	MaxAge             int      `mapstructure:"max_age"`
-	Priority           int      `mapstructure:"priority"`
-	AllowedMethods     []string `mapstructure:"allowed_methods"`
-	AllowedHeaders     []string `mapstructure:"allowed_headers"`
-	ExposedHeaders     []string `mapstructure:"exposed_headers"`
-	AllowedOrigins     []string `mapstructure:"allowed_origins"`
+	cors.Config `mapstructure:",squash"`
+	Priority    int `mapstructure:"priority"`
 }
 
-// New creates a new CORS middleware.
+// New creates a new CORS middleware which applies to all services.
+// CORS can also be configured for individual services using the cors section of their configuration.
 func New(m map[string]interface{}) (global.Middleware, int, error) {
 	conf := &config{}
 	if err := mapstructure.Decode(m, conf); err != nil {
@@ -54,75 +48,7 @@ func New(m map[string]interface{}) (global.Middleware, int, error) {
 	if conf.Priority == 0 {
 		conf.Priority = defaultPriority
 	}
+	conf.Init()
 
-	// apply some defaults to reduce configuration boilerplate
-	if len(conf.AllowedOrigins) == 0 {
-		conf.AllowedOrigins = []string{"*"}
-	}
-
-	if len(conf.AllowedMethods) == 0 {
-		conf.AllowedMethods = []string{
-			"OPTIONS",
-			"HEAD",
-			"GET",
-			"PUT",
-			"POST",
-			"DELETE",
-			"MKCOL",
-			"PROPFIND",
-			"PROPPATCH",
-			"MOVE",
-			"COPY",
-			"REPORT",
-			"SEARCH",
-		}
-	}
-
-	if len(conf.AllowedHeaders) == 0 {
-		conf.AllowedHeaders = []string{
-			"Origin",
-			"Accept",
-			"Content-Type",
-			"Depth",
-			"Authorization",
-			"Ocs-Apirequest",
-			"If-None-Match",
-			"If-Match",
-			"Destination",
-			"Overwrite",
-			"X-Request-Id",
-			"X-Requested-With",
-			"Tus-Resumable",
-			"Tus-Checksum-Algorithm",
-			"Upload-Concat",
-			"Upload-Length",
-			"Upload-Metadata",
-			"Upload-Defer-Length",
-			"Upload-Expires",
-			"Upload-Checksum",
-			"Upload-Offset",
-			"X-HTTP-Method-Override",
-		}
-	}
-
-	if len(conf.ExposedHeaders) == 0 {
-		conf.ExposedHeaders = []string{
-			"Location",
-		}
-	}
-
-	// TODO(jfd): use log from request context, otherwise fmt will be used to log,
-	// preventing us from pinging the log to eg jq
-	c := cors.New(cors.Options{
-		AllowCredentials:   conf.AllowCredentials,
-		AllowedHeaders:     conf.AllowedHeaders,
-		AllowedMethods:     conf.AllowedMethods,
-		AllowedOrigins:     conf.AllowedOrigins,
-		ExposedHeaders:     conf.ExposedHeaders,
-		MaxAge:             conf.MaxAge,
-		OptionsPassthrough: conf.OptionsPassthrough,
-		Debug:              conf.Debug,
-	})
-
-	return c.Handler, conf.Priority, nil
+	return cors.New(&conf.Config).Handler, conf.Priority, nil
 }
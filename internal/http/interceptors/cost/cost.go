@@ -0,0 +1,97 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package cost
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/cs3org/reva/pkg/cost"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+)
+
+// New returns a new HTTP middleware accounting the resources consumed by the
+// requests, including the bytes of their bodies and of the responses, to their
+// users and client applications.
+func New() func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := cost.NewRecord()
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = &body{ReadCloser: r.Body, rec: rec}
+			}
+
+			start := time.Now()
+			h.ServeHTTP(&responseWriter{ResponseWriter: w, rec: rec}, r.WithContext(cost.ContextSetRecord(r.Context(), rec)))
+			cost.Account(rec, cost.ClientApp(r.UserAgent()), time.Since(start))
+		})
+	}
+}
+
+// User returns a new HTTP middleware setting the user the request has been
+// authenticated as in its record. It has to run after the authentication.
+func User(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, ok := ctxpkg.ContextGetUser(r.Context()); ok {
+			cost.SetUser(r.Context(), u.Username)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// body counts the bytes read from the body of a request.
+type body struct {
+	io.ReadCloser
+	rec *cost.Record
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.rec.AddBytes(int64(n))
+	return n, err
+}
+
+// responseWriter counts the bytes of the response.
+type responseWriter struct {
+	http.ResponseWriter
+	rec *cost.Record
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.rec.AddBytes(int64(n))
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return h.Hijack()
+}
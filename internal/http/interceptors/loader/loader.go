@@ -20,6 +20,7 @@ package loader
 
 import (
 	// Load core HTTP middlewares.
+	_ "github.com/cs3org/reva/internal/http/interceptors/clientversion"
 	_ "github.com/cs3org/reva/internal/http/interceptors/cors"
 	_ "github.com/cs3org/reva/internal/http/interceptors/providerauthorizer"
 	// Add your own middleware.
@@ -0,0 +1,519 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package absence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/asim/go-micro/plugins/events/nats/v4"
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	preferences "github.com/cs3org/go-cs3apis/cs3/preferences/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/events/server"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/share/absence"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/smtpclient"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/metadata"
+)
+
+func init() {
+	global.Register(serviceName, New)
+}
+
+const serviceName = "absence"
+
+const defaultSubject = `{{.Grantee}} is absent until {{.Until}}`
+
+const defaultBody = `Hello {{.Sharer}},
+
+{{.Grantee}} is absent until {{.Until}} and will see {{if .ResourceName}}"{{.ResourceName}}"{{else}}your share{{end}} when back.
+{{- if .Delegate}}
+In the meantime, it was passed on to {{.Delegate}}.{{end}}
+{{- if .Message}}
+
+{{.Message}}{{end}}
+
+This is an automatic reply.
+`
+
+// Config holds the config options for the absence service.
+type Config struct {
+	Prefix        string `mapstructure:"prefix"`
+	GatewaySvc    string `mapstructure:"gatewaysvc"`
+	NatsAddress   string `mapstructure:"nats_address"`
+	NatsClusterID string `mapstructure:"nats_clusterid"`
+	// MachineAuthAPIKey is the key of the machine auth provider, used to act on behalf of the absent users.
+	MachineAuthAPIKey string `mapstructure:"machine_auth_apikey"`
+	// SMTPCredentials are used to send the replies, which are not sent if not configured.
+	SMTPCredentials *smtpclient.SMTPCredentials `mapstructure:"smtp_credentials"`
+	// SubjectTemplate and BodyTemplate are text templates executed with the reply.
+	SubjectTemplate string `mapstructure:"subject_template"`
+	BodyTemplate    string `mapstructure:"body_template"`
+}
+
+func (c *Config) init() {
+	if c.Prefix == "" {
+		c.Prefix = serviceName
+	}
+	if c.SubjectTemplate == "" {
+		c.SubjectTemplate = defaultSubject
+	}
+	if c.BodyTemplate == "" {
+		c.BodyTemplate = defaultBody
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+type svc struct {
+	conf    *Config
+	log     *zerolog.Logger
+	router  *chi.Mux
+	creds   *smtpclient.SMTPCredentials
+	subject *template.Template
+	body    *template.Template
+
+	// replied remembers until when the sharers were answered, so that they
+	// get a single reply per absence.
+	mu      sync.Mutex
+	replied map[string]time.Time
+}
+
+// New returns a new service answering the shares received by absent users
+// and passing them on to the colleague they designated, according to the
+// absence stored in their preferences. The service also exposes the absence
+// of the current user.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &Config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, errors.Wrap(err, "absence: error decoding configuration")
+	}
+	conf.init()
+
+	if conf.NatsAddress == "" {
+		return nil, errors.New("absence: no nats address configured")
+	}
+	if conf.MachineAuthAPIKey == "" {
+		return nil, errors.New("absence: no machine auth api key configured")
+	}
+
+	subject, err := template.New("subject").Parse(conf.SubjectTemplate)
+	if err != nil {
+		return nil, errors.Wrap(err, "absence: error parsing subject template")
+	}
+	body, err := template.New("body").Parse(conf.BodyTemplate)
+	if err != nil {
+		return nil, errors.Wrap(err, "absence: error parsing body template")
+	}
+
+	stream, err := server.NewNatsStream(nats.Address(conf.NatsAddress), nats.ClusterID(conf.NatsClusterID))
+	if err != nil {
+		return nil, errors.Wrap(err, "absence: error connecting to the event stream")
+	}
+
+	// a share must be answered only once, so all instances share the same consumer group
+	evs, err := events.Consume(stream, serviceName, events.ShareCreated{})
+	if err != nil {
+		return nil, errors.Wrap(err, "absence: error consuming events")
+	}
+
+	s := &svc{
+		conf:    conf,
+		log:     log,
+		router:  chi.NewRouter(),
+		subject: subject,
+		body:    body,
+		replied: map[string]time.Time{},
+	}
+	if conf.SMTPCredentials != nil {
+		s.creds = smtpclient.NewSMTPCredentials(conf.SMTPCredentials)
+	}
+	s.router.Get("/", s.handleGetAbsence)
+	s.router.Put("/", s.handleSetAbsence)
+	s.router.Delete("/", s.handleDeleteAbsence)
+
+	go func() {
+		for ev := range evs {
+			if e, ok := ev.(events.ShareCreated); ok {
+				s.handleShareCreated(e)
+			}
+		}
+	}()
+
+	return s, nil
+}
+
+// Close is called when this service is being stopped.
+func (s *svc) Close() error {
+	return nil
+}
+
+// Prefix returns the main endpoint of this service.
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+// Unprotected returns all endpoints that can be queried without prior authorization.
+func (s *svc) Unprotected() []string {
+	return []string{}
+}
+
+// Handler serves all HTTP requests.
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.router.ServeHTTP(w, r)
+	})
+}
+
+func (s *svc) handleGetAbsence(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	a, err := s.absence(ctx, client)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if a == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	js, err := json.Marshal(a)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(js); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("absence: error writing response")
+	}
+}
+
+func (s *svc) handleSetAbsence(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	a := &absence.Absence{
+		From:    r.FormValue("from"),
+		Until:   r.FormValue("until"),
+		Message: r.FormValue("message"),
+	}
+	if err := a.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if username := r.FormValue("delegate"); username != "" {
+		res, err := client.GetUserByClaim(ctx, &userpb.GetUserByClaimRequest{Claim: "username", Value: username})
+		switch {
+		case err != nil:
+			writeError(w, r, err)
+			return
+		case res.Status.Code == rpc.Code_CODE_NOT_FOUND:
+			http.Error(w, "unknown delegate "+username, http.StatusBadRequest)
+			return
+		case res.Status.Code != rpc.Code_CODE_OK:
+			writeError(w, r, errtypes.InternalError(res.Status.Message))
+			return
+		}
+		if u, ok := ctxpkg.ContextGetUser(ctx); ok && utils.UserEqual(u.Id, res.User.Id) {
+			http.Error(w, "the delegate must be another user", http.StatusBadRequest)
+			return
+		}
+		a.Delegate = res.User.Id
+	}
+
+	v, err := a.Encode()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := setPreference(ctx, client, v); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *svc) handleDeleteAbsence(w http.ResponseWriter, r *http.Request) {
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	// the preferences can not be removed, an empty value means no absence
+	if err := setPreference(r.Context(), client, ""); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func setPreference(ctx context.Context, client gateway.GatewayAPIClient, v string) error {
+	res, err := client.SetKey(ctx, &preferences.SetKeyRequest{
+		Key: &preferences.PreferenceKey{Namespace: absence.PreferenceNamespace, Key: absence.PreferenceKey},
+		Val: v,
+	})
+	switch {
+	case err != nil:
+		return err
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return errtypes.InternalError(res.Status.Message)
+	}
+	return nil
+}
+
+// absence returns the absence of the user of the context, or nil if none is set.
+func (s *svc) absence(ctx context.Context, client gateway.GatewayAPIClient) (*absence.Absence, error) {
+	res, err := client.GetKey(ctx, &preferences.GetKeyRequest{
+		Key: &preferences.PreferenceKey{Namespace: absence.PreferenceNamespace, Key: absence.PreferenceKey},
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case res.Status.Code == rpc.Code_CODE_NOT_FOUND:
+		return nil, nil
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, errtypes.InternalError(res.Status.Message)
+	}
+	return absence.Parse(res.Val)
+}
+
+// handleShareCreated answers and delegates the shares received by absent users.
+// Group shares are ignored, as the other members of the group received them as well.
+func (s *svc) handleShareCreated(ev events.ShareCreated) {
+	if ev.GranteeUserID == nil {
+		return
+	}
+	log := s.log.With().Str("sharer", ev.Sharer.GetOpaqueId()).Str("grantee", ev.GranteeUserID.OpaqueId).Interface("item", ev.ItemID).Logger()
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		log.Error().Err(err).Msg("absence: error getting gateway client")
+		return
+	}
+
+	ctx, grantee, err := s.impersonate(client, ev.GranteeUserID)
+	if err != nil {
+		log.Error().Err(err).Msg("absence: error impersonating grantee")
+		return
+	}
+	a, err := s.absence(ctx, client)
+	if err != nil {
+		log.Error().Err(err).Msg("absence: error getting absence")
+		return
+	}
+	if !a.Active(time.Now()) {
+		return
+	}
+
+	reply := &absence.Reply{
+		Grantee: grantee.DisplayName,
+		Until:   a.Until,
+		Message: a.Message,
+	}
+
+	info, perms, err := s.receivedResource(ctx, client, ev)
+	if err != nil {
+		log.Error().Err(err).Msg("absence: error getting received resource")
+	}
+	if info != nil {
+		reply.ResourceName = path.Base(info.Path)
+	}
+
+	if a.Delegate != nil {
+		if info == nil {
+			log.Error().Msg("absence: received resource unknown, not delegating share")
+		} else if delegate, err := s.delegate(ctx, client, info, perms, a.Delegate); err != nil {
+			log.Error().Err(err).Str("delegate", a.Delegate.OpaqueId).Msg("absence: error delegating share")
+		} else {
+			reply.Delegate = delegate.DisplayName
+			log.Info().Str("delegate", a.Delegate.OpaqueId).Msg("absence: share delegated")
+		}
+	}
+
+	if err := s.reply(ctx, client, ev.Sharer, ev.GranteeUserID, a, reply); err != nil {
+		log.Error().Err(err).Msg("absence: error replying to sharer")
+	}
+}
+
+// receivedResource returns the resource shared with the user of the context and
+// the permissions it was shared with.
+func (s *svc) receivedResource(ctx context.Context, client gateway.GatewayAPIClient, ev events.ShareCreated) (*provider.ResourceInfo, *provider.ResourcePermissions, error) {
+	var perms *provider.ResourcePermissions
+	if ev.ShareID != nil {
+		res, err := client.GetReceivedShare(ctx, &collaboration.GetReceivedShareRequest{
+			Ref: &collaboration.ShareReference{Spec: &collaboration.ShareReference_Id{Id: ev.ShareID}},
+		})
+		switch {
+		case err != nil:
+			return nil, nil, err
+		case res.Status.Code != rpc.Code_CODE_OK:
+			return nil, nil, errtypes.InternalError(res.Status.Message)
+		}
+		perms = res.Share.Share.GetPermissions().GetPermissions()
+	}
+
+	res, err := client.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{ResourceId: ev.ItemID}})
+	switch {
+	case err != nil:
+		return nil, nil, err
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, nil, errtypes.InternalError(res.Status.Message)
+	}
+	if perms == nil {
+		// events of older gateways do not carry the id of the share
+		perms = res.Info.PermissionSet
+	}
+	return res.Info, perms, nil
+}
+
+// delegate shares the resource with the delegate on behalf of the user of the
+// context, which requires the received share to allow resharing.
+func (s *svc) delegate(ctx context.Context, client gateway.GatewayAPIClient, info *provider.ResourceInfo, perms *provider.ResourcePermissions, delegate *userpb.UserId) (*userpb.User, error) {
+	userRes, err := client.GetUser(ctx, &userpb.GetUserRequest{UserId: delegate})
+	switch {
+	case err != nil:
+		return nil, err
+	case userRes.Status.Code != rpc.Code_CODE_OK:
+		return nil, errtypes.InternalError(userRes.Status.Message)
+	}
+
+	res, err := client.CreateShare(ctx, &collaboration.CreateShareRequest{
+		ResourceInfo: info,
+		Grant: &collaboration.ShareGrant{
+			Grantee: &provider.Grantee{
+				Type: provider.GranteeType_GRANTEE_TYPE_USER,
+				Id:   &provider.Grantee_UserId{UserId: delegate},
+			},
+			Permissions: &collaboration.SharePermissions{Permissions: perms},
+		},
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case res.Status.Code == rpc.Code_CODE_ALREADY_EXISTS:
+		// the delegate already has access
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, errtypes.InternalError(res.Status.Message)
+	}
+	return userRes.User, nil
+}
+
+// reply mails the reply to the sharer, once per absence of the grantee.
+func (s *svc) reply(ctx context.Context, client gateway.GatewayAPIClient, sharer, grantee *userpb.UserId, a *absence.Absence, reply *absence.Reply) error {
+	if s.creds == nil || utils.UserEqual(sharer, grantee) {
+		return nil
+	}
+
+	res, err := client.GetUser(ctx, &userpb.GetUserRequest{UserId: sharer})
+	switch {
+	case err != nil:
+		return err
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return errtypes.InternalError(res.Status.Message)
+	}
+	if res.User.Mail == "" {
+		return nil
+	}
+	reply.Sharer = res.User.DisplayName
+
+	key := fmt.Sprintf("%s:%s:%s:%s", grantee.Idp, grantee.OpaqueId, sharer.Idp, sharer.OpaqueId)
+	if !s.markReplied(key, a.End()) {
+		return nil
+	}
+
+	subject, body, err := reply.Render(s.subject, s.body)
+	if err != nil {
+		return err
+	}
+	return s.creds.SendMail(res.User.Mail, subject, body)
+}
+
+// markReplied records the reply to a sharer until the end of the absence and
+// reports whether the sharer was not answered yet.
+func (s *svc) markReplied(key string, until time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, t := range s.replied {
+		if now.After(t) {
+			delete(s.replied, k)
+		}
+	}
+	if _, ok := s.replied[key]; ok {
+		return false
+	}
+	s.replied[key] = until
+	return true
+}
+
+// impersonate returns a context authenticated as the given user.
+func (s *svc) impersonate(client gateway.GatewayAPIClient, id *userpb.UserId) (context.Context, *userpb.User, error) {
+	res, err := client.Authenticate(context.Background(), &gateway.AuthenticateRequest{
+		Type:         "machine",
+		ClientId:     "userid:" + id.OpaqueId,
+		ClientSecret: s.conf.MachineAuthAPIKey,
+	})
+	switch {
+	case err != nil:
+		return nil, nil, err
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, nil, fmt.Errorf("error authenticating as %s: %s", id.OpaqueId, res.Status.Message)
+	}
+
+	ctx := ctxpkg.ContextSetToken(context.Background(), res.Token)
+	ctx = ctxpkg.ContextSetUser(ctx, res.User)
+	ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.TokenHeader, res.Token)
+	return ctx, res.User, nil
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	appctx.GetLogger(r.Context()).Error().Err(err).Msg("absence: error handling request")
+	w.WriteHeader(http.StatusInternalServerError)
+}
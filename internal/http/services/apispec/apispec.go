@@ -0,0 +1,185 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package apispec
+
+import (
+	"net/http"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/cs3org/reva/pkg/apispec"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/sysinfo"
+)
+
+func init() {
+	global.Register(serviceName, New)
+	apispec.Register(&apispec.Service{
+		Name:        serviceName,
+		Prefix:      serviceName,
+		Description: "Serves the descriptions of the APIs of this process for the generation of clients.",
+		Operations: []apispec.Operation{
+			{
+				Method:    http.MethodGet,
+				Path:      "/" + openAPIFile,
+				Summary:   "Returns the OpenAPI document of the HTTP services",
+				Responses: []apispec.Response{{Status: http.StatusOK, ContentType: "application/json"}},
+			},
+			{
+				Method:    http.MethodGet,
+				Path:      "/" + descriptorsFile,
+				Summary:   "Returns the protobuf FileDescriptorSet of the gRPC services",
+				Responses: []apispec.Response{{Status: http.StatusOK, ContentType: "application/octet-stream"}},
+			},
+		},
+	})
+}
+
+const (
+	serviceName = "apispec"
+
+	openAPIFile     = "openapi.json"
+	descriptorsFile = "descriptors.pb"
+)
+
+type config struct {
+	Prefix string `mapstructure:"prefix"`
+	// Public allows to download the descriptions without being authenticated.
+	Public bool `mapstructure:"public" docs:"false;Whether the descriptions can be downloaded without being authenticated."`
+}
+
+type svc struct {
+	conf *config
+}
+
+// Close is called when this service is being stopped.
+func (s *svc) Close() error {
+	return nil
+}
+
+// Prefix returns the main endpoint of this service.
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+// Unprotected returns all endpoints that can be queried without prior authorization.
+func (s *svc) Unprotected() []string {
+	if s.conf.Public {
+		return []string{"/"}
+	}
+	return nil
+}
+
+// Handler serves all HTTP requests.
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		head, _ := router.ShiftPath(r.URL.Path)
+		switch head {
+		case openAPIFile:
+			s.handleOpenAPI(w, r)
+		case descriptorsFile:
+			s.handleDescriptors(w, r)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+// handleOpenAPI serves the OpenAPI document of the HTTP services enabled in this process.
+func (s *svc) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	log := appctx.GetLogger(r.Context())
+	data, err := apispec.OpenAPI(version(), apispec.Deployed()).JSON()
+	if err != nil {
+		log.Err(err).Msg("error encoding the OpenAPI document")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(data); err != nil {
+		log.Err(err).Msg("error writing the OpenAPI document")
+	}
+}
+
+// handleDescriptors serves the descriptors of the gRPC services enabled in
+// this process, or of all the services linked into the binary if the process
+// doesn't run any, which is the case of the HTTP only deployments.
+func (s *svc) handleDescriptors(w http.ResponseWriter, r *http.Request) {
+	log := appctx.GetLogger(r.Context())
+	services := sysinfo.Runtime().GRPCServices
+	if len(services) == 0 {
+		services = apispec.GRPCServices()
+	}
+
+	data, missing, err := apispec.Descriptors(services)
+	if err != nil {
+		log.Err(err).Msg("error encoding the descriptors")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if len(missing) > 0 {
+		log.Warn().Strs("services", missing).Msg("no descriptors found for some gRPC services")
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+descriptorsFile+"\"")
+	if _, err := w.Write(data); err != nil {
+		log.Err(err).Msg("error writing the descriptors")
+	}
+}
+
+func version() string {
+	if sysinfo.SysInfo.Reva == nil {
+		return ""
+	}
+	return sysinfo.SysInfo.Reva.Version
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	cfg := &config{}
+	if err := mapstructure.Decode(m, &cfg); err != nil {
+		return nil, errors.Wrap(err, "apispec: error decoding configuration")
+	}
+	applyDefaultConfig(cfg)
+	return cfg, nil
+}
+
+func applyDefaultConfig(conf *config) {
+	if conf.Prefix == "" {
+		conf.Prefix = serviceName
+	}
+}
+
+// New returns a new service serving the descriptions of the APIs.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf, err := parseConfig(m)
+	if err != nil {
+		return nil, err
+	}
+	return &svc{conf: conf}, nil
+}
@@ -23,6 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
@@ -32,12 +33,16 @@ import (
 	"regexp"
 
 	"github.com/cs3org/reva/internal/http/services/archiver/manager"
+	"github.com/cs3org/reva/pkg/apispec"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/rhttp"
 	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/problem"
+	"github.com/cs3org/reva/pkg/rhttp/router"
 	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/smtpclient"
 	"github.com/cs3org/reva/pkg/storage/utils/downloader"
 	"github.com/cs3org/reva/pkg/storage/utils/walker"
 	"github.com/cs3org/reva/pkg/utils/resourceid"
@@ -55,6 +60,10 @@ type svc struct {
 	downloader downloader.Downloader
 
 	allowedFolders []*regexp.Regexp
+
+	jobs            *jobsManager
+	smtpCredentials *smtpclient.SMTPCredentials
+	problems        *problem.Renderer
 }
 
 // Config holds the config options that need to be passed down to all ocdav handlers
@@ -67,10 +76,27 @@ type Config struct {
 	MaxNumFiles    int64    `mapstructure:"max_num_files"`
 	MaxSize        int64    `mapstructure:"max_size"`
 	AllowedFolders []string `mapstructure:"allowed_folders"`
+
+	// Jobs configures the asynchronous creation of archives too large to be streamed directly
+	Jobs struct {
+		Enabled        bool   `mapstructure:"enabled" docs:"false;Whether to enable the creation of archives through asynchronous jobs."`
+		Folder         string `mapstructure:"folder" docs:"/var/tmp/reva/archiver;The folder where the archives are temporarily stored."`
+		Workers        int    `mapstructure:"workers" docs:"2;The number of archives created concurrently."`
+		QueueSize      int    `mapstructure:"queue_size" docs:"100;The maximum number of queued jobs."`
+		MaxNumFiles    int64  `mapstructure:"max_num_files" docs:";The maximum number of files in an archive created by a job."`
+		MaxSize        int64  `mapstructure:"max_size" docs:";The maximum size of an archive created by a job."`
+		LinkExpiration int    `mapstructure:"link_expiration" docs:"86400;The time in seconds a completed archive can be downloaded, and a failed job is kept."`
+		PublicURL      string `mapstructure:"public_url" docs:";The public URL of the server, used in the notification emails."`
+	} `mapstructure:"jobs"`
+	SMTPCredentials *smtpclient.SMTPCredentials `mapstructure:"smtp_credentials"`
+
+	// Errors configures the error pages and problem details returned to the clients
+	Errors problem.Config `mapstructure:"errors"`
 }
 
 func init() {
 	global.Register("archiver", New)
+	apispec.Register(spec)
 }
 
 // New creates a new archiver service
@@ -98,14 +124,27 @@ func New(conf map[string]interface{}, log *zerolog.Logger) (global.Service, erro
 		allowedFolderRegex = append(allowedFolderRegex, regex)
 	}
 
-	return &svc{
+	s := &svc{
 		config:         c,
 		gtwClient:      gtw,
 		downloader:     downloader.NewDownloader(gtw, rhttp.Insecure(c.Insecure), rhttp.Timeout(time.Duration(c.Timeout*int64(time.Second)))),
 		walker:         walker.NewWalker(gtw),
 		log:            log,
 		allowedFolders: allowedFolderRegex,
-	}, nil
+		problems:       problem.NewRenderer(&c.Errors),
+	}
+
+	if c.SMTPCredentials != nil {
+		s.smtpCredentials = smtpclient.NewSMTPCredentials(c.SMTPCredentials)
+	}
+
+	if c.Jobs.Enabled {
+		if s.jobs, err = newJobsManager(s); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
 }
 
 func (c *Config) init() {
@@ -117,6 +156,30 @@ func (c *Config) init() {
 		c.Name = "download"
 	}
 
+	if c.Jobs.Folder == "" {
+		c.Jobs.Folder = "/var/tmp/reva/archiver"
+	}
+
+	if c.Jobs.Workers <= 0 {
+		c.Jobs.Workers = 2
+	}
+
+	if c.Jobs.QueueSize <= 0 {
+		c.Jobs.QueueSize = 100
+	}
+
+	if c.Jobs.MaxNumFiles == 0 {
+		c.Jobs.MaxNumFiles = c.MaxNumFiles
+	}
+
+	if c.Jobs.MaxSize == 0 {
+		c.Jobs.MaxSize = c.MaxSize
+	}
+
+	if c.Jobs.LinkExpiration <= 0 {
+		c.Jobs.LinkExpiration = 86400
+	}
+
 	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
 }
 
@@ -189,25 +252,89 @@ func (s *svc) allAllowed(paths []string) error {
 	return nil
 }
 
-func (s *svc) writeHTTPError(rw http.ResponseWriter, err error) {
+func (s *svc) writeHTTPError(rw http.ResponseWriter, r *http.Request, err error) {
 	s.log.Error().Msg(err.Error())
 
+	// The messages of internal errors are only logged
+	status, detail := http.StatusInternalServerError, ""
 	switch err.(type) {
 	case errtypes.NotFound:
-		rw.WriteHeader(http.StatusNotFound)
+		status, detail = http.StatusNotFound, err.Error()
 	case manager.ErrMaxSize, manager.ErrMaxFileCount:
-		rw.WriteHeader(http.StatusRequestEntityTooLarge)
-	case errtypes.BadRequest:
-		rw.WriteHeader(http.StatusBadRequest)
+		status, detail = http.StatusRequestEntityTooLarge, err.Error()
+	case errtypes.BadRequest, manager.ErrEncryptionNotSupported:
+		status, detail = http.StatusBadRequest, err.Error()
+	case errJobNotReady:
+		status, detail = http.StatusConflict, err.Error()
+	case errJobsQueueFull:
+		status, detail = http.StatusServiceUnavailable, err.Error()
+	}
+
+	s.problems.Write(rw, r, status, detail)
+}
+
+// archiveOptions holds the options of an archive requested by a client.
+type archiveOptions struct {
+	zip          bool
+	password     string
+	reproducible bool
+}
+
+// passwordHeader carries the password of an encrypted archive, as an
+// alternative to the query parameter which ends up in the logs of the URLs.
+const passwordHeader = "X-Archive-Password"
+
+// getArchiveOptions returns the options of the archive requested: the format
+// parameter selects a zip or a tar, by default a zip for the Windows clients
+// and the encrypted archives. The password parameter encrypts the files of a
+// zip, and the reproducible one sorts the entries and fixes their timestamps.
+func getArchiveOptions(r *http.Request) (*archiveOptions, error) {
+	v := r.URL.Query()
+	opts := &archiveOptions{password: r.Header.Get(passwordHeader)}
+	if opts.password == "" {
+		opts.password = v.Get("password")
+	}
+
+	switch format := v.Get("format"); format {
+	case "zip":
+		opts.zip = true
+	case "tar":
+	case "":
+		opts.zip = opts.password != "" || ua.Parse(r.Header.Get("User-Agent")).OS == ua.Windows
 	default:
-		rw.WriteHeader(http.StatusInternalServerError)
+		return nil, errtypes.BadRequest(fmt.Sprintf("unsupported archive format %s", format))
 	}
+	if opts.password != "" && !opts.zip {
+		return nil, manager.ErrEncryptionNotSupported{}
+	}
+
+	if reproducible := v.Get("reproducible"); reproducible != "" {
+		b, err := strconv.ParseBool(reproducible)
+		if err != nil {
+			return nil, errtypes.BadRequest(fmt.Sprintf("invalid reproducible parameter %s", reproducible))
+		}
+		opts.reproducible = b
+	}
+	return opts, nil
+}
 
-	_, _ = rw.Write([]byte(err.Error()))
+func (o *archiveOptions) extension() string {
+	if o.zip {
+		return ".zip"
+	}
+	return ".tar"
 }
 
 func (s *svc) Handler() http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if s.jobs != nil {
+			if head, tail := router.ShiftPath(r.URL.Path); head == "jobs" {
+				r.URL.Path = tail
+				s.handleJobs(rw, r)
+				return
+			}
+		}
+
 		// get the paths and/or the resources id from the query
 		ctx := r.Context()
 		log := appctx.GetLogger(ctx)
@@ -224,27 +351,28 @@ func (s *svc) Handler() http.Handler {
 
 		files, err := s.getFiles(ctx, paths, ids)
 		if err != nil {
-			s.writeHTTPError(rw, err)
+			s.writeHTTPError(rw, r, err)
+			return
+		}
+
+		opts, err := getArchiveOptions(r)
+		if err != nil {
+			s.writeHTTPError(rw, r, err)
 			return
 		}
 
 		arch, err := manager.NewArchiver(files, s.walker, s.downloader, manager.Config{
-			MaxNumFiles: s.config.MaxNumFiles,
-			MaxSize:     s.config.MaxSize,
+			MaxNumFiles:  s.config.MaxNumFiles,
+			MaxSize:      s.config.MaxSize,
+			Password:     opts.password,
+			Reproducible: opts.reproducible,
 		})
 		if err != nil {
-			s.writeHTTPError(rw, err)
+			s.writeHTTPError(rw, r, err)
 			return
 		}
 
-		userAgent := ua.Parse(r.Header.Get("User-Agent"))
-
-		archName := s.config.Name
-		if userAgent.OS == ua.Windows {
-			archName += ".zip"
-		} else {
-			archName += ".tar"
-		}
+		archName := s.config.Name + opts.extension()
 
 		log.Debug().Msg("Requested the following files/folders to archive: " + render.Render(files))
 
@@ -252,14 +380,14 @@ func (s *svc) Handler() http.Handler {
 		rw.Header().Set("Content-Transfer-Encoding", "binary")
 
 		// create the archive
-		if userAgent.OS == ua.Windows {
+		if opts.zip {
 			err = arch.CreateZip(ctx, rw)
 		} else {
 			err = arch.CreateTar(ctx, rw)
 		}
 
 		if err != nil {
-			s.writeHTTPError(rw, err)
+			s.writeHTTPError(rw, r, err)
 			return
 		}
 
@@ -277,3 +405,8 @@ func (s *svc) Close() error {
 func (s *svc) Unprotected() []string {
 	return nil
 }
+
+// IsCrossOrigin returns true so that archives can be requested by web clients.
+func (s *svc) IsCrossOrigin() bool {
+	return true
+}
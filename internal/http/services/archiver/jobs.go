@@ -0,0 +1,360 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package archiver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/archiver/manager"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/metadata"
+)
+
+// JobStatus represents the state of an archive job.
+type JobStatus string
+
+const (
+	// JobPending means that the job has been queued but not started yet.
+	JobPending JobStatus = "pending"
+	// JobRunning means that the archive is currently being created.
+	JobRunning JobStatus = "running"
+	// JobCompleted means that the archive is ready to be downloaded.
+	JobCompleted JobStatus = "completed"
+	// JobFailed means that the archive couldn't be created.
+	JobFailed JobStatus = "failed"
+)
+
+// errJobsQueueFull is returned when no more jobs can be queued
+type errJobsQueueFull struct{}
+
+// errJobNotReady is returned when trying to download the archive of a job not completed yet
+type errJobNotReady struct {
+	status JobStatus
+}
+
+func (errJobsQueueFull) Error() string {
+	return "too many archive jobs queued, please try again later"
+}
+
+func (e errJobNotReady) Error() string {
+	return fmt.Sprintf("archive job is %s", e.status)
+}
+
+// Job is an asynchronous archive creation job.
+type Job struct {
+	ID         string    `json:"id"`
+	Status     JobStatus `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	Name       string    `json:"name"`
+	FilesCount int64     `json:"files_count"`
+	Size       int64     `json:"size"`
+	Created    time.Time `json:"created"`
+	Expires    time.Time `json:"expires,omitempty"`
+	Download   string    `json:"download,omitempty"`
+
+	owner  *userpb.UserId
+	file   string
+	cancel context.CancelFunc
+}
+
+type jobsManager struct {
+	svc   *svc
+	jobs  map[string]*Job
+	queue chan func()
+	mutex sync.RWMutex
+}
+
+func newJobsManager(s *svc) (*jobsManager, error) {
+	if err := os.MkdirAll(s.config.Jobs.Folder, 0700); err != nil {
+		return nil, err
+	}
+
+	m := &jobsManager{
+		svc:   s,
+		jobs:  make(map[string]*Job),
+		queue: make(chan func(), s.config.Jobs.QueueSize),
+	}
+	for i := 0; i < s.config.Jobs.Workers; i++ {
+		go func() {
+			for work := range m.queue {
+				work()
+			}
+		}()
+	}
+	go m.janitor()
+	return m, nil
+}
+
+// detachedContext creates a context that outlives the request it has been created from,
+// keeping the user and the token needed to access the gateway.
+func detachedContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	u := ctxpkg.ContextMustGetUser(ctx)
+	tkn := ctxpkg.ContextMustGetToken(ctx)
+
+	newCtx := appctx.WithLogger(context.Background(), appctx.GetLogger(ctx))
+	newCtx = ctxpkg.ContextSetUser(newCtx, u)
+	newCtx = ctxpkg.ContextSetToken(newCtx, tkn)
+	newCtx = metadata.AppendToOutgoingContext(newCtx, ctxpkg.TokenHeader, tkn)
+	return context.WithCancel(newCtx)
+}
+
+func (m *jobsManager) create(ctx context.Context, files []string, opts *archiveOptions) (*Job, error) {
+	u := ctxpkg.ContextMustGetUser(ctx)
+
+	job := &Job{
+		ID:      uuid.NewString(),
+		Status:  JobPending,
+		Name:    m.svc.config.Name + opts.extension(),
+		Created: time.Now(),
+		owner:   u.Id,
+	}
+	job.file = filepath.Join(m.svc.config.Jobs.Folder, job.ID)
+
+	arch, err := manager.NewArchiver(files, m.svc.walker, m.svc.downloader, manager.Config{
+		MaxNumFiles:  m.svc.config.Jobs.MaxNumFiles,
+		MaxSize:      m.svc.config.Jobs.MaxSize,
+		Password:     opts.password,
+		Reproducible: opts.reproducible,
+		OnProgress: func(filesCount, sizeFiles int64) {
+			m.mutex.Lock()
+			job.FilesCount, job.Size = filesCount, sizeFiles
+			m.mutex.Unlock()
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	jobCtx, cancel := detachedContext(ctx)
+	job.cancel = cancel
+
+	m.mutex.Lock()
+	m.jobs[job.ID] = job
+	m.mutex.Unlock()
+
+	select {
+	case m.queue <- func() { m.run(jobCtx, job, arch, opts.zip, u) }:
+	default:
+		m.remove(job.ID)
+		return nil, errJobsQueueFull{}
+	}
+
+	return job, nil
+}
+
+func (m *jobsManager) run(ctx context.Context, job *Job, arch *manager.Archiver, zip bool, u *userpb.User) {
+	log := appctx.GetLogger(ctx)
+	defer job.cancel()
+
+	m.setStatus(job, JobRunning, nil)
+
+	f, err := os.OpenFile(job.file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		m.setStatus(job, JobFailed, err)
+		return
+	}
+
+	if zip {
+		err = arch.CreateZip(ctx, f)
+	} else {
+		err = arch.CreateTar(ctx, f)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Error().Err(err).Str("job", job.ID).Msg("archiver: error creating archive")
+		_ = os.Remove(job.file)
+		m.setStatus(job, JobFailed, err)
+		return
+	}
+
+	m.mutex.Lock()
+	job.Download = path.Join("/", m.svc.config.Prefix, "jobs", job.ID, "download")
+	m.mutex.Unlock()
+	m.setStatus(job, JobCompleted, nil)
+
+	m.notify(job, u)
+}
+
+func (m *jobsManager) notify(job *Job, u *userpb.User) {
+	if m.svc.smtpCredentials == nil || u.Mail == "" {
+		return
+	}
+
+	subject := "Your archive is ready for download"
+	body := "Dear " + u.DisplayName + ",\n\n" +
+		"the archive '" + job.Name + "' you requested has been created and can be downloaded until " + job.Expires.Format(time.RFC1123) + " from:\n" +
+		strings.TrimSuffix(m.svc.config.Jobs.PublicURL, "/") + job.Download + "\n"
+	if err := m.svc.smtpCredentials.SendMail(u.Mail, subject, body); err != nil {
+		m.svc.log.Error().Err(err).Str("job", job.ID).Msg("archiver: error sending job notification")
+	}
+}
+
+func (m *jobsManager) setStatus(job *Job, status JobStatus, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	job.Status = status
+	if err != nil {
+		job.Error = err.Error()
+	}
+	// finished jobs, failed or not, are kept until the link expiration
+	// so that the clients polling them get to know the outcome
+	if status == JobCompleted || status == JobFailed {
+		job.Expires = time.Now().Add(time.Duration(m.svc.config.Jobs.LinkExpiration) * time.Second)
+	}
+}
+
+func (m *jobsManager) get(ctx context.Context, id string) (*Job, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	job, ok := m.jobs[id]
+	if !ok || !utils.UserEqual(job.owner, ctxpkg.ContextMustGetUser(ctx).Id) {
+		return nil, errtypes.NotFound(id)
+	}
+	clone := *job
+	return &clone, nil
+}
+
+func (m *jobsManager) remove(id string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if job, ok := m.jobs[id]; ok {
+		job.cancel()
+		_ = os.Remove(job.file)
+		delete(m.jobs, id)
+	}
+}
+
+// janitor periodically removes expired archives.
+func (m *jobsManager) janitor() {
+	for now := range time.Tick(time.Minute) {
+		m.removeExpired(now)
+	}
+}
+
+// removeExpired removes the finished jobs expired at the given time.
+func (m *jobsManager) removeExpired(now time.Time) {
+	var expired []string
+	m.mutex.RLock()
+	for id, job := range m.jobs {
+		if (job.Status == JobCompleted || job.Status == JobFailed) && now.After(job.Expires) {
+			expired = append(expired, id)
+		}
+	}
+	m.mutex.RUnlock()
+
+	for _, id := range expired {
+		m.remove(id)
+	}
+}
+
+// handleJobs serves the /jobs endpoints:
+// POST /jobs creates a new job, GET /jobs/<id> returns its status,
+// GET /jobs/<id>/download serves the archive and DELETE /jobs/<id> cancels it.
+func (s *svc) handleJobs(rw http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var id, action string
+	id, r.URL.Path = router.ShiftPath(r.URL.Path)
+	action, _ = router.ShiftPath(r.URL.Path)
+
+	switch {
+	case id == "" && r.Method == http.MethodPost:
+		v := r.URL.Query()
+		files, err := s.getFiles(ctx, v["path"], v["id"])
+		if err != nil {
+			s.writeHTTPError(rw, r, err)
+			return
+		}
+		opts, err := getArchiveOptions(r)
+		if err != nil {
+			s.writeHTTPError(rw, r, err)
+			return
+		}
+		job, err := s.jobs.create(ctx, files, opts)
+		if err != nil {
+			s.writeHTTPError(rw, r, err)
+			return
+		}
+		s.writeJob(rw, r, job, http.StatusAccepted)
+
+	case id != "" && action == "" && r.Method == http.MethodGet:
+		job, err := s.jobs.get(ctx, id)
+		if err != nil {
+			s.writeHTTPError(rw, r, err)
+			return
+		}
+		s.writeJob(rw, r, job, http.StatusOK)
+
+	case id != "" && action == "" && r.Method == http.MethodDelete:
+		if _, err := s.jobs.get(ctx, id); err != nil {
+			s.writeHTTPError(rw, r, err)
+			return
+		}
+		s.jobs.remove(id)
+		rw.WriteHeader(http.StatusNoContent)
+
+	case id != "" && action == "download" && r.Method == http.MethodGet:
+		job, err := s.jobs.get(ctx, id)
+		if err != nil {
+			s.writeHTTPError(rw, r, err)
+			return
+		}
+		if job.Status != JobCompleted {
+			s.writeHTTPError(rw, r, errJobNotReady{status: job.Status})
+			return
+		}
+		rw.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", job.Name))
+		rw.Header().Set("Content-Transfer-Encoding", "binary")
+		http.ServeFile(rw, r, job.file)
+
+	default:
+		s.problems.Write(rw, r, http.StatusMethodNotAllowed, "")
+	}
+}
+
+func (s *svc) writeJob(rw http.ResponseWriter, r *http.Request, job *Job, status int) {
+	data, err := json.Marshal(job)
+	if err != nil {
+		s.writeHTTPError(rw, r, err)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	_, _ = rw.Write(data)
+}
@@ -0,0 +1,154 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package archiver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	downMock "github.com/cs3org/reva/pkg/storage/utils/downloader/mock"
+	"github.com/cs3org/reva/pkg/storage/utils/walker"
+	walkerMock "github.com/cs3org/reva/pkg/storage/utils/walker/mock"
+	"github.com/rs/zerolog"
+)
+
+type failingWalker struct{}
+
+func (failingWalker) Walk(context.Context, string, walker.WalkFunc) error {
+	return errors.New("walk failed")
+}
+
+func newTestJobsManager(t *testing.T, w walker.Walker) *jobsManager {
+	c := &Config{MaxNumFiles: 10, MaxSize: 1024}
+	c.Jobs.Folder = t.TempDir()
+	c.init()
+	log := zerolog.Nop()
+	s := &svc{
+		config:     c,
+		walker:     w,
+		downloader: downMock.NewDownloader(),
+		log:        &log,
+	}
+	m, err := newJobsManager(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func userContext(id string) context.Context {
+	ctx := ctxpkg.ContextSetUser(context.Background(), &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: id}})
+	return ctxpkg.ContextSetToken(ctx, "token")
+}
+
+// waitJob waits for the job to be finished.
+func waitJob(t *testing.T, ctx context.Context, m *jobsManager, id string) *Job {
+	for i := 0; i < 100; i++ {
+		job, err := m.get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status == JobCompleted || job.Status == JobFailed {
+			return job
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("job %s not finished", id)
+	return nil
+}
+
+func testFiles(t *testing.T) []string {
+	dir := t.TempDir()
+	f := filepath.Join(dir, "file")
+	if err := os.WriteFile(f, []byte("content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return []string{f}
+}
+
+func TestJobCompleted(t *testing.T) {
+	m := newTestJobsManager(t, walkerMock.NewWalker())
+	ctx := userContext("einstein")
+
+	job, err := m.create(ctx, testFiles(t), &archiveOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job = waitJob(t, ctx, m, job.ID)
+
+	if job.Status != JobCompleted {
+		t.Fatalf("expected the job to be completed, got %s: %s", job.Status, job.Error)
+	}
+	if expected := "/download_archive/jobs/" + job.ID + "/download"; job.Download != expected {
+		t.Errorf("expected download path %s, got %s", expected, job.Download)
+	}
+	if _, err := os.Stat(job.file); err != nil {
+		t.Errorf("expected the archive to be stored: %v", err)
+	}
+}
+
+func TestJobFailedExpires(t *testing.T) {
+	m := newTestJobsManager(t, failingWalker{})
+	ctx := userContext("einstein")
+
+	job, err := m.create(ctx, testFiles(t), &archiveOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job = waitJob(t, ctx, m, job.ID)
+
+	if job.Status != JobFailed || job.Error == "" {
+		t.Fatalf("expected the job to be failed with an error, got %s", job.Status)
+	}
+	if job.Download != "" {
+		t.Errorf("expected no download path for a failed job, got %s", job.Download)
+	}
+
+	m.removeExpired(time.Now())
+	if _, err := m.get(ctx, job.ID); err != nil {
+		t.Fatalf("expected the failed job to be kept until it expires: %v", err)
+	}
+	m.removeExpired(job.Expires.Add(time.Second))
+	if _, err := m.get(ctx, job.ID); err == nil {
+		t.Fatal("expected the expired job to be removed")
+	}
+}
+
+func TestJobOwner(t *testing.T) {
+	m := newTestJobsManager(t, walkerMock.NewWalker())
+	ctx := userContext("einstein")
+
+	job, err := m.create(ctx, testFiles(t), &archiveOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitJob(t, ctx, m, job.ID)
+
+	_, err = m.get(userContext("marie"), job.ID)
+	if _, ok := err.(errtypes.IsNotFound); !ok {
+		t.Fatalf("expected a not found error for another user, got %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package manager
+
+import (
+	"archive/zip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"hash"
+	"io"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// The WinZip AES encryption, see https://www.winzip.com/en/support/aes-encryption/
+const (
+	// methodWinZipAES is the compression method of the encrypted entries,
+	// the actual one is stored in the extra field
+	methodWinZipAES = 99
+	// extraWinZipAES is the id of the extra field of the encrypted entries
+	extraWinZipAES = 0x9901
+	// aesVersion is AE-1, where the entries keep the CRC of their content
+	aesVersion = 1
+	// aesStrength is AES-256
+	aesStrength = 3
+	aesKeyLen   = 32
+	aesSaltLen  = 16
+	// pbkdf2Iterations is the number of iterations of the key derivation
+	pbkdf2Iterations = 1000
+	// authCodeLen is the length of the truncated HMAC-SHA1 following the data
+	authCodeLen = 10
+)
+
+// setAESEncryption marks a file header as encrypted with AES-256, the content
+// being stored uncompressed.
+func setAESEncryption(h *zip.FileHeader) {
+	extra := make([]byte, 11)
+	binary.LittleEndian.PutUint16(extra[0:], extraWinZipAES)
+	binary.LittleEndian.PutUint16(extra[2:], 7)
+	binary.LittleEndian.PutUint16(extra[4:], aesVersion)
+	copy(extra[6:], "AE")
+	extra[8] = aesStrength
+	binary.LittleEndian.PutUint16(extra[9:], zip.Store)
+
+	h.Method = methodWinZipAES
+	h.Flags |= 0x1
+	h.Extra = append(h.Extra, extra...)
+}
+
+// newAESCompressor returns the compressor encrypting the entries with keys
+// derived from the password and a random salt per entry.
+func newAESCompressor(password string) zip.Compressor {
+	return func(w io.Writer) (io.WriteCloser, error) {
+		salt := make([]byte, aesSaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		encKey, authKey, verifier := deriveAESKeys(password, salt)
+
+		block, err := aes.NewCipher(encKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return &aesWriter{
+			w:       w,
+			header:  append(salt, verifier...),
+			stream:  newWinZipCTR(block),
+			mac:     hmac.New(sha1.New, authKey),
+			scratch: make([]byte, 32*1024),
+		}, nil
+	}
+}
+
+// deriveAESKeys derives the encryption and authentication keys and the
+// password verifier from the password and the salt of an entry.
+func deriveAESKeys(password string, salt []byte) (encKey, authKey, verifier []byte) {
+	k := pbkdf2.Key([]byte(password), salt, pbkdf2Iterations, 2*aesKeyLen+2, sha1.New)
+	return k[:aesKeyLen], k[aesKeyLen : 2*aesKeyLen], k[2*aesKeyLen:]
+}
+
+// aesWriter encrypts the content of an entry, preceded by the salt and the
+// password verifier, and appends the authentication code of the encrypted data
+// when closed.
+type aesWriter struct {
+	w io.Writer
+	// header is written with the first data, as the compressor is created
+	// before the local header of the entry is written
+	header  []byte
+	stream  cipher.Stream
+	mac     hash.Hash
+	scratch []byte
+}
+
+func (a *aesWriter) writeHeader() error {
+	if a.header == nil {
+		return nil
+	}
+	_, err := a.w.Write(a.header)
+	a.header = nil
+	return err
+}
+
+func (a *aesWriter) Write(p []byte) (int, error) {
+	if err := a.writeHeader(); err != nil {
+		return 0, err
+	}
+	n := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > len(a.scratch) {
+			chunk = chunk[:len(a.scratch)]
+		}
+		buf := a.scratch[:len(chunk)]
+		a.stream.XORKeyStream(buf, chunk)
+		a.mac.Write(buf)
+		if _, err := a.w.Write(buf); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+		p = p[len(chunk):]
+	}
+	return n, nil
+}
+
+func (a *aesWriter) Close() error {
+	if err := a.writeHeader(); err != nil {
+		return err
+	}
+	_, err := a.w.Write(a.mac.Sum(nil)[:authCodeLen])
+	return err
+}
+
+// winZipCTR is the counter mode of the WinZip AES encryption, which differs
+// from the standard one by incrementing the counter as a little endian number
+// starting at 1.
+type winZipCTR struct {
+	block   cipher.Block
+	counter [aes.BlockSize]byte
+	stream  [aes.BlockSize]byte
+	used    int
+}
+
+func newWinZipCTR(block cipher.Block) *winZipCTR {
+	return &winZipCTR{block: block, used: aes.BlockSize}
+}
+
+func (c *winZipCTR) XORKeyStream(dst, src []byte) {
+	for i := range src {
+		if c.used == aes.BlockSize {
+			for j := range c.counter {
+				c.counter[j]++
+				if c.counter[j] != 0 {
+					break
+				}
+			}
+			c.block.Encrypt(c.stream[:], c.counter[:])
+			c.used = 0
+		}
+		dst[i] = src[i] ^ c.stream[c.used]
+		c.used++
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"io"
 	"path"
 	"path/filepath"
+	"sort"
 	"time"
 
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
@@ -32,12 +33,25 @@ import (
 	"github.com/cs3org/reva/pkg/storage/utils/walker"
 )
 
+// ProgressFunc is called by the Archiver every time a resource has been added to the archive
+type ProgressFunc func(filesCount, sizeFiles int64)
+
 // Config is the config for the Archiver
 type Config struct {
 	MaxNumFiles int64
 	MaxSize     int64
+	OnProgress  ProgressFunc
+	// Password encrypts the files of zip archives, tar archives cannot be encrypted
+	Password string
+	// Reproducible sorts the entries by name and fixes their modification time,
+	// so that the same resources always give the same archive
+	Reproducible bool
 }
 
+// reproducibleTime is the modification time of the entries of reproducible
+// archives, the earliest time representable in a zip.
+var reproducibleTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
 // Archiver is the struct able to create an archive
 type Archiver struct {
 	files      []string
@@ -113,11 +127,28 @@ func getDeepestCommonDir(files []string) string {
 	return filepath.Clean(res)
 }
 
-// CreateTar creates a tar and write it into the dst Writer
-func (a *Archiver) CreateTar(ctx context.Context, dst io.Writer) error {
-	w := tar.NewWriter(dst)
+// entry is a resource added to an archive.
+type entry struct {
+	path  string
+	name  string
+	info  *provider.ResourceInfo
+	isDir bool
+}
 
+// modTime returns the modification time of an entry, fixed for reproducible archives.
+func (a *Archiver) modTime(e *entry) time.Time {
+	if a.config.Reproducible {
+		return reproducibleTime
+	}
+	return time.Unix(int64(e.info.Mtime.Seconds), 0)
+}
+
+// walk calls fn for every resource to be archived, enforcing the limits of the
+// configuration. The resources of reproducible archives are all listed first,
+// and then added sorted by name without duplicates.
+func (a *Archiver) walk(ctx context.Context, fn func(e *entry) error) error {
 	var filesCount, sizeFiles int64
+	var entries []*entry
 
 	for _, root := range a.files {
 
@@ -145,37 +176,20 @@ func (a *Archiver) CreateTar(ctx context.Context, dst io.Writer) error {
 
 			// TODO (gdelmont): remove duplicates if the resources requested overlaps
 			fileName, err := filepath.Rel(a.dir, path)
-
 			if err != nil {
 				return err
 			}
 
-			header := tar.Header{
-				Name:    fileName,
-				ModTime: time.Unix(int64(info.Mtime.Seconds), 0),
-			}
-
-			if isDir {
-				// the resource is a folder
-				header.Mode = 0755
-				header.Typeflag = tar.TypeDir
-			} else {
-				header.Mode = 0644
-				header.Typeflag = tar.TypeReg
-				header.Size = int64(info.Size)
+			e := &entry{path: path, name: fileName, info: info, isDir: isDir}
+			if a.config.Reproducible {
+				entries = append(entries, e)
+				return nil
 			}
 
-			err = w.WriteHeader(&header)
-			if err != nil {
+			if err := fn(e); err != nil {
 				return err
 			}
-
-			if !isDir {
-				err = a.downloader.Download(ctx, path, w)
-				if err != nil {
-					return err
-				}
-			}
+			a.reportProgress(filesCount, sizeFiles)
 			return nil
 		})
 
@@ -184,78 +198,116 @@ func (a *Archiver) CreateTar(ctx context.Context, dst io.Writer) error {
 		}
 
 	}
-	return w.Close()
-}
-
-// CreateZip creates a zip and write it into the dst Writer
-func (a *Archiver) CreateZip(ctx context.Context, dst io.Writer) error {
-	w := zip.NewWriter(dst)
 
-	var filesCount, sizeFiles int64
+	if !a.config.Reproducible {
+		return nil
+	}
 
-	for _, root := range a.files {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	filesCount, sizeFiles = 0, 0
+	for i, e := range entries {
+		if i > 0 && e.name == entries[i-1].name {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+		filesCount++
+		if !e.isDir {
+			sizeFiles += int64(e.info.Size)
+		}
+		a.reportProgress(filesCount, sizeFiles)
+	}
+	return nil
+}
 
-		err := a.walker.Walk(ctx, root, func(path string, info *provider.ResourceInfo, err error) error {
-			if err != nil {
-				return err
-			}
+// CreateTar creates a tar and write it into the dst Writer
+func (a *Archiver) CreateTar(ctx context.Context, dst io.Writer) error {
+	if a.config.Password != "" {
+		return ErrEncryptionNotSupported{}
+	}
 
-			isDir := info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER
+	w := tar.NewWriter(dst)
 
-			filesCount++
-			if filesCount > a.config.MaxNumFiles {
-				return ErrMaxFileCount{}
-			}
+	err := a.walk(ctx, func(e *entry) error {
+		header := tar.Header{
+			Name:    e.name,
+			ModTime: a.modTime(e),
+		}
 
-			if !isDir {
-				// only add the size if the resource is not a directory
-				// as its size could be resursive-computed, and we would
-				// count the files not only once
-				sizeFiles += int64(info.Size)
-				if sizeFiles > a.config.MaxSize {
-					return ErrMaxSize{}
-				}
-			}
+		if e.isDir {
+			// the resource is a folder
+			header.Mode = 0755
+			header.Typeflag = tar.TypeDir
+		} else {
+			header.Mode = 0644
+			header.Typeflag = tar.TypeReg
+			header.Size = int64(e.info.Size)
+		}
 
-			// TODO (gdelmont): remove duplicates if the resources requested overlaps
-			fileName, err := filepath.Rel(a.dir, path)
-			if err != nil {
-				return err
-			}
+		if err := w.WriteHeader(&header); err != nil {
+			return err
+		}
 
-			if fileName == "" {
-				return nil
-			}
+		if !e.isDir {
+			return a.downloader.Download(ctx, e.path, w)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
 
-			header := zip.FileHeader{
-				Name:     fileName,
-				Modified: time.Unix(int64(info.Mtime.Seconds), 0),
-			}
+// CreateZip creates a zip and write it into the dst Writer. The ZIP64
+// extensions are used for the files and archives larger than 4 GiB and for
+// more than 65535 entries. With a password, the files are encrypted with
+// AES-256 following the WinZip AE-1 specification.
+func (a *Archiver) CreateZip(ctx context.Context, dst io.Writer) error {
+	w := zip.NewWriter(dst)
+	if a.config.Password != "" {
+		w.RegisterCompressor(methodWinZipAES, newAESCompressor(a.config.Password))
+	}
 
-			if isDir {
-				header.Name += "/"
-			} else {
-				header.UncompressedSize64 = info.Size
-			}
+	err := a.walk(ctx, func(e *entry) error {
+		if e.name == "" {
+			return nil
+		}
 
-			dst, err := w.CreateHeader(&header)
-			if err != nil {
-				return err
-			}
+		header := zip.FileHeader{
+			Name:     e.name,
+			Modified: a.modTime(e),
+		}
 
-			if !isDir {
-				err = a.downloader.Download(ctx, path, dst)
-				if err != nil {
-					return err
-				}
+		if e.isDir {
+			header.Name += "/"
+		} else {
+			// a known size over 4 GiB already marks the entry as ZIP64 in its local header
+			header.UncompressedSize64 = e.info.Size
+			if a.config.Password != "" {
+				setAESEncryption(&header)
 			}
-			return nil
-		})
+		}
 
+		dst, err := w.CreateHeader(&header)
 		if err != nil {
 			return err
 		}
 
+		if !e.isDir {
+			return a.downloader.Download(ctx, e.path, dst)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 	return w.Close()
 }
+
+func (a *Archiver) reportProgress(filesCount, sizeFiles int64) {
+	if a.config.OnProgress != nil {
+		a.config.OnProgress(filesCount, sizeFiles)
+	}
+}
@@ -23,14 +23,23 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
 	"strings"
 	"testing"
 
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	downMock "github.com/cs3org/reva/pkg/storage/utils/downloader/mock"
+	"github.com/cs3org/reva/pkg/storage/utils/walker"
 	walkerMock "github.com/cs3org/reva/pkg/storage/utils/walker/mock"
 	"github.com/cs3org/reva/pkg/test"
 )
@@ -977,3 +986,221 @@ func TestCreateZip(t *testing.T) {
 	}
 
 }
+
+// decryptZipFile returns the content of a file of a zip encrypted with AES.
+func decryptZipFile(f *zip.File, password string) ([]byte, error) {
+	if f.Method != methodWinZipAES || f.Flags&0x1 == 0 {
+		return nil, errors.New("file not encrypted")
+	}
+	extra := f.Extra
+	for len(extra) >= 4 && binary.LittleEndian.Uint16(extra) != extraWinZipAES {
+		extra = extra[4+binary.LittleEndian.Uint16(extra[2:]):]
+	}
+	if len(extra) < 11 || extra[8] != aesStrength {
+		return nil, errors.New("missing aes extra field")
+	}
+
+	rc, err := f.OpenRaw()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	salt, verifier := raw[:aesSaltLen], raw[aesSaltLen:aesSaltLen+2]
+	data, authCode := raw[aesSaltLen+2:len(raw)-authCodeLen], raw[len(raw)-authCodeLen:]
+
+	encKey, authKey, expected := deriveAESKeys(password, salt)
+	if !bytes.Equal(verifier, expected) {
+		return nil, errors.New("wrong password")
+	}
+	mac := hmac.New(sha1.New, authKey)
+	mac.Write(data)
+	if !hmac.Equal(mac.Sum(nil)[:authCodeLen], authCode) {
+		return nil, errors.New("wrong authentication code")
+	}
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+	content := make([]byte, len(data))
+	newWinZipCTR(block).XORKeyStream(content, data)
+	return content, nil
+}
+
+func TestCreateZipEncrypted(t *testing.T) {
+	tmpdir, cleanup, err := test.NewTestDir(test.Dir{
+		"foo": test.Dir{
+			"bar": test.File{Content: strings.Repeat("secret ", 10000)},
+			"baz": test.File{Content: ""},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	arch, err := NewArchiver([]string{path.Join(tmpdir, "foo")}, walkerMock.NewWalker(), downMock.NewDownloader(), Config{
+		MaxSize:     1024 * 1024,
+		MaxNumFiles: 10,
+		Password:    "s3cr3t",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var zipFile bytes.Buffer
+	if err := arch.CreateZip(context.TODO(), &zipFile); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(zipFile.Bytes(), []byte("secret secret")) {
+		t.Fatal("the content of the archive is not encrypted")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(zipFile.Bytes()), int64(zipFile.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		content, err := decryptZipFile(f, "s3cr3t")
+		if err != nil {
+			t.Fatalf("error decrypting %s: %v", f.Name, err)
+		}
+		contents[f.Name] = string(content)
+
+		if _, err := decryptZipFile(f, "wrong"); err == nil {
+			t.Errorf("expected %s not to be decrypted with a wrong password", f.Name)
+		}
+	}
+	if contents["foo/bar"] != strings.Repeat("secret ", 10000) || contents["foo/baz"] != "" || len(contents) != 2 {
+		t.Errorf("unexpected decrypted contents for %d files", len(contents))
+	}
+}
+
+func TestCreateTarEncrypted(t *testing.T) {
+	arch, err := NewArchiver([]string{"/foo"}, walkerMock.NewWalker(), downMock.NewDownloader(), Config{Password: "s3cr3t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := arch.CreateTar(context.TODO(), ioutil.Discard); err != (ErrEncryptionNotSupported{}) {
+		t.Fatalf("expected an error, got %v", err)
+	}
+}
+
+func TestReproducible(t *testing.T) {
+	tmpdir, cleanup, err := test.NewTestDir(test.Dir{
+		"foo": test.Dir{
+			"zeta":  test.File{Content: "z"},
+			"alpha": test.Dir{"b": test.File{Content: "b"}, "a": test.File{Content: "a"}},
+			"beta":  test.File{Content: "beta"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	create := func(files []string, zipped bool) []byte {
+		arch, err := NewArchiver(files, walkerMock.NewWalker(), downMock.NewDownloader(), Config{
+			MaxSize:      1000,
+			MaxNumFiles:  100,
+			Reproducible: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if zipped {
+			err = arch.CreateZip(context.TODO(), &b)
+		} else {
+			err = arch.CreateTar(context.TODO(), &b)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b.Bytes()
+	}
+
+	foo := path.Join(tmpdir, "foo")
+	// the overlapping resources are added once
+	first := create([]string{foo}, true)
+	second := create([]string{path.Join(foo, "beta"), foo}, true)
+	if !bytes.Equal(first, second) {
+		t.Fatal("expected identical zip archives")
+	}
+	if !bytes.Equal(create([]string{foo}, false), create([]string{foo}, false)) {
+		t.Fatal("expected identical tar archives")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(first), int64(len(first)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if !f.Modified.Equal(reproducibleTime) {
+			t.Errorf("unexpected modification time %v of %s", f.Modified, f.Name)
+		}
+	}
+	if expected := "foo/ foo/alpha/ foo/alpha/a foo/alpha/b foo/beta foo/zeta"; strings.Join(names, " ") != expected {
+		t.Errorf("expected entries %s, got %s", expected, strings.Join(names, " "))
+	}
+}
+
+// manyFilesWalker walks a folder with the given number of empty files.
+type manyFilesWalker struct {
+	files int
+}
+
+func (m manyFilesWalker) Walk(_ context.Context, root string, fn walker.WalkFunc) error {
+	mtime := &typesv1beta1.Timestamp{Seconds: 1600000000}
+	if err := fn(root, &provider.ResourceInfo{Type: provider.ResourceType_RESOURCE_TYPE_CONTAINER, Path: root, Mtime: mtime}, nil); err != nil {
+		return err
+	}
+	for i := 0; i < m.files; i++ {
+		p := path.Join(root, fmt.Sprintf("file-%d", i))
+		if err := fn(p, &provider.ResourceInfo{Type: provider.ResourceType_RESOURCE_TYPE_FILE, Path: p, Mtime: mtime}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type emptyDownloader struct{}
+
+func (emptyDownloader) Download(context.Context, string, io.Writer) error {
+	return nil
+}
+
+func TestCreateZip64(t *testing.T) {
+	const files = 70000
+	arch, err := NewArchiver([]string{"/foo"}, manyFilesWalker{files: files}, emptyDownloader{}, Config{
+		MaxSize:     1,
+		MaxNumFiles: files + 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var zipFile bytes.Buffer
+	if err := arch.CreateZip(context.TODO(), &zipFile); err != nil {
+		t.Fatal(err)
+	}
+	// the zip64 end of central directory record holds the number of entries
+	if !bytes.Contains(zipFile.Bytes(), []byte("PK\x06\x06")) {
+		t.Error("expected a zip64 end of central directory record")
+	}
+	zr, err := zip.NewReader(bytes.NewReader(zipFile.Bytes()), int64(zipFile.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != files+1 {
+		t.Errorf("expected %d entries, got %d", files+1, len(zr.File))
+	}
+}
@@ -41,3 +41,11 @@ func (ErrMaxSize) Error() string {
 func (ErrEmptyList) Error() string {
 	return "list of files to archive empty"
 }
+
+// ErrEncryptionNotSupported is the error returned when a password is given for an archive format without encryption
+type ErrEncryptionNotSupported struct{}
+
+// Error returns the string error msg for ErrEncryptionNotSupported
+func (ErrEncryptionNotSupported) Error() string {
+	return "only zip archives can be encrypted"
+}
@@ -0,0 +1,93 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package archiver
+
+import (
+	"net/http"
+
+	"github.com/cs3org/reva/pkg/apispec"
+	"github.com/cs3org/reva/pkg/rhttp/problem"
+)
+
+var archiveParameters = []apispec.Parameter{
+	{Name: "path", In: "query", Description: "A path to include in the archive; can be repeated."},
+	{Name: "id", In: "query", Description: "The id of a resource to include in the archive; can be repeated."},
+	{Name: "format", In: "query", Description: "The format of the archive, either zip or tar."},
+	{Name: "password", In: "query", Description: "The password encrypting the files of a zip archive."},
+	{Name: "reproducible", In: "query", Description: "Whether the entries are sorted and their timestamps fixed.", Type: "boolean"},
+	{Name: passwordHeader, In: "header", Description: "The password encrypting the files of a zip archive, instead of the query parameter."},
+}
+
+var archiveErrors = []apispec.Response{
+	{Status: http.StatusBadRequest, ContentType: problem.ContentType},
+	{Status: http.StatusNotFound, ContentType: problem.ContentType},
+	{Status: http.StatusRequestEntityTooLarge, ContentType: problem.ContentType},
+}
+
+func withErrors(responses ...apispec.Response) []apispec.Response {
+	return append(responses, archiveErrors...)
+}
+
+// spec describes the endpoints of the service; the jobs ones are only
+// available when the asynchronous archives are enabled.
+var spec = &apispec.Service{
+	Name:        "archiver",
+	Prefix:      "download_archive",
+	Description: "Downloads files and folders as zip or tar archives.",
+	Operations: []apispec.Operation{
+		{
+			Method:     http.MethodGet,
+			Path:       "/",
+			Summary:    "Streams an archive of the given resources",
+			Parameters: archiveParameters,
+			Responses:  withErrors(apispec.Response{Status: http.StatusOK, ContentType: "application/octet-stream"}),
+		},
+		{
+			Method:     http.MethodPost,
+			Path:       "/jobs",
+			Summary:    "Creates the archive of the given resources in the background",
+			Parameters: archiveParameters,
+			Responses: withErrors(
+				apispec.Response{Status: http.StatusAccepted, ContentType: "application/json"},
+				apispec.Response{Status: http.StatusServiceUnavailable, ContentType: problem.ContentType},
+			),
+		},
+		{
+			Method:    http.MethodGet,
+			Path:      "/jobs/{id}",
+			Summary:   "Returns the status of an archive job",
+			Responses: withErrors(apispec.Response{Status: http.StatusOK, ContentType: "application/json"}),
+		},
+		{
+			Method:    http.MethodDelete,
+			Path:      "/jobs/{id}",
+			Summary:   "Removes an archive job and its archive",
+			Responses: withErrors(apispec.Response{Status: http.StatusNoContent}),
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/jobs/{id}/download",
+			Summary: "Downloads the archive created by a job",
+			Responses: withErrors(
+				apispec.Response{Status: http.StatusOK, ContentType: "application/octet-stream"},
+				apispec.Response{Status: http.StatusConflict, ContentType: problem.ContentType},
+			),
+		},
+	},
+}
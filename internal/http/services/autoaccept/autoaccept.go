@@ -0,0 +1,392 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package autoaccept
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/asim/go-micro/plugins/events/nats/v4"
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	invitepb "github.com/cs3org/go-cs3apis/cs3/ocm/invite/v1beta1"
+	preferences "github.com/cs3org/go-cs3apis/cs3/preferences/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/events/server"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/share/autoaccept"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func init() {
+	global.Register(serviceName, New)
+}
+
+const serviceName = "autoaccept"
+
+// Config holds the config options for the auto-accept service.
+type Config struct {
+	Prefix        string `mapstructure:"prefix"`
+	GatewaySvc    string `mapstructure:"gatewaysvc"`
+	NatsAddress   string `mapstructure:"nats_address"`
+	NatsClusterID string `mapstructure:"nats_clusterid"`
+	// MachineAuthAPIKey is the key of the machine auth provider, used to act on behalf of the grantees.
+	MachineAuthAPIKey string `mapstructure:"machine_auth_apikey"`
+	DefaultPolicy     string `mapstructure:"default_policy" docs:"none;The policy of the users who did not choose one."`
+}
+
+func (c *Config) init() {
+	if c.Prefix == "" {
+		c.Prefix = serviceName
+	}
+	if c.DefaultPolicy == "" {
+		c.DefaultPolicy = string(autoaccept.PolicyNone)
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+type svc struct {
+	conf          *Config
+	log           *zerolog.Logger
+	router        *chi.Mux
+	defaultPolicy autoaccept.Policy
+}
+
+// New returns a new service accepting the shares received by the users on
+// their behalf, according to the policy stored in their preferences. The
+// service also exposes the policy of the current user.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &Config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, errors.Wrap(err, "autoaccept: error decoding configuration")
+	}
+	conf.init()
+
+	if conf.NatsAddress == "" {
+		return nil, errors.New("autoaccept: no nats address configured")
+	}
+	if conf.MachineAuthAPIKey == "" {
+		return nil, errors.New("autoaccept: no machine auth api key configured")
+	}
+	defaultPolicy, err := autoaccept.ParsePolicy(conf.DefaultPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := server.NewNatsStream(nats.Address(conf.NatsAddress), nats.ClusterID(conf.NatsClusterID))
+	if err != nil {
+		return nil, errors.Wrap(err, "autoaccept: error connecting to the event stream")
+	}
+
+	// a share must be accepted only once, so all instances share the same consumer group
+	evs, err := events.Consume(stream, serviceName, events.ShareCreated{})
+	if err != nil {
+		return nil, errors.Wrap(err, "autoaccept: error consuming events")
+	}
+
+	s := &svc{
+		conf:          conf,
+		log:           log,
+		router:        chi.NewRouter(),
+		defaultPolicy: defaultPolicy,
+	}
+	s.router.Get("/policy", s.handleGetPolicy)
+	s.router.Put("/policy", s.handleSetPolicy)
+
+	go func() {
+		for ev := range evs {
+			if e, ok := ev.(events.ShareCreated); ok {
+				s.handleShareCreated(e)
+			}
+		}
+	}()
+
+	return s, nil
+}
+
+// Close is called when this service is being stopped.
+func (s *svc) Close() error {
+	return nil
+}
+
+// Prefix returns the main endpoint of this service.
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+// Unprotected returns all endpoints that can be queried without prior authorization.
+func (s *svc) Unprotected() []string {
+	return []string{}
+}
+
+// Handler serves all HTTP requests.
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.router.ServeHTTP(w, r)
+	})
+}
+
+func (s *svc) handleGetPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	p, err := s.policy(ctx, client)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	js, err := json.Marshal(map[string]string{"policy": string(p)})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(js); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("autoaccept: error writing response")
+	}
+}
+
+func (s *svc) handleSetPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	p, err := autoaccept.ParsePolicy(r.FormValue("policy"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	res, err := client.SetKey(ctx, &preferences.SetKeyRequest{
+		Key: &preferences.PreferenceKey{Namespace: autoaccept.PreferenceNamespace, Key: autoaccept.PreferenceKey},
+		Val: string(p),
+	})
+	switch {
+	case err != nil:
+		writeError(w, r, err)
+		return
+	case res.Status.Code != rpc.Code_CODE_OK:
+		writeError(w, r, errtypes.InternalError(res.Status.Message))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// policy returns the policy of the user of the context.
+func (s *svc) policy(ctx context.Context, client gateway.GatewayAPIClient) (autoaccept.Policy, error) {
+	res, err := client.GetKey(ctx, &preferences.GetKeyRequest{
+		Key: &preferences.PreferenceKey{Namespace: autoaccept.PreferenceNamespace, Key: autoaccept.PreferenceKey},
+	})
+	switch {
+	case err != nil:
+		return "", err
+	case res.Status.Code == rpc.Code_CODE_NOT_FOUND:
+		return s.defaultPolicy, nil
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return "", errtypes.InternalError(res.Status.Message)
+	}
+	return autoaccept.ParsePolicy(res.Val)
+}
+
+func (s *svc) handleShareCreated(ev events.ShareCreated) {
+	log := s.log.With().Str("sharer", ev.Sharer.GetOpaqueId()).Interface("item", ev.ItemID).Logger()
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		log.Error().Err(err).Msg("autoaccept: error getting gateway client")
+		return
+	}
+
+	grantees := []*userpb.UserId{}
+	switch {
+	case ev.GranteeUserID != nil:
+		grantees = append(grantees, ev.GranteeUserID)
+	case ev.GranteeGroupID != nil:
+		// the members are looked up as the sharer, who was allowed to share with the group
+		ctx, _, err := s.impersonate(client, ev.Sharer)
+		if err != nil {
+			log.Error().Err(err).Msg("autoaccept: error impersonating sharer")
+			return
+		}
+		res, err := client.GetMembers(ctx, &grouppb.GetMembersRequest{GroupId: ev.GranteeGroupID})
+		if err == nil && res.Status.Code != rpc.Code_CODE_OK {
+			err = errtypes.InternalError(res.Status.Message)
+		}
+		if err != nil {
+			log.Error().Err(err).Str("group", ev.GranteeGroupID.OpaqueId).Msg("autoaccept: error getting group members")
+			return
+		}
+		for _, m := range res.Members {
+			if !utils.UserEqual(m, ev.Sharer) {
+				grantees = append(grantees, m)
+			}
+		}
+	}
+
+	for _, g := range grantees {
+		accepted, err := s.autoAccept(client, ev, g)
+		if err != nil {
+			log.Error().Err(err).Str("grantee", g.OpaqueId).Msg("autoaccept: error processing received share")
+			continue
+		}
+		if accepted {
+			log.Info().Str("grantee", g.OpaqueId).Msg("autoaccept: share accepted")
+		}
+	}
+}
+
+// autoAccept accepts the share for the grantee if their policy trusts the sharer.
+func (s *svc) autoAccept(client gateway.GatewayAPIClient, ev events.ShareCreated, grantee *userpb.UserId) (bool, error) {
+	ctx, u, err := s.impersonate(client, grantee)
+	if err != nil {
+		return false, err
+	}
+
+	p, err := s.policy(ctx, client)
+	if err != nil {
+		return false, errors.Wrap(err, "error getting policy")
+	}
+	trusted, err := p.Trusts(ev.Sharer, u.Id, func() (bool, error) {
+		return s.isContact(ctx, client, ev.Sharer)
+	})
+	if err != nil || !trusted {
+		return false, err
+	}
+
+	shareID := ev.ShareID
+	if shareID == nil {
+		// events of older gateways do not carry the id of the share
+		if shareID, err = s.findPendingShare(ctx, client, ev); err != nil || shareID == nil {
+			return false, err
+		}
+	}
+
+	// accepting through the gateway also mounts the share
+	res, err := client.UpdateReceivedShare(ctx, &collaboration.UpdateReceivedShareRequest{
+		Share: &collaboration.ReceivedShare{
+			Share: &collaboration.Share{Id: shareID},
+			State: collaboration.ShareState_SHARE_STATE_ACCEPTED,
+		},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"state"}},
+	})
+	switch {
+	case err != nil:
+		return false, err
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return false, errtypes.InternalError(res.Status.Message)
+	}
+	return true, nil
+}
+
+// impersonate returns a context authenticated as the given user.
+func (s *svc) impersonate(client gateway.GatewayAPIClient, id *userpb.UserId) (context.Context, *userpb.User, error) {
+	res, err := client.Authenticate(context.Background(), &gateway.AuthenticateRequest{
+		Type:         "machine",
+		ClientId:     "userid:" + id.OpaqueId,
+		ClientSecret: s.conf.MachineAuthAPIKey,
+	})
+	switch {
+	case err != nil:
+		return nil, nil, err
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, nil, fmt.Errorf("error authenticating as %s: %s", id.OpaqueId, res.Status.Message)
+	}
+
+	ctx := ctxpkg.ContextSetToken(context.Background(), res.Token)
+	ctx = ctxpkg.ContextSetUser(ctx, res.User)
+	ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.TokenHeader, res.Token)
+	return ctx, res.User, nil
+}
+
+// isContact checks whether the user of the context knows the sharer, either
+// because they accepted an OCM invitation or because they shared with them before.
+func (s *svc) isContact(ctx context.Context, client gateway.GatewayAPIClient, sharer *userpb.UserId) (bool, error) {
+	usersRes, err := client.FindAcceptedUsers(ctx, &invitepb.FindAcceptedUsersRequest{})
+	if err != nil {
+		return false, err
+	}
+	// a failure only means that OCM is not available on this deployment
+	if usersRes.Status.Code == rpc.Code_CODE_OK {
+		for _, u := range usersRes.AcceptedUsers {
+			if utils.UserEqual(u.Id, sharer) {
+				return true, nil
+			}
+		}
+	}
+
+	sharesRes, err := client.ListShares(ctx, &collaboration.ListSharesRequest{})
+	switch {
+	case err != nil:
+		return false, err
+	case sharesRes.Status.Code != rpc.Code_CODE_OK:
+		return false, errtypes.InternalError(sharesRes.Status.Message)
+	}
+	for _, share := range sharesRes.Shares {
+		if utils.UserEqual(share.Grantee.GetUserId(), sharer) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// findPendingShare returns the id of the pending received share matching the event, if any.
+func (s *svc) findPendingShare(ctx context.Context, client gateway.GatewayAPIClient, ev events.ShareCreated) (*collaboration.ShareId, error) {
+	res, err := client.ListReceivedShares(ctx, &collaboration.ListReceivedSharesRequest{})
+	switch {
+	case err != nil:
+		return nil, err
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, errtypes.InternalError(res.Status.Message)
+	}
+	for _, rs := range res.Shares {
+		if rs.State == collaboration.ShareState_SHARE_STATE_PENDING &&
+			utils.ResourceIDEqual(rs.Share.ResourceId, ev.ItemID) &&
+			utils.UserEqual(rs.Share.Creator, ev.Sharer) {
+			return rs.Share.Id, nil
+		}
+	}
+	return nil, nil
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	appctx.GetLogger(r.Context()).Error().Err(err).Msg("autoaccept: error handling request")
+	w.WriteHeader(http.StatusInternalServerError)
+}
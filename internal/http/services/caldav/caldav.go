@@ -0,0 +1,160 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package caldav proxies CalDAV and CardDAV requests to a groupware server,
+// so clients can use the same endpoint and credentials for files, calendars
+// and contacts.
+package caldav
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register("caldav", New)
+}
+
+type config struct {
+	Prefix         string            `mapstructure:"prefix" docs:"caldav;The prefix under which the service is served."`
+	Backend        string            `mapstructure:"backend" docs:";The URL of the CalDAV/CardDAV server, e.g. http://localhost:5232/."`
+	Secrets        map[string]string `mapstructure:"secrets" docs:";Maps usernames to the passwords used to authenticate them at the backend."`
+	SecretsFile    string            `mapstructure:"secrets_file" docs:";A JSON file mapping usernames to passwords, merged with secrets."`
+	UsernameHeader string            `mapstructure:"username_header" docs:";If set, the username is sent in this header instead of basic auth credentials, for backends trusting the proxy."`
+	Insecure       bool              `mapstructure:"insecure" docs:"false;Whether to skip certificate checks when sending requests."`
+}
+
+func (c *config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "caldav"
+	}
+}
+
+type svc struct {
+	c       *config
+	backend *url.URL
+	secrets map[string]string
+	proxy   *httputil.ReverseProxy
+}
+
+// New returns a new caldav proxy service.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, err
+	}
+	c.init()
+
+	backend, err := url.Parse(c.Backend)
+	if err != nil || backend.Scheme == "" || backend.Host == "" {
+		return nil, errors.Errorf("caldav: invalid backend url %q", c.Backend)
+	}
+
+	secrets, err := loadSecrets(c)
+	if err != nil {
+		return nil, err
+	}
+	if len(secrets) == 0 && c.UsernameHeader == "" {
+		return nil, errors.New("caldav: either secrets, secrets_file or username_header must be configured")
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(backend)
+	proxy.Transport = rhttp.GetHTTPClient(rhttp.Insecure(c.Insecure)).Transport
+
+	return &svc{c: c, backend: backend, secrets: secrets, proxy: proxy}, nil
+}
+
+func loadSecrets(c *config) (map[string]string, error) {
+	secrets := map[string]string{}
+	if c.SecretsFile != "" {
+		f, err := ioutil.ReadFile(c.SecretsFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "caldav: error reading secrets file")
+		}
+		if err := json.Unmarshal(f, &secrets); err != nil {
+			return nil, errors.Wrap(err, "caldav: error decoding secrets file")
+		}
+	}
+	for u, s := range c.Secrets {
+		secrets[u] = s
+	}
+	return secrets, nil
+}
+
+func (s *svc) Close() error {
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.c.Prefix
+}
+
+func (s *svc) Unprotected() []string {
+	return []string{}
+}
+
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log := appctx.GetLogger(r.Context())
+
+		u, ok := ctxpkg.ContextGetUser(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		r = r.Clone(r.Context())
+		// never leak reva credentials to the backend
+		r.Header.Del("Authorization")
+		r.Header.Del("Cookie")
+		r.Header.Del(ctxpkg.TokenHeader)
+
+		if s.c.UsernameHeader != "" {
+			r.Header.Set(s.c.UsernameHeader, u.Username)
+		} else {
+			secret, ok := s.secrets[u.Username]
+			if !ok {
+				log.Debug().Str("username", u.Username).Msg("caldav: no backend credentials for user")
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			r.SetBasicAuth(u.Username, secret)
+		}
+
+		// let the backend generate hrefs pointing to this service
+		prefix := path.Join("/", s.c.Prefix)
+		r.Header.Set("X-Script-Name", prefix)
+		r.Header.Set("X-Forwarded-Prefix", prefix)
+		r.Header.Set("X-Forwarded-Host", r.Host)
+		r.Host = s.backend.Host
+
+		s.proxy.ServeHTTP(w, r)
+	})
+}
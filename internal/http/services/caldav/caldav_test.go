@@ -0,0 +1,83 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package caldav
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+)
+
+func TestHandler(t *testing.T) {
+	var got *http.Request
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.WriteHeader(http.StatusMultiStatus)
+	}))
+	defer backend.Close()
+
+	s, err := New(map[string]interface{}{
+		"backend": backend.URL + "/radicale",
+		"secrets": map[string]string{"einstein": "relativity"},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		username string
+		status   int
+	}{
+		{"einstein", http.StatusMultiStatus},
+		{"marie", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		got = nil
+		r := httptest.NewRequest("PROPFIND", "/einstein/calendar/", nil)
+		r.Header.Set(ctxpkg.TokenHeader, "reva-token")
+		r = r.WithContext(ctxpkg.ContextSetUser(r.Context(), &userpb.User{Username: tt.username}))
+		w := httptest.NewRecorder()
+		s.Handler().ServeHTTP(w, r)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.username, tt.status, w.Code)
+		}
+		if tt.status == http.StatusForbidden {
+			if got != nil {
+				t.Errorf("%s: request must not reach the backend", tt.username)
+			}
+			continue
+		}
+		if got.URL.Path != "/radicale/einstein/calendar/" {
+			t.Errorf("unexpected backend path %q", got.URL.Path)
+		}
+		if u, p, ok := got.BasicAuth(); !ok || u != "einstein" || p != "relativity" {
+			t.Errorf("unexpected backend credentials %q:%q", u, p)
+		}
+		if got.Header.Get(ctxpkg.TokenHeader) != "" {
+			t.Error("the reva token must not be forwarded")
+		}
+		if got.Header.Get("X-Script-Name") != "/caldav" {
+			t.Errorf("unexpected X-Script-Name %q", got.Header.Get("X-Script-Name"))
+		}
+	}
+}
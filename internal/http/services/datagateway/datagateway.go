@@ -20,16 +20,25 @@ package datagateway
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	ocmcache "github.com/cs3org/reva/pkg/ocm/cache"
 	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/downloadtoken"
+	downloadtokenregistry "github.com/cs3org/reva/pkg/rhttp/datatx/utils/downloadtoken/registry"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/limiter"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/uploadtoken"
 	"github.com/cs3org/reva/pkg/rhttp/global"
 	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/golang-jwt/jwt"
@@ -43,6 +52,9 @@ const (
 	TokenTransportHeader = "X-Reva-Transfer"
 	// UploadExpiresHeader holds the timestamp for the transport token expiry, defined in https://tus.io/protocols/resumable-upload.html#expiration
 	UploadExpiresHeader = "Upload-Expires"
+	// DownloadPath is the path of the downloads of the browsers with one-time download tokens, which are
+	// appended to it.
+	DownloadPath = "/download/"
 )
 
 func init() {
@@ -53,12 +65,24 @@ func init() {
 type transferClaims struct {
 	jwt.StandardClaims
 	Target string `json:"target"`
+	// OCM is set for the downloads from the remote providers of OCM shares to be cached.
+	OCM *ocmcache.Transfer `json:"ocm,omitempty"`
+	// Scope binds the token to a resumable upload.
+	uploadtoken.Scope
 }
 type config struct {
-	Prefix               string `mapstructure:"prefix"`
-	TransferSharedSecret string `mapstructure:"transfer_shared_secret"`
-	Timeout              int64  `mapstructure:"timeout"`
-	Insecure             bool   `mapstructure:"insecure" docs:"false;Whether to skip certificate checks when sending requests."`
+	Prefix               string         `mapstructure:"prefix"`
+	TransferSharedSecret string         `mapstructure:"transfer_shared_secret"`
+	Timeout              int64          `mapstructure:"timeout"`
+	Insecure             bool           `mapstructure:"insecure" docs:"false;Whether to skip certificate checks when sending requests."`
+	Limits               limiter.Config `mapstructure:"transfer_limits"`
+	// OCMCache caches the content of the files received through OCM shares. It has to be
+	// configured with the same directory as the one of the gateway.
+	OCMCache ocmcache.Config `mapstructure:"ocm_cache"`
+	// DownloadTokenStore enables the downloads of the browsers with the one-time tokens minted
+	// by the OCS service, which has to be configured with the same store.
+	DownloadTokenStore  string                            `mapstructure:"download_token_store"`
+	DownloadTokenStores map[string]map[string]interface{} `mapstructure:"download_token_stores"`
 }
 
 func (c *config) init() {
@@ -70,9 +94,12 @@ func (c *config) init() {
 }
 
 type svc struct {
-	conf    *config
-	handler http.Handler
-	client  *http.Client
+	conf      *config
+	handler   http.Handler
+	client    *http.Client
+	ocmCache  *ocmcache.Cache
+	downloads downloadtoken.Store
+	limits    *limiter.Limits
 }
 
 // New returns a new datagateway
@@ -85,12 +112,31 @@ func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error)
 	conf.init()
 
 	s := &svc{
-		conf: conf,
+		conf:   conf,
+		limits: limiter.NewLimits(&conf.Limits),
 		client: rhttp.GetHTTPClient(
 			rhttp.Timeout(time.Duration(conf.Timeout*int64(time.Second))),
 			rhttp.Insecure(conf.Insecure),
 		),
 	}
+	if conf.OCMCache.Enabled && conf.OCMCache.Dir != "" {
+		c, err := ocmcache.New(&conf.OCMCache)
+		if err != nil {
+			return nil, err
+		}
+		s.ocmCache = c
+	}
+	if conf.DownloadTokenStore != "" {
+		f, ok := downloadtokenregistry.NewFuncs[conf.DownloadTokenStore]
+		if !ok {
+			return nil, fmt.Errorf("download token store not found: %s", conf.DownloadTokenStore)
+		}
+		store, err := f(conf.DownloadTokenStores[conf.DownloadTokenStore])
+		if err != nil {
+			return nil, err
+		}
+		s.downloads = store
+	}
 	s.setHandler()
 	return s, nil
 }
@@ -114,18 +160,22 @@ func (s *svc) Unprotected() []string {
 	}
 }
 
+// IsCrossOrigin returns true so that browsers can up- and download files directly.
+func (s *svc) IsCrossOrigin() bool {
+	return true
+}
+
 func (s *svc) setHandler() {
 	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case "HEAD":
-			addCorsHeader(w)
 			s.doHead(w, r)
 			return
 		case "GET":
 			s.doGet(w, r)
 			return
-		case "PUT":
-			s.doPut(w, r)
+		case "PUT", "POST":
+			s.doUpload(w, r)
 			return
 		case "PATCH":
 			s.doPatch(w, r)
@@ -137,13 +187,6 @@ func (s *svc) setHandler() {
 	})
 }
 
-func addCorsHeader(res http.ResponseWriter) {
-	headers := res.Header()
-	headers.Set("Access-Control-Allow-Origin", "*")
-	headers.Set("Access-Control-Allow-Headers", "Content-Type, Origin, Authorization")
-	headers.Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, HEAD")
-}
-
 func (s *svc) verify(ctx context.Context, r *http.Request) (*transferClaims, error) {
 	// Extract transfer token from request header. If not existing, assume that it's the last path segment instead.
 	token := r.Header.Get(TokenTransportHeader)
@@ -168,6 +211,63 @@ func (s *svc) verify(ctx context.Context, r *http.Request) (*transferClaims, err
 	return nil, err
 }
 
+// acquire takes a transfer slot of the user the transfer token was minted for. The
+// requests to the datagateway are not authenticated, so the limits can't be keyed on
+// the user of the request context. It returns false if the transfer has to be rejected.
+func (s *svc) acquire(r *http.Request, claims *transferClaims) (func(), bool) {
+	key := claims.Subject
+	if key == "" {
+		key = limiter.ClientKey(r)
+	}
+	return s.limits.Acquire(r.Context(), r.Method, key)
+}
+
+// redeem exchanges the one-time download token in the path of a request of a
+// browser for the transfer token it was minted for. The session cookie is not
+// forwarded to the data server.
+func (s *svc) redeem(ctx context.Context, r *http.Request) (*downloadtoken.Grant, error) {
+	if s.downloads == nil {
+		return nil, errtypes.NotSupported("datagateway: download tokens are not enabled")
+	}
+	g, err := downloadtoken.Redeem(ctx, s.downloads, strings.TrimPrefix(r.URL.Path, DownloadPath), downloadtoken.Session(r))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Del("Cookie")
+	r.Header.Set(TokenTransportHeader, g.Token)
+	return g, nil
+}
+
+// setDownloadHeaders makes the browsers save the file downloaded with a download
+// token and keeps the URL, which can't be used again anyway, out of the caches and
+// referrers.
+func setDownloadHeaders(w http.ResponseWriter, g *downloadtoken.Grant) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	if g.Filename != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": g.Filename}))
+	}
+}
+
+// renew hands out a new transfer token for a resumable upload which is past
+// half of its lifetime. Clients following the upload with the token of the
+// response header don't run into its expiry as long as the upload goes on.
+func (s *svc) renew(w http.ResponseWriter, claims *transferClaims) {
+	expires, ok := uploadtoken.Renew(claims.StandardClaims, claims.Scope, time.Now())
+	if !ok {
+		return
+	}
+	renewed := *claims
+	renewed.IssuedAt = time.Now().Unix()
+	renewed.ExpiresAt = expires
+	tkn, err := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), renewed).SignedString([]byte(s.conf.TransferSharedSecret))
+	if err != nil {
+		return
+	}
+	*claims = renewed
+	w.Header().Set(TokenTransportHeader, tkn)
+}
+
 func (s *svc) doHead(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := appctx.GetLogger(ctx)
@@ -190,6 +290,9 @@ func (s *svc) doHead(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	httpReq.Header = r.Header
+	if claims.OCM != nil {
+		httpReq.Header.Set(ctxpkg.TokenHeader, claims.OCM.Token)
+	}
 
 	httpRes, err := httpClient.Do(httpReq)
 	if err != nil {
@@ -201,6 +304,7 @@ func (s *svc) doHead(w http.ResponseWriter, r *http.Request) {
 
 	copyHeader(w.Header(), httpRes.Header)
 
+	s.renew(w, claims)
 	// add upload expiry / transfer token expiry header for tus https://tus.io/protocols/resumable-upload.html#expiration
 	w.Header().Set(UploadExpiresHeader, time.Unix(claims.ExpiresAt, 0).Format(time.RFC1123))
 
@@ -218,6 +322,16 @@ func (s *svc) doGet(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := appctx.GetLogger(ctx)
 
+	var grant *downloadtoken.Grant
+	if strings.HasPrefix(r.URL.Path, DownloadPath) {
+		var err error
+		if grant, err = s.redeem(ctx, r); err != nil {
+			log.Warn().Err(err).Msg("datagateway: invalid download token")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+	}
+
 	claims, err := s.verify(ctx, r)
 	if err != nil {
 		err = errors.Wrap(err, "datagateway: error validating transfer token")
@@ -226,6 +340,21 @@ func (s *svc) doGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	release, ok := s.acquire(r, claims)
+	if !ok {
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	defer release()
+
+	if claims.OCM != nil && s.ocmCache != nil {
+		if grant != nil {
+			setDownloadHeaders(w, grant)
+		}
+		s.doOCMGet(w, r, claims)
+		return
+	}
+
 	log.Debug().Str("target", claims.Target).Msg("sending request to internal data server")
 
 	httpClient := s.client
@@ -236,6 +365,9 @@ func (s *svc) doGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	httpReq.Header = r.Header
+	if claims.OCM != nil {
+		httpReq.Header.Set(ctxpkg.TokenHeader, claims.OCM.Token)
+	}
 
 	httpRes, err := httpClient.Do(httpReq)
 	if err != nil {
@@ -246,6 +378,9 @@ func (s *svc) doGet(w http.ResponseWriter, r *http.Request) {
 	defer httpRes.Body.Close()
 
 	copyHeader(w.Header(), httpRes.Header)
+	if grant != nil {
+		setDownloadHeaders(w, grant)
+	}
 	switch httpRes.StatusCode {
 	case http.StatusOK:
 	case http.StatusPartialContent:
@@ -273,7 +408,8 @@ func (s *svc) doGet(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (s *svc) doPut(w http.ResponseWriter, r *http.Request) {
+// doUpload forwards PUT uploads and the multipart/form-data POST uploads of web forms to the data server.
+func (s *svc) doUpload(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := appctx.GetLogger(ctx)
 
@@ -285,6 +421,13 @@ func (s *svc) doPut(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	release, ok := s.acquire(r, claims)
+	if !ok {
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	defer release()
+
 	target := claims.Target
 	// add query params to target, clients can send checksums and other information.
 	targetURL, err := url.Parse(target)
@@ -300,7 +443,7 @@ func (s *svc) doPut(w http.ResponseWriter, r *http.Request) {
 	log.Debug().Str("target", claims.Target).Msg("sending request to internal data server")
 
 	httpClient := s.client
-	httpReq, err := rhttp.NewRequest(ctx, "PUT", target, r.Body)
+	httpReq, err := rhttp.NewRequest(ctx, r.Method, target, r.Body)
 	if err != nil {
 		log.Err(err).Msg("wrong request")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -310,21 +453,21 @@ func (s *svc) doPut(w http.ResponseWriter, r *http.Request) {
 
 	httpRes, err := httpClient.Do(httpReq)
 	if err != nil {
-		log.Err(err).Msg("error doing PUT request to data service")
+		log.Err(err).Str("method", r.Method).Msg("error doing upload request to data service")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 	defer httpRes.Body.Close()
 
 	copyHeader(w.Header(), httpRes.Header)
-	if httpRes.StatusCode != http.StatusOK {
+	if httpRes.StatusCode != http.StatusOK && httpRes.StatusCode != http.StatusCreated {
 		// swallow the body and set content-length to 0 to prevent reverse proxies from trying to read from it
 		w.Header().Set("Content-Length", "0")
 		w.WriteHeader(httpRes.StatusCode)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(httpRes.StatusCode)
 	_, err = io.Copy(w, httpRes.Body)
 	if err != nil {
 		log.Err(err).Msg("error writing body after header were set")
@@ -344,6 +487,13 @@ func (s *svc) doPatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	release, ok := s.acquire(r, claims)
+	if !ok {
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	defer release()
+
 	target := claims.Target
 	// add query params to target, clients can send checksums and other information.
 	targetURL, err := url.Parse(target)
@@ -377,6 +527,9 @@ func (s *svc) doPatch(w http.ResponseWriter, r *http.Request) {
 
 	copyHeader(w.Header(), httpRes.Header)
 
+	s.renew(w, claims)
+	w.Header().Set(UploadExpiresHeader, time.Unix(claims.ExpiresAt, 0).Format(time.RFC1123))
+
 	if httpRes.StatusCode != http.StatusOK {
 		// swallow the body and set content-length to 0 to prevent reverse proxies from trying to read from it
 		w.Header().Set("Content-Length", "0")
@@ -0,0 +1,125 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package datagateway
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	ocmcache "github.com/cs3org/reva/pkg/ocm/cache"
+	"github.com/cs3org/reva/pkg/rhttp"
+)
+
+// doOCMGet serves the download of a remote file of an OCM share from the
+// cache if its content did not change, and caches it otherwise.
+func (s *svc) doOCMGet(w http.ResponseWriter, r *http.Request, claims *transferClaims) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+	key := claims.OCM.Key
+
+	content, cached := s.ocmCache.OpenContent(key)
+	if cached {
+		defer content.Close()
+		if sameETag(content.ETag, claims.OCM.ETag) {
+			log.Debug().Str("domain", key.Domain).Msg("serving remote file from the ocm cache")
+			serveContent(w, r, content)
+			return
+		}
+	}
+
+	log.Debug().Str("target", claims.Target).Msg("sending request to remote ocm provider")
+
+	httpReq, err := rhttp.NewRequest(ctx, http.MethodGet, claims.Target, nil)
+	if err != nil {
+		log.Error().Err(err).Msg("wrong request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	httpReq.Header.Set(ctxpkg.TokenHeader, key.Token)
+	if cached {
+		httpReq.Header.Set("If-None-Match", content.ETag)
+	} else if rng := r.Header.Get("Range"); rng != "" {
+		// partial content is not cached
+		httpReq.Header.Set("Range", rng)
+	}
+
+	httpRes, err := s.client.Do(httpReq)
+	if err != nil {
+		log.Error().Err(err).Msg("error doing GET request to remote ocm provider")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer httpRes.Body.Close()
+
+	switch httpRes.StatusCode {
+	case http.StatusNotModified:
+		if cached {
+			s.ocmCache.ContentRevalidated(key)
+			serveContent(w, r, content)
+			return
+		}
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(httpRes.StatusCode)
+		return
+	case http.StatusOK:
+	case http.StatusPartialContent:
+	default:
+		// swallow the body and set content-length to 0 to prevent reverse proxies from trying to read from it
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(httpRes.StatusCode)
+		return
+	}
+
+	copyHeader(w.Header(), httpRes.Header)
+	w.WriteHeader(httpRes.StatusCode)
+
+	if httpRes.StatusCode != http.StatusOK {
+		if _, err := io.Copy(w, httpRes.Body); err != nil {
+			log.Error().Err(err).Msg("error writing body after headers were sent")
+		}
+		return
+	}
+
+	cw := s.ocmCache.NewContentWriter(key, httpRes.Header.Get("ETag"))
+	if _, err := io.Copy(io.MultiWriter(w, cw), httpRes.Body); err != nil {
+		cw.Abort()
+		log.Error().Err(err).Msg("error writing body after headers were sent")
+		return
+	}
+	if err := cw.Commit(); err != nil {
+		log.Warn().Err(err).Str("domain", key.Domain).Msg("error caching remote file")
+	}
+}
+
+func serveContent(w http.ResponseWriter, r *http.Request, content *ocmcache.Content) {
+	w.Header().Set("ETag", content.ETag)
+	http.ServeContent(w, r, "", content.Fetched, content.File)
+}
+
+// sameETag compares the ETags of the webdav listings, which are unquoted, with
+// the ones of the HTTP headers.
+func sameETag(a, b string) bool {
+	norm := func(etag string) string {
+		return strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+	}
+	return a != "" && norm(a) == norm(b)
+}
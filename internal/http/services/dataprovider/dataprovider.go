@@ -19,15 +19,25 @@
 package dataprovider
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
+	"github.com/cs3org/reva/internal/http/services/datagateway"
 	"github.com/cs3org/reva/pkg/appctx"
 	datatxregistry "github.com/cs3org/reva/pkg/rhttp/datatx/manager/registry"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/limiter"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/uploadtoken"
 	"github.com/cs3org/reva/pkg/rhttp/global"
 	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/cs3org/reva/pkg/storage/utils/quarantine"
+	"github.com/cs3org/reva/pkg/storage/utils/slowlog"
+	"github.com/cs3org/reva/pkg/storage/utils/throttle"
+	"github.com/cs3org/reva/pkg/storage/utils/tiering"
+	"github.com/cs3org/reva/pkg/storage/utils/virusscan"
 	"github.com/mitchellh/mapstructure"
 	"github.com/rs/zerolog"
 )
@@ -37,12 +47,23 @@ func init() {
 }
 
 type config struct {
-	Prefix   string                            `mapstructure:"prefix" docs:"data;The prefix to be used for this HTTP service"`
-	Driver   string                            `mapstructure:"driver" docs:"localhome;The storage driver to be used."`
-	Drivers  map[string]map[string]interface{} `mapstructure:"drivers" docs:"url:pkg/storage/fs/localhome/localhome.go;The configuration for the storage driver"`
-	DataTXs  map[string]map[string]interface{} `mapstructure:"data_txs" docs:"url:pkg/rhttp/datatx/manager/simple/simple.go;The configuration for the data tx protocols"`
-	Timeout  int64                             `mapstructure:"timeout"`
-	Insecure bool                              `mapstructure:"insecure" docs:"false;Whether to skip certificate checks when sending requests."`
+	Prefix     string                            `mapstructure:"prefix" docs:"data;The prefix to be used for this HTTP service"`
+	Driver     string                            `mapstructure:"driver" docs:"localhome;The storage driver to be used."`
+	Drivers    map[string]map[string]interface{} `mapstructure:"drivers" docs:"url:pkg/storage/fs/localhome/localhome.go;The configuration for the storage driver"`
+	DataTXs    map[string]map[string]interface{} `mapstructure:"data_txs" docs:"url:pkg/rhttp/datatx/manager/simple/simple.go;The configuration for the data tx protocols"`
+	Timeout    int64                             `mapstructure:"timeout"`
+	Insecure   bool                              `mapstructure:"insecure" docs:"false;Whether to skip certificate checks when sending requests."`
+	Limits     limiter.Config                    `mapstructure:"transfer_limits"`
+	Tiering    tiering.Config                    `mapstructure:"tiering"`
+	SlowLog    slowlog.Config                    `mapstructure:"slow_log"`
+	Quarantine quarantine.Config                 `mapstructure:"quarantine"`
+	VirusScan  virusscan.Config                  `mapstructure:"virus_scan"`
+	Throttle   throttle.Config                   `mapstructure:"throttle"`
+	// VerifyUploadTokens makes tus uploads require a transfer token bound to
+	// the upload, as issued by the gateway. It can't be used when the data
+	// server is exposed to the clients.
+	VerifyUploadTokens   bool   `mapstructure:"verify_upload_tokens" docs:"false;Whether tus uploads are only accepted with a transfer token bound to the upload."`
+	TransferSharedSecret string `mapstructure:"transfer_shared_secret" docs:";The secret the transfer tokens are signed with."`
 }
 
 func (c *config) init() {
@@ -52,6 +73,7 @@ func (c *config) init() {
 	if c.Driver == "" {
 		c.Driver = "localhome"
 	}
+	c.TransferSharedSecret = sharedconf.GetJWTSecret(c.TransferSharedSecret)
 }
 
 type svc struct {
@@ -91,10 +113,41 @@ func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error)
 }
 
 func getFS(c *config) (storage.FS, error) {
-	if f, ok := registry.NewFuncs[c.Driver]; ok {
-		return f(c.Drivers[c.Driver])
+	f, ok := registry.NewFuncs[c.Driver]
+	if !ok {
+		return nil, fmt.Errorf("driver not found: %s", c.Driver)
 	}
-	return nil, fmt.Errorf("driver not found: %s", c.Driver)
+	fs, err := f(c.Drivers[c.Driver])
+	if err != nil {
+		return nil, err
+	}
+	// the scan needs to reach the uploads of the driver itself
+	if c.VirusScan.Enabled() {
+		if fs, err = virusscan.New(fs, &c.VirusScan); err != nil {
+			return nil, err
+		}
+	}
+	if c.SlowLog.Enabled() {
+		if fs, err = slowlog.New(fs, &c.SlowLog); err != nil {
+			return nil, err
+		}
+	}
+	if c.Throttle.Enabled() {
+		if fs, err = throttle.New(fs, &c.Throttle); err != nil {
+			return nil, err
+		}
+	}
+	// cold files are recalled when downloaded, which happens here
+	if c.Tiering.Enabled() {
+		if fs, err = tiering.New(fs, &c.Tiering); err != nil {
+			return nil, err
+		}
+	}
+	// infected files are neither served nor recalled from the cold tier
+	if c.Quarantine.Enabled() {
+		return quarantine.New(fs, &c.Quarantine)
+	}
+	return fs, nil
 }
 
 func getDataTXs(c *config, fs storage.FS) (map[string]http.Handler, error) {
@@ -121,6 +174,10 @@ func getDataTXs(c *config, fs storage.FS) (map[string]http.Handler, error) {
 }
 
 func (s *svc) Close() error {
+	if s.conf.Tiering.Enabled() {
+		// stops the scan for cold files
+		return s.storage.Shutdown(context.Background())
+	}
 	return nil
 }
 
@@ -138,6 +195,22 @@ func (s *svc) Handler() http.Handler {
 	return s.handler
 }
 
+// verifyUpload checks that a tus request carries a transfer token bound to
+// the upload it addresses. The access token of the user is not needed, so
+// that uploads outlasting it can still finish.
+func (s *svc) verifyUpload(w http.ResponseWriter, r *http.Request, tail string) bool {
+	if r.Method == http.MethodOptions {
+		return true
+	}
+	uploadID, _ := router.ShiftPath(tail)
+	if err := uploadtoken.Verify(s.conf.TransferSharedSecret, r.Header.Get(datagateway.TokenTransportHeader), uploadID); err != nil {
+		appctx.GetLogger(r.Context()).Debug().Err(err).Str("upload", uploadID).Msg("dataprovider: upload not allowed")
+		w.WriteHeader(http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
 func (s *svc) setHandler() error {
 
 	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -147,6 +220,9 @@ func (s *svc) setHandler() error {
 		head, tail := router.ShiftPath(r.URL.Path)
 
 		if handler, ok := s.dataTXs[head]; ok {
+			if head == "tus" && s.conf.VerifyUploadTokens && !s.verifyUpload(w, r, tail) {
+				return
+			}
 			r.URL.Path = tail
 			handler.ServeHTTP(w, r)
 			return
@@ -161,6 +237,10 @@ func (s *svc) setHandler() error {
 
 		w.WriteHeader(http.StatusInternalServerError)
 	})
+	if s.conf.Throttle.Enabled() {
+		s.handler = throttle.Handler(&s.conf.Throttle, s.handler)
+	}
+	s.handler = limiter.Handler(&s.conf.Limits, s.handler)
 
 	return nil
 }
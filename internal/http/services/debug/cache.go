@@ -0,0 +1,62 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package debug
+
+import (
+	"net/http"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/kvcache"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+)
+
+type flushResult struct {
+	Namespace string `json:"namespace"`
+	Flushed   int    `json:"flushed"`
+}
+
+// handleCache lists the namespaces of the shared key-value cache with their statistics on GET /cache,
+// and flushes them on POST /cache/flush, optionally limited to the namespace given as query parameter.
+func (s *svc) handleCache(w http.ResponseWriter, r *http.Request) {
+	action, _ := router.ShiftPath(r.URL.Path)
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		writeJSON(w, r, http.StatusOK, kvcache.Namespaces())
+	case action == "flush" && r.Method == http.MethodPost:
+		s.handleCacheFlush(w, r)
+	case action == "" || action == "flush":
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *svc) handleCacheFlush(w http.ResponseWriter, r *http.Request) {
+	log := appctx.GetLogger(r.Context())
+
+	namespace := r.URL.Query().Get("namespace")
+	n, err := kvcache.Flush(namespace)
+	if err != nil {
+		log.Error().Err(err).Str("namespace", namespace).Msg("debug: error flushing the cache")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	log.Info().Str("namespace", namespace).Int("flushed", n).Msg("debug: cache flushed")
+	writeJSON(w, r, http.StatusOK, flushResult{Namespace: namespace, Flushed: n})
+}
@@ -0,0 +1,44 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package debug
+
+import (
+	"net/http"
+
+	"github.com/cs3org/reva/pkg/cost"
+)
+
+type costReports struct {
+	Current *cost.Report `json:"current"`
+	Last    *cost.Report `json:"last"`
+}
+
+// handleCosts returns the top consumers of the interval in progress and of the
+// last complete one on GET /costs.
+func (s *svc) handleCosts(w http.ResponseWriter, r *http.Request) {
+	if !cost.Enabled() {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, costReports{Current: cost.Current(), Last: cost.Last()})
+}
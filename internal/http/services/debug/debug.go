@@ -0,0 +1,258 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/sysinfo"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register(serviceName, New)
+}
+
+const serviceName = "debug"
+
+type config struct {
+	Prefix           string   `mapstructure:"prefix" docs:"debug;The prefix to be used for this HTTP service"`
+	EnableProfiling  bool     `mapstructure:"enable_profiling" docs:"false;Whether to expose the pprof profiles under /pprof."`
+	Admins           []string `mapstructure:"admins" docs:"[];The usernames allowed to query the service. If empty, nobody is allowed."`
+	SnapshotDir      string   `mapstructure:"snapshot_dir" docs:"/tmp/reva-diagnostics;The directory where heap snapshots and traces are stored."`
+	HeapThreshold    uint64   `mapstructure:"heap_threshold" docs:"0;The heap size in MB above which a heap snapshot is taken automatically. 0 disables automatic snapshots."`
+	CheckInterval    int      `mapstructure:"check_interval" docs:"30;Interval in seconds between heap size checks."`
+	SnapshotCooldown int      `mapstructure:"snapshot_cooldown" docs:"600;Minimum time in seconds between two automatic heap snapshots."`
+	MaxSnapshots     int      `mapstructure:"max_snapshots" docs:"10;Number of heap snapshots and traces kept, older ones are removed."`
+	MaxTraceSeconds  int      `mapstructure:"max_trace_seconds" docs:"60;Maximum duration in seconds of an on-demand execution trace."`
+}
+
+func (c *config) init() {
+	if c.Prefix == "" {
+		c.Prefix = serviceName
+	}
+	if c.SnapshotDir == "" {
+		c.SnapshotDir = filepath.Join(os.TempDir(), "reva-diagnostics")
+	}
+	if c.CheckInterval <= 0 {
+		c.CheckInterval = 30
+	}
+	if c.SnapshotCooldown <= 0 {
+		c.SnapshotCooldown = 600
+	}
+	if c.MaxSnapshots <= 0 {
+		c.MaxSnapshots = 10
+	}
+	if c.MaxTraceSeconds <= 0 {
+		c.MaxTraceSeconds = 60
+	}
+}
+
+type svc struct {
+	conf    *config
+	admins  map[string]struct{}
+	done    chan struct{}
+	tracing int32
+}
+
+// New returns a new debug service exposing runtime information and, optionally,
+// the profiles of the running daemon. All endpoints are restricted to the configured admins.
+// If a heap threshold is configured, heap snapshots are taken automatically when it is exceeded.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, errors.Wrap(err, "debug: error decoding configuration")
+	}
+	conf.init()
+
+	admins := make(map[string]struct{}, len(conf.Admins))
+	for _, a := range conf.Admins {
+		admins[a] = struct{}{}
+	}
+
+	s := &svc{
+		conf:   conf,
+		admins: admins,
+		done:   make(chan struct{}),
+	}
+
+	if conf.EnableProfiling || conf.HeapThreshold > 0 {
+		if err := os.MkdirAll(conf.SnapshotDir, 0700); err != nil {
+			return nil, errors.Wrap(err, "debug: error creating snapshot directory")
+		}
+	}
+	if conf.HeapThreshold > 0 {
+		go s.watchHeap(log)
+	}
+
+	return s, nil
+}
+
+// Close is called when this service is being stopped.
+func (s *svc) Close() error {
+	close(s.done)
+	return nil
+}
+
+// Prefix returns the main endpoint of this service.
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+// Unprotected returns all endpoints that can be queried without prior authorization.
+func (s *svc) Unprotected() []string {
+	return nil
+}
+
+// Handler serves all HTTP requests.
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.isAllowed(r) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		var head string
+		head, r.URL.Path = router.ShiftPath(r.URL.Path)
+
+		switch head {
+		case "info":
+			s.handleInfo(w, r)
+		case "pprof":
+			if !s.conf.EnableProfiling {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			s.handlePprof(w, r)
+		case "capture":
+			if !s.conf.EnableProfiling {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			s.handleCapture(w, r)
+		case "cache":
+			s.handleCache(w, r)
+		case "costs":
+			s.handleCosts(w, r)
+		case "snapshots":
+			if !s.conf.EnableProfiling && s.conf.HeapThreshold == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			s.handleSnapshots(w, r)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+func (s *svc) isAllowed(r *http.Request) bool {
+	u, ok := ctxpkg.ContextGetUser(r.Context())
+	if !ok {
+		return false
+	}
+	_, ok = s.admins[u.Username]
+	return ok
+}
+
+type info struct {
+	Reva         *sysinfo.RevaVersion `json:"reva"`
+	Uptime       string               `json:"uptime"`
+	ConfigHash   string               `json:"config_hash"`
+	GRPCServices []string             `json:"grpc_services"`
+	HTTPServices []string             `json:"http_services"`
+	NumCPU       int                  `json:"num_cpu"`
+	GoMaxProcs   int                  `json:"gomaxprocs"`
+	Goroutines   int                  `json:"goroutines"`
+	HeapAlloc    uint64               `json:"heap_alloc"`
+	HeapSys      uint64               `json:"heap_sys"`
+	NumGC        uint32               `json:"num_gc"`
+}
+
+func (s *svc) handleInfo(w http.ResponseWriter, r *http.Request) {
+	log := appctx.GetLogger(r.Context())
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	rt := sysinfo.Runtime()
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	data, err := json.Marshal(&info{
+		Reva:         sysinfo.SysInfo.Reva,
+		Uptime:       time.Since(rt.StartTime).Round(time.Second).String(),
+		ConfigHash:   rt.ConfigHash,
+		GRPCServices: rt.GRPCServices,
+		HTTPServices: rt.HTTPServices,
+		NumCPU:       runtime.NumCPU(),
+		GoMaxProcs:   runtime.GOMAXPROCS(0),
+		Goroutines:   runtime.NumGoroutine(),
+		HeapAlloc:    mem.HeapAlloc,
+		HeapSys:      mem.HeapSys,
+		NumGC:        mem.NumGC,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("debug: error marshalling runtime information")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(data); err != nil {
+		log.Error().Err(err).Msg("debug: error writing response")
+	}
+}
+
+// handlePprof serves the profiles of the pprof package, which relies on the
+// profile name being the last element of the path.
+func (s *svc) handlePprof(w http.ResponseWriter, r *http.Request) {
+	name, _ := router.ShiftPath(r.URL.Path)
+	switch name {
+	case "":
+		r.URL.Path = "/debug/pprof/"
+		pprof.Index(w, r)
+	case "cmdline":
+		pprof.Cmdline(w, r)
+	case "profile":
+		pprof.Profile(w, r)
+	case "symbol":
+		pprof.Symbol(w, r)
+	case "trace":
+		if !s.withTrace(func() { pprof.Trace(w, r) }) {
+			w.WriteHeader(http.StatusConflict)
+		}
+	default:
+		pprof.Handler(name).ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package debug
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+)
+
+func newTestService(t *testing.T, conf *config) *svc {
+	conf.SnapshotDir = t.TempDir()
+	srv, err := New(map[string]interface{}{
+		"admins":           conf.Admins,
+		"enable_profiling": conf.EnableProfiling,
+		"snapshot_dir":     conf.SnapshotDir,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = srv.Close() })
+	return srv.(*svc)
+}
+
+func request(method, target, username string) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	if username != "" {
+		r = r.WithContext(ctxpkg.ContextSetUser(context.Background(), &userpb.User{Username: username}))
+	}
+	return r
+}
+
+func TestAccess(t *testing.T) {
+	tests := []struct {
+		admins   []string
+		username string
+		expected int
+	}{
+		// nobody is allowed without admins
+		{nil, "einstein", http.StatusForbidden},
+		{nil, "", http.StatusForbidden},
+		{[]string{"admin"}, "einstein", http.StatusForbidden},
+		{[]string{"admin"}, "", http.StatusForbidden},
+		{[]string{"admin"}, "admin", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		s := newTestService(t, &config{Admins: tt.admins, EnableProfiling: true})
+		for _, target := range []string{"/info", "/pprof/"} {
+			w := httptest.NewRecorder()
+			s.Handler().ServeHTTP(w, request(http.MethodGet, target, tt.username))
+			if w.Code != tt.expected {
+				t.Errorf("%s by %q with admins %v: expected %d, got %d", target, tt.username, tt.admins, tt.expected, w.Code)
+			}
+		}
+	}
+}
+
+func TestProfilingDisabled(t *testing.T) {
+	s := newTestService(t, &config{Admins: []string{"admin"}})
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, request(http.MethodGet, "/pprof/", "admin"))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected the profiles not to be served, got %d", w.Code)
+	}
+}
+
+func TestSingleTrace(t *testing.T) {
+	s := newTestService(t, &config{Admins: []string{"admin"}, EnableProfiling: true})
+	targets := []struct {
+		method string
+		target string
+		ok     int
+	}{
+		{http.MethodGet, "/pprof/trace?seconds=0.01", http.StatusOK},
+		{http.MethodPost, "/capture/trace?seconds=1", http.StatusCreated},
+	}
+
+	// a trace is already running
+	s.tracing = 1
+	for _, tt := range targets {
+		w := httptest.NewRecorder()
+		s.Handler().ServeHTTP(w, request(tt.method, tt.target, "admin"))
+		if w.Code != http.StatusConflict {
+			t.Errorf("%s: expected a conflict while tracing, got %d", tt.target, w.Code)
+		}
+	}
+
+	s.tracing = 0
+	for _, tt := range targets {
+		w := httptest.NewRecorder()
+		s.Handler().ServeHTTP(w, request(tt.method, tt.target, "admin"))
+		if w.Code != tt.ok {
+			t.Errorf("%s: expected %d, got %d", tt.target, tt.ok, w.Code)
+		}
+		if s.tracing != 0 {
+			t.Errorf("%s: expected the trace guard to be released", tt.target)
+		}
+	}
+}
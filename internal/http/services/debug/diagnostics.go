@@ -0,0 +1,257 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	rpprof "runtime/pprof"
+	"runtime/trace"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	heapPrefix  = "heap-"
+	tracePrefix = "trace-"
+)
+
+type snapshot struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+}
+
+// watchHeap periodically checks the heap usage and takes a snapshot whenever it exceeds
+// the configured threshold, at most once per cooldown period.
+func (s *svc) watchHeap(log *zerolog.Logger) {
+	ticker := time.NewTicker(time.Duration(s.conf.CheckInterval) * time.Second)
+	defer ticker.Stop()
+
+	threshold := s.conf.HeapThreshold * 1024 * 1024
+	cooldown := time.Duration(s.conf.SnapshotCooldown) * time.Second
+	var last time.Time
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			var mem runtime.MemStats
+			runtime.ReadMemStats(&mem)
+			if mem.HeapAlloc < threshold || time.Since(last) < cooldown {
+				continue
+			}
+			name, err := s.writeHeapSnapshot()
+			if err != nil {
+				log.Error().Err(err).Msg("debug: error taking heap snapshot")
+				continue
+			}
+			last = time.Now()
+			log.Warn().Uint64("heap_alloc", mem.HeapAlloc).Str("snapshot", name).Msg("debug: heap threshold exceeded, snapshot taken")
+		}
+	}
+}
+
+func (s *svc) snapshotName(prefix, ext string) string {
+	return prefix + time.Now().UTC().Format("20060102T150405.000") + ext
+}
+
+func (s *svc) writeHeapSnapshot() (string, error) {
+	name := s.snapshotName(heapPrefix, ".pprof")
+	f, err := os.Create(filepath.Join(s.conf.SnapshotDir, name))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if err := rpprof.Lookup("heap").WriteTo(f, 0); err != nil {
+		return "", err
+	}
+	s.pruneSnapshots(heapPrefix)
+	return name, nil
+}
+
+// withTrace calls fn unless an execution trace is already being captured, as
+// the runtime only runs one at a time. Both the captures and the trace profile
+// of pprof go through it.
+func (s *svc) withTrace(fn func()) bool {
+	if !atomic.CompareAndSwapInt32(&s.tracing, 0, 1) {
+		return false
+	}
+	defer atomic.StoreInt32(&s.tracing, 0)
+	fn()
+	return true
+}
+
+func (s *svc) writeTrace(d time.Duration) (string, error) {
+	name := s.snapshotName(tracePrefix, ".out")
+	f, err := os.Create(filepath.Join(s.conf.SnapshotDir, name))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if err := trace.Start(f); err != nil {
+		_ = os.Remove(f.Name())
+		return "", errors.Wrap(err, "debug: error starting trace")
+	}
+	time.Sleep(d)
+	trace.Stop()
+
+	s.pruneSnapshots(tracePrefix)
+	return name, nil
+}
+
+// pruneSnapshots removes the oldest snapshots of the given kind exceeding the configured maximum.
+func (s *svc) pruneSnapshots(prefix string) {
+	snapshots, err := s.listSnapshots()
+	if err != nil {
+		return
+	}
+	var names []string
+	for _, sn := range snapshots {
+		if strings.HasPrefix(sn.Name, prefix) {
+			names = append(names, sn.Name)
+		}
+	}
+	// names contain the UTC timestamp, so they sort chronologically
+	sort.Strings(names)
+	for len(names) > s.conf.MaxSnapshots {
+		_ = os.Remove(filepath.Join(s.conf.SnapshotDir, names[0]))
+		names = names[1:]
+	}
+}
+
+func (s *svc) listSnapshots() ([]*snapshot, error) {
+	entries, err := os.ReadDir(s.conf.SnapshotDir)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := []*snapshot{}
+	for _, e := range entries {
+		if e.IsDir() || !(strings.HasPrefix(e.Name(), heapPrefix) || strings.HasPrefix(e.Name(), tracePrefix)) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, &snapshot{Name: e.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	return snapshots, nil
+}
+
+// handleCapture takes a heap snapshot or captures an execution trace of the given number of seconds.
+func (s *svc) handleCapture(w http.ResponseWriter, r *http.Request) {
+	log := appctx.GetLogger(r.Context())
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var name string
+	var err error
+	kind, _ := router.ShiftPath(r.URL.Path)
+	switch kind {
+	case "heap":
+		name, err = s.writeHeapSnapshot()
+	case "trace":
+		seconds := 5
+		if v := r.URL.Query().Get("seconds"); v != "" {
+			seconds, err = strconv.Atoi(v)
+			if err != nil || seconds <= 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+		if seconds > s.conf.MaxTraceSeconds {
+			seconds = s.conf.MaxTraceSeconds
+		}
+		if !s.withTrace(func() { name, err = s.writeTrace(time.Duration(seconds) * time.Second) }) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("kind", kind).Msg("debug: error capturing diagnostics")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, map[string]string{"name": name})
+}
+
+// handleSnapshots lists the stored snapshots or serves the requested one.
+func (s *svc) handleSnapshots(w http.ResponseWriter, r *http.Request) {
+	log := appctx.GetLogger(r.Context())
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, _ := router.ShiftPath(r.URL.Path)
+	if name == "" {
+		snapshots, err := s.listSnapshots()
+		if err != nil {
+			log.Error().Err(err).Msg("debug: error listing snapshots")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, r, http.StatusOK, snapshots)
+		return
+	}
+
+	if !strings.HasPrefix(name, heapPrefix) && !strings.HasPrefix(name, tracePrefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeFile(w, r, filepath.Join(s.conf.SnapshotDir, filepath.Base(name)))
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, code int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		appctx.GetLogger(r.Context()).Error().Err(err).Msg("debug: error marshalling response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(data)
+}
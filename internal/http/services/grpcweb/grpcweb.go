@@ -0,0 +1,114 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package grpcweb
+
+import (
+	"net/http"
+
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/grpcweb"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+)
+
+func init() {
+	global.Register("grpcweb", New)
+}
+
+type config struct {
+	Prefix             string   `mapstructure:"prefix" docs:"grpcweb;The prefix the gRPC-Web clients use as base URL."`
+	GatewaySvc         string   `mapstructure:"gatewaysvc" docs:";The gateway the calls are forwarded to."`
+	Services           []string `mapstructure:"services" docs:"[cs3.gateway.v1beta1.GatewayAPI, revad.watch.WatchService, revad.sharedwithme.SharedWithMeService];The gRPC services browser clients may call."`
+	MaxCallRecvMsgSize int      `mapstructure:"max_call_recv_msg_size" docs:"10240000;The maximum size in bytes of a response message."`
+}
+
+func (c *config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "grpcweb"
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+	if len(c.Services) == 0 {
+		c.Services = []string{
+			"cs3.gateway.v1beta1.GatewayAPI",
+			"revad.watch.WatchService",
+			"revad.sharedwithme.SharedWithMeService",
+		}
+	}
+	if c.MaxCallRecvMsgSize == 0 {
+		c.MaxCallRecvMsgSize = 10240000
+	}
+}
+
+type svc struct {
+	conf    *config
+	conn    *grpc.ClientConn
+	handler http.Handler
+}
+
+// New returns a service translating the gRPC-Web calls of browser clients to gRPC calls on the gateway.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, err
+	}
+	conf.init()
+
+	conn, err := pool.NewConn(pool.Options{Endpoint: conf.GatewaySvc, MaxCallRecvMsgSize: conf.MaxCallRecvMsgSize})
+	if err != nil {
+		return nil, errors.Wrap(err, "grpcweb: error connecting to the gateway")
+	}
+
+	return &svc{
+		conf:    conf,
+		conn:    conn,
+		handler: grpcweb.NewProxy(conn, conf.Services...),
+	}, nil
+}
+
+func (s *svc) Close() error {
+	return s.conn.Close()
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+func (s *svc) Handler() http.Handler {
+	return s.handler
+}
+
+// Unprotected returns all paths, the calls are authenticated by the gateway using the token in their metadata.
+func (s *svc) Unprotected() []string {
+	return []string{"/"}
+}
+
+// IsCrossOrigin returns true as the calls are made by web clients served from other origins.
+func (s *svc) IsCrossOrigin() bool {
+	return true
+}
+
+// CrossOriginHeaders returns the headers of the gRPC-Web protocol and the metadata passed on to the gateway.
+func (s *svc) CrossOriginHeaders() ([]string, []string) {
+	allowed := append([]string{"X-Access-Token", "Idempotency-Key"}, grpcweb.RequestHeaders...)
+	return allowed, grpcweb.ResponseHeaders
+}
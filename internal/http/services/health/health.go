@@ -0,0 +1,125 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/readiness"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register(serviceName, New)
+}
+
+const serviceName = "health"
+
+type config struct {
+	Prefix string `mapstructure:"prefix" docs:"health;The prefix to be used for this HTTP service"`
+}
+
+func (c *config) init() {
+	if c.Prefix == "" {
+		c.Prefix = serviceName
+	}
+}
+
+type svc struct {
+	conf *config
+}
+
+type readyResponse struct {
+	Ready      bool               `json:"ready"`
+	Components []readiness.Status `json:"components"`
+}
+
+// New returns a new health service, exposing the liveness of the daemon under
+// /live and the readiness of its components, like the storage drivers of the
+// mounts, under /ready.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, errors.Wrap(err, "health: error decoding configuration")
+	}
+	conf.init()
+
+	return &svc{conf: conf}, nil
+}
+
+// Close is called when this service is being stopped.
+func (s *svc) Close() error {
+	return nil
+}
+
+// Prefix returns the main endpoint of this service.
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+// Unprotected returns all endpoints that can be queried without prior authorization.
+func (s *svc) Unprotected() []string {
+	return []string{"/"}
+}
+
+// Handler serves all HTTP requests.
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		var head string
+		head, r.URL.Path = router.ShiftPath(r.URL.Path)
+		switch head {
+		case "live":
+			w.WriteHeader(http.StatusOK)
+		case "ready":
+			s.handleReady(w, r)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+func (s *svc) handleReady(w http.ResponseWriter, r *http.Request) {
+	components := readiness.List()
+	res := readyResponse{Ready: true, Components: components}
+	for _, c := range components {
+		if c.State != readiness.Ready {
+			res.Ready = false
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !res.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		appctx.GetLogger(r.Context()).Error().Err(err).Msg("health: error encoding readiness")
+	}
+}
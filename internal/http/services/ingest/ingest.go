@@ -0,0 +1,441 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/ingest"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/metadata"
+)
+
+func init() {
+	global.Register("ingest", New)
+}
+
+// Config holds the config options for the ingest HTTP service.
+type Config struct {
+	Prefix     string `mapstructure:"prefix"`
+	GatewaySvc string `mapstructure:"gatewaysvc"`
+	Secret     string `mapstructure:"secret" docs:"The secret the ingest URLs are signed with. Defaults to the shared JWT secret and must match the one of the ingest auth manager."`
+	// PublicURL is the URL the service is reachable at from the outside, used to build the ingest URLs.
+	// The host of the request minting the URL is used if empty.
+	PublicURL         string `mapstructure:"public_url"`
+	DefaultExpiration int64  `mapstructure:"default_expiration" docs:"86400;The time in seconds an ingest URL is valid for, unless set when minting it."`
+	MaxExpiration     int64  `mapstructure:"max_expiration" docs:"604800;The maximum time in seconds an ingest URL can be valid for."`
+	DefaultMaxSize    uint64 `mapstructure:"default_max_size" docs:"1073741824;The maximum size in bytes of the files uploaded through an ingest URL, unless set when minting it."`
+	MaxSize           uint64 `mapstructure:"max_size" docs:"10737418240;The upper limit of the maximum size that can be set when minting an ingest URL."`
+	AuthType          string `mapstructure:"auth_type" docs:"ingest;The auth type the uploads are authenticated with."`
+	Insecure          bool   `mapstructure:"insecure" docs:"false;Whether to skip the verification of the certificate of the data gateway."`
+}
+
+func (c *Config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "ingest"
+	}
+	if c.DefaultExpiration == 0 {
+		c.DefaultExpiration = 86400
+	}
+	if c.MaxExpiration == 0 {
+		c.MaxExpiration = 604800
+	}
+	if c.DefaultExpiration > c.MaxExpiration {
+		c.DefaultExpiration = c.MaxExpiration
+	}
+	if c.DefaultMaxSize == 0 {
+		c.DefaultMaxSize = 1 << 30
+	}
+	if c.MaxSize == 0 {
+		c.MaxSize = 10 << 30
+	}
+	if c.DefaultMaxSize > c.MaxSize {
+		c.DefaultMaxSize = c.MaxSize
+	}
+	if c.AuthType == "" {
+		c.AuthType = "ingest"
+	}
+	c.PublicURL = strings.TrimSuffix(c.PublicURL, "/")
+	c.Secret = sharedconf.GetJWTSecret(c.Secret)
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+type svc struct {
+	conf   *Config
+	router *chi.Mux
+}
+
+// New returns a new service letting users mint ingest URLs, to which third
+// parties such as lab instruments can upload files without the credentials of the user.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &Config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, err
+	}
+	conf.init()
+
+	s := &svc{
+		conf:   conf,
+		router: chi.NewRouter(),
+	}
+	s.routerInit()
+
+	return s, nil
+}
+
+func (s *svc) routerInit() {
+	s.router.Post("/urls", s.handleMint)
+	s.router.Put("/u/{token}/{name}", s.handleUpload)
+}
+
+// Close performs cleanup.
+func (s *svc) Close() error {
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+func (s *svc) Unprotected() []string {
+	// the uploads are authenticated with the token of the ingest URL
+	return []string{"/u/"}
+}
+
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.router.ServeHTTP(w, r)
+	})
+}
+
+type mintRequest struct {
+	Path         string   `json:"path"`
+	Actor        string   `json:"actor"`
+	MaxSize      uint64   `json:"max_size"`
+	ExpiresIn    int64    `json:"expires_in"`
+	ContentTypes []string `json:"content_types"`
+}
+
+// handleMint mints an ingest URL for a folder of the authenticated user.
+func (s *svc) handleMint(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+	u := ctxpkg.ContextMustGetUser(ctx)
+
+	var req mintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if req.Path == "" || req.Actor == "" {
+		http.Error(w, "missing path or actor", http.StatusBadRequest)
+		return
+	}
+	if req.ExpiresIn == 0 {
+		req.ExpiresIn = s.conf.DefaultExpiration
+	}
+	if req.ExpiresIn < 0 || req.ExpiresIn > s.conf.MaxExpiration {
+		http.Error(w, "invalid expiration, the maximum is "+strconv.FormatInt(s.conf.MaxExpiration, 10), http.StatusBadRequest)
+		return
+	}
+	if req.MaxSize == 0 {
+		req.MaxSize = s.conf.DefaultMaxSize
+	}
+	if req.MaxSize > s.conf.MaxSize {
+		http.Error(w, "invalid max size, the maximum is "+strconv.FormatUint(s.conf.MaxSize, 10), http.StatusBadRequest)
+		return
+	}
+	for _, t := range req.ContentTypes {
+		if !strings.Contains(t, "/") {
+			http.Error(w, "invalid content type "+t, http.StatusBadRequest)
+			return
+		}
+	}
+
+	gtw, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	// the URL can only be minted for a folder the user can upload to
+	res, err := gtw.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{Path: req.Path}})
+	switch {
+	case err != nil:
+		writeError(w, r, err)
+		return
+	case res.Status.Code == rpc.Code_CODE_NOT_FOUND:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case res.Status.Code != rpc.Code_CODE_OK:
+		writeError(w, r, errtypes.InternalError(res.Status.Message))
+		return
+	}
+	if res.Info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		http.Error(w, "not a folder", http.StatusBadRequest)
+		return
+	}
+	if res.Info.PermissionSet != nil && !res.Info.PermissionSet.InitiateFileUpload {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	claims := &ingest.Claims{
+		Owner:        u.Id,
+		Folder:       res.Info.Id,
+		Path:         res.Info.Path,
+		Actor:        req.Actor,
+		MaxSize:      req.MaxSize,
+		ContentTypes: req.ContentTypes,
+	}
+	claims.Id = uuid.NewString()
+	claims.ExpiresAt = time.Now().Add(time.Duration(req.ExpiresIn) * time.Second).Unix()
+	tkn, err := ingest.Mint(s.conf.Secret, claims)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	log.Info().Str("id", claims.Id).Str("user", u.Username).Str("actor", claims.Actor).Str("path", claims.Path).
+		Int64("expires_at", claims.ExpiresAt).Msg("ingest: url minted")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, r, map[string]interface{}{
+		"id":            claims.Id,
+		"url":           s.baseURL(r) + "/u/" + tkn,
+		"path":          claims.Path,
+		"actor":         claims.Actor,
+		"max_size":      claims.MaxSize,
+		"content_types": claims.ContentTypes,
+		"expires_at":    claims.ExpiresAt,
+	})
+}
+
+func (s *svc) baseURL(r *http.Request) string {
+	if s.conf.PublicURL != "" {
+		return s.conf.PublicURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/" + s.conf.Prefix
+}
+
+// handleUpload stores a file uploaded through an ingest URL in the folder of the URL.
+// Existing files are never overwritten.
+func (s *svc) handleUpload(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	token, name := chi.URLParam(r, "token"), chi.URLParam(r, "name")
+	claims, err := ingest.Parse(s.conf.Secret, token)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if !ingest.ValidName(name) {
+		http.Error(w, "invalid file name", http.StatusBadRequest)
+		return
+	}
+	if !claims.AllowsContentType(r.Header.Get("Content-Type")) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	if r.ContentLength < 0 {
+		w.WriteHeader(http.StatusLengthRequired)
+		return
+	}
+	if uint64(r.ContentLength) > claims.MaxSize {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	ctx, err = s.authenticate(ctx, token)
+	if err != nil {
+		if _, ok := err.(errtypes.IsInvalidCredentials); ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeError(w, r, err)
+		return
+	}
+	gtw, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	quota, err := gtw.GetQuota(ctx, &gateway.GetQuotaRequest{Ref: &provider.Reference{ResourceId: claims.Folder}})
+	switch {
+	case err != nil:
+		writeError(w, r, err)
+		return
+	case quota.Status.Code == rpc.Code_CODE_OK:
+		if quota.TotalBytes > 0 && quota.UsedBytes+uint64(r.ContentLength) > quota.TotalBytes {
+			w.WriteHeader(http.StatusInsufficientStorage)
+			return
+		}
+	case quota.Status.Code != rpc.Code_CODE_UNIMPLEMENTED:
+		writeError(w, r, errtypes.InternalError(quota.Status.Message))
+		return
+	}
+
+	ref := &provider.Reference{ResourceId: claims.Folder, Path: "./" + name}
+	stat, err := gtw.Stat(ctx, &provider.StatRequest{Ref: ref})
+	switch {
+	case err != nil:
+		writeError(w, r, err)
+		return
+	case stat.Status.Code == rpc.Code_CODE_OK:
+		w.WriteHeader(http.StatusConflict)
+		return
+	case stat.Status.Code != rpc.Code_CODE_NOT_FOUND:
+		writeError(w, r, errtypes.InternalError(stat.Status.Message))
+		return
+	}
+
+	up, err := gtw.InitiateFileUpload(ctx, &provider.InitiateFileUploadRequest{
+		Ref: ref,
+		Opaque: &typespb.Opaque{
+			Map: map[string]*typespb.OpaqueEntry{
+				"Upload-Length": {
+					Decoder: "plain",
+					Value:   []byte(strconv.FormatInt(r.ContentLength, 10)),
+				},
+			},
+		},
+	})
+	switch {
+	case err != nil:
+		writeError(w, r, err)
+		return
+	case up.Status.Code == rpc.Code_CODE_INSUFFICIENT_STORAGE:
+		w.WriteHeader(http.StatusInsufficientStorage)
+		return
+	case up.Status.Code != rpc.Code_CODE_OK:
+		writeError(w, r, errtypes.InternalError(up.Status.Message))
+		return
+	}
+	var ep, transferToken string
+	for _, p := range up.Protocols {
+		if p.Protocol == "simple" {
+			ep, transferToken = p.UploadEndpoint, p.Token
+		}
+	}
+	if ep == "" {
+		writeError(w, r, errtypes.NotSupported("ingest: no simple upload protocol"))
+		return
+	}
+
+	httpReq, err := rhttp.NewRequest(ctx, http.MethodPut, ep, r.Body)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	httpReq.ContentLength = r.ContentLength
+	httpReq.Header.Set(datagateway.TokenTransportHeader, transferToken)
+	httpRes, err := rhttp.GetHTTPClient(
+		rhttp.Context(ctx),
+		rhttp.Insecure(s.conf.Insecure),
+	).Do(httpReq)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer httpRes.Body.Close()
+	switch httpRes.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+	case http.StatusInsufficientStorage, http.StatusRequestEntityTooLarge:
+		w.WriteHeader(httpRes.StatusCode)
+		return
+	default:
+		writeError(w, r, errtypes.InternalError("ingest: unexpected status from the data gateway: "+httpRes.Status))
+		return
+	}
+
+	log.Info().Str("id", claims.Id).Str("actor", claims.Actor).Str("owner", claims.Owner.GetOpaqueId()).
+		Str("path", claims.Path+"/"+name).Int64("size", r.ContentLength).Msg("ingest: file uploaded")
+	w.WriteHeader(http.StatusCreated)
+}
+
+// authenticate obtains a token restricted to the folder of the ingest URL.
+func (s *svc) authenticate(ctx context.Context, token string) (context.Context, error) {
+	gtw, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		return nil, err
+	}
+	res, err := gtw.Authenticate(ctx, &gateway.AuthenticateRequest{
+		Type:     s.conf.AuthType,
+		ClientId: token,
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case res.Status.Code == rpc.Code_CODE_UNAUTHENTICATED || res.Status.Code == rpc.Code_CODE_PERMISSION_DENIED:
+		return nil, errtypes.InvalidCredentials(res.Status.Message)
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, errtypes.InternalError(res.Status.Message)
+	}
+
+	ctx = ctxpkg.ContextSetToken(ctx, res.Token)
+	ctx = ctxpkg.ContextSetUser(ctx, res.User)
+	ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.TokenHeader, res.Token)
+	return ctx, nil
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if _, err := w.Write(js); err != nil {
+		appctx.GetLogger(r.Context()).Error().Err(err).Msg("ingest: error writing response")
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if _, ok := err.(errtypes.IsNotFound); ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	appctx.GetLogger(r.Context()).Error().Err(err).Msg("ingest: error handling request")
+	w.WriteHeader(http.StatusInternalServerError)
+}
@@ -0,0 +1,170 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package latencyprobe
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/mentix/latency"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register(serviceName, New)
+}
+
+const (
+	serviceName = "latencyprobe"
+)
+
+type config struct {
+	Prefix    string           `mapstructure:"prefix"`
+	Site      string           `mapstructure:"site" docs:";The ID of this site as known to Mentix."`
+	SiteKey   string           `mapstructure:"site_key" docs:";The site key issued by the site accounts service; required if Mentix verifies site keys."`
+	MentixURL string           `mapstructure:"mentix_url" docs:";The URL of the Mentix latency importer the reports are pushed to."`
+	Targets   []latency.Target `mapstructure:"targets" docs:";The peer endpoints to probe."`
+	Subset    int              `mapstructure:"subset" docs:"0;Number of targets probed per round; all targets are probed if 0."`
+	Interval  int              `mapstructure:"interval" docs:"300;Interval in seconds between two probing rounds."`
+	Timeout   int              `mapstructure:"timeout" docs:"10;Timeout in seconds for a single probe."`
+}
+
+func (c *config) init() {
+	if c.Prefix == "" {
+		c.Prefix = serviceName
+	}
+	if c.Interval <= 0 {
+		c.Interval = 300
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10
+	}
+}
+
+type svc struct {
+	conf   *config
+	log    *zerolog.Logger
+	prober *latency.Prober
+	cancel context.CancelFunc
+
+	lastReport *latency.Report
+	mutex      sync.RWMutex
+}
+
+// New returns a new latency probe service which periodically measures the
+// round-trip times to the configured peer sites and reports them to Mentix.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, errors.Wrap(err, "latencyprobe: error decoding configuration")
+	}
+	conf.init()
+
+	if conf.MentixURL == "" {
+		return nil, errors.New("latencyprobe: no mentix url configured")
+	}
+
+	prober, err := latency.NewProber(conf.Site, conf.SiteKey, conf.MentixURL, conf.Targets, conf.Subset, time.Duration(conf.Timeout)*time.Second)
+	if err != nil {
+		return nil, errors.Wrap(err, "latencyprobe: error creating prober")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &svc{
+		conf:   conf,
+		log:    log,
+		prober: prober,
+		cancel: cancel,
+	}
+	go s.run(ctx)
+
+	return s, nil
+}
+
+func (s *svc) run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(s.conf.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		// Each round gets its own request ID to correlate it with the logs of Mentix
+		roundCtx := ctxpkg.ContextSetRequestID(ctx, uuid.NewString())
+		report := s.prober.Probe(roundCtx)
+		s.mutex.Lock()
+		s.lastReport = report
+		s.mutex.Unlock()
+
+		if err := s.prober.Push(roundCtx, report); err != nil {
+			s.log.Error().Err(err).Msg("latencyprobe: error pushing report to mentix")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close is called when this service is being stopped.
+func (s *svc) Close() error {
+	s.cancel()
+	return nil
+}
+
+// Prefix returns the main endpoint of this service.
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+// Unprotected returns all endpoints that can be queried without prior authorization.
+func (s *svc) Unprotected() []string {
+	return []string{}
+}
+
+// Handler serves the measurements of the last probing round.
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		s.mutex.RLock()
+		report := s.lastReport
+		s.mutex.RUnlock()
+
+		if report == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			s.log.Error().Err(err).Msg("latencyprobe: error encoding report")
+		}
+	})
+}
@@ -0,0 +1,365 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package legalhold
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/archiver/manager"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/legalhold"
+	_ "github.com/cs3org/reva/pkg/legalhold/manager/loader" // Load the legal hold managers
+	"github.com/cs3org/reva/pkg/legalhold/manager/registry"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/storage/utils/downloader"
+	"github.com/cs3org/reva/pkg/storage/utils/walker"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register("legalhold", New)
+}
+
+// Config holds the config options for the legal hold HTTP service.
+type Config struct {
+	Prefix     string `mapstructure:"prefix"`
+	GatewaySvc string `mapstructure:"gatewaysvc"`
+	// Admins are the usernames of the users allowed to manage the holds.
+	Admins   []string                          `mapstructure:"admins"`
+	Driver   string                            `mapstructure:"driver"`
+	Drivers  map[string]map[string]interface{} `mapstructure:"drivers"`
+	Insecure bool                              `mapstructure:"insecure" docs:"false;Whether to skip certificate checks when downloading the exported files."`
+	Timeout  int64                             `mapstructure:"timeout" docs:"3600;The timeout in seconds of the download of an exported file."`
+}
+
+func (c *Config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "legalhold"
+	}
+	if c.Driver == "" {
+		c.Driver = "json"
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 3600
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+type svc struct {
+	conf   *Config
+	router *chi.Mux
+	holds  legalhold.Manager
+	admins map[string]struct{}
+}
+
+// New returns a new service allowing admins to place and release legal holds,
+// which are enforced by the storage providers configured with the same manager,
+// and to export the held trees.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &Config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, err
+	}
+	conf.init()
+
+	f, ok := registry.NewFuncs[conf.Driver]
+	if !ok {
+		return nil, fmt.Errorf("legalhold: driver not found: %s", conf.Driver)
+	}
+	holds, err := f(conf.Drivers[conf.Driver])
+	if err != nil {
+		return nil, errors.Wrap(err, "legalhold: error creating the legal hold manager")
+	}
+
+	admins := make(map[string]struct{}, len(conf.Admins))
+	for _, a := range conf.Admins {
+		admins[a] = struct{}{}
+	}
+
+	s := &svc{
+		conf:   conf,
+		router: chi.NewRouter(),
+		holds:  holds,
+		admins: admins,
+	}
+	s.routerInit()
+
+	return s, nil
+}
+
+func (s *svc) routerInit() {
+	s.router.Use(s.adminsOnly)
+	s.router.Get("/holds", s.handleList)
+	s.router.Post("/holds", s.handlePlace)
+	s.router.Get("/holds/{id}", s.handleGet)
+	s.router.Post("/holds/{id}/release", s.handleRelease)
+	s.router.Get("/holds/{id}/attempts", s.handleAttempts)
+	s.router.Get("/holds/{id}/export", s.handleExport)
+}
+
+// Close performs cleanup.
+func (s *svc) Close() error {
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+func (s *svc) Unprotected() []string {
+	return []string{}
+}
+
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.router.ServeHTTP(w, r)
+	})
+}
+
+func (s *svc) adminsOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u := ctxpkg.ContextMustGetUser(r.Context())
+		if _, ok := s.admins[u.Username]; !ok {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *svc) handleList(w http.ResponseWriter, r *http.Request) {
+	holds, err := s.holds.ListHolds(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, holds)
+}
+
+func (s *svc) handleGet(w http.ResponseWriter, r *http.Request) {
+	h, err := s.holds.GetHold(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, h)
+}
+
+func (s *svc) handlePlace(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+	admin := ctxpkg.ContextMustGetUser(ctx)
+
+	h := &legalhold.Hold{
+		ID:        uuid.NewString(),
+		Type:      legalhold.Type(r.FormValue("type")),
+		Reason:    r.FormValue("reason"),
+		CreatedBy: admin.Id,
+		CreatedAt: time.Now().Unix(),
+	}
+	target := r.FormValue("target")
+	if target == "" {
+		http.Error(w, "missing target", http.StatusBadRequest)
+		return
+	}
+
+	switch h.Type {
+	case legalhold.TypeUser:
+		client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		res, err := client.GetUserByClaim(ctx, &userpb.GetUserByClaimRequest{Claim: "username", Value: target})
+		switch {
+		case err != nil:
+			writeError(w, r, err)
+			return
+		case res.Status.Code == rpc.Code_CODE_NOT_FOUND:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case res.Status.Code != rpc.Code_CODE_OK:
+			writeError(w, r, errtypes.InternalError(res.Status.Message))
+			return
+		}
+		h.User = res.User.Id
+	case legalhold.TypeSpace:
+		h.SpaceID = target
+	case legalhold.TypePath:
+		h.Path = target
+	default:
+		http.Error(w, "invalid type", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.holds.PlaceHold(ctx, h); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	log.Info().Str("hold", h.ID).Str("admin", admin.Username).Str("type", string(h.Type)).Str("target", target).
+		Str("reason", h.Reason).Msg("legalhold: hold placed")
+	writeJSON(w, r, h)
+}
+
+func (s *svc) handleRelease(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+	admin := ctxpkg.ContextMustGetUser(ctx)
+
+	h, err := s.holds.GetHold(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !h.Active() {
+		http.Error(w, "hold is already released", http.StatusConflict)
+		return
+	}
+
+	h.Release(admin.Id)
+	if err := s.holds.UpdateHold(ctx, h); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	log.Info().Str("hold", h.ID).Str("admin", admin.Username).Msg("legalhold: hold released")
+	writeJSON(w, r, h)
+}
+
+func (s *svc) handleAttempts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+	if _, err := s.holds.GetHold(ctx, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	attempts, err := s.holds.ListAttempts(ctx, id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, attempts)
+}
+
+// handleExport streams a tar archive of a held tree, given by its path in the
+// namespace of the gateway. The tree cannot change while the hold is active,
+// which makes the archive a consistent snapshot.
+func (s *svc) handleExport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+	admin := ctxpkg.ContextMustGetUser(ctx)
+
+	h, err := s.holds.GetHold(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !h.Active() {
+		http.Error(w, "a released hold cannot be exported consistently", http.StatusConflict)
+		return
+	}
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		http.Error(w, "missing path", http.StatusBadRequest)
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	res, err := client.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{Path: p}})
+	switch {
+	case err != nil:
+		writeError(w, r, err)
+		return
+	case res.Status.Code == rpc.Code_CODE_NOT_FOUND:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case res.Status.Code != rpc.Code_CODE_OK:
+		writeError(w, r, errtypes.InternalError(res.Status.Message))
+		return
+	}
+	// the paths of space and path holds are relative to the storage and
+	// cannot be compared, but the owner of a held user's tree is known
+	if h.Type == legalhold.TypeUser && !utils.UserEqual(h.User, res.Info.Owner) {
+		http.Error(w, "path is not covered by the hold", http.StatusBadRequest)
+		return
+	}
+
+	d := downloader.NewDownloader(client, rhttp.Insecure(s.conf.Insecure), rhttp.Timeout(time.Duration(s.conf.Timeout*int64(time.Second))))
+	arch, err := manager.NewArchiver([]string{p}, walker.NewWalker(client), d, manager.Config{})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	log.Info().Str("hold", h.ID).Str("admin", admin.Username).Str("path", p).Msg("legalhold: export started")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"legalhold-%s.tar\"", h.ID))
+	w.Header().Set("Content-Transfer-Encoding", "binary")
+	if err := arch.CreateTar(ctx, w); err != nil {
+		// the archive is already partially sent, the client sees a truncated stream
+		log.Error().Err(err).Str("hold", h.ID).Str("path", p).Msg("legalhold: error exporting hold")
+		return
+	}
+	log.Info().Str("hold", h.ID).Str("admin", admin.Username).Str("path", p).Msg("legalhold: export finished")
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(js); err != nil {
+		appctx.GetLogger(r.Context()).Error().Err(err).Msg("legalhold: error writing response")
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err.(type) {
+	case errtypes.IsNotFound:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case errtypes.IsAlreadyExists:
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	appctx.GetLogger(r.Context()).Error().Err(err).Msg("legalhold: error handling request")
+	w.WriteHeader(http.StatusInternalServerError)
+}
@@ -20,22 +20,41 @@ package loader
 
 import (
 	// Load core HTTP services
+	_ "github.com/cs3org/reva/internal/http/services/absence"
+	_ "github.com/cs3org/reva/internal/http/services/apispec"
 	_ "github.com/cs3org/reva/internal/http/services/appprovider"
 	_ "github.com/cs3org/reva/internal/http/services/archiver"
+	_ "github.com/cs3org/reva/internal/http/services/autoaccept"
+	_ "github.com/cs3org/reva/internal/http/services/caldav"
 	_ "github.com/cs3org/reva/internal/http/services/datagateway"
 	_ "github.com/cs3org/reva/internal/http/services/dataprovider"
+	_ "github.com/cs3org/reva/internal/http/services/debug"
+	_ "github.com/cs3org/reva/internal/http/services/grpcweb"
+	_ "github.com/cs3org/reva/internal/http/services/health"
 	_ "github.com/cs3org/reva/internal/http/services/helloworld"
+	_ "github.com/cs3org/reva/internal/http/services/ingest"
+	_ "github.com/cs3org/reva/internal/http/services/latencyprobe"
+	_ "github.com/cs3org/reva/internal/http/services/legalhold"
+	_ "github.com/cs3org/reva/internal/http/services/loginguard"
 	_ "github.com/cs3org/reva/internal/http/services/mentix"
 	_ "github.com/cs3org/reva/internal/http/services/meshdirectory"
 	_ "github.com/cs3org/reva/internal/http/services/metrics"
+	_ "github.com/cs3org/reva/internal/http/services/notifications"
+	_ "github.com/cs3org/reva/internal/http/services/oauthapps"
 	_ "github.com/cs3org/reva/internal/http/services/ocmd"
 	_ "github.com/cs3org/reva/internal/http/services/owncloud/ocdav"
 	_ "github.com/cs3org/reva/internal/http/services/owncloud/ocs"
 	_ "github.com/cs3org/reva/internal/http/services/preferences"
 	_ "github.com/cs3org/reva/internal/http/services/prometheus"
+	_ "github.com/cs3org/reva/internal/http/services/quarantine"
 	_ "github.com/cs3org/reva/internal/http/services/reverseproxy"
+	_ "github.com/cs3org/reva/internal/http/services/revocation"
+	_ "github.com/cs3org/reva/internal/http/services/s3"
 	_ "github.com/cs3org/reva/internal/http/services/siteacc"
+	_ "github.com/cs3org/reva/internal/http/services/spaceprovisioner"
+	_ "github.com/cs3org/reva/internal/http/services/supportaccess"
 	_ "github.com/cs3org/reva/internal/http/services/sysinfo"
 	_ "github.com/cs3org/reva/internal/http/services/wellknown"
+	_ "github.com/cs3org/reva/internal/http/services/wopi"
 	// Add your own service here
 )
@@ -0,0 +1,161 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package loginguard
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/auth/loginguard"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register(serviceName, New)
+}
+
+const (
+	serviceName = "loginguard"
+
+	// secretHeader is the header carrying the secret which authorizes the admin requests.
+	secretHeader = "X-Admin-Secret"
+)
+
+type config struct {
+	Prefix      string `mapstructure:"prefix"`
+	AdminSecret string `mapstructure:"admin_secret" docs:";The secret admins have to provide in the X-Admin-Secret header."`
+}
+
+func (c *config) init() {
+	if c.Prefix == "" {
+		c.Prefix = serviceName
+	}
+}
+
+type svc struct {
+	conf *config
+}
+
+// New returns a new service allowing admins to inspect and clear the blocks of the
+// brute-force protection of the auth middleware. The middleware has to run in the
+// same process, as the failed attempts are tracked in memory.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, errors.Wrap(err, "loginguard: error decoding configuration")
+	}
+	conf.init()
+
+	if conf.AdminSecret == "" {
+		return nil, errors.New("loginguard: no admin secret configured")
+	}
+	return &svc{conf: conf}, nil
+}
+
+// Close is called when this service is being stopped.
+func (s *svc) Close() error {
+	return nil
+}
+
+// Prefix returns the main endpoint of this service.
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+// Unprotected returns all endpoints that can be queried without prior authorization.
+// The requests are authorized by the service itself using the admin secret.
+func (s *svc) Unprotected() []string {
+	return []string{"/"}
+}
+
+// Handler serves all HTTP requests.
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(secretHeader)), []byte(s.conf.AdminSecret)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var head string
+		head, r.URL.Path = router.ShiftPath(r.URL.Path)
+
+		switch {
+		case head == "blocks" && r.Method == http.MethodGet:
+			s.handleBlocks(w, r)
+		case head == "clear" && r.Method == http.MethodPost:
+			s.handleClear(w, r)
+		case head == "blocks" || head == "clear":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+// handleBlocks lists the IPs and accounts currently blocked, optionally only those of a guard,
+// i.e. of the basic or publicshares credentials.
+func (s *svc) handleBlocks(w http.ResponseWriter, r *http.Request) {
+	log := appctx.GetLogger(r.Context())
+
+	name := r.FormValue("guard")
+	blocks := []*loginguard.Block{}
+	for _, g := range loginguard.All() {
+		if name == "" || g.Name() == name {
+			blocks = append(blocks, g.Blocks()...)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(blocks); err != nil {
+		log.Error().Err(err).Msg("loginguard: error encoding blocks")
+	}
+}
+
+// handleClear forgets the failed attempts and the block of an IP or account.
+func (s *svc) handleClear(w http.ResponseWriter, r *http.Request) {
+	log := appctx.GetLogger(r.Context())
+
+	g, ok := loginguard.Get(r.FormValue("guard"))
+	if !ok {
+		http.Error(w, "unknown guard", http.StatusBadRequest)
+		return
+	}
+	k := loginguard.Key{Kind: r.FormValue("kind"), Value: r.FormValue("value")}
+	if k.Kind != loginguard.KindIP && k.Kind != loginguard.KindAccount {
+		http.Error(w, "kind must be ip or account", http.StatusBadRequest)
+		return
+	}
+	if k.Value == "" {
+		http.Error(w, "missing value", http.StatusBadRequest)
+		return
+	}
+
+	if !g.Clear(k) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	log.Info().Str("guard", g.Name()).Str("kind", k.Kind).Str("value", k.Value).Msg("loginguard: cleared failed attempts")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -137,13 +137,47 @@ func applyDefaultConfig(conf *config.Configuration) {
 		conf.Connectors.GOCDB.Scope = "SM" // TODO(Daniel-WWU-IT): This might change in the future
 	}
 
-	// Exporters
 	addDefaultConnector := func(enabledList *[]string) {
 		if len(*enabledList) == 0 {
 			*enabledList = append(*enabledList, "*")
 		}
 	}
 
+	// Importers
+	if conf.Importers.Latency.Endpoint == "" {
+		conf.Importers.Latency.Endpoint = "/latency"
+	}
+	addDefaultConnector(&conf.Importers.Latency.EnabledConnectors)
+	if conf.Importers.Latency.MaxAge == "" {
+		conf.Importers.Latency.MaxAge = "24h"
+	}
+	if conf.Importers.Latency.SiteKeys.CacheDuration == "" {
+		conf.Importers.Latency.SiteKeys.CacheDuration = "5m"
+	}
+
+	if conf.Importers.SiteUpdate.Endpoint == "" {
+		conf.Importers.SiteUpdate.Endpoint = "/siteupdate"
+	}
+	addDefaultConnector(&conf.Importers.SiteUpdate.EnabledConnectors)
+
+	if conf.Importers.Usage.Endpoint == "" {
+		conf.Importers.Usage.Endpoint = "/usage"
+	}
+	addDefaultConnector(&conf.Importers.Usage.EnabledConnectors)
+	if conf.Importers.Usage.MaxAge == "" {
+		conf.Importers.Usage.MaxAge = "8760h" // Keep the usage of one year
+	}
+	if conf.Importers.Usage.SiteKeys.CacheDuration == "" {
+		conf.Importers.Usage.SiteKeys.CacheDuration = "5m"
+	}
+
+	// Signing
+	if conf.Signing.IsEnabled() && len(conf.Signing.Endpoints) == 0 {
+		conf.Signing.Endpoints = append(conf.Signing.Endpoints, conf.Importers.SiteUpdate.Endpoint)
+	}
+
+	// Exporters
+
 	if conf.Exporters.WebAPI.Endpoint == "" {
 		conf.Exporters.WebAPI.Endpoint = "/sites"
 	}
@@ -164,6 +198,18 @@ func applyDefaultConfig(conf *config.Configuration) {
 
 	addDefaultConnector(&conf.Exporters.PrometheusSD.EnabledConnectors)
 	addDefaultConnector(&conf.Exporters.Metrics.EnabledConnectors)
+
+	if conf.Exporters.Latency.Endpoint == "" {
+		conf.Exporters.Latency.Endpoint = "/latency"
+	}
+	addDefaultConnector(&conf.Exporters.Latency.EnabledConnectors)
+
+	if conf.Exporters.Usage.Endpoint == "" {
+		conf.Exporters.Usage.Endpoint = "/usage"
+	}
+	addDefaultConnector(&conf.Exporters.Usage.EnabledConnectors)
+
+	addDefaultConnector(&conf.Exporters.Webhook.EnabledConnectors)
 }
 
 // New returns a new Mentix service.
@@ -0,0 +1,172 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/cs3org/reva/pkg/utils"
+	"google.golang.org/grpc/metadata"
+)
+
+// audience holds the users and groups a change of a resource is pushed to: the owner
+// of the resource and the grantees of the shares of the resource or of its ancestors.
+type audience struct {
+	users  []*userpb.UserId
+	groups []*grouppb.GroupId
+}
+
+func (a *audience) includes(u *userpb.User) bool {
+	if a == nil {
+		return false
+	}
+	for _, id := range a.users {
+		if utils.UserEqual(u.Id, id) {
+			return true
+		}
+	}
+	for _, g := range a.groups {
+		if isMemberOf(u, g) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *audience) addGrantee(g *provider.Grantee) {
+	switch {
+	case g.GetUserId() != nil:
+		a.users = append(a.users, g.GetUserId())
+	case g.GetGroupId() != nil:
+		a.groups = append(a.groups, g.GetGroupId())
+	}
+}
+
+// resolver looks up the audience of the changes of resources through the gateway.
+type resolver struct {
+	gatewaySvc        string
+	machineAuthAPIKey string
+}
+
+// resolve returns the audience of a change made by the executant to the referenced resource.
+// The reference is the one of the request of the executant, so it is stated on their behalf;
+// the shares are then listed on behalf of the owner, who can see all the shares of the resource.
+func (r *resolver) resolve(executant *userpb.UserId, ref *provider.Reference) (*audience, error) {
+	if executant == nil || ref == nil {
+		return nil, errtypes.BadRequest("missing executant or reference")
+	}
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(r.gatewaySvc))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := r.impersonate(client, executant)
+	if err != nil {
+		return nil, err
+	}
+	info, err := stat(ctx, client, ref)
+	if _, ok := err.(errtypes.IsNotFound); ok && ref.ResourceId == nil && ref.Path != "" {
+		// the resource is gone, e.g. it was trashed: the shares of its parent still apply
+		info, err = stat(ctx, client, &provider.Reference{Path: path.Dir(ref.Path)})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	aud := &audience{}
+	if info.Owner != nil {
+		aud.users = append(aud.users, info.Owner)
+		if !utils.UserEqual(info.Owner, executant) {
+			if ctx, err = r.impersonate(client, info.Owner); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// the path of the resource is the one in the namespace of the owner from now on
+	if info, err = stat(ctx, client, &provider.Reference{ResourceId: info.Id}); err != nil {
+		return nil, err
+	}
+	filters := []*collaboration.Filter{share.ResourceIDFilter(info.Id)}
+	for p := path.Dir(info.Path); p != "/" && p != "."; p = path.Dir(p) {
+		parent, err := stat(ctx, client, &provider.Reference{Path: p})
+		if err != nil {
+			// the ancestors above the storage of the resource are not shared
+			break
+		}
+		filters = append(filters, share.ResourceIDFilter(parent.Id))
+	}
+
+	res, err := client.ListShares(ctx, &collaboration.ListSharesRequest{Filters: filters})
+	switch {
+	case err != nil:
+		return nil, err
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, errtypes.InternalError(res.Status.Message)
+	}
+	for _, s := range res.Shares {
+		aud.addGrantee(s.Grantee)
+	}
+	return aud, nil
+}
+
+// impersonate returns a context authenticated as the given user.
+func (r *resolver) impersonate(client gateway.GatewayAPIClient, id *userpb.UserId) (context.Context, error) {
+	res, err := client.Authenticate(context.Background(), &gateway.AuthenticateRequest{
+		Type:         "machine",
+		ClientId:     "userid:" + id.OpaqueId,
+		ClientSecret: r.machineAuthAPIKey,
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, fmt.Errorf("error authenticating as %s: %s", id.OpaqueId, res.Status.Message)
+	}
+
+	ctx := ctxpkg.ContextSetToken(context.Background(), res.Token)
+	ctx = ctxpkg.ContextSetUser(ctx, res.User)
+	ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.TokenHeader, res.Token)
+	return ctx, nil
+}
+
+func stat(ctx context.Context, client gateway.GatewayAPIClient, ref *provider.Reference) (*provider.ResourceInfo, error) {
+	res, err := client.Stat(ctx, &provider.StatRequest{Ref: ref})
+	switch {
+	case err != nil:
+		return nil, err
+	case res.Status.Code == rpc.Code_CODE_NOT_FOUND:
+		return nil, errtypes.NotFound(res.Status.Message)
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, errtypes.InternalError(res.Status.Message)
+	}
+	return res.Info, nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package notifications
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// fakeGateway serves the resources of einstein, whose docs folder is shared with marie and
+// the physics group; all the other calls panic.
+type fakeGateway struct {
+	gateway.GatewayAPIServer
+	mu         sync.Mutex
+	listedWith []string
+}
+
+var (
+	home = &provider.ResourceInfo{Id: &provider.ResourceId{StorageId: "home", OpaqueId: "home"}, Path: "/home", Owner: einstein.Id}
+	docs = &provider.ResourceInfo{Id: &provider.ResourceId{StorageId: "home", OpaqueId: "docs"}, Path: "/home/docs", Owner: einstein.Id}
+	file = &provider.ResourceInfo{Id: &provider.ResourceId{StorageId: "home", OpaqueId: "file"}, Path: "/home/docs/relativity.md", Owner: einstein.Id}
+
+	// the resources as seen by marie
+	byPath = map[string]*provider.ResourceInfo{
+		"/home":                           home,
+		"/home/docs":                      docs,
+		"/home/docs/relativity.md":        file,
+		"/home/Shares/docs":               {Id: docs.Id, Path: "/home/Shares/docs", Owner: einstein.Id},
+		"/home/Shares/docs/relativity.md": {Id: file.Id, Path: "/home/Shares/docs/relativity.md", Owner: einstein.Id},
+	}
+	byID = map[string]*provider.ResourceInfo{"home": home, "docs": docs, "file": file}
+
+	shares = []*collaboration.Share{
+		{ResourceId: docs.Id, Grantee: &provider.Grantee{Type: provider.GranteeType_GRANTEE_TYPE_USER, Id: &provider.Grantee_UserId{UserId: marie.Id}}},
+		{ResourceId: docs.Id, Grantee: &provider.Grantee{Type: provider.GranteeType_GRANTEE_TYPE_GROUP, Id: &provider.Grantee_GroupId{GroupId: &grouppb.GroupId{OpaqueId: "physics"}}}},
+		{ResourceId: &provider.ResourceId{StorageId: "home", OpaqueId: "other"}, Grantee: &provider.Grantee{Type: provider.GranteeType_GRANTEE_TYPE_USER, Id: &provider.Grantee_UserId{UserId: richard.Id}}},
+	}
+)
+
+func (g *fakeGateway) Authenticate(ctx context.Context, req *gateway.AuthenticateRequest) (*gateway.AuthenticateResponse, error) {
+	id := &userpb.UserId{Idp: "idp", OpaqueId: req.ClientId[len("userid:"):]}
+	return &gateway.AuthenticateResponse{Status: status.NewOK(ctx), Token: "token-" + id.OpaqueId, User: &userpb.User{Id: id}}, nil
+}
+
+func (g *fakeGateway) Stat(ctx context.Context, req *provider.StatRequest) (*provider.StatResponse, error) {
+	var info *provider.ResourceInfo
+	if req.Ref.ResourceId != nil {
+		info = byID[req.Ref.ResourceId.OpaqueId]
+	} else {
+		info = byPath[req.Ref.Path]
+	}
+	if info == nil {
+		return &provider.StatResponse{Status: status.NewNotFound(ctx, "not found")}, nil
+	}
+	return &provider.StatResponse{Status: status.NewOK(ctx), Info: info}, nil
+}
+
+func (g *fakeGateway) ListShares(ctx context.Context, req *collaboration.ListSharesRequest) (*collaboration.ListSharesResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	g.mu.Lock()
+	g.listedWith = append(g.listedWith, md.Get(ctxpkg.TokenHeader)...)
+	g.mu.Unlock()
+
+	res := &collaboration.ListSharesResponse{Status: status.NewOK(ctx)}
+	for _, s := range shares {
+		for _, f := range req.Filters {
+			if utils.ResourceIDEqual(s.ResourceId, f.GetResourceId()) {
+				res.Shares = append(res.Shares, s)
+				break
+			}
+		}
+	}
+	return res, nil
+}
+
+func startFakeGateway(t *testing.T, g *fakeGateway) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	gateway.RegisterGatewayAPIServer(srv, g)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func TestResolveAudience(t *testing.T) {
+	g := &fakeGateway{}
+	r := &resolver{gatewaySvc: startFakeGateway(t, g), machineAuthAPIKey: "secret"}
+	albert := &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "albert"}, Groups: []string{"physics"}}
+
+	tests := []struct {
+		name string
+		ev   interface{}
+	}{
+		{name: "upload in a shared folder", ev: events.FileUploaded{Executant: marie.Id, Ref: &provider.Reference{Path: "/home/Shares/docs/relativity.md"}}},
+		{name: "trashed item of a shared folder", ev: events.ItemTrashed{Executant: marie.Id, Ref: &provider.Reference{Path: "/home/Shares/docs/trashed.md"}}},
+		{name: "new shared folder", ev: events.ContainerCreated{Executant: marie.Id, Ref: &provider.Reference{ResourceId: docs.Id}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executant, ref, ok := fileChange(tt.ev)
+			if !ok {
+				t.Fatalf("expected %T to be a file change", tt.ev)
+			}
+			aud, err := r.resolve(executant, ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			h := newHub()
+			owner := h.subscribe(einstein, 1)
+			grantee := h.subscribe(marie, 1)
+			member := h.subscribe(albert, 1)
+			other := h.subscribe(richard, 1)
+			h.dispatch(tt.ev, aud)
+
+			if n := received(owner); len(n) != 1 {
+				t.Errorf("expected the owner to be notified, got %v", n)
+			}
+			if n := received(member); len(n) != 1 {
+				t.Errorf("expected the member of the grantee group to be notified, got %v", n)
+			}
+			if n := received(grantee); len(n) != 0 {
+				t.Errorf("expected the executant not to be notified, got %v", n)
+			}
+			if n := received(other); len(n) != 0 {
+				t.Errorf("expected the unrelated user not to be notified, got %v", n)
+			}
+		})
+	}
+
+	// einstein uploads to his own folder, the grantees are notified
+	aud, err := r.resolve(einstein.Id, &provider.Reference{Path: "/home/docs/relativity.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !aud.includes(marie) || aud.includes(richard) {
+		t.Errorf("expected marie but not richard in the audience, got %+v", aud)
+	}
+
+	for _, tkn := range g.listedWith {
+		if tkn != "token-einstein" {
+			t.Errorf("expected the shares to be listed as the owner, got %s", tkn)
+		}
+	}
+
+	if _, err := r.resolve(marie.Id, &provider.Reference{Path: "/home/unknown/file"}); err == nil {
+		t.Error("expected an error for a resource not found")
+	}
+}
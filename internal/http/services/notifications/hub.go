@@ -0,0 +1,185 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package notifications
+
+import (
+	"sync"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+// consumedEvents lists the events pushed to the clients.
+var consumedEvents = []events.Unmarshaller{
+	events.ShareCreated{},
+	events.CommentMentioned{},
+	events.ContainerCreated{},
+	events.FileTouched{},
+	events.FileUploaded{},
+	events.ItemTrashed{},
+	events.ItemMoved{},
+	events.ItemPurged{},
+}
+
+// notification is the payload pushed to the connected clients.
+type notification struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+type subscriber struct {
+	user *userpb.User
+	ch   chan *notification
+}
+
+// hub keeps track of all connected clients and fans out incoming events to them.
+type hub struct {
+	subscribers map[*subscriber]struct{}
+	mutex       sync.RWMutex
+}
+
+func newHub() *hub {
+	return &hub{
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+func (h *hub) subscribe(u *userpb.User, bufferSize int) *subscriber {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	sub := &subscriber{
+		user: u,
+		ch:   make(chan *notification, bufferSize),
+	}
+	h.subscribers[sub] = struct{}{}
+	return sub
+}
+
+func (h *hub) unsubscribe(sub *subscriber) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+// idle returns whether no client is connected.
+func (h *hub) idle() bool {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return len(h.subscribers) == 0
+}
+
+// dispatch pushes the event to every subscriber affected by it, the audience
+// being the one of the resource changed by a file event. Slow clients whose
+// buffer is full simply miss the notification instead of blocking the hub.
+func (h *hub) dispatch(ev interface{}, aud *audience) {
+	n := toNotification(ev)
+	if n == nil {
+		return
+	}
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for sub := range h.subscribers {
+		if !concernsUser(ev, aud, sub.user) {
+			continue
+		}
+		select {
+		case sub.ch <- n:
+		default:
+		}
+	}
+}
+
+func toNotification(ev interface{}) *notification {
+	switch e := ev.(type) {
+	case events.ShareCreated:
+		return &notification{Type: "share-created", Data: e}
+	case events.CommentMentioned:
+		return &notification{Type: "comment-mentioned", Data: e}
+	case events.ContainerCreated:
+		return &notification{Type: "container-created", Data: e}
+	case events.FileTouched:
+		return &notification{Type: "file-touched", Data: e}
+	case events.FileUploaded:
+		return &notification{Type: "file-uploaded", Data: e}
+	case events.ItemTrashed:
+		return &notification{Type: "item-trashed", Data: e}
+	case events.ItemMoved:
+		return &notification{Type: "item-moved", Data: e}
+	case events.ItemPurged:
+		return &notification{Type: "item-purged", Data: e}
+	}
+	return nil
+}
+
+// concernsUser checks whether the given user is involved in the event. The file
+// changes are pushed to the audience of the changed resource, but not to the
+// user who made them.
+func concernsUser(ev interface{}, aud *audience, u *userpb.User) bool {
+	switch e := ev.(type) {
+	case events.ShareCreated:
+		return utils.UserEqual(u.Id, e.Sharer) || utils.UserEqual(u.Id, e.GranteeUserID) || isMemberOf(u, e.GranteeGroupID)
+	case events.CommentMentioned:
+		return utils.UserEqual(u.Id, e.Mentioned)
+	}
+	if executant, _, ok := fileChange(ev); ok {
+		return !utils.UserEqual(u.Id, executant) && aud.includes(u)
+	}
+	return false
+}
+
+// fileChange returns the executant and the reference of the resource changed by a file event.
+func fileChange(ev interface{}) (*userpb.UserId, *provider.Reference, bool) {
+	switch e := ev.(type) {
+	case events.ContainerCreated:
+		return e.Executant, e.Ref, true
+	case events.FileTouched:
+		return e.Executant, e.Ref, true
+	case events.FileUploaded:
+		return e.Executant, e.Ref, true
+	case events.ItemTrashed:
+		return e.Executant, e.Ref, true
+	case events.ItemMoved:
+		return e.Executant, e.Ref, true
+	case events.ItemPurged:
+		return e.Executant, e.Ref, true
+	}
+	return nil, nil, false
+}
+
+func isMemberOf(u *userpb.User, g *grouppb.GroupId) bool {
+	if g == nil {
+		return false
+	}
+	for _, group := range u.Groups {
+		if group == g.OpaqueId {
+			return true
+		}
+	}
+	return false
+}
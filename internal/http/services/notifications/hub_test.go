@@ -0,0 +1,118 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package notifications
+
+import (
+	"testing"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/events"
+)
+
+var (
+	einstein = &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "einstein"}}
+	marie    = &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "marie"}, Groups: []string{"physics"}}
+	richard  = &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "richard"}}
+)
+
+func received(sub *subscriber) []string {
+	var types []string
+	for {
+		select {
+		case n := <-sub.ch:
+			types = append(types, n.Type)
+		default:
+			return types
+		}
+	}
+}
+
+func TestDispatchShareCreated(t *testing.T) {
+	h := newHub()
+	sharer := h.subscribe(einstein, 4)
+	member := h.subscribe(marie, 4)
+	other := h.subscribe(richard, 4)
+
+	h.dispatch(events.ShareCreated{Sharer: einstein.Id, GranteeGroupID: &grouppb.GroupId{OpaqueId: "physics"}}, nil)
+
+	if n := received(sharer); len(n) != 1 || n[0] != "share-created" {
+		t.Errorf("expected the sharer to be notified, got %v", n)
+	}
+	if n := received(member); len(n) != 1 {
+		t.Errorf("expected the group member to be notified, got %v", n)
+	}
+	if n := received(other); len(n) != 0 {
+		t.Errorf("expected other users not to be notified, got %v", n)
+	}
+}
+
+func TestDispatchFileChanges(t *testing.T) {
+	h := newHub()
+	laptop := h.subscribe(marie, 8)
+	phone := h.subscribe(marie, 8)
+	executant := h.subscribe(einstein, 8)
+	other := h.subscribe(richard, 8)
+
+	ref := &provider.Reference{Path: "/home/docs"}
+	aud := &audience{users: []*userpb.UserId{einstein.Id}, groups: []*grouppb.GroupId{{OpaqueId: "physics"}}}
+	h.dispatch(events.ContainerCreated{Executant: einstein.Id, Ref: ref}, aud)
+	h.dispatch(events.FileUploaded{Executant: einstein.Id, Ref: ref}, aud)
+	h.dispatch(events.ItemMoved{Executant: einstein.Id, Ref: ref, OldReference: ref}, aud)
+	h.dispatch(events.ItemTrashed{Executant: einstein.Id, Ref: ref}, aud)
+	h.dispatch(events.ItemPurged{Executant: einstein.Id, Ref: ref}, aud)
+	h.dispatch(events.FileTouched{Executant: einstein.Id, Ref: ref}, nil)
+
+	expected := []string{"container-created", "file-uploaded", "item-moved", "item-trashed", "item-purged"}
+	for _, sub := range []*subscriber{laptop, phone} {
+		n := received(sub)
+		if len(n) != len(expected) {
+			t.Fatalf("expected %v, got %v", expected, n)
+		}
+		for i := range expected {
+			if n[i] != expected[i] {
+				t.Errorf("expected %v, got %v", expected, n)
+			}
+		}
+	}
+	if n := received(executant); len(n) != 0 {
+		t.Errorf("expected the executant not to be notified, got %v", n)
+	}
+	if n := received(other); len(n) != 0 {
+		t.Errorf("expected other users not to be notified, got %v", n)
+	}
+}
+
+func TestDispatchSlowSubscriber(t *testing.T) {
+	h := newHub()
+	sub := h.subscribe(einstein, 1)
+
+	aud := &audience{users: []*userpb.UserId{einstein.Id}}
+	h.dispatch(events.FileTouched{Executant: marie.Id}, aud)
+	h.dispatch(events.FileTouched{Executant: marie.Id}, aud)
+
+	if n := received(sub); len(n) != 1 {
+		t.Errorf("expected the notifications exceeding the buffer to be dropped, got %v", n)
+	}
+	h.unsubscribe(sub)
+	if _, ok := <-sub.ch; ok {
+		t.Error("expected the channel to be closed")
+	}
+}
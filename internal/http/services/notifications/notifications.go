@@ -0,0 +1,264 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/asim/go-micro/plugins/events/nats/v4"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/events/server"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/token"
+	tokenmgr "github.com/cs3org/reva/pkg/token/manager/registry"
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register(serviceName, New)
+}
+
+const (
+	serviceName = "notifications"
+
+	// queryTokenParam is used by browser clients, as the EventSource API doesn't allow setting custom headers.
+	queryTokenParam = "access_token"
+)
+
+type config struct {
+	Prefix        string                            `mapstructure:"prefix"`
+	GatewaySvc    string                            `mapstructure:"gatewaysvc"`
+	NatsAddress   string                            `mapstructure:"nats_address"`
+	NatsClusterID string                            `mapstructure:"nats_clusterid"`
+	TokenManager  string                            `mapstructure:"token_manager"`
+	TokenManagers map[string]map[string]interface{} `mapstructure:"token_managers"`
+	KeepAlive     int                               `mapstructure:"keepalive" docs:"30;Interval in seconds between keep-alive messages sent to idle clients."`
+	BufferSize    int                               `mapstructure:"buffer_size" docs:"32;Number of notifications buffered per client before dropping new ones."`
+	// MachineAuthAPIKey is the key of the machine auth provider, used to look up the shares of the changed resources.
+	MachineAuthAPIKey string `mapstructure:"machine_auth_apikey"`
+}
+
+func (c *config) init() {
+	if c.Prefix == "" {
+		c.Prefix = serviceName
+	}
+	if c.TokenManager == "" {
+		c.TokenManager = "jwt"
+	}
+	if c.KeepAlive <= 0 {
+		c.KeepAlive = 30
+	}
+	if c.BufferSize <= 0 {
+		c.BufferSize = 32
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+type svc struct {
+	conf     *config
+	log      *zerolog.Logger
+	tokenmgr token.Manager
+	hub      *hub
+	resolver *resolver
+}
+
+// New returns a new notifications service which pushes events affecting the
+// connected users to them using Server-Sent Events.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, errors.Wrap(err, "notifications: error decoding configuration")
+	}
+	conf.init()
+
+	if conf.NatsAddress == "" {
+		return nil, errors.New("notifications: no nats address configured")
+	}
+	if conf.MachineAuthAPIKey == "" {
+		return nil, errors.New("notifications: no machine auth api key configured")
+	}
+
+	f, ok := tokenmgr.NewFuncs[conf.TokenManager]
+	if !ok {
+		return nil, fmt.Errorf("notifications: token manager not found: %s", conf.TokenManager)
+	}
+	tm, err := f(conf.TokenManagers[conf.TokenManager])
+	if err != nil {
+		return nil, errors.Wrap(err, "notifications: error creating token manager")
+	}
+
+	stream, err := server.NewNatsStream(nats.Address(conf.NatsAddress), nats.ClusterID(conf.NatsClusterID))
+	if err != nil {
+		return nil, errors.Wrap(err, "notifications: error connecting to the event stream")
+	}
+
+	// Every instance needs to see all events to serve its own connections, so each uses its own consumer group
+	evs, err := events.Consume(stream, serviceName+"-"+uuid.NewString(), consumedEvents...)
+	if err != nil {
+		return nil, errors.Wrap(err, "notifications: error consuming events")
+	}
+
+	s := &svc{
+		conf:     conf,
+		log:      log,
+		tokenmgr: tm,
+		hub:      newHub(),
+		resolver: &resolver{gatewaySvc: conf.GatewaySvc, machineAuthAPIKey: conf.MachineAuthAPIKey},
+	}
+
+	go func() {
+		for ev := range evs {
+			s.hub.dispatch(ev, s.audience(ev))
+		}
+	}()
+
+	return s, nil
+}
+
+// audience returns the audience of the resource changed by a file event, nil for the other events.
+func (s *svc) audience(ev interface{}) *audience {
+	executant, ref, ok := fileChange(ev)
+	if !ok || s.hub.idle() {
+		return nil
+	}
+	aud, err := s.resolver.resolve(executant, ref)
+	if err != nil {
+		s.log.Error().Err(err).Interface("ref", ref).Msg("notifications: error resolving the audience of the event")
+		return nil
+	}
+	return aud
+}
+
+// Close is called when this service is being stopped.
+func (s *svc) Close() error {
+	return nil
+}
+
+// Prefix returns the main endpoint of this service.
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+// Unprotected returns all endpoints that can be queried without prior authorization.
+// The token might be passed as a query parameter, which is verified by the service itself.
+func (s *svc) Unprotected() []string {
+	return []string{"/sse"}
+}
+
+// Handler serves all HTTP requests.
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var head string
+		head, r.URL.Path = router.ShiftPath(r.URL.Path)
+
+		switch head {
+		case "sse":
+			s.handleSSE(w, r)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+func (s *svc) getUser(r *http.Request) (*userpb.User, error) {
+	if u, ok := ctxpkg.ContextGetUser(r.Context()); ok {
+		return u, nil
+	}
+
+	tkn := r.URL.Query().Get(queryTokenParam)
+	if tkn == "" {
+		return nil, errors.New("no access token provided")
+	}
+	u, _, err := s.tokenmgr.DismantleToken(r.Context(), tkn)
+	return u, err
+}
+
+func (s *svc) handleSSE(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	u, err := s.getUser(r)
+	if err != nil {
+		log.Debug().Err(err).Msg("notifications: unauthenticated request")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.Error().Msg("notifications: streaming not supported by the response writer")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	sub := s.hub.subscribe(u, s.conf.BufferSize)
+	defer s.hub.unsubscribe(sub)
+
+	keepAlive := time.NewTicker(time.Duration(s.conf.KeepAlive) * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case n, ok := <-sub.ch:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(n.Data)
+			if err != nil {
+				log.Error().Err(err).Str("type", n.Type).Msg("notifications: error marshalling notification")
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", n.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
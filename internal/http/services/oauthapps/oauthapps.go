@@ -0,0 +1,686 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package oauthapps
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/oauthapps"
+	_ "github.com/cs3org/reva/pkg/oauthapps/manager/loader" // Load the oauth apps managers
+	"github.com/cs3org/reva/pkg/oauthapps/manager/registry"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register("oauthapps", New)
+}
+
+// Config holds the config options for the oauth apps HTTP service.
+type Config struct {
+	Prefix     string `mapstructure:"prefix"`
+	GatewaySvc string `mapstructure:"gatewaysvc"`
+	// Admins are the usernames of the users allowed to approve the registered clients.
+	Admins []string `mapstructure:"admins"`
+	// OpenRegistration lets clients register without being authenticated.
+	OpenRegistration bool `mapstructure:"open_registration"`
+	// AutoApprove approves the registered clients right away, without an admin.
+	AutoApprove          bool                              `mapstructure:"auto_approve"`
+	CodeLifetime         int64                             `mapstructure:"code_lifetime" docs:"60;The time in seconds an authorization code can be exchanged for a token."`
+	DefaultTokenLifetime int64                             `mapstructure:"default_token_lifetime" docs:"3600;The time in seconds the tokens issued to a client are valid for, unless set when approving it."`
+	MaxTokenLifetime     int64                             `mapstructure:"max_token_lifetime" docs:"86400;The maximum time in seconds the tokens issued to a client can be valid for."`
+	AuthType             string                            `mapstructure:"auth_type" docs:"oauthapp;The auth type the tokens are obtained with."`
+	Driver               string                            `mapstructure:"driver"`
+	Drivers              map[string]map[string]interface{} `mapstructure:"drivers"`
+}
+
+func (c *Config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "oauth2"
+	}
+	if c.CodeLifetime == 0 {
+		c.CodeLifetime = 60
+	}
+	if c.DefaultTokenLifetime == 0 {
+		c.DefaultTokenLifetime = 3600
+	}
+	if c.MaxTokenLifetime == 0 {
+		c.MaxTokenLifetime = 86400
+	}
+	if c.DefaultTokenLifetime > c.MaxTokenLifetime {
+		c.DefaultTokenLifetime = c.MaxTokenLifetime
+	}
+	if c.AuthType == "" {
+		c.AuthType = "oauthapp"
+	}
+	if c.Driver == "" {
+		c.Driver = "json"
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+// authorization is an authorization request waiting for the decision of the user on the consent screen.
+type authorization struct {
+	user                *userpb.UserId
+	client              *oauthapps.Client
+	scopes              []string
+	redirectURI         string
+	state               string
+	codeChallenge       string
+	codeChallengeMethod string
+	expiresAt           time.Time
+}
+
+type svc struct {
+	conf   *Config
+	router *chi.Mux
+	apps   oauthapps.Manager
+	admins map[string]struct{}
+
+	mutex          sync.Mutex
+	authorizations map[string]*authorization
+}
+
+// New returns a new service letting third-party applications register and
+// obtain tokens on behalf of the users who consent to it.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &Config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, err
+	}
+	conf.init()
+
+	f, ok := registry.NewFuncs[conf.Driver]
+	if !ok {
+		return nil, fmt.Errorf("oauthapps: driver not found: %s", conf.Driver)
+	}
+	apps, err := f(conf.Drivers[conf.Driver])
+	if err != nil {
+		return nil, errors.Wrap(err, "oauthapps: error creating the oauth apps manager")
+	}
+
+	admins := make(map[string]struct{}, len(conf.Admins))
+	for _, a := range conf.Admins {
+		admins[a] = struct{}{}
+	}
+
+	s := &svc{
+		conf:           conf,
+		router:         chi.NewRouter(),
+		apps:           apps,
+		admins:         admins,
+		authorizations: map[string]*authorization{},
+	}
+	s.routerInit()
+
+	return s, nil
+}
+
+func (s *svc) routerInit() {
+	s.router.Post("/register", s.handleRegister)
+	s.router.Get("/authorize", s.handleAuthorize)
+	s.router.Post("/authorize", s.handleDecision)
+	s.router.Post("/token", s.handleToken)
+
+	s.router.Get("/clients", s.handleListClients)
+	s.router.Post("/clients/{id}/approve", s.handleReview(true))
+	s.router.Post("/clients/{id}/reject", s.handleReview(false))
+	s.router.Delete("/clients/{id}", s.handleDeleteClient)
+
+	s.router.Get("/consents", s.handleListConsents)
+	s.router.Delete("/consents/{id}", s.handleDeleteConsent)
+}
+
+// Close performs cleanup.
+func (s *svc) Close() error {
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+func (s *svc) Unprotected() []string {
+	// clients authenticate at the token endpoint with their own credentials
+	unprotected := []string{"/token"}
+	if s.conf.OpenRegistration {
+		unprotected = append(unprotected, "/register")
+	}
+	return unprotected
+}
+
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.router.ServeHTTP(w, r)
+	})
+}
+
+func (s *svc) isAdmin(u *userpb.User) bool {
+	_, ok := s.admins[u.Username]
+	return ok
+}
+
+type registration struct {
+	ClientName   string   `json:"client_name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scope        string   `json:"scope"`
+}
+
+// handleRegister implements the dynamic client registration of RFC 7591.
+func (s *svc) handleRegister(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	var reg registration
+	if err := json.NewDecoder(r.Body).Decode(&reg); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_client_metadata", "invalid json")
+		return
+	}
+	if reg.ClientName == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_client_metadata", "missing client_name")
+		return
+	}
+	if len(reg.RedirectURIs) == 0 {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_redirect_uri", "missing redirect_uris")
+		return
+	}
+	for _, uri := range reg.RedirectURIs {
+		if !validRedirectURI(uri) {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_redirect_uri", "invalid redirect uri "+uri)
+			return
+		}
+	}
+	scopes, err := oauthapps.ParseScopes(reg.Scope)
+	if err != nil || len(scopes) == 0 {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_client_metadata", "invalid scope")
+		return
+	}
+
+	secret := uuid.NewString()
+	client := &oauthapps.Client{
+		ID:            uuid.NewString(),
+		Name:          reg.ClientName,
+		RedirectURIs:  reg.RedirectURIs,
+		Scopes:        scopes,
+		State:         oauthapps.StatePending,
+		TokenLifetime: s.conf.DefaultTokenLifetime,
+		CreatedAt:     time.Now().Unix(),
+	}
+	if u, ok := ctxpkg.ContextGetUser(ctx); ok {
+		client.Owner = u.Id
+	}
+	if s.conf.AutoApprove {
+		client.State = oauthapps.StateApproved
+	}
+	client.SetSecret(secret)
+	if err := s.apps.CreateClient(ctx, client); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	log.Info().Str("client_id", client.ID).Str("client_name", client.Name).Strs("scopes", scopes).
+		Str("state", string(client.State)).Msg("oauthapps: client registered")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, r, map[string]interface{}{
+		"client_id":                client.ID,
+		"client_secret":            secret,
+		"client_id_issued_at":      client.CreatedAt,
+		"client_secret_expires_at": 0,
+		"client_name":              client.Name,
+		"redirect_uris":            client.RedirectURIs,
+		"scope":                    strings.Join(client.Scopes, " "),
+		"state":                    client.State,
+	})
+}
+
+func validRedirectURI(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Fragment != "" || u.Host == "" {
+		return false
+	}
+	// plain http is only allowed for applications running on the machine of the user
+	return u.Scheme == "https" || (u.Scheme == "http" && (u.Hostname() == "localhost" || u.Hostname() == "127.0.0.1" || u.Hostname() == "::1"))
+}
+
+// handleAuthorize validates an authorization request and asks the user for consent, unless already given.
+func (s *svc) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	u := ctxpkg.ContextMustGetUser(ctx)
+	q := r.URL.Query()
+
+	client, err := s.apps.GetClient(ctx, q.Get("client_id"))
+	if err != nil || client.State != oauthapps.StateApproved {
+		// never redirect to the URI of an unknown client
+		http.Error(w, "unknown client", http.StatusBadRequest)
+		return
+	}
+	redirectURI := q.Get("redirect_uri")
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !client.AllowsRedirect(redirectURI) {
+		http.Error(w, "invalid redirect uri", http.StatusBadRequest)
+		return
+	}
+
+	state := q.Get("state")
+	if q.Get("response_type") != "code" {
+		redirectError(w, r, redirectURI, state, "unsupported_response_type")
+		return
+	}
+	scopes, err := oauthapps.ParseScopes(q.Get("scope"))
+	if err != nil || len(scopes) == 0 || !client.AllowsScopes(scopes) {
+		redirectError(w, r, redirectURI, state, "invalid_scope")
+		return
+	}
+	method := q.Get("code_challenge_method")
+	if q.Get("code_challenge") != "" && method == "" {
+		method = "plain"
+	}
+	if method != "" && method != "plain" && method != "S256" {
+		redirectError(w, r, redirectURI, state, "invalid_request")
+		return
+	}
+
+	a := &authorization{
+		user:                u.Id,
+		client:              client,
+		scopes:              scopes,
+		redirectURI:         redirectURI,
+		state:               state,
+		codeChallenge:       q.Get("code_challenge"),
+		codeChallengeMethod: method,
+	}
+
+	if consent, err := s.apps.GetConsent(ctx, client.ID, u.Id); err == nil && consent.Covers(scopes) {
+		s.grant(w, r, a)
+		return
+	}
+
+	id := s.addAuthorization(a)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Frame-Options", "DENY")
+	if err := consentTemplate.Execute(w, map[string]interface{}{
+		"ID":     id,
+		"Client": client.Name,
+		"User":   u.Username,
+		"Scopes": scopes,
+	}); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("oauthapps: error rendering the consent screen")
+	}
+}
+
+// handleDecision handles the answer of the user on the consent screen.
+func (s *svc) handleDecision(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+	u := ctxpkg.ContextMustGetUser(ctx)
+
+	a, ok := s.takeAuthorization(r.FormValue("authorization"))
+	if !ok || !utils.UserEqual(a.user, u.Id) {
+		http.Error(w, "unknown or expired authorization request", http.StatusBadRequest)
+		return
+	}
+	if r.FormValue("decision") != "allow" {
+		log.Info().Str("client_id", a.client.ID).Str("user", u.Username).Msg("oauthapps: consent denied")
+		redirectError(w, r, a.redirectURI, a.state, "access_denied")
+		return
+	}
+
+	if err := s.apps.SetConsent(ctx, &oauthapps.Consent{
+		ClientID:  a.client.ID,
+		User:      u.Id,
+		Scopes:    a.scopes,
+		GrantedAt: time.Now().Unix(),
+	}); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	log.Info().Str("client_id", a.client.ID).Str("user", u.Username).Strs("scopes", a.scopes).Msg("oauthapps: consent given")
+	s.grant(w, r, a)
+}
+
+// grant issues an authorization code and sends the user back to the client.
+func (s *svc) grant(w http.ResponseWriter, r *http.Request, a *authorization) {
+	ctx := r.Context()
+
+	code := uuid.NewString()
+	if err := s.apps.CreateCode(ctx, &oauthapps.Code{
+		Hash:                oauthapps.Hash(code),
+		ClientID:            a.client.ID,
+		User:                a.user,
+		Scopes:              a.scopes,
+		RedirectURI:         a.redirectURI,
+		CodeChallenge:       a.codeChallenge,
+		CodeChallengeMethod: a.codeChallengeMethod,
+		ExpiresAt:           time.Now().Add(time.Duration(s.conf.CodeLifetime) * time.Second).Unix(),
+	}); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	params := url.Values{}
+	params.Set("code", code)
+	if a.state != "" {
+		params.Set("state", a.state)
+	}
+	redirect(w, r, a.redirectURI, params)
+}
+
+// handleToken exchanges an authorization code for a reva token.
+func (s *svc) handleToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	if r.FormValue("grant_type") != "authorization_code" {
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "")
+		return
+	}
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID, secret = r.FormValue("client_id"), r.FormValue("client_secret")
+	}
+	client, err := s.apps.GetClient(ctx, clientID)
+	if err != nil || client.State != oauthapps.StateApproved || !client.VerifySecret(secret) {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "")
+		return
+	}
+
+	code := r.FormValue("code")
+	c, err := s.apps.GetCode(ctx, oauthapps.Hash(code))
+	if err != nil || c.ClientID != client.ID || c.RedirectURI != r.FormValue("redirect_uri") || !c.VerifyChallenge(r.FormValue("code_verifier")) {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "")
+		return
+	}
+
+	gtw, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	res, err := gtw.Authenticate(ctx, &gateway.AuthenticateRequest{
+		Type:         s.conf.AuthType,
+		ClientId:     code,
+		ClientSecret: secret,
+	})
+	switch {
+	case err != nil:
+		writeError(w, r, err)
+		return
+	case res.Status.Code == rpc.Code_CODE_UNAUTHENTICATED || res.Status.Code == rpc.Code_CODE_PERMISSION_DENIED:
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "")
+		return
+	case res.Status.Code != rpc.Code_CODE_OK:
+		writeError(w, r, errtypes.InternalError(res.Status.Message))
+		return
+	}
+
+	log.Info().Str("client_id", client.ID).Str("user", c.User.GetOpaqueId()).Strs("scopes", c.Scopes).Msg("oauthapps: token issued")
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, map[string]interface{}{
+		"access_token": res.Token,
+		"token_type":   "Bearer",
+		"expires_in":   client.TokenLifetime,
+		"scope":        strings.Join(c.Scopes, " "),
+	})
+}
+
+func (s *svc) handleListClients(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	u := ctxpkg.ContextMustGetUser(ctx)
+
+	clients, err := s.apps.ListClients(ctx)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	// admins review all the clients, the users only see the ones they registered
+	list := make([]*oauthapps.Client, 0, len(clients))
+	for _, c := range clients {
+		if s.isAdmin(u) || utils.UserEqual(c.Owner, u.Id) {
+			list = append(list, withoutSecret(c))
+		}
+	}
+	writeJSON(w, r, list)
+}
+
+func (s *svc) handleReview(approve bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := appctx.GetLogger(ctx)
+		admin := ctxpkg.ContextMustGetUser(ctx)
+
+		if !s.isAdmin(admin) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		client, err := s.apps.GetClient(ctx, chi.URLParam(r, "id"))
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		if approve {
+			if l := r.FormValue("token_lifetime"); l != "" {
+				lifetime, err := strconv.ParseInt(l, 10, 64)
+				if err != nil || lifetime <= 0 {
+					http.Error(w, "invalid token lifetime", http.StatusBadRequest)
+					return
+				}
+				client.TokenLifetime = lifetime
+			}
+			if client.TokenLifetime > s.conf.MaxTokenLifetime {
+				client.TokenLifetime = s.conf.MaxTokenLifetime
+			}
+			client.State = oauthapps.StateApproved
+		} else {
+			client.State = oauthapps.StateRejected
+		}
+		if err := s.apps.UpdateClient(ctx, client); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		log.Info().Str("client_id", client.ID).Str("admin", admin.Username).Str("state", string(client.State)).
+			Int64("token_lifetime", client.TokenLifetime).Msg("oauthapps: client reviewed")
+		writeJSON(w, r, withoutSecret(client))
+	}
+}
+
+func (s *svc) handleDeleteClient(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+	u := ctxpkg.ContextMustGetUser(ctx)
+
+	client, err := s.apps.GetClient(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !s.isAdmin(u) && !utils.UserEqual(client.Owner, u.Id) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err := s.apps.DeleteClient(ctx, client.ID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	log.Info().Str("client_id", client.ID).Str("by", u.Username).Msg("oauthapps: client deleted")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *svc) handleListConsents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	u := ctxpkg.ContextMustGetUser(ctx)
+
+	consents, err := s.apps.ListConsents(ctx, u.Id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, consents)
+}
+
+func (s *svc) handleDeleteConsent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+	u := ctxpkg.ContextMustGetUser(ctx)
+
+	clientID := chi.URLParam(r, "id")
+	if err := s.apps.DeleteConsent(ctx, clientID, u.Id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	log.Info().Str("client_id", clientID).Str("user", u.Username).Msg("oauthapps: consent withdrawn")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// addAuthorization keeps an authorization request until the user decides on it, for at most 10 minutes.
+func (s *svc) addAuthorization(a *authorization) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for id, p := range s.authorizations {
+		if now.After(p.expiresAt) {
+			delete(s.authorizations, id)
+		}
+	}
+	id := uuid.NewString()
+	a.expiresAt = now.Add(10 * time.Minute)
+	s.authorizations[id] = a
+	return id
+}
+
+func (s *svc) takeAuthorization(id string) (*authorization, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	a, ok := s.authorizations[id]
+	if !ok || time.Now().After(a.expiresAt) {
+		return nil, false
+	}
+	delete(s.authorizations, id)
+	return a, true
+}
+
+var consentTemplate = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Authorize {{.Client}}</title></head>
+<body>
+<h1>Authorize {{.Client}}</h1>
+<p>{{.Client}} wants to access your account {{.User}} to:</p>
+<ul>
+{{range .Scopes}}<li>{{if eq . "dav:read"}}read your files{{else if eq . "shares:manage"}}manage your shares and public links{{else}}{{.}}{{end}}</li>
+{{end}}</ul>
+<form method="post">
+<input type="hidden" name="authorization" value="{{.ID}}">
+<button type="submit" name="decision" value="allow">Allow</button>
+<button type="submit" name="decision" value="deny">Deny</button>
+</form>
+</body>
+</html>
+`))
+
+func redirect(w http.ResponseWriter, r *http.Request, uri string, params url.Values) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func redirectError(w http.ResponseWriter, r *http.Request, uri, state, code string) {
+	params := url.Values{}
+	params.Set("error", code)
+	if state != "" {
+		params.Set("state", state)
+	}
+	redirect(w, r, uri, params)
+}
+
+// withoutSecret returns a copy of the client which can be sent to the users.
+func withoutSecret(c *oauthapps.Client) *oauthapps.Client {
+	client := *c
+	client.Secret = ""
+	return &client
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	body := map[string]string{"error": code}
+	if description != "" {
+		body["error_description"] = description
+	}
+	js, _ := json.Marshal(body)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_, _ = w.Write(js)
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(js); err != nil {
+		appctx.GetLogger(r.Context()).Error().Err(err).Msg("oauthapps: error writing response")
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if _, ok := err.(errtypes.IsNotFound); ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	appctx.GetLogger(r.Context()).Error().Err(err).Msg("oauthapps: error handling request")
+	w.WriteHeader(http.StatusInternalServerError)
+}
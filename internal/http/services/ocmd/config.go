@@ -68,7 +68,7 @@ func (h *configHandler) init(c *Config) {
 	}
 	h.c.ResourceTypes = []resourceTypes{{
 		Name:       "file",
-		ShareTypes: []string{"user"},
+		ShareTypes: []string{"user", "group"},
 		Protocols: resourceTypesProtocols{
 			Webdav: fmt.Sprintf("/%s/ocm_webdav", h.c.Provider),
 		},
@@ -19,27 +19,98 @@
 package ocmd
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/cs3org/reva/pkg/appctx"
-	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/ocm/notifier"
+	"github.com/cs3org/reva/pkg/ocm/notifier/registry"
 )
 
 type notificationsHandler struct {
+	notifier notifier.Notifier // nil if the notifications are not forwarded
 }
 
 func (h *notificationsHandler) init(c *Config) {
 }
 
+func getNotifier(c *Config) (notifier.Notifier, error) {
+	if c.Notifier == "" {
+		return nil, nil
+	}
+	f, ok := registry.NewFuncs[c.Notifier]
+	if !ok {
+		return nil, fmt.Errorf("ocmd: notifier not found: %s", c.Notifier)
+	}
+	return f(c.Notifiers[c.Notifier])
+}
+
+// notify forwards a notification in the background, not to delay the
+// response to the remote provider.
+func notify(ctx context.Context, nt notifier.Notifier, n *notifier.Notification) {
+	if nt == nil {
+		return
+	}
+	log := appctx.GetLogger(ctx)
+	n.Time = time.Now()
+	go func() {
+		if err := nt.Notify(context.Background(), n); err != nil {
+			log.Error().Err(err).Str("type", string(n.Type)).Str("provider_id", n.ProviderID).Msg("error forwarding ocm notification")
+		}
+	}()
+}
+
 func (h *notificationsHandler) Handler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log := appctx.GetLogger(r.Context())
+		switch r.Method {
+		case http.MethodPost:
+			h.receiveNotification(w, r)
+		default:
+			WriteError(w, r, APIErrorInvalidParameter, "Only POST method is allowed", nil)
+		}
+	})
+}
 
-		var head string
-		head, r.URL.Path = router.ShiftPath(r.URL.Path)
+// receiveNotification handles the notifications sent by the remote
+// providers about the shares, like a share created here being accepted by
+// its recipient.
+func (h *notificationsHandler) receiveNotification(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
 
-		log.Debug().Str("head", head).Str("tail", r.URL.Path).Msg("http routing")
+	var req struct {
+		NotificationType string                 `json:"notificationType"`
+		ResourceType     string                 `json:"resourceType"`
+		ProviderID       string                 `json:"providerId"`
+		Notification     map[string]interface{} `json:"notification"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, r, APIErrorInvalidParameter, "could not parse json request body", nil)
+		return
+	}
+	if req.NotificationType == "" || req.ProviderID == "" {
+		WriteError(w, r, APIErrorInvalidParameter, "missing notification parameters", nil)
+		return
+	}
 
-		w.WriteHeader(http.StatusOK)
-	})
+	n := &notifier.Notification{
+		Type:         notifier.Type(req.NotificationType),
+		ResourceType: req.ResourceType,
+		ProviderID:   req.ProviderID,
+		Details:      req.Notification,
+	}
+	if n.Details != nil {
+		if sender, ok := n.Details["sender"].(string); ok {
+			n.Sender = sender
+		}
+		// the secret of the share must not leave the service
+		delete(n.Details, "sharedSecret")
+	}
+	log.Info().Str("type", req.NotificationType).Str("provider_id", req.ProviderID).Msg("ocm notification received")
+	notify(ctx, h.notifier, n)
+
+	w.WriteHeader(http.StatusCreated)
 }
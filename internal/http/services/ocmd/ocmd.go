@@ -22,6 +22,8 @@ import (
 	"net/http"
 
 	"github.com/cs3org/reva/pkg/appctx"
+	_ "github.com/cs3org/reva/pkg/ocm/groupmapping/loader" // Load the group mapping drivers
+	_ "github.com/cs3org/reva/pkg/ocm/notifier/loader"     // Load the notifiers
 	"github.com/cs3org/reva/pkg/rhttp/global"
 	"github.com/cs3org/reva/pkg/rhttp/router"
 	"github.com/cs3org/reva/pkg/sharedconf"
@@ -42,6 +44,14 @@ type Config struct {
 	GatewaySvc       string                      `mapstructure:"gatewaysvc"`
 	MeshDirectoryURL string                      `mapstructure:"mesh_directory_url"`
 	Config           configData                  `mapstructure:"config"`
+	// GroupMapper maps the remote groups of incoming group shares to local groups.
+	GroupMapper  string                            `mapstructure:"group_mapper"`
+	GroupMappers map[string]map[string]interface{} `mapstructure:"group_mappers"`
+	// Notifier forwards the incoming shares and the notifications of the remote providers.
+	Notifier  string                            `mapstructure:"notifier"`
+	Notifiers map[string]map[string]interface{} `mapstructure:"notifiers"`
+	// Sharees controls the searches of the local users by the other sites of the mesh.
+	Sharees shareesConfig `mapstructure:"sharees"`
 }
 
 func (c *Config) init() {
@@ -50,6 +60,9 @@ func (c *Config) init() {
 	if c.Prefix == "" {
 		c.Prefix = "ocm"
 	}
+	if c.GroupMapper == "" {
+		c.GroupMapper = "static"
+	}
 }
 
 type svc struct {
@@ -59,6 +72,7 @@ type svc struct {
 	ConfigHandler        *configHandler
 	InvitesHandler       *invitesHandler
 	SendHandler          *sendHandler
+	ShareesHandler       *shareesHandler
 }
 
 // New returns a new ocmd object
@@ -78,13 +92,22 @@ func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error)
 	s.ConfigHandler = new(configHandler)
 	s.InvitesHandler = new(invitesHandler)
 	s.SendHandler = new(sendHandler)
-	s.SharesHandler.init(s.Conf)
+	s.ShareesHandler = new(shareesHandler)
+	if err := s.SharesHandler.init(s.Conf); err != nil {
+		return nil, err
+	}
 	s.NotificationsHandler.init(s.Conf)
+	nt, err := getNotifier(s.Conf)
+	if err != nil {
+		return nil, err
+	}
+	s.SharesHandler.notifier, s.NotificationsHandler.notifier = nt, nt
 	log.Debug().Str("initializing ConfigHandler Host", s.Conf.Host)
 
 	s.ConfigHandler.init(s.Conf)
 	s.InvitesHandler.init(s.Conf)
 	s.SendHandler.init(s.Conf)
+	s.ShareesHandler.init(s.Conf)
 
 	return s, nil
 }
@@ -99,7 +122,7 @@ func (s *svc) Prefix() string {
 }
 
 func (s *svc) Unprotected() []string {
-	return []string{"/invites/accept", "/shares", "/ocm-provider", "/notifications"}
+	return []string{"/invites/accept", "/shares", "/ocm-provider", "/notifications", "/sharees"}
 }
 
 func (s *svc) Handler() http.Handler {
@@ -127,6 +150,10 @@ func (s *svc) Handler() http.Handler {
 			return
 		case "send":
 			s.SendHandler.Handler().ServeHTTP(w, r)
+			return
+		case "sharees":
+			s.ShareesHandler.Handler().ServeHTTP(w, r)
+			return
 		}
 
 		log.Warn().Msg("request not handled")
@@ -0,0 +1,165 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	ocmprovider "github.com/cs3org/go-cs3apis/cs3/ocm/provider/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/ocm/directory"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+// shareesConfig holds the privacy settings of the searches of the local users by the other sites of the mesh.
+type shareesConfig struct {
+	// Enabled must be set for the site to take part in the mesh directory.
+	Enabled         bool `mapstructure:"enabled"`
+	MinSearchLength int  `mapstructure:"min_search_length" docs:"3"`
+	MaxResults      int  `mapstructure:"max_results" docs:"10"`
+	// ExposeMail returns the mails of the users found.
+	ExposeMail bool `mapstructure:"expose_mail"`
+	// ExactMatch only returns the users whose username or mail is the search term,
+	// so that the users can't be enumerated.
+	ExactMatch bool `mapstructure:"exact_match"`
+}
+
+func (c *shareesConfig) init() {
+	if c.MinSearchLength <= 0 {
+		c.MinSearchLength = 3
+	}
+	if c.MaxResults <= 0 {
+		c.MaxResults = 10
+	}
+}
+
+type shareesHandler struct {
+	gatewayAddr string
+	c           shareesConfig
+}
+
+func (h *shareesHandler) init(c *Config) {
+	h.gatewayAddr = c.GatewaySvc
+	h.c = c.Sharees
+	h.c.init()
+}
+
+func (h *shareesHandler) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.findSharees(w, r)
+		default:
+			WriteError(w, r, APIErrorInvalidParameter, "Only GET method is allowed", nil)
+		}
+	})
+}
+
+// findSharees serves the searches of the local users sent by the other sites of the mesh.
+func (h *shareesHandler) findSharees(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	if !h.c.Enabled {
+		WriteError(w, r, APIErrorUnimplemented, "the users of this site are not listed in the mesh directory", nil)
+		return
+	}
+
+	term, meshProvider := strings.TrimSpace(r.FormValue("search")), r.FormValue("meshProvider")
+	if meshProvider == "" {
+		WriteError(w, r, APIErrorInvalidParameter, "missing meshProvider", nil)
+		return
+	}
+	if len([]rune(term)) < h.c.MinSearchLength {
+		WriteError(w, r, APIErrorInvalidParameter, fmt.Sprintf("search must be at least %d characters long", h.c.MinSearchLength), nil)
+		return
+	}
+
+	gatewayClient, err := pool.GetGatewayServiceClient(pool.Endpoint(h.gatewayAddr))
+	if err != nil {
+		WriteError(w, r, APIErrorServerError, "error getting gateway grpc client", err)
+		return
+	}
+
+	clientIP, err := utils.GetClientIP(r)
+	if err != nil {
+		WriteError(w, r, APIErrorServerError, fmt.Sprintf("error retrieving client IP from request: %s", r.RemoteAddr), err)
+		return
+	}
+	providerAllowedResp, err := gatewayClient.IsProviderAllowed(ctx, &ocmprovider.IsProviderAllowedRequest{
+		Provider: &ocmprovider.ProviderInfo{
+			Domain:   meshProvider,
+			Services: []*ocmprovider.Service{{Host: clientIP}},
+		},
+	})
+	if err != nil {
+		WriteError(w, r, APIErrorServerError, "error sending a grpc is provider allowed request", err)
+		return
+	}
+	if providerAllowedResp.Status.Code != rpc.Code_CODE_OK {
+		WriteError(w, r, APIErrorUnauthenticated, "provider not authorized", errors.New(providerAllowedResp.Status.Message))
+		return
+	}
+
+	usersRes, err := gatewayClient.FindUsers(ctx, &userpb.FindUsersRequest{Filter: term, SkipFetchingUserGroups: true})
+	if err != nil {
+		WriteError(w, r, APIErrorServerError, "error searching users", err)
+		return
+	}
+	if usersRes.Status.Code != rpc.Code_CODE_OK {
+		WriteError(w, r, APIErrorServerError, "error searching users", errors.New(usersRes.Status.Message))
+		return
+	}
+
+	sharees := make([]*directory.Sharee, 0, h.c.MaxResults)
+	for _, u := range usersRes.Users {
+		if len(sharees) == h.c.MaxResults {
+			break
+		}
+		// guests and the federated users of other sites can't receive shares through this site
+		if u.Id.GetType() != userpb.UserType_USER_TYPE_PRIMARY {
+			continue
+		}
+		if h.c.ExactMatch && !strings.EqualFold(u.Username, term) && !strings.EqualFold(u.Mail, term) {
+			continue
+		}
+		s := &directory.Sharee{
+			UserID:      u.Id.OpaqueId,
+			Idp:         u.Id.Idp,
+			DisplayName: u.DisplayName,
+		}
+		if h.c.ExposeMail {
+			s.Mail = u.Mail
+		}
+		sharees = append(sharees, s)
+	}
+	log.Debug().Str("provider", meshProvider).Int("count", len(sharees)).Msg("mesh directory search served")
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(sharees); err != nil {
+		log.Err(err).Msg("error writing sharees")
+	}
+}
@@ -30,6 +30,7 @@ import (
 	"strings"
 	"time"
 
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	ocmcore "github.com/cs3org/go-cs3apis/cs3/ocm/core/v1beta1"
 	ocmprovider "github.com/cs3org/go-cs3apis/cs3/ocm/provider/v1beta1"
@@ -37,16 +38,37 @@ import (
 	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/ocm/groupmapping"
+	"github.com/cs3org/reva/pkg/ocm/groupmapping/registry"
+	"github.com/cs3org/reva/pkg/ocm/notifier"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/utils"
 )
 
+const (
+	shareTypeUser  = "user"
+	shareTypeGroup = "group"
+)
+
 type sharesHandler struct {
 	gatewayAddr string
+	groupMapper groupmapping.Mapper
+	notifier    notifier.Notifier // nil if the incoming shares are not notified
 }
 
-func (h *sharesHandler) init(c *Config) {
+func (h *sharesHandler) init(c *Config) error {
 	h.gatewayAddr = c.GatewaySvc
+
+	f, ok := registry.NewFuncs[c.GroupMapper]
+	if !ok {
+		return fmt.Errorf("ocmd: group mapper not found: %s", c.GroupMapper)
+	}
+	gm, err := f(c.GroupMappers[c.GroupMapper])
+	if err != nil {
+		return err
+	}
+	h.groupMapper = gm
+	return nil
 }
 
 func (h *sharesHandler) Handler() http.Handler {
@@ -65,7 +87,7 @@ func (h *sharesHandler) createShare(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := appctx.GetLogger(ctx)
 	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
-	var shareWith, meshProvider, resource, providerID, owner string
+	var shareWith, shareType, meshProvider, resource, providerID, owner string
 	var protocol map[string]interface{}
 	if err == nil && contentType == "application/json" {
 		defer r.Body.Close()
@@ -77,6 +99,7 @@ func (h *sharesHandler) createShare(w http.ResponseWriter, r *http.Request) {
 				meshProvider = reqMap["meshProvider"].(string) // FIXME: get this from sharedBy string?
 				shareWith, protocol = reqMap["shareWith"].(string), reqMap["protocol"].(map[string]interface{})
 				resource, owner = reqMap["name"].(string), reqMap["owner"].(string)
+				shareType, _ = reqMap["shareType"].(string)
 				// Note that if an OCM request were to go directly from a Nextcloud server
 				// to a Reva server, it will (incorrectly) sends an integer provider_id instead a string one.
 				// This doesn't happen when using the sciencemesh-nextcloud app, but in order to make the OCM
@@ -94,6 +117,7 @@ func (h *sharesHandler) createShare(w http.ResponseWriter, r *http.Request) {
 		var protocolJSON string
 		shareWith, protocolJSON, meshProvider = r.FormValue("shareWith"), r.FormValue("protocol"), r.FormValue("meshProvider")
 		resource, providerID, owner = r.FormValue("name"), r.FormValue("providerId"), r.FormValue("owner")
+		shareType = r.FormValue("shareType")
 		err = json.Unmarshal([]byte(protocolJSON), &protocol)
 		if err != nil {
 			WriteError(w, r, APIErrorInvalidParameter, "invalid protocol parameters", nil)
@@ -142,15 +166,32 @@ func (h *sharesHandler) createShare(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var shareWithParts []string = strings.Split(shareWith, "@")
-	userRes, err := gatewayClient.GetUser(ctx, &userpb.GetUserRequest{
-		UserId: &userpb.UserId{OpaqueId: shareWithParts[0]}, SkipFetchingUserGroups: true,
-	})
-	if err != nil {
-		WriteError(w, r, APIErrorServerError, "error searching recipient", err)
-		return
-	}
-	if userRes.Status.Code != rpc.Code_CODE_OK {
-		WriteError(w, r, APIErrorNotFound, "user not found", errors.New(userRes.Status.Message))
+	var recipient *userpb.UserId
+	var recipientMail string
+	var group *grouppb.GroupId
+	switch shareType {
+	case shareTypeGroup:
+		// remote groups are mapped to local ones, whose members will receive the share
+		group, err = h.groupMapper.MapGroup(ctx, shareWithParts[0], meshProvider)
+		if err != nil {
+			WriteError(w, r, APIErrorNotFound, "group not found", err)
+			return
+		}
+	case "", shareTypeUser:
+		userRes, err := gatewayClient.GetUser(ctx, &userpb.GetUserRequest{
+			UserId: &userpb.UserId{OpaqueId: shareWithParts[0]}, SkipFetchingUserGroups: true,
+		})
+		if err != nil {
+			WriteError(w, r, APIErrorServerError, "error searching recipient", err)
+			return
+		}
+		if userRes.Status.Code != rpc.Code_CODE_OK {
+			WriteError(w, r, APIErrorNotFound, "user not found", errors.New(userRes.Status.Message))
+			return
+		}
+		recipient, recipientMail = userRes.User.GetId(), userRes.User.GetMail()
+	default:
+		WriteError(w, r, APIErrorInvalidParameter, "share type not supported: "+shareType, nil)
 		return
 	}
 
@@ -198,7 +239,7 @@ func (h *sharesHandler) createShare(w http.ResponseWriter, r *http.Request) {
 		Name:       resource,
 		ProviderId: providerID,
 		Owner:      ownerID,
-		ShareWith:  userRes.User.GetId(),
+		ShareWith:  recipient,
 		Protocol: &ocmcore.Protocol{
 			Name: protocol["name"].(string),
 			Opaque: &types.Opaque{
@@ -215,6 +256,12 @@ func (h *sharesHandler) createShare(w http.ResponseWriter, r *http.Request) {
 			},
 		},
 	}
+	if group != nil {
+		createShareReq.Protocol.Opaque.Map["groupId"] = &types.OpaqueEntry{
+			Decoder: "plain",
+			Value:   []byte(group.OpaqueId),
+		}
+	}
 	createShareResponse, err := gatewayClient.CreateOCMCoreShare(ctx, createShareReq)
 	if err != nil {
 		WriteError(w, r, APIErrorServerError, "error sending a grpc create ocm core share request", err)
@@ -229,6 +276,20 @@ func (h *sharesHandler) createShare(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	n := &notifier.Notification{
+		Type:          notifier.ShareReceived,
+		ProviderID:    providerID,
+		ShareName:     resource,
+		Owner:         owner + "@" + meshProvider,
+		Recipient:     recipient.GetOpaqueId(),
+		RecipientMail: recipientMail,
+		Sender:        meshProvider,
+	}
+	if group != nil {
+		n.Recipient = group.OpaqueId
+	}
+	notify(ctx, h.notifier, n)
+
 	timeCreated := createShareResponse.Created
 	jsonOut, err := json.Marshal(
 		map[string]string{
@@ -0,0 +1,88 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/publicshare/analytics"
+	analyticsregistry "github.com/cs3org/reva/pkg/publicshare/analytics/registry"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+)
+
+func getAnalytics(c *analytics.Config) (*analytics.Analytics, error) {
+	f, ok := analyticsregistry.NewFuncs[c.Driver]
+	if !ok {
+		return nil, errtypes.NotFound("analytics driver not found: " + c.Driver)
+	}
+	m, err := f(c.Drivers[c.Driver])
+	if err != nil {
+		return nil, err
+	}
+	return analytics.New(c, m)
+}
+
+// statusRecorder keeps the status of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// servePublic serves a request to a public link and counts it in the
+// analytics of the link: listing its root is a view, getting a file in full
+// a download. fileName is the name of the shared file, empty for folders.
+func (s *svc) servePublic(w http.ResponseWriter, r *http.Request, h http.Handler, token, fileName string) {
+	if s.analytics == nil {
+		h.ServeHTTP(w, r)
+		return
+	}
+
+	// the path relative to the link
+	_, p := router.ShiftPath(r.URL.Path)
+	var kind analytics.Kind
+	switch {
+	case r.Method == MethodPropfind && p == "/":
+		kind = analytics.View
+	case r.Method == http.MethodGet && !isPreviewRequest(r):
+		kind = analytics.Download
+		if fileName != "" {
+			p = path.Join("/", fileName)
+		}
+	default:
+		h.ServeHTTP(w, r)
+		return
+	}
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	h.ServeHTTP(rec, r)
+	if rec.status != http.StatusOK && rec.status != http.StatusMultiStatus {
+		return
+	}
+	if err := s.analytics.Record(r.Context(), token, kind, p, r.RemoteAddr); err != nil {
+		appctx.GetLogger(r.Context()).Error().Err(err).Str("token", token).Msg("error recording public link access")
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// uploadChecksum is a checksum sent by a client to have the upload verified.
+type uploadChecksum struct {
+	algo string // lowercase, one of md5, sha1 or adler32
+	sum  []byte
+}
+
+// parseUploadChecksum parses the TUS style '[algorithm] [base64 checksum]' format of the Upload-Checksum header.
+func parseUploadChecksum(v string) (*uploadChecksum, error) {
+	parts := strings.SplitN(v, " ", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid Upload-Checksum format, expected '[algorithm] [checksum]'")
+	}
+	sum, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid Upload-Checksum, the checksum is not base64 encoded")
+	}
+	return newUploadChecksum(parts[0], sum)
+}
+
+// parseOCChecksum parses the ownCloud style '[ALGORITHM]:[hex checksum]' format of the OC-Checksum header.
+// The '[algorithm] [hex checksum]' format sent in the checksum metadata of TUS uploads is accepted as well.
+func parseOCChecksum(v string) (*uploadChecksum, error) {
+	parts := strings.SplitN(v, ":", 2)
+	if len(parts) != 2 {
+		parts = strings.SplitN(v, " ", 2)
+	}
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid OC-Checksum format, expected '[algorithm]:[checksum]'")
+	}
+	h := strings.ToLower(parts[1])
+	// some storages, like EOS, drop the leading zeros of adler32 checksums
+	if strings.EqualFold(parts[0], "adler32") && len(h) < 8 {
+		h = strings.Repeat("0", 8-len(h)) + h
+	}
+	sum, err := hex.DecodeString(h)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OC-Checksum, the checksum is not hex encoded")
+	}
+	return newUploadChecksum(parts[0], sum)
+}
+
+func newUploadChecksum(algo string, sum []byte) (*uploadChecksum, error) {
+	algo = strings.ToLower(algo)
+	switch algo {
+	case "md5", "sha1", "adler32":
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm %q, expected one of md5, sha1 or adler32", algo)
+	}
+	return &uploadChecksum{algo: algo, sum: sum}, nil
+}
+
+// header returns the checksum in the format of the Upload-Checksum header verified by the data server.
+func (c *uploadChecksum) header() string {
+	return c.algo + " " + base64.StdEncoding.EncodeToString(c.sum)
+}
+
+// opaque returns the checksum in the '[algorithm] [hex checksum]' format verified by the storage drivers.
+func (c *uploadChecksum) opaque() string {
+	return c.algo + " " + hex.EncodeToString(c.sum)
+}
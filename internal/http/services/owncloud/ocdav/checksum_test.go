@@ -0,0 +1,65 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"testing"
+)
+
+func TestParseChecksum(t *testing.T) {
+	tests := []struct {
+		name   string
+		parse  func(string) (*uploadChecksum, error)
+		value  string
+		opaque string
+		header string
+	}{
+		{"upload checksum", parseUploadChecksum, "sha1 qvTGHdzF6KLavt4PO0gs2a6pQ00=", "sha1 aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d", "sha1 qvTGHdzF6KLavt4PO0gs2a6pQ00="},
+		{"oc checksum", parseOCChecksum, "MD5:5D41402ABC4B2A76B9719D911017C592", "md5 5d41402abc4b2a76b9719d911017c592", "md5 XUFAKrxLKna5cZ2REBfFkg=="},
+		{"oc checksum without leading zeros", parseOCChecksum, "ADLER32:62c0215", "adler32 062c0215", "adler32 BiwCFQ=="},
+		{"tus metadata checksum", parseOCChecksum, "sha1 aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d", "sha1 aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d", "sha1 qvTGHdzF6KLavt4PO0gs2a6pQ00="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := tt.parse(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.opaque() != tt.opaque {
+				t.Errorf("expected opaque %q, got %q", tt.opaque, c.opaque())
+			}
+			if c.header() != tt.header {
+				t.Errorf("expected header %q, got %q", tt.header, c.header())
+			}
+		})
+	}
+}
+
+func TestParseChecksumInvalid(t *testing.T) {
+	for _, v := range []string{"sha1", "crc32 AAAA", "md5 not-base64!"} {
+		if _, err := parseUploadChecksum(v); err == nil {
+			t.Errorf("expected Upload-Checksum %q to be rejected", v)
+		}
+	}
+	for _, v := range []string{"SHA1", "CRC32:0000", "MD5:xyz"} {
+		if _, err := parseOCChecksum(v); err == nil {
+			t.Errorf("expected OC-Checksum %q to be rejected", v)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"context"
+	"path"
+	"strings"
+)
+
+// strictCompliance returns whether the request is served by a route configured
+// for strict RFC 4918 compliance instead of the ownCloud client compatible behavior.
+func (s *svc) strictCompliance(ctx context.Context) bool {
+	base, ok := ctx.Value(ctxKeyBaseURI).(string)
+	if !ok {
+		return false
+	}
+	base = strings.TrimPrefix(base, path.Join("/", s.Prefix()))
+	for _, route := range s.c.StrictComplianceRoutes {
+		route = path.Join("/", route)
+		if base == route || strings.HasPrefix(base, route+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// davProps filters the properties in the DAV: namespace. An allprop PROPFIND only
+// returns the live properties defined by RFC 4918 and dead properties, see
+// https://tools.ietf.org/html/rfc4918#section-9.1
+func davProps(props []*propertyXML) []*propertyXML {
+	filtered := make([]*propertyXML, 0, len(props))
+	for _, p := range props {
+		if strings.HasPrefix(p.XMLName.Local, "d:") {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,59 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStrictCompliance(t *testing.T) {
+	s := &svc{c: &Config{Prefix: "ocdav", StrictComplianceRoutes: []string{"remote.php/webdav", "/dav/spaces"}}}
+
+	tests := map[string]bool{
+		"/ocdav/remote.php/webdav":            true,
+		"/ocdav/dav/spaces/123":               true,
+		"/ocdav/remote.php/dav/files/marie":   false,
+		"/ocdav/remote.php/webdavfoo":         false,
+		"/ocdav/dav/public-files/sometoken/a": false,
+	}
+	for base, expected := range tests {
+		ctx := context.WithValue(context.Background(), ctxKeyBaseURI, base)
+		if got := s.strictCompliance(ctx); got != expected {
+			t.Errorf("strictCompliance(%s) = %t, expected %t", base, got, expected)
+		}
+	}
+
+	if s.strictCompliance(context.Background()) {
+		t.Error("expected requests without base uri not to be strict")
+	}
+}
+
+func TestDavProps(t *testing.T) {
+	s := &svc{c: &Config{}}
+	props := davProps([]*propertyXML{
+		s.newProp("oc:id", "1"),
+		s.newProp("d:getetag", "\"1\""),
+		s.newProp("oc:permissions", "RDNVW"),
+		s.newProp("d:resourcetype", ""),
+	})
+	if len(props) != 2 || props[0].XMLName.Local != "d:getetag" || props[1].XMLName.Local != "d:resourcetype" {
+		t.Errorf("unexpected props %v", props)
+	}
+}
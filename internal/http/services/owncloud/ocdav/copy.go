@@ -81,9 +81,12 @@ func (s *svc) handlePathCopy(w http.ResponseWriter, r *http.Request, ns string)
 		}
 	}
 
-	dst = path.Join(ns, dst)
+	dst = destinationPath(ctx, ns, dst)
 
 	sublog := appctx.GetLogger(ctx).With().Str("src", src).Str("dst", dst).Logger()
+	if !s.checkFilename(w, dst, &sublog) {
+		return
+	}
 
 	srcRef := &provider.Reference{Path: src}
 
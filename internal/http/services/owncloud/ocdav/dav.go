@@ -31,6 +31,7 @@ import (
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/publicshare"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/rhttp/router"
 	"google.golang.org/grpc/metadata"
@@ -56,11 +57,11 @@ func (h *DavHandler) init(c *Config) error {
 		return err
 	}
 	h.FilesHandler = new(WebDavHandler)
-	if err := h.FilesHandler.init(c.FilesNamespace, false); err != nil {
+	if err := h.FilesHandler.init(c.FilesNamespace, false, c.NamespaceRules, routeFiles); err != nil {
 		return err
 	}
 	h.FilesHomeHandler = new(WebDavHandler)
-	if err := h.FilesHomeHandler.init(c.WebdavNamespace, true); err != nil {
+	if err := h.FilesHomeHandler.init(c.WebdavNamespace, true, c.NamespaceRules, routeFiles); err != nil {
 		return err
 	}
 	h.MetaHandler = new(MetaHandler)
@@ -75,7 +76,7 @@ func (h *DavHandler) init(c *Config) error {
 	}
 
 	h.PublicFolderHandler = new(WebDavHandler)
-	if err := h.PublicFolderHandler.init("public", true); err != nil { // jail public file requests to /public/ prefix
+	if err := h.PublicFolderHandler.init("public", true, c.NamespaceRules, routePublicFiles); err != nil { // jail public file requests to /public/ prefix
 		return err
 	}
 
@@ -169,6 +170,14 @@ func (h *DavHandler) Handler(s *svc) http.Handler {
 			h.TrashbinHandler.Handler(s).ServeHTTP(w, r)
 		case "spaces":
 			base := path.Join(ctx.Value(ctxKeyBaseURI).(string), "spaces")
+			if next, tail := router.ShiftPath(r.URL.Path); next == "trash-bin" {
+				// the trash of a space, scoped by the space id
+				ctx := context.WithValue(ctx, ctxKeyBaseURI, path.Join(base, "trash-bin"))
+				r = r.WithContext(ctx)
+				r.URL.Path = tail
+				h.TrashbinHandler.SpaceHandler(s).ServeHTTP(w, r)
+				return
+			}
 			ctx := context.WithValue(ctx, ctxKeyBaseURI, base)
 			r = r.WithContext(ctx)
 			h.SpacesHandler.Handler(s).ServeHTTP(w, r)
@@ -216,6 +225,10 @@ func (h *DavHandler) Handler(s *svc) http.Handler {
 			ctx = ctxpkg.ContextSetToken(ctx, res.Token)
 			ctx = ctxpkg.ContextSetUser(ctx, res.User)
 			ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.TokenHeader, res.Token)
+			if pw := r.Header.Get(HeaderFolderPassword); pw != "" {
+				// unlocks the password protected subfolders of the link
+				ctx = ctxpkg.ContextSetFolderPassword(ctx, pw)
+			}
 
 			r = r.WithContext(ctx)
 
@@ -243,12 +256,22 @@ func (h *DavHandler) Handler(s *svc) http.Handler {
 			}
 			log.Debug().Interface("statInfo", sRes.Info).Msg("Stat info from public link token path")
 
+			if publicshare.IsUploadOnly(sRes.Info.PermissionSet) {
+				// a file drop only accepts uploads, its contents are not revealed
+				switch r.Method {
+				case MethodPropfind, http.MethodGet, http.MethodHead:
+					log.Debug().Str("token", token).Str("method", r.Method).Msg("upload only public link")
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+			}
+
 			if sRes.Info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
 				ctx := context.WithValue(ctx, tokenStatInfoKey{}, sRes.Info)
 				r = r.WithContext(ctx)
-				h.PublicFileHandler.Handler(s).ServeHTTP(w, r)
+				s.servePublic(w, r, h.PublicFileHandler.Handler(s), token, path.Base(sRes.Info.Path))
 			} else {
-				h.PublicFolderHandler.Handler(s).ServeHTTP(w, r)
+				s.servePublic(w, r, h.PublicFolderHandler.Handler(s), token, "")
 			}
 
 		default:
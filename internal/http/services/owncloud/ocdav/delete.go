@@ -50,6 +50,17 @@ func (s *svc) handleDelete(ctx context.Context, w http.ResponseWriter, r *http.R
 	ctx, span := rtrace.Provider.Tracer("reva").Start(ctx, "delete")
 	defer span.End()
 
+	// a DELETE on a collection must act as if Depth infinity was used, see https://tools.ietf.org/html/rfc4918#section-9.6.1
+	if depth := r.Header.Get(HeaderDepth); depth != "" && depth != "infinity" && s.strictCompliance(ctx) {
+		w.WriteHeader(http.StatusBadRequest)
+		b, err := Marshal(exception{
+			code:    SabredavBadRequest,
+			message: fmt.Sprintf("Depth header is set to incorrect value %v", depth),
+		})
+		HandleWebdavError(&log, w, b, err)
+		return
+	}
+
 	req := &provider.DeleteRequest{Ref: ref}
 	res, err := client.Delete(ctx, req)
 	if err != nil {
@@ -23,6 +23,7 @@ import (
 	"net/http"
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
@@ -94,6 +95,29 @@ type errorXML struct {
 
 var errInvalidPropfind = errors.New("webdav: invalid propfind")
 
+// retryAfterWriter is implemented by the response writers of the ocdav service, which carry
+// the Retry-After value sent when a storage rejects a write during maintenance.
+type retryAfterWriter interface {
+	RetryAfter() string
+}
+
+// maintenanceWriter wraps the response writer of a request with the configured Retry-After value.
+type maintenanceWriter struct {
+	http.ResponseWriter
+	retryAfter string
+}
+
+func (w *maintenanceWriter) RetryAfter() string {
+	return w.retryAfter
+}
+
+// Flush passes the flushes through, the third-party copies stream their progress.
+func (w *maintenanceWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 // HandleErrorStatus checks the status code, logs a Debug or Error level message
 // and writes an appropriate http status
 func HandleErrorStatus(log *zerolog.Logger, w http.ResponseWriter, s *rpc.Status) {
@@ -120,8 +144,19 @@ func HandleErrorStatus(log *zerolog.Logger, w http.ResponseWriter, s *rpc.Status
 		log.Debug().Interface("status", s).Msg("insufficient storage")
 		w.WriteHeader(http.StatusInsufficientStorage)
 	case rpc.Code_CODE_FAILED_PRECONDITION:
+		if status.IsLocked(s) {
+			log.Debug().Interface("status", s).Msg("resource is locked")
+			w.WriteHeader(http.StatusLocked)
+			return
+		}
 		log.Debug().Interface("status", s).Msg("destination does not exist")
 		w.WriteHeader(http.StatusConflict)
+	case rpc.Code_CODE_UNAVAILABLE:
+		log.Debug().Interface("status", s).Msg("storage unavailable")
+		if rw, ok := w.(retryAfterWriter); ok && status.IsMaintenance(s) {
+			w.Header().Set(HeaderRetryAfter, rw.RetryAfter())
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
 	default:
 		log.Error().Interface("status", s).Msg("grpc request failed")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -0,0 +1,65 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/rs/zerolog"
+)
+
+func TestHandleErrorStatusRetryAfter(t *testing.T) {
+	log := zerolog.Nop()
+	ctx := context.Background()
+
+	rec := httptest.NewRecorder()
+	HandleErrorStatus(&log, &maintenanceWriter{ResponseWriter: rec, retryAfter: "120"}, status.NewMaintenance(ctx))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	if h := rec.Header().Get(HeaderRetryAfter); h != "120" {
+		t.Errorf("expected the configured Retry-After during maintenance, got %q", h)
+	}
+
+	rec = httptest.NewRecorder()
+	HandleErrorStatus(&log, &maintenanceWriter{ResponseWriter: rec, retryAfter: "120"}, status.NewUnavailable(ctx, "storage unreachable"))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	if h := rec.Header().Get(HeaderRetryAfter); h != "" {
+		t.Errorf("expected no Retry-After outside of maintenance, got %q", h)
+	}
+}
+
+func TestMaintenanceWriterFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	var w http.ResponseWriter = &maintenanceWriter{ResponseWriter: rec}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		t.Fatal("expected the wrapped writer to be flushable")
+	}
+	flusher.Flush()
+	if !rec.Flushed {
+		t.Error("expected the flush to be passed through")
+	}
+}
@@ -32,8 +32,10 @@ import (
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/internal/grpc/services/storageprovider"
 	"github.com/cs3org/reva/internal/http/services/datagateway"
+	"github.com/cs3org/reva/pkg/antivirus"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/download"
 	rtrace "github.com/cs3org/reva/pkg/trace"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/cs3org/reva/pkg/utils/resourceid"
@@ -74,6 +76,21 @@ func (s *svc) handleGet(ctx context.Context, w http.ResponseWriter, r *http.Requ
 		log.Warn().Msg("resource is a folder and cannot be downloaded")
 		w.WriteHeader(http.StatusNotImplemented)
 		return
+	case antivirus.StatusFromResourceInfo(sRes.Info) == antivirus.StatusInfected:
+		log.Warn().Str("virus", antivirus.VirusFromResourceInfo(sRes.Info)).Msg("resource is infected and cannot be downloaded")
+		w.Header().Set(download.HeaderBlocked, "infected")
+		w.WriteHeader(http.StatusForbidden)
+		b, err := Marshal(exception{
+			code:    SabredavPermissionDenied,
+			message: "the file is infected and quarantined",
+		})
+		HandleWebdavError(&log, w, b, err)
+		return
+	}
+
+	if s.previews != nil && isPreviewRequest(r) {
+		s.handlePreview(ctx, w, r, client, ref, sRes.Info, dlProtocol, log)
+		return
 	}
 
 	dReq := &provider.InitiateFileDownloadRequest{Ref: ref}
@@ -117,6 +134,9 @@ func (s *svc) handleGet(ctx context.Context, w http.ResponseWriter, r *http.Requ
 	defer httpRes.Body.Close()
 
 	if httpRes.StatusCode != http.StatusOK && httpRes.StatusCode != http.StatusPartialContent {
+		if reason := httpRes.Header.Get(download.HeaderBlocked); reason != "" {
+			w.Header().Set(download.HeaderBlocked, reason)
+		}
 		w.WriteHeader(httpRes.StatusCode)
 		return
 	}
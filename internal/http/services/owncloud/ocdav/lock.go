@@ -19,33 +19,323 @@
 package ocdav
 
 import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
 	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	rtrace "github.com/cs3org/reva/pkg/trace"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
 )
 
-// TODO(jfd) implement lock
-func (s *svc) handleLock(w http.ResponseWriter, r *http.Request, ns string) {
-	log := appctx.GetLogger(r.Context())
-	xml := `<?xml version="1.0" encoding="utf-8"?>
-	<prop xmlns="DAV:">
-		<lockdiscovery>
-			<activelock>
-				<allprop/>
-				<timeout>Second-604800</timeout>
-				<depth>Infinity</depth>
-				<locktoken>
-				<href>opaquelocktoken:00000000-0000-0000-0000-000000000000</href>
-				</locktoken>
-			</activelock>
-		</lockdiscovery>
-	</prop>`
-
-	w.Header().Set("Content-Type", "text/xml; charset=\"utf-8\"")
-	w.Header().Set("Lock-Token",
-		"opaquelocktoken:00000000-0000-0000-0000-000000000000")
-	_, err := w.Write([]byte(xml))
+const (
+	// lockTokenPrefix is the scheme of the lock tokens, see https://tools.ietf.org/html/rfc4918#appendix-C
+	lockTokenPrefix = "opaquelocktoken:"
+	// defaultLockTimeout is used when the client does not ask for a timeout
+	defaultLockTimeout = 30 * time.Minute
+	// maxLockTimeout caps the timeout requested by the clients, including Infinite
+	maxLockTimeout = 7 * 24 * time.Hour
+)
+
+// http://www.webdav.org/specs/rfc4918.html#ELEMENT_lockinfo
+type lockInfoXML struct {
+	XMLName   xml.Name  `xml:"DAV: lockinfo"`
+	Exclusive *struct{} `xml:"lockscope>exclusive"`
+	Shared    *struct{} `xml:"lockscope>shared"`
+	Write     *struct{} `xml:"locktype>write"`
+	Owner     ownerXML  `xml:"owner"`
+}
+
+// http://www.webdav.org/specs/rfc4918.html#ELEMENT_owner
+type ownerXML struct {
+	InnerXML string `xml:",innerxml"`
+}
+
+func (s *svc) handlePathLock(w http.ResponseWriter, r *http.Request, ns string) {
+	ctx := r.Context()
+	fn := path.Join(ns, r.URL.Path)
+
+	sublog := appctx.GetLogger(ctx).With().Str("path", fn).Logger()
+	ref := &provider.Reference{Path: fn}
+	root := path.Join(ctx.Value(ctxKeyBaseURI).(string), r.URL.Path)
+	s.handleLock(ctx, w, r, ref, root, sublog)
+}
+
+func (s *svc) handleSpacesLock(w http.ResponseWriter, r *http.Request, spaceID string) {
+	ctx, span := rtrace.Provider.Tracer("reva").Start(r.Context(), "spaces_lock")
+	defer span.End()
+
+	sublog := appctx.GetLogger(ctx).With().Str("spaceid", spaceID).Str("path", r.URL.Path).Logger()
+
+	ref, status, err := s.lookUpStorageSpaceReference(ctx, spaceID, r.URL.Path)
+	if err != nil {
+		sublog.Error().Err(err).Msg("error sending a grpc request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if status.Code != rpc.Code_CODE_OK {
+		HandleErrorStatus(&sublog, w, status)
+		return
+	}
+
+	root := path.Join(ctx.Value(ctxKeyBaseURI).(string), spaceID, r.URL.Path)
+	s.handleLock(ctx, w, r, ref, root, sublog)
+}
+
+// handleLock creates a new lock or, when the request has no body, refreshes the lock
+// submitted in the If header, see https://tools.ietf.org/html/rfc4918#section-9.10
+func (s *svc) handleLock(ctx context.Context, w http.ResponseWriter, r *http.Request, ref *provider.Reference, root string, log zerolog.Logger) {
+	ctx, span := rtrace.Provider.Tracer("reva").Start(ctx, "lock")
+	defer span.End()
+
+	client, err := s.getClient()
+	if err != nil {
+		log.Error().Err(err).Msg("error getting grpc client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	timeout, err := parseLockTimeout(r.Header.Get(HeaderTimeout))
 	if err != nil {
+		log.Debug().Err(err).Msg("invalid timeout header")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	expiration := &types.Timestamp{Seconds: uint64(time.Now().Add(timeout).Unix())}
+
+	li, err := readLockInfo(r.Body)
+	if err != nil {
+		log.Debug().Err(err).Msg("invalid lockinfo")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if li == nil {
+		// refresh the lock held by the client
+		token, ok := ctxpkg.ContextGetLockID(ctx)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		gRes, err := client.GetLock(ctx, &provider.GetLockRequest{Ref: ref})
+		if err != nil {
+			log.Error().Err(err).Msg("error sending grpc get lock request")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if gRes.Status.Code != rpc.Code_CODE_OK {
+			if gRes.Status.Code == rpc.Code_CODE_NOT_FOUND {
+				// the resource is not locked, the token can not match
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			HandleErrorStatus(&log, w, gRes.Status)
+			return
+		}
+		if gRes.Lock.LockId != token {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+
+		lock := gRes.Lock
+		lock.Expiration = expiration
+		rRes, err := client.RefreshLock(ctx, &provider.RefreshLockRequest{Ref: ref, Lock: lock})
+		if err != nil {
+			log.Error().Err(err).Msg("error sending grpc refresh lock request")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if rRes.Status.Code != rpc.Code_CODE_OK {
+			if rRes.Status.Code == rpc.Code_CODE_FAILED_PRECONDITION {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			HandleErrorStatus(&log, w, rRes.Status)
+			return
+		}
+		writeLockDiscovery(w, http.StatusOK, lock, timeout, "", root, log)
+		return
+	}
+
+	lock := &provider.Lock{
+		LockId:     lockTokenPrefix + uuid.New().String(),
+		Type:       provider.LockType_LOCK_TYPE_WRITE,
+		User:       u.Id,
+		Expiration: expiration,
+	}
+	if li.Shared != nil {
+		lock.Type = provider.LockType_LOCK_TYPE_SHARED
+	}
+
+	// locking an unmapped url creates an empty resource, see https://tools.ietf.org/html/rfc4918#section-7.3
+	code := http.StatusOK
+	sRes, err := client.Stat(ctx, &provider.StatRequest{Ref: ref})
+	if err != nil {
+		log.Error().Err(err).Msg("error sending grpc stat request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	switch sRes.Status.Code {
+	case rpc.Code_CODE_OK:
+	case rpc.Code_CODE_NOT_FOUND:
+		tRes, err := client.TouchFile(ctx, &provider.TouchFileRequest{Ref: ref})
+		if err != nil {
+			log.Error().Err(err).Msg("error sending grpc touch file request")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if tRes.Status.Code != rpc.Code_CODE_OK {
+			if tRes.Status.Code == rpc.Code_CODE_NOT_FOUND {
+				// the parent does not exist
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			HandleErrorStatus(&log, w, tRes.Status)
+			return
+		}
+		code = http.StatusCreated
+	default:
+		HandleErrorStatus(&log, w, sRes.Status)
+		return
+	}
+
+	res, err := client.SetLock(ctx, &provider.SetLockRequest{Ref: ref, Lock: lock})
+	if err != nil {
+		log.Error().Err(err).Msg("error sending grpc set lock request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		if res.Status.Code == rpc.Code_CODE_FAILED_PRECONDITION {
+			// the resource is already locked
+			w.WriteHeader(http.StatusLocked)
+			return
+		}
+		HandleErrorStatus(&log, w, res.Status)
+		return
+	}
+
+	w.Header().Set(HeaderLockToken, "<"+lock.LockId+">")
+	writeLockDiscovery(w, code, lock, timeout, li.Owner.InnerXML, root, log)
+}
+
+// readLockInfo parses the lockinfo of a LOCK request, it returns nil when the body is empty
+func readLockInfo(r io.Reader) (*lockInfoXML, error) {
+	li := &lockInfoXML{}
+	if err := xml.NewDecoder(r).Decode(li); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if (li.Exclusive == nil) == (li.Shared == nil) || li.Write == nil {
+		return nil, fmt.Errorf("lockinfo must have one lockscope and a write locktype")
+	}
+	return li, nil
+}
+
+// parseLockTimeout parses the Timeout header, see https://tools.ietf.org/html/rfc4918#section-10.7
+// Only the first of the proposed timeouts is considered.
+func parseLockTimeout(h string) (time.Duration, error) {
+	if h == "" {
+		return defaultLockTimeout, nil
+	}
+	h = strings.TrimSpace(strings.Split(h, ",")[0])
+	if h == "Infinite" {
+		return maxLockTimeout, nil
+	}
+	if !strings.HasPrefix(h, "Second-") {
+		return 0, fmt.Errorf("invalid timeout %s", h)
+	}
+	sec, err := strconv.ParseUint(strings.TrimPrefix(h, "Second-"), 10, 32)
+	if err != nil || sec == 0 {
+		return 0, fmt.Errorf("invalid timeout %s", h)
+	}
+	if t := time.Duration(sec) * time.Second; t < maxLockTimeout {
+		return t, nil
+	}
+	return maxLockTimeout, nil
+}
+
+func writeLockDiscovery(w http.ResponseWriter, code int, lock *provider.Lock, timeout time.Duration, owner, root string, log zerolog.Logger) {
+	scope := "<d:exclusive/>"
+	if lock.Type == provider.LockType_LOCK_TYPE_SHARED {
+		scope = "<d:shared/>"
+	}
+	if owner != "" {
+		owner = "<d:owner>" + owner + "</d:owner>"
+	}
+	var href strings.Builder
+	_ = xml.EscapeText(&href, []byte(root))
+
+	b := xml.Header +
+		`<d:prop xmlns:d="DAV:"><d:lockdiscovery><d:activelock>` +
+		`<d:locktype><d:write/></d:locktype>` +
+		`<d:lockscope>` + scope + `</d:lockscope>` +
+		`<d:depth>0</d:depth>` +
+		owner +
+		`<d:timeout>Second-` + strconv.FormatInt(int64(timeout/time.Second), 10) + `</d:timeout>` +
+		`<d:locktoken><d:href>` + lock.LockId + `</d:href></d:locktoken>` +
+		`<d:lockroot><d:href>` + href.String() + `</d:href></d:lockroot>` +
+		`</d:activelock></d:lockdiscovery></d:prop>`
+
+	w.Header().Set(HeaderContentType, "application/xml; charset=utf-8")
+	w.WriteHeader(code)
+	if _, err := w.Write([]byte(b)); err != nil {
 		log.Err(err).Msg("error writing response")
 	}
 }
+
+// lockTokenFromIfHeader returns the first lock token submitted in the If header,
+// see https://tools.ietf.org/html/rfc4918#section-10.4. Tokens in a Not condition are skipped.
+func lockTokenFromIfHeader(h string) string {
+	depth, not := 0, false
+	for i := 0; i < len(h); i++ {
+		switch c := h[i]; {
+		case c == '(':
+			depth++
+			not = false
+		case c == ')':
+			depth--
+		case c == '[':
+			// skip entity tags, they may contain any character
+			if j := strings.IndexByte(h[i:], ']'); j >= 0 {
+				i += j
+			}
+		case c == '<':
+			j := strings.IndexByte(h[i:], '>')
+			if j < 0 {
+				return ""
+			}
+			token := h[i+1 : i+j]
+			i += j
+			// tagged lists start with the resource url outside of the parentheses
+			if depth > 0 && !not && strings.HasPrefix(token, lockTokenPrefix) {
+				return token
+			}
+			not = false
+		case depth > 0 && strings.HasPrefix(h[i:], "Not"):
+			not = true
+			i += 2
+		}
+	}
+	return ""
+}
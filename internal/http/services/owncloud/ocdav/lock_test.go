@@ -0,0 +1,98 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLockTokenFromIfHeader(t *testing.T) {
+	tests := []struct {
+		header, expected string
+	}{
+		{"", ""},
+		{"(<opaquelocktoken:a515cfa4>)", "opaquelocktoken:a515cfa4"},
+		{`(["I am an ETag"]) (<opaquelocktoken:a515cfa4> ["etag"])`, "opaquelocktoken:a515cfa4"},
+		{"<http://example.com/locked/> (<opaquelocktoken:a515cfa4>)", "opaquelocktoken:a515cfa4"},
+		{"(Not <opaquelocktoken:a515cfa4>) (<opaquelocktoken:e71d4fae>)", "opaquelocktoken:e71d4fae"},
+		{"(<DAV:no-lock>)", ""},
+		{"<opaquelocktoken:a515cfa4>", ""},
+	}
+	for _, tt := range tests {
+		if token := lockTokenFromIfHeader(tt.header); token != tt.expected {
+			t.Errorf("lockTokenFromIfHeader(%q) = %q, expected %q", tt.header, token, tt.expected)
+		}
+	}
+}
+
+func TestParseLockTimeout(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected time.Duration
+		err      bool
+	}{
+		{header: "", expected: defaultLockTimeout},
+		{header: "Second-3600", expected: time.Hour},
+		{header: "Second-60, Infinite", expected: time.Minute},
+		{header: "Infinite, Second-60", expected: maxLockTimeout},
+		{header: "Second-4100000000", expected: maxLockTimeout},
+		{header: "Second-0", err: true},
+		{header: "Minute-5", err: true},
+	}
+	for _, tt := range tests {
+		timeout, err := parseLockTimeout(tt.header)
+		if (err != nil) != tt.err {
+			t.Errorf("parseLockTimeout(%q) returned error %v", tt.header, err)
+			continue
+		}
+		if timeout != tt.expected {
+			t.Errorf("parseLockTimeout(%q) = %s, expected %s", tt.header, timeout, tt.expected)
+		}
+	}
+}
+
+func TestReadLockInfo(t *testing.T) {
+	li, err := readLockInfo(strings.NewReader(""))
+	if err != nil || li != nil {
+		t.Fatalf("expected an empty body to be a refresh, got %v, %v", li, err)
+	}
+
+	li, err = readLockInfo(strings.NewReader(`<?xml version="1.0" encoding="utf-8" ?>
+<D:lockinfo xmlns:D='DAV:'>
+  <D:lockscope><D:exclusive/></D:lockscope>
+  <D:locktype><D:write/></D:locktype>
+  <D:owner><D:href>http://example.org/~ejw/contact.html</D:href></D:owner>
+</D:lockinfo>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if li.Exclusive == nil || li.Shared != nil {
+		t.Error("expected an exclusive lock")
+	}
+	if !strings.Contains(li.Owner.InnerXML, "contact.html") {
+		t.Errorf("expected the owner to be kept, got %q", li.Owner.InnerXML)
+	}
+
+	_, err = readLockInfo(strings.NewReader(`<D:lockinfo xmlns:D='DAV:'><D:locktype><D:write/></D:locktype></D:lockinfo>`))
+	if err == nil {
+		t.Error("expected a lockinfo without lockscope to be rejected")
+	}
+}
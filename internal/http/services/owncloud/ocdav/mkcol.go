@@ -19,15 +19,21 @@
 package ocdav
 
 import (
+	"bytes"
 	"context"
+	"encoding/xml"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"path"
+	"strings"
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	rtrace "github.com/cs3org/reva/pkg/trace"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
@@ -43,6 +49,9 @@ func (s *svc) handlePathMkcol(w http.ResponseWriter, r *http.Request, ns string)
 		}
 	}
 	sublog := appctx.GetLogger(ctx).With().Str("path", fn).Logger()
+	if !s.checkFilename(w, fn, &sublog) {
+		return
+	}
 
 	parentRef := &provider.Reference{Path: path.Dir(fn)}
 	childRef := &provider.Reference{Path: fn}
@@ -85,9 +94,30 @@ func (s *svc) handleSpacesMkCol(w http.ResponseWriter, r *http.Request, spaceID
 }
 
 func (s *svc) handleMkcol(ctx context.Context, w http.ResponseWriter, r *http.Request, parentRef, childRef *provider.Reference, log zerolog.Logger) {
+	var props []propertyXML
 	if r.Body != http.NoBody {
-		w.WriteHeader(http.StatusUnsupportedMediaType)
-		return
+		// extended MKCOL, the body holds the properties of the new collection
+		// https://datatracker.ietf.org/doc/html/rfc5689
+		if !isXMLContentType(r.Header.Get(HeaderContentType)) {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		var err error
+		if props, err = readMkcol(r.Body); err != nil {
+			log.Debug().Err(err).Msg("error reading mkcol")
+			w.WriteHeader(http.StatusBadRequest)
+			b, err := Marshal(exception{
+				code:    SabredavBadRequest,
+				message: fmt.Sprintf("Error reading mkcol: %v", err),
+			})
+			HandleWebdavError(&log, w, b, err)
+			return
+		}
+		if failed := checkMkcolProps(props); len(failed) > 0 {
+			s.handleMkcolFailure(w, props, failed, log)
+			return
+		}
+		props = withoutResourceType(props)
 	}
 
 	client, err := s.getClient()
@@ -156,6 +186,14 @@ func (s *svc) handleMkcol(ctx context.Context, w http.ResponseWriter, r *http.Re
 	}
 	switch res.Status.Code {
 	case rpc.Code_CODE_OK:
+		if len(props) > 0 {
+			if _, _, ok := s.handleProppatch(ctx, w, r, childRef, []Proppatch{{Props: props}}, log); !ok {
+				// the collection must not be created if its properties cannot be set,
+				// handleProppatch already wrote the response
+				s.removeCollection(ctx, childRef, log)
+				return
+			}
+		}
 		w.WriteHeader(http.StatusCreated)
 	case rpc.Code_CODE_NOT_FOUND:
 		log.Debug().Str("path", childRef.Path).Interface("status", statRes.Status).Msg("conflict")
@@ -173,3 +211,184 @@ func (s *svc) handleMkcol(ctx context.Context, w http.ResponseWriter, r *http.Re
 		HandleErrorStatus(&log, w, res.Status)
 	}
 }
+
+// removeCollection deletes a collection created by an extended MKCOL whose properties could not be set.
+func (s *svc) removeCollection(ctx context.Context, ref *provider.Reference, log zerolog.Logger) {
+	client, err := s.getClient()
+	if err != nil {
+		log.Error().Err(err).Msg("error getting grpc client")
+		return
+	}
+	res, err := client.Delete(ctx, &provider.DeleteRequest{Ref: ref})
+	switch {
+	case err != nil:
+		log.Error().Err(err).Msg("error sending a grpc delete request")
+	case res.Status.Code != rpc.Code_CODE_OK:
+		log.Error().Interface("status", res.Status).Msg("error removing the collection of a failed mkcol")
+	}
+}
+
+// handleMkcolFailure rejects an extended MKCOL setting properties which cannot
+// be set, the other properties fail because of them.
+func (s *svc) handleMkcolFailure(w http.ResponseWriter, props []propertyXML, failed map[xml.Name]string, log zerolog.Logger) {
+	res := mkcolResponseXML{Xmlnsd: _nsDav}
+	dependent := mkcolPropstatXML{Status: "HTTP/1.1 424 Failed Dependency"}
+	for i := range props {
+		name := props[i].XMLName
+		prop := s.newPropNS(name.Space, name.Local, "")
+		if condition, ok := failed[name]; ok {
+			res.Propstat = append(res.Propstat, mkcolPropstatXML{
+				Prop:   []*propertyXML{prop},
+				Status: "HTTP/1.1 403 Forbidden",
+				Error:  &mkcolErrorXML{InnerXML: []byte("<d:" + condition + "/>")},
+			})
+			continue
+		}
+		dependent.Prop = append(dependent.Prop, prop)
+	}
+	if len(dependent.Prop) > 0 {
+		res.Propstat = append(res.Propstat, dependent)
+	}
+
+	b, err := xml.Marshal(&res)
+	if err == nil {
+		b = append([]byte(xml.Header), b...)
+		w.Header().Set(HeaderContentType, "application/xml; charset=utf-8")
+		w.WriteHeader(http.StatusForbidden)
+	}
+	HandleWebdavError(&log, w, b, err)
+}
+
+// https://datatracker.ietf.org/doc/html/rfc5689#section-5.1
+type mkcolXML struct {
+	XMLName xml.Name    `xml:"DAV: mkcol"`
+	Lang    string      `xml:"xml:lang,attr,omitempty"`
+	Set     []setRemove `xml:",any"`
+}
+
+// https://datatracker.ietf.org/doc/html/rfc5689#section-5.2
+type mkcolResponseXML struct {
+	XMLName  xml.Name           `xml:"d:mkcol-response"`
+	Xmlnsd   string             `xml:"xmlns:d,attr"`
+	Propstat []mkcolPropstatXML `xml:"d:propstat"`
+}
+
+type mkcolPropstatXML struct {
+	Prop   []*propertyXML `xml:"d:prop>_ignored_"`
+	Status string         `xml:"d:status"`
+	Error  *mkcolErrorXML `xml:"d:error,omitempty"`
+}
+
+// mkcolErrorXML holds the precondition which failed.
+type mkcolErrorXML struct {
+	InnerXML []byte `xml:",innerxml"`
+}
+
+var (
+	errInvalidMkcol  = errors.New("webdav: invalid mkcol")
+	propResourceType = xml.Name{Space: _nsDav, Local: "resourcetype"}
+
+	// protectedProps are the live properties computed by the storage, which
+	// cannot be set when creating a collection.
+	protectedProps = map[xml.Name]bool{
+		{Space: _nsDav, Local: "getetag"}:                 true,
+		{Space: _nsDav, Local: "getlastmodified"}:         true,
+		{Space: _nsDav, Local: "getcontentlength"}:        true,
+		{Space: _nsDav, Local: "getcontenttype"}:          true,
+		{Space: _nsDav, Local: "creationdate"}:            true,
+		{Space: _nsDav, Local: "lockdiscovery"}:           true,
+		{Space: _nsDav, Local: "supportedlock"}:           true,
+		{Space: _nsDav, Local: "quota-used-bytes"}:        true,
+		{Space: _nsDav, Local: "quota-available-bytes"}:   true,
+		{Space: _nsOwncloud, Local: "id"}:                 true,
+		{Space: _nsOwncloud, Local: "fileid"}:             true,
+		{Space: _nsOwncloud, Local: "permissions"}:        true,
+		{Space: _nsOwncloud, Local: "size"}:               true,
+		{Space: _nsOwncloud, Local: "checksums"}:          true,
+		{Space: _nsOwncloud, Local: "owner-id"}:           true,
+		{Space: _nsOwncloud, Local: "owner-display-name"}: true,
+		{Space: _nsOwncloud, Local: "share-types"}:        true,
+		{Space: _nsOwncloud, Local: "privatelink"}:        true,
+	}
+)
+
+// readMkcol returns the properties set by the body of an extended MKCOL.
+func readMkcol(r io.Reader) ([]propertyXML, error) {
+	var mk mkcolXML
+	if err := xml.NewDecoder(r).Decode(&mk); err != nil {
+		return nil, err
+	}
+	if len(mk.Set) == 0 {
+		return nil, errInvalidMkcol
+	}
+	props := []propertyXML{}
+	for _, op := range mk.Set {
+		if op.XMLName != (xml.Name{Space: _nsDav, Local: "set"}) {
+			return nil, errInvalidMkcol
+		}
+		props = append(props, op.Prop...)
+	}
+	return props, nil
+}
+
+// checkMkcolProps returns the properties of an extended MKCOL which cannot be
+// set, with the precondition they fail.
+func checkMkcolProps(props []propertyXML) map[xml.Name]string {
+	failed := map[xml.Name]string{}
+	for i := range props {
+		name := props[i].XMLName
+		switch {
+		case name == propResourceType:
+			if !isCollectionResourceType(props[i].InnerXML) {
+				failed[name] = "valid-resourcetype"
+			}
+		case protectedProps[name]:
+			failed[name] = "cannot-modify-protected-property"
+		}
+	}
+	return failed
+}
+
+// isCollectionResourceType returns whether a resource type is a plain
+// collection, the only type of resource which can be created. The inner XML of
+// a property keeps the prefixes declared by the enclosing elements, so an
+// unresolved prefix is assumed to be the one of the DAV: namespace.
+func isCollectionResourceType(inner []byte) bool {
+	d := xml.NewDecoder(bytes.NewReader(inner))
+	collection := false
+	for {
+		t, err := next(d)
+		if err == io.EOF {
+			return collection
+		}
+		if err != nil {
+			return false
+		}
+		if e, ok := t.(xml.StartElement); ok {
+			if e.Name.Local != "collection" || (e.Name.Space != _nsDav && strings.Contains(e.Name.Space, ":")) {
+				return false
+			}
+			collection = true
+		}
+	}
+}
+
+func withoutResourceType(props []propertyXML) []propertyXML {
+	filtered := make([]propertyXML, 0, len(props))
+	for i := range props {
+		if props[i].XMLName != propResourceType {
+			filtered = append(filtered, props[i])
+		}
+	}
+	return filtered
+}
+
+// isXMLContentType returns whether a request body is XML, which is assumed
+// when the content type is missing.
+func isXMLContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mt == "application/xml" || mt == "text/xml")
+}
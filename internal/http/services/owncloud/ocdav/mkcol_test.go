@@ -0,0 +1,110 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestReadMkcol(t *testing.T) {
+	body := `<?xml version="1.0" encoding="utf-8" ?>
+<D:mkcol xmlns:D="DAV:" xmlns:oc="http://owncloud.org/ns">
+  <D:set>
+    <D:prop>
+      <D:resourcetype><D:collection/></D:resourcetype>
+      <oc:favorite>1</oc:favorite>
+    </D:prop>
+  </D:set>
+  <D:set>
+    <D:prop>
+      <D:displayname>Special Resource</D:displayname>
+    </D:prop>
+  </D:set>
+</D:mkcol>`
+	props, err := readMkcol(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(props) != 3 {
+		t.Fatalf("expected 3 properties, got %d", len(props))
+	}
+	if failed := checkMkcolProps(props); len(failed) != 0 {
+		t.Errorf("expected all the properties to be valid, got %v", failed)
+	}
+	props = withoutResourceType(props)
+	if len(props) != 2 || props[0].XMLName.Local != "favorite" || string(props[0].InnerXML) != "1" {
+		t.Errorf("unexpected properties %+v", props)
+	}
+
+	for _, invalid := range []string{
+		`<D:mkcol xmlns:D="DAV:"/>`,
+		`<D:mkcol xmlns:D="DAV:"><D:remove><D:prop><D:displayname/></D:prop></D:remove></D:mkcol>`,
+		`<D:propertyupdate xmlns:D="DAV:"><D:set><D:prop><D:displayname>x</D:displayname></D:prop></D:set></D:propertyupdate>`,
+		`not xml`,
+	} {
+		if _, err := readMkcol(strings.NewReader(invalid)); err == nil {
+			t.Errorf("expected an error for %s", invalid)
+		}
+	}
+}
+
+func TestCheckMkcolProps(t *testing.T) {
+	props := []propertyXML{
+		{XMLName: propResourceType, InnerXML: []byte(`<D:collection/><C:calendar xmlns:C="urn:ietf:params:xml:ns:caldav"/>`)},
+		{XMLName: xml.Name{Space: _nsDav, Local: "getetag"}, InnerXML: []byte(`"abc"`)},
+		{XMLName: xml.Name{Space: _nsDav, Local: "displayname"}, InnerXML: []byte("x")},
+	}
+	failed := checkMkcolProps(props)
+	if len(failed) != 2 || failed[propResourceType] != "valid-resourcetype" || failed[props[1].XMLName] != "cannot-modify-protected-property" {
+		t.Errorf("unexpected failures %v", failed)
+	}
+}
+
+func TestIsCollectionResourceType(t *testing.T) {
+	tests := map[string]bool{
+		`<D:collection/>`:                   true,
+		`<collection/>`:                     true,
+		`<collection xmlns="DAV:"/>`:        true,
+		``:                                  false,
+		`<D:principal/>`:                    false,
+		`<collection xmlns="urn:example"/>`: false,
+	}
+	for inner, expected := range tests {
+		if got := isCollectionResourceType([]byte(inner)); got != expected {
+			t.Errorf("isCollectionResourceType(%q) = %v, expected %v", inner, got, expected)
+		}
+	}
+}
+
+func TestIsXMLContentType(t *testing.T) {
+	tests := map[string]bool{
+		"":                                  true,
+		"application/xml":                   true,
+		"text/xml; charset=utf-8":           true,
+		"application/json":                  false,
+		"application/x-www-form-urlencoded": false,
+	}
+	for ct, expected := range tests {
+		if got := isXMLContentType(ct); got != expected {
+			t.Errorf("isXMLContentType(%q) = %v, expected %v", ct, got, expected)
+		}
+	}
+}
@@ -52,9 +52,12 @@ func (s *svc) handlePathMove(w http.ResponseWriter, r *http.Request, ns string)
 		}
 	}
 
-	dstPath = path.Join(ns, dstPath)
+	dstPath = destinationPath(ctx, ns, dstPath)
 
 	sublog := appctx.GetLogger(ctx).With().Str("src", srcPath).Str("dst", dstPath).Logger()
+	if !s.checkFilename(w, dstPath, &sublog) {
+		return
+	}
 	src := &provider.Reference{Path: srcPath}
 	dst := &provider.Reference{Path: dstPath}
 
@@ -258,6 +261,12 @@ func (s *svc) handleMove(ctx context.Context, w http.ResponseWriter, r *http.Req
 	}
 
 	info := dstStatRes.Info
+	if mtime := r.Header.Get(HeaderOCMtime); mtime != "" {
+		var accepted bool
+		if info, accepted = s.applyMtime(ctx, client, dst, info, mtime); accepted {
+			w.Header().Set(HeaderOCMtime, "accepted")
+		}
+	}
 	w.Header().Set(HeaderContentType, info.MimeType)
 	w.Header().Set(HeaderETag, info.Etag)
 	w.Header().Set(HeaderOCFileID, resourceid.OwnCloudResourceIDWrap(info.Id))
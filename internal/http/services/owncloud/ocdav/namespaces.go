@@ -0,0 +1,178 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"strings"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/storage/utils/templates"
+	"github.com/pkg/errors"
+)
+
+// Names of the DAV routes namespace rules can be bound to.
+const (
+	routeWebDav      = "webdav"
+	routeFiles       = "files"
+	routePublicFiles = "public-files"
+)
+
+// NamespaceRule maps incoming request paths to a namespace, optionally rewriting the path.
+// Rules are evaluated in the order in which they are configured; the first matching rule
+// wins unless it is marked with Continue, in which case the following rules are evaluated
+// against the rewritten path as well. This allows chaining aliases, e.g. to migrate from
+// one layout to another.
+// Example: the rule
+//
+//	match: "^/projects/(?P<project>[^/]+)(?P<rest>/.*)?$"
+//	namespace: "/eos/project/{{substr 0 1 .Vars.project}}/{{.Vars.project}}"
+//	path: "{{.Vars.rest}}"
+//
+// maps /projects/cernbox/docs to /eos/project/c/cernbox/docs.
+type NamespaceRule struct {
+	// Route is the DAV route the rule applies to (webdav, files or public-files). An empty route matches all of them.
+	Route string `mapstructure:"route"`
+	// Match is a regular expression matched against the request path; named groups are accessible in the templates via {{.Vars.<name>}}.
+	Match string `mapstructure:"match"`
+	// Namespace is the template for the namespace to use; if empty, the current namespace is kept.
+	Namespace string `mapstructure:"namespace"`
+	// Path is the template for the rewritten request path; if empty, the request path is kept.
+	Path string `mapstructure:"path"`
+	// Continue makes the evaluation proceed with the next rules after this one matched.
+	Continue bool `mapstructure:"continue"`
+}
+
+type namespaceRule struct {
+	match     *regexp.Regexp
+	namespace string
+	path      string
+	cont      bool
+}
+
+func compileNamespaceRules(route string, rules []NamespaceRule) ([]*namespaceRule, error) {
+	compiled := make([]*namespaceRule, 0, len(rules))
+	for _, r := range rules {
+		if r.Route != "" && r.Route != route {
+			continue
+		}
+		re, err := regexp.Compile(r.Match)
+		if err != nil {
+			return nil, errors.Wrapf(err, "ocdav: invalid match expression in namespace rule: %s", r.Match)
+		}
+		for _, tpl := range []string{r.Namespace, r.Path} {
+			if tpl == "" {
+				continue
+			}
+			if err := templates.Validate(tpl); err != nil {
+				return nil, errors.Wrapf(err, "ocdav: invalid template in namespace rule: %s", tpl)
+			}
+		}
+		compiled = append(compiled, &namespaceRule{
+			match:     re,
+			namespace: r.Namespace,
+			path:      r.Path,
+			cont:      r.Continue,
+		})
+	}
+	return compiled, nil
+}
+
+// mapNamespace applies the given rules to the request path and returns the resulting
+// namespace and path. If no rule matches, the default namespace template ns is used.
+func mapNamespace(ctx context.Context, rules []*namespaceRule, ns string, useLoggedInUserNS bool, requestPath string) (string, string) {
+	u := layoutUser(ctx, useLoggedInUserNS, requestPath)
+	ns = templates.WithUser(u, ns)
+
+	for _, rule := range rules {
+		m := rule.match.FindStringSubmatch(requestPath)
+		if m == nil {
+			continue
+		}
+		vars := make(map[string]string, len(m))
+		for i, name := range rule.match.SubexpNames() {
+			if i > 0 && name != "" {
+				vars[name] = m[i]
+			}
+		}
+		if rule.namespace != "" {
+			ns = templates.WithUserAndVars(u, rule.namespace, vars)
+		}
+		if rule.path != "" {
+			requestPath = path.Join("/", templates.WithUserAndVars(u, rule.path, vars))
+		}
+		if !rule.cont {
+			break
+		}
+	}
+	return ns, requestPath
+}
+
+// namespaceMapping records how the namespace rules of a handler mapped the path of a request, so that
+// the destinations of the request and the hrefs of the response are mapped consistently with it.
+type namespaceMapping struct {
+	handler     *WebDavHandler
+	requestPath string
+	mappedPath  string
+}
+
+// destinationPath returns the CS3 path of the destination of a MOVE or COPY request. The destination
+// is mapped by the same rules as the request path, so it may end up in another namespace.
+func destinationPath(ctx context.Context, ns, dst string) string {
+	m, ok := ctx.Value(ctxKeyNamespaceMapping).(*namespaceMapping)
+	if !ok {
+		return path.Join(ns, dst)
+	}
+	dstNS, dstPath := mapNamespace(ctx, m.handler.rules, m.handler.namespace, m.handler.useLoggedInUserNS, dst)
+	return path.Join(dstNS, dstPath)
+}
+
+// hrefPath returns the path of a resource relative to the namespace as the client addressed it:
+// if a rule rewrote the request path, the rewritten prefix is replaced by the original one.
+func hrefPath(ctx context.Context, p string) string {
+	m, ok := ctx.Value(ctxKeyNamespaceMapping).(*namespaceMapping)
+	if !ok || m.requestPath == m.mappedPath {
+		return p
+	}
+	if p != m.mappedPath && !strings.HasPrefix(p, strings.TrimSuffix(m.mappedPath, "/")+"/") {
+		return p
+	}
+	return path.Join(m.requestPath, strings.TrimPrefix(p, m.mappedPath))
+}
+
+// layoutUser returns the user whose data is used to render the namespace templates.
+// If useLoggedInUserNS is false, that implies that the request is coming from
+// the FilesHandler method invoked by a /dav/files/fileOwner where fileOwner
+// is not the same as the logged in user. In that case, we'll treat fileOwner
+// as the username whose files are to be accessed and use that in the
+// namespace template.
+func layoutUser(ctx context.Context, useLoggedInUserNS bool, requestPath string) *userpb.User {
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok || !useLoggedInUserNS {
+		requestUserID, _ := router.ShiftPath(requestPath)
+		u = &userpb.User{
+			Username: requestUserID,
+		}
+	}
+	return u
+}
@@ -0,0 +1,386 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	_ "github.com/cs3org/reva/pkg/storage/favorite/memory"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+)
+
+var projectsRule = NamespaceRule{
+	Route:     routeWebDav,
+	Match:     "^/projects/(?P<project>[^/]+)(?P<rest>/.*)?$",
+	Namespace: "/eos/project/{{substr 0 1 .Vars.project}}/{{.Vars.project}}",
+	Path:      "{{.Vars.rest}}",
+}
+
+func TestCompileNamespaceRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		route    string
+		rules    []NamespaceRule
+		expected int
+		err      bool
+	}{
+		{name: "no rules", route: routeWebDav, expected: 0},
+		{name: "matching route", route: routeWebDav, rules: []NamespaceRule{projectsRule}, expected: 1},
+		{name: "other route", route: routeFiles, rules: []NamespaceRule{projectsRule}, expected: 0},
+		{name: "any route", route: routePublicFiles, rules: []NamespaceRule{{Match: "^/a"}}, expected: 1},
+		{name: "invalid match", route: routeWebDav, rules: []NamespaceRule{{Match: "^/(a"}}, err: true},
+		{name: "invalid namespace", route: routeWebDav, rules: []NamespaceRule{{Match: "^/a", Namespace: "/{{.Vars.a"}}, err: true},
+		{name: "invalid path", route: routeWebDav, rules: []NamespaceRule{{Match: "^/a", Path: "{{end}}"}}, err: true},
+		{name: "invalid rule for other route", route: routeFiles, rules: []NamespaceRule{{Route: routeWebDav, Match: "^/(a"}}, expected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compiled, err := compileNamespaceRules(tt.route, tt.rules)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %d rules", len(compiled))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(compiled) != tt.expected {
+				t.Errorf("expected %d rules, got %d", tt.expected, len(compiled))
+			}
+		})
+	}
+}
+
+func TestMapNamespace(t *testing.T) {
+	einstein := &userpb.User{Username: "einstein"}
+	tests := []struct {
+		name              string
+		rules             []NamespaceRule
+		ns                string
+		useLoggedInUserNS bool
+		user              *userpb.User
+		requestPath       string
+		expectedNS        string
+		expectedPath      string
+	}{
+		{
+			name:              "no rules",
+			ns:                "/home",
+			useLoggedInUserNS: true,
+			user:              einstein,
+			requestPath:       "/Documents/a.txt",
+			expectedNS:        "/home",
+			expectedPath:      "/Documents/a.txt",
+		},
+		{
+			name:              "no match falls back to the default namespace",
+			rules:             []NamespaceRule{projectsRule},
+			ns:                "/users/{{substr 0 1 .Username}}/{{.Username}}",
+			useLoggedInUserNS: true,
+			user:              einstein,
+			requestPath:       "/Documents/a.txt",
+			expectedNS:        "/users/e/einstein",
+			expectedPath:      "/Documents/a.txt",
+		},
+		{
+			name:              "match",
+			rules:             []NamespaceRule{projectsRule},
+			ns:                "/home",
+			useLoggedInUserNS: true,
+			user:              einstein,
+			requestPath:       "/projects/cernbox/docs/a.txt",
+			expectedNS:        "/eos/project/c/cernbox",
+			expectedPath:      "/docs/a.txt",
+		},
+		{
+			name:              "match of the project root",
+			rules:             []NamespaceRule{projectsRule},
+			ns:                "/home",
+			useLoggedInUserNS: true,
+			user:              einstein,
+			requestPath:       "/projects/cernbox",
+			expectedNS:        "/eos/project/c/cernbox",
+			expectedPath:      "/",
+		},
+		{
+			name: "first match wins",
+			rules: []NamespaceRule{
+				{Match: "^/projects/cernbox(?P<rest>/.*)?$", Namespace: "/eos/legacy/cernbox", Path: "{{.Vars.rest}}"},
+				projectsRule,
+			},
+			ns:                "/home",
+			useLoggedInUserNS: true,
+			user:              einstein,
+			requestPath:       "/projects/cernbox/docs",
+			expectedNS:        "/eos/legacy/cernbox",
+			expectedPath:      "/docs",
+		},
+		{
+			name: "later rules apply if the first one does not match",
+			rules: []NamespaceRule{
+				{Match: "^/projects/cernbox(?P<rest>/.*)?$", Namespace: "/eos/legacy/cernbox", Path: "{{.Vars.rest}}"},
+				projectsRule,
+			},
+			ns:                "/home",
+			useLoggedInUserNS: true,
+			user:              einstein,
+			requestPath:       "/projects/atlas/docs",
+			expectedNS:        "/eos/project/a/atlas",
+			expectedPath:      "/docs",
+		},
+		{
+			name: "continue chains the rules",
+			rules: []NamespaceRule{
+				{Match: "^/old-projects/(?P<rest>.*)$", Path: "/projects/{{.Vars.rest}}", Continue: true},
+				projectsRule,
+			},
+			ns:                "/home",
+			useLoggedInUserNS: true,
+			user:              einstein,
+			requestPath:       "/old-projects/cernbox/docs",
+			expectedNS:        "/eos/project/c/cernbox",
+			expectedPath:      "/docs",
+		},
+		{
+			name:              "user from the logged in user",
+			rules:             []NamespaceRule{{Match: "^/shared(?P<rest>/.*)?$", Namespace: "/eos/user/{{.Username}}/shared", Path: "{{.Vars.rest}}"}},
+			ns:                "/home",
+			useLoggedInUserNS: true,
+			user:              einstein,
+			requestPath:       "/shared/a.txt",
+			expectedNS:        "/eos/user/einstein/shared",
+			expectedPath:      "/a.txt",
+		},
+		{
+			name:              "user from the request path",
+			ns:                "/users/{{.Username}}",
+			useLoggedInUserNS: false,
+			user:              einstein,
+			requestPath:       "/marie/a.txt",
+			expectedNS:        "/users/marie",
+			expectedPath:      "/marie/a.txt",
+		},
+		{
+			name:              "user from the request path without a logged in user",
+			ns:                "/users/{{.Username}}",
+			useLoggedInUserNS: true,
+			requestPath:       "/marie/a.txt",
+			expectedNS:        "/users/marie",
+			expectedPath:      "/marie/a.txt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := compileNamespaceRules(routeWebDav, tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			if tt.user != nil {
+				ctx = ctxpkg.ContextSetUser(ctx, tt.user)
+			}
+			ns, p := mapNamespace(ctx, rules, tt.ns, tt.useLoggedInUserNS, tt.requestPath)
+			if ns != tt.expectedNS {
+				t.Errorf("expected namespace %s, got %s", tt.expectedNS, ns)
+			}
+			if p != tt.expectedPath {
+				t.Errorf("expected path %s, got %s", tt.expectedPath, p)
+			}
+		})
+	}
+}
+
+func TestDestinationAndHrefPath(t *testing.T) {
+	h := &WebDavHandler{}
+	if err := h.init("/home", true, []NamespaceRule{projectsRule}, routeWebDav); err != nil {
+		t.Fatal(err)
+	}
+	ctx := ctxpkg.ContextSetUser(context.Background(), &userpb.User{Username: "einstein"})
+
+	if p := destinationPath(ctx, "/home", "/a.txt"); p != "/home/a.txt" {
+		t.Errorf("expected /home/a.txt without a mapping, got %s", p)
+	}
+	if p := hrefPath(ctx, "/docs/a.txt"); p != "/docs/a.txt" {
+		t.Errorf("expected /docs/a.txt without a mapping, got %s", p)
+	}
+
+	ctx = context.WithValue(ctx, ctxKeyNamespaceMapping, &namespaceMapping{handler: h, requestPath: "/projects/cernbox/docs", mappedPath: "/docs"})
+	destinations := map[string]string{
+		"/projects/cernbox/docs/b.txt": "/eos/project/c/cernbox/docs/b.txt",
+		"/projects/atlas/b.txt":        "/eos/project/a/atlas/b.txt",
+		"/Documents/b.txt":             "/home/Documents/b.txt",
+	}
+	for dst, expected := range destinations {
+		if p := destinationPath(ctx, "/eos/project/c/cernbox", dst); p != expected {
+			t.Errorf("expected destination %s for %s, got %s", expected, dst, p)
+		}
+	}
+	hrefs := map[string]string{
+		"/docs":       "/projects/cernbox/docs",
+		"/docs/a.txt": "/projects/cernbox/docs/a.txt",
+		"/docsx":      "/docsx",
+		"/other":      "/other",
+	}
+	for p, expected := range hrefs {
+		if href := hrefPath(ctx, p); href != expected {
+			t.Errorf("expected href %s for %s, got %s", expected, p, href)
+		}
+	}
+}
+
+// fakeGateway serves an in-memory tree of resources; all the other calls panic.
+type fakeGateway struct {
+	gateway.GatewayAPIServer
+	mu    sync.Mutex
+	infos map[string]*provider.ResourceInfo
+	moves []*provider.MoveRequest
+}
+
+func (g *fakeGateway) Stat(ctx context.Context, req *provider.StatRequest) (*provider.StatResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	info, ok := g.infos[req.Ref.Path]
+	if !ok {
+		return &provider.StatResponse{Status: status.NewNotFound(ctx, "not found")}, nil
+	}
+	return &provider.StatResponse{Status: status.NewOK(ctx), Info: info}, nil
+}
+
+func (g *fakeGateway) ListContainer(ctx context.Context, req *provider.ListContainerRequest) (*provider.ListContainerResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var infos []*provider.ResourceInfo
+	for p, info := range g.infos {
+		if path.Dir(p) == req.Ref.Path {
+			infos = append(infos, info)
+		}
+	}
+	return &provider.ListContainerResponse{Status: status.NewOK(ctx), Infos: infos}, nil
+}
+
+func (g *fakeGateway) Move(ctx context.Context, req *provider.MoveRequest) (*provider.MoveResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.moves = append(g.moves, req)
+	info := g.infos[req.Source.Path]
+	delete(g.infos, req.Source.Path)
+	g.infos[req.Destination.Path] = &provider.ResourceInfo{Id: info.Id, Type: info.Type, Path: req.Destination.Path}
+	return &provider.MoveResponse{Status: status.NewOK(ctx)}, nil
+}
+
+func (g *fakeGateway) ListPublicShares(ctx context.Context, req *link.ListPublicSharesRequest) (*link.ListPublicSharesResponse, error) {
+	return &link.ListPublicSharesResponse{Status: status.NewOK(ctx)}, nil
+}
+
+func (g *fakeGateway) ListShares(ctx context.Context, req *collaboration.ListSharesRequest) (*collaboration.ListSharesResponse, error) {
+	return &collaboration.ListSharesResponse{Status: status.NewOK(ctx)}, nil
+}
+
+func startFakeGateway(t *testing.T, g *fakeGateway) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	gateway.RegisterGatewayAPIServer(srv, g)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+// TestNamespaceAliasRoundTrip lists and moves resources addressed through an alias and checks
+// that the hrefs keep the alias and the destination is mapped to the namespace of the project.
+func TestNamespaceAliasRoundTrip(t *testing.T) {
+	g := &fakeGateway{infos: map[string]*provider.ResourceInfo{}}
+	for p, tp := range map[string]provider.ResourceType{
+		"/eos/project/c/cernbox/docs":       provider.ResourceType_RESOURCE_TYPE_CONTAINER,
+		"/eos/project/c/cernbox/docs/a.txt": provider.ResourceType_RESOURCE_TYPE_FILE,
+	} {
+		g.infos[p] = &provider.ResourceInfo{Id: &provider.ResourceId{StorageId: "eos", OpaqueId: p}, Type: tp, Path: p}
+	}
+	addr := startFakeGateway(t, g)
+
+	log := zerolog.Nop()
+	s, err := New(map[string]interface{}{
+		"gatewaysvc":       addr,
+		"webdav_namespace": "/home",
+		"namespace_rules": []map[string]interface{}{
+			{"route": projectsRule.Route, "match": projectsRule.Match, "namespace": projectsRule.Namespace, "path": projectsRule.Path},
+		},
+	}, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := s.Handler()
+	user := &userpb.User{Id: &userpb.UserId{OpaqueId: "einstein"}, Username: "einstein"}
+
+	body := `<d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/></d:prop></d:propfind>`
+	r := httptest.NewRequest(MethodPropfind, "/remote.php/webdav/projects/cernbox/docs", strings.NewReader(body))
+	r = r.WithContext(ctxpkg.ContextSetUser(r.Context(), user))
+	r.Header.Set(HeaderDepth, "1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusMultiStatus, w.Code, w.Body.String())
+	}
+	for _, href := range []string{
+		"<d:href>/remote.php/webdav/projects/cernbox/docs/</d:href>",
+		"<d:href>/remote.php/webdav/projects/cernbox/docs/a.txt</d:href>",
+	} {
+		if !strings.Contains(w.Body.String(), href) {
+			t.Errorf("expected %s in the response, got %s", href, w.Body.String())
+		}
+	}
+
+	r = httptest.NewRequest(MethodMove, "/remote.php/webdav/projects/cernbox/docs/a.txt", nil)
+	r = r.WithContext(ctxpkg.ContextSetUser(r.Context(), user))
+	r.Header.Set(HeaderDestination, "https://cloud.example.org/remote.php/webdav/projects/cernbox/docs/b.txt")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if len(g.moves) != 1 {
+		t.Fatalf("expected 1 move, got %d", len(g.moves))
+	}
+	if src := g.moves[0].Source.Path; src != "/eos/project/c/cernbox/docs/a.txt" {
+		t.Errorf("expected source /eos/project/c/cernbox/docs/a.txt, got %s", src)
+	}
+	if dst := g.moves[0].Destination.Path; dst != "/eos/project/c/cernbox/docs/b.txt" {
+		t.Errorf("expected destination /eos/project/c/cernbox/docs/b.txt, got %s", dst)
+	}
+}
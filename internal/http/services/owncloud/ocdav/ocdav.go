@@ -25,22 +25,27 @@ import (
 	"net/url"
 	"path"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ReneKroon/ttlcache/v2"
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
-	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/preview"
+	"github.com/cs3org/reva/pkg/publicshare/analytics"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/rhttp"
 	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/httpcache"
 	"github.com/cs3org/reva/pkg/rhttp/router"
 	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/cs3org/reva/pkg/storage/favorite"
 	"github.com/cs3org/reva/pkg/storage/favorite/registry"
-	"github.com/cs3org/reva/pkg/storage/utils/templates"
+	"github.com/cs3org/reva/pkg/storage/utils/namepolicy"
+	"github.com/cs3org/reva/pkg/tenant"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
@@ -50,6 +55,7 @@ type ctxKey int
 
 const (
 	ctxKeyBaseURI ctxKey = iota
+	ctxKeyNamespaceMapping
 )
 
 var (
@@ -79,6 +85,23 @@ func (r nameDoesNotContain) Test(name string) bool {
 	return !strings.ContainsAny(name, r.chars)
 }
 
+// checkFilename validates fn against the filename policy. If it is violated an
+// error carrying the violation code is written and false is returned.
+func (s *svc) checkFilename(w http.ResponseWriter, fn string, log *zerolog.Logger) bool {
+	err := s.filenamePolicy.Check(fn)
+	if err == nil {
+		return true
+	}
+	log.Debug().Err(err).Str("path", fn).Msg("filename policy violated")
+	w.WriteHeader(http.StatusBadRequest)
+	b, err := Marshal(exception{
+		code:    SabredavBadRequest,
+		message: err.Error(),
+	})
+	HandleWebdavError(log, w, b, err)
+	return false
+}
+
 func init() {
 	global.Register("ocdav", New)
 }
@@ -104,6 +127,28 @@ type Config struct {
 	PublicURL              string                            `mapstructure:"public_url"`
 	FavoriteStorageDriver  string                            `mapstructure:"favorite_storage_driver"`
 	FavoriteStorageDrivers map[string]map[string]interface{} `mapstructure:"favorite_storage_drivers"`
+	// NamespaceRules map request paths of the webdav, files and public-files routes to namespaces.
+	// If no rule matches, the FilesNamespace and WebdavNamespace are used.
+	NamespaceRules []NamespaceRule `mapstructure:"namespace_rules"`
+	// MaintenanceRetryAfter is sent in the Retry-After header when a storage rejects writes during maintenance.
+	MaintenanceRetryAfter int `mapstructure:"maintenance_retry_after" docs:"300;Seconds clients are asked to wait before retrying writes rejected during maintenance."`
+	// StrictComplianceRoutes are the DAV routes, e.g. remote.php/webdav or dav/files, behaving strictly
+	// as specified by RFC 4918 instead of mimicking the quirks ownCloud clients rely on.
+	StrictComplianceRoutes []string `mapstructure:"strict_compliance_routes"`
+	// FilenamePolicy restricts the names of newly created, copied or moved resources.
+	// Storage specific rules are matched against the internal path, including the namespace.
+	FilenamePolicy namepolicy.Config `mapstructure:"filename_policy"`
+	// StatusCache configures the caching of status.php, which clients query on every sync cycle.
+	StatusCache httpcache.Config `mapstructure:"status_cache"`
+	// QuotaCacheTTL is the number of seconds the quota of a storage is cached for the DAV:quota-available-bytes property.
+	QuotaCacheTTL int `mapstructure:"quota_cache_ttl" docs:"30;Seconds the quota of a storage is cached for the quota properties of PROPFIND responses."`
+	// ShareAnalytics records the views and downloads of the public links, which the public share provider reports.
+	ShareAnalytics analytics.Config `mapstructure:"share_analytics"`
+	// Previews configures the thumbnails served for the GET requests with ?preview=1.
+	Previews preview.Config `mapstructure:"previews"`
+	// SearchSvc is the address of the search service answering the search-files REPORT requests,
+	// which are not implemented if it is not set.
+	SearchSvc string `mapstructure:"searchsvc"`
 }
 
 func (c *Config) init() {
@@ -113,6 +158,16 @@ func (c *Config) init() {
 	if c.FavoriteStorageDriver == "" {
 		c.FavoriteStorageDriver = "memory"
 	}
+
+	if c.MaintenanceRetryAfter == 0 {
+		c.MaintenanceRetryAfter = 300
+	}
+
+	c.StatusCache.Init()
+
+	if c.QuotaCacheTTL == 0 {
+		c.QuotaCacheTTL = 30
+	}
 }
 
 type svc struct {
@@ -121,6 +176,13 @@ type svc struct {
 	davHandler       *DavHandler
 	favoritesManager favorite.Manager
 	client           *http.Client
+	filenamePolicy   *namepolicy.Policy
+	statusHandler    http.Handler
+	tenants          *tenant.Manager
+	quotaCache       *ttlcache.Cache
+	analytics        *analytics.Analytics // nil if the public links are not tracked
+	previews         *preview.Generator   // nil if the previews are disabled
+	retryAfter       string               // sent with the writes rejected during maintenance
 }
 
 func getFavoritesManager(c *Config) (favorite.Manager, error) {
@@ -153,9 +215,30 @@ func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error)
 			rhttp.Insecure(conf.Insecure),
 		),
 		favoritesManager: fm,
+		filenamePolicy:   namepolicy.New(&conf.FilenamePolicy),
+		retryAfter:       strconv.Itoa(conf.MaintenanceRetryAfter),
+	}
+	if s.tenants, err = tenant.New(sharedconf.GetTenancy()); err != nil {
+		return nil, err
+	}
+	if conf.ShareAnalytics.Enabled() {
+		if s.analytics, err = getAnalytics(&conf.ShareAnalytics); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Previews.Enabled {
+		if s.previews, err = getPreviews(&conf.Previews); err != nil {
+			return nil, err
+		}
+	}
+	s.statusHandler = httpcache.New(&conf.StatusCache).Handler(http.HandlerFunc(s.doStatus))
+	if conf.QuotaCacheTTL > 0 {
+		s.quotaCache = ttlcache.NewCache()
+		_ = s.quotaCache.SetTTL(time.Duration(conf.QuotaCacheTTL) * time.Second)
+		s.quotaCache.SkipTTLExtensionOnHit(true)
 	}
 	// initialize handlers and set default configs
-	if err := s.webDavHandler.init(conf.WebdavNamespace, true); err != nil {
+	if err := s.webDavHandler.init(conf.WebdavNamespace, true, conf.NamespaceRules, routeWebDav); err != nil {
 		return nil, err
 	}
 	if err := s.davHandler.init(conf); err != nil {
@@ -169,6 +252,9 @@ func (s *svc) Prefix() string {
 }
 
 func (s *svc) Close() error {
+	if s.quotaCache != nil {
+		return s.quotaCache.Close()
+	}
 	return nil
 }
 
@@ -176,12 +262,24 @@ func (s *svc) Unprotected() []string {
 	return []string{"/status.php", "/remote.php/dav/public-files/", "/apps/files/", "/index.php/f/", "/index.php/s/"}
 }
 
+// IsCrossOrigin returns true as the webdav api is accessible from anywhere.
+func (s *svc) IsCrossOrigin() bool {
+	return true
+}
+
 func (s *svc) Handler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		log := appctx.GetLogger(ctx)
 
 		addAccessHeaders(w, r)
+		w = &maintenanceWriter{ResponseWriter: w, retryAfter: s.retryAfter}
+
+		// the storage providers let writes to locked resources through when the lock is held by the client
+		if token := lockTokenFromIfHeader(r.Header.Get(HeaderIf)); token != "" {
+			ctx = ctxpkg.ContextSetLockID(ctx, token)
+			r = r.WithContext(ctx)
+		}
 
 		// TODO(jfd): do we need this?
 		// fake litmus testing for empty namespace: see https://github.com/golang/net/blob/e514e69ffb8bc3c76a71ae40de0118d794855992/webdav/litmus_test_server.go#L58-L89
@@ -199,7 +297,7 @@ func (s *svc) Handler() http.Handler {
 		log.Debug().Str("head", head).Str("tail", r.URL.Path).Msg("http routing")
 		switch head {
 		case "status.php":
-			s.doStatus(w, r)
+			s.statusHandler.ServeHTTP(w, r)
 			return
 		case "remote.php":
 			// skip optional "remote.php"
@@ -253,26 +351,8 @@ func (s *svc) getClient() (gateway.GatewayAPIClient, error) {
 	return pool.GetGatewayServiceClient(pool.Endpoint(s.c.GatewaySvc))
 }
 
-func applyLayout(ctx context.Context, ns string, useLoggedInUserNS bool, requestPath string) string {
-	// If useLoggedInUserNS is false, that implies that the request is coming from
-	// the FilesHandler method invoked by a /dav/files/fileOwner where fileOwner
-	// is not the same as the logged in user. In that case, we'll treat fileOwner
-	// as the username whose files are to be accessed and use that in the
-	// namespace template.
-	u, ok := ctxpkg.ContextGetUser(ctx)
-	if !ok || !useLoggedInUserNS {
-		requestUserID, _ := router.ShiftPath(requestPath)
-		u = &userpb.User{
-			Username: requestUserID,
-		}
-	}
-	return templates.WithUser(u, ns)
-}
-
 func addAccessHeaders(w http.ResponseWriter, r *http.Request) {
 	headers := w.Header()
-	// the webdav api is accessible from anywhere
-	headers.Set("Access-Control-Allow-Origin", "*")
 	// all resources served via the DAV endpoint should have the strictest possible as default
 	headers.Set("Content-Security-Policy", "default-src 'none';")
 	// disable sniffing the content type for IE
@@ -30,7 +30,10 @@ func (s *svc) handleOptions(w http.ResponseWriter, r *http.Request) {
 
 	isPublic := strings.Contains(r.Context().Value(ctxKeyBaseURI).(string), "public-files")
 
-	w.Header().Set(HeaderContentType, "application/xml")
+	if !s.strictCompliance(r.Context()) {
+		// there is no body, but ownCloud clients expect a content type
+		w.Header().Set(HeaderContentType, "application/xml")
+	}
 	w.Header().Set("Allow", allow)
 	w.Header().Set("DAV", "1, 2")
 	w.Header().Set("MS-Author-Via", "DAV")
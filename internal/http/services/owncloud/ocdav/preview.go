@@ -0,0 +1,139 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/preview"
+	"github.com/cs3org/reva/pkg/preview/cache/registry"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/utils/resourceid"
+	"github.com/rs/zerolog"
+)
+
+func getPreviews(c *preview.Config) (*preview.Generator, error) {
+	if c.CacheDriver == "" {
+		return preview.New(c, nil), nil
+	}
+	f, ok := registry.NewFuncs[c.CacheDriver]
+	if !ok {
+		return nil, errtypes.NotFound("preview cache driver not found: " + c.CacheDriver)
+	}
+	cache, err := f(c.CacheDrivers[c.CacheDriver])
+	if err != nil {
+		return nil, err
+	}
+	return preview.New(c, cache), nil
+}
+
+// isPreviewRequest returns whether a GET request asks for the preview of a
+// file instead of its content, as the clients do with ?preview=1.
+func isPreviewRequest(r *http.Request) bool {
+	return r.URL.Query().Get("preview") == "1"
+}
+
+// handlePreview serves the preview of a file, sized by the x and y query
+// parameters and keeping its aspect ratio if a=1. Files without previews
+// are reported as not found, so that the clients show their icons.
+func (s *svc) handlePreview(ctx context.Context, w http.ResponseWriter, r *http.Request, client gateway.GatewayAPIClient, ref *provider.Reference, info *provider.ResourceInfo, dlProtocol string, log zerolog.Logger) {
+	q := r.URL.Query()
+	req := preview.Request{KeepAspect: q.Get("a") == "1"}
+	req.Width, _ = strconv.Atoi(q.Get("x"))
+	req.Height, _ = strconv.Atoi(q.Get("y"))
+
+	etag := fmt.Sprintf("\"%s-%dx%d-%t\"", info.Etag, req.Width, req.Height, req.KeepAspect)
+	if r.Header.Get(HeaderIfNoneMatch) == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	src := &preview.Source{
+		ID:       resourceid.OwnCloudResourceIDWrap(info.Id),
+		ETag:     info.Etag,
+		MimeType: info.MimeType,
+		Size:     info.Size,
+		Open: func(ctx context.Context) (io.ReadCloser, error) {
+			return s.openDownload(ctx, client, ref, dlProtocol)
+		},
+	}
+	p, err := s.previews.Preview(ctx, src, req)
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotSupported); ok {
+			log.Debug().Err(err).Str("mimetype", info.MimeType).Msg("no preview available")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		log.Error().Err(err).Msg("error generating preview")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(HeaderContentType, p.ContentType)
+	w.Header().Set(HeaderContentLength, strconv.Itoa(len(p.Data)))
+	w.Header().Set(HeaderETag, etag)
+	w.Header().Set(HeaderCacheControl, "private, no-cache")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(p.Data); err != nil {
+		log.Error().Err(err).Msg("error writing preview")
+	}
+}
+
+// openDownload returns the content of a file from the data gateway.
+func (s *svc) openDownload(ctx context.Context, client gateway.GatewayAPIClient, ref *provider.Reference, dlProtocol string) (io.ReadCloser, error) {
+	dRes, err := client.InitiateFileDownload(ctx, &provider.InitiateFileDownloadRequest{Ref: ref})
+	if err != nil {
+		return nil, err
+	}
+	if dRes.Status.Code != rpc.Code_CODE_OK {
+		return nil, errtypes.InternalError("error initiating file download: " + dRes.Status.Message)
+	}
+
+	var ep, token string
+	for _, p := range dRes.Protocols {
+		if p.Protocol == dlProtocol {
+			ep, token = p.DownloadEndpoint, p.Token
+		}
+	}
+	httpReq, err := rhttp.NewRequest(ctx, http.MethodGet, ep, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set(datagateway.TokenTransportHeader, token)
+
+	httpRes, err := s.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if httpRes.StatusCode != http.StatusOK {
+		httpRes.Body.Close()
+		// e.g. blocked by the antivirus
+		return nil, errtypes.NotSupported("file download failed: " + httpRes.Status)
+	}
+	return httpRes.Body, nil
+}
@@ -38,12 +38,16 @@ import (
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
 	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/internal/grpc/services/storageprovider"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
+	"github.com/cs3org/reva/pkg/antivirus"
 	"github.com/cs3org/reva/pkg/appctx"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/publicshare"
 	"github.com/cs3org/reva/pkg/share"
+	"github.com/cs3org/reva/pkg/storage/utils/protect"
+	"github.com/cs3org/reva/pkg/storage/utils/retention"
 	rtrace "github.com/cs3org/reva/pkg/trace"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/cs3org/reva/pkg/utils/resourceid"
@@ -62,6 +66,7 @@ const (
 	// RFC1123 time that mimics oc10. time.RFC1123 would end in "UTC", see https://github.com/golang/go/issues/13781
 	RFC1123 = "Mon, 02 Jan 2006 15:04:05 GMT"
 
+	// the special values of the DAV:quota-available-bytes property
 	// _propQuotaUncalculated = "-1"
 	_propQuotaUnknown = "-2"
 	// _propQuotaUnlimited    = "-3"
@@ -161,8 +166,12 @@ func (s *svc) propfindResponse(ctx context.Context, w http.ResponseWriter, r *ht
 		return
 	}
 
+	// only the ids of the shared resources are needed to render the share types
 	var linkshares map[string]struct{}
-	listResp, err := client.ListPublicShares(ctx, &link.ListPublicSharesRequest{Filters: linkFilters})
+	listResp, err := client.ListPublicShares(ctx, &link.ListPublicSharesRequest{
+		Opaque:  resourceIDsOnly(publicshare.OpaqueResourceIDsOnly),
+		Filters: linkFilters,
+	})
 	if err == nil {
 		linkshares = make(map[string]struct{}, len(listResp.Share))
 		for i := range listResp.Share {
@@ -174,7 +183,10 @@ func (s *svc) propfindResponse(ctx context.Context, w http.ResponseWriter, r *ht
 	}
 
 	var usershares map[string]struct{}
-	listSharesResp, err := client.ListShares(ctx, &collaboration.ListSharesRequest{Filters: shareFilters})
+	listSharesResp, err := client.ListShares(ctx, &collaboration.ListSharesRequest{
+		Opaque:  resourceIDsOnly(share.OpaqueResourceIDsOnly),
+		Filters: shareFilters,
+	})
 	if err == nil {
 		usershares = make(map[string]struct{}, len(listSharesResp.Shares))
 		for i := range listSharesResp.Shares {
@@ -213,10 +225,24 @@ func (s *svc) propfindResponse(ctx context.Context, w http.ResponseWriter, r *ht
 	}
 }
 
+// resourceIDsOnly asks the share providers to answer with the ids of the
+// shared resources, which they can find with a single batched query.
+func resourceIDsOnly(key string) *typesv1beta1.Opaque {
+	return &typesv1beta1.Opaque{
+		Map: map[string]*typesv1beta1.OpaqueEntry{
+			key: {Decoder: "plain", Value: []byte("true")},
+		},
+	}
+}
+
 func (s *svc) getResourceInfos(ctx context.Context, w http.ResponseWriter, r *http.Request, pf propfindXML, ref *provider.Reference, spacesPropfind bool, log zerolog.Logger) (*provider.ResourceInfo, []*provider.ResourceInfo, bool) {
 	depth := r.Header.Get(HeaderDepth)
 	if depth == "" {
+		// a missing Depth header means infinity, ownCloud clients however expect 1
 		depth = "1"
+		if s.strictCompliance(ctx) {
+			depth = "infinity"
+		}
 	}
 	// see https://tools.ietf.org/html/rfc4918#section-9.1
 	if depth != "0" && depth != "1" && depth != "infinity" {
@@ -252,6 +278,10 @@ func (s *svc) getResourceInfos(ctx context.Context, w http.ResponseWriter, r *ht
 			if requiresExplicitFetching(&pf.Prop[i]) {
 				metadataKeys = append(metadataKeys, metadataKeyOf(&pf.Prop[i]))
 			}
+			// protected and retained resources are reported as neither deletable nor movable
+			if pf.Prop[i].Space == _nsOwncloud && pf.Prop[i].Local == "permissions" {
+				metadataKeys = append(metadataKeys, protect.MetadataKey, retention.MetadataKey)
+			}
 		}
 	}
 	req := &provider.StatRequest{
@@ -404,7 +434,7 @@ func requiresExplicitFetching(n *xml.Name) bool {
 		}
 	case _nsOwncloud:
 		switch n.Local {
-		case "favorite", "share-types", "checksums", "size":
+		case "favorite", "share-types", "checksums", "size", "protected", "retention-until":
 			return true
 		default:
 			return false
@@ -507,7 +537,7 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 
 	baseURI := ctx.Value(ctxKeyBaseURI).(string)
 
-	ref := path.Join(baseURI, md.Path)
+	ref := path.Join(baseURI, hrefPath(ctx, md.Path))
 	if md.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
 		ref += "/"
 	}
@@ -519,10 +549,6 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 
 	var ls *link.PublicShare
 
-	// -1 indicates uncalculated
-	// -2 indicates unknown (default)
-	// -3 indicates unlimited
-	quota := _propQuotaUnknown
 	size := fmt.Sprintf("%d", md.Size)
 	// TODO refactor helper functions: GetOpaqueJSONEncoded(opaque, key string, *struct) err, GetOpaquePlainEncoded(opaque, key) value, err
 	// or use ok like pattern and return bool?
@@ -534,9 +560,6 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 				sublog.Error().Err(err).Msg("could not unmarshal link json")
 			}
 		}
-		if md.Opaque.Map["quota"] != nil && md.Opaque.Map["quota"].Decoder == "plain" {
-			quota = string(md.Opaque.Map["quota"].Value)
-		}
 	}
 
 	role := conversions.RoleFromResourcePermissions(md.PermissionSet)
@@ -551,6 +574,17 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 			false,
 			isPublic,
 		)
+		if protect.IsProtected(md) {
+			wdp = strings.NewReplacer("D", "", "NV", "").Replace(wdp)
+		}
+		if _, retained := retention.RetainedUntil(md); retained {
+			// the content of retained folders can be added to, but not modified
+			replacer := strings.NewReplacer("D", "", "NV", "")
+			if md.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+				replacer = strings.NewReplacer("D", "", "NV", "", "W", "")
+			}
+			wdp = replacer.Replace(wdp)
+		}
 		sublog.Debug().Interface("role", role).Str("dav-permissions", wdp).Msg("converted PermissionSet")
 	}
 
@@ -594,7 +628,7 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 			// A <DAV:allprop> PROPFIND request SHOULD NOT return DAV:quota-available-bytes and DAV:quota-used-bytes
 			// from https://www.rfc-editor.org/rfc/rfc4331.html#section-2
 			// propstatOK.Prop = append(propstatOK.Prop, s.newProp("d:quota-used-bytes", size))
-			// propstatOK.Prop = append(propstatOK.Prop, s.newProp("d:quota-available-bytes", quota))
+			// propstatOK.Prop = append(propstatOK.Prop, s.newProp("d:quota-available-bytes", s.quotaAvailableBytes(ctx, md)))
 		} else {
 			propstatOK.Prop = append(propstatOK.Prop,
 				s.newProp("d:resourcetype", ""),
@@ -603,6 +637,9 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 			if md.MimeType != "" {
 				propstatOK.Prop = append(propstatOK.Prop, s.newProp("d:getcontenttype", md.MimeType))
 			}
+			if md.Type == provider.ResourceType_RESOURCE_TYPE_SYMLINK && md.Target != "" {
+				propstatOK.Prop = append(propstatOK.Prop, s.newProp("oc:symlink-target", md.Target))
+			}
 		}
 		// Finder needs the getLastModified property to work.
 		if md.Mtime != nil {
@@ -654,8 +691,19 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 			} else {
 				propstatOK.Prop = append(propstatOK.Prop, s.newProp("oc:favorite", "0"))
 			}
+			if protect.IsProtected(md) {
+				propstatOK.Prop = append(propstatOK.Prop, s.newProp("oc:protected", "1"))
+			} else {
+				propstatOK.Prop = append(propstatOK.Prop, s.newProp("oc:protected", "0"))
+			}
+			if until, ok := retention.RetainedUntil(md); ok {
+				propstatOK.Prop = append(propstatOK.Prop, s.newProp("oc:retention-until", until.UTC().Format(time.RFC3339)))
+			}
 		}
 		// TODO return other properties ... but how do we put them in a namespace?
+		if s.strictCompliance(ctx) {
+			propstatOK.Prop = davProps(propstatOK.Prop)
+		}
 	} else {
 		// otherwise return only the requested properties
 		for i := range pf.Prop {
@@ -835,6 +883,34 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 					} else {
 						propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("oc:"+pf.Prop[i].Local, ""))
 					}
+				case "protected": // web, set by the managers of the space
+					if ls == nil {
+						if protect.IsProtected(md) {
+							propstatOK.Prop = append(propstatOK.Prop, s.newProp("oc:protected", "1"))
+						} else {
+							propstatOK.Prop = append(propstatOK.Prop, s.newProp("oc:protected", "0"))
+						}
+					} else {
+						propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("oc:protected", ""))
+					}
+				case "retention-until": // web, set by privileged users
+					if until, ok := retention.RetainedUntil(md); ok && ls == nil {
+						propstatOK.Prop = append(propstatOK.Prop, s.newProp("oc:retention-until", until.UTC().Format(time.RFC3339)))
+					} else {
+						propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("oc:retention-until", ""))
+					}
+				case "scan-status": // web, pending, clean or infected
+					if st := antivirus.StatusFromResourceInfo(md); st != "" {
+						propstatOK.Prop = append(propstatOK.Prop, s.newProp("oc:scan-status", string(st)))
+					} else {
+						propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("oc:scan-status", ""))
+					}
+				case "symlink-target": // web, the path of the target of a symlink in the same storage
+					if md.Type == provider.ResourceType_RESOURCE_TYPE_SYMLINK && md.Target != "" {
+						propstatOK.Prop = append(propstatOK.Prop, s.newProp("oc:symlink-target", md.Target))
+					} else {
+						propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("oc:symlink-target", ""))
+					}
 				case "owner-display-name": // phoenix only
 					if md.Owner != nil {
 						if isCurrentUserOwner(ctx, md.Owner) {
@@ -955,7 +1031,7 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 				case "quota-available-bytes": // RFC 4331
 					if md.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
 						// oc10 returns -3 for unlimited, -2 for unknown, -1 for uncalculated
-						propstatOK.Prop = append(propstatOK.Prop, s.newProp("d:quota-available-bytes", quota))
+						propstatOK.Prop = append(propstatOK.Prop, s.newProp("d:quota-available-bytes", s.quotaAvailableBytes(ctx, md)))
 					} else {
 						propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("d:quota-available-bytes", ""))
 					}
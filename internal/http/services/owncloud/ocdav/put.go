@@ -20,19 +20,23 @@ package ocdav
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"path"
 	"strconv"
 	"strings"
 	"time"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/internal/grpc/services/storageprovider"
 	"github.com/cs3org/reva/internal/http/services/datagateway"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/download"
 	"github.com/cs3org/reva/pkg/storage/utils/chunking"
 	rtrace "github.com/cs3org/reva/pkg/trace"
 	"github.com/cs3org/reva/pkg/utils"
@@ -112,6 +116,9 @@ func (s *svc) handlePathPut(w http.ResponseWriter, r *http.Request, ns string) {
 	fn := path.Join(ns, r.URL.Path)
 
 	sublog := appctx.GetLogger(ctx).With().Str("path", fn).Logger()
+	if !s.checkFilename(w, fn, &sublog) {
+		return
+	}
 
 	ref := &provider.Reference{Path: fn}
 
@@ -153,6 +160,12 @@ func (s *svc) handlePut(ctx context.Context, w http.ResponseWriter, r *http.Requ
 	if info != nil {
 		if info.Type != provider.ResourceType_RESOURCE_TYPE_FILE {
 			log.Debug().Msg("resource is not a file")
+			if info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER && s.strictCompliance(ctx) {
+				// PUT is not defined for collections
+				w.Header().Set("Allow", "OPTIONS, GET, HEAD, DELETE, PROPPATCH, COPY, MOVE, LOCK, UNLOCK, PROPFIND")
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
 			w.WriteHeader(http.StatusConflict)
 			return
 		}
@@ -179,38 +192,33 @@ func (s *svc) handlePut(ctx context.Context, w http.ResponseWriter, r *http.Requ
 			Decoder: "plain",
 			Value:   []byte(mtime),
 		}
-
-		// TODO: find a way to check if the storage really accepted the value
-		w.Header().Set(HeaderOCMtime, "accepted")
 	}
 
 	// curl -X PUT https://demo.owncloud.com/remote.php/webdav/testcs.bin -u demo:demo -d '123' -v -H 'OC-Checksum: SHA1:40bd001563085fc35165329ea1ff5c5ecbdbbeef'
 
-	var cparts []string
+	var checksum *uploadChecksum
 	// TUS Upload-Checksum header takes precedence
-	if checksum := r.Header.Get(HeaderUploadChecksum); checksum != "" {
-		cparts = strings.SplitN(checksum, " ", 2)
-		if len(cparts) != 2 {
-			log.Debug().Str("upload-checksum", checksum).Msg("invalid Upload-Checksum format, expected '[algorithm] [checksum]'")
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
+	if v := r.Header.Get(HeaderUploadChecksum); v != "" {
+		checksum, err = parseUploadChecksum(v)
 		// Then try owncloud header
-	} else if checksum := r.Header.Get(HeaderOCChecksum); checksum != "" {
-		cparts = strings.SplitN(checksum, ":", 2)
-		if len(cparts) != 2 {
-			log.Debug().Str("oc-checksum", checksum).Msg("invalid OC-Checksum format, expected '[algorithm]:[checksum]'")
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
+	} else if v := r.Header.Get(HeaderOCChecksum); v != "" {
+		checksum, err = parseOCChecksum(v)
 	}
-	// we do not check the algorithm here, because it might depend on the storage
-	if len(cparts) == 2 {
-		// Translate into TUS style Upload-Checksum header
+	if err != nil {
+		log.Debug().Err(err).Msg("invalid checksum")
+		w.WriteHeader(http.StatusBadRequest)
+		b, err := Marshal(exception{
+			code:    SabredavBadRequest,
+			message: err.Error(),
+		})
+		HandleWebdavError(&log, w, b, err)
+		return
+	}
+	if checksum != nil {
+		// Translate into TUS style Upload-Checksum header, verified by the storage
 		opaqueMap[HeaderUploadChecksum] = &typespb.OpaqueEntry{
 			Decoder: "plain",
-			// algorithm is always lowercase, checksum is separated by space
-			Value: []byte(strings.ToLower(cparts[0]) + " " + cparts[1]),
+			Value:   []byte(checksum.opaque()),
 		}
 	}
 
@@ -257,6 +265,10 @@ func (s *svc) handlePut(ctx context.Context, w http.ResponseWriter, r *http.Requ
 		return
 	}
 	httpReq.Header.Set(datagateway.TokenTransportHeader, token)
+	if checksum != nil {
+		// have the data server verify the content while receiving it
+		httpReq.Header.Set(HeaderUploadChecksum, checksum.header())
+	}
 
 	httpRes, err := s.client.Do(httpReq)
 	if err != nil {
@@ -271,14 +283,20 @@ func (s *svc) handlePut(ctx context.Context, w http.ResponseWriter, r *http.Requ
 			return
 		}
 		if httpRes.StatusCode == errtypes.StatusChecksumMismatch {
-			w.WriteHeader(http.StatusBadRequest)
+			w.WriteHeader(http.StatusPreconditionFailed)
 			b, err := Marshal(exception{
-				code:    SabredavBadRequest,
+				code:    SabredavPreconditionFailed,
 				message: "The computed checksum does not match the one received from the client.",
 			})
 			HandleWebdavError(&log, w, b, err)
 			return
 		}
+		if reason := httpRes.Header.Get(download.HeaderBlocked); reason != "" {
+			// the virus scan of the data server rejected the content
+			w.Header().Set(download.HeaderBlocked, reason)
+			w.WriteHeader(httpRes.StatusCode)
+			return
+		}
 		log.Error().Err(err).Msg("PUT request to data server failed")
 		w.WriteHeader(httpRes.StatusCode)
 		return
@@ -313,10 +331,20 @@ func (s *svc) handlePut(ctx context.Context, w http.ResponseWriter, r *http.Requ
 
 	newInfo := sRes.Info
 
+	if mtime := r.Header.Get(HeaderOCMtime); mtime != "" {
+		var accepted bool
+		if newInfo, accepted = s.applyMtime(ctx, client, sReq.Ref, newInfo, mtime); accepted {
+			w.Header().Set(HeaderOCMtime, "accepted")
+		}
+	}
+
 	w.Header().Add(HeaderContentType, newInfo.MimeType)
 	w.Header().Set(HeaderETag, newInfo.Etag)
 	w.Header().Set(HeaderOCFileID, resourceid.OwnCloudResourceIDWrap(newInfo.Id))
 	w.Header().Set(HeaderOCETag, newInfo.Etag)
+	if newInfo.Checksum != nil && newInfo.Checksum.Sum != "" {
+		w.Header().Set(HeaderOCChecksum, fmt.Sprintf("%s:%s", strings.ToUpper(string(storageprovider.GRPC2PKGXS(newInfo.Checksum.Type))), newInfo.Checksum.Sum))
+	}
 	t := utils.TSToTime(newInfo.Mtime).UTC()
 	lastModifiedString := t.Format(time.RFC1123Z)
 	w.Header().Set(HeaderLastModified, lastModifiedString)
@@ -331,6 +359,45 @@ func (s *svc) handlePut(ctx context.Context, w http.ResponseWriter, r *http.Requ
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// applyMtime makes sure the modification time sent by the client in the
+// X-OC-Mtime header has been persisted, explicitly setting it on storage
+// drivers that did not honor it during the upload or move. It returns the
+// updated resource info and whether the mtime was accepted.
+func (s *svc) applyMtime(ctx context.Context, client gateway.GatewayAPIClient, ref *provider.Reference, info *provider.ResourceInfo, mtime string) (*provider.ResourceInfo, bool) {
+	log := appctx.GetLogger(ctx)
+
+	sec, err := strconv.ParseUint(strings.SplitN(mtime, ".", 2)[0], 10, 64)
+	if err != nil {
+		log.Debug().Str("mtime", mtime).Msg("ignoring invalid mtime")
+		return info, false
+	}
+	if info.Mtime != nil && info.Mtime.Seconds == sec {
+		return info, true
+	}
+
+	res, err := client.SetArbitraryMetadata(ctx, &provider.SetArbitraryMetadataRequest{
+		Ref: ref,
+		ArbitraryMetadata: &provider.ArbitraryMetadata{
+			Metadata: map[string]string{"mtime": mtime},
+		},
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("error sending grpc set arbitrary metadata request")
+		return info, false
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		log.Debug().Interface("status", res.Status).Msg("storage did not accept the mtime")
+		return info, false
+	}
+
+	sRes, err := client.Stat(ctx, &provider.StatRequest{Ref: ref})
+	if err != nil || sRes.Status.Code != rpc.Code_CODE_OK {
+		log.Error().Err(err).Msg("error stating resource after setting the mtime")
+		return info, true
+	}
+	return sRes.Info, true
+}
+
 func (s *svc) handleSpacesPut(w http.ResponseWriter, r *http.Request, spaceID string) {
 	ctx, span := rtrace.Provider.Tracer("ocdav").Start(r.Context(), "spaces_put")
 	defer span.End()
@@ -0,0 +1,112 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+// quotaAvailableBytes returns the DAV:quota-available-bytes property of a collection, see RFC 4331.
+// It is computed from the quota of the storage holding the collection. As all collections listed by
+// a PROPFIND usually live in the same storage, the quota is cached per user and storage.
+func (s *svc) quotaAvailableBytes(ctx context.Context, md *provider.ResourceInfo) string {
+	if md.Id == nil {
+		return opaqueQuota(md)
+	}
+
+	key := quotaCacheKey(ctx, md.Id)
+	if s.quotaCache != nil {
+		if v, err := s.quotaCache.Get(key); err == nil {
+			if available := v.(string); available != "" {
+				return available
+			}
+			return opaqueQuota(md)
+		}
+	}
+
+	available := s.fetchQuotaAvailableBytes(ctx, md.Id)
+	if s.quotaCache != nil {
+		// failures are cached as well, so that storages without quota are not asked for every collection
+		_ = s.quotaCache.Set(key, available)
+	}
+	if available == "" {
+		return opaqueQuota(md)
+	}
+	return available
+}
+
+// fetchQuotaAvailableBytes asks the storage for its quota; an empty string is returned if it can't tell.
+func (s *svc) fetchQuotaAvailableBytes(ctx context.Context, id *provider.ResourceId) string {
+	log := appctx.GetLogger(ctx)
+
+	client, err := s.getClient()
+	if err != nil {
+		log.Error().Err(err).Msg("error getting grpc client")
+		return ""
+	}
+	res, err := client.GetQuota(ctx, &gateway.GetQuotaRequest{Ref: &provider.Reference{ResourceId: id}})
+	switch {
+	case err != nil:
+		log.Error().Err(err).Interface("id", id).Msg("error sending get quota grpc request")
+		return ""
+	case res.Status.Code != rpc.Code_CODE_OK:
+		log.Debug().Interface("id", id).Interface("status", res.Status).Msg("storage did not report its quota")
+		return ""
+	}
+	return availableBytes(res.TotalBytes, res.UsedBytes)
+}
+
+// availableBytes computes the free space from the quota reported by a storage. Storages report
+// a total of 0 if they don't know it, in which case an empty string is returned.
+func availableBytes(total, used uint64) string {
+	q := storage.Quota{Total: total, Used: used}
+	if !q.IsKnown() {
+		return ""
+	}
+	return strconv.FormatUint(q.Free(), 10)
+}
+
+// opaqueQuota returns the quota flag a storage may have put in the opaque data of a resource,
+// i.e. -1 for uncalculated, -2 for unknown or -3 for unlimited, and unknown otherwise.
+// Positive values are quota limits, which are not the available bytes.
+func opaqueQuota(md *provider.ResourceInfo) string {
+	if md.Opaque != nil && md.Opaque.Map != nil {
+		if q := md.Opaque.Map["quota"]; q != nil && q.Decoder == "plain" && strings.HasPrefix(string(q.Value), "-") {
+			return string(q.Value)
+		}
+	}
+	return _propQuotaUnknown
+}
+
+func quotaCacheKey(ctx context.Context, id *provider.ResourceId) string {
+	// the quota of a shared storage like a home provider depends on the user
+	if u, ok := ctxpkg.ContextGetUser(ctx); ok && u.Id != nil {
+		return u.Id.Idp + "!" + u.Id.OpaqueId + "!" + id.StorageId
+	}
+	return "!" + id.StorageId
+}
@@ -0,0 +1,61 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+func TestAvailableBytes(t *testing.T) {
+	tests := []struct {
+		total, used uint64
+		expected    string
+	}{
+		{total: 0, used: 10, expected: ""},
+		{total: 100, used: 40, expected: "60"},
+		{total: 100, used: 100, expected: "0"},
+		{total: 100, used: 150, expected: "0"},
+	}
+	for _, tt := range tests {
+		if available := availableBytes(tt.total, tt.used); available != tt.expected {
+			t.Errorf("availableBytes(%d, %d) = %q, expected %q", tt.total, tt.used, available, tt.expected)
+		}
+	}
+}
+
+func TestOpaqueQuota(t *testing.T) {
+	withQuota := func(v string) *provider.ResourceInfo {
+		return &provider.ResourceInfo{Opaque: &typesv1beta1.Opaque{Map: map[string]*typesv1beta1.OpaqueEntry{
+			"quota": {Decoder: "plain", Value: []byte(v)},
+		}}}
+	}
+
+	if q := opaqueQuota(&provider.ResourceInfo{}); q != _propQuotaUnknown {
+		t.Errorf("expected unknown quota without opaque data, got %s", q)
+	}
+	if q := opaqueQuota(withQuota("-3")); q != "-3" {
+		t.Errorf("expected the unlimited flag to be passed on, got %s", q)
+	}
+	if q := opaqueQuota(withQuota("1000")); q != _propQuotaUnknown {
+		t.Errorf("expected a quota limit not to be reported as available bytes, got %s", q)
+	}
+}
@@ -19,21 +19,28 @@
 package ocdav
 
 import (
+	"context"
 	"encoding/xml"
 	"io"
 	"net/http"
 	"strings"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	rpcv1beta1 "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	providerv1beta1 "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	searchpb "github.com/cs3org/reva/pkg/search/proto"
 )
 
 const (
 	elementNameSearchFiles = "search-files"
 	elementNameFilterFiles = "filter-files"
+
+	// defaultSearchLimit is the number of matches returned when the client does not ask for a limit.
+	defaultSearchLimit = 50
 )
 
 func (s *svc) handleReport(w http.ResponseWriter, r *http.Request, ns string) {
@@ -48,7 +55,7 @@ func (s *svc) handleReport(w http.ResponseWriter, r *http.Request, ns string) {
 		return
 	}
 	if rep.SearchFiles != nil {
-		s.doSearchFiles(w, r, rep.SearchFiles)
+		s.doSearchFiles(w, r, rep.SearchFiles, ns)
 		return
 	}
 
@@ -62,16 +69,55 @@ func (s *svc) handleReport(w http.ResponseWriter, r *http.Request, ns string) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
-func (s *svc) doSearchFiles(w http.ResponseWriter, r *http.Request, sf *reportSearchFiles) {
+func (s *svc) doSearchFiles(w http.ResponseWriter, r *http.Request, sf *reportSearchFiles, namespace string) {
 	ctx := r.Context()
 	log := appctx.GetLogger(ctx)
-	_, err := s.getClient()
+	if s.c.SearchSvc == "" {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	searchClient, err := pool.GetSearchServiceClient(pool.Endpoint(s.c.SearchSvc))
 	if err != nil {
-		log.Error().Err(err).Msg("error getting grpc client")
+		log.Error().Err(err).Msg("error getting search client")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	w.WriteHeader(http.StatusNotImplemented)
+	limit := sf.Search.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	res, err := searchClient.Search(ctx, &searchpb.SearchRequest{
+		Query:  sf.Search.Pattern,
+		Limit:  uint32(limit),
+		Offset: uint32(sf.Search.Offset),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("error searching")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if res.Status.Code != rpcv1beta1.Code_CODE_OK {
+		log.Error().Interface("status", res.Status).Msg("error searching")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	client, err := s.getClient()
+	if err != nil {
+		log.Error().Err(err).Msg("error getting gateway client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	// the matches are stat'ed as the user, which drops the ones which are
+	// gone or not accessible anymore since they were indexed
+	ids := make([]*provider.ResourceId, 0, len(res.Matches))
+	for _, m := range res.Matches {
+		ids = append(ids, m.Id)
+	}
+	infos := s.statResources(ctx, client, ids)
+
+	s.writeReportResponse(w, r, &propfindXML{Prop: sf.Prop}, infos, namespace)
 }
 
 func (s *svc) doFilterFiles(w http.ResponseWriter, r *http.Request, ff *reportFilterFiles, namespace string) {
@@ -94,46 +140,59 @@ func (s *svc) doFilterFiles(w http.ResponseWriter, r *http.Request, ff *reportFi
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		infos := s.statResources(ctx, client, favorites)
 
-		infos := make([]*provider.ResourceInfo, 0, len(favorites))
-		for i := range favorites {
-			statRes, err := client.Stat(ctx, &providerv1beta1.StatRequest{Ref: &providerv1beta1.Reference{ResourceId: favorites[i]}})
-			if err != nil {
-				log.Error().Err(err).Msg("error getting resource info")
-				continue
-			}
-			if statRes.Status.Code != rpcv1beta1.Code_CODE_OK {
-				log.Error().Interface("stat_response", statRes).Msg("error getting resource info")
-				continue
-			}
-
-			// If global URLs are not supported, return only the file path
-			if s.c.WebdavNamespace != "" {
-				// The paths we receive have the format /user/<username>/<filepath>
-				// We only want the `<filepath>` part. Thus we remove the /user/<username>/ part.
-				parts := strings.SplitN(statRes.Info.Path, "/", 4)
-				if len(parts) != 4 {
-					log.Error().Str("path", statRes.Info.Path).Msg("path doesn't have the expected format")
-					continue
-				}
-				statRes.Info.Path = parts[3]
-			}
-
-			infos = append(infos, statRes.Info)
-		}
+		s.writeReportResponse(w, r, &propfindXML{Prop: ff.Prop}, infos, namespace)
+	}
+}
 
-		responsesXML, err := s.multistatusResponse(ctx, &propfindXML{Prop: ff.Prop}, infos, namespace, nil, nil)
+// statResources returns the infos of the given resources, leaving out the
+// ones which cannot be stat'ed.
+func (s *svc) statResources(ctx context.Context, client gateway.GatewayAPIClient, ids []*provider.ResourceId) []*provider.ResourceInfo {
+	log := appctx.GetLogger(ctx)
+	infos := make([]*provider.ResourceInfo, 0, len(ids))
+	for i := range ids {
+		statRes, err := client.Stat(ctx, &providerv1beta1.StatRequest{Ref: &providerv1beta1.Reference{ResourceId: ids[i]}})
 		if err != nil {
-			log.Error().Err(err).Msg("error formatting propfind")
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+			log.Error().Err(err).Msg("error getting resource info")
+			continue
+		}
+		if statRes.Status.Code != rpcv1beta1.Code_CODE_OK {
+			log.Error().Interface("stat_response", statRes).Msg("error getting resource info")
+			continue
 		}
-		w.Header().Set(HeaderDav, "1, 3, extended-mkcol")
-		w.Header().Set(HeaderContentType, "application/xml; charset=utf-8")
-		w.WriteHeader(http.StatusMultiStatus)
-		if _, err := w.Write([]byte(responsesXML)); err != nil {
-			log.Err(err).Msg("error writing response")
+
+		// If global URLs are not supported, return only the file path
+		if s.c.WebdavNamespace != "" {
+			// The paths we receive have the format /user/<username>/<filepath>
+			// We only want the `<filepath>` part. Thus we remove the /user/<username>/ part.
+			parts := strings.SplitN(statRes.Info.Path, "/", 4)
+			if len(parts) != 4 {
+				log.Error().Str("path", statRes.Info.Path).Msg("path doesn't have the expected format")
+				continue
+			}
+			statRes.Info.Path = parts[3]
 		}
+
+		infos = append(infos, statRes.Info)
+	}
+	return infos
+}
+
+func (s *svc) writeReportResponse(w http.ResponseWriter, r *http.Request, pf *propfindXML, infos []*provider.ResourceInfo, namespace string) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+	responsesXML, err := s.multistatusResponse(ctx, pf, infos, namespace, nil, nil)
+	if err != nil {
+		log.Error().Err(err).Msg("error formatting propfind")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(HeaderDav, "1, 3, extended-mkcol")
+	w.Header().Set(HeaderContentType, "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	if _, err := w.Write([]byte(responsesXML)); err != nil {
+		log.Err(err).Msg("error writing response")
 	}
 }
 
@@ -149,7 +208,7 @@ type reportSearchFiles struct {
 	Search  reportSearchFilesSearch `xml:"search"`
 }
 type reportSearchFilesSearch struct {
-	Pattern string `xml:"search"`
+	Pattern string `xml:"pattern"`
 	Limit   int    `xml:"limit"`
 	Offset  int    `xml:"offset"`
 }
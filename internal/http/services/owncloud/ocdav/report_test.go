@@ -61,3 +61,30 @@ func TestUnmarshallReportFilterFiles(t *testing.T) {
 		t.Error("Failed to correctly unmarshal filter-rules. Favorite is expected to be true.")
 	}
 }
+
+func TestUnmarshallReportSearchFiles(t *testing.T) {
+	sfXML := `<oc:search-files xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns">
+    <d:prop>
+        <d:getlastmodified />
+        <oc:fileid />
+    </d:prop>
+    <oc:search>
+        <oc:pattern>report</oc:pattern>
+        <oc:limit>30</oc:limit>
+        <oc:offset>60</oc:offset>
+    </oc:search>
+</oc:search-files>`
+
+	report, status, err := readReport(strings.NewReader(sfXML))
+	if status != 0 || err != nil {
+		t.Fatal("Failed to unmarshal search-files xml")
+	}
+
+	if report.SearchFiles == nil {
+		t.Fatal("Failed to unmarshal search-files xml. SearchFiles is nil")
+	}
+
+	if report.SearchFiles.Search.Pattern != "report" || report.SearchFiles.Search.Limit != 30 || report.SearchFiles.Search.Offset != 60 {
+		t.Errorf("Failed to correctly unmarshal the search. Got %+v", report.SearchFiles.Search)
+	}
+}
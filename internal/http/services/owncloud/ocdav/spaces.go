@@ -65,9 +65,9 @@ func (h *SpacesHandler) Handler(s *svc) http.Handler {
 		case MethodProppatch:
 			s.handleSpacesProppatch(w, r, spaceID)
 		case MethodLock:
-			s.handleLock(w, r, spaceID)
+			s.handleSpacesLock(w, r, spaceID)
 		case MethodUnlock:
-			s.handleUnlock(w, r, spaceID)
+			s.handleSpacesUnlock(w, r, spaceID)
 		case MethodMkcol:
 			s.handleSpacesMkCol(w, r, spaceID)
 		case MethodMove:
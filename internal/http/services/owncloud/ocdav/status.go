@@ -38,6 +38,9 @@ func (s *svc) doStatus(w http.ResponseWriter, r *http.Request) {
 		ProductName:    "reva",
 		Product:        "reva",
 	}
+	if o, ok := s.tenants.Overrides(s.tenants.OfRequest(r)); ok && o.Name != "" {
+		status.ProductName = o.Name
+	}
 
 	statusJSON, err := json.MarshalIndent(status, "", "    ")
 	if err != nil {
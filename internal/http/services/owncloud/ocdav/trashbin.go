@@ -129,41 +129,129 @@ func (h *TrashbinHandler) Handler(s *svc) http.Handler {
 			basePath = getHomeRes.Path
 		}
 
-		if r.Method == MethodPropfind {
-			h.listTrashbin(w, r, s, u, basePath, key, r.URL.Path)
+		trashBase := ctx.Value(ctxKeyBaseURI).(string)
+		h.handle(w, r, s, u, &trashScope{
+			name:     username,
+			ref:      &provider.Reference{Path: basePath},
+			filesURI: path.Join(path.Dir(trashBase), "files", username),
+		}, key)
+	})
+}
+
+// SpaceHandler handles requests to the trash of a space. Only the managers of
+// the space may access it, they see the items deleted by all of its members.
+func (h *TrashbinHandler) SpaceHandler(s *svc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := appctx.GetLogger(ctx)
+
+		if r.Method == http.MethodOptions {
+			s.handleOptions(w, r)
 			return
 		}
-		if key != "" && r.Method == MethodMove {
-			// find path in url relative to trash base
-			trashBase := ctx.Value(ctxKeyBaseURI).(string)
-			baseURI := path.Join(path.Dir(trashBase), "files", username)
-			ctx = context.WithValue(ctx, ctxKeyBaseURI, baseURI)
-			r = r.WithContext(ctx)
-
-			// TODO make request.php optional in destination header
-			dst, err := extractDestination(r)
-			if err != nil {
-				w.WriteHeader(http.StatusBadRequest)
-				return
-			}
-			dst = path.Clean(dst)
 
-			log.Debug().Str("key", key).Str("dst", dst).Msg("restore")
+		var spaceID string
+		spaceID, r.URL.Path = router.ShiftPath(r.URL.Path)
 
-			h.restore(w, r, s, u, basePath, dst, key, r.URL.Path)
+		if spaceID == "" {
+			// listing is disabled, no auth will change that
+			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
 
-		if r.Method == http.MethodDelete {
-			h.delete(w, r, s, u, basePath, key, r.URL.Path)
+		u, ok := ctxpkg.ContextGetUser(ctx)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		ref, rpcStatus, err := s.lookUpStorageSpaceReference(ctx, spaceID, ".")
+		if err != nil {
+			log.Error().Err(err).Str("space", spaceID).Msg("error looking up the space")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if rpcStatus.Code != rpc.Code_CODE_OK {
+			HandleErrorStatus(log, w, rpcStatus)
 			return
 		}
 
-		http.Error(w, "501 Not implemented", http.StatusNotImplemented)
+		var key string
+		key, r.URL.Path = router.ShiftPath(r.URL.Path)
+
+		// the spaces endpoint lives next to the trash-bin of the spaces
+		trashBase := ctx.Value(ctxKeyBaseURI).(string)
+		h.handle(w, r, s, u, &trashScope{
+			name:     spaceID,
+			ref:      ref,
+			filesURI: path.Join(path.Dir(trashBase), spaceID),
+		}, key)
 	})
 }
 
-func (h *TrashbinHandler) listTrashbin(w http.ResponseWriter, r *http.Request, s *svc, u *userpb.User, basePath, key, itemPath string) {
+// trashScope is the trash a request is about, either the one of the current
+// user or the one of a space.
+type trashScope struct {
+	// name follows the trash base uri in hrefs, the username or the space id
+	name string
+	// ref references the storage holding the trash
+	ref *provider.Reference
+	// filesURI is the base uri of the restore destinations
+	filesURI string
+}
+
+// basePath is the prefix the storage reports the original locations with
+func (t *trashScope) basePath() string {
+	if t.ref.ResourceId != nil {
+		return "."
+	}
+	return t.ref.Path
+}
+
+// destination returns a reference to a destination relative to the scope
+func (t *trashScope) destination(p string) *provider.Reference {
+	if t.ref.ResourceId != nil {
+		return &provider.Reference{ResourceId: t.ref.ResourceId, Path: utils.MakeRelativePath(p)}
+	}
+	return &provider.Reference{Path: path.Join(t.ref.Path, p)}
+}
+
+func (h *TrashbinHandler) handle(w http.ResponseWriter, r *http.Request, s *svc, u *userpb.User, scope *trashScope, key string) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	if r.Method == MethodPropfind {
+		h.listTrashbin(w, r, s, u, scope, key, r.URL.Path)
+		return
+	}
+	if key != "" && r.Method == MethodMove {
+		// find path in url relative to trash base
+		ctx = context.WithValue(ctx, ctxKeyBaseURI, scope.filesURI)
+		r = r.WithContext(ctx)
+
+		// TODO make request.php optional in destination header
+		dst, err := extractDestination(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		dst = path.Clean(dst)
+
+		log.Debug().Str("key", key).Str("dst", dst).Msg("restore")
+
+		h.restore(w, r, s, u, scope, dst, key, r.URL.Path)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		h.delete(w, r, s, u, scope, key, r.URL.Path)
+		return
+	}
+
+	http.Error(w, "501 Not implemented", http.StatusNotImplemented)
+}
+
+func (h *TrashbinHandler) listTrashbin(w http.ResponseWriter, r *http.Request, s *svc, u *userpb.User, scope *trashScope, key, itemPath string) {
 	ctx, span := rtrace.Provider.Tracer("trash-bin").Start(r.Context(), "list_trashbin")
 	defer span.End()
 
@@ -182,7 +270,7 @@ func (h *TrashbinHandler) listTrashbin(w http.ResponseWriter, r *http.Request, s
 	}
 
 	if depth == "0" {
-		propRes, err := h.formatTrashPropfind(ctx, s, u, nil, nil, basePath)
+		propRes, err := h.formatTrashPropfind(ctx, s, scope, nil, nil)
 		if err != nil {
 			sublog.Error().Err(err).Msg("error formatting propfind")
 			w.WriteHeader(http.StatusInternalServerError)
@@ -217,7 +305,7 @@ func (h *TrashbinHandler) listTrashbin(w http.ResponseWriter, r *http.Request, s
 	}
 
 	// ask gateway for recycle items
-	getRecycleRes, err := gc.ListRecycle(ctx, &provider.ListRecycleRequest{Ref: &provider.Reference{Path: basePath}, Key: path.Join(key, itemPath)})
+	getRecycleRes, err := gc.ListRecycle(ctx, &provider.ListRecycleRequest{Ref: scope.ref, Key: path.Join(key, itemPath)})
 
 	if err != nil {
 		sublog.Error().Err(err).Msg("error calling ListRecycle")
@@ -245,7 +333,7 @@ func (h *TrashbinHandler) listTrashbin(w http.ResponseWriter, r *http.Request, s
 
 		for len(stack) > 0 {
 			key := stack[len(stack)-1]
-			getRecycleRes, err := gc.ListRecycle(ctx, &provider.ListRecycleRequest{Ref: &provider.Reference{Path: basePath}, Key: key})
+			getRecycleRes, err := gc.ListRecycle(ctx, &provider.ListRecycleRequest{Ref: scope.ref, Key: key})
 			if err != nil {
 				sublog.Error().Err(err).Msg("error calling ListRecycle")
 				w.WriteHeader(http.StatusInternalServerError)
@@ -270,7 +358,7 @@ func (h *TrashbinHandler) listTrashbin(w http.ResponseWriter, r *http.Request, s
 		}
 	}
 
-	propRes, err := h.formatTrashPropfind(ctx, s, u, &pf, items, basePath)
+	propRes, err := h.formatTrashPropfind(ctx, s, scope, &pf, items)
 	if err != nil {
 		sublog.Error().Err(err).Msg("error formatting propfind")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -286,7 +374,7 @@ func (h *TrashbinHandler) listTrashbin(w http.ResponseWriter, r *http.Request, s
 	}
 }
 
-func (h *TrashbinHandler) formatTrashPropfind(ctx context.Context, s *svc, u *userpb.User, pf *propfindXML, items []*provider.RecycleItem, basePath string) (string, error) {
+func (h *TrashbinHandler) formatTrashPropfind(ctx context.Context, s *svc, scope *trashScope, pf *propfindXML, items []*provider.RecycleItem) (string, error) {
 	responses := make([]*responseXML, 0, len(items)+1)
 	// add trashbin dir . entry
 	responses = append(responses, &responseXML{
@@ -311,7 +399,7 @@ func (h *TrashbinHandler) formatTrashPropfind(ctx context.Context, s *svc, u *us
 	})
 
 	for i := range items {
-		res, err := h.itemToPropResponse(ctx, s, u, pf, items[i], basePath)
+		res, err := h.itemToPropResponse(ctx, s, scope, pf, items[i])
 		if err != nil {
 			return "", err
 		}
@@ -331,10 +419,10 @@ func (h *TrashbinHandler) formatTrashPropfind(ctx context.Context, s *svc, u *us
 // itemToPropResponse needs to create a listing that contains a key and destination
 // the key is the name of an entry in the trash listing
 // for now we need to limit trash to the users home, so we can expect all trash keys to have the home storage as the opaque id
-func (h *TrashbinHandler) itemToPropResponse(ctx context.Context, s *svc, u *userpb.User, pf *propfindXML, item *provider.RecycleItem, basePath string) (*responseXML, error) {
+func (h *TrashbinHandler) itemToPropResponse(ctx context.Context, s *svc, scope *trashScope, pf *propfindXML, item *provider.RecycleItem) (*responseXML, error) {
 
 	baseURI := ctx.Value(ctxKeyBaseURI).(string)
-	ref := path.Join(baseURI, u.Username, item.Key)
+	ref := path.Join(baseURI, scope.name, item.Key)
 	if item.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
 		ref += "/"
 	}
@@ -348,7 +436,7 @@ func (h *TrashbinHandler) itemToPropResponse(ctx context.Context, s *svc, u *use
 
 	t := utils.TSToTime(item.DeletionTime).UTC()
 	dTime := t.Format(time.RFC1123Z)
-	restorePath := strings.TrimPrefix(strings.TrimPrefix(item.Ref.Path, basePath), "/")
+	restorePath := strings.TrimPrefix(strings.TrimPrefix(item.Ref.Path, scope.basePath()), "/")
 
 	// when allprops has been requested
 	if pf.Allprop != nil {
@@ -441,7 +529,7 @@ func (h *TrashbinHandler) itemToPropResponse(ctx context.Context, s *svc, u *use
 	return &response, nil
 }
 
-func (h *TrashbinHandler) restore(w http.ResponseWriter, r *http.Request, s *svc, u *userpb.User, basePath, dst, key, itemPath string) {
+func (h *TrashbinHandler) restore(w http.ResponseWriter, r *http.Request, s *svc, u *userpb.User, scope *trashScope, dst, key, itemPath string) {
 	ctx, span := rtrace.Provider.Tracer("trash-bin").Start(r.Context(), "restore")
 	defer span.End()
 
@@ -466,9 +554,7 @@ func (h *TrashbinHandler) restore(w http.ResponseWriter, r *http.Request, s *svc
 		return
 	}
 
-	dstRef := &provider.Reference{
-		Path: path.Join(basePath, dst),
-	}
+	dstRef := scope.destination(dst)
 
 	dstStatReq := &provider.StatRequest{
 		Ref: dstRef,
@@ -490,7 +576,7 @@ func (h *TrashbinHandler) restore(w http.ResponseWriter, r *http.Request, s *svc
 	// restore location exists, and if it doesn't returns a conflict error code.
 	if dstStatRes.Status.Code == rpc.Code_CODE_NOT_FOUND && isNested(dst) {
 		parentStatReq := &provider.StatRequest{
-			Ref: &provider.Reference{Path: path.Join(basePath, filepath.Dir(dst))},
+			Ref: scope.destination(filepath.Dir(dst)),
 		}
 
 		parentStatResponse, err := client.Stat(ctx, parentStatReq)
@@ -536,16 +622,18 @@ func (h *TrashbinHandler) restore(w http.ResponseWriter, r *http.Request, s *svc
 		}
 	}
 
+	restoreRef := &provider.Reference{Path: dst}
+	if scope.ref.ResourceId != nil {
+		restoreRef = scope.destination(dst)
+	}
 	req := &provider.RestoreRecycleItemRequest{
 		// use the target path to find the storage provider
 		// this means we can only undelete on the same storage, not to a different folder
 		// use the key which is prefixed with the StoragePath to lookup the correct storage ...
 		// TODO currently limited to the home storage
-		Ref: &provider.Reference{
-			Path: basePath,
-		},
+		Ref:        scope.ref,
 		Key:        path.Join(key, itemPath),
-		RestoreRef: &provider.Reference{Path: dst},
+		RestoreRef: restoreRef,
 	}
 
 	res, err := client.RestoreRecycleItem(ctx, req)
@@ -589,7 +677,7 @@ func (h *TrashbinHandler) restore(w http.ResponseWriter, r *http.Request, s *svc
 }
 
 // delete has only a key
-func (h *TrashbinHandler) delete(w http.ResponseWriter, r *http.Request, s *svc, u *userpb.User, basePath, key, itemPath string) {
+func (h *TrashbinHandler) delete(w http.ResponseWriter, r *http.Request, s *svc, u *userpb.User, scope *trashScope, key, itemPath string) {
 	ctx, span := rtrace.Provider.Tracer("trash-bin").Start(r.Context(), "erase")
 	defer span.End()
 
@@ -606,9 +694,7 @@ func (h *TrashbinHandler) delete(w http.ResponseWriter, r *http.Request, s *svc,
 	// storage drives  PurgeRecycleItem key call
 
 	req := &provider.PurgeRecycleRequest{
-		Ref: &provider.Reference{
-			Path: basePath,
-		},
+		Ref: scope.ref,
 		Key: path.Join(key, itemPath),
 	}
 
@@ -622,9 +708,9 @@ func (h *TrashbinHandler) delete(w http.ResponseWriter, r *http.Request, s *svc,
 	case rpc.Code_CODE_OK:
 		w.WriteHeader(http.StatusNoContent)
 	case rpc.Code_CODE_NOT_FOUND:
-		sublog.Debug().Str("path", basePath).Str("key", key).Interface("status", res.Status).Msg("resource not found")
+		sublog.Debug().Str("path", scope.basePath()).Str("key", key).Interface("status", res.Status).Msg("resource not found")
 		w.WriteHeader(http.StatusConflict)
-		m := fmt.Sprintf("path %s not found", basePath)
+		m := fmt.Sprintf("path %s not found", scope.basePath())
 		b, err := Marshal(exception{
 			code:    SabredavConflict,
 			message: m,
@@ -33,6 +33,7 @@ import (
 	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rhttp"
 	rtrace "github.com/cs3org/reva/pkg/trace"
 	"github.com/cs3org/reva/pkg/utils"
@@ -58,6 +59,9 @@ func (s *svc) handlePathTusPost(w http.ResponseWriter, r *http.Request, ns strin
 	fn := path.Join(ns, r.URL.Path, meta["filename"])
 
 	sublog := appctx.GetLogger(ctx).With().Str("path", fn).Logger()
+	if !s.checkFilename(w, fn, &sublog) {
+		return
+	}
 	// check tus headers?
 
 	ref := &provider.Reference{Path: fn}
@@ -109,9 +113,23 @@ func (s *svc) handleTusPost(ctx context.Context, w http.ResponseWriter, r *http.
 		w.WriteHeader(http.StatusPreconditionFailed)
 		return
 	}
-	// r.Header.Get("OC-Checksum")
-	// TODO must be SHA1, ADLER32 or MD5 ... in capital letters????
-	// curl -X PUT https://demo.owncloud.com/remote.php/webdav/testcs.bin -u demo:demo -d '123' -v -H 'OC-Checksum: SHA1:40bd001563085fc35165329ea1ff5c5ecbdbbeef'
+	// the checksum of the whole file is sent in the metadata, the Upload-Checksum header only covers the uploaded chunk
+	var checksum, chunkChecksum *uploadChecksum
+	var err error
+	if v := meta["checksum"]; v != "" {
+		if checksum, err = parseOCChecksum(v); err != nil {
+			log.Debug().Err(err).Msg("invalid checksum in the upload metadata")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	if v := r.Header.Get(HeaderUploadChecksum); v != "" {
+		if chunkChecksum, err = parseUploadChecksum(v); err != nil {
+			log.Debug().Err(err).Msg("invalid Upload-Checksum")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
 
 	// TODO check Expect: 100-continue
 
@@ -171,6 +189,13 @@ func (s *svc) handleTusPost(ctx context.Context, w http.ResponseWriter, r *http.
 			Value:   []byte(mtime),
 		}
 	}
+	if checksum != nil {
+		// verified by the storage when the upload is finished
+		opaqueMap[HeaderUploadChecksum] = &typespb.OpaqueEntry{
+			Decoder: "plain",
+			Value:   []byte(checksum.opaque()),
+		}
+	}
 
 	// initiateUpload
 	uReq := &provider.InitiateFileUploadRequest{
@@ -242,6 +267,9 @@ func (s *svc) handleTusPost(ctx context.Context, w http.ResponseWriter, r *http.
 			httpReq.Header.Set(HeaderUploadOffset, "0")
 		}
 		httpReq.Header.Set(HeaderTusResumable, r.Header.Get(HeaderTusResumable))
+		if chunkChecksum != nil {
+			httpReq.Header.Set(HeaderUploadChecksum, chunkChecksum.header())
+		}
 
 		httpRes, err = s.client.Do(httpReq)
 		if err != nil {
@@ -254,6 +282,15 @@ func (s *svc) handleTusPost(ctx context.Context, w http.ResponseWriter, r *http.
 		w.Header().Set(HeaderUploadOffset, httpRes.Header.Get(HeaderUploadOffset))
 		w.Header().Set(HeaderTusResumable, httpRes.Header.Get(HeaderTusResumable))
 		w.Header().Set(HeaderTusUploadExpires, httpRes.Header.Get(HeaderTusUploadExpires))
+		if httpRes.StatusCode == errtypes.StatusTusChecksumMismatch {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			b, err := Marshal(exception{
+				code:    SabredavPreconditionFailed,
+				message: "The computed checksum does not match the one received from the client.",
+			})
+			HandleWebdavError(&log, w, b, err)
+			return
+		}
 		if httpRes.StatusCode != http.StatusNoContent {
 			w.WriteHeader(httpRes.StatusCode)
 			return
@@ -290,9 +327,11 @@ func (s *svc) handleTusPost(ctx context.Context, w http.ResponseWriter, r *http.
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
-			if httpRes != nil && httpRes.Header != nil && httpRes.Header.Get(HeaderOCMtime) != "" {
-				// set the "accepted" value if returned in the upload response headers
-				w.Header().Set(HeaderOCMtime, httpRes.Header.Get(HeaderOCMtime))
+			if mtime != "" {
+				var accepted bool
+				if info, accepted = s.applyMtime(ctx, client, sReq.Ref, info, mtime); accepted {
+					w.Header().Set(HeaderOCMtime, "accepted")
+				}
 			}
 
 			// get WebDav permissions for file
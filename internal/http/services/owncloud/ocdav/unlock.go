@@ -19,10 +19,90 @@
 package ocdav
 
 import (
+	"context"
 	"net/http"
+	"path"
+	"strings"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	rtrace "github.com/cs3org/reva/pkg/trace"
+	"github.com/rs/zerolog"
 )
 
-// TODO(jfd): implement unlock
-func (s *svc) handleUnlock(w http.ResponseWriter, r *http.Request, ns string) {
-	w.WriteHeader(http.StatusNotImplemented)
+func (s *svc) handlePathUnlock(w http.ResponseWriter, r *http.Request, ns string) {
+	fn := path.Join(ns, r.URL.Path)
+
+	sublog := appctx.GetLogger(r.Context()).With().Str("path", fn).Logger()
+	ref := &provider.Reference{Path: fn}
+	s.handleUnlock(r.Context(), w, r, ref, sublog)
+}
+
+func (s *svc) handleSpacesUnlock(w http.ResponseWriter, r *http.Request, spaceID string) {
+	ctx, span := rtrace.Provider.Tracer("reva").Start(r.Context(), "spaces_unlock")
+	defer span.End()
+
+	sublog := appctx.GetLogger(ctx).With().Str("spaceid", spaceID).Str("path", r.URL.Path).Logger()
+
+	ref, status, err := s.lookUpStorageSpaceReference(ctx, spaceID, r.URL.Path)
+	if err != nil {
+		sublog.Error().Err(err).Msg("error sending a grpc request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if status.Code != rpc.Code_CODE_OK {
+		HandleErrorStatus(&sublog, w, status)
+		return
+	}
+
+	s.handleUnlock(ctx, w, r, ref, sublog)
+}
+
+// handleUnlock removes the lock identified by the Lock-Token header, see https://tools.ietf.org/html/rfc4918#section-9.11
+func (s *svc) handleUnlock(ctx context.Context, w http.ResponseWriter, r *http.Request, ref *provider.Reference, log zerolog.Logger) {
+	ctx, span := rtrace.Provider.Tracer("reva").Start(ctx, "unlock")
+	defer span.End()
+
+	token := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(r.Header.Get(HeaderLockToken)), "<"), ">")
+	if token == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	client, err := s.getClient()
+	if err != nil {
+		log.Error().Err(err).Msg("error getting grpc client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res, err := client.Unlock(ctx, &provider.UnlockRequest{
+		Ref: ref,
+		Lock: &provider.Lock{
+			LockId: token,
+			Type:   provider.LockType_LOCK_TYPE_WRITE,
+			User:   u.Id,
+		},
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("error sending grpc unlock request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		// a token not matching the lock of the resource fails with 409, see https://tools.ietf.org/html/rfc4918#section-9.11.1
+		HandleErrorStatus(&log, w, res.Status)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
@@ -19,6 +19,7 @@
 package ocdav
 
 import (
+	"context"
 	"net/http"
 	"path"
 )
@@ -51,7 +52,13 @@ const (
 	HeaderLocation                   = "Location"
 	HeaderRange                      = "Range"
 	HeaderIfMatch                    = "If-Match"
+	HeaderIfNoneMatch                = "If-None-Match"
+	HeaderIf                         = "If"
+	HeaderLockToken                  = "Lock-Token"
+	HeaderTimeout                    = "Timeout"
+	HeaderCacheControl               = "Cache-Control"
 	HeaderChecksum                   = "Digest"
+	HeaderRetryAfter                 = "Retry-After"
 )
 
 // Non standard HTTP headers.
@@ -76,31 +83,41 @@ const (
 	HeaderOCMtime              = "X-OC-Mtime"
 	HeaderExpectedEntityLength = "X-Expected-Entity-Length"
 	HeaderTransferAuth         = "TransferHeaderAuthorization"
+	HeaderFolderPassword       = "X-Reva-Folder-Password"
 )
 
 // WebDavHandler implements a dav endpoint
 type WebDavHandler struct {
 	namespace         string
 	useLoggedInUserNS bool
+	rules             []*namespaceRule
 }
 
-func (h *WebDavHandler) init(ns string, useLoggedInUserNS bool) error {
+func (h *WebDavHandler) init(ns string, useLoggedInUserNS bool, rules []NamespaceRule, route string) error {
 	h.namespace = path.Join("/", ns)
 	h.useLoggedInUserNS = useLoggedInUserNS
-	return nil
+	var err error
+	h.rules, err = compileNamespaceRules(route, rules)
+	return err
 }
 
 // Handler handles requests
 func (h *WebDavHandler) Handler(s *svc) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ns := applyLayout(r.Context(), h.namespace, h.useLoggedInUserNS, r.URL.Path)
+		requestPath := r.URL.Path
+		var ns string
+		ns, r.URL.Path = mapNamespace(r.Context(), h.rules, h.namespace, h.useLoggedInUserNS, requestPath)
+		if len(h.rules) > 0 {
+			m := &namespaceMapping{handler: h, requestPath: requestPath, mappedPath: r.URL.Path}
+			r = r.WithContext(context.WithValue(r.Context(), ctxKeyNamespaceMapping, m))
+		}
 		switch r.Method {
 		case MethodPropfind:
 			s.handlePathPropfind(w, r, ns)
 		case MethodLock:
-			s.handleLock(w, r, ns)
+			s.handlePathLock(w, r, ns)
 		case MethodUnlock:
-			s.handleUnlock(w, r, ns)
+			s.handlePathUnlock(w, r, ns)
 		case MethodProppatch:
 			s.handlePathProppatch(w, r, ns)
 		case MethodMkcol:
@@ -20,6 +20,9 @@ package config
 
 import (
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/data"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/ocm/directory"
+	"github.com/cs3org/reva/pkg/rhttp/httpcache"
 	"github.com/cs3org/reva/pkg/sharedconf"
 )
 
@@ -42,6 +45,41 @@ type Config struct {
 	ResourceInfoCacheTTL     int                               `mapstructure:"resource_info_cache_ttl"`
 	ResourceInfoCacheDrivers map[string]map[string]interface{} `mapstructure:"resource_info_caches"`
 	UserIdentifierCacheTTL   int                               `mapstructure:"user_identifier_cache_ttl"`
+	// ResponseCache configures the caching of the capabilities and user endpoints.
+	ResponseCache httpcache.Config `mapstructure:"response_cache"`
+	// Dialects selects whether responses are shaped for ownCloud or Nextcloud clients.
+	Dialects response.DialectConfig `mapstructure:"dialects"`
+	// LocaleHints adds the formatting hints of the locale of the user to the meta of the responses.
+	LocaleHints   bool   `mapstructure:"locale_hints"`
+	DefaultLocale string `mapstructure:"default_locale"`
+	// DeviceRegistry enables the endpoints listing and revoking the devices of the users.
+	DeviceRegistry   string                            `mapstructure:"device_registry"`
+	DeviceRegistries map[string]map[string]interface{} `mapstructure:"device_registries"`
+	// RevocationStore is the store the tokens used by revoked devices are added to.
+	RevocationStore  string                            `mapstructure:"revocation_store"`
+	RevocationStores map[string]map[string]interface{} `mapstructure:"revocation_stores"`
+	// AnnouncementsRegistry enables the endpoint listing the active announcements, which are posted through the siteacc service.
+	AnnouncementsRegistry   string                            `mapstructure:"announcements_registry"`
+	AnnouncementsRegistries map[string]map[string]interface{} `mapstructure:"announcements_registries"`
+	// CommentsManager enables the endpoints of the comment threads on the resources.
+	CommentsManager  string                            `mapstructure:"comments_manager"`
+	CommentsManagers map[string]map[string]interface{} `mapstructure:"comments_managers"`
+	// NatsAddress is the event stream the mentions in the comments are published to,
+	// and from which the purged resources are learned to clean up their comments.
+	NatsAddress   string `mapstructure:"nats_address"`
+	NatsClusterID string `mapstructure:"nats_clusterid"`
+	// MeshDirectory adds the users of the other sites of the mesh to the sharee searches.
+	MeshDirectory directory.Config `mapstructure:"mesh_directory"`
+	// DownloadTokenStore enables the endpoint minting the one-time tokens the browsers download the
+	// files with. The datagateways redeeming them have to be configured with the same store.
+	DownloadTokenStore  string                            `mapstructure:"download_token_store"`
+	DownloadTokenStores map[string]map[string]interface{} `mapstructure:"download_token_stores"`
+	DownloadTokenTTL    int                               `mapstructure:"download_token_ttl"`
+	// PIDProvider enables the endpoints publishing folders as datasets with a persistent identifier.
+	PIDProvider  string                            `mapstructure:"pid_provider"`
+	PIDProviders map[string]map[string]interface{} `mapstructure:"pid_providers"`
+	// PublicationRetention is the number of years the published folders are put under retention for.
+	PublicationRetention int `mapstructure:"publication_retention"`
 }
 
 // Init sets sane defaults
@@ -70,5 +108,19 @@ func (c *Config) Init() {
 		c.UserIdentifierCacheTTL = 60
 	}
 
+	if c.DownloadTokenTTL <= 0 {
+		c.DownloadTokenTTL = 60
+	}
+
+	if c.PublicationRetention <= 0 {
+		c.PublicationRetention = 10
+	}
+
+	if c.DefaultLocale == "" {
+		c.DefaultLocale = "en"
+	}
+
+	c.ResponseCache.Init()
+
 	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
 }
@@ -144,6 +144,34 @@ type ShareData struct {
 	Attributes string `json:"attributes,omitempty" xml:"attributes,omitempty"`
 	// PasswordProtected represents a public share is password protected
 	// PasswordProtected bool `json:"password_protected,omitempty" xml:"password_protected,omitempty"`
+	// ScanStatus is the virus scan status of a shared file: pending, clean or infected
+	ScanStatus string `json:"scan_status,omitempty" xml:"scan_status,omitempty"`
+	// AccessRules restrict the access to subfolders of a public link, without their passwords
+	AccessRules publicshare.AccessRules `json:"access_rules,omitempty" xml:"access_rules>element,omitempty"`
+}
+
+// RemoteShareData represents an OCM share received from a remote provider, like the federated shares of
+// https://doc.owncloud.com/server/developer_manual/core/apis/ocs-share-api.html#federated-cloud-shares
+type RemoteShareData struct {
+	ID string `json:"id" xml:"id"`
+	// The domain of the provider the share comes from.
+	Remote string `json:"remote" xml:"remote"`
+	// The id of the share at the remote provider.
+	RemoteID string `json:"remote_id" xml:"remote_id"`
+	Name     string `json:"name" xml:"name"`
+	// The owner of the share at the remote provider.
+	Owner string `json:"owner" xml:"owner"`
+	// The local user or group the share was sent to.
+	User      string    `json:"user" xml:"user"`
+	ShareType ShareType `json:"share_type" xml:"share_type"`
+	// share state, 0 = accepted, 1 = pending, 2 = declined
+	State       int         `json:"state" xml:"state"`
+	Accepted    bool        `json:"accepted" xml:"accepted"`
+	Permissions Permissions `json:"permissions" xml:"permissions"`
+	// The UNIX timestamp when the share was created.
+	STime uint64 `json:"stime" xml:"stime"`
+	// The UNIX timestamp when the share was last modified.
+	MTime uint64 `json:"mtime" xml:"mtime"`
 }
 
 // ShareeData holds share recipient search results
@@ -172,6 +200,8 @@ type MatchValueData struct {
 	ShareType               int    `json:"shareType" xml:"shareType"`
 	ShareWith               string `json:"shareWith" xml:"shareWith"`
 	ShareWithAdditionalInfo string `json:"shareWithAdditionalInfo" xml:"shareWithAdditionalInfo"`
+	// ShareWithProvider is the domain of the site of the remote users.
+	ShareWithProvider string `json:"shareWithProvider,omitempty" xml:"shareWithProvider,omitempty"`
 }
 
 // CS3Share2ShareData converts a cs3api user share into shareData data model
@@ -56,6 +56,7 @@ type Capabilities struct {
 	Dav          *CapabilitiesDav          `json:"dav" xml:"dav"`
 	FilesSharing *CapabilitiesFilesSharing `json:"files_sharing" xml:"files_sharing" mapstructure:"files_sharing"`
 	Spaces       *Spaces                   `json:"spaces,omitempty" xml:"spaces,omitempty" mapstructure:"spaces"`
+	Theming      *CapabilitiesTheming      `json:"theming,omitempty" xml:"theming,omitempty" mapstructure:"theming"`
 
 	Notifications *CapabilitiesNotifications `json:"notifications,omitempty" xml:"notifications,omitempty"`
 
@@ -68,6 +69,15 @@ type Spaces struct {
 	Enabled bool   `json:"enabled" xml:"enabled" mapstructure:"enabled"`
 }
 
+// CapabilitiesTheming holds the branding shown by the clients
+type CapabilitiesTheming struct {
+	Name   string `json:"name" xml:"name" mapstructure:"name"`
+	URL    string `json:"url" xml:"url" mapstructure:"url"`
+	Slogan string `json:"slogan" xml:"slogan" mapstructure:"slogan"`
+	Color  string `json:"color" xml:"color" mapstructure:"color"`
+	Logo   string `json:"logo" xml:"logo" mapstructure:"logo"`
+}
+
 // CapabilitiesCore holds webdav config
 type CapabilitiesCore struct {
 	PollInterval      int     `json:"pollinterval" xml:"pollinterval" mapstructure:"poll_interval"`
@@ -135,6 +145,7 @@ type CapabilitiesFiles struct {
 	TusSupport        *CapabilitiesFilesTusSupport `json:"tus_support" xml:"tus_support" mapstructure:"tus_support"`
 	Archivers         []*CapabilitiesArchiver      `json:"archivers" xml:"archivers" mapstructure:"archivers"`
 	AppProviders      []*CapabilitiesAppProvider   `json:"app_providers" xml:"app_providers" mapstructure:"app_providers"`
+	DefaultQuota      string                       `json:"default_quota,omitempty" xml:"default_quota,omitempty" mapstructure:"default_quota"`
 }
 
 // CapabilitiesDav holds dav endpoint config
@@ -172,6 +183,7 @@ type CapabilitiesFilesSharingPublic struct {
 	Multiple           ocsBool                                   `json:"multiple" xml:"multiple"`
 	SupportsUploadOnly ocsBool                                   `json:"supports_upload_only" xml:"supports_upload_only" mapstructure:"supports_upload_only"`
 	CanEdit            ocsBool                                   `json:"can_edit" xml:"can_edit" mapstructure:"can_edit"`
+	CanShareSpaces     ocsBool                                   `json:"can_share_spaces" xml:"can_share_spaces" mapstructure:"can_share_spaces"`
 	Password           *CapabilitiesFilesSharingPublicPassword   `json:"password" xml:"password"`
 	ExpireDate         *CapabilitiesFilesSharingPublicExpireDate `json:"expire_date" xml:"expire_date" mapstructure:"expire_date"`
 }
@@ -0,0 +1,413 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package comments
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/asim/go-micro/plugins/events/nats/v4"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/comments"
+	"github.com/cs3org/reva/pkg/comments/registry"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/events/server"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/cs3org/reva/pkg/utils/resourceid"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// consumerGroup is the group of the ocs services cleaning up the comments of the purged resources,
+// so that every purge is handled by one instance only
+const consumerGroup = "ocs-comments"
+
+// Handler serves the comment threads on the resources
+type Handler struct {
+	gatewayAddr string
+	manager     comments.Manager
+	publisher   events.Publisher
+}
+
+// Init initializes this and any contained handlers
+func (h *Handler) Init(c *config.Config) error {
+	f, ok := registry.NewFuncs[c.CommentsManager]
+	if !ok {
+		return fmt.Errorf("comments manager not found: %s", c.CommentsManager)
+	}
+	mgr, err := f(c.CommentsManagers[c.CommentsManager])
+	if err != nil {
+		return err
+	}
+	h.manager = mgr
+	h.gatewayAddr = c.GatewaySvc
+
+	if c.NatsAddress != "" {
+		stream, err := server.NewNatsStream(nats.Address(c.NatsAddress), nats.ClusterID(c.NatsClusterID))
+		if err != nil {
+			return fmt.Errorf("error connecting to the event stream: %w", err)
+		}
+		evs, err := events.Consume(stream, consumerGroup, events.ItemPurged{})
+		if err != nil {
+			return fmt.Errorf("error consuming events: %w", err)
+		}
+		h.publisher = stream
+		go h.cleanup(evs)
+	}
+	return nil
+}
+
+// Mention holds the data of a user mentioned in a comment
+type Mention struct {
+	MentionType string `json:"mentionType" xml:"mentionType"`
+	MentionID   string `json:"mentionId" xml:"mentionId"`
+}
+
+// Comment holds the data of a comment, named as in the comments app of ownCloud
+type Comment struct {
+	ID                  string     `json:"id" xml:"id"`
+	ParentID            string     `json:"parentId" xml:"parentId"`
+	TopmostParentID     string     `json:"topmostParentId" xml:"topmostParentId"`
+	ChildrenCount       int        `json:"childrenCount" xml:"childrenCount"`
+	Verb                string     `json:"verb" xml:"verb"`
+	ActorType           string     `json:"actorType" xml:"actorType"`
+	ActorID             string     `json:"actorId" xml:"actorId"`
+	ActorDisplayName    string     `json:"actorDisplayName" xml:"actorDisplayName"`
+	CreationDateTime    string     `json:"creationDateTime" xml:"creationDateTime"`
+	LatestChildDateTime string     `json:"latestChildDateTime,omitempty" xml:"latestChildDateTime,omitempty"`
+	ObjectType          string     `json:"objectType" xml:"objectType"`
+	ObjectID            string     `json:"objectId" xml:"objectId"`
+	Message             string     `json:"message" xml:"message"`
+	Mentions            []*Mention `json:"mentions" xml:"mentions>element"`
+}
+
+func toComment(c *comments.Comment) *Comment {
+	res := &Comment{
+		ID:               c.ID,
+		ParentID:         c.ParentID,
+		TopmostParentID:  c.TopmostParentID,
+		ChildrenCount:    c.ChildrenCount,
+		Verb:             c.Verb,
+		ActorType:        c.ActorType,
+		ActorID:          c.ActorID,
+		ActorDisplayName: c.ActorDisplayName,
+		CreationDateTime: c.CreationTime.UTC().Format(time.RFC3339),
+		ObjectType:       c.ObjectType,
+		ObjectID:         c.ObjectID,
+		Message:          c.Message,
+		Mentions:         []*Mention{},
+	}
+	if !c.LatestChildTime.IsZero() {
+		res.LatestChildDateTime = c.LatestChildTime.UTC().Format(time.RFC3339)
+	}
+	for _, m := range c.Mentions() {
+		res.Mentions = append(res.Mentions, &Mention{MentionType: m.Type, MentionID: m.ID})
+	}
+	return res
+}
+
+// ListComments handles GET requests on /apps/comments/api/v1/comments/{objecttype}/{objectid}
+func (h *Handler) ListComments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	objectType, objectID, ok := h.checkObject(w, r)
+	if !ok {
+		return
+	}
+
+	opts := &comments.ListOptions{}
+	for param, v := range map[string]*int{"limit": &opts.Limit, "offset": &opts.Offset} {
+		if s := r.URL.Query().Get(param); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid "+param, nil)
+				return
+			}
+			*v = n
+		}
+	}
+
+	list, err := h.manager.List(ctx, objectType, objectID, opts)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error listing comments", err)
+		return
+	}
+	res := make([]*Comment, 0, len(list))
+	for _, c := range list {
+		res = append(res, toComment(c))
+	}
+	response.WriteOCSSuccess(w, r, res)
+}
+
+// GetComment handles GET requests on /apps/comments/api/v1/comments/{objecttype}/{objectid}/{commentid}
+func (h *Handler) GetComment(w http.ResponseWriter, r *http.Request) {
+	objectType, objectID, ok := h.checkObject(w, r)
+	if !ok {
+		return
+	}
+	c, ok := h.getComment(w, r, objectType, objectID)
+	if !ok {
+		return
+	}
+	response.WriteOCSSuccess(w, r, toComment(c))
+}
+
+// CreateComment handles POST requests on /apps/comments/api/v1/comments/{objecttype}/{objectid}
+func (h *Handler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "missing user in context", fmt.Errorf("missing user in context"))
+		return
+	}
+	objectType, objectID, ok := h.checkObject(w, r)
+	if !ok {
+		return
+	}
+	message, ok := checkMessage(w, r)
+	if !ok {
+		return
+	}
+
+	c, err := h.manager.Add(ctx, &comments.Comment{
+		ParentID:         r.FormValue("parentId"),
+		Verb:             comments.VerbComment,
+		ActorType:        comments.ActorTypeUsers,
+		ActorID:          u.Username,
+		ActorDisplayName: u.DisplayName,
+		Message:          message,
+		ObjectType:       objectType,
+		ObjectID:         objectID,
+	})
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "parent comment not found", nil)
+			return
+		}
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error adding comment", err)
+		return
+	}
+
+	h.notifyMentions(ctx, u, c, comments.ExtractMentions(c.Message))
+	response.WriteOCSSuccess(w, r, toComment(c))
+}
+
+// UpdateComment handles PUT requests on /apps/comments/api/v1/comments/{objecttype}/{objectid}/{commentid}
+func (h *Handler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	objectType, objectID, ok := h.checkObject(w, r)
+	if !ok {
+		return
+	}
+	c, ok := h.getComment(w, r, objectType, objectID)
+	if !ok {
+		return
+	}
+	u, ok := checkActor(w, r, c)
+	if !ok {
+		return
+	}
+	message, ok := checkMessage(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := h.manager.Update(ctx, c.ID, message)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error updating comment", err)
+		return
+	}
+
+	// only the users mentioned by the edit are notified, the others already have been
+	h.notifyMentions(ctx, u, updated, comments.NewMentions(c.Message, updated.Message))
+	response.WriteOCSSuccess(w, r, toComment(updated))
+}
+
+// DeleteComment handles DELETE requests on /apps/comments/api/v1/comments/{objecttype}/{objectid}/{commentid}
+func (h *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	objectType, objectID, ok := h.checkObject(w, r)
+	if !ok {
+		return
+	}
+	c, ok := h.getComment(w, r, objectType, objectID)
+	if !ok {
+		return
+	}
+	if _, ok := checkActor(w, r, c); !ok {
+		return
+	}
+
+	if err := h.manager.Delete(ctx, c.ID); err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error deleting comment", err)
+		return
+	}
+	response.WriteOCSSuccess(w, r, nil)
+}
+
+// checkObject returns the object of the request if it exists and the user has access to it.
+// The comments are readable and writable by everyone who can stat the resource.
+func (h *Handler) checkObject(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	ctx := r.Context()
+	objectType, objectID := chi.URLParam(r, "objecttype"), chi.URLParam(r, "objectid")
+	if objectType != comments.ObjectTypeFiles {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "unsupported object type: "+objectType, nil)
+		return "", "", false
+	}
+	rid := resourceid.OwnCloudResourceIDUnwrap(objectID)
+	if rid == nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid file id", nil)
+		return "", "", false
+	}
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(h.gatewayAddr))
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return "", "", false
+	}
+	res, err := client.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{ResourceId: rid}})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc stat request", err)
+		return "", "", false
+	}
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+	case rpc.Code_CODE_NOT_FOUND, rpc.Code_CODE_PERMISSION_DENIED:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "file not found", nil)
+		return "", "", false
+	default:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, res.Status.Message, nil)
+		return "", "", false
+	}
+	return objectType, resourceid.OwnCloudResourceIDWrap(res.Info.Id), true
+}
+
+// getComment returns the comment of the request if it belongs to the object.
+func (h *Handler) getComment(w http.ResponseWriter, r *http.Request, objectType, objectID string) (*comments.Comment, bool) {
+	c, err := h.manager.Get(r.Context(), chi.URLParam(r, "commentid"))
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "comment not found", nil)
+			return nil, false
+		}
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting comment", err)
+		return nil, false
+	}
+	if c.ObjectType != objectType || c.ObjectID != objectID {
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "comment not found", nil)
+		return nil, false
+	}
+	return c, true
+}
+
+// checkActor returns the user of the request if they wrote the comment, only the authors can edit and delete their comments.
+func checkActor(w http.ResponseWriter, r *http.Request, c *comments.Comment) (*userpb.User, bool) {
+	u, ok := ctxpkg.ContextGetUser(r.Context())
+	if !ok {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "missing user in context", fmt.Errorf("missing user in context"))
+		return nil, false
+	}
+	if c.ActorType != comments.ActorTypeUsers || c.ActorID != u.Username {
+		response.WriteOCSError(w, r, http.StatusForbidden, "only the author can change a comment", nil)
+		return nil, false
+	}
+	return u, true
+}
+
+func checkMessage(w http.ResponseWriter, r *http.Request) (string, bool) {
+	message := strings.TrimSpace(r.FormValue("message"))
+	if message == "" {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "missing message", nil)
+		return "", false
+	}
+	if utf8.RuneCountInString(message) > comments.MaxMessageLength {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, fmt.Sprintf("message longer than %d characters", comments.MaxMessageLength), nil)
+		return "", false
+	}
+	return message, true
+}
+
+// notifyMentions emits an event for each of the mentioned users, except the author.
+// Mentions of unknown users are ignored.
+func (h *Handler) notifyMentions(ctx context.Context, author *userpb.User, c *comments.Comment, mentions []comments.Mention) {
+	if h.publisher == nil || len(mentions) == 0 {
+		return
+	}
+	log := appctx.GetLogger(ctx)
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(h.gatewayAddr))
+	if err != nil {
+		log.Error().Err(err).Msg("comments: error getting grpc gateway client")
+		return
+	}
+	for _, m := range mentions {
+		if m.Type != comments.MentionTypeUser || m.ID == author.Username {
+			continue
+		}
+		res, err := client.GetUserByClaim(ctx, &userpb.GetUserByClaimRequest{Claim: "username", Value: m.ID})
+		if err != nil || res.Status.Code != rpc.Code_CODE_OK {
+			log.Debug().Err(err).Str("mention", m.ID).Msg("comments: ignoring mention of unknown user")
+			continue
+		}
+		if utils.UserEqual(res.User.Id, author.Id) {
+			continue
+		}
+		if err := events.Publish(h.publisher, events.CommentMentioned{
+			Author:    author.Id,
+			Mentioned: res.User.Id,
+			ItemID:    resourceid.OwnCloudResourceIDUnwrap(c.ObjectID),
+			CommentID: c.ID,
+			Message:   c.Message,
+		}); err != nil {
+			log.Error().Err(err).Str("mention", m.ID).Msg("comments: error publishing mention")
+		}
+	}
+}
+
+// cleanup deletes the comments of the resources purged from the trash bin.
+// The drivers key the trash items by the opaque id of the trashed resource,
+// the comments of the children of a purged folder are kept.
+func (h *Handler) cleanup(evs <-chan interface{}) {
+	for ev := range evs {
+		e, ok := ev.(events.ItemPurged)
+		if !ok || e.Key == "" || strings.Contains(strings.Trim(e.Key, "/"), "/") || e.Ref.GetResourceId() == nil {
+			// items purged from within a trashed folder or emptied trash bins can't be mapped to a resource
+			continue
+		}
+		objectID := resourceid.OwnCloudResourceIDWrap(&provider.ResourceId{
+			StorageId: e.Ref.ResourceId.StorageId,
+			OpaqueId:  strings.Trim(e.Key, "/"),
+		})
+		if err := h.manager.DeleteObject(context.Background(), comments.ObjectTypeFiles, objectID); err != nil {
+			log.Error().Err(err).Str("object", objectID).Msg("comments: error deleting the comments of a purged resource")
+		}
+	}
+}
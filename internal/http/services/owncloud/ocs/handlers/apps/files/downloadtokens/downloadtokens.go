@@ -0,0 +1,166 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package downloadtokens
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/downloadtoken"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/downloadtoken/registry"
+	"github.com/cs3org/reva/pkg/utils/resourceid"
+)
+
+// downloadProtocol is the protocol of the downloads the tokens are minted for.
+const downloadProtocol = "simple"
+
+// Handler mints the one-time tokens the browsers download the files from the datagateway with
+type Handler struct {
+	gatewayAddr   string
+	homeNamespace string
+	store         downloadtoken.Store
+	ttl           time.Duration
+}
+
+// DownloadToken holds the data of a minted token
+type DownloadToken struct {
+	Token   string `json:"token" xml:"token"`
+	URL     string `json:"url" xml:"url"`
+	Expires int64  `json:"expires" xml:"expires"`
+}
+
+// Init initializes this and any contained handlers
+func (h *Handler) Init(c *config.Config) error {
+	f, ok := registry.NewFuncs[c.DownloadTokenStore]
+	if !ok {
+		return fmt.Errorf("download token store not found: %s", c.DownloadTokenStore)
+	}
+	store, err := f(c.DownloadTokenStores[c.DownloadTokenStore])
+	if err != nil {
+		return err
+	}
+	h.store = store
+	h.gatewayAddr = c.GatewaySvc
+	h.homeNamespace = c.HomeNamespace
+	h.ttl = time.Duration(c.DownloadTokenTTL) * time.Second
+	return nil
+}
+
+// CreateDownloadToken handles POST requests on /apps/files/api/v1/downloadtokens. The file is
+// given by its path in the home of the user or by its id. The token is bound to the download
+// session cookie of the browser, which is set on the response if the request has none.
+func (h *Handler) CreateDownloadToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var ref *provider.Reference
+	switch {
+	case r.FormValue("path") != "":
+		ref = &provider.Reference{Path: path.Join(h.homeNamespace, r.FormValue("path"))}
+	case r.FormValue("fileid") != "":
+		rid := resourceid.OwnCloudResourceIDUnwrap(r.FormValue("fileid"))
+		if rid == nil {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid file id", nil)
+			return
+		}
+		ref = &provider.Reference{ResourceId: rid}
+	default:
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "missing path or fileid", nil)
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(h.gatewayAddr))
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+	statRes, err := client.Stat(ctx, &provider.StatRequest{Ref: ref})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc stat request", err)
+		return
+	}
+	switch statRes.Status.Code {
+	case rpc.Code_CODE_OK:
+	case rpc.Code_CODE_NOT_FOUND, rpc.Code_CODE_PERMISSION_DENIED:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "file not found", nil)
+		return
+	default:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, statRes.Status.Message, nil)
+		return
+	}
+	if statRes.Info.Type != provider.ResourceType_RESOURCE_TYPE_FILE {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "only files can be downloaded", nil)
+		return
+	}
+
+	dRes, err := client.InitiateFileDownload(ctx, &provider.InitiateFileDownloadRequest{Ref: ref})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error initiating file download", err)
+		return
+	}
+	if dRes.Status.Code != rpc.Code_CODE_OK {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, dRes.Status.Message, nil)
+		return
+	}
+	grant := &downloadtoken.Grant{Filename: path.Base(statRes.Info.Path)}
+	for _, p := range dRes.Protocols {
+		if p.Protocol == downloadProtocol {
+			grant.Endpoint, grant.Token = p.DownloadEndpoint, p.Token
+		}
+	}
+	if grant.Token == "" {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "the file is not downloaded through the datagateway", nil)
+		return
+	}
+
+	session := downloadtoken.Session(r)
+	if session == "" {
+		if session, err = downloadtoken.NewSession(); err != nil {
+			response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error creating download session", err)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     downloadtoken.CookieName,
+			Value:    session,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	tkn, err := downloadtoken.Mint(ctx, h.store, grant, session, h.ttl)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error minting download token", err)
+		return
+	}
+	response.WriteOCSSuccess(w, r, &DownloadToken{
+		Token:   tkn,
+		URL:     strings.TrimSuffix(grant.Endpoint, "/") + datagateway.DownloadPath + tkn,
+		Expires: time.Now().Add(h.ttl).Unix(),
+	})
+}
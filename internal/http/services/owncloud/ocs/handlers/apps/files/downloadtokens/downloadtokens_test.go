@@ -0,0 +1,183 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package downloadtokens
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/downloadtoken"
+	_ "github.com/cs3org/reva/pkg/rhttp/datatx/utils/downloadtoken/memory"
+	"github.com/golang-jwt/jwt"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+)
+
+const transferSecret = "transfer-secret"
+
+// fakeGateway serves the calls of the handler; all the other ones panic.
+type fakeGateway struct {
+	gateway.GatewayAPIServer
+	endpoint string
+	token    string
+}
+
+func (g *fakeGateway) Stat(ctx context.Context, req *provider.StatRequest) (*provider.StatResponse, error) {
+	return &provider.StatResponse{
+		Status: status.NewOK(ctx),
+		Info:   &provider.ResourceInfo{Type: provider.ResourceType_RESOURCE_TYPE_FILE, Path: req.Ref.Path},
+	}, nil
+}
+
+func (g *fakeGateway) InitiateFileDownload(ctx context.Context, req *provider.InitiateFileDownloadRequest) (*gateway.InitiateFileDownloadResponse, error) {
+	return &gateway.InitiateFileDownloadResponse{
+		Status: status.NewOK(ctx),
+		Protocols: []*gateway.FileDownloadProtocol{
+			{Protocol: "simple", DownloadEndpoint: g.endpoint, Token: g.token},
+		},
+	}, nil
+}
+
+func startFakeGateway(t *testing.T, g *fakeGateway) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	gateway.RegisterGatewayAPIServer(srv, g)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+// TestMintAndRedeem mints a token through the OCS handler and redeems it at the datagateway
+// of the same process, both configured with their own memory store.
+func TestMintAndRedeem(t *testing.T) {
+	dataServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("E = mc²"))
+	}))
+	defer dataServer.Close()
+
+	transferToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"target": dataServer.URL + "/data/relativity.md",
+		"exp":    time.Now().Add(time.Minute).Unix(),
+	}).SignedString([]byte(transferSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gatewayAddr := startFakeGateway(t, &fakeGateway{endpoint: "https://cloud.example.org/datagateway", token: transferToken})
+
+	h := &Handler{}
+	if err := h.Init(&config.Config{
+		GatewaySvc:         gatewayAddr,
+		HomeNamespace:      "/home",
+		DownloadTokenStore: "memory",
+		DownloadTokenTTL:   60,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	log := zerolog.Nop()
+	dg, err := datagateway.New(map[string]interface{}{
+		"transfer_shared_secret": transferSecret,
+		"download_token_store":   "memory",
+	}, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/apps/files/api/v1/downloadtokens?format=json", strings.NewReader("path=Documents/relativity.md"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.CreateDownloadToken(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the token to be minted, got %d: %s", w.Code, w.Body.String())
+	}
+
+	res := &struct {
+		OCS struct {
+			Data DownloadToken `json:"data"`
+		} `json:"ocs"`
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(res); err != nil {
+		t.Fatal(err)
+	}
+	tkn := res.OCS.Data.Token
+	if tkn == "" || res.OCS.Data.URL != "https://cloud.example.org/datagateway"+datagateway.DownloadPath+tkn {
+		t.Fatalf("unexpected download token %+v", res.OCS.Data)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != downloadtoken.CookieName {
+		t.Fatalf("expected the download session cookie to be set, got %v", cookies)
+	}
+
+	download := func(cookie *http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, datagateway.DownloadPath+tkn, nil)
+		r.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		dg.Handler().ServeHTTP(w, r)
+		return w
+	}
+
+	// A token is bound to the session of the browser it was minted for and burnt by any attempt
+	if w := download(&http.Cookie{Name: downloadtoken.CookieName, Value: "another-session"}); w.Code != http.StatusForbidden {
+		t.Errorf("expected a token of another session to be rejected, got %d", w.Code)
+	}
+
+	// mint a fresh token, as the first one was burnt
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/apps/files/api/v1/downloadtokens?format=json", strings.NewReader("path=Documents/relativity.md"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.AddCookie(cookies[0])
+	h.CreateDownloadToken(w, r)
+	if err := json.NewDecoder(w.Body).Decode(res); err != nil {
+		t.Fatal(err)
+	}
+	tkn = res.OCS.Data.Token
+
+	w = download(cookies[0])
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the token to be redeemed, got %d", w.Code)
+	}
+	body, _ := ioutil.ReadAll(w.Body)
+	if string(body) != "E = mc²" {
+		t.Errorf("unexpected content %q", body)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename=relativity.md` {
+		t.Errorf("unexpected content disposition %q", cd)
+	}
+
+	if w := download(cookies[0]); w.Code != http.StatusForbidden {
+		t.Errorf("expected a redeemed token to be rejected, got %d", w.Code)
+	}
+}
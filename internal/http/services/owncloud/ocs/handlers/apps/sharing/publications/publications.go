@@ -0,0 +1,307 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package publications
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/pid"
+	"github.com/cs3org/reva/pkg/pid/registry"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/storage/utils/retention"
+	"google.golang.org/protobuf/proto"
+)
+
+// Handler publishes the folders of the users as datasets: the folder gets a
+// read-only public link, is put under retention and a persistent identifier
+// resolving to the link is minted for it.
+type Handler struct {
+	gatewayAddr   string
+	homeNamespace string
+	publicURL     string
+	provider      pid.Provider
+	retention     int
+}
+
+// Publication holds the data of a published folder
+type Publication struct {
+	Path          string `json:"path" xml:"path"`
+	DOI           string `json:"doi" xml:"doi"`
+	DOIURL        string `json:"doi_url" xml:"doi_url"`
+	Token         string `json:"token" xml:"token"`
+	URL           string `json:"url" xml:"url"`
+	RetainedUntil string `json:"retained_until,omitempty" xml:"retained_until,omitempty"`
+}
+
+// Init initializes this and any contained handlers
+func (h *Handler) Init(c *config.Config) error {
+	f, ok := registry.NewFuncs[c.PIDProvider]
+	if !ok {
+		return fmt.Errorf("pid provider not found: %s", c.PIDProvider)
+	}
+	p, err := f(c.PIDProviders[c.PIDProvider])
+	if err != nil {
+		return err
+	}
+	h.provider = p
+	h.gatewayAddr = c.GatewaySvc
+	h.homeNamespace = c.HomeNamespace
+	h.publicURL = c.Config.Host
+	h.retention = c.PublicationRetention
+	return nil
+}
+
+// Publish handles POST requests on /apps/files_sharing/api/v1/publications. The folder is given by
+// its path in the home of the user, the dataset is described by the title, creator and description
+// form values. The title defaults to the name of the folder and the creator to the user.
+func (h *Handler) Publish(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	p := r.FormValue("path")
+	if p == "" {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "missing path", nil)
+		return
+	}
+	ref := &provider.Reference{Path: path.Join(h.homeNamespace, p)}
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(h.gatewayAddr))
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+	info, ok := h.stat(w, r, client, ref)
+	if !ok {
+		return
+	}
+	if info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "only folders can be published", nil)
+		return
+	}
+	if doi := info.GetArbitraryMetadata().GetMetadata()[pid.MetadataKey]; doi != "" {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "the folder is already published as "+doi, nil)
+		return
+	}
+
+	dataset := &pid.Dataset{
+		Title:       strings.TrimSpace(r.FormValue("title")),
+		Creators:    r.Form["creator"],
+		Description: r.FormValue("description"),
+		Year:        time.Now().Year(),
+	}
+	if dataset.Title == "" {
+		dataset.Title = path.Base(info.Path)
+	}
+	if len(dataset.Creators) == 0 {
+		if u, ok := ctxpkg.ContextGetUser(ctx); ok {
+			dataset.Creators = []string{u.DisplayName}
+		}
+	}
+
+	share, ok := h.createLink(w, r, client, info, dataset.Title)
+	if !ok {
+		return
+	}
+	dataset.URL = h.publicURL + path.Join("/", "s/"+share.Token)
+
+	until, ok := h.freeze(w, r, client, ref, info)
+	if !ok {
+		h.removeLink(ctx, client, share)
+		return
+	}
+
+	doi, err := h.provider.Mint(ctx, dataset)
+	if err != nil {
+		log.Error().Err(err).Str("path", info.Path).Msg("error minting the identifier of a publication, the folder stays under retention")
+		h.removeLink(ctx, client, share)
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error minting the identifier", err)
+		return
+	}
+
+	res, err := client.SetArbitraryMetadata(ctx, &provider.SetArbitraryMetadataRequest{
+		Ref: ref,
+		ArbitraryMetadata: &provider.ArbitraryMetadata{
+			Metadata: map[string]string{pid.MetadataKey: doi, pid.LinkMetadataKey: share.Token},
+		},
+	})
+	if err == nil && res.Status.Code != rpc.Code_CODE_OK {
+		err = fmt.Errorf("%s: %s", res.Status.Code, res.Status.Message)
+	}
+	if err != nil {
+		// the identifier is registered and resolves to the link, which is kept
+		log.Error().Err(err).Str("path", info.Path).Str("doi", doi).Msg("error storing the identifier of a publication")
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error storing the identifier "+doi, err)
+		return
+	}
+
+	log.Info().Str("path", info.Path).Str("doi", doi).Msg("folder published")
+	response.WriteOCSSuccess(w, r, &Publication{
+		Path:          p,
+		DOI:           doi,
+		DOIURL:        h.provider.ResolverURL(doi),
+		Token:         share.Token,
+		URL:           dataset.URL,
+		RetainedUntil: until.UTC().Format(time.RFC3339),
+	})
+}
+
+// GetPublication handles GET requests on /apps/files_sharing/api/v1/publications, returning the
+// publication of the folder given by its path in the home of the user.
+func (h *Handler) GetPublication(w http.ResponseWriter, r *http.Request) {
+	p := r.FormValue("path")
+	if p == "" {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "missing path", nil)
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(h.gatewayAddr))
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+	info, ok := h.stat(w, r, client, &provider.Reference{Path: path.Join(h.homeNamespace, p)})
+	if !ok {
+		return
+	}
+	md := info.GetArbitraryMetadata().GetMetadata()
+	doi := md[pid.MetadataKey]
+	if doi == "" {
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "the folder is not published", nil)
+		return
+	}
+
+	pub := &Publication{
+		Path:   p,
+		DOI:    doi,
+		DOIURL: h.provider.ResolverURL(doi),
+		Token:  md[pid.LinkMetadataKey],
+	}
+	if pub.Token != "" {
+		pub.URL = h.publicURL + path.Join("/", "s/"+pub.Token)
+	}
+	if until, ok := retention.RetainedUntil(info); ok {
+		pub.RetainedUntil = until.UTC().Format(time.RFC3339)
+	}
+	response.WriteOCSSuccess(w, r, pub)
+}
+
+func (h *Handler) stat(w http.ResponseWriter, r *http.Request, client gateway.GatewayAPIClient, ref *provider.Reference) (*provider.ResourceInfo, bool) {
+	res, err := client.Stat(r.Context(), &provider.StatRequest{
+		Ref:                   ref,
+		ArbitraryMetadataKeys: []string{pid.MetadataKey, pid.LinkMetadataKey, retention.MetadataKey},
+	})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc stat request", err)
+		return nil, false
+	}
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+		return res.Info, true
+	case rpc.Code_CODE_NOT_FOUND, rpc.Code_CODE_PERMISSION_DENIED:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "folder not found", nil)
+	default:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, res.Status.Message, nil)
+	}
+	return nil, false
+}
+
+// createLink creates the read-only public link the identifier resolves to.
+func (h *Handler) createLink(w http.ResponseWriter, r *http.Request, client gateway.GatewayAPIClient, info *provider.ResourceInfo, name string) (*link.PublicShare, bool) {
+	// the name of the link is sent in the metadata of the resource
+	ri := proto.Clone(info).(*provider.ResourceInfo)
+	ri.ArbitraryMetadata = &provider.ArbitraryMetadata{Metadata: map[string]string{"name": name}}
+	res, err := client.CreatePublicShare(r.Context(), &link.CreatePublicShareRequest{
+		ResourceInfo: ri,
+		Grant: &link.Grant{
+			Permissions: &link.PublicSharePermissions{
+				Permissions: conversions.NewViewerRole().CS3ResourcePermissions(),
+			},
+		},
+	})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error creating the public link", err)
+		return nil, false
+	}
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+		return res.Share, true
+	case rpc.Code_CODE_PERMISSION_DENIED:
+		response.WriteOCSError(w, r, http.StatusForbidden, res.Status.Message, nil)
+	default:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "grpc create public share request failed: "+res.Status.Message, nil)
+	}
+	return nil, false
+}
+
+// freeze puts the folder under retention for the configured number of years,
+// unless it already is for longer, and returns the retention date.
+func (h *Handler) freeze(w http.ResponseWriter, r *http.Request, client gateway.GatewayAPIClient, ref *provider.Reference, info *provider.ResourceInfo) (time.Time, bool) {
+	until := time.Now().AddDate(h.retention, 0, 0)
+	if current, ok := retention.RetainedUntil(info); ok && current.After(until) {
+		return current, true
+	}
+	res, err := client.SetArbitraryMetadata(r.Context(), &provider.SetArbitraryMetadataRequest{
+		Ref: ref,
+		ArbitraryMetadata: &provider.ArbitraryMetadata{
+			Metadata: map[string]string{retention.MetadataKey: until.UTC().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error putting the folder under retention", err)
+		return time.Time{}, false
+	}
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+		return until, true
+	case rpc.Code_CODE_PERMISSION_DENIED:
+		response.WriteOCSError(w, r, http.StatusForbidden, "not allowed to put the folder under retention", nil)
+	default:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error putting the folder under retention: "+res.Status.Message, nil)
+	}
+	return time.Time{}, false
+}
+
+// removeLink removes the link of a failed publication.
+func (h *Handler) removeLink(ctx context.Context, client gateway.GatewayAPIClient, share *link.PublicShare) {
+	res, err := client.RemovePublicShare(ctx, &link.RemovePublicShareRequest{
+		Ref: &link.PublicShareReference{
+			Spec: &link.PublicShareReference_Id{Id: share.Id},
+		},
+	})
+	if err == nil && res.Status.Code != rpc.Code_CODE_OK {
+		err = fmt.Errorf("%s: %s", res.Status.Code, res.Status.Message)
+	}
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("token", share.Token).Msg("error removing the public link of a failed publication")
+	}
+}
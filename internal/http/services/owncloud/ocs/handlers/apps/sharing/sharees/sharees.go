@@ -19,29 +19,46 @@
 package sharees
 
 import (
+	"context"
 	"net/http"
 
 	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	ocmprovider "github.com/cs3org/go-cs3apis/cs3/ocm/provider/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
 	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/ocm/directory"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/cs3org/reva/pkg/storage/utils/templates"
+	"github.com/cs3org/reva/pkg/tenant"
 )
 
 // Handler implements the ownCloud sharing API
 type Handler struct {
 	gatewayAddr             string
 	additionalInfoAttribute string
+	tenants                 *tenant.Manager
+	directory               *directory.Directory // nil if the other sites of the mesh are not searched
 }
 
 // Init initializes this and any contained handlers
-func (h *Handler) Init(c *config.Config) {
+func (h *Handler) Init(c *config.Config) error {
 	h.gatewayAddr = c.GatewaySvc
 	h.additionalInfoAttribute = c.AdditionalInfoAttribute
+	if c.MeshDirectory.Enabled {
+		h.directory = directory.New(&c.MeshDirectory, h.listProviders)
+	}
+
+	var err error
+	h.tenants, err = tenant.New(sharedconf.GetTenancy())
+	return err
 }
 
 // FindSharees implements the /apps/files_sharing/api/v1/sharees endpoint
@@ -68,6 +85,9 @@ func (h *Handler) FindSharees(w http.ResponseWriter, r *http.Request) {
 
 	userMatches := make([]*conversions.MatchData, 0, len(usersRes.GetUsers()))
 	for _, user := range usersRes.GetUsers() {
+		if !h.tenants.CanShare(h.currentTenant(r.Context()), h.tenants.Of(user)) {
+			continue
+		}
 		match := h.userAsMatch(user)
 		log.Debug().Interface("user", user).Interface("match", match).Msg("mapped")
 		userMatches = append(userMatches, match)
@@ -82,11 +102,27 @@ func (h *Handler) FindSharees(w http.ResponseWriter, r *http.Request) {
 
 	groupMatches := make([]*conversions.MatchData, 0, len(groupsRes.GetGroups()))
 	for _, g := range groupsRes.GetGroups() {
+		if !h.tenants.CanShare(h.currentTenant(r.Context()), h.tenants.OfGroup(g)) {
+			continue
+		}
 		match := h.groupAsMatch(g)
 		log.Debug().Interface("group", g).Interface("match", match).Msg("mapped")
 		groupMatches = append(groupMatches, match)
 	}
 
+	remoteMatches := []*conversions.MatchData{}
+	if h.directory != nil {
+		// the local users are still returned when the other sites can't be searched
+		sharees, err := h.directory.Search(r.Context(), term)
+		if err != nil {
+			log.Error().Err(err).Str("search", term).Msg("error searching the mesh directory")
+		}
+		for _, s := range sharees {
+			remoteMatches = append(remoteMatches, remoteAsMatch(s))
+		}
+		log.Debug().Int("count", len(remoteMatches)).Str("search", term).Msg("remote users found")
+	}
+
 	response.WriteOCSSuccess(w, r, &conversions.ShareeData{
 		Exact: &conversions.ExactMatchesData{
 			Users:   []*conversions.MatchData{},
@@ -95,7 +131,7 @@ func (h *Handler) FindSharees(w http.ResponseWriter, r *http.Request) {
 		},
 		Users:   userMatches,
 		Groups:  groupMatches,
-		Remotes: []*conversions.MatchData{},
+		Remotes: remoteMatches,
 	})
 }
 
@@ -122,6 +158,44 @@ func (h *Handler) groupAsMatch(g *grouppb.Group) *conversions.MatchData {
 	}
 }
 
+func remoteAsMatch(s *directory.Sharee) *conversions.MatchData {
+	info := s.Mail
+	if info == "" {
+		info = s.Provider
+	}
+	return &conversions.MatchData{
+		Label: s.DisplayName,
+		Value: &conversions.MatchValueData{
+			ShareType:               int(conversions.ShareTypeFederatedCloudShare),
+			ShareWith:               s.UserID,
+			ShareWithAdditionalInfo: info,
+			ShareWithProvider:       s.Provider,
+		},
+	}
+}
+
+// listProviders lists the sites of the mesh known to the provider authorizer of the gateway.
+func (h *Handler) listProviders(ctx context.Context) ([]*ocmprovider.ProviderInfo, error) {
+	gwc, err := pool.GetGatewayServiceClient(pool.Endpoint(h.gatewayAddr))
+	if err != nil {
+		return nil, err
+	}
+	res, err := gwc.ListAllProviders(ctx, &ocmprovider.ListAllProvidersRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, errtypes.InternalError(res.Status.Message)
+	}
+	return res.Providers, nil
+}
+
+// currentTenant returns the tenant of the user performing the search.
+func (h *Handler) currentTenant(ctx context.Context) string {
+	u, _ := ctxpkg.ContextGetUser(ctx)
+	return h.tenants.Of(u)
+}
+
 func (h *Handler) getAdditionalInfoAttribute(u *userpb.User) string {
 	return templates.WithUser(u, h.additionalInfoAttribute)
 }
@@ -0,0 +1,118 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package shares
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/publicshare/analytics"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetShareAnalytics returns the views, downloads and visitors of a public
+// link to its owner. The period is selected with the since and until
+// parameters, dates or RFC 3339 times, and the number of most downloaded
+// files reported with top.
+func (h *Handler) GetShareAnalytics(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shareID := chi.URLParam(r, "shareid")
+
+	q, err := analyticsQuery(r)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, err.Error(), nil)
+		return
+	}
+	o, err := analytics.AddQueryToOpaque(nil, q)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error encoding analytics query", err)
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(h.gatewayAddr))
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+	res, err := client.GetPublicShare(ctx, &link.GetPublicShareRequest{
+		Opaque: o,
+		Ref: &link.PublicShareReference{
+			Spec: &link.PublicShareReference_Id{
+				Id: &link.PublicShareId{OpaqueId: shareID},
+			},
+		},
+	})
+	switch {
+	case err != nil:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc get public share request", err)
+		return
+	case res.Status.Code == rpc.Code_CODE_NOT_FOUND:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "public link not found", nil)
+		return
+	case res.Status.Code == rpc.Code_CODE_PERMISSION_DENIED:
+		response.WriteOCSError(w, r, http.StatusForbidden, res.Status.Message, nil)
+		return
+	case res.Status.Code == rpc.Code_CODE_UNIMPLEMENTED:
+		response.WriteOCSError(w, r, http.StatusNotImplemented, res.Status.Message, nil)
+		return
+	case res.Status.Code != rpc.Code_CODE_OK:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "grpc get public share request failed", nil)
+		return
+	}
+
+	report, ok, err := analytics.ReportFromOpaque(res.Opaque)
+	if err != nil || !ok {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error decoding analytics report", err)
+		return
+	}
+	response.WriteOCSSuccess(w, r, report)
+}
+
+func analyticsQuery(r *http.Request) (*analytics.Query, error) {
+	q := &analytics.Query{}
+	var err error
+	if v := r.URL.Query().Get("since"); v != "" {
+		if q.Since, err = parseAnalyticsTime(v); err != nil {
+			return nil, err
+		}
+	}
+	if v := r.URL.Query().Get("until"); v != "" {
+		if q.Until, err = parseAnalyticsTime(v); err != nil {
+			return nil, err
+		}
+	}
+	if v := r.URL.Query().Get("top"); v != "" {
+		if q.Top, err = strconv.Atoi(v); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+func parseAnalyticsTime(v string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
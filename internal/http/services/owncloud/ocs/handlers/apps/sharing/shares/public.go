@@ -33,6 +33,7 @@ import (
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/publicshare"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/pkg/errors"
 )
@@ -70,6 +71,10 @@ func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request,
 	}
 
 	if statInfo != nil && statInfo.Type == provider.ResourceType_RESOURCE_TYPE_FILE {
+		if publicshare.IsUploadOnly(newPermissions) {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "upload only links can only be created for folders", nil)
+			return
+		}
 		// Single file shares should never have delete or create permissions
 		role := conversions.RoleFromResourcePermissions(newPermissions)
 		permissions := role.OCSPermissions()
@@ -78,6 +83,11 @@ func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request,
 		newPermissions = conversions.RoleFromOCSPermissions(permissions).CS3ResourcePermissions()
 	}
 
+	if !linkPermissionsAllowed(statInfo.GetPermissionSet(), newPermissions) {
+		response.WriteOCSError(w, r, http.StatusForbidden, "Cannot set the requested share permissions", nil)
+		return
+	}
+
 	req := link.CreatePublicShareRequest{
 		ResourceInfo: statInfo,
 		Grant: &link.Grant{
@@ -96,6 +106,10 @@ func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request,
 				response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "invalid datetime format", err)
 				return
 			}
+			if publicshare.IsExpired(&link.PublicShare{Expiration: expireTime}) {
+				response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "expiration date is in the past", nil)
+				return
+			}
 			if expireTime != nil {
 				req.Grant.Expiration = expireTime
 			}
@@ -118,6 +132,15 @@ func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request,
 	}
 
 	if createRes.Status.Code != rpc.Code_CODE_OK {
+		switch createRes.Status.Code {
+		case rpc.Code_CODE_PERMISSION_DENIED:
+			// e.g. refused by the share policy of the space
+			response.WriteOCSError(w, r, http.StatusForbidden, createRes.Status.Message, nil)
+			return
+		case rpc.Code_CODE_INVALID_ARGUMENT:
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, createRes.Status.Message, nil)
+			return
+		}
 		log.Debug().Err(errors.New("create public share failed")).Str("shares", "createShare").Msgf("create public share failed with status code: %v", createRes.Status.Code.String())
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "grpc create public share request failed", err)
 		return
@@ -269,6 +292,19 @@ func (h *Handler) updatePublicShare(w http.ResponseWriter, r *http.Request, shar
 	// update permissions if given
 	if newPermissions != nil {
 		updatesFound = true
+		statRes, err := gwC.Stat(r.Context(), &provider.StatRequest{Ref: &provider.Reference{ResourceId: before.GetShare().GetResourceId()}})
+		if err != nil || statRes.Status.Code != rpc.Code_CODE_OK {
+			response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "missing resource information", err)
+			return
+		}
+		if !linkPermissionsAllowed(statRes.Info.PermissionSet, newPermissions) {
+			response.WriteOCSError(w, r, http.StatusForbidden, "Cannot set the requested share permissions", nil)
+			return
+		}
+		if statRes.Info.Type == provider.ResourceType_RESOURCE_TYPE_FILE && publicshare.IsUploadOnly(newPermissions) {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "upload only links can only be created for folders", nil)
+			return
+		}
 		publicSharePermissions := &link.PublicSharePermissions{
 			Permissions: newPermissions,
 		}
@@ -297,6 +333,10 @@ func (h *Handler) updatePublicShare(w http.ResponseWriter, r *http.Request, shar
 				response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid datetime format", err)
 				return
 			}
+			if publicshare.IsExpired(&link.PublicShare{Expiration: newExpiration}) {
+				response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "expiration date is in the past", nil)
+				return
+			}
 		}
 
 		beforeExpiration, _ := json.Marshal(before.Share.Expiration)
@@ -326,7 +366,50 @@ func (h *Handler) updatePublicShare(w http.ResponseWriter, r *http.Request, shar
 		})
 	}
 
+	// Access rules of subfolders, replaced as a whole
+	var rulesOpaque *types.Opaque
+	if v, ok := r.Form["access_rules"]; ok {
+		updatesFound = true
+		rules, err := publicshare.DecodeAccessRules([]byte(v[0]))
+		if err != nil {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid access rules", err)
+			return
+		}
+		if rulesOpaque, err = publicshare.AddAccessRulesToOpaque(nil, rules); err != nil {
+			response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error encoding access rules", err)
+			return
+		}
+	}
+
 	publicShare := before.Share
+	accessRules := before.Opaque
+
+	if rulesOpaque != nil {
+		uRes, err := gwC.UpdatePublicShare(r.Context(), &link.UpdatePublicShareRequest{
+			Opaque: rulesOpaque,
+			Ref: &link.PublicShareReference{
+				Spec: &link.PublicShareReference_Id{
+					Id: &link.PublicShareId{
+						OpaqueId: shareID,
+					},
+				},
+			},
+		})
+		switch {
+		case err != nil:
+			log.Err(err).Str("shareID", shareID).Msg("sending access rules to public link provider")
+			response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "Error sending update request to public link provider", err)
+			return
+		case uRes.Status.Code == rpc.Code_CODE_PERMISSION_DENIED:
+			response.WriteOCSError(w, r, http.StatusForbidden, uRes.Status.Message, nil)
+			return
+		case uRes.Status.Code != rpc.Code_CODE_OK:
+			response.WriteOCSError(w, r, response.MetaServerError.StatusCode, uRes.Status.Message, nil)
+			return
+		}
+		publicShare = uRes.Share
+		accessRules = uRes.Opaque
+	}
 
 	// Updates are atomical. See: https://github.com/cs3org/cs3apis/pull/67#issuecomment-617651428 so in order to get the latest updated version
 	if len(updates) > 0 {
@@ -349,6 +432,7 @@ func (h *Handler) updatePublicShare(w http.ResponseWriter, r *http.Request, shar
 			}
 		}
 		publicShare = uRes.Share
+		accessRules = uRes.Opaque
 	} else if !updatesFound {
 		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "No updates specified in request", nil)
 		return
@@ -364,6 +448,7 @@ func (h *Handler) updatePublicShare(w http.ResponseWriter, r *http.Request, shar
 	}
 
 	s := conversions.PublicShare2ShareData(publicShare, r, h.publicURL)
+	addAccessRules(s, accessRules)
 	err = h.addFileInfo(r.Context(), s, statRes.Info)
 	if err != nil {
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error enhancing response with share data", err)
@@ -410,6 +495,26 @@ func (h *Handler) removePublicShare(w http.ResponseWriter, r *http.Request, shar
 	response.WriteOCSSuccess(w, r, nil)
 }
 
+// linkPermissionsAllowed checks that a link does not grant more than the user
+// creating it holds on the resource. For a space these are the permissions of
+// the space role, so that e.g. a viewer of a space cannot hand out an editor
+// link to it.
+// addAccessRules adds the access rules in the opaque of a public share
+// response to the share data, without the hashes of their passwords.
+func addAccessRules(s *conversions.ShareData, o *types.Opaque) {
+	rules, _, err := publicshare.AccessRulesFromOpaque(o)
+	if err != nil || len(rules) == 0 {
+		return
+	}
+	s.AccessRules = rules.Redacted()
+}
+
+func linkPermissionsAllowed(granted, requested *provider.ResourcePermissions) bool {
+	have := conversions.RoleFromResourcePermissions(granted).OCSPermissions()
+	want := conversions.RoleFromResourcePermissions(requested).OCSPermissions()
+	return have.Contain(want &^ conversions.PermissionShare)
+}
+
 func ocPublicPermToCs3(permKey int, h *Handler) (*provider.ResourcePermissions, error) {
 	// TODO refactor this ocPublicPermToRole[permKey] check into a conversions.NewPublicSharePermissions?
 	// not all permissions are possible for public shares
@@ -0,0 +1,47 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package shares
+
+import (
+	"testing"
+
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
+)
+
+func TestLinkPermissionsAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		granted   *conversions.Role
+		requested *conversions.Role
+		expected  bool
+	}{
+		{"manager shares read only", conversions.NewManagerRole(), conversions.NewViewerRole(), true},
+		{"manager shares editor", conversions.NewManagerRole(), conversions.NewEditorRole(), true},
+		{"editor shares upload only", conversions.NewEditorRole(), conversions.NewUploaderRole(), true},
+		{"viewer shares read only", conversions.NewViewerRole(), conversions.NewViewerRole(), true},
+		{"viewer shares editor", conversions.NewViewerRole(), conversions.NewEditorRole(), false},
+		{"viewer shares upload only", conversions.NewViewerRole(), conversions.NewUploaderRole(), false},
+	}
+
+	for _, tt := range tests {
+		if got := linkPermissionsAllowed(tt.granted.CS3ResourcePermissions(), tt.requested.CS3ResourcePermissions()); got != tt.expected {
+			t.Errorf("%s: linkPermissionsAllowed returned %t instead of expected %t", tt.name, got, tt.expected)
+		}
+	}
+}
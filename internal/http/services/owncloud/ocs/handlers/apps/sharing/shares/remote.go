@@ -19,6 +19,7 @@
 package shares
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -30,6 +31,7 @@ import (
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/go-chi/chi/v5"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
@@ -181,3 +183,148 @@ func (h *Handler) ListFederatedShares(w http.ResponseWriter, r *http.Request) {
 	}
 	response.WriteOCSSuccess(w, r, shares)
 }
+
+// ListReceivedFederatedShares handles GET requests on /apps/files_sharing/api/v1/remote_shares
+func (h *Handler) ListReceivedFederatedShares(w http.ResponseWriter, r *http.Request) {
+	h.listReceivedFederatedShares(w, r, ocm.ShareState_SHARE_STATE_ACCEPTED)
+}
+
+// ListPendingFederatedShares handles GET requests on /apps/files_sharing/api/v1/remote_shares/pending
+func (h *Handler) ListPendingFederatedShares(w http.ResponseWriter, r *http.Request) {
+	h.listReceivedFederatedShares(w, r, ocm.ShareState_SHARE_STATE_PENDING)
+}
+
+func (h *Handler) listReceivedFederatedShares(w http.ResponseWriter, r *http.Request, state ocm.ShareState) {
+	ctx := r.Context()
+
+	gatewayClient, err := pool.GetGatewayServiceClient(pool.Endpoint(h.gatewayAddr))
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+
+	res, err := gatewayClient.ListReceivedOCMShares(ctx, &ocm.ListReceivedOCMSharesRequest{})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc list received ocm shares request", err)
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "grpc list received ocm shares request failed", errors.New(res.Status.Message))
+		return
+	}
+
+	shares := make([]*conversions.RemoteShareData, 0, len(res.Shares))
+	for _, rs := range res.Shares {
+		if rs.State == state {
+			shares = append(shares, receivedOCMShare2RemoteShareData(rs))
+		}
+	}
+	response.WriteOCSSuccess(w, r, shares)
+}
+
+// GetReceivedFederatedShare handles GET requests on /apps/files_sharing/api/v1/remote_shares/{shareid}
+func (h *Handler) GetReceivedFederatedShare(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shareID := chi.URLParam(r, "shareid")
+
+	gatewayClient, err := pool.GetGatewayServiceClient(pool.Endpoint(h.gatewayAddr))
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+
+	res, err := gatewayClient.GetReceivedOCMShare(ctx, &ocm.GetReceivedOCMShareRequest{
+		Ref: &ocm.ShareReference{
+			Spec: &ocm.ShareReference_Id{Id: &ocm.ShareId{OpaqueId: shareID}},
+		},
+	})
+	switch {
+	case err != nil:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc get received ocm share request", err)
+		return
+	case res.Status.Code == rpc.Code_CODE_NOT_FOUND:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "share not found", nil)
+		return
+	case res.Status.Code != rpc.Code_CODE_OK:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "grpc get received ocm share request failed", errors.New(res.Status.Message))
+		return
+	}
+	response.WriteOCSSuccess(w, r, receivedOCMShare2RemoteShareData(res.Share))
+}
+
+// AcceptFederatedShare handles POST requests on /apps/files_sharing/api/v1/remote_shares/pending/{shareid}
+func (h *Handler) AcceptFederatedShare(w http.ResponseWriter, r *http.Request) {
+	h.updateReceivedFederatedShare(w, r, ocm.ShareState_SHARE_STATE_ACCEPTED)
+}
+
+// DeclineFederatedShare handles DELETE requests on /apps/files_sharing/api/v1/remote_shares/pending/{shareid}
+// and /apps/files_sharing/api/v1/remote_shares/{shareid}
+func (h *Handler) DeclineFederatedShare(w http.ResponseWriter, r *http.Request) {
+	h.updateReceivedFederatedShare(w, r, ocm.ShareState_SHARE_STATE_REJECTED)
+}
+
+func (h *Handler) updateReceivedFederatedShare(w http.ResponseWriter, r *http.Request, state ocm.ShareState) {
+	ctx := r.Context()
+	shareID := chi.URLParam(r, "shareid")
+
+	gatewayClient, err := pool.GetGatewayServiceClient(pool.Endpoint(h.gatewayAddr))
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+
+	res, err := gatewayClient.UpdateReceivedOCMShare(ctx, &ocm.UpdateReceivedOCMShareRequest{
+		Share: &ocm.ReceivedShare{
+			Share: &ocm.Share{Id: &ocm.ShareId{OpaqueId: shareID}},
+			State: state,
+		},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"state"}},
+	})
+	switch {
+	case err != nil:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc update received ocm share request", err)
+		return
+	case res.Status.Code == rpc.Code_CODE_NOT_FOUND:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "share not found", nil)
+		return
+	case res.Status.Code != rpc.Code_CODE_OK:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "grpc update received ocm share request failed", errors.New(res.Status.Message))
+		return
+	}
+	response.WriteOCSSuccess(w, r, nil)
+}
+
+func receivedOCMShare2RemoteShareData(rs *ocm.ReceivedShare) *conversions.RemoteShareData {
+	s := rs.GetShare()
+	data := &conversions.RemoteShareData{
+		ID:        s.GetId().GetOpaqueId(),
+		Remote:    s.GetOwner().GetIdp(),
+		RemoteID:  string(s.GetGrantee().GetOpaque().GetMap()["remoteShareId"].GetValue()),
+		Name:      s.GetName(),
+		Owner:     s.GetOwner().GetOpaqueId(),
+		ShareType: conversions.ShareTypeFederatedCloudShare,
+		Accepted:  rs.GetState() == ocm.ShareState_SHARE_STATE_ACCEPTED,
+		STime:     s.GetCtime().GetSeconds(),
+		MTime:     s.GetMtime().GetSeconds(),
+	}
+	switch s.GetGrantee().GetType() {
+	case provider.GranteeType_GRANTEE_TYPE_GROUP:
+		data.User = s.GetGrantee().GetGroupId().GetOpaqueId()
+	default:
+		data.User = s.GetGrantee().GetUserId().GetOpaqueId()
+	}
+	switch rs.GetState() {
+	case ocm.ShareState_SHARE_STATE_ACCEPTED:
+		data.State = ocsStateAccepted
+	case ocm.ShareState_SHARE_STATE_PENDING:
+		data.State = ocsStatePending
+	case ocm.ShareState_SHARE_STATE_REJECTED:
+		data.State = ocsStateRejected
+	default:
+		data.State = ocsStateUnknown
+	}
+	if p := s.GetPermissions().GetPermissions(); p != nil {
+		data.Permissions = conversions.RoleFromResourcePermissions(p).OCSPermissions()
+	}
+	return data
+}
@@ -46,6 +46,7 @@ import (
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/antivirus"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/publicshare"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
@@ -73,6 +74,7 @@ type Handler struct {
 	userIdentifierCache    *ttlcache.Cache
 	resourceInfoCache      cache.ResourceInfoCache
 	resourceInfoCacheTTL   time.Duration
+	publicSpaceLinks       bool
 }
 
 // we only cache the minimal set of data instead of the full user metadata
@@ -106,6 +108,9 @@ func (h *Handler) Init(c *config.Config) {
 
 	h.additionalInfoTemplate, _ = template.New("additionalInfo").Parse(c.AdditionalInfoAttribute)
 	h.resourceInfoCacheTTL = time.Second * time.Duration(c.ResourceInfoCacheTTL)
+	if cs := c.Capabilities.Capabilities; cs != nil && cs.FilesSharing != nil && cs.FilesSharing.Public != nil {
+		h.publicSpaceLinks = bool(cs.FilesSharing.Public.CanShareSpaces)
+	}
 
 	h.userIdentifierCache = ttlcache.NewCache()
 	_ = h.userIdentifierCache.SetTTL(time.Second * time.Duration(c.UserIdentifierCacheTTL))
@@ -207,6 +212,10 @@ func (h *Handler) CreateShare(w http.ResponseWriter, r *http.Request) {
 			h.createGroupShare(w, r, statRes.Info, role, val)
 		}
 	case int(conversions.ShareTypePublicLink):
+		if isSpaceRoot(&ref) && !h.publicSpaceLinks {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "public links on spaces are disabled", nil)
+			return
+		}
 		// public links default to read only
 		if _, _, err := h.extractPermissions(w, r, statRes.Info, conversions.NewViewerRole()); err == nil {
 			h.createPublicLinkShare(w, r, statRes.Info)
@@ -337,6 +346,7 @@ func (h *Handler) GetShare(w http.ResponseWriter, r *http.Request) {
 
 	if err == nil && psRes.GetShare() != nil {
 		share = conversions.PublicShare2ShareData(psRes.Share, r, h.publicURL)
+		addAccessRules(share, psRes.Opaque)
 		resourceID = psRes.Share.ResourceId
 	}
 
@@ -897,6 +907,8 @@ func (h *Handler) addFileInfo(ctx context.Context, s *conversions.ShareData, inf
 		// item type
 		s.ItemType = conversions.ResourceType(info.GetType()).String()
 
+		s.ScanStatus = string(antivirus.StatusFromResourceInfo(info))
+
 		// file owner might not yet be set. Use file info
 		if s.UIDFileOwner == "" {
 			s.UIDFileOwner = info.GetOwner().GetOpaqueId()
@@ -1093,9 +1105,17 @@ func (h *Handler) createCs3Share(ctx context.Context, w http.ResponseWriter, r *
 		return
 	}
 	if createShareResponse.Status.Code != rpc.Code_CODE_OK {
-		if createShareResponse.Status.Code == rpc.Code_CODE_NOT_FOUND {
+		switch createShareResponse.Status.Code {
+		case rpc.Code_CODE_NOT_FOUND:
 			response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "not found", nil)
 			return
+		case rpc.Code_CODE_PERMISSION_DENIED:
+			// e.g. refused by the share policy of the space
+			response.WriteOCSError(w, r, http.StatusForbidden, createShareResponse.Status.Message, nil)
+			return
+		case rpc.Code_CODE_INVALID_ARGUMENT:
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, createShareResponse.Status.Message, nil)
+			return
 		}
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "grpc create share request failed", err)
 		return
@@ -166,6 +166,12 @@ func (h *Handler) removeSpaceMember(w http.ResponseWriter, r *http.Request, spac
 	response.WriteOCSSuccess(w, r, nil)
 }
 
+// isSpaceRoot tells whether the reference parsed from a space_ref points to the
+// root of the space rather than to a resource inside it.
+func isSpaceRoot(ref *provider.Reference) bool {
+	return ref.ResourceId != nil && ref.Path == "."
+}
+
 func (h *Handler) getStorageProviderClient(p *registry.ProviderInfo) (provider.ProviderAPIClient, error) {
 	c, err := pool.GetStorageProviderServiceClient(pool.Endpoint(p.Address))
 	if err != nil {
@@ -0,0 +1,86 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package announcements
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/announcements"
+	"github.com/cs3org/reva/pkg/announcements/registry"
+)
+
+// Handler lists the active announcements
+type Handler struct {
+	registry announcements.Registry
+}
+
+// Init initializes this and any contained handlers
+func (h *Handler) Init(c *config.Config) error {
+	f, ok := registry.NewFuncs[c.AnnouncementsRegistry]
+	if !ok {
+		return fmt.Errorf("announcements registry not found: %s", c.AnnouncementsRegistry)
+	}
+	reg, err := f(c.AnnouncementsRegistries[c.AnnouncementsRegistry])
+	if err != nil {
+		return err
+	}
+	h.registry = reg
+	return nil
+}
+
+// Announcement holds the data of an announcement
+type Announcement struct {
+	ID       string `json:"id" xml:"id"`
+	Title    string `json:"title" xml:"title"`
+	Message  string `json:"message" xml:"message"`
+	Severity string `json:"severity" xml:"severity"`
+	Start    int64  `json:"start" xml:"start"`
+	// End is 0 for announcements shown until they are removed
+	End int64 `json:"end" xml:"end"`
+}
+
+// ListAnnouncements handles GET requests on /cloud/announcements
+func (h *Handler) ListAnnouncements(w http.ResponseWriter, r *http.Request) {
+	list, err := announcements.Active(r.Context(), h.registry, time.Now())
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error listing announcements", err)
+		return
+	}
+
+	res := make([]*Announcement, 0, len(list))
+	for _, a := range list {
+		end := int64(0)
+		if !a.End.IsZero() {
+			end = a.End.Unix()
+		}
+		res = append(res, &Announcement{
+			ID:       a.ID,
+			Title:    a.Title,
+			Message:  a.Message,
+			Severity: a.Severity,
+			Start:    a.Start.Unix(),
+			End:      end,
+		})
+	}
+	response.WriteOCSSuccess(w, r, res)
+}
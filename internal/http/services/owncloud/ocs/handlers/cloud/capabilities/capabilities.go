@@ -24,6 +24,8 @@ import (
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/data"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/tenant"
 )
 
 // Handler renders the capability endpoint
@@ -32,10 +34,11 @@ type Handler struct {
 	defaultUploadProtocol  string
 	userAgentChunkingMap   map[string]string
 	groupBasedCapabilities map[string][]string
+	tenants                *tenant.Manager
 }
 
 // Init initializes this and any contained handlers
-func (h *Handler) Init(c *config.Config) {
+func (h *Handler) Init(c *config.Config) error {
 	h.c = c.Capabilities
 	h.defaultUploadProtocol = c.DefaultUploadProtocol
 	h.userAgentChunkingMap = c.UserAgentChunkingMap
@@ -168,6 +171,7 @@ func (h *Handler) Init(c *config.Config) {
 	// h.c.Capabilities.FilesSharing.IsPublic.Upload is boolean
 	// h.c.Capabilities.FilesSharing.IsPublic.Multiple is boolean
 	// h.c.Capabilities.FilesSharing.IsPublic.SupportsUploadOnly is boolean
+	// h.c.Capabilities.FilesSharing.IsPublic.CanShareSpaces is boolean
 
 	if h.c.Capabilities.FilesSharing.User == nil {
 		h.c.Capabilities.FilesSharing.User = &data.CapabilitiesFilesSharingUser{}
@@ -234,10 +238,16 @@ func (h *Handler) Init(c *config.Config) {
 	// upload protocol-specific details
 	setCapabilitiesForChunkProtocol(chunkProtocol(h.defaultUploadProtocol), h.c.Capabilities)
 
+	var err error
+	h.tenants, err = tenant.New(sharedconf.GetTenancy())
+	return err
 }
 
 // Handler renders the capabilities
 func (h *Handler) GetCapabilities(w http.ResponseWriter, r *http.Request) {
 	c := h.getCapabilitiesForUserAgent(r.Context(), r.UserAgent())
+	if o, ok := h.tenants.Overrides(h.tenants.OfRequest(r)); ok {
+		applyTenantOverrides(o, c.Capabilities)
+	}
 	response.WriteOCSSuccess(w, r, c)
 }
@@ -21,9 +21,13 @@ package capabilities
 import (
 	"encoding/json"
 	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/data"
+	"github.com/cs3org/reva/pkg/rhttp/httpcache"
+	"github.com/cs3org/reva/pkg/tenant"
 )
 
 func TestMarshal(t *testing.T) {
@@ -56,3 +60,52 @@ func TestMarshal(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestApplyTenantOverrides(t *testing.T) {
+	shared := &data.Capabilities{
+		Core: &data.CapabilitiesCore{
+			Status: &data.Status{ProductName: "reva"},
+		},
+		FilesSharing: &data.CapabilitiesFilesSharing{
+			GroupSharing: true,
+			Public:       &data.CapabilitiesFilesSharingPublic{Enabled: true},
+		},
+	}
+
+	c := *shared
+	applyTenantOverrides(&tenant.Overrides{Name: "CERNBox", ShareTypes: []string{"user", "public"}}, &c)
+
+	if c.Core.Status.ProductName != "CERNBox" || c.Theming == nil || c.Theming.Name != "CERNBox" {
+		t.Errorf("branding not applied: %+v %+v", c.Core.Status, c.Theming)
+	}
+	if c.FilesSharing.GroupSharing || !c.FilesSharing.Public.Enabled {
+		t.Errorf("share types not applied: %+v", c.FilesSharing)
+	}
+	if shared.Core.Status.ProductName != "reva" || !shared.FilesSharing.GroupSharing {
+		t.Error("the shared capabilities must not be changed")
+	}
+}
+
+func TestUserAgentVariant(t *testing.T) {
+	h := &Handler{userAgentChunkingMap: map[string]string{"mirall": "v1", "iOs": "tus"}}
+
+	key := func(userAgent string) string {
+		var k string
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { k = httpcache.Key(r) })
+		r := httptest.NewRequest(http.MethodGet, "/cloud/capabilities", nil)
+		r.Header.Set("User-Agent", userAgent)
+		h.UserAgentVariant(next).ServeHTTP(httptest.NewRecorder(), r)
+		return k
+	}
+
+	// the anonymous requests of the clients of different classes don't share a response
+	if key("Mozilla/5.0 (mirall 2.9)") == key("Mozilla/5.0 (iOs 11)") {
+		t.Error("expected the user agent classes to have their own keys")
+	}
+	if key("Mozilla/5.0 (mirall 2.9)") != key("Mozilla/5.0 (mirall 2.10)") {
+		t.Error("expected the user agents of a class to share a key")
+	}
+	if key("curl/7.79") != key("") {
+		t.Error("expected the user agents matching no class to share a key")
+	}
+}
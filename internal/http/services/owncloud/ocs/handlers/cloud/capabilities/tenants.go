@@ -0,0 +1,82 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package capabilities
+
+import (
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/data"
+	"github.com/cs3org/reva/pkg/tenant"
+)
+
+// applyTenantOverrides presents the branding and capabilities configured for
+// a tenant. The nested structs are copied before being changed, as they are
+// shared by all requests.
+func applyTenantOverrides(o *tenant.Overrides, c *data.Capabilities) {
+	if o.Name != "" && c.Core != nil && c.Core.Status != nil {
+		core := *c.Core
+		status := *c.Core.Status
+		status.ProductName = o.Name
+		core.Status = &status
+		c.Core = &core
+	}
+
+	if o.Name != "" || o.URL != "" || o.Slogan != "" || o.Color != "" || o.Logo != "" {
+		c.Theming = &data.CapabilitiesTheming{
+			Name:   o.Name,
+			URL:    o.URL,
+			Slogan: o.Slogan,
+			Color:  o.Color,
+			Logo:   o.Logo,
+		}
+	}
+
+	if o.DefaultQuota != "" && c.Files != nil {
+		files := *c.Files
+		files.DefaultQuota = o.DefaultQuota
+		c.Files = &files
+	}
+
+	if len(o.ShareTypes) > 0 && c.FilesSharing != nil {
+		sharing := *c.FilesSharing
+		sharing.GroupSharing = false
+		if hasShareType(o.ShareTypes, "group") {
+			sharing.GroupSharing = true
+		}
+		if sharing.Public != nil {
+			public := *sharing.Public
+			public.Enabled = false
+			if hasShareType(o.ShareTypes, "public") {
+				public.Enabled = true
+			}
+			sharing.Public = &public
+		}
+		if sharing.Federation != nil && !hasShareType(o.ShareTypes, "federated") {
+			sharing.Federation = &data.CapabilitiesFilesSharingFederation{}
+		}
+		c.FilesSharing = &sharing
+	}
+}
+
+func hasShareType(types []string, t string) bool {
+	for _, e := range types {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
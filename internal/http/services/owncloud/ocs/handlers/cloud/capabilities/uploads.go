@@ -20,10 +20,13 @@ package capabilities
 
 import (
 	"context"
+	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/data"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/rhttp/httpcache"
 	"github.com/juliangruber/go-intersect"
 )
 
@@ -57,6 +60,30 @@ func (h *Handler) getCapabilitiesForUserAgent(ctx context.Context, userAgent str
 	return data.CapabilitiesData{Capabilities: &c, Version: h.c.Version}
 }
 
+// userAgentClass returns the chunking protocols set for the user agent, which
+// is the part of the capabilities depending on it.
+func (h *Handler) userAgentClass(userAgent string) string {
+	if userAgent == "" {
+		return ""
+	}
+	var protocols []string
+	for k, v := range h.userAgentChunkingMap {
+		if strings.Contains(userAgent, k) {
+			protocols = append(protocols, v)
+		}
+	}
+	sort.Strings(protocols)
+	return strings.Join(protocols, ",")
+}
+
+// UserAgentVariant marks the capabilities served to the clients of a class of
+// user agents as a variant of their own, so that they are cached separately.
+func (h *Handler) UserAgentVariant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, httpcache.WithVariant(r, h.userAgentClass(r.UserAgent())))
+	})
+}
+
 func ctxUserBelongsToGroups(ctx context.Context, groups []string) bool {
 	if user, ok := ctxpkg.ContextGetUser(ctx); ok {
 		return len(intersect.Simple(groups, user.Groups)) > 0
@@ -0,0 +1,126 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package devices
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/devices"
+	"github.com/cs3org/reva/pkg/devices/registry"
+	"github.com/cs3org/reva/pkg/errtypes"
+	revocationregistry "github.com/cs3org/reva/pkg/token/revocation/registry"
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler lists and revokes the devices of the current user
+type Handler struct {
+	tracker *devices.Tracker
+}
+
+// Init initializes this and any contained handlers
+func (h *Handler) Init(c *config.Config) error {
+	f, ok := registry.NewFuncs[c.DeviceRegistry]
+	if !ok {
+		return fmt.Errorf("device registry not found: %s", c.DeviceRegistry)
+	}
+	reg, err := f(c.DeviceRegistries[c.DeviceRegistry])
+	if err != nil {
+		return err
+	}
+
+	store, err := revocationregistry.NewStore(c.RevocationStore, c.RevocationStores)
+	if err != nil {
+		return err
+	}
+
+	// the activity of the devices is tracked by the auth interceptor, only listing and revoking is done here
+	h.tracker = devices.NewTracker(reg, store, 0, 0)
+	return nil
+}
+
+// Device holds the data of a device
+type Device struct {
+	ID             string `json:"id" xml:"id"`
+	UserAgent      string `json:"user-agent" xml:"user-agent"`
+	CredentialType string `json:"credential-type,omitempty" xml:"credential-type,omitempty"`
+	AppPassword    string `json:"app-password,omitempty" xml:"app-password,omitempty"`
+	IP             string `json:"ip" xml:"ip"`
+	FirstSeen      int64  `json:"first-seen" xml:"first-seen"`
+	LastSeen       int64  `json:"last-seen" xml:"last-seen"`
+	Revoked        bool   `json:"revoked" xml:"revoked"`
+}
+
+// ListDevices handles GET requests on /cloud/user/devices
+func (h *Handler) ListDevices(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "missing user in context", fmt.Errorf("missing user in context"))
+		return
+	}
+
+	list, err := h.tracker.List(ctx, u.Id)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error listing devices", err)
+		return
+	}
+
+	res := make([]*Device, 0, len(list))
+	for _, d := range list {
+		res = append(res, &Device{
+			ID:             d.ID,
+			UserAgent:      d.UserAgent,
+			CredentialType: d.CredentialType,
+			AppPassword:    d.AppPassword,
+			IP:             d.IP,
+			FirstSeen:      d.FirstSeen.Unix(),
+			LastSeen:       d.LastSeen.Unix(),
+			Revoked:        d.Revoked,
+		})
+	}
+	response.WriteOCSSuccess(w, r, res)
+}
+
+// RevokeDevice handles DELETE requests on /cloud/user/devices/{deviceid}
+func (h *Handler) RevokeDevice(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "missing user in context", fmt.Errorf("missing user in context"))
+		return
+	}
+
+	deviceID := chi.URLParam(r, "deviceid")
+	if err := h.tracker.Revoke(ctx, u.Id, deviceID); err != nil {
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "device not found", nil)
+			return
+		}
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error revoking device", err)
+		return
+	}
+
+	appctx.GetLogger(ctx).Info().Str("user", u.Id.OpaqueId).Str("device", deviceID).Msg("device revoked")
+	response.WriteOCSSuccess(w, r, nil)
+}
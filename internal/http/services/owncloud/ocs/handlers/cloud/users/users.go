@@ -33,6 +33,7 @@ import (
 	"github.com/cs3org/reva/pkg/appctx"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/storage"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -114,8 +115,8 @@ func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
 		ocdav.HandleErrorStatus(sublog, w, getHomeRes.Status)
 		return
 	}
-	var total, used uint64 = 2, 1
-	var relative float32
+	// the quota is unknown unless the storage reports it
+	quota := &Quota{Free: -2, Total: -2, Definition: "default"}
 	// lightweight and federated accounts don't have access to their storage space
 	if u.Id.Type != userpb.UserType_USER_TYPE_LIGHTWEIGHT && u.Id.Type != userpb.UserType_USER_TYPE_FEDERATED {
 		getQuotaRes, err := gc.GetQuota(ctx, &gateway.GetQuotaRequest{Ref: &provider.Reference{Path: getHomeRes.Path}})
@@ -129,22 +130,19 @@ func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
 			ocdav.HandleErrorStatus(sublog, w, getQuotaRes.Status)
 			return
 		}
-		total = getQuotaRes.TotalBytes
-		used = getQuotaRes.UsedBytes
-		relative = float32(float64(used) / float64(total))
+		q := storage.Quota{Total: getQuotaRes.TotalBytes, Used: getQuotaRes.UsedBytes}
+		quota.Used = int64(q.Used)
+		if q.IsKnown() {
+			// the free space matches the DAV:quota-available-bytes reported by PROPFIND
+			quota.Free = int64(q.Free())
+			quota.Total = int64(q.Total)
+			quota.Relative = float32(float64(q.Used) / float64(q.Total))
+		}
 	}
 
 	response.WriteOCSSuccess(w, r, &Users{
-		// ocs can only return the home storage quota
-		Quota: &Quota{
-			Free: int64(total - used),
-			Used: int64(used),
-			// TODO support negative values or flags for the quota to carry special meaning: -1 = uncalculated, -2 = unknown, -3 = unlimited
-			// for now we can only report total and used
-			Total:      int64(total),
-			Relative:   relative,
-			Definition: "default",
-		},
+		// ocs can only return the home storage quota, using -2 for free and total if it is unknown like oc10
+		Quota:       quota,
 		DisplayName: u.DisplayName,
 		Email:       u.Mail,
 		UserType:    conversions.UserTypeString(u.Id.Type),
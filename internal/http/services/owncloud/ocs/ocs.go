@@ -19,23 +19,38 @@
 package ocs
 
 import (
+	"context"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/ReneKroon/ttlcache/v2"
+	preferences "github.com/cs3org/go-cs3apis/cs3/preferences/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/apps/comments"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/apps/files/downloadtokens"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/apps/sharing/publications"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/apps/sharing/sharees"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/apps/sharing/shares"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/cloud/announcements"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/cloud/capabilities"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/cloud/devices"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/cloud/user"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/cloud/users"
 	configHandler "github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/config"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
 	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/locale"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/httpcache"
 	"github.com/go-chi/chi/v5"
 	"github.com/mitchellh/mapstructure"
 	"github.com/rs/zerolog"
+	"golang.org/x/text/language"
 )
 
 func init() {
@@ -86,6 +101,11 @@ func (s *svc) Unprotected() []string {
 	return []string{"/v1.php/cloud/capabilities", "/v2.php/cloud/capabilities"}
 }
 
+// IsCrossOrigin returns true as the OCS API is used by web clients served from other origins.
+func (s *svc) IsCrossOrigin() bool {
+	return true
+}
+
 func (s *svc) routerInit() error {
 	capabilitiesHandler := new(capabilities.Handler)
 	userHandler := new(user.Handler)
@@ -93,14 +113,63 @@ func (s *svc) routerInit() error {
 	configHandler := new(configHandler.Handler)
 	sharesHandler := new(shares.Handler)
 	shareesHandler := new(sharees.Handler)
-	capabilitiesHandler.Init(s.c)
+	if err := capabilitiesHandler.Init(s.c); err != nil {
+		return err
+	}
 	usersHandler.Init(s.c)
 	configHandler.Init(s.c)
 	sharesHandler.Init(s.c)
-	shareesHandler.Init(s.c)
+	if err := shareesHandler.Init(s.c); err != nil {
+		return err
+	}
+	var devicesHandler *devices.Handler
+	if s.c.DeviceRegistry != "" {
+		devicesHandler = new(devices.Handler)
+		if err := devicesHandler.Init(s.c); err != nil {
+			return err
+		}
+	}
+	var announcementsHandler *announcements.Handler
+	if s.c.AnnouncementsRegistry != "" {
+		announcementsHandler = new(announcements.Handler)
+		if err := announcementsHandler.Init(s.c); err != nil {
+			return err
+		}
+	}
+	var commentsHandler *comments.Handler
+	if s.c.CommentsManager != "" {
+		commentsHandler = new(comments.Handler)
+		if err := commentsHandler.Init(s.c); err != nil {
+			return err
+		}
+	}
+	var downloadTokensHandler *downloadtokens.Handler
+	if s.c.DownloadTokenStore != "" {
+		downloadTokensHandler = new(downloadtokens.Handler)
+		if err := downloadTokensHandler.Init(s.c); err != nil {
+			return err
+		}
+	}
+	var publicationsHandler *publications.Handler
+	if s.c.PIDProvider != "" {
+		publicationsHandler = new(publications.Handler)
+		if err := publicationsHandler.Init(s.c); err != nil {
+			return err
+		}
+	}
+	dialects, err := response.NewDialects(&s.c.Dialects)
+	if err != nil {
+		return err
+	}
+	// capabilities and user info are requested by the clients on every sync cycle
+	cache := httpcache.New(&s.c.ResponseCache)
 
 	s.router.Route("/v{version:(1|2)}.php", func(r chi.Router) {
 		r.Use(response.VersionCtx)
+		r.Use(dialects.Handler)
+		if s.c.LocaleHints {
+			r.Use(s.localeHints)
+		}
 		r.Route("/apps/files_sharing/api/v1", func(r chi.Router) {
 			r.Route("/shares", func(r chi.Router) {
 				r.Get("/", sharesHandler.ListShares)
@@ -117,12 +186,41 @@ func (s *svc) routerInit() error {
 					r.Get("/{shareid}", sharesHandler.GetFederatedShare)
 				})
 				r.Get("/{shareid}", sharesHandler.GetShare)
+				r.Get("/{shareid}/analytics", sharesHandler.GetShareAnalytics)
 				r.Put("/{shareid}", sharesHandler.UpdateShare)
 				r.Delete("/{shareid}", sharesHandler.RemoveShare)
 			})
+			r.Route("/remote_shares", func(r chi.Router) {
+				r.Get("/", sharesHandler.ListReceivedFederatedShares)
+				r.Get("/pending", sharesHandler.ListPendingFederatedShares)
+				r.Post("/pending/{shareid}", sharesHandler.AcceptFederatedShare)
+				r.Delete("/pending/{shareid}", sharesHandler.DeclineFederatedShare)
+				r.Get("/{shareid}", sharesHandler.GetReceivedFederatedShare)
+				r.Delete("/{shareid}", sharesHandler.DeclineFederatedShare)
+			})
 			r.Get("/sharees", shareesHandler.FindSharees)
+			if publicationsHandler != nil {
+				r.Route("/publications", func(r chi.Router) {
+					r.Get("/", publicationsHandler.GetPublication)
+					r.Post("/", publicationsHandler.Publish)
+				})
+			}
 		})
 
+		if commentsHandler != nil {
+			r.Route("/apps/comments/api/v1/comments/{objecttype}/{objectid}", func(r chi.Router) {
+				r.Get("/", commentsHandler.ListComments)
+				r.Post("/", commentsHandler.CreateComment)
+				r.Get("/{commentid}", commentsHandler.GetComment)
+				r.Put("/{commentid}", commentsHandler.UpdateComment)
+				r.Delete("/{commentid}", commentsHandler.DeleteComment)
+			})
+		}
+
+		if downloadTokensHandler != nil {
+			r.Post("/apps/files/api/v1/downloadtokens", downloadTokensHandler.CreateDownloadToken)
+		}
+
 		// placeholder for notifications
 		r.Get("/apps/notifications/api/v1/notifications", func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
@@ -131,8 +229,17 @@ func (s *svc) routerInit() error {
 		r.Get("/config", configHandler.GetConfig)
 
 		r.Route("/cloud", func(r chi.Router) {
-			r.Get("/capabilities", capabilitiesHandler.GetCapabilities)
-			r.Get("/user", userHandler.GetSelf)
+			r.With(capabilitiesHandler.UserAgentVariant, cache.Handler).Get("/capabilities", capabilitiesHandler.GetCapabilities)
+			r.With(cache.Handler).Get("/user", userHandler.GetSelf)
+			if devicesHandler != nil {
+				r.Route("/user/devices", func(r chi.Router) {
+					r.Get("/", devicesHandler.ListDevices)
+					r.Delete("/{deviceid}", devicesHandler.RevokeDevice)
+				})
+			}
+			if announcementsHandler != nil {
+				r.Get("/announcements", announcementsHandler.ListAnnouncements)
+			}
 			r.Route("/users", func(r chi.Router) {
 				r.Get("/{userid}", usersHandler.GetUsers)
 				r.Get("/{userid}/groups", usersHandler.GetGroups)
@@ -155,3 +262,62 @@ func (s *svc) Handler() http.Handler {
 		s.router.ServeHTTP(w, r)
 	})
 }
+
+// localeHints adds the formatting hints of the locale preferred by the
+// authenticated user to the responses. The preference is only fetched when a
+// response is written or looked up in the response cache, which caches the
+// responses of each locale on their own.
+func (s *svc) localeHints(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if _, ok := ctxpkg.ContextGetUser(ctx); !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		var (
+			once  sync.Once
+			hints *locale.Hints
+		)
+		lookup := func() *locale.Hints {
+			once.Do(func() {
+				t, err := s.userLocale(ctx)
+				if err != nil {
+					appctx.GetLogger(ctx).Warn().Err(err).Msg("ocs: error getting the locale of the user")
+				}
+				hints = locale.HintsFor(t)
+			})
+			return hints
+		}
+		r = r.WithContext(response.ContextWithLazyLocale(ctx, lookup))
+		next.ServeHTTP(w, httpcache.WithLazyVariant(r, func() string { return lookup().Locale }))
+	})
+}
+
+// userLocale returns the locale preferred by the user of the context, or the
+// default one if the user has not chosen it.
+func (s *svc) userLocale(ctx context.Context) (language.Tag, error) {
+	fallback, err := locale.Parse(s.c.DefaultLocale)
+	if err != nil {
+		fallback = language.English
+	}
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.c.GatewaySvc))
+	if err != nil {
+		return fallback, err
+	}
+	res, err := client.GetKey(ctx, &preferences.GetKeyRequest{
+		Key: &preferences.PreferenceKey{Namespace: locale.PreferenceNamespace, Key: locale.PreferenceKey},
+	})
+	switch {
+	case err != nil:
+		return fallback, err
+	case res.Status.Code == rpc.Code_CODE_NOT_FOUND:
+		return fallback, nil
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return fallback, errtypes.InternalError(res.Status.Message)
+	}
+	t, err := locale.Parse(res.Val)
+	if err != nil {
+		return fallback, err
+	}
+	return t, nil
+}
@@ -0,0 +1,368 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package response
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/locale"
+	"github.com/cs3org/reva/pkg/rhttp/httpcache"
+)
+
+// Names of the built-in dialects.
+const (
+	DialectOwnCloud  = "owncloud"
+	DialectNextcloud = "nextcloud"
+)
+
+// Dialect describes how the responses expected by a family of clients differ
+// from the ownCloud ones.
+type Dialect struct {
+	// ListEmptyData sends missing data as an empty list instead of omitting it.
+	ListEmptyData bool `mapstructure:"list_empty_data"`
+	// FullMeta always sends the totalitems and itemsperpage meta fields.
+	FullMeta bool `mapstructure:"full_meta"`
+	// StatusCodes maps OCS status codes to the HTTP status codes sent instead
+	// of the ones of the API version, e.g. "997" = 401.
+	StatusCodes map[string]int `mapstructure:"status_codes"`
+	// Fields adjusts the fields of the data objects.
+	Fields []FieldRule `mapstructure:"fields"`
+}
+
+// FieldRule renames and adds fields of the data objects holding the Match
+// field, e.g. share_type for shares, or of all data objects when Match is
+// empty.
+type FieldRule struct {
+	Match    string                 `mapstructure:"match"`
+	Rename   map[string]string      `mapstructure:"rename"`
+	Defaults map[string]interface{} `mapstructure:"defaults"`
+}
+
+var builtinDialects = map[string]*Dialect{
+	DialectOwnCloud: {},
+	// Nextcloud always sends the full meta and a data element, answers
+	// unauthorized requests with 401 on v1 too and calls the name of a
+	// public link its label.
+	DialectNextcloud: {
+		ListEmptyData: true,
+		FullMeta:      true,
+		StatusCodes:   map[string]int{strconv.Itoa(MetaUnauthorized.StatusCode): http.StatusUnauthorized},
+		Fields: []FieldRule{{
+			Match:    "share_type",
+			Rename:   map[string]string{"name": "label"},
+			Defaults: map[string]interface{}{"note": "", "hide_download": 0},
+		}},
+	},
+}
+
+// DialectConfig selects the dialect of the responses to a request.
+type DialectConfig struct {
+	Default     string              `mapstructure:"default" docs:"owncloud;The dialect used when neither a route nor the user agent match, owncloud or nextcloud."`
+	Routes      map[string]string   `mapstructure:"routes" docs:";Maps route prefixes, e.g. /apps/files_sharing, to the dialect of their responses. Routes take precedence over user agents."`
+	UserAgents  map[string]string   `mapstructure:"user_agents" docs:";Maps substrings of the user agent, e.g. Nextcloud, to the dialect of the clients."`
+	Definitions map[string]*Dialect `mapstructure:"definitions" docs:";Defines additional dialects or overrides the built-in ones."`
+}
+
+// Dialects selects the dialect of the responses to a request.
+type Dialects struct {
+	conf       *DialectConfig
+	dialects   map[string]*Dialect
+	routes     []string
+	userAgents []string
+}
+
+// NewDialects returns the dialect selector for the given configuration.
+func NewDialects(c *DialectConfig) (*Dialects, error) {
+	if c.Default == "" {
+		c.Default = DialectOwnCloud
+	}
+	d := &Dialects{
+		conf:       c,
+		dialects:   make(map[string]*Dialect, len(builtinDialects)+len(c.Definitions)),
+		routes:     longestFirst(c.Routes),
+		userAgents: longestFirst(c.UserAgents),
+	}
+	for n, v := range builtinDialects {
+		d.dialects[n] = v
+	}
+	for n, v := range c.Definitions {
+		if v == nil {
+			v = &Dialect{}
+		}
+		d.dialects[n] = v
+	}
+
+	names := []string{c.Default}
+	for _, n := range c.Routes {
+		names = append(names, n)
+	}
+	for _, n := range c.UserAgents {
+		names = append(names, n)
+	}
+	for _, n := range names {
+		if _, ok := d.dialects[n]; !ok {
+			return nil, fmt.Errorf("ocs: unknown dialect %s", n)
+		}
+	}
+	return d, nil
+}
+
+// longestFirst returns the keys of a map, longest first, so that the most
+// specific match wins.
+func longestFirst(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// Select returns the name of the dialect of the response to a request. The
+// route is matched relative to the API version, e.g. /cloud/capabilities.
+func (d *Dialects) Select(r *http.Request) string {
+	route := r.URL.Path
+	if i := strings.Index(route, ".php/"); i >= 0 {
+		route = route[i+len(".php"):]
+	}
+	for _, p := range d.routes {
+		if strings.HasPrefix(route, p) {
+			return d.conf.Routes[p]
+		}
+	}
+	if ua := r.UserAgent(); ua != "" {
+		for _, s := range d.userAgents {
+			if strings.Contains(ua, s) {
+				return d.conf.UserAgents[s]
+			}
+		}
+	}
+	return d.conf.Default
+}
+
+// Handler stores the dialect selected for a request in its context.
+func (d *Dialects) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(d.userAgents) > 0 {
+			w.Header().Add("Vary", "User-Agent")
+		}
+		name := d.Select(r)
+		r = r.WithContext(context.WithValue(r.Context(), dialectKey, d.dialects[name]))
+		// cached responses must not be served to clients of another dialect
+		next.ServeHTTP(w, httpcache.WithVariant(r, name))
+	})
+}
+
+func dialectOf(ctx context.Context) *Dialect {
+	d, _ := ctx.Value(dialectKey).(*Dialect)
+	return d
+}
+
+// plain tells whether the dialect encodes responses like ownCloud.
+func (d *Dialect) plain() bool {
+	return d == nil || (!d.ListEmptyData && !d.FullMeta && len(d.Fields) == 0)
+}
+
+func (d *Dialect) statusCode(meta Meta) (int, bool) {
+	if d == nil {
+		return 0, false
+	}
+	c, ok := d.StatusCodes[strconv.Itoa(meta.StatusCode)]
+	return c, ok
+}
+
+// fullMeta is the meta of the dialects always sending all of its fields.
+type fullMeta struct {
+	Status       string        `json:"status" xml:"status"`
+	StatusCode   int           `json:"statuscode" xml:"statuscode"`
+	Message      string        `json:"message" xml:"message"`
+	TotalItems   string        `json:"totalitems" xml:"totalitems"`
+	ItemsPerPage string        `json:"itemsperpage" xml:"itemsperpage"`
+	Locale       *locale.Hints `json:"locale,omitempty" xml:"locale,omitempty"`
+}
+
+// dialectPayload holds the meta and the generic data of a response adjusted
+// to a dialect.
+type dialectPayload struct {
+	Meta interface{} `json:"meta"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+func (d *Dialect) encoder(jsonFormat bool) func(Response) ([]byte, error) {
+	return func(res Response) ([]byte, error) {
+		data, err := d.data(res.OCS.Data)
+		if err != nil {
+			return nil, err
+		}
+		p := dialectPayload{Meta: res.OCS.Meta, Data: data}
+		if d.FullMeta {
+			p.Meta = fullMeta(res.OCS.Meta)
+		}
+		if jsonFormat {
+			return json.Marshal(struct {
+				OCS dialectPayload `json:"ocs"`
+			}{p})
+		}
+		marshalled, err := xml.Marshal(p)
+		if err != nil {
+			return nil, err
+		}
+		b := new(bytes.Buffer)
+		b.Write([]byte(xml.Header))
+		b.Write(marshalled)
+		return b.Bytes(), nil
+	}
+}
+
+// data converts the data of a response into its generic json form and
+// applies the field rules to it.
+func (d *Dialect) data(v interface{}) (interface{}, error) {
+	var generic interface{}
+	if v != nil {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		if err := dec.Decode(&generic); err != nil {
+			return nil, err
+		}
+	}
+	if generic == nil {
+		if d.ListEmptyData {
+			return []interface{}{}, nil
+		}
+		return nil, nil
+	}
+	return d.adjust(generic), nil
+}
+
+func (d *Dialect) adjust(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = d.adjust(e)
+		}
+		for i := range d.Fields {
+			d.Fields[i].apply(t)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = d.adjust(e)
+		}
+	}
+	return v
+}
+
+func (f *FieldRule) apply(o map[string]interface{}) {
+	if f.Match != "" {
+		if _, ok := o[f.Match]; !ok {
+			return
+		}
+	}
+	// collect the renamed fields first so that fields can be swapped
+	moved := make(map[string]interface{}, len(f.Rename))
+	for from, to := range f.Rename {
+		if e, ok := o[from]; ok {
+			moved[to] = e
+			delete(o, from)
+		}
+	}
+	for k, e := range moved {
+		o[k] = e
+	}
+	for k, e := range f.Defaults {
+		if _, ok := o[k]; !ok {
+			o[k] = e
+		}
+	}
+}
+
+// MarshalXML encodes the generic data like the ocs payload: objects as
+// elements named after their fields, list members in 'element' tags and
+// booleans as 1 or 0.
+func (p dialectPayload) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = ocsName
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := e.EncodeElement(p.Meta, metaStartElement); err != nil {
+		return err
+	}
+	if p.Data != nil {
+		if err := encodeGeneric(e, dataName, p.Data); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(xml.EndElement{Name: start.Name})
+}
+
+func encodeGeneric(e *xml.Encoder, name xml.Name, v interface{}) error {
+	start := xml.StartElement{Name: name}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := encodeGeneric(e, xml.Name{Local: k}, t[k]); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case []interface{}:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, m := range t {
+			if err := encodeGeneric(e, elementStartElement.Name, m); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case nil:
+		return e.EncodeElement("", start)
+	case bool:
+		if t {
+			return e.EncodeElement("1", start)
+		}
+		return e.EncodeElement("0", start)
+	default:
+		return e.EncodeElement(t, start)
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type share struct {
+	ID        string `json:"id" xml:"id"`
+	ShareType int    `json:"share_type" xml:"share_type"`
+	Name      string `json:"name" xml:"name"`
+}
+
+type write func(w http.ResponseWriter, r *http.Request)
+
+var (
+	writeShares = func(w http.ResponseWriter, r *http.Request) {
+		WriteOCSSuccess(w, r, []*share{{ID: "1", ShareType: 3, Name: "team link"}})
+	}
+	writeEmpty = func(w http.ResponseWriter, r *http.Request) {
+		WriteOCSSuccess(w, r, nil)
+	}
+	writeUnauthorized = func(w http.ResponseWriter, r *http.Request) {
+		WriteOCSError(w, r, MetaUnauthorized.StatusCode, "no auth", nil)
+	}
+)
+
+func serve(t *testing.T, d *Dialects, version, format, userAgent string, fn write) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/v"+version+".php/apps/files_sharing/api/v1/shares?format="+format, nil)
+	r.Header.Set("User-Agent", userAgent)
+	r = r.WithContext(context.WithValue(r.Context(), apiVersionKey, version))
+	w := httptest.NewRecorder()
+	d.Handler(http.HandlerFunc(fn)).ServeHTTP(w, r)
+	return w
+}
+
+// TestDialectMatrix checks the responses of both dialects against what the
+// ownCloud and Nextcloud clients expect.
+func TestDialectMatrix(t *testing.T) {
+	d, err := NewDialects(&DialectConfig{UserAgents: map[string]string{"Nextcloud": DialectNextcloud}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	agents := map[string]string{
+		DialectOwnCloud:  "Mozilla/5.0 (Linux) mirall/2.10.0 (ownCloud, linux)",
+		DialectNextcloud: "Mozilla/5.0 (Linux) mirall/3.4.0 (Nextcloud, linux)",
+	}
+
+	tests := []struct {
+		dialect string
+		version string
+		fn      write
+		status  int
+		// fields expected in the json data, or absent when prefixed with !
+		data      []string
+		meta      []string
+		xml       []string
+		hasData   bool
+		emptyData bool
+	}{
+		{DialectOwnCloud, "1", writeShares, http.StatusOK, []string{"name", "!label", "!note"}, []string{"!totalitems"}, []string{"<name>team link</name>", "!<label>", "!<totalitems>"}, true, false},
+		{DialectOwnCloud, "2", writeShares, http.StatusOK, []string{"name", "!label"}, []string{"!itemsperpage"}, []string{"<statuscode>200</statuscode>", "<name>team link</name>"}, true, false},
+		{DialectOwnCloud, "1", writeEmpty, http.StatusOK, nil, []string{"!totalitems"}, []string{"!<data>"}, false, false},
+		{DialectOwnCloud, "1", writeUnauthorized, http.StatusOK, nil, nil, []string{"<statuscode>997</statuscode>"}, false, false},
+		{DialectOwnCloud, "2", writeUnauthorized, http.StatusUnauthorized, nil, nil, []string{"<statuscode>997</statuscode>"}, false, false},
+		{DialectNextcloud, "1", writeShares, http.StatusOK, []string{"label", "note", "hide_download", "!name"}, []string{"totalitems", "itemsperpage"}, []string{"<element>", "<label>team link</label>", "<note></note>", "<share_type>3</share_type>", "<totalitems></totalitems>", "!<name>"}, true, false},
+		{DialectNextcloud, "2", writeShares, http.StatusOK, []string{"label", "!name"}, []string{"totalitems"}, []string{"<statuscode>200</statuscode>", "<label>team link</label>"}, true, false},
+		{DialectNextcloud, "2", writeEmpty, http.StatusOK, nil, []string{"totalitems", "itemsperpage"}, []string{"<itemsperpage></itemsperpage>"}, true, true},
+		{DialectNextcloud, "1", writeUnauthorized, http.StatusUnauthorized, nil, []string{"totalitems"}, []string{"<statuscode>997</statuscode>"}, true, true},
+		{DialectNextcloud, "2", writeUnauthorized, http.StatusUnauthorized, nil, nil, []string{"<statuscode>997</statuscode>"}, true, true},
+	}
+
+	for i, tt := range tests {
+		w := serve(t, d, tt.version, "json", agents[tt.dialect], tt.fn)
+		if w.Code != tt.status {
+			t.Errorf("%d %s v%s json: expected status %d, got %d", i, tt.dialect, tt.version, tt.status, w.Code)
+		}
+		var res struct {
+			OCS map[string]json.RawMessage `json:"ocs"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatalf("%d %s v%s: invalid json %s: %v", i, tt.dialect, tt.version, w.Body.String(), err)
+		}
+		data, hasData := res.OCS["data"]
+		if hasData != tt.hasData {
+			t.Errorf("%d %s v%s json: expected data presence %t in %s", i, tt.dialect, tt.version, tt.hasData, w.Body.String())
+		}
+		if tt.emptyData && string(data) != "[]" {
+			t.Errorf("%d %s v%s json: expected empty list data, got %s", i, tt.dialect, tt.version, data)
+		}
+		if len(tt.data) > 0 {
+			var shares []map[string]interface{}
+			if err := json.Unmarshal(data, &shares); err != nil || len(shares) != 1 {
+				t.Fatalf("%d %s v%s: unexpected data %s: %v", i, tt.dialect, tt.version, data, err)
+			}
+			checkFields(t, i, "data", shares[0], tt.data)
+		}
+		var meta map[string]interface{}
+		if err := json.Unmarshal(res.OCS["meta"], &meta); err != nil {
+			t.Fatal(err)
+		}
+		checkFields(t, i, "meta", meta, tt.meta)
+
+		w = serve(t, d, tt.version, "xml", agents[tt.dialect], tt.fn)
+		if w.Code != tt.status {
+			t.Errorf("%d %s v%s xml: expected status %d, got %d", i, tt.dialect, tt.version, tt.status, w.Code)
+		}
+		body := w.Body.String()
+		if !strings.HasPrefix(body, "<?xml") || !strings.Contains(body, "<ocs>") {
+			t.Errorf("%d %s v%s xml: unexpected envelope %s", i, tt.dialect, tt.version, body)
+		}
+		if tt.emptyData && !strings.Contains(body, "<data></data>") {
+			t.Errorf("%d %s v%s xml: expected an empty data element in %s", i, tt.dialect, tt.version, body)
+		}
+		for _, s := range tt.xml {
+			if strings.HasPrefix(s, "!") {
+				if strings.Contains(body, s[1:]) {
+					t.Errorf("%d %s v%s xml: unexpected %s in %s", i, tt.dialect, tt.version, s[1:], body)
+				}
+			} else if !strings.Contains(body, s) {
+				t.Errorf("%d %s v%s xml: expected %s in %s", i, tt.dialect, tt.version, s, body)
+			}
+		}
+	}
+}
+
+func checkFields(t *testing.T, i int, what string, o map[string]interface{}, fields []string) {
+	t.Helper()
+	for _, f := range fields {
+		if strings.HasPrefix(f, "!") {
+			if _, ok := o[f[1:]]; ok {
+				t.Errorf("%d: unexpected %s field %s in %v", i, what, f[1:], o)
+			}
+		} else if _, ok := o[f]; !ok {
+			t.Errorf("%d: expected %s field %s in %v", i, what, f, o)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	d, err := NewDialects(&DialectConfig{
+		Routes:     map[string]string{"/apps/files_sharing": DialectOwnCloud, "/cloud": DialectNextcloud},
+		UserAgents: map[string]string{"Nextcloud": DialectNextcloud},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path, userAgent, want string
+	}{
+		{"/v1.php/config", "", DialectOwnCloud},
+		{"/v1.php/config", "Nextcloud-android/3.18.0", DialectNextcloud},
+		{"/v2.php/cloud/capabilities", "", DialectNextcloud},
+		{"/v2.php/apps/files_sharing/api/v1/shares", "Nextcloud-android/3.18.0", DialectOwnCloud},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		r.Header.Set("User-Agent", tt.userAgent)
+		if got := d.Select(r); got != tt.want {
+			t.Errorf("Select(%s, %s) = %s, want %s", tt.path, tt.userAgent, got, tt.want)
+		}
+	}
+
+	if _, err := NewDialects(&DialectConfig{Default: "sabre"}); err == nil {
+		t.Error("expected an unknown default dialect to be refused")
+	}
+	custom, err := NewDialects(&DialectConfig{
+		Default:     "legacy",
+		Definitions: map[string]*Dialect{"legacy": {FullMeta: true}},
+	})
+	if err != nil || custom.dialects["legacy"] == nil || !custom.dialects["legacy"].FullMeta {
+		t.Errorf("expected the custom dialect to be defined: %v", err)
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"reflect"
 
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/locale"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -34,6 +35,8 @@ type key int
 
 const (
 	apiVersionKey key = 1
+	dialectKey    key = 2
+	localeKey     key = 3
 )
 
 var (
@@ -107,6 +110,8 @@ type Meta struct {
 	Message      string `json:"message" xml:"message"`
 	TotalItems   string `json:"totalitems,omitempty" xml:"totalitems,omitempty"`
 	ItemsPerPage string `json:"itemsperpage,omitempty" xml:"itemsperpage,omitempty"`
+	// Locale hints how the sizes and dates of the data are rendered for the user.
+	Locale *locale.Hints `json:"locale,omitempty" xml:"locale,omitempty"`
 }
 
 // MetaOK is the default ok response
@@ -154,20 +159,31 @@ func WriteOCSResponse(w http.ResponseWriter, r *http.Request, res Response, err
 	}
 
 	version := APIVersion(r.Context())
+	dialect := dialectOf(r.Context())
+	if hints, ok := r.Context().Value(localeKey).(func() *locale.Hints); ok && res.OCS.Meta.Locale == nil {
+		res.OCS.Meta.Locale = hints()
+	}
 	m := statusCodeMapper(version)
 	statusCode := m(res.OCS.Meta)
+	if c, ok := dialect.statusCode(res.OCS.Meta); ok {
+		statusCode = c
+	}
 	if version == "2" && statusCode == http.StatusOK {
 		res.OCS.Meta.StatusCode = statusCode
 	}
 
 	var encoder func(Response) ([]byte, error)
-	if r.URL.Query().Get("format") == "json" {
+	jsonFormat := r.URL.Query().Get("format") == "json"
+	if jsonFormat {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		encoder = encodeJSON
 	} else {
 		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
 		encoder = encodeXML
 	}
+	if !dialect.plain() {
+		encoder = dialect.encoder(jsonFormat)
+	}
 	w.WriteHeader(statusCode)
 	encoded, err := encoder(res)
 	if err != nil {
@@ -258,6 +274,18 @@ func APIVersion(ctx context.Context) string {
 	return ""
 }
 
+// ContextWithLocale stores the formatting hints added to the meta of the responses.
+func ContextWithLocale(ctx context.Context, h *locale.Hints) context.Context {
+	return ContextWithLazyLocale(ctx, func() *locale.Hints { return h })
+}
+
+// ContextWithLazyLocale stores a function returning the formatting hints
+// added to the meta of the responses, which is only called when a response
+// is written.
+func ContextWithLazyLocale(ctx context.Context, hints func() *locale.Hints) context.Context {
+	return context.WithValue(ctx, localeKey, hints)
+}
+
 func statusCodeMapper(version string) func(Meta) int {
 	var mapper func(Meta) int
 	switch version {
@@ -0,0 +1,49 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/locale"
+)
+
+func TestLazyLocale(t *testing.T) {
+	calls := 0
+	r := httptest.NewRequest(http.MethodGet, "/v1.php/cloud/user?format=json", nil)
+	r = r.WithContext(ContextWithLazyLocale(r.Context(), func() *locale.Hints {
+		calls++
+		return &locale.Hints{Locale: "de-DE", DecimalSeparator: ","}
+	}))
+	if calls != 0 {
+		t.Fatal("expected the hints not to be computed before a response is written")
+	}
+
+	w := httptest.NewRecorder()
+	WriteOCSSuccess(w, r, nil)
+	if calls != 1 {
+		t.Errorf("expected the hints to be computed once, got %d calls", calls)
+	}
+	if !strings.Contains(w.Body.String(), `"locale":"de-DE"`) {
+		t.Errorf("expected the hints in the meta, got %s", w.Body.String())
+	}
+}
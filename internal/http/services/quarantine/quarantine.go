@@ -0,0 +1,275 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package quarantine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/antivirus"
+	_ "github.com/cs3org/reva/pkg/antivirus/manager/loader" // Load the scan result managers
+	"github.com/cs3org/reva/pkg/antivirus/manager/registry"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/utils/resourceid"
+	"github.com/go-chi/chi/v5"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register("quarantine", New)
+}
+
+// Config holds the config options for the quarantine HTTP service.
+type Config struct {
+	Prefix     string `mapstructure:"prefix"`
+	GatewaySvc string `mapstructure:"gatewaysvc"`
+	// Admins are the usernames of the users allowed to manage the quarantine.
+	Admins  []string                          `mapstructure:"admins"`
+	Driver  string                            `mapstructure:"driver"`
+	Drivers map[string]map[string]interface{} `mapstructure:"drivers"`
+}
+
+func (c *Config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "quarantine"
+	}
+	if c.Driver == "" {
+		c.Driver = "json"
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+type svc struct {
+	conf    *Config
+	router  *chi.Mux
+	results antivirus.Manager
+	admins  map[string]struct{}
+}
+
+// New returns a new service allowing admins to list the infected files and
+// to release them from quarantine or to delete them. The quarantine is
+// enforced by the storage providers configured with the same manager.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &Config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, err
+	}
+	conf.init()
+
+	f, ok := registry.NewFuncs[conf.Driver]
+	if !ok {
+		return nil, fmt.Errorf("quarantine: driver not found: %s", conf.Driver)
+	}
+	results, err := f(conf.Drivers[conf.Driver])
+	if err != nil {
+		return nil, errors.Wrap(err, "quarantine: error creating the scan result manager")
+	}
+
+	admins := make(map[string]struct{}, len(conf.Admins))
+	for _, a := range conf.Admins {
+		admins[a] = struct{}{}
+	}
+
+	s := &svc{
+		conf:    conf,
+		router:  chi.NewRouter(),
+		results: results,
+		admins:  admins,
+	}
+	s.routerInit()
+
+	return s, nil
+}
+
+func (s *svc) routerInit() {
+	s.router.Use(s.adminsOnly)
+	s.router.Get("/items", s.handleList)
+	s.router.Get("/items/{id}", s.handleGet)
+	s.router.Post("/items/{id}/release", s.handleRelease)
+	s.router.Delete("/items/{id}", s.handleDelete)
+}
+
+// Close performs cleanup.
+func (s *svc) Close() error {
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+func (s *svc) Unprotected() []string {
+	return []string{}
+}
+
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.router.ServeHTTP(w, r)
+	})
+}
+
+func (s *svc) adminsOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u := ctxpkg.ContextMustGetUser(r.Context())
+		if _, ok := s.admins[u.Username]; !ok {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleList lists the quarantined files, or the results with the status
+// given in the query, e.g. to follow the pending scans.
+func (s *svc) handleList(w http.ResponseWriter, r *http.Request) {
+	status := antivirus.Status(r.URL.Query().Get("status"))
+	switch status {
+	case "":
+		status = antivirus.StatusInfected
+	case antivirus.StatusPending, antivirus.StatusClean, antivirus.StatusInfected:
+	default:
+		http.Error(w, "invalid status", http.StatusBadRequest)
+		return
+	}
+
+	results, err := s.results.ListResults(r.Context(), status)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, results)
+}
+
+func (s *svc) handleGet(w http.ResponseWriter, r *http.Request) {
+	result, ok := s.getResult(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, r, result)
+}
+
+// handleRelease marks a quarantined file as clean, which makes it downloadable again.
+func (s *svc) handleRelease(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	admin := ctxpkg.ContextMustGetUser(ctx)
+
+	result, ok := s.getResult(w, r)
+	if !ok {
+		return
+	}
+	if result.Status != antivirus.StatusInfected {
+		http.Error(w, "file is not quarantined", http.StatusConflict)
+		return
+	}
+
+	result.Release(admin.Id)
+	if err := s.results.SetResult(ctx, result); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	appctx.GetLogger(ctx).Info().Str("id", result.ID()).Str("path", result.Path).Str("virus", result.Virus).
+		Str("admin", admin.Username).Msg("quarantine: file released")
+	writeJSON(w, r, result)
+}
+
+// handleDelete deletes a quarantined file from its storage and forgets its scan result.
+func (s *svc) handleDelete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	admin := ctxpkg.ContextMustGetUser(ctx)
+
+	result, ok := s.getResult(w, r)
+	if !ok {
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	res, err := client.Delete(ctx, &provider.DeleteRequest{Ref: &provider.Reference{ResourceId: result.ResourceID}})
+	switch {
+	case err != nil:
+		writeError(w, r, err)
+		return
+	case res.Status.Code == rpc.Code_CODE_NOT_FOUND:
+		// already gone, only the result is left
+	case res.Status.Code == rpc.Code_CODE_PERMISSION_DENIED:
+		w.WriteHeader(http.StatusForbidden)
+		return
+	case res.Status.Code != rpc.Code_CODE_OK:
+		writeError(w, r, errtypes.InternalError(res.Status.Message))
+		return
+	}
+
+	if err := s.results.DeleteResult(ctx, result.ResourceID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	appctx.GetLogger(ctx).Info().Str("id", result.ID()).Str("path", result.Path).Str("virus", result.Virus).
+		Str("admin", admin.Username).Msg("quarantine: file deleted")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *svc) getResult(w http.ResponseWriter, r *http.Request) (*antivirus.Result, bool) {
+	id := resourceid.OwnCloudResourceIDUnwrap(chi.URLParam(r, "id"))
+	if id == nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return nil, false
+	}
+	result, err := s.results.GetResult(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	return result, true
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(js); err != nil {
+		appctx.GetLogger(r.Context()).Error().Err(err).Msg("quarantine: error writing response")
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if _, ok := err.(errtypes.IsNotFound); ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	appctx.GetLogger(r.Context()).Error().Err(err).Msg("quarantine: error handling request")
+	w.WriteHeader(http.StatusInternalServerError)
+}
@@ -0,0 +1,163 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package revocation
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	"github.com/cs3org/reva/pkg/token/revocation/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register(serviceName, New)
+}
+
+const (
+	serviceName = "revocation"
+
+	// secretHeader is the header carrying the secret which authorizes the admin requests.
+	secretHeader = "X-Admin-Secret"
+)
+
+type config struct {
+	Prefix           string                            `mapstructure:"prefix"`
+	AdminSecret      string                            `mapstructure:"admin_secret" docs:";The secret admins have to provide in the X-Admin-Secret header."`
+	RevocationStore  string                            `mapstructure:"revocation_store"`
+	RevocationStores map[string]map[string]interface{} `mapstructure:"revocation_stores"`
+	TokenLifetime    int                               `mapstructure:"token_lifetime" docs:"86400;The maximum lifetime of the tokens in seconds, for which revocations are kept."`
+}
+
+func (c *config) init() {
+	if c.Prefix == "" {
+		c.Prefix = serviceName
+	}
+	if c.RevocationStore == "" {
+		c.RevocationStore = "sql"
+	}
+	if c.TokenLifetime == 0 {
+		c.TokenLifetime = 86400
+	}
+}
+
+type svc struct {
+	conf  *config
+	store revocation.Store
+}
+
+// New returns a new service allowing admins to revoke single tokens or all the tokens of a user.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, errors.Wrap(err, "revocation: error decoding configuration")
+	}
+	conf.init()
+
+	if conf.AdminSecret == "" {
+		return nil, errors.New("revocation: no admin secret configured")
+	}
+
+	f, ok := registry.NewFuncs[conf.RevocationStore]
+	if !ok {
+		return nil, fmt.Errorf("revocation: store not found: %s", conf.RevocationStore)
+	}
+	store, err := f(conf.RevocationStores[conf.RevocationStore])
+	if err != nil {
+		return nil, errors.Wrap(err, "revocation: error creating store")
+	}
+
+	return &svc{conf: conf, store: store}, nil
+}
+
+// Close is called when this service is being stopped.
+func (s *svc) Close() error {
+	return nil
+}
+
+// Prefix returns the main endpoint of this service.
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+// Unprotected returns all endpoints that can be queried without prior authorization.
+// The requests are authorized by the service itself using the admin secret.
+func (s *svc) Unprotected() []string {
+	return []string{"/"}
+}
+
+// Handler serves all HTTP requests.
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log := appctx.GetLogger(r.Context())
+
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(secretHeader)), []byte(s.conf.AdminSecret)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var head string
+		head, r.URL.Path = router.ShiftPath(r.URL.Path)
+
+		ttl := time.Duration(s.conf.TokenLifetime) * time.Second
+		var err error
+		switch head {
+		case "token":
+			tkn := r.FormValue("token")
+			if tkn == "" {
+				http.Error(w, "missing token", http.StatusBadRequest)
+				return
+			}
+			err = s.store.RevokeToken(r.Context(), revocation.TokenID(tkn), ttl)
+		case "user":
+			userID := &userpb.UserId{Idp: r.FormValue("idp"), OpaqueId: r.FormValue("opaque_id")}
+			if userID.OpaqueId == "" {
+				http.Error(w, "missing opaque_id", http.StatusBadRequest)
+				return
+			}
+			err = s.store.RevokeUser(r.Context(), userID, time.Now(), ttl)
+			if err == nil {
+				log.Info().Str("idp", userID.Idp).Str("opaque_id", userID.OpaqueId).Msg("revocation: revoked all the tokens of the user")
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			log.Error().Err(err).Msg("revocation: error storing revocation")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
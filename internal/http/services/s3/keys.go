@@ -0,0 +1,111 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package s3
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/s3"
+)
+
+// keyInfo is a key as listed to its owner, without its secret
+type keyInfo struct {
+	AccessKey   string    `json:"access_key"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// handleKeys lets the users authenticated with reva manage their access keys:
+// GET lists them, POST creates a new one and DELETE /<access key> deletes one.
+// The secret of a key is only returned when it is created.
+func (s *svc) handleKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+	user, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	accessKey := strings.Trim(r.URL.Path, "/")
+
+	switch {
+	case r.Method == http.MethodGet && accessKey == "":
+		keys, err := s.keys.ListKeys(ctx, user.Id)
+		if err != nil {
+			log.Error().Err(err).Msg("s3: error listing the access keys")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		infos := make([]*keyInfo, 0, len(keys))
+		for _, k := range keys {
+			infos = append(infos, &keyInfo{AccessKey: k.AccessKey, Description: k.Description, CreatedAt: k.CreatedAt})
+		}
+		writeJSON(w, r, http.StatusOK, infos)
+
+	case r.Method == http.MethodPost && accessKey == "":
+		k, err := s3.NewKey(user.Id, r.FormValue("description"))
+		if err == nil {
+			err = s.keys.AddKey(ctx, k)
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("s3: error creating an access key")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		log.Info().Str("access_key", k.AccessKey).Str("user", user.Username).Msg("s3: access key created")
+		writeJSON(w, r, http.StatusCreated, k)
+
+	case r.Method == http.MethodDelete && accessKey != "":
+		if err := s.keys.DeleteKey(ctx, user.Id, accessKey); err != nil {
+			if _, ok := err.(errtypes.IsNotFound); ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			log.Error().Err(err).Msg("s3: error deleting an access key")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		// cached sessions are bound to the user, not to the key, and need not be dropped
+		log.Info().Str("access_key", accessKey).Str("user", user.Username).Msg("s3: access key deleted")
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		appctx.GetLogger(r.Context()).Error().Err(err).Msg("s3: error encoding the response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(js); err != nil {
+		appctx.GetLogger(r.Context()).Error().Err(err).Msg("s3: error writing response")
+	}
+}
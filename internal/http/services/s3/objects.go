@@ -0,0 +1,572 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package s3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/s3"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// The bucket of a user is named after their username and contains the files of their home,
+// the keys of the objects are the paths relative to the home.
+
+const (
+	timeFormat = "2006-01-02T15:04:05.000Z"
+	// maxKeys is the maximum number of keys returned by a listing
+	maxKeys = 1000
+)
+
+var (
+	errMethodNotAllowed = &s3.Error{Code: "MethodNotAllowed", Message: "the method is not allowed against this resource", Status: http.StatusMethodNotAllowed}
+	errNoSuchBucket     = &s3.Error{Code: "NoSuchBucket", Message: "the bucket does not exist", Status: http.StatusNotFound}
+	errNoSuchKey        = &s3.Error{Code: "NoSuchKey", Message: "the key does not exist", Status: http.StatusNotFound}
+)
+
+func errNotImplemented(msg string) error {
+	return &s3.Error{Code: "NotImplemented", Message: msg, Status: http.StatusNotImplemented}
+}
+
+func errInvalidArgument(msg string) error {
+	return &s3.Error{Code: "InvalidArgument", Message: msg, Status: http.StatusBadRequest}
+}
+
+// objectPath returns the bucket and key of a request, relative to the prefix of the service.
+func (s *svc) objectPath(r *http.Request) (string, string, error) {
+	u, err := url.ParseRequestURI(r.RequestURI)
+	if err != nil {
+		return "", "", &s3.Error{Code: "InvalidURI", Message: err.Error(), Status: http.StatusBadRequest}
+	}
+	p := strings.TrimPrefix(u.Path, "/"+strings.Trim(s.conf.Prefix, "/"))
+	parts := strings.SplitN(strings.TrimPrefix(p, "/"), "/", 2)
+	if len(parts) == 1 {
+		return parts[0], "", nil
+	}
+	return parts[0], parts[1], nil
+}
+
+func (s *svc) handleObjects(w http.ResponseWriter, r *http.Request, user *userpb.User) {
+	ctx := r.Context()
+	bucket, key, err := s.objectPath(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	homeRes, err := client.GetHome(ctx, &provider.GetHomeRequest{})
+	switch {
+	case err != nil:
+		writeError(w, r, err)
+		return
+	case homeRes.Status.Code != rpc.Code_CODE_OK:
+		writeError(w, r, statusError(homeRes.Status, "NoSuchBucket"))
+		return
+	}
+	home := homeRes.Path
+
+	if bucket == "" {
+		if r.Method != http.MethodGet {
+			writeError(w, r, errMethodNotAllowed)
+			return
+		}
+		s.listBuckets(w, r, client, user, home)
+		return
+	}
+	if bucket != user.Username {
+		writeError(w, r, errNoSuchBucket)
+		return
+	}
+
+	if key == "" {
+		switch r.Method {
+		case http.MethodGet:
+			if r.URL.Query().Get("list-type") != "2" {
+				writeError(w, r, errNotImplemented("only ListObjectsV2 is supported for listing objects"))
+				return
+			}
+			s.listObjects(w, r, client, bucket, home)
+		case http.MethodHead:
+			w.WriteHeader(http.StatusOK)
+		default:
+			writeError(w, r, errMethodNotAllowed)
+		}
+		return
+	}
+
+	p := path.Join(home, key)
+	// keys are not cleaned, so that each key names a single object
+	if p != strings.TrimSuffix(home, "/")+"/"+strings.TrimSuffix(key, "/") {
+		writeError(w, r, errInvalidArgument("the key is not a valid path"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.getObject(w, r, client, p)
+	case http.MethodHead:
+		s.headObject(w, r, client, p)
+	case http.MethodPut:
+		s.putObject(w, r, client, home, p, strings.HasSuffix(key, "/"))
+	default:
+		writeError(w, r, errMethodNotAllowed)
+	}
+}
+
+func (s *svc) listBuckets(w http.ResponseWriter, r *http.Request, client gateway.GatewayAPIClient, user *userpb.User, home string) {
+	res, err := client.Stat(r.Context(), &provider.StatRequest{Ref: &provider.Reference{Path: home}})
+	switch {
+	case err != nil:
+		writeError(w, r, err)
+		return
+	case res.Status.Code != rpc.Code_CODE_OK:
+		writeError(w, r, statusError(res.Status, "NoSuchBucket"))
+		return
+	}
+	writeXML(w, r, http.StatusOK, &listAllMyBucketsResult{
+		Xmlns: xmlns,
+		Owner: owner{ID: user.Id.OpaqueId, DisplayName: user.DisplayName},
+		Buckets: []bucket{{
+			Name:         user.Username,
+			CreationDate: utils.TSToTime(res.Info.Mtime).UTC().Format(timeFormat),
+		}},
+	})
+}
+
+// entry is an object or, when listing with a delimiter, a common prefix
+type entry struct {
+	key  string
+	info *provider.ResourceInfo
+}
+
+func (s *svc) listObjects(w http.ResponseWriter, r *http.Request, client gateway.GatewayAPIClient, bucket, home string) {
+	q := r.URL.Query()
+	prefix, delimiter := q.Get("prefix"), q.Get("delimiter")
+	if delimiter != "" && delimiter != "/" {
+		writeError(w, r, errNotImplemented("only / is supported as delimiter"))
+		return
+	}
+
+	res := &listBucketResult{
+		Xmlns:             xmlns,
+		Name:              bucket,
+		Prefix:            prefix,
+		Delimiter:         delimiter,
+		StartAfter:        q.Get("start-after"),
+		ContinuationToken: q.Get("continuation-token"),
+		MaxKeys:           maxKeys,
+	}
+	if v := q.Get("max-keys"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, r, errInvalidArgument("invalid max-keys"))
+			return
+		}
+		if n < maxKeys {
+			res.MaxKeys = n
+		}
+	}
+	marker := res.StartAfter
+	if res.ContinuationToken != "" {
+		b, err := base64.RawURLEncoding.DecodeString(res.ContinuationToken)
+		if err != nil {
+			writeError(w, r, errInvalidArgument("invalid continuation token"))
+			return
+		}
+		marker = string(b)
+	}
+
+	entries, err := s.collect(r.Context(), client, home, prefix, delimiter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var last string
+	for _, e := range entries {
+		if e.key <= marker {
+			continue
+		}
+		if res.KeyCount == res.MaxKeys {
+			res.IsTruncated = true
+			res.NextContinuationToken = base64.RawURLEncoding.EncodeToString([]byte(last))
+			break
+		}
+		if e.info == nil {
+			res.CommonPrefixes = append(res.CommonPrefixes, commonPrefix{Prefix: e.key})
+		} else {
+			res.Contents = append(res.Contents, object{
+				Key:          e.key,
+				LastModified: utils.TSToTime(e.info.Mtime).UTC().Format(timeFormat),
+				ETag:         quoteETag(e.info.Etag),
+				Size:         e.info.Size,
+				StorageClass: "STANDARD",
+			})
+		}
+		res.KeyCount++
+		last = e.key
+	}
+	writeXML(w, r, http.StatusOK, res)
+}
+
+// collect returns the objects whose key starts with the prefix, sorted by key. With a delimiter
+// the folders directly below the prefix are returned as common prefixes instead of their objects.
+func (s *svc) collect(ctx context.Context, client gateway.GatewayAPIClient, home, prefix, delimiter string) ([]entry, error) {
+	var entries []entry
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		res, err := client.ListContainer(ctx, &provider.ListContainerRequest{Ref: &provider.Reference{Path: path.Join(home, dir)}})
+		switch {
+		case err != nil:
+			return err
+		case res.Status.Code == rpc.Code_CODE_NOT_FOUND:
+			// no object has the prefix
+			return nil
+		case res.Status.Code != rpc.Code_CODE_OK:
+			return statusError(res.Status, "NoSuchKey")
+		}
+
+		for _, info := range res.Infos {
+			key := path.Join(dir, path.Base(info.Path))
+			if info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+				if strings.HasPrefix(key, prefix) {
+					entries = append(entries, entry{key: key, info: info})
+				}
+				continue
+			}
+			key += "/"
+			switch {
+			case delimiter != "" && strings.HasPrefix(key, prefix):
+				entries = append(entries, entry{key: key})
+			case strings.HasPrefix(key, prefix) || strings.HasPrefix(prefix, key):
+				if err := walk(key); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	dir := ""
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir = prefix[:i]
+	}
+	if err := walk(dir); err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+	return entries, nil
+}
+
+func (s *svc) statObject(ctx context.Context, client gateway.GatewayAPIClient, p string) (*provider.ResourceInfo, error) {
+	res, err := client.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{Path: p}})
+	switch {
+	case err != nil:
+		return nil, err
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, statusError(res.Status, "NoSuchKey")
+	case res.Info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER:
+		return nil, errNoSuchKey
+	}
+	return res.Info, nil
+}
+
+func setObjectHeaders(w http.ResponseWriter, info *provider.ResourceInfo) {
+	w.Header().Set("Content-Type", info.MimeType)
+	w.Header().Set("ETag", quoteETag(info.Etag))
+	w.Header().Set("Last-Modified", utils.TSToTime(info.Mtime).UTC().Format(http.TimeFormat))
+	w.Header().Set("Accept-Ranges", "bytes")
+}
+
+func (s *svc) headObject(w http.ResponseWriter, r *http.Request, client gateway.GatewayAPIClient, p string) {
+	info, err := s.statObject(r.Context(), client, p)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	setObjectHeaders(w, info)
+	w.Header().Set("Content-Length", strconv.FormatUint(info.Size, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *svc) getObject(w http.ResponseWriter, r *http.Request, client gateway.GatewayAPIClient, p string) {
+	ctx := r.Context()
+	info, err := s.statObject(ctx, client, p)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	dRes, err := client.InitiateFileDownload(ctx, &provider.InitiateFileDownloadRequest{Ref: &provider.Reference{Path: p}})
+	switch {
+	case err != nil:
+		writeError(w, r, err)
+		return
+	case dRes.Status.Code != rpc.Code_CODE_OK:
+		writeError(w, r, statusError(dRes.Status, "NoSuchKey"))
+		return
+	}
+	var ep, token string
+	for _, proto := range dRes.Protocols {
+		if proto.Protocol == "simple" {
+			ep, token = proto.DownloadEndpoint, proto.Token
+		}
+	}
+
+	httpReq, err := rhttp.NewRequest(ctx, http.MethodGet, ep, nil)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	httpReq.Header.Set(datagateway.TokenTransportHeader, token)
+	if rng := r.Header.Get("Range"); rng != "" {
+		httpReq.Header.Set("Range", rng)
+	}
+	httpRes, err := s.client.Do(httpReq)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer httpRes.Body.Close()
+
+	setObjectHeaders(w, info)
+	switch httpRes.StatusCode {
+	case http.StatusOK:
+		w.Header().Set("Content-Length", strconv.FormatUint(info.Size, 10))
+		w.WriteHeader(http.StatusOK)
+	case http.StatusPartialContent:
+		w.Header().Set("Content-Range", httpRes.Header.Get("Content-Range"))
+		w.Header().Set("Content-Length", httpRes.Header.Get("Content-Length"))
+		w.WriteHeader(http.StatusPartialContent)
+	case http.StatusRequestedRangeNotSatisfiable:
+		writeError(w, r, &s3.Error{Code: "InvalidRange", Message: "the requested range is not satisfiable", Status: http.StatusRequestedRangeNotSatisfiable})
+		return
+	default:
+		writeError(w, r, errors.Errorf("unexpected status %d of the data gateway", httpRes.StatusCode))
+		return
+	}
+	if _, err := io.Copy(w, httpRes.Body); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("path", p).Msg("s3: error sending the object")
+	}
+}
+
+func (s *svc) putObject(w http.ResponseWriter, r *http.Request, client gateway.GatewayAPIClient, home, p string, folder bool) {
+	ctx := r.Context()
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if strings.HasPrefix(payloadHash, "STREAMING-") {
+		writeError(w, r, errNotImplemented("chunked uploads are not supported, please sign the payload or leave it unsigned"))
+		return
+	}
+	if r.ContentLength < 0 {
+		writeError(w, r, &s3.Error{Code: "MissingContentLength", Message: "the content length is required", Status: http.StatusLengthRequired})
+		return
+	}
+
+	// keys ending with a slash are folders, as created by many clients to show empty folders
+	if folder {
+		if r.ContentLength != 0 {
+			writeError(w, r, errInvalidArgument("a folder cannot have content"))
+			return
+		}
+		if err := s.ensureFolder(ctx, client, home, p); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// a signed payload is verified before anything is written, so that a
+	// mismatching content never replaces an existing object
+	var body io.Reader = r.Body
+	if payloadHash != "" && payloadHash != s3.UnsignedPayload {
+		spool, err := spoolPayload(s.conf.SpoolDir, r.Body, payloadHash)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		defer func() {
+			spool.Close()
+			os.Remove(spool.Name())
+		}()
+		body = spool
+	}
+
+	if err := s.ensureFolder(ctx, client, home, path.Dir(p)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	opaque := map[string]*typespb.OpaqueEntry{
+		"Upload-Length": {Decoder: "plain", Value: []byte(strconv.FormatInt(r.ContentLength, 10))},
+	}
+	if md5 := r.Header.Get("Content-MD5"); md5 != "" {
+		sum, err := base64.StdEncoding.DecodeString(md5)
+		if err != nil {
+			writeError(w, r, &s3.Error{Code: "InvalidDigest", Message: "the Content-MD5 is not valid", Status: http.StatusBadRequest})
+			return
+		}
+		// the storage verifies the checksum of the upload
+		opaque["Upload-Checksum"] = &typespb.OpaqueEntry{Decoder: "plain", Value: []byte("md5 " + hex.EncodeToString(sum))}
+	}
+
+	uRes, err := client.InitiateFileUpload(ctx, &provider.InitiateFileUploadRequest{
+		Ref:    &provider.Reference{Path: p},
+		Opaque: &typespb.Opaque{Map: opaque},
+	})
+	switch {
+	case err != nil:
+		writeError(w, r, err)
+		return
+	case uRes.Status.Code != rpc.Code_CODE_OK:
+		writeError(w, r, statusError(uRes.Status, "NoSuchKey"))
+		return
+	}
+	var ep, token string
+	for _, proto := range uRes.Protocols {
+		if proto.Protocol == "simple" {
+			ep, token = proto.UploadEndpoint, proto.Token
+		}
+	}
+
+	httpReq, err := rhttp.NewRequest(ctx, http.MethodPut, ep, body)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	httpReq.ContentLength = r.ContentLength
+	httpReq.Header.Set(datagateway.TokenTransportHeader, token)
+	httpRes, err := s.client.Do(httpReq)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer httpRes.Body.Close()
+	switch httpRes.StatusCode {
+	case http.StatusOK:
+	case errtypes.StatusChecksumMismatch:
+		writeError(w, r, &s3.Error{Code: "BadDigest", Message: "the Content-MD5 does not match the content", Status: http.StatusBadRequest})
+		return
+	default:
+		writeError(w, r, errors.Errorf("unexpected status %d of the data gateway", httpRes.StatusCode))
+		return
+	}
+
+	info, err := s.statObject(ctx, client, p)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", quoteETag(info.Etag))
+	w.WriteHeader(http.StatusOK)
+}
+
+// spoolPayload stores a signed payload in a temporary file of the given folder
+// and verifies its hash. The returned file is positioned at its start.
+func spoolPayload(dir string, body io.Reader, payloadHash string) (*os.File, error) {
+	f, err := ioutil.TempFile(dir, "s3-payload-")
+	if err != nil {
+		return nil, err
+	}
+	discard := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, hasher), body); err != nil {
+		discard()
+		return nil, err
+	}
+	if !strings.EqualFold(hex.EncodeToString(hasher.Sum(nil)), payloadHash) {
+		discard()
+		return nil, &s3.Error{Code: "XAmzContentSHA256Mismatch", Message: "the content does not match the signed payload hash", Status: http.StatusBadRequest}
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		discard()
+		return nil, err
+	}
+	return f, nil
+}
+
+// ensureFolder creates the folder and its parents below the home if they do not exist.
+func (s *svc) ensureFolder(ctx context.Context, client gateway.GatewayAPIClient, home, p string) error {
+	if p == home {
+		return nil
+	}
+	res, err := client.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{Path: p}})
+	switch {
+	case err != nil:
+		return err
+	case res.Status.Code == rpc.Code_CODE_OK:
+		if res.Info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			return errInvalidArgument("a parent of the key is an object")
+		}
+		return nil
+	case res.Status.Code != rpc.Code_CODE_NOT_FOUND:
+		return statusError(res.Status, "NoSuchKey")
+	}
+
+	if err := s.ensureFolder(ctx, client, home, path.Dir(p)); err != nil {
+		return err
+	}
+	cRes, err := client.CreateContainer(ctx, &provider.CreateContainerRequest{Ref: &provider.Reference{Path: p}})
+	switch {
+	case err != nil:
+		return err
+	case cRes.Status.Code != rpc.Code_CODE_OK && cRes.Status.Code != rpc.Code_CODE_ALREADY_EXISTS:
+		return statusError(cRes.Status, "NoSuchKey")
+	}
+	return nil
+}
+
+// quoteETag returns the etag in quotes, as expected by S3 clients.
+func quoteETag(etag string) string {
+	return `"` + strings.Trim(etag, `"`) + `"`
+}
@@ -0,0 +1,51 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package s3
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/s3"
+)
+
+func TestSpoolPayload(t *testing.T) {
+	dir := t.TempDir()
+	sum := sha256.Sum256([]byte("hello"))
+
+	f, err := spoolPayload(dir, strings.NewReader("hello"), strings.ToUpper(hex.EncodeToString(sum[:])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if data, err := ioutil.ReadAll(f); err != nil || string(data) != "hello" {
+		t.Fatalf("expected the payload to be readable from the start, got %q", data)
+	}
+
+	_, err = spoolPayload(dir, strings.NewReader("tampered"), hex.EncodeToString(sum[:]))
+	if e, ok := err.(*s3.Error); !ok || e.Code != "XAmzContentSHA256Mismatch" {
+		t.Fatalf("expected a hash mismatch, got %v", err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Fatalf("expected the mismatching payload to be discarded, found %d files", len(files))
+	}
+}
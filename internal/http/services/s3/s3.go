@@ -0,0 +1,224 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ReneKroon/ttlcache/v2"
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/s3"
+	"github.com/cs3org/reva/pkg/s3/manager/registry"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/metadata"
+
+	// load the key managers
+	_ "github.com/cs3org/reva/pkg/s3/manager/loader"
+)
+
+func init() {
+	global.Register("s3", New)
+}
+
+// Config holds the config options for the S3 service.
+type Config struct {
+	Prefix     string `mapstructure:"prefix" docs:"s3;The prefix S3 clients use as endpoint."`
+	GatewaySvc string `mapstructure:"gatewaysvc"`
+	// MachineAuthAPIKey is the key of the machine auth provider, used to act on behalf of the owners of the access keys.
+	MachineAuthAPIKey string                            `mapstructure:"machine_auth_apikey"`
+	Driver            string                            `mapstructure:"driver" docs:"json;The driver storing the access keys."`
+	Drivers           map[string]map[string]interface{} `mapstructure:"drivers"`
+	TokenCacheTTL     int                               `mapstructure:"token_cache_ttl" docs:"300;Seconds the token of a user is reused for the requests signed with their access keys."`
+	Timeout           int64                             `mapstructure:"timeout"`
+	Insecure          bool                              `mapstructure:"insecure" docs:"false;Whether to skip certificate checks when sending requests to the data gateway."`
+	SpoolDir          string                            `mapstructure:"spool_dir" docs:";The folder where signed payloads are verified before being uploaded; the temporary folder of the system if empty."`
+}
+
+func (c *Config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "s3"
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+	if c.Driver == "" {
+		c.Driver = "json"
+	}
+	if c.TokenCacheTTL == 0 {
+		c.TokenCacheTTL = 300
+	}
+}
+
+type svc struct {
+	conf   *Config
+	keys   s3.KeyManager
+	client *http.Client
+	// tokens caches the sessions of the users by user id
+	tokens *ttlcache.Cache
+}
+
+// session is the authentication of a user on behalf of their access key
+type session struct {
+	token string
+	user  *userpb.User
+}
+
+// New returns a service serving the homes of the users to S3 clients.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &Config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, err
+	}
+	conf.init()
+
+	if conf.MachineAuthAPIKey == "" {
+		return nil, errors.New("s3: no machine auth api key configured")
+	}
+
+	f, ok := registry.NewFuncs[conf.Driver]
+	if !ok {
+		return nil, errtypes.NotFound("s3: driver not found: " + conf.Driver)
+	}
+	keys, err := f(conf.Drivers[conf.Driver])
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := ttlcache.NewCache()
+	_ = tokens.SetTTL(time.Duration(conf.TokenCacheTTL) * time.Second)
+	tokens.SkipTTLExtensionOnHit(true)
+
+	return &svc{
+		conf: conf,
+		keys: keys,
+		client: rhttp.GetHTTPClient(
+			rhttp.Timeout(time.Duration(conf.Timeout*int64(time.Second))),
+			rhttp.Insecure(conf.Insecure),
+		),
+		tokens: tokens,
+	}, nil
+}
+
+func (s *svc) Close() error {
+	s.tokens.Close()
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+// Unprotected returns all paths, as S3 requests are authenticated by their signature.
+// The key management endpoints rely on the user authenticated by the middleware if any.
+func (s *svc) Unprotected() []string {
+	return []string{"/"}
+}
+
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if head, tail := router.ShiftPath(r.URL.Path); head == "_keys" {
+			r.URL.Path = tail
+			s.handleKeys(w, r)
+			return
+		}
+
+		ctx, user, err := s.authenticate(r)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		s.handleObjects(w, r.WithContext(ctx), user)
+	})
+}
+
+// authenticate verifies the signature of a request and returns a context authenticated as the owner of its access key.
+func (s *svc) authenticate(r *http.Request) (context.Context, *userpb.User, error) {
+	ctx := r.Context()
+	sig, err := s3.ParseSignature(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := s.keys.GetKey(ctx, sig.AccessKey)
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			return nil, nil, &s3.Error{Code: "InvalidAccessKeyId", Message: "the access key does not exist", Status: http.StatusForbidden}
+		}
+		return nil, nil, err
+	}
+
+	// the signature covers the path sent by the client, including the prefix of the service
+	u, err := url.ParseRequestURI(r.RequestURI)
+	if err != nil {
+		return nil, nil, &s3.Error{Code: "InvalidURI", Message: err.Error(), Status: http.StatusBadRequest}
+	}
+	if err := sig.Verify(r, u.Path, key.Secret, time.Now()); err != nil {
+		appctx.GetLogger(ctx).Debug().Err(err).Str("access_key", sig.AccessKey).Msg("s3: rejected signature")
+		return nil, nil, err
+	}
+
+	sess, err := s.session(key.User)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx = ctxpkg.ContextSetToken(ctx, sess.token)
+	ctx = ctxpkg.ContextSetUser(ctx, sess.user)
+	ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.TokenHeader, sess.token)
+	return ctx, sess.user, nil
+}
+
+// session returns the possibly cached authentication of the user.
+func (s *svc) session(id *userpb.UserId) (*session, error) {
+	if v, err := s.tokens.Get(id.OpaqueId); err == nil {
+		return v.(*session), nil
+	}
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Authenticate(context.Background(), &gateway.AuthenticateRequest{
+		Type:         "machine",
+		ClientId:     "userid:" + id.OpaqueId,
+		ClientSecret: s.conf.MachineAuthAPIKey,
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, fmt.Errorf("error authenticating as %s: %s", id.OpaqueId, res.Status.Message)
+	}
+
+	sess := &session{token: res.Token, user: res.User}
+	_ = s.tokens.Set(id.OpaqueId, sess)
+	return sess, nil
+}
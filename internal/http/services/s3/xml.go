@@ -0,0 +1,126 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package s3
+
+import (
+	"encoding/xml"
+	"net/http"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/s3"
+)
+
+const xmlns = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+type errorResponse struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string   `xml:"Code"`
+	Message  string   `xml:"Message"`
+	Resource string   `xml:"Resource"`
+}
+
+type bucket struct {
+	Name         string `xml:"Name"`
+	CreationDate string `xml:"CreationDate"`
+}
+
+type owner struct {
+	ID          string `xml:"ID"`
+	DisplayName string `xml:"DisplayName"`
+}
+
+type listAllMyBucketsResult struct {
+	XMLName xml.Name `xml:"ListAllMyBucketsResult"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Owner   owner    `xml:"Owner"`
+	Buckets []bucket `xml:"Buckets>Bucket"`
+}
+
+type object struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         uint64 `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type commonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+type listBucketResult struct {
+	XMLName               xml.Name       `xml:"ListBucketResult"`
+	Xmlns                 string         `xml:"xmlns,attr"`
+	Name                  string         `xml:"Name"`
+	Prefix                string         `xml:"Prefix"`
+	Delimiter             string         `xml:"Delimiter,omitempty"`
+	StartAfter            string         `xml:"StartAfter,omitempty"`
+	ContinuationToken     string         `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+	KeyCount              int            `xml:"KeyCount"`
+	MaxKeys               int            `xml:"MaxKeys"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	Contents              []object       `xml:"Contents"`
+	CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+}
+
+func writeXML(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	b, err := xml.Marshal(v)
+	if err != nil {
+		appctx.GetLogger(r.Context()).Error().Err(err).Msg("s3: error encoding the response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(b)
+}
+
+// writeError writes an error in the format of S3, errors other than s3.Error are internal errors.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	e, ok := err.(*s3.Error)
+	if !ok {
+		appctx.GetLogger(r.Context()).Error().Err(err).Msg("s3: internal error")
+		e = &s3.Error{Code: "InternalError", Message: "we encountered an internal error, please try again", Status: http.StatusInternalServerError}
+	}
+	if r.Method == http.MethodHead {
+		// responses to HEAD requests have no body
+		w.WriteHeader(e.Status)
+		return
+	}
+	writeXML(w, r, e.Status, &errorResponse{Code: e.Code, Message: e.Message, Resource: r.URL.Path})
+}
+
+// statusError translates the status of a failed CS3 call to an S3 error.
+func statusError(st *rpc.Status, notFound string) error {
+	switch st.Code {
+	case rpc.Code_CODE_NOT_FOUND:
+		return &s3.Error{Code: notFound, Message: st.Message, Status: http.StatusNotFound}
+	case rpc.Code_CODE_PERMISSION_DENIED:
+		return &s3.Error{Code: "AccessDenied", Message: st.Message, Status: http.StatusForbidden}
+	case rpc.Code_CODE_INSUFFICIENT_STORAGE:
+		return &s3.Error{Code: "EntityTooLarge", Message: st.Message, Status: http.StatusBadRequest}
+	case rpc.Code_CODE_INVALID_ARGUMENT:
+		return &s3.Error{Code: "InvalidArgument", Message: st.Message, Status: http.StatusBadRequest}
+	default:
+		return &s3.Error{Code: "InternalError", Message: st.Message, Status: http.StatusInternalServerError}
+	}
+}
@@ -21,6 +21,7 @@ package siteacc
 import (
 	"net/http"
 
+	"github.com/cs3org/reva/pkg/apispec"
 	"github.com/cs3org/reva/pkg/siteacc"
 	"github.com/cs3org/reva/pkg/siteacc/config"
 	"github.com/mitchellh/mapstructure"
@@ -32,6 +33,12 @@ import (
 
 func init() {
 	global.Register(serviceName, New)
+	apispec.Register(&apispec.Service{
+		Name:        serviceName,
+		Prefix:      serviceName,
+		Description: "Manages the accounts, operators and sites of the ScienceMesh.",
+		Operations:  siteacc.Operations(),
+	})
 }
 
 type svc struct {
@@ -47,6 +54,7 @@ const (
 
 // Close is called when this service is being stopped.
 func (s *svc) Close() error {
+	s.siteacc.Close()
 	return nil
 }
 
@@ -97,6 +105,14 @@ func applyDefaultConfig(conf *config.Configuration) {
 		conf.Mentix.SiteRegistrationEndpoint = "/sitereg"
 	}
 
+	if conf.Mentix.SiteUpdateEndpoint == "" {
+		conf.Mentix.SiteUpdateEndpoint = "/siteupdate"
+	}
+
+	if conf.Mentix.UsageEndpoint == "" {
+		conf.Mentix.UsageEndpoint = "/usage"
+	}
+
 	// Enforce a minimum session timeout of 1 minute (and default to 5 minutes)
 	if conf.Webserver.SessionTimeout < 60 {
 		conf.Webserver.SessionTimeout = 5 * 60
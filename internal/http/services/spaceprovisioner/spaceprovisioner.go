@@ -0,0 +1,246 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package spaceprovisioner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/spaceprovisioner"
+	"github.com/go-chi/chi/v5"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/metadata"
+)
+
+func init() {
+	global.Register(serviceName, New)
+}
+
+const serviceName = "spaceprovisioner"
+
+// Config holds the config options for the space provisioner service.
+type Config struct {
+	Prefix             string `mapstructure:"prefix"`
+	GatewaySvc         string `mapstructure:"gatewaysvc"`
+	StorageRegistrySvc string `mapstructure:"storage_registry_svc"`
+	// MachineAuthAPIKey is the key of the machine auth provider, used to act as the service user.
+	MachineAuthAPIKey string `mapstructure:"machine_auth_apikey"`
+	ServiceUser       string `mapstructure:"service_user" docs:";The user creating and managing the provisioned spaces."`
+	Interval          int    `mapstructure:"interval" docs:"300;The interval in seconds between two synchronizations of the groups."`
+	// Admins are the usernames of the users allowed to trigger a synchronization.
+	Admins      []string                `mapstructure:"admins"`
+	Provisioner spaceprovisioner.Config `mapstructure:"provisioner" docs:"url:pkg/spaceprovisioner/spaceprovisioner.go"`
+}
+
+func (c *Config) init() {
+	if c.Prefix == "" {
+		c.Prefix = serviceName
+	}
+	if c.Interval == 0 {
+		c.Interval = 300
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+	if c.StorageRegistrySvc == "" {
+		c.StorageRegistrySvc = c.GatewaySvc
+	}
+}
+
+type svc struct {
+	conf        *Config
+	log         *zerolog.Logger
+	router      *chi.Mux
+	provisioner *spaceprovisioner.Provisioner
+	admins      map[string]struct{}
+	done        chan struct{}
+}
+
+// New returns a new service periodically provisioning the project spaces of
+// the groups and exposing the provisioned spaces to admins.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &Config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, errors.Wrap(err, "spaceprovisioner: error decoding configuration")
+	}
+	conf.init()
+
+	if conf.MachineAuthAPIKey == "" {
+		return nil, errors.New("spaceprovisioner: no machine auth api key configured")
+	}
+	if conf.ServiceUser == "" {
+		return nil, errors.New("spaceprovisioner: no service user configured")
+	}
+	p, err := spaceprovisioner.New(&conf.Provisioner, conf.GatewaySvc, conf.StorageRegistrySvc)
+	if err != nil {
+		return nil, err
+	}
+
+	admins := make(map[string]struct{}, len(conf.Admins))
+	for _, a := range conf.Admins {
+		admins[a] = struct{}{}
+	}
+
+	s := &svc{
+		conf:        conf,
+		log:         log,
+		router:      chi.NewRouter(),
+		provisioner: p,
+		admins:      admins,
+		done:        make(chan struct{}),
+	}
+	s.router.Use(s.adminsOnly)
+	s.router.Get("/spaces", s.handleList)
+	s.router.Post("/sync", s.handleSync)
+
+	go s.loop()
+
+	return s, nil
+}
+
+// Close stops the periodic synchronization.
+func (s *svc) Close() error {
+	close(s.done)
+	return nil
+}
+
+// Prefix returns the main endpoint of this service.
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+// Unprotected returns all endpoints that can be queried without prior authorization.
+func (s *svc) Unprotected() []string {
+	return []string{}
+}
+
+// Handler serves all HTTP requests.
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.router.ServeHTTP(w, r)
+	})
+}
+
+func (s *svc) adminsOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u := ctxpkg.ContextMustGetUser(r.Context())
+		if _, ok := s.admins[u.Username]; !ok {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *svc) loop() {
+	ticker := time.NewTicker(time.Duration(s.conf.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		if _, err := s.sync(); err != nil {
+			s.log.Error().Err(err).Msg("spaceprovisioner: error synchronizing the groups")
+		}
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *svc) sync() (*spaceprovisioner.Report, error) {
+	ctx, err := s.serviceContext()
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.provisioner.Run(ctx)
+	if report != nil {
+		s.log.Info().Interface("report", report).Msg("spaceprovisioner: groups synchronized")
+	}
+	return report, err
+}
+
+// serviceContext returns a context authenticated as the service user.
+func (s *svc) serviceContext() (context.Context, error) {
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Authenticate(context.Background(), &gateway.AuthenticateRequest{
+		Type:         "machine",
+		ClientId:     s.conf.ServiceUser,
+		ClientSecret: s.conf.MachineAuthAPIKey,
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, errors.Errorf("error authenticating as %s: %s", s.conf.ServiceUser, res.Status.Message)
+	}
+
+	ctx := appctx.WithLogger(context.Background(), s.log)
+	ctx = ctxpkg.ContextSetToken(ctx, res.Token)
+	ctx = ctxpkg.ContextSetUser(ctx, res.User)
+	ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.TokenHeader, res.Token)
+	return ctx, nil
+}
+
+func (s *svc) handleList(w http.ResponseWriter, r *http.Request) {
+	spaces, err := s.provisioner.Spaces()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, spaces)
+}
+
+// handleSync runs a synchronization right away, e.g. after changing groups.
+func (s *svc) handleSync(w http.ResponseWriter, r *http.Request) {
+	report, err := s.sync()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, report)
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(js); err != nil {
+		appctx.GetLogger(r.Context()).Error().Err(err).Msg("spaceprovisioner: error writing response")
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	appctx.GetLogger(r.Context()).Error().Err(err).Msg("spaceprovisioner: error handling request")
+	w.WriteHeader(http.StatusInternalServerError)
+}
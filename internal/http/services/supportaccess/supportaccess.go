@@ -0,0 +1,383 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package supportaccess
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/supportaccess"
+	_ "github.com/cs3org/reva/pkg/supportaccess/manager/loader" // Load the support access managers
+	"github.com/cs3org/reva/pkg/supportaccess/manager/registry"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register("supportaccess", New)
+}
+
+// Config holds the config options for the support access HTTP service.
+type Config struct {
+	Prefix     string `mapstructure:"prefix"`
+	GatewaySvc string `mapstructure:"gatewaysvc"`
+	// Admins are the usernames of the users allowed to request access to other accounts.
+	Admins []string `mapstructure:"admins"`
+	// SkipConsent grants the requested access right away, without the consent of the users.
+	SkipConsent     bool                              `mapstructure:"skip_consent"`
+	DefaultDuration int64                             `mapstructure:"default_duration" docs:"3600;The time in seconds the access is granted for if not specified."`
+	MaxDuration     int64                             `mapstructure:"max_duration" docs:"86400;The maximum time in seconds the access can be granted for."`
+	AuthType        string                            `mapstructure:"auth_type" docs:"supportaccess;The auth type the tokens are obtained with."`
+	Driver          string                            `mapstructure:"driver"`
+	Drivers         map[string]map[string]interface{} `mapstructure:"drivers"`
+}
+
+func (c *Config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "supportaccess"
+	}
+	if c.DefaultDuration == 0 {
+		c.DefaultDuration = 3600
+	}
+	if c.MaxDuration == 0 {
+		c.MaxDuration = 86400
+	}
+	if c.AuthType == "" {
+		c.AuthType = "supportaccess"
+	}
+	if c.Driver == "" {
+		c.Driver = "json"
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+type svc struct {
+	conf     *Config
+	router   *chi.Mux
+	requests supportaccess.Manager
+	admins   map[string]struct{}
+}
+
+// New returns a new service handling the requests of admins to temporarily access the accounts of users.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &Config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, err
+	}
+	conf.init()
+
+	f, ok := registry.NewFuncs[conf.Driver]
+	if !ok {
+		return nil, fmt.Errorf("supportaccess: driver not found: %s", conf.Driver)
+	}
+	requests, err := f(conf.Drivers[conf.Driver])
+	if err != nil {
+		return nil, errors.Wrap(err, "supportaccess: error creating the request manager")
+	}
+
+	admins := make(map[string]struct{}, len(conf.Admins))
+	for _, a := range conf.Admins {
+		admins[a] = struct{}{}
+	}
+
+	s := &svc{
+		conf:     conf,
+		router:   chi.NewRouter(),
+		requests: requests,
+		admins:   admins,
+	}
+	s.routerInit()
+
+	return s, nil
+}
+
+func (s *svc) routerInit() {
+	s.router.Get("/requests", s.handleList)
+	s.router.Post("/requests", s.handleCreate)
+	s.router.Post("/requests/{id}/approve", s.handleConsent(true))
+	s.router.Post("/requests/{id}/deny", s.handleConsent(false))
+	s.router.Post("/requests/{id}/revoke", s.handleRevoke)
+	s.router.Post("/requests/{id}/token", s.handleToken)
+}
+
+// Close performs cleanup.
+func (s *svc) Close() error {
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+func (s *svc) Unprotected() []string {
+	return []string{}
+}
+
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.router.ServeHTTP(w, r)
+	})
+}
+
+func (s *svc) isAdmin(u *userpb.User) bool {
+	_, ok := s.admins[u.Username]
+	return ok
+}
+
+func (s *svc) handleList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	u := ctxpkg.ContextMustGetUser(ctx)
+
+	requests, err := s.requests.ListRequests(ctx, u.Id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	list := make([]*supportaccess.Request, 0, len(requests))
+	for _, req := range requests {
+		list = append(list, withoutSecret(req))
+	}
+	writeJSON(w, r, list)
+}
+
+func (s *svc) handleCreate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+	admin := ctxpkg.ContextMustGetUser(ctx)
+
+	if !s.isAdmin(admin) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	username := r.FormValue("user")
+	if username == "" {
+		http.Error(w, "missing user", http.StatusBadRequest)
+		return
+	}
+	duration := s.conf.DefaultDuration
+	if d := r.FormValue("duration"); d != "" {
+		var err error
+		if duration, err = strconv.ParseInt(d, 10, 64); err != nil || duration <= 0 {
+			http.Error(w, "invalid duration", http.StatusBadRequest)
+			return
+		}
+	}
+	if duration > s.conf.MaxDuration {
+		duration = s.conf.MaxDuration
+	}
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	res, err := client.GetUserByClaim(ctx, &userpb.GetUserByClaimRequest{Claim: "username", Value: username})
+	switch {
+	case err != nil:
+		writeError(w, r, err)
+		return
+	case res.Status.Code == rpc.Code_CODE_NOT_FOUND:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case res.Status.Code != rpc.Code_CODE_OK:
+		writeError(w, r, errtypes.InternalError(res.Status.Message))
+		return
+	}
+
+	req := &supportaccess.Request{
+		ID:        uuid.NewString(),
+		Admin:     admin.Id,
+		User:      res.User.Id,
+		Reason:    r.FormValue("reason"),
+		Duration:  duration,
+		State:     supportaccess.StatePending,
+		CreatedAt: time.Now().Unix(),
+	}
+	if s.conf.SkipConsent {
+		req.Approve()
+	}
+	if err := s.requests.CreateRequest(ctx, req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	log.Info().Str("request_id", req.ID).Str("admin", admin.Username).Str("user", username).Str("reason", req.Reason).
+		Str("state", string(req.State)).Msg("supportaccess: access to user account requested")
+	writeJSON(w, r, withoutSecret(req))
+}
+
+func (s *svc) handleConsent(approve bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := appctx.GetLogger(ctx)
+		u := ctxpkg.ContextMustGetUser(ctx)
+
+		req, err := s.requests.GetRequest(ctx, chi.URLParam(r, "id"))
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		// only the user whose account is accessed can give consent
+		if !utils.UserEqual(req.User, u.Id) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if req.State != supportaccess.StatePending {
+			http.Error(w, "request is not pending", http.StatusConflict)
+			return
+		}
+
+		if approve {
+			req.Approve()
+		} else {
+			req.State = supportaccess.StateDenied
+		}
+		if err := s.requests.UpdateRequest(ctx, req); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		log.Info().Str("request_id", req.ID).Str("user", u.Username).Str("state", string(req.State)).Msg("supportaccess: consent given")
+		writeJSON(w, r, withoutSecret(req))
+	}
+}
+
+func (s *svc) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+	u := ctxpkg.ContextMustGetUser(ctx)
+
+	req, err := s.requests.GetRequest(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !utils.UserEqual(req.User, u.Id) && !utils.UserEqual(req.Admin, u.Id) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	req.State = supportaccess.StateRevoked
+	req.Secret = ""
+	if err := s.requests.UpdateRequest(ctx, req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	log.Info().Str("request_id", req.ID).Str("by", u.Username).Msg("supportaccess: access revoked")
+	writeJSON(w, r, withoutSecret(req))
+}
+
+func (s *svc) handleToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+	admin := ctxpkg.ContextMustGetUser(ctx)
+
+	req, err := s.requests.GetRequest(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !s.isAdmin(admin) || !utils.UserEqual(req.Admin, admin.Id) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if !req.Active() {
+		http.Error(w, "access is not granted", http.StatusForbidden)
+		return
+	}
+
+	// a new secret is generated for every token, so that it never needs to be stored in clear
+	secret := uuid.NewString()
+	req.SetSecret(secret)
+	if err := s.requests.UpdateRequest(ctx, req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	res, err := client.Authenticate(ctx, &gateway.AuthenticateRequest{
+		Type:         s.conf.AuthType,
+		ClientId:     req.ID,
+		ClientSecret: secret,
+	})
+	switch {
+	case err != nil:
+		writeError(w, r, err)
+		return
+	case res.Status.Code != rpc.Code_CODE_OK:
+		writeError(w, r, errtypes.InternalError(res.Status.Message))
+		return
+	}
+
+	log.Info().Str("request_id", req.ID).Str("admin", admin.Username).Msg("supportaccess: token issued")
+	writeJSON(w, r, map[string]interface{}{
+		"token":      res.Token,
+		"expires_at": req.ExpiresAt,
+	})
+}
+
+// withoutSecret returns a copy of the request which can be sent to the clients.
+func withoutSecret(req *supportaccess.Request) *supportaccess.Request {
+	r := *req
+	r.Secret = ""
+	return &r
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(js); err != nil {
+		appctx.GetLogger(r.Context()).Error().Err(err).Msg("supportaccess: error writing response")
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if _, ok := err.(errtypes.IsNotFound); ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	appctx.GetLogger(r.Context()).Error().Err(err).Msg("supportaccess: error handling request")
+	w.WriteHeader(http.StatusInternalServerError)
+}
@@ -89,6 +89,11 @@ func (s *svc) Unprotected() []string {
 	}
 }
 
+// IsCrossOrigin returns true as discovery documents need to be readable from any origin.
+func (s *svc) IsCrossOrigin() bool {
+	return true
+}
+
 func (s *svc) setHandler() {
 	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log := appctx.GetLogger(r.Context())
@@ -0,0 +1,80 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package wopi
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/wopi"
+)
+
+// discoveryTTL is how long the mime types found in the discovery
+// of the app are kept before querying it again.
+const discoveryTTL = time.Hour
+
+type discoveryResponse struct {
+	AppName   string   `json:"app_name"`
+	MimeTypes []string `json:"mime_types"`
+}
+
+// handleDiscovery reports the mime types the app can open, so that the
+// web frontend knows which files to offer to open with it.
+func (s *svc) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	log := appctx.GetLogger(r.Context())
+
+	if s.conf.AppIntURL == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	mimeTypes, err := s.getMimeTypes()
+	if err != nil {
+		log.Error().Err(err).Str("app", s.conf.AppName).Msg("wopi: error querying the app discovery")
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(discoveryResponse{
+		AppName:   s.conf.AppName,
+		MimeTypes: mimeTypes,
+	}); err != nil {
+		log.Error().Err(err).Msg("wopi: error encoding discovery")
+	}
+}
+
+func (s *svc) getMimeTypes() ([]string, error) {
+	s.discoveryMu.Lock()
+	defer s.discoveryMu.Unlock()
+
+	if s.mimeTypes != nil && time.Since(s.discoveredAt) < discoveryTTL {
+		return s.mimeTypes, nil
+	}
+
+	appURLs, err := wopi.GetDiscovery(s.conf.AppIntURL, s.conf.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	s.mimeTypes = wopi.MimeTypes(appURLs)
+	s.discoveredAt = time.Now()
+	return s.mimeTypes, nil
+}
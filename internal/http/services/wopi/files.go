@@ -0,0 +1,305 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package wopi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/cs3org/reva/pkg/wopi"
+)
+
+// fileInfo is the response to the CheckFileInfo operation,
+// see https://docs.microsoft.com/en-us/microsoft-365/cloud-storage-partner-program/rest/files/checkfileinfo
+type fileInfo struct {
+	BaseFileName               string `json:"BaseFileName"`
+	OwnerID                    string `json:"OwnerId"`
+	Size                       int64  `json:"Size"`
+	UserID                     string `json:"UserId"`
+	Version                    string `json:"Version"`
+	UserFriendlyName           string `json:"UserFriendlyName"`
+	UserCanWrite               bool   `json:"UserCanWrite"`
+	UserCanNotWriteRelative    bool   `json:"UserCanNotWriteRelative"`
+	ReadOnly                   bool   `json:"ReadOnly"`
+	SupportsLocks              bool   `json:"SupportsLocks"`
+	SupportsGetLock            bool   `json:"SupportsGetLock"`
+	SupportsExtendedLockLength bool   `json:"SupportsExtendedLockLength"`
+	SupportsUpdate             bool   `json:"SupportsUpdate"`
+	LastModifiedTime           string `json:"LastModifiedTime"`
+}
+
+func (s *svc) handleCheckFileInfo(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		log.Error().Err(err).Msg("wopi: error getting grpc gateway client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	info, ok := s.stat(ctx, w, client)
+	if !ok {
+		return
+	}
+
+	u := ctxpkg.ContextMustGetUser(ctx)
+	canWrite := getAccess(ctx).canWrite() && info.PermissionSet.GetInitiateFileUpload()
+	fi := fileInfo{
+		BaseFileName:               info.Name,
+		OwnerID:                    userID(info.Owner),
+		Size:                       int64(info.Size),
+		UserID:                     userID(u.Id),
+		Version:                    strings.Trim(info.Etag, `"`),
+		UserFriendlyName:           u.DisplayName,
+		UserCanWrite:               canWrite,
+		UserCanNotWriteRelative:    true,
+		ReadOnly:                   !canWrite,
+		SupportsLocks:              true,
+		SupportsGetLock:            true,
+		SupportsExtendedLockLength: true,
+		SupportsUpdate:             true,
+		LastModifiedTime:           utils.TSToTime(info.Mtime).UTC().Format(time.RFC3339),
+	}
+	if fi.BaseFileName == "" {
+		fi.BaseFileName = info.Path[strings.LastIndex(info.Path, "/")+1:]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(fi); err != nil {
+		log.Error().Err(err).Msg("wopi: error encoding file info")
+	}
+}
+
+func (s *svc) handleGetFile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		log.Error().Err(err).Msg("wopi: error getting grpc gateway client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	info, ok := s.stat(ctx, w, client)
+	if !ok {
+		return
+	}
+
+	dRes, err := client.InitiateFileDownload(ctx, &provider.InitiateFileDownloadRequest{Ref: getAccess(ctx).ref})
+	if err != nil {
+		log.Error().Err(err).Msg("wopi: error initiating file download")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if dRes.Status.Code != rpc.Code_CODE_OK {
+		writeStatus(w, dRes.Status)
+		return
+	}
+
+	var ep, token string
+	for _, p := range dRes.Protocols {
+		if p.Protocol == "simple" {
+			ep, token = p.DownloadEndpoint, p.Token
+		}
+	}
+
+	httpReq, err := rhttp.NewRequest(ctx, http.MethodGet, ep, nil)
+	if err != nil {
+		log.Error().Err(err).Msg("wopi: error creating http request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	httpReq.Header.Set(datagateway.TokenTransportHeader, token)
+
+	httpRes, err := s.client.Do(httpReq)
+	if err != nil {
+		log.Error().Err(err).Msg("wopi: error performing http request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer httpRes.Body.Close()
+
+	if httpRes.StatusCode != http.StatusOK {
+		w.WriteHeader(httpRes.StatusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set(wopi.HeaderItemVersion, strings.Trim(info.Etag, `"`))
+	if l := httpRes.Header.Get("Content-Length"); l != "" {
+		w.Header().Set("Content-Length", l)
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, httpRes.Body); err != nil {
+		log.Error().Err(err).Msg("wopi: error writing file contents")
+	}
+}
+
+func (s *svc) handlePutFile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+	acc := getAccess(ctx)
+
+	if !acc.canWrite() {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		log.Error().Err(err).Msg("wopi: error getting grpc gateway client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	info, ok := s.stat(ctx, w, client)
+	if !ok {
+		return
+	}
+
+	// the file can only be written by the holder of the lock,
+	// or without a lock if it is still empty
+	lock, ok := s.getLock(ctx, w, client)
+	if !ok {
+		return
+	}
+	lockID := r.Header.Get(wopi.HeaderLock)
+	if (lock == nil && info.Size > 0) || (lock != nil && lock.LockId != lockID) {
+		writeLockConflict(w, lock, "lock mismatch")
+		return
+	}
+
+	uRes, err := client.InitiateFileUpload(ctx, &provider.InitiateFileUploadRequest{
+		Ref: acc.ref,
+		Opaque: &typespb.Opaque{
+			Map: map[string]*typespb.OpaqueEntry{
+				"Upload-Length": {
+					Decoder: "plain",
+					Value:   []byte(strconv.FormatInt(r.ContentLength, 10)),
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("wopi: error initiating file upload")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if uRes.Status.Code != rpc.Code_CODE_OK {
+		writeStatus(w, uRes.Status)
+		return
+	}
+
+	var ep, token string
+	for _, p := range uRes.Protocols {
+		if p.Protocol == "simple" {
+			ep, token = p.UploadEndpoint, p.Token
+		}
+	}
+
+	httpReq, err := rhttp.NewRequest(ctx, http.MethodPut, ep, r.Body)
+	if err != nil {
+		log.Error().Err(err).Msg("wopi: error creating http request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	httpReq.ContentLength = r.ContentLength
+	httpReq.Header.Set(datagateway.TokenTransportHeader, token)
+
+	httpRes, err := s.client.Do(httpReq)
+	if err != nil {
+		log.Error().Err(err).Msg("wopi: error doing PUT request to data service")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer httpRes.Body.Close()
+	if httpRes.StatusCode != http.StatusOK {
+		log.Error().Int("status", httpRes.StatusCode).Msg("wopi: PUT request to data server failed")
+		w.WriteHeader(httpRes.StatusCode)
+		return
+	}
+
+	// report the new version of the file to the app
+	if info, ok := s.stat(ctx, w, client); ok {
+		w.Header().Set(wopi.HeaderItemVersion, strings.Trim(info.Etag, `"`))
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// stat returns the resource info of the requested file, or writes
+// the error response and returns false.
+func (s *svc) stat(ctx context.Context, w http.ResponseWriter, client gateway.GatewayAPIClient) (*provider.ResourceInfo, bool) {
+	res, err := client.Stat(ctx, &provider.StatRequest{Ref: getAccess(ctx).ref})
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("wopi: error sending grpc stat request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		writeStatus(w, res.Status)
+		return nil, false
+	}
+	if res.Info.Type != provider.ResourceType_RESOURCE_TYPE_FILE {
+		w.WriteHeader(http.StatusNotFound)
+		return nil, false
+	}
+	return res.Info, true
+}
+
+// writeStatus writes the HTTP status code matching the status of a failed CS3 call.
+func writeStatus(w http.ResponseWriter, st *rpc.Status) {
+	switch st.Code {
+	case rpc.Code_CODE_NOT_FOUND:
+		w.WriteHeader(http.StatusNotFound)
+	case rpc.Code_CODE_UNAUTHENTICATED, rpc.Code_CODE_PERMISSION_DENIED:
+		w.WriteHeader(http.StatusUnauthorized)
+	case rpc.Code_CODE_FAILED_PRECONDITION, rpc.Code_CODE_ABORTED:
+		w.WriteHeader(http.StatusConflict)
+	case rpc.Code_CODE_INSUFFICIENT_STORAGE:
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func userID(id *userpb.UserId) string {
+	if id == nil {
+		return ""
+	}
+	return id.OpaqueId + "@" + id.Idp
+}
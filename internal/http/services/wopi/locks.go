@@ -0,0 +1,175 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package wopi
+
+import (
+	"context"
+	"net/http"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/wopi"
+)
+
+// handleFileOperation dispatches the operations sent as POST requests
+// to the file endpoint, identified by the X-WOPI-Override header.
+func (s *svc) handleFileOperation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	op := r.Header.Get(wopi.HeaderOverride)
+	switch op {
+	case "LOCK", "GET_LOCK", "REFRESH_LOCK", "UNLOCK":
+	default:
+		log.Debug().Str("operation", op).Msg("wopi: unsupported operation")
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	if op != "GET_LOCK" && !getAccess(ctx).canWrite() {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(s.conf.GatewaySvc))
+	if err != nil {
+		log.Error().Err(err).Msg("wopi: error getting grpc gateway client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	lock, ok := s.getLock(ctx, w, client)
+	if !ok {
+		return
+	}
+
+	lockID := r.Header.Get(wopi.HeaderLock)
+	if op != "GET_LOCK" && lockID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch op {
+	case "GET_LOCK":
+		if lock != nil {
+			w.Header().Set(wopi.HeaderLock, lock.LockId)
+		} else {
+			w.Header().Set(wopi.HeaderLock, "")
+		}
+		w.WriteHeader(http.StatusOK)
+
+	case "LOCK":
+		if oldLockID := r.Header.Get(wopi.HeaderOldLock); oldLockID != "" {
+			// UnlockAndRelock: the lock is replaced only if it is held with the old id
+			if lock == nil || lock.LockId != oldLockID {
+				writeLockConflict(w, lock, "old lock mismatch")
+				return
+			}
+			s.refreshLock(ctx, w, client, lockID)
+			return
+		}
+		switch {
+		case lock == nil:
+			s.setLock(ctx, w, client, lockID)
+		case lock.LockId == lockID:
+			// locking again with the same id refreshes the lock
+			s.refreshLock(ctx, w, client, lockID)
+		default:
+			writeLockConflict(w, lock, "file already locked")
+		}
+
+	case "REFRESH_LOCK":
+		if lock == nil || lock.LockId != lockID {
+			writeLockConflict(w, lock, "lock mismatch")
+			return
+		}
+		s.refreshLock(ctx, w, client, lockID)
+
+	case "UNLOCK":
+		if lock == nil || lock.LockId != lockID {
+			writeLockConflict(w, lock, "lock mismatch")
+			return
+		}
+		res, err := client.Unlock(ctx, &provider.UnlockRequest{Ref: getAccess(ctx).ref, Lock: lockFromRequest(lockID)})
+		writeLockResponse(ctx, w, res.GetStatus(), err)
+	}
+}
+
+// getLock returns the lock currently held on the requested file, or nil
+// if it is not locked. In case of failure it writes the error response
+// and returns false.
+func (s *svc) getLock(ctx context.Context, w http.ResponseWriter, client gateway.GatewayAPIClient) (*provider.Lock, bool) {
+	res, err := client.GetLock(ctx, &provider.GetLockRequest{Ref: getAccess(ctx).ref})
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("wopi: error getting lock")
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+		return res.Lock, true
+	case rpc.Code_CODE_NOT_FOUND:
+		return nil, true
+	default:
+		writeStatus(w, res.Status)
+		return nil, false
+	}
+}
+
+func (s *svc) setLock(ctx context.Context, w http.ResponseWriter, client gateway.GatewayAPIClient, lockID string) {
+	res, err := client.SetLock(ctx, &provider.SetLockRequest{Ref: getAccess(ctx).ref, Lock: lockFromRequest(lockID)})
+	writeLockResponse(ctx, w, res.GetStatus(), err)
+}
+
+func (s *svc) refreshLock(ctx context.Context, w http.ResponseWriter, client gateway.GatewayAPIClient, lockID string) {
+	res, err := client.RefreshLock(ctx, &provider.RefreshLockRequest{Ref: getAccess(ctx).ref, Lock: lockFromRequest(lockID)})
+	writeLockResponse(ctx, w, res.GetStatus(), err)
+}
+
+func writeLockResponse(ctx context.Context, w http.ResponseWriter, st *rpc.Status, err error) {
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("wopi: error updating lock")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if st.Code != rpc.Code_CODE_OK {
+		if st.Code == rpc.Code_CODE_FAILED_PRECONDITION {
+			// somebody else locked the file in the meantime
+			w.Header().Set(wopi.HeaderLockFailureReason, st.Message)
+		}
+		writeStatus(w, st)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeLockConflict answers with the 409 status required by the protocol
+// when the lock of the request does not match the current one.
+func writeLockConflict(w http.ResponseWriter, current *provider.Lock, reason string) {
+	if current != nil {
+		w.Header().Set(wopi.HeaderLock, current.LockId)
+	} else {
+		w.Header().Set(wopi.HeaderLock, "")
+	}
+	w.Header().Set(wopi.HeaderLockFailureReason, reason)
+	w.WriteHeader(http.StatusConflict)
+}
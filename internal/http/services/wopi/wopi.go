@@ -0,0 +1,232 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package wopi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/token"
+	tokenmgr "github.com/cs3org/reva/pkg/token/manager/registry"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/cs3org/reva/pkg/wopi"
+	"github.com/go-chi/chi/v5"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/metadata"
+)
+
+func init() {
+	global.Register("wopi", New)
+}
+
+// Config holds the config options for the HTTP wopi service.
+type Config struct {
+	Prefix        string                            `mapstructure:"prefix"`
+	GatewaySvc    string                            `mapstructure:"gatewaysvc"`
+	Insecure      bool                              `mapstructure:"insecure" docs:"false;Whether to skip certificate checks when sending requests."`
+	AppName       string                            `mapstructure:"app_name" docs:";The user-friendly name of the app reported by the discovery endpoint."`
+	AppIntURL     string                            `mapstructure:"app_int_url" docs:";The internal URL of the app, used to query its WOPI discovery."`
+	TokenManager  string                            `mapstructure:"token_manager" docs:"jwt;The token manager used to verify the access tokens minted by the wopihost app provider."`
+	TokenManagers map[string]map[string]interface{} `mapstructure:"token_managers"`
+}
+
+func (c *Config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "wopi"
+	}
+	if c.TokenManager == "" {
+		c.TokenManager = "jwt"
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+type svc struct {
+	conf     *Config
+	router   *chi.Mux
+	tokenmgr token.Manager
+	client   *http.Client
+
+	discoveryMu  sync.Mutex
+	mimeTypes    []string
+	discoveredAt time.Time
+}
+
+// New returns a new HTTP service implementing the host side of the WOPI protocol,
+// through which WOPI applications such as Collabora and OnlyOffice
+// read, write and lock the documents opened with the wopihost app provider.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &Config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, err
+	}
+	conf.init()
+
+	f, ok := tokenmgr.NewFuncs[conf.TokenManager]
+	if !ok {
+		return nil, fmt.Errorf("wopi: token manager not found: %s", conf.TokenManager)
+	}
+	tm, err := f(conf.TokenManagers[conf.TokenManager])
+	if err != nil {
+		return nil, errors.Wrap(err, "wopi: error creating token manager")
+	}
+
+	s := &svc{
+		conf:     conf,
+		router:   chi.NewRouter(),
+		tokenmgr: tm,
+		client:   rhttp.GetHTTPClient(rhttp.Insecure(conf.Insecure)),
+	}
+	s.routerInit()
+
+	return s, nil
+}
+
+func (s *svc) routerInit() {
+	s.router.Get("/discovery", s.handleDiscovery)
+	s.router.Route("/files/{fileid}", func(r chi.Router) {
+		r.Use(s.authenticate)
+		r.Get("/", s.handleCheckFileInfo)
+		r.Post("/", s.handleFileOperation)
+		r.Get("/contents", s.handleGetFile)
+		r.Post("/contents", s.handlePutFile)
+	})
+}
+
+// Close performs cleanup.
+func (s *svc) Close() error {
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+// Unprotected returns all the paths of the service: the WOPI applications
+// authenticate with the access_token query parameter, which is verified
+// by the service itself.
+func (s *svc) Unprotected() []string {
+	return []string{"/"}
+}
+
+func (s *svc) Handler() http.Handler {
+	return s.router
+}
+
+type accessKey struct{}
+
+// access is what the access token of a WOPI request grants.
+type access struct {
+	ref  *provider.Reference
+	role authpb.Role
+}
+
+func (a *access) canWrite() bool {
+	return a.role == authpb.Role_ROLE_OWNER || a.role == authpb.Role_ROLE_EDITOR
+}
+
+func getAccess(ctx context.Context) *access {
+	return ctx.Value(accessKey{}).(*access)
+}
+
+// authenticate verifies that the access token of the request was minted for
+// the requested file and sets the user and the token in the context,
+// so that the requests to the gateway are made on behalf of the user.
+func (s *svc) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := appctx.GetLogger(ctx)
+
+		id, err := wopi.ParseFileID(chi.URLParam(r, "fileid"))
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		tkn := r.URL.Query().Get("access_token")
+		if tkn == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		u, scopes, err := s.tokenmgr.DismantleToken(ctx, tkn)
+		if err != nil {
+			log.Debug().Err(err).Msg("wopi: invalid access token")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		sc, ok := resourceScope(scopes, id)
+		if !ok {
+			log.Debug().Str("fileid", id.String()).Msg("wopi: access token not valid for the requested file")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		ctx = ctxpkg.ContextSetUser(ctx, u)
+		ctx = ctxpkg.ContextSetToken(ctx, tkn)
+		ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.TokenHeader, tkn)
+		ctx = context.WithValue(ctx, accessKey{}, &access{
+			ref:  &provider.Reference{ResourceId: id},
+			role: sc.Role,
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// resourceScope returns the resourceinfo scope granting access to the given resource.
+func resourceScope(scopes map[string]*authpb.Scope, id *provider.ResourceId) (*authpb.Scope, bool) {
+	for k, sc := range scopes {
+		if !strings.HasPrefix(k, "resourceinfo:") {
+			continue
+		}
+		var r provider.ResourceInfo
+		if err := utils.UnmarshalJSONToProtoV1(sc.Resource.Value, &r); err != nil {
+			continue
+		}
+		if utils.ResourceIDEqual(r.Id, id) {
+			return sc, true
+		}
+	}
+	return nil, false
+}
+
+// lockFromRequest returns the CS3 lock matching the given WOPI lock id.
+// WOPI locks are shared by all the users editing a document together,
+// hence they are held by the app rather than by a user.
+func lockFromRequest(lockID string) *provider.Lock {
+	return &provider.Lock{
+		LockId:     lockID,
+		Type:       provider.LockType_LOCK_TYPE_WRITE,
+		AppName:    wopi.LockAppName,
+		Expiration: &typespb.Timestamp{Seconds: uint64(time.Now().Add(wopi.LockDuration).Unix())},
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package accesslog writes the access logs of the HTTP and gRPC servers,
+// separately from the application logs.
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/pkg/errors"
+)
+
+// The formats of the entries besides custom templates.
+const (
+	// FormatJSON writes an entry as a JSON object per line.
+	FormatJSON = "json"
+	// FormatCombined writes an entry similarly to the combined log format of
+	// Apache, followed by the duration of the request in milliseconds.
+	FormatCombined = "combined"
+)
+
+// Config holds the configuration of the access log of a server.
+type Config struct {
+	Enabled bool `mapstructure:"enabled" docs:"false;Whether to write the access log."`
+	// Output is the file the entries are appended to, or stdout or stderr.
+	Output string `mapstructure:"output" docs:"stderr;The file the entries are appended to, or stdout or stderr."`
+	// Format is json, combined or a Go template rendering an Entry.
+	Format string `mapstructure:"format" docs:"json;The format of the entries: json, combined or a Go template rendering an entry."`
+	// Services flags the services whose requests are logged. The services not
+	// listed are logged.
+	Services map[string]bool `mapstructure:"services" docs:";Enables or disables the access log per service."`
+	// Sampling logs only one of every n requests whose method, or the prefix
+	// of whose route, is the key. Failed requests are always logged.
+	Sampling map[string]int `mapstructure:"sampling" docs:";Logs only one of every n requests with the given method or route prefix."`
+}
+
+// Entry describes a request served by a server.
+type Entry struct {
+	Time time.Time
+	// Protocol is http or grpc.
+	Protocol string
+	// Service is the name of the service the request was routed to.
+	Service string
+	// Method is the HTTP method or the gRPC method name.
+	Method string
+	// Route is the path of the URL or the full gRPC method.
+	Route     string
+	User      string
+	Remote    string
+	UserAgent string
+	// Status is the HTTP status code or the gRPC status code.
+	Status   string
+	Failed   bool
+	Duration time.Duration
+	// Bytes is the size of the response.
+	Bytes int64
+}
+
+type sampler struct {
+	key   string
+	every uint64
+	count uint64
+}
+
+// Logger writes the entries of an access log.
+type Logger struct {
+	format   func(w io.Writer, e *Entry) error
+	services map[string]bool
+	samplers []*sampler
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// New returns a logger writing the entries as configured.
+func New(c *Config) (*Logger, error) {
+	l := &Logger{services: c.Services}
+
+	switch c.Format {
+	case "", FormatJSON:
+		l.format = formatJSON
+	case FormatCombined:
+		l.format = formatCombined
+	default:
+		tpl, err := template.New("accesslog").Parse(c.Format)
+		if err != nil {
+			return nil, errors.Wrap(err, "accesslog: error parsing format")
+		}
+		l.format = func(w io.Writer, e *Entry) error {
+			return tpl.Execute(w, e)
+		}
+	}
+
+	for key, every := range c.Sampling {
+		if every < 1 {
+			return nil, fmt.Errorf("accesslog: invalid sampling of %s: %d", key, every)
+		}
+		l.samplers = append(l.samplers, &sampler{key: key, every: uint64(every)})
+	}
+	// the most specific key applies
+	sort.Slice(l.samplers, func(i, j int) bool {
+		return len(l.samplers[i].key) > len(l.samplers[j].key)
+	})
+
+	w, err := getWriter(c.Output)
+	if err != nil {
+		return nil, err
+	}
+	l.w = w
+	return l, nil
+}
+
+func getWriter(out string) (io.Writer, error) {
+	switch out {
+	case "", "stderr":
+		return os.Stderr, nil
+	case "stdout":
+		return os.Stdout, nil
+	}
+
+	fd, err := os.OpenFile(out, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "accesslog: error opening "+out)
+	}
+	return fd, nil
+}
+
+// Enabled tells whether the requests to the service are logged.
+func (l *Logger) Enabled(service string) bool {
+	enabled, ok := l.services[service]
+	return !ok || enabled
+}
+
+func (l *Logger) sampled(e *Entry) bool {
+	if e.Failed {
+		return true
+	}
+	for _, s := range l.samplers {
+		if e.Method == s.key || strings.HasPrefix(e.Route, s.key) {
+			return (atomic.AddUint64(&s.count, 1)-1)%s.every == 0
+		}
+	}
+	return true
+}
+
+// Log writes the entry, unless its service is disabled or it is sampled out.
+func (l *Logger) Log(e *Entry) {
+	if !l.Enabled(e.Service) || !l.sampled(e) {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := l.format(&buf, e); err != nil {
+		fmt.Fprintf(os.Stderr, "accesslog: error formatting entry: %v\n", err)
+		return
+	}
+	if b := buf.Bytes(); len(b) == 0 || b[len(b)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.w.Write(buf.Bytes()); err != nil {
+		fmt.Fprintf(os.Stderr, "accesslog: error writing entry: %v\n", err)
+	}
+}
+
+func formatJSON(w io.Writer, e *Entry) error {
+	return json.NewEncoder(w).Encode(struct {
+		Time       string  `json:"time"`
+		Protocol   string  `json:"protocol"`
+		Service    string  `json:"service,omitempty"`
+		Method     string  `json:"method"`
+		Route      string  `json:"route"`
+		User       string  `json:"user,omitempty"`
+		Remote     string  `json:"remote,omitempty"`
+		UserAgent  string  `json:"user_agent,omitempty"`
+		Status     string  `json:"status"`
+		DurationMS float64 `json:"duration_ms"`
+		Bytes      int64   `json:"bytes"`
+	}{
+		Time:       e.Time.Format(time.RFC3339Nano),
+		Protocol:   e.Protocol,
+		Service:    e.Service,
+		Method:     e.Method,
+		Route:      e.Route,
+		User:       e.User,
+		Remote:     e.Remote,
+		UserAgent:  e.UserAgent,
+		Status:     e.Status,
+		DurationMS: durationMS(e.Duration),
+		Bytes:      e.Bytes,
+	})
+}
+
+func formatCombined(w io.Writer, e *Entry) error {
+	_, err := fmt.Fprintf(w, "%s - %s [%s] \"%s %s %s\" %s %d \"-\" %q %.3f\n",
+		orDash(e.Remote), orDash(e.User), e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		e.Method, e.Route, e.Protocol, e.Status, e.Bytes, e.UserAgent, durationMS(e.Duration))
+	return err
+}
+
+func durationMS(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+type entryKey struct{}
+
+// ContextSetEntry stores the entry of the request in the context, so that the
+// handlers can complete it.
+func ContextSetEntry(ctx context.Context, e *Entry) context.Context {
+	return context.WithValue(ctx, entryKey{}, e)
+}
+
+// ContextGetEntry returns the entry of the request if set in the given context.
+func ContextGetEntry(ctx context.Context) (*Entry, bool) {
+	e, ok := ctx.Value(entryKey{}).(*Entry)
+	return e, ok
+}
+
+// SetUser records the user the request has been authenticated as in the entry
+// stored in the context.
+func SetUser(ctx context.Context, u *userpb.User) {
+	if e, ok := ContextGetEntry(ctx); ok {
+		e.SetUser(u)
+	}
+}
+
+// SetUser records the user the request has been authenticated as.
+func (e *Entry) SetUser(u *userpb.User) {
+	if e.User = u.GetUsername(); e.User == "" {
+		e.User = u.GetId().GetOpaqueId()
+	}
+}
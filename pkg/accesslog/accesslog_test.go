@@ -0,0 +1,164 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+)
+
+func newLogger(t *testing.T, c *Config) (*Logger, *bytes.Buffer) {
+	l, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	l.w = buf
+	return l, buf
+}
+
+func entry(method, route, status string) *Entry {
+	return &Entry{
+		Time:     time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC),
+		Protocol: "http",
+		Service:  "ocdav",
+		Method:   method,
+		Route:    route,
+		User:     "einstein",
+		Remote:   "10.0.0.1",
+		Status:   status,
+		Duration: 1500 * time.Microsecond,
+		Bytes:    42,
+	}
+}
+
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "combined",
+			want:   `10.0.0.1 - einstein [01/Oct/2021:12:00:00 +0000] "GET /remote.php/webdav/a http" 200 42 "-" "" 1.500` + "\n",
+		},
+		{
+			format: "{{.Method}} {{.Route}} {{.Status}} {{.Duration}}",
+			want:   "GET /remote.php/webdav/a 200 1.5ms\n",
+		},
+	}
+	for _, tt := range tests {
+		l, buf := newLogger(t, &Config{Format: tt.format})
+		l.Log(entry("GET", "/remote.php/webdav/a", "200"))
+		if buf.String() != tt.want {
+			t.Errorf("format %q: got %q, want %q", tt.format, buf.String(), tt.want)
+		}
+	}
+
+	l, buf := newLogger(t, &Config{})
+	l.Log(entry("GET", "/remote.php/webdav/a", "200"))
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["route"] != "/remote.php/webdav/a" || m["user"] != "einstein" || m["duration_ms"] != 1.5 || m["bytes"] != 42.0 {
+		t.Errorf("unexpected json entry %v", m)
+	}
+
+	if _, err := New(&Config{Format: "{{.Method"}); err == nil {
+		t.Error("expected an error for an invalid template")
+	}
+}
+
+func TestSampling(t *testing.T) {
+	l, buf := newLogger(t, &Config{
+		Format: "{{.Method}} {{.Route}}",
+		Sampling: map[string]int{
+			"PROPFIND":              10,
+			"/remote.php/dav/files": 2,
+		},
+	})
+	for i := 0; i < 20; i++ {
+		l.Log(entry("PROPFIND", "/remote.php/webdav/a", "207"))
+		l.Log(entry("GET", "/remote.php/dav/files/einstein/a", "200"))
+		l.Log(entry("GET", "/remote.php/webdav/a", "200"))
+	}
+
+	counts := map[string]int{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		counts[line]++
+	}
+	if got := counts["PROPFIND /remote.php/webdav/a"]; got != 2 {
+		t.Errorf("got %d PROPFIND entries, want 2", got)
+	}
+	if got := counts["GET /remote.php/dav/files/einstein/a"]; got != 10 {
+		t.Errorf("got %d sampled GET entries, want 10", got)
+	}
+	if got := counts["GET /remote.php/webdav/a"]; got != 20 {
+		t.Errorf("got %d GET entries, want 20", got)
+	}
+
+	if _, err := New(&Config{Sampling: map[string]int{"GET": 0}}); err == nil {
+		t.Error("expected an error for an invalid sampling")
+	}
+}
+
+func TestSamplingFailed(t *testing.T) {
+	l, buf := newLogger(t, &Config{Format: "{{.Status}}", Sampling: map[string]int{"PROPFIND": 100}})
+	for i := 0; i < 3; i++ {
+		e := entry("PROPFIND", "/", "500")
+		e.Failed = true
+		l.Log(e)
+	}
+	if got := strings.Count(buf.String(), "500"); got != 3 {
+		t.Errorf("got %d failed entries, want 3", got)
+	}
+}
+
+func TestServices(t *testing.T) {
+	l, buf := newLogger(t, &Config{Format: "{{.Service}}", Services: map[string]bool{"dataprovider": false, "ocdav": true}})
+	for _, svc := range []string{"ocdav", "dataprovider", "ocs"} {
+		e := entry("GET", "/", "200")
+		e.Service = svc
+		l.Log(e)
+	}
+	if buf.String() != "ocdav\nocs\n" {
+		t.Errorf("unexpected entries %q", buf.String())
+	}
+}
+
+func TestSetUser(t *testing.T) {
+	e := &Entry{}
+	ctx := ContextSetEntry(context.Background(), e)
+	SetUser(ctx, &userpb.User{Id: &userpb.UserId{OpaqueId: "4c510ada"}})
+	if e.User != "4c510ada" {
+		t.Errorf("got user %q", e.User)
+	}
+	SetUser(ctx, &userpb.User{Username: "einstein"})
+	if e.User != "einstein" {
+		t.Errorf("got user %q", e.User)
+	}
+	// no entry in the context
+	SetUser(context.Background(), &userpb.User{Username: "marie"})
+}
@@ -0,0 +1,174 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package affiliation carries the organizational claims of a user, i.e. its
+// affiliations (e.g. staff, student), department and country, in the opaque
+// of the user so that they travel with the token and can be used by policies.
+package affiliation
+
+import (
+	"encoding/json"
+	"strings"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+const (
+	// OpaqueKey is the key in the user opaque holding the affiliations.
+	OpaqueKey = "affiliation"
+	// DepartmentOpaqueKey is the key in the user opaque holding the department.
+	DepartmentOpaqueKey = "department"
+	// CountryOpaqueKey is the key in the user opaque holding the country.
+	CountryOpaqueKey = "country"
+)
+
+// FromClaim normalizes the value of an affiliation claim. Claims may hold a
+// single value or a list, and scoped affiliations such as staff@example.org
+// are reduced to their unscoped value.
+func FromClaim(v interface{}) []string {
+	var values []string
+	switch t := v.(type) {
+	case string:
+		values = strings.FieldsFunc(t, func(r rune) bool { return r == ',' || r == ' ' })
+	case []string:
+		values = t
+	case []interface{}:
+		for _, e := range t {
+			if s, ok := e.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	return Normalize(values)
+}
+
+// Normalize lower-cases the given affiliations, strips their scope and drops
+// empty values and duplicates.
+func Normalize(values []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, v := range values {
+		if i := strings.Index(v, "@"); i >= 0 {
+			v = v[:i]
+		}
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	return out
+}
+
+// Set stores the affiliations and the department of a user in its opaque.
+// Empty values are not stored.
+func Set(u *userpb.User, affiliations []string, department string) {
+	if len(affiliations) == 0 && department == "" {
+		return
+	}
+	if u.Opaque == nil {
+		u.Opaque = &types.Opaque{}
+	}
+	if u.Opaque.Map == nil {
+		u.Opaque.Map = map[string]*types.OpaqueEntry{}
+	}
+	if len(affiliations) > 0 {
+		v, _ := json.Marshal(affiliations)
+		u.Opaque.Map[OpaqueKey] = &types.OpaqueEntry{
+			Decoder: "json",
+			Value:   v,
+		}
+	}
+	if department != "" {
+		u.Opaque.Map[DepartmentOpaqueKey] = &types.OpaqueEntry{
+			Decoder: "plain",
+			Value:   []byte(department),
+		}
+	}
+}
+
+// SetCountry stores the country of a user, as an upper-cased ISO 3166 code
+// e.g. CH, in its opaque. An empty country is not stored.
+func SetCountry(u *userpb.User, country string) {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if country == "" {
+		return
+	}
+	if u.Opaque == nil {
+		u.Opaque = &types.Opaque{}
+	}
+	if u.Opaque.Map == nil {
+		u.Opaque.Map = map[string]*types.OpaqueEntry{}
+	}
+	u.Opaque.Map[CountryOpaqueKey] = &types.OpaqueEntry{
+		Decoder: "plain",
+		Value:   []byte(country),
+	}
+}
+
+// Of returns the affiliations of a user.
+func Of(u *userpb.User) []string {
+	e, ok := u.GetOpaque().GetMap()[OpaqueKey]
+	if !ok {
+		return nil
+	}
+	var affiliations []string
+	switch e.Decoder {
+	case "json":
+		if err := json.Unmarshal(e.Value, &affiliations); err != nil {
+			return nil
+		}
+	case "plain":
+		affiliations = strings.Split(string(e.Value), ",")
+	}
+	return Normalize(affiliations)
+}
+
+// DepartmentOf returns the department of a user.
+func DepartmentOf(u *userpb.User) string {
+	if e, ok := u.GetOpaque().GetMap()[DepartmentOpaqueKey]; ok && e.Decoder == "plain" {
+		return string(e.Value)
+	}
+	return ""
+}
+
+// CountryOf returns the country of a user.
+func CountryOf(u *userpb.User) string {
+	if e, ok := u.GetOpaque().GetMap()[CountryOpaqueKey]; ok && e.Decoder == "plain" {
+		return string(e.Value)
+	}
+	return ""
+}
+
+// Has tells whether a user holds any of the given affiliations. An empty list
+// matches every user.
+func Has(u *userpb.User, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range Of(u) {
+		for _, b := range Normalize(allowed) {
+			if a == b {
+				return true
+			}
+		}
+	}
+	return false
+}
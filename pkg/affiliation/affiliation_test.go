@@ -0,0 +1,85 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package affiliation
+
+import (
+	"reflect"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+)
+
+func TestFromClaim(t *testing.T) {
+	tests := []struct {
+		claim interface{}
+		want  []string
+	}{
+		{"staff", []string{"staff"}},
+		{"staff, Member", []string{"staff", "member"}},
+		{[]interface{}{"Staff@cern.ch", "member@cern.ch", "staff"}, []string{"staff", "member"}},
+		{[]interface{}{1, "student"}, []string{"student"}},
+		{nil, nil},
+	}
+	for _, tt := range tests {
+		if got := FromClaim(tt.claim); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FromClaim(%v) = %v, want %v", tt.claim, got, tt.want)
+		}
+	}
+}
+
+func TestSetAndOf(t *testing.T) {
+	u := &userpb.User{}
+	Set(u, nil, "")
+	if u.Opaque != nil {
+		t.Fatal("expected no opaque for empty claims")
+	}
+
+	Set(u, []string{"staff", "member"}, "IT")
+	if got := Of(u); !reflect.DeepEqual(got, []string{"staff", "member"}) {
+		t.Errorf("Of() = %v", got)
+	}
+	if got := DepartmentOf(u); got != "IT" {
+		t.Errorf("DepartmentOf() = %s", got)
+	}
+	SetCountry(u, " ch ")
+	if got := CountryOf(u); got != "CH" {
+		t.Errorf("CountryOf() = %s", got)
+	}
+	if Of(&userpb.User{}) != nil || DepartmentOf(&userpb.User{}) != "" || CountryOf(&userpb.User{}) != "" {
+		t.Error("expected no claims for a user without opaque")
+	}
+}
+
+func TestHas(t *testing.T) {
+	u := &userpb.User{}
+	Set(u, []string{"staff"}, "")
+
+	if !Has(u, nil) {
+		t.Error("an empty list must match every user")
+	}
+	if !Has(u, []string{"student", "Staff"}) {
+		t.Error("expected staff to match")
+	}
+	if Has(u, []string{"student"}) {
+		t.Error("expected student not to match")
+	}
+	if Has(&userpb.User{}, []string{"staff"}) {
+		t.Error("a user without affiliations must not match")
+	}
+}
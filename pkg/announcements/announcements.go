@@ -0,0 +1,139 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package announcements provides the administrative announcements, like maintenance windows or
+// policy changes, which are broadcast to the users of the services for a period of time.
+package announcements
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// The severities of the announcements, from the least to the most severe.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+var severityRanks = map[string]int{
+	SeverityInfo:     0,
+	SeverityWarning:  1,
+	SeverityCritical: 2,
+}
+
+// Announcement is a message broadcast to the users.
+type Announcement struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+	Severity string `json:"severity"`
+	// Start and End delimit the period the announcement is shown in; a zero End shows it until it is removed.
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Author  string    `json:"author,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// Registry is the interface to implement registries of the announcements, shared by all
+// the services showing them.
+type Registry interface {
+	// ListAnnouncements returns all the announcements, including the ones not active.
+	ListAnnouncements(ctx context.Context) ([]*Announcement, error)
+	// StoreAnnouncement stores the announcement, replacing its previous version.
+	StoreAnnouncement(ctx context.Context, a *Announcement) error
+	// DeleteAnnouncement removes the announcement with the given ID; an error of type
+	// errtypes.NotFound is returned if it doesn't exist.
+	DeleteAnnouncement(ctx context.Context, id string) error
+}
+
+// New returns a new announcement with a random ID. If no start is given, the announcement
+// is shown right away.
+func New(title, message, severity string, start, end time.Time, author string) (*Announcement, error) {
+	now := time.Now()
+	if start.IsZero() {
+		start = now
+	}
+	if severity == "" {
+		severity = SeverityInfo
+	}
+	a := &Announcement{
+		ID:       uuid.New().String(),
+		Title:    strings.TrimSpace(title),
+		Message:  strings.TrimSpace(message),
+		Severity: strings.ToLower(severity),
+		Start:    start,
+		End:      end,
+		Author:   author,
+		Created:  now,
+	}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Validate checks whether the announcement is complete.
+func (a *Announcement) Validate() error {
+	if a.Title == "" {
+		return errors.New("announcements: no title given")
+	}
+	if a.Message == "" {
+		return errors.New("announcements: no message given")
+	}
+	if _, ok := severityRanks[a.Severity]; !ok {
+		return errors.Errorf("announcements: unknown severity %q", a.Severity)
+	}
+	if !a.End.IsZero() && !a.End.After(a.Start) {
+		return errors.New("announcements: the end lies before the start")
+	}
+	return nil
+}
+
+// IsActive tells whether the announcement is shown at the given time.
+func (a *Announcement) IsActive(t time.Time) bool {
+	return !t.Before(a.Start) && (a.End.IsZero() || t.Before(a.End))
+}
+
+// Active returns the announcements of the registry which are shown at the given time, the
+// most severe ones first and the most recent ones first within the same severity.
+func Active(ctx context.Context, r Registry, t time.Time) ([]*Announcement, error) {
+	list, err := r.ListAnnouncements(ctx)
+	if err != nil {
+		return nil, err
+	}
+	active := make([]*Announcement, 0, len(list))
+	for _, a := range list {
+		if a.IsActive(t) {
+			active = append(active, a)
+		}
+	}
+	sort.SliceStable(active, func(i, j int) bool {
+		if ri, rj := severityRanks[active[i].Severity], severityRanks[active[j].Severity]; ri != rj {
+			return ri > rj
+		}
+		return active[i].Start.After(active[j].Start)
+	})
+	return active, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package announcements
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type fakeRegistry struct {
+	announcements []*Announcement
+}
+
+func (r *fakeRegistry) ListAnnouncements(ctx context.Context) ([]*Announcement, error) {
+	return r.announcements, nil
+}
+
+func (r *fakeRegistry) StoreAnnouncement(ctx context.Context, a *Announcement) error {
+	r.announcements = append(r.announcements, a)
+	return nil
+}
+
+func (r *fakeRegistry) DeleteAnnouncement(ctx context.Context, id string) error {
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		title    string
+		severity string
+		start    time.Time
+		end      time.Time
+		valid    bool
+	}{
+		{"defaults", "Maintenance", "", time.Time{}, time.Time{}, true},
+		{"window", "Maintenance", "Warning", now.Add(time.Hour), now.Add(2 * time.Hour), true},
+		{"no title", " ", "info", time.Time{}, time.Time{}, false},
+		{"unknown severity", "Maintenance", "urgent", time.Time{}, time.Time{}, false},
+		{"end before start", "Maintenance", "info", now, now.Add(-time.Hour), false},
+	}
+	for _, tt := range tests {
+		a, err := New(tt.title, "The service will be down.", tt.severity, tt.start, tt.end, "admin")
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if tt.valid && (a.ID == "" || a.Start.IsZero() || a.Severity == "") {
+			t.Errorf("%s: expected the defaults to be set, got %+v", tt.name, a)
+		}
+	}
+}
+
+func TestActive(t *testing.T) {
+	now := time.Now()
+	r := &fakeRegistry{}
+	for _, a := range []*Announcement{
+		{ID: "past", Severity: SeverityCritical, Start: now.Add(-2 * time.Hour), End: now.Add(-time.Hour)},
+		{ID: "future", Severity: SeverityCritical, Start: now.Add(time.Hour)},
+		{ID: "info", Severity: SeverityInfo, Start: now.Add(-time.Hour)},
+		{ID: "old-warning", Severity: SeverityWarning, Start: now.Add(-2 * time.Hour), End: now.Add(time.Hour)},
+		{ID: "new-warning", Severity: SeverityWarning, Start: now.Add(-time.Minute)},
+	} {
+		_ = r.StoreAnnouncement(context.Background(), a)
+	}
+
+	active, err := Active(context.Background(), r, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"new-warning", "old-warning", "info"}
+	if len(active) != len(expected) {
+		t.Fatalf("got %d announcements, expected %d", len(active), len(expected))
+	}
+	for i, id := range expected {
+		if active[i].ID != id {
+			t.Errorf("announcement %d: got %s, expected %s", i, active[i].ID, id)
+		}
+	}
+}
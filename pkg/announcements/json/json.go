@@ -0,0 +1,145 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cs3org/reva/pkg/announcements"
+	"github.com/cs3org/reva/pkg/announcements/registry"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("json", New)
+}
+
+type config struct {
+	File string `mapstructure:"file"`
+}
+
+func (c *config) init() {
+	if c.File == "" {
+		c.File = "/var/tmp/reva/announcements.json"
+	}
+}
+
+type store struct {
+	sync.Mutex
+	file string
+}
+
+// New returns an announcement registry persisting the announcements in a JSON file. The file
+// is read on every access, so services on the same host (or sharing the file through a
+// network file system) see the announcements posted by each other.
+func New(m map[string]interface{}) (announcements.Registry, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	c.init()
+
+	if err := os.MkdirAll(filepath.Dir(c.File), 0700); err != nil {
+		return nil, errors.Wrap(err, "error creating the directory of the announcements file")
+	}
+	return &store{file: c.File}, nil
+}
+
+func (s *store) ListAnnouncements(ctx context.Context) ([]*announcements.Announcement, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.read()
+}
+
+func (s *store) StoreAnnouncement(ctx context.Context, a *announcements.Announcement) error {
+	s.Lock()
+	defer s.Unlock()
+	list, err := s.read()
+	if err != nil {
+		return err
+	}
+	for i, stored := range list {
+		if stored.ID == a.ID {
+			list[i] = a
+			return s.write(list)
+		}
+	}
+	return s.write(append(list, a))
+}
+
+func (s *store) DeleteAnnouncement(ctx context.Context, id string) error {
+	s.Lock()
+	defer s.Unlock()
+	list, err := s.read()
+	if err != nil {
+		return err
+	}
+	for i, stored := range list {
+		if stored.ID == id {
+			return s.write(append(list[:i], list[i+1:]...))
+		}
+	}
+	return errtypes.NotFound(id)
+}
+
+func (s *store) read() ([]*announcements.Announcement, error) {
+	data, err := ioutil.ReadFile(s.file)
+	if os.IsNotExist(err) {
+		return []*announcements.Announcement{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading the announcements file")
+	}
+	list := []*announcements.Announcement{}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, errors.Wrap(err, "error decoding the announcements file")
+	}
+	return list, nil
+}
+
+func (s *store) write(list []*announcements.Announcement) error {
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	// the file is replaced atomically, so readers in other processes never see it half written
+	tmp, err := ioutil.TempFile(filepath.Dir(s.file), filepath.Base(s.file)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "error writing the announcements file")
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "error writing the announcements file")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "error writing the announcements file")
+	}
+	if err := os.Rename(tmp.Name(), s.file); err != nil {
+		return errors.Wrap(err, "error writing the announcements file")
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/announcements"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "announcements")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "announcements.json")
+
+	r, err := New(map[string]interface{}{"file": file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if list, err := r.ListAnnouncements(ctx); err != nil || len(list) != 0 {
+		t.Fatalf("expected no announcements, got %v (%v)", list, err)
+	}
+
+	a, _ := announcements.New("Maintenance", "The service will be down.", announcements.SeverityWarning, time.Time{}, time.Time{}, "admin")
+	if err := r.StoreAnnouncement(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	a.Title = "Extended maintenance"
+	if err := r.StoreAnnouncement(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+
+	// another instance sharing the file sees the announcement
+	other, _ := New(map[string]interface{}{"file": file})
+	list, err := other.ListAnnouncements(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Title != "Extended maintenance" {
+		t.Fatalf("expected the updated announcement, got %v", list)
+	}
+
+	if err := other.DeleteAnnouncement(ctx, a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DeleteAnnouncement(ctx, a.ID); err == nil {
+		t.Fatal("expected an error deleting a missing announcement")
+	} else if _, ok := err.(errtypes.IsNotFound); !ok {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+}
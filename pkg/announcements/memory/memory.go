@@ -0,0 +1,74 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cs3org/reva/pkg/announcements"
+	"github.com/cs3org/reva/pkg/announcements/registry"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+func init() {
+	registry.Register("memory", New)
+}
+
+type store struct {
+	sync.RWMutex
+	announcements map[string]announcements.Announcement
+}
+
+// New returns an announcement registry keeping the announcements in memory. They are neither
+// shared with other instances nor persisted, so it is only meant for single-instance
+// deployments and tests.
+func New(m map[string]interface{}) (announcements.Registry, error) {
+	return &store{
+		announcements: map[string]announcements.Announcement{},
+	}, nil
+}
+
+func (s *store) ListAnnouncements(ctx context.Context) ([]*announcements.Announcement, error) {
+	s.RLock()
+	defer s.RUnlock()
+	list := make([]*announcements.Announcement, 0, len(s.announcements))
+	for _, a := range s.announcements {
+		a := a
+		list = append(list, &a)
+	}
+	return list, nil
+}
+
+func (s *store) StoreAnnouncement(ctx context.Context, a *announcements.Announcement) error {
+	s.Lock()
+	defer s.Unlock()
+	s.announcements[a.ID] = *a
+	return nil
+}
+
+func (s *store) DeleteAnnouncement(ctx context.Context, id string) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.announcements[id]; !ok {
+		return errtypes.NotFound(id)
+	}
+	delete(s.announcements, id)
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package antivirus defines the results of scanning files for viruses, which
+// keep infected files in quarantine until an admin releases or deletes them,
+// and the interface of the managers storing them.
+package antivirus
+
+import (
+	"context"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/utils/resourceid"
+)
+
+// Status is the outcome of the scan of a file.
+type Status string
+
+const (
+	// StatusPending marks files which have not been scanned yet.
+	StatusPending Status = "pending"
+	// StatusClean marks files in which no virus was found.
+	StatusClean Status = "clean"
+	// StatusInfected marks quarantined files, which cannot be downloaded.
+	StatusInfected Status = "infected"
+)
+
+const (
+	// StatusOpaqueKey is the key of the scan status in the opaque of a resource info.
+	StatusOpaqueKey = "scan_status"
+	// VirusOpaqueKey is the key of the name of the virus found in an infected file.
+	VirusOpaqueKey = "scan_virus"
+)
+
+// Result records the scan of a file.
+type Result struct {
+	ResourceID *provider.ResourceId `json:"resource_id"`
+	Path       string               `json:"path"`
+	Owner      *userpb.UserId       `json:"owner,omitempty"`
+	Status     Status               `json:"status"`
+	Virus      string               `json:"virus,omitempty"`
+	ScannedAt  int64                `json:"scanned_at,omitempty"`
+	// ReleasedBy is the admin who released the file from quarantine.
+	ReleasedBy *userpb.UserId `json:"released_by,omitempty"`
+	ReleasedAt int64          `json:"released_at,omitempty"`
+}
+
+// ID returns the key identifying the result, which is the wrapped resource id of the file.
+func (r *Result) ID() string {
+	return ID(r.ResourceID)
+}
+
+// Release marks an infected file as clean, for instance after a false positive.
+func (r *Result) Release(by *userpb.UserId) {
+	r.Status = StatusClean
+	r.ReleasedBy = by
+	r.ReleasedAt = time.Now().Unix()
+}
+
+// ID returns the key of the result of the given resource.
+func ID(id *provider.ResourceId) string {
+	return resourceid.OwnCloudResourceIDWrap(id)
+}
+
+// AddToOpaque adds the scan status of a file to the given opaque.
+func AddToOpaque(o *types.Opaque, r *Result) *types.Opaque {
+	if o == nil {
+		o = &types.Opaque{}
+	}
+	if o.Map == nil {
+		o.Map = map[string]*types.OpaqueEntry{}
+	}
+	o.Map[StatusOpaqueKey] = &types.OpaqueEntry{Decoder: "plain", Value: []byte(r.Status)}
+	if r.Status == StatusInfected && r.Virus != "" {
+		o.Map[VirusOpaqueKey] = &types.OpaqueEntry{Decoder: "plain", Value: []byte(r.Virus)}
+	} else {
+		delete(o.Map, VirusOpaqueKey)
+	}
+	return o
+}
+
+// StatusFromResourceInfo returns the scan status of a file, or an empty
+// status if the storage provider does not know about scans.
+func StatusFromResourceInfo(ri *provider.ResourceInfo) Status {
+	if e, ok := ri.GetOpaque().GetMap()[StatusOpaqueKey]; ok {
+		return Status(e.Value)
+	}
+	return ""
+}
+
+// VirusFromResourceInfo returns the name of the virus found in a file.
+func VirusFromResourceInfo(ri *provider.ResourceInfo) string {
+	if e, ok := ri.GetOpaque().GetMap()[VirusOpaqueKey]; ok {
+		return string(e.Value)
+	}
+	return ""
+}
+
+// Manager is the interface to implement to store scan results.
+type Manager interface {
+	// SetResult stores the result of a scan, replacing the previous one of the file.
+	SetResult(ctx context.Context, r *Result) error
+	// GetResult returns the result of the last scan of a file.
+	GetResult(ctx context.Context, id *provider.ResourceId) (*Result, error)
+	// GetResults returns the results of the last scans of the given files, indexed by their ID.
+	// The files which have not been scanned are left out.
+	GetResults(ctx context.Context, ids []*provider.ResourceId) (map[string]*Result, error)
+	// ListResults returns the results with the given status, all of them if the status is empty.
+	ListResults(ctx context.Context, status Status) ([]*Result, error)
+	// DeleteResult forgets the result of a file, e.g. after it has been deleted.
+	DeleteResult(ctx context.Context, id *provider.ResourceId) error
+}
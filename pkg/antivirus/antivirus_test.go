@@ -0,0 +1,51 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package antivirus
+
+import (
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+func TestOpaque(t *testing.T) {
+	ri := &provider.ResourceInfo{}
+	if StatusFromResourceInfo(ri) != "" {
+		t.Fatal("unscanned files must have no status")
+	}
+
+	r := &Result{ResourceID: &provider.ResourceId{StorageId: "storage", OpaqueId: "file"}, Status: StatusInfected, Virus: "Eicar-Test-Signature"}
+	ri.Opaque = AddToOpaque(ri.Opaque, r)
+	if StatusFromResourceInfo(ri) != StatusInfected || VirusFromResourceInfo(ri) != "Eicar-Test-Signature" {
+		t.Fatalf("unexpected scan status in %v", ri.Opaque)
+	}
+
+	r.Release(&userpb.UserId{OpaqueId: "admin"})
+	if r.Status != StatusClean || r.ReleasedAt == 0 {
+		t.Fatal("released file must be clean")
+	}
+	ri.Opaque = AddToOpaque(ri.Opaque, r)
+	if StatusFromResourceInfo(ri) != StatusClean || VirusFromResourceInfo(ri) != "" {
+		t.Fatalf("unexpected scan status in %v", ri.Opaque)
+	}
+	if r.ID() != "storage!file" {
+		t.Fatalf("unexpected id %s", r.ID())
+	}
+}
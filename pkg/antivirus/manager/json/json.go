@@ -0,0 +1,174 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/antivirus"
+	"github.com/cs3org/reva/pkg/antivirus/manager/registry"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("json", New)
+}
+
+type config struct {
+	File string `mapstructure:"file"`
+}
+
+func (c *config) init() {
+	if c.File == "" {
+		c.File = "/var/tmp/reva/antivirus.json"
+	}
+}
+
+type manager struct {
+	sync.Mutex
+	file string
+}
+
+// New returns a scan result manager storing the results in a JSON file. The
+// results are read on every access, as they are shared between the scanners
+// writing them, the storage providers enforcing the quarantine and the HTTP
+// service managing it.
+func New(m map[string]interface{}) (antivirus.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	c.init()
+
+	mgr := &manager{file: c.File}
+	if _, err := os.Stat(c.File); os.IsNotExist(err) {
+		if err := mgr.save(map[string]*antivirus.Result{}); err != nil {
+			return nil, err
+		}
+	}
+	return mgr, nil
+}
+
+func (m *manager) load() (map[string]*antivirus.Result, error) {
+	data, err := ioutil.ReadFile(m.file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading the file %s", m.file)
+	}
+	results := map[string]*antivirus.Result{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &results); err != nil {
+			return nil, errors.Wrapf(err, "error parsing the file %s", m.file)
+		}
+	}
+	return results, nil
+}
+
+func (m *manager) save(results map[string]*antivirus.Result) error {
+	data, err := json.Marshal(results)
+	if err != nil {
+		return errors.Wrap(err, "error encoding the scan results")
+	}
+	if err := ioutil.WriteFile(m.file, data, 0600); err != nil {
+		return errors.Wrapf(err, "error writing the file %s", m.file)
+	}
+	return nil
+}
+
+func (m *manager) SetResult(ctx context.Context, r *antivirus.Result) error {
+	m.Lock()
+	defer m.Unlock()
+
+	results, err := m.load()
+	if err != nil {
+		return err
+	}
+	results[r.ID()] = r
+	return m.save(results)
+}
+
+func (m *manager) GetResult(ctx context.Context, id *provider.ResourceId) (*antivirus.Result, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	results, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	r, ok := results[antivirus.ID(id)]
+	if !ok {
+		return nil, errtypes.NotFound(antivirus.ID(id))
+	}
+	return r, nil
+}
+
+func (m *manager) GetResults(ctx context.Context, ids []*provider.ResourceId) (map[string]*antivirus.Result, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	results, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]*antivirus.Result, len(ids))
+	for _, id := range ids {
+		if r, ok := results[antivirus.ID(id)]; ok {
+			found[r.ID()] = r
+		}
+	}
+	return found, nil
+}
+
+func (m *manager) ListResults(ctx context.Context, status antivirus.Status) ([]*antivirus.Result, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	results, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*antivirus.Result, 0, len(results))
+	for _, r := range results {
+		if status == "" || r.Status == status {
+			list = append(list, r)
+		}
+	}
+	return list, nil
+}
+
+func (m *manager) DeleteResult(ctx context.Context, id *provider.ResourceId) error {
+	m.Lock()
+	defer m.Unlock()
+
+	results, err := m.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := results[antivirus.ID(id)]; !ok {
+		return errtypes.NotFound(antivirus.ID(id))
+	}
+	delete(results, antivirus.ID(id))
+	return m.save(results)
+}
@@ -0,0 +1,165 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package clamd implements a virus scanner streaming the content to a clamd
+// daemon with the INSTREAM command.
+package clamd
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/antivirus/scanner"
+	"github.com/cs3org/reva/pkg/antivirus/scanner/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("clamd", New)
+}
+
+type config struct {
+	// Address of the daemon, either a unix socket path or a host:port.
+	Address string `mapstructure:"address" docs:"/var/run/clamav/clamd.ctl;The unix socket or TCP address of clamd."`
+	// ChunkSize is the size of the chunks the content is streamed in.
+	ChunkSize int `mapstructure:"chunk_size" docs:"65536;The size of the chunks the content is sent in. It must not exceed the StreamMaxLength of clamd."`
+	// Timeout is the time in seconds a scan may take.
+	Timeout int `mapstructure:"timeout" docs:"300;The number of seconds a scan may take."`
+}
+
+func (c *config) init() {
+	if c.Address == "" {
+		c.Address = "/var/run/clamav/clamd.ctl"
+	}
+	if c.ChunkSize <= 0 {
+		c.ChunkSize = 64 * 1024
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 300
+	}
+}
+
+type clamd struct {
+	c *config
+}
+
+// New returns a scanner talking to a clamd daemon.
+func New(m map[string]interface{}) (scanner.Scanner, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	c.init()
+	return &clamd{c: c}, nil
+}
+
+func (s *clamd) dial(ctx context.Context) (net.Conn, error) {
+	network := "tcp"
+	if strings.HasPrefix(s.c.Address, "/") {
+		network = "unix"
+	}
+	d := net.Dialer{}
+	return d.DialContext(ctx, network, s.c.Address)
+}
+
+// Scan sends the content in length prefixed chunks, terminated by an empty
+// chunk, and parses the reply, e.g. "stream: Eicar-Test-Signature FOUND".
+func (s *clamd) Scan(ctx context.Context, r io.Reader) (*scanner.Result, error) {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "clamd: error connecting")
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(time.Duration(s.c.Timeout) * time.Second)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	// the replies are read concurrently, as clamd answers and closes the
+	// connection early when the stream is too large
+	replies := make(chan string, 1)
+	go func() {
+		reply, _ := bufio.NewReader(conn).ReadString(0)
+		replies <- strings.TrimRight(reply, "\x00\n")
+	}()
+
+	if err := s.send(conn, r); err != nil {
+		select {
+		case reply := <-replies:
+			if reply != "" {
+				return parseReply(reply)
+			}
+		case <-time.After(time.Second):
+		}
+		return nil, errors.Wrap(err, "clamd: error streaming the content")
+	}
+	return parseReply(<-replies)
+}
+
+func (s *clamd) send(w io.Writer, r io.Reader) error {
+	if _, err := io.WriteString(w, "zINSTREAM\x00"); err != nil {
+		return err
+	}
+	buf := make([]byte, 4+s.c.ChunkSize)
+	for {
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, werr := w.Write(buf[:4+n]); werr != nil {
+				return werr
+			}
+		}
+		switch err {
+		case nil:
+		case io.EOF, io.ErrUnexpectedEOF:
+			_, err = w.Write([]byte{0, 0, 0, 0})
+			return err
+		default:
+			return err
+		}
+	}
+}
+
+func parseReply(reply string) (*scanner.Result, error) {
+	// the reply is prefixed with the name of the scanned stream
+	verdict := reply
+	if i := strings.Index(reply, ": "); i >= 0 {
+		verdict = reply[i+2:]
+	}
+	switch {
+	case verdict == "OK":
+		return &scanner.Result{}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return &scanner.Result{Infected: true, Virus: strings.TrimSuffix(verdict, " FOUND")}, nil
+	case reply == "":
+		return nil, errors.New("clamd: no reply")
+	default:
+		return nil, fmt.Errorf("clamd: %s", reply)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package clamd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// serve answers one INSTREAM request with the verdict on the received content.
+func serve(t *testing.T, l net.Listener) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	cmd, err := r.ReadString(0)
+	if err != nil || cmd != "zINSTREAM\x00" {
+		t.Errorf("unexpected command %q", cmd)
+		return
+	}
+	content := bytes.Buffer{}
+	for {
+		var size uint32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			t.Error(err)
+			return
+		}
+		if size == 0 {
+			break
+		}
+		if _, err := io.CopyN(&content, r, int64(size)); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	if strings.Contains(content.String(), "EICAR") {
+		io.WriteString(conn, "stream: Eicar-Test-Signature FOUND\x00")
+	} else {
+		io.WriteString(conn, "stream: OK\x00")
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+		virus   string
+	}{
+		{"clean content spanning several chunks", false, ""},
+		{"some EICAR test file", true, "Eicar-Test-Signature"},
+	}
+	for _, tt := range tests {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go serve(t, l)
+
+		s, err := New(map[string]interface{}{"address": l.Addr().String(), "chunk_size": 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := s.Scan(context.Background(), strings.NewReader(tt.content))
+		l.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Infected != tt.want || res.Virus != tt.virus {
+			t.Errorf("Scan(%q) = %+v, want infected %v with %q", tt.content, res, tt.want, tt.virus)
+		}
+	}
+}
+
+func TestParseReply(t *testing.T) {
+	if _, err := parseReply("INSTREAM size limit exceeded. ERROR"); err == nil {
+		t.Error("expected an error")
+	}
+}
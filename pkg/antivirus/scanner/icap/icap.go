@@ -0,0 +1,202 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package icap implements a virus scanner sending the content to an ICAP
+// server (RFC 3507) in a RESPMOD request.
+package icap
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/antivirus/scanner"
+	"github.com/cs3org/reva/pkg/antivirus/scanner/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("icap", New)
+}
+
+// the encapsulated HTTP response headers the content is sent with
+const resHeader = "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n"
+
+// the headers ICAP servers report the viruses found in
+var virusHeaders = []string{"X-Infection-Found", "X-Violations-Found", "X-Virus-Id"}
+
+type config struct {
+	// URL of the scan service, e.g. icap://localhost:1344/avscan.
+	URL string `mapstructure:"url" docs:"icap://localhost:1344/avscan;The URL of the ICAP service."`
+	// ChunkSize is the size of the chunks the content is streamed in.
+	ChunkSize int `mapstructure:"chunk_size" docs:"65536;The size of the chunks the content is sent in."`
+	// Timeout is the time in seconds a scan may take.
+	Timeout int `mapstructure:"timeout" docs:"300;The number of seconds a scan may take."`
+}
+
+func (c *config) init() {
+	if c.URL == "" {
+		c.URL = "icap://localhost:1344/avscan"
+	}
+	if c.ChunkSize <= 0 {
+		c.ChunkSize = 64 * 1024
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 300
+	}
+}
+
+type icap struct {
+	c    *config
+	host string
+}
+
+// New returns a scanner talking to an ICAP server.
+func New(m map[string]interface{}) (scanner.Scanner, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	c.init()
+
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, "icap: invalid url")
+	}
+	if u.Scheme != "icap" {
+		return nil, fmt.Errorf("icap: unsupported scheme %q", u.Scheme)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "1344")
+	}
+	return &icap{c: c, host: host}, nil
+}
+
+// Scan sends the content as the body of an encapsulated HTTP response. The
+// server answers 204 if it has no objections and describes the virus in the
+// headers of its answer otherwise.
+func (s *icap) Scan(ctx context.Context, r io.Reader) (*scanner.Result, error) {
+	d := net.Dialer{}
+	conn, err := d.DialContext(ctx, "tcp", s.host)
+	if err != nil {
+		return nil, errors.Wrap(err, "icap: error connecting")
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(time.Duration(s.c.Timeout) * time.Second)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "RESPMOD %s ICAP/1.0\r\n", s.c.URL)
+	fmt.Fprintf(w, "Host: %s\r\n", s.host)
+	fmt.Fprintf(w, "Allow: 204\r\n")
+	fmt.Fprintf(w, "Encapsulated: res-hdr=0, res-body=%d\r\n\r\n", len(resHeader))
+	if _, err := w.WriteString(resHeader); err != nil {
+		return nil, errors.Wrap(err, "icap: error sending the request")
+	}
+	if err := s.sendBody(w, r); err != nil {
+		return nil, errors.Wrap(err, "icap: error streaming the content")
+	}
+
+	tp := textproto.NewReader(bufio.NewReader(conn))
+	line, err := tp.ReadLine()
+	if err != nil {
+		return nil, errors.Wrap(err, "icap: error reading the response")
+	}
+	header, err := tp.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "icap: error reading the response headers")
+	}
+	return parseResponse(line, header)
+}
+
+func (s *icap) sendBody(w *bufio.Writer, r io.Reader) error {
+	// room for the CRLF terminating the chunk
+	buf := make([]byte, s.c.ChunkSize+2)
+	for {
+		n, err := r.Read(buf[:s.c.ChunkSize])
+		if n > 0 {
+			fmt.Fprintf(w, "%x\r\n", n)
+			if _, werr := w.Write(append(buf[:n], '\r', '\n')); werr != nil {
+				return werr
+			}
+		}
+		switch err {
+		case nil:
+		case io.EOF:
+			if _, err := w.WriteString("0\r\n\r\n"); err != nil {
+				return err
+			}
+			return w.Flush()
+		default:
+			return err
+		}
+	}
+}
+
+func parseResponse(line string, header textproto.MIMEHeader) (*scanner.Result, error) {
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) < 2 || !strings.HasPrefix(parts[0], "ICAP/") {
+		return nil, fmt.Errorf("icap: malformed status line %q", line)
+	}
+	code, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("icap: malformed status line %q", line)
+	}
+
+	switch code {
+	case 204:
+		return &scanner.Result{}, nil
+	case 200:
+		for _, h := range virusHeaders {
+			if v := header.Get(h); v != "" {
+				return &scanner.Result{Infected: true, Virus: virusName(v)}, nil
+			}
+		}
+		// the content was modified, e.g. replaced by a block page
+		return &scanner.Result{Infected: true}, nil
+	default:
+		return nil, fmt.Errorf("icap: unexpected status %q", line)
+	}
+}
+
+// virusName extracts the threat from headers like
+// "Type=0; Resolution=2; Threat=Eicar-Test-Signature;".
+func virusName(v string) string {
+	for _, field := range strings.Split(v, ";") {
+		field = strings.TrimSpace(field)
+		if strings.HasPrefix(field, "Threat=") {
+			return strings.TrimPrefix(field, "Threat=")
+		}
+	}
+	return strings.TrimSpace(v)
+}
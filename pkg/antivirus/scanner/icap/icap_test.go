@@ -0,0 +1,96 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package icap
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// serve answers one RESPMOD request with the verdict on the received content.
+func serve(t *testing.T, l net.Listener) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	tp := textproto.NewReader(r)
+	if line, err := tp.ReadLine(); err != nil || !strings.HasPrefix(line, "RESPMOD icap://") {
+		t.Errorf("unexpected request %q", line)
+		return
+	}
+	if _, err := tp.ReadMIMEHeader(); err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	content, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if strings.Contains(string(content), "EICAR") {
+		io.WriteString(conn, "ICAP/1.0 200 OK\r\nX-Infection-Found: Type=0; Resolution=2; Threat=Eicar-Test-Signature;\r\nEncapsulated: null-body=0\r\n\r\n")
+	} else {
+		io.WriteString(conn, "ICAP/1.0 204 No Content\r\nEncapsulated: null-body=0\r\n\r\n")
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+		virus   string
+	}{
+		{"clean content spanning several chunks", false, ""},
+		{"some EICAR test file", true, "Eicar-Test-Signature"},
+	}
+	for _, tt := range tests {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go serve(t, l)
+
+		s, err := New(map[string]interface{}{"url": "icap://" + l.Addr().String() + "/avscan", "chunk_size": 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := s.Scan(context.Background(), strings.NewReader(tt.content))
+		l.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Infected != tt.want || res.Virus != tt.virus {
+			t.Errorf("Scan(%q) = %+v, want infected %v with %q", tt.content, res, tt.want, tt.virus)
+		}
+	}
+}
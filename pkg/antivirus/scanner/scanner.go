@@ -0,0 +1,40 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package scanner defines the interface of the engines scanning the content
+// of uploaded files for viruses.
+package scanner
+
+import (
+	"context"
+	"io"
+)
+
+// Result is the verdict of a scanner on some content.
+type Result struct {
+	// Infected is set when a virus was found.
+	Infected bool
+	// Virus is the name of the virus found, if the engine reports it.
+	Virus string
+}
+
+// Scanner is the interface to implement to plug in a virus scanning engine.
+type Scanner interface {
+	// Scan streams the content to the engine and returns its verdict.
+	Scan(ctx context.Context, r io.Reader) (*Result, error)
+}
@@ -0,0 +1,409 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package apispec describes the APIs exposed by a reva process, so that
+// typed clients matching the deployed version can be generated: the
+// protobuf descriptors of its gRPC services and an OpenAPI document of the
+// HTTP services that register a description of their endpoints.
+package apispec
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// The security schemes the endpoints can accept besides the reva token.
+const (
+	// SchemeBasic is the HTTP basic authentication.
+	SchemeBasic = "basicAuth"
+	// SchemeBearer is a bearer token in the Authorization header.
+	SchemeBearer = "bearerAuth"
+	// SchemeAPIKey is an API key sent in the X-API-Key header.
+	SchemeAPIKey = "apiKey"
+
+	schemeToken = "revaToken"
+)
+
+// Parameter describes a query, header or path parameter of an operation.
+type Parameter struct {
+	Name string
+	// In is either query, header or path; the parameters enclosed in braces
+	// in the path of an operation don't need to be listed.
+	In          string
+	Description string
+	Required    bool
+	// Type is the JSON schema type of the parameter, string by default.
+	Type string
+}
+
+// Response describes a response of an operation.
+type Response struct {
+	Status      int
+	Description string
+	// ContentType is the type of the body, empty if there is none.
+	ContentType string
+}
+
+// Operation describes an endpoint of an HTTP service.
+type Operation struct {
+	Method string
+	// Path is relative to the prefix of the service.
+	Path        string
+	Summary     string
+	Parameters  []Parameter
+	RequestType string
+	Responses   []Response
+	// Public marks the endpoints that can be called without a reva token.
+	Public bool
+	// Security lists the schemes accepted besides the reva token.
+	Security []string
+}
+
+// Service is the description of an HTTP service.
+type Service struct {
+	Name string
+	// Prefix is the default prefix of the service.
+	Prefix      string
+	Description string
+	Operations  []Operation
+}
+
+var (
+	mutex    sync.RWMutex
+	services = map[string]*Service{}
+	deployed = map[string]string{}
+)
+
+// Register registers the description of an HTTP service.
+// It is meant to be called in the init function of the service, next to its
+// registration in the global registry.
+func Register(s *Service) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	services[s.Name] = s
+}
+
+// AddHTTPService records an HTTP service enabled in this process and the
+// prefix it is served at.
+func AddHTTPService(name, prefix string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	deployed[name] = prefix
+}
+
+// Deployed returns the HTTP services enabled in this process by prefix.
+func Deployed() map[string]string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	m := make(map[string]string, len(deployed))
+	for name, prefix := range deployed {
+		m[name] = prefix
+	}
+	return m
+}
+
+// Registered returns all the described HTTP services by their default prefix.
+func Registered() map[string]string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	m := make(map[string]string, len(services))
+	for name, s := range services {
+		m[name] = s.Prefix
+	}
+	return m
+}
+
+// Document is an OpenAPI 3.0 document.
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Tags       []Tag                            `json:"tags,omitempty"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components components                       `json:"components"`
+	Security   []map[string][]string            `json:"security"`
+
+	// Undescribed lists the HTTP services which don't describe their endpoints,
+	// like the WebDAV and OCS ones implementing well-known protocols.
+	Undescribed []string `json:"x-reva-undescribed-services,omitempty"`
+}
+
+// Info holds the metadata of an OpenAPI document.
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Tag groups the operations of a service.
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags"`
+	Parameters  []*parameter          `json:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *schema `json:"schema"`
+}
+
+type schema struct {
+	Type   string `json:"type,omitempty"`
+	Format string `json:"format,omitempty"`
+}
+
+type requestBody struct {
+	Content map[string]*mediaType `json:"content"`
+}
+
+type response struct {
+	Description string                `json:"description"`
+	Content     map[string]*mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type components struct {
+	SecuritySchemes map[string]*securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+var securitySchemes = map[string]*securityScheme{
+	schemeToken:  {Type: "apiKey", In: "header", Name: "X-Access-Token"},
+	SchemeBasic:  {Type: "http", Scheme: "basic"},
+	SchemeBearer: {Type: "http", Scheme: "bearer"},
+	SchemeAPIKey: {Type: "apiKey", In: "header", Name: "X-API-Key"},
+}
+
+var pathParameter = regexp.MustCompile(`{([^}]+)}`)
+
+// OpenAPI returns the OpenAPI document of the given HTTP services, passed by
+// name with the prefix they are served at.
+func OpenAPI(version string, prefixes map[string]string) *Document {
+	if version == "" {
+		version = "unknown"
+	}
+	doc := &Document{
+		OpenAPI:    "3.0.3",
+		Info:       Info{Title: "reva", Version: version},
+		Paths:      map[string]map[string]*operation{},
+		Components: components{SecuritySchemes: securitySchemes},
+		Security:   []map[string][]string{{schemeToken: {}}},
+	}
+
+	names := make([]string, 0, len(prefixes))
+	for name := range prefixes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	mutex.RLock()
+	defer mutex.RUnlock()
+	for _, name := range names {
+		s, ok := services[name]
+		if !ok {
+			doc.Undescribed = append(doc.Undescribed, name)
+			continue
+		}
+		doc.Tags = append(doc.Tags, Tag{Name: name, Description: s.Description})
+		for _, op := range s.Operations {
+			p := joinPath(prefixes[name], op.Path)
+			if doc.Paths[p] == nil {
+				doc.Paths[p] = map[string]*operation{}
+			}
+			doc.Paths[p][strings.ToLower(op.Method)] = newOperation(name, p, op)
+		}
+	}
+	return doc
+}
+
+// JSON returns the indented JSON encoding of the document.
+func (d *Document) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+func joinPath(prefix, p string) string {
+	joined := path.Join("/", prefix, p)
+	if strings.HasSuffix(p, "/") && joined != "/" {
+		joined += "/"
+	}
+	return joined
+}
+
+func newOperation(service, p string, op Operation) *operation {
+	o := &operation{
+		OperationID: operationID(service, op.Method, p),
+		Summary:     op.Summary,
+		Tags:        []string{service},
+		Responses:   map[string]*response{},
+	}
+
+	for _, m := range pathParameter.FindAllStringSubmatch(p, -1) {
+		o.Parameters = append(o.Parameters, &parameter{Name: m[1], In: "path", Required: true, Schema: &schema{Type: "string"}})
+	}
+	for _, param := range op.Parameters {
+		t := param.Type
+		if t == "" {
+			t = "string"
+		}
+		o.Parameters = append(o.Parameters, &parameter{Name: param.Name, In: param.In, Description: param.Description, Required: param.Required, Schema: &schema{Type: t}})
+	}
+
+	if op.RequestType != "" {
+		o.RequestBody = &requestBody{Content: content(op.RequestType)}
+	}
+
+	responses := op.Responses
+	if len(responses) == 0 {
+		responses = []Response{{Status: http.StatusOK}}
+	}
+	for _, r := range responses {
+		desc := r.Description
+		if desc == "" {
+			desc = http.StatusText(r.Status)
+		}
+		o.Responses[strconv.Itoa(r.Status)] = &response{Description: desc, Content: content(r.ContentType)}
+	}
+
+	// an empty requirement makes the security of an operation optional
+	switch {
+	case op.Public && len(op.Security) == 0:
+		o.Security = []map[string][]string{{}}
+	case len(op.Security) > 0:
+		if !op.Public {
+			o.Security = append(o.Security, map[string][]string{schemeToken: {}})
+		}
+		for _, scheme := range op.Security {
+			o.Security = append(o.Security, map[string][]string{scheme: {}})
+		}
+	}
+	return o
+}
+
+func content(contentType string) map[string]*mediaType {
+	if contentType == "" {
+		return nil
+	}
+	s := &schema{}
+	switch {
+	case strings.HasSuffix(contentType, "json"):
+		s.Type = "object"
+	case strings.HasPrefix(contentType, "text/"):
+		s.Type = "string"
+	default:
+		s.Type, s.Format = "string", "binary"
+	}
+	return map[string]*mediaType{contentType: {Schema: s}}
+}
+
+func operationID(service, method, p string) string {
+	id := service + "_" + strings.ToLower(method)
+	for _, part := range strings.FieldsFunc(p, func(r rune) bool { return !isIdentifier(r) }) {
+		id += "_" + part
+	}
+	return id
+}
+
+func isIdentifier(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+// GRPCServices returns the full names of all the gRPC services linked into
+// this binary.
+func GRPCServices() []string {
+	var names []string
+	protoregistry.GlobalFiles.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		for i := 0; i < fd.Services().Len(); i++ {
+			names = append(names, string(fd.Services().Get(i).FullName()))
+		}
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// Descriptors returns the serialized FileDescriptorSet of the files defining
+// the given gRPC services, including all the files they import, with every
+// file listed after its dependencies like protoc --include_imports does.
+// The services whose descriptors aren't linked into the binary are returned
+// as missing.
+func Descriptors(names []string) (set []byte, missing []string, err error) {
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+
+	fds := &descriptorpb.FileDescriptorSet{}
+	seen := map[string]bool{}
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] || fd.IsPlaceholder() {
+			return
+		}
+		seen[fd.Path()] = true
+		for i := 0; i < fd.Imports().Len(); i++ {
+			add(fd.Imports().Get(i).FileDescriptor)
+		}
+		fds.File = append(fds.File, protodesc.ToFileDescriptorProto(fd))
+	}
+
+	for _, name := range sorted {
+		d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			missing = append(missing, name)
+			continue
+		}
+		sd, ok := d.(protoreflect.ServiceDescriptor)
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		add(sd.ParentFile())
+	}
+
+	set, err = proto.MarshalOptions{Deterministic: true}.Marshal(fds)
+	return set, missing, err
+}
@@ -0,0 +1,120 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package apispec
+
+import (
+	"encoding/json"
+	"testing"
+
+	_ "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestOpenAPI(t *testing.T) {
+	Register(&Service{
+		Name:        "testsvc",
+		Prefix:      "test",
+		Description: "A test service",
+		Operations: []Operation{
+			{Method: "GET", Path: "/", Summary: "Lists things", Parameters: []Parameter{{Name: "limit", In: "query", Type: "integer"}}, Responses: []Response{{Status: 200, ContentType: "application/json"}}},
+			{Method: "DELETE", Path: "/things/{id}", Responses: []Response{{Status: 204}}},
+			{Method: "GET", Path: "/public", Public: true},
+			{Method: "GET", Path: "/keyed", Public: true, Security: []string{SchemeAPIKey}},
+		},
+	})
+
+	doc := OpenAPI("", map[string]string{"testsvc": "api/test", "ocdav": "remote.php/webdav"})
+	if doc.Info.Version != "unknown" {
+		t.Errorf("expected an unknown version, got %q", doc.Info.Version)
+	}
+	if len(doc.Undescribed) != 1 || doc.Undescribed[0] != "ocdav" {
+		t.Errorf("expected ocdav to be undescribed, got %v", doc.Undescribed)
+	}
+
+	list := doc.Paths["/api/test/"]["get"]
+	if list == nil {
+		t.Fatalf("expected the operations to be prefixed, got %v", doc.Paths)
+	}
+	if list.OperationID != "testsvc_get_api_test" || len(list.Parameters) != 1 || list.Parameters[0].Schema.Type != "integer" {
+		t.Errorf("unexpected operation %+v", list)
+	}
+	if list.Security != nil {
+		t.Error("expected the default security to apply")
+	}
+
+	del := doc.Paths["/api/test/things/{id}"]["delete"]
+	if del == nil || len(del.Parameters) != 1 || del.Parameters[0].In != "path" || !del.Parameters[0].Required {
+		t.Fatalf("expected the path parameter to be described, got %+v", del)
+	}
+	if _, ok := del.Responses["204"]; !ok {
+		t.Error("expected a 204 response")
+	}
+
+	if sec := doc.Paths["/api/test/public"]["get"].Security; len(sec) != 1 || len(sec[0]) != 0 {
+		t.Errorf("expected the public endpoint to be callable anonymously, got %v", sec)
+	}
+	if sec := doc.Paths["/api/test/keyed"]["get"].Security; len(sec) != 1 || sec[0][SchemeAPIKey] == nil {
+		t.Errorf("expected the endpoint to accept an API key only, got %v", sec)
+	}
+
+	data, err := doc.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["openapi"] != "3.0.3" {
+		t.Errorf("unexpected document %s", data)
+	}
+}
+
+func TestDescriptors(t *testing.T) {
+	data, missing, err := Descriptors([]string{"cs3.gateway.v1beta1.GatewayAPI", "bogus.Service"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 1 || missing[0] != "bogus.Service" {
+		t.Errorf("expected the unknown service to be missing, got %v", missing)
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, set); err != nil {
+		t.Fatal(err)
+	}
+	if len(set.File) < 2 || set.File[len(set.File)-1].GetName() != "cs3/gateway/v1beta1/gateway_api.proto" {
+		t.Fatal("expected the file of the service to come last")
+	}
+	// every file must come after its dependencies
+	seen := map[string]bool{}
+	for _, f := range set.File {
+		for _, dep := range f.Dependency {
+			if !seen[dep] {
+				t.Fatalf("%s is listed before its dependency %s", f.GetName(), dep)
+			}
+		}
+		seen[f.GetName()] = true
+	}
+
+	if again, _, _ := Descriptors([]string{"bogus.Service", "cs3.gateway.v1beta1.GatewayAPI"}); string(again) != string(data) {
+		t.Error("expected the descriptor set to be deterministic")
+	}
+}
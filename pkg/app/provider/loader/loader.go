@@ -22,5 +22,6 @@ import (
 	// Load core application providers.
 	_ "github.com/cs3org/reva/pkg/app/provider/demo"
 	_ "github.com/cs3org/reva/pkg/app/provider/wopi"
+	_ "github.com/cs3org/reva/pkg/app/provider/wopihost"
 	// Add your own here
 )
@@ -23,7 +23,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -33,7 +32,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/beevik/etree"
 	appprovider "github.com/cs3org/go-cs3apis/cs3/app/provider/v1beta1"
 	appregistry "github.com/cs3org/go-cs3apis/cs3/app/registry/v1beta1"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
@@ -46,6 +44,7 @@ import (
 	"github.com/cs3org/reva/pkg/mime"
 	"github.com/cs3org/reva/pkg/rhttp"
 	"github.com/cs3org/reva/pkg/sharedconf"
+	wopipkg "github.com/cs3org/reva/pkg/wopi"
 	"github.com/golang-jwt/jwt"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
@@ -269,19 +268,7 @@ func (p *wopiProvider) GetAppURL(ctx context.Context, resource *provider.Resourc
 }
 
 func (p *wopiProvider) GetAppProviderInfo(ctx context.Context) (*appregistry.ProviderInfo, error) {
-	// Initially we store the mime types in a map to avoid duplicates
-	mimeTypesMap := make(map[string]bool)
-	for _, extensions := range p.appURLs {
-		for ext := range extensions {
-			m := mime.Detect(false, ext)
-			mimeTypesMap[m] = true
-		}
-	}
-
-	mimeTypes := make([]string, 0, len(mimeTypesMap))
-	for m := range mimeTypesMap {
-		mimeTypes = append(mimeTypes, m)
-	}
+	mimeTypes := wopipkg.MimeTypes(p.appURLs)
 
 	return &appregistry.ProviderInfo{
 		Name:        p.conf.AppName,
@@ -338,7 +325,7 @@ func getAppURLs(c *config) (map[string]map[string]string, error) {
 	var appURLs map[string]map[string]string
 
 	if discRes.StatusCode == http.StatusOK {
-		appURLs, err = parseWopiDiscovery(discRes.Body)
+		appURLs, err = wopipkg.ParseDiscovery(discRes.Body)
 		if err != nil {
 			return nil, errors.Wrap(err, "error parsing wopi discovery response")
 		}
@@ -396,59 +383,6 @@ func (p *wopiProvider) getAccessTokenTTL(ctx context.Context) (string, error) {
 	return "", errtypes.InvalidCredentials("wopi: invalid token present in ctx")
 }
 
-func parseWopiDiscovery(body io.Reader) (map[string]map[string]string, error) {
-	appURLs := make(map[string]map[string]string)
-
-	doc := etree.NewDocument()
-	if _, err := doc.ReadFrom(body); err != nil {
-		return nil, err
-	}
-	root := doc.SelectElement("wopi-discovery")
-
-	for _, netzone := range root.SelectElements("net-zone") {
-
-		if strings.Contains(netzone.SelectAttrValue("name", ""), "external") {
-			for _, app := range netzone.SelectElements("app") {
-				for _, action := range app.SelectElements("action") {
-					access := action.SelectAttrValue("name", "")
-					if access == "view" || access == "edit" || access == "editnew" {
-						ext := action.SelectAttrValue("ext", "")
-						urlString := action.SelectAttrValue("urlsrc", "")
-
-						if ext == "" || urlString == "" {
-							continue
-						}
-
-						u, err := url.Parse(urlString)
-						if err != nil {
-							// it sucks we cannot log here because this function is run
-							// on init without any context.
-							// TODO(labkode): add logging when we'll have static logging in boot phase.
-							continue
-						}
-
-						// remove any malformed query parameter from discovery urls
-						q := u.Query()
-						for k := range q {
-							if strings.Contains(k, "<") || strings.Contains(k, ">") {
-								q.Del(k)
-							}
-						}
-
-						u.RawQuery = q.Encode()
-
-						if _, ok := appURLs[access]; !ok {
-							appURLs[access] = make(map[string]string)
-						}
-						appURLs[access]["."+ext] = u.String()
-					}
-				}
-			}
-		}
-	}
-	return appURLs, nil
-}
-
 func getCodimdExtensions(appURL string) map[string]map[string]string {
 	// Register custom mime types
 	mime.RegisterMime(".zmd", "application/compressed-markdown")
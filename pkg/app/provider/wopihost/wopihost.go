@@ -0,0 +1,194 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package wopihost
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"time"
+
+	appprovider "github.com/cs3org/go-cs3apis/cs3/app/provider/v1beta1"
+	appregistry "github.com/cs3org/go-cs3apis/cs3/app/registry/v1beta1"
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/app"
+	"github.com/cs3org/reva/pkg/app/provider/registry"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/token"
+	tokenmgr "github.com/cs3org/reva/pkg/token/manager/registry"
+	"github.com/cs3org/reva/pkg/wopi"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("wopihost", New)
+}
+
+type config struct {
+	AppName             string                            `mapstructure:"app_name" docs:";The App user-friendly name."`
+	AppIconURI          string                            `mapstructure:"app_icon_uri" docs:";A URI to a static asset which represents the app icon."`
+	AppURL              string                            `mapstructure:"app_url" docs:";The App URL."`
+	AppIntURL           string                            `mapstructure:"app_int_url" docs:";The internal app URL in case of dockerized deployments. Defaults to AppURL"`
+	AppDesktopOnly      bool                              `mapstructure:"app_desktop_only" docs:"false;Specifies if the app can be opened only on desktop."`
+	InsecureConnections bool                              `mapstructure:"insecure_connections" docs:"false;Whether to skip certificate checks when querying the app discovery."`
+	WopiHostURL         string                            `mapstructure:"wopi_host_url" docs:";The public URL of the reva wopi HTTP service, as reached by the app."`
+	AccessTokenTTL      int64                             `mapstructure:"access_token_ttl" docs:"86400;The validity in seconds advertised to the app for the access tokens. It must match the expiration of the token manager."`
+	TokenManager        string                            `mapstructure:"token_manager" docs:"jwt;The token manager used to mint the access tokens handed to the app."`
+	TokenManagers       map[string]map[string]interface{} `mapstructure:"token_managers"`
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *config) init() {
+	if c.AppIntURL == "" {
+		c.AppIntURL = c.AppURL
+	}
+	if c.AccessTokenTTL == 0 {
+		c.AccessTokenTTL = 86400
+	}
+	if c.TokenManager == "" {
+		c.TokenManager = "jwt"
+	}
+}
+
+type wopiHostProvider struct {
+	conf     *config
+	tokenmgr token.Manager
+	appURLs  map[string]map[string]string // map[viewMode]map[extension]appURL
+}
+
+// New returns an implementation of the app.Provider interface that opens
+// the documents in a WOPI application using reva itself as the WOPI host,
+// see the wopi HTTP service.
+func New(m map[string]interface{}) (app.Provider, error) {
+	c, err := parseConfig(m)
+	if err != nil {
+		return nil, err
+	}
+	c.init()
+
+	if c.WopiHostURL == "" {
+		return nil, errors.New("wopihost: wopi_host_url must be configured")
+	}
+
+	f, ok := tokenmgr.NewFuncs[c.TokenManager]
+	if !ok {
+		return nil, fmt.Errorf("wopihost: token manager not found: %s", c.TokenManager)
+	}
+	tm, err := f(c.TokenManagers[c.TokenManager])
+	if err != nil {
+		return nil, errors.Wrap(err, "wopihost: error creating token manager")
+	}
+
+	appURLs, err := wopi.GetDiscovery(c.AppIntURL, c.InsecureConnections)
+	if err != nil {
+		return nil, errors.Wrap(err, "wopihost: error querying the app discovery")
+	}
+
+	return &wopiHostProvider{
+		conf:     c,
+		tokenmgr: tm,
+		appURLs:  appURLs,
+	}, nil
+}
+
+func (p *wopiHostProvider) GetAppURL(ctx context.Context, resource *provider.ResourceInfo, viewMode appprovider.OpenInAppRequest_ViewMode, _ string) (*appprovider.OpenInAppURL, error) {
+	log := appctx.GetLogger(ctx)
+
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		return nil, errtypes.UserRequired("wopihost: no user in ctx")
+	}
+
+	actionURL, role := p.actionURL(path.Ext(resource.Path), resource.GetSize(), viewMode)
+	if actionURL == "" {
+		return nil, errtypes.NotSupported("wopihost: no app url found for " + resource.Path)
+	}
+
+	wopiSrc, err := wopi.SrcURL(p.conf.WopiHostURL, resource.Id)
+	if err != nil {
+		return nil, err
+	}
+	appURL, err := wopi.AppURL(actionURL, wopiSrc)
+	if err != nil {
+		return nil, err
+	}
+
+	// the token handed to the app only grants access to the opened resource
+	scopes, err := scope.AddResourceInfoScope(resource, role, nil)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := p.tokenmgr.MintToken(ctx, u, scopes)
+	if err != nil {
+		return nil, errors.Wrap(err, "wopihost: error minting access token")
+	}
+	// milliseconds since Jan 1, 1970 UTC as required in https://wopi.readthedocs.io/projects/wopirest/en/latest/concepts.html?highlight=access_token_ttl#term-access-token-ttl
+	ttl := time.Now().Add(time.Duration(p.conf.AccessTokenTTL)*time.Second).UnixNano() / int64(time.Millisecond)
+
+	log.Info().Str("url", appURL).Str("role", role.String()).Msg("wopihost: returning app URL")
+	return &appprovider.OpenInAppURL{
+		AppUrl: appURL,
+		Method: "POST",
+		FormParameters: map[string]string{
+			"access_token":     accessToken,
+			"access_token_ttl": strconv.FormatInt(ttl, 10),
+		},
+	}, nil
+}
+
+// actionURL returns the discovery URL to open a file with the given extension
+// and the role to grant to the app on it.
+func (p *wopiHostProvider) actionURL(ext string, size uint64, viewMode appprovider.OpenInAppRequest_ViewMode) (string, authpb.Role) {
+	if viewMode == appprovider.OpenInAppRequest_VIEW_MODE_READ_WRITE {
+		access := "edit"
+		if size == 0 {
+			if _, ok := p.appURLs["editnew"][ext]; ok {
+				access = "editnew"
+			}
+		}
+		if u, ok := p.appURLs[access][ext]; ok {
+			return u, authpb.Role_ROLE_EDITOR
+		}
+	}
+	// assuming that a view action is always available in the /hosting/discovery manifest,
+	// eg. Collabora does support viewing jpgs but no editing
+	return p.appURLs["view"][ext], authpb.Role_ROLE_VIEWER
+}
+
+func (p *wopiHostProvider) GetAppProviderInfo(ctx context.Context) (*appregistry.ProviderInfo, error) {
+	return &appregistry.ProviderInfo{
+		Name:        p.conf.AppName,
+		Icon:        p.conf.AppIconURI,
+		DesktopOnly: p.conf.AppDesktopOnly,
+		MimeTypes:   wopi.MimeTypes(p.appURLs),
+	}, nil
+}
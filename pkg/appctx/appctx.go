@@ -21,12 +21,27 @@ package appctx
 import (
 	"context"
 
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/rs/zerolog"
 )
 
 // DeletingSharedResource flags to a storage a shared resource is being deleted not by the owner.
 var DeletingSharedResource struct{}
 
+type recycleSpaceKey struct{}
+
+// ContextSetRecycleSpace flags to a storage the trash of the space with the
+// given root is accessed, rather than the trash of the current user.
+func ContextSetRecycleSpace(ctx context.Context, root *provider.ResourceId) context.Context {
+	return context.WithValue(ctx, recycleSpaceKey{}, root)
+}
+
+// ContextGetRecycleSpace returns the root of the space whose trash is accessed, if any.
+func ContextGetRecycleSpace(ctx context.Context) (*provider.ResourceId, bool) {
+	root, ok := ctx.Value(recycleSpaceKey{}).(*provider.ResourceId)
+	return root, ok && root != nil
+}
+
 // WithLogger returns a context with an associated logger.
 func WithLogger(ctx context.Context, l *zerolog.Logger) context.Context {
 	return l.WithContext(ctx)
@@ -0,0 +1,397 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package loginguard tracks the failed login attempts per client IP and per
+// account to slow down and block brute-force attacks.
+package loginguard
+
+import (
+	"container/list"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The kinds of keys attempts are tracked by.
+const (
+	KindIP      = "ip"
+	KindAccount = "account"
+)
+
+// Limit configures when the attempts of an IP or an account are blocked.
+// Unset values default to those of the guard.
+type Limit struct {
+	// MaxAttempts is the number of failed attempts within the window after which further attempts are blocked.
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// Window is the time in seconds of the sliding window in which failed attempts are counted.
+	Window int `mapstructure:"window"`
+	// BlockDuration is the time in seconds further attempts are rejected for once MaxAttempts is reached.
+	BlockDuration int `mapstructure:"block_duration"`
+	// MaxBlockDuration enables an exponential backoff: the block duration doubles with every consecutive block up to this time in seconds.
+	MaxBlockDuration int `mapstructure:"max_block_duration"`
+}
+
+// blockDuration returns how long attempts are blocked for after the given number of consecutive blocks.
+func (l *Limit) blockDuration(previous int) time.Duration {
+	d := time.Duration(l.BlockDuration) * time.Second
+	max := time.Duration(l.MaxBlockDuration) * time.Second
+	for i := 0; i < previous && d < max; i++ {
+		d *= 2
+		if d > max {
+			d = max
+		}
+	}
+	return d
+}
+
+func (l *Limit) backoff() bool {
+	return l.MaxBlockDuration > l.BlockDuration
+}
+
+// Config configures a guard.
+type Config struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MaxAttempts, Window and BlockDuration are the defaults of the IP and account limits.
+	MaxAttempts   int `mapstructure:"max_attempts" docs:"10;Number of failed attempts within the window after which further attempts are blocked."`
+	Window        int `mapstructure:"window" docs:"900;Time in seconds of the sliding window in which failed attempts are counted."`
+	BlockDuration int `mapstructure:"block_duration" docs:"900;Time in seconds further attempts are rejected for once the maximum is reached."`
+	// MaxBlockDuration is the default of the IP and account limits.
+	MaxBlockDuration int `mapstructure:"max_block_duration" docs:"0;Maximum time in seconds of a block when the block duration doubles with every consecutive block. 0 disables the backoff."`
+	// IP and Account override the limits per client IP and per account.
+	IP      Limit `mapstructure:"ip"`
+	Account Limit `mapstructure:"account"`
+	// DelayStep is the delay in milliseconds added to every attempt per previous failed attempt.
+	DelayStep int `mapstructure:"delay_step" docs:"500;Delay in milliseconds added to every attempt per previous failed attempt."`
+	// MaxDelay is the maximum delay in milliseconds of an attempt.
+	MaxDelay int `mapstructure:"max_delay" docs:"5000;Maximum delay in milliseconds of an attempt."`
+	// AllowedNetworks are the networks, in CIDR notation, whose attempts are never tracked nor blocked.
+	AllowedNetworks []string `mapstructure:"allowed_networks"`
+	// MaxEntries bounds the number of IPs and accounts tracked.
+	MaxEntries int `mapstructure:"max_entries" docs:"100000;Maximum number of IPs and accounts tracked; the least recently seen are forgotten first."`
+}
+
+func (c *Config) init() {
+	if c.MaxAttempts == 0 {
+		c.MaxAttempts = 10
+	}
+	if c.Window == 0 {
+		c.Window = 900
+	}
+	if c.BlockDuration == 0 {
+		c.BlockDuration = 900
+	}
+	if c.DelayStep == 0 {
+		c.DelayStep = 500
+	}
+	if c.MaxDelay == 0 {
+		c.MaxDelay = 5000
+	}
+	if c.MaxEntries == 0 {
+		c.MaxEntries = 100000
+	}
+	for _, l := range []*Limit{&c.IP, &c.Account} {
+		if l.MaxAttempts == 0 {
+			l.MaxAttempts = c.MaxAttempts
+		}
+		if l.Window == 0 {
+			l.Window = c.Window
+		}
+		if l.BlockDuration == 0 {
+			l.BlockDuration = c.BlockDuration
+		}
+		if l.MaxBlockDuration == 0 {
+			l.MaxBlockDuration = c.MaxBlockDuration
+		}
+	}
+}
+
+// Key identifies what attempts are tracked by.
+type Key struct {
+	Kind  string
+	Value string
+}
+
+// Block is an IP or account whose attempts are currently blocked.
+type Block struct {
+	Guard string    `json:"guard"`
+	Kind  string    `json:"kind"`
+	Value string    `json:"value"`
+	Until time.Time `json:"until"`
+}
+
+type entry struct {
+	key Key
+	// failures are the times of the failed attempts within the window, oldest first.
+	failures     []time.Time
+	blockedUntil time.Time
+	// blocks counts the consecutive blocks when backing off, which are remembered
+	// for as long as the last block lasted after its end.
+	blocks    int
+	lastBlock time.Duration
+}
+
+// Guard tracks the failed attempts in sliding windows per IP and per account.
+type Guard struct {
+	name    string
+	conf    *Config
+	allowed []*net.IPNet
+
+	mutex   sync.Mutex
+	lru     *list.List
+	entries map[Key]*list.Element
+	now     func() time.Time
+}
+
+// New returns a guard with the given name, which identifies it in the admin API.
+func New(name string, c *Config) (*Guard, error) {
+	c.init()
+	g := &Guard{
+		name:    name,
+		conf:    c,
+		lru:     list.New(),
+		entries: map[Key]*list.Element{},
+		now:     time.Now,
+	}
+	for _, n := range c.AllowedNetworks {
+		_, network, err := net.ParseCIDR(n)
+		if err != nil {
+			return nil, errors.Wrap(err, "loginguard: invalid allowed network "+n)
+		}
+		g.allowed = append(g.allowed, network)
+	}
+	return g, nil
+}
+
+// Name returns the name of the guard.
+func (g *Guard) Name() string {
+	return g.name
+}
+
+// Allowed returns whether the IP is in one of the allowed networks.
+// The IP may be the value of a X-Forwarded-For header, whose first address is the client.
+func (g *Guard) Allowed(ip string) bool {
+	parsed := net.ParseIP(strings.TrimSpace(strings.Split(ip, ",")[0]))
+	if parsed == nil {
+		return false
+	}
+	for _, n := range g.allowed {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Guard) keys(ip, account string) []Key {
+	if g.Allowed(ip) {
+		return nil
+	}
+	return []Key{{Kind: KindIP, Value: ip}, {Kind: KindAccount, Value: account}}
+}
+
+func (g *Guard) limit(k Key) *Limit {
+	if k.Kind == KindIP {
+		return &g.conf.IP
+	}
+	return &g.conf.Account
+}
+
+// get returns the entry of the key with the failures outside of the window
+// dropped, or nil if there is nothing to remember about the key.
+func (g *Guard) get(k Key, now time.Time) *entry {
+	el, ok := g.entries[k]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*entry)
+	since := now.Add(-time.Duration(g.limit(k).Window) * time.Second)
+	i := 0
+	for i < len(e.failures) && !e.failures[i].After(since) {
+		i++
+	}
+	e.failures = e.failures[i:]
+	if len(e.failures) == 0 && !e.blockedUntil.Add(e.lastBlock).After(now) {
+		g.remove(el)
+		return nil
+	}
+	g.lru.MoveToFront(el)
+	return e
+}
+
+func (g *Guard) remove(el *list.Element) {
+	g.lru.Remove(el)
+	delete(g.entries, el.Value.(*entry).key)
+}
+
+// Blocked returns whether attempts from the IP or for the account are
+// currently blocked, and for how long.
+func (g *Guard) Blocked(ip, account string) (time.Duration, bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	now := g.now()
+	var remaining time.Duration
+	for _, k := range g.keys(ip, account) {
+		if e := g.get(k, now); e != nil && e.blockedUntil.After(now) {
+			if d := e.blockedUntil.Sub(now); d > remaining {
+				remaining = d
+			}
+		}
+	}
+	return remaining, remaining > 0
+}
+
+// Delay returns how long the next attempt has to be delayed based on the previous failed attempts.
+func (g *Guard) Delay(ip, account string) time.Duration {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	now := g.now()
+	failures := 0
+	for _, k := range g.keys(ip, account) {
+		if e := g.get(k, now); e != nil && len(e.failures) > failures {
+			failures = len(e.failures)
+		}
+	}
+	d := failures * g.conf.DelayStep
+	if d > g.conf.MaxDelay {
+		d = g.conf.MaxDelay
+	}
+	return time.Duration(d) * time.Millisecond
+}
+
+// Fail registers a failed attempt and blocks the IP or account if too many attempts failed within the window.
+func (g *Guard) Fail(ip, account string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	now := g.now()
+	for _, k := range g.keys(ip, account) {
+		e := g.get(k, now)
+		if e == nil {
+			e = &entry{key: k}
+			g.entries[k] = g.lru.PushFront(e)
+			for g.lru.Len() > g.conf.MaxEntries {
+				g.remove(g.lru.Back())
+			}
+		}
+		l := g.limit(k)
+		e.failures = append(e.failures, now)
+		if len(e.failures) >= l.MaxAttempts {
+			d := l.blockDuration(e.blocks)
+			e.blockedUntil = now.Add(d)
+			e.failures = nil
+			if l.backoff() {
+				e.blocks++
+				e.lastBlock = d
+			}
+		}
+	}
+}
+
+// Succeed resets the failed attempts of the account; those of the IP are
+// kept, so that guessing the passwords of several accounts is still slowed down.
+func (g *Guard) Succeed(account string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	k := Key{Kind: KindAccount, Value: account}
+	if el, ok := g.entries[k]; ok && !el.Value.(*entry).blockedUntil.After(g.now()) {
+		g.remove(el)
+	}
+}
+
+// Blocks returns the IPs and accounts currently blocked.
+func (g *Guard) Blocks() []*Block {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	now := g.now()
+	blocks := []*Block{}
+	for el := g.lru.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*entry)
+		if e.blockedUntil.After(now) {
+			blocks = append(blocks, &Block{Guard: g.name, Kind: e.key.Kind, Value: e.key.Value, Until: e.blockedUntil})
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Until.Before(blocks[j].Until) })
+	return blocks
+}
+
+// Clear forgets the failed attempts and the block of the IP or account.
+// It returns whether there was anything to forget.
+func (g *Guard) Clear(k Key) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	el, ok := g.entries[k]
+	if ok {
+		g.remove(el)
+	}
+	return ok
+}
+
+var (
+	guards      = map[string]*Guard{}
+	guardsMutex sync.Mutex
+)
+
+// Shared returns the guard with the name, creating it with the configuration
+// if it does not exist yet. The guards are shared within the process, so
+// that the attempts against all the HTTP servers are tracked together and
+// the admin API can inspect them.
+func Shared(name string, c *Config) (*Guard, error) {
+	guardsMutex.Lock()
+	defer guardsMutex.Unlock()
+
+	if g, ok := guards[name]; ok {
+		return g, nil
+	}
+	g, err := New(name, c)
+	if err != nil {
+		return nil, err
+	}
+	guards[name] = g
+	return g, nil
+}
+
+// Get returns the shared guard with the name.
+func Get(name string) (*Guard, bool) {
+	guardsMutex.Lock()
+	defer guardsMutex.Unlock()
+
+	g, ok := guards[name]
+	return g, ok
+}
+
+// All returns the shared guards, ordered by name.
+func All() []*Guard {
+	guardsMutex.Lock()
+	defer guardsMutex.Unlock()
+
+	all := make([]*Guard, 0, len(guards))
+	for _, g := range guards {
+		all = append(all, g)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].name < all[j].name })
+	return all
+}
@@ -0,0 +1,216 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package loginguard
+
+import (
+	"testing"
+	"time"
+)
+
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func newGuard(t *testing.T, c *Config) (*Guard, *clock) {
+	g, err := New("test", c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk := &clock{t: time.Unix(1000000, 0)}
+	g.now = clk.now
+	return g, clk
+}
+
+func TestSlidingWindow(t *testing.T) {
+	g, clk := newGuard(t, &Config{MaxAttempts: 3, Window: 60, BlockDuration: 300})
+
+	g.Fail("1.2.3.4", "einstein")
+	clk.t = clk.t.Add(40 * time.Second)
+	g.Fail("1.2.3.4", "einstein")
+	// the first failure leaves the window
+	clk.t = clk.t.Add(30 * time.Second)
+	g.Fail("1.2.3.4", "einstein")
+	if _, blocked := g.Blocked("1.2.3.4", "einstein"); blocked {
+		t.Fatal("expected the attempts not to be blocked, the first failure left the window")
+	}
+
+	g.Fail("1.2.3.4", "einstein")
+	d, blocked := g.Blocked("5.6.7.8", "einstein")
+	if !blocked || d != 300*time.Second {
+		t.Fatalf("expected the account to be blocked for 300s, got %v %v", blocked, d)
+	}
+	if _, blocked := g.Blocked("1.2.3.4", "marie"); !blocked {
+		t.Fatal("expected the IP to be blocked")
+	}
+	if _, blocked := g.Blocked("5.6.7.8", "marie"); blocked {
+		t.Fatal("expected other IPs and accounts not to be blocked")
+	}
+
+	clk.t = clk.t.Add(301 * time.Second)
+	if _, blocked := g.Blocked("1.2.3.4", "einstein"); blocked {
+		t.Fatal("expected the block to expire")
+	}
+}
+
+func TestLimits(t *testing.T) {
+	g, _ := newGuard(t, &Config{MaxAttempts: 2, IP: Limit{MaxAttempts: 4, BlockDuration: 10}})
+
+	g.Fail("1.2.3.4", "einstein")
+	g.Fail("1.2.3.4", "marie")
+	g.Fail("1.2.3.4", "einstein")
+	if _, blocked := g.Blocked("5.6.7.8", "einstein"); !blocked {
+		t.Fatal("expected the account to be blocked after 2 attempts")
+	}
+	if _, blocked := g.Blocked("1.2.3.4", "marie"); blocked {
+		t.Fatal("expected the IP not to be blocked after 3 attempts")
+	}
+	g.Fail("1.2.3.4", "marie")
+	if d, blocked := g.Blocked("1.2.3.4", "richard"); !blocked || d != 10*time.Second {
+		t.Fatalf("expected the IP to be blocked for 10s, got %v %v", blocked, d)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	g, clk := newGuard(t, &Config{MaxAttempts: 1, BlockDuration: 60, MaxBlockDuration: 200})
+
+	for _, want := range []time.Duration{60, 120, 200, 200} {
+		g.Fail("1.2.3.4", "einstein")
+		d, blocked := g.Blocked("5.6.7.8", "einstein")
+		if !blocked || d != want*time.Second {
+			t.Fatalf("expected the account to be blocked for %ds, got %v %v", want, blocked, d)
+		}
+		clk.t = clk.t.Add(d)
+	}
+
+	// the backoff is forgotten once the account stayed unblocked for as long as its last block
+	clk.t = clk.t.Add(200 * time.Second)
+	g.Fail("1.2.3.4", "einstein")
+	if d, _ := g.Blocked("5.6.7.8", "einstein"); d != 60*time.Second {
+		t.Fatalf("expected the backoff to be reset, got %v", d)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	g, _ := newGuard(t, &Config{MaxAttempts: 5, DelayStep: 100, MaxDelay: 250})
+
+	if d := g.Delay("1.2.3.4", "einstein"); d != 0 {
+		t.Fatalf("expected no delay without failed attempts, got %v", d)
+	}
+	g.Fail("1.2.3.4", "einstein")
+	g.Fail("1.2.3.4", "einstein")
+	if d := g.Delay("5.6.7.8", "einstein"); d != 200*time.Millisecond {
+		t.Fatalf("expected a delay of 200ms, got %v", d)
+	}
+	g.Fail("1.2.3.4", "einstein")
+	if d := g.Delay("1.2.3.4", "marie"); d != 250*time.Millisecond {
+		t.Fatalf("expected the delay to be capped at 250ms, got %v", d)
+	}
+
+	g.Succeed("einstein")
+	if d := g.Delay("5.6.7.8", "einstein"); d != 0 {
+		t.Fatalf("expected the failed attempts of the account to be reset, got %v", d)
+	}
+	if d := g.Delay("1.2.3.4", "marie"); d == 0 {
+		t.Fatal("expected the failed attempts of the IP to be kept")
+	}
+}
+
+func TestAllowedNetworks(t *testing.T) {
+	if _, err := New("test", &Config{AllowedNetworks: []string{"10.0.0.0"}}); err == nil {
+		t.Fatal("expected an error for an invalid network")
+	}
+
+	g, _ := newGuard(t, &Config{MaxAttempts: 1, AllowedNetworks: []string{"10.0.0.0/8", "fd00::/8"}})
+	g.Fail("10.1.2.3", "einstein")
+	g.Fail("fd00::1", "einstein")
+	if _, blocked := g.Blocked("1.2.3.4", "einstein"); blocked {
+		t.Fatal("expected the attempts from allowed networks not to be tracked")
+	}
+
+	g.Fail("1.2.3.4", "einstein")
+	if _, blocked := g.Blocked("10.1.2.3, 1.2.3.4", "einstein"); blocked {
+		t.Fatal("expected the attempts from allowed networks not to be blocked")
+	}
+	if _, blocked := g.Blocked("1.2.3.4", "einstein"); !blocked {
+		t.Fatal("expected the attempts from other networks to be blocked")
+	}
+}
+
+func TestBlocksAndClear(t *testing.T) {
+	g, clk := newGuard(t, &Config{MaxAttempts: 1, BlockDuration: 60})
+
+	g.Fail("1.2.3.4", "einstein")
+	clk.t = clk.t.Add(time.Second)
+	g.Fail("5.6.7.8", "marie")
+
+	blocks := g.Blocks()
+	if len(blocks) != 4 {
+		t.Fatalf("expected 4 blocks, got %d", len(blocks))
+	}
+	if b := blocks[0]; b.Guard != "test" || b.Until != time.Unix(1000060, 0) {
+		t.Fatalf("unexpected first block %+v", b)
+	}
+
+	if !g.Clear(Key{Kind: KindAccount, Value: "einstein"}) {
+		t.Fatal("expected the block of the account to be cleared")
+	}
+	if g.Clear(Key{Kind: KindAccount, Value: "einstein"}) {
+		t.Fatal("expected nothing left to clear")
+	}
+	if _, blocked := g.Blocked("9.9.9.9", "einstein"); blocked {
+		t.Fatal("expected the account not to be blocked anymore")
+	}
+	if len(g.Blocks()) != 3 {
+		t.Fatal("expected 3 blocks left")
+	}
+}
+
+func TestMaxEntries(t *testing.T) {
+	g, _ := newGuard(t, &Config{MaxAttempts: 2, MaxEntries: 2})
+
+	g.Fail("1.2.3.4", "einstein")
+	g.Fail("5.6.7.8", "marie")
+	if len(g.entries) != 2 || g.lru.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(g.entries))
+	}
+	if d := g.Delay("5.6.7.8", "marie"); d == 0 {
+		t.Fatal("expected the most recent entries to be kept")
+	}
+}
+
+func TestShared(t *testing.T) {
+	a, err := Shared("shared-test", &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := Shared("shared-test", &Config{MaxAttempts: 1})
+	if a != b {
+		t.Fatal("expected the guard to be shared")
+	}
+	if g, ok := Get("shared-test"); !ok || g != a {
+		t.Fatal("expected to get the shared guard")
+	}
+	if len(All()) != 1 {
+		t.Fatal("expected a single shared guard")
+	}
+}
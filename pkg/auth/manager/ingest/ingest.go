@@ -0,0 +1,103 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ingest
+
+import (
+	"context"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpcv1beta1 "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/auth"
+	"github.com/cs3org/reva/pkg/auth/manager/registry"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/ingest"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("ingest", New)
+}
+
+type config struct {
+	GatewayAddr string `mapstructure:"gateway_addr"`
+	Secret      string `mapstructure:"secret" docs:"The secret the ingest URLs are signed with. Defaults to the shared JWT secret."`
+}
+
+type manager struct {
+	conf *config
+}
+
+// New returns an auth manager authenticating the uploads made through ingest
+// URLs. The client ID is the token of the URL, the secret is ignored.
+func New(m map[string]interface{}) (auth.Manager, error) {
+	mgr := &manager{}
+	if err := mgr.Configure(m); err != nil {
+		return nil, err
+	}
+	return mgr, nil
+}
+
+func (m *manager) Configure(ml map[string]interface{}) error {
+	c := &config{}
+	if err := mapstructure.Decode(ml, c); err != nil {
+		return errors.Wrap(err, "error decoding conf")
+	}
+	c.GatewayAddr = sharedconf.GetGatewaySVC(c.GatewayAddr)
+	c.Secret = sharedconf.GetJWTSecret(c.Secret)
+	m.conf = c
+	return nil
+}
+
+func (m *manager) Authenticate(ctx context.Context, token, _ string) (*user.User, map[string]*authpb.Scope, error) {
+	claims, err := ingest.Parse(m.conf.Secret, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gtw, err := pool.GetGatewayServiceClient(pool.Endpoint(m.conf.GatewayAddr))
+	if err != nil {
+		return nil, nil, err
+	}
+	userResponse, err := gtw.GetUser(ctx, &user.GetUserRequest{UserId: claims.Owner})
+	switch {
+	case err != nil:
+		return nil, nil, err
+	case userResponse.Status.Code == rpcv1beta1.Code_CODE_NOT_FOUND:
+		return nil, nil, errtypes.NotFound(userResponse.Status.Message)
+	case userResponse.Status.Code != rpcv1beta1.Code_CODE_OK:
+		return nil, nil, errtypes.InternalError(userResponse.Status.Message)
+	}
+
+	s, err := scope.AddIngestScope(&scope.Ingest{
+		ID:         claims.Id,
+		Actor:      claims.Actor,
+		Folder:     claims.Folder,
+		Expiration: claims.ExpiresAt,
+	}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return userResponse.GetUser(), s, nil
+}
@@ -26,6 +26,7 @@ import (
 	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
 	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/affiliation"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/auth"
 	"github.com/cs3org/reva/pkg/auth/manager/registry"
@@ -74,6 +75,12 @@ type attributes struct {
 	UIDNumber string `mapstructure:"uidNumber"`
 	// GIDNumber is a numeric id that maps to a filesystem gid, eg. 654321
 	GIDNumber string `mapstructure:"gidNumber"`
+	// Affiliation is the optional multi-valued affiliation of a user, e.g. `eduPersonAffiliation`
+	Affiliation string `mapstructure:"affiliation"`
+	// Department is the optional department of a user, e.g. `departmentNumber`
+	Department string `mapstructure:"department"`
+	// Country is the optional country of a user as an ISO 3166 code, e.g. `c`
+	Country string `mapstructure:"country"`
 }
 
 // Default attributes (Active Directory)
@@ -131,6 +138,17 @@ func (am *mgr) Configure(m map[string]interface{}) error {
 	return nil
 }
 
+// Warmup checks that the LDAP server can be reached and that the bind
+// credentials are valid.
+func (am *mgr) Warmup(ctx context.Context) error {
+	l, err := utils.GetLDAPConnection(&am.c.LDAPConn)
+	if err != nil {
+		return errors.Wrap(err, "ldap: error binding to the server")
+	}
+	l.Close()
+	return nil
+}
+
 func (am *mgr) Authenticate(ctx context.Context, clientID, clientSecret string) (*user.User, map[string]*authpb.Scope, error) {
 	log := appctx.GetLogger(ctx)
 	l, err := utils.GetLDAPConnection(&am.c.LDAPConn)
@@ -139,12 +157,23 @@ func (am *mgr) Authenticate(ctx context.Context, clientID, clientSecret string)
 	}
 	defer l.Close()
 
+	attrs := []string{am.c.Schema.DN, am.c.Schema.UID, am.c.Schema.CN, am.c.Schema.Mail, am.c.Schema.DisplayName, am.c.Schema.UIDNumber, am.c.Schema.GIDNumber}
+	if am.c.Schema.Affiliation != "" {
+		attrs = append(attrs, am.c.Schema.Affiliation)
+	}
+	if am.c.Schema.Department != "" {
+		attrs = append(attrs, am.c.Schema.Department)
+	}
+	if am.c.Schema.Country != "" {
+		attrs = append(attrs, am.c.Schema.Country)
+	}
+
 	// Search for the given clientID
 	searchRequest := ldap.NewSearchRequest(
 		am.c.BaseDN,
 		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
 		am.getLoginFilter(clientID),
-		[]string{am.c.Schema.DN, am.c.Schema.UID, am.c.Schema.CN, am.c.Schema.Mail, am.c.Schema.DisplayName, am.c.Schema.UIDNumber, am.c.Schema.GIDNumber},
+		attrs,
 		nil,
 	)
 
@@ -211,6 +240,18 @@ func (am *mgr) Authenticate(ctx context.Context, clientID, clientSecret string)
 		UidNumber:   uidNumber,
 		GidNumber:   gidNumber,
 	}
+	var affiliations []string
+	if am.c.Schema.Affiliation != "" {
+		affiliations = affiliation.Normalize(sr.Entries[0].GetEqualFoldAttributeValues(am.c.Schema.Affiliation))
+	}
+	var department string
+	if am.c.Schema.Department != "" {
+		department = sr.Entries[0].GetEqualFoldAttributeValue(am.c.Schema.Department)
+	}
+	affiliation.Set(u, affiliations, department)
+	if am.c.Schema.Country != "" {
+		affiliation.SetCountry(u, sr.Entries[0].GetEqualFoldAttributeValue(am.c.Schema.Country))
+	}
 
 	var scopes map[string]*authpb.Scope
 	if userID != nil && userID.Type == user.UserType_USER_TYPE_LIGHTWEIGHT {
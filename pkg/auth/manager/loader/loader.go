@@ -23,12 +23,16 @@ import (
 	_ "github.com/cs3org/reva/pkg/auth/manager/appauth"
 	_ "github.com/cs3org/reva/pkg/auth/manager/demo"
 	_ "github.com/cs3org/reva/pkg/auth/manager/impersonator"
+	_ "github.com/cs3org/reva/pkg/auth/manager/ingest"
 	_ "github.com/cs3org/reva/pkg/auth/manager/json"
 	_ "github.com/cs3org/reva/pkg/auth/manager/ldap"
 	_ "github.com/cs3org/reva/pkg/auth/manager/machine"
 	_ "github.com/cs3org/reva/pkg/auth/manager/nextcloud"
+	_ "github.com/cs3org/reva/pkg/auth/manager/oauthapp"
 	_ "github.com/cs3org/reva/pkg/auth/manager/oidc"
 	_ "github.com/cs3org/reva/pkg/auth/manager/owncloudsql"
 	_ "github.com/cs3org/reva/pkg/auth/manager/publicshares"
+	_ "github.com/cs3org/reva/pkg/auth/manager/sql"
+	_ "github.com/cs3org/reva/pkg/auth/manager/supportaccess"
 	// Add your own here
 )
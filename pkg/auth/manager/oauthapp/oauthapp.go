@@ -0,0 +1,130 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package oauthapp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpcv1beta1 "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/auth"
+	"github.com/cs3org/reva/pkg/auth/manager/registry"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/oauthapps"
+	_ "github.com/cs3org/reva/pkg/oauthapps/manager/loader" // Load the oauth apps managers
+	oa "github.com/cs3org/reva/pkg/oauthapps/manager/registry"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("oauthapp", New)
+}
+
+type config struct {
+	GatewayAddr string                            `mapstructure:"gateway_addr"`
+	Driver      string                            `mapstructure:"driver"`
+	Drivers     map[string]map[string]interface{} `mapstructure:"drivers"`
+}
+
+type manager struct {
+	conf *config
+	apps oauthapps.Manager
+}
+
+// New returns an auth manager issuing tokens to third-party applications. The
+// client ID is an authorization code obtained after the consent of the user, the
+// secret the one of the client the code was issued to.
+func New(m map[string]interface{}) (auth.Manager, error) {
+	mgr := &manager{}
+	if err := mgr.Configure(m); err != nil {
+		return nil, err
+	}
+	return mgr, nil
+}
+
+func (m *manager) Configure(ml map[string]interface{}) error {
+	c := &config{}
+	if err := mapstructure.Decode(ml, c); err != nil {
+		return errors.Wrap(err, "error decoding conf")
+	}
+	if c.Driver == "" {
+		c.Driver = "json"
+	}
+	c.GatewayAddr = sharedconf.GetGatewaySVC(c.GatewayAddr)
+
+	f, ok := oa.NewFuncs[c.Driver]
+	if !ok {
+		return fmt.Errorf("oauth apps driver not found: %s", c.Driver)
+	}
+	apps, err := f(c.Drivers[c.Driver])
+	if err != nil {
+		return errors.Wrap(err, "error creating the oauth apps manager")
+	}
+
+	m.conf = c
+	m.apps = apps
+	return nil
+}
+
+func (m *manager) Authenticate(ctx context.Context, code, secret string) (*user.User, map[string]*authpb.Scope, error) {
+	// codes are single use, whether the exchange succeeds or not
+	c, err := m.apps.TakeCode(ctx, oauthapps.Hash(code))
+	if err != nil {
+		return nil, nil, errtypes.InvalidCredentials("code")
+	}
+	client, err := m.apps.GetClient(ctx, c.ClientID)
+	if err != nil {
+		return nil, nil, errtypes.InvalidCredentials(c.ClientID)
+	}
+	if client.State != oauthapps.StateApproved || !client.VerifySecret(secret) {
+		return nil, nil, errtypes.InvalidCredentials(c.ClientID)
+	}
+
+	gtw, err := pool.GetGatewayServiceClient(pool.Endpoint(m.conf.GatewayAddr))
+	if err != nil {
+		return nil, nil, err
+	}
+	userResponse, err := gtw.GetUser(ctx, &user.GetUserRequest{UserId: c.User})
+	switch {
+	case err != nil:
+		return nil, nil, err
+	case userResponse.Status.Code == rpcv1beta1.Code_CODE_NOT_FOUND:
+		return nil, nil, errtypes.NotFound(userResponse.Status.Message)
+	case userResponse.Status.Code != rpcv1beta1.Code_CODE_OK:
+		return nil, nil, errtypes.InternalError(userResponse.Status.Message)
+	}
+
+	s, err := scope.AddOAuthAppScope(&scope.OAuthApp{
+		ClientID:   client.ID,
+		Scopes:     c.Scopes,
+		Expiration: time.Now().Add(time.Duration(client.TokenLifetime) * time.Second).Unix(),
+	}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return userResponse.GetUser(), s, nil
+}
@@ -32,6 +32,7 @@ import (
 	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
 	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/affiliation"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/auth"
 	"github.com/cs3org/reva/pkg/auth/manager/registry"
@@ -41,6 +42,7 @@ import (
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/rhttp"
 	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/tenant"
 	"github.com/juliangruber/go-intersect"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
@@ -55,6 +57,7 @@ type mgr struct {
 	provider         *oidc.Provider // cached on first request
 	c                *config
 	oidcUsersMapping map[string]*oidcUserMapping
+	tenants          *tenant.Manager
 }
 
 type config struct {
@@ -66,6 +69,10 @@ type config struct {
 	GatewaySvc   string `mapstructure:"gatewaysvc" docs:";The endpoint at which the GRPC gateway is exposed."`
 	UsersMapping string `mapstructure:"users_mapping" docs:"; The optional OIDC users mapping file path"`
 	GroupClaim   string `mapstructure:"group_claim" docs:"; The group claim to be looked up to map the user (default to 'groups')."`
+
+	AffiliationClaim string `mapstructure:"affiliation_claim" docs:";The claim containing the affiliations of the user, e.g. eduperson_affiliation."`
+	DepartmentClaim  string `mapstructure:"department_claim" docs:";The claim containing the department of the user."`
+	CountryClaim     string `mapstructure:"country_claim" docs:";The claim containing the country of the user as an ISO 3166 code, e.g. c."`
 }
 
 type oidcUserMapping struct {
@@ -119,6 +126,11 @@ func (am *mgr) Configure(m map[string]interface{}) error {
 	c.init()
 	am.c = c
 
+	am.tenants, err = tenant.New(sharedconf.GetTenancy())
+	if err != nil {
+		return err
+	}
+
 	am.oidcUsersMapping = map[string]*oidcUserMapping{}
 	if c.UsersMapping == "" {
 		// no mapping defined, leave the map empty and move on
@@ -240,6 +252,24 @@ func (am *mgr) Authenticate(ctx context.Context, clientID, clientSecret string)
 		UidNumber:    claims[am.c.UIDClaim].(int64),
 		GidNumber:    claims[am.c.GIDClaim].(int64),
 	}
+	if claim := am.tenants.Claim(); claim != "" {
+		if t, ok := claims[claim].(string); ok && t != "" {
+			tenant.Set(u, t)
+		}
+	}
+	var affiliations []string
+	if am.c.AffiliationClaim != "" {
+		affiliations = affiliation.FromClaim(claims[am.c.AffiliationClaim])
+	}
+	var department string
+	if am.c.DepartmentClaim != "" {
+		department, _ = claims[am.c.DepartmentClaim].(string)
+	}
+	affiliation.Set(u, affiliations, department)
+	if am.c.CountryClaim != "" {
+		country, _ := claims[am.c.CountryClaim].(string)
+		affiliation.SetCountry(u, country)
+	}
 
 	var scopes map[string]*authpb.Scope
 	if userID != nil && (userID.Type == user.UserType_USER_TYPE_LIGHTWEIGHT || userID.Type == user.UserType_USER_TYPE_FEDERATED) {
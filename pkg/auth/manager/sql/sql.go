@@ -0,0 +1,152 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/auth"
+	"github.com/cs3org/reva/pkg/auth/manager/registry"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/user/manager/sql/accounts"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("sql", New)
+}
+
+type config struct {
+	DbUsername  string   `mapstructure:"db_username"`
+	DbPassword  string   `mapstructure:"db_password"`
+	DbHost      string   `mapstructure:"db_host"`
+	DbPort      int      `mapstructure:"db_port"`
+	DbName      string   `mapstructure:"db_name"`
+	Idp         string   `mapstructure:"idp"`
+	Nobody      int64    `mapstructure:"nobody"`
+	LoginClaims []string `mapstructure:"login_claims" docs:"[username];The claims users can log in with, tried in order, e.g. username and mail."`
+}
+
+func (c *config) init() {
+	if c.Nobody == 0 {
+		c.Nobody = 99
+	}
+	if len(c.LoginClaims) == 0 {
+		c.LoginClaims = []string{"username"}
+	}
+}
+
+type manager struct {
+	c  *config
+	db *accounts.Accounts
+}
+
+// New returns an auth manager verifying the bcrypt or argon2id password hashes
+// of the users stored in the SQL database of the sql user manager.
+func New(m map[string]interface{}) (auth.Manager, error) {
+	mgr := &manager{}
+	if err := mgr.Configure(m); err != nil {
+		return nil, errors.Wrap(err, "sql: error creating a new auth manager")
+	}
+
+	db, err := accounts.NewMysql(fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", mgr.c.DbUsername, mgr.c.DbPassword, mgr.c.DbHost, mgr.c.DbPort, mgr.c.DbName))
+	if err != nil {
+		return nil, err
+	}
+	mgr.db = db
+	return mgr, nil
+}
+
+func (m *manager) Configure(ml map[string]interface{}) error {
+	c := &config{}
+	if err := mapstructure.Decode(ml, c); err != nil {
+		return errors.Wrap(err, "error decoding conf")
+	}
+	c.init()
+	m.c = c
+	return nil
+}
+
+func (m *manager) findAccount(ctx context.Context, login string) (*accounts.Account, error) {
+	for _, claim := range m.c.LoginClaims {
+		a, err := m.db.GetAccountByClaim(ctx, claim, login)
+		if err == nil {
+			return a, nil
+		}
+		if _, ok := err.(errtypes.IsNotFound); !ok {
+			return nil, err
+		}
+	}
+	return nil, errtypes.NotFound(login)
+}
+
+func (m *manager) Authenticate(ctx context.Context, login, clientSecret string) (*userpb.User, map[string]*authpb.Scope, error) {
+	log := appctx.GetLogger(ctx)
+
+	account, err := m.findAccount(ctx, login)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ok, err := accounts.VerifyPassword(account.PasswordHash, clientSecret)
+	if err != nil {
+		log.Error().Err(err).Str("userid", account.ID).Msg("error verifying password")
+	}
+	if !ok {
+		return nil, nil, errtypes.InvalidCredentials(login)
+	}
+	if account.Disabled {
+		return nil, nil, errtypes.PermissionDenied("user " + login + " is disabled")
+	}
+
+	u := &userpb.User{
+		Id: &userpb.UserId{
+			Idp:      m.c.Idp,
+			OpaqueId: account.ID,
+			Type:     userpb.UserType_USER_TYPE_PRIMARY,
+		},
+		Username:    account.Username,
+		Mail:        account.Mail,
+		DisplayName: account.DisplayName,
+		UidNumber:   account.UIDNumber,
+		GidNumber:   account.GIDNumber,
+	}
+	if u.UidNumber == 0 {
+		u.UidNumber = m.c.Nobody
+	}
+	if u.GidNumber == 0 {
+		u.GidNumber = m.c.Nobody
+	}
+	if u.Groups, err = m.db.GetAccountGroups(ctx, account.ID); err != nil {
+		return nil, nil, err
+	}
+
+	scopes, err := scope.AddOwnerScope(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Debug().Str("userid", account.ID).Msg("authenticated user")
+	return u, scopes, nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package scope
+
+import (
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+const credentialScope = "credential"
+
+// AddCredentialScope records the type of the credentials a token is minted
+// for. The scope carries no role and grants access to nothing on its own.
+func AddCredentialScope(authType string, scopes map[string]*authpb.Scope) (map[string]*authpb.Scope, error) {
+	if scopes == nil {
+		scopes = make(map[string]*authpb.Scope)
+	}
+	scopes[credentialScope] = &authpb.Scope{
+		Resource: &types.OpaqueEntry{
+			Decoder: "plain",
+			Value:   []byte(authType),
+		},
+		Role: authpb.Role_ROLE_INVALID,
+	}
+	return scopes, nil
+}
+
+// GetCredentialType returns the type of the credentials recorded in the
+// given scopes, if any.
+func GetCredentialType(scopes map[string]*authpb.Scope) (string, bool) {
+	s, ok := scopes[credentialScope]
+	if !ok || s.Resource == nil {
+		return "", false
+	}
+	return string(s.Resource.Value), true
+}
@@ -0,0 +1,118 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package scope
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+	"time"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	registry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/rs/zerolog"
+)
+
+// Ingest describes the access granted to a third party uploading files to a folder through an ingest URL.
+type Ingest struct {
+	ID         string               `json:"id"`
+	Actor      string               `json:"actor"`
+	Folder     *provider.ResourceId `json:"folder"`
+	Expiration int64                `json:"expiration"`
+}
+
+func ingestScope(_ context.Context, scope *authpb.Scope, resource interface{}, logger *zerolog.Logger) (bool, error) {
+	var ingest Ingest
+	if err := json.Unmarshal(scope.Resource.Value, &ingest); err != nil {
+		return false, err
+	}
+	if time.Now().Unix() >= ingest.Expiration {
+		logger.Debug().Str("id", ingest.ID).Msg("ingest access expired")
+		return false, nil
+	}
+
+	switch v := resource.(type) {
+	case *registry.GetStorageProvidersRequest:
+		return checkIngestRef(ingest.Folder, v.GetRef()), nil
+	case *provider.StatRequest:
+		return checkIngestRef(ingest.Folder, v.GetRef()), nil
+	case *provider.InitiateFileUploadRequest:
+		return checkIngestRef(ingest.Folder, v.GetRef()), nil
+	case *provider.GetQuotaRequest:
+		return checkIngestRef(ingest.Folder, v.GetRef()), nil
+	case *gateway.GetQuotaRequest:
+		return checkIngestRef(ingest.Folder, v.GetRef()), nil
+	case string:
+		return hasAnyPrefix(v, "/datagateway", "/dataprovider", "/data"), nil
+	}
+	return false, nil
+}
+
+// checkIngestRef allows the folder itself, referenced by its id, and its direct children,
+// referenced relative to it. Nested paths are denied, so that files can only be added.
+func checkIngestRef(folder *provider.ResourceId, r *provider.Reference) bool {
+	if !utils.ResourceIDEqual(folder, r.GetResourceId()) {
+		return false
+	}
+	if r.Path == "" || r.Path == "." {
+		return true
+	}
+	if !utils.IsRelativeReference(r) {
+		return false
+	}
+	p := path.Clean(r.Path)
+	return p != "." && p != ".." && !strings.Contains(p, "/")
+}
+
+// AddIngestScope adds the scope granting a third party the right to upload files to a folder.
+func AddIngestScope(ingest *Ingest, scopes map[string]*authpb.Scope) (map[string]*authpb.Scope, error) {
+	val, err := json.Marshal(ingest)
+	if err != nil {
+		return nil, err
+	}
+	if scopes == nil {
+		scopes = make(map[string]*authpb.Scope)
+	}
+	scopes["ingest"] = &authpb.Scope{
+		Resource: &types.OpaqueEntry{
+			Decoder: "json",
+			Value:   val,
+		},
+		Role: authpb.Role_ROLE_UPLOADER,
+	}
+	return scopes, nil
+}
+
+// GetIngest returns the ingest access contained in the given scopes, if any.
+func GetIngest(scopes map[string]*authpb.Scope) (*Ingest, bool) {
+	s, ok := scopes["ingest"]
+	if !ok || s.Resource == nil {
+		return nil, false
+	}
+	var ingest Ingest
+	if err := json.Unmarshal(s.Resource.Value, &ingest); err != nil {
+		return nil, false
+	}
+	return &ingest, true
+}
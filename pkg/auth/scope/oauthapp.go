@@ -0,0 +1,174 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package scope
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	registry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/rs/zerolog"
+)
+
+// The scopes third-party applications can request.
+const (
+	// AppScopeDAVRead allows reading the files of the user over WebDAV.
+	AppScopeDAVRead = "dav:read"
+	// AppScopeShares allows managing the shares and public links of the user.
+	AppScopeShares = "shares:manage"
+)
+
+// AppScopes are all the scopes third-party applications can request.
+var AppScopes = []string{AppScopeDAVRead, AppScopeShares}
+
+// OAuthApp describes the access granted to a third-party application with the consent of a user.
+type OAuthApp struct {
+	ClientID   string   `json:"client_id"`
+	Scopes     []string `json:"scopes"`
+	Expiration int64    `json:"expiration"`
+}
+
+func oauthAppScope(_ context.Context, scope *authpb.Scope, resource interface{}, logger *zerolog.Logger) (bool, error) {
+	var app OAuthApp
+	if err := json.Unmarshal(scope.Resource.Value, &app); err != nil {
+		return false, err
+	}
+	if time.Now().Unix() >= app.Expiration {
+		logger.Debug().Str("client_id", app.ClientID).Msg("oauth app access expired")
+		return false, nil
+	}
+
+	for _, s := range app.Scopes {
+		switch s {
+		case AppScopeDAVRead:
+			if checkAppDAVRead(resource) {
+				return true, nil
+			}
+		case AppScopeShares:
+			if checkAppShares(resource) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func checkAppDAVRead(resource interface{}) bool {
+	switch v := resource.(type) {
+	case *registry.GetStorageProvidersRequest, *registry.ListStorageProvidersRequest, *registry.GetHomeRequest,
+		*gateway.WhoAmIRequest, *provider.GetHomeRequest, *provider.StatRequest, *provider.ListContainerRequest,
+		*provider.ListContainerStreamRequest, *provider.InitiateFileDownloadRequest, *provider.GetPathRequest,
+		*provider.GetQuotaRequest, *gateway.GetQuotaRequest, *provider.ListFileVersionsRequest, *provider.ListStorageSpacesRequest:
+		return true
+	case string:
+		return hasAnyPrefix(v,
+			"/remote.php/webdav",
+			"/remote.php/dav/files",
+			"/remote.php/dav/spaces",
+			"/webdav",
+			"/dav/files",
+			"/dav/spaces",
+			"/dataprovider",
+			"/data",
+		)
+	}
+	return false
+}
+
+func checkAppShares(resource interface{}) bool {
+	switch v := resource.(type) {
+	case *registry.GetStorageProvidersRequest, *registry.ListStorageProvidersRequest, *registry.GetHomeRequest,
+		*gateway.WhoAmIRequest, *provider.GetHomeRequest, *provider.StatRequest, *provider.GetPathRequest,
+		*userpb.GetUserRequest, *userpb.GetUserByClaimRequest, *userpb.FindUsersRequest, *userpb.GetUserGroupsRequest,
+		*grouppb.GetGroupRequest, *grouppb.GetGroupByClaimRequest, *grouppb.FindGroupsRequest,
+		*collaboration.CreateShareRequest, *collaboration.RemoveShareRequest, *collaboration.GetShareRequest,
+		*collaboration.ListSharesRequest, *collaboration.UpdateShareRequest, *collaboration.ListReceivedSharesRequest,
+		*collaboration.GetReceivedShareRequest, *collaboration.UpdateReceivedShareRequest,
+		*link.CreatePublicShareRequest, *link.RemovePublicShareRequest, *link.GetPublicShareRequest,
+		*link.ListPublicSharesRequest, *link.UpdatePublicShareRequest:
+		return true
+	case string:
+		return hasAnyPrefix(v,
+			"/ocs/v2.php/apps/files_sharing/api/v1/shares",
+			"/ocs/v1.php/apps/files_sharing/api/v1/shares",
+			"/ocs/v2.php/apps/files_sharing//api/v1/shares",
+			"/ocs/v1.php/apps/files_sharing//api/v1/shares",
+			"/ocs/v2.php/apps/files_sharing/api/v1/sharees",
+			"/ocs/v1.php/apps/files_sharing/api/v1/sharees",
+		)
+	}
+	return false
+}
+
+func hasAnyPrefix(s string, prefixes ...string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddOAuthAppScope adds the scope granting a third-party application the access the user consented to.
+func AddOAuthAppScope(app *OAuthApp, scopes map[string]*authpb.Scope) (map[string]*authpb.Scope, error) {
+	val, err := json.Marshal(app)
+	if err != nil {
+		return nil, err
+	}
+	if scopes == nil {
+		scopes = make(map[string]*authpb.Scope)
+	}
+	role := authpb.Role_ROLE_VIEWER
+	for _, s := range app.Scopes {
+		if s == AppScopeShares {
+			role = authpb.Role_ROLE_EDITOR
+		}
+	}
+	scopes["oauthapp"] = &authpb.Scope{
+		Resource: &types.OpaqueEntry{
+			Decoder: "json",
+			Value:   val,
+		},
+		Role: role,
+	}
+	return scopes, nil
+}
+
+// GetOAuthApp returns the third-party application access contained in the given scopes, if any.
+func GetOAuthApp(scopes map[string]*authpb.Scope) (*OAuthApp, bool) {
+	s, ok := scopes["oauthapp"]
+	if !ok || s.Resource == nil {
+		return nil, false
+	}
+	var app OAuthApp
+	if err := json.Unmarshal(s.Resource.Value, &app); err != nil {
+		return nil, false
+	}
+	return &app, true
+}
@@ -21,6 +21,7 @@ package scope
 import (
 	"context"
 	"fmt"
+	"path"
 	"strings"
 
 	appprovider "github.com/cs3org/go-cs3apis/cs3/app/provider/v1beta1"
@@ -102,6 +103,12 @@ func checkStorageRef(ctx context.Context, s *link.PublicShare, r *provider.Refer
 		return utils.ResourceIDEqual(s.ResourceId, r.GetResourceId()) || strings.HasPrefix(r.ResourceId.OpaqueId, s.Token)
 	}
 
+	// r: <resource_id:<storage_id:$storageID opaque_id:$opaqueID> path:"./$relative-path" >
+	// the public storage provider resolves paths relative to the shared resource
+	if utils.IsRelativeReference(r) {
+		return utils.ResourceIDEqual(s.ResourceId, r.GetResourceId()) && !strings.HasPrefix(path.Clean(r.Path), "..")
+	}
+
 	// r: <path:"/public/$token" >
 	if strings.HasPrefix(r.GetPath(), "/public/"+s.Token) {
 		return true
@@ -56,6 +56,8 @@ func resourceinfoScope(_ context.Context, scope *authpb.Scope, resource interfac
 		return checkResourceInfo(&r, &provider.Reference{ResourceId: v.ResourceInfo.Id}), nil
 	case *gateway.OpenInAppRequest:
 		return checkResourceInfo(&r, v.GetRef()), nil
+	case *provider.GetLockRequest:
+		return checkResourceInfo(&r, v.GetRef()), nil
 
 	// Editor role
 	// need to return appropriate status codes in the ocs/ocdav layers.
@@ -73,6 +75,12 @@ func resourceinfoScope(_ context.Context, scope *authpb.Scope, resource interfac
 		return hasRoleEditor(*scope) && checkResourceInfo(&r, v.GetRef()), nil
 	case *provider.UnsetArbitraryMetadataRequest:
 		return hasRoleEditor(*scope) && checkResourceInfo(&r, v.GetRef()), nil
+	case *provider.SetLockRequest:
+		return hasRoleEditor(*scope) && checkResourceInfo(&r, v.GetRef()), nil
+	case *provider.RefreshLockRequest:
+		return hasRoleEditor(*scope) && checkResourceInfo(&r, v.GetRef()), nil
+	case *provider.UnlockRequest:
+		return hasRoleEditor(*scope) && checkResourceInfo(&r, v.GetRef()), nil
 
 	case string:
 		return checkResourcePath(v), nil
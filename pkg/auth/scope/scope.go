@@ -37,6 +37,9 @@ var supportedScopes = map[string]Verifier{
 	"share":         shareScope,
 	"receivedshare": receivedShareScope,
 	"lightweight":   lightweightAccountScope,
+	"supportaccess": supportAccessScope,
+	"oauthapp":      oauthAppScope,
+	"ingest":        ingestScope,
 }
 
 // VerifyScope is the function to be called when dismantling tokens to check if
@@ -0,0 +1,82 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package scope
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/rs/zerolog"
+)
+
+// SupportAccess describes the access of an admin to the account of a user, granted with the consent of the user.
+type SupportAccess struct {
+	RequestID  string         `json:"request_id"`
+	Admin      *userpb.UserId `json:"admin"`
+	Expiration int64          `json:"expiration"`
+}
+
+func supportAccessScope(_ context.Context, scope *authpb.Scope, resource interface{}, logger *zerolog.Logger) (bool, error) {
+	var access SupportAccess
+	if err := json.Unmarshal(scope.Resource.Value, &access); err != nil {
+		return false, err
+	}
+	// The access is as wide as the one of the user, but only until it expires.
+	if time.Now().Unix() >= access.Expiration {
+		logger.Debug().Str("request_id", access.RequestID).Msg("support access expired")
+		return false, nil
+	}
+	return true, nil
+}
+
+// AddSupportAccessScope adds the scope granting an admin time-limited access to the account of a user.
+func AddSupportAccessScope(access *SupportAccess, scopes map[string]*authpb.Scope) (map[string]*authpb.Scope, error) {
+	val, err := json.Marshal(access)
+	if err != nil {
+		return nil, err
+	}
+	if scopes == nil {
+		scopes = make(map[string]*authpb.Scope)
+	}
+	scopes["supportaccess"] = &authpb.Scope{
+		Resource: &types.OpaqueEntry{
+			Decoder: "json",
+			Value:   val,
+		},
+		Role: authpb.Role_ROLE_OWNER,
+	}
+	return scopes, nil
+}
+
+// GetSupportAccess returns the support access contained in the given scopes, if any.
+func GetSupportAccess(scopes map[string]*authpb.Scope) (*SupportAccess, bool) {
+	s, ok := scopes["supportaccess"]
+	if !ok || s.Resource == nil {
+		return nil, false
+	}
+	var access SupportAccess
+	if err := json.Unmarshal(s.Resource.Value, &access); err != nil {
+		return nil, false
+	}
+	return &access, true
+}
@@ -0,0 +1,46 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package cbox
+
+import "github.com/cs3org/reva/pkg/migrate"
+
+// Schema is the migration component of the metadata table holding the favorites.
+const Schema = "cbox_favorites"
+
+func init() {
+	migrate.Register(Schema, migrate.Migration{
+		Version:     1,
+		Description: "create the metadata table",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS cbox_metadata (
+				id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				item_type INT NOT NULL,
+				uid VARCHAR(64) NOT NULL,
+				fileid_prefix VARCHAR(255) NOT NULL,
+				fileid VARCHAR(255) NOT NULL,
+				tag_key VARCHAR(255) NOT NULL,
+				tag_val VARCHAR(255) DEFAULT NULL,
+				INDEX uid_tag_key_index (uid, tag_key)
+			)`,
+		},
+		Down: []string{
+			"DROP TABLE IF EXISTS cbox_metadata",
+		},
+	})
+}
@@ -27,6 +27,7 @@ import (
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/cbox/utils"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/migrate"
 	"github.com/cs3org/reva/pkg/storage/favorite"
 	"github.com/cs3org/reva/pkg/storage/favorite/registry"
 	"github.com/mitchellh/mapstructure"
@@ -42,6 +43,8 @@ type config struct {
 	DbHost     string `mapstructure:"db_host"`
 	DbPort     int    `mapstructure:"db_port"`
 	DbName     string `mapstructure:"db_name"`
+	// SkipMigrations disables applying the pending schema migrations on startup.
+	SkipMigrations bool `mapstructure:"skip_migrations"`
 }
 
 type mgr struct {
@@ -61,6 +64,12 @@ func New(m map[string]interface{}) (favorite.Manager, error) {
 		return nil, err
 	}
 
+	if !c.SkipMigrations {
+		if err := migrate.Up(context.Background(), db, Schema); err != nil {
+			return nil, err
+		}
+	}
+
 	return &mgr{
 		c:  c,
 		db: db,
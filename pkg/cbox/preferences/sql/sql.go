@@ -21,12 +21,13 @@ package sql
 import (
 	"context"
 	"database/sql"
-	"fmt"
 
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/migrate"
 	"github.com/cs3org/reva/pkg/preferences"
 	"github.com/cs3org/reva/pkg/preferences/registry"
+	"github.com/cs3org/reva/pkg/sqldb"
 	"github.com/mitchellh/mapstructure"
 )
 
@@ -35,11 +36,17 @@ func init() {
 }
 
 type config struct {
+	// DbEngine is either mysql, the default, or sqlite.
+	DbEngine   string `mapstructure:"db_engine"`
 	DbUsername string `mapstructure:"db_username"`
 	DbPassword string `mapstructure:"db_password"`
 	DbHost     string `mapstructure:"db_host"`
 	DbPort     int    `mapstructure:"db_port"`
 	DbName     string `mapstructure:"db_name"`
+	// DbPath is the file of the sqlite database.
+	DbPath string `mapstructure:"db_path"`
+	// SkipMigrations disables applying the pending schema migrations on startup.
+	SkipMigrations bool `mapstructure:"skip_migrations"`
 }
 
 type mgr struct {
@@ -54,11 +61,25 @@ func New(m map[string]interface{}) (preferences.Manager, error) {
 		return nil, err
 	}
 
-	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", c.DbUsername, c.DbPassword, c.DbHost, c.DbPort, c.DbName))
+	db, err := sqldb.Open(sqldb.Config{
+		Engine:   c.DbEngine,
+		Username: c.DbUsername,
+		Password: c.DbPassword,
+		Host:     c.DbHost,
+		Port:     c.DbPort,
+		Name:     c.DbName,
+		Path:     c.DbPath,
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	if !c.SkipMigrations {
+		if err := migrate.Up(context.Background(), db, Schema); err != nil {
+			return nil, err
+		}
+	}
+
 	return &mgr{
 		c:  c,
 		db: db,
@@ -71,6 +92,9 @@ func (m *mgr) SetKey(ctx context.Context, key, namespace, value string) error {
 		return errtypes.UserRequired("preferences: error getting user from ctx")
 	}
 	query := `INSERT INTO oc_preferences(userid, appid, configkey, configvalue) values(?, ?, ?, ?) ON DUPLICATE KEY UPDATE configvalue = ?`
+	if sqldb.Engine(m.db) == sqldb.SQLite {
+		query = `INSERT INTO oc_preferences(userid, appid, configkey, configvalue) values(?, ?, ?, ?) ON CONFLICT(userid, appid, configkey) DO UPDATE SET configvalue = ?`
+	}
 	params := []interface{}{user.Id.OpaqueId, namespace, key, value, value}
 	stmt, err := m.db.Prepare(query)
 	if err != nil {
@@ -38,11 +38,13 @@ import (
 	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	conversions "github.com/cs3org/reva/pkg/cbox/utils"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/lease"
+	"github.com/cs3org/reva/pkg/migrate"
 	"github.com/cs3org/reva/pkg/publicshare"
 	"github.com/cs3org/reva/pkg/publicshare/manager/registry"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/sharedconf"
-	"github.com/cs3org/reva/pkg/utils"
+	"github.com/cs3org/reva/pkg/sqldb"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 )
@@ -68,7 +70,15 @@ type config struct {
 	DbHost                     string `mapstructure:"db_host"`
 	DbPort                     int    `mapstructure:"db_port"`
 	DbName                     string `mapstructure:"db_name"`
-	GatewaySvc                 string `mapstructure:"gatewaysvc"`
+	// DbEngine is either mysql, the default, or sqlite.
+	DbEngine string `mapstructure:"db_engine"`
+	// DbPath is the file of the sqlite database.
+	DbPath        string `mapstructure:"db_path"`
+	GatewaySvc    string `mapstructure:"gatewaysvc"`
+	TokenLength   int    `mapstructure:"token_length"`
+	TokenAlphabet string `mapstructure:"token_alphabet"`
+	// SkipMigrations disables applying the pending schema migrations on startup.
+	SkipMigrations bool `mapstructure:"skip_migrations"`
 }
 
 type manager struct {
@@ -101,7 +111,10 @@ func (m *manager) startJanitorRun() {
 		case <-work:
 			return
 		case <-ticker.C:
-			_ = m.cleanupExpiredShares()
+			// Only one replica sharing the database runs the cleanup
+			_, _ = lease.Run(context.Background(), "cbox_publicshare_janitor", func(context.Context, int64) error {
+				return m.cleanupExpiredShares()
+			})
 		}
 	}
 }
@@ -114,11 +127,25 @@ func New(m map[string]interface{}) (publicshare.Manager, error) {
 	}
 	c.init()
 
-	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", c.DbUsername, c.DbPassword, c.DbHost, c.DbPort, c.DbName))
+	db, err := sqldb.Open(sqldb.Config{
+		Engine:   c.DbEngine,
+		Username: c.DbUsername,
+		Password: c.DbPassword,
+		Host:     c.DbHost,
+		Port:     c.DbPort,
+		Name:     c.DbName,
+		Path:     c.DbPath,
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	if !c.SkipMigrations {
+		if err := migrate.Up(context.Background(), db, conversions.SharesSchema); err != nil {
+			return nil, err
+		}
+	}
+
 	mgr := manager{
 		c:  c,
 		db: db,
@@ -128,9 +155,21 @@ func New(m map[string]interface{}) (publicshare.Manager, error) {
 	return &mgr, nil
 }
 
+// timeParam returns the parameter to store a time in a DATETIME column. SQLite
+// has no such type, the time is stored as text in the format it is read back in.
+func (m *manager) timeParam(t time.Time) interface{} {
+	if sqldb.Engine(m.db) == sqldb.SQLite {
+		return t.UTC().Format("2006-01-02 15:04:05")
+	}
+	return t
+}
+
 func (m *manager) CreatePublicShare(ctx context.Context, u *user.User, rInfo *provider.ResourceInfo, g *link.Grant) (*link.PublicShare, error) {
 
-	tkn := utils.RandString(15)
+	tkn, err := publicshare.GenerateToken(m.c.TokenLength, m.c.TokenAlphabet)
+	if err != nil {
+		return nil, err
+	}
 	now := time.Now().Unix()
 
 	displayName, ok := rInfo.ArbitraryMetadata.Metadata["name"]
@@ -154,7 +193,7 @@ func (m *manager) CreatePublicShare(ctx context.Context, u *user.User, rInfo *pr
 		fileSource = 0
 	}
 
-	query := "insert into oc_share set share_type=?,uid_owner=?,uid_initiator=?,item_type=?,fileid_prefix=?,item_source=?,file_source=?,permissions=?,stime=?,token=?,share_name=?"
+	columns := "share_type,uid_owner,uid_initiator,item_type,fileid_prefix,item_source,file_source,permissions,stime,token,share_name"
 	params := []interface{}{publicShareType, owner, creator, itemType, prefix, itemSource, fileSource, permissions, now, tkn, displayName}
 
 	var passwordProtected bool
@@ -166,16 +205,18 @@ func (m *manager) CreatePublicShare(ctx context.Context, u *user.User, rInfo *pr
 		}
 		passwordProtected = true
 
-		query += ",share_with=?"
+		columns += ",share_with"
 		params = append(params, password)
 	}
 
 	if g.Expiration != nil && g.Expiration.Seconds != 0 {
 		t := time.Unix(int64(g.Expiration.Seconds), 0)
-		query += ",expiration=?"
-		params = append(params, t)
+		columns += ",expiration"
+		params = append(params, m.timeParam(t))
 	}
 
+	query := "insert into oc_share (" + columns + ") values (?" + strings.Repeat(",?", len(params)-1) + ")"
+
 	stmt, err := m.db.Prepare(query)
 	if err != nil {
 		return nil, err
@@ -220,7 +261,7 @@ func (m *manager) UpdatePublicShare(ctx context.Context, u *user.User, req *link
 	case link.UpdatePublicShareRequest_Update_TYPE_PERMISSIONS:
 		paramsMap["permissions"] = conversions.SharePermToInt(req.Update.GetGrant().GetPermissions().Permissions)
 	case link.UpdatePublicShareRequest_Update_TYPE_EXPIRATION:
-		paramsMap["expiration"] = time.Unix(int64(req.Update.GetGrant().Expiration.Seconds), 0)
+		paramsMap["expiration"] = m.timeParam(time.Unix(int64(req.Update.GetGrant().Expiration.Seconds), 0))
 	case link.UpdatePublicShareRequest_Update_TYPE_PASSWORD:
 		if req.Update.GetGrant().Password == "" {
 			paramsMap["share_with"] = ""
@@ -401,6 +442,54 @@ func (m *manager) ListPublicShares(ctx context.Context, u *user.User, filters []
 	return shares, nil
 }
 
+// ListSharedResources returns the ids of the given resources with public links
+// of the user which did not expire, reading only the ids of the resources.
+func (m *manager) ListSharedResources(ctx context.Context, u *user.User, ids []*provider.ResourceId) ([]*provider.ResourceId, error) {
+	if len(ids) == 0 {
+		return []*provider.ResourceId{}, nil
+	}
+	query := "SELECT DISTINCT coalesce(fileid_prefix, '') as fileid_prefix, coalesce(item_source, '') as item_source FROM oc_share WHERE (orphan = 0 or orphan IS NULL) AND (share_type=?) AND (expiration IS NULL OR expiration > ?) AND ("
+	params := []interface{}{publicShareType, m.timeParam(time.Now())}
+	filters := make([]*link.ListPublicSharesRequest_Filter, 0, len(ids))
+	for i, id := range ids {
+		if i > 0 {
+			query += " OR "
+		}
+		query += "(fileid_prefix=? AND item_source=?)"
+		params = append(params, id.StorageId, id.OpaqueId)
+		filters = append(filters, publicshare.ResourceIDFilter(id))
+	}
+	query += ")"
+
+	uidOwnersQuery, uidOwnersParams, err := m.uidOwnerFilters(ctx, u, filters)
+	if err != nil {
+		return nil, err
+	}
+	params = append(params, uidOwnersParams...)
+	if uidOwnersQuery != "" {
+		query = fmt.Sprintf("%s AND (%s)", query, uidOwnersQuery)
+	}
+
+	rows, err := m.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shared := []*provider.ResourceId{}
+	for rows.Next() {
+		id := &provider.ResourceId{}
+		if err := rows.Scan(&id.StorageId, &id.OpaqueId); err != nil {
+			continue
+		}
+		shared = append(shared, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return shared, nil
+}
+
 func (m *manager) RevokePublicShare(ctx context.Context, u *user.User, ref *link.PublicShareReference) error {
 	uid := conversions.FormatUserID(u.Id)
 	query := "delete from oc_share where "
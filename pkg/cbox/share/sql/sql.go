@@ -34,17 +34,16 @@ import (
 	conversions "github.com/cs3org/reva/pkg/cbox/utils"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/migrate"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/share"
 	"github.com/cs3org/reva/pkg/share/manager/registry"
 	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/sqldb"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"google.golang.org/genproto/protobuf/field_mask"
-
-	// Provides mysql drivers
-	_ "github.com/go-sql-driver/mysql"
 )
 
 const (
@@ -61,12 +60,18 @@ func init() {
 }
 
 type config struct {
+	// DbEngine is either mysql, the default, or sqlite.
+	DbEngine   string `mapstructure:"db_engine"`
 	DbUsername string `mapstructure:"db_username"`
 	DbPassword string `mapstructure:"db_password"`
 	DbHost     string `mapstructure:"db_host"`
 	DbPort     int    `mapstructure:"db_port"`
 	DbName     string `mapstructure:"db_name"`
+	// DbPath is the file of the sqlite database.
+	DbPath     string `mapstructure:"db_path"`
 	GatewaySvc string `mapstructure:"gatewaysvc"`
+	// SkipMigrations disables applying the pending schema migrations on startup.
+	SkipMigrations bool `mapstructure:"skip_migrations"`
 }
 
 type mgr struct {
@@ -82,11 +87,25 @@ func New(m map[string]interface{}) (share.Manager, error) {
 		return nil, err
 	}
 
-	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", c.DbUsername, c.DbPassword, c.DbHost, c.DbPort, c.DbName))
+	db, err := sqldb.Open(sqldb.Config{
+		Engine:   c.DbEngine,
+		Username: c.DbUsername,
+		Password: c.DbPassword,
+		Host:     c.DbHost,
+		Port:     c.DbPort,
+		Name:     c.DbName,
+		Path:     c.DbPath,
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	if !c.SkipMigrations {
+		if err := migrate.Up(context.Background(), db, conversions.SharesSchema); err != nil {
+			return nil, err
+		}
+	}
+
 	return &mgr{
 		c:  c,
 		db: db,
@@ -143,7 +162,7 @@ func (m *mgr) Share(ctx context.Context, md *provider.ResourceInfo, g *collabora
 		fileSource = 0
 	}
 
-	stmtString := "insert into oc_share set share_type=?,uid_owner=?,uid_initiator=?,item_type=?,fileid_prefix=?,item_source=?,file_source=?,permissions=?,stime=?,share_with=?,file_target=?"
+	stmtString := "insert into oc_share (share_type,uid_owner,uid_initiator,item_type,fileid_prefix,item_source,file_source,permissions,stime,share_with,file_target) values (?,?,?,?,?,?,?,?,?,?,?)"
 	stmtValues := []interface{}{shareType, conversions.FormatUserID(md.Owner), conversions.FormatUserID(user.Id), itemType, prefix, itemSource, fileSource, permissions, now, shareWith, targetPath}
 
 	stmt, err := m.db.Prepare(stmtString)
@@ -338,6 +357,55 @@ func (m *mgr) ListShares(ctx context.Context, filters []*collaboration.Filter) (
 	return shares, nil
 }
 
+// ListSharedResources returns the ids of the given resources shared by the
+// user, reading only the ids of the shared resources.
+func (m *mgr) ListSharedResources(ctx context.Context, ids []*provider.ResourceId) ([]*provider.ResourceId, error) {
+	if len(ids) == 0 {
+		return []*provider.ResourceId{}, nil
+	}
+	filters := make([]*collaboration.Filter, 0, len(ids))
+	for _, id := range ids {
+		filters = append(filters, share.ResourceIDFilter(id))
+	}
+	groupedFilters := share.GroupFiltersByType(filters)
+	filterQuery, filterParams, err := translateFilters(groupedFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT DISTINCT coalesce(fileid_prefix, '') as fileid_prefix, coalesce(item_source, '') as item_source
+			  FROM oc_share WHERE (orphan = 0 or orphan IS NULL) AND (share_type=? OR share_type=?) AND (` + filterQuery + ")"
+	params := append([]interface{}{shareTypeUser, shareTypeGroup}, filterParams...)
+
+	uidOwnersQuery, uidOwnersParams, err := m.uidOwnerFilters(ctx, groupedFilters)
+	if err != nil {
+		return nil, err
+	}
+	params = append(params, uidOwnersParams...)
+	if uidOwnersQuery != "" {
+		query = fmt.Sprintf("%s AND (%s)", query, uidOwnersQuery)
+	}
+
+	rows, err := m.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shared := []*provider.ResourceId{}
+	for rows.Next() {
+		id := &provider.ResourceId{}
+		if err := rows.Scan(&id.StorageId, &id.OpaqueId); err != nil {
+			continue
+		}
+		shared = append(shared, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return shared, nil
+}
+
 // we list the shares that are targeted to the user in context or to the user groups.
 func (m *mgr) ListReceivedShares(ctx context.Context, filters []*collaboration.Filter) ([]*collaboration.ReceivedShare, error) {
 	user := ctxpkg.ContextMustGetUser(ctx)
@@ -498,6 +566,9 @@ func (m *mgr) UpdateReceivedShare(ctx context.Context, share *collaboration.Rece
 
 	params := []interface{}{rs.Share.Id.OpaqueId, conversions.FormatUserID(user.Id), state, state}
 	query := "insert into oc_share_status(id, recipient, state) values(?, ?, ?) ON DUPLICATE KEY UPDATE state = ?"
+	if sqldb.Engine(m.db) == sqldb.SQLite {
+		query = "insert into oc_share_status(id, recipient, state) values(?, ?, ?) ON CONFLICT(id, recipient) DO UPDATE SET state = ?"
+	}
 
 	stmt, err := m.db.Prepare(query)
 	if err != nil {
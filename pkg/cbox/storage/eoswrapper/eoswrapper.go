@@ -174,6 +174,10 @@ func (w *wrapper) DenyGrant(ctx context.Context, ref *provider.Reference, g *pro
 	return errtypes.NotSupported("eos: deny grant is only enabled for project spaces")
 }
 
+func (w *wrapper) GetQuotaInfo(ctx context.Context, ref *provider.Reference) (*storage.Quota, error) {
+	return storage.QuotaOf(ctx, w.FS, ref)
+}
+
 func (w *wrapper) getMountID(ctx context.Context, r *provider.ResourceInfo) string {
 	if r == nil {
 		return ""
@@ -0,0 +1,101 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package utils
+
+import "github.com/cs3org/reva/pkg/migrate"
+
+// SharesSchema is the migration component of the oc_share tables used by the cbox share and public share managers.
+const SharesSchema = "cbox_shares"
+
+func init() {
+	migrate.Register(SharesSchema, migrate.Migration{
+		Version:     1,
+		Description: "create the share tables",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS oc_share (
+				id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				share_type SMALLINT NOT NULL DEFAULT 0,
+				share_with VARCHAR(255) DEFAULT NULL,
+				uid_owner VARCHAR(64) NOT NULL DEFAULT '',
+				uid_initiator VARCHAR(64) DEFAULT NULL,
+				parent INT DEFAULT NULL,
+				item_type VARCHAR(64) NOT NULL DEFAULT '',
+				item_source VARCHAR(255) DEFAULT NULL,
+				item_target VARCHAR(255) DEFAULT NULL,
+				file_source BIGINT DEFAULT NULL,
+				file_target VARCHAR(512) DEFAULT NULL,
+				permissions SMALLINT NOT NULL DEFAULT 0,
+				stime BIGINT NOT NULL DEFAULT 0,
+				accepted SMALLINT NOT NULL DEFAULT 0,
+				expiration DATETIME DEFAULT NULL,
+				token VARCHAR(32) DEFAULT NULL,
+				mail_send SMALLINT NOT NULL DEFAULT 0,
+				share_name VARCHAR(64) DEFAULT NULL,
+				fileid_prefix VARCHAR(255) DEFAULT NULL,
+				orphan TINYINT DEFAULT NULL,
+				INDEX item_share_type_index (item_type, share_type),
+				INDEX file_source_index (file_source),
+				INDEX token_index (token)
+			)`,
+			`CREATE TABLE IF NOT EXISTS oc_share_status (
+				id INT NOT NULL,
+				recipient VARCHAR(255) NOT NULL,
+				state INT DEFAULT 0,
+				PRIMARY KEY (id, recipient)
+			)`,
+		},
+		Down: []string{
+			"DROP TABLE IF EXISTS oc_share_status",
+			"DROP TABLE IF EXISTS oc_share",
+		},
+		SQLiteUp: []string{
+			`CREATE TABLE IF NOT EXISTS oc_share (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				share_type SMALLINT NOT NULL DEFAULT 0,
+				share_with VARCHAR(255) DEFAULT NULL,
+				uid_owner VARCHAR(64) NOT NULL DEFAULT '',
+				uid_initiator VARCHAR(64) DEFAULT NULL,
+				parent INT DEFAULT NULL,
+				item_type VARCHAR(64) NOT NULL DEFAULT '',
+				item_source VARCHAR(255) DEFAULT NULL,
+				item_target VARCHAR(255) DEFAULT NULL,
+				file_source BIGINT DEFAULT NULL,
+				file_target VARCHAR(512) DEFAULT NULL,
+				permissions SMALLINT NOT NULL DEFAULT 0,
+				stime BIGINT NOT NULL DEFAULT 0,
+				accepted SMALLINT NOT NULL DEFAULT 0,
+				expiration DATETIME DEFAULT NULL,
+				token VARCHAR(32) DEFAULT NULL,
+				mail_send SMALLINT NOT NULL DEFAULT 0,
+				share_name VARCHAR(64) DEFAULT NULL,
+				fileid_prefix VARCHAR(255) DEFAULT NULL,
+				orphan TINYINT DEFAULT NULL
+			)`,
+			"CREATE INDEX IF NOT EXISTS item_share_type_index ON oc_share (item_type, share_type)",
+			"CREATE INDEX IF NOT EXISTS file_source_index ON oc_share (file_source)",
+			"CREATE INDEX IF NOT EXISTS token_index ON oc_share (token)",
+			`CREATE TABLE IF NOT EXISTS oc_share_status (
+				id INT NOT NULL,
+				recipient VARCHAR(255) NOT NULL,
+				state INT DEFAULT 0,
+				PRIMARY KEY (id, recipient)
+			)`,
+		},
+	})
+}
@@ -0,0 +1,139 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package comments holds the threaded comments users write on resources and
+// the users they mention in them.
+package comments
+
+import (
+	"context"
+	"regexp"
+	"time"
+)
+
+const (
+	// ObjectTypeFiles is the type of the comments written on files and folders.
+	ObjectTypeFiles = "files"
+	// ActorTypeUsers is the type of the comments written by users.
+	ActorTypeUsers = "users"
+	// VerbComment is the verb of the comments written by users, as opposed to
+	// the ones recording system actions.
+	VerbComment = "comment"
+	// MentionTypeUser is the type of the mentions of users.
+	MentionTypeUser = "user"
+
+	// MaxMessageLength is the longest message accepted, in characters.
+	MaxMessageLength = 1000
+)
+
+// Mention is a user mentioned in a comment.
+type Mention struct {
+	Type string
+	ID   string
+}
+
+// Comment is a comment on a resource. Replies reference the comment they
+// answer as their parent and the comment starting the thread as their
+// topmost parent.
+type Comment struct {
+	ID              string
+	ParentID        string
+	TopmostParentID string
+	ChildrenCount   int
+	Verb            string
+	ActorType       string
+	ActorID         string
+	// ActorDisplayName is the display name of the actor when the comment was written.
+	ActorDisplayName string
+	Message          string
+	ObjectType       string
+	ObjectID         string
+	CreationTime     time.Time
+	// LatestChildTime is the time of the latest reply, zero when there is none.
+	LatestChildTime time.Time
+}
+
+// Mentions returns the users mentioned in the message of the comment.
+func (c *Comment) Mentions() []Mention {
+	return ExtractMentions(c.Message)
+}
+
+// ListOptions limits the comments listed, the newest are listed first.
+type ListOptions struct {
+	// Limit is the maximum number of comments returned, 0 for all.
+	Limit  int
+	Offset int
+}
+
+// Manager stores the comments.
+type Manager interface {
+	// Add stores a new comment, setting its id, creation time and topmost
+	// parent, and updates the replies counter of its parent.
+	Add(ctx context.Context, c *Comment) (*Comment, error)
+	// Get returns a comment, or an errtypes.NotFound error.
+	Get(ctx context.Context, id string) (*Comment, error)
+	// List returns the comments on an object.
+	List(ctx context.Context, objectType, objectID string, opts *ListOptions) ([]*Comment, error)
+	// Update replaces the message of a comment.
+	Update(ctx context.Context, id, message string) (*Comment, error)
+	// Delete removes a comment and the replies to it.
+	Delete(ctx context.Context, id string) error
+	// DeleteObject removes all the comments on an object, e.g. when it has
+	// been purged.
+	DeleteObject(ctx context.Context, objectType, objectID string) error
+}
+
+// mentionRegex matches @username as well as @"user name" for the names
+// containing spaces. The @ must not be preceded by a word character so that
+// mail addresses are not taken for mentions.
+var mentionRegex = regexp.MustCompile(`(?:^|[^\w@])@(?:"([^"]+)"|([\w.\-]+[\w\-]|[\w\-]))`)
+
+// ExtractMentions returns the users mentioned in a message, each only once
+// and in the order they are first mentioned.
+func ExtractMentions(message string) []Mention {
+	mentions := []Mention{}
+	seen := map[string]bool{}
+	for _, m := range mentionRegex.FindAllStringSubmatch(message, -1) {
+		id := m[1]
+		if id == "" {
+			id = m[2]
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		mentions = append(mentions, Mention{Type: MentionTypeUser, ID: id})
+	}
+	return mentions
+}
+
+// NewMentions returns the mentions of the updated message which were not in
+// the previous one, the users to notify after an edit.
+func NewMentions(previous, updated string) []Mention {
+	old := map[string]bool{}
+	for _, m := range ExtractMentions(previous) {
+		old[m.ID] = true
+	}
+	mentions := []Mention{}
+	for _, m := range ExtractMentions(updated) {
+		if !old[m.ID] {
+			mentions = append(mentions, m)
+		}
+	}
+	return mentions
+}
@@ -0,0 +1,64 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package comments
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractMentions(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected []string
+	}{
+		{"no mentions here", []string{}},
+		{"@alice please review", []string{"alice"}},
+		{"thanks @alice.", []string{"alice"}},
+		{"@alice and @bob, then @alice again", []string{"alice", "bob"}},
+		{"ping @\"John Doe\" about it", []string{"John Doe"}},
+		{"@first.last-name: done", []string{"first.last-name"}},
+		{"mail alice@example.org", []string{}},
+		{"(@carol)", []string{"carol"}},
+		{"@ nobody", []string{}},
+	}
+
+	for _, tt := range tests {
+		ids := []string{}
+		for _, m := range ExtractMentions(tt.message) {
+			if m.Type != MentionTypeUser {
+				t.Errorf("%q: unexpected mention type %s", tt.message, m.Type)
+			}
+			ids = append(ids, m.ID)
+		}
+		if !reflect.DeepEqual(ids, tt.expected) {
+			t.Errorf("%q: expected mentions %v, got %v", tt.message, tt.expected, ids)
+		}
+	}
+}
+
+func TestNewMentions(t *testing.T) {
+	mentions := NewMentions("@alice have a look", "@alice and @bob have a look")
+	if len(mentions) != 1 || mentions[0].ID != "bob" {
+		t.Errorf("expected only bob to be newly mentioned, got %v", mentions)
+	}
+	if mentions := NewMentions("@alice", "@alice"); len(mentions) != 0 {
+		t.Errorf("expected no new mentions, got %v", mentions)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/comments"
+	"github.com/cs3org/reva/pkg/comments/registry"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/google/uuid"
+)
+
+func init() {
+	registry.Register("memory", New)
+}
+
+type manager struct {
+	sync.RWMutex
+	comments map[string]*comments.Comment
+}
+
+// New returns a comments manager keeping the comments in memory, which are
+// lost on restart.
+func New(m map[string]interface{}) (comments.Manager, error) {
+	return &manager{comments: map[string]*comments.Comment{}}, nil
+}
+
+func (m *manager) Add(ctx context.Context, c *comments.Comment) (*comments.Comment, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	n := *c
+	n.ID = uuid.NewString()
+	n.CreationTime = time.Now()
+	n.ChildrenCount = 0
+	n.LatestChildTime = time.Time{}
+	n.TopmostParentID = ""
+	if n.ParentID != "" {
+		parent, ok := m.comments[n.ParentID]
+		if !ok || parent.ObjectType != n.ObjectType || parent.ObjectID != n.ObjectID {
+			return nil, errtypes.NotFound("comment " + n.ParentID)
+		}
+		n.TopmostParentID = parent.TopmostParentID
+		if n.TopmostParentID == "" {
+			n.TopmostParentID = parent.ID
+		}
+		parent.ChildrenCount++
+		parent.LatestChildTime = n.CreationTime
+	}
+	m.comments[n.ID] = &n
+
+	res := n
+	return &res, nil
+}
+
+func (m *manager) Get(ctx context.Context, id string) (*comments.Comment, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	c, ok := m.comments[id]
+	if !ok {
+		return nil, errtypes.NotFound("comment " + id)
+	}
+	res := *c
+	return &res, nil
+}
+
+func (m *manager) List(ctx context.Context, objectType, objectID string, opts *comments.ListOptions) ([]*comments.Comment, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	list := []*comments.Comment{}
+	for _, c := range m.comments {
+		if c.ObjectType == objectType && c.ObjectID == objectID {
+			res := *c
+			list = append(list, &res)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CreationTime.Equal(list[j].CreationTime) {
+			return list[i].ID > list[j].ID
+		}
+		return list[i].CreationTime.After(list[j].CreationTime)
+	})
+
+	if opts != nil {
+		if opts.Offset >= len(list) {
+			return []*comments.Comment{}, nil
+		}
+		list = list[opts.Offset:]
+		if opts.Limit > 0 && opts.Limit < len(list) {
+			list = list[:opts.Limit]
+		}
+	}
+	return list, nil
+}
+
+func (m *manager) Update(ctx context.Context, id, message string) (*comments.Comment, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	c, ok := m.comments[id]
+	if !ok {
+		return nil, errtypes.NotFound("comment " + id)
+	}
+	c.Message = message
+	res := *c
+	return &res, nil
+}
+
+func (m *manager) Delete(ctx context.Context, id string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	c, ok := m.comments[id]
+	if !ok {
+		return errtypes.NotFound("comment " + id)
+	}
+	if parent, ok := m.comments[c.ParentID]; ok {
+		parent.ChildrenCount--
+	}
+	m.delete(id)
+	return nil
+}
+
+// delete removes a comment and, recursively, its replies.
+func (m *manager) delete(id string) {
+	delete(m.comments, id)
+	for _, c := range m.comments {
+		if c.ParentID == id {
+			m.delete(c.ID)
+		}
+	}
+}
+
+func (m *manager) DeleteObject(ctx context.Context, objectType, objectID string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for id, c := range m.comments {
+		if c.ObjectType == objectType && c.ObjectID == objectID {
+			delete(m.comments, id)
+		}
+	}
+	return nil
+}
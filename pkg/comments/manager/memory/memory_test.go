@@ -0,0 +1,87 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/comments"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+func TestThreads(t *testing.T) {
+	ctx := context.Background()
+	m, _ := New(nil)
+
+	add := func(parent, message string) *comments.Comment {
+		c, err := m.Add(ctx, &comments.Comment{
+			ParentID:   parent,
+			Verb:       comments.VerbComment,
+			ActorType:  comments.ActorTypeUsers,
+			ActorID:    "einstein",
+			Message:    message,
+			ObjectType: comments.ObjectTypeFiles,
+			ObjectID:   "storage!file",
+		})
+		if err != nil {
+			t.Fatalf("error adding comment: %v", err)
+		}
+		return c
+	}
+
+	root := add("", "first")
+	reply := add(root.ID, "reply")
+	nested := add(reply.ID, "nested reply")
+	other := add("", "second")
+
+	if reply.TopmostParentID != root.ID || nested.TopmostParentID != root.ID {
+		t.Errorf("expected replies to reference the thread %s, got %s and %s", root.ID, reply.TopmostParentID, nested.TopmostParentID)
+	}
+	if c, _ := m.Get(ctx, root.ID); c.ChildrenCount != 1 || c.LatestChildTime.IsZero() {
+		t.Errorf("expected one reply on the root, got %d", c.ChildrenCount)
+	}
+
+	if _, err := m.Add(ctx, &comments.Comment{ParentID: root.ID, ObjectType: comments.ObjectTypeFiles, ObjectID: "storage!other"}); err == nil {
+		t.Error("expected replies on another object to be rejected")
+	}
+
+	if list, _ := m.List(ctx, comments.ObjectTypeFiles, "storage!file", &comments.ListOptions{Limit: 2, Offset: 1}); len(list) != 2 {
+		t.Errorf("expected 2 comments in the page, got %d", len(list))
+	}
+
+	if err := m.Delete(ctx, reply.ID); err != nil {
+		t.Fatalf("error deleting comment: %v", err)
+	}
+	if _, err := m.Get(ctx, nested.ID); err == nil {
+		t.Error("expected the replies to be deleted with their parent")
+	} else if _, ok := err.(errtypes.IsNotFound); !ok {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+	if c, _ := m.Get(ctx, root.ID); c.ChildrenCount != 0 {
+		t.Errorf("expected no replies left on the root, got %d", c.ChildrenCount)
+	}
+
+	if err := m.DeleteObject(ctx, comments.ObjectTypeFiles, "storage!file"); err != nil {
+		t.Fatalf("error deleting comments of the object: %v", err)
+	}
+	if _, err := m.Get(ctx, other.ID); err == nil {
+		t.Error("expected all comments of the object to be deleted")
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import "github.com/cs3org/reva/pkg/migrate"
+
+// Schema is the migration component of the comments table.
+const Schema = "comments"
+
+func init() {
+	migrate.Register(Schema, migrate.Migration{
+		Version:     1,
+		Description: "create the comments table",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS comments (
+				id VARCHAR(36) NOT NULL PRIMARY KEY,
+				parent_id VARCHAR(36) NOT NULL DEFAULT '',
+				topmost_parent_id VARCHAR(36) NOT NULL DEFAULT '',
+				children_count INT NOT NULL DEFAULT 0,
+				verb VARCHAR(64) NOT NULL,
+				actor_type VARCHAR(64) NOT NULL,
+				actor_id VARCHAR(255) NOT NULL,
+				actor_display_name VARCHAR(255) NOT NULL DEFAULT '',
+				message TEXT NOT NULL,
+				object_type VARCHAR(64) NOT NULL,
+				object_id VARCHAR(255) NOT NULL,
+				creation_time BIGINT NOT NULL,
+				latest_child_time BIGINT NOT NULL DEFAULT 0,
+				INDEX comments_object_index (object_type, object_id),
+				INDEX comments_parent_index (parent_id)
+			)`,
+		},
+		Down: []string{
+			"DROP TABLE IF EXISTS comments",
+		},
+		SQLiteUp: []string{
+			`CREATE TABLE IF NOT EXISTS comments (
+				id VARCHAR(36) NOT NULL PRIMARY KEY,
+				parent_id VARCHAR(36) NOT NULL DEFAULT '',
+				topmost_parent_id VARCHAR(36) NOT NULL DEFAULT '',
+				children_count INT NOT NULL DEFAULT 0,
+				verb VARCHAR(64) NOT NULL,
+				actor_type VARCHAR(64) NOT NULL,
+				actor_id VARCHAR(255) NOT NULL,
+				actor_display_name VARCHAR(255) NOT NULL DEFAULT '',
+				message TEXT NOT NULL,
+				object_type VARCHAR(64) NOT NULL,
+				object_id VARCHAR(255) NOT NULL,
+				creation_time BIGINT NOT NULL,
+				latest_child_time BIGINT NOT NULL DEFAULT 0
+			)`,
+			"CREATE INDEX IF NOT EXISTS comments_object_index ON comments (object_type, object_id)",
+			"CREATE INDEX IF NOT EXISTS comments_parent_index ON comments (parent_id)",
+		},
+		PostgresUp: []string{
+			`CREATE TABLE IF NOT EXISTS comments (
+				id VARCHAR(36) NOT NULL PRIMARY KEY,
+				parent_id VARCHAR(36) NOT NULL DEFAULT '',
+				topmost_parent_id VARCHAR(36) NOT NULL DEFAULT '',
+				children_count INT NOT NULL DEFAULT 0,
+				verb VARCHAR(64) NOT NULL,
+				actor_type VARCHAR(64) NOT NULL,
+				actor_id VARCHAR(255) NOT NULL,
+				actor_display_name VARCHAR(255) NOT NULL DEFAULT '',
+				message TEXT NOT NULL,
+				object_type VARCHAR(64) NOT NULL,
+				object_id VARCHAR(255) NOT NULL,
+				creation_time BIGINT NOT NULL,
+				latest_child_time BIGINT NOT NULL DEFAULT 0
+			)`,
+			"CREATE INDEX IF NOT EXISTS comments_object_index ON comments (object_type, object_id)",
+			"CREATE INDEX IF NOT EXISTS comments_parent_index ON comments (parent_id)",
+		},
+	})
+}
@@ -0,0 +1,275 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/comments"
+	"github.com/cs3org/reva/pkg/comments/registry"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/migrate"
+	"github.com/cs3org/reva/pkg/sqldb"
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("sql", New)
+}
+
+type config struct {
+	// DbEngine is either mysql, the default, sqlite or postgres.
+	DbEngine   string `mapstructure:"db_engine"`
+	DbUsername string `mapstructure:"db_username"`
+	DbPassword string `mapstructure:"db_password"`
+	DbHost     string `mapstructure:"db_host"`
+	DbPort     int    `mapstructure:"db_port"`
+	DbName     string `mapstructure:"db_name"`
+	// DbPath is the file of the sqlite database.
+	DbPath string `mapstructure:"db_path"`
+	// SkipMigrations disables applying the pending schema migrations on startup.
+	SkipMigrations bool `mapstructure:"skip_migrations"`
+}
+
+type manager struct {
+	db *sql.DB
+}
+
+const columns = "id, parent_id, topmost_parent_id, children_count, verb, actor_type, actor_id, actor_display_name, message, object_type, object_id, creation_time, latest_child_time"
+
+// New returns a comments manager storing the comments in a SQL database.
+func New(m map[string]interface{}) (comments.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "comments: error decoding configuration")
+	}
+
+	db, err := sqldb.Open(sqldb.Config{
+		Engine:   c.DbEngine,
+		Username: c.DbUsername,
+		Password: c.DbPassword,
+		Host:     c.DbHost,
+		Port:     c.DbPort,
+		Name:     c.DbName,
+		Path:     c.DbPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.SkipMigrations {
+		if err := migrate.Up(context.Background(), db, Schema); err != nil {
+			return nil, err
+		}
+	}
+	return &manager{db: db}, nil
+}
+
+func (m *manager) Add(ctx context.Context, c *comments.Comment) (*comments.Comment, error) {
+	n := *c
+	n.ID = uuid.NewString()
+	n.CreationTime = time.Unix(time.Now().Unix(), 0)
+	n.ChildrenCount = 0
+	n.LatestChildTime = time.Time{}
+	n.TopmostParentID = ""
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "comments: error starting transaction")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if n.ParentID != "" {
+		parent, err := m.get(ctx, tx, n.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.ObjectType != n.ObjectType || parent.ObjectID != n.ObjectID {
+			return nil, errtypes.NotFound("comment " + n.ParentID)
+		}
+		n.TopmostParentID = parent.TopmostParentID
+		if n.TopmostParentID == "" {
+			n.TopmostParentID = parent.ID
+		}
+		query := "UPDATE comments SET children_count = children_count + 1, latest_child_time = ? WHERE id = ?"
+		if _, err := tx.ExecContext(ctx, sqldb.Rebind(m.db, query), n.CreationTime.Unix(), parent.ID); err != nil {
+			return nil, errors.Wrap(err, "comments: error updating parent")
+		}
+	}
+
+	query := "INSERT INTO comments (" + columns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	if _, err := tx.ExecContext(ctx, sqldb.Rebind(m.db, query),
+		n.ID, n.ParentID, n.TopmostParentID, 0, n.Verb, n.ActorType, n.ActorID, n.ActorDisplayName,
+		n.Message, n.ObjectType, n.ObjectID, n.CreationTime.Unix(), 0); err != nil {
+		return nil, errors.Wrap(err, "comments: error inserting comment")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "comments: error committing comment")
+	}
+	return &n, nil
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (m *manager) get(ctx context.Context, q queryer, id string) (*comments.Comment, error) {
+	query := "SELECT " + columns + " FROM comments WHERE id = ?"
+	c, err := scan(q.QueryRowContext(ctx, sqldb.Rebind(m.db, query), id))
+	if err == sql.ErrNoRows {
+		return nil, errtypes.NotFound("comment " + id)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "comments: error getting comment")
+	}
+	return c, nil
+}
+
+func (m *manager) Get(ctx context.Context, id string) (*comments.Comment, error) {
+	return m.get(ctx, m.db, id)
+}
+
+func (m *manager) List(ctx context.Context, objectType, objectID string, opts *comments.ListOptions) ([]*comments.Comment, error) {
+	query := "SELECT " + columns + " FROM comments WHERE object_type = ? AND object_id = ? ORDER BY creation_time DESC, id DESC"
+	params := []interface{}{objectType, objectID}
+	if opts != nil && (opts.Limit > 0 || opts.Offset > 0) {
+		limit := opts.Limit
+		if limit <= 0 {
+			// not all the engines support an offset without a limit
+			limit = 1<<31 - 1
+		}
+		query += " LIMIT ? OFFSET ?"
+		params = append(params, limit, opts.Offset)
+	}
+
+	rows, err := m.db.QueryContext(ctx, sqldb.Rebind(m.db, query), params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "comments: error listing comments")
+	}
+	defer rows.Close()
+
+	list := []*comments.Comment{}
+	for rows.Next() {
+		c, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+func (m *manager) Update(ctx context.Context, id, message string) (*comments.Comment, error) {
+	query := "UPDATE comments SET message = ? WHERE id = ?"
+	res, err := m.db.ExecContext(ctx, sqldb.Rebind(m.db, query), message, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "comments: error updating comment")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, errtypes.NotFound("comment " + id)
+	}
+	return m.Get(ctx, id)
+}
+
+func (m *manager) Delete(ctx context.Context, id string) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "comments: error starting transaction")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	c, err := m.get(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	// collect the whole subtree of replies level by level
+	ids := []string{id}
+	for level := []string{id}; len(level) > 0; {
+		query := "SELECT id FROM comments WHERE parent_id IN (?" + strings.Repeat(", ?", len(level)-1) + ")"
+		rows, err := tx.QueryContext(ctx, sqldb.Rebind(m.db, query), strs2params(level)...)
+		if err != nil {
+			return errors.Wrap(err, "comments: error listing replies")
+		}
+		next := []string{}
+		for rows.Next() {
+			var child string
+			if err := rows.Scan(&child); err != nil {
+				rows.Close()
+				return err
+			}
+			next = append(next, child)
+		}
+		rows.Close()
+		ids = append(ids, next...)
+		level = next
+	}
+
+	query := "DELETE FROM comments WHERE id IN (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+	if _, err := tx.ExecContext(ctx, sqldb.Rebind(m.db, query), strs2params(ids)...); err != nil {
+		return errors.Wrap(err, "comments: error deleting comment")
+	}
+	if c.ParentID != "" {
+		query := "UPDATE comments SET children_count = children_count - 1 WHERE id = ? AND children_count > 0"
+		if _, err := tx.ExecContext(ctx, sqldb.Rebind(m.db, query), c.ParentID); err != nil {
+			return errors.Wrap(err, "comments: error updating parent")
+		}
+	}
+	return tx.Commit()
+}
+
+func (m *manager) DeleteObject(ctx context.Context, objectType, objectID string) error {
+	query := "DELETE FROM comments WHERE object_type = ? AND object_id = ?"
+	if _, err := m.db.ExecContext(ctx, sqldb.Rebind(m.db, query), objectType, objectID); err != nil {
+		return errors.Wrap(err, "comments: error deleting comments of object")
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scan(s scanner) (*comments.Comment, error) {
+	c := &comments.Comment{}
+	var ctime, latest int64
+	if err := s.Scan(&c.ID, &c.ParentID, &c.TopmostParentID, &c.ChildrenCount, &c.Verb, &c.ActorType, &c.ActorID,
+		&c.ActorDisplayName, &c.Message, &c.ObjectType, &c.ObjectID, &ctime, &latest); err != nil {
+		return nil, err
+	}
+	c.CreationTime = time.Unix(ctime, 0)
+	if latest > 0 {
+		c.LatestChildTime = time.Unix(latest, 0)
+	}
+	return c, nil
+}
+
+func strs2params(s []string) []interface{} {
+	params := make([]interface{}, 0, len(s))
+	for _, v := range s {
+		params = append(params, v)
+	}
+	return params
+}
@@ -0,0 +1,426 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package cost accounts for the resources consumed by the requests served by
+// the process: the database queries issued, the storage operations, the bytes
+// moved and the time spent. The usage is aggregated per user and client
+// application, and the top consumers of each reporting interval are logged and
+// kept for the debug service, to identify abusive clients and hot paths.
+package cost
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// The usages the consumers can be ranked by.
+const (
+	RankByTime       = "time"
+	RankByBytes      = "bytes"
+	RankByDBQueries  = "db_queries"
+	RankByStorageOps = "storage_ops"
+	RankByRequests   = "requests"
+)
+
+// Other is the user and the application the usage is accounted to once the
+// maximum number of consumers of an interval is reached.
+const Other = "other"
+
+// Anonymous is the user of the requests which are not authenticated.
+const Anonymous = "anonymous"
+
+// Config holds the configuration of the cost accounting.
+type Config struct {
+	// Enabled enables the accounting of the requests.
+	Enabled bool `mapstructure:"enabled"`
+	// ReportInterval is the number of seconds between two reports.
+	ReportInterval int `mapstructure:"report_interval"`
+	// Top is the number of consumers in a report.
+	Top int `mapstructure:"top"`
+	// RankBy is the usage the consumers are ranked by: time, bytes,
+	// db_queries, storage_ops or requests.
+	RankBy string `mapstructure:"rank_by"`
+	// MaxEntries is the maximum number of consumers tracked in an interval,
+	// the usage of the other ones is accounted together.
+	MaxEntries int `mapstructure:"max_entries"`
+}
+
+func (c *Config) init() error {
+	if c.ReportInterval <= 0 {
+		c.ReportInterval = 300
+	}
+	if c.Top <= 0 {
+		c.Top = 10
+	}
+	if c.MaxEntries <= 0 {
+		c.MaxEntries = 10000
+	}
+	switch c.RankBy {
+	case "":
+		c.RankBy = RankByTime
+	case RankByTime, RankByBytes, RankByDBQueries, RankByStorageOps, RankByRequests:
+	default:
+		return fmt.Errorf("cost: unknown usage %s to rank the consumers by", c.RankBy)
+	}
+	return nil
+}
+
+// Record accumulates the resources consumed by a request. It is safe for
+// concurrent use, as a request may spread its work over several goroutines.
+type Record struct {
+	dbQueries  int64
+	storageOps int64
+	bytes      int64
+
+	mu   sync.Mutex
+	user string
+}
+
+// NewRecord returns a new empty record.
+func NewRecord() *Record {
+	return &Record{}
+}
+
+// AddDBQueries counts database queries issued by the request.
+func (r *Record) AddDBQueries(n int64) {
+	atomic.AddInt64(&r.dbQueries, n)
+}
+
+// AddStorageOps counts storage operations performed by the request.
+func (r *Record) AddStorageOps(n int64) {
+	atomic.AddInt64(&r.storageOps, n)
+}
+
+// AddBytes counts bytes moved by the request.
+func (r *Record) AddBytes(n int64) {
+	atomic.AddInt64(&r.bytes, n)
+}
+
+// SetUser sets the user the request is accounted to.
+func (r *Record) SetUser(user string) {
+	r.mu.Lock()
+	r.user = user
+	r.mu.Unlock()
+}
+
+func (r *Record) getUser() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.user
+}
+
+type recordKey struct{}
+
+// ContextSetRecord stores the record of a request in the context.
+func ContextSetRecord(ctx context.Context, r *Record) context.Context {
+	return context.WithValue(ctx, recordKey{}, r)
+}
+
+// ContextGetRecord returns the record of the request stored in the context.
+func ContextGetRecord(ctx context.Context) (*Record, bool) {
+	r, ok := ctx.Value(recordKey{}).(*Record)
+	return r, ok
+}
+
+// AddDBQueries counts database queries in the record of the request, if any.
+func AddDBQueries(ctx context.Context, n int64) {
+	if r, ok := ContextGetRecord(ctx); ok {
+		r.AddDBQueries(n)
+	}
+}
+
+// AddStorageOps counts storage operations in the record of the request, if any.
+func AddStorageOps(ctx context.Context, n int64) {
+	if r, ok := ContextGetRecord(ctx); ok {
+		r.AddStorageOps(n)
+	}
+}
+
+// AddBytes counts bytes moved in the record of the request, if any.
+func AddBytes(ctx context.Context, n int64) {
+	if r, ok := ContextGetRecord(ctx); ok {
+		r.AddBytes(n)
+	}
+}
+
+// SetUser sets the user the request is accounted to in its record, if any.
+func SetUser(ctx context.Context, user string) {
+	if r, ok := ContextGetRecord(ctx); ok {
+		r.SetUser(user)
+	}
+}
+
+// Usage is the usage of a consumer, or the total usage, over an interval.
+type Usage struct {
+	User       string `json:"user,omitempty"`
+	App        string `json:"app,omitempty"`
+	Requests   int64  `json:"requests"`
+	DBQueries  int64  `json:"db_queries"`
+	StorageOps int64  `json:"storage_ops"`
+	Bytes      int64  `json:"bytes"`
+	// TimeMS is the time spent serving the requests, in milliseconds.
+	TimeMS int64 `json:"time_ms"`
+
+	time time.Duration
+}
+
+func (u *Usage) add(o *Usage) {
+	u.Requests += o.Requests
+	u.DBQueries += o.DBQueries
+	u.StorageOps += o.StorageOps
+	u.Bytes += o.Bytes
+	u.time += o.time
+	u.TimeMS = u.time.Milliseconds()
+}
+
+func (u *Usage) value(rankBy string) int64 {
+	switch rankBy {
+	case RankByBytes:
+		return u.Bytes
+	case RankByDBQueries:
+		return u.DBQueries
+	case RankByStorageOps:
+		return u.StorageOps
+	case RankByRequests:
+		return u.Requests
+	default:
+		return int64(u.time)
+	}
+}
+
+// Report lists the top consumers of an interval.
+type Report struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// RankBy is the usage the consumers are ranked by.
+	RankBy string `json:"rank_by"`
+	// Consumers is the number of consumers in the interval.
+	Consumers int `json:"consumers"`
+	// Total is the usage of all the consumers.
+	Total *Usage   `json:"total"`
+	Top   []*Usage `json:"top"`
+}
+
+type consumer struct {
+	user string
+	app  string
+}
+
+// accountant aggregates the usage of the consumers per interval.
+type accountant struct {
+	c *Config
+
+	mu    sync.Mutex
+	from  time.Time
+	usage map[consumer]*Usage
+	last  *Report
+}
+
+func newAccountant(c *Config, now time.Time) *accountant {
+	return &accountant{c: c, from: now, usage: map[consumer]*Usage{}}
+}
+
+func (a *accountant) account(r *Record, app string, d time.Duration) {
+	k := consumer{user: r.getUser(), app: app}
+	if k.user == "" {
+		k.user = Anonymous
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	u, ok := a.usage[k]
+	if !ok {
+		if len(a.usage) >= a.c.MaxEntries {
+			k = consumer{user: Other, app: Other}
+			u, ok = a.usage[k]
+		}
+		if !ok {
+			u = &Usage{User: k.user, App: k.app}
+			a.usage[k] = u
+		}
+	}
+	u.add(&Usage{
+		Requests:   1,
+		DBQueries:  atomic.LoadInt64(&r.dbQueries),
+		StorageOps: atomic.LoadInt64(&r.storageOps),
+		Bytes:      atomic.LoadInt64(&r.bytes),
+		time:       d,
+	})
+}
+
+// report returns the report of the current interval. The caller must hold the lock.
+func (a *accountant) report(now time.Time) *Report {
+	rep := &Report{From: a.from, To: now, RankBy: a.c.RankBy, Consumers: len(a.usage), Total: &Usage{}}
+	all := make([]*Usage, 0, len(a.usage))
+	for _, u := range a.usage {
+		rep.Total.add(u)
+		c := *u
+		all = append(all, &c)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		vi, vj := all[i].value(a.c.RankBy), all[j].value(a.c.RankBy)
+		if vi != vj {
+			return vi > vj
+		}
+		if all[i].User != all[j].User {
+			return all[i].User < all[j].User
+		}
+		return all[i].App < all[j].App
+	})
+	if len(all) > a.c.Top {
+		all = all[:a.c.Top]
+	}
+	rep.Top = all
+	return rep
+}
+
+// current returns the report of the interval in progress.
+func (a *accountant) current(now time.Time) *Report {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.report(now)
+}
+
+// rotate closes the current interval and returns its report.
+func (a *accountant) rotate(now time.Time) *Report {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	rep := a.report(now)
+	a.last = rep
+	a.from = now
+	a.usage = map[consumer]*Usage{}
+	return rep
+}
+
+func (a *accountant) lastReport() *Report {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.last
+}
+
+var global *accountant
+
+// Configure starts accounting for the requests and logging the reports of the
+// top consumers. It has to be called before the requests are served.
+func Configure(c *Config, log *zerolog.Logger) error {
+	if err := c.init(); err != nil {
+		return err
+	}
+	global = newAccountant(c, time.Now())
+	go report(global, time.Duration(c.ReportInterval)*time.Second, log)
+	return nil
+}
+
+// Enabled returns whether the requests are accounted for.
+func Enabled() bool {
+	return global != nil
+}
+
+// Account accounts the resources consumed by a request, served in the given
+// time, to its user and the given client application.
+func Account(r *Record, app string, d time.Duration) {
+	if global == nil {
+		return
+	}
+	global.account(r, app, d)
+}
+
+// Current returns the report of the interval in progress, nil if the requests
+// are not accounted for.
+func Current() *Report {
+	if global == nil {
+		return nil
+	}
+	return global.current(time.Now())
+}
+
+// Last returns the report of the last complete interval, nil if there is none yet.
+func Last() *Report {
+	if global == nil {
+		return nil
+	}
+	return global.lastReport()
+}
+
+// report periodically logs the top consumers of the interval and starts a new one.
+func report(a *accountant, interval time.Duration, log *zerolog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		rep := a.rotate(now)
+		for i, u := range rep.Top {
+			log.Info().Int("rank", i+1).Str("user", u.User).Str("app", u.App).
+				Int64("requests", u.Requests).Int64("db_queries", u.DBQueries).Int64("storage_ops", u.StorageOps).
+				Int64("bytes", u.Bytes).Int64("time_ms", u.TimeMS).Str("rank_by", rep.RankBy).
+				Msg("cost: top consumer")
+		}
+	}
+}
+
+// browserEngines are the products found in the user agents of the browsers.
+var browserEngines = []string{"AppleWebKit/", "Gecko/", "Trident/"}
+
+// ClientApp returns the client application of a user agent: browser for the
+// browsers, otherwise the name of its first product, skipping the Mozilla
+// compatibility token, e.g. mirall for the desktop clients or curl.
+func ClientApp(userAgent string) string {
+	if strings.TrimSpace(userAgent) == "" {
+		return "unknown"
+	}
+
+	products := []string{}
+	depth := 0
+	for _, f := range strings.Fields(userAgent) {
+		switch {
+		case strings.HasPrefix(f, "("):
+			depth += strings.Count(f, "(") - strings.Count(f, ")")
+			continue
+		case depth > 0:
+			depth += strings.Count(f, "(") - strings.Count(f, ")")
+			continue
+		}
+		products = append(products, f)
+	}
+	if len(products) == 0 {
+		return "unknown"
+	}
+
+	if strings.HasPrefix(products[0], "Mozilla/") {
+		for _, e := range browserEngines {
+			if strings.Contains(userAgent, e) {
+				return "browser"
+			}
+		}
+		if len(products) > 1 {
+			products = products[1:]
+		}
+	}
+	name := strings.ToLower(strings.SplitN(products[0], "/", 2)[0])
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
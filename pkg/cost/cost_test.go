@@ -0,0 +1,129 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package cost
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func record(user string, queries, ops, bytes int64) *Record {
+	r := NewRecord()
+	ctx := ContextSetRecord(context.Background(), r)
+	SetUser(ctx, user)
+	AddDBQueries(ctx, queries)
+	AddStorageOps(ctx, ops)
+	AddBytes(ctx, bytes)
+	return r
+}
+
+func TestReport(t *testing.T) {
+	c := &Config{Top: 2, RankBy: RankByDBQueries}
+	if err := c.init(); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	a := newAccountant(c, now)
+
+	a.account(record("einstein", 5, 1, 100), "mirall", time.Second)
+	a.account(record("einstein", 5, 1, 100), "mirall", time.Second)
+	a.account(record("marie", 20, 0, 10), "browser", time.Millisecond)
+	a.account(record("", 1, 0, 0), "curl", time.Millisecond)
+	a.account(record("einstein", 1, 0, 0), "browser", time.Millisecond)
+
+	rep := a.rotate(now.Add(time.Minute))
+	if rep.Consumers != 4 {
+		t.Fatalf("expected 4 consumers, got %d", rep.Consumers)
+	}
+	if len(rep.Top) != 2 {
+		t.Fatalf("expected the top 2 consumers, got %d", len(rep.Top))
+	}
+	if u := rep.Top[0]; u.User != "marie" || u.App != "browser" || u.DBQueries != 20 {
+		t.Errorf("unexpected first consumer %+v", u)
+	}
+	if u := rep.Top[1]; u.User != "einstein" || u.App != "mirall" || u.Requests != 2 || u.DBQueries != 10 || u.StorageOps != 2 || u.Bytes != 200 || u.TimeMS != 2000 {
+		t.Errorf("unexpected second consumer %+v", u)
+	}
+	if tot := rep.Total; tot.Requests != 5 || tot.DBQueries != 32 || tot.Bytes != 210 {
+		t.Errorf("unexpected total %+v", tot)
+	}
+
+	if a.lastReport() != rep {
+		t.Error("expected the rotated report to be the last one")
+	}
+	if cur := a.current(now.Add(2 * time.Minute)); cur.Consumers != 0 || !cur.From.Equal(now.Add(time.Minute)) {
+		t.Errorf("expected a new empty interval, got %+v", cur)
+	}
+}
+
+func TestAnonymous(t *testing.T) {
+	c := &Config{}
+	_ = c.init()
+	a := newAccountant(c, time.Now())
+	a.account(NewRecord(), "curl", time.Millisecond)
+	if rep := a.current(time.Now()); rep.Top[0].User != Anonymous {
+		t.Errorf("expected the anonymous user, got %s", rep.Top[0].User)
+	}
+}
+
+func TestMaxEntries(t *testing.T) {
+	c := &Config{MaxEntries: 2, RankBy: RankByRequests}
+	_ = c.init()
+	a := newAccountant(c, time.Now())
+	for _, u := range []string{"a", "b", "c", "d", "a"} {
+		a.account(record(u, 0, 0, 0), "curl", time.Millisecond)
+	}
+
+	rep := a.current(time.Now())
+	if rep.Consumers != 3 {
+		t.Fatalf("expected 2 consumers and the others, got %d", rep.Consumers)
+	}
+	if u := rep.Top[0]; u.User != "a" && u.User != Other || u.Requests != 2 {
+		t.Errorf("unexpected first consumer %+v", u)
+	}
+	if rep.Total.Requests != 5 {
+		t.Errorf("expected 5 requests, got %d", rep.Total.Requests)
+	}
+}
+
+func TestRankBy(t *testing.T) {
+	if err := (&Config{RankBy: "cpu"}).init(); err == nil {
+		t.Error("expected an error for an unknown usage")
+	}
+}
+
+func TestClientApp(t *testing.T) {
+	tests := map[string]string{
+		"": "unknown",
+		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/96.0.4664.45 Safari/537.36": "browser",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:94.0) Gecko/20100101 Firefox/94.0":                                     "browser",
+		"Mozilla/5.0 (Macintosh) mirall/2.9.1 (build 5500) (ownCloud, osx-20.6.0 ClientArchitecture: x86_64)":      "mirall",
+		"Mozilla/5.0 (Android) ownCloud-android/2.19.0":                                                            "owncloud-android",
+		"curl/7.79.1":       "curl",
+		"grpc-go/1.42.0":    "grpc-go",
+		"rclone/v1.57.0":    "rclone",
+		"(weird) agent/1.0": "agent",
+	}
+	for ua, app := range tests {
+		if got := ClientApp(ua); got != app {
+			t.Errorf("expected %s for %q, got %s", app, ua, got)
+		}
+	}
+}
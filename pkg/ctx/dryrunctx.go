@@ -0,0 +1,54 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ctx
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// DryRunHeader is the header used to ask the gateway to only evaluate a
+// destructive request and report what it would do, without executing it.
+const DryRunHeader = "dry-run"
+
+// DryRunSupportedHeader is the header of the responses with which the gateway
+// acknowledges that it evaluates the dry-run requests.
+const DryRunSupportedHeader = "dry-run-supported"
+
+// ContextGetDryRun returns whether the request is a dry-run, falling back to
+// the incoming grpc metadata.
+func ContextGetDryRun(ctx context.Context) bool {
+	if dryRun, ok := ctx.Value(dryRunKey).(bool); ok {
+		return dryRun
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if lst := md.Get(DryRunHeader); len(lst) != 0 {
+			return lst[0] == "true"
+		}
+	}
+	return false
+}
+
+// ContextSetDryRun marks the request as a dry-run and adds the flag to the
+// outgoing grpc metadata so that it is forwarded to the gateway.
+func ContextSetDryRun(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, dryRunKey, true)
+	return metadata.AppendToOutgoingContext(ctx, DryRunHeader, "true")
+}
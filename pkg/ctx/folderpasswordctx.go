@@ -0,0 +1,51 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ctx
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// FolderPasswordHeader is the header carrying the password unlocking the
+// password protected subfolders of a public link.
+const FolderPasswordHeader = "x-folder-password"
+
+// ContextGetFolderPassword returns the folder password of the request,
+// falling back to the incoming grpc metadata.
+func ContextGetFolderPassword(ctx context.Context) (string, bool) {
+	if pw, ok := ctx.Value(folderPasswordKey).(string); ok {
+		return pw, true
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if lst := md.Get(FolderPasswordHeader); len(lst) != 0 {
+			return lst[0], true
+		}
+	}
+	return "", false
+}
+
+// ContextSetFolderPassword stores the folder password in the context and adds
+// it to the outgoing grpc metadata so that it reaches the public storage
+// provider.
+func ContextSetFolderPassword(ctx context.Context, pw string) context.Context {
+	ctx = context.WithValue(ctx, folderPasswordKey, pw)
+	return metadata.AppendToOutgoingContext(ctx, FolderPasswordHeader, pw)
+}
@@ -0,0 +1,50 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ctx
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// IdempotencyKeyHeader is the header used across grpc and http services to
+// identify the retries of a mutating request, which must not be applied twice.
+const IdempotencyKeyHeader = "idempotency-key"
+
+// ContextGetIdempotencyKey returns the idempotency key if set in the given
+// context, falling back to the incoming grpc metadata.
+func ContextGetIdempotencyKey(ctx context.Context) (string, bool) {
+	if key, ok := ctx.Value(idempotencyKey).(string); ok && key != "" {
+		return key, true
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if lst := md.Get(IdempotencyKeyHeader); len(lst) != 0 && lst[0] != "" {
+			return lst[0], true
+		}
+	}
+	return "", false
+}
+
+// ContextSetIdempotencyKey stores the idempotency key in the context and adds
+// it to the outgoing grpc metadata so that it is forwarded to the gateway.
+func ContextSetIdempotencyKey(ctx context.Context, key string) context.Context {
+	ctx = context.WithValue(ctx, idempotencyKey, key)
+	return metadata.AppendToOutgoingContext(ctx, IdempotencyKeyHeader, key)
+}
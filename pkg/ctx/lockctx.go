@@ -0,0 +1,50 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ctx
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// LockIDHeader is the header carrying the id of the lock the client holds on
+// the resource it is modifying.
+const LockIDHeader = "lock-id"
+
+// ContextGetLockID returns the lock id submitted with the request,
+// falling back to the incoming grpc metadata.
+func ContextGetLockID(ctx context.Context) (string, bool) {
+	if id, ok := ctx.Value(lockIDKey).(string); ok {
+		return id, true
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if lst := md.Get(LockIDHeader); len(lst) != 0 {
+			return lst[0], true
+		}
+	}
+	return "", false
+}
+
+// ContextSetLockID stores the lock id in the context and adds it to the
+// outgoing grpc metadata so that it reaches the storage provider.
+func ContextSetLockID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, lockIDKey, id)
+	return metadata.AppendToOutgoingContext(ctx, LockIDHeader, id)
+}
@@ -0,0 +1,76 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ctx
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDHeader is the header used across grpc and http services
+// to correlate all the work done on behalf of a single request.
+const RequestIDHeader = "x-request-id"
+
+// maxRequestIDLength limits the size of the request IDs accepted from clients.
+const maxRequestIDLength = 128
+
+// IsValidRequestID checks whether a request ID received from a client is sane:
+// not longer than 128 bytes and made of printable ASCII characters without
+// spaces, as it ends up in logs and in the arguments of commands.
+func IsValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// ContextGetRequestID returns the request ID if set in the given context,
+// falling back to the incoming grpc metadata if it carries a valid one.
+func ContextGetRequestID(ctx context.Context) (string, bool) {
+	if id, ok := ctx.Value(requestIDKey).(string); ok && id != "" {
+		return id, true
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if lst := md.Get(RequestIDHeader); len(lst) != 0 && IsValidRequestID(lst[0]) {
+			return lst[0], true
+		}
+	}
+	return "", false
+}
+
+// ContextSetRequestID stores the request ID in the context and adds it to the
+// outgoing grpc metadata so that it is forwarded to the called services.
+func ContextSetRequestID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey, id)
+	return metadata.AppendToOutgoingContext(ctx, RequestIDHeader, id)
+}
+
+// AddRequestIDHeader sets the request ID stored in the context, if any, on the headers of an outgoing http request.
+func AddRequestIDHeader(ctx context.Context, h http.Header) {
+	if id, ok := ContextGetRequestID(ctx); ok && h.Get(RequestIDHeader) == "" {
+		h.Set(RequestIDHeader, id)
+	}
+}
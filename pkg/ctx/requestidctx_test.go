@@ -0,0 +1,67 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ctx
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRequestIDPropagation(t *testing.T) {
+	if _, ok := ContextGetRequestID(context.Background()); ok {
+		t.Fatal("expected no request id in an empty context")
+	}
+
+	ctx := ContextSetRequestID(context.Background(), "abc")
+	if id, ok := ContextGetRequestID(ctx); !ok || id != "abc" {
+		t.Fatalf("expected request id abc, got %q", id)
+	}
+
+	// The outgoing metadata of the caller becomes the incoming metadata of the callee
+	md, _ := metadata.FromOutgoingContext(ctx)
+	callee := metadata.NewIncomingContext(context.Background(), md)
+	if id, ok := ContextGetRequestID(callee); !ok || id != "abc" {
+		t.Fatalf("expected forwarded request id abc, got %q", id)
+	}
+
+	h := http.Header{}
+	AddRequestIDHeader(callee, h)
+	if got := h.Get(RequestIDHeader); got != "abc" {
+		t.Fatalf("expected request id header abc, got %q", got)
+	}
+}
+
+func TestInvalidRequestID(t *testing.T) {
+	for _, id := range []string{"", "a b", "a\nb", "é", strings.Repeat("a", 129)} {
+		if IsValidRequestID(id) {
+			t.Errorf("expected %q to be invalid", id)
+		}
+		callee := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDHeader, id))
+		if got, ok := ContextGetRequestID(callee); ok {
+			t.Errorf("expected the invalid request id %q to be ignored, got %q", id, got)
+		}
+	}
+	if !IsValidRequestID(strings.Repeat("a", 128)) {
+		t.Error("expected a request id of 128 bytes to be valid")
+	}
+}
@@ -30,6 +30,12 @@ const (
 	userKey key = iota
 	tokenKey
 	idKey
+	requestIDKey
+	idempotencyKey
+	dryRunKey
+	clientIPKey
+	folderPasswordKey
+	lockIDKey
 )
 
 // ContextGetUser returns the user if set in the given context.
@@ -829,3 +829,63 @@ func (driver *rclone) remotePathIsFolder(remote string, remotePath string, remot
 	// in all other cases the remote path is a directory
 	return true, nil
 }
+
+// TransferSize returns the total size in bytes of the files to be transferred from the source path.
+func (driver *rclone) TransferSize(ctx context.Context, srcRemote string, srcPath string, srcToken string) (uint64, error) {
+	type rcloneSizeReqJSON struct {
+		Fs string `json:"fs"`
+	}
+	rcloneReq := &rcloneSizeReqJSON{
+		Fs: fmt.Sprintf(":webdav,headers=\"x-access-token,%v\",url=\"%v\":%v", srcToken, srcRemote, srcPath),
+	}
+	data, err := json.Marshal(rcloneReq)
+	if err != nil {
+		return 0, errors.Wrap(err, "rclone: error marshalling rclone req data")
+	}
+
+	sizeMethod := "/operations/size"
+
+	u, err := url.Parse(driver.config.Endpoint)
+	if err != nil {
+		return 0, errors.Wrap(err, "rclone driver: error parsing driver endpoint")
+	}
+	u.Path = path.Join(u.Path, sizeMethod)
+	requestURL := u.String()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", requestURL, bytes.NewReader(data))
+	if err != nil {
+		return 0, errors.Wrap(err, "rclone driver: error framing post request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	req.SetBasicAuth(driver.config.AuthUser, driver.config.AuthPass)
+
+	res, err := driver.client.Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "rclone driver: error sending post request")
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var errorResData rcloneHTTPErrorRes
+		if err = json.NewDecoder(res.Body).Decode(&errorResData); err != nil {
+			return 0, errors.Wrap(err, "rclone driver: error decoding response data")
+		}
+		return 0, errors.Wrap(errors.Errorf("status: %v, error: %v", errorResData.Status, errorResData.Error), "rclone driver: rclone request responded with error")
+	}
+
+	type rcloneSizeResJSON struct {
+		Count int64 `json:"count"`
+		Bytes int64 `json:"bytes"`
+	}
+
+	var resData rcloneSizeResJSON
+	if err = json.NewDecoder(res.Body).Decode(&resData); err != nil {
+		return 0, errors.Wrap(err, "rclone driver: error decoding response data")
+	}
+	if resData.Bytes < 0 {
+		return 0, nil
+	}
+	return uint64(resData.Bytes), nil
+}
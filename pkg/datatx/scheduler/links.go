@@ -0,0 +1,207 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package scheduler
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/mentix/key"
+	"github.com/cs3org/reva/pkg/mentix/latency"
+	"github.com/cs3org/reva/pkg/mentix/utils/network"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// links holds the links between the local site and the remote ones, as
+// measured by Mentix, keyed by the hosts of the remote sites.
+type links struct {
+	conf *Config
+	log  *zerolog.Logger
+
+	mu    sync.RWMutex
+	links map[string]latency.Link
+}
+
+func newLinks(c *Config, log *zerolog.Logger) *links {
+	return &links{
+		conf:  c,
+		log:   log,
+		links: map[string]latency.Link{},
+	}
+}
+
+// get returns the link with the site of the given remote host, if Mentix knows it.
+func (l *links) get(remote string) (latency.Link, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	link, ok := l.links[strings.ToLower(remote)]
+	return link, ok
+}
+
+func (l *links) refreshPeriodically(stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Duration(l.conf.Mentix.RefreshInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if err := l.refresh(); err != nil {
+			// the last known links are kept until Mentix answers again
+			l.log.Warn().Err(err).Msg("scheduler: error querying the links from Mentix")
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *links) refresh() error {
+	signer, err := key.NewRequestSigner(&l.conf.Mentix.Signing)
+	if err != nil {
+		return errors.Wrap(err, "unable to create the request signer")
+	}
+
+	hosts, err := l.fetchSiteHosts(signer)
+	if err != nil {
+		return err
+	}
+	measured, err := l.fetchLinks(signer)
+	if err != nil {
+		return err
+	}
+
+	found := map[string]latency.Link{}
+	for _, link := range measured {
+		var site string
+		switch l.conf.Mentix.Site {
+		case link.Source:
+			site = link.Target
+		case link.Target:
+			site = link.Source
+		default:
+			continue
+		}
+		for _, host := range hosts[site] {
+			found[host] = *link
+		}
+	}
+
+	l.mu.Lock()
+	l.links = found
+	l.mu.Unlock()
+	return nil
+}
+
+// fetchSiteHosts returns the hosts of the services of every site in the mesh.
+func (l *links) fetchSiteHosts(signer *key.RequestSigner) (map[string][]string, error) {
+	data, err := l.read(l.conf.Mentix.DataEndpoint, network.URLParams{}, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode the data into a simplified, reduced data type
+	type meshData struct {
+		Operators []struct {
+			Sites []struct {
+				ID       string
+				Services []struct {
+					URL                 string
+					Host                string
+					AdditionalEndpoints []struct {
+						URL string
+					}
+				}
+			}
+		}
+	}
+	mesh := meshData{}
+	if err := json.Unmarshal(data, &mesh); err != nil {
+		return nil, errors.Wrap(err, "error while decoding the Mentix mesh data")
+	}
+
+	hosts := map[string][]string{}
+	for _, op := range mesh.Operators {
+		for _, site := range op.Sites {
+			for _, service := range site.Services {
+				urls := []string{service.URL, service.Host}
+				for _, endpoint := range service.AdditionalEndpoints {
+					urls = append(urls, endpoint.URL)
+				}
+				for _, u := range urls {
+					if host := hostOf(u); host != "" {
+						hosts[site.ID] = append(hosts[site.ID], host)
+					}
+				}
+			}
+		}
+	}
+	return hosts, nil
+}
+
+// fetchLinks returns the links from or to the local site.
+func (l *links) fetchLinks(signer *key.RequestSigner) ([]*latency.Link, error) {
+	data, err := l.read(l.conf.Mentix.LatencyEndpoint, network.URLParams{"action": "site", "id": l.conf.Mentix.Site}, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	var measured []*latency.Link
+	if err := json.Unmarshal(data, &measured); err != nil {
+		return nil, errors.Wrap(err, "error while decoding the Mentix latency matrix")
+	}
+	return measured, nil
+}
+
+func (l *links) read(endpoint string, params network.URLParams, signer *key.RequestSigner) ([]byte, error) {
+	mentixURL, err := network.GenerateURL(l.conf.Mentix.URL, endpoint, params)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to generate Mentix URL")
+	}
+	data, err := network.ReadSignedEndpoint(mentixURL, signer, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the Mentix endpoint")
+	}
+	return data, nil
+}
+
+// windowOpen returns whether the link is good enough to start transfers over it.
+func (s *Scheduler) windowOpen(l latency.Link) bool {
+	if l.Probes == 0 {
+		return true
+	}
+	if !l.Available || l.Availability < s.conf.MinAvailability {
+		return false
+	}
+	return s.conf.MaxRTT <= 0 || l.LastRTT <= s.conf.MaxRTT
+}
+
+// hostOf returns the lower-cased host of a URL or of a bare host name.
+func hostOf(s string) string {
+	if s == "" {
+		return ""
+	}
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		return strings.ToLower(u.Host)
+	}
+	return strings.ToLower(strings.SplitN(s, "/", 2)[0])
+}
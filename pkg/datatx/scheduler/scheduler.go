@@ -0,0 +1,509 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package scheduler queues the data transfers with the remote providers, so
+// that only a limited number of them run concurrently with every provider.
+// Small transfers are started first, and transfers are held back while the
+// link with the remote site is unreliable according to Mentix.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	datatx "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	txdriver "github.com/cs3org/reva/pkg/datatx"
+	"github.com/cs3org/reva/pkg/datatx/scheduler/proto"
+	"github.com/cs3org/reva/pkg/mentix/key"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// The states of a transfer.
+const (
+	StateQueued  = "queued"
+	StateRunning = "running"
+	StateDone    = "done"
+)
+
+// Sizer is implemented by the transfer drivers able to tell the size of a
+// transfer before starting it.
+type Sizer interface {
+	TransferSize(ctx context.Context, srcRemote, srcPath, srcToken string) (uint64, error)
+}
+
+// Config configures the scheduling of the transfers.
+type Config struct {
+	MaxPerRemote int    `mapstructure:"max_per_remote" docs:"0;Maximum number of concurrent transfers with a remote provider. 0 disables the scheduler."`
+	MaxTotal     int    `mapstructure:"max_total" docs:"0;Maximum number of concurrent transfers with all the remote providers. 0 means no limit."`
+	MaxWait      int    `mapstructure:"max_wait" docs:"3600;Seconds after which a queued transfer is started before smaller ones, so that big transfers are not starved."`
+	Interval     int    `mapstructure:"interval" docs:"10;Seconds between two checks of the running transfers."`
+	File         string `mapstructure:"file" docs:"/var/tmp/reva/datatx-queue.json;The file where the queue is persisted."`
+
+	Mentix struct {
+		URL             string            `mapstructure:"url" docs:";The URL of Mentix. If empty, the transfers are not held back depending on the links with the remote sites."`
+		DataEndpoint    string            `mapstructure:"data_endpoint" docs:"/sites;The endpoint of Mentix serving the mesh data, used to find the site of a remote provider."`
+		LatencyEndpoint string            `mapstructure:"latency_endpoint" docs:"/latency;The endpoint of Mentix serving the latency matrix."`
+		Site            string            `mapstructure:"site" docs:";The ID of the local site in the mesh."`
+		RefreshInterval int               `mapstructure:"refresh_interval" docs:"300;Seconds between two queries of the links to Mentix."`
+		Signing         key.SigningConfig `mapstructure:"signing" docs:";The keys used to sign the requests sent to Mentix."`
+	} `mapstructure:"mentix"`
+	MinAvailability float64 `mapstructure:"min_availability" docs:"0.9;Transfers with a remote site are held back while the availability of the link, between 0 and 1, is lower."`
+	MaxRTT          float64 `mapstructure:"max_rtt" docs:"0;Transfers with a remote site are held back while the round-trip time of the link in milliseconds is higher. 0 means no limit."`
+}
+
+// Enabled returns whether the configuration requires scheduling the transfers.
+func (c *Config) Enabled() bool {
+	return c.MaxPerRemote > 0
+}
+
+func (c *Config) init() {
+	if c.MaxWait <= 0 {
+		c.MaxWait = 3600
+	}
+	if c.Interval <= 0 {
+		c.Interval = 10
+	}
+	if c.File == "" {
+		c.File = "/var/tmp/reva/datatx-queue.json"
+	}
+	if c.Mentix.DataEndpoint == "" {
+		c.Mentix.DataEndpoint = "/sites"
+	}
+	if c.Mentix.LatencyEndpoint == "" {
+		c.Mentix.LatencyEndpoint = "/latency"
+	}
+	if c.Mentix.RefreshInterval <= 0 {
+		c.Mentix.RefreshInterval = 300
+	}
+	if c.MinAvailability == 0 {
+		c.MinAvailability = 0.9
+	}
+}
+
+// transfer is a transfer known to the scheduler; its ID is the one returned
+// to the clients, the driver knows it under DriverID once it is started.
+type transfer struct {
+	ID       string
+	DriverID string
+	Remote   string
+	State    string
+	Size     uint64
+	Sized    bool
+	Enqueued time.Time
+
+	SrcRemote  string
+	SrcPath    string
+	SrcToken   string
+	DestRemote string
+	DestPath   string
+	DestToken  string
+}
+
+// Scheduler is a transfer driver queuing the transfers and starting them
+// through the given driver.
+type Scheduler struct {
+	next  txdriver.Manager
+	conf  *Config
+	log   *zerolog.Logger
+	links *links
+
+	mu        sync.Mutex
+	transfers map[string]*transfer
+
+	kick chan struct{}
+	stop chan struct{}
+}
+
+// New returns a scheduler starting the transfers through next.
+func New(next txdriver.Manager, c *Config, log *zerolog.Logger) (*Scheduler, error) {
+	c.init()
+
+	transfers := map[string]*transfer{}
+	if data, err := ioutil.ReadFile(c.File); err == nil {
+		if err := json.Unmarshal(data, &transfers); err != nil {
+			return nil, errors.Wrap(err, "scheduler: error decoding the queue "+c.File)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "scheduler: error reading the queue "+c.File)
+	}
+
+	s := &Scheduler{
+		next:      next,
+		conf:      c,
+		log:       log,
+		transfers: transfers,
+		kick:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+	}
+	if c.Mentix.URL != "" {
+		s.links = newLinks(c, log)
+		go s.links.refreshPeriodically(s.stop)
+	}
+	go s.run()
+	return s, nil
+}
+
+// Close stops the scheduling; the queue is kept and resumed at the next start.
+func (s *Scheduler) Close() {
+	close(s.stop)
+}
+
+// StartTransfer queues a transfer, which is started as soon as the limits allow.
+func (s *Scheduler) StartTransfer(ctx context.Context, srcRemote string, srcPath string, srcToken string, destRemote string, destPath string, destToken string) (*datatx.TxInfo, error) {
+	t := &transfer{
+		ID:         uuid.New().String(),
+		Remote:     remoteOf(srcRemote),
+		State:      StateQueued,
+		Enqueued:   time.Now(),
+		SrcRemote:  srcRemote,
+		SrcPath:    srcPath,
+		SrcToken:   srcToken,
+		DestRemote: destRemote,
+		DestPath:   destPath,
+		DestToken:  destToken,
+	}
+
+	s.mu.Lock()
+	s.transfers[t.ID] = t
+	err := s.save()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	s.wake()
+	return t.queuedInfo(), nil
+}
+
+// GetTransferStatus returns the status of a transfer; transfers still queued are new.
+func (s *Scheduler) GetTransferStatus(ctx context.Context, transferID string) (*datatx.TxInfo, error) {
+	t, ok := s.get(transferID)
+	if !ok {
+		return s.next.GetTransferStatus(ctx, transferID)
+	}
+	if t.State == StateQueued {
+		return t.queuedInfo(), nil
+	}
+	return t.rename(s.next.GetTransferStatus(ctx, t.DriverID))
+}
+
+// CancelTransfer cancels a transfer; transfers still queued are dropped from the queue.
+func (s *Scheduler) CancelTransfer(ctx context.Context, transferID string) (*datatx.TxInfo, error) {
+	s.mu.Lock()
+	t, ok := s.transfers[transferID]
+	if ok && t.State == StateQueued {
+		delete(s.transfers, transferID)
+		err := s.save()
+		s.mu.Unlock()
+		info := t.queuedInfo()
+		info.Status = datatx.Status_STATUS_TRANSFER_CANCELLED
+		return info, err
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return s.next.CancelTransfer(ctx, transferID)
+	}
+	return t.rename(s.next.CancelTransfer(ctx, t.DriverID))
+}
+
+// RetryTransfer retries a finished transfer through the driver; retries are not queued.
+func (s *Scheduler) RetryTransfer(ctx context.Context, transferID string) (*datatx.TxInfo, error) {
+	t, ok := s.get(transferID)
+	if !ok {
+		return s.next.RetryTransfer(ctx, transferID)
+	}
+	if t.State == StateQueued {
+		return t.queuedInfo(), errors.New("scheduler: transfer still queued, unable to retry")
+	}
+
+	info, err := t.rename(s.next.RetryTransfer(ctx, t.DriverID))
+	if err == nil {
+		s.mu.Lock()
+		t.State = StateRunning
+		err = s.save()
+		s.mu.Unlock()
+	}
+	return info, err
+}
+
+// Queue returns the state of the queues, restricted to the given remote if set.
+func (s *Scheduler) Queue(remote string) ([]*proto.RemoteQueue, []*proto.QueuedTransfer) {
+	s.mu.Lock()
+	pending := s.pending()
+	s.mu.Unlock()
+
+	queues := map[string]*proto.RemoteQueue{}
+	var transfers []*proto.QueuedTransfer
+	for _, t := range pending {
+		if remote != "" && t.Remote != remote {
+			continue
+		}
+		q, ok := queues[t.Remote]
+		if !ok {
+			q = &proto.RemoteQueue{Remote: t.Remote, WindowOpen: true, Availability: 100}
+			if s.links != nil {
+				if l, ok := s.links.get(t.Remote); ok {
+					q.WindowOpen = s.windowOpen(l)
+					q.Availability = uint32(l.Availability * 100)
+					q.Rtt = uint64(l.LastRTT)
+				}
+			}
+			queues[t.Remote] = q
+		}
+		if t.State == StateRunning {
+			q.Running++
+		} else {
+			q.Queued++
+		}
+		transfers = append(transfers, &proto.QueuedTransfer{
+			Id:       t.ID,
+			Remote:   t.Remote,
+			State:    t.State,
+			Size:     t.Size,
+			Enqueued: uint64(t.Enqueued.Unix()),
+		})
+	}
+
+	remotes := make([]*proto.RemoteQueue, 0, len(queues))
+	for _, q := range queues {
+		remotes = append(remotes, q)
+	}
+	sort.Slice(remotes, func(i, j int) bool { return remotes[i].Remote < remotes[j].Remote })
+	return remotes, transfers
+}
+
+func (s *Scheduler) get(id string) (transfer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.transfers[id]
+	if !ok {
+		return transfer{}, false
+	}
+	return *t, true
+}
+
+func (s *Scheduler) wake() {
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) run() {
+	ticker := time.NewTicker(time.Duration(s.conf.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		s.schedule()
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		case <-s.kick:
+		}
+	}
+}
+
+// schedule checks which running transfers ended, sizes the queued ones and
+// starts as many of them as the limits allow.
+func (s *Scheduler) schedule() {
+	ctx := appctx.WithLogger(context.Background(), s.log)
+
+	s.mu.Lock()
+	pending := s.pending()
+	s.mu.Unlock()
+
+	for _, t := range pending {
+		switch {
+		case t.State == StateRunning:
+			info, err := s.next.GetTransferStatus(ctx, t.DriverID)
+			if err == nil && isFinal(info.GetStatus()) {
+				s.update(t.ID, func(t *transfer) { t.State = StateDone })
+			}
+		case !t.Sized:
+			size, sized := s.size(ctx, t)
+			s.update(t.ID, func(t *transfer) { t.Size, t.Sized = size, sized })
+		}
+	}
+
+	for {
+		s.mu.Lock()
+		t := s.nextStartable()
+		if t != nil {
+			// reserved while starting, so that it is not cancelled as queued meanwhile
+			t.State = StateRunning
+		}
+		s.mu.Unlock()
+		if t == nil {
+			return
+		}
+
+		info, err := s.next.StartTransfer(ctx, t.SrcRemote, t.SrcPath, t.SrcToken, t.DestRemote, t.DestPath, t.DestToken)
+		if err != nil {
+			s.log.Error().Err(err).Str("transfer", t.ID).Str("remote", t.Remote).Msg("scheduler: error starting transfer")
+		}
+		s.update(t.ID, func(t *transfer) {
+			t.DriverID = info.GetId().GetOpaqueId()
+			if err != nil {
+				t.State = StateDone
+			}
+		})
+	}
+}
+
+func (s *Scheduler) size(ctx context.Context, t transfer) (uint64, bool) {
+	sizer, ok := s.next.(Sizer)
+	if !ok {
+		return 0, true
+	}
+	size, err := sizer.TransferSize(ctx, t.SrcRemote, t.SrcPath, t.SrcToken)
+	if err != nil {
+		s.log.Warn().Err(err).Str("transfer", t.ID).Msg("scheduler: error getting the size of the transfer")
+		return 0, true
+	}
+	return size, true
+}
+
+func (s *Scheduler) update(id string, f func(*transfer)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.transfers[id]; ok {
+		f(t)
+		if err := s.save(); err != nil {
+			s.log.Error().Err(err).Msg("scheduler: error saving the queue")
+		}
+	}
+}
+
+// pending returns copies of the running and queued transfers, in the order
+// they are started. It must be called holding the lock.
+func (s *Scheduler) pending() []transfer {
+	now := time.Now()
+	maxWait := time.Duration(s.conf.MaxWait) * time.Second
+
+	var list []transfer
+	for _, t := range s.transfers {
+		if t.State != StateDone {
+			list = append(list, *t)
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if (a.State == StateRunning) != (b.State == StateRunning) {
+			return a.State == StateRunning
+		}
+		// transfers waiting for too long go first, the oldest first
+		aw, bw := now.Sub(a.Enqueued) > maxWait, now.Sub(b.Enqueued) > maxWait
+		if aw != bw {
+			return aw
+		}
+		if !aw && a.Size != b.Size && a.Size > 0 && b.Size > 0 {
+			return a.Size < b.Size
+		}
+		if !aw && (a.Size > 0) != (b.Size > 0) {
+			// transfers of unknown size are assumed to be big
+			return a.Size > 0
+		}
+		return a.Enqueued.Before(b.Enqueued)
+	})
+	return list
+}
+
+// nextStartable returns the first queued transfer the limits allow to start.
+// It must be called holding the lock.
+func (s *Scheduler) nextStartable() *transfer {
+	total := 0
+	perRemote := map[string]int{}
+	for _, t := range s.transfers {
+		if t.State == StateRunning {
+			total++
+			perRemote[t.Remote]++
+		}
+	}
+	if s.conf.MaxTotal > 0 && total >= s.conf.MaxTotal {
+		return nil
+	}
+
+	for _, p := range s.pending() {
+		if p.State != StateQueued || !p.Sized || perRemote[p.Remote] >= s.conf.MaxPerRemote {
+			continue
+		}
+		if s.links != nil {
+			if l, ok := s.links.get(p.Remote); ok && !s.windowOpen(l) {
+				continue
+			}
+		}
+		return s.transfers[p.ID]
+	}
+	return nil
+}
+
+// save must be called holding the lock.
+func (s *Scheduler) save() error {
+	data, err := json.Marshal(s.transfers)
+	if err != nil {
+		return errors.Wrap(err, "scheduler: error encoding the queue")
+	}
+	if err := ioutil.WriteFile(s.conf.File, data, 0600); err != nil {
+		return errors.Wrap(err, "scheduler: error writing the queue to "+s.conf.File)
+	}
+	return nil
+}
+
+func (t *transfer) queuedInfo() *datatx.TxInfo {
+	return &datatx.TxInfo{
+		Id:     &datatx.TxId{OpaqueId: t.ID},
+		Status: datatx.Status_STATUS_TRANSFER_NEW,
+		Ctime:  &typespb.Timestamp{Seconds: uint64(t.Enqueued.Unix())},
+	}
+}
+
+// rename replaces the ID of the driver by the one of the scheduler.
+func (t *transfer) rename(info *datatx.TxInfo, err error) (*datatx.TxInfo, error) {
+	if info != nil {
+		info.Id = &datatx.TxId{OpaqueId: t.ID}
+	}
+	return info, err
+}
+
+func isFinal(status datatx.Status) bool {
+	switch status {
+	case datatx.Status_STATUS_TRANSFER_NEW, datatx.Status_STATUS_TRANSFER_IN_PROGRESS:
+		return false
+	}
+	return true
+}
+
+// remoteOf returns the host of the remote provider of a transfer.
+func remoteOf(remote string) string {
+	if u, err := url.Parse(remote); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return remote
+}
@@ -0,0 +1,174 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	datatx "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
+	"github.com/cs3org/reva/pkg/mentix/latency"
+	"github.com/rs/zerolog"
+)
+
+// fakeDriver starts the transfers immediately and reports them in progress until finished
+type fakeDriver struct {
+	mu       sync.Mutex
+	sizes    map[string]uint64
+	started  []string
+	finished map[string]bool
+}
+
+func (d *fakeDriver) StartTransfer(ctx context.Context, srcRemote, srcPath, srcToken, destRemote, destPath, destToken string) (*datatx.TxInfo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.started = append(d.started, srcPath)
+	return &datatx.TxInfo{Id: &datatx.TxId{OpaqueId: srcPath}, Status: datatx.Status_STATUS_TRANSFER_IN_PROGRESS}, nil
+}
+
+func (d *fakeDriver) GetTransferStatus(ctx context.Context, transferID string) (*datatx.TxInfo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	status := datatx.Status_STATUS_TRANSFER_IN_PROGRESS
+	if d.finished[transferID] {
+		status = datatx.Status_STATUS_TRANSFER_COMPLETE
+	}
+	return &datatx.TxInfo{Id: &datatx.TxId{OpaqueId: transferID}, Status: status}, nil
+}
+
+func (d *fakeDriver) CancelTransfer(ctx context.Context, transferID string) (*datatx.TxInfo, error) {
+	return &datatx.TxInfo{Id: &datatx.TxId{OpaqueId: transferID}, Status: datatx.Status_STATUS_TRANSFER_CANCELLED}, nil
+}
+
+func (d *fakeDriver) RetryTransfer(ctx context.Context, transferID string) (*datatx.TxInfo, error) {
+	return d.StartTransfer(ctx, "", transferID, "", "", "", "")
+}
+
+func (d *fakeDriver) TransferSize(ctx context.Context, srcRemote, srcPath, srcToken string) (uint64, error) {
+	return d.sizes[srcPath], nil
+}
+
+func (d *fakeDriver) startedPaths() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.started...)
+}
+
+func newTestScheduler(t *testing.T, d *fakeDriver, c *Config) *Scheduler {
+	c.File = filepath.Join(t.TempDir(), "queue.json")
+	c.init()
+	log := zerolog.Nop()
+	return &Scheduler{
+		next:      d,
+		conf:      c,
+		log:       &log,
+		transfers: map[string]*transfer{},
+		kick:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+	}
+}
+
+func TestLimitPerRemoteAndSmallFirst(t *testing.T) {
+	d := &fakeDriver{
+		sizes:    map[string]uint64{"/big": 1 << 30, "/small": 1 << 10, "/medium": 1 << 20, "/other": 1 << 30},
+		finished: map[string]bool{},
+	}
+	s := newTestScheduler(t, d, &Config{MaxPerRemote: 1})
+	ctx := context.Background()
+
+	for _, p := range []string{"/big", "/small", "/medium"} {
+		if _, err := s.StartTransfer(ctx, "https://site-a.org/webdav", p, "", "https://local.org", p, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.StartTransfer(ctx, "https://site-b.org/webdav", "/other", "", "https://local.org", "/other", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	s.schedule()
+	if started := d.startedPaths(); fmt.Sprint(started) != "[/small /other]" {
+		t.Fatalf("expected the smallest transfer of every remote to start, got %v", started)
+	}
+
+	remotes, transfers := s.Queue("site-a.org")
+	if len(remotes) != 1 || remotes[0].Running != 1 || remotes[0].Queued != 2 {
+		t.Fatalf("unexpected queue of site-a.org: %v", remotes)
+	}
+	if len(transfers) != 3 || transfers[0].State != StateRunning {
+		t.Fatalf("unexpected transfers of site-a.org: %v", transfers)
+	}
+
+	d.mu.Lock()
+	d.finished["/small"] = true
+	d.mu.Unlock()
+	s.schedule()
+	if started := d.startedPaths(); fmt.Sprint(started) != "[/small /other /medium]" {
+		t.Fatalf("expected the next smallest transfer to start, got %v", started)
+	}
+}
+
+func TestQueuedTransfersCancelled(t *testing.T) {
+	d := &fakeDriver{finished: map[string]bool{}}
+	s := newTestScheduler(t, d, &Config{MaxPerRemote: 1})
+	ctx := context.Background()
+
+	if _, err := s.StartTransfer(ctx, "https://site-a.org", "/first", "", "https://local.org", "/first", ""); err != nil {
+		t.Fatal(err)
+	}
+	info, err := s.StartTransfer(ctx, "https://site-a.org", "/second", "", "https://local.org", "/second", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.schedule()
+
+	if info, err = s.CancelTransfer(ctx, info.Id.OpaqueId); err != nil || info.Status != datatx.Status_STATUS_TRANSFER_CANCELLED {
+		t.Fatalf("expected the queued transfer to be cancelled, got %v, %v", info, err)
+	}
+	d.mu.Lock()
+	d.finished["/first"] = true
+	d.mu.Unlock()
+	s.schedule()
+
+	if started := d.startedPaths(); fmt.Sprint(started) != "[/first]" {
+		t.Fatalf("expected the cancelled transfer not to start, got %v", started)
+	}
+}
+
+func TestWindowOpen(t *testing.T) {
+	s := newTestScheduler(t, &fakeDriver{}, &Config{MaxPerRemote: 1, MaxRTT: 100})
+
+	tests := []struct {
+		link latency.Link
+		open bool
+	}{
+		{latency.Link{}, true},
+		{latency.Link{Probes: 10, Available: true, Availability: 1, LastRTT: 20}, true},
+		{latency.Link{Probes: 10, Available: true, Availability: 0.5, LastRTT: 20}, false},
+		{latency.Link{Probes: 10, Available: false, Availability: 1, LastRTT: 20}, false},
+		{latency.Link{Probes: 10, Available: true, Availability: 1, LastRTT: 200}, false},
+	}
+	for _, tt := range tests {
+		if open := s.windowOpen(tt.link); open != tt.open {
+			t.Errorf("windowOpen(%+v) = %v, expected %v", tt.link, open, tt.open)
+		}
+	}
+}
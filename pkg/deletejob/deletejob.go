@@ -0,0 +1,217 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package deletejob deletes folder trees in the background, so that huge
+// trees do not time out the requests deleting them. A job lets the driver
+// delete the tree at once, so that it lands in the trash as a single item.
+package deletejob
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/deletejob/proto"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/utils"
+	"google.golang.org/grpc/metadata"
+)
+
+// The states of a job.
+const (
+	StateRunning   = "running"
+	StateDone      = "done"
+	StateFailed    = "failed"
+	StateCancelled = "cancelled"
+)
+
+// RecursiveDeleter is implemented by the drivers able to delete a whole tree
+// in a cheaper way than resource by resource, e.g. by moving it to the trash.
+// The progress function reports the resources found and deleted so far.
+type RecursiveDeleter interface {
+	DeleteRecursive(ctx context.Context, ref *provider.Reference, progress func(total, deleted uint64)) error
+}
+
+// DeleteRecursive deletes the tree at ref with the DeleteRecursive method of
+// fs if it has one, and otherwise deletes the top-level folder, which the
+// drivers do at once.
+func DeleteRecursive(ctx context.Context, fs storage.FS, ref *provider.Reference, progress func(total, deleted uint64)) error {
+	if d, ok := fs.(RecursiveDeleter); ok {
+		return d.DeleteRecursive(ctx, ref, progress)
+	}
+	progress(1, 0)
+	if err := fs.Delete(ctx, ref); err != nil {
+		return err
+	}
+	progress(1, 1)
+	return nil
+}
+
+// Config configures the jobs.
+type Config struct {
+	JobTTL int `mapstructure:"job_ttl" docs:"3600;Seconds during which a finished job can still be queried."`
+}
+
+func (c *Config) init() {
+	if c.JobTTL <= 0 {
+		c.JobTTL = 3600
+	}
+}
+
+// Manager runs the jobs and keeps track of them.
+type Manager struct {
+	c *Config
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+type job struct {
+	// accessed atomically, first for 64-bit alignment
+	total   uint64
+	deleted uint64
+	failed  uint64
+
+	id     string
+	owner  *userpb.UserId
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	state    string
+	err      string
+	finished time.Time
+}
+
+// NewManager returns a manager running jobs with the given configuration.
+func NewManager(c *Config) *Manager {
+	c.init()
+	return &Manager{c: c, jobs: map[string]*job{}}
+}
+
+// Start deletes the tree at ref in the background. The job runs on behalf of
+// the user of ctx, but is not cancelled when ctx is.
+func (m *Manager) Start(ctx context.Context, id string, fs storage.FS, ref *provider.Reference) *proto.DeleteJob {
+	jctx, cancel := context.WithCancel(appctx.WithLogger(context.Background(), appctx.GetLogger(ctx)))
+	j := &job{id: id, cancel: cancel, state: StateRunning}
+	if u, ok := ctxpkg.ContextGetUser(ctx); ok {
+		jctx = ctxpkg.ContextSetUser(jctx, u)
+		j.owner = u.Id
+	}
+	if t, ok := ctxpkg.ContextGetToken(ctx); ok {
+		jctx = ctxpkg.ContextSetToken(jctx, t)
+		jctx = metadata.AppendToOutgoingContext(jctx, ctxpkg.TokenHeader, t)
+	}
+
+	m.mu.Lock()
+	m.gc()
+	m.jobs[id] = j
+	m.mu.Unlock()
+
+	go m.run(jctx, j, fs, ref)
+	return j.proto()
+}
+
+// Get returns the job with the given id, if it was started by the user of ctx.
+func (m *Manager) Get(ctx context.Context, id string) (*proto.DeleteJob, error) {
+	j, err := m.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return j.proto(), nil
+}
+
+// Cancel stops the job with the given id, if it was started by the user of ctx.
+func (m *Manager) Cancel(ctx context.Context, id string) (*proto.DeleteJob, error) {
+	j, err := m.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	j.cancel()
+	return j.proto(), nil
+}
+
+func (m *Manager) get(ctx context.Context, id string) (*job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gc()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return nil, errtypes.NotFound("delete job " + id)
+	}
+	if u, ok := ctxpkg.ContextGetUser(ctx); j.owner != nil && (!ok || !utils.UserEqual(u.Id, j.owner)) {
+		return nil, errtypes.NotFound("delete job " + id)
+	}
+	return j, nil
+}
+
+// gc forgets the jobs finished for longer than the ttl, it must be called with the lock held.
+func (m *Manager) gc() {
+	expired := time.Now().Add(-time.Duration(m.c.JobTTL) * time.Second)
+	for id, j := range m.jobs {
+		j.mu.Lock()
+		if !j.finished.IsZero() && j.finished.Before(expired) {
+			delete(m.jobs, id)
+		}
+		j.mu.Unlock()
+	}
+}
+
+func (m *Manager) run(ctx context.Context, j *job, fs storage.FS, ref *provider.Reference) {
+	err := DeleteRecursive(ctx, fs, ref, func(total, deleted uint64) {
+		atomic.StoreUint64(&j.total, total)
+		atomic.StoreUint64(&j.deleted, deleted)
+	})
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch {
+	case ctx.Err() != nil:
+		j.state = StateCancelled
+	case err != nil:
+		j.state = StateFailed
+		j.err = err.Error()
+		atomic.StoreUint64(&j.failed, atomic.LoadUint64(&j.total)-atomic.LoadUint64(&j.deleted))
+	default:
+		j.state = StateDone
+	}
+	j.finished = time.Now()
+	j.cancel()
+
+	log := appctx.GetLogger(ctx)
+	log.Info().Str("job", j.id).Str("state", j.state).Uint64("deleted", atomic.LoadUint64(&j.deleted)).Uint64("failed", atomic.LoadUint64(&j.failed)).Msg("deletejob: job finished")
+}
+
+func (j *job) proto() *proto.DeleteJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return &proto.DeleteJob{
+		Id:      j.id,
+		State:   j.state,
+		Total:   atomic.LoadUint64(&j.total),
+		Deleted: atomic.LoadUint64(&j.deleted),
+		Failed:  atomic.LoadUint64(&j.failed),
+		Error:   j.err,
+	}
+}
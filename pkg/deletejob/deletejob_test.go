@@ -0,0 +1,188 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package deletejob
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/deletejob/proto"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+// treeFS holds a tree of folders and files, deleting a folder with its children
+type treeFS struct {
+	storage.FS
+
+	mu      sync.Mutex
+	nodes   map[string]bool // path -> is folder
+	deletes int
+	block   chan struct{}
+}
+
+func newTreeFS(folders, files int) *treeFS {
+	fs := &treeFS{nodes: map[string]bool{"/root": true}}
+	for i := 0; i < folders; i++ {
+		dir := fmt.Sprintf("/root/d%d", i)
+		fs.nodes[dir] = true
+		for k := 0; k < files; k++ {
+			fs.nodes[fmt.Sprintf("%s/f%d", dir, k)] = false
+		}
+	}
+	return fs
+}
+
+func (fs *treeFS) Delete(ctx context.Context, ref *provider.Reference) error {
+	if fs.block != nil {
+		<-fs.block
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if _, ok := fs.nodes[ref.Path]; !ok {
+		return errtypes.NotFound(ref.Path)
+	}
+	fs.deletes++
+	for p := range fs.nodes {
+		if p == ref.Path || strings.HasPrefix(p, ref.Path+"/") {
+			delete(fs.nodes, p)
+		}
+	}
+	return nil
+}
+
+func wait(ctx context.Context, t *testing.T, m *Manager, id string) *proto.DeleteJob {
+	t.Helper()
+	for i := 0; i < 500; i++ {
+		j, err := m.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if j.State != StateRunning {
+			return j
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("the job did not finish")
+	return nil
+}
+
+func userContext(id string) context.Context {
+	return ctxpkg.ContextSetUser(context.Background(), &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: id}})
+}
+
+func TestDeleteTopLevelFolder(t *testing.T) {
+	fs := newTreeFS(10, 25)
+	m := NewManager(&Config{})
+	ctx := userContext("einstein")
+
+	m.Start(ctx, "job", fs, &provider.Reference{Path: "/root"})
+	j := wait(ctx, t, m, "job")
+
+	if j.State != StateDone || j.Error != "" {
+		t.Fatalf("expected the job to be done, got %+v", j)
+	}
+	if j.Total != 1 || j.Deleted != 1 || j.Failed != 0 {
+		t.Errorf("unexpected progress %+v", j)
+	}
+	// the folder is deleted at once, not resource by resource
+	if fs.deletes != 1 || len(fs.nodes) != 0 {
+		t.Errorf("expected the tree to be deleted at once, got %d deletes and %d nodes left", fs.deletes, len(fs.nodes))
+	}
+}
+
+func TestDeleteFailed(t *testing.T) {
+	m := NewManager(&Config{})
+	ctx := userContext("einstein")
+
+	m.Start(ctx, "job", newTreeFS(1, 1), &provider.Reference{Path: "/unknown"})
+	j := wait(ctx, t, m, "job")
+	if j.State != StateFailed || j.Error == "" || j.Failed != 1 {
+		t.Errorf("expected the job to fail, got %+v", j)
+	}
+}
+
+func TestJobOwner(t *testing.T) {
+	m := NewManager(&Config{})
+	m.Start(userContext("einstein"), "job", newTreeFS(1, 1), &provider.Reference{Path: "/root"})
+
+	if _, err := m.Get(userContext("marie"), "job"); err == nil {
+		t.Error("expected the job of another user to be hidden")
+	}
+	if _, err := m.Cancel(userContext("marie"), "job"); err == nil {
+		t.Error("expected the job of another user not to be cancelled")
+	}
+	if _, err := m.Get(userContext("einstein"), "unknown"); err == nil {
+		t.Error("expected an unknown job not to be found")
+	}
+}
+
+func TestCancel(t *testing.T) {
+	fs := newTreeFS(5, 50)
+	fs.block = make(chan struct{})
+	m := NewManager(&Config{})
+	ctx := userContext("einstein")
+
+	m.Start(ctx, "job", fs, &provider.Reference{Path: "/root"})
+	if _, err := m.Cancel(ctx, "job"); err != nil {
+		t.Fatal(err)
+	}
+	close(fs.block)
+
+	j := wait(ctx, t, m, "job")
+	if j.State != StateCancelled {
+		t.Fatalf("expected the job to be cancelled, got %+v", j)
+	}
+	if _, ok := fs.nodes["/root"]; !ok {
+		t.Error("expected the root to be left")
+	}
+}
+
+type recursiveFS struct {
+	*treeFS
+	called bool
+}
+
+func (fs *recursiveFS) DeleteRecursive(ctx context.Context, ref *provider.Reference, progress func(total, deleted uint64)) error {
+	fs.called = true
+	progress(1, 1)
+	return nil
+}
+
+func TestRecursiveDeleter(t *testing.T) {
+	fs := &recursiveFS{treeFS: newTreeFS(1, 1)}
+	m := NewManager(&Config{})
+	ctx := userContext("einstein")
+
+	m.Start(ctx, "job", fs, &provider.Reference{Path: "/root"})
+	j := wait(ctx, t, m, "job")
+	if !fs.called || j.State != StateDone || j.Deleted != 1 {
+		t.Errorf("expected the driver to delete the tree, got %+v", j)
+	}
+}
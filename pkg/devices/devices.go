@@ -0,0 +1,277 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package devices
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/bluele/gcache"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	"github.com/pkg/errors"
+)
+
+// maxTokens is the maximum number of tokens remembered per device.
+const maxTokens = 20
+
+// Device is a client (e.g. a sync client or a browser) a user has connected with.
+type Device struct {
+	ID        string `json:"id"`
+	UserAgent string `json:"user_agent"`
+	// CredentialType is the type of the credentials the device last authenticated with; it is empty if it only presented tokens.
+	CredentialType string `json:"credential_type,omitempty"`
+	// AppPassword identifies the app password used by the device without revealing it.
+	AppPassword string    `json:"app_password,omitempty"`
+	IP          string    `json:"ip"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	Revoked     bool      `json:"revoked"`
+	// Tokens maps the IDs of the tokens used by the device to their expiration; they are revoked along with the device.
+	Tokens map[string]time.Time `json:"tokens,omitempty"`
+}
+
+// Registry is the interface to implement shared registries of the devices of the users.
+type Registry interface {
+	// GetDevice returns the device of the user with the given ID; nil is returned for unknown devices.
+	GetDevice(ctx context.Context, userID *userpb.UserId, deviceID string) (*Device, error)
+	// ListDevices returns all the devices of the user.
+	ListDevices(ctx context.Context, userID *userpb.UserId) ([]*Device, error)
+	// StoreDevice stores the device of the user, replacing its previous version.
+	StoreDevice(ctx context.Context, userID *userpb.UserId, device *Device) error
+}
+
+// Client describes the client a request was sent from.
+type Client struct {
+	UserAgent string
+	IP        string
+	// CredentialType is the type of the credentials presented by the client; it is empty if the client presented a token.
+	CredentialType string
+	// AppPassword is the app password presented by the client, if any.
+	AppPassword string
+	// Token is the token used for the request; Issued tells whether it was just issued for the presented credentials.
+	Token  string
+	Issued bool
+}
+
+// Tracker records the devices of the users and enforces their revocation. To keep the load on the
+// registry low, the last activity of a device is only updated after the given interval, so
+// revocations made on other instances take effect after at most that interval.
+type Tracker struct {
+	registry      Registry
+	revocations   revocation.Store
+	tokenLifetime time.Duration
+	interval      time.Duration
+
+	devices gcache.Cache
+	tokens  gcache.Cache
+}
+
+// NewTracker returns a tracker storing the devices in the given registry. If a revocation store is
+// given, the tokens of revoked devices are added to it, so they are rejected by all services.
+func NewTracker(registry Registry, revocations revocation.Store, tokenLifetime, interval time.Duration) *Tracker {
+	return &Tracker{
+		registry:      registry,
+		revocations:   revocations,
+		tokenLifetime: tokenLifetime,
+		interval:      interval,
+		devices:       gcache.New(100000).LRU().Build(),
+		tokens:        gcache.New(100000).LRU().Build(),
+	}
+}
+
+// Track records a request of the given user sent from the client. An error of type
+// errtypes.PermissionDenied is returned if the device of the client has been revoked.
+func (t *Tracker) Track(ctx context.Context, userID *userpb.UserId, client *Client) (*Device, error) {
+	now := time.Now()
+	tokenID := revocation.TokenID(client.Token)
+	deviceID := t.deviceID(userID, client, tokenID)
+
+	// tokens issued for credentials are attributed to the same device when they are presented later on
+	if client.Issued {
+		_ = t.tokens.SetWithExpire(tokenID, deviceID, t.tokenLifetime)
+	}
+
+	device, stored, err := t.getDevice(ctx, userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if device == nil {
+		device = &Device{ID: deviceID, FirstSeen: now}
+	}
+	if device.Revoked {
+		return device, errtypes.PermissionDenied("device has been revoked")
+	}
+
+	dirty := now.Sub(stored) >= t.interval
+	device.UserAgent = client.UserAgent
+	device.IP = client.IP
+	device.LastSeen = now
+	if client.CredentialType != "" {
+		device.CredentialType = client.CredentialType
+	}
+	if client.AppPassword != "" {
+		device.AppPassword = fingerprint(client.AppPassword)
+	}
+	if !client.Issued && client.Token != "" {
+		if _, ok := device.Tokens[tokenID]; !ok {
+			device.addToken(tokenID, now.Add(t.tokenLifetime))
+			dirty = true
+		}
+	}
+
+	if dirty {
+		if err := t.registry.StoreDevice(ctx, userID, device); err != nil {
+			return nil, errors.Wrap(err, "devices: error storing device")
+		}
+		stored = now
+	}
+	_ = t.devices.SetWithExpire(deviceKey(userID, deviceID), &cachedDevice{device: device, stored: stored}, t.interval)
+	return device, nil
+}
+
+// List returns the devices of the user, most recently seen first.
+func (t *Tracker) List(ctx context.Context, userID *userpb.UserId) ([]*Device, error) {
+	devices, err := t.registry.ListDevices(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "devices: error listing devices")
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].LastSeen.After(devices[j].LastSeen)
+	})
+	return devices, nil
+}
+
+// Revoke revokes the device of the user, rejecting any further requests from it as well as the tokens it used.
+func (t *Tracker) Revoke(ctx context.Context, userID *userpb.UserId, deviceID string) error {
+	device, err := t.registry.GetDevice(ctx, userID, deviceID)
+	if err != nil {
+		return errors.Wrap(err, "devices: error getting device")
+	}
+	if device == nil {
+		return errtypes.NotFound(deviceID)
+	}
+
+	device.Revoked = true
+	if err := t.registry.StoreDevice(ctx, userID, device); err != nil {
+		return errors.Wrap(err, "devices: error storing device")
+	}
+	t.devices.Remove(deviceKey(userID, deviceID))
+
+	if t.revocations != nil {
+		now := time.Now()
+		for tokenID, expiresAt := range device.Tokens {
+			if !expiresAt.After(now) {
+				continue
+			}
+			if err := t.revocations.RevokeToken(ctx, tokenID, expiresAt.Sub(now)); err != nil {
+				return errors.Wrap(err, "devices: error revoking token")
+			}
+		}
+	}
+	return nil
+}
+
+// deviceID identifies the device of the client. Clients using app passwords are identified by the
+// app password, so that a revoked app password is rejected regardless of the user agent.
+func (t *Tracker) deviceID(userID *userpb.UserId, client *Client, tokenID string) string {
+	var key string
+	switch {
+	case client.AppPassword != "":
+		key = "app-password:" + fingerprint(client.AppPassword)
+	case client.CredentialType == "" && client.Token != "":
+		if id, err := t.tokens.Get(tokenID); err == nil {
+			return id.(string)
+		}
+		key = "user-agent:" + client.UserAgent
+	default:
+		key = "user-agent:" + client.UserAgent
+	}
+	h := sha256.Sum256([]byte(revocation.UserKey(userID) + "\n" + key))
+	return hex.EncodeToString(h[:8])
+}
+
+type cachedDevice struct {
+	device *Device
+	stored time.Time
+}
+
+func (t *Tracker) getDevice(ctx context.Context, userID *userpb.UserId, deviceID string) (*Device, time.Time, error) {
+	// cached devices are shared by concurrent requests, so they are only modified as copies
+	if v, err := t.devices.Get(deviceKey(userID, deviceID)); err == nil {
+		c := v.(*cachedDevice)
+		return c.device.Clone(), c.stored, nil
+	}
+	device, err := t.registry.GetDevice(ctx, userID, deviceID)
+	if err != nil {
+		return nil, time.Time{}, errors.Wrap(err, "devices: error getting device")
+	}
+	if device == nil {
+		return nil, time.Time{}, nil
+	}
+	return device, device.LastSeen, nil
+}
+
+// Clone returns a copy of the device.
+func (d *Device) Clone() *Device {
+	c := *d
+	if d.Tokens != nil {
+		c.Tokens = make(map[string]time.Time, len(d.Tokens))
+		for id, exp := range d.Tokens {
+			c.Tokens[id] = exp
+		}
+	}
+	return &c
+}
+
+func (d *Device) addToken(tokenID string, expiresAt time.Time) {
+	if d.Tokens == nil {
+		d.Tokens = map[string]time.Time{}
+	}
+	now := time.Now()
+	for id, exp := range d.Tokens {
+		if !exp.After(now) {
+			delete(d.Tokens, id)
+		}
+	}
+	// forget the token expiring first if too many are remembered
+	if len(d.Tokens) >= maxTokens {
+		var oldest string
+		for id, exp := range d.Tokens {
+			if oldest == "" || exp.Before(d.Tokens[oldest]) {
+				oldest = id
+			}
+		}
+		delete(d.Tokens, oldest)
+	}
+	d.Tokens[tokenID] = expiresAt
+}
+
+func deviceKey(userID *userpb.UserId, deviceID string) string {
+	return revocation.UserKey(userID) + "!" + deviceID
+}
+
+// fingerprint returns a short hash identifying an app password.
+func fingerprint(secret string) string {
+	return revocation.TokenID(secret)[:12]
+}
@@ -0,0 +1,130 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package devices
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/token/revocation"
+)
+
+type fakeRegistry struct {
+	devices map[string]*Device
+	writes  int
+}
+
+func (r *fakeRegistry) GetDevice(ctx context.Context, userID *userpb.UserId, deviceID string) (*Device, error) {
+	if d, ok := r.devices[deviceID]; ok {
+		return d.Clone(), nil
+	}
+	return nil, nil
+}
+
+func (r *fakeRegistry) ListDevices(ctx context.Context, userID *userpb.UserId) ([]*Device, error) {
+	list := []*Device{}
+	for _, d := range r.devices {
+		list = append(list, d.Clone())
+	}
+	return list, nil
+}
+
+func (r *fakeRegistry) StoreDevice(ctx context.Context, userID *userpb.UserId, device *Device) error {
+	r.writes++
+	r.devices[device.ID] = device.Clone()
+	return nil
+}
+
+type fakeRevocations struct {
+	revocation.Store
+	tokens map[string]bool
+}
+
+func (s *fakeRevocations) RevokeToken(ctx context.Context, tokenID string, ttl time.Duration) error {
+	s.tokens[tokenID] = true
+	return nil
+}
+
+func TestTracker(t *testing.T) {
+	ctx := context.Background()
+	reg := &fakeRegistry{devices: map[string]*Device{}}
+	revocations := &fakeRevocations{tokens: map[string]bool{}}
+	userID := &userpb.UserId{Idp: "idp", OpaqueId: "einstein"}
+	tracker := NewTracker(reg, revocations, time.Hour, time.Minute)
+
+	// a sync client authenticating with an app password and reusing the issued token
+	client := &Client{UserAgent: "Mozilla/5.0 (Linux) mirall/2.10", IP: "10.0.0.1", CredentialType: "appauth", AppPassword: "secret", Token: "token1", Issued: true}
+	syncClient, err := tracker.Track(ctx, userID, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reused, err := tracker.Track(ctx, userID, &Client{UserAgent: client.UserAgent, IP: "10.0.0.2", Token: "token1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reused.ID != syncClient.ID {
+		t.Fatalf("expected the issued token to be attributed to device %s, got %s", syncClient.ID, reused.ID)
+	}
+	if reused.IP != "10.0.0.2" || reused.AppPassword == "" || reused.AppPassword == "secret" {
+		t.Fatalf("unexpected device %+v", reused)
+	}
+
+	// further requests within the interval don't cause writes
+	writes := reg.writes
+	if _, err := tracker.Track(ctx, userID, &Client{UserAgent: client.UserAgent, Token: "token1"}); err != nil {
+		t.Fatal(err)
+	}
+	if reg.writes != writes {
+		t.Fatalf("expected no writes, got %d", reg.writes-writes)
+	}
+
+	// a browser is a different device
+	if _, err := tracker.Track(ctx, userID, &Client{UserAgent: "Mozilla/5.0 (X11) Firefox/115.0", Token: "token2"}); err != nil {
+		t.Fatal(err)
+	}
+	list, _ := tracker.List(ctx, userID)
+	if len(list) != 2 {
+		t.Fatalf("expected 2 devices, got %d", len(list))
+	}
+
+	if err := tracker.Revoke(ctx, userID, syncClient.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !revocations.tokens[revocation.TokenID("token1")] {
+		t.Fatal("expected the token of the revoked device to be revoked")
+	}
+	if revocations.tokens[revocation.TokenID("token2")] {
+		t.Fatal("expected the token of the other device not to be revoked")
+	}
+
+	// the app password is rejected regardless of the user agent
+	client = &Client{UserAgent: "curl/8.0", CredentialType: "appauth", AppPassword: "secret", Token: "token3", Issued: true}
+	if _, err := tracker.Track(ctx, userID, client); err == nil {
+		t.Fatal("expected the revoked device to be rejected")
+	} else if _, ok := err.(errtypes.PermissionDenied); !ok {
+		t.Fatalf("expected a permission denied error, got %v", err)
+	}
+
+	if err := tracker.Revoke(ctx, userID, "unknown"); err == nil {
+		t.Fatal("expected revoking an unknown device to fail")
+	}
+}
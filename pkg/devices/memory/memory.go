@@ -0,0 +1,79 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package memory
+
+import (
+	"context"
+	"sync"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/devices"
+	"github.com/cs3org/reva/pkg/devices/registry"
+	"github.com/cs3org/reva/pkg/token/revocation"
+)
+
+func init() {
+	registry.Register("memory", New)
+}
+
+type store struct {
+	sync.RWMutex
+	devices map[string]map[string]devices.Device
+}
+
+// New returns a device registry keeping the devices in memory. The devices are neither
+// shared with other instances nor persisted, so it is only meant for single-instance
+// deployments and tests.
+func New(m map[string]interface{}) (devices.Registry, error) {
+	return &store{
+		devices: map[string]map[string]devices.Device{},
+	}, nil
+}
+
+func (s *store) GetDevice(ctx context.Context, userID *userpb.UserId, deviceID string) (*devices.Device, error) {
+	s.RLock()
+	defer s.RUnlock()
+	d, ok := s.devices[revocation.UserKey(userID)][deviceID]
+	if !ok {
+		return nil, nil
+	}
+	return d.Clone(), nil
+}
+
+func (s *store) ListDevices(ctx context.Context, userID *userpb.UserId) ([]*devices.Device, error) {
+	s.RLock()
+	defer s.RUnlock()
+	list := make([]*devices.Device, 0, len(s.devices[revocation.UserKey(userID)]))
+	for _, d := range s.devices[revocation.UserKey(userID)] {
+		d := d
+		list = append(list, d.Clone())
+	}
+	return list, nil
+}
+
+func (s *store) StoreDevice(ctx context.Context, userID *userpb.UserId, device *devices.Device) error {
+	s.Lock()
+	defer s.Unlock()
+	key := revocation.UserKey(userID)
+	if s.devices[key] == nil {
+		s.devices[key] = map[string]devices.Device{}
+	}
+	s.devices[key][device.ID] = *device.Clone()
+	return nil
+}
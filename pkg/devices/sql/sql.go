@@ -0,0 +1,128 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/devices"
+	"github.com/cs3org/reva/pkg/devices/registry"
+	"github.com/cs3org/reva/pkg/migrate"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+
+	// Provides mysql drivers
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func init() {
+	registry.Register("sql", New)
+}
+
+type config struct {
+	DbUsername string `mapstructure:"db_username"`
+	DbPassword string `mapstructure:"db_password"`
+	DbHost     string `mapstructure:"db_host"`
+	DbPort     int    `mapstructure:"db_port"`
+	DbName     string `mapstructure:"db_name"`
+	// SkipMigrations disables applying the pending schema migrations on startup.
+	SkipMigrations bool `mapstructure:"skip_migrations"`
+}
+
+type store struct {
+	db *sql.DB
+}
+
+// New returns a device registry persisting the devices in a SQL database.
+func New(m map[string]interface{}) (devices.Registry, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", c.DbUsername, c.DbPassword, c.DbHost, c.DbPort, c.DbName))
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.SkipMigrations {
+		if err := migrate.Up(context.Background(), db, Schema); err != nil {
+			return nil, err
+		}
+	}
+	return &store{db: db}, nil
+}
+
+func (s *store) GetDevice(ctx context.Context, userID *userpb.UserId, deviceID string) (*devices.Device, error) {
+	var data string
+	query := "SELECT data FROM user_devices WHERE user_key = ? AND device_id = ?"
+	err := s.db.QueryRowContext(ctx, query, revocation.UserKey(userID), deviceID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeDevice(data)
+}
+
+func (s *store) ListDevices(ctx context.Context, userID *userpb.UserId) ([]*devices.Device, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT data FROM user_devices WHERE user_key = ?", revocation.UserKey(userID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*devices.Device{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		d, err := decodeDevice(data)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+func (s *store) StoreDevice(ctx context.Context, userID *userpb.UserId, device *devices.Device) error {
+	data, err := json.Marshal(device)
+	if err != nil {
+		return err
+	}
+	query := "INSERT INTO user_devices (user_key, device_id, data) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE data = VALUES(data)"
+	_, err = s.db.ExecContext(ctx, query, revocation.UserKey(userID), device.ID, string(data))
+	return err
+}
+
+func decodeDevice(data string) (*devices.Device, error) {
+	d := &devices.Device{}
+	if err := json.Unmarshal([]byte(data), d); err != nil {
+		return nil, errors.Wrap(err, "error decoding device")
+	}
+	return d, nil
+}
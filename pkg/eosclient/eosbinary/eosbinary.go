@@ -235,7 +235,11 @@ func (c *Client) executeEOS(ctx context.Context, cmdArgs []string, auth eosclien
 	cmd.Args = append(cmd.Args, cmdArgs...)
 
 	span := trace.SpanFromContext(ctx)
-	cmd.Args = append(cmd.Args, "--comment", span.SpanContext().TraceID().String())
+	comment := span.SpanContext().TraceID().String()
+	if reqID, ok := ctxpkg.ContextGetRequestID(ctx); ok {
+		comment += " " + reqID
+	}
+	cmd.Args = append(cmd.Args, "--comment", comment)
 
 	err := cmd.Run()
 
@@ -31,6 +31,7 @@ import (
 	"time"
 
 	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/eosclient"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/logger"
@@ -284,6 +285,7 @@ func (c *EOSHTTPClient) GETFile(ctx context.Context, remoteuser string, auth eos
 
 		// Execute the request. I don't like that there is no explicit timeout or buffer control on the input stream
 		log.Debug().Str("func", "GETFile").Msg("sending req")
+		ctxpkg.AddRequestIDHeader(ctx, req.Header)
 		resp, err := c.cl.Do(req)
 
 		// Let's support redirections... and if we retry we have to retry at the same FST, avoid going back to the MGM
@@ -378,6 +380,7 @@ func (c *EOSHTTPClient) PUTFile(ctx context.Context, remoteuser string, auth eos
 
 		// Execute the request. I don't like that there is no explicit timeout or buffer control on the input stream
 		log.Debug().Str("func", "PUTFile").Msg("sending req")
+		ctxpkg.AddRequestIDHeader(ctx, req.Header)
 		resp, err := c.cl.Do(req)
 
 		// Let's support redirections... and if we retry we retry at the same FST
@@ -470,6 +473,7 @@ func (c *EOSHTTPClient) Head(ctx context.Context, remoteuser string, auth eoscli
 			return errtypes.InternalError("Timeout with url" + finalurl)
 		}
 		// Execute the request. I don't like that there is no explicit timeout or buffer control on the input stream
+		ctxpkg.AddRequestIDHeader(ctx, req.Header)
 		resp, err := c.cl.Do(req)
 
 		// And get an error code (if error) that is worth propagating
@@ -110,6 +110,18 @@ func (e ChecksumMismatch) IsChecksumMismatch() {}
 // oc clienst issue: https://github.com/owncloud/core/issues/22711
 const StatusChecksumMismatch = 419
 
+// StatusTusChecksumMismatch 460 is the unassigned http status code the TUS checksum extension uses for checksum mismatches
+// https://tus.io/protocols/resumable-upload.html#checksum
+const StatusTusChecksumMismatch = 460
+
+// Infected is the error to use when a file is quarantined because a virus was found in it.
+type Infected string
+
+func (e Infected) Error() string { return "error: infected: " + string(e) }
+
+// IsInfected implements the IsInfected interface.
+func (e Infected) IsInfected() {}
+
 // InsufficientStorage is the error to use when there is insufficient storage.
 type InsufficientStorage string
 
@@ -182,6 +194,12 @@ type IsChecksumMismatch interface {
 	IsChecksumMismatch()
 }
 
+// IsInfected is the interface to implement
+// to specify that a file is quarantined.
+type IsInfected interface {
+	IsInfected()
+}
+
 // IsInsufficientStorage is the interface to implement
 // to specify that there is insufficient storage.
 type IsInsufficientStorage interface {
@@ -0,0 +1,133 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package events
+
+import (
+	"encoding/json"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+// ContainerCreated is emitted when a folder has been created
+type ContainerCreated struct {
+	Executant *user.UserId
+	Ref       *provider.Reference
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (ContainerCreated) Unmarshal(v []byte) (interface{}, error) {
+	e := ContainerCreated{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// FileTouched is emitted when an empty file has been created
+type FileTouched struct {
+	Executant *user.UserId
+	Ref       *provider.Reference
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (FileTouched) Unmarshal(v []byte) (interface{}, error) {
+	e := FileTouched{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// FileUploaded is emitted when the upload of a file has been finished
+type FileUploaded struct {
+	Executant *user.UserId
+	// Ref references the uploaded file by its id. It is the reference of the file
+	// as known to the storage if the file could not be stated after the upload.
+	Ref *provider.Reference
+	// Path is the path of the file inside its storage, empty if it is unknown
+	Path     string
+	UploadID string
+	Size     int64
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (FileUploaded) Unmarshal(v []byte) (interface{}, error) {
+	e := FileUploaded{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// ItemTrashed is emitted when a file or folder has been deleted
+type ItemTrashed struct {
+	Executant *user.UserId
+	Ref       *provider.Reference
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (ItemTrashed) Unmarshal(v []byte) (interface{}, error) {
+	e := ItemTrashed{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// ItemPurged is emitted when a file or folder has been removed from the trash bin for good
+type ItemPurged struct {
+	Executant *user.UserId
+	Ref       *provider.Reference
+	// Key is the key of the purged trash item, empty when the whole trash bin has been emptied
+	Key string
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (ItemPurged) Unmarshal(v []byte) (interface{}, error) {
+	e := ItemPurged{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// ItemMoved is emitted when a file or folder has been moved or renamed
+type ItemMoved struct {
+	Executant    *user.UserId
+	Ref          *provider.Reference
+	OldReference *provider.Reference
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (ItemMoved) Unmarshal(v []byte) (interface{}, error) {
+	e := ItemMoved{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// SoftQuotaExceeded is emitted when the usage of a space exceeds its quota for the first time
+// and the overage tolerated by the soft quota is in use
+type SoftQuotaExceeded struct {
+	SpaceOwner *user.UserId
+	SpaceID    string
+	Used       uint64
+	Quota      uint64
+	HardLimit  uint64
+	// GraceUntil is nil if the overage is tolerated indefinitely
+	GraceUntil *types.Timestamp
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (SoftQuotaExceeded) Unmarshal(v []byte) (interface{}, error) {
+	e := SoftQuotaExceeded{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
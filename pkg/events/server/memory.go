@@ -0,0 +1,134 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package server
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go-micro.dev/v4/events"
+)
+
+// memoryQueueSize is the number of events buffered per consumer.
+const memoryQueueSize = 1024
+
+var (
+	memoryStreamsMu sync.Mutex
+	memoryStreams   = map[string]*memoryStream{}
+)
+
+// NewMemoryStream returns the in-memory stream with the given name. The
+// services running in the same process and using the same name exchange
+// events through it, without the need of a nats server.
+func NewMemoryStream(name string) events.Stream {
+	memoryStreamsMu.Lock()
+	defer memoryStreamsMu.Unlock()
+	s, ok := memoryStreams[name]
+	if !ok {
+		s = &memoryStream{topics: map[string]map[string]*memoryGroup{}}
+		memoryStreams[name] = s
+	}
+	return s
+}
+
+type memoryStream struct {
+	mu sync.Mutex
+	// topics holds the groups of consumers by topic and group name
+	topics map[string]map[string]*memoryGroup
+}
+
+// memoryGroup holds the consumers of a group, each event is delivered to one
+// of them in turn.
+type memoryGroup struct {
+	consumers []chan events.Event
+	next      int
+}
+
+// Publish delivers the message to every group consuming the topic.
+func (s *memoryStream) Publish(topic string, msg interface{}, opts ...events.PublishOption) error {
+	o := events.PublishOptions{Timestamp: time.Now()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	payload, ok := msg.([]byte)
+	if !ok {
+		var err error
+		if payload, err = json.Marshal(msg); err != nil {
+			return errors.Wrap(err, "error encoding event")
+		}
+	}
+	ev := events.Event{
+		ID:        uuid.New().String(),
+		Topic:     topic,
+		Timestamp: o.Timestamp,
+		Metadata:  o.Metadata,
+		Payload:   payload,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var dropped int
+	for _, g := range s.topics[topic] {
+		c := g.consumers[g.next%len(g.consumers)]
+		g.next++
+		select {
+		case c <- ev:
+		default:
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		return errors.Errorf("event dropped for %d slow consumer groups", dropped)
+	}
+	return nil
+}
+
+// Consume subscribes to the topic. The consumers without a group get every
+// event, the ones sharing a group get exactly one copy for all of them.
+func (s *memoryStream) Consume(topic string, opts ...events.ConsumeOption) (<-chan events.Event, error) {
+	o := events.ConsumeOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	group := o.Group
+	if group == "" {
+		group = uuid.New().String()
+	}
+
+	c := make(chan events.Event, memoryQueueSize)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	groups, ok := s.topics[topic]
+	if !ok {
+		groups = map[string]*memoryGroup{}
+		s.topics[topic] = groups
+	}
+	g, ok := groups[group]
+	if !ok {
+		g = &memoryGroup{}
+		groups[group] = g
+	}
+	g.consumers = append(g.consumers, c)
+	return c, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/events"
+)
+
+type uploaded struct {
+	Name string
+}
+
+func receive(t *testing.T, c <-chan events.Event) events.Event {
+	select {
+	case ev := <-c:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("expected an event")
+	}
+	return events.Event{}
+}
+
+func empty(c <-chan events.Event) bool {
+	select {
+	case <-c:
+		return false
+	default:
+		return true
+	}
+}
+
+func TestMemoryStream(t *testing.T) {
+	s := NewMemoryStream(t.Name())
+	if NewMemoryStream(t.Name()) != s {
+		t.Fatal("expected streams with the same name to be shared")
+	}
+
+	indexer1, _ := s.Consume("main", events.WithGroup("indexer"))
+	indexer2, _ := s.Consume("main", events.WithGroup("indexer"))
+	audit, _ := s.Consume("main", events.WithGroup("audit"))
+	other, _ := s.Consume("other")
+
+	for _, name := range []string{"a", "b"} {
+		if err := s.Publish("main", uploaded{Name: name}, events.WithMetadata(map[string]string{"eventtype": "uploaded"})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the consumers of a group get one copy in turn
+	if ev := receive(t, indexer1); string(ev.Payload) != `{"Name":"a"}` || ev.Metadata["eventtype"] != "uploaded" {
+		t.Errorf("unexpected event %+v", ev)
+	}
+	if ev := receive(t, indexer2); string(ev.Payload) != `{"Name":"b"}` {
+		t.Errorf("unexpected event %+v", ev)
+	}
+	if !empty(indexer1) || !empty(indexer2) {
+		t.Error("expected the group to get a single copy of each event")
+	}
+
+	// every group gets all the events
+	receive(t, audit)
+	receive(t, audit)
+
+	if !empty(other) {
+		t.Error("expected no events of other topics")
+	}
+}
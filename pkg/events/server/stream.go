@@ -0,0 +1,55 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package server
+
+import (
+	"fmt"
+
+	"github.com/asim/go-micro/plugins/events/nats/v4"
+	"go-micro.dev/v4/events"
+)
+
+// Config selects the broker events are published to and consumed from.
+type Config struct {
+	// Type is the broker, either "nats" or "memory".
+	Type string `mapstructure:"type"`
+	// Address is the address of the nats streaming server.
+	Address string `mapstructure:"address"`
+	// ClusterID is the cluster id of the nats streaming server.
+	ClusterID string `mapstructure:"cluster_id"`
+	// Name is the name of the in-memory stream. The services of a process
+	// using the same name exchange events.
+	Name string `mapstructure:"name"`
+}
+
+// NewStream returns the stream of the configured broker.
+func NewStream(c *Config) (events.Stream, error) {
+	switch c.Type {
+	case "nats":
+		return NewNatsStream(nats.Address(c.Address), nats.ClusterID(c.ClusterID))
+	case "memory":
+		name := c.Name
+		if name == "" {
+			name = "reva"
+		}
+		return NewMemoryStream(name), nil
+	default:
+		return nil, fmt.Errorf("stream type '%s' not supported", c.Type)
+	}
+}
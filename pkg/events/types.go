@@ -23,13 +23,17 @@ import (
 
 	group "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
 	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 )
 
 // ShareCreated is emitted when a share is created
 type ShareCreated struct { // TODO: Rename to ShareCreatedEvent?
-	Sharer *user.UserId
+	ShareID *collaboration.ShareId
+	Sharer  *user.UserId
 	// split the protobuf Grantee oneof so we can use stdlib encoding/json
 	GranteeUserID  *user.UserId
 	GranteeGroupID *group.GroupId
@@ -44,3 +48,168 @@ func (ShareCreated) Unmarshal(v []byte) (interface{}, error) {
 	err := json.Unmarshal(v, &e)
 	return e, err
 }
+
+// ShareRemoved is emitted when a share is removed
+type ShareRemoved struct {
+	Executant *user.UserId
+	ShareID   *collaboration.ShareId
+	ShareKey  *collaboration.ShareKey
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (ShareRemoved) Unmarshal(v []byte) (interface{}, error) {
+	e := ShareRemoved{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// ReceivedShareUpdated is emitted when the grantee of a share updates it, e.g. accepts it
+type ReceivedShareUpdated struct {
+	Executant *user.UserId
+	ShareID   *collaboration.ShareId
+	State     collaboration.ShareState
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (ReceivedShareUpdated) Unmarshal(v []byte) (interface{}, error) {
+	e := ReceivedShareUpdated{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// ReceivedOCMShareUpdated is emitted when the grantee of an OCM share updates it, e.g. accepts it
+type ReceivedOCMShareUpdated struct {
+	Executant *user.UserId
+	ShareID   *ocm.ShareId
+	State     ocm.ShareState
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (ReceivedOCMShareUpdated) Unmarshal(v []byte) (interface{}, error) {
+	e := ReceivedOCMShareUpdated{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// SpaceCreated is emitted when a storage space is created
+type SpaceCreated struct {
+	Executant *user.UserId
+	ID        *provider.StorageSpaceId
+	Type      string
+	Name      string
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (SpaceCreated) Unmarshal(v []byte) (interface{}, error) {
+	e := SpaceCreated{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// LinkExpiring is emitted when the owner of a public link is notified about its upcoming expiration
+type LinkExpiring struct {
+	ShareID    *link.PublicShareId
+	Owner      *user.UserId
+	ItemID     *provider.ResourceId
+	Expiration *types.Timestamp
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (LinkExpiring) Unmarshal(v []byte) (interface{}, error) {
+	e := LinkExpiring{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// LinkExpired is emitted when an expired public link is removed
+type LinkExpired struct {
+	ShareID    *link.PublicShareId
+	Owner      *user.UserId
+	ItemID     *provider.ResourceId
+	Expiration *types.Timestamp
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (LinkExpired) Unmarshal(v []byte) (interface{}, error) {
+	e := LinkExpired{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// LinkCreated is emitted when a public link is created
+type LinkCreated struct {
+	ShareID           *link.PublicShareId
+	Sharer            *user.UserId
+	ItemID            *provider.ResourceId
+	Permissions       *link.PublicSharePermissions
+	DisplayName       string
+	Expiration        *types.Timestamp
+	PasswordProtected bool
+	CTime             *types.Timestamp
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (LinkCreated) Unmarshal(v []byte) (interface{}, error) {
+	e := LinkCreated{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// LinkRemoved is emitted when a public link is removed
+type LinkRemoved struct {
+	Executant  *user.UserId
+	ShareID    *link.PublicShareId
+	ShareToken string
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (LinkRemoved) Unmarshal(v []byte) (interface{}, error) {
+	e := LinkRemoved{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// LinkAccessed is emitted when a public link is accessed
+type LinkAccessed struct {
+	ShareID    *link.PublicShareId
+	Sharer     *user.UserId
+	ItemID     *provider.ResourceId
+	ShareToken string
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (LinkAccessed) Unmarshal(v []byte) (interface{}, error) {
+	e := LinkAccessed{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// CommentMentioned is emitted when a user is mentioned in a comment on a resource
+type CommentMentioned struct {
+	Author    *user.UserId
+	Mentioned *user.UserId
+	ItemID    *provider.ResourceId
+	CommentID string
+	Message   string
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (CommentMentioned) Unmarshal(v []byte) (interface{}, error) {
+	e := CommentMentioned{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// GroupMembershipChanged is emitted when the groups of a user in the user provider have changed
+type GroupMembershipChanged struct {
+	UserID  *user.UserId
+	Added   []string
+	Removed []string
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (GroupMembershipChanged) Unmarshal(v []byte) (interface{}, error) {
+	e := GroupMembershipChanged{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
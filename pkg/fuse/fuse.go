@@ -0,0 +1,94 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+//go:build linux
+// +build linux
+
+// Package fuse implements a minimal FUSE server on top of the kernel protocol,
+// enough to expose a remote tree as a local mount point.
+package fuse
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"time"
+)
+
+// RootID is the node id of the root of the file system.
+const RootID uint64 = 1
+
+// Attr holds the attributes of a node.
+type Attr struct {
+	Ino   uint64
+	Size  uint64
+	Mode  os.FileMode
+	Nlink uint32
+	UID   uint32
+	GID   uint32
+	Mtime time.Time
+	// Valid is how long the kernel may cache the attributes.
+	Valid time.Duration
+}
+
+// Dirent is an entry of a directory listing.
+type Dirent struct {
+	Ino  uint64
+	Name string
+	Dir  bool
+}
+
+// StatFS describes the capacity of the file system.
+type StatFS struct {
+	Blocks uint64
+	Bfree  uint64
+	Bavail uint64
+	Bsize  uint32
+}
+
+// FileSystem is implemented by the backends served over FUSE.
+// Nodes are identified by the ids handed out by Lookup, Mkdir and Create;
+// errors are reported to the kernel as syscall.Errno, any other error maps to EIO.
+type FileSystem interface {
+	Lookup(ctx context.Context, parent uint64, name string) (*Attr, error)
+	Forget(id uint64, n uint64)
+	GetAttr(ctx context.Context, id uint64) (*Attr, error)
+	Truncate(ctx context.Context, id uint64, fh uint64, size uint64) (*Attr, error)
+	Mkdir(ctx context.Context, parent uint64, name string) (*Attr, error)
+	Unlink(ctx context.Context, parent uint64, name string) error
+	Rmdir(ctx context.Context, parent uint64, name string) error
+	Rename(ctx context.Context, parent uint64, name string, newParent uint64, newName string) error
+	ReadDir(ctx context.Context, id uint64) ([]Dirent, error)
+	Open(ctx context.Context, id uint64, flags int) (uint64, error)
+	Create(ctx context.Context, parent uint64, name string, flags int) (*Attr, uint64, error)
+	Read(ctx context.Context, id uint64, fh uint64, off int64, size int) ([]byte, error)
+	Write(ctx context.Context, id uint64, fh uint64, off int64, data []byte) (int, error)
+	Flush(ctx context.Context, id uint64, fh uint64) error
+	Release(ctx context.Context, id uint64, fh uint64) error
+	StatFS(ctx context.Context) (*StatFS, error)
+}
+
+func errno(err error) int32 {
+	if err == nil {
+		return 0
+	}
+	if e, ok := err.(syscall.Errno); ok {
+		return -int32(e)
+	}
+	return -int32(syscall.EIO)
+}
@@ -0,0 +1,194 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+//go:build linux
+// +build linux
+
+package fuse
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// MountOptions configures a mount.
+type MountOptions struct {
+	// FSName is shown as the source of the mount.
+	FSName string
+	// AllowOther lets other users access the mount.
+	AllowOther bool
+}
+
+// Conn is an open FUSE connection for a mount point.
+type Conn struct {
+	fd  int
+	dir string
+}
+
+// Mount mounts a FUSE file system at dir and returns the connection serving it.
+// The mount is done directly when running as root, with the fusermount helper otherwise.
+func Mount(dir string, opts MountOptions) (*Conn, error) {
+	if opts.FSName == "" {
+		opts.FSName = "reva"
+	}
+	options := []string{"fsname=" + opts.FSName, "subtype=reva", "default_permissions"}
+	if opts.AllowOther {
+		options = append(options, "allow_other")
+	}
+
+	var fd int
+	var err error
+	if os.Geteuid() == 0 {
+		fd, err = mountDirect(dir, options)
+	} else {
+		fd, err = mountHelper(dir, options)
+	}
+	if err != nil {
+		return nil, err
+	}
+	syscall.CloseOnExec(fd)
+	return &Conn{fd: fd, dir: dir}, nil
+}
+
+func mountDirect(dir string, options []string) (int, error) {
+	fd, err := syscall.Open("/dev/fuse", syscall.O_RDWR, 0)
+	if err != nil {
+		return -1, errors.Wrap(err, "fuse: error opening /dev/fuse")
+	}
+	data := append([]string{
+		fmt.Sprintf("fd=%d", fd),
+		"rootmode=40000",
+		fmt.Sprintf("user_id=%d", os.Getuid()),
+		fmt.Sprintf("group_id=%d", os.Getgid()),
+	}, options...)
+	var source, fstype string
+	var rest []string
+	for _, o := range data {
+		switch {
+		case strings.HasPrefix(o, "fsname="):
+			source = strings.TrimPrefix(o, "fsname=")
+		case strings.HasPrefix(o, "subtype="):
+			fstype = "fuse." + strings.TrimPrefix(o, "subtype=")
+		default:
+			rest = append(rest, o)
+		}
+	}
+	if err := syscall.Mount(source, dir, fstype, syscall.MS_NOSUID|syscall.MS_NODEV, strings.Join(rest, ",")); err != nil {
+		syscall.Close(fd)
+		return -1, errors.Wrapf(err, "fuse: error mounting %s", dir)
+	}
+	return fd, nil
+}
+
+// mountHelper runs fusermount, which passes the opened /dev/fuse back over a unix socket
+func mountHelper(dir string, options []string) (int, error) {
+	bin, err := fusermount()
+	if err != nil {
+		return -1, err
+	}
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		return -1, errors.Wrap(err, "fuse: error creating socket pair")
+	}
+	local := os.NewFile(uintptr(fds[0]), "fuse-local")
+	remote := os.NewFile(uintptr(fds[1]), "fuse-remote")
+	defer local.Close()
+	defer remote.Close()
+
+	cmd := exec.Command(bin, "-o", strings.Join(options, ","), "--", dir)
+	cmd.Env = append(os.Environ(), "_FUSE_COMMFD=3")
+	cmd.ExtraFiles = []*os.File{remote}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return -1, errors.Wrapf(err, "fuse: %s failed: %s", bin, strings.TrimSpace(string(out)))
+	}
+
+	buf := make([]byte, 4)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	_, oobn, _, _, err := syscall.Recvmsg(int(local.Fd()), buf, oob, 0)
+	if err != nil {
+		return -1, errors.Wrap(err, "fuse: error receiving the fuse descriptor")
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) != 1 {
+		return -1, errors.New("fuse: invalid control message from " + bin)
+	}
+	rights, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil || len(rights) != 1 {
+		return -1, errors.New("fuse: no descriptor received from " + bin)
+	}
+	return rights[0], nil
+}
+
+func fusermount() (string, error) {
+	for _, name := range []string{"fusermount3", "fusermount"} {
+		if p, err := exec.LookPath(name); err == nil {
+			return p, nil
+		}
+	}
+	return "", errors.New("fuse: fusermount not found in PATH")
+}
+
+// Unmount unmounts the file system mounted at dir.
+func Unmount(dir string) error {
+	if os.Geteuid() == 0 {
+		return syscall.Unmount(dir, 0)
+	}
+	bin, err := fusermount()
+	if err != nil {
+		return err
+	}
+	if out, err := exec.Command(bin, "-u", dir).CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "fuse: %s failed: %s", bin, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Read reads the next request from the kernel.
+func (c *Conn) Read(b []byte) (int, error) {
+	n, err := syscall.Read(c.fd, b)
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Write sends a reply to the kernel.
+func (c *Conn) Write(b []byte) (int, error) {
+	n, err := syscall.Write(c.fd, b)
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Close closes the connection; it does not unmount the file system.
+func (c *Conn) Close() error {
+	return syscall.Close(c.fd)
+}
+
+// Dir returns the mount point.
+func (c *Conn) Dir() string {
+	return c.dir
+}
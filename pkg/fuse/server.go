@@ -0,0 +1,476 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+//go:build linux
+// +build linux
+
+package fuse
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// nativeEndian is the byte order the kernel uses for the protocol structures
+var nativeEndian binary.ByteOrder
+
+func init() {
+	i := uint16(1)
+	if *(*byte)(unsafe.Pointer(&i)) == 1 {
+		nativeEndian = binary.LittleEndian
+	} else {
+		nativeEndian = binary.BigEndian
+	}
+}
+
+// Server dispatches the requests read from a FUSE connection to a FileSystem.
+type Server struct {
+	fs   FileSystem
+	conn io.ReadWriter
+
+	mu      sync.Mutex
+	dirs    map[uint64][]Dirent
+	nextDir uint64
+	pending map[uint64]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewServer returns a server answering the requests read from conn with fs.
+func NewServer(conn io.ReadWriter, fs FileSystem) *Server {
+	return &Server{
+		fs:      fs,
+		conn:    conn,
+		dirs:    map[uint64][]Dirent{},
+		pending: map[uint64]context.CancelFunc{},
+	}
+}
+
+// Serve handles requests until the file system is unmounted.
+// Every request is handled in its own goroutine.
+func (s *Server) Serve() error {
+	defer s.wg.Wait()
+	buf := make([]byte, bufferSize)
+	for {
+		n, err := s.conn.Read(buf)
+		switch {
+		case err == syscall.EINTR || err == syscall.ENOENT || err == syscall.EAGAIN:
+			// the read was interrupted or the request was aborted by the kernel
+			continue
+		case err == syscall.ENODEV || err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+		if n < binary.Size(inHeader{}) {
+			continue
+		}
+
+		req := make([]byte, n)
+		copy(req, buf[:n])
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(req)
+		}()
+	}
+}
+
+func (s *Server) handle(req []byte) {
+	var hdr inHeader
+	_ = decode(req, &hdr)
+	if int(hdr.Len) <= len(req) {
+		req = req[:hdr.Len]
+	}
+	body := req[binary.Size(hdr):]
+
+	switch hdr.Opcode {
+	case opForget:
+		var in forgetIn
+		_ = decode(body, &in)
+		s.fs.Forget(hdr.Nodeid, in.Nlookup)
+		return
+	case opBatchForget:
+		var in batchForgetIn
+		_ = decode(body, &in)
+		body = body[binary.Size(in):]
+		for i := uint32(0); i < in.Count; i++ {
+			var one forgetOne
+			if decode(body, &one) != nil {
+				break
+			}
+			s.fs.Forget(one.Nodeid, one.Nlookup)
+			body = body[binary.Size(one):]
+		}
+		return
+	case opInterrupt:
+		var unique uint64
+		_ = decode(body, &unique)
+		s.mu.Lock()
+		if cancel, ok := s.pending[unique]; ok {
+			cancel()
+		}
+		s.mu.Unlock()
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.pending[hdr.Unique] = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, hdr.Unique)
+		s.mu.Unlock()
+		cancel()
+	}()
+
+	out, err := s.dispatch(ctx, &hdr, body)
+	s.reply(hdr.Unique, errno(err), out)
+}
+
+func (s *Server) dispatch(ctx context.Context, hdr *inHeader, body []byte) ([]byte, error) {
+	id := hdr.Nodeid
+	switch hdr.Opcode {
+	case opInit:
+		var in initIn
+		if err := decode(body, &in); err != nil {
+			return nil, err
+		}
+		if in.Major != kernelVersion {
+			return nil, syscall.EPROTO
+		}
+		return encode(initOut{
+			Major:               kernelVersion,
+			Minor:               kernelMinorVersion,
+			MaxReadahead:        in.MaxReadahead,
+			Flags:               in.Flags & (initAsyncRead | initBigWrites),
+			MaxBackground:       12,
+			CongestionThreshold: 9,
+			MaxWrite:            maxWrite,
+		}), nil
+
+	case opDestroy:
+		return nil, nil
+
+	case opLookup:
+		a, err := s.fs.Lookup(ctx, id, cstring(body))
+		if err != nil {
+			return nil, err
+		}
+		return encode(newEntryOut(a)), nil
+
+	case opGetattr:
+		a, err := s.fs.GetAttr(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return encode(newAttrOut(a)), nil
+
+	case opSetattr:
+		var in setattrIn
+		if err := decode(body, &in); err != nil {
+			return nil, err
+		}
+		var a *Attr
+		var err error
+		if in.Valid&setattrSize != 0 {
+			var fh uint64
+			if in.Valid&setattrFh != 0 {
+				fh = in.Fh
+			}
+			a, err = s.fs.Truncate(ctx, id, fh, in.Size)
+		} else {
+			// modes, owners and times are not stored remotely
+			a, err = s.fs.GetAttr(ctx, id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return encode(newAttrOut(a)), nil
+
+	case opMkdir:
+		var in mkdirIn
+		if err := decode(body, &in); err != nil {
+			return nil, err
+		}
+		a, err := s.fs.Mkdir(ctx, id, cstring(body[binary.Size(in):]))
+		if err != nil {
+			return nil, err
+		}
+		return encode(newEntryOut(a)), nil
+
+	case opUnlink:
+		return nil, s.fs.Unlink(ctx, id, cstring(body))
+
+	case opRmdir:
+		return nil, s.fs.Rmdir(ctx, id, cstring(body))
+
+	case opRename:
+		var in renameIn
+		if err := decode(body, &in); err != nil {
+			return nil, err
+		}
+		names := bytes.SplitN(body[binary.Size(in):], []byte{0}, 3)
+		if len(names) < 2 {
+			return nil, syscall.EINVAL
+		}
+		return nil, s.fs.Rename(ctx, id, string(names[0]), in.Newdir, string(names[1]))
+
+	case opOpen:
+		var in openIn
+		if err := decode(body, &in); err != nil {
+			return nil, err
+		}
+		fh, err := s.fs.Open(ctx, id, int(in.Flags))
+		if err != nil {
+			return nil, err
+		}
+		return encode(openOut{Fh: fh}), nil
+
+	case opCreate:
+		var in createIn
+		if err := decode(body, &in); err != nil {
+			return nil, err
+		}
+		a, fh, err := s.fs.Create(ctx, id, cstring(body[binary.Size(in):]), int(in.Flags))
+		if err != nil {
+			return nil, err
+		}
+		return append(encode(newEntryOut(a)), encode(openOut{Fh: fh})...), nil
+
+	case opRead:
+		var in readIn
+		if err := decode(body, &in); err != nil {
+			return nil, err
+		}
+		return s.fs.Read(ctx, id, in.Fh, int64(in.Offset), int(in.Size))
+
+	case opWrite:
+		var in writeIn
+		if err := decode(body, &in); err != nil {
+			return nil, err
+		}
+		data := body[binary.Size(in):]
+		if int(in.Size) < len(data) {
+			data = data[:in.Size]
+		}
+		n, err := s.fs.Write(ctx, id, in.Fh, int64(in.Offset), data)
+		if err != nil {
+			return nil, err
+		}
+		return encode(writeOut{Size: uint32(n)}), nil
+
+	case opFlush, opFsync:
+		var fh uint64
+		if err := decode(body, &fh); err != nil {
+			return nil, err
+		}
+		return nil, s.fs.Flush(ctx, id, fh)
+
+	case opRelease:
+		var in releaseIn
+		if err := decode(body, &in); err != nil {
+			return nil, err
+		}
+		return nil, s.fs.Release(ctx, id, in.Fh)
+
+	case opOpendir:
+		entries, err := s.fs.ReadDir(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		entries = append([]Dirent{{Ino: id, Name: ".", Dir: true}, {Name: "..", Dir: true}}, entries...)
+		s.mu.Lock()
+		s.nextDir++
+		fh := s.nextDir
+		s.dirs[fh] = entries
+		s.mu.Unlock()
+		return encode(openOut{Fh: fh}), nil
+
+	case opReaddir:
+		var in readIn
+		if err := decode(body, &in); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		entries, ok := s.dirs[in.Fh]
+		s.mu.Unlock()
+		if !ok {
+			return nil, syscall.EBADF
+		}
+		return encodeDirents(entries, in.Offset, int(in.Size)), nil
+
+	case opReleasedir:
+		var in releaseIn
+		if err := decode(body, &in); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		delete(s.dirs, in.Fh)
+		s.mu.Unlock()
+		return nil, nil
+
+	case opFsyncdir:
+		return nil, nil
+
+	case opStatfs:
+		st, err := s.fs.StatFS(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return encode(kstatfs{
+			Blocks:  st.Blocks,
+			Bfree:   st.Bfree,
+			Bavail:  st.Bavail,
+			Bsize:   st.Bsize,
+			Frsize:  st.Bsize,
+			Namelen: 255,
+		}), nil
+	}
+	return nil, syscall.ENOSYS
+}
+
+func (s *Server) reply(unique uint64, errno int32, payload []byte) {
+	if errno != 0 {
+		payload = nil
+	}
+	hdr := outHeader{
+		Len:    uint32(binary.Size(outHeader{}) + len(payload)),
+		Error:  errno,
+		Unique: unique,
+	}
+	// the reply has to reach the kernel in a single write
+	_, _ = s.conn.Write(append(encode(hdr), payload...))
+}
+
+// encodeDirents packs the entries starting at index off into at most size bytes.
+// The offset of every entry is the index of the next one.
+func encodeDirents(entries []Dirent, off uint64, size int) []byte {
+	var buf bytes.Buffer
+	direntSize := binary.Size(dirent{})
+	for i := off; i < uint64(len(entries)); i++ {
+		e := entries[i]
+		l := direntSize + len(e.Name)
+		padded := (l + 7) &^ 7
+		if buf.Len()+padded > size {
+			break
+		}
+		typ := uint32(syscall.DT_REG)
+		if e.Dir {
+			typ = syscall.DT_DIR
+		}
+		ino := e.Ino
+		if ino == 0 {
+			// zero inodes are skipped by readdir
+			ino = ^uint64(0)
+		}
+		_ = binary.Write(&buf, nativeEndian, dirent{Ino: ino, Off: i + 1, Namelen: uint32(len(e.Name)), Type: typ})
+		buf.WriteString(e.Name)
+		buf.Write(make([]byte, padded-l))
+	}
+	return buf.Bytes()
+}
+
+func newAttr(a *Attr) attr {
+	mode := uint32(a.Mode.Perm())
+	if a.Mode.IsDir() {
+		mode |= syscall.S_IFDIR
+	} else {
+		mode |= syscall.S_IFREG
+	}
+	nlink := a.Nlink
+	if nlink == 0 {
+		nlink = 1
+	}
+	var mtime uint64
+	var mtimensec uint32
+	if !a.Mtime.IsZero() {
+		mtime = uint64(a.Mtime.Unix())
+		mtimensec = uint32(a.Mtime.Nanosecond())
+	}
+	return attr{
+		Ino:       a.Ino,
+		Size:      a.Size,
+		Blocks:    (a.Size + 511) / 512,
+		Atime:     mtime,
+		Mtime:     mtime,
+		Ctime:     mtime,
+		Atimensec: mtimensec,
+		Mtimensec: mtimensec,
+		Ctimensec: mtimensec,
+		Mode:      mode,
+		Nlink:     nlink,
+		UID:       a.UID,
+		GID:       a.GID,
+		Blksize:   4096,
+	}
+}
+
+func newEntryOut(a *Attr) entryOut {
+	sec, nsec := split(a.Valid)
+	return entryOut{
+		Nodeid:         a.Ino,
+		EntryValid:     sec,
+		EntryValidNsec: nsec,
+		AttrValid:      sec,
+		AttrValidNsec:  nsec,
+		Attr:           newAttr(a),
+	}
+}
+
+func newAttrOut(a *Attr) attrOut {
+	sec, nsec := split(a.Valid)
+	return attrOut{
+		AttrValid:     sec,
+		AttrValidNsec: nsec,
+		Attr:          newAttr(a),
+	}
+}
+
+func split(d time.Duration) (uint64, uint32) {
+	if d < 0 {
+		return 0, 0
+	}
+	return uint64(d / time.Second), uint32(d % time.Second)
+}
+
+func cstring(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+func encode(v interface{}) []byte {
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, nativeEndian, v)
+	return buf.Bytes()
+}
+
+func decode(b []byte, v interface{}) error {
+	if err := binary.Read(bytes.NewReader(b), nativeEndian, v); err != nil {
+		return syscall.EINVAL
+	}
+	return nil
+}
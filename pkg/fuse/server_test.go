@@ -0,0 +1,258 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+//go:build linux
+// +build linux
+
+package fuse
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeKernel feeds requests to the server and collects its replies
+type fakeKernel struct {
+	mu       sync.Mutex
+	requests [][]byte
+	replies  map[uint64][]byte
+}
+
+func (k *fakeKernel) Read(b []byte) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if len(k.requests) == 0 {
+		return 0, syscall.ENODEV
+	}
+	n := copy(b, k.requests[0])
+	k.requests = k.requests[1:]
+	return n, nil
+}
+
+func (k *fakeKernel) Write(b []byte) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var hdr outHeader
+	_ = decode(b, &hdr)
+	k.replies[hdr.Unique] = append([]byte(nil), b...)
+	return len(b), nil
+}
+
+func (k *fakeKernel) send(op opcode, node uint64, args ...interface{}) uint64 {
+	var body []byte
+	for _, a := range args {
+		switch v := a.(type) {
+		case string:
+			body = append(body, v...)
+			body = append(body, 0)
+		case []byte:
+			body = append(body, v...)
+		default:
+			body = append(body, encode(v)...)
+		}
+	}
+	unique := uint64(len(k.requests) + 1)
+	hdr := inHeader{Len: uint32(binary.Size(inHeader{}) + len(body)), Opcode: op, Unique: unique, Nodeid: node}
+	k.requests = append(k.requests, append(encode(hdr), body...))
+	return unique
+}
+
+func (k *fakeKernel) reply(t *testing.T, unique uint64, out interface{}) int32 {
+	t.Helper()
+	b, ok := k.replies[unique]
+	if !ok {
+		t.Fatalf("no reply to request %d", unique)
+	}
+	var hdr outHeader
+	_ = decode(b, &hdr)
+	if int(hdr.Len) != len(b) {
+		t.Fatalf("reply length %d does not match header %d", len(b), hdr.Len)
+	}
+	if out != nil && hdr.Error == 0 {
+		if p, ok := out.(*[]byte); ok {
+			*p = b[binary.Size(hdr):]
+		} else if err := decode(b[binary.Size(hdr):], out); err != nil {
+			t.Fatalf("error decoding reply: %v", err)
+		}
+	}
+	return hdr.Error
+}
+
+// memFS is a flat file system holding a few files in its root
+type memFS struct {
+	FileSystem
+	files map[string][]byte
+	inos  map[string]uint64
+}
+
+func (m *memFS) Lookup(ctx context.Context, parent uint64, name string) (*Attr, error) {
+	data, ok := m.files[name]
+	if !ok || parent != RootID {
+		return nil, syscall.ENOENT
+	}
+	return &Attr{Ino: m.inos[name], Size: uint64(len(data)), Mode: 0644, Valid: time.Second}, nil
+}
+
+func (m *memFS) ReadDir(ctx context.Context, id uint64) ([]Dirent, error) {
+	var entries []Dirent
+	for _, name := range []string{"a", "bb", "a-much-longer-name"} {
+		entries = append(entries, Dirent{Ino: m.inos[name], Name: name})
+	}
+	return entries, nil
+}
+
+func (m *memFS) Read(ctx context.Context, id uint64, fh uint64, off int64, size int) ([]byte, error) {
+	for name, ino := range m.inos {
+		if ino == id {
+			data := m.files[name]
+			if off >= int64(len(data)) {
+				return nil, nil
+			}
+			end := off + int64(size)
+			if end > int64(len(data)) {
+				end = int64(len(data))
+			}
+			return data[off:end], nil
+		}
+	}
+	return nil, syscall.EBADF
+}
+
+func newMemFS() *memFS {
+	return &memFS{
+		files: map[string][]byte{"a": []byte("hello world"), "bb": nil, "a-much-longer-name": []byte("x")},
+		inos:  map[string]uint64{"a": 2, "bb": 3, "a-much-longer-name": 4},
+	}
+}
+
+func TestServer(t *testing.T) {
+	k := &fakeKernel{replies: map[uint64][]byte{}}
+	initReq := k.send(opInit, 0, initIn{Major: 7, Minor: 31, MaxReadahead: 65536, Flags: initAsyncRead | initBigWrites | 1<<10})
+	lookup := k.send(opLookup, RootID, "a")
+	missing := k.send(opLookup, RootID, "nope")
+	read := k.send(opRead, 2, readIn{Offset: 6, Size: 100})
+	opendir := k.send(opOpendir, RootID, openIn{})
+	unknown := k.send(opcode(9999), RootID)
+
+	if err := NewServer(k, newMemFS()).Serve(); err != nil {
+		t.Fatal(err)
+	}
+
+	var init initOut
+	if e := k.reply(t, initReq, &init); e != 0 {
+		t.Fatalf("init failed: %d", e)
+	}
+	if init.Major != 7 || init.Minor != kernelMinorVersion || init.Flags != initAsyncRead|initBigWrites || init.MaxWrite != maxWrite {
+		t.Errorf("unexpected init reply %+v", init)
+	}
+
+	var entry entryOut
+	if e := k.reply(t, lookup, &entry); e != 0 {
+		t.Fatalf("lookup failed: %d", e)
+	}
+	if entry.Nodeid != 2 || entry.Attr.Size != 11 || entry.Attr.Mode != syscall.S_IFREG|0644 || entry.EntryValid != 1 {
+		t.Errorf("unexpected lookup reply %+v", entry)
+	}
+	if e := k.reply(t, missing, nil); e != -int32(syscall.ENOENT) {
+		t.Errorf("expected ENOENT, got %d", e)
+	}
+
+	var data []byte
+	if e := k.reply(t, read, &data); e != 0 || string(data) != "world" {
+		t.Errorf("unexpected read reply %q (%d)", data, e)
+	}
+
+	var open openOut
+	if e := k.reply(t, opendir, &open); e != 0 || open.Fh == 0 {
+		t.Errorf("unexpected opendir reply %+v (%d)", open, e)
+	}
+
+	if e := k.reply(t, unknown, nil); e != -int32(syscall.ENOSYS) {
+		t.Errorf("expected ENOSYS, got %d", e)
+	}
+}
+
+func TestEncodeDirents(t *testing.T) {
+	entries := []Dirent{
+		{Ino: 1, Name: ".", Dir: true},
+		{Name: "..", Dir: true},
+		{Ino: 7, Name: "file.txt"},
+	}
+	b := encodeDirents(entries, 0, 4096)
+	if len(b)%8 != 0 {
+		t.Fatalf("dirents are not padded: %d bytes", len(b))
+	}
+
+	var names []string
+	r := bytes.NewReader(b)
+	for r.Len() > 0 {
+		var d dirent
+		if err := binary.Read(r, nativeEndian, &d); err != nil {
+			t.Fatal(err)
+		}
+		name := make([]byte, (int(d.Namelen)+7)&^7)
+		_, _ = r.Read(name)
+		names = append(names, string(name[:d.Namelen]))
+		if d.Ino == 0 {
+			t.Errorf("entry %s has a zero inode", names[len(names)-1])
+		}
+		if d.Off != uint64(len(names)) {
+			t.Errorf("entry %s has offset %d", names[len(names)-1], d.Off)
+		}
+	}
+	if len(names) != 3 || names[2] != "file.txt" {
+		t.Errorf("unexpected names %v", names)
+	}
+
+	// entries not fitting the buffer are left for the next request
+	if b := encodeDirents(entries, 0, 40); len(b) != 32 {
+		t.Errorf("expected a single entry, got %d bytes", len(b))
+	}
+	if b := encodeDirents(entries, 2, 4096); len(b) != 32 {
+		t.Errorf("expected only the last entry, got %d bytes", len(b))
+	}
+}
+
+func TestNewAttr(t *testing.T) {
+	mtime := time.Unix(1600000000, 42)
+	a := newAttr(&Attr{Ino: 3, Size: 1025, Mode: os.ModeDir | 0755, Mtime: mtime})
+	if a.Mode != syscall.S_IFDIR|0755 || a.Blocks != 3 || a.Nlink != 1 || a.Mtime != 1600000000 || a.Mtimensec != 42 {
+		t.Errorf("unexpected attributes %+v", a)
+	}
+}
+
+func TestStructSizes(t *testing.T) {
+	for _, c := range []struct {
+		v    interface{}
+		size int
+	}{
+		{inHeader{}, 40}, {outHeader{}, 16}, {attr{}, 88}, {entryOut{}, 128}, {attrOut{}, 104},
+		{setattrIn{}, 88}, {readIn{}, 40}, {writeIn{}, 40}, {createIn{}, 16}, {initOut{}, 24},
+		{kstatfs{}, 80}, {dirent{}, 24}, {releaseIn{}, 24}, {flushIn{}, 24},
+	} {
+		if s := binary.Size(c.v); s != c.size {
+			t.Errorf("%T has size %d, expected %d", c.v, s, c.size)
+		}
+	}
+}
@@ -33,3 +33,17 @@ type Manager interface {
 	GetMembers(ctx context.Context, gid *grouppb.GroupId) ([]*userpb.UserId, error)
 	HasMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) (bool, error)
 }
+
+// Writer is implemented by group managers able to provision groups.
+type Writer interface {
+	// CreateGroup creates the group. If the group has no id, one is assigned.
+	CreateGroup(ctx context.Context, g *grouppb.Group) (*grouppb.Group, error)
+	// UpdateGroup updates the attributes of the group.
+	UpdateGroup(ctx context.Context, g *grouppb.Group) error
+	// DeleteGroup deletes the group.
+	DeleteGroup(ctx context.Context, gid *grouppb.GroupId) error
+	// AddMember adds the user to the group.
+	AddMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) error
+	// RemoveMember removes the user from the group.
+	RemoveMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) error
+}
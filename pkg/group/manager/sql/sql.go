@@ -0,0 +1,187 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/group"
+	"github.com/cs3org/reva/pkg/group/manager/registry"
+	"github.com/cs3org/reva/pkg/user/manager/sql/accounts"
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("sql", New)
+}
+
+type config struct {
+	DbUsername string `mapstructure:"db_username"`
+	DbPassword string `mapstructure:"db_password"`
+	DbHost     string `mapstructure:"db_host"`
+	DbPort     int    `mapstructure:"db_port"`
+	DbName     string `mapstructure:"db_name"`
+	Idp        string `mapstructure:"idp"`
+}
+
+type manager struct {
+	c  *config
+	db *accounts.Accounts
+}
+
+// New returns a group manager storing the groups in the SQL database shared with the sql user manager.
+// The groups can be provisioned using the group.Writer interface.
+func New(m map[string]interface{}) (group.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+
+	db, err := accounts.NewMysql(fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", c.DbUsername, c.DbPassword, c.DbHost, c.DbPort, c.DbName))
+	if err != nil {
+		return nil, err
+	}
+	return &manager{c: c, db: db}, nil
+}
+
+func (m *manager) GetGroup(ctx context.Context, gid *grouppb.GroupId, skipFetchingMembers bool) (*grouppb.Group, error) {
+	g, err := m.db.GetGroupByClaim(ctx, "groupid", gid.OpaqueId)
+	if err != nil {
+		return nil, err
+	}
+	return m.convertToCS3Group(ctx, g, skipFetchingMembers)
+}
+
+func (m *manager) GetGroupByClaim(ctx context.Context, claim, value string, skipFetchingMembers bool) (*grouppb.Group, error) {
+	g, err := m.db.GetGroupByClaim(ctx, claim, value)
+	if err != nil {
+		return nil, err
+	}
+	return m.convertToCS3Group(ctx, g, skipFetchingMembers)
+}
+
+func (m *manager) FindGroups(ctx context.Context, query string, skipFetchingMembers bool) ([]*grouppb.Group, error) {
+	groups, err := m.db.FindGroups(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*grouppb.Group, 0, len(groups))
+	for _, g := range groups {
+		cs3Group, err := m.convertToCS3Group(ctx, g, skipFetchingMembers)
+		if err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Str("groupid", g.ID).Msg("could not convert group, skipping")
+			continue
+		}
+		res = append(res, cs3Group)
+	}
+	return res, nil
+}
+
+func (m *manager) GetMembers(ctx context.Context, gid *grouppb.GroupId) ([]*userpb.UserId, error) {
+	ids, err := m.db.GetMembers(ctx, gid.OpaqueId)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]*userpb.UserId, 0, len(ids))
+	for _, id := range ids {
+		members = append(members, &userpb.UserId{OpaqueId: id, Idp: m.c.Idp, Type: userpb.UserType_USER_TYPE_PRIMARY})
+	}
+	return members, nil
+}
+
+func (m *manager) HasMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) (bool, error) {
+	return m.db.HasMember(ctx, gid.OpaqueId, uid.OpaqueId)
+}
+
+func (m *manager) CreateGroup(ctx context.Context, g *grouppb.Group) (*grouppb.Group, error) {
+	dbGroup := convertToDBGroup(g)
+	if dbGroup.ID == "" {
+		dbGroup.ID = uuid.NewString()
+	}
+	if dbGroup.GroupName == "" {
+		return nil, errors.New("sql: groups need a name")
+	}
+	if err := m.db.CreateGroup(ctx, dbGroup); err != nil {
+		return nil, errors.Wrap(err, "sql: error creating group")
+	}
+	return m.convertToCS3Group(ctx, dbGroup, true)
+}
+
+func (m *manager) UpdateGroup(ctx context.Context, g *grouppb.Group) error {
+	if g.Id == nil || g.Id.OpaqueId == "" {
+		return errors.New("sql: missing group id")
+	}
+	return m.db.UpdateGroup(ctx, convertToDBGroup(g))
+}
+
+func (m *manager) DeleteGroup(ctx context.Context, gid *grouppb.GroupId) error {
+	return m.db.DeleteGroup(ctx, gid.OpaqueId)
+}
+
+func (m *manager) AddMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) error {
+	return m.db.AddMember(ctx, gid.OpaqueId, uid.OpaqueId)
+}
+
+func (m *manager) RemoveMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) error {
+	return m.db.RemoveMember(ctx, gid.OpaqueId, uid.OpaqueId)
+}
+
+func convertToDBGroup(g *grouppb.Group) *accounts.Group {
+	dbGroup := &accounts.Group{
+		GroupName:   g.GroupName,
+		Mail:        g.Mail,
+		DisplayName: g.DisplayName,
+		GIDNumber:   g.GidNumber,
+	}
+	if g.Id != nil {
+		dbGroup.ID = g.Id.OpaqueId
+	}
+	return dbGroup
+}
+
+func (m *manager) convertToCS3Group(ctx context.Context, g *accounts.Group, skipFetchingMembers bool) (*grouppb.Group, error) {
+	cs3Group := &grouppb.Group{
+		Id: &grouppb.GroupId{
+			Idp:      m.c.Idp,
+			OpaqueId: g.ID,
+		},
+		GroupName:   g.GroupName,
+		Mail:        g.Mail,
+		DisplayName: g.DisplayName,
+		GidNumber:   g.GIDNumber,
+	}
+	if cs3Group.DisplayName == "" {
+		cs3Group.DisplayName = g.GroupName
+	}
+
+	if !skipFetchingMembers {
+		var err error
+		if cs3Group.Members, err = m.GetMembers(ctx, cs3Group.Id); err != nil {
+			return nil, err
+		}
+	}
+	return cs3Group, nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package idempotency deduplicates the retries of mutating requests carrying
+// the same idempotency key, so that a client retrying after a timeout gets the
+// response of the first attempt instead of applying the operation twice.
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"google.golang.org/protobuf/proto"
+)
+
+// Response is a CS3 response, only successful ones are remembered.
+type Response interface {
+	proto.Message
+	GetStatus() *rpc.Status
+}
+
+type entry struct {
+	fingerprint string
+	done        chan struct{}
+	res         Response
+	expires     time.Time
+}
+
+// Cache remembers the responses of the requests by idempotency key.
+type Cache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+// New returns a Cache remembering the responses for the given duration.
+func New(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:       ttl,
+		entries:   map[string]*entry{},
+		lastSweep: time.Now(),
+	}
+}
+
+// Fingerprint identifies the payload of a request, to detect a key reused
+// for a different request.
+func Fingerprint(msgs ...proto.Message) string {
+	h := sha256.New()
+	for _, m := range msgs {
+		if m == nil {
+			continue
+		}
+		// errors only make the fingerprint less specific
+		b, _ := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+		_, _ = h.Write(b)
+		_, _ = h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Do runs fn once for the given key and returns its response to all the
+// requests with the same key until it expires. Concurrent requests wait for
+// the first one. Failed responses are not remembered, so that the request
+// can be retried. Reusing a key for a request with a different fingerprint
+// is an error.
+func (c *Cache) Do(key, fingerprint string, fn func() (Response, error)) (Response, error) {
+	for {
+		c.mu.Lock()
+		c.sweep()
+		e, ok := c.entries[key]
+		if ok && e.res != nil && time.Now().After(e.expires) {
+			ok = false
+		}
+		if !ok {
+			e = &entry{fingerprint: fingerprint, done: make(chan struct{})}
+			c.entries[key] = e
+			c.mu.Unlock()
+			return c.run(key, e, fn)
+		}
+		c.mu.Unlock()
+
+		if e.fingerprint != fingerprint {
+			return nil, errtypes.BadRequest("idempotency key reused for a different request")
+		}
+		<-e.done
+		if e.res != nil {
+			return proto.Clone(e.res).(Response), nil
+		}
+		// the first attempt failed and was forgotten, try again
+	}
+}
+
+func (c *Cache) run(key string, e *entry, fn func() (Response, error)) (Response, error) {
+	res, err := fn()
+
+	c.mu.Lock()
+	if err == nil && res != nil && res.GetStatus().GetCode() == rpc.Code_CODE_OK {
+		e.res = proto.Clone(res).(Response)
+		e.expires = time.Now().Add(c.ttl)
+	} else {
+		delete(c.entries, key)
+	}
+	c.mu.Unlock()
+	close(e.done)
+
+	return res, err
+}
+
+// sweep drops the expired entries, at most once per ttl. It must be called
+// with the lock held.
+func (c *Cache) sweep() {
+	now := time.Now()
+	if now.Sub(c.lastSweep) < c.ttl {
+		return
+	}
+	for k, e := range c.entries {
+		if e.res != nil && now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.lastSweep = now
+}
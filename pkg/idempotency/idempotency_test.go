@@ -0,0 +1,129 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package idempotency
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+func TestDo(t *testing.T) {
+	c := New(time.Minute)
+	var calls int32
+	create := func() (Response, error) {
+		n := atomic.AddInt32(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return &collaboration.CreateShareResponse{
+			Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+			Share:  &collaboration.Share{Id: &collaboration.ShareId{OpaqueId: fmt.Sprint(n)}},
+		}, nil
+	}
+
+	var wg sync.WaitGroup
+	ids := make([]string, 5)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			res, err := c.Do("einstein:CreateShare:k1", "fp", create)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			ids[i] = res.(*collaboration.CreateShareResponse).Share.Id.OpaqueId
+		}(i)
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("expected a single call, got %d", calls)
+	}
+	for _, id := range ids {
+		if id != "1" {
+			t.Fatalf("expected all requests to get the first share, got %v", ids)
+		}
+	}
+
+	if _, err := c.Do("einstein:CreateShare:k1", "other", create); err == nil {
+		t.Fatal("expected an error reusing the key for a different request")
+	} else if _, ok := err.(errtypes.BadRequest); !ok {
+		t.Fatalf("unexpected error type %T", err)
+	}
+
+	if _, err := c.Do("einstein:CreateShare:k2", "fp", create); err != nil || calls != 2 {
+		t.Fatalf("expected a new key to run again, got %d calls, %v", calls, err)
+	}
+}
+
+func TestDoForgetsFailures(t *testing.T) {
+	c := New(time.Minute)
+	var calls int
+	fail := func() (Response, error) {
+		calls++
+		return &collaboration.CreateShareResponse{Status: &rpc.Status{Code: rpc.Code_CODE_INTERNAL}}, nil
+	}
+	broken := func() (Response, error) {
+		calls++
+		return nil, errors.New("connection refused")
+	}
+
+	if _, err := c.Do("k", "fp", fail); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do("k", "fp", broken); err == nil {
+		t.Fatal("expected the error of the retry")
+	}
+	if calls != 2 {
+		t.Fatalf("expected failed attempts to be retried, got %d calls", calls)
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	c := New(10 * time.Millisecond)
+	var calls int
+	ok := func() (Response, error) {
+		calls++
+		return &collaboration.CreateShareResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil
+	}
+	_, _ = c.Do("k", "fp", ok)
+	time.Sleep(20 * time.Millisecond)
+	_, _ = c.Do("k", "fp", ok)
+	if calls != 2 {
+		t.Fatalf("expected the expired key to run again, got %d calls", calls)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := &collaboration.ShareId{OpaqueId: "a"}
+	b := &collaboration.ShareId{OpaqueId: "b"}
+	if Fingerprint(a, b) != Fingerprint(a, b) {
+		t.Fatal("fingerprint must be stable")
+	}
+	if Fingerprint(a, b) == Fingerprint(b, a) {
+		t.Fatal("fingerprint must depend on the payload")
+	}
+}
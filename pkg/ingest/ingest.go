@@ -0,0 +1,110 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package ingest mints and verifies the tokens of the ingest URLs, which let
+// third parties such as lab instruments upload files to a folder of a user
+// without holding the credentials of the user.
+package ingest
+
+import (
+	"mime"
+	"strings"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/golang-jwt/jwt"
+	"github.com/pkg/errors"
+)
+
+// audience distinguishes the ingest tokens from the other tokens signed with the same secret.
+const audience = "ingest"
+
+// Claims describe what can be uploaded through an ingest URL.
+type Claims struct {
+	jwt.StandardClaims
+	// Owner is the user the URL was minted by and the uploads are made on behalf of.
+	Owner *userpb.UserId `json:"owner"`
+	// Folder is the folder the files are uploaded to.
+	Folder *provider.ResourceId `json:"folder"`
+	// Path is the path of the folder when the URL was minted, for display only.
+	Path string `json:"path"`
+	// Actor identifies the instrument or party the URL was minted for.
+	Actor string `json:"actor"`
+	// MaxSize is the maximum size in bytes of every uploaded file.
+	MaxSize uint64 `json:"max_size"`
+	// ContentTypes are the accepted media types, like image/png or image/*.
+	// Any type is accepted if empty.
+	ContentTypes []string `json:"content_types,omitempty"`
+}
+
+// Mint signs the claims with the given secret, setting their audience and issue time.
+func Mint(secret string, c *Claims) (string, error) {
+	c.Audience = audience
+	c.IssuedAt = time.Now().Unix()
+	t := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), c)
+	tkn, err := t.SignedString([]byte(secret))
+	if err != nil {
+		return "", errors.Wrap(err, "ingest: error signing token")
+	}
+	return tkn, nil
+}
+
+// Parse verifies the token with the given secret and returns its claims.
+// Expired tokens and the tokens not minted for ingest URLs are rejected.
+func Parse(secret, token string) (*Claims, error) {
+	j, err := jwt.ParseWithClaims(token, &Claims{}, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+	if err != nil {
+		return nil, errtypes.InvalidCredentials("ingest: invalid token: " + err.Error())
+	}
+	c, ok := j.Claims.(*Claims)
+	if !ok || !j.Valid || !c.VerifyAudience(audience, true) || c.Owner == nil || c.Folder == nil {
+		return nil, errtypes.InvalidCredentials("ingest: invalid token")
+	}
+	return c, nil
+}
+
+// AllowsContentType tells whether a file of the given content type can be uploaded.
+func (c *Claims) AllowsContentType(contentType string) bool {
+	if len(c.ContentTypes) == 0 {
+		return true
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range c.ContentTypes {
+		t = strings.ToLower(t)
+		if t == mt || (strings.HasSuffix(t, "/*") && strings.HasPrefix(mt, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidName tells whether the name of an uploaded file is acceptable, i.e.
+// whether it refers to a direct child of the folder.
+func ValidName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\\x00")
+}
@@ -0,0 +1,123 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ingest
+
+import (
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/golang-jwt/jwt"
+)
+
+func newClaims(expires time.Time) *Claims {
+	return &Claims{
+		StandardClaims: jwt.StandardClaims{ExpiresAt: expires.Unix()},
+		Owner:          &userpb.UserId{Idp: "idp", OpaqueId: "einstein"},
+		Folder:         &provider.ResourceId{StorageId: "storage", OpaqueId: "folder"},
+		Path:           "/home/data",
+		Actor:          "microscope-1",
+		MaxSize:        1024,
+		ContentTypes:   []string{"image/*", "text/csv"},
+	}
+}
+
+func TestMintAndParse(t *testing.T) {
+	tkn, err := Mint("secret", newClaims(time.Now().Add(time.Hour)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c, err := Parse("secret", tkn)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Owner.OpaqueId != "einstein" || c.Folder.OpaqueId != "folder" || c.Actor != "microscope-1" || c.MaxSize != 1024 {
+		t.Errorf("unexpected claims: %+v", c)
+	}
+
+	if _, err := Parse("other", tkn); err == nil {
+		t.Error("expected the token signed with another secret to be rejected")
+	}
+	if _, err := Parse("secret", tkn+"x"); err == nil {
+		t.Error("expected the tampered token to be rejected")
+	}
+}
+
+func TestParseExpired(t *testing.T) {
+	tkn, err := Mint("secret", newClaims(time.Now().Add(-time.Minute)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Parse("secret", tkn); err == nil {
+		t.Error("expected the expired token to be rejected")
+	}
+}
+
+func TestParseOtherAudience(t *testing.T) {
+	c := newClaims(time.Now().Add(time.Hour))
+	c.Audience = "reva"
+	tkn, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Parse("secret", tkn); err == nil {
+		t.Error("expected the token minted for another audience to be rejected")
+	}
+}
+
+func TestAllowsContentType(t *testing.T) {
+	c := newClaims(time.Now())
+	tests := map[string]bool{
+		"image/png":                true,
+		"IMAGE/TIFF":               true,
+		"text/csv; charset=utf-8":  true,
+		"text/plain":               false,
+		"application/octet-stream": false,
+		"":                         false,
+		"imagex/png":               false,
+	}
+	for ct, want := range tests {
+		if got := c.AllowsContentType(ct); got != want {
+			t.Errorf("AllowsContentType(%q) = %v, want %v", ct, got, want)
+		}
+	}
+
+	c.ContentTypes = nil
+	if !c.AllowsContentType("application/octet-stream") {
+		t.Error("expected any content type to be allowed without restrictions")
+	}
+}
+
+func TestValidName(t *testing.T) {
+	tests := map[string]bool{
+		"sample.tiff":  true,
+		"run 42.csv":   true,
+		"":             false,
+		".":            false,
+		"..":           false,
+		"a/b.csv":      false,
+		"..\\evil.csv": false,
+	}
+	for name, want := range tests {
+		if got := ValidName(name); got != want {
+			t.Errorf("ValidName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
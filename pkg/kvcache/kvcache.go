@@ -0,0 +1,286 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package kvcache implements the key-value cache shared by the drivers of a reva process, like the space
+// metadata of decomposedfs, the stat results of EOS or the user lookups of the share managers.
+// The entries are kept in a store, either in memory or in Redis to share them between several instances,
+// and are grouped in namespaces which have their own TTL and can be flushed separately.
+package kvcache
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// keyPrefix is prepended to all keys, so that a Redis store can be shared with other applications.
+const keyPrefix = "reva:"
+
+const (
+	lookupResultHit  = "hit"
+	lookupResultMiss = "miss"
+)
+
+var (
+	cacheLookups  = stats.Int64("reva_kvcache_lookups", "The number of lookups in the key-value cache", stats.UnitDimensionless)
+	namespaceKey  = tag.MustNewKey("namespace")
+	resultKey     = tag.MustNewKey("result")
+	registerViews sync.Once
+
+	mutex      sync.RWMutex
+	store      Store
+	ttls       map[string]int
+	namespaces = map[string]*Cache{}
+)
+
+// Store is the interface to implement for the stores the entries of the cache are kept in.
+type Store interface {
+	// Get returns the value of the key or an errtypes.NotFound error if there is none.
+	Get(key string) ([]byte, error)
+	// Set stores the value of the key, which expires after the TTL.
+	Set(key string, value []byte, ttl time.Duration) error
+	// Delete removes the keys.
+	Delete(keys ...string) error
+	// Flush removes all keys starting with the prefix and returns their number.
+	Flush(prefix string) (int, error)
+}
+
+// Config holds the configuration of the cache.
+type Config struct {
+	Driver  string                            `mapstructure:"driver" docs:"memory;The store the entries are kept in, either memory or redis."`
+	Drivers map[string]map[string]interface{} `mapstructure:"drivers" docs:"url:pkg/kvcache/redis/redis.go"`
+	TTLs    map[string]int                    `mapstructure:"ttls" docs:"{};The TTL in seconds of the namespaces, overriding the defaults of the drivers using them; 0 disables a namespace."`
+}
+
+func (c *Config) init() {
+	if c.Driver == "" {
+		c.Driver = "memory"
+	}
+}
+
+// ParseConfig decodes the configuration of the cache.
+func ParseConfig(m map[string]interface{}) (*Config, error) {
+	c := &Config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "kvcache: error decoding configuration")
+	}
+	c.init()
+	return c, nil
+}
+
+// Configure makes all namespaces keep their entries in the store, using the TTLs of the configuration.
+// Until the cache is configured, nothing is cached.
+func Configure(s Store, c *Config) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	store = s
+	ttls = c.TTLs
+}
+
+// Cache is a namespace of the shared cache.
+type Cache struct {
+	name       string
+	defaultTTL time.Duration
+
+	hits   int64
+	misses int64
+}
+
+// Namespace returns the namespace with the given name, whose entries expire after the default TTL unless
+// another one is configured. Namespaces are created on first use and shared by all their users.
+func Namespace(name string, defaultTTL time.Duration) *Cache {
+	registerViews.Do(func() {
+		_ = view.Register(&view.View{
+			Name:        cacheLookups.Name(),
+			Description: cacheLookups.Description(),
+			Measure:     cacheLookups,
+			TagKeys:     []tag.Key{namespaceKey, resultKey},
+			Aggregation: view.Count(),
+		})
+	})
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if c, ok := namespaces[name]; ok {
+		return c
+	}
+	c := &Cache{name: name, defaultTTL: defaultTTL}
+	namespaces[name] = c
+	return c
+}
+
+// Name returns the name of the namespace.
+func (c *Cache) Name() string {
+	return c.name
+}
+
+// TTL returns the time after which the entries of the namespace expire.
+func (c *Cache) TTL() time.Duration {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return c.ttl()
+}
+
+func (c *Cache) ttl() time.Duration {
+	if ttl, ok := ttls[c.name]; ok {
+		return time.Duration(ttl) * time.Second
+	}
+	return c.defaultTTL
+}
+
+// store returns the store of the namespace, or nil if nothing should be cached.
+func (c *Cache) store() Store {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	if c.ttl() <= 0 {
+		return nil
+	}
+	return store
+}
+
+func (c *Cache) key(key string) string {
+	return c.prefix() + key
+}
+
+func (c *Cache) prefix() string {
+	return keyPrefix + c.name + ":"
+}
+
+// Get decodes the cached value of the key into v and reports whether it was found.
+func (c *Cache) Get(key string, v interface{}) bool {
+	s := c.store()
+	if s == nil {
+		return false
+	}
+	found := false
+	if value, err := s.Get(c.key(key)); err == nil {
+		found = json.Unmarshal(value, v) == nil
+	}
+	c.recordLookup(found)
+	return found
+}
+
+// Set caches the value of the key.
+func (c *Cache) Set(key string, v interface{}) error {
+	s := c.store()
+	if s == nil {
+		return nil
+	}
+	value, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "kvcache: error encoding value")
+	}
+	return s.Set(c.key(key), value, c.TTL())
+}
+
+// Delete removes the cached values of the keys.
+func (c *Cache) Delete(keys ...string) error {
+	s := c.store()
+	if s == nil || len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.key(key)
+	}
+	return s.Delete(prefixed...)
+}
+
+// Flush removes all entries of the namespace and returns their number.
+func (c *Cache) Flush() (int, error) {
+	mutex.RLock()
+	s := store
+	mutex.RUnlock()
+	if s == nil {
+		return 0, nil
+	}
+	return s.Flush(c.prefix())
+}
+
+func (c *Cache) recordLookup(hit bool) {
+	result := lookupResultMiss
+	if hit {
+		atomic.AddInt64(&c.hits, 1)
+		result = lookupResultHit
+	} else {
+		atomic.AddInt64(&c.misses, 1)
+	}
+	if ctx, err := tag.New(context.Background(), tag.Insert(namespaceKey, c.name), tag.Insert(resultKey, result)); err == nil {
+		stats.Record(ctx, cacheLookups.M(1))
+	}
+}
+
+// Stats holds the statistics of a namespace since the start of the process.
+type Stats struct {
+	Namespace string `json:"namespace"`
+	TTL       int    `json:"ttl"`
+	Hits      int64  `json:"hits"`
+	Misses    int64  `json:"misses"`
+}
+
+// Namespaces returns the statistics of all namespaces in use, sorted by name.
+func Namespaces() []Stats {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	list := make([]Stats, 0, len(namespaces))
+	for _, c := range namespaces {
+		list = append(list, Stats{
+			Namespace: c.name,
+			TTL:       int(c.ttl().Seconds()),
+			Hits:      atomic.LoadInt64(&c.hits),
+			Misses:    atomic.LoadInt64(&c.misses),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Namespace < list[j].Namespace })
+	return list
+}
+
+// Flush removes all entries of the namespace with the given name, or of all namespaces in use if the
+// name is empty, and returns their number. Namespaces not in use by this process can be flushed as well,
+// as their entries may be kept in a store shared with other instances.
+func Flush(name string) (int, error) {
+	var list []*Cache
+	if name != "" {
+		list = append(list, &Cache{name: name})
+	} else {
+		mutex.RLock()
+		for _, c := range namespaces {
+			list = append(list, c)
+		}
+		mutex.RUnlock()
+	}
+
+	total := 0
+	for _, c := range list {
+		n, err := c.Flush()
+		if err != nil {
+			return total, errors.Wrapf(err, "kvcache: error flushing namespace %s", c.name)
+		}
+		total += n
+	}
+	return total, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package kvcache
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+type mapStore map[string][]byte
+
+func (s mapStore) Get(key string) ([]byte, error) {
+	if v, ok := s[key]; ok {
+		return v, nil
+	}
+	return nil, errtypes.NotFound(key)
+}
+
+func (s mapStore) Set(key string, value []byte, ttl time.Duration) error {
+	s[key] = value
+	return nil
+}
+
+func (s mapStore) Delete(keys ...string) error {
+	for _, key := range keys {
+		delete(s, key)
+	}
+	return nil
+}
+
+func (s mapStore) Flush(prefix string) (int, error) {
+	n := 0
+	for key := range s {
+		if strings.HasPrefix(key, prefix) {
+			delete(s, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestNamespace(t *testing.T) {
+	store := mapStore{}
+	Configure(store, &Config{TTLs: map[string]int{"disabled": 0, "overridden": 30}})
+	defer Configure(nil, &Config{})
+
+	users := Namespace("users", time.Minute)
+	if Namespace("users", time.Hour) != users {
+		t.Fatal("namespaces with the same name must be shared")
+	}
+	if ttl := users.TTL(); ttl != time.Minute {
+		t.Errorf("TTL() = %v, expected the default of 1m", ttl)
+	}
+	if ttl := Namespace("overridden", time.Minute).TTL(); ttl != 30*time.Second {
+		t.Errorf("TTL() = %v, expected the configured 30s", ttl)
+	}
+
+	var name string
+	if users.Get("id", &name) {
+		t.Fatal("Get() found a value which was never set")
+	}
+	if err := users.Set("id", "einstein"); err != nil {
+		t.Fatal(err)
+	}
+	if !users.Get("id", &name) || name != "einstein" {
+		t.Fatalf("Get() = %q, expected einstein", name)
+	}
+	if err := users.Delete("id"); err != nil {
+		t.Fatal(err)
+	}
+	if users.Get("id", &name) {
+		t.Fatal("Get() found a deleted value")
+	}
+
+	disabled := Namespace("disabled", time.Minute)
+	_ = disabled.Set("id", "einstein")
+	if disabled.Get("id", &name) || len(store) != 0 {
+		t.Fatal("a namespace with a TTL of 0 must not cache anything")
+	}
+
+	_ = users.Set("a", 1)
+	_ = users.Set("b", 2)
+	_ = Namespace("overridden", time.Minute).Set("a", 3)
+	if n, err := Flush("users"); err != nil || n != 2 {
+		t.Fatalf("Flush(users) = %d, %v, expected 2 entries", n, err)
+	}
+	if n, err := Flush(""); err != nil || n != 1 {
+		t.Fatalf("Flush() = %d, %v, expected 1 entry", n, err)
+	}
+
+	for _, s := range Namespaces() {
+		if s.Namespace == "users" && (s.Hits != 1 || s.Misses != 2) {
+			t.Errorf("users namespace has %d hits and %d misses, expected 1 and 2", s.Hits, s.Misses)
+		}
+	}
+}
+
+func TestUnconfigured(t *testing.T) {
+	c := Namespace("unconfigured", time.Minute)
+	if err := c.Set("id", "einstein"); err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if c.Get("id", &name) {
+		t.Fatal("nothing must be cached until the cache is configured")
+	}
+}
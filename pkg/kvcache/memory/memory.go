@@ -0,0 +1,87 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package memory
+
+import (
+	"strings"
+	"time"
+
+	"github.com/bluele/gcache"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/kvcache"
+	"github.com/cs3org/reva/pkg/kvcache/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("memory", New)
+}
+
+type config struct {
+	CacheSize int `mapstructure:"cache_size" docs:"1000000;The maximum number of entries, the least recently used ones are evicted first."`
+}
+
+type store struct {
+	cache gcache.Cache
+}
+
+// New returns a store keeping the entries of the cache in the memory of the process.
+func New(m map[string]interface{}) (kvcache.Store, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	if c.CacheSize == 0 {
+		c.CacheSize = 1000000
+	}
+
+	return &store{
+		cache: gcache.New(c.CacheSize).LRU().Build(),
+	}, nil
+}
+
+func (s *store) Get(key string) ([]byte, error) {
+	v, err := s.cache.Get(key)
+	if err != nil {
+		return nil, errtypes.NotFound(key)
+	}
+	return v.([]byte), nil
+}
+
+func (s *store) Set(key string, value []byte, ttl time.Duration) error {
+	return s.cache.SetWithExpire(key, value, ttl)
+}
+
+func (s *store) Delete(keys ...string) error {
+	for _, key := range keys {
+		s.cache.Remove(key)
+	}
+	return nil
+}
+
+func (s *store) Flush(prefix string) (int, error) {
+	n := 0
+	for _, key := range s.cache.Keys(false) {
+		if k, ok := key.(string); ok && strings.HasPrefix(k, prefix) && s.cache.Remove(k) {
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package redis
+
+import (
+	"time"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/kvcache"
+	"github.com/cs3org/reva/pkg/kvcache/registry"
+	"github.com/gomodule/redigo/redis"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("redis", New)
+}
+
+// scanCount is the number of keys Redis is asked to look at per SCAN call when flushing.
+const scanCount = 1000
+
+type config struct {
+	RedisAddress  string `mapstructure:"redis_address" docs:"localhost:6379;The address of the Redis server."`
+	RedisUsername string `mapstructure:"redis_username" docs:";The username to authenticate with."`
+	RedisPassword string `mapstructure:"redis_password" docs:";The password to authenticate with."`
+}
+
+type store struct {
+	redisPool *redis.Pool
+}
+
+// New returns a store keeping the entries of the cache in Redis, where they are shared by all reva
+// instances using the same server.
+func New(m map[string]interface{}) (kvcache.Store, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+
+	if c.RedisAddress == "" {
+		c.RedisAddress = "localhost:6379"
+	}
+
+	pool := &redis.Pool{
+		MaxIdle:     50,
+		MaxActive:   1000,
+		IdleTimeout: 240 * time.Second,
+
+		Dial: func() (redis.Conn, error) {
+			var opts []redis.DialOption
+			if c.RedisUsername != "" {
+				opts = append(opts, redis.DialUsername(c.RedisUsername))
+			}
+			if c.RedisPassword != "" {
+				opts = append(opts, redis.DialPassword(c.RedisPassword))
+			}
+			return redis.Dial("tcp", c.RedisAddress, opts...)
+		},
+
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+
+	return &store{
+		redisPool: pool,
+	}, nil
+}
+
+func (s *store) Get(key string) ([]byte, error) {
+	conn := s.redisPool.Get()
+	defer conn.Close()
+
+	value, err := redis.Bytes(conn.Do("GET", key))
+	if err == redis.ErrNil {
+		return nil, errtypes.NotFound(key)
+	}
+	return value, err
+}
+
+func (s *store) Set(key string, value []byte, ttl time.Duration) error {
+	conn := s.redisPool.Get()
+	defer conn.Close()
+
+	// Redis rejects expiry times of 0, so sub-millisecond TTLs are rounded up
+	ms := ttl.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	_, err := conn.Do("SET", key, value, "PX", ms)
+	return err
+}
+
+func (s *store) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	conn := s.redisPool.Get()
+	defer conn.Close()
+
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+	_, err := conn.Do("DEL", args...)
+	return err
+}
+
+func (s *store) Flush(prefix string) (int, error) {
+	conn := s.redisPool.Get()
+	defer conn.Close()
+
+	n := 0
+	cursor := "0"
+	for {
+		reply, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", prefix+"*", "COUNT", scanCount))
+		if err != nil {
+			return n, err
+		}
+		if cursor, err = redis.String(reply[0], nil); err != nil {
+			return n, err
+		}
+		keys, err := redis.Values(reply[1], nil)
+		if err != nil {
+			return n, err
+		}
+		if len(keys) > 0 {
+			deleted, err := redis.Int(conn.Do("DEL", keys...))
+			if err != nil {
+				return n, err
+			}
+			n += deleted
+		}
+		if cursor == "0" {
+			return n, nil
+		}
+	}
+}
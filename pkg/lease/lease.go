@@ -0,0 +1,274 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package lease lets the replicas of a reva deployment agree on which one runs a background job, like the
+// janitors, the expiry sweepers and the migration workers. A lease is held by a single process at a time
+// until it is released or expires, and it is renewed while the job runs. Every acquisition of a lease gets
+// a fencing token greater than all tokens handed out before, so that the writes of a holder whose lease
+// was taken over can be told apart from the ones of the current holder.
+// The leases are kept by a locker, in memory for single replica deployments, or in Redis or a SQL database.
+package lease
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+// Locker is the interface to implement for the backends keeping the leases.
+type Locker interface {
+	// Acquire takes the lease for the owner if it is free or expired and returns its fencing token;
+	// ok is false if the lease is held by another owner.
+	Acquire(ctx context.Context, name, owner string, ttl time.Duration) (token int64, ok bool, err error)
+	// Renew extends the lease if it is still held by the owner with the given token; ok is false if it was lost.
+	Renew(ctx context.Context, name, owner string, token int64, ttl time.Duration) (ok bool, err error)
+	// Release gives up the lease if it is still held by the owner with the given token.
+	Release(ctx context.Context, name, owner string, token int64) error
+}
+
+// Config holds the configuration of the leases.
+type Config struct {
+	Driver  string                            `mapstructure:"driver" docs:"memory;The locker keeping the leases, either memory, redis or sql. Deployments with several replicas need redis or sql."`
+	Drivers map[string]map[string]interface{} `mapstructure:"drivers" docs:"url:pkg/lease/redis/redis.go"`
+	TTL     int                               `mapstructure:"ttl" docs:"30;The time in seconds after which a lease expires if its holder stops renewing it."`
+}
+
+func (c *Config) init() {
+	if c.Driver == "" {
+		c.Driver = "memory"
+	}
+	if c.TTL <= 0 {
+		c.TTL = 30
+	}
+}
+
+// ParseConfig decodes the configuration of the leases.
+func ParseConfig(m map[string]interface{}) (*Config, error) {
+	c := &Config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "lease: error decoding configuration")
+	}
+	c.init()
+	return c, nil
+}
+
+var (
+	mutex  sync.RWMutex
+	locker Locker = NewMemoryLocker()
+	ttl           = 30 * time.Second
+
+	// owner identifies this process as the holder of its leases.
+	owner = newOwner()
+)
+
+func newOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), uuid.NewString())
+}
+
+// Configure makes all leases be kept by the given locker. Until it is configured, the leases are kept in
+// memory, which only coordinates the jobs of a single process.
+func Configure(l Locker, c *Config) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	locker = l
+	ttl = time.Duration(c.TTL) * time.Second
+}
+
+func current() (Locker, time.Duration) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return locker, ttl
+}
+
+// Lease is a lease held by this process.
+type Lease struct {
+	name   string
+	token  int64
+	locker Locker
+	ttl    time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+// TryAcquire takes the lease with the given name and keeps renewing it until it is released; nil is
+// returned if the lease is held by another process.
+func TryAcquire(ctx context.Context, name string) (*Lease, error) {
+	l, ttl := current()
+	token, ok, err := l.Acquire(ctx, name, owner, ttl)
+	if err != nil {
+		return nil, errors.Wrapf(err, "lease: error acquiring lease %s", name)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	// The context of the lease is not bound to the one of the caller, which may well be a single request
+	leaseCtx, cancel := context.WithCancel(context.Background())
+	lease := &Lease{
+		name:   name,
+		token:  token,
+		locker: l,
+		ttl:    ttl,
+		ctx:    leaseCtx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go lease.renew()
+	return lease, nil
+}
+
+// Name returns the name of the lease.
+func (lease *Lease) Name() string {
+	return lease.name
+}
+
+// Token returns the fencing token of the lease.
+func (lease *Lease) Token() int64 {
+	return lease.token
+}
+
+// Context returns a context which is canceled once the lease is lost or released; the work done
+// under the lease should stop then.
+func (lease *Lease) Context() context.Context {
+	return lease.ctx
+}
+
+// Release stops renewing the lease and gives it up.
+func (lease *Lease) Release() {
+	lease.once.Do(func() {
+		lease.cancel()
+		<-lease.done
+		ctx, cancel := context.WithTimeout(context.Background(), lease.ttl)
+		defer cancel()
+		_ = lease.locker.Release(ctx, lease.name, owner, lease.token)
+	})
+}
+
+// renew extends the lease three times per TTL; the lease is considered lost if it was taken over or if
+// it could not be renewed before it expired.
+func (lease *Lease) renew() {
+	defer close(lease.done)
+
+	ticker := time.NewTicker(lease.ttl / 3)
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-lease.ctx.Done():
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(lease.ctx, lease.ttl/3)
+			ok, err := lease.locker.Renew(ctx, lease.name, owner, lease.token, lease.ttl)
+			cancel()
+			switch {
+			case err == nil && ok:
+				renewed = time.Now()
+			case err == nil || time.Since(renewed) >= lease.ttl:
+				lease.cancel()
+				return
+			}
+		}
+	}
+}
+
+// Run runs the job under the lease with the given name, unless another process holds it; it reports
+// whether the job was run. The job gets the context of the lease and its fencing token.
+func Run(ctx context.Context, name string, job func(ctx context.Context, token int64) error) (bool, error) {
+	lease, err := TryAcquire(ctx, name)
+	if err != nil || lease == nil {
+		return false, err
+	}
+	defer lease.Release()
+
+	// The job stops if either the caller gives up or the lease is lost
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-lease.Context().Done():
+			cancel()
+		case <-jobCtx.Done():
+		}
+	}()
+	return true, job(jobCtx, lease.Token())
+}
+
+// memoryLocker keeps the leases in memory.
+type memoryLocker struct {
+	mutex  sync.Mutex
+	leases map[string]*memoryLease
+	tokens map[string]int64
+}
+
+type memoryLease struct {
+	owner   string
+	token   int64
+	expires time.Time
+}
+
+// NewMemoryLocker returns a locker keeping the leases in memory, which only coordinates the jobs of a single process.
+func NewMemoryLocker() Locker {
+	return &memoryLocker{
+		leases: map[string]*memoryLease{},
+		tokens: map[string]int64{},
+	}
+}
+
+func (m *memoryLocker) Acquire(_ context.Context, name, owner string, ttl time.Duration) (int64, bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if l, ok := m.leases[name]; ok && time.Now().Before(l.expires) {
+		return 0, false, nil
+	}
+	m.tokens[name]++
+	m.leases[name] = &memoryLease{owner: owner, token: m.tokens[name], expires: time.Now().Add(ttl)}
+	return m.tokens[name], true, nil
+}
+
+func (m *memoryLocker) Renew(_ context.Context, name, owner string, token int64, ttl time.Duration) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	l, ok := m.leases[name]
+	if !ok || l.owner != owner || l.token != token || time.Now().After(l.expires) {
+		return false, nil
+	}
+	l.expires = time.Now().Add(ttl)
+	return true, nil
+}
+
+func (m *memoryLocker) Release(_ context.Context, name, owner string, token int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if l, ok := m.leases[name]; ok && l.owner == owner && l.token == token {
+		delete(m.leases, name)
+	}
+	return nil
+}
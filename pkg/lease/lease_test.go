@@ -0,0 +1,123 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package lease
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryLocker(t *testing.T) {
+	ctx := context.Background()
+	l := NewMemoryLocker()
+
+	token, ok, err := l.Acquire(ctx, "janitor", "a", time.Minute)
+	if err != nil || !ok || token != 1 {
+		t.Fatalf("expected to acquire the lease with token 1, got %d %v %v", token, ok, err)
+	}
+	if _, ok, _ := l.Acquire(ctx, "janitor", "b", time.Minute); ok {
+		t.Fatal("a held lease must not be acquired by another owner")
+	}
+	if ok, _ := l.Renew(ctx, "janitor", "b", token, time.Minute); ok {
+		t.Fatal("a lease must only be renewed by its holder")
+	}
+	if ok, _ := l.Renew(ctx, "janitor", "a", token, time.Minute); !ok {
+		t.Fatal("the holder must be able to renew its lease")
+	}
+
+	_ = l.Release(ctx, "janitor", "b", token)
+	if _, ok, _ := l.Acquire(ctx, "janitor", "b", time.Minute); ok {
+		t.Fatal("a lease must only be released by its holder")
+	}
+	_ = l.Release(ctx, "janitor", "a", token)
+	token, ok, _ = l.Acquire(ctx, "janitor", "b", time.Millisecond)
+	if !ok || token != 2 {
+		t.Fatalf("expected to acquire the released lease with token 2, got %d %v", token, ok)
+	}
+
+	// Expired leases are taken over and the previous holder loses them
+	time.Sleep(5 * time.Millisecond)
+	token, ok, _ = l.Acquire(ctx, "janitor", "a", time.Minute)
+	if !ok || token != 3 {
+		t.Fatalf("expected to take over the expired lease with token 3, got %d %v", token, ok)
+	}
+	if ok, _ := l.Renew(ctx, "janitor", "b", 2, time.Minute); ok {
+		t.Fatal("a lease taken over must not be renewed")
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	Configure(NewMemoryLocker(), &Config{TTL: 1})
+
+	ran, err := Run(ctx, "sweeper", func(ctx context.Context, token int64) error {
+		// The lease is held while the job runs
+		if lease, err := TryAcquire(ctx, "sweeper"); err != nil || lease != nil {
+			t.Errorf("a running job's lease must not be acquired again")
+		}
+		if token != 1 {
+			t.Errorf("expected token 1, got %d", token)
+		}
+		return errors.New("failed")
+	})
+	if !ran || err == nil || err.Error() != "failed" {
+		t.Fatalf("expected the job to run and fail, got %v %v", ran, err)
+	}
+
+	// The lease is released once the job is done
+	lease, err := TryAcquire(ctx, "sweeper")
+	if err != nil || lease == nil || lease.Token() != 2 {
+		t.Fatalf("expected to acquire the released lease, got %v %v", lease, err)
+	}
+	if ran, _ := Run(ctx, "sweeper", func(context.Context, int64) error { return nil }); ran {
+		t.Fatal("a job must not run while another process holds its lease")
+	}
+	lease.Release()
+	lease.Release()
+	if lease.Context().Err() == nil {
+		t.Fatal("the context of a released lease must be canceled")
+	}
+}
+
+type lostLocker struct {
+	Locker
+}
+
+func (l lostLocker) Renew(context.Context, string, string, int64, time.Duration) (bool, error) {
+	return false, nil
+}
+
+func TestLostLease(t *testing.T) {
+	Configure(lostLocker{NewMemoryLocker()}, &Config{TTL: 1})
+	defer Configure(NewMemoryLocker(), &Config{TTL: 30})
+
+	lease, err := TryAcquire(context.Background(), "migration")
+	if err != nil || lease == nil {
+		t.Fatalf("expected to acquire the lease, got %v", err)
+	}
+	defer lease.Release()
+
+	select {
+	case <-lease.Context().Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("the context of a lost lease must be canceled")
+	}
+}
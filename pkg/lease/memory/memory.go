@@ -0,0 +1,34 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package memory
+
+import (
+	"github.com/cs3org/reva/pkg/lease"
+	"github.com/cs3org/reva/pkg/lease/registry"
+)
+
+func init() {
+	registry.Register("memory", New)
+}
+
+// New returns a locker keeping the leases in the memory of the process; it only coordinates the jobs of
+// a single process, so deployments with several replicas need a shared locker.
+func New(m map[string]interface{}) (lease.Locker, error) {
+	return lease.NewMemoryLocker(), nil
+}
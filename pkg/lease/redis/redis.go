@@ -0,0 +1,164 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/cs3org/reva/pkg/lease"
+	"github.com/cs3org/reva/pkg/lease/registry"
+	"github.com/gomodule/redigo/redis"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("redis", New)
+}
+
+// keyPrefix is prepended to all keys, so that a Redis server can be shared with other applications.
+const keyPrefix = "reva:lease:"
+
+var (
+	// acquireScript sets the lease to the owner and its new fencing token unless it is held already.
+	acquireScript = redis.NewScript(2, `
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+local token = redis.call("INCR", KEYS[2])
+redis.call("SET", KEYS[1], ARGV[1] .. "/" .. token, "PX", ARGV[2])
+return token
+`)
+	// renewScript extends the lease if it is still held by the owner with the given token.
+	renewScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+	// releaseScript removes the lease if it is still held by the owner with the given token.
+	releaseScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+)
+
+type config struct {
+	RedisAddress  string `mapstructure:"redis_address" docs:"localhost:6379;The address of the Redis server."`
+	RedisUsername string `mapstructure:"redis_username" docs:";The username to authenticate with."`
+	RedisPassword string `mapstructure:"redis_password" docs:";The password to authenticate with."`
+}
+
+type locker struct {
+	redisPool *redis.Pool
+}
+
+// New returns a locker keeping the leases in Redis, where they are shared by all reva instances using
+// the same server. The fencing tokens are kept without expiry, so they keep growing across acquisitions.
+func New(m map[string]interface{}) (lease.Locker, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+
+	if c.RedisAddress == "" {
+		c.RedisAddress = "localhost:6379"
+	}
+
+	pool := &redis.Pool{
+		MaxIdle:     10,
+		MaxActive:   100,
+		IdleTimeout: 240 * time.Second,
+
+		Dial: func() (redis.Conn, error) {
+			var opts []redis.DialOption
+			if c.RedisUsername != "" {
+				opts = append(opts, redis.DialUsername(c.RedisUsername))
+			}
+			if c.RedisPassword != "" {
+				opts = append(opts, redis.DialPassword(c.RedisPassword))
+			}
+			return redis.Dial("tcp", c.RedisAddress, opts...)
+		},
+
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+
+	return &locker{
+		redisPool: pool,
+	}, nil
+}
+
+func value(owner string, token int64) string {
+	return owner + "/" + strconv.FormatInt(token, 10)
+}
+
+func milliseconds(ttl time.Duration) int64 {
+	// Redis rejects expiry times of 0, so sub-millisecond TTLs are rounded up
+	if ms := ttl.Milliseconds(); ms >= 1 {
+		return ms
+	}
+	return 1
+}
+
+func (l *locker) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (int64, bool, error) {
+	conn, err := l.redisPool.GetContext(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer conn.Close()
+
+	token, err := redis.Int64(acquireScript.Do(conn, keyPrefix+name, keyPrefix+name+":token", owner, milliseconds(ttl)))
+	if err != nil {
+		return 0, false, err
+	}
+	return token, token > 0, nil
+}
+
+func (l *locker) Renew(ctx context.Context, name, owner string, token int64, ttl time.Duration) (bool, error) {
+	conn, err := l.redisPool.GetContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	renewed, err := redis.Int(renewScript.Do(conn, keyPrefix+name, value(owner, token), milliseconds(ttl)))
+	if err != nil {
+		return false, err
+	}
+	return renewed == 1, nil
+}
+
+func (l *locker) Release(ctx context.Context, name, owner string, token int64) error {
+	conn, err := l.redisPool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = releaseScript.Do(conn, keyPrefix+name, value(owner, token))
+	return err
+}
@@ -0,0 +1,40 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import "github.com/cs3org/reva/pkg/migrate"
+
+// Schema is the migration component of the table holding the fencing tokens of the leases.
+const Schema = "lease_tokens"
+
+func init() {
+	migrate.Register(Schema, migrate.Migration{
+		Version:     1,
+		Description: "create the lease tokens table",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS lease_tokens (
+				name VARCHAR(255) NOT NULL PRIMARY KEY,
+				token BIGINT NOT NULL
+			)`,
+		},
+		Down: []string{
+			"DROP TABLE IF EXISTS lease_tokens",
+		},
+	})
+}
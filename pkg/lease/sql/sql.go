@@ -0,0 +1,220 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/lease"
+	"github.com/cs3org/reva/pkg/lease/registry"
+	"github.com/cs3org/reva/pkg/migrate"
+	"github.com/cs3org/reva/pkg/sqldb"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("sql", New)
+}
+
+type config struct {
+	// DbEngine is either mysql, the default, or postgres; SQLite has no advisory locks.
+	DbEngine   string `mapstructure:"db_engine"`
+	DbUsername string `mapstructure:"db_username"`
+	DbPassword string `mapstructure:"db_password"`
+	DbHost     string `mapstructure:"db_host"`
+	DbPort     int    `mapstructure:"db_port"`
+	DbName     string `mapstructure:"db_name"`
+	// SkipMigrations disables applying the pending schema migrations on startup.
+	SkipMigrations bool `mapstructure:"skip_migrations"`
+}
+
+// held is a lease held through the advisory lock of a dedicated connection.
+type held struct {
+	conn  *sql.Conn
+	owner string
+	token int64
+}
+
+type locker struct {
+	db     *sql.DB
+	engine string
+
+	mutex sync.Mutex
+	held  map[string]*held
+}
+
+// New returns a locker backed by the advisory locks of a MySQL or PostgreSQL database. A lease is held
+// by keeping the connection which took its lock open, so the database releases it as soon as the holder
+// disappears; the TTL only applies to the renewals, which check that the connection is still alive.
+func New(m map[string]interface{}) (lease.Locker, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "lease: error decoding configuration")
+	}
+
+	db, err := sqldb.Open(sqldb.Config{
+		Engine:   c.DbEngine,
+		Username: c.DbUsername,
+		Password: c.DbPassword,
+		Host:     c.DbHost,
+		Port:     c.DbPort,
+		Name:     c.DbName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	engine := sqldb.Engine(db)
+	if engine == sqldb.SQLite {
+		return nil, errors.New("lease: sqlite has no advisory locks")
+	}
+
+	if !c.SkipMigrations {
+		if err := migrate.Up(context.Background(), db, Schema); err != nil {
+			return nil, err
+		}
+	}
+	return &locker{db: db, engine: engine, held: map[string]*held{}}, nil
+}
+
+// lockKey returns the key of the advisory lock of a lease: MySQL limits lock names to 64 characters and
+// PostgreSQL identifies its locks by a number, so both are derived from a hash of the lease name.
+func (l *locker) lockKey(name string) interface{} {
+	sum := sha1.Sum([]byte(name))
+	if l.engine == sqldb.Postgres {
+		return int64(binary.BigEndian.Uint64(sum[:8]))
+	}
+	return "reva:" + hex.EncodeToString(sum[:])
+}
+
+func (l *locker) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (int64, bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// Leases held by this process are kept by their connections, which would take the lock again
+	if _, ok := l.held[name]; ok {
+		return 0, false, nil
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return 0, false, errors.Wrap(err, "lease: error getting a connection")
+	}
+
+	query := "SELECT GET_LOCK(?, 0)"
+	if l.engine == sqldb.Postgres {
+		query = "SELECT CASE WHEN pg_try_advisory_lock($1) THEN 1 ELSE 0 END"
+	}
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, query, l.lockKey(name)).Scan(&locked); err != nil {
+		conn.Close()
+		return 0, false, errors.Wrap(err, "lease: error taking the advisory lock")
+	}
+	if locked.Int64 != 1 {
+		conn.Close()
+		return 0, false, nil
+	}
+
+	// The fencing token is only updated by the holder of the lock
+	token, err := l.nextToken(ctx, conn, name)
+	if err != nil {
+		l.unlock(conn, name)
+		return 0, false, err
+	}
+	l.held[name] = &held{conn: conn, owner: owner, token: token}
+	return token, true, nil
+}
+
+func (l *locker) nextToken(ctx context.Context, conn *sql.Conn, name string) (int64, error) {
+	update := "INSERT INTO lease_tokens (name, token) VALUES (?, 1) ON DUPLICATE KEY UPDATE token = token + 1"
+	if l.engine == sqldb.Postgres {
+		update = "INSERT INTO lease_tokens (name, token) VALUES ($1, 1) ON CONFLICT (name) DO UPDATE SET token = lease_tokens.token + 1"
+	}
+	if _, err := conn.ExecContext(ctx, update, name); err != nil {
+		return 0, errors.Wrap(err, "lease: error updating the fencing token")
+	}
+
+	var token int64
+	if err := conn.QueryRowContext(ctx, sqldb.RebindEngine(l.engine, "SELECT token FROM lease_tokens WHERE name = ?"), name).Scan(&token); err != nil {
+		return 0, errors.Wrap(err, "lease: error reading the fencing token")
+	}
+	return token, nil
+}
+
+func (l *locker) Renew(ctx context.Context, name, owner string, token int64, ttl time.Duration) (bool, error) {
+	l.mutex.Lock()
+	h, ok := l.held[name]
+	l.mutex.Unlock()
+	if !ok || h.owner != owner || h.token != token {
+		return false, nil
+	}
+
+	// The lock is held as long as its connection lives
+	if err := h.conn.PingContext(ctx); err != nil {
+		l.mutex.Lock()
+		if l.held[name] == h {
+			delete(l.held, name)
+			discard(h.conn)
+		}
+		l.mutex.Unlock()
+		return false, nil
+	}
+	return true, nil
+}
+
+func (l *locker) Release(ctx context.Context, name, owner string, token int64) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	h, ok := l.held[name]
+	if !ok || h.owner != owner || h.token != token {
+		return nil
+	}
+	delete(l.held, name)
+	l.unlock(h.conn, name)
+	return nil
+}
+
+// unlock releases the advisory lock and hands the connection back to the pool.
+func (l *locker) unlock(conn *sql.Conn, name string) {
+	query := "SELECT RELEASE_LOCK(?)"
+	if l.engine == sqldb.Postgres {
+		query = "SELECT pg_advisory_unlock($1)"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := conn.ExecContext(ctx, query, l.lockKey(name)); err != nil {
+		discard(conn)
+		return
+	}
+	conn.Close()
+}
+
+// discard closes the connection instead of handing it back to the pool, where it would keep any lock it holds.
+func discard(conn *sql.Conn) {
+	_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	conn.Close()
+}
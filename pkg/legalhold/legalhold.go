@@ -0,0 +1,118 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package legalhold defines the legal holds, which freeze the resources of a
+// user, a storage space or a tree for compliance, and the interface of the
+// managers storing them.
+package legalhold
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+// Type is the kind of target a hold is placed on.
+type Type string
+
+const (
+	// TypeUser holds all the resources owned by a user.
+	TypeUser Type = "user"
+	// TypeSpace holds all the resources of a storage space.
+	TypeSpace Type = "space"
+	// TypePath holds a tree of a storage, given by its path in the storage.
+	TypePath Type = "path"
+)
+
+// Hold freezes a set of resources: while it is active they can neither be
+// modified nor deleted.
+type Hold struct {
+	ID      string         `json:"id"`
+	Type    Type           `json:"type"`
+	User    *userpb.UserId `json:"user,omitempty"`
+	SpaceID string         `json:"space_id,omitempty"`
+	Path    string         `json:"path,omitempty"`
+	Reason  string         `json:"reason"`
+	// CreatedBy is the admin who placed the hold.
+	CreatedBy  *userpb.UserId `json:"created_by"`
+	CreatedAt  int64          `json:"created_at"`
+	ReleasedBy *userpb.UserId `json:"released_by,omitempty"`
+	ReleasedAt int64          `json:"released_at,omitempty"`
+}
+
+// Active checks whether the hold has not been released.
+func (h *Hold) Active() bool {
+	return h.ReleasedAt == 0
+}
+
+// Release lifts the hold.
+func (h *Hold) Release(by *userpb.UserId) {
+	h.ReleasedBy = by
+	h.ReleasedAt = time.Now().Unix()
+}
+
+// Covers checks whether a path or a resource owned by the given user falls
+// under a user or path hold. Space holds are matched by the caller, which
+// knows where the spaces are rooted.
+func (h *Hold) Covers(owner *userpb.UserId, p string) bool {
+	switch h.Type {
+	case TypeUser:
+		return owner != nil && utils.UserEqual(h.User, owner)
+	case TypePath:
+		return IsBelow(p, h.Path)
+	default:
+		return false
+	}
+}
+
+// IsBelow checks whether p is root or one of its descendants.
+func IsBelow(p, root string) bool {
+	if root == "" {
+		return false
+	}
+	root = strings.TrimSuffix(root, "/")
+	return p == root || strings.HasPrefix(p, root+"/")
+}
+
+// Attempt records a modification rejected because of a hold.
+type Attempt struct {
+	HoldID    string         `json:"hold_id"`
+	User      *userpb.UserId `json:"user,omitempty"`
+	Operation string         `json:"operation"`
+	Path      string         `json:"path"`
+	Time      int64          `json:"time"`
+}
+
+// Manager is the interface to implement to store legal holds.
+type Manager interface {
+	// PlaceHold stores a new hold.
+	PlaceHold(ctx context.Context, h *Hold) error
+	// GetHold returns the hold with the given ID.
+	GetHold(ctx context.Context, id string) (*Hold, error)
+	// ListHolds returns all the holds, including the released ones.
+	ListHolds(ctx context.Context) ([]*Hold, error)
+	// UpdateHold updates an existing hold.
+	UpdateHold(ctx context.Context, h *Hold) error
+	// RecordAttempt stores a rejected modification.
+	RecordAttempt(ctx context.Context, a *Attempt) error
+	// ListAttempts returns the modifications rejected because of the given hold.
+	ListAttempts(ctx context.Context, holdID string) ([]*Attempt, error)
+}
@@ -0,0 +1,62 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package legalhold
+
+import (
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+)
+
+func TestCovers(t *testing.T) {
+	einstein := &userpb.UserId{Idp: "https://idp", OpaqueId: "einstein"}
+	marie := &userpb.UserId{Idp: "https://idp", OpaqueId: "marie"}
+
+	h := &Hold{Type: TypeUser, User: einstein}
+	if !h.Covers(einstein, "/einstein/docs") || h.Covers(marie, "/einstein/docs") || h.Covers(nil, "/einstein") {
+		t.Fatal("user hold must cover exactly the resources of the user")
+	}
+
+	h = &Hold{Type: TypePath, Path: "/projects/case/"}
+	tests := map[string]bool{
+		"/projects/case":        true,
+		"/projects/case/a/b":    true,
+		"/projects/casefile":    false,
+		"/projects":             false,
+		"/other/projects/case/": false,
+	}
+	for p, expected := range tests {
+		if h.Covers(marie, p) != expected {
+			t.Errorf("path hold covering %s: expected %v", p, expected)
+		}
+	}
+
+	h = &Hold{Type: TypeSpace, SpaceID: "storage!space"}
+	if h.Covers(einstein, "/") {
+		t.Fatal("space holds must be matched by the caller")
+	}
+
+	if !h.Active() {
+		t.Fatal("new hold must be active")
+	}
+	h.Release(einstein)
+	if h.Active() || h.ReleasedAt == 0 {
+		t.Fatal("released hold must not be active")
+	}
+}
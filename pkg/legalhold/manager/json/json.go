@@ -0,0 +1,213 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/legalhold"
+	"github.com/cs3org/reva/pkg/legalhold/manager/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("json", New)
+}
+
+type config struct {
+	File         string `mapstructure:"file"`
+	AttemptsFile string `mapstructure:"attempts_file"`
+}
+
+func (c *config) init() {
+	if c.File == "" {
+		c.File = "/var/tmp/reva/legalholds.json"
+	}
+	if c.AttemptsFile == "" {
+		c.AttemptsFile = "/var/tmp/reva/legalhold-attempts.jsonl"
+	}
+}
+
+type manager struct {
+	sync.Mutex
+	file         string
+	attemptsFile string
+}
+
+// New returns a legal hold manager storing the holds in a JSON file and the
+// rejected modifications in an append-only JSON lines file. The holds are
+// read on every access, as they are shared between the HTTP service placing
+// them and the storage providers enforcing them.
+func New(m map[string]interface{}) (legalhold.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	c.init()
+
+	mgr := &manager{file: c.File, attemptsFile: c.AttemptsFile}
+	if _, err := os.Stat(c.File); os.IsNotExist(err) {
+		if err := mgr.save(map[string]*legalhold.Hold{}); err != nil {
+			return nil, err
+		}
+	}
+	return mgr, nil
+}
+
+func (m *manager) load() (map[string]*legalhold.Hold, error) {
+	data, err := ioutil.ReadFile(m.file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading the file %s", m.file)
+	}
+	holds := map[string]*legalhold.Hold{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &holds); err != nil {
+			return nil, errors.Wrapf(err, "error parsing the file %s", m.file)
+		}
+	}
+	return holds, nil
+}
+
+func (m *manager) save(holds map[string]*legalhold.Hold) error {
+	data, err := json.Marshal(holds)
+	if err != nil {
+		return errors.Wrap(err, "error encoding the legal holds")
+	}
+	if err := ioutil.WriteFile(m.file, data, 0600); err != nil {
+		return errors.Wrapf(err, "error writing the file %s", m.file)
+	}
+	return nil
+}
+
+func (m *manager) PlaceHold(ctx context.Context, h *legalhold.Hold) error {
+	m.Lock()
+	defer m.Unlock()
+
+	holds, err := m.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := holds[h.ID]; ok {
+		return errtypes.AlreadyExists(h.ID)
+	}
+	holds[h.ID] = h
+	return m.save(holds)
+}
+
+func (m *manager) GetHold(ctx context.Context, id string) (*legalhold.Hold, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	holds, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	h, ok := holds[id]
+	if !ok {
+		return nil, errtypes.NotFound(id)
+	}
+	return h, nil
+}
+
+func (m *manager) ListHolds(ctx context.Context) ([]*legalhold.Hold, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	holds, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*legalhold.Hold, 0, len(holds))
+	for _, h := range holds {
+		list = append(list, h)
+	}
+	return list, nil
+}
+
+func (m *manager) UpdateHold(ctx context.Context, h *legalhold.Hold) error {
+	m.Lock()
+	defer m.Unlock()
+
+	holds, err := m.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := holds[h.ID]; !ok {
+		return errtypes.NotFound(h.ID)
+	}
+	holds[h.ID] = h
+	return m.save(holds)
+}
+
+func (m *manager) RecordAttempt(ctx context.Context, a *legalhold.Attempt) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return errors.Wrap(err, "error encoding the attempt")
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	f, err := os.OpenFile(m.attemptsFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "error opening the file %s", m.attemptsFile)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return errors.Wrapf(err, "error writing the file %s", m.attemptsFile)
+	}
+	return nil
+}
+
+func (m *manager) ListAttempts(ctx context.Context, holdID string) ([]*legalhold.Attempt, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	list := []*legalhold.Attempt{}
+	f, err := os.Open(m.attemptsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return list, nil
+		}
+		return nil, errors.Wrapf(err, "error opening the file %s", m.attemptsFile)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		a := &legalhold.Attempt{}
+		if err := json.Unmarshal(scanner.Bytes(), a); err != nil {
+			return nil, errors.Wrapf(err, "error parsing the file %s", m.attemptsFile)
+		}
+		if a.HoldID == holdID {
+			list = append(list, a)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "error reading the file %s", m.attemptsFile)
+	}
+	return list, nil
+}
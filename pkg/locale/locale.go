@@ -0,0 +1,140 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package locale defines the preference of the users holding their locale and
+// the locale-aware ordering and formatting of the resources listed for them.
+package locale
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+const (
+	// PreferenceNamespace is the namespace of the preference holding the locale.
+	PreferenceNamespace = "core"
+	// PreferenceKey is the key of the preference holding the locale.
+	PreferenceKey = "locale"
+)
+
+// Parse validates the value of the preference, a BCP 47 language tag.
+func Parse(v string) (language.Tag, error) {
+	if v == "" {
+		return language.Und, fmt.Errorf("locale: empty locale")
+	}
+	t, err := language.Parse(v)
+	if err != nil {
+		return language.Und, fmt.Errorf("locale: invalid locale %q: %w", v, err)
+	}
+	return t, nil
+}
+
+// Sort orders the resources by their names following the collation rules of
+// the locale, comparing the sequences of digits by their numeric value.
+func Sort(infos []*provider.ResourceInfo, t language.Tag) {
+	c := collate.New(t, collate.Numeric)
+	keys := make([][]byte, len(infos))
+	var b collate.Buffer
+	for i, info := range infos {
+		// keys are appended to the buffer, so they stay valid until it is reset
+		keys[i] = c.KeyFromString(&b, path.Base(info.GetPath()))
+	}
+	sort.Stable(byKey{infos: infos, keys: keys})
+}
+
+type byKey struct {
+	infos []*provider.ResourceInfo
+	keys  [][]byte
+}
+
+func (s byKey) Len() int { return len(s.infos) }
+
+func (s byKey) Less(i, j int) bool { return string(s.keys[i]) < string(s.keys[j]) }
+
+func (s byKey) Swap(i, j int) {
+	s.infos[i], s.infos[j] = s.infos[j], s.infos[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+// Hints tell the clients how sizes and dates are rendered in a locale.
+type Hints struct {
+	Locale            string `json:"locale" xml:"locale"`
+	DecimalSeparator  string `json:"decimalseparator" xml:"decimalseparator"`
+	GroupingSeparator string `json:"groupingseparator" xml:"groupingseparator"`
+	// DateOrder is the order of the day, month and year fields, e.g. DMY.
+	DateOrder string `json:"dateorder" xml:"dateorder"`
+	// HourCycle is h12 or h23.
+	HourCycle string `json:"hourcycle" xml:"hourcycle"`
+}
+
+// regions writing the month first or the year first, the day is first in all
+// the others.
+var (
+	monthFirst = map[string]bool{"US": true, "PH": true, "FM": true, "MH": true, "PW": true}
+	yearFirst  = map[string]bool{"CN": true, "JP": true, "KR": true, "TW": true, "HU": true, "LT": true, "MN": true, "IR": true}
+	hour12     = map[string]bool{
+		"US": true, "CA": true, "AU": true, "NZ": true, "IN": true, "PH": true, "PK": true,
+		"EG": true, "SA": true, "KR": true, "TW": true, "MX": true, "CO": true, "BD": true,
+	}
+)
+
+// HintsFor returns the formatting hints of the locale.
+func HintsFor(t language.Tag) *Hints {
+	h := &Hints{
+		Locale:            t.String(),
+		DecimalSeparator:  ".",
+		GroupingSeparator: ",",
+		DateOrder:         "DMY",
+		HourCycle:         "h23",
+	}
+
+	// the printer formats the numbers with the separators of the locale
+	if s := message.NewPrinter(t).Sprintf("%.1f", 1234.5); utf8.RuneCountInString(s) > 1 {
+		r := []rune(s)
+		h.DecimalSeparator = string(r[len(r)-2])
+		if g := strings.TrimFunc(s[:strings.LastIndex(s, h.DecimalSeparator)], isDigit); g != "" {
+			h.GroupingSeparator = g
+		} else {
+			h.GroupingSeparator = ""
+		}
+	}
+
+	region, _ := t.Region()
+	switch r := region.String(); {
+	case monthFirst[r]:
+		h.DateOrder = "MDY"
+	case yearFirst[r]:
+		h.DateOrder = "YMD"
+	}
+	if hour12[region.String()] {
+		h.HourCycle = "h12"
+	}
+	return h
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
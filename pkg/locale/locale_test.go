@@ -0,0 +1,73 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package locale
+
+import (
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"golang.org/x/text/language"
+)
+
+func TestParse(t *testing.T) {
+	if _, err := Parse("de-CH"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, v := range []string{"", "not a locale"} {
+		if _, err := Parse(v); err == nil {
+			t.Errorf("expected an error parsing %q", v)
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	infos := []*provider.ResourceInfo{
+		{Path: "/home/file10"},
+		{Path: "/home/File2"},
+		{Path: "/home/éclair"},
+		{Path: "/home/zebra"},
+		{Path: "/home/eagle"},
+	}
+	Sort(infos, language.French)
+
+	expected := []string{"/home/eagle", "/home/éclair", "/home/File2", "/home/file10", "/home/zebra"}
+	for i, info := range infos {
+		if info.Path != expected[i] {
+			t.Fatalf("expected %v at %d, got %v", expected[i], i, info.Path)
+		}
+	}
+}
+
+func TestHintsFor(t *testing.T) {
+	tests := []struct {
+		tag       language.Tag
+		dateOrder string
+		hourCycle string
+	}{
+		{language.AmericanEnglish, "MDY", "h12"},
+		{language.BritishEnglish, "DMY", "h23"},
+		{language.Japanese, "YMD", "h23"},
+	}
+	for _, tt := range tests {
+		h := HintsFor(tt.tag)
+		if h.DateOrder != tt.dateOrder || h.HourCycle != tt.hourCycle {
+			t.Errorf("%s: expected %s %s, got %s %s", tt.tag, tt.dateOrder, tt.hourCycle, h.DateOrder, h.HourCycle)
+		}
+	}
+}
@@ -18,6 +18,8 @@
 
 package config
 
+import "github.com/cs3org/reva/pkg/mentix/key"
+
 // Configuration holds the general Mentix configuration.
 type Configuration struct {
 	Prefix string `mapstructure:"prefix"`
@@ -27,15 +29,58 @@ type Configuration struct {
 			Address string `mapstructure:"address"`
 			Scope   string `mapstructure:"scope"`
 			APIKey  string `mapstructure:"apikey"`
+
+			WriteAddress string `mapstructure:"write_address"`
 		} `mapstructure:"gocdb"`
 	} `mapstructure:"connectors"`
 
 	UpdateInterval string `mapstructure:"update_interval"`
 
+	// Signing holds the keys used to sign and verify requests exchanged with the site accounts service.
+	Signing struct {
+		key.SigningConfig `mapstructure:",squash"`
+		// Endpoints are the endpoints that only accept signed requests; defaults to the site update endpoint.
+		Endpoints []string `mapstructure:"endpoints"`
+	} `mapstructure:"signing"`
+
 	Services struct {
 		CriticalTypes []string `mapstructure:"critical_types"`
 	} `mapstructure:"services"`
 
+	Importers struct {
+		Latency struct {
+			Endpoint          string   `mapstructure:"endpoint"`
+			EnabledConnectors []string `mapstructure:"enabled_connectors"`
+			IsProtected       bool     `mapstructure:"is_protected"`
+			MaxAge            string   `mapstructure:"max_age"`
+
+			SiteKeys struct {
+				VerifyURL     string `mapstructure:"verify_url"`
+				CacheDuration string `mapstructure:"cache_duration"`
+			} `mapstructure:"sitekeys"`
+		} `mapstructure:"latency"`
+
+		SiteUpdate struct {
+			Endpoint          string   `mapstructure:"endpoint"`
+			EnabledConnectors []string `mapstructure:"enabled_connectors"`
+			IsProtected       bool     `mapstructure:"is_protected"`
+		} `mapstructure:"siteupdate"`
+
+		Usage struct {
+			Endpoint          string   `mapstructure:"endpoint"`
+			EnabledConnectors []string `mapstructure:"enabled_connectors"`
+			IsProtected       bool     `mapstructure:"is_protected"`
+			MaxAge            string   `mapstructure:"max_age"`
+			// File persists the reported usage; it is only kept in memory if empty.
+			File string `mapstructure:"file"`
+
+			SiteKeys struct {
+				VerifyURL     string `mapstructure:"verify_url"`
+				CacheDuration string `mapstructure:"cache_duration"`
+			} `mapstructure:"sitekeys"`
+		} `mapstructure:"usage"`
+	} `mapstructure:"importers"`
+
 	Exporters struct {
 		WebAPI struct {
 			Endpoint          string   `mapstructure:"endpoint"`
@@ -64,6 +109,25 @@ type Configuration struct {
 		Metrics struct {
 			EnabledConnectors []string `mapstructure:"enabled_connectors"`
 		} `mapstructure:"metrics"`
+
+		Latency struct {
+			Endpoint          string   `mapstructure:"endpoint"`
+			EnabledConnectors []string `mapstructure:"enabled_connectors"`
+			IsProtected       bool     `mapstructure:"is_protected"`
+		} `mapstructure:"latency"`
+
+		Usage struct {
+			Endpoint          string   `mapstructure:"endpoint"`
+			EnabledConnectors []string `mapstructure:"enabled_connectors"`
+			IsProtected       bool     `mapstructure:"is_protected"`
+		} `mapstructure:"usage"`
+
+		Webhook struct {
+			URL               string   `mapstructure:"url"`
+			User              string   `mapstructure:"user"`
+			Password          string   `mapstructure:"password"`
+			EnabledConnectors []string `mapstructure:"enabled_connectors"`
+		} `mapstructure:"webhook"`
 	} `mapstructure:"exporters"`
 
 	// Internal settings
@@ -23,6 +23,15 @@ const (
 	ConnectorIDGOCDB = "gocdb"
 )
 
+const (
+	// ImporterIDLatency is the identifier for the Latency importer.
+	ImporterIDLatency = "latency"
+	// ImporterIDSiteUpdate is the identifier for the Site Update importer.
+	ImporterIDSiteUpdate = "siteupdate"
+	// ImporterIDUsage is the identifier for the Usage importer.
+	ImporterIDUsage = "usage"
+)
+
 const (
 	// ExporterIDWebAPI is the identifier for the WebAPI exporter.
 	ExporterIDWebAPI = "webapi"
@@ -34,4 +43,10 @@ const (
 	ExporterIDPrometheusSD = "promsd"
 	// ExporterIDMetrics is the identifier for the Metrics exporter.
 	ExporterIDMetrics = "metrics"
+	// ExporterIDLatency is the identifier for the Latency exporter.
+	ExporterIDLatency = "latency"
+	// ExporterIDUsage is the identifier for the Usage exporter.
+	ExporterIDUsage = "usage"
+	// ExporterIDWebhook is the identifier for the Webhook exporter.
+	ExporterIDWebhook = "webhook"
 )
@@ -21,6 +21,7 @@ package connectors
 import (
 	"encoding/xml"
 	"fmt"
+	"net"
 	"net/url"
 	"path"
 	"strings"
@@ -35,7 +36,7 @@ import (
 	"github.com/cs3org/reva/pkg/mentix/utils/network"
 )
 
-// GOCDBConnector is used to read mesh data from a GOCDB instance.
+// GOCDBConnector is used to read mesh data from a GOCDB instance and to write site updates back to it.
 type GOCDBConnector struct {
 	BaseConnector
 
@@ -92,6 +93,26 @@ func (connector *GOCDBConnector) RetrieveMeshData() (*meshdata.MeshData, error)
 	return meshData, nil
 }
 
+// UpdateMeshData updates the provided mesh data on the target side. The provided data only contains the data that
+// should be updated, not the entire data set.
+func (connector *GOCDBConnector) UpdateMeshData(data *meshdata.MeshData) error {
+	writeAddress := connector.conf.Connectors.GOCDB.WriteAddress
+	if len(writeAddress) == 0 {
+		return fmt.Errorf("no GOCDB write address configured")
+	}
+
+	for _, op := range data.Operators {
+		for _, site := range op.Sites {
+			if err := gocdb.WriteGOCDB(writeAddress, "site", gocdb.WriteOperationUpdate, connector.conf.Connectors.GOCDB.APIKey, connector.getWriteSiteData(site)); err != nil {
+				return fmt.Errorf("unable to write site '%v': %v", site.Name, err)
+			}
+			connector.log.Info().Str("site", site.ID).Msg("wrote site update to GOCDB")
+		}
+	}
+
+	return nil
+}
+
 func (connector *GOCDBConnector) query(v interface{}, method string, isPrivate bool, hasScope bool, params network.URLParams) error {
 	var scope string
 	if hasScope {
@@ -292,6 +313,44 @@ func (connector *GOCDBConnector) findServiceType(meshData *meshdata.MeshData, na
 	return &meshdata.ServiceType{Name: name, Description: ""}
 }
 
+func (connector *GOCDBConnector) getWriteSiteData(site *meshdata.Site) *gocdb.WriteSiteData {
+	getTypeName := func(serviceType *meshdata.ServiceType) string {
+		if serviceType != nil {
+			return serviceType.Name
+		}
+		return ""
+	}
+
+	siteData := &gocdb.WriteSiteData{
+		Name:       site.Name,
+		Extensions: site.Properties,
+	}
+	for _, service := range site.Services {
+		// The port might have been appended to the host, so remove it again
+		host := service.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		serviceData := gocdb.WriteServiceData{
+			Type:        getTypeName(service.Type),
+			Host:        host,
+			URL:         service.RawURL,
+			IsMonitored: service.IsMonitored,
+		}
+		for _, endpoint := range service.AdditionalEndpoints {
+			serviceData.Endpoints = append(serviceData.Endpoints, gocdb.WriteEndpointData{
+				Name:        endpoint.Name,
+				Type:        getTypeName(endpoint.Type),
+				URL:         endpoint.RawURL,
+				IsMonitored: endpoint.IsMonitored,
+			})
+		}
+		siteData.Services = append(siteData.Services, serviceData)
+	}
+	return siteData
+}
+
 func (connector *GOCDBConnector) extensionsToMap(extensions *gocdb.Extensions) map[string]string {
 	properties := make(map[string]string)
 	for _, ext := range extensions.Extensions {
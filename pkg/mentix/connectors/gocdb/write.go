@@ -0,0 +1,98 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gocdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/cs3org/reva/pkg/mentix/utils/network"
+)
+
+const (
+	// WriteOperationUpdate is the write operation to update an existing entity.
+	WriteOperationUpdate = "Update"
+)
+
+// WriteEndpointData holds the data of a service endpoint sent to the GOCDB write API.
+type WriteEndpointData struct {
+	Name        string `json:"Name"`
+	Type        string `json:"Type"`
+	URL         string `json:"URL"`
+	IsMonitored bool   `json:"IsMonitored"`
+}
+
+// WriteServiceData holds the data of a service sent to the GOCDB write API.
+type WriteServiceData struct {
+	Type        string              `json:"Type"`
+	Host        string              `json:"Host"`
+	URL         string              `json:"URL"`
+	IsMonitored bool                `json:"IsMonitored"`
+	Endpoints   []WriteEndpointData `json:"Endpoints"`
+}
+
+// WriteSiteData holds the data of a site sent to the GOCDB write API.
+type WriteSiteData struct {
+	Name       string             `json:"Name"`
+	Extensions map[string]string  `json:"Extensions"`
+	Services   []WriteServiceData `json:"Services"`
+}
+
+type writeData struct {
+	APIKey    string `json:"APIKey"`
+	Operation string `json:"Operation"`
+
+	Data interface{} `json:"Data"`
+}
+
+// WriteGOCDB sends data of the given entity type (e.g., "site") to the GOCDB write API.
+func WriteGOCDB(address string, entity string, operation string, apiKey string, data interface{}) error {
+	endpointURL, err := network.GenerateURL(address, "/ext/v1/"+entity, network.URLParams{})
+	if err != nil {
+		return fmt.Errorf("unable to generate the GOCDB URL: %v", err)
+	}
+
+	jsonData, err := json.Marshal(&writeData{APIKey: apiKey, Operation: operation, Data: data})
+	if err != nil {
+		return fmt.Errorf("unable to marshal the %v data: %v", entity, err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpointURL.String(), bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("unable to create HTTP request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send data to the GOCDB write API: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("writing the %v data to GOCDB failed: %v (%v)", entity, resp.Status, string(msg))
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package latency
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cs3org/reva/pkg/mentix/config"
+	"github.com/cs3org/reva/pkg/mentix/latency"
+	"github.com/cs3org/reva/pkg/mentix/meshdata"
+)
+
+// HandleDefaultQuery returns the entire latency matrix.
+func HandleDefaultQuery(_ *meshdata.MeshData, _ url.Values, _ *config.Configuration, _ *zerolog.Logger) (int, []byte, error) {
+	return marshalLinks(latency.Measurements().Links())
+}
+
+// HandleSiteQuery returns all links from or to the site specified by the 'id' parameter.
+func HandleSiteQuery(_ *meshdata.MeshData, params url.Values, _ *config.Configuration, _ *zerolog.Logger) (int, []byte, error) {
+	siteID := params.Get("id")
+	if siteID == "" {
+		return http.StatusBadRequest, []byte{}, fmt.Errorf("no site specified")
+	}
+
+	links := make([]*latency.Link, 0)
+	for _, link := range latency.Measurements().Links() {
+		if link.Source == siteID || link.Target == siteID {
+			links = append(links, link)
+		}
+	}
+	return marshalLinks(links)
+}
+
+func marshalLinks(links []*latency.Link) (int, []byte, error) {
+	data, err := json.MarshalIndent(links, "", "\t")
+	if err != nil {
+		return http.StatusBadRequest, []byte{}, fmt.Errorf("unable to marshal the latency matrix: %v", err)
+	}
+	return http.StatusOK, data, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package exporters
+
+import (
+	"github.com/rs/zerolog"
+
+	"github.com/cs3org/reva/pkg/mentix/config"
+	"github.com/cs3org/reva/pkg/mentix/exchangers/exporters/usage"
+)
+
+// UsageExporter implements the Usage exporter which exposes the daily API usage of the sites.
+type UsageExporter struct {
+	BaseRequestExporter
+}
+
+// Activate activates the exporter.
+func (exporter *UsageExporter) Activate(conf *config.Configuration, log *zerolog.Logger) error {
+	if err := exporter.BaseRequestExporter.Activate(conf, log); err != nil {
+		return err
+	}
+
+	// Store Usage specifics
+	exporter.SetEndpoint(conf.Exporters.Usage.Endpoint, conf.Exporters.Usage.IsProtected)
+	exporter.SetEnabledConnectors(conf.Exporters.Usage.EnabledConnectors)
+
+	exporter.RegisterActionHandler("", usage.HandleDefaultQuery)
+	exporter.RegisterActionHandler("site", usage.HandleSiteQuery)
+
+	return nil
+}
+
+// GetID returns the ID of the exporter.
+func (exporter *UsageExporter) GetID() string {
+	return config.ExporterIDUsage
+}
+
+// GetName returns the display name of the exporter.
+func (exporter *UsageExporter) GetName() string {
+	return "Usage"
+}
+
+func init() {
+	registerExporter(&UsageExporter{})
+}
@@ -0,0 +1,65 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cs3org/reva/pkg/mentix/config"
+	"github.com/cs3org/reva/pkg/mentix/meshdata"
+	"github.com/cs3org/reva/pkg/mentix/usage"
+)
+
+// HandleDefaultQuery returns the usage of all sites between the days specified by the 'from' and 'to' parameters.
+func HandleDefaultQuery(_ *meshdata.MeshData, params url.Values, _ *config.Configuration, _ *zerolog.Logger) (int, []byte, error) {
+	return queryRecords("", params)
+}
+
+// HandleSiteQuery returns the usage of the site specified by the 'id' parameter between the days specified by the 'from' and 'to' parameters.
+func HandleSiteQuery(_ *meshdata.MeshData, params url.Values, _ *config.Configuration, _ *zerolog.Logger) (int, []byte, error) {
+	siteID := params.Get("id")
+	if siteID == "" {
+		return http.StatusBadRequest, []byte{}, fmt.Errorf("no site specified")
+	}
+	return queryRecords(siteID, params)
+}
+
+func queryRecords(siteID string, params url.Values) (int, []byte, error) {
+	from, to := params.Get("from"), params.Get("to")
+	for _, date := range []string{from, to} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(usage.DateLayout, date); err != nil {
+			return http.StatusBadRequest, []byte{}, fmt.Errorf("invalid date %v: %v", date, err)
+		}
+	}
+
+	data, err := json.MarshalIndent(usage.Usage().Records(siteID, from, to), "", "\t")
+	if err != nil {
+		return http.StatusBadRequest, []byte{}, fmt.Errorf("unable to marshal the usage: %v", err)
+	}
+	return http.StatusOK, data, nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package exporters
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cs3org/reva/pkg/mentix/config"
+	"github.com/cs3org/reva/pkg/mentix/exchangers/exporters/webhook"
+	"github.com/cs3org/reva/pkg/mentix/key"
+	"github.com/cs3org/reva/pkg/mentix/meshdata"
+	"github.com/cs3org/reva/pkg/mentix/utils/network"
+)
+
+// WebhookExporter posts site events (sites entering or leaving the mesh, unhealthy sites) to a webhook.
+type WebhookExporter struct {
+	BaseExporter
+
+	url    string
+	auth   *network.BasicAuth
+	signer *key.RequestSigner
+	client *http.Client
+
+	siteStates webhook.SiteStates
+
+	statesLocker sync.Mutex
+}
+
+const (
+	webhookTimeout = 30 * time.Second
+)
+
+// Activate activates the exporter.
+func (exporter *WebhookExporter) Activate(conf *config.Configuration, log *zerolog.Logger) error {
+	if err := exporter.BaseExporter.Activate(conf, log); err != nil {
+		return err
+	}
+
+	// Store Webhook specifics
+	if conf.Exporters.Webhook.URL == "" {
+		return fmt.Errorf("no webhook URL configured")
+	}
+	exporter.url = conf.Exporters.Webhook.URL
+	if conf.Exporters.Webhook.User != "" {
+		exporter.auth = &network.BasicAuth{
+			User:     conf.Exporters.Webhook.User,
+			Password: conf.Exporters.Webhook.Password,
+		}
+	}
+	signer, err := key.NewRequestSigner(&conf.Signing.SigningConfig)
+	if err != nil {
+		return fmt.Errorf("unable to create the request signer: %v", err)
+	}
+	exporter.signer = signer
+	exporter.client = &http.Client{Timeout: webhookTimeout}
+
+	exporter.SetEnabledConnectors(conf.Exporters.Webhook.EnabledConnectors)
+
+	return nil
+}
+
+// Update is called whenever the mesh data set has changed to reflect these changes.
+func (exporter *WebhookExporter) Update(meshDataSet meshdata.Map) error {
+	if err := exporter.BaseExporter.Update(meshDataSet); err != nil {
+		return err
+	}
+
+	// Data is read, so acquire a read lock
+	exporter.Locker().RLock()
+	siteStates := webhook.GetSiteStates(exporter.MeshData())
+	exporter.Locker().RUnlock()
+
+	exporter.statesLocker.Lock()
+	defer exporter.statesLocker.Unlock()
+
+	// The first non-empty mesh data only serves as the baseline; otherwise, all sites would be reported as new after each restart
+	if exporter.siteStates == nil {
+		if len(siteStates) > 0 {
+			exporter.siteStates = siteStates
+		}
+		return nil
+	}
+
+	events := webhook.CompareSiteStates(exporter.siteStates, siteStates, time.Now())
+	exporter.siteStates = siteStates
+
+	if len(events) > 0 {
+		// Perform posting the events asynchronously
+		go exporter.postEvents(events)
+	}
+	return nil
+}
+
+func (exporter *WebhookExporter) postEvents(events []*webhook.SiteEvent) {
+	if err := exporter.post(&webhook.Payload{Events: events}); err != nil {
+		exporter.Log().Err(err).Int("events", len(events)).Msg("error posting site events to the webhook")
+	} else {
+		exporter.Log().Debug().Int("events", len(events)).Msg("posted site events to the webhook")
+	}
+}
+
+func (exporter *WebhookExporter) post(payload *webhook.Payload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("unable to marshal the site events: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, exporter.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("unable to create HTTP request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if exporter.auth != nil {
+		req.SetBasicAuth(exporter.auth.User, exporter.auth.Password)
+	}
+	if err := exporter.signer.Sign(req, data); err != nil {
+		return fmt.Errorf("unable to sign the request: %v", err)
+	}
+
+	resp, err := exporter.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to post the site events: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("posting the site events failed: %v (%v)", resp.Status, string(body))
+	}
+	return nil
+}
+
+// GetID returns the ID of the exporter.
+func (exporter *WebhookExporter) GetID() string {
+	return config.ExporterIDWebhook
+}
+
+// GetName returns the display name of the exporter.
+func (exporter *WebhookExporter) GetName() string {
+	return "Webhook"
+}
+
+func init() {
+	registerExporter(&WebhookExporter{})
+}
@@ -0,0 +1,100 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package webhook
+
+import (
+	"sort"
+	"time"
+
+	"github.com/cs3org/reva/pkg/mentix/meshdata"
+)
+
+// SiteState holds the state of a single site relevant for detecting changes.
+type SiteState struct {
+	OperatorID string
+	SiteName   string
+	Healthy    bool
+	Reason     string
+}
+
+// SiteStates maps site IDs to their states.
+type SiteStates = map[string]SiteState
+
+// GetSiteStates extracts the states of all sites in the given mesh data.
+func GetSiteStates(meshData *meshdata.MeshData) SiteStates {
+	states := make(SiteStates)
+	for _, op := range meshData.Operators {
+		for _, site := range op.Sites {
+			state := SiteState{
+				OperatorID: op.ID,
+				SiteName:   site.Name,
+				Healthy:    true,
+			}
+			if site.Downtimes.IsAnyActive() {
+				state.Healthy = false
+				state.Reason = "the site is in a scheduled downtime"
+			}
+			states[site.ID] = state
+		}
+	}
+	return states
+}
+
+// CompareSiteStates creates events for all sites that entered or left the mesh or became unhealthy.
+func CompareSiteStates(oldStates SiteStates, newStates SiteStates, timestamp time.Time) []*SiteEvent {
+	events := make([]*SiteEvent, 0)
+	newEvent := func(eventType string, siteID string, state SiteState) {
+		events = append(events, &SiteEvent{
+			Type:       eventType,
+			OperatorID: state.OperatorID,
+			SiteID:     siteID,
+			SiteName:   state.SiteName,
+			Reason:     state.Reason,
+			Timestamp:  timestamp,
+		})
+	}
+
+	for siteID, state := range newStates {
+		if oldState, ok := oldStates[siteID]; !ok {
+			newEvent(EventSiteAdded, siteID, state)
+			if !state.Healthy {
+				newEvent(EventSiteUnhealthy, siteID, state)
+			}
+		} else if oldState.Healthy && !state.Healthy {
+			newEvent(EventSiteUnhealthy, siteID, state)
+		}
+	}
+
+	for siteID, state := range oldStates {
+		if _, ok := newStates[siteID]; !ok {
+			state.Reason = ""
+			newEvent(EventSiteRemoved, siteID, state)
+		}
+	}
+
+	// Keep the events in a stable order
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].SiteID != events[j].SiteID {
+			return events[i].SiteID < events[j].SiteID
+		}
+		return events[i].Type < events[j].Type
+	})
+
+	return events
+}
@@ -0,0 +1,62 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompareSiteStates(t *testing.T) {
+	oldStates := SiteStates{
+		"a": {OperatorID: "op1", SiteName: "A", Healthy: true},
+		"b": {OperatorID: "op1", SiteName: "B", Healthy: true},
+		"c": {OperatorID: "op2", SiteName: "C", Healthy: false, Reason: "down"},
+	}
+	newStates := SiteStates{
+		"a": {OperatorID: "op1", SiteName: "A", Healthy: false, Reason: "down"},
+		"c": {OperatorID: "op2", SiteName: "C", Healthy: false, Reason: "down"},
+		"d": {OperatorID: "op2", SiteName: "D", Healthy: true},
+	}
+
+	events := CompareSiteStates(oldStates, newStates, time.Now())
+	expected := []struct{ siteID, eventType string }{
+		{"a", EventSiteUnhealthy},
+		{"b", EventSiteRemoved},
+		{"d", EventSiteAdded},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %d", len(expected), len(events))
+	}
+	for i, exp := range expected {
+		if events[i].SiteID != exp.siteID || events[i].Type != exp.eventType {
+			t.Errorf("event %d: expected %v/%v, got %v/%v", i, exp.siteID, exp.eventType, events[i].SiteID, events[i].Type)
+		}
+	}
+	if events[0].Reason != "down" || events[0].OperatorID != "op1" {
+		t.Errorf("unexpected unhealthy event: %+v", events[0])
+	}
+}
+
+func TestCompareSiteStatesUnchanged(t *testing.T) {
+	states := SiteStates{"a": {OperatorID: "op1", SiteName: "A", Healthy: true}}
+	if events := CompareSiteStates(states, states, time.Now()); len(events) != 0 {
+		t.Errorf("expected no events, got %d", len(events))
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package webhook
+
+import "time"
+
+const (
+	// EventSiteAdded is sent when a site has entered the mesh.
+	EventSiteAdded = "site_added"
+	// EventSiteRemoved is sent when a site has left the mesh.
+	EventSiteRemoved = "site_removed"
+	// EventSiteUnhealthy is sent when a site has been flagged as unhealthy.
+	EventSiteUnhealthy = "site_unhealthy"
+)
+
+// SiteEvent describes a change of a site within the mesh.
+type SiteEvent struct {
+	Type       string    `json:"type"`
+	OperatorID string    `json:"operatorID"`
+	SiteID     string    `json:"siteID"`
+	SiteName   string    `json:"siteName"`
+	Reason     string    `json:"reason,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// Payload is the data posted to the webhook.
+type Payload struct {
+	Events []*SiteEvent `json:"events"`
+}
@@ -0,0 +1,108 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package importers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cs3org/reva/pkg/mentix/config"
+	"github.com/cs3org/reva/pkg/mentix/exchangers/importers/latency"
+	"github.com/cs3org/reva/pkg/mentix/key"
+	latencydata "github.com/cs3org/reva/pkg/mentix/latency"
+)
+
+// LatencyImporter implements the Latency importer which receives the probing reports of the sites.
+type LatencyImporter struct {
+	BaseRequestImporter
+
+	keyVerifier *key.SiteKeyVerifier
+}
+
+// Activate activates the importer.
+func (importer *LatencyImporter) Activate(conf *config.Configuration, log *zerolog.Logger) error {
+	if err := importer.BaseRequestImporter.Activate(conf, log); err != nil {
+		return err
+	}
+
+	maxAge, err := time.ParseDuration(conf.Importers.Latency.MaxAge)
+	if err != nil {
+		return fmt.Errorf("invalid maximum age for latency reports: %v", err)
+	}
+	latencydata.Measurements().SetMaxAge(maxAge)
+
+	// Store Latency specifics
+	importer.SetEndpoint(conf.Importers.Latency.Endpoint, conf.Importers.Latency.IsProtected)
+	importer.SetEnabledConnectors(conf.Importers.Latency.EnabledConnectors)
+
+	// If a verification URL is configured, sites need to pass a valid site key to push reports
+	if verifyURL := conf.Importers.Latency.SiteKeys.VerifyURL; verifyURL != "" {
+		cacheDuration, err := time.ParseDuration(conf.Importers.Latency.SiteKeys.CacheDuration)
+		if err != nil {
+			return fmt.Errorf("invalid cache duration for site keys: %v", err)
+		}
+
+		signer, err := key.NewRequestSigner(&conf.Signing.SigningConfig)
+		if err != nil {
+			return fmt.Errorf("unable to create the request signer: %v", err)
+		}
+
+		keyVerifier, err := key.NewSiteKeyVerifier(verifyURL, cacheDuration, signer)
+		if err != nil {
+			return fmt.Errorf("unable to create the site key verifier: %v", err)
+		}
+		importer.keyVerifier = keyVerifier
+	}
+
+	importer.RegisterExtendedActionHandler("report", latency.HandleReportQuery)
+
+	return nil
+}
+
+// HandleRequest handles the actual HTTP request, verifying the site key first if required.
+func (importer *LatencyImporter) HandleRequest(resp http.ResponseWriter, req *http.Request, conf *config.Configuration, log *zerolog.Logger) {
+	if importer.keyVerifier != nil {
+		site := req.URL.Query().Get("site")
+		if err := importer.keyVerifier.Verify(site, req.Header.Get(key.SiteKeyHeader)); err != nil {
+			log.Warn().Err(err).Str("site", site).Msg("rejected latency report")
+			resp.WriteHeader(http.StatusUnauthorized)
+			_, _ = resp.Write([]byte(fmt.Sprintf("site key verification failed: %v", err)))
+			return
+		}
+	}
+
+	importer.BaseRequestImporter.HandleRequest(resp, req, conf, log)
+}
+
+// GetID returns the ID of the importer.
+func (importer *LatencyImporter) GetID() string {
+	return config.ImporterIDLatency
+}
+
+// GetName returns the display name of the importer.
+func (importer *LatencyImporter) GetName() string {
+	return "Latency"
+}
+
+func init() {
+	registerImporter(&LatencyImporter{})
+}
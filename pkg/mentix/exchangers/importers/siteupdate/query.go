@@ -0,0 +1,113 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package siteupdate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cs3org/reva/pkg/mentix/config"
+	"github.com/cs3org/reva/pkg/mentix/meshdata"
+)
+
+// HandleUpdateQuery updates the data of a site; if the dryrun parameter is set, the changes are only previewed.
+func HandleUpdateQuery(meshData *meshdata.MeshData, data []byte, params url.Values, _ *config.Configuration, log *zerolog.Logger) (meshdata.Vector, int, []byte, error) {
+	req := &Request{}
+	if err := json.Unmarshal(data, req); err != nil {
+		return nil, http.StatusBadRequest, []byte{}, fmt.Errorf("unable to unmarshal the site update: %v", err)
+	}
+	if req.SiteID == "" || req.Data == nil {
+		return nil, http.StatusBadRequest, []byte{}, fmt.Errorf("no site or site data specified")
+	}
+
+	dryRun := false
+	if val := params.Get("dryrun"); val != "" {
+		if v, err := strconv.ParseBool(val); err == nil {
+			dryRun = v
+		} else {
+			return nil, http.StatusBadRequest, []byte{}, fmt.Errorf("invalid dry-run flag: %v", err)
+		}
+	}
+
+	site := findSite(meshData, req.SiteID)
+	if site == nil {
+		return nil, http.StatusNotFound, []byte{}, fmt.Errorf("no site with ID %v found", req.SiteID)
+	}
+	currentData := GetSiteData(site)
+
+	resp := &Response{DryRun: dryRun}
+	if req.Base != nil {
+		// Only the changes made on top of the base data are applied; if any of them collide with changes made in the meantime, the update is rejected
+		changes := CompareSiteData(req.Base, req.Data)
+		if conflicts := FindConflicts(req.Base, currentData, changes); len(conflicts) > 0 {
+			resp.Conflicts = conflicts
+			return nil, http.StatusConflict, marshalResponse(resp), nil
+		}
+
+		currentValues := currentData.values()
+		for _, change := range changes {
+			if currentValues[changeKey{change.Type, change.Key}] != change.NewValue {
+				resp.Changes = append(resp.Changes, change)
+			}
+		}
+	} else {
+		resp.Changes = CompareSiteData(currentData, req.Data)
+	}
+
+	if dryRun || len(resp.Changes) == 0 {
+		return nil, http.StatusOK, marshalResponse(resp), nil
+	}
+
+	// Create mesh data only containing the updated site; it will be passed on to the connectors
+	update := meshData.Clone()
+	for _, op := range update.Operators {
+		if site := op.FindSite(req.SiteID); site != nil {
+			if err := ApplyChanges(site, resp.Changes); err != nil {
+				return nil, http.StatusBadRequest, []byte{}, fmt.Errorf("unable to apply the site update: %v", err)
+			}
+
+			op.Sites = []*meshdata.Site{site}
+			update.Operators = []*meshdata.Operator{op}
+			break
+		}
+	}
+
+	log.Debug().Str("site", req.SiteID).Int("changes", len(resp.Changes)).Msg("received site update")
+
+	return meshdata.Vector{update}, http.StatusOK, marshalResponse(resp), nil
+}
+
+func findSite(meshData *meshdata.MeshData, siteID string) *meshdata.Site {
+	for _, op := range meshData.Operators {
+		if site := op.FindSite(siteID); site != nil {
+			return site
+		}
+	}
+	return nil
+}
+
+func marshalResponse(resp *Response) []byte {
+	data, _ := json.Marshal(resp)
+	return data
+}
@@ -0,0 +1,139 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package siteupdate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cs3org/reva/pkg/mentix/meshdata"
+)
+
+func newTestMeshData() *meshdata.MeshData {
+	return &meshdata.MeshData{
+		Operators: []*meshdata.Operator{{
+			ID: "op",
+			Sites: []*meshdata.Site{{
+				ID:         "site",
+				Name:       "site",
+				Properties: map[string]string{"METRICS_PATH": "/metrics", "API_VERSION": "1"},
+				Services: []*meshdata.Service{{
+					ServiceEndpoint: &meshdata.ServiceEndpoint{
+						Type:        &meshdata.ServiceType{Name: "REVAD"},
+						Name:        "REVAD",
+						RawURL:      "https://revad.example.org",
+						IsMonitored: true,
+					},
+					Host: "revad.example.org",
+					AdditionalEndpoints: []*meshdata.ServiceEndpoint{{
+						Type:   &meshdata.ServiceType{Name: "GATEWAY"},
+						Name:   "gateway",
+						RawURL: "/gateway",
+					}},
+				}},
+			}},
+		}},
+	}
+}
+
+func handleUpdate(t *testing.T, meshData *meshdata.MeshData, req *Request, dryRun bool) (meshdata.Vector, int, *Response) {
+	data, _ := json.Marshal(req)
+	params := url.Values{}
+	if dryRun {
+		params.Set("dryrun", "true")
+	}
+
+	log := zerolog.Nop()
+	updates, status, respData, err := HandleUpdateQuery(meshData, data, params, nil, &log)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp := &Response{}
+	if err := json.Unmarshal(respData, resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	return updates, status, resp
+}
+
+func TestUpdateDryRun(t *testing.T) {
+	meshData := newTestMeshData()
+	siteData := GetSiteData(meshData.Operators[0].Sites[0])
+	siteData.Properties["API_VERSION"] = "2"
+	siteData.Endpoints[1].URL = "/gw"
+
+	updates, status, resp := handleUpdate(t, meshData, &Request{SiteID: "site", Data: siteData}, true)
+	if status != http.StatusOK || len(updates) != 0 || !resp.DryRun {
+		t.Fatalf("unexpected dry-run result: %v, %v, %+v", status, len(updates), resp)
+	}
+	if len(resp.Changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d", len(resp.Changes))
+	}
+	if c := resp.Changes[0]; c.Type != ChangeProperty || c.Key != "API_VERSION" || c.OldValue != "1" || c.NewValue != "2" {
+		t.Errorf("unexpected property change: %+v", c)
+	}
+	if c := resp.Changes[1]; c.Type != ChangeEndpointURL || c.Key != "REVAD@revad.example.org/gateway" || c.NewValue != "/gw" {
+		t.Errorf("unexpected endpoint change: %+v", c)
+	}
+	if meshData.Operators[0].Sites[0].Properties["API_VERSION"] != "1" {
+		t.Errorf("dry run modified the mesh data")
+	}
+}
+
+func TestUpdateApply(t *testing.T) {
+	meshData := newTestMeshData()
+	siteData := GetSiteData(meshData.Operators[0].Sites[0])
+	siteData.Endpoints[0].IsMonitored = false
+
+	updates, status, resp := handleUpdate(t, meshData, &Request{SiteID: "site", Data: siteData}, false)
+	if status != http.StatusOK || len(updates) != 1 || len(resp.Changes) != 1 {
+		t.Fatalf("unexpected update result: %v, %v, %+v", status, len(updates), resp)
+	}
+	if site := updates[0].Operators[0].Sites[0]; site.Services[0].IsMonitored {
+		t.Errorf("change was not applied to the update")
+	}
+}
+
+func TestUpdateConflicts(t *testing.T) {
+	meshData := newTestMeshData()
+	base := GetSiteData(meshData.Operators[0].Sites[0])
+
+	// Someone else changed the site in the meantime
+	meshData.Operators[0].Sites[0].Properties["API_VERSION"] = "3"
+	meshData.Operators[0].Sites[0].Properties["METRICS_PATH"] = "/m"
+
+	// Changing another value is fine
+	update := GetSiteData(newTestMeshData().Operators[0].Sites[0])
+	update.Properties["NEW"] = "x"
+	_, status, resp := handleUpdate(t, meshData, &Request{SiteID: "site", Base: base, Data: update}, true)
+	if status != http.StatusOK || len(resp.Changes) != 1 || resp.Changes[0].Key != "NEW" {
+		t.Fatalf("unexpected result for non-conflicting update: %v, %+v", status, resp)
+	}
+
+	// Changing the same value is a conflict
+	update.Properties["API_VERSION"] = "2"
+	_, status, resp = handleUpdate(t, meshData, &Request{SiteID: "site", Base: base, Data: update}, false)
+	if status != http.StatusConflict || len(resp.Conflicts) != 1 || resp.Conflicts[0].Key != "API_VERSION" || resp.Conflicts[0].NewValue != "3" {
+		t.Fatalf("unexpected result for conflicting update: %v, %+v", status, resp)
+	}
+}
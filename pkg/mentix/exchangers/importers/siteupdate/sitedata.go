@@ -0,0 +1,207 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package siteupdate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/mentix/meshdata"
+)
+
+type changeKey struct {
+	Type string
+	Key  string
+}
+
+// GetEndpointKey returns the key identifying the given endpoint within its site.
+func GetEndpointKey(endpoint *Endpoint) string {
+	if endpoint.Name == "" {
+		return endpoint.Service
+	}
+	return endpoint.Service + "/" + endpoint.Name
+}
+
+// GetServiceID returns the ID of a service as used by the site data.
+func GetServiceID(service *meshdata.Service) string {
+	serviceType := ""
+	if service.Type != nil {
+		serviceType = service.Type.Name
+	}
+	return serviceType + "@" + service.Host
+}
+
+// GetSiteData extracts the updatable data of a site.
+func GetSiteData(site *meshdata.Site) *SiteData {
+	siteData := &SiteData{
+		Properties: make(map[string]string, len(site.Properties)),
+		Endpoints:  make([]*Endpoint, 0, len(site.Services)),
+	}
+
+	for key, value := range site.Properties {
+		siteData.Properties[key] = value
+	}
+
+	for _, service := range site.Services {
+		serviceID := GetServiceID(service)
+		siteData.Endpoints = append(siteData.Endpoints, &Endpoint{
+			Service:     serviceID,
+			URL:         service.RawURL,
+			IsMonitored: service.IsMonitored,
+		})
+
+		for _, endpoint := range service.AdditionalEndpoints {
+			siteData.Endpoints = append(siteData.Endpoints, &Endpoint{
+				Service:     serviceID,
+				Name:        endpoint.Name,
+				URL:         endpoint.RawURL,
+				IsMonitored: endpoint.IsMonitored,
+			})
+		}
+	}
+
+	return siteData
+}
+
+// CompareSiteData returns all changes necessary to turn the old site data into the new one.
+func CompareSiteData(oldData *SiteData, newData *SiteData) []*Change {
+	oldValues := oldData.values()
+	newValues := newData.values()
+
+	changes := make([]*Change, 0)
+	addChange := func(key changeKey) {
+		if oldValue, newValue := oldValues[key], newValues[key]; oldValue != newValue {
+			changes = append(changes, &Change{Type: key.Type, Key: key.Key, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+
+	for key := range newValues {
+		addChange(key)
+	}
+	for key := range oldValues {
+		if _, ok := newValues[key]; !ok {
+			addChange(key)
+		}
+	}
+
+	sortChanges(changes)
+	return changes
+}
+
+// FindConflicts returns all changes made between the base and the current site data which collide with the given changes.
+func FindConflicts(baseData *SiteData, currentData *SiteData, changes []*Change) []*Change {
+	changed := make(map[changeKey]bool, len(changes))
+	for _, change := range changes {
+		changed[changeKey{change.Type, change.Key}] = true
+	}
+
+	conflicts := make([]*Change, 0)
+	for _, change := range CompareSiteData(baseData, currentData) {
+		if changed[changeKey{change.Type, change.Key}] {
+			conflicts = append(conflicts, change)
+		}
+	}
+	return conflicts
+}
+
+// ApplyChanges applies the given changes to a site.
+func ApplyChanges(site *meshdata.Site, changes []*Change) error {
+	for _, change := range changes {
+		switch change.Type {
+		case ChangeProperty:
+			if site.Properties == nil {
+				site.Properties = make(map[string]string)
+			}
+			if change.NewValue != "" {
+				site.Properties[change.Key] = change.NewValue
+			} else {
+				delete(site.Properties, change.Key)
+			}
+
+		case ChangeEndpointURL, ChangeEndpointMonitored:
+			endpoint := findServiceEndpoint(site, change.Key)
+			if endpoint == nil {
+				return fmt.Errorf("no endpoint %v found; only existing endpoints can be updated", change.Key)
+			}
+
+			if change.Type == ChangeEndpointURL {
+				if change.NewValue == "" {
+					return fmt.Errorf("the URL of endpoint %v must not be empty", change.Key)
+				}
+				endpoint.RawURL = change.NewValue
+				endpoint.URL = change.NewValue
+			} else {
+				monitored, err := strconv.ParseBool(change.NewValue)
+				if err != nil {
+					return fmt.Errorf("invalid monitoring flag for endpoint %v: %v", change.Key, err)
+				}
+				endpoint.IsMonitored = monitored
+			}
+
+		default:
+			return fmt.Errorf("unsupported change type %v", change.Type)
+		}
+	}
+
+	return nil
+}
+
+func (siteData *SiteData) values() map[changeKey]string {
+	values := make(map[changeKey]string)
+	if siteData == nil {
+		return values
+	}
+
+	for key, value := range siteData.Properties {
+		values[changeKey{ChangeProperty, key}] = value
+	}
+	for _, endpoint := range siteData.Endpoints {
+		key := GetEndpointKey(endpoint)
+		values[changeKey{ChangeEndpointURL, key}] = endpoint.URL
+		values[changeKey{ChangeEndpointMonitored, key}] = strconv.FormatBool(endpoint.IsMonitored)
+	}
+	return values
+}
+
+func findServiceEndpoint(site *meshdata.Site, key string) *meshdata.ServiceEndpoint {
+	for _, service := range site.Services {
+		serviceID := GetServiceID(service)
+		if strings.EqualFold(key, serviceID) {
+			return service.ServiceEndpoint
+		}
+
+		for _, endpoint := range service.AdditionalEndpoints {
+			if strings.EqualFold(key, serviceID+"/"+endpoint.Name) {
+				return endpoint
+			}
+		}
+	}
+	return nil
+}
+
+func sortChanges(changes []*Change) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Key != changes[j].Key {
+			return changes[i].Key < changes[j].Key
+		}
+		return changes[i].Type < changes[j].Type
+	})
+}
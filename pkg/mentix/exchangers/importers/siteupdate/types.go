@@ -0,0 +1,71 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package siteupdate
+
+const (
+	// ChangeProperty marks a change of a site property.
+	ChangeProperty = "property"
+	// ChangeEndpointURL marks a change of the URL of a service endpoint.
+	ChangeEndpointURL = "endpoint_url"
+	// ChangeEndpointMonitored marks a change of the monitoring flag of a service endpoint.
+	ChangeEndpointMonitored = "endpoint_monitored"
+)
+
+// Endpoint holds the updatable data of a single service endpoint.
+type Endpoint struct {
+	// Service identifies the service the endpoint belongs to (in the form <type>@<host>).
+	Service string `json:"service"`
+	// Name is the name of an additional endpoint; it is empty for the main service endpoint.
+	Name string `json:"name,omitempty"`
+
+	URL         string `json:"url"`
+	IsMonitored bool   `json:"isMonitored"`
+}
+
+// SiteData holds the updatable data of a site.
+type SiteData struct {
+	Properties map[string]string `json:"properties"`
+	Endpoints  []*Endpoint       `json:"endpoints"`
+}
+
+// Change describes a single changed value of a site.
+type Change struct {
+	Type string `json:"type"`
+	Key  string `json:"key"`
+
+	OldValue string `json:"oldValue"`
+	NewValue string `json:"newValue"`
+}
+
+// Request is used to update the data of a site.
+type Request struct {
+	SiteID string `json:"siteID"`
+
+	// Base holds the site data the update is based on; if set, it is used to detect conflicting changes.
+	Base *SiteData `json:"base,omitempty"`
+	Data *SiteData `json:"data"`
+}
+
+// Response is returned after (previewing) an update of a site.
+type Response struct {
+	DryRun bool `json:"dryRun"`
+
+	Changes   []*Change `json:"changes"`
+	Conflicts []*Change `json:"conflicts,omitempty"`
+}
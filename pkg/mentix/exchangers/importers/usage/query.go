@@ -0,0 +1,55 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cs3org/reva/pkg/mentix/config"
+	"github.com/cs3org/reva/pkg/mentix/meshdata"
+	"github.com/cs3org/reva/pkg/mentix/usage"
+)
+
+// HandleReportQuery stores the usage reported by a site.
+func HandleReportQuery(_ *meshdata.MeshData, data []byte, params url.Values, _ *config.Configuration, log *zerolog.Logger) (meshdata.Vector, int, []byte, error) {
+	report := &usage.Report{}
+	if err := json.Unmarshal(data, report); err != nil {
+		return nil, http.StatusBadRequest, []byte{}, fmt.Errorf("unable to unmarshal the usage report: %v", err)
+	}
+
+	// The site passed in the request is the one whose key has been verified, so the report must belong to it
+	if site := params.Get("site"); site != "" && !strings.EqualFold(site, report.Site) {
+		return nil, http.StatusBadRequest, []byte{}, fmt.Errorf("the usage report doesn't belong to site %v", site)
+	}
+
+	if err := usage.Usage().AddReport(report); err != nil {
+		return nil, http.StatusBadRequest, []byte{}, fmt.Errorf("invalid usage report: %v", err)
+	}
+
+	log.Debug().Str("site", report.Site).Int("days", len(report.Usage)).Msg("received usage report")
+
+	// The usage is not part of the mesh data, so nothing needs to be merged
+	return nil, http.StatusOK, []byte{}, nil
+}
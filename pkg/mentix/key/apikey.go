@@ -100,6 +100,11 @@ func SaltFromEmail(email string) string {
 	return strings.ToLower(email)
 }
 
+// SaltFromSiteID generates a salt-value from a site ID.
+func SaltFromSiteID(siteID string) string {
+	return strings.ToLower(siteID)
+}
+
 func calculateHash(randomString string, flags int, salt string) hashpkg.Hash {
 	hash := md5.New()
 	_, _ = hash.Write([]byte(randomString))
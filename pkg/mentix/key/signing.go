@@ -0,0 +1,242 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package key
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The HTTP headers carrying the signature of a request.
+const (
+	SignatureKeyIDHeader     = "X-Mesh-Key-ID"
+	SignatureTimestampHeader = "X-Mesh-Timestamp"
+	SignatureNonceHeader     = "X-Mesh-Nonce"
+	SignaturePathHeader      = "X-Mesh-Path"
+	SignatureHeader          = "X-Mesh-Signature"
+)
+
+const defaultMaxSkew = 5 * time.Minute
+
+// SigningConfig holds the settings used to sign and verify requests exchanged between Mentix and the site accounts service.
+type SigningConfig struct {
+	// KeyID is the ID of the key used to sign outgoing requests.
+	KeyID string `mapstructure:"key_id"`
+	// Keys maps key IDs to their shared secrets; requests signed with any of these keys are accepted, so keys can be rotated without downtime.
+	Keys map[string]string `mapstructure:"keys"`
+	// MaxSkew is the maximum difference between the time a request was signed and the time it is received (e.g., "5m").
+	MaxSkew string `mapstructure:"max_skew"`
+}
+
+// IsEnabled tells whether any signing keys have been configured.
+func (conf *SigningConfig) IsEnabled() bool {
+	return conf != nil && len(conf.Keys) > 0
+}
+
+// RequestSigner signs outgoing requests using the currently active key.
+type RequestSigner struct {
+	keyID  string
+	secret []byte
+}
+
+// Sign adds the signature headers to the given request; body must hold the exact request body. Signing with a nil signer does nothing.
+func (signer *RequestSigner) Sign(req *http.Request, body []byte) error {
+	if signer == nil {
+		return nil
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return errors.Wrap(err, "unable to generate a nonce")
+	}
+
+	// The path is passed along as the receiving service might be mounted below an arbitrary prefix
+	path := req.URL.EscapedPath()
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonceStr := hex.EncodeToString(nonce)
+
+	req.Header.Set(SignatureKeyIDHeader, signer.keyID)
+	req.Header.Set(SignatureTimestampHeader, timestamp)
+	req.Header.Set(SignatureNonceHeader, nonceStr)
+	req.Header.Set(SignaturePathHeader, path)
+	req.Header.Set(SignatureHeader, computeSignature(signer.secret, req.Method, path, req.URL.RawQuery, timestamp, nonceStr, body))
+	return nil
+}
+
+// RequestVerifier verifies the signatures of incoming requests; nonces of accepted requests are remembered to reject replayed requests.
+type RequestVerifier struct {
+	keys    map[string][]byte
+	maxSkew time.Duration
+
+	nonces map[string]time.Time
+	mutex  sync.Mutex
+}
+
+// Verify checks the signature of the given request; the request body is read and replaced by an in-memory copy.
+func (verifier *RequestVerifier) Verify(r *http.Request) error {
+	var body []byte
+	if r.Body != nil {
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return errors.Wrap(err, "unable to read the request body")
+		}
+		_ = r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(data))
+		body = data
+	}
+	return verifier.VerifyBody(r, body)
+}
+
+// VerifyBody checks the signature of the given request using an already read request body.
+func (verifier *RequestVerifier) VerifyBody(r *http.Request, body []byte) error {
+	if !IsRequestSigned(r) {
+		return errors.Errorf("the request is not signed")
+	}
+
+	keyID := r.Header.Get(SignatureKeyIDHeader)
+	secret, ok := verifier.keys[keyID]
+	if !ok {
+		return errors.Errorf("unknown signing key %v", keyID)
+	}
+
+	timestamp := r.Header.Get(SignatureTimestampHeader)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.Errorf("invalid signature timestamp")
+	}
+	if skew := time.Since(time.Unix(seconds, 0)); skew > verifier.maxSkew || skew < -verifier.maxSkew {
+		return errors.Errorf("the signature has expired")
+	}
+
+	// The signed path may carry a prefix that has already been stripped from the request path
+	path := r.Header.Get(SignaturePathHeader)
+	if !strings.HasSuffix(path, r.URL.EscapedPath()) {
+		return errors.Errorf("the signature was issued for a different endpoint")
+	}
+
+	nonce := r.Header.Get(SignatureNonceHeader)
+	if nonce == "" {
+		return errors.Errorf("no signature nonce specified")
+	}
+
+	signature := computeSignature(secret, r.Method, path, r.URL.RawQuery, timestamp, nonce, body)
+	if !hmac.Equal([]byte(signature), []byte(r.Header.Get(SignatureHeader))) {
+		return errors.Errorf("invalid request signature")
+	}
+
+	// Only remember nonces of authentic requests, so that forged requests can't flood the cache
+	if !verifier.useNonce(keyID + ":" + nonce) {
+		return errors.Errorf("the request has already been received")
+	}
+	return nil
+}
+
+func (verifier *RequestVerifier) useNonce(nonce string) bool {
+	verifier.mutex.Lock()
+	defer verifier.mutex.Unlock()
+
+	// A nonce needs to be remembered as long as its timestamp is accepted
+	now := time.Now()
+	for n, expiry := range verifier.nonces {
+		if now.After(expiry) {
+			delete(verifier.nonces, n)
+		}
+	}
+
+	if _, used := verifier.nonces[nonce]; used {
+		return false
+	}
+	verifier.nonces[nonce] = now.Add(2 * verifier.maxSkew)
+	return true
+}
+
+// IsRequestSigned tells whether the given request carries a signature.
+func IsRequestSigned(r *http.Request) bool {
+	return r.Header.Get(SignatureHeader) != ""
+}
+
+func computeSignature(secret []byte, method, path, query, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	data := strings.Join([]string{strings.ToUpper(method), path, query, timestamp, nonce, hex.EncodeToString(bodyHash[:])}, "\n")
+
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewRequestSigner creates a new request signer using the active key of the given configuration; if signing is disabled, nil is returned.
+func NewRequestSigner(conf *SigningConfig) (*RequestSigner, error) {
+	if !conf.IsEnabled() {
+		return nil, nil
+	}
+
+	if conf.KeyID == "" {
+		return nil, errors.Errorf("no signing key ID specified")
+	}
+	secret := conf.Keys[conf.KeyID]
+	if secret == "" {
+		return nil, errors.Errorf("no secret configured for signing key %v", conf.KeyID)
+	}
+
+	return &RequestSigner{
+		keyID:  conf.KeyID,
+		secret: []byte(secret),
+	}, nil
+}
+
+// NewRequestVerifier creates a new request verifier accepting all keys of the given configuration.
+func NewRequestVerifier(conf *SigningConfig) (*RequestVerifier, error) {
+	if !conf.IsEnabled() {
+		return nil, errors.Errorf("no signing keys configured")
+	}
+
+	maxSkew := defaultMaxSkew
+	if conf.MaxSkew != "" {
+		duration, err := time.ParseDuration(conf.MaxSkew)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid maximum signature skew")
+		}
+		maxSkew = duration
+	}
+
+	keys := make(map[string][]byte, len(conf.Keys))
+	for id, secret := range conf.Keys {
+		if secret == "" {
+			return nil, errors.Errorf("no secret configured for signing key %v", id)
+		}
+		keys[id] = []byte(secret)
+	}
+
+	return &RequestVerifier{
+		keys:    keys,
+		maxSkew: maxSkew,
+		nonces:  make(map[string]time.Time),
+	}, nil
+}
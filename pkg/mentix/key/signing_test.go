@@ -0,0 +1,137 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package key
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newSignedRequest(t *testing.T, signer *RequestSigner, method, url string, body []byte) *http.Request {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signer.Sign(req, body); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestRequestSigning(t *testing.T) {
+	conf := &SigningConfig{KeyID: "key1", Keys: map[string]string{"key1": "secret1"}}
+	signer, err := NewRequestSigner(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := NewRequestVerifier(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := []byte(`{"site": "SITE-A"}`)
+	req := newSignedRequest(t, signer, http.MethodPost, "https://mesh.example.org/siteacc/verify-site-key?dryrun=true", body)
+	if err := verifier.Verify(req); err != nil {
+		t.Errorf("expected the request to be valid, got %v", err)
+	}
+	if data, _ := ioutil.ReadAll(req.Body); !bytes.Equal(data, body) {
+		t.Errorf("expected the request body to be preserved, got %q", data)
+	}
+
+	// Replaying the same request is rejected
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err := verifier.Verify(req); err == nil {
+		t.Error("expected a replayed request to be rejected")
+	}
+
+	// The receiving service might have stripped its prefix from the path
+	req = newSignedRequest(t, signer, http.MethodGet, "https://mesh.example.org/mentix/sites", nil)
+	req.URL.Path = "/sites"
+	if err := verifier.Verify(req); err != nil {
+		t.Errorf("expected the request with a stripped prefix to be valid, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(req *http.Request)
+	}{
+		{"body", func(req *http.Request) { req.Body = ioutil.NopCloser(bytes.NewReader([]byte(`{"site": "SITE-B"}`))) }},
+		{"query", func(req *http.Request) { req.URL.RawQuery = "dryrun=false" }},
+		{"method", func(req *http.Request) { req.Method = http.MethodPut }},
+		{"path", func(req *http.Request) { req.URL.Path = "/siteacc/dispatch-site-events" }},
+		{"signature", func(req *http.Request) { req.Header.Set(SignatureHeader, "invalid") }},
+		{"unknown key", func(req *http.Request) { req.Header.Set(SignatureKeyIDHeader, "key2") }},
+		{"expired", func(req *http.Request) {
+			req.Header.Set(SignatureTimestampHeader, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+		}},
+		{"unsigned", func(req *http.Request) { req.Header.Del(SignatureHeader) }},
+	}
+	for _, test := range tests {
+		req := newSignedRequest(t, signer, http.MethodPost, "https://mesh.example.org/siteacc/verify-site-key?dryrun=true", body)
+		test.tamper(req)
+		if err := verifier.Verify(req); err == nil {
+			t.Errorf("expected the request with a tampered %v to be rejected", test.name)
+		}
+	}
+}
+
+func TestRequestSigningKeyRotation(t *testing.T) {
+	oldSigner, _ := NewRequestSigner(&SigningConfig{KeyID: "old", Keys: map[string]string{"old": "secret1"}})
+	newSigner, _ := NewRequestSigner(&SigningConfig{KeyID: "new", Keys: map[string]string{"new": "secret2"}})
+
+	// During a rotation, both the old and the new key are accepted
+	verifier, err := NewRequestVerifier(&SigningConfig{Keys: map[string]string{"old": "secret1", "new": "secret2"}, MaxSkew: "1m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, signer := range []*RequestSigner{oldSigner, newSigner} {
+		if err := verifier.Verify(newSignedRequest(t, signer, http.MethodGet, "https://mesh.example.org/sites", nil)); err != nil {
+			t.Errorf("expected the request signed with key %v to be valid, got %v", signer.keyID, err)
+		}
+	}
+
+	// Afterwards, the old key is rejected
+	verifier, _ = NewRequestVerifier(&SigningConfig{Keys: map[string]string{"new": "secret2"}})
+	if err := verifier.Verify(newSignedRequest(t, oldSigner, http.MethodGet, "https://mesh.example.org/sites", nil)); err == nil {
+		t.Error("expected the request signed with the retired key to be rejected")
+	}
+}
+
+func TestRequestSignerConfiguration(t *testing.T) {
+	if signer, err := NewRequestSigner(&SigningConfig{}); signer != nil || err != nil {
+		t.Errorf("expected no signer if signing is disabled, got %v (%v)", signer, err)
+	}
+	if _, err := NewRequestSigner(&SigningConfig{KeyID: "key2", Keys: map[string]string{"key1": "secret1"}}); err == nil {
+		t.Error("expected an unknown active key to be rejected")
+	}
+	if _, err := NewRequestVerifier(&SigningConfig{Keys: map[string]string{"key1": "secret1"}, MaxSkew: "soon"}); err == nil {
+		t.Error("expected an invalid maximum skew to be rejected")
+	}
+
+	// Signing with a nil signer leaves the request untouched
+	var signer *RequestSigner
+	req := newSignedRequest(t, signer, http.MethodGet, "https://mesh.example.org/sites", nil)
+	if IsRequestSigned(req) {
+		t.Error("expected the request to be unsigned")
+	}
+}
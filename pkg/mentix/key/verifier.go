@@ -0,0 +1,149 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package key
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SiteKeyHeader is the HTTP header used by sites to pass their site key.
+const SiteKeyHeader = "X-Site-Key"
+
+// SiteKeyVerifier verifies site keys using the site accounts service; successful verifications are cached for a while.
+type SiteKeyVerifier struct {
+	verifyURL     string
+	cacheDuration time.Duration
+
+	client *http.Client
+	signer *RequestSigner
+
+	verified map[string]time.Time
+	mutex    sync.Mutex
+}
+
+// Verify checks whether the given key has been issued for the specified site.
+func (verifier *SiteKeyVerifier) Verify(siteID string, apiKey APIKey) error {
+	if siteID == "" {
+		return errors.Errorf("no site specified")
+	}
+	if apiKey == "" {
+		return errors.Errorf("no site key specified")
+	}
+
+	// Reject malformed keys right away without bothering the accounts service
+	if err := VerifyAPIKey(apiKey, SaltFromSiteID(siteID)); err != nil {
+		return err
+	}
+
+	cacheKey := SaltFromSiteID(siteID) + ":" + apiKey
+	if verifier.isCached(cacheKey) {
+		return nil
+	}
+
+	if err := verifier.verifyRemotely(siteID, apiKey); err != nil {
+		return err
+	}
+
+	verifier.cache(cacheKey)
+	return nil
+}
+
+func (verifier *SiteKeyVerifier) verifyRemotely(siteID string, apiKey APIKey) error {
+	data, err := json.Marshal(map[string]string{"site": siteID, "key": apiKey})
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal the site key")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, verifier.verifyURL, bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "unable to create HTTP request")
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	if err := verifier.signer.Sign(req, data); err != nil {
+		return errors.Wrap(err, "unable to sign the site key verification request")
+	}
+
+	resp, err := verifier.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "unable to reach the site key verification endpoint")
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	result := struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return errors.Errorf("invalid response from the site key verification endpoint: %v", resp.Status)
+	}
+
+	if resp.StatusCode != http.StatusOK || !result.Success {
+		return errors.Errorf("the site key was rejected: %v", result.Error)
+	}
+	return nil
+}
+
+func (verifier *SiteKeyVerifier) isCached(cacheKey string) bool {
+	verifier.mutex.Lock()
+	defer verifier.mutex.Unlock()
+
+	expiry, ok := verifier.verified[cacheKey]
+	return ok && time.Now().Before(expiry)
+}
+
+func (verifier *SiteKeyVerifier) cache(cacheKey string) {
+	verifier.mutex.Lock()
+	defer verifier.mutex.Unlock()
+
+	// Drop expired entries so that rotated keys don't pile up
+	now := time.Now()
+	for k, expiry := range verifier.verified {
+		if now.After(expiry) {
+			delete(verifier.verified, k)
+		}
+	}
+
+	verifier.verified[cacheKey] = now.Add(verifier.cacheDuration)
+}
+
+// NewSiteKeyVerifier creates a new site key verifier using the given verification URL of the site accounts service; if a signer is given, the verification requests are signed.
+func NewSiteKeyVerifier(verifyURL string, cacheDuration time.Duration, signer *RequestSigner) (*SiteKeyVerifier, error) {
+	verifyURL = strings.TrimSpace(verifyURL)
+	if verifyURL == "" {
+		return nil, errors.Errorf("no verification URL specified")
+	}
+
+	return &SiteKeyVerifier{
+		verifyURL:     verifyURL,
+		cacheDuration: cacheDuration,
+		client:        &http.Client{Timeout: 10 * time.Second},
+		signer:        signer,
+		verified:      make(map[string]time.Time),
+	}, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package key
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSiteKeyVerifier(t *testing.T) {
+	validKey, err := GenerateAPIKey(SaltFromSiteID("SITE-A"), FlagScienceMesh)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		data := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&data)
+		if data["site"] == "SITE-A" && data["key"] == validKey {
+			_, _ = w.Write([]byte(`{"success": true}`))
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"success": false, "error": "key verification failed"}`))
+		}
+	}))
+	defer server.Close()
+
+	verifier, err := NewSiteKeyVerifier(server.URL, time.Minute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifier.Verify("SITE-A", validKey); err != nil {
+		t.Errorf("expected the key to be valid, got %v", err)
+	}
+	if err := verifier.Verify("site-a", validKey); err != nil {
+		t.Errorf("expected the cached key to be valid, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected a single verification request, got %d", calls)
+	}
+
+	// A well-formed key that hasn't been issued is rejected by the accounts service
+	otherKey, _ := GenerateAPIKey(SaltFromSiteID("SITE-A"), FlagScienceMesh)
+	if err := verifier.Verify("SITE-A", otherKey); err == nil {
+		t.Error("expected an unknown key to be rejected")
+	}
+
+	// Malformed keys and keys of other sites are rejected without asking the accounts service
+	calls = 0
+	if err := verifier.Verify("SITE-B", validKey); err == nil {
+		t.Error("expected the key of another site to be rejected")
+	}
+	if err := verifier.Verify("SITE-A", "invalid"); err == nil {
+		t.Error("expected a malformed key to be rejected")
+	}
+	if calls != 0 {
+		t.Errorf("expected no verification requests, got %d", calls)
+	}
+}
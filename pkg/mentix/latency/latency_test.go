@@ -0,0 +1,81 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package latency
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatrixAggregation(t *testing.T) {
+	matrix := NewMatrix(0)
+	reports := []*Report{
+		{Site: "A", Measurements: []*Measurement{{Target: "B", Available: true, RTT: 10}, {Target: "C", Available: false}}},
+		{Site: "A", Measurements: []*Measurement{{Target: "B", Available: true, RTT: 30}, {Target: "A", Available: true, RTT: 1}}},
+	}
+	for _, r := range reports {
+		if err := matrix.AddReport(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	links := matrix.Links()
+	if len(links) != 2 {
+		t.Fatalf("expected 2 links, got %d", len(links))
+	}
+	if l := links[0]; l.Target != "B" || l.Probes != 2 || l.AverageRTT != 20 || l.LastRTT != 30 || l.Availability != 1 {
+		t.Errorf("unexpected link A->B: %+v", l)
+	}
+	if l := links[1]; l.Target != "C" || l.Available || l.Availability != 0 {
+		t.Errorf("unexpected link A->C: %+v", l)
+	}
+
+	if err := matrix.AddReport(&Report{}); err == nil {
+		t.Error("expected an error for a report without site")
+	}
+}
+
+func TestMatrixExpiry(t *testing.T) {
+	matrix := NewMatrix(time.Nanosecond)
+	_ = matrix.AddReport(&Report{Site: "A", Measurements: []*Measurement{{Target: "B", Available: true}}})
+	time.Sleep(time.Millisecond)
+	if links := matrix.Links(); len(links) != 0 {
+		t.Errorf("expected expired links to be dropped, got %d", len(links))
+	}
+}
+
+func TestProberSubsetRotation(t *testing.T) {
+	targets := []Target{{Site: "A"}, {Site: "B"}, {Site: "C"}}
+	prober, err := NewProber("X", "", "https://mentix/latency", targets, 2, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen []string
+	for i := 0; i < 3; i++ {
+		for _, target := range prober.nextTargets() {
+			seen = append(seen, target.Site)
+		}
+	}
+	expected := []string{"A", "B", "C", "A", "B", "C"}
+	for i := range expected {
+		if seen[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, seen)
+		}
+	}
+}
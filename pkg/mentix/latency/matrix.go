@@ -0,0 +1,174 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package latency
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Matrix aggregates the measurements reported by all sites into a site-to-site matrix.
+type Matrix struct {
+	links  map[string]map[string]*link
+	maxAge time.Duration
+
+	locker sync.RWMutex
+}
+
+type link struct {
+	probes     int
+	successes  int
+	totalRTT   float64
+	lastRTT    float64
+	available  bool
+	lastSeen   time.Time
+	lastUpdate time.Time
+}
+
+var (
+	measurements = NewMatrix(0)
+)
+
+// Measurements returns the matrix shared by the latency importer and exporter.
+func Measurements() *Matrix {
+	return measurements
+}
+
+// NewMatrix creates a new matrix; links that haven't been updated for longer than maxAge are dropped (0 disables expiry).
+func NewMatrix(maxAge time.Duration) *Matrix {
+	return &Matrix{
+		links:  make(map[string]map[string]*link),
+		maxAge: maxAge,
+	}
+}
+
+// SetMaxAge sets the age after which links that haven't been updated are dropped.
+func (matrix *Matrix) SetMaxAge(maxAge time.Duration) {
+	matrix.locker.Lock()
+	defer matrix.locker.Unlock()
+
+	matrix.maxAge = maxAge
+}
+
+// AddReport merges the measurements of a report into the matrix.
+func (matrix *Matrix) AddReport(report *Report) error {
+	source := strings.TrimSpace(report.Site)
+	if source == "" {
+		return fmt.Errorf("no reporting site specified")
+	}
+
+	matrix.locker.Lock()
+	defer matrix.locker.Unlock()
+
+	targets, ok := matrix.links[source]
+	if !ok {
+		targets = make(map[string]*link)
+		matrix.links[source] = targets
+	}
+
+	now := time.Now()
+	for _, m := range report.Measurements {
+		target := strings.TrimSpace(m.Target)
+		if target == "" || target == source {
+			continue
+		}
+
+		l, ok := targets[target]
+		if !ok {
+			l = &link{}
+			targets[target] = l
+		}
+
+		l.probes++
+		l.available = m.Available
+		if m.Available {
+			l.successes++
+			l.totalRTT += m.RTT
+			l.lastRTT = m.RTT
+		}
+		l.lastSeen = m.Timestamp
+		if l.lastSeen.IsZero() {
+			l.lastSeen = now
+		}
+		l.lastUpdate = now
+	}
+
+	return nil
+}
+
+// Links returns the aggregated links of the matrix, sorted by source and target.
+func (matrix *Matrix) Links() []*Link {
+	matrix.expire()
+
+	matrix.locker.RLock()
+	defer matrix.locker.RUnlock()
+
+	links := make([]*Link, 0, len(matrix.links))
+	for source, targets := range matrix.links {
+		for target, l := range targets {
+			entry := &Link{
+				Source:    source,
+				Target:    target,
+				Probes:    l.probes,
+				LastRTT:   l.lastRTT,
+				Available: l.available,
+				LastSeen:  l.lastSeen,
+			}
+			if l.probes > 0 {
+				entry.Availability = float64(l.successes) / float64(l.probes)
+			}
+			if l.successes > 0 {
+				entry.AverageRTT = l.totalRTT / float64(l.successes)
+			}
+			links = append(links, entry)
+		}
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].Source != links[j].Source {
+			return links[i].Source < links[j].Source
+		}
+		return links[i].Target < links[j].Target
+	})
+	return links
+}
+
+func (matrix *Matrix) expire() {
+	matrix.locker.Lock()
+	defer matrix.locker.Unlock()
+
+	if matrix.maxAge <= 0 {
+		return
+	}
+
+	deadline := time.Now().Add(-matrix.maxAge)
+	for source, targets := range matrix.links {
+		for target, l := range targets {
+			if l.lastUpdate.Before(deadline) {
+				delete(targets, target)
+			}
+		}
+		if len(targets) == 0 {
+			delete(matrix.links, source)
+		}
+	}
+}
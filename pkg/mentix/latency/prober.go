@@ -0,0 +1,158 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package latency
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/mentix/key"
+)
+
+// Target describes a peer endpoint that should be probed.
+type Target struct {
+	Site     string `mapstructure:"site"`
+	Endpoint string `mapstructure:"endpoint"`
+}
+
+// Prober periodically probes a subset of peer endpoints and pushes the results to Mentix.
+type Prober struct {
+	site      string
+	siteKey   string
+	reportURL string
+	targets   []Target
+	subset    int
+
+	client *http.Client
+	offset int
+}
+
+// NewProber creates a new prober for the given site; if subset is positive, only that many targets are probed per round.
+// If a site key is given, it is passed along with each report so that Mentix can verify the origin of the report.
+func NewProber(site string, siteKey string, reportURL string, targets []Target, subset int, timeout time.Duration) (*Prober, error) {
+	if site == "" {
+		return nil, fmt.Errorf("no site specified")
+	}
+
+	u, err := url.Parse(reportURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid report URL: %v", err)
+	}
+	params := u.Query()
+	params.Set("action", "report")
+	params.Set("site", site)
+	u.RawQuery = params.Encode()
+
+	return &Prober{
+		site:      site,
+		siteKey:   siteKey,
+		reportURL: u.String(),
+		targets:   targets,
+		subset:    subset,
+		client:    &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Probe performs a single probing round and returns the resulting report.
+func (prober *Prober) Probe(ctx context.Context) *Report {
+	report := &Report{Site: prober.site}
+	for _, target := range prober.nextTargets() {
+		report.Measurements = append(report.Measurements, prober.probeTarget(ctx, target))
+	}
+	return report
+}
+
+// Push sends the report to Mentix.
+func (prober *Prober) Push(ctx context.Context, report *Report) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("unable to marshal the report: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, prober.reportURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if prober.siteKey != "" {
+		req.Header.Set(key.SiteKeyHeader, prober.siteKey)
+	}
+	ctxpkg.AddRequestIDHeader(ctx, req.Header)
+
+	resp, err := prober.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to push the report: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("pushing the report failed: %v (%v)", resp.Status, string(body))
+	}
+	return nil
+}
+
+// nextTargets returns the targets to probe in the next round; if only a subset is probed, the targets are rotated so that all peers get covered over time.
+func (prober *Prober) nextTargets() []Target {
+	if prober.subset <= 0 || prober.subset >= len(prober.targets) {
+		return prober.targets
+	}
+
+	targets := make([]Target, 0, prober.subset)
+	for i := 0; i < prober.subset; i++ {
+		targets = append(targets, prober.targets[(prober.offset+i)%len(prober.targets)])
+	}
+	prober.offset = (prober.offset + prober.subset) % len(prober.targets)
+	return targets
+}
+
+func (prober *Prober) probeTarget(ctx context.Context, target Target) *Measurement {
+	m := &Measurement{
+		Target:    target.Site,
+		Endpoint:  target.Endpoint,
+		Timestamp: time.Now(),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target.Endpoint, nil)
+	if err != nil {
+		return m
+	}
+
+	start := time.Now()
+	resp, err := prober.client.Do(req)
+	if err != nil {
+		return m
+	}
+	_ = resp.Body.Close()
+
+	// Any response that isn't a server error means that the endpoint is reachable
+	m.Available = resp.StatusCode < http.StatusInternalServerError
+	if m.Available {
+		m.RTT = float64(time.Since(start).Microseconds()) / 1000.0
+	}
+	return m
+}
@@ -0,0 +1,48 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package latency
+
+import "time"
+
+// Measurement holds the result of probing a single peer endpoint.
+type Measurement struct {
+	Target    string    `json:"target"`
+	Endpoint  string    `json:"endpoint"`
+	Available bool      `json:"available"`
+	RTT       float64   `json:"rtt"` // In milliseconds
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Report is sent by a probing site to Mentix and contains all measurements of a probing round.
+type Report struct {
+	Site         string         `json:"site"`
+	Measurements []*Measurement `json:"measurements"`
+}
+
+// Link holds the aggregated measurements between two sites.
+type Link struct {
+	Source       string    `json:"source"`
+	Target       string    `json:"target"`
+	Probes       int       `json:"probes"`
+	Availability float64   `json:"availability"` // Ratio of successful probes, 0..1
+	LastRTT      float64   `json:"lastRTT"`
+	AverageRTT   float64   `json:"averageRTT"`
+	Available    bool      `json:"available"`
+	LastSeen     time.Time `json:"lastSeen"`
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/cs3org/reva/pkg/mentix/exchangers"
 	"github.com/cs3org/reva/pkg/mentix/exchangers/exporters"
 	"github.com/cs3org/reva/pkg/mentix/exchangers/importers"
+	"github.com/cs3org/reva/pkg/mentix/key"
 	"github.com/cs3org/reva/pkg/mentix/meshdata"
 )
 
@@ -47,6 +48,8 @@ type Mentix struct {
 
 	meshDataSet meshdata.Map
 
+	requestVerifier *key.RequestVerifier
+
 	updateInterval time.Duration
 }
 
@@ -75,6 +78,15 @@ func (mntx *Mentix) initialize(conf *config.Configuration, log *zerolog.Logger)
 		return fmt.Errorf("unable to initialize exchangers: %v", err)
 	}
 
+	// Verify signed requests if signing keys have been configured
+	if conf.Signing.IsEnabled() {
+		verifier, err := key.NewRequestVerifier(&conf.Signing.SigningConfig)
+		if err != nil {
+			return fmt.Errorf("unable to create the request verifier: %v", err)
+		}
+		mntx.requestVerifier = verifier
+	}
+
 	// Get the update interval
 	duration, err := time.ParseDuration(mntx.conf.UpdateInterval)
 	if err != nil {
@@ -298,6 +310,13 @@ func (mntx *Mentix) RequestHandler(w http.ResponseWriter, r *http.Request) {
 
 	log := appctx.GetLogger(r.Context())
 
+	if err := mntx.verifyRequest(r); err != nil {
+		log.Warn().Err(err).Str("path", r.URL.Path).Msg("rejected unverified request")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(fmt.Sprintf("request verification failed: %v", err)))
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		mntx.handleRequest(mntx.GetRequestExporters(), w, r, log)
@@ -310,6 +329,23 @@ func (mntx *Mentix) RequestHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (mntx *Mentix) verifyRequest(r *http.Request) error {
+	if mntx.requestVerifier == nil {
+		return nil
+	}
+
+	// Signed requests are always verified, while unsigned ones are only rejected by endpoints requiring signatures
+	if key.IsRequestSigned(r) {
+		return mntx.requestVerifier.Verify(r)
+	}
+	for _, endpoint := range mntx.conf.Signing.Endpoints {
+		if r.URL.Path == endpoint {
+			return fmt.Errorf("the endpoint only accepts signed requests")
+		}
+	}
+	return nil
+}
+
 func (mntx *Mentix) handleRequest(exchangers []exchangers.RequestExchanger, w http.ResponseWriter, r *http.Request, log *zerolog.Logger) {
 	// Ask each RequestExchanger if it wants to handle the request
 	for _, exchanger := range exchangers {
@@ -32,6 +32,8 @@ const (
 	EndpointOCM = "OCM"
 	// EndpointMeshDir identifies the Mesh Directory endpoint
 	EndpointMeshDir = "MESHDIR"
+	// EndpointSharees identifies the endpoint searching the users of a site for federated shares
+	EndpointSharees = "SHAREES"
 )
 
 // GetServiceEndpoints returns an array of all service endpoint identifiers.
@@ -44,5 +46,6 @@ func GetServiceEndpoints() []string {
 		EndpointWebdav,
 		EndpointOCM,
 		EndpointMeshDir,
+		EndpointSharees,
 	}
 }
@@ -0,0 +1,191 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ledger accounts the daily API usage reported by all sites.
+type Ledger struct {
+	sites  map[string]map[string]Counters
+	maxAge time.Duration
+	file   string
+
+	locker sync.RWMutex
+}
+
+var (
+	ledger = NewLedger(0)
+)
+
+// Usage returns the ledger shared by the usage importer and exporter.
+func Usage() *Ledger {
+	return ledger
+}
+
+// NewLedger creates a new ledger; days older than maxAge are dropped (0 disables expiry).
+func NewLedger(maxAge time.Duration) *Ledger {
+	return &Ledger{
+		sites:  make(map[string]map[string]Counters),
+		maxAge: maxAge,
+	}
+}
+
+// SetMaxAge sets the age after which the usage of a day is dropped.
+func (ledger *Ledger) SetMaxAge(maxAge time.Duration) {
+	ledger.locker.Lock()
+	defer ledger.locker.Unlock()
+
+	ledger.maxAge = maxAge
+}
+
+// SetFile makes the ledger persist the usage in the given file, reading the usage stored in it first.
+func (ledger *Ledger) SetFile(file string) error {
+	ledger.locker.Lock()
+	defer ledger.locker.Unlock()
+
+	ledger.file = file
+	if file == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to read the usage file: %v", err)
+	}
+
+	sites := make(map[string]map[string]Counters)
+	if err := json.Unmarshal(data, &sites); err != nil {
+		return fmt.Errorf("unable to decode the usage file: %v", err)
+	}
+	ledger.sites = sites
+	return nil
+}
+
+// AddReport stores the daily usage of a report, replacing the usage previously reported for the same days.
+func (ledger *Ledger) AddReport(report *Report) error {
+	site := strings.TrimSpace(report.Site)
+	if site == "" {
+		return fmt.Errorf("no reporting site specified")
+	}
+
+	// Validate the entire report first, so that it is either stored completely or not at all
+	for _, usage := range report.Usage {
+		if _, err := time.Parse(DateLayout, usage.Date); err != nil {
+			return fmt.Errorf("invalid date %v: %v", usage.Date, err)
+		}
+		if !usage.Counters.isValid() {
+			return fmt.Errorf("negative usage reported for %v", usage.Date)
+		}
+	}
+
+	ledger.locker.Lock()
+	defer ledger.locker.Unlock()
+
+	days, ok := ledger.sites[site]
+	if !ok {
+		days = make(map[string]Counters)
+		ledger.sites[site] = days
+	}
+	for _, usage := range report.Usage {
+		days[usage.Date] = usage.Counters
+	}
+
+	ledger.expire()
+	return ledger.save()
+}
+
+// Records returns the usage of the given site (or of all sites if empty) between the two days (inclusive), sorted by site and day.
+// An empty bound leaves the range open.
+func (ledger *Ledger) Records(siteID string, from, to string) []*Record {
+	ledger.locker.RLock()
+	defer ledger.locker.RUnlock()
+
+	records := make([]*Record, 0)
+	for site, days := range ledger.sites {
+		if siteID != "" && !strings.EqualFold(site, siteID) {
+			continue
+		}
+
+		// Days are formatted as YYYY-MM-DD, so they can be compared as strings
+		for date, counters := range days {
+			if (from != "" && date < from) || (to != "" && date > to) {
+				continue
+			}
+			records = append(records, &Record{Site: site, Date: date, Counters: counters})
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Site != records[j].Site {
+			return records[i].Site < records[j].Site
+		}
+		return records[i].Date < records[j].Date
+	})
+	return records
+}
+
+func (ledger *Ledger) expire() {
+	if ledger.maxAge <= 0 {
+		return
+	}
+
+	deadline := time.Now().Add(-ledger.maxAge).Format(DateLayout)
+	for site, days := range ledger.sites {
+		for date := range days {
+			if date < deadline {
+				delete(days, date)
+			}
+		}
+		if len(days) == 0 {
+			delete(ledger.sites, site)
+		}
+	}
+}
+
+func (ledger *Ledger) save() error {
+	if ledger.file == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(ledger.sites)
+	if err != nil {
+		return fmt.Errorf("unable to encode the usage: %v", err)
+	}
+
+	// Write to a temporary file first, so that a failed write doesn't destroy the stored usage
+	tmpFile := ledger.file + ".tmp"
+	if err := ioutil.WriteFile(tmpFile, data, 0600); err != nil {
+		return fmt.Errorf("unable to write the usage file: %v", err)
+	}
+	if err := os.Rename(tmpFile, ledger.file); err != nil {
+		return fmt.Errorf("unable to write the usage file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package usage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLedgerReports(t *testing.T) {
+	ledger := NewLedger(0)
+	reports := []*Report{
+		{Site: "A", Usage: []*DailyUsage{{Date: "2021-03-01", Counters: Counters{SharesSent: 1, Requests: 10}}, {Date: "2021-03-02", Counters: Counters{Errors: 2}}}},
+		{Site: "B", Usage: []*DailyUsage{{Date: "2021-03-01", Counters: Counters{SharesReceived: 3}}}},
+		// Reporting a day again replaces its totals
+		{Site: "A", Usage: []*DailyUsage{{Date: "2021-03-01", Counters: Counters{SharesSent: 4, Requests: 20}}}},
+	}
+	for _, r := range reports {
+		if err := ledger.AddReport(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records := ledger.Records("", "", "")
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+	if r := records[0]; r.Site != "A" || r.Date != "2021-03-01" || r.SharesSent != 4 || r.Requests != 20 {
+		t.Errorf("unexpected record: %+v", r)
+	}
+	if r := records[2]; r.Site != "B" || r.SharesReceived != 3 {
+		t.Errorf("unexpected record: %+v", r)
+	}
+
+	if records := ledger.Records("a", "2021-03-02", "2021-03-31"); len(records) != 1 || records[0].Errors != 2 {
+		t.Errorf("unexpected records in range: %+v", records)
+	}
+	if records := ledger.Records("B", "", "2021-02-28"); len(records) != 0 {
+		t.Errorf("expected no records, got %d", len(records))
+	}
+
+	invalid := []*Report{
+		{},
+		{Site: "A", Usage: []*DailyUsage{{Date: "03/01/2021"}}},
+		{Site: "A", Usage: []*DailyUsage{{Date: "2021-03-03"}, {Date: "2021-03-04", Counters: Counters{Errors: -1}}}},
+	}
+	for _, r := range invalid {
+		if err := ledger.AddReport(r); err == nil {
+			t.Errorf("expected an error for report %+v", r)
+		}
+	}
+	if records := ledger.Records("A", "2021-03-03", ""); len(records) != 0 {
+		t.Errorf("an invalid report was stored partially: %+v", records)
+	}
+}
+
+func TestLedgerExpiry(t *testing.T) {
+	ledger := NewLedger(48 * time.Hour)
+	old := time.Now().AddDate(0, 0, -5).Format(DateLayout)
+	today := time.Now().Format(DateLayout)
+	_ = ledger.AddReport(&Report{Site: "A", Usage: []*DailyUsage{{Date: old}, {Date: today}}})
+	if records := ledger.Records("", "", ""); len(records) != 1 || records[0].Date != today {
+		t.Errorf("expected old days to be dropped, got %+v", records)
+	}
+}
+
+func TestLedgerPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "usage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "usage.json")
+
+	ledger := NewLedger(0)
+	if err := ledger.SetFile(file); err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.AddReport(&Report{Site: "A", Usage: []*DailyUsage{{Date: "2021-03-01", Counters: Counters{BytesTransferred: 1024}}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewLedger(0)
+	if err := reloaded.SetFile(file); err != nil {
+		t.Fatal(err)
+	}
+	if records := reloaded.Records("A", "", ""); len(records) != 1 || records[0].BytesTransferred != 1024 {
+		t.Errorf("unexpected records after reloading: %+v", records)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package usage
+
+// DateLayout is the layout of the days the usage is accounted for.
+const DateLayout = "2006-01-02"
+
+// Counters holds the API usage of a site.
+type Counters struct {
+	SharesSent       int64 `json:"sharesSent"`
+	SharesReceived   int64 `json:"sharesReceived"`
+	BytesTransferred int64 `json:"bytesTransferred"`
+	Requests         int64 `json:"requests"`
+	Errors           int64 `json:"errors"`
+}
+
+// Add adds the given counters.
+func (counters *Counters) Add(other Counters) {
+	counters.SharesSent += other.SharesSent
+	counters.SharesReceived += other.SharesReceived
+	counters.BytesTransferred += other.BytesTransferred
+	counters.Requests += other.Requests
+	counters.Errors += other.Errors
+}
+
+func (counters *Counters) isValid() bool {
+	return counters.SharesSent >= 0 && counters.SharesReceived >= 0 && counters.BytesTransferred >= 0 && counters.Requests >= 0 && counters.Errors >= 0
+}
+
+// DailyUsage holds the usage totals of a site for a single day.
+type DailyUsage struct {
+	Date string `json:"date"`
+	Counters
+}
+
+// Report is sent by a site to Mentix and contains the usage totals of one or more days; reporting a day again replaces its totals.
+type Report struct {
+	Site  string        `json:"site"`
+	Usage []*DailyUsage `json:"usage"`
+}
+
+// Record holds the usage of a site during a single day.
+type Record struct {
+	Site string `json:"site"`
+	Date string `json:"date"`
+	Counters
+}
@@ -41,6 +41,11 @@ type BasicAuth struct {
 	Password string
 }
 
+// RequestSigner signs outgoing requests.
+type RequestSigner interface {
+	Sign(req *http.Request, body []byte) error
+}
+
 // GenerateURL creates a URL object from a host, path and optional parameters.
 func GenerateURL(host string, path string, params URLParams) (*url.URL, error) {
 	fullURL, err := url.Parse(host)
@@ -65,7 +70,7 @@ func GenerateURL(host string, path string, params URLParams) (*url.URL, error) {
 	return fullURL, nil
 }
 
-func queryEndpoint(method string, endpointURL *url.URL, auth *BasicAuth, checkStatus bool) ([]byte, error) {
+func queryEndpoint(method string, endpointURL *url.URL, auth *BasicAuth, signer RequestSigner, checkStatus bool) ([]byte, error) {
 	// Prepare the request
 	req, err := http.NewRequest(method, endpointURL.String(), nil)
 	if err != nil {
@@ -76,6 +81,12 @@ func queryEndpoint(method string, endpointURL *url.URL, auth *BasicAuth, checkSt
 		req.SetBasicAuth(auth.User, auth.Password)
 	}
 
+	if signer != nil {
+		if err := signer.Sign(req, nil); err != nil {
+			return nil, fmt.Errorf("unable to sign HTTP request: %v", err)
+		}
+	}
+
 	// Fetch the data and read the body
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -93,12 +104,17 @@ func queryEndpoint(method string, endpointURL *url.URL, auth *BasicAuth, checkSt
 
 // ReadEndpoint reads data from an HTTP endpoint via GET.
 func ReadEndpoint(endpointURL *url.URL, auth *BasicAuth, checkStatus bool) ([]byte, error) {
-	return queryEndpoint(http.MethodGet, endpointURL, auth, checkStatus)
+	return queryEndpoint(http.MethodGet, endpointURL, auth, nil, checkStatus)
+}
+
+// ReadSignedEndpoint reads data from an HTTP endpoint via GET, signing the request using the given signer.
+func ReadSignedEndpoint(endpointURL *url.URL, signer RequestSigner, checkStatus bool) ([]byte, error) {
+	return queryEndpoint(http.MethodGet, endpointURL, nil, signer, checkStatus)
 }
 
 // WriteEndpoint sends data to an HTTP endpoint via POST.
 func WriteEndpoint(endpointURL *url.URL, auth *BasicAuth, checkStatus bool) ([]byte, error) {
-	return queryEndpoint(http.MethodPost, endpointURL, auth, checkStatus)
+	return queryEndpoint(http.MethodPost, endpointURL, auth, nil, checkStatus)
 }
 
 // CreateResponse creates a generic HTTP response in JSON format.
@@ -0,0 +1,303 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package migrate provides versioned schema migrations for the SQL backed drivers.
+// Every driver registers the migrations of its schema under a component name; the
+// version each component is at is recorded in the reva_schema_migrations table.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/sqldb"
+	"github.com/pkg/errors"
+)
+
+const (
+	versionTable = "reva_schema_migrations"
+	// lockTimeout is the number of seconds to wait for other replicas migrating the same component.
+	lockTimeout = 300
+)
+
+// Migration describes a single schema change.
+type Migration struct {
+	Version     int
+	Description string
+	// Up holds the statements that apply the migration.
+	Up []string
+	// Down holds the statements that revert the migration.
+	Down []string
+	// SQLiteUp and SQLiteDown replace Up and Down on SQLite databases, where
+	// the MySQL dialect is not understood.
+	SQLiteUp   []string
+	SQLiteDown []string
+	// PostgresUp and PostgresDown replace Up and Down on PostgreSQL databases.
+	PostgresUp   []string
+	PostgresDown []string
+}
+
+func (m Migration) up(engine string) []string {
+	if engine == sqldb.SQLite && m.SQLiteUp != nil {
+		return m.SQLiteUp
+	}
+	if engine == sqldb.Postgres && m.PostgresUp != nil {
+		return m.PostgresUp
+	}
+	return m.Up
+}
+
+func (m Migration) down(engine string) []string {
+	if engine == sqldb.SQLite && m.SQLiteDown != nil {
+		return m.SQLiteDown
+	}
+	if engine == sqldb.Postgres && m.PostgresDown != nil {
+		return m.PostgresDown
+	}
+	return m.Down
+}
+
+var (
+	registry = map[string][]Migration{}
+	mutex    sync.RWMutex
+)
+
+// Register registers the migrations of a component. Versions must be positive and unique within the component.
+func Register(component string, migrations ...Migration) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	all := append(append([]Migration{}, registry[component]...), migrations...)
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	for i, m := range all {
+		if m.Version <= 0 || (i > 0 && all[i-1].Version == m.Version) {
+			panic(fmt.Sprintf("migrate: invalid or duplicate version %d for component %s", m.Version, component))
+		}
+	}
+	registry[component] = all
+}
+
+// Components returns the names of all components with registered migrations.
+func Components() []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	components := make([]string, 0, len(registry))
+	for c := range registry {
+		components = append(components, c)
+	}
+	sort.Strings(components)
+	return components
+}
+
+// Latest returns the highest version registered for the component.
+func Latest(component string) int {
+	migrations := get(component)
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+func get(component string) []Migration {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return registry[component]
+}
+
+// Current returns the versions the components are at in the database; components without any applied migration are omitted.
+func Current(ctx context.Context, db *sql.DB) (map[string]int, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "migrate: error connecting to the database")
+	}
+	defer conn.Close()
+
+	if err := ensureVersionTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT component, version FROM "+versionTable)
+	if err != nil {
+		return nil, errors.Wrap(err, "migrate: error reading the schema versions")
+	}
+	defer rows.Close()
+
+	versions := map[string]int{}
+	for rows.Next() {
+		var component string
+		var version int
+		if err := rows.Scan(&component, &version); err != nil {
+			return nil, err
+		}
+		versions[component] = version
+	}
+	return versions, rows.Err()
+}
+
+// Up applies all pending migrations of the component.
+func Up(ctx context.Context, db *sql.DB, component string) error {
+	return To(ctx, db, component, Latest(component))
+}
+
+// To migrates the component up or down to the given version. Concurrent runs from
+// several replicas are serialized using a database lock, so only one of them applies the changes.
+func To(ctx context.Context, db *sql.DB, component string, target int) error {
+	migrations := get(component)
+	if target < 0 || target > Latest(component) {
+		return fmt.Errorf("migrate: unknown version %d for component %s", target, component)
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "migrate: error connecting to the database")
+	}
+	defer conn.Close()
+
+	engine := sqldb.Engine(db)
+	if err := lock(ctx, conn, engine, component); err != nil {
+		return err
+	}
+	defer unlock(conn, engine, component)
+
+	if err := ensureVersionTable(ctx, conn); err != nil {
+		return err
+	}
+
+	current, err := currentVersion(ctx, conn, engine, component)
+	if err != nil {
+		return err
+	}
+	if current > Latest(component) {
+		return fmt.Errorf("migrate: the schema of %s is at version %d, which is newer than the supported version %d", component, current, Latest(component))
+	}
+
+	if target >= current {
+		for _, m := range migrations {
+			if m.Version <= current || m.Version > target {
+				continue
+			}
+			if err := apply(ctx, conn, engine, component, m.up(engine), m.Version); err != nil {
+				return errors.Wrapf(err, "migrate: error applying migration %d (%s) of %s", m.Version, m.Description, component)
+			}
+		}
+		return nil
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version > current || m.Version <= target {
+			continue
+		}
+		previous := 0
+		if i > 0 {
+			previous = migrations[i-1].Version
+		}
+		if err := apply(ctx, conn, engine, component, m.down(engine), previous); err != nil {
+			return errors.Wrapf(err, "migrate: error reverting migration %d (%s) of %s", m.Version, m.Description, component)
+		}
+	}
+	return nil
+}
+
+func apply(ctx context.Context, conn *sql.Conn, engine, component string, stmts []string, version int) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	query := "INSERT INTO " + versionTable + " (component, version, applied_at) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE version=VALUES(version), applied_at=VALUES(applied_at)"
+	if engine == sqldb.SQLite || engine == sqldb.Postgres {
+		query = "INSERT INTO " + versionTable + " (component, version, applied_at) VALUES (?, ?, ?) ON CONFLICT(component) DO UPDATE SET version=excluded.version, applied_at=excluded.applied_at"
+	}
+	if _, err := tx.ExecContext(ctx, sqldb.RebindEngine(engine, query), component, version, time.Now().Unix()); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func ensureVersionTable(ctx context.Context, conn *sql.Conn) error {
+	query := "CREATE TABLE IF NOT EXISTS " + versionTable + " (component VARCHAR(64) NOT NULL PRIMARY KEY, version INT NOT NULL, applied_at BIGINT NOT NULL)"
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return errors.Wrap(err, "migrate: error creating the schema version table")
+	}
+	return nil
+}
+
+func currentVersion(ctx context.Context, conn *sql.Conn, engine, component string) (int, error) {
+	var version int
+	err := conn.QueryRowContext(ctx, sqldb.RebindEngine(engine, "SELECT version FROM "+versionTable+" WHERE component=?"), component).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "migrate: error reading the schema version")
+	}
+	return version, nil
+}
+
+func lockName(component string) string {
+	return "reva_migrate_" + component
+}
+
+func lock(ctx context.Context, conn *sql.Conn, engine, component string) error {
+	if engine == sqldb.SQLite {
+		// an SQLite database is local to a single node and the transactions
+		// applying the migrations lock the whole database
+		return nil
+	}
+	if engine == sqldb.Postgres {
+		// advisory locks have no timeout of their own, so the wait is bounded by the context
+		lockCtx, cancel := context.WithTimeout(ctx, lockTimeout*time.Second)
+		defer cancel()
+		if _, err := conn.ExecContext(lockCtx, "SELECT pg_advisory_lock(hashtext($1))", lockName(component)); err != nil {
+			return errors.Wrapf(err, "migrate: error acquiring the migration lock of %s", component)
+		}
+		return nil
+	}
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName(component), lockTimeout).Scan(&acquired); err != nil {
+		return errors.Wrap(err, "migrate: error acquiring the migration lock")
+	}
+	if !acquired.Valid || acquired.Int64 != 1 {
+		return fmt.Errorf("migrate: timeout waiting for the migration lock of %s", component)
+	}
+	return nil
+}
+
+func unlock(conn *sql.Conn, engine, component string) {
+	if engine == sqldb.SQLite {
+		return
+	}
+	if engine == sqldb.Postgres {
+		_, _ = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtext($1))", lockName(component))
+		return
+	}
+	_, _ = conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName(component))
+}
@@ -0,0 +1,71 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package migrate
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/sqldb"
+)
+
+func TestRegister(t *testing.T) {
+	Register("test_component", Migration{Version: 2, Description: "second"}, Migration{Version: 1, Description: "first"})
+	Register("test_component", Migration{Version: 3, Description: "third"})
+
+	if latest := Latest("test_component"); latest != 3 {
+		t.Fatalf("expected latest version 3, got %d", latest)
+	}
+	for i, m := range get("test_component") {
+		if m.Version != i+1 {
+			t.Fatalf("expected migrations to be sorted, got version %d at position %d", m.Version, i)
+		}
+	}
+	if latest := Latest("unknown_component"); latest != 0 {
+		t.Fatalf("expected latest version 0 for an unknown component, got %d", latest)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected registering a duplicate version to panic")
+		}
+	}()
+	Register("test_component", Migration{Version: 2, Description: "duplicate"})
+}
+
+func TestDialects(t *testing.T) {
+	m := Migration{
+		Up:         []string{"mysql up"},
+		Down:       []string{"mysql down"},
+		PostgresUp: []string{"postgres up"},
+	}
+
+	if up := m.up(sqldb.MySQL); !reflect.DeepEqual(up, m.Up) {
+		t.Errorf("expected the mysql statements, got %v", up)
+	}
+	if up := m.up(sqldb.SQLite); !reflect.DeepEqual(up, m.Up) {
+		t.Errorf("expected the mysql statements as fallback on sqlite, got %v", up)
+	}
+	if up := m.up(sqldb.Postgres); !reflect.DeepEqual(up, m.PostgresUp) {
+		t.Errorf("expected the postgres statements, got %v", up)
+	}
+	if down := m.down(sqldb.Postgres); !reflect.DeepEqual(down, m.Down) {
+		t.Errorf("expected the mysql statements as fallback on postgres, got %v", down)
+	}
+}
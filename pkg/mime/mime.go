@@ -19,13 +19,21 @@
 package mime
 
 import (
+	"net/http"
 	"path"
+	"strings"
 	"sync"
 
 	gomime "github.com/cubewise-code/go-mime"
 )
 
-const defaultMimeDir = "httpd/unix-directory"
+const (
+	defaultMimeDir  = "httpd/unix-directory"
+	defaultMimeFile = "application/octet-stream"
+)
+
+// SniffLen is the number of bytes DetectContent looks at.
+const SniffLen = 512
 
 var mimes sync.Map
 
@@ -55,12 +63,32 @@ func Detect(isDir bool, fn string) string {
 	}
 
 	if mimeType == "" {
-		mimeType = "application/octet-stream"
+		mimeType = defaultMimeFile
 	}
 
 	return mimeType
 }
 
+// DetectContent returns the mimetype of a file from the magic bytes at the
+// beginning of its content, or application/octet-stream if they aren't known.
+func DetectContent(head []byte) string {
+	if len(head) > SniffLen {
+		head = head[:SniffLen]
+	}
+	mimeType := http.DetectContentType(head)
+	// drop parameters like the charset of text files
+	if i := strings.Index(mimeType, ";"); i != -1 {
+		mimeType = strings.TrimSpace(mimeType[:i])
+	}
+	return mimeType
+}
+
+// IsGeneric returns whether the mimetype doesn't tell anything about the
+// content of a file.
+func IsGeneric(mimeType string) bool {
+	return mimeType == "" || mimeType == defaultMimeFile
+}
+
 func getCustomMime(ext string) string {
 	if m, ok := mimes.Load(ext); ok {
 		return m.(string)
@@ -0,0 +1,315 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/oauthapps"
+	"github.com/cs3org/reva/pkg/oauthapps/manager/registry"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("json", New)
+}
+
+type config struct {
+	File string `mapstructure:"file"`
+}
+
+func (c *config) init() {
+	if c.File == "" {
+		c.File = "/var/tmp/reva/oauthapps.json"
+	}
+}
+
+type model struct {
+	Clients  map[string]*oauthapps.Client  `json:"clients"`
+	Codes    map[string]*oauthapps.Code    `json:"codes"`
+	Consents map[string]*oauthapps.Consent `json:"consents"`
+}
+
+type manager struct {
+	sync.Mutex
+	file string
+}
+
+// New returns an oauth apps manager storing the clients, codes and consents in
+// a JSON file. The file is read on every access, as it is shared between the
+// HTTP service handling the authorization flow and the auth provider issuing
+// the tokens.
+func New(m map[string]interface{}) (oauthapps.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	c.init()
+
+	mgr := &manager{file: c.File}
+	if _, err := os.Stat(c.File); os.IsNotExist(err) {
+		if err := mgr.save(&model{}); err != nil {
+			return nil, err
+		}
+	}
+	return mgr, nil
+}
+
+func (m *manager) load() (*model, error) {
+	data, err := ioutil.ReadFile(m.file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading the file %s", m.file)
+	}
+	db := &model{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, db); err != nil {
+			return nil, errors.Wrapf(err, "error parsing the file %s", m.file)
+		}
+	}
+	if db.Clients == nil {
+		db.Clients = map[string]*oauthapps.Client{}
+	}
+	if db.Codes == nil {
+		db.Codes = map[string]*oauthapps.Code{}
+	}
+	if db.Consents == nil {
+		db.Consents = map[string]*oauthapps.Consent{}
+	}
+	return db, nil
+}
+
+func (m *manager) save(db *model) error {
+	// expired codes can never be used, drop them
+	for hash, c := range db.Codes {
+		if c.Expired() {
+			delete(db.Codes, hash)
+		}
+	}
+	data, err := json.Marshal(db)
+	if err != nil {
+		return errors.Wrap(err, "error encoding the oauth apps")
+	}
+	if err := ioutil.WriteFile(m.file, data, 0600); err != nil {
+		return errors.Wrapf(err, "error writing the file %s", m.file)
+	}
+	return nil
+}
+
+func consentKey(clientID string, u *userpb.UserId) string {
+	return clientID + "!" + u.GetIdp() + "!" + u.GetOpaqueId()
+}
+
+func (m *manager) CreateClient(ctx context.Context, c *oauthapps.Client) error {
+	m.Lock()
+	defer m.Unlock()
+
+	db, err := m.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := db.Clients[c.ID]; ok {
+		return errtypes.AlreadyExists(c.ID)
+	}
+	db.Clients[c.ID] = c
+	return m.save(db)
+}
+
+func (m *manager) GetClient(ctx context.Context, id string) (*oauthapps.Client, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	db, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	c, ok := db.Clients[id]
+	if !ok {
+		return nil, errtypes.NotFound(id)
+	}
+	return c, nil
+}
+
+func (m *manager) ListClients(ctx context.Context) ([]*oauthapps.Client, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	db, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*oauthapps.Client, 0, len(db.Clients))
+	for _, c := range db.Clients {
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+func (m *manager) UpdateClient(ctx context.Context, c *oauthapps.Client) error {
+	m.Lock()
+	defer m.Unlock()
+
+	db, err := m.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := db.Clients[c.ID]; !ok {
+		return errtypes.NotFound(c.ID)
+	}
+	db.Clients[c.ID] = c
+	return m.save(db)
+}
+
+func (m *manager) DeleteClient(ctx context.Context, id string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	db, err := m.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := db.Clients[id]; !ok {
+		return errtypes.NotFound(id)
+	}
+	delete(db.Clients, id)
+	for hash, c := range db.Codes {
+		if c.ClientID == id {
+			delete(db.Codes, hash)
+		}
+	}
+	for key, c := range db.Consents {
+		if c.ClientID == id {
+			delete(db.Consents, key)
+		}
+	}
+	return m.save(db)
+}
+
+func (m *manager) CreateCode(ctx context.Context, c *oauthapps.Code) error {
+	m.Lock()
+	defer m.Unlock()
+
+	db, err := m.load()
+	if err != nil {
+		return err
+	}
+	db.Codes[c.Hash] = c
+	return m.save(db)
+}
+
+func (m *manager) GetCode(ctx context.Context, hash string) (*oauthapps.Code, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	db, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	c, ok := db.Codes[hash]
+	if !ok || c.Expired() {
+		return nil, errtypes.NotFound("code")
+	}
+	return c, nil
+}
+
+func (m *manager) TakeCode(ctx context.Context, hash string) (*oauthapps.Code, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	db, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	c, ok := db.Codes[hash]
+	if !ok || c.Expired() {
+		return nil, errtypes.NotFound("code")
+	}
+	delete(db.Codes, hash)
+	if err := m.save(db); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (m *manager) SetConsent(ctx context.Context, c *oauthapps.Consent) error {
+	m.Lock()
+	defer m.Unlock()
+
+	db, err := m.load()
+	if err != nil {
+		return err
+	}
+	db.Consents[consentKey(c.ClientID, c.User)] = c
+	return m.save(db)
+}
+
+func (m *manager) GetConsent(ctx context.Context, clientID string, u *userpb.UserId) (*oauthapps.Consent, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	db, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	c, ok := db.Consents[consentKey(clientID, u)]
+	if !ok {
+		return nil, errtypes.NotFound(clientID)
+	}
+	return c, nil
+}
+
+func (m *manager) ListConsents(ctx context.Context, u *userpb.UserId) ([]*oauthapps.Consent, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	db, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	list := []*oauthapps.Consent{}
+	for _, c := range db.Consents {
+		if utils.UserEqual(c.User, u) {
+			list = append(list, c)
+		}
+	}
+	return list, nil
+}
+
+func (m *manager) DeleteConsent(ctx context.Context, clientID string, u *userpb.UserId) error {
+	m.Lock()
+	defer m.Unlock()
+
+	db, err := m.load()
+	if err != nil {
+		return err
+	}
+	key := consentKey(clientID, u)
+	if _, ok := db.Consents[key]; !ok {
+		return errtypes.NotFound(clientID)
+	}
+	delete(db.Consents, key)
+	return m.save(db)
+}
@@ -0,0 +1,197 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package oauthapps
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// State is the state of a registered client.
+type State string
+
+const (
+	// StatePending means that an admin has not approved the client yet.
+	StatePending State = "pending"
+	// StateApproved means that the client can obtain tokens.
+	StateApproved State = "approved"
+	// StateRejected means that an admin refused the client.
+	StateRejected State = "rejected"
+)
+
+// Client is a third-party application registered to act on behalf of users.
+type Client struct {
+	ID           string         `json:"client_id"`
+	Name         string         `json:"client_name"`
+	RedirectURIs []string       `json:"redirect_uris"`
+	Scopes       []string       `json:"scopes"`
+	Owner        *userpb.UserId `json:"owner,omitempty"`
+	State        State          `json:"state"`
+	// TokenLifetime is the time in seconds the tokens issued to the client are valid for.
+	TokenLifetime int64  `json:"token_lifetime"`
+	CreatedAt     int64  `json:"created_at"`
+	Secret        string `json:"secret,omitempty"`
+}
+
+// SetSecret stores a hash of the secret the client authenticates with.
+func (c *Client) SetSecret(secret string) {
+	c.Secret = Hash(secret)
+}
+
+// VerifySecret checks the given secret against the one stored for the client.
+func (c *Client) VerifySecret(secret string) bool {
+	return c.Secret != "" && subtle.ConstantTimeCompare([]byte(c.Secret), []byte(Hash(secret))) == 1
+}
+
+// AllowsRedirect checks whether the URI is one of the registered redirect URIs.
+func (c *Client) AllowsRedirect(uri string) bool {
+	for _, r := range c.RedirectURIs {
+		if r == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsScopes checks whether the client registered all the given scopes.
+func (c *Client) AllowsScopes(scopes []string) bool {
+	return contains(c.Scopes, scopes)
+}
+
+// Code is an authorization code issued after the consent of a user, which the
+// client exchanges for a token.
+type Code struct {
+	// Hash is the hash of the code, which is never stored in clear.
+	Hash                string         `json:"hash"`
+	ClientID            string         `json:"client_id"`
+	User                *userpb.UserId `json:"user"`
+	Scopes              []string       `json:"scopes"`
+	RedirectURI         string         `json:"redirect_uri"`
+	CodeChallenge       string         `json:"code_challenge,omitempty"`
+	CodeChallengeMethod string         `json:"code_challenge_method,omitempty"`
+	ExpiresAt           int64          `json:"expires_at"`
+}
+
+// Expired checks whether the code can no longer be exchanged.
+func (c *Code) Expired() bool {
+	return time.Now().Unix() >= c.ExpiresAt
+}
+
+// VerifyChallenge checks the PKCE code verifier against the challenge the code was requested with.
+func (c *Code) VerifyChallenge(verifier string) bool {
+	switch c.CodeChallengeMethod {
+	case "":
+		return c.CodeChallenge == ""
+	case "plain":
+		return subtle.ConstantTimeCompare([]byte(c.CodeChallenge), []byte(verifier)) == 1
+	case "S256":
+		h := sha256.Sum256([]byte(verifier))
+		challenge := base64.RawURLEncoding.EncodeToString(h[:])
+		return subtle.ConstantTimeCompare([]byte(c.CodeChallenge), []byte(challenge)) == 1
+	}
+	return false
+}
+
+// Consent records the scopes a user allowed a client to use.
+type Consent struct {
+	ClientID  string         `json:"client_id"`
+	User      *userpb.UserId `json:"user"`
+	Scopes    []string       `json:"scopes"`
+	GrantedAt int64          `json:"granted_at"`
+}
+
+// Covers checks whether the user already consented to all the given scopes.
+func (c *Consent) Covers(scopes []string) bool {
+	return contains(c.Scopes, scopes)
+}
+
+// ParseScopes parses a space separated list of scopes, as sent by the clients.
+func ParseScopes(s string) ([]string, error) {
+	scopes := []string{}
+	for _, f := range strings.Fields(s) {
+		if !contains(scope.AppScopes, []string{f}) {
+			return nil, errtypes.BadRequest("unknown scope " + f)
+		}
+		if !contains(scopes, []string{f}) {
+			scopes = append(scopes, f)
+		}
+	}
+	return scopes, nil
+}
+
+// Hash returns the hash under which secrets and codes are stored.
+func Hash(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+func contains(set, items []string) bool {
+	for _, i := range items {
+		found := false
+		for _, s := range set {
+			if s == i {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Manager is the interface to implement to store clients, codes and consents.
+type Manager interface {
+	// CreateClient stores a newly registered client.
+	CreateClient(ctx context.Context, c *Client) error
+	// GetClient returns the client with the given ID.
+	GetClient(ctx context.Context, id string) (*Client, error)
+	// ListClients returns all the registered clients.
+	ListClients(ctx context.Context) ([]*Client, error)
+	// UpdateClient updates an existing client.
+	UpdateClient(ctx context.Context, c *Client) error
+	// DeleteClient removes a client together with its codes and consents.
+	DeleteClient(ctx context.Context, id string) error
+
+	// CreateCode stores an authorization code.
+	CreateCode(ctx context.Context, c *Code) error
+	// GetCode returns the authorization code with the given hash.
+	GetCode(ctx context.Context, hash string) (*Code, error)
+	// TakeCode returns and removes the authorization code with the given hash, so that it is used only once.
+	TakeCode(ctx context.Context, hash string) (*Code, error)
+
+	// SetConsent stores the consent of a user, replacing the previous one for the same client.
+	SetConsent(ctx context.Context, c *Consent) error
+	// GetConsent returns the consent the user gave to the client.
+	GetConsent(ctx context.Context, clientID string, u *userpb.UserId) (*Consent, error)
+	// ListConsents returns the consents given by the user.
+	ListConsents(ctx context.Context, u *userpb.UserId) ([]*Consent, error)
+	// DeleteConsent withdraws the consent the user gave to the client.
+	DeleteConsent(ctx context.Context, clientID string, u *userpb.UserId) error
+}
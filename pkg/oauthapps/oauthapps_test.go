@@ -0,0 +1,87 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package oauthapps
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	c := &Client{RedirectURIs: []string{"https://app.example.org/callback"}, Scopes: []string{"dav:read"}}
+	if c.VerifySecret("") {
+		t.Fatal("no secret must be accepted before one is set")
+	}
+	c.SetSecret("secret")
+	if c.Secret == "secret" {
+		t.Fatal("secret must not be stored in clear")
+	}
+	if !c.VerifySecret("secret") || c.VerifySecret("other") {
+		t.Fatal("secret verification failed")
+	}
+	if !c.AllowsRedirect("https://app.example.org/callback") || c.AllowsRedirect("https://app.example.org/other") {
+		t.Fatal("redirect uri verification failed")
+	}
+	if !c.AllowsScopes([]string{"dav:read"}) || c.AllowsScopes([]string{"dav:read", "shares:manage"}) {
+		t.Fatal("scope verification failed")
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	h := sha256.Sum256([]byte(verifier))
+
+	tests := []struct {
+		code     Code
+		verifier string
+		expected bool
+	}{
+		{Code{}, "", true},
+		{Code{CodeChallenge: verifier, CodeChallengeMethod: "plain"}, verifier, true},
+		{Code{CodeChallenge: verifier, CodeChallengeMethod: "plain"}, "other", false},
+		{Code{CodeChallenge: base64.RawURLEncoding.EncodeToString(h[:]), CodeChallengeMethod: "S256"}, verifier, true},
+		{Code{CodeChallenge: base64.RawURLEncoding.EncodeToString(h[:]), CodeChallengeMethod: "S256"}, "other", false},
+		{Code{CodeChallenge: verifier, CodeChallengeMethod: "unknown"}, verifier, false},
+	}
+	for i, tt := range tests {
+		if got := tt.code.VerifyChallenge(tt.verifier); got != tt.expected {
+			t.Errorf("test %d: expected %v, got %v", i, tt.expected, got)
+		}
+	}
+
+	c := &Code{ExpiresAt: time.Now().Add(-time.Second).Unix()}
+	if !c.Expired() {
+		t.Fatal("code must be expired")
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes("dav:read  shares:manage dav:read")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scopes) != 2 || scopes[0] != "dav:read" || scopes[1] != "shares:manage" {
+		t.Fatalf("unexpected scopes %v", scopes)
+	}
+	if _, err := ParseScopes("dav:write"); err == nil {
+		t.Fatal("expected an error for an unknown scope")
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package cache is a read-through cache of the resources received through OCM
+// shares. Browsing a share goes over the network to the remote provider for
+// every request, which is unusable for slow or distant providers, so the
+// metadata of the remote resources is kept in memory and the content of the
+// remote files on disk. Cached resources are used as they are for a short
+// time and validated against their remote ETags afterwards.
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Stat marks the cached metadata of a remote resource.
+	Stat = "stat"
+	// Listing marks the cached listing of a remote container.
+	Listing = "ls"
+)
+
+// Config holds the configuration of the cache.
+type Config struct {
+	Enabled     bool   `mapstructure:"enabled" docs:"false;Whether the resources received through OCM shares are cached."`
+	TTL         int    `mapstructure:"ttl" docs:"60;Seconds a cached resource is used without validating it against the remote provider."`
+	MaxEntries  int    `mapstructure:"max_entries" docs:"10000;Number of stat and listing results kept in memory."`
+	Dir         string `mapstructure:"dir" docs:";Directory the content of the remote files is cached in. The content is not cached if unset."`
+	MaxSize     int64  `mapstructure:"max_size" docs:"1073741824;Number of bytes the cached content may take up on disk."`
+	MaxFileSize int64  `mapstructure:"max_file_size" docs:"104857600;Files larger than this number of bytes are not cached."`
+}
+
+func (c *Config) init() {
+	if c.TTL <= 0 {
+		c.TTL = 60
+	}
+	if c.MaxEntries <= 0 {
+		c.MaxEntries = 10000
+	}
+	if c.MaxSize <= 0 {
+		c.MaxSize = 1 << 30
+	}
+	if c.MaxFileSize <= 0 {
+		c.MaxFileSize = 100 << 20
+	}
+	if c.MaxFileSize > c.MaxSize {
+		c.MaxFileSize = c.MaxSize
+	}
+}
+
+// Key identifies a remote resource. The token is part of it, as the same path
+// refers to different resources in different shares of a provider.
+type Key struct {
+	Domain string `json:"domain"`
+	Token  string `json:"token"`
+	Path   string `json:"path"`
+}
+
+// Transfer describes the download of a remote file passed through the
+// datagateway to be cached.
+type Transfer struct {
+	Key
+	// ETag is the current ETag of the file, the cached content is only used if it matches.
+	ETag string `json:"etag"`
+}
+
+func (k Key) clean() Key {
+	k.Path = path.Clean("/" + k.Path)
+	return k
+}
+
+func (k Key) id() string {
+	k = k.clean()
+	h := sha256.Sum256([]byte(k.Domain + "\x00" + k.Token + "\x00" + k.Path))
+	return hex.EncodeToString(h[:])
+}
+
+// Entry is a cached stat or listing result.
+type Entry struct {
+	Value interface{}
+	ETag  string
+	// Fetched is when the entry was last fetched from or validated against the remote provider.
+	Fetched time.Time
+}
+
+type entry struct {
+	mapKey string
+	kind   string
+	key    Key
+	Entry
+}
+
+// Cache caches the metadata and content of remote resources.
+type Cache struct {
+	conf *Config
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+
+	content *contentStore
+}
+
+// New returns a new cache. The content of the remote files is only cached if
+// a directory is configured.
+func New(conf *Config) (*Cache, error) {
+	conf.init()
+	c := &Cache{
+		conf:    conf,
+		ttl:     time.Duration(conf.TTL) * time.Second,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+	if conf.Dir != "" {
+		s, err := newContentStore(conf.Dir, conf.MaxSize, conf.MaxFileSize)
+		if err != nil {
+			return nil, err
+		}
+		c.content = s
+	}
+	return c, nil
+}
+
+// CachesContent tells whether the content of the remote files is cached.
+func (c *Cache) CachesContent() bool {
+	return c.content != nil
+}
+
+// Fresh tells whether an entry may be used without validating it against the
+// remote provider.
+func (c *Cache) Fresh(fetched time.Time) bool {
+	return time.Since(fetched) < c.ttl
+}
+
+// Get returns a copy of the cached entry of the given kind.
+func (c *Cache) Get(kind string, k Key) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[kind+":"+k.id()]
+	if !ok {
+		return Entry{}, false
+	}
+	c.lru.MoveToFront(el)
+	return el.Value.(*entry).Entry, true
+}
+
+// Set caches a value of the given kind, evicting the least recently used
+// entries if the cache is full.
+func (c *Cache) Set(kind string, k Key, etag string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	mk := kind + ":" + k.id()
+	e := &entry{
+		mapKey: mk,
+		kind:   kind,
+		key:    k.clean(),
+		Entry:  Entry{Value: value, ETag: etag, Fetched: time.Now()},
+	}
+	if el, ok := c.entries[mk]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[mk] = c.lru.PushFront(e)
+	for c.lru.Len() > c.conf.MaxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Revalidated marks an entry as just validated against the remote provider.
+func (c *Cache) Revalidated(kind string, k Key) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[kind+":"+k.id()]; ok {
+		el.Value.(*entry).Fetched = time.Now()
+	}
+}
+
+// Invalidate drops the cached metadata of a resource which was changed
+// through this instance, together with the one of its descendants and the
+// listing of its parent. The cached content does not need to be dropped, as it
+// is only used if its ETag matches the one of the metadata.
+func (c *Cache) Invalidate(k Key) {
+	k = k.clean()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		e := el.Value.(*entry)
+		if e.key.Domain == k.Domain && e.key.Token == k.Token && (isAncestor(k.Path, e.key.Path) || (e.kind == Listing && e.key.Path == path.Dir(k.Path))) {
+			c.remove(el)
+		}
+		el = next
+	}
+}
+
+// Purge drops everything cached for the resources of a remote provider, or
+// for all of them if the domain is empty. It returns the number of dropped
+// metadata entries and the number of bytes of content freed on disk.
+func (c *Cache) Purge(domain string) (int, int64, error) {
+	c.mu.Lock()
+	n := 0
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		if domain == "" || el.Value.(*entry).key.Domain == domain {
+			c.remove(el)
+			n++
+		}
+		el = next
+	}
+	c.mu.Unlock()
+
+	if c.content == nil {
+		return n, 0, nil
+	}
+	freed, err := c.content.purge(domain)
+	return n, freed, err
+}
+
+// remove must be called with the lock held.
+func (c *Cache) remove(el *list.Element) {
+	delete(c.entries, el.Value.(*entry).mapKey)
+	c.lru.Remove(el)
+}
+
+func isAncestor(p, child string) bool {
+	return p == child || p == "/" || strings.HasPrefix(child, p+"/")
+}
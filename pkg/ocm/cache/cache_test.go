@@ -0,0 +1,169 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package cache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMetadata(t *testing.T) {
+	c, err := New(&Config{MaxEntries: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := Key{Domain: "cernbox.cern.ch", Token: "t1", Path: "/photos"}
+
+	c.Set(Stat, k, "e1", "info")
+	if e, ok := c.Get(Stat, k); !ok || e.Value != "info" || e.ETag != "e1" || !c.Fresh(e.Fetched) {
+		t.Fatalf("unexpected entry %+v, %v", e, ok)
+	}
+	if _, ok := c.Get(Listing, k); ok {
+		t.Fatal("the listing must be cached separately")
+	}
+	if _, ok := c.Get(Stat, Key{Domain: k.Domain, Token: "t2", Path: k.Path}); ok {
+		t.Fatal("the same path of another share must not be returned")
+	}
+
+	// the least recently used entries are evicted
+	for i := 0; i < 3; i++ {
+		c.Set(Stat, Key{Domain: k.Domain, Token: "t1", Path: fmt.Sprintf("/f%d", i)}, "", i)
+	}
+	if _, ok := c.Get(Stat, k); ok {
+		t.Fatal("the oldest entry must have been evicted")
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	c, err := New(&Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := func(p string) Key { return Key{Domain: "cernbox.cern.ch", Token: "t1", Path: p} }
+
+	c.Set(Listing, key("/"), "", nil)
+	c.Set(Stat, key("/"), "", nil)
+	c.Set(Stat, key("/photos"), "", nil)
+	c.Set(Listing, key("/photos"), "", nil)
+	c.Set(Stat, key("/photos/a.jpg"), "", nil)
+	c.Set(Stat, key("/photos2"), "", nil)
+
+	c.Invalidate(key("/photos"))
+
+	for _, tc := range []struct {
+		kind, path string
+		cached     bool
+	}{
+		{Listing, "/", false},
+		{Stat, "/", true},
+		{Stat, "/photos", false},
+		{Listing, "/photos", false},
+		{Stat, "/photos/a.jpg", false},
+		{Stat, "/photos2", true},
+	} {
+		if _, ok := c.Get(tc.kind, key(tc.path)); ok != tc.cached {
+			t.Errorf("%s %s: expected cached=%v", tc.kind, tc.path, tc.cached)
+		}
+	}
+}
+
+func TestContent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ocmcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	conf := &Config{Dir: dir, MaxSize: 10, MaxFileSize: 6}
+	c, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k1 := Key{Domain: "a.org", Token: "t", Path: "/f1"}
+	k2 := Key{Domain: "a.org", Token: "t", Path: "/f2"}
+	k3 := Key{Domain: "b.org", Token: "t", Path: "/f3"}
+
+	store := func(k Key, etag, data string) {
+		w := c.NewContentWriter(k, etag)
+		if _, err := w.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(k Key) (string, string, bool) {
+		content, ok := c.OpenContent(k)
+		if !ok {
+			return "", "", false
+		}
+		defer content.Close()
+		b, err := ioutil.ReadAll(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b), content.ETag, true
+	}
+
+	store(k1, "e1", "hello")
+	if data, etag, ok := read(k1); !ok || data != "hello" || etag != "e1" {
+		t.Fatalf("unexpected content %q %q %v", data, etag, ok)
+	}
+
+	// files without an ETag or too large are not cached
+	store(k2, "", "hello")
+	store(k3, "e3", "too large")
+	if _, _, ok := read(k2); ok {
+		t.Fatal("content without an ETag must not be cached")
+	}
+	if _, _, ok := read(k3); ok {
+		t.Fatal("content larger than the limit must not be cached")
+	}
+
+	// the least recently used content is evicted
+	time.Sleep(time.Millisecond)
+	store(k2, "e2", "world")
+	store(k3, "e3", "!")
+	if _, _, ok := read(k1); ok {
+		t.Fatal("the oldest content must have been evicted")
+	}
+
+	// the content is picked up again after a restart
+	c, err = New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _, ok := read(k2); !ok || data != "world" {
+		t.Fatalf("unexpected content %q %v", data, ok)
+	}
+
+	c.Set(Stat, k2, "e2", nil)
+	n, freed, err := c.Purge("a.org")
+	if err != nil || n != 1 || freed != 5 {
+		t.Fatalf("unexpected purge result %d %d %v", n, freed, err)
+	}
+	if _, _, ok := read(k2); ok {
+		t.Fatal("the purged content must be gone")
+	}
+	if data, _, ok := read(k3); !ok || data != "!" {
+		t.Fatal("content of other domains must not be purged")
+	}
+}
@@ -0,0 +1,371 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package cache
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const tmpPrefix = ".tmp-"
+
+// Content is the cached content of a remote file, which has to be closed
+// after use.
+type Content struct {
+	*os.File
+	ETag string
+	Size int64
+	// Fetched is when the content was downloaded from the remote provider.
+	Fetched time.Time
+}
+
+type contentFile struct {
+	domain  string
+	etag    string
+	size    int64
+	fetched time.Time
+	used    time.Time
+}
+
+// contentMeta is stored next to the cached content, to pick it up again after
+// a restart.
+type contentMeta struct {
+	Domain string `json:"domain"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
+
+type contentStore struct {
+	dir         string
+	maxSize     int64
+	maxFileSize int64
+
+	mu    sync.Mutex
+	files map[string]*contentFile
+	size  int64
+}
+
+func newContentStore(dir string, maxSize, maxFileSize int64) (*contentStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "ocm cache: error creating the content directory")
+	}
+	s := &contentStore{
+		dir:         dir,
+		maxSize:     maxSize,
+		maxFileSize: maxFileSize,
+		files:       map[string]*contentFile{},
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.evict(0)
+	s.mu.Unlock()
+	return s, nil
+}
+
+// load picks up the content cached before a restart and drops the leftovers of
+// interrupted downloads.
+func (s *contentStore) load() error {
+	dirs, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return errors.Wrap(err, "ocm cache: error reading the content directory")
+	}
+	for _, d := range dirs {
+		if !d.IsDir() {
+			if strings.HasPrefix(d.Name(), tmpPrefix) {
+				_ = os.Remove(filepath.Join(s.dir, d.Name()))
+			}
+			continue
+		}
+		files, err := ioutil.ReadDir(filepath.Join(s.dir, d.Name()))
+		if err != nil {
+			return errors.Wrap(err, "ocm cache: error reading the content directory")
+		}
+		for _, f := range files {
+			if !strings.HasSuffix(f.Name(), ".json") {
+				continue
+			}
+			id := strings.TrimSuffix(f.Name(), ".json")
+			metaPath := filepath.Join(s.dir, d.Name(), f.Name())
+			dataPath := filepath.Join(s.dir, d.Name(), id)
+
+			var meta contentMeta
+			b, err := ioutil.ReadFile(metaPath)
+			if err == nil {
+				err = json.Unmarshal(b, &meta)
+			}
+			info, statErr := os.Stat(dataPath)
+			if err != nil || statErr != nil || info.Size() != meta.Size {
+				_ = os.Remove(metaPath)
+				_ = os.Remove(dataPath)
+				continue
+			}
+			s.files[id] = &contentFile{
+				domain:  meta.Domain,
+				etag:    meta.ETag,
+				size:    meta.Size,
+				fetched: info.ModTime(),
+				used:    info.ModTime(),
+			}
+			s.size += meta.Size
+		}
+	}
+	return nil
+}
+
+func (s *contentStore) paths(domain, id string) (string, string) {
+	d := domainDir(s.dir, domain)
+	return filepath.Join(d, id), filepath.Join(d, id+".json")
+}
+
+func domainDir(dir, domain string) string {
+	name := url.PathEscape(domain)
+	if name == "" || name == "." || name == ".." {
+		name = "_" + name
+	}
+	return filepath.Join(dir, name)
+}
+
+func (s *contentStore) open(k Key) (*Content, bool) {
+	id := k.id()
+	s.mu.Lock()
+	f, ok := s.files[id]
+	if ok {
+		f.used = time.Now()
+	}
+	s.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	dataPath, _ := s.paths(f.domain, id)
+	fh, err := os.Open(dataPath)
+	if err != nil {
+		// purged by another instance sharing the directory
+		s.mu.Lock()
+		s.drop(id)
+		s.mu.Unlock()
+		return nil, false
+	}
+	return &Content{File: fh, ETag: f.etag, Size: f.size, Fetched: f.fetched}, true
+}
+
+func (s *contentStore) touch(k Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.files[k.id()]; ok {
+		f.fetched = time.Now()
+	}
+}
+
+// evict drops the least recently used content until the given number of bytes
+// fits into the cache. It must be called with the lock held.
+func (s *contentStore) evict(needed int64) {
+	if s.size+needed <= s.maxSize {
+		return
+	}
+	ids := make([]string, 0, len(s.files))
+	for id := range s.files {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return s.files[ids[i]].used.Before(s.files[ids[j]].used) })
+	for _, id := range ids {
+		if s.size+needed <= s.maxSize {
+			return
+		}
+		s.drop(id)
+	}
+}
+
+// drop must be called with the lock held.
+func (s *contentStore) drop(id string) int64 {
+	f, ok := s.files[id]
+	if !ok {
+		return 0
+	}
+	dataPath, metaPath := s.paths(f.domain, id)
+	_ = os.Remove(dataPath)
+	_ = os.Remove(metaPath)
+	delete(s.files, id)
+	s.size -= f.size
+	return f.size
+}
+
+func (s *contentStore) purge(domain string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var freed int64
+	for id, f := range s.files {
+		if domain == "" || f.domain == domain {
+			freed += s.drop(id)
+		}
+	}
+
+	// remove what was cached by other instances sharing the directory, too
+	if domain != "" {
+		if err := os.RemoveAll(domainDir(s.dir, domain)); err != nil {
+			return freed, errors.Wrap(err, "ocm cache: error purging the content")
+		}
+		return freed, nil
+	}
+	dirs, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return freed, errors.Wrap(err, "ocm cache: error purging the content")
+	}
+	for _, d := range dirs {
+		if d.IsDir() {
+			if err := os.RemoveAll(filepath.Join(s.dir, d.Name())); err != nil {
+				return freed, errors.Wrap(err, "ocm cache: error purging the content")
+			}
+		}
+	}
+	return freed, nil
+}
+
+// ContentWriter stores the content of a remote file while it is being
+// downloaded. Writing never fails, so that it can be used together with the
+// writer the content is streamed to; content which cannot be cached is
+// silently skipped.
+type ContentWriter struct {
+	s    *contentStore
+	key  Key
+	etag string
+
+	tmp  *os.File
+	size int64
+	skip bool
+}
+
+func (s *contentStore) writer(k Key, etag string) *ContentWriter {
+	w := &ContentWriter{s: s, key: k, etag: etag, skip: etag == ""}
+	if !w.skip {
+		tmp, err := ioutil.TempFile(s.dir, tmpPrefix)
+		if err != nil {
+			w.skip = true
+		}
+		w.tmp = tmp
+	}
+	return w
+}
+
+// Write implements io.Writer.
+func (w *ContentWriter) Write(p []byte) (int, error) {
+	if w.skip {
+		return len(p), nil
+	}
+	if w.size+int64(len(p)) > w.s.maxFileSize {
+		w.Abort()
+		return len(p), nil
+	}
+	n, err := w.tmp.Write(p)
+	w.size += int64(n)
+	if err != nil {
+		w.Abort()
+	}
+	return len(p), nil
+}
+
+// Commit adds the written content to the cache. It must only be called once
+// the whole file has been written.
+func (w *ContentWriter) Commit() error {
+	if w.skip {
+		return nil
+	}
+	w.skip = true
+	tmpPath := w.tmp.Name()
+	if err := w.tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return errors.Wrap(err, "ocm cache: error writing the content")
+	}
+
+	s := w.s
+	id := w.key.id()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drop(id)
+	s.evict(w.size)
+
+	dataPath, metaPath := s.paths(w.key.Domain, id)
+	meta, _ := json.Marshal(&contentMeta{Domain: w.key.Domain, ETag: w.etag, Size: w.size})
+	if err := os.MkdirAll(filepath.Dir(dataPath), 0700); err != nil {
+		_ = os.Remove(tmpPath)
+		return errors.Wrap(err, "ocm cache: error creating the content directory")
+	}
+	if err := os.Rename(tmpPath, dataPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return errors.Wrap(err, "ocm cache: error storing the content")
+	}
+	if err := ioutil.WriteFile(metaPath, meta, 0600); err != nil {
+		_ = os.Remove(dataPath)
+		return errors.Wrap(err, "ocm cache: error storing the content")
+	}
+
+	now := time.Now()
+	s.files[id] = &contentFile{domain: w.key.Domain, etag: w.etag, size: w.size, fetched: now, used: now}
+	s.size += w.size
+	return nil
+}
+
+// Abort discards the written content.
+func (w *ContentWriter) Abort() {
+	if w.skip {
+		return
+	}
+	w.skip = true
+	_ = w.tmp.Close()
+	_ = os.Remove(w.tmp.Name())
+}
+
+// OpenContent returns the cached content of a remote file. The caller has to
+// compare its ETag with the current one of the file before using it.
+func (c *Cache) OpenContent(k Key) (*Content, bool) {
+	if c.content == nil {
+		return nil, false
+	}
+	return c.content.open(k)
+}
+
+// ContentRevalidated marks the cached content of a remote file as just
+// validated against the remote provider.
+func (c *Cache) ContentRevalidated(k Key) {
+	if c.content != nil {
+		c.content.touch(k)
+	}
+}
+
+// NewContentWriter returns a writer storing the content of a remote file with
+// the given ETag in the cache. Files without an ETag cannot be validated and
+// are not cached.
+func (c *Cache) NewContentWriter(k Key, etag string) *ContentWriter {
+	if c.content == nil {
+		return &ContentWriter{skip: true}
+	}
+	return c.content.writer(k, etag)
+}
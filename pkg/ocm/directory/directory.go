@@ -0,0 +1,307 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package directory searches the users of the other sites of the mesh, so
+// that federated collaborators can be found by their name or mail instead of
+// their exact federated id. The sites taking part in the directory register
+// an endpoint of type SHAREES in Mentix, which serves the searches of the
+// other sites according to the privacy settings of the site.
+package directory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ReneKroon/ttlcache/v2"
+	ocmprovider "github.com/cs3org/go-cs3apis/cs3/ocm/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/mentix/meshdata"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/pkg/errors"
+)
+
+// Sharee is a user of a site of the mesh who can receive federated shares.
+type Sharee struct {
+	UserID      string `json:"userId"`
+	Idp         string `json:"idp"`
+	DisplayName string `json:"displayName"`
+	// Mail is only returned by the sites exposing the mails of their users.
+	Mail string `json:"mail,omitempty"`
+	// Provider is the domain of the site of the user, set by the searching site.
+	Provider string `json:"-"`
+}
+
+// Config holds the configuration of the searches in the mesh directory.
+type Config struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Provider is the domain of this site, sent along the searches for the
+	// other sites to authorize them. Its own users are not searched.
+	Provider string `mapstructure:"provider"`
+	// Timeout is the number of seconds to wait for the sites to answer, the slower ones are left out.
+	Timeout int `mapstructure:"timeout" docs:"5"`
+	// CacheTTL is the number of seconds the results of a search are reused, 0 disables caching.
+	CacheTTL  int `mapstructure:"cache_ttl" docs:"300"`
+	CacheSize int `mapstructure:"cache_size" docs:"1000"`
+	// MinSearchLength is the shortest search term sent to the other sites.
+	MinSearchLength int `mapstructure:"min_search_length" docs:"3"`
+	// MaxResults is the maximum number of users returned for a search across all sites.
+	MaxResults int `mapstructure:"max_results" docs:"25"`
+	// ExcludedProviders lists the domains of the sites never searched.
+	ExcludedProviders []string `mapstructure:"excluded_providers"`
+	Insecure          bool     `mapstructure:"insecure" docs:"false;Whether to skip certificate checks when sending requests."`
+}
+
+// Init sets the defaults of the configuration.
+func (c *Config) Init() {
+	if c.Timeout <= 0 {
+		c.Timeout = 5
+	}
+	if c.CacheTTL < 0 {
+		c.CacheTTL = 0
+	} else if c.CacheTTL == 0 {
+		c.CacheTTL = 300
+	}
+	if c.CacheSize <= 0 {
+		c.CacheSize = 1000
+	}
+	if c.MinSearchLength <= 0 {
+		c.MinSearchLength = 3
+	}
+	if c.MaxResults <= 0 {
+		c.MaxResults = 25
+	}
+}
+
+// ProviderLister lists the sites of the mesh, usually through the provider
+// authorizer of the gateway backed by Mentix.
+type ProviderLister func(ctx context.Context) ([]*ocmprovider.ProviderInfo, error)
+
+// Directory searches the users of the sites of the mesh.
+type Directory struct {
+	c      *Config
+	list   ProviderLister
+	client *http.Client
+	cache  *ttlcache.Cache // nil if the results are not cached
+}
+
+// New returns a directory searching the sites returned by list.
+func New(c *Config, list ProviderLister) *Directory {
+	c.Init()
+	d := &Directory{
+		c:    c,
+		list: list,
+		client: rhttp.GetHTTPClient(
+			rhttp.Timeout(time.Duration(c.Timeout)*time.Second),
+			rhttp.Insecure(c.Insecure),
+		),
+	}
+	if c.CacheTTL > 0 {
+		d.cache = ttlcache.NewCache()
+		_ = d.cache.SetTTL(time.Duration(c.CacheTTL) * time.Second)
+		d.cache.SkipTTLExtensionOnHit(true)
+		d.cache.SetCacheSizeLimit(c.CacheSize)
+	}
+	return d
+}
+
+// site is a site of the mesh serving the directory searches.
+type site struct {
+	domain   string
+	endpoint string
+}
+
+// Search returns the users of the other sites matching the term, the best
+// matches first. Sites failing to answer are left out of the results.
+func (d *Directory) Search(ctx context.Context, term string) ([]*Sharee, error) {
+	term = strings.TrimSpace(term)
+	if len([]rune(term)) < d.c.MinSearchLength {
+		return []*Sharee{}, nil
+	}
+
+	key := strings.ToLower(term)
+	if d.cache != nil {
+		if v, err := d.cache.Get(key); err == nil {
+			return v.([]*Sharee), nil
+		}
+	}
+
+	sites, err := d.sites(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log := appctx.GetLogger(ctx)
+	var (
+		wg      sync.WaitGroup
+		mutex   sync.Mutex
+		sharees []*Sharee
+	)
+	for _, s := range sites {
+		wg.Add(1)
+		go func(s site) {
+			defer wg.Done()
+			res, err := d.query(ctx, s, term)
+			if err != nil {
+				log.Warn().Err(err).Str("provider", s.domain).Msg("directory: error searching the users of the site")
+				return
+			}
+			mutex.Lock()
+			sharees = append(sharees, res...)
+			mutex.Unlock()
+		}(s)
+	}
+	wg.Wait()
+
+	sharees = rank(sharees, term)
+	if len(sharees) > d.c.MaxResults {
+		sharees = sharees[:d.c.MaxResults]
+	}
+	if d.cache != nil {
+		_ = d.cache.Set(key, sharees)
+	}
+	return sharees, nil
+}
+
+// sites returns the sites of the mesh exposing a sharee endpoint, except
+// this one and the excluded ones.
+func (d *Directory) sites(ctx context.Context) ([]site, error) {
+	providers, err := d.list(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "directory: error listing the sites of the mesh")
+	}
+
+	sites := []site{}
+	for _, p := range providers {
+		if p.Domain == "" || strings.EqualFold(p.Domain, d.c.Provider) || d.excluded(p.Domain) {
+			continue
+		}
+		if endpoint := shareesEndpoint(p); endpoint != "" {
+			sites = append(sites, site{domain: p.Domain, endpoint: endpoint})
+		}
+	}
+	return sites, nil
+}
+
+func (d *Directory) excluded(domain string) bool {
+	for _, e := range d.c.ExcludedProviders {
+		if strings.EqualFold(e, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// shareesEndpoint returns the URL of the sharee endpoint of a site, which is
+// registered either as a service of its own or as an additional endpoint of
+// the main service.
+func shareesEndpoint(p *ocmprovider.ProviderInfo) string {
+	for _, s := range p.Services {
+		if s.GetEndpoint().GetType().GetName() == meshdata.EndpointSharees {
+			if s.Endpoint.Path != "" {
+				return s.Endpoint.Path
+			}
+			return s.Host
+		}
+		for _, e := range s.AdditionalEndpoints {
+			if e.GetType().GetName() == meshdata.EndpointSharees {
+				return e.Path
+			}
+		}
+	}
+	return ""
+}
+
+func (d *Directory) query(ctx context.Context, s site, term string) ([]*Sharee, error) {
+	u, err := url.Parse(s.endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "directory: invalid sharee endpoint")
+	}
+	q := u.Query()
+	q.Set("search", term)
+	q.Set("meshProvider", d.c.Provider)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("directory: unexpected status %s", res.Status)
+	}
+
+	var sharees []*Sharee
+	if err := json.NewDecoder(res.Body).Decode(&sharees); err != nil {
+		return nil, errors.Wrap(err, "directory: error decoding sharees")
+	}
+	valid := make([]*Sharee, 0, len(sharees))
+	for _, sh := range sharees {
+		if sh == nil || sh.UserID == "" {
+			continue
+		}
+		sh.Provider = s.domain
+		if sh.Idp == "" {
+			sh.Idp = s.domain
+		}
+		valid = append(valid, sh)
+	}
+	return valid, nil
+}
+
+// rank sorts the sharees by relevance: exact matches of the id or mail first,
+// then the display names starting with the term, then the others, each
+// group alphabetically.
+func rank(sharees []*Sharee, term string) []*Sharee {
+	term = strings.ToLower(term)
+	score := func(s *Sharee) int {
+		switch {
+		case strings.ToLower(s.UserID) == term || strings.ToLower(s.Mail) == term:
+			return 0
+		case strings.HasPrefix(strings.ToLower(s.DisplayName), term):
+			return 1
+		}
+		return 2
+	}
+	sort.SliceStable(sharees, func(i, j int) bool {
+		si, sj := score(sharees[i]), score(sharees[j])
+		if si != sj {
+			return si < sj
+		}
+		if sharees[i].DisplayName != sharees[j].DisplayName {
+			return strings.ToLower(sharees[i].DisplayName) < strings.ToLower(sharees[j].DisplayName)
+		}
+		return sharees[i].Provider < sharees[j].Provider
+	})
+	if sharees == nil {
+		return []*Sharee{}
+	}
+	return sharees
+}
@@ -0,0 +1,123 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package directory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	ocmprovider "github.com/cs3org/go-cs3apis/cs3/ocm/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/mentix/meshdata"
+)
+
+func shareesServer(t *testing.T, sharees []*Sharee, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		if r.URL.Query().Get("meshProvider") != "here.org" {
+			t.Errorf("expected the searching site to be sent, got %q", r.URL.Query().Get("meshProvider"))
+		}
+		_ = json.NewEncoder(w).Encode(sharees)
+	}))
+}
+
+func provider(domain, endpoint string, additional bool) *ocmprovider.ProviderInfo {
+	e := &ocmprovider.ServiceEndpoint{Type: &ocmprovider.ServiceType{Name: meshdata.EndpointSharees}, Path: endpoint}
+	if additional {
+		return &ocmprovider.ProviderInfo{Domain: domain, Services: []*ocmprovider.Service{{
+			Endpoint:            &ocmprovider.ServiceEndpoint{Type: &ocmprovider.ServiceType{Name: meshdata.EndpointRevad}},
+			AdditionalEndpoints: []*ocmprovider.ServiceEndpoint{e},
+		}}}
+	}
+	return &ocmprovider.ProviderInfo{Domain: domain, Services: []*ocmprovider.Service{{Endpoint: e}}}
+}
+
+func TestSearch(t *testing.T) {
+	var callsA, callsB, callsSelf int32
+	a := shareesServer(t, []*Sharee{
+		{UserID: "mmueller", Idp: "a.org", DisplayName: "Maria Müller"},
+		{UserID: "mario", Idp: "a.org", DisplayName: "Mario Rossi"},
+	}, &callsA)
+	defer a.Close()
+	b := shareesServer(t, []*Sharee{
+		{UserID: "ana", DisplayName: "Anna Mario", Mail: "mario@b.org"},
+	}, &callsB)
+	defer b.Close()
+	self := shareesServer(t, []*Sharee{{UserID: "me"}}, &callsSelf)
+	defer self.Close()
+
+	providers := []*ocmprovider.ProviderInfo{
+		provider("a.org", a.URL, false),
+		provider("b.org", b.URL, true),
+		provider("here.org", self.URL, false),
+		{Domain: "c.org"}, // not taking part in the directory
+	}
+	d := New(&Config{Provider: "here.org", MaxResults: 2}, func(ctx context.Context) ([]*ocmprovider.ProviderInfo, error) {
+		return providers, nil
+	})
+
+	sharees, err := d.Search(context.Background(), "mario")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sharees) != 2 {
+		t.Fatalf("expected the results to be limited to 2, got %d", len(sharees))
+	}
+	// the exact match on the id comes first, then the others alphabetically
+	if sharees[0].UserID != "mario" || sharees[0].Provider != "a.org" {
+		t.Errorf("unexpected first match %+v", sharees[0])
+	}
+	if sharees[1].UserID != "ana" || sharees[1].Provider != "b.org" || sharees[1].Idp != "b.org" {
+		t.Errorf("unexpected second match %+v", sharees[1])
+	}
+	if callsSelf != 0 {
+		t.Error("expected this site not to be searched")
+	}
+
+	if _, err := d.Search(context.Background(), "Mario"); err != nil {
+		t.Fatal(err)
+	}
+	if callsA != 1 || callsB != 1 {
+		t.Errorf("expected the results to be cached, the sites were searched %d and %d times", callsA, callsB)
+	}
+
+	if sharees, _ := d.Search(context.Background(), "ma"); len(sharees) != 0 || callsA != 1 {
+		t.Error("expected short terms not to be searched")
+	}
+}
+
+func TestSearchExcluded(t *testing.T) {
+	var calls int32
+	a := shareesServer(t, []*Sharee{{UserID: "alice", DisplayName: "Alice"}}, &calls)
+	defer a.Close()
+
+	d := New(&Config{Provider: "here.org", CacheTTL: -1, ExcludedProviders: []string{"A.org"}}, func(ctx context.Context) ([]*ocmprovider.ProviderInfo, error) {
+		return []*ocmprovider.ProviderInfo{provider("a.org", a.URL, false)}, nil
+	})
+	sharees, err := d.Search(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sharees) != 0 || calls != 0 {
+		t.Errorf("expected the excluded site not to be searched, got %d results", len(sharees))
+	}
+}
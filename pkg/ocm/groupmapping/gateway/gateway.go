@@ -0,0 +1,78 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/ocm/groupmapping"
+	"github.com/cs3org/reva/pkg/ocm/groupmapping/registry"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("gateway", New)
+}
+
+type config struct {
+	GatewaySvc string `mapstructure:"gatewaysvc"`
+	// Prefix is prepended to the remote group names, e.g. to map them to dedicated local groups.
+	Prefix string `mapstructure:"prefix"`
+}
+
+type mapper struct {
+	c *config
+}
+
+// New returns a group mapper which maps remote groups to the local
+// groups with the same name, as known to the group providers.
+func New(m map[string]interface{}) (groupmapping.Mapper, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		err = errors.Wrap(err, "error decoding conf")
+		return nil, err
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+	return &mapper{c: c}, nil
+}
+
+func (m *mapper) MapGroup(ctx context.Context, group, provider string) (*grouppb.GroupId, error) {
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(m.c.GatewaySvc))
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.GetGroupByClaim(ctx, &grouppb.GetGroupByClaimRequest{
+		Claim:               "group_name",
+		Value:               m.c.Prefix + group,
+		SkipFetchingMembers: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, status.NewErrorFromCode(res.Status.Code, "gateway")
+	}
+	return res.Group.Id, nil
+}
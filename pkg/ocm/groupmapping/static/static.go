@@ -0,0 +1,64 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package static
+
+import (
+	"context"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/ocm/groupmapping"
+	"github.com/cs3org/reva/pkg/ocm/groupmapping/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("static", New)
+}
+
+type config struct {
+	// Groups maps remote groups, either as group@provider or as plain group names
+	// matching the group of any provider, to the IDs of local groups.
+	Groups map[string]string `mapstructure:"groups"`
+}
+
+type mapper struct {
+	groups map[string]string
+}
+
+// New returns a group mapper using a static list of mappings.
+func New(m map[string]interface{}) (groupmapping.Mapper, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		err = errors.Wrap(err, "error decoding conf")
+		return nil, err
+	}
+	return &mapper{groups: c.Groups}, nil
+}
+
+func (m *mapper) MapGroup(ctx context.Context, group, provider string) (*grouppb.GroupId, error) {
+	if gid, ok := m.groups[group+"@"+provider]; ok {
+		return &grouppb.GroupId{OpaqueId: gid}, nil
+	}
+	if gid, ok := m.groups[group]; ok {
+		return &grouppb.GroupId{OpaqueId: gid}, nil
+	}
+	return nil, errtypes.NotFound("no local group mapped to " + group + "@" + provider)
+}
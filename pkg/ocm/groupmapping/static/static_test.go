@@ -0,0 +1,60 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package static
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+func TestMapGroup(t *testing.T) {
+	m, err := New(map[string]interface{}{
+		"groups": map[string]string{
+			"physics@cern.ch": "cern-physics",
+			"physics":         "physics",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		group, provider, expected string
+	}{
+		{"physics", "cern.ch", "cern-physics"},
+		{"physics", "example.org", "physics"},
+	}
+	for _, tt := range tests {
+		gid, err := m.MapGroup(context.Background(), tt.group, tt.provider)
+		if err != nil {
+			t.Fatalf("%s@%s: %v", tt.group, tt.provider, err)
+		}
+		if gid.OpaqueId != tt.expected {
+			t.Errorf("%s@%s: expected %s, got %s", tt.group, tt.provider, tt.expected, gid.OpaqueId)
+		}
+	}
+
+	if _, err := m.MapGroup(context.Background(), "chemistry", "cern.ch"); err == nil {
+		t.Error("expected an error for an unmapped group")
+	} else if _, ok := err.(errtypes.IsNotFound); !ok {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
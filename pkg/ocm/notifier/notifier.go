@@ -0,0 +1,66 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package notifier forwards the events of the OCM shares, like a share
+// received from a remote provider or accepted by its recipient, to the
+// users or to external systems.
+package notifier
+
+import (
+	"context"
+	"time"
+)
+
+// Type is the type of a notification. Besides ShareReceived, the types are
+// the ones of the notifications exchanged by the OCM providers.
+type Type string
+
+// The notification types.
+const (
+	ShareReceived Type = "SHARE_RECEIVED"
+	ShareAccepted Type = "SHARE_ACCEPTED"
+	ShareDeclined Type = "SHARE_DECLINED"
+	ShareUnshared Type = "SHARE_UNSHARED"
+)
+
+// Notification is an event of an OCM share.
+type Notification struct {
+	Type         Type   `json:"type"`
+	ResourceType string `json:"resource_type,omitempty"`
+	// ProviderID identifies the share at the provider of its owner.
+	ProviderID string `json:"provider_id"`
+	ShareName  string `json:"share_name,omitempty"`
+	// Owner is the owner of the share, as user@provider for remote users.
+	Owner string `json:"owner,omitempty"`
+	// Recipient is the local user or group notified.
+	Recipient string `json:"recipient,omitempty"`
+	// RecipientMail is the address the notification is mailed to, empty
+	// for groups and unknown users.
+	RecipientMail string `json:"-"`
+	// Sender is the domain of the provider the notification comes from.
+	Sender string `json:"sender,omitempty"`
+	// Details holds the notification object sent by a remote provider,
+	// without its shared secret.
+	Details map[string]interface{} `json:"details,omitempty"`
+	Time    time.Time              `json:"time"`
+}
+
+// Notifier delivers the notifications.
+type Notifier interface {
+	Notify(ctx context.Context, n *Notification) error
+}
@@ -0,0 +1,107 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"text/template"
+
+	"github.com/cs3org/reva/pkg/ocm/notifier"
+	"github.com/cs3org/reva/pkg/ocm/notifier/registry"
+	"github.com/cs3org/reva/pkg/smtpclient"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("smtp", New)
+}
+
+const defaultSubject = `{{if eq .Type "SHARE_RECEIVED"}}{{.Owner}} shared "{{.ShareName}}" with you{{else if eq .Type "SHARE_ACCEPTED"}}Your share "{{.ShareName}}" was accepted{{else if eq .Type "SHARE_DECLINED"}}Your share "{{.ShareName}}" was declined{{else if eq .Type "SHARE_UNSHARED"}}"{{.ShareName}}" is no longer shared with you{{else}}Notification about "{{.ShareName}}"{{end}}`
+
+const defaultBody = `Hello,
+
+{{if eq .Type "SHARE_RECEIVED"}}{{.Owner}} shared "{{.ShareName}}" with you from {{.Sender}}.
+You can accept or decline the share in your account.{{else if eq .Type "SHARE_ACCEPTED"}}The share "{{.ShareName}}" was accepted by its recipient on {{.Sender}}.{{else if eq .Type "SHARE_DECLINED"}}The share "{{.ShareName}}" was declined by its recipient on {{.Sender}}.{{else if eq .Type "SHARE_UNSHARED"}}{{.Owner}} no longer shares "{{.ShareName}}" with you.{{else}}{{.Sender}} sent a {{.Type}} notification about "{{.ShareName}}".{{end}}
+`
+
+type config struct {
+	SMTPCredentials *smtpclient.SMTPCredentials `mapstructure:"smtp_credentials"`
+	// SubjectTemplate and BodyTemplate are text templates executed with the
+	// notification.
+	SubjectTemplate string `mapstructure:"subject_template"`
+	BodyTemplate    string `mapstructure:"body_template"`
+}
+
+func (c *config) init() {
+	if c.SubjectTemplate == "" {
+		c.SubjectTemplate = defaultSubject
+	}
+	if c.BodyTemplate == "" {
+		c.BodyTemplate = defaultBody
+	}
+}
+
+type smtpNotifier struct {
+	creds   *smtpclient.SMTPCredentials
+	subject *template.Template
+	body    *template.Template
+}
+
+// New returns a notifier mailing the notifications to the users they
+// concern. Notifications without a recipient address are dropped.
+func New(m map[string]interface{}) (notifier.Notifier, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	c.init()
+	if c.SMTPCredentials == nil {
+		return nil, errors.New("smtp: smtp_credentials not configured")
+	}
+
+	subject, err := template.New("subject").Parse(c.SubjectTemplate)
+	if err != nil {
+		return nil, errors.Wrap(err, "smtp: error parsing subject template")
+	}
+	body, err := template.New("body").Parse(c.BodyTemplate)
+	if err != nil {
+		return nil, errors.Wrap(err, "smtp: error parsing body template")
+	}
+	return &smtpNotifier{
+		creds:   smtpclient.NewSMTPCredentials(c.SMTPCredentials),
+		subject: subject,
+		body:    body,
+	}, nil
+}
+
+func (s *smtpNotifier) Notify(ctx context.Context, n *notifier.Notification) error {
+	if n.RecipientMail == "" {
+		return nil
+	}
+	var subject, body bytes.Buffer
+	if err := s.subject.Execute(&subject, n); err != nil {
+		return errors.Wrap(err, "smtp: error executing subject template")
+	}
+	if err := s.body.Execute(&body, n); err != nil {
+		return errors.Wrap(err, "smtp: error executing body template")
+	}
+	return s.creds.SendMail(n.RecipientMail, subject.String(), body.String())
+}
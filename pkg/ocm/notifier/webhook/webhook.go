@@ -0,0 +1,110 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cs3org/reva/pkg/ocm/notifier"
+	"github.com/cs3org/reva/pkg/ocm/notifier/registry"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("webhook", New)
+}
+
+// SignatureHeader carries the HMAC-SHA256 of the body, keyed with the
+// configured secret, for the receivers to authenticate the notifications.
+const SignatureHeader = "X-Reva-Signature"
+
+type config struct {
+	URL      string `mapstructure:"url"`
+	Secret   string `mapstructure:"secret"`
+	Timeout  int64  `mapstructure:"timeout"`
+	Insecure bool   `mapstructure:"insecure"`
+}
+
+type webhook struct {
+	c      *config
+	client *http.Client
+}
+
+// New returns a notifier posting the notifications as JSON to a URL.
+func New(m map[string]interface{}) (notifier.Notifier, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	if c.URL == "" {
+		return nil, errors.New("webhook: url not configured")
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10
+	}
+	return &webhook{
+		c: c,
+		client: rhttp.GetHTTPClient(
+			rhttp.Timeout(time.Duration(c.Timeout)*time.Second),
+			rhttp.Insecure(c.Insecure),
+		),
+	}, nil
+}
+
+// Sign returns the signature of a body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w *webhook) Notify(ctx context.Context, n *notifier.Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.c.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "webhook: error creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.c.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(w.c.Secret, body))
+	}
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "webhook: error sending notification")
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook: notification rejected: %s", res.Status)
+	}
+	return nil
+}
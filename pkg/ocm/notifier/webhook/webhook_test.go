@@ -0,0 +1,82 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/ocm/notifier"
+)
+
+func TestNotify(t *testing.T) {
+	var got notifier.Notification
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if sig := r.Header.Get(SignatureHeader); sig != Sign("secret", body) {
+			t.Errorf("invalid signature %q", sig)
+		}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	n, err := New(map[string]interface{}{"url": srv.URL, "secret": "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n.Notify(context.Background(), &notifier.Notification{
+		Type:          notifier.ShareReceived,
+		ProviderID:    "storage:id",
+		ShareName:     "report.pdf",
+		Owner:         "einstein@cernbox.cern.ch",
+		Recipient:     "marie",
+		RecipientMail: "marie@example.org",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != notifier.ShareReceived || got.ShareName != "report.pdf" || got.Recipient != "marie" {
+		t.Errorf("unexpected notification %+v", got)
+	}
+	if got.RecipientMail != "" {
+		t.Error("the recipient address must not be forwarded")
+	}
+}
+
+func TestNotifyRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	n, err := New(map[string]interface{}{"url": srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(context.Background(), &notifier.Notification{Type: notifier.ShareAccepted}); err == nil {
+		t.Error("expected an error")
+	}
+}
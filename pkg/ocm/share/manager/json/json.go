@@ -107,6 +107,9 @@ func loadOrCreate(file string) (*shareModel, error) {
 	if m.ReceivedShares == nil {
 		m.ReceivedShares = map[string]interface{}{}
 	}
+	if m.GroupShareStates == nil {
+		m.GroupShareStates = map[string]map[string]ocm.ShareState{}
+	}
 	m.file = file
 
 	return m, nil
@@ -116,6 +119,8 @@ type shareModel struct {
 	file           string
 	Shares         map[string]interface{} `json:"shares"`
 	ReceivedShares map[string]interface{} `json:"received_shares"`
+	// GroupShareStates holds the state of received group shares for every member of the group, by share ID
+	GroupShareStates map[string]map[string]ocm.ShareState `json:"group_share_states"`
 }
 
 type config struct {
@@ -208,13 +213,13 @@ func (m *mgr) Share(ctx context.Context, md *provider.ResourceId, g *ocm.ShareGr
 			return nil, errors.New("json: owner of resource not provided")
 		}
 		userID = owner
-		g.Grantee.Opaque = &typespb.Opaque{
-			Map: map[string]*typespb.OpaqueEntry{
-				"token": {
-					Decoder: "plain",
-					Value:   []byte(token),
-				},
-			},
+		// keep the id of the share at the remote provider, needed to notify it
+		if g.Grantee.Opaque == nil || g.Grantee.Opaque.Map == nil {
+			g.Grantee.Opaque = &typespb.Opaque{Map: map[string]*typespb.OpaqueEntry{}}
+		}
+		g.Grantee.Opaque.Map["token"] = &typespb.OpaqueEntry{
+			Decoder: "plain",
+			Value:   []byte(token),
 		}
 	} else {
 		userID = ctxpkg.ContextMustGetUser(ctx).GetId()
@@ -265,8 +270,13 @@ func (m *mgr) Share(ctx context.Context, md *provider.ResourceId, g *ocm.ShareGr
 			protocol["name"] = "datatx"
 		}
 
+		shareWith, shareType := g.Grantee.GetUserId().GetOpaqueId(), "user"
+		if g.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_GROUP {
+			shareWith, shareType = g.Grantee.GetGroupId().GetOpaqueId(), "group"
+		}
 		requestBodyMap := map[string]interface{}{
-			"shareWith":    g.Grantee.GetUserId().OpaqueId,
+			"shareWith":    shareWith,
+			"shareType":    shareType,
 			"name":         name,
 			"providerId":   fmt.Sprintf("%s:%s", md.StorageId, md.OpaqueId),
 			"owner":        userID.OpaqueId,
@@ -527,7 +537,8 @@ func (m *mgr) ListReceivedShares(ctx context.Context) ([]*ocm.ReceivedShare, err
 			// omit shares created by me
 			continue
 		}
-		if share.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_USER && utils.UserEqual(user.Id, share.Grantee.GetUserId()) {
+		if isGrantee(user, share.Grantee) {
+			m.applyGroupShareState(user, &rs)
 			rss = append(rss, &rs)
 		}
 	}
@@ -555,7 +566,8 @@ func (m *mgr) getReceived(ctx context.Context, ref *ocm.ShareReference) (*ocm.Re
 		}
 		share := rs.Share
 		if sharesEqual(ref, share) {
-			if share.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_USER && utils.UserEqual(user.Id, share.Grantee.GetUserId()) {
+			if isGrantee(user, share.Grantee) {
+				m.applyGroupShareState(user, &rs)
 				return &rs, nil
 			}
 		}
@@ -587,11 +599,22 @@ func (m *mgr) UpdateReceivedShare(ctx context.Context, share *ocm.ReceivedShare,
 		return nil, err
 	}
 
-	encShare, err := utils.MarshalProtoV1ToJSON(rs)
-	if err != nil {
-		return nil, err
+	if rs.Share.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_GROUP {
+		// every member of the group accepts or rejects the share on their own
+		user := ctxpkg.ContextMustGetUser(ctx)
+		states, ok := m.model.GroupShareStates[rs.Share.Id.GetOpaqueId()]
+		if !ok {
+			states = map[string]ocm.ShareState{}
+			m.model.GroupShareStates[rs.Share.Id.GetOpaqueId()] = states
+		}
+		states[user.Id.GetOpaqueId()] = rs.State
+	} else {
+		encShare, err := utils.MarshalProtoV1ToJSON(rs)
+		if err != nil {
+			return nil, err
+		}
+		m.model.ReceivedShares[rs.Share.Id.GetOpaqueId()] = string(encShare)
 	}
-	m.model.ReceivedShares[rs.Share.Id.GetOpaqueId()] = string(encShare)
 
 	if err := m.model.Save(); err != nil {
 		err = errors.Wrap(err, "error saving model")
@@ -600,3 +623,31 @@ func (m *mgr) UpdateReceivedShare(ctx context.Context, share *ocm.ReceivedShare,
 
 	return rs, nil
 }
+
+// isGrantee checks whether the user is the grantee of a share, either directly or as a member of the group.
+func isGrantee(user *userpb.User, g *provider.Grantee) bool {
+	switch g.Type {
+	case provider.GranteeType_GRANTEE_TYPE_USER:
+		return utils.UserEqual(user.Id, g.GetUserId())
+	case provider.GranteeType_GRANTEE_TYPE_GROUP:
+		for _, group := range user.Groups {
+			if group == g.GetGroupId().GetOpaqueId() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyGroupShareState sets the state of a received group share to the one chosen by the user.
+// The caller is expected to hold the lock.
+func (m *mgr) applyGroupShareState(user *userpb.User, rs *ocm.ReceivedShare) {
+	if rs.Share.Grantee.Type != provider.GranteeType_GRANTEE_TYPE_GROUP {
+		return
+	}
+	if state, ok := m.model.GroupShareStates[rs.Share.Id.GetOpaqueId()][user.Id.GetOpaqueId()]; ok {
+		rs.State = state
+	} else {
+		rs.State = ocm.ShareState_SHARE_STATE_PENDING
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/utils"
+	"google.golang.org/genproto/protobuf/field_mask"
+)
+
+var (
+	einstein = &userpb.User{Id: &userpb.UserId{Idp: "cernbox.cern.ch", OpaqueId: "einstein"}, Groups: []string{"physics"}}
+	marie    = &userpb.User{Id: &userpb.UserId{Idp: "cernbox.cern.ch", OpaqueId: "marie"}, Groups: []string{"physics"}}
+	richard  = &userpb.User{Id: &userpb.UserId{Idp: "cernbox.cern.ch", OpaqueId: "richard"}, Groups: []string{"sailing"}}
+)
+
+// newGroupShareManager returns a manager holding a share received by the physics group.
+func newGroupShareManager(t *testing.T) *mgr {
+	m, err := New(map[string]interface{}{"file": filepath.Join(t.TempDir(), "shares.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := &ocm.ReceivedShare{
+		Share: &ocm.Share{
+			Id:    &ocm.ShareId{OpaqueId: "group-share"},
+			Owner: &userpb.UserId{Idp: "example.org", OpaqueId: "bob"},
+			Grantee: &provider.Grantee{
+				Type: provider.GranteeType_GRANTEE_TYPE_GROUP,
+				Id:   &provider.Grantee_GroupId{GroupId: &grouppb.GroupId{OpaqueId: "physics"}},
+			},
+		},
+		State: ocm.ShareState_SHARE_STATE_PENDING,
+	}
+	enc, err := utils.MarshalProtoV1ToJSON(rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := m.(*mgr)
+	manager.model.ReceivedShares["group-share"] = string(enc)
+	if err := manager.model.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return manager
+}
+
+func TestReceivedGroupShares(t *testing.T) {
+	m := newGroupShareManager(t)
+
+	for _, u := range []*userpb.User{einstein, marie} {
+		rss, err := m.ListReceivedShares(ctxpkg.ContextSetUser(context.Background(), u))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rss) != 1 {
+			t.Errorf("expected %s to receive the share of the group, got %d shares", u.Id.OpaqueId, len(rss))
+		}
+	}
+	rss, err := m.ListReceivedShares(ctxpkg.ContextSetUser(context.Background(), richard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rss) != 0 {
+		t.Errorf("expected the share not to be received outside the group, got %d shares", len(rss))
+	}
+}
+
+func TestUpdateReceivedGroupShare(t *testing.T) {
+	m := newGroupShareManager(t)
+	ref := &ocm.ShareReference{Spec: &ocm.ShareReference_Id{Id: &ocm.ShareId{OpaqueId: "group-share"}}}
+
+	ctx := ctxpkg.ContextSetUser(context.Background(), einstein)
+	rs, err := m.GetReceivedShare(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.State = ocm.ShareState_SHARE_STATE_ACCEPTED
+	if _, err := m.UpdateReceivedShare(ctx, rs, &field_mask.FieldMask{Paths: []string{"state"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if rs, err := m.GetReceivedShare(ctx, ref); err != nil || rs.State != ocm.ShareState_SHARE_STATE_ACCEPTED {
+		t.Errorf("expected the share to be accepted by einstein, got %v", rs.GetState())
+	}
+	// every member of the group accepts the share on their own
+	rs, err = m.GetReceivedShare(ctxpkg.ContextSetUser(context.Background(), marie), ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.State != ocm.ShareState_SHARE_STATE_PENDING {
+		t.Errorf("expected the share to be pending for marie, got %v", rs.State)
+	}
+}
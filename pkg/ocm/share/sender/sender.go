@@ -34,7 +34,10 @@ import (
 	"github.com/pkg/errors"
 )
 
-const createOCMCoreShareEndpoint = "shares"
+const (
+	createOCMCoreShareEndpoint = "shares"
+	notificationsEndpoint      = "notifications"
+)
 
 func getOCMEndpoint(originProvider *ocmprovider.ProviderInfo) (string, error) {
 	for _, s := range originProvider.Services {
@@ -48,6 +51,16 @@ func getOCMEndpoint(originProvider *ocmprovider.ProviderInfo) (string, error) {
 // Send executes the POST to the OCM shares endpoint to create the share at the
 // remote site.
 func Send(requestBodyMap map[string]interface{}, pi *ocmprovider.ProviderInfo) error {
+	return post(createOCMCoreShareEndpoint, requestBodyMap, pi)
+}
+
+// SendNotification executes the POST to the OCM notifications endpoint to
+// notify the remote site of an event of a share, e.g. its acceptance.
+func SendNotification(notification map[string]interface{}, pi *ocmprovider.ProviderInfo) error {
+	return post(notificationsEndpoint, notification, pi)
+}
+
+func post(endpoint string, requestBodyMap map[string]interface{}, pi *ocmprovider.ProviderInfo) error {
 	requestBody, err := json.Marshal(requestBodyMap)
 	if err != nil {
 		err = errors.Wrap(err, "error marshalling request body")
@@ -61,7 +74,7 @@ func Send(requestBodyMap map[string]interface{}, pi *ocmprovider.ProviderInfo) e
 	if err != nil {
 		return err
 	}
-	u.Path = path.Join(u.Path, createOCMCoreShareEndpoint)
+	u.Path = path.Join(u.Path, endpoint)
 	recipientURL := u.String()
 
 	req, err := http.NewRequest("POST", recipientURL, strings.NewReader(string(requestBody)))
@@ -86,7 +99,7 @@ func Send(requestBodyMap map[string]interface{}, pi *ocmprovider.ProviderInfo) e
 			e = errors.Wrap(e, "sender: error reading request body")
 			return e
 		}
-		err = errors.Wrap(fmt.Errorf("%s: %s", resp.Status, string(respBody)), "sender: error sending create ocm core share post request")
+		err = errors.Wrap(fmt.Errorf("%s: %s", resp.Status, string(respBody)), "sender: error sending post request to "+endpoint)
 		return err
 	}
 	return nil
@@ -0,0 +1,51 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package pid mints the persistent identifiers, like DOIs, of the folders
+// the users publish as datasets.
+package pid
+
+import "context"
+
+const (
+	// MetadataKey is the arbitrary metadata holding the identifier of a
+	// published folder, exposed as the oc:doi WebDAV property.
+	MetadataKey = "http://owncloud.org/ns/doi"
+	// LinkMetadataKey is the arbitrary metadata holding the token of the
+	// public link the identifier of a published folder resolves to.
+	LinkMetadataKey = "http://owncloud.org/ns/publication-link"
+)
+
+// Dataset describes a published folder to the registration agency.
+type Dataset struct {
+	Title       string
+	Creators    []string
+	Description string
+	// Year is the year of the publication.
+	Year int
+	// URL is the landing page the identifier resolves to.
+	URL string
+}
+
+// Provider mints persistent identifiers.
+type Provider interface {
+	// Mint registers the dataset and returns its new identifier.
+	Mint(ctx context.Context, d *Dataset) (string, error)
+	// ResolverURL returns the URL resolving the identifier.
+	ResolverURL(id string) string
+}
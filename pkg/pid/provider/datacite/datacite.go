@@ -0,0 +1,213 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package datacite mints DOIs through the REST API of DataCite.
+package datacite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/pid"
+	"github.com/cs3org/reva/pkg/pid/registry"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("datacite", New)
+}
+
+type config struct {
+	Endpoint     string `mapstructure:"endpoint" docs:"https://api.datacite.org;The DataCite REST API, https://api.test.datacite.org for the test environment."`
+	RepositoryID string `mapstructure:"repository_id" docs:";The id of the repository the DOIs are minted for."`
+	Password     string `mapstructure:"password" docs:";The password of the repository."`
+	Prefix       string `mapstructure:"prefix" docs:";The DOI prefix of the repository, the suffixes are generated by DataCite."`
+	Publisher    string `mapstructure:"publisher" docs:";The publisher of the datasets, usually the name of the institution."`
+	Draft        bool   `mapstructure:"draft" docs:"false;Whether the DOIs are minted as drafts, which can be deleted but are not resolvable, instead of findable."`
+	Resolver     string `mapstructure:"resolver" docs:"https://doi.org;The resolver of the DOIs."`
+	Insecure     bool   `mapstructure:"insecure" docs:"false;Whether to skip the verification of the certificate of the API."`
+	Timeout      int    `mapstructure:"timeout" docs:"10;Timeout in seconds of the requests to the API."`
+}
+
+func (c *config) init() {
+	if c.Endpoint == "" {
+		c.Endpoint = "https://api.datacite.org"
+	}
+	c.Endpoint = strings.TrimSuffix(c.Endpoint, "/")
+	if c.Resolver == "" {
+		c.Resolver = "https://doi.org"
+	}
+	c.Resolver = strings.TrimSuffix(c.Resolver, "/")
+	if c.Timeout <= 0 {
+		c.Timeout = 10
+	}
+}
+
+type provider struct {
+	c      *config
+	client *http.Client
+}
+
+// New returns a PID provider minting DOIs with DataCite.
+func New(m map[string]interface{}) (pid.Provider, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	c.init()
+	if c.RepositoryID == "" || c.Prefix == "" || c.Publisher == "" {
+		return nil, errors.New("datacite: repository_id, prefix and publisher are required")
+	}
+
+	return &provider{
+		c:      c,
+		client: rhttp.GetHTTPClient(rhttp.Timeout(time.Duration(c.Timeout)*time.Second), rhttp.Insecure(c.Insecure)),
+	}, nil
+}
+
+type name struct {
+	Name string `json:"name"`
+}
+
+type title struct {
+	Title string `json:"title"`
+}
+
+type description struct {
+	Description     string `json:"description"`
+	DescriptionType string `json:"descriptionType"`
+}
+
+type attributes struct {
+	DOI             string            `json:"doi,omitempty"`
+	Prefix          string            `json:"prefix,omitempty"`
+	Event           string            `json:"event,omitempty"`
+	Creators        []name            `json:"creators,omitempty"`
+	Titles          []title           `json:"titles,omitempty"`
+	Descriptions    []description     `json:"descriptions,omitempty"`
+	Publisher       string            `json:"publisher,omitempty"`
+	PublicationYear int               `json:"publicationYear,omitempty"`
+	Types           map[string]string `json:"types,omitempty"`
+	URL             string            `json:"url,omitempty"`
+}
+
+type document struct {
+	Data struct {
+		ID         string     `json:"id,omitempty"`
+		Type       string     `json:"type"`
+		Attributes attributes `json:"attributes"`
+	} `json:"data"`
+}
+
+type errorDocument struct {
+	Errors []struct {
+		Source string `json:"source"`
+		Title  string `json:"title"`
+	} `json:"errors"`
+}
+
+func (p *provider) Mint(ctx context.Context, d *pid.Dataset) (string, error) {
+	doc := &document{}
+	doc.Data.Type = "dois"
+	doc.Data.Attributes = attributes{
+		Prefix:          p.c.Prefix,
+		Titles:          []title{{Title: d.Title}},
+		Publisher:       p.c.Publisher,
+		PublicationYear: d.Year,
+		Types:           map[string]string{"resourceTypeGeneral": "Dataset"},
+		URL:             d.URL,
+	}
+	if !p.c.Draft {
+		doc.Data.Attributes.Event = "publish"
+	}
+	for _, c := range d.Creators {
+		doc.Data.Attributes.Creators = append(doc.Data.Attributes.Creators, name{Name: c})
+	}
+	if d.Description != "" {
+		doc.Data.Attributes.Descriptions = []description{{Description: d.Description, DescriptionType: "Abstract"}}
+	}
+
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.c.Endpoint+"/dois", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/vnd.api+json")
+	req.SetBasicAuth(p.c.RepositoryID, p.c.Password)
+	res, err := p.client.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "datacite: error minting the DOI")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("datacite: error minting the DOI: %s%s", res.Status, errorMessage(res.Body))
+	}
+	created := &document{}
+	if err := json.NewDecoder(res.Body).Decode(created); err != nil {
+		return "", errors.Wrap(err, "datacite: error decoding response")
+	}
+	doi := created.Data.Attributes.DOI
+	if doi == "" {
+		doi = created.Data.ID
+	}
+	if doi == "" {
+		return "", errors.New("datacite: no DOI in the response")
+	}
+	return doi, nil
+}
+
+func (p *provider) ResolverURL(id string) string {
+	return p.c.Resolver + "/" + id
+}
+
+// errorMessage returns the errors listed in the body of a failed request.
+func errorMessage(r io.Reader) string {
+	data, _ := ioutil.ReadAll(io.LimitReader(r, 64*1024))
+	doc := &errorDocument{}
+	if err := json.Unmarshal(data, doc); err != nil || len(doc.Errors) == 0 {
+		if len(data) == 0 {
+			return ""
+		}
+		if len(data) > 1024 {
+			data = data[:1024]
+		}
+		return " " + string(data)
+	}
+	msgs := make([]string, 0, len(doc.Errors))
+	for _, e := range doc.Errors {
+		if e.Source != "" {
+			msgs = append(msgs, e.Source+": "+e.Title)
+		} else {
+			msgs = append(msgs, e.Title)
+		}
+	}
+	return " " + strings.Join(msgs, ", ")
+}
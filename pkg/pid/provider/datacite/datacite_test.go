@@ -0,0 +1,119 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package datacite
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/pid"
+)
+
+func TestMint(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/dois" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if user, pass, _ := r.BasicAuth(); user != "CERN.REVA" || pass != "secret" {
+			t.Errorf("unexpected credentials %s:%s", user, pass)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/vnd.api+json" {
+			t.Errorf("unexpected content type %q", ct)
+		}
+		doc := &document{}
+		if err := json.NewDecoder(r.Body).Decode(doc); err != nil {
+			t.Fatal(err)
+		}
+		a := doc.Data.Attributes
+		if doc.Data.Type != "dois" || a.Prefix != "10.1234" || a.Event != "publish" || a.Publisher != "CERN" ||
+			a.PublicationYear != 2026 || a.URL != "https://cloud.example.org/s/abc" || a.Types["resourceTypeGeneral"] != "Dataset" {
+			t.Errorf("unexpected attributes %+v", a)
+		}
+		if len(a.Creators) != 2 || a.Creators[1].Name != "Einstein, Albert" || len(a.Titles) != 1 || a.Titles[0].Title != "Measurements" {
+			t.Errorf("unexpected creators or titles %+v", a)
+		}
+		if len(a.Descriptions) != 0 {
+			t.Errorf("expected no descriptions, got %+v", a.Descriptions)
+		}
+
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"data":{"id":"10.1234/abcd-1234","type":"dois","attributes":{"doi":"10.1234/abcd-1234","state":"findable"}}}`))
+	}))
+	defer s.Close()
+
+	p, err := New(map[string]interface{}{
+		"endpoint":      s.URL + "/",
+		"repository_id": "CERN.REVA",
+		"password":      "secret",
+		"prefix":        "10.1234",
+		"publisher":     "CERN",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doi, err := p.Mint(context.Background(), &pid.Dataset{
+		Title:    "Measurements",
+		Creators: []string{"Curie, Marie", "Einstein, Albert"},
+		Year:     2026,
+		URL:      "https://cloud.example.org/s/abc",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doi != "10.1234/abcd-1234" {
+		t.Errorf("expected DOI 10.1234/abcd-1234, got %s", doi)
+	}
+	if u := p.ResolverURL(doi); u != "https://doi.org/10.1234/abcd-1234" {
+		t.Errorf("unexpected resolver URL %s", u)
+	}
+}
+
+func TestMintError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"errors":[{"source":"url","title":"Can't be blank"}]}`))
+	}))
+	defer s.Close()
+
+	p, err := New(map[string]interface{}{
+		"endpoint":      s.URL,
+		"repository_id": "CERN.REVA",
+		"prefix":        "10.1234",
+		"publisher":     "CERN",
+		"draft":         true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.Mint(context.Background(), &pid.Dataset{Title: "Measurements", Year: 2026})
+	if err == nil || !strings.Contains(err.Error(), "url: Can't be blank") {
+		t.Errorf("expected the error of DataCite, got %v", err)
+	}
+}
+
+func TestNewRequiresRepository(t *testing.T) {
+	if _, err := New(map[string]interface{}{"prefix": "10.1234"}); err == nil {
+		t.Error("expected an error without repository and publisher")
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package disk
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/preview"
+	"github.com/cs3org/reva/pkg/preview/cache/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("disk", New)
+}
+
+type config struct {
+	Dir string `mapstructure:"dir"`
+	// MaxSize is the number of bytes the cached previews may take, the
+	// least recently used ones are evicted beyond it.
+	MaxSize int64 `mapstructure:"max_size"`
+}
+
+type cache struct {
+	c    *config
+	mu   sync.Mutex
+	size int64
+}
+
+// New returns a preview cache storing the previews in a local directory.
+func New(m map[string]interface{}) (preview.Cache, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	if c.Dir == "" {
+		c.Dir = filepath.Join(os.TempDir(), "reva-previews")
+	}
+	if c.MaxSize == 0 {
+		c.MaxSize = 1024 * 1024 * 1024
+	}
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "preview: error creating cache dir")
+	}
+
+	cc := &cache{c: c}
+	files, err := cc.files()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		cc.size += f.size
+	}
+	return cc, nil
+}
+
+// the previews are spread over subdirectories named after the first
+// characters of their keys
+func (c *cache) path(key string) string {
+	return filepath.Join(c.c.Dir, key[:2], key)
+}
+
+func (c *cache) Get(ctx context.Context, key string) ([]byte, error) {
+	p := c.path(key)
+	data, err := ioutil.ReadFile(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errtypes.NotFound(key)
+		}
+		return nil, err
+	}
+	// the modification time tracks the last use for the eviction
+	now := time.Now()
+	_ = os.Chtimes(p, now, now)
+	return data, nil
+}
+
+func (c *cache) Set(ctx context.Context, key string, data []byte) error {
+	p := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(p), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	var old int64
+	if fi, err := os.Stat(p); err == nil {
+		old = fi.Size()
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size += int64(len(data)) - old
+	if c.size > c.c.MaxSize {
+		return c.evict()
+	}
+	return nil
+}
+
+type file struct {
+	path  string
+	size  int64
+	mtime time.Time
+}
+
+func (c *cache) files() ([]file, error) {
+	var files []file
+	err := filepath.Walk(c.c.Dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			files = append(files, file{path: p, size: fi.Size(), mtime: fi.ModTime()})
+		}
+		return nil
+	})
+	return files, err
+}
+
+// evict removes the least recently used previews until the cache is back
+// under 90% of its maximum size, leaving room for the next ones.
+func (c *cache) evict() error {
+	files, err := c.files()
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].mtime.Before(files[j].mtime) })
+
+	c.size = 0
+	for _, f := range files {
+		c.size += f.size
+	}
+	target := c.c.MaxSize / 10 * 9
+	for _, f := range files {
+		if c.size <= target {
+			break
+		}
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		c.size -= f.size
+	}
+	return nil
+}
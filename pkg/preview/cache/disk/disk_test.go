@@ -0,0 +1,90 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package disk
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+func key(c byte) string {
+	return strings.Repeat(string(c), 64)
+}
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "previews")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+
+	c, err := New(map[string]interface{}{"dir": dir, "max_size": 250})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, key('a')); err == nil {
+		t.Fatal("expected not found error")
+	} else if _, ok := err.(errtypes.IsNotFound); !ok {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	data := bytes.Repeat([]byte{1}, 100)
+	for _, k := range []byte("ab") {
+		if err := c.Set(ctx, key(k), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a was stored first but used last
+	past := time.Now().Add(-time.Hour)
+	_ = os.Chtimes(filepath.Join(dir, "bb", key('b')), past, past)
+	if got, err := c.Get(ctx, key('a')); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("got %v, %v", got, err)
+	}
+
+	// exceeding the maximum size evicts the least recently used preview
+	if err := c.Set(ctx, key('c'), data); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, key('b')); err == nil {
+		t.Error("expected b to be evicted")
+	}
+	for _, k := range []byte("ac") {
+		if _, err := c.Get(ctx, key(k)); err != nil {
+			t.Errorf("expected %c to be cached: %v", k, err)
+		}
+	}
+
+	// the size of the cache is restored on restart
+	c, err = New(map[string]interface{}{"dir": dir, "max_size": 250})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := c.(*cache).size; size != 200 {
+		t.Errorf("got size %d, expected 200", size)
+	}
+}
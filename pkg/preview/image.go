@@ -0,0 +1,271 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package preview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+
+	// register the decoders of the supported formats
+	_ "image/gif"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// thumbnail decodes an image and scales it down to the requested size.
+// JPEG images are encoded as JPEG, the others as PNG to keep transparency.
+func thumbnail(data []byte, r Request, maxPixels, quality int) (*Preview, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errtypes.NotSupported("invalid image: " + err.Error())
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, errtypes.NotSupported("image too large for a preview")
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errtypes.NotSupported("invalid image: " + err.Error())
+	}
+
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+	// the image is scaled before being rotated, so the requested size is
+	// rotated too
+	if orientation >= 5 {
+		r.Width, r.Height = r.Height, r.Width
+	}
+	out := orient(scale(img, r), orientation)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		if err := jpeg.Encode(&buf, out, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, err
+		}
+		return &Preview{Data: buf.Bytes(), ContentType: "image/jpeg"}, nil
+	}
+	if err := png.Encode(&buf, out); err != nil {
+		return nil, err
+	}
+	return &Preview{Data: buf.Bytes(), ContentType: "image/png"}, nil
+}
+
+// scale fits an image into the requested size, cropping its center to the
+// requested aspect ratio unless the aspect is kept.
+func scale(img image.Image, r Request) *image.RGBA {
+	src := img.Bounds()
+	sw, sh := src.Dx(), src.Dy()
+
+	if !r.KeepAspect {
+		// the largest centered area with the requested aspect ratio
+		cw, ch := sw, sw*r.Height/r.Width
+		if ch > sh {
+			cw, ch = sh*r.Width/r.Height, sh
+		}
+		cw, ch = atLeast1(cw), atLeast1(ch)
+		x0, y0 := src.Min.X+(sw-cw)/2, src.Min.Y+(sh-ch)/2
+		src = image.Rect(x0, y0, x0+cw, y0+ch)
+		sw, sh = cw, ch
+	}
+
+	f := math.Min(float64(r.Width)/float64(sw), float64(r.Height)/float64(sh))
+	if f > 1 {
+		f = 1
+	}
+	dw, dh := atLeast1(int(math.Round(float64(sw)*f))), atLeast1(int(math.Round(float64(sh)*f)))
+
+	rgba := image.NewRGBA(image.Rect(0, 0, sw, sh))
+	draw.Draw(rgba, rgba.Bounds(), img, src.Min, draw.Src)
+	if dw == sw && dh == sh {
+		return rgba
+	}
+	return resample(rgba, dw, dh)
+}
+
+type weight struct {
+	index int
+	value float64
+}
+
+// boxWeights returns, for every destination pixel, the source pixels it
+// covers and their share of it.
+func boxWeights(n, m int) [][]weight {
+	ws := make([][]weight, m)
+	ratio := float64(n) / float64(m)
+	for i := range ws {
+		start, end := float64(i)*ratio, float64(i+1)*ratio
+		for j := int(start); j < n && float64(j) < end; j++ {
+			cover := math.Min(end, float64(j+1)) - math.Max(start, float64(j))
+			if cover > 0 {
+				ws[i] = append(ws[i], weight{j, cover / ratio})
+			}
+		}
+	}
+	return ws
+}
+
+// resample scales an image down by averaging the source pixels covered by
+// each destination pixel, first horizontally then vertically. The colors
+// are premultiplied, so transparent pixels do not bleed into the result.
+func resample(src *image.RGBA, dw, dh int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	xw, yw := boxWeights(sw, dw), boxWeights(sh, dh)
+
+	tmp := make([]float64, dw*sh*4)
+	for y := 0; y < sh; y++ {
+		row := src.Pix[y*src.Stride:]
+		for x, ws := range xw {
+			t := tmp[(y*dw+x)*4:]
+			for _, w := range ws {
+				p := row[w.index*4:]
+				t[0] += float64(p[0]) * w.value
+				t[1] += float64(p[1]) * w.value
+				t[2] += float64(p[2]) * w.value
+				t[3] += float64(p[3]) * w.value
+			}
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y, ws := range yw {
+		for x := 0; x < dw; x++ {
+			var c [4]float64
+			for _, w := range ws {
+				t := tmp[(w.index*dw+x)*4:]
+				for k := range c {
+					c[k] += t[k] * w.value
+				}
+			}
+			d := dst.Pix[y*dst.Stride+x*4:]
+			for k := range c {
+				d[k] = uint8(math.Min(255, math.Round(c[k])))
+			}
+		}
+	}
+	return dst
+}
+
+// orient applies an EXIF orientation to an image.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored
+				sx, sy = w-1-x, y
+			case 3: // rotated by 180°
+				sx, sy = w-1-x, h-1-y
+			case 4: // flipped
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // rotated by 90° clockwise
+				sx, sy = y, h-1-x
+			case 7: // transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotated by 90° counterclockwise
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[y*dst.Stride+x*4:y*dst.Stride+x*4+4], src.Pix[sy*src.Stride+sx*4:])
+		}
+	}
+	return dst
+}
+
+// jpegOrientation returns the EXIF orientation of a JPEG image, 1 if it has
+// none.
+func jpegOrientation(b []byte) int {
+	if len(b) < 4 || b[0] != 0xFF || b[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(b); {
+		if b[i] != 0xFF {
+			return 1
+		}
+		marker := b[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			// the metadata precedes the image data
+			return 1
+		}
+		l := int(binary.BigEndian.Uint16(b[i+2:]))
+		if l < 2 || i+2+l > len(b) {
+			return 1
+		}
+		if seg := b[i+4 : i+2+l]; marker == 0xE1 && len(seg) > 6 && string(seg[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(seg[6:])
+		}
+		i += 2 + l
+	}
+	return 1
+}
+
+func tiffOrientation(t []byte) int {
+	if len(t) < 8 {
+		return 1
+	}
+	var bo binary.ByteOrder
+	switch string(t[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(bo.Uint32(t[4:]))
+	if ifd < 8 || ifd+2 > len(t) {
+		return 1
+	}
+	n := int(bo.Uint16(t[ifd:]))
+	for k := 0; k < n; k++ {
+		e := ifd + 2 + k*12
+		if e+12 > len(t) {
+			return 1
+		}
+		if bo.Uint16(t[e:]) == 0x0112 {
+			if v := int(bo.Uint16(t[e+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+func atLeast1(v int) int {
+	if v < 1 {
+		return 1
+	}
+	return v
+}
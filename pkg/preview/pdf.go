@@ -0,0 +1,61 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package preview
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/pkg/errors"
+)
+
+// renderPDF renders the first page of a PDF document with the configured
+// command, which writes a PNG image to its standard output.
+func renderPDF(ctx context.Context, command []string, data []byte, size int) ([]byte, error) {
+	f, err := ioutil.TempFile("", "reva-preview-*.pdf")
+	if err != nil {
+		return nil, errors.Wrap(err, "preview: error creating temporary file")
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, errors.Wrap(err, "preview: error writing temporary file")
+	}
+	if err := f.Close(); err != nil {
+		return nil, errors.Wrap(err, "preview: error writing temporary file")
+	}
+
+	args := commandArgs(command, f.Name(), size)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.Wrap(ctx.Err(), "preview: pdf command did not complete")
+		}
+		// most likely a damaged or encrypted document
+		return nil, errtypes.NotSupported("pdf preview failed: " + err.Error() + ": " + string(bytes.TrimSpace(stderr.Bytes())))
+	}
+	return stdout.Bytes(), nil
+}
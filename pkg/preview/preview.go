@@ -0,0 +1,254 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package preview generates the thumbnails of images and the previews of
+// the first page of PDF documents served to the clients.
+package preview
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache stores the generated previews.
+type Cache interface {
+	// Get returns a preview, errtypes.NotFound if it is not cached.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores a preview.
+	Set(ctx context.Context, key string, data []byte) error
+}
+
+// Config holds the configuration of the previews.
+type Config struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MaxSourceSize is the size in bytes of the largest file previewed.
+	MaxSourceSize int64 `mapstructure:"max_source_size"`
+	// MaxPixels is the number of pixels of the largest image decoded,
+	// protecting from images expanding to huge bitmaps.
+	MaxPixels int `mapstructure:"max_pixels"`
+	// MaxDimension bounds the width and the height of the previews.
+	MaxDimension int `mapstructure:"max_dimension"`
+	// Concurrency is the number of previews generated at the same time.
+	Concurrency int `mapstructure:"concurrency"`
+	JPEGQuality int `mapstructure:"jpeg_quality"`
+	// PDFCommand renders the first page of a PDF document as a PNG image on
+	// its standard output. {input} is replaced by the path of the document
+	// and {size} by the requested size in pixels.
+	PDFCommand []string `mapstructure:"pdf_command"`
+	// Timeout is the number of seconds the PDF command may run.
+	Timeout      int                               `mapstructure:"timeout"`
+	CacheDriver  string                            `mapstructure:"cache_driver"`
+	CacheDrivers map[string]map[string]interface{} `mapstructure:"cache_drivers"`
+}
+
+// Init sets the defaults of the configuration.
+func (c *Config) Init() {
+	if c.MaxSourceSize == 0 {
+		c.MaxSourceSize = 50 * 1024 * 1024
+	}
+	if c.MaxPixels == 0 {
+		c.MaxPixels = 40 * 1000 * 1000
+	}
+	if c.MaxDimension == 0 {
+		c.MaxDimension = 1024
+	}
+	if c.Concurrency == 0 {
+		c.Concurrency = runtime.NumCPU()
+	}
+	if c.JPEGQuality == 0 {
+		c.JPEGQuality = 85
+	}
+	if len(c.PDFCommand) == 0 {
+		c.PDFCommand = []string{"pdftoppm", "-png", "-singlefile", "-f", "1", "-l", "1", "-scale-to", "{size}", "{input}"}
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 30
+	}
+}
+
+// Request is the size of a preview. Unless KeepAspect is set, the preview
+// is cropped to the requested aspect ratio. Images are never scaled up.
+type Request struct {
+	Width      int
+	Height     int
+	KeepAspect bool
+}
+
+// Source is the file a preview is generated from.
+type Source struct {
+	ID       string
+	ETag     string
+	MimeType string
+	Size     uint64
+	// Open returns the content of the file, only called on cache misses.
+	Open func(ctx context.Context) (io.ReadCloser, error)
+}
+
+// Preview is a generated preview.
+type Preview struct {
+	Data        []byte
+	ContentType string
+}
+
+// Generator generates and caches the previews.
+type Generator struct {
+	c     *Config
+	cache Cache // nil if the previews are not cached
+	pdf   bool
+	sem   chan struct{}
+	group singleflight.Group
+}
+
+// New returns a generator of previews. The cache may be nil.
+func New(c *Config, cache Cache) *Generator {
+	c.Init()
+	_, err := exec.LookPath(c.PDFCommand[0])
+	return &Generator{
+		c:     c,
+		cache: cache,
+		pdf:   err == nil,
+		sem:   make(chan struct{}, c.Concurrency),
+	}
+}
+
+// Supports returns whether previews of a mime type can be generated.
+func (g *Generator) Supports(mimeType string) bool {
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	case "application/pdf":
+		return g.pdf
+	}
+	return false
+}
+
+// Preview returns the preview of a file. It fails with errtypes.NotSupported
+// when the file cannot be previewed, because of its type, its size or its
+// content.
+func (g *Generator) Preview(ctx context.Context, src *Source, r Request) (*Preview, error) {
+	if !g.Supports(src.MimeType) {
+		return nil, errtypes.NotSupported("no preview for " + src.MimeType)
+	}
+	if src.Size > uint64(g.c.MaxSourceSize) {
+		return nil, errtypes.NotSupported("file too large for a preview")
+	}
+	r = g.normalize(r)
+	key := cacheKey(src, r)
+
+	if g.cache != nil {
+		if data, err := g.cache.Get(ctx, key); err == nil {
+			return &Preview{Data: data, ContentType: http.DetectContentType(data)}, nil
+		}
+	}
+
+	v, err, _ := g.group.Do(key, func() (interface{}, error) {
+		p, err := g.generate(ctx, src, r)
+		if err != nil {
+			return nil, err
+		}
+		if g.cache != nil {
+			if err := g.cache.Set(ctx, key, p.Data); err != nil {
+				appctx.GetLogger(ctx).Error().Err(err).Str("id", src.ID).Msg("error caching preview")
+			}
+		}
+		return p, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Preview), nil
+}
+
+func (g *Generator) normalize(r Request) Request {
+	clamp := func(v int) int {
+		switch {
+		case v <= 0:
+			return 32
+		case v > g.c.MaxDimension:
+			return g.c.MaxDimension
+		}
+		return v
+	}
+	r.Width, r.Height = clamp(r.Width), clamp(r.Height)
+	return r
+}
+
+func cacheKey(src *Source, r Request) string {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%dx%d\x00%t", src.ID, src.ETag, r.Width, r.Height, r.KeepAspect)))
+	return hex.EncodeToString(h[:])
+}
+
+func (g *Generator) generate(ctx context.Context, src *Source, r Request) (*Preview, error) {
+	select {
+	case g.sem <- struct{}{}:
+		defer func() { <-g.sem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	rc, err := src.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, g.c.MaxSourceSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > g.c.MaxSourceSize {
+		return nil, errtypes.NotSupported("file too large for a preview")
+	}
+
+	if src.MimeType == "application/pdf" {
+		ctx, cancel := context.WithTimeout(ctx, time.Duration(g.c.Timeout)*time.Second)
+		defer cancel()
+		if data, err = renderPDF(ctx, g.c.PDFCommand, data, max(r.Width, r.Height)); err != nil {
+			return nil, err
+		}
+	}
+	return thumbnail(data, r, g.c.MaxPixels, g.c.JPEGQuality)
+}
+
+func commandArgs(command []string, input string, size int) []string {
+	args := make([]string, 0, len(command))
+	for _, a := range command {
+		a = strings.ReplaceAll(a, "{input}", input)
+		a = strings.ReplaceAll(a, "{size}", fmt.Sprint(size))
+		args = append(args, a)
+	}
+	return args
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
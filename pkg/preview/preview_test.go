@@ -0,0 +1,263 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package preview
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			// red left half, transparent right half
+			if x < w/2 {
+				img.Set(x, y, color.NRGBA{R: 255, A: 255})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decode(t *testing.T, p *Preview) image.Image {
+	img, _, err := image.Decode(bytes.NewReader(p.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func TestThumbnailSize(t *testing.T) {
+	data := encodePNG(t, 400, 200)
+	tests := []struct {
+		r    Request
+		w, h int
+	}{
+		{Request{Width: 100, Height: 100, KeepAspect: true}, 100, 50},
+		{Request{Width: 100, Height: 100}, 100, 100},
+		{Request{Width: 50, Height: 100}, 50, 100},
+		// never scaled up
+		{Request{Width: 1000, Height: 1000, KeepAspect: true}, 400, 200},
+		{Request{Width: 1000, Height: 1000}, 200, 200},
+	}
+	for _, tt := range tests {
+		p, err := thumbnail(data, tt.r, 1000000, 85)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.ContentType != "image/png" {
+			t.Errorf("%+v: got content type %s", tt.r, p.ContentType)
+		}
+		if b := decode(t, p).Bounds(); b.Dx() != tt.w || b.Dy() != tt.h {
+			t.Errorf("%+v: got %dx%d, want %dx%d", tt.r, b.Dx(), b.Dy(), tt.w, tt.h)
+		}
+	}
+}
+
+func TestThumbnailPixels(t *testing.T) {
+	p, err := thumbnail(encodePNG(t, 400, 200), Request{Width: 40, Height: 20, KeepAspect: true}, 1000000, 85)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := decode(t, p)
+	if c := color.NRGBAModel.Convert(img.At(5, 10)).(color.NRGBA); c != (color.NRGBA{R: 255, A: 255}) {
+		t.Errorf("left pixel: got %v", c)
+	}
+	if c := color.NRGBAModel.Convert(img.At(35, 10)).(color.NRGBA); c.A != 0 {
+		t.Errorf("right pixel: got %v", c)
+	}
+	// the pixels on the border are blended without darkening
+	if c := color.NRGBAModel.Convert(img.At(19, 10)).(color.NRGBA); c.R != 255 || c.A != 255 {
+		t.Errorf("border pixel: got %v", c)
+	}
+}
+
+func TestThumbnailLimits(t *testing.T) {
+	if _, err := thumbnail(encodePNG(t, 400, 200), Request{Width: 10, Height: 10}, 1000, 85); !isNotSupported(err) {
+		t.Errorf("expected not supported error for too many pixels, got %v", err)
+	}
+	if _, err := thumbnail([]byte("not an image"), Request{Width: 10, Height: 10}, 1000, 85); !isNotSupported(err) {
+		t.Errorf("expected not supported error for invalid image, got %v", err)
+	}
+}
+
+// exifJPEG returns a JPEG image with an EXIF orientation.
+func exifJPEG(t *testing.T, w, h, orientation int) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], uint16(orientation))
+	tiff = append(append(tiff, entry...), 0, 0, 0, 0)
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(seg)+2))
+
+	b := buf.Bytes()
+	return append(append(append([]byte{}, b[:2]...), append(app1, seg...)...), b[2:]...)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	for _, o := range []int{1, 3, 6, 8} {
+		data := exifJPEG(t, 200, 100, o)
+		if got := jpegOrientation(data); got != o {
+			t.Errorf("got orientation %d, want %d", got, o)
+		}
+		p, err := thumbnail(data, Request{Width: 100, Height: 100, KeepAspect: true}, 1000000, 85)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.ContentType != "image/jpeg" {
+			t.Errorf("got content type %s", p.ContentType)
+		}
+		w, h := 100, 50
+		if o >= 5 {
+			w, h = 50, 100
+		}
+		if b := decode(t, p).Bounds(); b.Dx() != w || b.Dy() != h {
+			t.Errorf("orientation %d: got %dx%d, want %dx%d", o, b.Dx(), b.Dy(), w, h)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// 2x1 image, red then blue
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	red, blue := color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255}
+	src.Set(0, 0, red)
+	src.Set(1, 0, blue)
+
+	tests := map[int][]color.RGBA{ // pixels of the result, row by row
+		2: {blue, red},
+		3: {blue, red},
+		4: {red, blue},
+		5: {red, blue},
+		6: {red, blue},
+		7: {blue, red},
+		8: {blue, red},
+	}
+	for o, want := range tests {
+		dst := orient(src, o)
+		var got []color.RGBA
+		for y := 0; y < dst.Rect.Dy(); y++ {
+			for x := 0; x < dst.Rect.Dx(); x++ {
+				got = append(got, dst.RGBAAt(x, y))
+			}
+		}
+		if (o >= 5) != (dst.Rect.Dy() == 2) || got[0] != want[0] || got[1] != want[1] {
+			t.Errorf("orientation %d: got %v in %v", o, got, dst.Rect)
+		}
+	}
+}
+
+type memCache struct {
+	sync.Mutex
+	m map[string][]byte
+}
+
+func (c *memCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.Lock()
+	defer c.Unlock()
+	if d, ok := c.m[key]; ok {
+		return d, nil
+	}
+	return nil, errtypes.NotFound(key)
+}
+
+func (c *memCache) Set(ctx context.Context, key string, data []byte) error {
+	c.Lock()
+	defer c.Unlock()
+	c.m[key] = data
+	return nil
+}
+
+func TestPreviewCache(t *testing.T) {
+	data := encodePNG(t, 64, 64)
+	var opened int32
+	src := &Source{
+		ID:       "id",
+		ETag:     "etag",
+		MimeType: "image/png",
+		Size:     uint64(len(data)),
+		Open: func(ctx context.Context) (io.ReadCloser, error) {
+			atomic.AddInt32(&opened, 1)
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		},
+	}
+	g := New(&Config{}, &memCache{m: map[string][]byte{}})
+
+	for i := 0; i < 3; i++ {
+		p, err := g.Preview(context.Background(), src, Request{Width: 16, Height: 16})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.ContentType != "image/png" {
+			t.Errorf("got content type %s", p.ContentType)
+		}
+	}
+	if opened != 1 {
+		t.Errorf("source opened %d times, expected 1", opened)
+	}
+
+	// a new version of the file is previewed again
+	src.ETag = "etag2"
+	if _, err := g.Preview(context.Background(), src, Request{Width: 16, Height: 16}); err != nil {
+		t.Fatal(err)
+	}
+	if opened != 2 {
+		t.Errorf("source opened %d times, expected 2", opened)
+	}
+}
+
+func TestPreviewUnsupported(t *testing.T) {
+	g := New(&Config{MaxSourceSize: 10}, nil)
+	if _, err := g.Preview(context.Background(), &Source{MimeType: "text/plain"}, Request{}); !isNotSupported(err) {
+		t.Errorf("expected not supported error for text, got %v", err)
+	}
+	if _, err := g.Preview(context.Background(), &Source{MimeType: "image/png", Size: 11}, Request{}); !isNotSupported(err) {
+		t.Errorf("expected not supported error for large file, got %v", err)
+	}
+}
+
+func isNotSupported(err error) bool {
+	_, ok := err.(errtypes.IsNotSupported)
+	return ok
+}
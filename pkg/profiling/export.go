@@ -0,0 +1,120 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/pkg/errors"
+)
+
+const bundlePrefix = "reva-profile-"
+
+// Exporter stores the profile bundles.
+type Exporter interface {
+	Export(ctx context.Context, name string, bundle []byte) error
+}
+
+// S3Config holds the configuration of the object store the bundles are
+// exported to.
+type S3Config struct {
+	Endpoint  string `mapstructure:"endpoint"`
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	Prefix    string `mapstructure:"prefix"`
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+}
+
+type dirExporter struct {
+	dir  string
+	keep int
+}
+
+func newDirExporter(dir string, keep int) (*dirExporter, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "profiling: error creating bundle directory")
+	}
+	return &dirExporter{dir: dir, keep: keep}, nil
+}
+
+// Export writes the bundle to the directory and removes the oldest bundles
+// exceeding the configured number.
+func (e *dirExporter) Export(_ context.Context, name string, bundle []byte) error {
+	if err := os.WriteFile(filepath.Join(e.dir, name), bundle, 0600); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(e.dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), bundlePrefix) {
+			names = append(names, entry.Name())
+		}
+	}
+	// names end with the UTC timestamp, so they sort chronologically per host
+	sort.Strings(names)
+	for len(names) > e.keep {
+		_ = os.Remove(filepath.Join(e.dir, names[0]))
+		names = names[1:]
+	}
+	return nil
+}
+
+type s3Exporter struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+func newS3Exporter(c *S3Config) (*s3Exporter, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "profiling: error parsing s3 endpoint")
+	}
+	client, err := minio.New(u.Host, &minio.Options{
+		Region: c.Region,
+		Creds:  credentials.NewStaticV4(c.AccessKey, c.SecretKey, ""),
+		Secure: u.Scheme != "http",
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "profiling: error creating s3 client")
+	}
+	return &s3Exporter{client: client, bucket: c.Bucket, prefix: c.Prefix}, nil
+}
+
+// Export uploads the bundle to the bucket, the lifecycle of the objects is
+// left to the bucket policies.
+func (e *s3Exporter) Export(ctx context.Context, name string, bundle []byte) error {
+	key := path.Join(e.prefix, name)
+	_, err := e.client.PutObject(ctx, e.bucket, key, bytes.NewReader(bundle), int64(len(bundle)), minio.PutObjectOptions{ContentType: "application/gzip"})
+	return errors.Wrapf(err, "profiling: error uploading %s", key)
+}
@@ -0,0 +1,297 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package profiling
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Folded is a profile reduced to its collapsed stacks, the format rendered
+// by the flamegraph tools.
+type Folded struct {
+	// Stacks map the frames from the root to the leaf, separated by
+	// semicolons, to their values. The stacks sampled while serving an RPC
+	// method start with a frame naming it.
+	Stacks map[string]int64
+	// Methods map the RPC methods to the values sampled while serving them.
+	Methods map[string]int64
+}
+
+// WriteTo writes the collapsed stacks, one per line.
+func (f *Folded) WriteTo(w io.Writer) (int64, error) {
+	stacks := make([]string, 0, len(f.Stacks))
+	for s := range f.Stacks {
+		stacks = append(stacks, s)
+	}
+	sort.Strings(stacks)
+
+	bw := bufio.NewWriter(w)
+	var n int64
+	for _, s := range stacks {
+		c, err := fmt.Fprintf(bw, "%s %d\n", s, f.Stacks[s])
+		n += int64(c)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, bw.Flush()
+}
+
+// the subset of the pprof profile.proto needed to fold the stacks
+type profile struct {
+	sampleTypes []int64
+	samples     []sample
+	locations   map[uint64][]uint64
+	functions   map[uint64]int64
+	strings     []string
+}
+
+type sample struct {
+	locations []uint64
+	values    []int64
+	labels    map[int64]int64
+}
+
+// Fold reduces a pprof profile, gzipped or not, to its collapsed stacks. The
+// values are the ones of the sample type prefixed by valueType, e.g. "cpu"
+// or "alloc_space", or the last sample type if none matches.
+func Fold(r io.Reader, valueType string) (*Folded, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 1 && data[0] == 0x1f && data[1] == 0x8b {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = io.ReadAll(gz); err != nil {
+			return nil, err
+		}
+	}
+
+	p := &profile{locations: map[uint64][]uint64{}, functions: map[uint64]int64{}}
+	if err := p.decode(data); err != nil {
+		return nil, errors.Wrap(err, "profiling: error decoding profile")
+	}
+
+	idx := len(p.sampleTypes) - 1
+	for i, t := range p.sampleTypes {
+		if strings.HasPrefix(p.str(t), valueType) {
+			idx = i
+			break
+		}
+	}
+
+	f := &Folded{Stacks: map[string]int64{}, Methods: map[string]int64{}}
+	if idx < 0 {
+		return f, nil
+	}
+	methodKey := p.index(MethodLabel)
+	for _, s := range p.samples {
+		if idx >= len(s.values) {
+			continue
+		}
+		v := s.values[idx]
+
+		var frames []string
+		if m, ok := s.labels[methodKey]; ok && methodKey >= 0 {
+			method := p.str(m)
+			f.Methods[method] += v
+			frames = append(frames, method)
+		}
+		// the locations and their inlined functions go from the leaf to the root
+		for i := len(s.locations) - 1; i >= 0; i-- {
+			funcs := p.locations[s.locations[i]]
+			for j := len(funcs) - 1; j >= 0; j-- {
+				frames = append(frames, p.str(p.functions[funcs[j]]))
+			}
+		}
+		if len(frames) > 0 {
+			f.Stacks[strings.Join(frames, ";")] += v
+		}
+	}
+	return f, nil
+}
+
+func (p *profile) str(i int64) string {
+	if i < 0 || int(i) >= len(p.strings) {
+		return ""
+	}
+	return p.strings[i]
+}
+
+func (p *profile) index(s string) int64 {
+	for i, v := range p.strings {
+		if v == s {
+			return int64(i)
+		}
+	}
+	return -1
+}
+
+func (p *profile) decode(b []byte) error {
+	return decodeMessage(b, func(num protowire.Number, typ protowire.Type, v uint64, m []byte) error {
+		switch num {
+		case 1: // sample_type
+			return decodeMessage(m, func(num protowire.Number, _ protowire.Type, v uint64, _ []byte) error {
+				if num == 1 {
+					p.sampleTypes = append(p.sampleTypes, int64(v))
+				}
+				return nil
+			})
+		case 2: // sample
+			s := sample{labels: map[int64]int64{}}
+			err := decodeMessage(m, func(num protowire.Number, typ protowire.Type, v uint64, m []byte) error {
+				switch num {
+				case 1:
+					return appendUints(&s.locations, typ, v, m)
+				case 2:
+					var values []uint64
+					if err := appendUints(&values, typ, v, m); err != nil {
+						return err
+					}
+					for _, v := range values {
+						s.values = append(s.values, int64(v))
+					}
+				case 3:
+					var key, str int64
+					err := decodeMessage(m, func(num protowire.Number, _ protowire.Type, v uint64, _ []byte) error {
+						switch num {
+						case 1:
+							key = int64(v)
+						case 2:
+							str = int64(v)
+						}
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+					if str != 0 {
+						s.labels[key] = str
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			p.samples = append(p.samples, s)
+		case 4: // location
+			var id uint64
+			var funcs []uint64
+			err := decodeMessage(m, func(num protowire.Number, _ protowire.Type, v uint64, m []byte) error {
+				switch num {
+				case 1:
+					id = v
+				case 4:
+					return decodeMessage(m, func(num protowire.Number, _ protowire.Type, v uint64, _ []byte) error {
+						if num == 1 {
+							funcs = append(funcs, v)
+						}
+						return nil
+					})
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			p.locations[id] = funcs
+		case 5: // function
+			var id uint64
+			var name int64
+			err := decodeMessage(m, func(num protowire.Number, _ protowire.Type, v uint64, _ []byte) error {
+				switch num {
+				case 1:
+					id = v
+				case 2:
+					name = int64(v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			p.functions[id] = name
+		case 6: // string_table
+			p.strings = append(p.strings, string(m))
+		}
+		return nil
+	})
+}
+
+// decodeMessage calls f with the fields of a protobuf message, with the value
+// of the varint fields or the content of the length delimited ones.
+func decodeMessage(b []byte, f func(num protowire.Number, typ protowire.Type, v uint64, m []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var v uint64
+		var m []byte
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			m, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := f(num, typ, v, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendUints appends a repeated integer field, packed or not.
+func appendUints(dst *[]uint64, typ protowire.Type, v uint64, m []byte) error {
+	if typ == protowire.VarintType {
+		*dst = append(*dst, v)
+		return nil
+	}
+	for len(m) > 0 {
+		v, n := protowire.ConsumeVarint(m)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		*dst = append(*dst, v)
+		m = m[n:]
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package profiling
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"runtime/pprof"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func message(fields ...func([]byte) []byte) []byte {
+	var b []byte
+	for _, f := range fields {
+		b = f(b)
+	}
+	return b
+}
+
+func varint(num protowire.Number, v uint64) func([]byte) []byte {
+	return func(b []byte) []byte {
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, v)
+	}
+}
+
+func bytesField(num protowire.Number, v []byte) func([]byte) []byte {
+	return func(b []byte) []byte {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, v)
+	}
+}
+
+func packed(num protowire.Number, vs ...uint64) func([]byte) []byte {
+	var p []byte
+	for _, v := range vs {
+		p = protowire.AppendVarint(p, v)
+	}
+	return bytesField(num, p)
+}
+
+func testProfile() []byte {
+	strs := []string{"", "samples", "count", "cpu", "nanoseconds", MethodLabel, "/cs3.gateway.v1beta1.GatewayAPI/Stat", "main", "handler", "leaf"}
+	var fields []func([]byte) []byte
+	fields = append(fields,
+		bytesField(1, message(varint(1, 1), varint(2, 2))),
+		bytesField(1, message(varint(1, 3), varint(2, 4))),
+		// a sample serving Stat, with the leaf inlined in the handler
+		bytesField(2, message(packed(1, 1, 2), packed(2, 1, 10), bytesField(3, message(varint(1, 5), varint(2, 6))))),
+		// an unpacked sample outside of any method
+		bytesField(2, message(varint(1, 2), varint(2, 1), varint(2, 5))),
+		bytesField(4, message(varint(1, 1), bytesField(4, message(varint(1, 3))), bytesField(4, message(varint(1, 2))))),
+		bytesField(4, message(varint(1, 2), bytesField(4, message(varint(1, 1))))),
+		bytesField(5, message(varint(1, 1), varint(2, 7))),
+		bytesField(5, message(varint(1, 2), varint(2, 8))),
+		bytesField(5, message(varint(1, 3), varint(2, 9))),
+	)
+	for _, s := range strs {
+		fields = append(fields, bytesField(6, []byte(s)))
+	}
+	return message(fields...)
+}
+
+func TestFold(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_, _ = w.Write(testProfile())
+	_ = w.Close()
+
+	for name, data := range map[string][]byte{"plain": testProfile(), "gzipped": gz.Bytes()} {
+		f, err := Fold(bytes.NewReader(data), "cpu")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		stacks := map[string]int64{
+			"/cs3.gateway.v1beta1.GatewayAPI/Stat;main;handler;leaf": 10,
+			"main": 5,
+		}
+		if !reflect.DeepEqual(f.Stacks, stacks) {
+			t.Errorf("%s: got stacks %v, want %v", name, f.Stacks, stacks)
+		}
+		methods := map[string]int64{"/cs3.gateway.v1beta1.GatewayAPI/Stat": 10}
+		if !reflect.DeepEqual(f.Methods, methods) {
+			t.Errorf("%s: got methods %v, want %v", name, f.Methods, methods)
+		}
+
+		var out bytes.Buffer
+		if _, err := f.WriteTo(&out); err != nil {
+			t.Fatal(err)
+		}
+		if want := "/cs3.gateway.v1beta1.GatewayAPI/Stat;main;handler;leaf 10\nmain 5\n"; out.String() != want {
+			t.Errorf("%s: got %q, want %q", name, out.String(), want)
+		}
+	}
+}
+
+func TestFoldRuntimeProfile(t *testing.T) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Fold(&buf, "goroutine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for s := range f.Stacks {
+		if strings.Contains(s, "TestFoldRuntimeProfile") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("the stack of the test is missing from %v", f.Stacks)
+	}
+}
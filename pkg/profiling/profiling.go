@@ -0,0 +1,249 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package profiling implements an opt-in agent continuously sampling the
+// profiles of the running daemon and exporting them periodically, so that
+// performance regressions can be diagnosed in production without manual
+// pprof sessions.
+package profiling
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime/pprof"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// MethodLabel is the profiler label carrying the RPC method being served,
+// which attributes the CPU samples to the methods.
+const MethodLabel = "rpc_method"
+
+// Do calls f with the profiler labels of the given RPC method.
+func Do(ctx context.Context, method string, f func(context.Context)) {
+	pprof.Do(ctx, pprof.Labels(MethodLabel, method), f)
+}
+
+// Config holds the configuration of the profiling agent.
+type Config struct {
+	// Interval is the number of seconds between two exports.
+	Interval int `mapstructure:"interval"`
+	// CPUDuration is the number of seconds the CPU profile is sampled in every interval.
+	CPUDuration int `mapstructure:"cpu_duration"`
+	// Profiles are the profiles in the bundles: cpu, heap, allocs, goroutine, mutex and block.
+	Profiles []string `mapstructure:"profiles"`
+	// Dir is the directory the bundles are exported to.
+	Dir string `mapstructure:"dir"`
+	// Keep is the number of bundles kept in the directory, older ones are removed.
+	Keep int `mapstructure:"keep"`
+	// S3 is the object store the bundles are exported to.
+	S3 S3Config `mapstructure:"s3"`
+}
+
+// Enabled returns whether the agent is configured, which requires a
+// destination for the bundles.
+func (c *Config) Enabled() bool {
+	return c.Dir != "" || c.S3.Bucket != ""
+}
+
+func (c *Config) init() {
+	if c.Interval <= 0 {
+		c.Interval = 300
+	}
+	if c.CPUDuration <= 0 {
+		c.CPUDuration = 30
+	}
+	if c.CPUDuration > c.Interval {
+		c.CPUDuration = c.Interval
+	}
+	if len(c.Profiles) == 0 {
+		c.Profiles = []string{"cpu", "allocs", "goroutine"}
+	}
+	if c.Keep <= 0 {
+		c.Keep = 24
+	}
+}
+
+// Agent samples the profiles and exports them as bundles.
+type Agent struct {
+	c         *Config
+	log       *zerolog.Logger
+	exporters []Exporter
+	host      string
+	done      chan struct{}
+}
+
+// New returns a profiling agent, which samples once started.
+func New(c *Config, log *zerolog.Logger) (*Agent, error) {
+	c.init()
+	for _, p := range c.Profiles {
+		if p != "cpu" && pprof.Lookup(p) == nil {
+			return nil, fmt.Errorf("profiling: unknown profile %s", p)
+		}
+	}
+
+	a := &Agent{c: c, log: log, done: make(chan struct{})}
+	a.host, _ = os.Hostname()
+	if c.Dir != "" {
+		e, err := newDirExporter(c.Dir, c.Keep)
+		if err != nil {
+			return nil, err
+		}
+		a.exporters = append(a.exporters, e)
+	}
+	if c.S3.Bucket != "" {
+		e, err := newS3Exporter(&c.S3)
+		if err != nil {
+			return nil, err
+		}
+		a.exporters = append(a.exporters, e)
+	}
+	return a, nil
+}
+
+// Start runs the agent in the background until it is closed.
+func (a *Agent) Start() {
+	go a.run()
+}
+
+// Close stops the agent.
+func (a *Agent) Close() error {
+	close(a.done)
+	return nil
+}
+
+func (a *Agent) run() {
+	ticker := time.NewTicker(time.Duration(a.c.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-ticker.C:
+			if err := a.collect(); err != nil {
+				a.log.Error().Err(err).Msg("profiling: error collecting profiles")
+			}
+		}
+	}
+}
+
+// collect samples the profiles and exports them in a bundle named after the
+// host and the time.
+func (a *Agent) collect() error {
+	now := time.Now().UTC()
+	files, err := a.sample()
+	if err != nil {
+		return err
+	}
+	bundle, err := writeBundle(files, now)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("reva-profile-%s-%s.tar.gz", a.host, now.Format("20060102T150405"))
+	for _, e := range a.exporters {
+		if err := e.Export(context.Background(), name, bundle); err != nil {
+			a.log.Error().Err(err).Str("bundle", name).Msg("profiling: error exporting bundle")
+		}
+	}
+	return nil
+}
+
+type file struct {
+	name string
+	data []byte
+}
+
+// sample returns the configured profiles. The CPU profile comes with its
+// flamegraph in the collapsed stacks format and the CPU time attributed to
+// the RPC methods.
+func (a *Agent) sample() ([]file, error) {
+	var files []file
+	for _, p := range a.c.Profiles {
+		var buf bytes.Buffer
+		if p != "cpu" {
+			if err := pprof.Lookup(p).WriteTo(&buf, 0); err != nil {
+				return nil, errors.Wrapf(err, "profiling: error writing %s profile", p)
+			}
+			files = append(files, file{name: p + ".pprof", data: buf.Bytes()})
+			continue
+		}
+
+		// the CPU profile can be taken by a manual pprof session already
+		if err := pprof.StartCPUProfile(&buf); err != nil {
+			a.log.Warn().Err(err).Msg("profiling: skipping cpu profile")
+			continue
+		}
+		select {
+		case <-time.After(time.Duration(a.c.CPUDuration) * time.Second):
+		case <-a.done:
+		}
+		pprof.StopCPUProfile()
+		files = append(files, file{name: "cpu.pprof", data: buf.Bytes()})
+
+		folded, err := Fold(bytes.NewReader(buf.Bytes()), "cpu")
+		if err != nil {
+			return nil, err
+		}
+		var stacks bytes.Buffer
+		if _, err := folded.WriteTo(&stacks); err != nil {
+			return nil, err
+		}
+		methods, err := json.MarshalIndent(folded.Methods, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file{name: "cpu.folded", data: stacks.Bytes()}, file{name: "cpu-methods.json", data: methods})
+	}
+	return files, nil
+}
+
+func writeBundle(files []file, t time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		hdr := &tar.Header{
+			Name:    f.name,
+			Mode:    0600,
+			Size:    int64(len(f.data)),
+			ModTime: t,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,389 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package analytics aggregates the accesses to the public links into
+// per-link statistics for their owners: the views over time, the downloads
+// per file and an estimate of the unique visitors. The visitors are only
+// kept as their contribution to a HyperLogLog sketch of their anonymized and
+// salted IP addresses, so no address can be recovered from the counters.
+package analytics
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+)
+
+// OpaqueAnalytics is the opaque key of the analytics query in the requests
+// for a public share and of the analytics report in the responses.
+const OpaqueAnalytics = "analytics"
+
+// Kind is the kind of access to a public link.
+type Kind string
+
+const (
+	// View is an access to the root of a public link, e.g. opening it in the browser.
+	View Kind = "view"
+	// Download is the download of a file of a public link.
+	Download Kind = "download"
+)
+
+// Hit is an access to a public link, counted in the time bucket it falls in.
+type Hit struct {
+	Token  string
+	Kind   Kind
+	Path   string
+	Bucket time.Time
+	// Visitor is the hash of the anonymized address of the client.
+	Visitor uint64
+}
+
+// Counter is the number of hits of a kind on a path of a public link in a
+// time bucket.
+type Counter struct {
+	Bucket time.Time
+	Kind   Kind
+	Path   string
+	Count  uint64
+}
+
+// Manager stores the counters of the public links.
+type Manager interface {
+	// Add counts a hit.
+	Add(ctx context.Context, h *Hit) error
+	// Counters returns the counters of a public link in the buckets between
+	// since and until, and the sketch of their visitors.
+	Counters(ctx context.Context, token string, since, until time.Time) ([]*Counter, *Sketch, error)
+	// Purge removes the counters of the buckets before the given time.
+	Purge(ctx context.Context, before time.Time) error
+}
+
+// Config holds the configuration of the public link analytics.
+type Config struct {
+	Driver  string                            `mapstructure:"driver"`
+	Drivers map[string]map[string]interface{} `mapstructure:"drivers"`
+	// Resolution is the length of the time buckets, hour or day.
+	Resolution string `mapstructure:"resolution"`
+	// RetentionDays is the number of days the counters are kept.
+	RetentionDays int `mapstructure:"retention_days"`
+	// MinCount hides the counts below it from the reports, so that single
+	// visits cannot be told apart.
+	MinCount int `mapstructure:"min_count"`
+	// IPv4Prefix and IPv6Prefix are the lengths of the network prefixes the
+	// client addresses are truncated to before being hashed.
+	IPv4Prefix int `mapstructure:"ipv4_prefix"`
+	IPv6Prefix int `mapstructure:"ipv6_prefix"`
+	// Salt is the secret the addresses are hashed with. It has to be shared
+	// by the services recording the hits to count the visitors consistently,
+	// a random one is generated if not set.
+	Salt string `mapstructure:"salt"`
+}
+
+// Enabled returns whether the analytics are configured.
+func (c *Config) Enabled() bool {
+	return c.Driver != ""
+}
+
+func (c *Config) init() {
+	if c.Resolution == "" {
+		c.Resolution = "day"
+	}
+	if c.RetentionDays <= 0 {
+		c.RetentionDays = 90
+	}
+	if c.IPv4Prefix <= 0 || c.IPv4Prefix > 32 {
+		c.IPv4Prefix = 24
+	}
+	if c.IPv6Prefix <= 0 || c.IPv6Prefix > 128 {
+		c.IPv6Prefix = 48
+	}
+}
+
+// Analytics records the accesses to the public links and reports them.
+type Analytics struct {
+	c          *Config
+	m          Manager
+	salt       []byte
+	resolution time.Duration
+
+	mu        sync.Mutex
+	lastPurge time.Time
+}
+
+// New returns the analytics of the public links stored by the given manager.
+func New(c *Config, m Manager) (*Analytics, error) {
+	c.init()
+	a := &Analytics{c: c, m: m, salt: []byte(c.Salt), lastPurge: time.Now()}
+	switch c.Resolution {
+	case "hour":
+		a.resolution = time.Hour
+	case "day":
+		a.resolution = 24 * time.Hour
+	default:
+		return nil, fmt.Errorf("analytics: unknown resolution %s", c.Resolution)
+	}
+	if len(a.salt) == 0 {
+		a.salt = make([]byte, 32)
+		if _, err := rand.Read(a.salt); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// Record counts an access to a public link by the client at the given
+// address.
+func (a *Analytics) Record(ctx context.Context, token string, kind Kind, path, remoteAddr string) error {
+	now := time.Now().UTC()
+	h := &Hit{
+		Token:   token,
+		Kind:    kind,
+		Path:    path,
+		Bucket:  now.Truncate(a.resolution),
+		Visitor: a.visitor(remoteAddr),
+	}
+	if err := a.m.Add(ctx, h); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	purge := now.Sub(a.lastPurge) > time.Hour
+	if purge {
+		a.lastPurge = now
+	}
+	a.mu.Unlock()
+	if purge {
+		go func() {
+			if err := a.m.Purge(context.Background(), now.Add(-a.retention())); err != nil {
+				appctx.GetLogger(ctx).Error().Err(err).Msg("analytics: error purging expired counters")
+			}
+		}()
+	}
+	return nil
+}
+
+func (a *Analytics) retention() time.Duration {
+	return time.Duration(a.c.RetentionDays) * 24 * time.Hour
+}
+
+// visitor returns the salted hash of the network the client address is in.
+func (a *Analytics) visitor(remoteAddr string) uint64 {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+	case ip.To4() != nil:
+		ip = ip.Mask(net.CIDRMask(a.c.IPv4Prefix, 32))
+	default:
+		ip = ip.Mask(net.CIDRMask(a.c.IPv6Prefix, 128))
+	}
+	mac := hmac.New(sha256.New, a.salt)
+	if ip != nil {
+		_, _ = mac.Write(ip)
+	} else {
+		_, _ = mac.Write([]byte(host))
+	}
+	return binary.BigEndian.Uint64(mac.Sum(nil))
+}
+
+// Query selects the period and the number of files of a report.
+type Query struct {
+	Since time.Time `json:"since,omitempty"`
+	Until time.Time `json:"until,omitempty"`
+	// Top is the number of most downloaded files reported.
+	Top int `json:"top,omitempty"`
+}
+
+// Bucket holds the hits of a period.
+type Bucket struct {
+	Time      time.Time `json:"time"`
+	Views     uint64    `json:"views"`
+	Downloads uint64    `json:"downloads"`
+}
+
+// FileDownloads is the number of downloads of a file.
+type FileDownloads struct {
+	Path      string `json:"path"`
+	Downloads uint64 `json:"downloads"`
+}
+
+// Report holds the statistics of a public link. The counts below the
+// configured minimum are reported as zero and the files downloaded less
+// often are left out.
+type Report struct {
+	Since          time.Time        `json:"since"`
+	Until          time.Time        `json:"until"`
+	Resolution     string           `json:"resolution"`
+	MinCount       int              `json:"min_count,omitempty"`
+	Views          uint64           `json:"views"`
+	Downloads      uint64           `json:"downloads"`
+	UniqueVisitors uint64           `json:"unique_visitors"`
+	Timeline       []*Bucket        `json:"timeline"`
+	Files          []*FileDownloads `json:"files"`
+}
+
+// Report returns the statistics of a public link.
+func (a *Analytics) Report(ctx context.Context, token string, q *Query) (*Report, error) {
+	until := q.Until
+	if until.IsZero() {
+		until = time.Now()
+	}
+	since := q.Since
+	if since.IsZero() || until.Sub(since) > a.retention() {
+		since = until.Add(-a.retention())
+	}
+	top := q.Top
+	if top <= 0 {
+		top = 10
+	}
+
+	counters, sketch, err := a.m.Counters(ctx, token, since.UTC().Truncate(a.resolution), until.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Report{
+		Since:      since.UTC(),
+		Until:      until.UTC(),
+		Resolution: a.c.Resolution,
+		MinCount:   a.c.MinCount,
+		Timeline:   []*Bucket{},
+		Files:      []*FileDownloads{},
+	}
+	buckets := map[time.Time]*Bucket{}
+	files := map[string]uint64{}
+	for _, c := range counters {
+		b, ok := buckets[c.Bucket]
+		if !ok {
+			b = &Bucket{Time: c.Bucket}
+			buckets[c.Bucket] = b
+			r.Timeline = append(r.Timeline, b)
+		}
+		switch c.Kind {
+		case View:
+			b.Views += c.Count
+			r.Views += c.Count
+		case Download:
+			b.Downloads += c.Count
+			r.Downloads += c.Count
+			files[c.Path] += c.Count
+		}
+	}
+	sort.Slice(r.Timeline, func(i, j int) bool { return r.Timeline[i].Time.Before(r.Timeline[j].Time) })
+	for _, b := range r.Timeline {
+		b.Views = a.hide(b.Views)
+		b.Downloads = a.hide(b.Downloads)
+	}
+
+	for p, n := range files {
+		if a.hide(n) > 0 {
+			r.Files = append(r.Files, &FileDownloads{Path: p, Downloads: n})
+		}
+	}
+	sort.Slice(r.Files, func(i, j int) bool {
+		if r.Files[i].Downloads != r.Files[j].Downloads {
+			return r.Files[i].Downloads > r.Files[j].Downloads
+		}
+		return r.Files[i].Path < r.Files[j].Path
+	})
+	if len(r.Files) > top {
+		r.Files = r.Files[:top]
+	}
+
+	if sketch != nil {
+		r.UniqueVisitors = a.hide(sketch.Estimate())
+	}
+	return r, nil
+}
+
+func (a *Analytics) hide(n uint64) uint64 {
+	if n < uint64(a.c.MinCount) {
+		return 0
+	}
+	return n
+}
+
+// QueryFromOpaque returns the analytics query of a request, the second
+// return value is false if the request does not ask for analytics.
+func QueryFromOpaque(o *typesv1beta1.Opaque) (*Query, bool, error) {
+	e, ok := o.GetMap()[OpaqueAnalytics]
+	if !ok {
+		return nil, false, nil
+	}
+	q := &Query{}
+	if len(e.Value) > 0 {
+		if err := json.Unmarshal(e.Value, q); err != nil {
+			return nil, true, err
+		}
+	}
+	return q, true, nil
+}
+
+// AddQueryToOpaque encodes the analytics query into the given opaque and
+// returns it.
+func AddQueryToOpaque(o *typesv1beta1.Opaque, q *Query) (*typesv1beta1.Opaque, error) {
+	return addToOpaque(o, q)
+}
+
+// ReportFromOpaque returns the analytics report of a response.
+func ReportFromOpaque(o *typesv1beta1.Opaque) (*Report, bool, error) {
+	e, ok := o.GetMap()[OpaqueAnalytics]
+	if !ok {
+		return nil, false, nil
+	}
+	r := &Report{}
+	if err := json.Unmarshal(e.Value, r); err != nil {
+		return nil, true, err
+	}
+	return r, true, nil
+}
+
+// AddReportToOpaque encodes the analytics report into the given opaque and
+// returns it.
+func AddReportToOpaque(o *typesv1beta1.Opaque, r *Report) (*typesv1beta1.Opaque, error) {
+	return addToOpaque(o, r)
+}
+
+func addToOpaque(o *typesv1beta1.Opaque, v interface{}) (*typesv1beta1.Opaque, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if o == nil {
+		o = &typesv1beta1.Opaque{}
+	}
+	if o.Map == nil {
+		o.Map = map[string]*typesv1beta1.OpaqueEntry{}
+	}
+	o.Map[OpaqueAnalytics] = &typesv1beta1.OpaqueEntry{Decoder: "json", Value: b}
+	return o, nil
+}
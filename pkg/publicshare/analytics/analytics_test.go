@@ -0,0 +1,159 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type testManager struct {
+	hits []*Hit
+}
+
+func (m *testManager) Add(ctx context.Context, h *Hit) error {
+	m.hits = append(m.hits, h)
+	return nil
+}
+
+func (m *testManager) Counters(ctx context.Context, token string, since, until time.Time) ([]*Counter, *Sketch, error) {
+	counts := map[Counter]uint64{}
+	sketch := &Sketch{}
+	for _, h := range m.hits {
+		if h.Token != token {
+			continue
+		}
+		counts[Counter{Bucket: h.Bucket, Kind: h.Kind, Path: h.Path}]++
+		sketch.Add(h.Visitor)
+	}
+	var counters []*Counter
+	for c, n := range counts {
+		c := c
+		c.Count = n
+		counters = append(counters, &c)
+	}
+	return counters, sketch, nil
+}
+
+func (m *testManager) Purge(ctx context.Context, before time.Time) error {
+	return nil
+}
+
+func TestSketchEstimate(t *testing.T) {
+	a, err := New(&Config{Salt: "salt", IPv4Prefix: 32}, &testManager{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{10, 1000, 20000} {
+		s := &Sketch{}
+		for i := 0; i < n; i++ {
+			// every address is added twice, duplicates are not counted
+			addr := fmt.Sprintf("10.%d.%d.%d:1234", i>>16&0xff, i>>8&0xff, i&0xff)
+			s.Add(a.visitor(addr))
+			s.Add(a.visitor(addr))
+		}
+		e := float64(s.Estimate())
+		if e < float64(n)*0.9 || e > float64(n)*1.1 {
+			t.Errorf("estimated %v visitors for %d", e, n)
+		}
+	}
+}
+
+func TestVisitorAnonymization(t *testing.T) {
+	a, err := New(&Config{Salt: "salt"}, &testManager{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.visitor("192.168.1.10:5555") != a.visitor("192.168.1.200:80") {
+		t.Error("addresses of the same /24 network are different visitors")
+	}
+	if a.visitor("192.168.1.10:5555") == a.visitor("192.168.2.10:5555") {
+		t.Error("addresses of different networks are the same visitor")
+	}
+	if a.visitor("2001:db8:1:2::1") != a.visitor("[2001:db8:1:3::2]:443") {
+		t.Error("addresses of the same /48 network are different visitors")
+	}
+
+	b, err := New(&Config{Salt: "other"}, &testManager{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.visitor("192.168.1.10") == b.visitor("192.168.1.10") {
+		t.Error("the visitors do not depend on the salt")
+	}
+}
+
+func TestReport(t *testing.T) {
+	m := &testManager{}
+	a, err := New(&Config{MinCount: 2, IPv4Prefix: 32}, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	record := func(kind Kind, path, addr string) {
+		if err := a.Record(ctx, "token", kind, path, addr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record(View, "/", "10.0.0.1")
+	record(View, "/", "10.0.0.2")
+	record(View, "/", "10.0.0.1")
+	record(Download, "/a.txt", "10.0.0.1")
+	record(Download, "/a.txt", "10.0.0.2")
+	record(Download, "/a.txt", "10.0.0.3")
+	record(Download, "/b.txt", "10.0.0.1")
+	record(Download, "/c.txt", "10.0.0.1")
+	record(Download, "/c.txt", "10.0.0.2")
+	if err := a.Record(ctx, "other", View, "/", "10.0.0.4"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := a.Report(ctx, "token", &Query{Top: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Views != 3 || r.Downloads != 6 || r.UniqueVisitors != 3 {
+		t.Errorf("got %d views, %d downloads and %d visitors, want 3, 6 and 3", r.Views, r.Downloads, r.UniqueVisitors)
+	}
+	if len(r.Timeline) != 1 || r.Timeline[0].Views != 3 || r.Timeline[0].Downloads != 6 {
+		t.Errorf("unexpected timeline %+v", r.Timeline)
+	}
+	if len(r.Files) != 1 || r.Files[0].Path != "/a.txt" || r.Files[0].Downloads != 3 {
+		t.Errorf("unexpected top files %+v", r.Files)
+	}
+
+	r, err = a.Report(ctx, "token", &Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// b.txt was downloaded less than the minimum count
+	if len(r.Files) != 2 || r.Files[1].Path != "/c.txt" {
+		t.Errorf("unexpected files %+v", r.Files)
+	}
+
+	r, err = a.Report(ctx, "other", &Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Timeline[0].Views != 0 || r.UniqueVisitors != 0 {
+		t.Errorf("the single view was not hidden: %+v", r)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/publicshare/analytics"
+	"github.com/cs3org/reva/pkg/publicshare/analytics/registry"
+)
+
+func init() {
+	registry.Register("memory", New)
+}
+
+type counterKey struct {
+	token  string
+	bucket time.Time
+	kind   analytics.Kind
+	path   string
+}
+
+type sketchKey struct {
+	token  string
+	bucket time.Time
+}
+
+type manager struct {
+	sync.RWMutex
+	counters map[counterKey]uint64
+	sketches map[sketchKey]*analytics.Sketch
+}
+
+// the counters are shared by the services of a process, so that the ones
+// recorded by ocdav are reported by the public share provider
+var shared = &manager{
+	counters: map[counterKey]uint64{},
+	sketches: map[sketchKey]*analytics.Sketch{},
+}
+
+// New returns an analytics manager keeping the counters in memory.
+func New(m map[string]interface{}) (analytics.Manager, error) {
+	return shared, nil
+}
+
+func (m *manager) Add(ctx context.Context, h *analytics.Hit) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.counters[counterKey{token: h.Token, bucket: h.Bucket, kind: h.Kind, path: h.Path}]++
+	sk := sketchKey{token: h.Token, bucket: h.Bucket}
+	s, ok := m.sketches[sk]
+	if !ok {
+		s = &analytics.Sketch{}
+		m.sketches[sk] = s
+	}
+	s.Add(h.Visitor)
+	return nil
+}
+
+func (m *manager) Counters(ctx context.Context, token string, since, until time.Time) ([]*analytics.Counter, *analytics.Sketch, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	counters := []*analytics.Counter{}
+	for k, n := range m.counters {
+		if k.token == token && !k.bucket.Before(since) && !k.bucket.After(until) {
+			counters = append(counters, &analytics.Counter{Bucket: k.bucket, Kind: k.kind, Path: k.path, Count: n})
+		}
+	}
+	sketch := &analytics.Sketch{}
+	for k, s := range m.sketches {
+		if k.token == token && !k.bucket.Before(since) && !k.bucket.After(until) {
+			sketch.Merge(s)
+		}
+	}
+	return counters, sketch, nil
+}
+
+func (m *manager) Purge(ctx context.Context, before time.Time) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for k := range m.counters {
+		if k.bucket.Before(before) {
+			delete(m.counters, k)
+		}
+	}
+	for k := range m.sketches {
+		if k.bucket.Before(before) {
+			delete(m.sketches, k)
+		}
+	}
+	return nil
+}
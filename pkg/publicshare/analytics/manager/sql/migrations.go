@@ -0,0 +1,52 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import "github.com/cs3org/reva/pkg/migrate"
+
+// Schema is the migration component of the public link analytics tables.
+const Schema = "share_analytics"
+
+func init() {
+	migrate.Register(Schema, migrate.Migration{
+		Version:     1,
+		Description: "create the public link analytics tables",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS share_analytics_hits (
+				token VARCHAR(64) NOT NULL,
+				bucket BIGINT NOT NULL,
+				kind VARCHAR(16) NOT NULL,
+				path VARCHAR(512) NOT NULL,
+				hits BIGINT NOT NULL DEFAULT 0,
+				PRIMARY KEY (token, bucket, kind, path)
+			)`,
+			`CREATE TABLE IF NOT EXISTS share_analytics_visitors (
+				token VARCHAR(64) NOT NULL,
+				bucket BIGINT NOT NULL,
+				reg INT NOT NULL,
+				val INT NOT NULL,
+				PRIMARY KEY (token, bucket, reg)
+			)`,
+		},
+		Down: []string{
+			"DROP TABLE IF EXISTS share_analytics_visitors",
+			"DROP TABLE IF EXISTS share_analytics_hits",
+		},
+	})
+}
@@ -0,0 +1,164 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/cs3org/reva/pkg/migrate"
+	"github.com/cs3org/reva/pkg/publicshare/analytics"
+	"github.com/cs3org/reva/pkg/publicshare/analytics/registry"
+	"github.com/cs3org/reva/pkg/sqldb"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+// the longest paths stored, longer ones are truncated
+const maxPathLength = 512
+
+func init() {
+	registry.Register("sql", New)
+}
+
+type config struct {
+	// DbEngine is either mysql, the default, sqlite or postgres.
+	DbEngine   string `mapstructure:"db_engine"`
+	DbUsername string `mapstructure:"db_username"`
+	DbPassword string `mapstructure:"db_password"`
+	DbHost     string `mapstructure:"db_host"`
+	DbPort     int    `mapstructure:"db_port"`
+	DbName     string `mapstructure:"db_name"`
+	// DbPath is the file of the sqlite database.
+	DbPath string `mapstructure:"db_path"`
+	// SkipMigrations disables applying the pending schema migrations on startup.
+	SkipMigrations bool `mapstructure:"skip_migrations"`
+}
+
+type manager struct {
+	db *sql.DB
+}
+
+// New returns an analytics manager keeping the counters in a SQL database,
+// which the services recording and reporting them can share.
+func New(m map[string]interface{}) (analytics.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "analytics: error decoding configuration")
+	}
+
+	db, err := sqldb.Open(sqldb.Config{
+		Engine:   c.DbEngine,
+		Username: c.DbUsername,
+		Password: c.DbPassword,
+		Host:     c.DbHost,
+		Port:     c.DbPort,
+		Name:     c.DbName,
+		Path:     c.DbPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.SkipMigrations {
+		if err := migrate.Up(context.Background(), db, Schema); err != nil {
+			return nil, err
+		}
+	}
+	return &manager{db: db}, nil
+}
+
+func (m *manager) Add(ctx context.Context, h *analytics.Hit) error {
+	path := []rune(h.Path)
+	if len(path) > maxPathLength {
+		path = path[:maxPathLength]
+	}
+
+	hits := "INSERT INTO share_analytics_hits (token, bucket, kind, path, hits) VALUES (?, ?, ?, ?, 1) ON DUPLICATE KEY UPDATE hits = hits + 1"
+	visitors := "INSERT INTO share_analytics_visitors (token, bucket, reg, val) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE val = GREATEST(val, VALUES(val))"
+	switch sqldb.Engine(m.db) {
+	case sqldb.SQLite:
+		hits = "INSERT INTO share_analytics_hits (token, bucket, kind, path, hits) VALUES (?, ?, ?, ?, 1) ON CONFLICT(token, bucket, kind, path) DO UPDATE SET hits = hits + 1"
+		visitors = "INSERT INTO share_analytics_visitors (token, bucket, reg, val) VALUES (?, ?, ?, ?) ON CONFLICT(token, bucket, reg) DO UPDATE SET val = MAX(val, excluded.val)"
+	case sqldb.Postgres:
+		hits = "INSERT INTO share_analytics_hits (token, bucket, kind, path, hits) VALUES (?, ?, ?, ?, 1) ON CONFLICT (token, bucket, kind, path) DO UPDATE SET hits = share_analytics_hits.hits + 1"
+		visitors = "INSERT INTO share_analytics_visitors (token, bucket, reg, val) VALUES (?, ?, ?, ?) ON CONFLICT (token, bucket, reg) DO UPDATE SET val = GREATEST(share_analytics_visitors.val, excluded.val)"
+	}
+
+	bucket := h.Bucket.Unix()
+	if _, err := m.db.ExecContext(ctx, sqldb.Rebind(m.db, hits), h.Token, bucket, string(h.Kind), string(path)); err != nil {
+		return errors.Wrap(err, "analytics: error counting hit")
+	}
+	reg, rank := analytics.Position(h.Visitor)
+	if _, err := m.db.ExecContext(ctx, sqldb.Rebind(m.db, visitors), h.Token, bucket, reg, int(rank)); err != nil {
+		return errors.Wrap(err, "analytics: error counting visitor")
+	}
+	return nil
+}
+
+func (m *manager) Counters(ctx context.Context, token string, since, until time.Time) ([]*analytics.Counter, *analytics.Sketch, error) {
+	query := "SELECT bucket, kind, path, hits FROM share_analytics_hits WHERE token = ? AND bucket >= ? AND bucket <= ?"
+	rows, err := m.db.QueryContext(ctx, sqldb.Rebind(m.db, query), token, since.Unix(), until.Unix())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "analytics: error querying hits")
+	}
+	defer rows.Close()
+
+	counters := []*analytics.Counter{}
+	for rows.Next() {
+		var bucket int64
+		c := &analytics.Counter{}
+		if err := rows.Scan(&bucket, &c.Kind, &c.Path, &c.Count); err != nil {
+			return nil, nil, err
+		}
+		c.Bucket = time.Unix(bucket, 0).UTC()
+		counters = append(counters, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	query = "SELECT reg, MAX(val) FROM share_analytics_visitors WHERE token = ? AND bucket >= ? AND bucket <= ? GROUP BY reg"
+	vrows, err := m.db.QueryContext(ctx, sqldb.Rebind(m.db, query), token, since.Unix(), until.Unix())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "analytics: error querying visitors")
+	}
+	defer vrows.Close()
+
+	sketch := &analytics.Sketch{}
+	for vrows.Next() {
+		var reg, rank int
+		if err := vrows.Scan(&reg, &rank); err != nil {
+			return nil, nil, err
+		}
+		sketch.Set(reg, uint8(rank))
+	}
+	return counters, sketch, vrows.Err()
+}
+
+func (m *manager) Purge(ctx context.Context, before time.Time) error {
+	for _, table := range []string{"share_analytics_hits", "share_analytics_visitors"} {
+		query := "DELETE FROM " + table + " WHERE bucket < ?"
+		if _, err := m.db.ExecContext(ctx, sqldb.Rebind(m.db, query), before.Unix()); err != nil {
+			return errors.Wrap(err, "analytics: error purging "+table)
+		}
+	}
+	return nil
+}
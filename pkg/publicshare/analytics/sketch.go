@@ -0,0 +1,82 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package analytics
+
+import (
+	"math"
+	"math/bits"
+)
+
+// the precision of the sketches, which have 2^precision registers and a
+// standard error of about 1.04/sqrt(2^precision), i.e. 3%
+const (
+	precision = 10
+	registers = 1 << precision
+)
+
+// Sketch is a HyperLogLog sketch estimating the number of distinct visitors.
+type Sketch struct {
+	Registers [registers]uint8
+}
+
+// Position returns the register a visitor hash falls in and the rank it
+// contributes to it.
+func Position(hash uint64) (int, uint8) {
+	idx := int(hash >> (64 - precision))
+	w := hash<<precision | 1<<(precision-1)
+	return idx, uint8(bits.LeadingZeros64(w) + 1)
+}
+
+// Add adds a visitor hash to the sketch.
+func (s *Sketch) Add(hash uint64) {
+	s.Set(Position(hash))
+}
+
+// Set raises a register to the given rank.
+func (s *Sketch) Set(idx int, rank uint8) {
+	if idx >= 0 && idx < registers && rank > s.Registers[idx] {
+		s.Registers[idx] = rank
+	}
+}
+
+// Merge adds the visitors of another sketch.
+func (s *Sketch) Merge(o *Sketch) {
+	for i, r := range o.Registers {
+		s.Set(i, r)
+	}
+}
+
+// Estimate returns the estimated number of distinct visitors.
+func (s *Sketch) Estimate() uint64 {
+	m := float64(registers)
+	var sum float64
+	var zeros int
+	for _, r := range s.Registers {
+		sum += math.Pow(2, -float64(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	e := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		// linear counting is more accurate for small cardinalities
+		e = m * math.Log(m/float64(zeros))
+	}
+	return uint64(math.Round(e))
+}
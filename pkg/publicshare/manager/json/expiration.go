@@ -0,0 +1,209 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/asim/go-micro/plugins/events/nats/v4"
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/events/server"
+	"github.com/cs3org/reva/pkg/publicshare"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/smtpclient"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// expirationNotifiedKey stores the expiration date the owner of a share has been notified about,
+// so that changing the expiration of a share leads to a new notification.
+const expirationNotifiedKey = "expiration_notified"
+
+// expirationNotifier notifies the owners of public links about their upcoming expiration
+// and emits the corresponding events.
+type expirationNotifier struct {
+	days       int
+	gatewaySvc string
+	smtp       *smtpclient.SMTPCredentials
+	publisher  events.Publisher
+
+	// mail sends the notification mail to the owner of a share.
+	mail func(ctx context.Context, s *link.PublicShare) error
+}
+
+func newExpirationNotifier(c *config) (*expirationNotifier, error) {
+	n := &expirationNotifier{
+		days:       c.ExpirationNotificationDays,
+		gatewaySvc: c.GatewaySvc,
+	}
+	if c.SMTPCredentials != nil {
+		n.smtp = smtpclient.NewSMTPCredentials(c.SMTPCredentials)
+		n.mail = n.sendMail
+	}
+	if c.NatsAddress != "" {
+		stream, err := server.NewNatsStream(nats.Address(c.NatsAddress), nats.ClusterID(c.NatsClusterID))
+		if err != nil {
+			return nil, errors.Wrap(err, "error connecting to the event stream")
+		}
+		n.publisher = stream
+	}
+	return n, nil
+}
+
+func (n *expirationNotifier) enabled() bool {
+	return n.days > 0
+}
+
+func (n *expirationNotifier) publish(ev interface{}) {
+	if n.publisher == nil {
+		return
+	}
+	if err := events.Publish(n.publisher, ev); err != nil {
+		log.Err(err).Msg("publicShareJSONManager: error publishing event")
+	}
+}
+
+func (n *expirationNotifier) notify(ctx context.Context, s *link.PublicShare) error {
+	n.publish(events.LinkExpiring{
+		ShareID:    s.Id,
+		Owner:      s.Owner,
+		ItemID:     s.ResourceId,
+		Expiration: s.Expiration,
+	})
+
+	if n.mail == nil {
+		return nil
+	}
+	return n.mail(ctx, s)
+}
+
+func (n *expirationNotifier) sendMail(ctx context.Context, s *link.PublicShare) error {
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(n.gatewaySvc))
+	if err != nil {
+		return err
+	}
+	res, err := client.GetUser(ctx, &user.GetUserRequest{UserId: s.Owner, SkipFetchingUserGroups: true})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return errors.New(res.Status.Message)
+	}
+	if res.User.Mail == "" {
+		return nil
+	}
+
+	expiration := time.Unix(int64(s.Expiration.GetSeconds()), int64(s.Expiration.GetNanos()))
+	subject := fmt.Sprintf("Your public link \"%s\" expires soon", s.DisplayName)
+	body := fmt.Sprintf("Dear %s,\n\nyour public link \"%s\" will expire on %s and will no longer be accessible afterwards.\n"+
+		"You can extend its expiration date if you would like to keep sharing the resource.",
+		res.User.DisplayName, s.DisplayName, expiration.Format(time.RFC1123))
+	return n.smtp.SendMail(res.User.Mail, subject, body)
+}
+
+// notifyExpiringShares notifies the owners of all public shares which expire within the configured number of days.
+// The shares are collected under the lock, while the owners are looked up and mailed without holding it.
+func (m *manager) notifyExpiringShares() {
+	if !m.expiration.enabled() {
+		return
+	}
+
+	expiring, err := m.expiringShares()
+	if err != nil {
+		log.Err(err).Msg("publicShareJSONManager: error reading the public shares")
+		return
+	}
+
+	var notified []*link.PublicShare
+	for _, ps := range expiring {
+		if err := m.expiration.notify(context.Background(), ps); err != nil {
+			log.Err(err).Str("id", ps.Id.OpaqueId).Msg("publicShareJSONManager: error notifying about the expiration of a public share")
+			continue
+		}
+		notified = append(notified, ps)
+	}
+	if len(notified) == 0 {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	db, err := m.readDb()
+	if err != nil {
+		log.Err(err).Msg("publicShareJSONManager: error reading the public shares")
+		return
+	}
+	for _, ps := range notified {
+		// the share may have been removed in the meantime
+		if data, ok := db[ps.Id.OpaqueId].(map[string]interface{}); ok {
+			data[expirationNotifiedKey] = ps.Expiration.Seconds
+		}
+	}
+	if err := m.writeDb(db); err != nil {
+		log.Err(err).Msg("publicShareJSONManager: error writing the public shares")
+	}
+}
+
+// expiringShares returns the public shares expiring within the configured number of days
+// whose owners haven't been notified about their current expiration yet.
+func (m *manager) expiringShares() ([]*link.PublicShare, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	db, err := m.readDb()
+	if err != nil {
+		return nil, err
+	}
+
+	threshold := time.Now().Add(time.Duration(m.expiration.days) * 24 * time.Hour)
+	var expiring []*link.PublicShare
+	for _, v := range db {
+		data, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		encShare, ok := data["share"].(string)
+		if !ok {
+			continue
+		}
+		ps := &link.PublicShare{}
+		if err := utils.UnmarshalJSONToProtoV1([]byte(encShare), ps); err != nil {
+			continue
+		}
+
+		if ps.Expiration == nil || publicshare.IsExpired(ps) {
+			continue
+		}
+		if time.Unix(int64(ps.Expiration.Seconds), 0).After(threshold) {
+			continue
+		}
+		if notified, ok := data[expirationNotifiedKey].(float64); ok && uint64(notified) == ps.Expiration.Seconds {
+			continue
+		}
+		expiring = append(expiring, ps)
+	}
+	return expiring, nil
+}
@@ -39,8 +39,11 @@ import (
 	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
 	"github.com/cs3org/reva/pkg/publicshare"
 	"github.com/cs3org/reva/pkg/publicshare/manager/registry"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/smtpclient"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
@@ -59,17 +62,24 @@ func New(c map[string]interface{}) (publicshare.Manager, error) {
 
 	conf.init()
 
+	expiration, err := newExpirationNotifier(conf)
+	if err != nil {
+		return nil, err
+	}
+
 	m := manager{
 		mutex:                      &sync.Mutex{},
 		file:                       conf.File,
 		passwordHashCost:           conf.SharePasswordHashCost,
 		janitorRunInterval:         conf.JanitorRunInterval,
 		enableExpiredSharesCleanup: conf.EnableExpiredSharesCleanup,
+		tokenLength:                conf.TokenLength,
+		tokenAlphabet:              conf.TokenAlphabet,
+		expiration:                 expiration,
 	}
 
 	// attempt to create the db file
 	var fi os.FileInfo
-	var err error
 	if fi, err = os.Stat(m.file); os.IsNotExist(err) {
 		folder := filepath.Dir(m.file)
 		if err := os.MkdirAll(folder, 0755); err != nil {
@@ -97,6 +107,14 @@ type config struct {
 	SharePasswordHashCost      int    `mapstructure:"password_hash_cost"`
 	JanitorRunInterval         int    `mapstructure:"janitor_run_interval"`
 	EnableExpiredSharesCleanup bool   `mapstructure:"enable_expired_shares_cleanup"`
+	TokenLength                int    `mapstructure:"token_length"`
+	TokenAlphabet              string `mapstructure:"token_alphabet"`
+	// ExpirationNotificationDays is the number of days before their expiration the owners of public links are notified.
+	ExpirationNotificationDays int                         `mapstructure:"expiration_notification_days"`
+	GatewaySvc                 string                      `mapstructure:"gatewaysvc"`
+	SMTPCredentials            *smtpclient.SMTPCredentials `mapstructure:"smtp_credentials"`
+	NatsAddress                string                      `mapstructure:"nats_address"`
+	NatsClusterID              string                      `mapstructure:"nats_clusterid"`
 }
 
 func (c *config) init() {
@@ -109,6 +127,7 @@ func (c *config) init() {
 	if c.JanitorRunInterval == 0 {
 		c.JanitorRunInterval = 60
 	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
 }
 
 type manager struct {
@@ -118,10 +137,13 @@ type manager struct {
 	passwordHashCost           int
 	janitorRunInterval         int
 	enableExpiredSharesCleanup bool
+	tokenLength                int
+	tokenAlphabet              string
+	expiration                 *expirationNotifier
 }
 
 func (m *manager) startJanitorRun() {
-	if !m.enableExpiredSharesCleanup {
+	if !m.enableExpiredSharesCleanup && !m.expiration.enabled() {
 		return
 	}
 
@@ -134,18 +156,29 @@ func (m *manager) startJanitorRun() {
 		case <-work:
 			return
 		case <-ticker.C:
-			m.cleanupExpiredShares()
+			m.runJanitor()
 		}
 	}
 }
 
+// runJanitor notifies the owners of the expiring shares and removes the expired ones, if enabled.
+func (m *manager) runJanitor() {
+	m.notifyExpiringShares()
+	if m.enableExpiredSharesCleanup {
+		m.cleanupExpiredShares()
+	}
+}
+
 // CreatePublicShare adds a new entry to manager.shares
 func (m *manager) CreatePublicShare(ctx context.Context, u *user.User, rInfo *provider.ResourceInfo, g *link.Grant) (*link.PublicShare, error) {
 	id := &link.PublicShareId{
 		OpaqueId: utils.RandString(15),
 	}
 
-	tkn := utils.RandString(15)
+	tkn, err := publicshare.GenerateToken(m.tokenLength, m.tokenAlphabet)
+	if err != nil {
+		return nil, err
+	}
 	now := time.Now().UnixNano()
 
 	displayName, ok := rInfo.ArbitraryMetadata.Metadata["name"]
@@ -306,6 +339,16 @@ func (m *manager) GetPublicShare(ctx context.Context, u *user.User, ref *link.Pu
 		if err != nil {
 			return nil, errors.New("no shares found by token")
 		}
+		// the public storage provider resolves the token on every access, so
+		// a link expiring after it was opened can't be used anymore either
+		if publicshare.IsExpired(ps) {
+			m.mutex.Lock()
+			defer m.mutex.Unlock()
+			if err := m.revokeExpiredPublicShare(ctx, ps, u); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("no shares found by token")
+		}
 		if ps.PasswordProtected && sign {
 			err := publicshare.AddSignature(ps, pw)
 			if err != nil {
@@ -436,6 +479,12 @@ func (m *manager) revokeExpiredPublicShare(ctx context.Context, s *link.PublicSh
 		return err
 	}
 
+	m.expiration.publish(events.LinkExpired{
+		ShareID:    s.Id,
+		Owner:      s.Owner,
+		ItemID:     s.ResourceId,
+		Expiration: s.Expiration,
+	})
 	return nil
 }
 
@@ -521,7 +570,7 @@ func (m *manager) GetPublicShareByToken(ctx context.Context, token string, auth
 			}
 
 			if local.PasswordProtected {
-				if authenticate(&local, passDB, auth) {
+				if publicshare.Authenticate(&local, passDB, auth) {
 					if sign {
 						err := publicshare.AddSignature(&local, passDB)
 						if err != nil {
@@ -565,31 +614,58 @@ func (m *manager) writeDb(db map[string]interface{}) error {
 	return nil
 }
 
-func authenticate(share *link.PublicShare, pw string, auth *link.PublicShareAuthentication) bool {
-	switch {
-	case auth.GetPassword() != "":
-		if err := bcrypt.CompareHashAndPassword([]byte(pw), []byte(auth.GetPassword())); err == nil {
-			return true
-		}
-	case auth.GetSignature() != nil:
-		sig := auth.GetSignature()
-		now := time.Now()
-		expiration := time.Unix(int64(sig.GetSignatureExpiration().GetSeconds()), int64(sig.GetSignatureExpiration().GetNanos()))
-		if now.After(expiration) {
-			return false
-		}
-		s, err := publicshare.CreateSignature(share.Token, pw, expiration)
-		if err != nil {
-			// TODO(labkode): pass ctx to log error
-			// Now we are blind
-			return false
-		}
-		return sig.GetSignature() == s
-	}
-	return false
-}
-
 type publicShare struct {
 	link.PublicShare
 	Password string `json:"password"`
 }
+
+// GetAccessRules returns the access rules of a public share.
+func (m *manager) GetAccessRules(ctx context.Context, id *link.PublicShareId) (publicshare.AccessRules, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	db, err := m.readDb()
+	if err != nil {
+		return nil, err
+	}
+	data, ok := db[id.GetOpaqueId()].(map[string]interface{})
+	if !ok {
+		return nil, errtypes.NotFound(id.GetOpaqueId())
+	}
+	rules, _ := data["access_rules"].(string)
+	return publicshare.DecodeAccessRules([]byte(rules))
+}
+
+// SetAccessRules replaces the access rules of a public share.
+func (m *manager) SetAccessRules(ctx context.Context, u *user.User, ref *link.PublicShareReference, rules publicshare.AccessRules) error {
+	share, err := m.GetPublicShare(ctx, u, ref, false)
+	if err != nil {
+		return errors.New("ref does not exist")
+	}
+	if err := rules.HashPasswords(m.passwordHashCost); err != nil {
+		return errors.Wrap(err, "could not hash access rule password")
+	}
+	encRules, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	db, err := m.readDb()
+	if err != nil {
+		return err
+	}
+	data, ok := db[share.Id.OpaqueId].(map[string]interface{})
+	if !ok {
+		return errtypes.NotFound(share.Id.OpaqueId)
+	}
+	if len(rules) == 0 {
+		delete(data, "access_rules")
+	} else {
+		data["access_rules"] = string(encRules)
+	}
+	db[share.Id.OpaqueId] = data
+	return m.writeDb(db)
+}
@@ -0,0 +1,229 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/events"
+	microevents "go-micro.dev/v4/events"
+)
+
+func newTestManager(t *testing.T, cleanup bool, mail func(context.Context, *link.PublicShare) error) *manager {
+	m := &manager{
+		mutex:                      &sync.Mutex{},
+		file:                       filepath.Join(t.TempDir(), "publicshares"),
+		passwordHashCost:           4,
+		enableExpiredSharesCleanup: cleanup,
+		tokenLength:                15,
+		expiration:                 &expirationNotifier{days: 3, mail: mail},
+	}
+	if err := m.writeDb(map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func createShare(t *testing.T, m *manager, name string, expiration time.Time) *link.PublicShare {
+	u := &user.User{Id: &user.UserId{OpaqueId: "einstein"}}
+	info := &provider.ResourceInfo{
+		Id:                &provider.ResourceId{StorageId: "storage", OpaqueId: name},
+		Owner:             u.Id,
+		ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: map[string]string{"name": name}},
+	}
+	s, err := m.CreatePublicShare(context.Background(), u, info, &link.Grant{
+		Permissions: &link.PublicSharePermissions{Permissions: &provider.ResourcePermissions{Stat: true}},
+		Expiration:  &typespb.Timestamp{Seconds: uint64(expiration.Unix())},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func exists(t *testing.T, m *manager, s *link.PublicShare) bool {
+	db, err := m.readDb()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ok := db[s.Id.OpaqueId]
+	return ok
+}
+
+func TestJanitorNotifiesWithoutCleanup(t *testing.T) {
+	var m *manager
+	var notified []string
+	m = newTestManager(t, false, func(ctx context.Context, s *link.PublicShare) error {
+		// the lock must not be held while the owners are notified
+		done := make(chan struct{})
+		go func() {
+			m.mutex.Lock()
+			m.mutex.Unlock()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Error("the shares are locked while notifying")
+		}
+		notified = append(notified, s.DisplayName)
+		return nil
+	})
+
+	expired := createShare(t, m, "expired", time.Now().Add(-time.Hour))
+	expiring := createShare(t, m, "expiring", time.Now().Add(24*time.Hour))
+	createShare(t, m, "later", time.Now().Add(30*24*time.Hour))
+
+	m.runJanitor()
+	if len(notified) != 1 || notified[0] != "expiring" {
+		t.Fatalf("expected the owner of the expiring share to be notified, got %v", notified)
+	}
+	if !exists(t, m, expired) || !exists(t, m, expiring) {
+		t.Fatal("expected no share to be removed when the cleanup is disabled")
+	}
+
+	m.runJanitor()
+	if len(notified) != 1 {
+		t.Fatalf("expected the owner to be notified once, got %v", notified)
+	}
+}
+
+func TestJanitorCleanup(t *testing.T) {
+	m := newTestManager(t, true, nil)
+	expired := createShare(t, m, "expired", time.Now().Add(-time.Hour))
+	valid := createShare(t, m, "valid", time.Now().Add(24*time.Hour))
+
+	m.runJanitor()
+	if exists(t, m, expired) {
+		t.Error("expected the expired share to be removed")
+	}
+	if !exists(t, m, valid) {
+		t.Error("expected the valid share to be kept")
+	}
+}
+
+func TestJanitorFailedNotificationIsRetried(t *testing.T) {
+	fail := true
+	calls := 0
+	m := newTestManager(t, false, func(ctx context.Context, s *link.PublicShare) error {
+		calls++
+		if fail {
+			return context.DeadlineExceeded
+		}
+		return nil
+	})
+	createShare(t, m, "expiring", time.Now().Add(time.Hour))
+
+	m.runJanitor()
+	fail = false
+	m.runJanitor()
+	m.runJanitor()
+	if calls != 2 {
+		t.Fatalf("expected the failed notification to be retried once, got %d calls", calls)
+	}
+}
+
+type recordingPublisher struct {
+	events []interface{}
+}
+
+func (p *recordingPublisher) Publish(_ string, ev interface{}, _ ...microevents.PublishOption) error {
+	p.events = append(p.events, ev)
+	return nil
+}
+
+func TestJanitorNotifiesAgainAfterNewExpiration(t *testing.T) {
+	calls := 0
+	m := newTestManager(t, false, func(ctx context.Context, s *link.PublicShare) error {
+		calls++
+		return nil
+	})
+	s := createShare(t, m, "expiring", time.Now().Add(time.Hour))
+
+	m.runJanitor()
+	u := &user.User{Id: &user.UserId{OpaqueId: "einstein"}}
+	_, err := m.UpdatePublicShare(context.Background(), u, &link.UpdatePublicShareRequest{
+		Ref: &link.PublicShareReference{Spec: &link.PublicShareReference_Id{Id: s.Id}},
+		Update: &link.UpdatePublicShareRequest_Update{
+			Type:  link.UpdatePublicShareRequest_Update_TYPE_EXPIRATION,
+			Grant: &link.Grant{Expiration: &typespb.Timestamp{Seconds: uint64(time.Now().Add(2 * time.Hour).Unix())}},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.runJanitor()
+	if calls != 2 {
+		t.Fatalf("expected the owner to be notified about the new expiration, got %d calls", calls)
+	}
+}
+
+func TestJanitorPublishesExpirationEvents(t *testing.T) {
+	m := newTestManager(t, true, nil)
+	p := &recordingPublisher{}
+	m.expiration.publisher = p
+	expired := createShare(t, m, "expired", time.Now().Add(-time.Hour))
+	expiring := createShare(t, m, "expiring", time.Now().Add(time.Hour))
+
+	m.runJanitor()
+	var expiringEv *events.LinkExpiring
+	var expiredEv *events.LinkExpired
+	for _, ev := range p.events {
+		switch e := ev.(type) {
+		case events.LinkExpiring:
+			expiringEv = &e
+		case events.LinkExpired:
+			expiredEv = &e
+		}
+	}
+	if expiringEv == nil || expiringEv.ShareID.OpaqueId != expiring.Id.OpaqueId {
+		t.Errorf("expected an event for the expiring share, got %+v", p.events)
+	}
+	if expiredEv == nil || expiredEv.ShareID.OpaqueId != expired.Id.OpaqueId {
+		t.Errorf("expected an event for the removed share, got %+v", p.events)
+	}
+}
+
+func TestGetPublicShareRevokesExpired(t *testing.T) {
+	m := newTestManager(t, true, nil)
+	u := &user.User{Id: &user.UserId{OpaqueId: "einstein"}}
+	expired := createShare(t, m, "expired", time.Now().Add(-time.Hour))
+	valid := createShare(t, m, "valid", time.Now().Add(time.Hour))
+
+	ref := &link.PublicShareReference{Spec: &link.PublicShareReference_Token{Token: expired.Token}}
+	if _, err := m.GetPublicShare(context.Background(), u, ref, false); err == nil {
+		t.Error("expected the expired share not to be found")
+	}
+	if exists(t, m, expired) {
+		t.Error("expected the expired share to be removed")
+	}
+
+	ref = &link.PublicShareReference{Spec: &link.PublicShareReference_Token{Token: valid.Token}}
+	if _, err := m.GetPublicShare(context.Background(), u, ref, false); err != nil {
+		t.Errorf("expected the valid share to be found, got %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+var testUser = &user.User{Id: &user.UserId{OpaqueId: "einstein"}}
+
+func createShare(t *testing.T, m *manager, g *link.Grant) *link.PublicShare {
+	info := &provider.ResourceInfo{
+		Id:                &provider.ResourceId{StorageId: "storage", OpaqueId: "file"},
+		Owner:             testUser.Id,
+		ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: map[string]string{"name": "file"}},
+	}
+	s, err := m.CreatePublicShare(context.Background(), testUser, info, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func passwordAuth(pw string) *link.PublicShareAuthentication {
+	return &link.PublicShareAuthentication{Spec: &link.PublicShareAuthentication_Password{Password: pw}}
+}
+
+func TestPasswordProtectedShare(t *testing.T) {
+	m := &manager{}
+	ctx := context.Background()
+	protected := createShare(t, m, &link.Grant{Password: "s3cret"})
+	open := createShare(t, m, &link.Grant{})
+
+	if !protected.PasswordProtected || open.PasswordProtected {
+		t.Fatal("expected only the share with a password to be protected")
+	}
+
+	if _, err := m.GetPublicShareByToken(ctx, protected.Token, passwordAuth("wrong"), false); err == nil {
+		t.Error("expected a wrong password to be rejected")
+	} else if _, ok := err.(errtypes.IsInvalidCredentials); !ok {
+		t.Errorf("expected invalid credentials, got %v", err)
+	}
+	if _, err := m.GetPublicShareByToken(ctx, open.Token, &link.PublicShareAuthentication{}, false); err != nil {
+		t.Errorf("expected the share without password to be accessible, got %v", err)
+	}
+
+	signed, err := m.GetPublicShareByToken(ctx, protected.Token, passwordAuth("s3cret"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed.Signature == nil {
+		t.Fatal("expected the share to be signed")
+	}
+	if stored, _ := m.getPublicShareByToken(protected.Token); stored.Signature != nil {
+		t.Error("expected the stored share not to be signed")
+	}
+	auth := &link.PublicShareAuthentication{Spec: &link.PublicShareAuthentication_Signature{Signature: signed.Signature}}
+	if _, err := m.GetPublicShareByToken(ctx, protected.Token, auth, false); err != nil {
+		t.Errorf("expected the signature to be accepted, got %v", err)
+	}
+}
+
+func TestUpdatePassword(t *testing.T) {
+	m := &manager{}
+	ctx := context.Background()
+	s := createShare(t, m, &link.Grant{Password: "s3cret"})
+	ref := &link.PublicShareReference{Spec: &link.PublicShareReference_Token{Token: s.Token}}
+
+	update := func(pw string) {
+		_, err := m.UpdatePublicShare(ctx, testUser, &link.UpdatePublicShareRequest{
+			Ref: ref,
+			Update: &link.UpdatePublicShareRequest_Update{
+				Type:  link.UpdatePublicShareRequest_Update_TYPE_PASSWORD,
+				Grant: &link.Grant{Password: pw},
+			},
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	update("changed")
+	if _, err := m.GetPublicShareByToken(ctx, s.Token, passwordAuth("s3cret"), false); err == nil {
+		t.Error("expected the old password to be rejected")
+	}
+	if _, err := m.GetPublicShareByToken(ctx, s.Token, passwordAuth("changed"), false); err != nil {
+		t.Errorf("expected the new password to be accepted, got %v", err)
+	}
+
+	update("")
+	share, err := m.GetPublicShareByToken(ctx, s.Token, &link.PublicShareAuthentication{}, false)
+	if err != nil {
+		t.Fatalf("expected the password to be removed, got %v", err)
+	}
+	if share.PasswordProtected {
+		t.Error("expected the share not to be protected anymore")
+	}
+}
+
+func TestExpiredShare(t *testing.T) {
+	m := &manager{}
+	ctx := context.Background()
+	expired := createShare(t, m, &link.Grant{
+		Password:   "s3cret",
+		Expiration: &typespb.Timestamp{Seconds: uint64(time.Now().Add(-time.Hour).Unix())},
+	})
+	valid := createShare(t, m, &link.Grant{
+		Expiration: &typespb.Timestamp{Seconds: uint64(time.Now().Add(time.Hour).Unix())},
+	})
+
+	shares, err := m.ListPublicShares(ctx, testUser, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != 1 || shares[0].Token != valid.Token {
+		t.Errorf("expected only the valid share to be listed, got %v", shares)
+	}
+
+	if _, err := m.GetPublicShareByToken(ctx, expired.Token, passwordAuth("s3cret"), false); err == nil {
+		t.Error("expected the expired share not to be found")
+	}
+	if _, ok := m.passwords.Load(expired.Token); ok {
+		t.Error("expected the password of the expired share to be removed")
+	}
+
+	ref := &link.PublicShareReference{Spec: &link.PublicShareReference_Token{Token: valid.Token}}
+	if _, err := m.GetPublicShare(ctx, testUser, ref, false); err != nil {
+		t.Errorf("expected the valid share to be found, got %v", err)
+	}
+}
@@ -21,7 +21,6 @@ package memory
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"math/rand"
 	"sync"
@@ -36,6 +35,9 @@ import (
 	"github.com/cs3org/reva/pkg/publicshare"
 	"github.com/cs3org/reva/pkg/publicshare/manager/registry"
 	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/protobuf/proto"
 )
 
 func init() {
@@ -51,12 +53,12 @@ func New(c map[string]interface{}) (publicshare.Manager, error) {
 
 type manager struct {
 	shares sync.Map
+	// passwords holds the bcrypt hashes of the share passwords by token
+	passwords sync.Map
+	// rules holds the access rules of the shares by token
+	rules sync.Map
 }
 
-var (
-	passwordProtected bool
-)
-
 // CreatePublicShare adds a new entry to manager.shares
 func (m *manager) CreatePublicShare(ctx context.Context, u *user.User, rInfo *provider.ResourceInfo, g *link.Grant) (*link.PublicShare, error) {
 	id := &link.PublicShareId{
@@ -71,7 +73,13 @@ func (m *manager) CreatePublicShare(ctx context.Context, u *user.User, rInfo *pr
 		displayName = tkn
 	}
 
+	passwordProtected := false
 	if g.Password != "" {
+		h, err := bcrypt.GenerateFromPassword([]byte(g.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not hash share password")
+		}
+		m.passwords.Store(tkn, string(h))
 		passwordProtected = true
 	}
 
@@ -128,8 +136,18 @@ func (m *manager) UpdatePublicShare(ctx context.Context, u *user.User, req *link
 		log.Debug().Str("memory", "update expiration").Msgf("from: `%v`\nto\n`%v`", old, new)
 		share.Expiration = req.Update.GetGrant().Expiration
 	case link.UpdatePublicShareRequest_Update_TYPE_PASSWORD:
-		// TODO(refs) Do public shares need Grants? Struct is defined, just not used. Fill this once it's done.
-		fallthrough
+		log.Debug().Str("memory", "update password").Msg("password updated")
+		if req.Update.GetGrant().GetPassword() == "" {
+			m.passwords.Delete(token)
+			share.PasswordProtected = false
+		} else {
+			h, err := bcrypt.GenerateFromPassword([]byte(req.Update.GetGrant().GetPassword()), bcrypt.DefaultCost)
+			if err != nil {
+				return nil, errors.Wrap(err, "could not hash share password")
+			}
+			m.passwords.Store(token, string(h))
+			share.PasswordProtected = true
+		}
 	default:
 		return nil, fmt.Errorf("invalid update type: %v", req.GetUpdate().GetType())
 	}
@@ -146,11 +164,9 @@ func (m *manager) UpdatePublicShare(ctx context.Context, u *user.User, req *link
 }
 
 func (m *manager) GetPublicShare(ctx context.Context, u *user.User, ref *link.PublicShareReference, sign bool) (share *link.PublicShare, err error) {
-	// TODO(refs) return an error if the share is expired.
-
 	// Attempt to fetch public share by token
 	if ref.GetToken() != "" {
-		share, err = m.GetPublicShareByToken(ctx, ref.GetToken(), &link.PublicShareAuthentication{}, sign)
+		share, err = m.getPublicShareByToken(ref.GetToken())
 		if err != nil {
 			return nil, errors.New("no shares found by token")
 		}
@@ -164,15 +180,29 @@ func (m *manager) GetPublicShare(ctx context.Context, u *user.User, ref *link.Pu
 		}
 	}
 
-	return
+	if share == nil {
+		return nil, errors.New("no shares found")
+	}
+	if publicshare.IsExpired(share) {
+		m.revoke(share.Token)
+		return nil, errors.New("no shares found")
+	}
+	if share.PasswordProtected && sign {
+		return m.sign(share)
+	}
+	return share, nil
 }
 
 func (m *manager) ListPublicShares(ctx context.Context, u *user.User, filters []*link.ListPublicSharesRequest_Filter, md *provider.ResourceInfo, sign bool) ([]*link.PublicShare, error) {
-	// TODO(refs) filter out expired shares
 	shares := []*link.PublicShare{}
 	m.shares.Range(func(k, v interface{}) bool {
 		s := v.(*link.PublicShare)
 
+		if publicshare.IsExpired(s) {
+			m.revoke(s.Token)
+			return true
+		}
+
 		// Skip if the share isn't created by the current user
 		if s.Creator.GetOpaqueId() == u.Id.OpaqueId && (s.Creator.GetIdp() == "" || u.Id.Idp == s.Creator.GetIdp()) {
 			if len(filters) == 0 {
@@ -201,12 +231,12 @@ func (m *manager) RevokePublicShare(ctx context.Context, u *user.User, ref *link
 		if err != nil {
 			return errors.New("reference does not exist")
 		}
-		m.shares.Delete(s.Token)
+		m.revoke(s.Token)
 	case ref.GetToken() != "":
-		if _, err := m.GetPublicShareByToken(ctx, ref.GetToken(), &link.PublicShareAuthentication{}, false); err != nil {
+		if _, err := m.getPublicShareByToken(ref.GetToken()); err != nil {
 			return errors.New("reference does not exist")
 		}
-		m.shares.Delete(ref.GetToken())
+		m.revoke(ref.GetToken())
 	default:
 		return errors.New("reference does not exist")
 	}
@@ -214,12 +244,84 @@ func (m *manager) RevokePublicShare(ctx context.Context, u *user.User, ref *link
 }
 
 func (m *manager) GetPublicShareByToken(ctx context.Context, token string, auth *link.PublicShareAuthentication, sign bool) (*link.PublicShare, error) {
+	share, err := m.getPublicShareByToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if publicshare.IsExpired(share) {
+		m.revoke(token)
+		return nil, errtypes.NotFound("invalid token")
+	}
+	if !share.PasswordProtected {
+		return share, nil
+	}
+
+	pw, ok := m.passwords.Load(token)
+	if !ok || !publicshare.Authenticate(share, pw.(string), auth) {
+		return nil, errtypes.InvalidCredentials("memory: invalid password")
+	}
+	if sign {
+		return m.sign(share)
+	}
+	return share, nil
+}
+
+func (m *manager) getPublicShareByToken(token string) (*link.PublicShare, error) {
 	if ps, ok := m.shares.Load(token); ok {
 		return ps.(*link.PublicShare), nil
 	}
 	return nil, errtypes.NotFound("invalid token")
 }
 
+// sign returns a copy of the share carrying a signature, leaving the stored
+// share untouched.
+func (m *manager) sign(share *link.PublicShare) (*link.PublicShare, error) {
+	pw, ok := m.passwords.Load(share.Token)
+	if !ok {
+		return nil, errtypes.InternalError("memory: missing password of share " + share.Token)
+	}
+	signed := proto.Clone(share).(*link.PublicShare)
+	if err := publicshare.AddSignature(signed, pw.(string)); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
+
+func (m *manager) revoke(token string) {
+	m.shares.Delete(token)
+	m.passwords.Delete(token)
+	m.rules.Delete(token)
+}
+
+// GetAccessRules returns the access rules of a public share.
+func (m *manager) GetAccessRules(ctx context.Context, id *link.PublicShareId) (publicshare.AccessRules, error) {
+	share, err := m.getPublicShareByTokenID(ctx, *id)
+	if err != nil {
+		return nil, errtypes.NotFound(id.GetOpaqueId())
+	}
+	if rules, ok := m.rules.Load(share.Token); ok {
+		return rules.(publicshare.AccessRules), nil
+	}
+	return nil, nil
+}
+
+// SetAccessRules replaces the access rules of a public share.
+func (m *manager) SetAccessRules(ctx context.Context, u *user.User, ref *link.PublicShareReference, rules publicshare.AccessRules) error {
+	share, err := m.GetPublicShare(ctx, u, ref, false)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		m.rules.Delete(share.Token)
+		return nil
+	}
+	if err := rules.HashPasswords(bcrypt.DefaultCost); err != nil {
+		return errors.Wrap(err, "could not hash access rule password")
+	}
+	m.rules.Store(share.Token, rules)
+	return nil
+}
+
 func randString(n int) string {
 	var l = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
 	b := make([]rune, n)
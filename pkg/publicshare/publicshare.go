@@ -21,9 +21,12 @@ package publicshare
 import (
 	"context"
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/hex"
+	"errors"
+	"math/big"
 	"time"
 
 	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
@@ -31,6 +34,14 @@ import (
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// DefaultTokenLength is the default length of the tokens of new public shares.
+	DefaultTokenLength = 15
+	// DefaultTokenAlphabet contains the characters used by default in the tokens of new public shares.
+	DefaultTokenAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 )
 
 // Manager manipulates public shares.
@@ -43,6 +54,88 @@ type Manager interface {
 	GetPublicShareByToken(ctx context.Context, token string, auth *link.PublicShareAuthentication, sign bool) (*link.PublicShare, error)
 }
 
+// OpaqueResourceIDsOnly marks the requests listing public shares which only
+// need to know which of the filtered resources are shared. The shares in the
+// response then only carry their resource id.
+const OpaqueResourceIDsOnly = "resource_ids_only"
+
+// SharedResourcesLister is implemented by the managers able to tell which of
+// many resources have public links in a single query, without loading them.
+type SharedResourcesLister interface {
+	// ListSharedResources returns the ids of the given resources with public
+	// links created by the user which did not expire.
+	ListSharedResources(ctx context.Context, u *user.User, ids []*provider.ResourceId) ([]*provider.ResourceId, error)
+}
+
+// ListSharedResources returns the ids of the given resources with public links
+// of the user, falling back to listing the shares if the manager can't batch it.
+func ListSharedResources(ctx context.Context, m Manager, u *user.User, ids []*provider.ResourceId) ([]*provider.ResourceId, error) {
+	if len(ids) == 0 {
+		return []*provider.ResourceId{}, nil
+	}
+	if l, ok := m.(SharedResourcesLister); ok {
+		return l.ListSharedResources(ctx, u, ids)
+	}
+
+	filters := make([]*link.ListPublicSharesRequest_Filter, 0, len(ids))
+	for _, id := range ids {
+		filters = append(filters, ResourceIDFilter(id))
+	}
+	shares, err := m.ListPublicShares(ctx, u, filters, &provider.ResourceInfo{}, false)
+	if err != nil {
+		return nil, err
+	}
+	shared := []*provider.ResourceId{}
+	for _, id := range ids {
+		for _, s := range shares {
+			if utils.ResourceIDEqual(s.ResourceId, id) {
+				shared = append(shared, id)
+				break
+			}
+		}
+	}
+	return shared, nil
+}
+
+// ResourceIDsOf returns the resources the filters select, if all of them are
+// filters by resource id.
+func ResourceIDsOf(filters []*link.ListPublicSharesRequest_Filter) ([]*provider.ResourceId, bool) {
+	ids := make([]*provider.ResourceId, 0, len(filters))
+	for _, f := range filters {
+		if f.Type != link.ListPublicSharesRequest_Filter_TYPE_RESOURCE_ID {
+			return nil, false
+		}
+		ids = append(ids, f.GetResourceId())
+	}
+	return ids, true
+}
+
+// GenerateToken creates a random token for a new public share using a cryptographically
+// secure random number generator. If length or alphabet are empty, the defaults are used.
+func GenerateToken(length int, alphabet string) (string, error) {
+	if length <= 0 {
+		length = DefaultTokenLength
+	}
+	if alphabet == "" {
+		alphabet = DefaultTokenAlphabet
+	}
+	chars := []rune(alphabet)
+	if len(chars) < 2 {
+		return "", errors.New("the token alphabet must contain at least two characters")
+	}
+
+	max := big.NewInt(int64(len(chars)))
+	token := make([]rune, length)
+	for i := range token {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		token[i] = chars[n.Int64()]
+	}
+	return string(token), nil
+}
+
 // CreateSignature calculates a signature for a public share.
 func CreateSignature(token, pw string, expiration time.Time) (string, error) {
 	h := sha256.New()
@@ -143,3 +236,33 @@ func IsExpired(s *link.PublicShare) bool {
 	expiration := time.Unix(int64(s.Expiration.GetSeconds()), int64(s.Expiration.GetNanos()))
 	return s.Expiration != nil && expiration.Before(time.Now())
 }
+
+// IsUploadOnly tests whether the permissions only allow to upload into a
+// resource without revealing its contents, i.e. the link is a file drop.
+func IsUploadOnly(p *provider.ResourcePermissions) bool {
+	return p.GetInitiateFileUpload() && !p.GetInitiateFileDownload()
+}
+
+// Authenticate checks the password or the signature given to access a public
+// share against the bcrypt hash of its password.
+func Authenticate(share *link.PublicShare, pw string, auth *link.PublicShareAuthentication) bool {
+	switch {
+	case auth.GetPassword() != "":
+		if err := bcrypt.CompareHashAndPassword([]byte(pw), []byte(auth.GetPassword())); err == nil {
+			return true
+		}
+	case auth.GetSignature() != nil:
+		sig := auth.GetSignature()
+		now := time.Now()
+		expiration := time.Unix(int64(sig.GetSignatureExpiration().GetSeconds()), int64(sig.GetSignatureExpiration().GetNanos()))
+		if now.After(expiration) {
+			return false
+		}
+		s, err := CreateSignature(share.Token, pw, expiration)
+		if err != nil {
+			return false
+		}
+		return sig.GetSignature() == s
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package publicshare
+
+import (
+	"testing"
+	"time"
+
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestIsUploadOnly(t *testing.T) {
+	tests := []struct {
+		p        *provider.ResourcePermissions
+		expected bool
+	}{
+		{&provider.ResourcePermissions{InitiateFileUpload: true}, true},
+		{&provider.ResourcePermissions{InitiateFileUpload: true, InitiateFileDownload: true}, false},
+		{&provider.ResourcePermissions{InitiateFileDownload: true}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if IsUploadOnly(tt.p) != tt.expected {
+			t.Errorf("IsUploadOnly(%v) = %v, expected %v", tt.p, !tt.expected, tt.expected)
+		}
+	}
+}
+
+func TestIsExpired(t *testing.T) {
+	if IsExpired(&link.PublicShare{}) {
+		t.Error("expected a share without expiration not to expire")
+	}
+	past := &typesv1beta1.Timestamp{Seconds: uint64(time.Now().Add(-time.Minute).Unix())}
+	if !IsExpired(&link.PublicShare{Expiration: past}) {
+		t.Error("expected the share to have expired")
+	}
+	future := &typesv1beta1.Timestamp{Seconds: uint64(time.Now().Add(time.Minute).Unix())}
+	if IsExpired(&link.PublicShare{Expiration: future}) {
+		t.Error("expected the share not to have expired yet")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	h, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pw := string(h)
+	share := &link.PublicShare{Token: "token"}
+
+	if !Authenticate(share, pw, &link.PublicShareAuthentication{Spec: &link.PublicShareAuthentication_Password{Password: "s3cret"}}) {
+		t.Error("expected the password to be accepted")
+	}
+	if Authenticate(share, pw, &link.PublicShareAuthentication{Spec: &link.PublicShareAuthentication_Password{Password: "wrong"}}) {
+		t.Error("expected a wrong password to be rejected")
+	}
+	if Authenticate(share, pw, &link.PublicShareAuthentication{}) {
+		t.Error("expected a missing password to be rejected")
+	}
+
+	signed := &link.PublicShare{Token: "token"}
+	if err := AddSignature(signed, pw); err != nil {
+		t.Fatal(err)
+	}
+	auth := &link.PublicShareAuthentication{Spec: &link.PublicShareAuthentication_Signature{Signature: signed.Signature}}
+	if !Authenticate(share, pw, auth) {
+		t.Error("expected the signature to be accepted")
+	}
+	if Authenticate(&link.PublicShare{Token: "other"}, pw, auth) {
+		t.Error("expected the signature of another share to be rejected")
+	}
+
+	expired, err := CreateSignature("token", pw, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth = &link.PublicShareAuthentication{Spec: &link.PublicShareAuthentication_Signature{Signature: &link.ShareSignature{
+		Signature:           expired,
+		SignatureExpiration: &typesv1beta1.Timestamp{Seconds: uint64(time.Now().Add(-time.Minute).Unix())},
+	}}}
+	if Authenticate(share, pw, auth) {
+		t.Error("expected an expired signature to be rejected")
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package publicshare
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// OpaqueAccessRules is the opaque entry holding the access rules of a public
+// share. Clients set it on the update requests to replace the rules, the
+// publicshareprovider adds it to the responses returning a single share.
+const OpaqueAccessRules = "access_rules"
+
+// AccessRule restricts the access to a subtree of a shared folder.
+type AccessRule struct {
+	// Path of the subtree, relative to the shared folder.
+	Path string `json:"path"`
+	// Hidden hides the subtree from the recipients of the link.
+	Hidden bool `json:"hidden,omitempty"`
+	// ReadOnly denies changes to the subtree, even if the link allows them.
+	ReadOnly bool `json:"read_only,omitempty"`
+	// Password protects the subtree with an additional password. It is sent
+	// in clear text when setting the rules and stored as a bcrypt hash.
+	Password string `json:"password,omitempty"`
+	// PasswordProtected tells whether the subtree is protected by a password
+	// in the redacted rules returned to the owner.
+	PasswordProtected bool `json:"password_protected,omitempty"`
+}
+
+// AccessRules are the access rules of a public share.
+type AccessRules []*AccessRule
+
+// AccessRulesManager is implemented by the managers able to store access
+// rules for the subfolders of a public share.
+type AccessRulesManager interface {
+	// GetAccessRules returns the access rules of the share with the given id.
+	GetAccessRules(ctx context.Context, id *link.PublicShareId) (AccessRules, error)
+	// SetAccessRules replaces the access rules of a share of the user. The
+	// managers store the hashes of the clear text passwords of the rules.
+	SetAccessRules(ctx context.Context, u *user.User, ref *link.PublicShareReference, rules AccessRules) error
+}
+
+// Access is the access the rules grant to a path below the shared folder.
+type Access struct {
+	// Hidden is set when the path is hidden from the recipients.
+	Hidden bool
+	// ReadOnly is set when the path must not be changed.
+	ReadOnly bool
+	// Locked is set when the path is protected by a password which was not
+	// given or did not match.
+	Locked bool
+}
+
+// DecodeAccessRules parses and normalizes access rules. An empty value yields
+// no rules.
+func DecodeAccessRules(v []byte) (AccessRules, error) {
+	if len(v) == 0 {
+		return nil, nil
+	}
+	rules := AccessRules{}
+	if err := json.Unmarshal(v, &rules); err != nil {
+		return nil, errtypes.BadRequest("invalid access rules: " + err.Error())
+	}
+	for _, r := range rules {
+		if r == nil {
+			return nil, errtypes.BadRequest("invalid access rules: empty rule")
+		}
+		r.Path = cleanRulePath(r.Path)
+	}
+	return rules, nil
+}
+
+// AccessRulesFromOpaque returns the access rules in the given opaque. The
+// second return value is false if the opaque carries no rules.
+func AccessRulesFromOpaque(o *typesv1beta1.Opaque) (AccessRules, bool, error) {
+	e, ok := o.GetMap()[OpaqueAccessRules]
+	if !ok {
+		return nil, false, nil
+	}
+	rules, err := DecodeAccessRules(e.Value)
+	return rules, true, err
+}
+
+// AddAccessRulesToOpaque encodes the access rules into the given opaque and
+// returns it.
+func AddAccessRulesToOpaque(o *typesv1beta1.Opaque, rules AccessRules) (*typesv1beta1.Opaque, error) {
+	v, err := json.Marshal(rules)
+	if err != nil {
+		return nil, err
+	}
+	if o == nil {
+		o = &typesv1beta1.Opaque{}
+	}
+	if o.Map == nil {
+		o.Map = map[string]*typesv1beta1.OpaqueEntry{}
+	}
+	o.Map[OpaqueAccessRules] = &typesv1beta1.OpaqueEntry{Decoder: "json", Value: v}
+	return o, nil
+}
+
+// HashPasswords replaces the clear text passwords of the rules by their hash.
+func (rules AccessRules) HashPasswords(cost int) error {
+	for _, r := range rules {
+		if r.Password == "" {
+			continue
+		}
+		h, err := bcrypt.GenerateFromPassword([]byte(r.Password), cost)
+		if err != nil {
+			return err
+		}
+		r.Password = string(h)
+	}
+	return nil
+}
+
+// Redacted returns a copy of the rules without the password hashes, which is
+// safe to hand out to clients.
+func (rules AccessRules) Redacted() AccessRules {
+	redacted := make(AccessRules, 0, len(rules))
+	for _, r := range rules {
+		redacted = append(redacted, &AccessRule{
+			Path:              r.Path,
+			Hidden:            r.Hidden,
+			ReadOnly:          r.ReadOnly,
+			PasswordProtected: r.Password != "",
+		})
+	}
+	return redacted
+}
+
+// Evaluate returns the access to the given path relative to the shared
+// folder. The rules on the path and on all its parents apply, the password
+// must match all password rules among them.
+func (rules AccessRules) Evaluate(relativePath, password string) Access {
+	a := Access{}
+	p := cleanRulePath(relativePath)
+	for _, r := range rules {
+		if !appliesTo(r.Path, p) {
+			continue
+		}
+		a.Hidden = a.Hidden || r.Hidden
+		a.ReadOnly = a.ReadOnly || r.ReadOnly
+		if r.Password != "" && (password == "" || bcrypt.CompareHashAndPassword([]byte(r.Password), []byte(password)) != nil) {
+			a.Locked = true
+		}
+	}
+	return a
+}
+
+// cleanRulePath returns the path without leading and trailing slashes. The
+// shared folder itself is the empty path.
+func cleanRulePath(p string) string {
+	return strings.Trim(path.Clean("/"+p), "/")
+}
+
+// appliesTo tells whether a rule on the given subtree covers the path.
+func appliesTo(subtree, p string) bool {
+	return subtree == "" || p == subtree || strings.HasPrefix(p, subtree+"/")
+}
@@ -0,0 +1,71 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package publicshare
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAccessRules(t *testing.T) {
+	rules, err := DecodeAccessRules([]byte(`[
+		{"path": "/private/"},
+		{"path": "drafts", "hidden": true},
+		{"path": "archive", "read_only": true},
+		{"path": "archive/secret", "password": "s3cret"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules[0].Path != "private" {
+		t.Errorf("expected the rule path to be cleaned, got %q", rules[0].Path)
+	}
+	if err := rules.HashPasswords(bcrypt.MinCost); err != nil {
+		t.Fatal(err)
+	}
+	if rules[3].Password == "s3cret" {
+		t.Error("expected the password to be hashed")
+	}
+
+	tests := []struct {
+		path     string
+		password string
+		want     Access
+	}{
+		{"", "", Access{}},
+		{"drafts", "", Access{Hidden: true}},
+		{"drafts/a.txt", "", Access{Hidden: true}},
+		{"draftsfolder", "", Access{}},
+		{"archive/2020", "", Access{ReadOnly: true}},
+		{"archive/secret/a.txt", "", Access{ReadOnly: true, Locked: true}},
+		{"archive/secret", "wrong", Access{ReadOnly: true, Locked: true}},
+		{"/archive/secret/", "s3cret", Access{ReadOnly: true}},
+	}
+	for _, tt := range tests {
+		if got := rules.Evaluate(tt.path, tt.password); got != tt.want {
+			t.Errorf("Evaluate(%q, %q) = %+v, want %+v", tt.path, tt.password, got, tt.want)
+		}
+	}
+
+	redacted := rules.Redacted()
+	if redacted[3].Password != "" || !redacted[3].PasswordProtected {
+		t.Errorf("expected the password to be redacted, got %+v", redacted[3])
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package readiness keeps track of the availability of the components of the
+// daemon, such as the storage drivers of the mounts, and initializes them
+// lazily or in the background, so that revad does not wait at startup for the
+// backends they depend on.
+package readiness
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// State is the state of a component.
+type State string
+
+const (
+	// Pending is the state of a component not initialized yet.
+	Pending State = "pending"
+	// Ready is the state of a component which can be used.
+	Ready State = "ready"
+	// Failed is the state of a component whose last initialization failed.
+	Failed State = "failed"
+)
+
+// The initialization modes.
+const (
+	ModeEager      = "eager"
+	ModeLazy       = "lazy"
+	ModeBackground = "background"
+)
+
+// Warmer is implemented by the drivers having expensive checks to run before
+// being used, like probing a connection or checking that a bucket exists.
+type Warmer interface {
+	Warmup(ctx context.Context) error
+}
+
+// Check returns a function running the warmup checks of v, if it implements
+// Warmer, to be used as the initialization of a component.
+func Check(v interface{}) func(context.Context) error {
+	return func(ctx context.Context) error {
+		if w, ok := v.(Warmer); ok {
+			return w.Warmup(ctx)
+		}
+		return nil
+	}
+}
+
+// Status describes the state of a component.
+type Status struct {
+	Name     string    `json:"name"`
+	State    State     `json:"state"`
+	Error    string    `json:"error,omitempty"`
+	Since    time.Time `json:"since"`
+	Attempts int       `json:"attempts"`
+}
+
+// Probe reports the state of a component.
+type Probe struct {
+	mu     sync.Mutex
+	status Status
+}
+
+var (
+	mu     sync.RWMutex
+	probes = map[string]*Probe{}
+)
+
+// Register returns a pending probe for the component with the given name,
+// replacing the one registered before under the same name.
+func Register(name string) *Probe {
+	p := &Probe{status: Status{Name: name, State: Pending, Since: time.Now()}}
+	mu.Lock()
+	probes[name] = p
+	mu.Unlock()
+	return p
+}
+
+// Unregister removes the probe of the component with the given name.
+func Unregister(name string) {
+	mu.Lock()
+	delete(probes, name)
+	mu.Unlock()
+}
+
+// List returns the states of all the components, sorted by name.
+func List() []Status {
+	mu.RLock()
+	list := make([]Status, 0, len(probes))
+	for _, p := range probes {
+		list = append(list, p.Status())
+	}
+	mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Report records the outcome of an attempt to initialize the component.
+func (p *Probe) Report(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.Attempts++
+	state, msg := Ready, ""
+	if err != nil {
+		state, msg = Failed, err.Error()
+	}
+	if state != p.status.State {
+		p.status.Since = time.Now()
+	}
+	p.status.State, p.status.Error = state, msg
+}
+
+// Status returns the state of the component.
+func (p *Probe) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+// Config configures when a component is initialized.
+type Config struct {
+	Mode          string `mapstructure:"mode" docs:";When the driver is initialized: eager (at startup, which fails with it), lazy (on its first use) or background (right after startup, without delaying it). If empty, the driver is created at startup without running its warmup checks."`
+	RetryInterval int    `mapstructure:"retry_interval" docs:"30;Seconds to wait before initializing the driver again after a failure."`
+	Timeout       int    `mapstructure:"timeout" docs:"60;Timeout in seconds of a single initialization attempt."`
+}
+
+// Enabled returns whether the configuration requires an initializer.
+func (c *Config) Enabled() bool {
+	return c.Mode != ""
+}
+
+func (c *Config) init() {
+	if c.RetryInterval <= 0 {
+		c.RetryInterval = 30
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 60
+	}
+}
+
+// Initializer runs the initialization of a component according to its
+// configuration and reports its outcome to the probe of the component.
+type Initializer struct {
+	name  string
+	conf  *Config
+	init  func(context.Context) error
+	probe *Probe
+	stop  chan struct{}
+
+	// run serializes the attempts, mu guards the outcome of the last one
+	run  sync.Mutex
+	mu   sync.Mutex
+	done bool
+	last time.Time
+	err  error
+}
+
+// NewInitializer returns an initializer running init for the component with
+// the given name; the initialization begins with Start.
+func NewInitializer(name string, c *Config, init func(context.Context) error) (*Initializer, error) {
+	switch c.Mode {
+	case ModeEager, ModeLazy, ModeBackground:
+	default:
+		return nil, errors.Errorf("readiness: unknown initialization mode %q for %s", c.Mode, name)
+	}
+	c.init()
+
+	return &Initializer{
+		name:  name,
+		conf:  c,
+		init:  init,
+		probe: Register(name),
+		stop:  make(chan struct{}),
+	}, nil
+}
+
+// Start initializes the component right away in eager mode, returning the
+// error, and starts initializing it in background mode.
+func (i *Initializer) Start() error {
+	switch i.conf.Mode {
+	case ModeEager:
+		i.run.Lock()
+		defer i.run.Unlock()
+		return i.attempt()
+	case ModeBackground:
+		go i.loop()
+	}
+	return nil
+}
+
+// Ensure returns nil once the component is initialized. In lazy mode, it
+// initializes the component if it is not, unless the last attempt failed
+// less than the retry interval ago.
+func (i *Initializer) Ensure(ctx context.Context) error {
+	if done, err := i.outcome(); done {
+		return nil
+	} else if i.conf.Mode != ModeLazy {
+		return i.notReady(err)
+	}
+
+	i.run.Lock()
+	defer i.run.Unlock()
+	i.mu.Lock()
+	done, last, err := i.done, i.last, i.err
+	i.mu.Unlock()
+	if done {
+		return nil
+	}
+	if !last.IsZero() && time.Since(last) < time.Duration(i.conf.RetryInterval)*time.Second {
+		return i.notReady(err)
+	}
+	if err := i.attempt(); err != nil {
+		return i.notReady(err)
+	}
+	return nil
+}
+
+// Close stops the initialization in background and removes the probe.
+func (i *Initializer) Close() {
+	close(i.stop)
+	Unregister(i.name)
+}
+
+func (i *Initializer) outcome() (bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.done, i.err
+}
+
+func (i *Initializer) notReady(err error) error {
+	if err == nil {
+		return errors.Errorf("readiness: %s is not ready yet", i.name)
+	}
+	return errors.Wrapf(err, "readiness: %s is not ready", i.name)
+}
+
+// attempt must be called holding the run lock.
+func (i *Initializer) attempt() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(i.conf.Timeout)*time.Second)
+	defer cancel()
+	err := i.init(ctx)
+
+	i.mu.Lock()
+	i.done, i.last, i.err = err == nil, time.Now(), err
+	i.mu.Unlock()
+	i.probe.Report(err)
+	return err
+}
+
+func (i *Initializer) loop() {
+	for {
+		i.run.Lock()
+		err := i.attempt()
+		i.run.Unlock()
+		if err == nil {
+			return
+		}
+
+		select {
+		case <-i.stop:
+			return
+		case <-time.After(time.Duration(i.conf.RetryInterval) * time.Second):
+		}
+	}
+}
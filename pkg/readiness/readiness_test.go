@@ -0,0 +1,122 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package readiness
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLazy(t *testing.T) {
+	calls := 0
+	fail := true
+	i, err := NewInitializer("test-lazy", &Config{Mode: ModeLazy, RetryInterval: 1}, func(context.Context) error {
+		calls++
+		if fail {
+			return errors.New("backend down")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer i.Close()
+
+	if err := i.Start(); err != nil || calls != 0 {
+		t.Fatalf("lazy initializer ran at startup: calls=%d err=%v", calls, err)
+	}
+	if s := i.probe.Status(); s.State != Pending {
+		t.Fatalf("unexpected status %+v", s)
+	}
+	if err := i.Ensure(context.Background()); err == nil || calls != 1 {
+		t.Fatalf("expected a failed attempt, got calls=%d err=%v", calls, err)
+	}
+	// within the retry interval the last error is returned without retrying
+	fail = false
+	if err := i.Ensure(context.Background()); err == nil || calls != 1 {
+		t.Fatalf("expected no retry, got calls=%d err=%v", calls, err)
+	}
+
+	time.Sleep(time.Second)
+	if err := i.Ensure(context.Background()); err != nil || calls != 2 {
+		t.Fatalf("expected a successful retry, got calls=%d err=%v", calls, err)
+	}
+	if err := i.Ensure(context.Background()); err != nil || calls != 2 {
+		t.Fatalf("expected no further attempt, got calls=%d err=%v", calls, err)
+	}
+	if s := i.probe.Status(); s.State != Ready || s.Attempts != 2 {
+		t.Fatalf("unexpected status %+v", s)
+	}
+}
+
+func TestBackground(t *testing.T) {
+	ready := make(chan struct{})
+	i, err := NewInitializer("test-background", &Config{Mode: ModeBackground}, func(context.Context) error {
+		<-ready
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer i.Close()
+
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Ensure(context.Background()); err == nil {
+		t.Fatal("component reported as initialized before its initialization ended")
+	}
+	close(ready)
+	for deadline := time.Now().Add(time.Second); i.Ensure(context.Background()) != nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("component not initialized in background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	list := List()
+	if len(list) != 1 || list[0].Name != "test-background" || list[0].State != Ready {
+		t.Fatalf("unexpected components %+v", list)
+	}
+}
+
+func TestEager(t *testing.T) {
+	i, err := NewInitializer("test-eager", &Config{Mode: ModeEager}, func(context.Context) error {
+		return errors.New("backend down")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer i.Close()
+
+	if err := i.Start(); err == nil {
+		t.Fatal("expected the eager initialization to fail")
+	}
+	if s := i.probe.Status(); s.State != Failed || s.Error != "backend down" {
+		t.Fatalf("unexpected status %+v", s)
+	}
+}
+
+func TestUnknownMode(t *testing.T) {
+	if _, err := NewInitializer("test-unknown", &Config{Mode: "sometimes"}, nil); err == nil {
+		t.Fatal("expected an error for an unknown mode")
+	}
+}
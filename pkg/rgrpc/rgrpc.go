@@ -24,13 +24,20 @@ import (
 	"net"
 	"sort"
 
+	"github.com/cs3org/reva/internal/grpc/interceptors/accesslog"
 	"github.com/cs3org/reva/internal/grpc/interceptors/appctx"
 	"github.com/cs3org/reva/internal/grpc/interceptors/auth"
+	"github.com/cs3org/reva/internal/grpc/interceptors/cost"
 	"github.com/cs3org/reva/internal/grpc/interceptors/log"
 	"github.com/cs3org/reva/internal/grpc/interceptors/recovery"
+	"github.com/cs3org/reva/internal/grpc/interceptors/slo"
 	"github.com/cs3org/reva/internal/grpc/interceptors/token"
 	"github.com/cs3org/reva/internal/grpc/interceptors/useragent"
+	rlog "github.com/cs3org/reva/pkg/accesslog"
+	rcost "github.com/cs3org/reva/pkg/cost"
 	"github.com/cs3org/reva/pkg/sharedconf"
+	rslo "github.com/cs3org/reva/pkg/slo"
+	"github.com/cs3org/reva/pkg/sysinfo"
 	rtrace "github.com/cs3org/reva/pkg/trace"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/mitchellh/mapstructure"
@@ -101,6 +108,8 @@ type config struct {
 	Services         map[string]map[string]interface{} `mapstructure:"services"`
 	Interceptors     map[string]map[string]interface{} `mapstructure:"interceptors"`
 	EnableReflection bool                              `mapstructure:"enable_reflection"`
+	// AccessLog configures the access log of the calls, written apart from the application logs.
+	AccessLog rlog.Config `mapstructure:"access_log"`
 }
 
 func (c *config) init() {
@@ -120,6 +129,8 @@ type Server struct {
 	listener net.Listener
 	log      zerolog.Logger
 	services map[string]Service
+	// grpcServices maps the gRPC services to the names of the services implementing them.
+	grpcServices map[string]string
 }
 
 // NewServer returns a new Server.
@@ -131,7 +142,7 @@ func NewServer(m interface{}, log zerolog.Logger) (*Server, error) {
 
 	conf.init()
 
-	server := &Server{conf: conf, log: log, services: map[string]Service{}}
+	server := &Server{conf: conf, log: log, services: map[string]Service{}, grpcServices: map[string]string{}}
 
 	return server, nil
 }
@@ -199,9 +210,20 @@ func (s *Server) registerServices() error {
 	}
 	grpcServer := grpc.NewServer(opts...)
 
-	for _, svc := range s.services {
+	for svcName, svc := range s.services {
 		svc.Register(grpcServer)
+		for name := range grpcServer.GetServiceInfo() {
+			if _, ok := s.grpcServices[name]; !ok {
+				s.grpcServices[name] = svcName
+			}
+		}
+	}
+
+	names := make([]string, 0, len(grpcServer.GetServiceInfo()))
+	for name := range grpcServer.GetServiceInfo() {
+		names = append(names, name)
 	}
+	sysinfo.AddGRPCServices(names...)
 
 	if s.conf.EnableReflection {
 		s.log.Info().Msg("rgrpc: grpc server reflection enabled")
@@ -276,25 +298,47 @@ func (s *Server) getInterceptors(unprotected []string) ([]grpc.ServerOption, err
 		return nil, errors.Wrap(err, "rgrpc: error creating unary auth interceptor")
 	}
 
+	var accessLog *rlog.Logger
+	if s.conf.AccessLog.Enabled {
+		if accessLog, err = rlog.New(&s.conf.AccessLog); err != nil {
+			return nil, errors.Wrap(err, "rgrpc: error creating access log")
+		}
+	}
+
 	unaryInterceptors := []grpc.UnaryServerInterceptor{authUnary}
+	if accessLog != nil {
+		unaryInterceptors = append(unaryInterceptors, accesslog.NewUserUnary())
+	}
+	if rcost.Enabled() {
+		unaryInterceptors = append(unaryInterceptors, cost.NewUserUnary())
+	}
 	for _, t := range unaryTriples {
 		unaryInterceptors = append(unaryInterceptors, t.Interceptor)
 		s.log.Info().Msgf("rgrpc: chaining grpc unary interceptor %s with priority %d", t.Name, t.Priority)
 	}
 
-	unaryInterceptors = append(unaryInterceptors,
+	// the span of the request is started first, as a child of the one of the
+	// caller, so that the logs and the other interceptors are part of the trace
+	coreUnary := []grpc.UnaryServerInterceptor{
 		otelgrpc.UnaryServerInterceptor(
 			otelgrpc.WithTracerProvider(rtrace.Provider),
 			otelgrpc.WithPropagators(rtrace.Propagator)),
-	)
-
-	unaryInterceptors = append([]grpc.UnaryServerInterceptor{
 		appctx.NewUnary(s.log),
 		token.NewUnary(),
 		useragent.NewUnary(),
 		log.NewUnary(),
-		recovery.NewUnary(),
-	}, unaryInterceptors...)
+	}
+	if accessLog != nil {
+		coreUnary = append(coreUnary, accesslog.NewUnary(accessLog, s.grpcServices))
+	}
+	if rslo.Enabled() {
+		coreUnary = append(coreUnary, slo.NewUnary(s.grpcServices))
+	}
+	if rcost.Enabled() {
+		coreUnary = append(coreUnary, cost.NewUnary())
+	}
+	coreUnary = append(coreUnary, recovery.NewUnary())
+	unaryInterceptors = append(coreUnary, unaryInterceptors...)
 	unaryChain := grpc_middleware.ChainUnaryServer(unaryInterceptors...)
 
 	streamTriples := []*streamInterceptorTriple{}
@@ -329,14 +373,21 @@ func (s *Server) getInterceptors(unprotected []string) ([]grpc.ServerOption, err
 		s.log.Info().Msgf("rgrpc: chaining grpc streaming interceptor %s with priority %d", t.Name, t.Priority)
 	}
 
-	streamInterceptors = append([]grpc.StreamServerInterceptor{
+	coreStream := []grpc.StreamServerInterceptor{
+		otelgrpc.StreamServerInterceptor(
+			otelgrpc.WithTracerProvider(rtrace.Provider),
+			otelgrpc.WithPropagators(rtrace.Propagator)),
 		authStream,
 		appctx.NewStream(s.log),
 		token.NewStream(),
 		useragent.NewStream(),
 		log.NewStream(),
-		recovery.NewStream(),
-	}, streamInterceptors...)
+	}
+	if accessLog != nil {
+		coreStream = append(coreStream, accesslog.NewStream(accessLog, s.grpcServices))
+	}
+	coreStream = append(coreStream, recovery.NewStream())
+	streamInterceptors = append(coreStream, streamInterceptors...)
 	streamChain := grpc_middleware.ChainStreamServer(streamInterceptors...)
 
 	opts := []grpc.ServerOption{
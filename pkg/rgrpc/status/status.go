@@ -137,6 +137,15 @@ func NewAlreadyExists(ctx context.Context, err error, msg string) *rpc.Status {
 	}
 }
 
+// NewUnavailable returns a Status with CODE_UNAVAILABLE.
+func NewUnavailable(ctx context.Context, msg string) *rpc.Status {
+	return &rpc.Status{
+		Code:    rpc.Code_CODE_UNAVAILABLE,
+		Message: msg,
+		Trace:   getTrace(ctx),
+	}
+}
+
 // NewInvalidArg returns a Status with CODE_INVALID_ARGUMENT.
 func NewInvalidArg(ctx context.Context, msg string) *rpc.Status {
 	return &rpc.Status{Code: rpc.Code_CODE_INVALID_ARGUMENT,
@@ -165,6 +174,40 @@ func NewFailedPrecondition(ctx context.Context, err error, msg string) *rpc.Stat
 	}
 }
 
+// LockedMessage is the message of the status returned when a write is rejected because
+// the resource carries a lock the caller does not hold.
+const LockedMessage = "resource is locked"
+
+// NewLocked returns a Status with CODE_FAILED_PRECONDITION telling that the resource is locked.
+// The cs3apis have no dedicated code for locks, clients recognize it with IsLocked.
+func NewLocked(ctx context.Context) *rpc.Status {
+	return &rpc.Status{
+		Code:    rpc.Code_CODE_FAILED_PRECONDITION,
+		Message: LockedMessage,
+		Trace:   getTrace(ctx),
+	}
+}
+
+// IsLocked tells whether the status was returned because the resource is locked.
+func IsLocked(s *rpc.Status) bool {
+	return s.GetCode() == rpc.Code_CODE_FAILED_PRECONDITION && s.GetMessage() == LockedMessage
+}
+
+// MaintenanceMessage is the message of the status returned when a write is rejected because
+// the storage is in maintenance mode.
+const MaintenanceMessage = "storage provider is in maintenance mode, writes are not allowed"
+
+// NewMaintenance returns a Status with CODE_UNAVAILABLE telling that the storage is in maintenance mode.
+// Clients recognize it with IsMaintenance and can retry the write later.
+func NewMaintenance(ctx context.Context) *rpc.Status {
+	return NewUnavailable(ctx, MaintenanceMessage)
+}
+
+// IsMaintenance tells whether the status was returned because the storage is in maintenance mode.
+func IsMaintenance(s *rpc.Status) bool {
+	return s.GetCode() == rpc.Code_CODE_UNAVAILABLE && s.GetMessage() == MaintenanceMessage
+}
+
 // NewStatusFromErrType returns a status that corresponds to the given errtype
 func NewStatusFromErrType(ctx context.Context, msg string, err error) *rpc.Status {
 	switch e := err.(type) {
@@ -40,7 +40,14 @@ import (
 	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	storageregistry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
 	datatx "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
+	deletejob "github.com/cs3org/reva/pkg/deletejob/proto"
+	search "github.com/cs3org/reva/pkg/search/proto"
+	sharedwithme "github.com/cs3org/reva/pkg/sharedwithme/proto"
+	fsck "github.com/cs3org/reva/pkg/storage/utils/fsck/proto"
+	movejournal "github.com/cs3org/reva/pkg/storage/utils/movejournal/proto"
+	verify "github.com/cs3org/reva/pkg/storage/utils/verify/proto"
 	rtrace "github.com/cs3org/reva/pkg/trace"
+	watch "github.com/cs3org/reva/pkg/watch/proto"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -80,6 +87,13 @@ var (
 	userProviders          = newProvider()
 	groupProviders         = newProvider()
 	dataTxs                = newProvider()
+	watchProviders         = newProvider()
+	sharedWithMeProviders  = newProvider()
+	deleteJobProviders     = newProvider()
+	moveJournalProviders   = newProvider()
+	fsckProviders          = newProvider()
+	verifyProviders        = newProvider()
+	searchProviders        = newProvider()
 )
 
 // NewConn creates a new connection to a grpc server
@@ -517,3 +531,150 @@ func GetDataTxClient(opts ...Option) (datatx.TxAPIClient, error) {
 //
 //		return "", fmt.Errorf("could not get service by name: %v", name)
 //	}
+
+// GetWatchServiceClient returns a WatchServiceClient.
+func GetWatchServiceClient(opts ...Option) (watch.WatchServiceClient, error) {
+	watchProviders.m.Lock()
+	defer watchProviders.m.Unlock()
+
+	options := newOptions(opts...)
+	if val, ok := watchProviders.conn[options.Endpoint]; ok {
+		return val.(watch.WatchServiceClient), nil
+	}
+
+	conn, err := NewConn(options)
+	if err != nil {
+		return nil, err
+	}
+
+	v := watch.NewWatchServiceClient(conn)
+	watchProviders.conn[options.Endpoint] = v
+
+	return v, nil
+}
+
+// GetSharedWithMeServiceClient returns a SharedWithMeServiceClient.
+func GetSharedWithMeServiceClient(opts ...Option) (sharedwithme.SharedWithMeServiceClient, error) {
+	sharedWithMeProviders.m.Lock()
+	defer sharedWithMeProviders.m.Unlock()
+
+	options := newOptions(opts...)
+	if val, ok := sharedWithMeProviders.conn[options.Endpoint]; ok {
+		return val.(sharedwithme.SharedWithMeServiceClient), nil
+	}
+
+	conn, err := NewConn(options)
+	if err != nil {
+		return nil, err
+	}
+
+	v := sharedwithme.NewSharedWithMeServiceClient(conn)
+	sharedWithMeProviders.conn[options.Endpoint] = v
+
+	return v, nil
+}
+
+// GetDeleteJobServiceClient returns a DeleteJobServiceClient.
+func GetDeleteJobServiceClient(opts ...Option) (deletejob.DeleteJobServiceClient, error) {
+	deleteJobProviders.m.Lock()
+	defer deleteJobProviders.m.Unlock()
+
+	options := newOptions(opts...)
+	if val, ok := deleteJobProviders.conn[options.Endpoint]; ok {
+		return val.(deletejob.DeleteJobServiceClient), nil
+	}
+
+	conn, err := NewConn(options)
+	if err != nil {
+		return nil, err
+	}
+
+	v := deletejob.NewDeleteJobServiceClient(conn)
+	deleteJobProviders.conn[options.Endpoint] = v
+
+	return v, nil
+}
+
+// GetMoveJournalServiceClient returns a MoveJournalServiceClient.
+func GetMoveJournalServiceClient(opts ...Option) (movejournal.MoveJournalServiceClient, error) {
+	moveJournalProviders.m.Lock()
+	defer moveJournalProviders.m.Unlock()
+
+	options := newOptions(opts...)
+	if val, ok := moveJournalProviders.conn[options.Endpoint]; ok {
+		return val.(movejournal.MoveJournalServiceClient), nil
+	}
+
+	conn, err := NewConn(options)
+	if err != nil {
+		return nil, err
+	}
+
+	v := movejournal.NewMoveJournalServiceClient(conn)
+	moveJournalProviders.conn[options.Endpoint] = v
+
+	return v, nil
+}
+
+// GetFsckServiceClient returns a FsckServiceClient.
+func GetFsckServiceClient(opts ...Option) (fsck.FsckServiceClient, error) {
+	fsckProviders.m.Lock()
+	defer fsckProviders.m.Unlock()
+
+	options := newOptions(opts...)
+	if val, ok := fsckProviders.conn[options.Endpoint]; ok {
+		return val.(fsck.FsckServiceClient), nil
+	}
+
+	conn, err := NewConn(options)
+	if err != nil {
+		return nil, err
+	}
+
+	v := fsck.NewFsckServiceClient(conn)
+	fsckProviders.conn[options.Endpoint] = v
+
+	return v, nil
+}
+
+// GetSearchServiceClient returns a SearchServiceClient.
+func GetSearchServiceClient(opts ...Option) (search.SearchServiceClient, error) {
+	searchProviders.m.Lock()
+	defer searchProviders.m.Unlock()
+
+	options := newOptions(opts...)
+	if val, ok := searchProviders.conn[options.Endpoint]; ok {
+		return val.(search.SearchServiceClient), nil
+	}
+
+	conn, err := NewConn(options)
+	if err != nil {
+		return nil, err
+	}
+
+	v := search.NewSearchServiceClient(conn)
+	searchProviders.conn[options.Endpoint] = v
+
+	return v, nil
+}
+
+// GetVerifyServiceClient returns a VerifyServiceClient.
+func GetVerifyServiceClient(opts ...Option) (verify.VerifyServiceClient, error) {
+	verifyProviders.m.Lock()
+	defer verifyProviders.m.Unlock()
+
+	options := newOptions(opts...)
+	if val, ok := verifyProviders.conn[options.Endpoint]; ok {
+		return val.(verify.VerifyServiceClient), nil
+	}
+
+	conn, err := NewConn(options)
+	if err != nil {
+		return nil, err
+	}
+
+	v := verify.NewVerifyServiceClient(conn)
+	verifyProviders.conn[options.Endpoint] = v
+
+	return v, nil
+}
@@ -25,8 +25,13 @@ import (
 	"net/http"
 
 	"go.opencensus.io/plugin/ochttp"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
 
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	rtrace "github.com/cs3org/reva/pkg/trace"
 	"github.com/pkg/errors"
 )
 
@@ -44,14 +49,64 @@ func GetHTTPClient(opts ...Option) *http.Client {
 
 	httpClient := &http.Client{
 		Timeout: options.Timeout,
-		Transport: &ochttp.Transport{
-			Base: tr,
+		Transport: &requestIDTransport{
+			base: &traceTransport{
+				base: &ochttp.Transport{
+					Base: tr,
+				},
+			},
 		},
 	}
 
 	return httpClient
 }
 
+// requestIDTransport forwards the request ID of the current request to the called service.
+type requestIDTransport struct {
+	base http.RoundTripper
+}
+
+func (t *requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if _, ok := ctxpkg.ContextGetRequestID(req.Context()); ok && req.Header.Get(ctxpkg.RequestIDHeader) == "" {
+		// A RoundTripper must not modify the original request
+		req = req.Clone(req.Context())
+		ctxpkg.AddRequestIDHeader(req.Context(), req.Header)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// traceTransport traces the requests to the called service, propagating the
+// trace of the current request with the traceparent header.
+type traceTransport struct {
+	base http.RoundTripper
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := rtrace.Provider.Tracer("http").Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPMethodKey.String(req.Method),
+			// the query is left out as it might carry credentials
+			semconv.HTTPURLKey.String(req.URL.Scheme+"://"+req.URL.Host+req.URL.Path),
+		))
+	defer span.End()
+
+	// A RoundTripper must not modify the original request
+	req = req.Clone(ctx)
+	rtrace.Propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPStatusCodeKey.Int(res.StatusCode))
+	if res.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, res.Status)
+	}
+	return res, nil
+}
+
 // NewRequest creates an HTTP request that sets the token if it is passed in ctx.
 func NewRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
 	httpReq, err := http.NewRequest(method, url, body)
@@ -64,6 +119,7 @@ func NewRequest(ctx context.Context, method, url string, body io.Reader) (*http.
 	if ok {
 		httpReq.Header.Set(ctxpkg.TokenHeader, tkn)
 	}
+	ctxpkg.AddRequestIDHeader(ctx, httpReq.Header)
 
 	httpReq = httpReq.WithContext(ctx)
 	return httpReq, nil
@@ -0,0 +1,141 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package clientip determines the IP of the clients of the http server.
+// The addresses reported by proxies, either through the X-Forwarded-For and
+// X-Real-IP headers or through the PROXY protocol, are only taken into
+// account if the proxy is trusted.
+package clientip
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/pkg/errors"
+)
+
+// Networks is a list of trusted networks.
+type Networks []*net.IPNet
+
+// ParseNetworks parses a list of IPs and networks in CIDR notation.
+func ParseNetworks(entries []string) (Networks, error) {
+	var nets Networks
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if !strings.Contains(e, "/") {
+			ip := net.ParseIP(e)
+			if ip == nil {
+				return nil, errors.Errorf("clientip: invalid IP %q", e)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(e)
+		if err != nil {
+			return nil, errors.Wrapf(err, "clientip: invalid network %q", e)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// Contains returns whether the IP is in one of the networks.
+func (nets Networks) Contains(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// FromRequest returns the IP of the client of the request. If the peer is a
+// trusted proxy, the X-Forwarded-For header is walked from the right, the
+// proxies appending to it, and the first untrusted address is the client;
+// the X-Real-IP header is used if there is no X-Forwarded-For header.
+func FromRequest(r *http.Request, trusted Networks) string {
+	peer := hostOf(r.RemoteAddr)
+	if !trusted.Contains(net.ParseIP(peer)) {
+		return peer
+	}
+
+	var hops []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(h, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hostOf(hop))
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hops[i])
+		if ip == nil {
+			// the header has been tampered with beyond this point
+			break
+		}
+		if i == 0 || !trusted.Contains(ip) {
+			return ip.String()
+		}
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return peer
+}
+
+// Handler returns a middleware storing the IP of the client in the context of
+// the requests and as their remote address, so that the handlers can rely on it.
+func Handler(trusted Networks) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := FromRequest(r, trusted)
+			if ip != "" {
+				if ip != hostOf(r.RemoteAddr) {
+					_, port, err := net.SplitHostPort(r.RemoteAddr)
+					if err != nil {
+						port = "0"
+					}
+					r.RemoteAddr = net.JoinHostPort(ip, port)
+				}
+				r = r.WithContext(ctxpkg.ContextSetClientIP(r.Context(), ip))
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// hostOf strips the port, if any, from an address.
+func hostOf(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.Trim(addr, "[]")
+}
@@ -0,0 +1,167 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package clientip
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+)
+
+func TestFromRequest(t *testing.T) {
+	trusted, err := ParseNetworks([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		realIP string
+		want   string
+	}{
+		{"untrusted peer", "203.0.113.7:1234", []string{"198.51.100.1"}, "", "203.0.113.7"},
+		{"trusted peer without headers", "10.1.2.3:1234", nil, "", "10.1.2.3"},
+		{"trusted peer", "10.1.2.3:1234", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"chain of proxies", "10.1.2.3:1234", []string{"1.1.1.1, 198.51.100.1", "192.168.1.1"}, "", "198.51.100.1"},
+		{"spoofed entries are skipped", "10.1.2.3:1234", []string{"6.6.6.6, 198.51.100.1, 10.9.9.9"}, "", "198.51.100.1"},
+		{"only proxies", "10.1.2.3:1234", []string{"10.9.9.9, 10.8.8.8"}, "", "10.9.9.9"},
+		{"garbage", "10.1.2.3:1234", []string{"not-an-ip"}, "", "10.1.2.3"},
+		{"real ip", "192.168.1.1:1234", nil, "198.51.100.2", "198.51.100.2"},
+		{"ipv6 peer", "[2001:db8::1]:1234", []string{"198.51.100.1"}, "", "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tt.remote
+		for _, v := range tt.xff {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		if tt.realIP != "" {
+			r.Header.Set("X-Real-IP", tt.realIP)
+		}
+		if got := FromRequest(r, trusted); got != tt.want {
+			t.Errorf("%s: got %q, expected %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	trusted, _ := ParseNetworks([]string{"10.0.0.0/8"})
+
+	var remote, ip string
+	h := Handler(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remote = r.RemoteAddr
+		ip, _ = ctxpkg.ContextGetClientIP(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.1.2.3:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if ip != "198.51.100.1" || remote != "198.51.100.1:1234" {
+		t.Fatalf("expected the forwarded client, got ip %q and remote address %q", ip, remote)
+	}
+}
+
+func TestReadHeader(t *testing.T) {
+	v2 := func(verCmd, family byte, payload []byte) []byte {
+		b := append([]byte{}, v2Signature...)
+		b = append(b, verCmd, family, byte(len(payload)>>8), byte(len(payload)))
+		return append(b, payload...)
+	}
+	ipv4 := []byte{198, 51, 100, 1, 10, 0, 0, 1, 0x30, 0x39, 0x01, 0xbb}
+
+	tests := []struct {
+		name   string
+		header []byte
+		want   string
+		err    bool
+	}{
+		{"v1 tcp4", []byte("PROXY TCP4 198.51.100.1 10.0.0.1 12345 443\r\n"), "198.51.100.1:12345", false},
+		{"v1 tcp6", []byte("PROXY TCP6 2001:db8::1 2001:db8::2 12345 443\r\n"), "[2001:db8::1]:12345", false},
+		{"v1 unknown", []byte("PROXY UNKNOWN\r\n"), "", false},
+		{"v1 malformed", []byte("PROXY TCP4 nope 10.0.0.1 12345 443\r\n"), "", true},
+		{"v2 tcp4", v2(0x21, 0x11, ipv4), "198.51.100.1:12345", false},
+		{"v2 local", v2(0x20, 0x00, nil), "", false},
+		{"v2 truncated", v2(0x21, 0x11, ipv4[:6]), "", true},
+		{"no header", []byte("GET / HTTP/1.1\r\nHost: example.org\r\n\r\n"), "", true},
+	}
+	for _, tt := range tests {
+		r := bufio.NewReader(bytes.NewReader(append(tt.header, "payload"...)))
+		addr, err := readHeader(r)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if tt.err {
+			continue
+		}
+		got := ""
+		if addr != nil {
+			got = addr.String()
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, expected %q", tt.name, got, tt.want)
+		}
+		if rest, _ := ioutil.ReadAll(r); string(rest) != "payload" {
+			t.Errorf("%s: expected the data following the header to be kept, got %q", tt.name, rest)
+		}
+	}
+}
+
+func TestProxyListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trusted, _ := ParseNetworks([]string{"127.0.0.1"})
+	pln := NewProxyListener(ln, trusted)
+	defer pln.Close()
+
+	go func() {
+		c, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		_, _ = c.Write([]byte("PROXY TCP4 198.51.100.1 127.0.0.1 12345 443\r\nhello"))
+	}()
+
+	c, err := pln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if got := c.RemoteAddr().String(); got != "198.51.100.1:12345" {
+		t.Fatalf("expected the address of the client, got %q", got)
+	}
+	data, _ := ioutil.ReadAll(c)
+	if string(data) != "hello" {
+		t.Fatalf("expected the data following the header, got %q", data)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package cors
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/cors"
+)
+
+// Config holds the CORS settings of the server or a single service.
+type Config struct {
+	AllowCredentials   bool     `mapstructure:"allow_credentials" docs:"false;Whether the request can include user credentials like cookies."`
+	OptionsPassthrough bool     `mapstructure:"options_passthrough" docs:"false;Pass preflight requests on to the service instead of answering them."`
+	Debug              bool     `mapstructure:"debug"`
+	MaxAge             int      `mapstructure:"max_age" docs:"0;How long (in seconds) the results of a preflight request can be cached."`
+	AllowedMethods     []string `mapstructure:"allowed_methods"`
+	AllowedHeaders     []string `mapstructure:"allowed_headers"`
+	ExposedHeaders     []string `mapstructure:"exposed_headers"`
+	AllowedOrigins     []string `mapstructure:"allowed_origins" docs:"[*];Allowed origins; an origin may contain a single * wildcard, e.g. https://*.example.org."`
+}
+
+// Init applies some defaults to reduce configuration boilerplate.
+func (c *Config) Init() {
+	if len(c.AllowedOrigins) == 0 {
+		c.AllowedOrigins = []string{"*"}
+	}
+
+	if len(c.AllowedMethods) == 0 {
+		c.AllowedMethods = []string{
+			"OPTIONS",
+			"HEAD",
+			"GET",
+			"PUT",
+			"PATCH",
+			"POST",
+			"DELETE",
+			"MKCOL",
+			"PROPFIND",
+			"PROPPATCH",
+			"MOVE",
+			"COPY",
+			"REPORT",
+			"SEARCH",
+		}
+	}
+
+	if len(c.AllowedHeaders) == 0 {
+		c.AllowedHeaders = []string{
+			"Origin",
+			"Accept",
+			"Content-Type",
+			"Depth",
+			"Authorization",
+			"Ocs-Apirequest",
+			"If-None-Match",
+			"If-Match",
+			"Destination",
+			"Overwrite",
+			"X-Request-Id",
+			"X-Requested-With",
+			"Tus-Resumable",
+			"Tus-Checksum-Algorithm",
+			"Upload-Concat",
+			"Upload-Length",
+			"Upload-Metadata",
+			"Upload-Defer-Length",
+			"Upload-Expires",
+			"Upload-Checksum",
+			"Upload-Offset",
+			"X-HTTP-Method-Override",
+		}
+	}
+
+	if len(c.ExposedHeaders) == 0 {
+		c.ExposedHeaders = []string{
+			"Location",
+		}
+	}
+}
+
+// Parse decodes a CORS configuration and applies the defaults.
+func Parse(m map[string]interface{}) (*Config, error) {
+	c := &Config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "cors: error decoding configuration")
+	}
+	c.Init()
+	return c, nil
+}
+
+// New creates a new CORS handler from the given configuration.
+func New(c *Config) *cors.Cors {
+	// TODO(jfd): use log from request context, otherwise fmt will be used to log,
+	// preventing us from pinging the log to eg jq
+	return cors.New(cors.Options{
+		AllowCredentials:   c.AllowCredentials,
+		AllowedHeaders:     c.AllowedHeaders,
+		AllowedMethods:     c.AllowedMethods,
+		AllowedOrigins:     c.AllowedOrigins,
+		ExposedHeaders:     c.ExposedHeaders,
+		MaxAge:             c.MaxAge,
+		OptionsPassthrough: c.OptionsPassthrough,
+		Debug:              c.Debug,
+	})
+}
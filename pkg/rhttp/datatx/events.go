@@ -0,0 +1,68 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package datatx
+
+import (
+	"context"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/events/server"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+// NewPublisher returns the publisher of the events about the uploads handled
+// by a data transfer protocol, nil if no broker is configured.
+func NewPublisher(c *server.Config) (events.Publisher, error) {
+	if c.Type == "" {
+		return nil, nil
+	}
+	return server.NewStream(c)
+}
+
+// EmitFileUploaded publishes that an upload finished, if there is a publisher.
+// The uploaded file is stated so that the consumers, which don't know the storage,
+// can look it up by its id.
+func EmitFileUploaded(ctx context.Context, p events.Publisher, fs storage.FS, ev events.FileUploaded) {
+	if p == nil {
+		return
+	}
+	if ev.Executant == nil {
+		ev.Executant = executant(ctx)
+	}
+	if ev.Ref != nil {
+		if md, err := fs.GetMD(ctx, ev.Ref, nil); err == nil {
+			ev.Ref = &provider.Reference{ResourceId: md.Id, Path: "."}
+			ev.Path = md.Path
+		}
+	}
+	if err := events.Publish(p, ev); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("upload", ev.UploadID).Msg("error publishing upload event")
+	}
+}
+
+func executant(ctx context.Context) *user.UserId {
+	if u, ok := ctxpkg.ContextGetUser(ctx); ok {
+		return u.Id
+	}
+	return nil
+}
@@ -19,27 +19,53 @@
 package simple
 
 import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"path"
+	"strings"
 
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/events/server"
 	"github.com/cs3org/reva/pkg/rhttp/datatx"
 	"github.com/cs3org/reva/pkg/rhttp/datatx/manager/registry"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/checksum"
 	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/download"
 	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/utils/resourceid"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultMaxFormUploadSize = 10 * 1024 * 1024
+
+	// formFieldFile is the form field holding the uploaded file.
+	formFieldFile = "file"
+	// formFieldChecksum is the optional form field holding the checksum of the file as '<algorithm> <base64 digest>'; it has to precede the file.
+	formFieldChecksum = "checksum"
 )
 
 func init() {
 	registry.Register("simple", New)
 }
 
-type config struct{}
+type config struct {
+	// Events publishes an event for every finished upload, if a broker is configured.
+	Events server.Config `mapstructure:"events"`
+	// MaxFormUploadSize is the maximum size in bytes of the multipart/form-data uploads sent by web forms.
+	MaxFormUploadSize int64 `mapstructure:"max_form_upload_size"`
+}
 
 type manager struct {
-	conf *config
+	conf      *config
+	publisher events.Publisher
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -48,6 +74,9 @@ func parseConfig(m map[string]interface{}) (*config, error) {
 		err = errors.Wrap(err, "error decoding conf")
 		return nil, err
 	}
+	if c.MaxFormUploadSize == 0 {
+		c.MaxFormUploadSize = defaultMaxFormUploadSize
+	}
 	return c, nil
 }
 
@@ -58,7 +87,12 @@ func New(m map[string]interface{}) (datatx.DataTX, error) {
 		return nil, err
 	}
 
-	return &manager{conf: c}, nil
+	publisher, err := datatx.NewPublisher(&c.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manager{conf: c, publisher: publisher}, nil
 }
 
 func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
@@ -75,26 +109,30 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 
 			ref := &provider.Reference{Path: fn}
 
-			err := fs.Upload(ctx, ref, r.Body)
-			switch v := err.(type) {
-			case nil:
-				w.WriteHeader(http.StatusOK)
-			case errtypes.PartialContent:
-				w.WriteHeader(http.StatusPartialContent)
-			case errtypes.ChecksumMismatch:
-				w.WriteHeader(errtypes.StatusChecksumMismatch)
-			case errtypes.NotFound:
-				w.WriteHeader(http.StatusNotFound)
-			case errtypes.PermissionDenied:
-				w.WriteHeader(http.StatusForbidden)
-			case errtypes.InvalidCredentials:
-				w.WriteHeader(http.StatusUnauthorized)
-			case errtypes.InsufficientStorage:
-				w.WriteHeader(http.StatusInsufficientStorage)
-			default:
-				sublog.Error().Err(v).Msg("error uploading file")
-				w.WriteHeader(http.StatusInternalServerError)
+			// the body is only hashed if the client announced a checksum
+			var body io.ReadCloser = r.Body
+			var cr *checksum.Reader
+			if checksum.Requested(r) {
+				cr = checksum.NewReader(r)
+				body = cr
+			}
+			err := fs.Upload(ctx, ref, body)
+			if cr != nil && cr.Err() != nil {
+				err = cr.Err()
 			}
+			if err != nil {
+				writeUploadError(w, &sublog, err)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			datatx.EmitFileUploaded(ctx, m.publisher, fs, events.FileUploaded{
+				Ref:      ref,
+				UploadID: path.Base(fn),
+				Size:     r.ContentLength,
+			})
+			return
+		case "POST":
+			m.formUpload(w, r, fs)
 			return
 		default:
 			w.WriteHeader(http.StatusNotImplemented)
@@ -102,3 +140,156 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 	})
 	return h, nil
 }
+
+// formUploadResponse is returned after a successful multipart/form-data upload.
+type formUploadResponse struct {
+	ID   string `json:"id,omitempty"`
+	ETag string `json:"etag,omitempty"`
+	Size uint64 `json:"size,omitempty"`
+}
+
+// formUpload handles the multipart/form-data uploads of small files sent by web forms, like the ones of public file-drop pages.
+// The file is passed to the storage the same way as a PUT request; the checksum can be sent as a form field or as part header.
+func (m *manager) formUpload(w http.ResponseWriter, r *http.Request, fs storage.FS) {
+	ctx := r.Context()
+	sublog := appctx.GetLogger(ctx).With().Str("datatx", "simple").Logger()
+	defer r.Body.Close()
+
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "multipart/form-data" {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	if r.ContentLength > m.conf.MaxFormUploadSize {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// The multipart overhead counts towards the limit as well
+	body := &limitedReader{r: r.Body, n: m.conf.MaxFormUploadSize}
+	r.Body = ioutil.NopCloser(body)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	fn := r.URL.Path
+	ref := &provider.Reference{Path: fn}
+	header := http.Header{}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			// No file was sent
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			if body.exceeded {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+			} else {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+			return
+		}
+
+		switch part.FormName() {
+		case formFieldChecksum:
+			v, err := ioutil.ReadAll(io.LimitReader(part, 1024))
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			header.Set(checksum.HeaderUploadChecksum, strings.TrimSpace(string(v)))
+		case formFieldFile:
+			for _, k := range []string{checksum.HeaderContentMD5, checksum.HeaderUploadChecksum} {
+				if v := part.Header.Get(k); v != "" {
+					header.Set(k, v)
+				}
+			}
+
+			// The part is bounded by the body already, its reader only counts the size of the file
+			file := &limitedReader{r: part, n: m.conf.MaxFormUploadSize}
+			cr := checksum.NewPartReader(ioutil.NopCloser(file), header)
+			err := fs.Upload(ctx, ref, cr)
+			if cerr := cr.Err(); cerr != nil {
+				err = cerr
+			}
+			if body.exceeded {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			if err != nil {
+				writeUploadError(w, &sublog, err)
+				return
+			}
+
+			datatx.EmitFileUploaded(ctx, m.publisher, fs, events.FileUploaded{
+				Ref:      ref,
+				UploadID: path.Base(fn),
+				Size:     file.read,
+			})
+
+			// Uploaders of file-drops might not be allowed to stat the uploaded file
+			res := &formUploadResponse{}
+			if md, err := fs.GetMD(ctx, ref, nil); err == nil {
+				res.ID = resourceid.OwnCloudResourceIDWrap(md.Id)
+				res.ETag = md.Etag
+				res.Size = md.Size
+				w.Header().Set("ETag", md.Etag)
+			} else {
+				sublog.Debug().Err(err).Str("path", fn).Msg("could not stat uploaded file")
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(res)
+			return
+		}
+	}
+}
+
+// limitedReader fails once more than n bytes have been read, remembering that the limit was exceeded.
+type limitedReader struct {
+	r        io.Reader
+	n        int64
+	read     int64
+	exceeded bool
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		l.exceeded = true
+		return 0, errtypes.BadRequest("request body too large")
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	l.read += int64(n)
+	return n, err
+}
+
+func writeUploadError(w http.ResponseWriter, log *zerolog.Logger, err error) {
+	switch v := err.(type) {
+	case errtypes.PartialContent:
+		w.WriteHeader(http.StatusPartialContent)
+	case errtypes.ChecksumMismatch:
+		w.WriteHeader(errtypes.StatusChecksumMismatch)
+	case errtypes.BadRequest:
+		w.WriteHeader(http.StatusBadRequest)
+	case errtypes.NotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case errtypes.PermissionDenied:
+		w.WriteHeader(http.StatusForbidden)
+	case errtypes.InvalidCredentials:
+		w.WriteHeader(http.StatusUnauthorized)
+	case errtypes.InsufficientStorage:
+		w.WriteHeader(http.StatusInsufficientStorage)
+	case errtypes.Infected:
+		w.Header().Set(download.HeaderBlocked, "infected")
+		w.WriteHeader(http.StatusForbidden)
+	default:
+		log.Error().Err(v).Msg("error uploading file")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
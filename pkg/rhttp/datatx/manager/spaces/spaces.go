@@ -19,6 +19,7 @@
 package spaces
 
 import (
+	"io"
 	"net/http"
 	"path"
 	"strings"
@@ -26,8 +27,11 @@ import (
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/events/server"
 	"github.com/cs3org/reva/pkg/rhttp/datatx"
 	"github.com/cs3org/reva/pkg/rhttp/datatx/manager/registry"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/checksum"
 	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/download"
 	"github.com/cs3org/reva/pkg/rhttp/router"
 	"github.com/cs3org/reva/pkg/storage"
@@ -40,10 +44,14 @@ func init() {
 	registry.Register("spaces", New)
 }
 
-type config struct{}
+type config struct {
+	// Events publishes an event for every finished upload, if a broker is configured.
+	Events server.Config `mapstructure:"events"`
+}
 
 type manager struct {
-	conf *config
+	conf      *config
+	publisher events.Publisher
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -62,7 +70,12 @@ func New(m map[string]interface{}) (datatx.DataTX, error) {
 		return nil, err
 	}
 
-	return &manager{conf: c}, nil
+	publisher, err := datatx.NewPublisher(&c.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manager{conf: c, publisher: publisher}, nil
 }
 
 func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
@@ -95,14 +108,30 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 				ResourceId: &provider.ResourceId{StorageId: storageid, OpaqueId: opaqeid},
 				Path:       fn,
 			}
-			err = fs.Upload(ctx, ref, r.Body)
+			// the body is only hashed if the client announced a checksum
+			var body io.ReadCloser = r.Body
+			var cr *checksum.Reader
+			if checksum.Requested(r) {
+				cr = checksum.NewReader(r)
+				body = cr
+			}
+			err = fs.Upload(ctx, ref, body)
+			if cr != nil && cr.Err() != nil {
+				err = cr.Err()
+			}
 			switch v := err.(type) {
 			case nil:
 				w.WriteHeader(http.StatusOK)
+				datatx.EmitFileUploaded(ctx, m.publisher, fs, events.FileUploaded{
+					Ref:  ref,
+					Size: r.ContentLength,
+				})
 			case errtypes.PartialContent:
 				w.WriteHeader(http.StatusPartialContent)
 			case errtypes.ChecksumMismatch:
 				w.WriteHeader(errtypes.StatusChecksumMismatch)
+			case errtypes.BadRequest:
+				w.WriteHeader(http.StatusBadRequest)
 			case errtypes.NotFound:
 				w.WriteHeader(http.StatusNotFound)
 			case errtypes.PermissionDenied:
@@ -111,6 +140,9 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 				w.WriteHeader(http.StatusUnauthorized)
 			case errtypes.InsufficientStorage:
 				w.WriteHeader(http.StatusInsufficientStorage)
+			case errtypes.Infected:
+				w.Header().Set(download.HeaderBlocked, "infected")
+				w.WriteHeader(http.StatusForbidden)
 			default:
 				sublog.Error().Err(v).Msg("error uploading file")
 				w.WriteHeader(http.StatusInternalServerError)
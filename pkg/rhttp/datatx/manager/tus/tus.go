@@ -19,15 +19,26 @@
 package tus
 
 import (
+	"context"
 	"net/http"
+	"path"
+	"strings"
 
 	"github.com/pkg/errors"
 
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/events/server"
 	"github.com/cs3org/reva/pkg/rhttp/datatx"
 	"github.com/cs3org/reva/pkg/rhttp/datatx/manager/registry"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/checksum"
 	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/download"
 	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/utils"
 	"github.com/mitchellh/mapstructure"
 	tusd "github.com/tus/tusd/pkg/handler"
 )
@@ -36,10 +47,14 @@ func init() {
 	registry.Register("tus", New)
 }
 
-type config struct{}
+type config struct {
+	// Events publishes an event for every finished upload, if a broker is configured.
+	Events server.Config `mapstructure:"events"`
+}
 
 type manager struct {
-	conf *config
+	conf      *config
+	publisher events.Publisher
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -58,7 +73,12 @@ func New(m map[string]interface{}) (datatx.DataTX, error) {
 		return nil, err
 	}
 
-	return &manager{conf: c}, nil
+	publisher, err := datatx.NewPublisher(&c.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manager{conf: c, publisher: publisher}, nil
 }
 
 func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
@@ -77,7 +97,8 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 	composable.UseIn(composer)
 
 	config := tusd.Config{
-		StoreComposer: composer,
+		StoreComposer:         composer,
+		NotifyCompleteUploads: m.publisher != nil,
 	}
 
 	handler, err := tusd.NewUnroutedHandler(config)
@@ -85,6 +106,10 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 		return nil, err
 	}
 
+	if m.publisher != nil {
+		go m.emitUploads(fs, handler.CompleteUploads)
+	}
+
 	h := handler.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		method := r.Method
@@ -99,6 +124,13 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 		case "HEAD":
 			handler.HeadFile(w, r)
 		case "PATCH":
+			if checksum.Requested(r) {
+				cleanup, ok := verifyChunk(w, r)
+				if !ok {
+					return
+				}
+				defer cleanup()
+			}
 			handler.PatchFile(w, r)
 		case "DELETE":
 			handler.DelFile(w, r)
@@ -109,7 +141,66 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 		}
 	}))
 
-	return h, nil
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Checksum-Algorithm", checksum.Algorithms)
+		h.ServeHTTP(&extensionWriter{ResponseWriter: w}, r)
+	}), nil
+}
+
+// emitUploads publishes the uploads finished by tusd. The user who started an
+// upload is taken from its info, as tusd doesn't pass on the request context.
+func (m *manager) emitUploads(fs storage.FS, uploads <-chan tusd.HookEvent) {
+	for ev := range uploads {
+		info := ev.Upload
+		uploaded := events.FileUploaded{
+			Ref:      &provider.Reference{Path: path.Join(info.MetaData["dir"], info.MetaData["filename"])},
+			UploadID: info.ID,
+			Size:     info.Size,
+		}
+		ctx := context.Background()
+		if info.Storage["UserId"] != "" {
+			uploaded.Executant = &userpb.UserId{
+				Idp:      info.Storage["Idp"],
+				OpaqueId: info.Storage["UserId"],
+				Type:     utils.UserTypeMap(info.Storage["UserType"]),
+			}
+			// the file is stated on behalf of the user
+			ctx = ctxpkg.ContextSetUser(ctx, &userpb.User{Id: uploaded.Executant, Username: info.Storage["UserName"]})
+		}
+		datatx.EmitFileUploaded(ctx, m.publisher, fs, uploaded)
+	}
+}
+
+// verifyChunk spools the body of a PATCH request and verifies the checksum
+// sent along with it before the chunk is handed to the storage, so that
+// mismatching chunks never get appended to the upload.
+func verifyChunk(w http.ResponseWriter, r *http.Request) (func(), bool) {
+	f, cleanup, err := checksum.Spool(r)
+	switch v := err.(type) {
+	case nil:
+		r.Body = f
+		return cleanup, true
+	case errtypes.ChecksumMismatch:
+		w.WriteHeader(errtypes.StatusTusChecksumMismatch)
+	case errtypes.BadRequest:
+		w.WriteHeader(http.StatusBadRequest)
+	default:
+		appctx.GetLogger(r.Context()).Error().Err(v).Msg("error verifying chunk checksum")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	return nil, false
+}
+
+// extensionWriter adds the checksum extension to the extensions announced by tusd.
+type extensionWriter struct {
+	http.ResponseWriter
+}
+
+func (w *extensionWriter) WriteHeader(code int) {
+	if ext := w.Header().Get("Tus-Extension"); ext != "" && !strings.Contains(ext, "checksum") {
+		w.Header().Set("Tus-Extension", ext+",checksum")
+	}
+	w.ResponseWriter.WriteHeader(code)
 }
 
 // Composable is the interface that a struct needs to implement
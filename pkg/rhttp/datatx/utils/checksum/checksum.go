@@ -0,0 +1,193 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package checksum verifies the checksums clients send along with uploaded data.
+package checksum
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/adler32"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+const (
+	// HeaderContentMD5 carries the base64 encoded md5 digest of the body, see RFC 1864.
+	HeaderContentMD5 = "Content-Md5"
+	// HeaderUploadChecksum carries '<algorithm> <base64 digest>' as defined by the TUS checksum extension.
+	HeaderUploadChecksum = "Upload-Checksum"
+	// Algorithms is the list of supported algorithms as announced in the Tus-Checksum-Algorithm header.
+	Algorithms = "md5,sha1,adler32"
+)
+
+// Reader hashes the body of a request while it is read and verifies the
+// checksums sent in its headers or trailers once the body has been consumed.
+type Reader struct {
+	header   http.Header
+	trailer  http.Header
+	body     io.ReadCloser
+	hashes   map[string]hash.Hash
+	w        io.Writer
+	done     bool
+	err      error
+	verified map[string]string
+}
+
+// NewReader returns a Reader for the body of the given request.
+func NewReader(r *http.Request) *Reader {
+	return newReader(r.Body, r.Header, r.Trailer)
+}
+
+// NewPartReader returns a Reader for a part of a multipart body; the checksums
+// are taken from the given part header.
+func NewPartReader(part io.ReadCloser, header http.Header) *Reader {
+	return newReader(part, header, nil)
+}
+
+func newReader(body io.ReadCloser, header, trailer http.Header) *Reader {
+	hashes := map[string]hash.Hash{
+		"md5":     md5.New(),
+		"sha1":    sha1.New(),
+		"adler32": adler32.New(),
+	}
+	return &Reader{
+		header:  header,
+		trailer: trailer,
+		body:    body,
+		hashes:  hashes,
+		w:       io.MultiWriter(hashes["md5"], hashes["sha1"], hashes["adler32"]),
+	}
+}
+
+// Requested tells if the client announced a checksum for the body of the request,
+// either as a header or as a trailer.
+func Requested(r *http.Request) bool {
+	for _, k := range []string{HeaderContentMD5, HeaderUploadChecksum} {
+		if r.Header.Get(k) != "" {
+			return true
+		}
+		if _, ok := r.Trailer[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Read implements io.Reader. Instead of io.EOF it returns the verification
+// error if the body does not match the announced checksums.
+func (cr *Reader) Read(p []byte) (int, error) {
+	n, err := cr.body.Read(p)
+	_, _ = cr.w.Write(p[:n])
+	if err == io.EOF && !cr.done {
+		cr.done = true
+		if cr.err = cr.verify(); cr.err != nil {
+			return n, cr.err
+		}
+	}
+	return n, err
+}
+
+// Close closes the underlying body.
+func (cr *Reader) Close() error {
+	return cr.body.Close()
+}
+
+// Err returns the verification error, if any. Storage drivers tend to wrap
+// the errors of the reader, so callers should check it after an upload.
+func (cr *Reader) Err() error {
+	return cr.err
+}
+
+// Verified returns the hex encoded checksums that were verified, keyed by algorithm.
+// It is only populated after the body has been read completely.
+func (cr *Reader) Verified() map[string]string {
+	return cr.verified
+}
+
+func (cr *Reader) verify() error {
+	cr.verified = map[string]string{}
+	for _, k := range []string{HeaderContentMD5, HeaderUploadChecksum} {
+		v := cr.header.Get(k)
+		if v == "" && cr.trailer != nil {
+			v = cr.trailer.Get(k)
+		}
+		if v == "" {
+			continue
+		}
+
+		algo, encoded := "md5", v
+		if k == HeaderUploadChecksum {
+			parts := strings.SplitN(v, " ", 2)
+			if len(parts) != 2 {
+				return errtypes.BadRequest("invalid checksum format, must be '[algorithm] [checksum]'")
+			}
+			algo, encoded = strings.ToLower(parts[0]), parts[1]
+		}
+		h, ok := cr.hashes[algo]
+		if !ok {
+			return errtypes.BadRequest("unsupported checksum algorithm: " + algo)
+		}
+		expected, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return errtypes.BadRequest("checksum in " + k + " is not base64 encoded")
+		}
+
+		sum := hex.EncodeToString(h.Sum(nil))
+		if hex.EncodeToString(expected) != sum {
+			return errtypes.ChecksumMismatch(fmt.Sprintf("%s: expected %s %x got %s", k, algo, expected, sum))
+		}
+		cr.verified[algo] = sum
+	}
+	return nil
+}
+
+// Spool reads the body of the request into a temporary file, verifying the
+// announced checksums on the way. The returned file is positioned at its start
+// and has to be released with the returned cleanup function.
+func Spool(r *http.Request) (*os.File, func(), error) {
+	f, err := ioutil.TempFile("", "reva-upload-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+
+	cr := NewReader(r)
+	defer cr.Close()
+	if _, err := io.Copy(f, cr); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return f, cleanup, nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package checksum
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+func b64(sum []byte) string {
+	return base64.StdEncoding.EncodeToString(sum)
+}
+
+func TestReader(t *testing.T) {
+	data := []byte("the quick brown fox")
+	md5sum := md5.Sum(data)
+	sha1sum := sha1.Sum(data)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		err     error
+	}{
+		{"no checksum", nil, nil},
+		{"content md5", map[string]string{HeaderContentMD5: b64(md5sum[:])}, nil},
+		{"upload checksum", map[string]string{HeaderUploadChecksum: "sha1 " + b64(sha1sum[:])}, nil},
+		{"content md5 mismatch", map[string]string{HeaderContentMD5: b64(sha1sum[:])}, errtypes.ChecksumMismatch("")},
+		{"upload checksum mismatch", map[string]string{HeaderUploadChecksum: "md5 " + b64(sha1sum[:])}, errtypes.ChecksumMismatch("")},
+		{"unsupported algorithm", map[string]string{HeaderUploadChecksum: "crc32 AAAA"}, errtypes.BadRequest("")},
+		{"invalid format", map[string]string{HeaderUploadChecksum: "md5"}, errtypes.BadRequest("")},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPut, "/", bytes.NewReader(data))
+		for k, v := range tt.headers {
+			r.Header.Set(k, v)
+		}
+		cr := NewReader(r)
+		b, err := ioutil.ReadAll(cr)
+		if !bytes.Equal(b, data) {
+			t.Errorf("%s: body was altered", tt.name)
+		}
+		switch tt.err.(type) {
+		case nil:
+			if err != nil || cr.Err() != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+		case errtypes.ChecksumMismatch:
+			if _, ok := cr.Err().(errtypes.ChecksumMismatch); !ok || err == nil {
+				t.Errorf("%s: expected a checksum mismatch, got %v", tt.name, err)
+			}
+		case errtypes.BadRequest:
+			if _, ok := cr.Err().(errtypes.BadRequest); !ok || err == nil {
+				t.Errorf("%s: expected a bad request, got %v", tt.name, err)
+			}
+		}
+	}
+}
+
+func TestTrailer(t *testing.T) {
+	data := []byte("the quick brown fox")
+	sum := md5.Sum(data)
+
+	r := httptest.NewRequest(http.MethodPatch, "/", bytes.NewReader(data))
+	r.Trailer = http.Header{HeaderUploadChecksum: nil}
+	if !Requested(r) {
+		t.Fatal("a checksum announced as trailer must be detected")
+	}
+	// the trailer value is only known once the body was read
+	r.Trailer.Set(HeaderUploadChecksum, "md5 "+b64(sum[:]))
+
+	f, cleanup, err := Spool(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	b, _ := ioutil.ReadAll(f)
+	if !bytes.Equal(b, data) {
+		t.Error("spooled body was altered")
+	}
+}
+
+func TestPartReader(t *testing.T) {
+	data := []byte("the quick brown fox")
+	sum := sha1.Sum(data)
+
+	header := http.Header{}
+	header.Set(HeaderUploadChecksum, "sha1 "+b64(sum[:]))
+	cr := NewPartReader(ioutil.NopCloser(bytes.NewReader(data)), header)
+	if _, err := ioutil.ReadAll(cr); err != nil || cr.Err() != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if cr.Verified()["sha1"] == "" {
+		t.Error("the checksum of the part must be verified")
+	}
+
+	header.Set(HeaderContentMD5, b64(sum[:]))
+	cr = NewPartReader(ioutil.NopCloser(bytes.NewReader(data)), header)
+	if _, err := ioutil.ReadAll(cr); err == nil {
+		t.Error("expected a checksum mismatch")
+	}
+	if _, ok := cr.Err().(errtypes.ChecksumMismatch); !ok {
+		t.Errorf("expected a checksum mismatch, got %v", cr.Err())
+	}
+}
@@ -35,6 +35,9 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// HeaderBlocked tells the reason why the download of a file was refused.
+const HeaderBlocked = "X-Reva-Blocked"
+
 // GetOrHeadFile returns the requested file content
 func GetOrHeadFile(w http.ResponseWriter, r *http.Request, fs storage.FS, spaceID string) {
 	ctx := r.Context()
@@ -206,6 +209,10 @@ func handleError(w http.ResponseWriter, log *zerolog.Logger, err error, action s
 	case errtypes.IsPermissionDenied:
 		log.Debug().Err(err).Str("action", action).Msg("permission denied")
 		w.WriteHeader(http.StatusForbidden)
+	case errtypes.IsInfected:
+		log.Debug().Err(err).Str("action", action).Msg("file is quarantined")
+		w.Header().Set(HeaderBlocked, "infected")
+		w.WriteHeader(http.StatusForbidden)
 	default:
 		log.Error().Err(err).Str("action", action).Msg("unexpected error")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -0,0 +1,131 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package downloadtoken implements the one-time tokens the browsers download
+// files from the datagateway with. The web frontends mint them through the OCS
+// API and put them in the download URLs instead of the transfer tokens. As the
+// tokens can only be redeemed once, shortly after they were minted and together
+// with the session cookie of the browser they were minted for, leaking them
+// through logs or referrers does not give access to the files.
+package downloadtoken
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/pkg/errors"
+)
+
+// CookieName is the name of the cookie holding the download session of a browser.
+const CookieName = "reva-download-session"
+
+// Grant is what a download token is redeemed for.
+type Grant struct {
+	// Endpoint is the datagateway endpoint of the download.
+	Endpoint string `json:"endpoint"`
+	// Token is the transfer token of the download.
+	Token string `json:"token"`
+	// Filename is the name the browser saves the file with.
+	Filename string `json:"filename"`
+	// Session is the hash of the download session the token is bound to.
+	Session string `json:"session"`
+}
+
+// Store is the interface to implement for the stores the grants are kept in until
+// their tokens are redeemed. The stores are shared between the OCS service minting
+// the tokens and the datagateways redeeming them.
+type Store interface {
+	// Put stores the grant under the given id, which expires after the TTL.
+	Put(ctx context.Context, id string, g *Grant, ttl time.Duration) error
+	// Take removes the grant stored under the given id and returns it, or an
+	// errtypes.NotFound error if there is none. Of concurrent calls, only one
+	// gets the grant.
+	Take(ctx context.Context, id string) (*Grant, error)
+}
+
+// ID returns the id under which the grant of a token is stored, so that the tokens themselves are never persisted.
+func ID(tkn string) string {
+	h := sha256.Sum256([]byte(tkn))
+	return hex.EncodeToString(h[:])
+}
+
+// NewSession returns the secret of a new download session.
+func NewSession() (string, error) {
+	return random()
+}
+
+// Session returns the download session of the browser sending the request, or
+// an empty string if it has none.
+func Session(r *http.Request) string {
+	c, err := r.Cookie(CookieName)
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
+
+// Mint stores the grant bound to the given session and returns the token redeeming it within the TTL.
+func Mint(ctx context.Context, s Store, g *Grant, session string, ttl time.Duration) (string, error) {
+	if session == "" {
+		return "", errtypes.BadRequest("downloadtoken: missing download session")
+	}
+	tkn, err := random()
+	if err != nil {
+		return "", err
+	}
+	bound := *g
+	bound.Session = ID(session)
+	if err := s.Put(ctx, ID(tkn), &bound, ttl); err != nil {
+		return "", errors.Wrap(err, "downloadtoken: error storing grant")
+	}
+	return tkn, nil
+}
+
+// Redeem returns the grant of the token if it was minted for the given session.
+// The token can't be redeemed again afterwards, even if the session does not match.
+func Redeem(ctx context.Context, s Store, tkn, session string) (*Grant, error) {
+	if tkn == "" {
+		return nil, errtypes.InvalidCredentials("downloadtoken: missing token")
+	}
+	g, err := s.Take(ctx, ID(tkn))
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			return nil, errtypes.InvalidCredentials("downloadtoken: unknown, expired or already redeemed token")
+		}
+		return nil, err
+	}
+	if session == "" || subtle.ConstantTimeCompare([]byte(g.Session), []byte(ID(session))) != 1 {
+		return nil, errtypes.PermissionDenied("downloadtoken: token minted for another session")
+	}
+	return g, nil
+}
+
+func random() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "downloadtoken: error generating random bytes")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package downloadtoken
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+type store struct {
+	sync.Mutex
+	grants map[string]Grant
+}
+
+func (s *store) Put(ctx context.Context, id string, g *Grant, ttl time.Duration) error {
+	s.Lock()
+	defer s.Unlock()
+	s.grants[id] = *g
+	return nil
+}
+
+func (s *store) Take(ctx context.Context, id string) (*Grant, error) {
+	s.Lock()
+	defer s.Unlock()
+	g, ok := s.grants[id]
+	if !ok {
+		return nil, errtypes.NotFound(id)
+	}
+	delete(s.grants, id)
+	return &g, nil
+}
+
+func TestRedeem(t *testing.T) {
+	ctx := context.Background()
+	s := &store{grants: map[string]Grant{}}
+	grant := &Grant{Endpoint: "https://cloud.example.org/datagateway", Token: "transfer-token", Filename: "report.pdf"}
+
+	if _, err := Mint(ctx, s, grant, "", time.Minute); err == nil {
+		t.Fatal("expected a token without session to be refused")
+	}
+
+	tkn, err := Mint(ctx, s, grant, "session", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, g := range s.grants {
+		if id == tkn || g.Session == "session" {
+			t.Fatal("expected the token and the session not to be stored")
+		}
+	}
+
+	g, err := Redeem(ctx, s, tkn, "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Token != grant.Token || g.Endpoint != grant.Endpoint || g.Filename != grant.Filename {
+		t.Fatalf("expected %+v, got %+v", grant, g)
+	}
+	if _, err := Redeem(ctx, s, tkn, "session"); err == nil {
+		t.Fatal("expected the token to be redeemed only once")
+	}
+
+	tkn, err = Mint(ctx, s, grant, "session", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Redeem(ctx, s, tkn, "other-session"); err == nil {
+		t.Fatal("expected the token to be refused for another session")
+	}
+	if _, err := Redeem(ctx, s, tkn, "session"); err == nil {
+		t.Fatal("expected the token to be dropped after a redemption for another session")
+	}
+
+	if _, err := Redeem(ctx, s, "", "session"); err == nil {
+		t.Fatal("expected an empty token to be refused")
+	}
+}
+
+func TestSession(t *testing.T) {
+	r := httptest.NewRequest("GET", "/download/token", nil)
+	if s := Session(r); s != "" {
+		t.Fatalf("expected no session, got %q", s)
+	}
+	r.Header.Set("Cookie", CookieName+"=secret")
+	if s := Session(r); s != "secret" {
+		t.Fatalf("expected the session of the cookie, got %q", s)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/downloadtoken"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/downloadtoken/registry"
+)
+
+func init() {
+	registry.Register("memory", New)
+}
+
+type entry struct {
+	grant     downloadtoken.Grant
+	expiresAt time.Time
+}
+
+type store struct {
+	sync.Mutex
+	grants map[string]entry
+}
+
+// shared holds the grants of all the stores of the process, so that the tokens
+// minted by the OCS service can be redeemed at the datagateway.
+var shared = &store{grants: map[string]entry{}}
+
+// New returns a download token store keeping the grants in memory. They are shared
+// by all the stores of the process but not with other instances, so the OCS service
+// and the datagateway have to run in the same process.
+func New(m map[string]interface{}) (downloadtoken.Store, error) {
+	return shared, nil
+}
+
+func (s *store) Put(ctx context.Context, id string, g *downloadtoken.Grant, ttl time.Duration) error {
+	s.Lock()
+	defer s.Unlock()
+	now := time.Now()
+	// the tokens are short-lived, so the expired ones are dropped on the way
+	for k, e := range s.grants {
+		if now.After(e.expiresAt) {
+			delete(s.grants, k)
+		}
+	}
+	s.grants[id] = entry{grant: *g, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (s *store) Take(ctx context.Context, id string) (*downloadtoken.Grant, error) {
+	s.Lock()
+	defer s.Unlock()
+	e, ok := s.grants[id]
+	if !ok {
+		return nil, errtypes.NotFound(id)
+	}
+	delete(s.grants, id)
+	if time.Now().After(e.expiresAt) {
+		return nil, errtypes.NotFound(id)
+	}
+	return &e.grant, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/downloadtoken"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/downloadtoken/registry"
+	"github.com/gomodule/redigo/redis"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("redis", New)
+}
+
+const keyPrefix = "download-token:"
+
+type config struct {
+	RedisAddress  string `mapstructure:"redis_address"`
+	RedisUsername string `mapstructure:"redis_username"`
+	RedisPassword string `mapstructure:"redis_password"`
+}
+
+type store struct {
+	redisPool *redis.Pool
+}
+
+// New returns a download token store keeping the grants in redis, so that the
+// tokens minted by an OCS service can be redeemed by any datagateway.
+func New(m map[string]interface{}) (downloadtoken.Store, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+
+	if c.RedisAddress == "" {
+		c.RedisAddress = "localhost:6379"
+	}
+
+	pool := &redis.Pool{
+		MaxIdle:     50,
+		MaxActive:   1000,
+		IdleTimeout: 240 * time.Second,
+
+		Dial: func() (redis.Conn, error) {
+			var opts []redis.DialOption
+			if c.RedisUsername != "" {
+				opts = append(opts, redis.DialUsername(c.RedisUsername))
+			}
+			if c.RedisPassword != "" {
+				opts = append(opts, redis.DialPassword(c.RedisPassword))
+			}
+			return redis.Dial("tcp", c.RedisAddress, opts...)
+		},
+
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+
+	return &store{redisPool: pool}, nil
+}
+
+func (s *store) Put(ctx context.Context, id string, g *downloadtoken.Grant, ttl time.Duration) error {
+	v, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+	conn := s.redisPool.Get()
+	defer conn.Close()
+	_, err = conn.Do("SET", keyPrefix+id, v, "PX", ttl.Milliseconds())
+	return err
+}
+
+func (s *store) Take(ctx context.Context, id string) (*downloadtoken.Grant, error) {
+	conn := s.redisPool.Get()
+	defer conn.Close()
+
+	// GET and DEL in a transaction, so that a token is redeemed only once
+	if err := conn.Send("MULTI"); err != nil {
+		return nil, err
+	}
+	if err := conn.Send("GET", keyPrefix+id); err != nil {
+		return nil, err
+	}
+	if err := conn.Send("DEL", keyPrefix+id); err != nil {
+		return nil, err
+	}
+	res, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return nil, err
+	}
+	v, err := redis.Bytes(res[0], nil)
+	if err == redis.ErrNil {
+		return nil, errtypes.NotFound(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	g := &downloadtoken.Grant{}
+	if err := json.Unmarshal(v, g); err != nil {
+		return nil, errors.Wrap(err, "error decoding grant")
+	}
+	return g, nil
+}
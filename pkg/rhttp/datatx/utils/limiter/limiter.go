@@ -0,0 +1,209 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package limiter
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+// Config configures the number of concurrent transfers per user.
+type Config struct {
+	// MaxUploads is the number of uploads a user can run concurrently. 0 means unlimited.
+	MaxUploads int `mapstructure:"max_uploads"`
+	// MaxDownloads is the number of downloads a user can run concurrently. 0 means unlimited.
+	MaxDownloads int `mapstructure:"max_downloads"`
+	// QueueDepth is the number of transfers per user waiting for a free slot, further ones are rejected.
+	QueueDepth int `mapstructure:"queue_depth"`
+	// QueueTimeout is the time in seconds a transfer waits for a free slot before being rejected.
+	QueueTimeout int `mapstructure:"queue_timeout"`
+}
+
+func (c *Config) init() {
+	if c.QueueTimeout == 0 {
+		c.QueueTimeout = 60
+	}
+}
+
+type slots struct {
+	ch      chan struct{}
+	waiting int
+	refs    int
+}
+
+// Limiter limits the number of concurrent operations per key, queuing the ones exceeding the limit.
+type Limiter struct {
+	max     int
+	depth   int
+	timeout time.Duration
+
+	mu   sync.Mutex
+	keys map[string]*slots
+}
+
+// New returns a limiter allowing max concurrent operations per key, queuing up to depth more.
+func New(max, depth int, timeout time.Duration) *Limiter {
+	return &Limiter{
+		max:     max,
+		depth:   depth,
+		timeout: timeout,
+		keys:    map[string]*slots{},
+	}
+}
+
+// Acquire waits for a free slot for the given key. It returns a function releasing the slot,
+// or false if the queue is full or no slot became free in time.
+func (l *Limiter) Acquire(ctx context.Context, key string) (func(), bool) {
+	l.mu.Lock()
+	s, ok := l.keys[key]
+	if !ok {
+		s = &slots{ch: make(chan struct{}, l.max)}
+		l.keys[key] = s
+	}
+	s.refs++
+
+	select {
+	case s.ch <- struct{}{}:
+		l.mu.Unlock()
+		return func() { l.release(key, s) }, true
+	default:
+	}
+
+	if s.waiting >= l.depth {
+		l.unref(key, s)
+		l.mu.Unlock()
+		return nil, false
+	}
+	s.waiting++
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+
+	acquired := false
+	select {
+	case s.ch <- struct{}{}:
+		acquired = true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s.waiting--
+	if !acquired {
+		l.unref(key, s)
+		return nil, false
+	}
+	return func() { l.release(key, s) }, true
+}
+
+func (l *Limiter) release(key string, s *slots) {
+	<-s.ch
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.unref(key, s)
+}
+
+// unref must be called with the lock held.
+func (l *Limiter) unref(key string, s *slots) {
+	s.refs--
+	if s.refs == 0 {
+		delete(l.keys, key)
+	}
+}
+
+// Limits limits the concurrent uploads and downloads of every user.
+type Limits struct {
+	uploads, downloads *Limiter // nil if unlimited
+}
+
+// NewLimits returns the limits of the given configuration.
+func NewLimits(c *Config) *Limits {
+	c.init()
+	timeout := time.Duration(c.QueueTimeout) * time.Second
+	l := &Limits{}
+	if c.MaxUploads > 0 {
+		l.uploads = New(c.MaxUploads, c.QueueDepth, timeout)
+	}
+	if c.MaxDownloads > 0 {
+		l.downloads = New(c.MaxDownloads, c.QueueDepth, timeout)
+	}
+	return l
+}
+
+// Enabled returns whether any transfer is limited.
+func (l *Limits) Enabled() bool {
+	return l.uploads != nil || l.downloads != nil
+}
+
+// Acquire waits for a free slot for a transfer of the given key with the given HTTP method.
+// It returns a function releasing the slot, or false if the transfer has to be rejected.
+func (l *Limits) Acquire(ctx context.Context, method, key string) (func(), bool) {
+	var lim *Limiter
+	switch method {
+	case http.MethodGet:
+		lim = l.downloads
+	case http.MethodPut, http.MethodPatch, http.MethodPost:
+		lim = l.uploads
+	}
+	if lim == nil {
+		return func() {}, true
+	}
+	return lim.Acquire(ctx, key)
+}
+
+// Handler wraps an HTTP handler, limiting the concurrent uploads and downloads of every user.
+// Transfers exceeding the limits are queued and rejected with 429 once the queue is full.
+// The handler has to run after the authentication, services handling unauthenticated
+// transfers use Limits with a key of their own.
+func Handler(c *Config, h http.Handler) http.Handler {
+	l := NewLimits(c)
+	if !l.Enabled() {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		release, ok := l.Acquire(r.Context(), r.Method, UserKey(r))
+		if !ok {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		defer release()
+		h.ServeHTTP(w, r)
+	})
+}
+
+// UserKey identifies the user of the request, falling back to the client IP for anonymous transfers.
+func UserKey(r *http.Request) string {
+	if u, ok := ctxpkg.ContextGetUser(r.Context()); ok && u.Id != nil {
+		return u.Id.Idp + "!" + u.Id.OpaqueId
+	}
+	return ClientKey(r)
+}
+
+// ClientKey identifies the client IP of the request.
+func ClientKey(r *http.Request) string {
+	ip, _ := utils.GetClientIP(r)
+	return ip
+}
@@ -0,0 +1,108 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package limiter
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	l := New(1, 1, 50*time.Millisecond)
+	ctx := context.Background()
+
+	release, ok := l.Acquire(ctx, "einstein")
+	if !ok {
+		t.Fatal("expected the first transfer to be allowed")
+	}
+
+	// other users are not affected
+	releaseOther, ok := l.Acquire(ctx, "marie")
+	if !ok {
+		t.Fatal("expected a transfer of another user to be allowed")
+	}
+	releaseOther()
+
+	// the second transfer is queued and runs once the first one is done
+	done := make(chan bool)
+	go func() {
+		r, ok := l.Acquire(ctx, "einstein")
+		if ok {
+			r()
+		}
+		done <- ok
+	}()
+
+	// wait for the transfer to be queued, the next one exceeds the queue depth
+	for {
+		l.mu.Lock()
+		waiting := l.keys["einstein"].waiting
+		l.mu.Unlock()
+		if waiting == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, ok := l.Acquire(ctx, "einstein"); ok {
+		t.Fatal("expected the transfer exceeding the queue to be rejected")
+	}
+
+	release()
+	if !<-done {
+		t.Fatal("expected the queued transfer to be allowed")
+	}
+
+	if len(l.keys) != 0 {
+		t.Fatalf("expected no remaining keys, got %d", len(l.keys))
+	}
+}
+
+func TestAcquireTimeout(t *testing.T) {
+	l := New(1, 1, 10*time.Millisecond)
+	release, _ := l.Acquire(context.Background(), "einstein")
+	defer release()
+
+	if _, ok := l.Acquire(context.Background(), "einstein"); ok {
+		t.Fatal("expected the queued transfer to time out")
+	}
+}
+
+func TestLimits(t *testing.T) {
+	l := NewLimits(&Config{MaxUploads: 1})
+	ctx := context.Background()
+
+	release, ok := l.Acquire(ctx, http.MethodPut, "einstein")
+	if !ok {
+		t.Fatal("expected the first upload to be allowed")
+	}
+	defer release()
+	if _, ok := l.Acquire(ctx, http.MethodPatch, "einstein"); ok {
+		t.Error("expected the second upload to be rejected")
+	}
+	if _, ok := l.Acquire(ctx, http.MethodPut, "marie"); !ok {
+		t.Error("expected an upload of another user to be allowed")
+	}
+	for i := 0; i < 3; i++ {
+		if _, ok := l.Acquire(ctx, http.MethodGet, "einstein"); !ok {
+			t.Error("expected the downloads to be unlimited")
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package uploadtoken binds the transfer tokens of resumable uploads to the
+// upload they were issued for. Such tokens live independently of the access
+// token of the user, so that long uploads survive its expiry.
+package uploadtoken
+
+import (
+	"path"
+	"time"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/golang-jwt/jwt"
+	"github.com/pkg/errors"
+)
+
+// Scope binds a transfer token to an upload. It is embedded in the claims of
+// the transfer tokens issued for uploads.
+type Scope struct {
+	// UploadID is the id of the upload the token is valid for.
+	UploadID string `json:"upload_id,omitempty"`
+	// RenewUntil is the unix time until which the token can be renewed.
+	RenewUntil int64 `json:"renew_until,omitempty"`
+}
+
+type claims struct {
+	jwt.StandardClaims
+	Scope
+}
+
+// NewScope returns the scope of a token for the upload behind the given
+// upload endpoint, renewable during maxLifetime.
+func NewScope(endpoint string, maxLifetime time.Duration, now time.Time) Scope {
+	return Scope{
+		UploadID:   path.Base(endpoint),
+		RenewUntil: now.Add(maxLifetime).Unix(),
+	}
+}
+
+// Verify checks that the transfer token was signed with the secret, did not
+// expire and is bound to the upload.
+func Verify(secret, token, uploadID string) error {
+	if token == "" {
+		return errtypes.InvalidCredentials("missing transfer token")
+	}
+	c := &claims{}
+	t, err := jwt.ParseWithClaims(token, c, func(*jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "error parsing transfer token")
+	}
+	if !t.Valid {
+		return errtypes.InvalidCredentials("transfer token invalid")
+	}
+	if c.UploadID == "" || c.UploadID != uploadID {
+		return errtypes.PermissionDenied("transfer token not valid for upload " + uploadID)
+	}
+	return nil
+}
+
+// Renew returns the new expiry of a token once it is past half of its
+// lifetime, keeping the lifetime but never going beyond the renewal limit.
+// It returns false if the token does not need or can't get a renewal.
+func Renew(std jwt.StandardClaims, s Scope, now time.Time) (int64, bool) {
+	if s.UploadID == "" || now.Unix() >= s.RenewUntil {
+		return 0, false
+	}
+	lifetime := std.ExpiresAt - std.IssuedAt
+	if lifetime <= 0 || now.Unix() < std.IssuedAt+lifetime/2 {
+		return 0, false
+	}
+	expires := now.Unix() + lifetime
+	if expires > s.RenewUntil {
+		expires = s.RenewUntil
+	}
+	return expires, expires > std.ExpiresAt
+}
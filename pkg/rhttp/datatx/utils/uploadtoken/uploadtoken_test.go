@@ -0,0 +1,88 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package uploadtoken
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func sign(t *testing.T, secret string, expires time.Time, s Scope) string {
+	tkn, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		StandardClaims: jwt.StandardClaims{ExpiresAt: expires.Unix(), IssuedAt: time.Now().Unix()},
+		Scope:          s,
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tkn
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	scope := NewScope("https://data.example.org/data/tus/upload-1", time.Hour, now)
+	if scope.UploadID != "upload-1" {
+		t.Fatalf("expected the upload id to be taken from the endpoint, got %q", scope.UploadID)
+	}
+
+	valid := sign(t, "secret", now.Add(time.Minute), scope)
+	if err := Verify("secret", valid, "upload-1"); err != nil {
+		t.Errorf("expected the token to be valid for its upload: %v", err)
+	}
+	if err := Verify("secret", valid, "upload-2"); err == nil {
+		t.Error("expected the token to be refused for another upload")
+	}
+	if err := Verify("other", valid, "upload-1"); err == nil {
+		t.Error("expected a token signed with another secret to be refused")
+	}
+	if err := Verify("secret", sign(t, "secret", now.Add(-time.Minute), scope), "upload-1"); err == nil {
+		t.Error("expected an expired token to be refused")
+	}
+	if err := Verify("secret", sign(t, "secret", now.Add(time.Minute), Scope{}), "upload-1"); err == nil {
+		t.Error("expected a token not bound to an upload to be refused")
+	}
+	if err := Verify("secret", "", "upload-1"); err == nil {
+		t.Error("expected a missing token to be refused")
+	}
+}
+
+func TestRenew(t *testing.T) {
+	issued := time.Unix(1000, 0)
+	std := jwt.StandardClaims{IssuedAt: issued.Unix(), ExpiresAt: issued.Add(time.Hour).Unix()}
+	scope := Scope{UploadID: "upload-1", RenewUntil: issued.Add(90 * time.Minute).Unix()}
+
+	if _, ok := Renew(std, scope, issued.Add(10*time.Minute)); ok {
+		t.Error("expected a fresh token not to be renewed")
+	}
+	if exp, ok := Renew(std, scope, issued.Add(40*time.Minute)); !ok || exp != issued.Add(90*time.Minute).Unix() {
+		t.Errorf("expected the token to be renewed up to the renewal limit, got %d %t", exp, ok)
+	}
+	scope.RenewUntil = issued.Add(24 * time.Hour).Unix()
+	if exp, ok := Renew(std, scope, issued.Add(40*time.Minute)); !ok || exp != issued.Add(100*time.Minute).Unix() {
+		t.Errorf("expected the token to be renewed for its lifetime, got %d %t", exp, ok)
+	}
+	if _, ok := Renew(std, scope, issued.Add(25*time.Hour)); ok {
+		t.Error("expected the token not to be renewed after the renewal limit")
+	}
+	if _, ok := Renew(std, Scope{}, issued.Add(40*time.Minute)); ok {
+		t.Error("expected a token not bound to an upload not to be renewed")
+	}
+}
@@ -59,3 +59,16 @@ type Service interface {
 	// GET is public and POST is not.
 	Unprotected() []string
 }
+
+// CrossOriginService is implemented by services that are meant to be accessed
+// from other origins; for these, CORS is handled with the default settings
+// even if no cors section is present in their configuration.
+type CrossOriginService interface {
+	IsCrossOrigin() bool
+}
+
+// CrossOriginHeadersService is implemented by services whose clients send or read
+// headers beyond the default ones; these are added to its CORS settings.
+type CrossOriginHeadersService interface {
+	CrossOriginHeaders() (allowed, exposed []string)
+}
@@ -0,0 +1,345 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package grpcweb translates gRPC-Web requests into gRPC calls, so that browser
+// clients can use gRPC APIs without a separate proxy.
+//
+// Browsers cannot access HTTP trailers, so gRPC-Web sends the status of a call in
+// a last length prefixed frame flagged as trailer. The text variant encodes all
+// frames in base64 for clients unable to handle binary responses.
+// Client streaming is not part of gRPC-Web, every call sends a single message.
+package grpcweb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ContentType is the content type of binary gRPC-Web requests.
+	ContentType = "application/grpc-web"
+	// ContentTypeText is the content type of base64 encoded gRPC-Web requests.
+	ContentTypeText = "application/grpc-web-text"
+
+	// flagTrailer marks the frame carrying the status and the trailers of a call
+	flagTrailer byte = 0x80
+	// maxMessageSize bounds the size of a request message
+	maxMessageSize = 16 << 20
+)
+
+// RequestHeaders are the headers sent by gRPC-Web clients, which need to be allowed for cross origin requests.
+var RequestHeaders = []string{"Content-Type", "X-Grpc-Web", "X-User-Agent", "Grpc-Timeout"}
+
+// ResponseHeaders are the headers read by gRPC-Web clients, which need to be exposed for cross origin requests.
+var ResponseHeaders = []string{"Grpc-Status", "Grpc-Message"}
+
+// headers which are not passed on as metadata of a call
+var skippedHeaders = map[string]bool{
+	"accept":            true,
+	"accept-encoding":   true,
+	"connection":        true,
+	"content-length":    true,
+	"content-type":      true,
+	"cookie":            true,
+	"grpc-timeout":      true,
+	"host":              true,
+	"origin":            true,
+	"referer":           true,
+	"te":                true,
+	"transfer-encoding": true,
+	"user-agent":        true,
+	"x-grpc-web":        true,
+	"x-user-agent":      true,
+}
+
+// IsRequest returns whether the request is a gRPC-Web request.
+func IsRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), ContentType)
+}
+
+// Proxy translates gRPC-Web requests into gRPC calls on a connection.
+type Proxy struct {
+	conn grpc.ClientConnInterface
+	// allowed are the fully qualified names of the services which may be called, all if empty
+	allowed map[string]bool
+}
+
+// NewProxy returns a proxy calling the given services on the connection, all services if none are given.
+func NewProxy(conn grpc.ClientConnInterface, services ...string) *Proxy {
+	p := &Proxy{conn: conn, allowed: map[string]bool{}}
+	for _, s := range services {
+		p.allowed[s] = true
+	}
+	return p
+}
+
+// ServeHTTP calls the method named by the path of the request, e.g. /cs3.gateway.v1beta1.GatewayAPI/Stat
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !IsRequest(r) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	method := path.Clean("/" + r.URL.Path)
+	parts := strings.Split(method, "/")
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	text := strings.HasPrefix(r.Header.Get("Content-Type"), ContentTypeText)
+	rw := &responseWriter{w: w, text: text, contentType: r.Header.Get("Content-Type")}
+
+	if len(p.allowed) > 0 && !p.allowed[parts[1]] {
+		rw.writeHeader(nil)
+		rw.writeTrailer(status.New(codes.Unimplemented, "unknown service "+parts[1]), nil)
+		return
+	}
+
+	var body io.Reader = r.Body
+	if text {
+		body = base64.NewDecoder(base64.StdEncoding, r.Body)
+	}
+	msg, err := readMessage(body)
+	if err != nil {
+		rw.writeHeader(nil)
+		rw.writeTrailer(status.New(codes.InvalidArgument, err.Error()), nil)
+		return
+	}
+
+	ctx := metadata.NewOutgoingContext(r.Context(), incomingMetadata(r.Header))
+	if t := r.Header.Get("Grpc-Timeout"); t != "" {
+		timeout, err := ParseTimeout(t)
+		if err != nil {
+			rw.writeHeader(nil)
+			rw.writeTrailer(status.New(codes.InvalidArgument, err.Error()), nil)
+			return
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// a server streaming call also handles unary methods, the single response is followed by io.EOF
+	stream, err := p.conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, method, grpc.ForceCodec(rawCodec{}))
+	if err == nil {
+		err = stream.SendMsg(&msg)
+	}
+	if err == nil {
+		err = stream.CloseSend()
+	}
+	if err != nil {
+		rw.writeHeader(nil)
+		rw.writeTrailer(status.Convert(err), nil)
+		return
+	}
+
+	for {
+		var out []byte
+		err = stream.RecvMsg(&out)
+		if !rw.wroteHeader {
+			header, _ := stream.Header()
+			rw.writeHeader(header)
+		}
+		if err != nil {
+			break
+		}
+		rw.writeFrame(0, out)
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	rw.writeTrailer(status.Convert(err), stream.Trailer())
+}
+
+// readMessage reads the single message of a request.
+func readMessage(r io.Reader) ([]byte, error) {
+	prefix := make([]byte, 5)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, fmt.Errorf("error reading the message prefix: %w", err)
+	}
+	if prefix[0] != 0 {
+		return nil, fmt.Errorf("unsupported message flags %#x", prefix[0])
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > maxMessageSize {
+		return nil, fmt.Errorf("message of %d bytes exceeds the limit", size)
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, fmt.Errorf("error reading the message: %w", err)
+	}
+	return msg, nil
+}
+
+// incomingMetadata returns the metadata of a call from the headers of a request.
+func incomingMetadata(h http.Header) metadata.MD {
+	md := metadata.MD{}
+	for k, vs := range h {
+		k = strings.ToLower(k)
+		if skippedHeaders[k] || strings.HasPrefix(k, "access-control-") || strings.HasPrefix(k, "sec-") {
+			continue
+		}
+		for _, v := range vs {
+			if strings.HasSuffix(k, "-bin") {
+				b, err := base64.StdEncoding.DecodeString(v)
+				if err != nil {
+					continue
+				}
+				v = string(b)
+			}
+			md.Append(k, v)
+		}
+	}
+	return md
+}
+
+// ParseTimeout parses the value of a grpc-timeout header, e.g. 10S.
+func ParseTimeout(v string) (time.Duration, error) {
+	if len(v) < 2 {
+		return 0, fmt.Errorf("invalid timeout %q", v)
+	}
+	units := map[byte]time.Duration{
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+		'm': time.Millisecond,
+		'u': time.Microsecond,
+		'n': time.Nanosecond,
+	}
+	unit, ok := units[v[len(v)-1]]
+	if !ok {
+		return 0, fmt.Errorf("invalid timeout unit %q", v)
+	}
+	n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid timeout %q", v)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+type responseWriter struct {
+	w           http.ResponseWriter
+	text        bool
+	contentType string
+	wroteHeader bool
+}
+
+func (rw *responseWriter) writeHeader(md metadata.MD) {
+	h := rw.w.Header()
+	for k, vs := range md {
+		for _, v := range vs {
+			h.Add(k, encodeMetadataValue(k, v))
+		}
+	}
+	h.Set("Content-Type", rw.contentType)
+	rw.w.WriteHeader(http.StatusOK)
+	rw.wroteHeader = true
+}
+
+func (rw *responseWriter) writeFrame(flags byte, payload []byte) {
+	frame := make([]byte, 5+len(payload))
+	frame[0] = flags
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+	copy(frame[5:], payload)
+	if rw.text {
+		frame = []byte(base64.StdEncoding.EncodeToString(frame))
+	}
+	_, _ = rw.w.Write(frame)
+	if f, ok := rw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (rw *responseWriter) writeTrailer(st *status.Status, md metadata.MD) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "grpc-status: %d\r\n", st.Code())
+	if st.Message() != "" {
+		fmt.Fprintf(&b, "grpc-message: %s\r\n", EncodeMessage(st.Message()))
+	}
+	for k, vs := range md {
+		for _, v := range vs {
+			fmt.Fprintf(&b, "%s: %s\r\n", k, encodeMetadataValue(k, v))
+		}
+	}
+	rw.writeFrame(flagTrailer, b.Bytes())
+}
+
+func encodeMetadataValue(k, v string) string {
+	if strings.HasSuffix(k, "-bin") {
+		return base64.StdEncoding.EncodeToString([]byte(v))
+	}
+	return v
+}
+
+// EncodeMessage percent encodes a status message as required for the grpc-message header.
+func EncodeMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c >= ' ' && c <= '~' && c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// rawCodec passes the encoded messages through, as the proxy does not need to know their types.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return *b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	*b = append([]byte(nil), data...)
+	return nil
+}
+
+// Name returns proto, the codec used by the servers for the messages passed through
+func (rawCodec) Name() string {
+	return "proto"
+}
@@ -0,0 +1,233 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package grpcweb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeConn answers every call with the configured responses, echoing the request message first.
+type fakeConn struct {
+	method    string
+	md        metadata.MD
+	responses [][]byte
+	err       error
+}
+
+func (c *fakeConn) Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption) error {
+	return status.Error(codes.Unimplemented, "unary calls are not used")
+}
+
+func (c *fakeConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	c.method = method
+	c.md, _ = metadata.FromOutgoingContext(ctx)
+	return &fakeStream{ctx: ctx, conn: c}, nil
+}
+
+type fakeStream struct {
+	ctx  context.Context
+	conn *fakeConn
+	sent int
+}
+
+func (s *fakeStream) Header() (metadata.MD, error) { return metadata.Pairs("x-served-by", "fake"), nil }
+func (s *fakeStream) Trailer() metadata.MD         { return metadata.Pairs("x-took", "1ms") }
+func (s *fakeStream) CloseSend() error             { return nil }
+func (s *fakeStream) Context() context.Context     { return s.ctx }
+
+func (s *fakeStream) SendMsg(m interface{}) error {
+	s.conn.responses = append([][]byte{*m.(*[]byte)}, s.conn.responses...)
+	return nil
+}
+
+func (s *fakeStream) RecvMsg(m interface{}) error {
+	if s.sent == len(s.conn.responses) {
+		if s.conn.err != nil {
+			return s.conn.err
+		}
+		return io.EOF
+	}
+	*m.(*[]byte) = s.conn.responses[s.sent]
+	s.sent++
+	return nil
+}
+
+func frame(flags byte, payload []byte) []byte {
+	f := make([]byte, 5+len(payload))
+	f[0] = flags
+	binary.BigEndian.PutUint32(f[1:], uint32(len(payload)))
+	copy(f[5:], payload)
+	return f
+}
+
+// readFrames splits a response body into its frames.
+func readFrames(t *testing.T, body []byte) (messages [][]byte, trailer string) {
+	for len(body) > 0 {
+		if len(body) < 5 {
+			t.Fatalf("truncated frame %v", body)
+		}
+		size := binary.BigEndian.Uint32(body[1:5])
+		payload := body[5 : 5+size]
+		if body[0]&flagTrailer != 0 {
+			trailer = string(payload)
+		} else {
+			messages = append(messages, payload)
+		}
+		body = body[5+size:]
+	}
+	return messages, trailer
+}
+
+func TestProxy(t *testing.T) {
+	conn := &fakeConn{responses: [][]byte{[]byte("second")}}
+	p := NewProxy(conn)
+
+	r := httptest.NewRequest(http.MethodPost, "/pkg.Service/Method", bytes.NewReader(frame(0, []byte("first"))))
+	r.Header.Set("Content-Type", ContentType+"+proto")
+	r.Header.Set("X-Access-Token", "token")
+	r.Header.Set("X-Grpc-Web", "1")
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+
+	if conn.method != "/pkg.Service/Method" {
+		t.Errorf("called %s", conn.method)
+	}
+	if v := conn.md.Get("x-access-token"); len(v) != 1 || v[0] != "token" {
+		t.Errorf("token not passed on: %v", conn.md)
+	}
+	if v := conn.md.Get("x-grpc-web"); len(v) != 0 {
+		t.Errorf("protocol header passed on: %v", conn.md)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != ContentType+"+proto" {
+		t.Errorf("content type %s", ct)
+	}
+	if h := w.Header().Get("X-Served-By"); h != "fake" {
+		t.Errorf("header metadata not returned: %v", w.Header())
+	}
+
+	messages, trailer := readFrames(t, w.Body.Bytes())
+	if len(messages) != 2 || string(messages[0]) != "first" || string(messages[1]) != "second" {
+		t.Errorf("unexpected messages %q", messages)
+	}
+	if !strings.Contains(trailer, "grpc-status: 0\r\n") || !strings.Contains(trailer, "x-took: 1ms\r\n") {
+		t.Errorf("unexpected trailer %q", trailer)
+	}
+}
+
+func TestProxyText(t *testing.T) {
+	conn := &fakeConn{err: status.Error(codes.NotFound, "no such file")}
+	p := NewProxy(conn)
+
+	body := base64.StdEncoding.EncodeToString(frame(0, []byte("request")))
+	r := httptest.NewRequest(http.MethodPost, "/pkg.Service/Method", strings.NewReader(body))
+	r.Header.Set("Content-Type", ContentTypeText)
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+
+	// every frame is encoded on its own
+	var decoded []byte
+	for _, chunk := range regexp.MustCompile(`[A-Za-z0-9+/]+=*`).FindAllString(w.Body.String(), -1) {
+		b, err := base64.StdEncoding.DecodeString(chunk)
+		if err != nil {
+			t.Fatalf("invalid base64 %q: %v", chunk, err)
+		}
+		decoded = append(decoded, b...)
+	}
+	messages, trailer := readFrames(t, decoded)
+	if len(messages) != 1 || string(messages[0]) != "request" {
+		t.Errorf("unexpected messages %q", messages)
+	}
+	if !strings.Contains(trailer, "grpc-status: 5\r\n") || !strings.Contains(trailer, "grpc-message: no such file\r\n") {
+		t.Errorf("unexpected trailer %q", trailer)
+	}
+}
+
+func TestProxyRejects(t *testing.T) {
+	p := NewProxy(&fakeConn{}, "pkg.Allowed")
+
+	tests := map[string]struct {
+		method      string
+		path        string
+		contentType string
+		code        int
+		trailer     string
+	}{
+		"get":          {http.MethodGet, "/pkg.Allowed/Method", ContentType, http.StatusMethodNotAllowed, ""},
+		"content type": {http.MethodPost, "/pkg.Allowed/Method", "application/json", http.StatusUnsupportedMediaType, ""},
+		"no method":    {http.MethodPost, "/pkg.Allowed", ContentType, http.StatusNotFound, ""},
+		"not allowed":  {http.MethodPost, "/pkg.Other/Method", ContentType, http.StatusOK, "grpc-status: 12\r\n"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, bytes.NewReader(frame(0, nil)))
+			r.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			p.ServeHTTP(w, r)
+			if w.Code != tt.code {
+				t.Errorf("expected %d, got %d", tt.code, w.Code)
+			}
+			if tt.trailer != "" {
+				if _, trailer := readFrames(t, w.Body.Bytes()); !strings.Contains(trailer, tt.trailer) {
+					t.Errorf("unexpected trailer %q", trailer)
+				}
+			}
+		})
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	tests := map[string]time.Duration{
+		"10S":  10 * time.Second,
+		"250m": 250 * time.Millisecond,
+		"1H":   time.Hour,
+	}
+	for v, expected := range tests {
+		d, err := ParseTimeout(v)
+		if err != nil || d != expected {
+			t.Errorf("%s: expected %s, got %s (%v)", v, expected, d, err)
+		}
+	}
+	for _, v := range []string{"", "S", "10", "10x", "-1S"} {
+		if _, err := ParseTimeout(v); err == nil {
+			t.Errorf("%q: expected an error", v)
+		}
+	}
+}
+
+func TestEncodeMessage(t *testing.T) {
+	if m := EncodeMessage("100% done\nnext"); m != "100%25 done%0Anext" {
+		t.Errorf("unexpected encoding %q", m)
+	}
+}
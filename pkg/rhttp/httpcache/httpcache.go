@@ -0,0 +1,235 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package httpcache caches the responses of read-only endpoints which clients
+// query on every sync cycle, like the capabilities or the user info. Responses
+// are kept in memory and tagged with an ETag, so clients can revalidate them
+// cheaply with If-None-Match.
+package httpcache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ReneKroon/ttlcache/v2"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/tenant"
+)
+
+// Config holds the cache settings of a service.
+type Config struct {
+	TTL    int `mapstructure:"ttl" docs:"30;Seconds a response is served from memory; a negative value disables the in-process cache."`
+	MaxAge int `mapstructure:"max_age" docs:"0;Seconds clients may use a response without revalidating it."`
+}
+
+// Init applies the defaults.
+func (c *Config) Init() {
+	if c.TTL == 0 {
+		c.TTL = 30
+	}
+}
+
+// Cache serves cached responses.
+type Cache struct {
+	conf    *Config
+	entries *ttlcache.Cache
+}
+
+type entry struct {
+	status int
+	header http.Header
+	body   []byte
+	etag   string
+}
+
+// New returns a cache for the given configuration.
+func New(c *Config) *Cache {
+	cache := &Cache{conf: c}
+	if c.TTL > 0 {
+		cache.entries = ttlcache.NewCache()
+		_ = cache.entries.SetTTL(time.Duration(c.TTL) * time.Second)
+		cache.entries.SkipTTLExtensionOnHit(true)
+	}
+	return cache
+}
+
+type variantKey struct{}
+
+// WithVariant marks the response to a request as one of several variants
+// served for the same URL, e.g. depending on the client, so that each variant
+// is cached on its own. The variants added by several handlers add up.
+func WithVariant(r *http.Request, variant string) *http.Request {
+	return WithLazyVariant(r, func() string { return variant })
+}
+
+// WithLazyVariant is like WithVariant for variants which are expensive to
+// compute, e.g. because they depend on a user preference. The variant is only
+// computed when the request reaches a cache.
+func WithLazyVariant(r *http.Request, variant func() string) *http.Request {
+	variants, _ := r.Context().Value(variantKey{}).([]func() string)
+	variants = append(variants[:len(variants):len(variants)], variant)
+	return r.WithContext(context.WithValue(r.Context(), variantKey{}, variants))
+}
+
+// Key identifies the response to a request. It includes the requested host
+// and URL, the attributes of the user and the variants of the response, so
+// changes to them result in a new entry.
+func Key(r *http.Request) string {
+	key := r.Host + r.URL.Path + "?" + r.URL.Query().Encode()
+	if u, ok := ctxpkg.ContextGetUser(r.Context()); ok {
+		key += "#" + fingerprint(u)
+	}
+	variants, _ := r.Context().Value(variantKey{}).([]func() string)
+	for _, variant := range variants {
+		if v := variant(); v != "" {
+			key += "@" + v
+		}
+	}
+	return key
+}
+
+func fingerprint(u *userpb.User) string {
+	h := sha256.New()
+	for _, s := range []string{u.GetId().GetIdp(), u.GetId().GetOpaqueId(), u.GetId().GetType().String(), u.Username, u.DisplayName, u.Mail, tenant.Get(u.Opaque)} {
+		_, _ = h.Write([]byte(s))
+		_, _ = h.Write([]byte{0})
+	}
+	_, _ = h.Write([]byte(strings.Join(u.Groups, "\x00")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Handler wraps the next handler, serving GET and HEAD requests from the
+// cache and answering conditional requests with 304 Not Modified. Only
+// successful responses are cached.
+func (c *Cache) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := Key(r)
+		e := c.get(key)
+		if e == nil && r.Method == http.MethodHead {
+			// the response to a HEAD request lacks the body to cache
+			next.ServeHTTP(w, r)
+			return
+		}
+		if e == nil {
+			rec := &recorder{header: http.Header{}}
+			next.ServeHTTP(rec, r)
+			e = &entry{
+				status: rec.status,
+				header: rec.header,
+				body:   rec.body.Bytes(),
+			}
+			if e.status == 0 {
+				e.status = http.StatusOK
+			}
+			if e.status != http.StatusOK {
+				// errors are passed on as they are
+				write(w, r, e)
+				return
+			}
+			sum := sha256.Sum256(e.body)
+			e.etag = fmt.Sprintf(`"%s"`, hex.EncodeToString(sum[:16]))
+			c.set(key, e)
+		}
+
+		w.Header().Set("ETag", e.etag)
+		w.Header().Set("Cache-Control", c.cacheControl())
+		if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, e.etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		write(w, r, e)
+	})
+}
+
+// recorder keeps the response of the next handler, so that it can be cached.
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(p)
+}
+
+func (c *Cache) get(key string) *entry {
+	if c.entries == nil {
+		return nil
+	}
+	if v, err := c.entries.Get(key); err == nil {
+		return v.(*entry)
+	}
+	return nil
+}
+
+func (c *Cache) set(key string, e *entry) {
+	if c.entries != nil {
+		_ = c.entries.Set(key, e)
+	}
+}
+
+// cacheControl marks the responses as private as they may depend on the user.
+func (c *Cache) cacheControl() string {
+	if c.conf.MaxAge > 0 {
+		return fmt.Sprintf("private, max-age=%d", c.conf.MaxAge)
+	}
+	return "private, no-cache"
+}
+
+func etagMatches(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == etag || t == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func write(w http.ResponseWriter, r *http.Request, e *entry) {
+	for k, v := range e.header {
+		w.Header()[k] = append([]string(nil), v...)
+	}
+	w.WriteHeader(e.status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(e.body)
+	}
+}
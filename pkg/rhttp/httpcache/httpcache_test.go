@@ -0,0 +1,141 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package httpcache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+)
+
+func TestHandler(t *testing.T) {
+	calls := 0
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		u, _ := ctxpkg.ContextGetUser(r.Context())
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("hello " + u.GetDisplayName()))
+	})
+	c := &Config{}
+	c.Init()
+	h := New(c).Handler(next)
+
+	serve := func(u *userpb.User, etag string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/cloud/user?format=json", nil)
+		r = r.WithContext(ctxpkg.ContextSetUser(context.Background(), u))
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	alice := &userpb.User{Id: &userpb.UserId{OpaqueId: "alice"}, DisplayName: "Alice"}
+	first := serve(alice, "")
+	if first.Code != http.StatusOK || first.Body.String() != "hello Alice" {
+		t.Fatalf("unexpected response %d %q", first.Code, first.Body.String())
+	}
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+
+	if w := serve(alice, ""); w.Body.String() != "hello Alice" || calls != 1 {
+		t.Errorf("expected a cached response, got %q after %d calls", w.Body.String(), calls)
+	}
+	if w := serve(alice, etag); w.Code != http.StatusNotModified {
+		t.Errorf("expected 304, got %d", w.Code)
+	}
+
+	// changed user attributes invalidate the cached response
+	renamed := &userpb.User{Id: &userpb.UserId{OpaqueId: "alice"}, DisplayName: "Alice Smith"}
+	w := serve(renamed, etag)
+	if w.Code != http.StatusOK || w.Body.String() != "hello Alice Smith" || calls != 2 {
+		t.Errorf("expected a fresh response, got %d %q after %d calls", w.Code, w.Body.String(), calls)
+	}
+	if w.Header().Get("ETag") == etag {
+		t.Error("expected a new ETag")
+	}
+}
+
+func TestHandlerPassesErrors(t *testing.T) {
+	calls := 0
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	c := &Config{}
+	c.Init()
+	h := New(c).Handler(next)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cloud/capabilities", nil))
+		if w.Code != http.StatusServiceUnavailable || w.Header().Get("ETag") != "" {
+			t.Errorf("expected the error to be passed on, got %d", w.Code)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected errors not to be cached, got %d calls", calls)
+	}
+}
+
+func TestKeyVariant(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/cloud/capabilities", nil)
+	if Key(r) != Key(WithVariant(r, "")) {
+		t.Error("an empty variant must not change the key")
+	}
+	if Key(WithVariant(r, "nextcloud")) == Key(r) || Key(WithVariant(r, "nextcloud")) == Key(WithVariant(r, "owncloud")) {
+		t.Error("expected each variant to have its own key")
+	}
+	if Key(WithVariant(WithVariant(r, "nextcloud"), "desktop")) == Key(WithVariant(r, "desktop")) {
+		t.Error("expected the variants of several handlers to add up")
+	}
+}
+
+func TestLazyVariant(t *testing.T) {
+	calls := 0
+	r := WithLazyVariant(httptest.NewRequest(http.MethodGet, "/cloud/user", nil), func() string {
+		calls++
+		return "de-DE"
+	})
+	if calls != 0 {
+		t.Fatal("expected the variant not to be computed before the request reaches a cache")
+	}
+	if Key(r) == Key(httptest.NewRequest(http.MethodGet, "/cloud/user", nil)) {
+		t.Error("expected the lazy variant to be part of the key")
+	}
+	if calls == 0 {
+		t.Error("expected the variant to be computed for the key")
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	if !etagMatches(`"a", W/"b"`, `"b"`) {
+		t.Error("expected weak ETag to match")
+	}
+	if etagMatches(`"a"`, `"b"`) {
+		t.Error("expected different ETags not to match")
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package problem renders the errors of the HTML-facing services, as error
+// pages for browsers and as RFC 7807 problem details for API clients.
+package problem
+
+import (
+	"encoding/json"
+	"html/template"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+)
+
+// ContentType is the media type of problem details as defined by RFC 7807.
+const ContentType = "application/problem+json"
+
+// Config holds the settings of the error responses of a service.
+type Config struct {
+	SupportContact string `mapstructure:"support_contact" docs:";The text pointing users to the support, like an email address or a URL, shown on all error pages."`
+}
+
+// Details describes an error as defined by RFC 7807.
+type Details struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	// RequestID correlates the error with the logs of the services.
+	RequestID string `json:"requestId,omitempty"`
+	Support   string `json:"support,omitempty"`
+}
+
+// Renderer writes errors in the format requested by the client.
+type Renderer struct {
+	conf Config
+}
+
+// NewRenderer returns a Renderer using the given settings.
+func NewRenderer(c *Config) *Renderer {
+	r := &Renderer{}
+	if c != nil {
+		r.conf = *c
+	}
+	return r
+}
+
+// New returns the problem details of an error that occurred while serving the given request.
+func (rd *Renderer) New(r *http.Request, status int, detail string) *Details {
+	d := &Details{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Support:  rd.conf.SupportContact,
+	}
+	if id, ok := ctxpkg.ContextGetRequestID(r.Context()); ok {
+		d.RequestID = id
+	}
+	return d
+}
+
+// Write writes an error to the client; browsers get an HTML error page, all other clients get problem details.
+// The detail is shown to the user, so it must not reveal any internals; these belong in the logs.
+func (rd *Renderer) Write(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	d := rd.New(r, status, detail)
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if WantsHTML(r) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		_ = pageTemplate.Execute(w, d)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(d)
+}
+
+// WantsHTML tells if the client prefers HTML over JSON, as browsers do; wildcards alone select JSON.
+func WantsHTML(r *http.Request) bool {
+	htmlQ, jsonQ := 0.0, 0.0
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case "text/html", "application/xhtml+xml":
+			if q > htmlQ {
+				htmlQ = q
+			}
+		case ContentType, "application/json", "application/*", "*/*":
+			if q > jsonQ {
+				jsonQ = q
+			}
+		}
+	}
+	return htmlQ > 0 && htmlQ >= jsonQ
+}
+
+var pageTemplate = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Status}} {{.Title}}</title>
+<style>
+body { font-family: sans-serif; color: #333; margin: 0; padding: 4em 1em; background: #f5f5f5; }
+main { max-width: 40em; margin: 0 auto; padding: 2em; background: #fff; border-radius: 4px; box-shadow: 0 1px 3px rgba(0, 0, 0, 0.2); }
+h1 { font-size: 1.5em; margin-top: 0; }
+.ref { color: #777; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Status}} {{.Title}}</h1>
+{{if .Detail}}<p>{{.Detail}}</p>{{end}}
+{{if .Support}}<p>If the problem persists, please contact {{.Support}}.</p>{{end}}
+{{if .RequestID}}<p class="ref">Reference: <code>{{.RequestID}}</code></p>{{end}}
+</main>
+</body>
+</html>
+`))
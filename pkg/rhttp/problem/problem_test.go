@@ -0,0 +1,83 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+)
+
+func TestWantsHTML(t *testing.T) {
+	tests := []struct {
+		accept string
+		html   bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", false},
+		{"application/problem+json, text/html;q=0.5", false},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", true},
+		{"text/html;q=0.9, application/json;q=0.9", true},
+		{"text/html;q=0", false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", tt.accept)
+		if got := WantsHTML(r); got != tt.html {
+			t.Errorf("accept %q: expected html=%v, got %v", tt.accept, tt.html, got)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	rd := NewRenderer(&Config{SupportContact: "support@example.org"})
+
+	r := httptest.NewRequest(http.MethodGet, "/archive", nil)
+	r = r.WithContext(ctxpkg.ContextSetRequestID(r.Context(), "req-1"))
+	w := httptest.NewRecorder()
+	rd.Write(w, r, http.StatusNotFound, "no such <file>")
+
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != ContentType {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	d := &Details{}
+	if err := json.NewDecoder(w.Body).Decode(d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Status != http.StatusNotFound || d.Title != "Not Found" || d.Detail != "no such <file>" || d.RequestID != "req-1" || d.Instance != "/archive" || d.Support != "support@example.org" {
+		t.Errorf("unexpected problem details %+v", d)
+	}
+
+	r.Header.Set("Accept", "text/html")
+	w = httptest.NewRecorder()
+	rd.Write(w, r, http.StatusNotFound, "no such <file>")
+	body := w.Body.String()
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("expected an html page, got %s", w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(body, "no such &lt;file&gt;") || !strings.Contains(body, "req-1") || !strings.Contains(body, "support@example.org") {
+		t.Errorf("unexpected error page %s", body)
+	}
+}
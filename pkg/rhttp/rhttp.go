@@ -28,16 +28,28 @@ import (
 	"strings"
 	"time"
 
+	"github.com/cs3org/reva/internal/http/interceptors/accesslog"
 	"github.com/cs3org/reva/internal/http/interceptors/appctx"
 	"github.com/cs3org/reva/internal/http/interceptors/auth"
+	"github.com/cs3org/reva/internal/http/interceptors/cost"
 	"github.com/cs3org/reva/internal/http/interceptors/log"
 	"github.com/cs3org/reva/internal/http/interceptors/providerauthorizer"
+	"github.com/cs3org/reva/internal/http/interceptors/slo"
+	rlog "github.com/cs3org/reva/pkg/accesslog"
+	"github.com/cs3org/reva/pkg/apispec"
+	rcost "github.com/cs3org/reva/pkg/cost"
+	"github.com/cs3org/reva/pkg/rhttp/clientip"
+	"github.com/cs3org/reva/pkg/rhttp/cors"
 	"github.com/cs3org/reva/pkg/rhttp/global"
+	rslo "github.com/cs3org/reva/pkg/slo"
+	"github.com/cs3org/reva/pkg/sysinfo"
 	rtrace "github.com/cs3org/reva/pkg/trace"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // New returns a new server
@@ -49,13 +61,21 @@ func New(m interface{}, l zerolog.Logger) (*Server, error) {
 
 	conf.init()
 
+	trusted, err := clientip.ParseNetworks(conf.TrustedProxies)
+	if err != nil {
+		return nil, errors.Wrap(err, "rhttp: error parsing the trusted proxies")
+	}
+
 	httpServer := &http.Server{}
 	s := &Server{
 		httpServer:  httpServer,
 		conf:        conf,
+		trusted:     trusted,
 		svcs:        map[string]global.Service{},
+		svcNames:    map[string]string{},
 		unprotected: []string{},
 		handlers:    map[string]http.Handler{},
+		cors:        map[string]global.Middleware{},
 		log:         l,
 	}
 	return s, nil
@@ -65,10 +85,13 @@ func New(m interface{}, l zerolog.Logger) (*Server, error) {
 type Server struct {
 	httpServer  *http.Server
 	conf        *config
+	trusted     clientip.Networks
 	listener    net.Listener
 	svcs        map[string]global.Service // map key is svc Prefix
+	svcNames    map[string]string         // map key is svc Prefix
 	unprotected []string
 	handlers    map[string]http.Handler
+	cors        map[string]global.Middleware // map key is svc Prefix
 	middlewares []*middlewareTriple
 	log         zerolog.Logger
 }
@@ -80,6 +103,13 @@ type config struct {
 	Middlewares map[string]map[string]interface{} `mapstructure:"middlewares"`
 	CertFile    string                            `mapstructure:"certfile"`
 	KeyFile     string                            `mapstructure:"keyfile"`
+	// TrustedProxies are the IPs and networks of the proxies whose X-Forwarded-For
+	// and X-Real-IP headers, respectively PROXY protocol headers, are honoured.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	// ProxyProtocol requires the connections from the trusted proxies to start with a PROXY protocol header.
+	ProxyProtocol bool `mapstructure:"proxy_protocol"`
+	// AccessLog configures the access log of the requests, written apart from the application logs.
+	AccessLog rlog.Config `mapstructure:"access_log"`
 }
 
 func (c *config) init() {
@@ -110,6 +140,9 @@ func (s *Server) Start(ln net.Listener) error {
 
 	s.httpServer.Handler = handler
 	s.listener = ln
+	if s.conf.ProxyProtocol {
+		s.listener = clientip.NewProxyListener(ln, s.trusted)
+	}
 
 	if (s.conf.CertFile != "") && (s.conf.KeyFile != "") {
 		s.log.Info().Msgf("https server listening at https://%s '%s' '%s'", s.conf.Address, s.conf.CertFile, s.conf.KeyFile)
@@ -208,9 +241,21 @@ func (s *Server) registerServices() error {
 
 			// instrument services with opencensus tracing.
 			h := traceHandler(svcName, svc.Handler())
+			if s.conf.AccessLog.Enabled {
+				h = accesslog.User(h)
+			}
+			if rcost.Enabled() {
+				h = cost.User(h)
+			}
 			s.handlers[svc.Prefix()] = h
+			s.svcNames[svc.Prefix()] = svcName
+			if err := s.registerCORS(svcName, svc); err != nil {
+				return err
+			}
 			s.svcs[svc.Prefix()] = svc
 			s.unprotected = append(s.unprotected, getUnprotected(svc.Prefix(), svc.Unprotected())...)
+			sysinfo.AddHTTPServices(svcName)
+			apispec.AddHTTPService(svcName, svc.Prefix())
 			s.log.Info().Msgf("http service enabled: %s@/%s", svcName, svc.Prefix())
 		} else {
 			message := fmt.Sprintf("http service %s does not exist", svcName)
@@ -220,6 +265,51 @@ func (s *Server) registerServices() error {
 	return nil
 }
 
+func (s *Server) registerCORS(svcName string, svc global.Service) error {
+	m, ok := s.conf.Services[svcName]["cors"].(map[string]interface{})
+	if !ok {
+		if cs, isCrossOrigin := svc.(global.CrossOriginService); !isCrossOrigin || !cs.IsCrossOrigin() {
+			return nil
+		}
+	}
+
+	c, err := cors.Parse(m)
+	if err != nil {
+		return errors.Wrapf(err, "http service %s has an invalid cors configuration", svcName)
+	}
+	if hs, ok := svc.(global.CrossOriginHeadersService); ok {
+		allowed, exposed := hs.CrossOriginHeaders()
+		c.AllowedHeaders = append(c.AllowedHeaders, allowed...)
+		c.ExposedHeaders = append(c.ExposedHeaders, exposed...)
+	}
+	s.cors[svc.Prefix()] = cors.New(c).Handler
+	s.log.Info().Msgf("cors enabled for http service %s with allowed origins %v", svcName, c.AllowedOrigins)
+	return nil
+}
+
+// corsHandler applies the CORS settings of the service the request is routed to.
+// It has to run before the authentication, as preflight requests are not authenticated.
+func (s *Server) corsHandler(h http.Handler) http.Handler {
+	handlers := make(map[string]http.Handler, len(s.cors))
+	for prefix, c := range s.cors {
+		handlers[prefix] = c(h)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var match string
+		for prefix := range handlers {
+			if urlHasPrefix(r.URL.Path, prefix) && len(prefix) >= len(match) {
+				match = prefix
+			}
+		}
+		if ch, ok := handlers[match]; ok {
+			ch.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
 func (s *Server) isServiceEnabled(svcName string) bool {
 	_, ok := global.Services[svcName]
 	return ok
@@ -281,6 +371,30 @@ func (s *Server) getHandlerLongestCommongURL(url string) (http.Handler, string,
 	return h, match, ok
 }
 
+// serviceName returns the name of the service the url is routed to.
+func (s *Server) serviceName(url string) string {
+	if _, prefix, ok := s.getHandlerLongestCommongURL(url); ok {
+		return s.svcNames[prefix]
+	}
+	return ""
+}
+
+// serve serves a request with the handler of the service at prefix, in a span
+// named after the service, so that the time spent in each service of a
+// request shows in its trace.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, h http.Handler, prefix, subURL string) {
+	ctx, span := rtrace.Provider.Tracer("reva").Start(r.Context(), s.svcNames[prefix],
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPMethodKey.String(r.Method),
+			semconv.HTTPTargetKey.String(r.URL.Path),
+		))
+	defer span.End()
+
+	r.URL.Path = subURL
+	h.ServeHTTP(w, r.WithContext(ctx))
+}
+
 func getSubURL(url, prefix string) string {
 	// pre cond: prefix is a prefix for url
 	// example: url = "/api/v0/", prefix = "/api", res = "/v0"
@@ -294,16 +408,14 @@ func (s *Server) getHandler() (http.Handler, error) {
 	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if h, ok := s.handlers[r.URL.Path]; ok {
 			s.log.Debug().Msgf("http routing: url=%s", r.URL.Path)
-			r.URL.Path = "/"
-			h.ServeHTTP(w, r)
+			s.serve(w, r, h, r.URL.Path, "/")
 			return
 		}
 
 		// find by longest common path
 		if h, url, ok := s.getHandlerLongestCommongURL(r.URL.Path); ok {
 			s.log.Debug().Msgf("http routing: url=%s", url)
-			r.URL.Path = getSubURL(r.URL.Path, url)
-			h.ServeHTTP(w, r)
+			s.serve(w, r, h, url, getSubURL(r.URL.Path, url))
 			return
 		}
 
@@ -344,8 +456,24 @@ func (s *Server) getHandler() (http.Handler, error) {
 	}
 
 	coreMiddlewares = append(coreMiddlewares, &middlewareTriple{Middleware: authMiddle, Name: "auth"})
+	coreMiddlewares = append(coreMiddlewares, &middlewareTriple{Middleware: s.corsHandler, Name: "cors"})
 	coreMiddlewares = append(coreMiddlewares, &middlewareTriple{Middleware: log.New(), Name: "log"})
+	if s.conf.AccessLog.Enabled {
+		l, err := rlog.New(&s.conf.AccessLog)
+		if err != nil {
+			return nil, errors.Wrap(err, "rhttp: error creating access log")
+		}
+		coreMiddlewares = append(coreMiddlewares, &middlewareTriple{Middleware: accesslog.New(l, s.serviceName), Name: "accesslog"})
+	}
+	if rslo.Enabled() {
+		coreMiddlewares = append(coreMiddlewares, &middlewareTriple{Middleware: slo.New(s.serviceName), Name: "slo"})
+	}
+	if rcost.Enabled() {
+		coreMiddlewares = append(coreMiddlewares, &middlewareTriple{Middleware: cost.New(), Name: "cost"})
+	}
 	coreMiddlewares = append(coreMiddlewares, &middlewareTriple{Middleware: appctx.New(s.log), Name: "appctx"})
+	// the client IP is determined first, so that all other middlewares can rely on it
+	coreMiddlewares = append(coreMiddlewares, &middlewareTriple{Middleware: clientip.Handler(s.trusted), Name: "clientip"})
 
 	for _, triple := range coreMiddlewares {
 		handler = triple.Middleware(traceHandler(triple.Name, handler))
@@ -18,7 +18,14 @@
 
 package rhttp
 
-import "testing"
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/rhttp/cors"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+)
 
 func TestURLHasPrefix(t *testing.T) {
 	tests := map[string]struct {
@@ -72,3 +79,83 @@ func TestURLHasPrefix(t *testing.T) {
 		})
 	}
 }
+
+func TestCORSHandler(t *testing.T) {
+	c, err := cors.Parse(map[string]interface{}{"allowed_origins": []string{"https://*.example.org"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{cors: map[string]global.Middleware{"remote.php": cors.New(c).Handler}}
+
+	reached := false
+	h := s.corsHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+
+	tests := map[string]struct {
+		url            string
+		origin         string
+		expectedOrigin string
+		expectReached  bool
+	}{
+		"allowed_origin":    {url: "/remote.php/dav/files", origin: "https://web.example.org", expectedOrigin: "https://web.example.org"},
+		"disallowed_origin": {url: "/remote.php/dav/files", origin: "https://evil.org", expectedOrigin: ""},
+		"other_service":     {url: "/ocs/v1.php", origin: "https://web.example.org", expectedOrigin: "", expectReached: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			reached = false
+			r := httptest.NewRequest(http.MethodOptions, tt.url, nil)
+			r.Header.Set("Origin", tt.origin)
+			r.Header.Set("Access-Control-Request-Method", "PROPFIND")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.expectedOrigin {
+				t.Errorf("expected allowed origin %q, got %q", tt.expectedOrigin, got)
+			}
+			if reached != tt.expectReached {
+				t.Errorf("expected next handler reached=%v, got %v", tt.expectReached, reached)
+			}
+		})
+	}
+}
+
+type headersService struct {
+	global.Service
+}
+
+func (headersService) Prefix() string      { return "grpcweb" }
+func (headersService) IsCrossOrigin() bool { return true }
+func (headersService) CrossOriginHeaders() ([]string, []string) {
+	return []string{"X-Grpc-Web"}, []string{"Grpc-Status"}
+}
+
+func TestRegisterCORSHeaders(t *testing.T) {
+	s := &Server{conf: &config{Services: map[string]map[string]interface{}{"grpcweb": {}}}, cors: map[string]global.Middleware{}}
+	if err := s.registerCORS("grpcweb", headersService{}); err != nil {
+		t.Fatal(err)
+	}
+	h := s.corsHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	r := httptest.NewRequest(http.MethodOptions, "/grpcweb/pkg.Service/Method", nil)
+	r.Header.Set("Origin", "https://web.example.org")
+	r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	r.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got == "" {
+		t.Errorf("expected the service headers to be allowed")
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/grpcweb/pkg.Service/Method", nil)
+	r.Header.Set("Origin", "https://web.example.org")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got != "Location, Grpc-Status" {
+		t.Errorf("unexpected exposed headers %q", got)
+	}
+}
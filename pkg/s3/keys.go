@@ -0,0 +1,71 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package s3
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+)
+
+// Key is an access key of a user. The secret is stored in clear, as the
+// server needs it to compute the signatures of the requests.
+type Key struct {
+	AccessKey   string         `json:"access_key"`
+	Secret      string         `json:"secret"`
+	User        *userpb.UserId `json:"user"`
+	Description string         `json:"description"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// KeyManager stores the access keys of the users.
+type KeyManager interface {
+	// AddKey stores a new key.
+	AddKey(ctx context.Context, k *Key) error
+	// GetKey returns the key with the given access key.
+	GetKey(ctx context.Context, accessKey string) (*Key, error)
+	// ListKeys returns the keys of a user.
+	ListKeys(ctx context.Context, user *userpb.UserId) ([]*Key, error)
+	// DeleteKey deletes a key of a user.
+	DeleteKey(ctx context.Context, user *userpb.UserId, accessKey string) error
+}
+
+// NewKey generates a new random access key for the user.
+func NewKey(user *userpb.UserId, description string) (*Key, error) {
+	id := make([]byte, 15)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	secret := make([]byte, 30)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return &Key{
+		// access keys are upper case alphanumeric by convention
+		AccessKey:   "RV" + base32.StdEncoding.EncodeToString(id),
+		Secret:      base64.RawURLEncoding.EncodeToString(secret),
+		User:        user,
+		Description: description,
+		CreatedAt:   time.Now().UTC(),
+	}, nil
+}
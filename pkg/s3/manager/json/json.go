@@ -0,0 +1,146 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/s3"
+	"github.com/cs3org/reva/pkg/s3/manager/registry"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("json", New)
+}
+
+type config struct {
+	File string `mapstructure:"file"`
+}
+
+func (c *config) init() {
+	if c.File == "" {
+		c.File = "/var/tmp/reva/s3-keys.json"
+	}
+}
+
+type manager struct {
+	sync.Mutex
+	file string
+	keys map[string]*s3.Key
+}
+
+// New returns a key manager storing the keys in a JSON file, which is only readable by its owner.
+func New(m map[string]interface{}) (s3.KeyManager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	c.init()
+
+	mgr := &manager{file: c.File, keys: map[string]*s3.Key{}}
+	data, err := ioutil.ReadFile(c.File)
+	switch {
+	case os.IsNotExist(err):
+		if err := mgr.save(); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, errors.Wrapf(err, "error reading the file %s", c.File)
+	case len(data) > 0:
+		if err := json.Unmarshal(data, &mgr.keys); err != nil {
+			return nil, errors.Wrapf(err, "error parsing the file %s", c.File)
+		}
+	}
+	return mgr, nil
+}
+
+func (m *manager) save() error {
+	data, err := json.Marshal(m.keys)
+	if err != nil {
+		return errors.Wrap(err, "error encoding the keys")
+	}
+	if err := ioutil.WriteFile(m.file, data, 0600); err != nil {
+		return errors.Wrapf(err, "error writing the file %s", m.file)
+	}
+	return nil
+}
+
+func (m *manager) AddKey(ctx context.Context, k *s3.Key) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.keys[k.AccessKey]; ok {
+		return errtypes.AlreadyExists(k.AccessKey)
+	}
+	m.keys[k.AccessKey] = k
+	if err := m.save(); err != nil {
+		delete(m.keys, k.AccessKey)
+		return err
+	}
+	return nil
+}
+
+func (m *manager) GetKey(ctx context.Context, accessKey string) (*s3.Key, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	k, ok := m.keys[accessKey]
+	if !ok {
+		return nil, errtypes.NotFound(accessKey)
+	}
+	return k, nil
+}
+
+func (m *manager) ListKeys(ctx context.Context, user *userpb.UserId) ([]*s3.Key, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	list := []*s3.Key{}
+	for _, k := range m.keys {
+		if utils.UserEqual(k.User, user) {
+			list = append(list, k)
+		}
+	}
+	return list, nil
+}
+
+func (m *manager) DeleteKey(ctx context.Context, user *userpb.UserId, accessKey string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	k, ok := m.keys[accessKey]
+	if !ok || !utils.UserEqual(k.User, user) {
+		return errtypes.NotFound(accessKey)
+	}
+	delete(m.keys, accessKey)
+	if err := m.save(); err != nil {
+		m.keys[accessKey] = k
+		return err
+	}
+	return nil
+}
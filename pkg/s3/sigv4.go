@@ -0,0 +1,271 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package s3 contains the parts of the S3 protocol used to serve reva storage to S3 clients.
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Algorithm is the only signature algorithm supported, AWS signature version 4
+	Algorithm = "AWS4-HMAC-SHA256"
+	// UnsignedPayload is the payload hash of requests whose body is not signed
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+	amzDateFormat = "20060102T150405Z"
+	scopeTerminal = "aws4_request"
+	// maxSkew is the difference tolerated between the time of a request and the server time
+	maxSkew = 15 * time.Minute
+	// maxExpires is the longest validity of a presigned url
+	maxExpires = 7 * 24 * time.Hour
+)
+
+// Error is an error with the code S3 uses for it.
+type Error struct {
+	Code    string
+	Message string
+	Status  int
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+func errMalformed(msg string) *Error {
+	return &Error{Code: "AuthorizationHeaderMalformed", Message: msg, Status: http.StatusBadRequest}
+}
+
+// ErrNoSignature is returned for requests which are not signed.
+var ErrNoSignature = &Error{Code: "AccessDenied", Message: "the request is not signed", Status: http.StatusForbidden}
+
+// Signature is the AWS signature version 4 of a request, either from its authorization header or from its query for presigned urls.
+type Signature struct {
+	AccessKey     string
+	Date          time.Time
+	Region        string
+	Service       string
+	SignedHeaders []string
+	Signature     string
+	// Expires is the validity of a presigned url, 0 for signatures in the authorization header
+	Expires time.Duration
+
+	presigned bool
+	scope     string
+}
+
+// ParseSignature extracts the signature of a request.
+func ParseSignature(r *http.Request) (*Signature, error) {
+	if r.URL.Query().Get("X-Amz-Algorithm") != "" {
+		return parsePresigned(r.URL.Query())
+	}
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		return nil, ErrNoSignature
+	}
+	if !strings.HasPrefix(auth, Algorithm+" ") {
+		return nil, errMalformed("unsupported signature algorithm")
+	}
+
+	s := &Signature{}
+	var credential string
+	for _, field := range strings.Split(strings.TrimPrefix(auth, Algorithm+" "), ",") {
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(kv) != 2 {
+			return nil, errMalformed("invalid authorization field " + field)
+		}
+		switch kv[0] {
+		case "Credential":
+			credential = kv[1]
+		case "SignedHeaders":
+			s.SignedHeaders = strings.Split(kv[1], ";")
+		case "Signature":
+			s.Signature = kv[1]
+		}
+	}
+	if credential == "" || len(s.SignedHeaders) == 0 || s.Signature == "" {
+		return nil, errMalformed("incomplete authorization header")
+	}
+
+	date := r.Header.Get("X-Amz-Date")
+	if date == "" {
+		date = r.Header.Get("Date")
+	}
+	if err := s.parseCredential(credential, date); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func parsePresigned(q url.Values) (*Signature, error) {
+	if q.Get("X-Amz-Algorithm") != Algorithm {
+		return nil, errMalformed("unsupported signature algorithm")
+	}
+	s := &Signature{
+		SignedHeaders: strings.Split(q.Get("X-Amz-SignedHeaders"), ";"),
+		Signature:     q.Get("X-Amz-Signature"),
+		presigned:     true,
+	}
+	if s.Signature == "" || q.Get("X-Amz-SignedHeaders") == "" {
+		return nil, errMalformed("incomplete presigned url")
+	}
+	expires, err := strconv.Atoi(q.Get("X-Amz-Expires"))
+	if err != nil || expires <= 0 {
+		return nil, errMalformed("invalid expiration of the presigned url")
+	}
+	s.Expires = time.Duration(expires) * time.Second
+	if s.Expires > maxExpires {
+		return nil, errMalformed("the expiration of a presigned url must be less than a week")
+	}
+	if err := s.parseCredential(q.Get("X-Amz-Credential"), q.Get("X-Amz-Date")); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parseCredential parses a credential of the form <access key>/<yyyymmdd>/<region>/<service>/aws4_request
+func (s *Signature) parseCredential(credential, date string) error {
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[4] != scopeTerminal {
+		return errMalformed("invalid credential " + credential)
+	}
+	t, err := time.Parse(amzDateFormat, date)
+	if err != nil {
+		return errMalformed("invalid date " + date)
+	}
+	if t.Format("20060102") != parts[1] {
+		return errMalformed("the date of the credential does not match the date of the request")
+	}
+	s.AccessKey, s.Date, s.Region, s.Service = parts[0], t, parts[2], parts[3]
+	s.scope = strings.Join(parts[1:], "/")
+	return nil
+}
+
+// Verify checks the signature of the request with the secret of its access key.
+// The path is the path the client sent the request to, which differs from the
+// path of the request if the service is served under a prefix.
+func (s *Signature) Verify(r *http.Request, path, secret string, now time.Time) error {
+	if s.presigned {
+		if now.After(s.Date.Add(s.Expires)) {
+			return &Error{Code: "AccessDenied", Message: "request has expired", Status: http.StatusForbidden}
+		}
+	} else if d := now.Sub(s.Date); d > maxSkew || d < -maxSkew {
+		return &Error{Code: "RequestTimeTooSkewed", Message: "the difference between the request time and the server time is too large", Status: http.StatusForbidden}
+	}
+
+	payload := UnsignedPayload
+	if !s.presigned {
+		payload = r.Header.Get("X-Amz-Content-Sha256")
+		if payload == "" {
+			return &Error{Code: "InvalidRequest", Message: "missing x-amz-content-sha256 header", Status: http.StatusBadRequest}
+		}
+	}
+
+	canonical := strings.Join([]string{
+		r.Method,
+		escape(path, false),
+		s.canonicalQuery(r.URL.Query()),
+		s.canonicalHeaders(r),
+		strings.Join(s.SignedHeaders, ";"),
+		payload,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonical))
+	stringToSign := strings.Join([]string{
+		Algorithm,
+		s.Date.Format(amzDateFormat),
+		s.scope,
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secret), s.Date.Format("20060102"))
+	for _, p := range []string{s.Region, s.Service, scopeTerminal} {
+		key = hmacSHA256(key, p)
+	}
+	expected := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	if !hmac.Equal([]byte(expected), []byte(s.Signature)) {
+		return &Error{Code: "SignatureDoesNotMatch", Message: "the request signature does not match", Status: http.StatusForbidden}
+	}
+	return nil
+}
+
+func (s *Signature) canonicalQuery(q url.Values) string {
+	if s.presigned {
+		q.Del("X-Amz-Signature")
+	}
+	params := make([]string, 0, len(q))
+	for k, vs := range q {
+		for _, v := range vs {
+			params = append(params, escape(k, true)+"="+escape(v, true))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+func (s *Signature) canonicalHeaders(r *http.Request) string {
+	var b strings.Builder
+	for _, h := range s.SignedHeaders {
+		var values []string
+		switch h {
+		case "host":
+			values = []string{r.Host}
+		case "content-length":
+			values = []string{strconv.FormatInt(r.ContentLength, 10)}
+		default:
+			values = append([]string(nil), r.Header.Values(h)...)
+		}
+		for i := range values {
+			values[i] = strings.Join(strings.Fields(values[i]), " ")
+		}
+		fmt.Fprintf(&b, "%s:%s\n", h, strings.Join(values, ","))
+	}
+	return b.String()
+}
+
+// escape encodes all characters but the unreserved ones, as defined by the signature version 4.
+func escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(data))
+	return h.Sum(nil)
+}
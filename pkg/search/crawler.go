@@ -0,0 +1,170 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package search
+
+import (
+	"context"
+	"path"
+	"time"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"google.golang.org/grpc"
+)
+
+// Client is the part of the gateway API the crawler lists the resources with.
+type Client interface {
+	Stat(ctx context.Context, in *provider.StatRequest, opts ...grpc.CallOption) (*provider.StatResponse, error)
+	ListContainer(ctx context.Context, in *provider.ListContainerRequest, opts ...grpc.CallOption) (*provider.ListContainerResponse, error)
+}
+
+// Crawler indexes the resources by walking through the folders.
+type Crawler struct {
+	index  Index
+	client Client
+}
+
+// NewCrawler returns a crawler storing the entries in the given index.
+func NewCrawler(index Index, client Client) *Crawler {
+	return &Crawler{index: index, client: client}
+}
+
+// Crawl indexes the referenced resource and the ones below it, then removes
+// the entries of the resources which are not below it anymore. The context
+// has to be authenticated as a user allowed to list the resources.
+func (c *Crawler) Crawl(ctx context.Context, ref *provider.Reference) error {
+	info, err := c.stat(ctx, ref)
+	if err != nil {
+		return err
+	}
+	ancestors, err := c.ancestors(ctx, info)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	if err := c.crawl(ctx, info, ancestors, now); err != nil {
+		return err
+	}
+	if info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		return c.index.Prune(ctx, ID(info.Id), now)
+	}
+	return nil
+}
+
+func (c *Crawler) crawl(ctx context.Context, info *provider.ResourceInfo, ancestors []string, now int64) error {
+	e := NewEntry(info, ancestors, now)
+	if info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		return c.index.Index(ctx, e)
+	}
+
+	res, err := c.client.ListContainer(ctx, &provider.ListContainerRequest{Ref: &provider.Reference{ResourceId: info.Id}})
+	switch {
+	case err != nil:
+		return err
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return errtypes.InternalError("search: error listing " + info.Path + ": " + res.Status.Message)
+	}
+
+	// the folders are walked once their entries are stored, so that the
+	// indexed tree is never disconnected
+	ancestors = append(ancestors[:len(ancestors):len(ancestors)], e.ID)
+	entries := []*Entry{e}
+	folders := []*provider.ResourceInfo{}
+	for _, child := range res.Infos {
+		if child.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			folders = append(folders, child)
+			continue
+		}
+		entries = append(entries, NewEntry(child, ancestors, now))
+	}
+	if err := c.index.Index(ctx, entries...); err != nil {
+		return err
+	}
+	for _, f := range folders {
+		if err := c.crawl(ctx, f, ancestors, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ancestors returns the ancestors of the resource from the entry of its
+// parent, which only the parent itself is known of if it is not indexed.
+func (c *Crawler) ancestors(ctx context.Context, info *provider.ResourceInfo) ([]string, error) {
+	if info.Path == "" || path.Dir(info.Path) == info.Path {
+		return []string{}, nil
+	}
+	parent, err := c.stat(ctx, &provider.Reference{Path: path.Dir(info.Path)})
+	if err != nil {
+		// the resource is the root the user can access
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			return []string{}, nil
+		}
+		if _, ok := err.(errtypes.IsPermissionDenied); ok {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	e, err := c.index.Get(ctx, ID(parent.Id))
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			return []string{ID(parent.Id)}, nil
+		}
+		return nil, err
+	}
+	return append(e.Ancestors[:len(e.Ancestors):len(e.Ancestors)], e.ID), nil
+}
+
+func (c *Crawler) stat(ctx context.Context, ref *provider.Reference) (*provider.ResourceInfo, error) {
+	res, err := c.client.Stat(ctx, &provider.StatRequest{Ref: ref})
+	switch {
+	case err != nil:
+		return nil, err
+	case res.Status.Code == rpc.Code_CODE_NOT_FOUND:
+		return nil, errtypes.NotFound(res.Status.Message)
+	case res.Status.Code == rpc.Code_CODE_PERMISSION_DENIED:
+		return nil, errtypes.PermissionDenied(res.Status.Message)
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, errtypes.InternalError(res.Status.Message)
+	}
+	return res.Info, nil
+}
+
+// NewEntry returns the entry of the given resource.
+func NewEntry(info *provider.ResourceInfo, ancestors []string, indexedAt int64) *Entry {
+	e := &Entry{
+		ID:        ID(info.Id),
+		Owner:     info.Owner.GetOpaqueId(),
+		Ancestors: ancestors,
+		Name:      path.Base(info.Path),
+		Path:      info.Path,
+		Type:      TypeFile,
+		Size:      info.Size,
+		Mtime:     info.Mtime.GetSeconds(),
+		MimeType:  info.MimeType,
+		IndexedAt: indexedAt,
+	}
+	if info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		e.Type = TypeContainer
+	}
+	return e
+}
@@ -0,0 +1,175 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/search"
+	"github.com/cs3org/reva/pkg/search/index/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+func init() {
+	registry.Register("bolt", New)
+}
+
+var entriesBucket = []byte("entries")
+
+type config struct {
+	File string `mapstructure:"file"`
+}
+
+func (c *config) init() {
+	if c.File == "" {
+		c.File = "/var/tmp/reva/search.db"
+	}
+}
+
+type index struct {
+	db *bolt.DB
+}
+
+// New returns a search index stored in a bbolt database. The searches scan
+// all the entries, which suits small deployments.
+func New(m map[string]interface{}) (search.Index, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	c.init()
+
+	if err := os.MkdirAll(filepath.Dir(c.File), 0700); err != nil {
+		return nil, errors.Wrapf(err, "error creating the directory of %s", c.File)
+	}
+	db, err := bolt.Open(c.File, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening the database %s", c.File)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(entriesBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "error creating the entries bucket")
+	}
+	return &index{db: db}, nil
+}
+
+func (i *index) Index(ctx context.Context, entries ...*search.Entry) error {
+	return i.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(entriesBucket)
+		for _, e := range entries {
+			v, err := json.Marshal(e)
+			if err != nil {
+				return errors.Wrap(err, "error encoding entry")
+			}
+			if err := b.Put([]byte(e.ID), v); err != nil {
+				return errors.Wrapf(err, "error storing entry %s", e.ID)
+			}
+		}
+		return nil
+	})
+}
+
+func (i *index) Get(ctx context.Context, id string) (*search.Entry, error) {
+	var e *search.Entry
+	err := i.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(entriesBucket).Get([]byte(id))
+		if v == nil {
+			return errtypes.NotFound(id)
+		}
+		e = &search.Entry{}
+		return json.Unmarshal(v, e)
+	})
+	return e, err
+}
+
+func (i *index) Delete(ctx context.Context, id string) error {
+	return i.remove(func(e *search.Entry) bool {
+		return e.Below(id)
+	})
+}
+
+func (i *index) Prune(ctx context.Context, id string, before int64) error {
+	return i.remove(func(e *search.Entry) bool {
+		return e.ID != id && e.Below(id) && e.IndexedAt < before
+	})
+}
+
+// remove deletes the entries selected by f.
+func (i *index) remove(f func(*search.Entry) bool) error {
+	return i.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(entriesBucket)
+		// the bucket cannot be modified while iterating over it
+		var keys [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			e := &search.Entry{}
+			if err := json.Unmarshal(v, e); err != nil {
+				return errors.Wrapf(err, "error decoding entry %s", k)
+			}
+			if f(e) {
+				keys = append(keys, append([]byte{}, k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return errors.Wrapf(err, "error deleting entry %s", k)
+			}
+		}
+		return nil
+	})
+}
+
+func (i *index) Search(ctx context.Context, q *search.Query) ([]*search.Entry, error) {
+	match := q.Matcher()
+	entries := []*search.Entry{}
+	err := i.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(entriesBucket).ForEach(func(k, v []byte) error {
+			e := &search.Entry{}
+			if err := json.Unmarshal(v, e); err != nil {
+				return errors.Wrapf(err, "error decoding entry %s", k)
+			}
+			if match(e) {
+				entries = append(entries, e)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return search.Page(entries, q), nil
+}
+
+func (i *index) Close() error {
+	return i.db.Close()
+}
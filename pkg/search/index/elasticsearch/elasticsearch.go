@@ -0,0 +1,295 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/search"
+	"github.com/cs3org/reva/pkg/search/index/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("elasticsearch", New)
+}
+
+// defaultSize is the number of entries returned by the queries without limit.
+const defaultSize = 1000
+
+var mapping = map[string]interface{}{
+	"mappings": map[string]interface{}{
+		"properties": map[string]interface{}{
+			"id":         map[string]string{"type": "keyword"},
+			"owner":      map[string]string{"type": "keyword"},
+			"ancestors":  map[string]string{"type": "keyword"},
+			"name":       map[string]string{"type": "keyword"},
+			"path":       map[string]string{"type": "keyword"},
+			"type":       map[string]string{"type": "keyword"},
+			"size":       map[string]string{"type": "long"},
+			"mtime":      map[string]string{"type": "long"},
+			"mime_type":  map[string]string{"type": "keyword"},
+			"indexed_at": map[string]string{"type": "long"},
+		},
+	},
+}
+
+type config struct {
+	Endpoint string `mapstructure:"endpoint"`
+	Index    string `mapstructure:"index"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	Insecure bool   `mapstructure:"insecure"`
+	Timeout  int    `mapstructure:"timeout"`
+}
+
+func (c *config) init() {
+	if c.Endpoint == "" {
+		c.Endpoint = "http://localhost:9200"
+	}
+	c.Endpoint = strings.TrimSuffix(c.Endpoint, "/")
+	if c.Index == "" {
+		c.Index = "reva-search"
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10
+	}
+}
+
+type index struct {
+	c      *config
+	client *http.Client
+}
+
+// New returns a search index stored in Elasticsearch, version 7.10 or later.
+// The index is created with its mapping if it does not exist.
+func New(m map[string]interface{}) (search.Index, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	c.init()
+
+	i := &index{
+		c:      c,
+		client: rhttp.GetHTTPClient(rhttp.Timeout(time.Duration(c.Timeout)*time.Second), rhttp.Insecure(c.Insecure)),
+	}
+	ctx := context.Background()
+	status, err := i.do(ctx, http.MethodHead, "", nil, nil)
+	if err != nil && status != http.StatusNotFound {
+		return nil, errors.Wrapf(err, "error checking the index %s", c.Index)
+	}
+	if status == http.StatusNotFound {
+		if _, err := i.do(ctx, http.MethodPut, "", mapping, nil); err != nil {
+			return nil, errors.Wrapf(err, "error creating the index %s", c.Index)
+		}
+	}
+	return i, nil
+}
+
+// do sends a request to the given path of the index and decodes the response
+// in out. body is sent as is if it is a byte slice, encoded as JSON otherwise.
+func (i *index) do(ctx context.Context, method, path string, body, out interface{}) (int, error) {
+	var r io.Reader
+	contentType := "application/json"
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		r = bytes.NewReader(b)
+		contentType = "application/x-ndjson"
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return 0, err
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, i.c.Endpoint+"/"+i.c.Index+path, r)
+	if err != nil {
+		return 0, err
+	}
+	if r != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if i.c.Username != "" {
+		req.SetBasicAuth(i.c.Username, i.c.Password)
+	}
+	res, err := i.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return res.StatusCode, fmt.Errorf("elasticsearch: %s %s: %s %s", method, req.URL.Path, res.Status, msg)
+	}
+	if out != nil {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			return res.StatusCode, errors.Wrap(err, "elasticsearch: error decoding response")
+		}
+	}
+	return res.StatusCode, nil
+}
+
+func (i *index) Index(ctx context.Context, entries ...*search.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(map[string]interface{}{"index": map[string]string{"_id": e.ID}}); err != nil {
+			return err
+		}
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	res := struct {
+		Errors bool `json:"errors"`
+	}{}
+	if _, err := i.do(ctx, http.MethodPost, "/_bulk", buf.Bytes(), &res); err != nil {
+		return err
+	}
+	if res.Errors {
+		return errors.New("elasticsearch: error indexing some of the entries")
+	}
+	return nil
+}
+
+func (i *index) Get(ctx context.Context, id string) (*search.Entry, error) {
+	res := struct {
+		Source *search.Entry `json:"_source"`
+	}{}
+	status, err := i.do(ctx, http.MethodGet, "/_doc/"+url.PathEscape(id), nil, &res)
+	if status == http.StatusNotFound {
+		return nil, errtypes.NotFound(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res.Source, nil
+}
+
+func (i *index) Delete(ctx context.Context, id string) error {
+	return i.deleteByQuery(ctx, map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should": []interface{}{
+				term("id", id),
+				term("ancestors", id),
+			},
+			"minimum_should_match": 1,
+		},
+	})
+}
+
+func (i *index) Prune(ctx context.Context, id string, before int64) error {
+	// the entries indexed last have to be visible, not to be pruned
+	if _, err := i.do(ctx, http.MethodPost, "/_refresh", nil, nil); err != nil {
+		return err
+	}
+	return i.deleteByQuery(ctx, map[string]interface{}{
+		"bool": map[string]interface{}{
+			"filter": []interface{}{
+				term("ancestors", id),
+				map[string]interface{}{"range": map[string]interface{}{"indexed_at": map[string]int64{"lt": before}}},
+			},
+		},
+	})
+}
+
+func (i *index) deleteByQuery(ctx context.Context, query interface{}) error {
+	_, err := i.do(ctx, http.MethodPost, "/_delete_by_query?conflicts=proceed&refresh=true", map[string]interface{}{"query": query}, nil)
+	return err
+}
+
+func (i *index) Search(ctx context.Context, q *search.Query) ([]*search.Entry, error) {
+	// ? is a wildcard as well for elasticsearch
+	pattern := strings.NewReplacer(`\`, `\\`, `?`, `\?`).Replace(q.Pattern())
+	filter := []interface{}{
+		map[string]interface{}{"wildcard": map[string]interface{}{"name": map[string]interface{}{"value": pattern, "case_insensitive": true}}},
+		map[string]interface{}{"bool": map[string]interface{}{
+			"should": []interface{}{
+				term("owner", q.Owner),
+				terms("id", q.Scopes),
+				terms("ancestors", q.Scopes),
+			},
+			"minimum_should_match": 1,
+		}},
+	}
+	if q.MimeType != "" {
+		filter = append(filter, map[string]interface{}{"prefix": map[string]string{"mime_type": q.MimeType}})
+	}
+	size := q.Limit
+	if size <= 0 {
+		size = defaultSize
+	}
+	body := map[string]interface{}{
+		"from":  q.Offset,
+		"size":  size,
+		"sort":  []interface{}{map[string]string{"name": "asc"}, map[string]string{"id": "asc"}},
+		"query": map[string]interface{}{"bool": map[string]interface{}{"filter": filter}},
+	}
+
+	res := struct {
+		Hits struct {
+			Hits []struct {
+				Source *search.Entry `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}{}
+	if _, err := i.do(ctx, http.MethodPost, "/_search", body, &res); err != nil {
+		return nil, err
+	}
+	entries := make([]*search.Entry, 0, len(res.Hits.Hits))
+	for _, h := range res.Hits.Hits {
+		entries = append(entries, h.Source)
+	}
+	return entries, nil
+}
+
+func (i *index) Close() error {
+	return nil
+}
+
+func term(field, value string) map[string]interface{} {
+	return map[string]interface{}{"term": map[string]string{field: value}}
+}
+
+func terms(field string, values []string) map[string]interface{} {
+	if values == nil {
+		values = []string{}
+	}
+	return map[string]interface{}{"terms": map[string][]string{field: values}}
+}
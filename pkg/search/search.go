@@ -0,0 +1,160 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package search defines the index of the metadata of the resources, which
+// lets the users find their files by name across their spaces and the shares
+// they received, and the interface of the drivers storing it.
+package search
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/utils/resourceid"
+)
+
+// The types of the indexed resources.
+const (
+	TypeFile      = "file"
+	TypeContainer = "container"
+)
+
+// Entry is the indexed metadata of a resource.
+type Entry struct {
+	// ID is the wrapped resource id, which is the key of the entry.
+	ID string `json:"id"`
+	// Owner is the opaque id of the owner of the space holding the resource.
+	Owner string `json:"owner"`
+	// Ancestors are the ids of the indexed folders holding the resource, from the root of the space.
+	Ancestors []string `json:"ancestors"`
+	Name      string   `json:"name"`
+	Path      string   `json:"path"`
+	Type      string   `json:"type"`
+	Size      uint64   `json:"size"`
+	Mtime     uint64   `json:"mtime"`
+	MimeType  string   `json:"mime_type"`
+	// IndexedAt is the time in seconds since the epoch the entry was last indexed at.
+	IndexedAt int64 `json:"indexed_at"`
+}
+
+// Query selects the entries the user is allowed to see whose metadata matches.
+type Query struct {
+	// Term is matched against the names ignoring the case. It may contain *
+	// wildcards, otherwise the names containing it match.
+	Term string
+	// MimeType is the prefix of the MIME types of the matching entries.
+	MimeType string
+	// Owner is the user whose entries are searched ...
+	Owner string
+	// ... together with the entries below these ids, i.e. the received shares.
+	Scopes []string
+	Limit  int
+	Offset int
+}
+
+// Index stores the entries.
+type Index interface {
+	// Index adds the entries, replacing the ones with the same id.
+	Index(ctx context.Context, entries ...*Entry) error
+	// Get returns the entry with the given id, or a NotFound error.
+	Get(ctx context.Context, id string) (*Entry, error)
+	// Delete removes the entry with the given id and the ones below it.
+	Delete(ctx context.Context, id string) error
+	// Prune removes the entries below the given id indexed before the given time.
+	Prune(ctx context.Context, id string, before int64) error
+	// Search returns the entries matching the query, ordered by name.
+	Search(ctx context.Context, q *Query) ([]*Entry, error)
+	// Close releases the resources of the index.
+	Close() error
+}
+
+// ID returns the key of the entry of the given resource.
+func ID(id *provider.ResourceId) string {
+	return resourceid.OwnCloudResourceIDWrap(id)
+}
+
+// ResourceID returns the resource id of the given entry id.
+func ResourceID(id string) *provider.ResourceId {
+	return resourceid.OwnCloudResourceIDUnwrap(id)
+}
+
+// Below returns whether the entry is one of the given ids or below one of them.
+func (e *Entry) Below(ids ...string) bool {
+	for _, id := range ids {
+		if e.ID == id {
+			return true
+		}
+		for _, a := range e.Ancestors {
+			if a == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Pattern returns the wildcard pattern of the term, matching the names
+// containing the term if it has no wildcard.
+func (q *Query) Pattern() string {
+	t := strings.ToLower(strings.TrimSpace(q.Term))
+	if !strings.Contains(t, "*") {
+		t = "*" + t + "*"
+	}
+	return t
+}
+
+// Matcher returns the function selecting the entries matching the query, for
+// the drivers filtering the entries themselves.
+func (q *Query) Matcher() func(*Entry) bool {
+	parts := strings.Split(q.Pattern(), "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	re := regexp.MustCompile("(?s)^" + strings.Join(parts, ".*") + "$")
+
+	return func(e *Entry) bool {
+		if e.Owner != q.Owner && !e.Below(q.Scopes...) {
+			return false
+		}
+		if !strings.HasPrefix(e.MimeType, q.MimeType) {
+			return false
+		}
+		return re.MatchString(strings.ToLower(e.Name))
+	}
+}
+
+// Page sorts the entries by name and returns the page selected by the query.
+func Page(entries []*Entry, q *Query) []*Entry {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	if q.Offset >= len(entries) {
+		return []*Entry{}
+	}
+	entries = entries[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(entries) {
+		entries = entries[:q.Limit]
+	}
+	return entries
+}
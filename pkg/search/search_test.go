@@ -0,0 +1,121 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package search
+
+import (
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	entries := map[string]*Entry{
+		"own":    {ID: "own", Owner: "einstein", Name: "Report 2021.PDF", MimeType: "application/pdf"},
+		"other":  {ID: "other", Owner: "marie", Name: "report.odt", MimeType: "application/vnd.oasis.opendocument.text"},
+		"shared": {ID: "shared", Owner: "marie", Ancestors: []string{"root", "share"}, Name: "reports.txt", MimeType: "text/plain"},
+		"share":  {ID: "share", Owner: "marie", Ancestors: []string{"root"}, Name: "Reporting", MimeType: "httpd/unix-directory"},
+	}
+
+	tests := map[string]struct {
+		query    *Query
+		expected []string
+	}{
+		"substring ignoring the case": {
+			query:    &Query{Term: "REPORT", Owner: "einstein", Scopes: []string{"share"}},
+			expected: []string{"own", "shared", "share"},
+		},
+		"wildcards": {
+			query:    &Query{Term: "report*.pdf", Owner: "einstein", Scopes: []string{"share"}},
+			expected: []string{"own"},
+		},
+		"wildcards anchored": {
+			query:    &Query{Term: "*s.txt", Owner: "einstein", Scopes: []string{"share"}},
+			expected: []string{"shared"},
+		},
+		"not shared": {
+			query:    &Query{Term: "report", Owner: "einstein"},
+			expected: []string{"own"},
+		},
+		"owner": {
+			query:    &Query{Term: "report", Owner: "marie"},
+			expected: []string{"other", "shared", "share"},
+		},
+		"mime type": {
+			query:    &Query{Term: "report", MimeType: "text/", Owner: "einstein", Scopes: []string{"share"}},
+			expected: []string{"shared"},
+		},
+		"regexp characters": {
+			query:    &Query{Term: "report.", Owner: "marie"},
+			expected: []string{"other"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			match := tt.query.Matcher()
+			matched := map[string]bool{}
+			for id, e := range entries {
+				if match(e) {
+					matched[id] = true
+				}
+			}
+			if len(matched) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, matched)
+			}
+			for _, id := range tt.expected {
+				if !matched[id] {
+					t.Fatalf("expected %v, got %v", tt.expected, matched)
+				}
+			}
+		})
+	}
+}
+
+func TestPage(t *testing.T) {
+	entries := func() []*Entry {
+		return []*Entry{
+			{ID: "3", Name: "c"},
+			{ID: "2", Name: "a"},
+			{ID: "1", Name: "a"},
+			{ID: "4", Name: "b"},
+		}
+	}
+
+	tests := map[string]struct {
+		query    *Query
+		expected []string
+	}{
+		"all":          {query: &Query{}, expected: []string{"1", "2", "4", "3"}},
+		"limit":        {query: &Query{Limit: 2}, expected: []string{"1", "2"}},
+		"offset":       {query: &Query{Offset: 1, Limit: 2}, expected: []string{"2", "4"}},
+		"past the end": {query: &Query{Offset: 4}, expected: []string{}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			page := Page(entries(), tt.query)
+			if len(page) != len(tt.expected) {
+				t.Fatalf("expected %v entries, got %d", tt.expected, len(page))
+			}
+			for i, e := range page {
+				if e.ID != tt.expected[i] {
+					t.Fatalf("expected %v, got %s at %d", tt.expected, e.ID, i)
+				}
+			}
+		})
+	}
+}
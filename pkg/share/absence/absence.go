@@ -0,0 +1,136 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package absence defines the preference of the users announcing their
+// absence, during which the shares they receive are answered automatically
+// and optionally delegated to a colleague.
+package absence
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+)
+
+const (
+	// PreferenceNamespace is the namespace of the preference holding the absence.
+	PreferenceNamespace = "core"
+	// PreferenceKey is the key of the preference holding the absence.
+	PreferenceKey = "absence"
+
+	// DateLayout is the layout of the first and last day of an absence.
+	DateLayout = "2006-01-02"
+)
+
+// Absence is the value of the preference, encoded in JSON.
+type Absence struct {
+	// From and Until are the first and the last day of the absence.
+	From  string `json:"from"`
+	Until string `json:"until"`
+	// Message is added to the replies sent to the sharers.
+	Message string `json:"message,omitempty"`
+	// Delegate is the colleague the received shares are passed on to.
+	Delegate *userpb.UserId `json:"delegate,omitempty"`
+}
+
+// Parse decodes and validates the value of the preference. An empty value
+// means that no absence is set and returns nil.
+func Parse(v string) (*Absence, error) {
+	if v == "" {
+		return nil, nil
+	}
+	a := &Absence{}
+	if err := json.Unmarshal([]byte(v), a); err != nil {
+		return nil, fmt.Errorf("absence: invalid preference: %w", err)
+	}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Validate checks that the absence covers a valid date range.
+func (a *Absence) Validate() error {
+	from, err := time.ParseInLocation(DateLayout, a.From, time.Local)
+	if err != nil {
+		return fmt.Errorf("absence: invalid first day %q", a.From)
+	}
+	until, err := time.ParseInLocation(DateLayout, a.Until, time.Local)
+	if err != nil {
+		return fmt.Errorf("absence: invalid last day %q", a.Until)
+	}
+	if until.Before(from) {
+		return fmt.Errorf("absence: last day %s is before first day %s", a.Until, a.From)
+	}
+	return nil
+}
+
+// Encode returns the value of the preference.
+func (a *Absence) Encode() (string, error) {
+	v, err := json.Marshal(a)
+	return string(v), err
+}
+
+// End returns the time the absence ends, at the end of its last day.
+func (a *Absence) End() time.Time {
+	until, _ := time.ParseInLocation(DateLayout, a.Until, time.Local)
+	return until.AddDate(0, 0, 1)
+}
+
+// Active checks whether the user is absent at the given time.
+func (a *Absence) Active(t time.Time) bool {
+	if a == nil {
+		return false
+	}
+	from, err := time.ParseInLocation(DateLayout, a.From, time.Local)
+	if err != nil {
+		return false
+	}
+	return !t.Before(from) && t.Before(a.End())
+}
+
+// Reply holds the data the templates of the automatic replies are executed with.
+type Reply struct {
+	// Grantee and Sharer are the display names of the absent user and of the sharer.
+	Grantee string
+	Sharer  string
+	// ResourceName is the name of the shared resource, if known.
+	ResourceName string
+	// Until is the last day of the absence.
+	Until string
+	// Message is the message of the absent user.
+	Message string
+	// Delegate is the display name of the colleague the share was passed on to, if any.
+	Delegate string
+}
+
+// Render executes the subject and body templates with the reply.
+func (r *Reply) Render(subject, body *template.Template) (string, string, error) {
+	var s, b bytes.Buffer
+	if err := subject.Execute(&s, r); err != nil {
+		return "", "", fmt.Errorf("absence: error executing subject template: %w", err)
+	}
+	if err := body.Execute(&b, r); err != nil {
+		return "", "", fmt.Errorf("absence: error executing body template: %w", err)
+	}
+	return s.String(), b.String(), nil
+}
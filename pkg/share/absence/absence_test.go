@@ -0,0 +1,96 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package absence
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	if a, err := Parse(""); err != nil || a != nil {
+		t.Fatalf("expected no absence for an empty preference, got %v, %v", a, err)
+	}
+
+	a, err := Parse(`{"from":"2022-08-01","until":"2022-08-14","message":"On holidays"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Message != "On holidays" || a.Delegate != nil {
+		t.Errorf("unexpected absence %+v", a)
+	}
+
+	for _, v := range []string{
+		`{"from":"2022-08-14","until":"2022-08-01"}`,
+		`{"from":"01/08/2022","until":"2022-08-14"}`,
+		`{"from":"2022-08-01"}`,
+		`not json`,
+	} {
+		if _, err := Parse(v); err == nil {
+			t.Errorf("expected %s to be rejected", v)
+		}
+	}
+}
+
+func TestActive(t *testing.T) {
+	a := &Absence{From: "2022-08-01", Until: "2022-08-14"}
+	day := func(d string, h int) time.Time {
+		t, _ := time.ParseInLocation(DateLayout, d, time.Local)
+		return t.Add(time.Duration(h) * time.Hour)
+	}
+
+	tests := []struct {
+		t        time.Time
+		expected bool
+	}{
+		{day("2022-07-31", 23), false},
+		{day("2022-08-01", 0), true},
+		{day("2022-08-14", 23), true},
+		{day("2022-08-15", 0), false},
+	}
+	for _, tt := range tests {
+		if active := a.Active(tt.t); active != tt.expected {
+			t.Errorf("Active(%s) = %v, expected %v", tt.t, active, tt.expected)
+		}
+	}
+
+	var none *Absence
+	if none.Active(time.Now()) {
+		t.Error("expected no absence not to be active")
+	}
+}
+
+func TestRender(t *testing.T) {
+	subject := template.Must(template.New("subject").Parse(`{{.Grantee}} is absent`))
+	body := template.Must(template.New("body").Parse(`Back after {{.Until}}.{{if .Delegate}} {{.Delegate}} received "{{.ResourceName}}".{{end}}`))
+
+	r := &Reply{Grantee: "Albert", Until: "2022-08-14", ResourceName: "thesis.tex", Delegate: "Marie"}
+	s, b, err := r.Render(subject, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "Albert is absent" {
+		t.Errorf("unexpected subject %q", s)
+	}
+	if !strings.Contains(b, `Marie received "thesis.tex"`) {
+		t.Errorf("unexpected body %q", b)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package autoaccept defines the preference of the users deciding which of
+// the shares they receive are accepted without their intervention.
+package autoaccept
+
+import (
+	"fmt"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+)
+
+const (
+	// PreferenceNamespace is the namespace of the preference holding the policy.
+	PreferenceNamespace = "core"
+	// PreferenceKey is the key of the preference holding the policy.
+	PreferenceKey = "auto_accept_shares"
+)
+
+// Policy decides which received shares are accepted automatically.
+type Policy string
+
+const (
+	// PolicyNone leaves all the shares pending.
+	PolicyNone Policy = "none"
+	// PolicyAll accepts all the shares.
+	PolicyAll Policy = "all"
+	// PolicyInstitution accepts the shares of the users of the same identity provider.
+	PolicyInstitution Policy = "institution"
+	// PolicyContacts accepts the shares of the contacts of the user.
+	PolicyContacts Policy = "contacts"
+)
+
+// ParsePolicy validates the value of the preference.
+func ParsePolicy(v string) (Policy, error) {
+	switch p := Policy(v); p {
+	case PolicyNone, PolicyAll, PolicyInstitution, PolicyContacts:
+		return p, nil
+	default:
+		return "", fmt.Errorf("autoaccept: invalid policy %q", v)
+	}
+}
+
+// Trusts checks whether the policy of the grantee accepts the shares of the
+// sharer. isContact is only called for the contacts policy, as finding the
+// contacts of a user is expensive.
+func (p Policy) Trusts(sharer, grantee *userpb.UserId, isContact func() (bool, error)) (bool, error) {
+	switch p {
+	case PolicyAll:
+		return true, nil
+	case PolicyInstitution:
+		return sharer.GetIdp() != "" && sharer.GetIdp() == grantee.GetIdp(), nil
+	case PolicyContacts:
+		return isContact()
+	default:
+		return false, nil
+	}
+}
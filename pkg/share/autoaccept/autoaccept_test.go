@@ -0,0 +1,75 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package autoaccept
+
+import (
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+)
+
+func TestTrusts(t *testing.T) {
+	einstein := &userpb.UserId{Idp: "https://cern.ch", OpaqueId: "einstein"}
+	marie := &userpb.UserId{Idp: "https://cern.ch", OpaqueId: "marie"}
+	richard := &userpb.UserId{Idp: "https://cesnet.cz", OpaqueId: "richard"}
+
+	called := false
+	contact := func(ok bool) func() (bool, error) {
+		return func() (bool, error) {
+			called = true
+			return ok, nil
+		}
+	}
+
+	tests := []struct {
+		policy   Policy
+		sharer   *userpb.UserId
+		contact  bool
+		expected bool
+	}{
+		{PolicyNone, marie, true, false},
+		{PolicyAll, richard, false, true},
+		{PolicyInstitution, marie, false, true},
+		{PolicyInstitution, richard, true, false},
+		{PolicyContacts, richard, true, true},
+		{PolicyContacts, marie, false, false},
+	}
+	for _, tt := range tests {
+		called = false
+		ok, err := tt.policy.Trusts(tt.sharer, einstein, contact(tt.contact))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != tt.expected {
+			t.Errorf("policy %s sharing from %s: expected %v", tt.policy, tt.sharer.OpaqueId, tt.expected)
+		}
+		if called != (tt.policy == PolicyContacts) {
+			t.Errorf("policy %s: contacts looked up: %v", tt.policy, called)
+		}
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	if p, err := ParsePolicy("contacts"); err != nil || p != PolicyContacts {
+		t.Fatalf("unexpected result %q, %v", p, err)
+	}
+	if _, err := ParsePolicy("friends"); err == nil {
+		t.Fatal("expected an error for an unknown policy")
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/share"
+)
+
+// Inconsistency describes a share which differs between two share managers.
+type Inconsistency struct {
+	Key     string
+	Message string
+}
+
+func (i Inconsistency) String() string {
+	return fmt.Sprintf("%s: %s", i.Key, i.Message)
+}
+
+// CheckConsistency compares the shares created by the user in the context in the primary
+// and secondary share managers, e.g. the oc_share table and the manager writes are mirrored to.
+// Shares are matched by their resource and grantee, as the IDs differ between the managers.
+func CheckConsistency(ctx context.Context, primary, secondary share.Manager) ([]Inconsistency, error) {
+	primaryShares, err := listSharesByKey(ctx, primary)
+	if err != nil {
+		return nil, err
+	}
+	secondaryShares, err := listSharesByKey(ctx, secondary)
+	if err != nil {
+		return nil, err
+	}
+
+	inconsistencies := []Inconsistency{}
+	for k, p := range primaryShares {
+		s, ok := secondaryShares[k]
+		if !ok {
+			inconsistencies = append(inconsistencies, Inconsistency{Key: k, Message: "missing in secondary"})
+			continue
+		}
+		if sharePermToInt(p.GetPermissions().GetPermissions()) != sharePermToInt(s.GetPermissions().GetPermissions()) {
+			inconsistencies = append(inconsistencies, Inconsistency{Key: k, Message: "permissions differ"})
+		}
+	}
+	for k := range secondaryShares {
+		if _, ok := primaryShares[k]; !ok {
+			inconsistencies = append(inconsistencies, Inconsistency{Key: k, Message: "missing in primary"})
+		}
+	}
+	return inconsistencies, nil
+}
+
+func listSharesByKey(ctx context.Context, m share.Manager) (map[string]*collaboration.Share, error) {
+	shares, err := m.ListShares(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]*collaboration.Share, len(shares))
+	for _, s := range shares {
+		byKey[consistencyKey(s)] = s
+	}
+	return byKey, nil
+}
+
+func consistencyKey(s *collaboration.Share) string {
+	var grantee string
+	switch s.GetGrantee().GetType() {
+	case provider.GranteeType_GRANTEE_TYPE_USER:
+		u := s.GetGrantee().GetUserId()
+		grantee = "user:" + u.GetOpaqueId()
+		if u.GetType() == userpb.UserType_USER_TYPE_FEDERATED {
+			grantee += "@" + u.GetIdp()
+		}
+	case provider.GranteeType_GRANTEE_TYPE_GROUP:
+		grantee = "group:" + s.GetGrantee().GetGroupId().GetOpaqueId()
+	}
+	return s.GetResourceId().GetOpaqueId() + "/" + grantee
+}
@@ -20,6 +20,8 @@ package sql
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
@@ -29,6 +31,7 @@ import (
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	conversions "github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
+	"github.com/cs3org/reva/pkg/kvcache"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 )
@@ -60,20 +63,31 @@ type UserConverter interface {
 	UserIDToUserName(ctx context.Context, userid *userpb.UserId) (string, error)
 }
 
+// userLookupTTL is the default time the lookups of the GatewayUserConverter are cached for.
+const userLookupTTL = 10 * time.Minute
+
 // GatewayUserConverter converts usernames and ids using the gateway
 type GatewayUserConverter struct {
 	gwAddr string
+	cache  *kvcache.Cache
 }
 
 // NewGatewayUserConverter returns a instance of GatewayUserConverter
 func NewGatewayUserConverter(gwAddr string) *GatewayUserConverter {
 	return &GatewayUserConverter{
 		gwAddr: gwAddr,
+		cache:  kvcache.Namespace("share_users", userLookupTTL),
 	}
 }
 
 // UserIDToUserName converts a user ID to an username
 func (c *GatewayUserConverter) UserIDToUserName(ctx context.Context, userid *userpb.UserId) (string, error) {
+	key := "id:" + userid.Idp + "!" + userid.OpaqueId
+	var username string
+	if c.cache.Get(key, &username) {
+		return username, nil
+	}
+
 	gwConn, err := pool.GetGatewayServiceClient(pool.Endpoint(c.gwAddr))
 	if err != nil {
 		return "", err
@@ -88,11 +102,18 @@ func (c *GatewayUserConverter) UserIDToUserName(ctx context.Context, userid *use
 	if getUserResponse.Status.Code != rpc.Code_CODE_OK {
 		return "", status.NewErrorFromCode(getUserResponse.Status.Code, "gateway")
 	}
+	_ = c.cache.Set(key, getUserResponse.User.Username)
 	return getUserResponse.User.Username, nil
 }
 
 // UserNameToUserID converts a username to an user ID
 func (c *GatewayUserConverter) UserNameToUserID(ctx context.Context, username string) (*userpb.UserId, error) {
+	key := "name:" + username
+	userid := &userpb.UserId{}
+	if c.cache.Get(key, userid) {
+		return userid, nil
+	}
+
 	gwConn, err := pool.GetGatewayServiceClient(pool.Endpoint(c.gwAddr))
 	if err != nil {
 		return nil, err
@@ -108,6 +129,7 @@ func (c *GatewayUserConverter) UserNameToUserID(ctx context.Context, username st
 	if getUserResponse.Status.Code != rpc.Code_CODE_OK {
 		return nil, status.NewErrorFromCode(getUserResponse.Status.Code, "gateway")
 	}
+	_ = c.cache.Set(key, getUserResponse.User.Id)
 	return getUserResponse.User.Id, nil
 }
 
@@ -116,6 +138,9 @@ func (m *mgr) formatGrantee(ctx context.Context, g *provider.Grantee) (int, stri
 	var formattedID string
 	switch g.Type {
 	case provider.GranteeType_GRANTEE_TYPE_USER:
+		if m.federatedShares && g.GetUserId().GetType() == userpb.UserType_USER_TYPE_FEDERATED {
+			return shareTypeFederated, formatFederatedUserID(g.GetUserId()), nil
+		}
 		granteeType = 0
 		var err error
 		formattedID, err = m.userConverter.UserIDToUserName(ctx, g.GetUserId())
@@ -144,6 +169,9 @@ func (m *mgr) extractGrantee(ctx context.Context, t int, g string) (*provider.Gr
 	case 1:
 		grantee.Type = provider.GranteeType_GRANTEE_TYPE_GROUP
 		grantee.Id = &provider.Grantee_GroupId{GroupId: extractGroupID(g)}
+	case shareTypeFederated:
+		grantee.Type = provider.GranteeType_GRANTEE_TYPE_USER
+		grantee.Id = &provider.Grantee_UserId{UserId: extractFederatedUserID(g)}
 	default:
 		grantee.Type = provider.GranteeType_GRANTEE_TYPE_INVALID
 	}
@@ -195,6 +223,22 @@ func formatUserID(u *userpb.UserId) string {
 	return u.OpaqueId
 }
 
+// formatFederatedUserID formats the ID of a remote user the way ownCloud 10 stores it, i.e. user@remote
+func formatFederatedUserID(u *userpb.UserId) string {
+	return u.OpaqueId + "@" + u.Idp
+}
+
+func extractFederatedUserID(u string) *userpb.UserId {
+	id := &userpb.UserId{
+		OpaqueId: u,
+		Type:     userpb.UserType_USER_TYPE_FEDERATED,
+	}
+	if i := strings.LastIndex(u, "@"); i > 0 {
+		id.OpaqueId, id.Idp = u[:i], u[i+1:]
+	}
+	return id
+}
+
 func formatGroupID(u *grouppb.GroupId) string {
 	return u.OpaqueId
 }
@@ -0,0 +1,102 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/cs3org/reva/pkg/share/manager/registry"
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/protobuf/field_mask"
+)
+
+// newSecondary creates the share manager all writes are mirrored to.
+func newSecondary(driver string, c map[string]interface{}) (share.Manager, error) {
+	if driver == "oc10-sql" {
+		return nil, errors.New("sql: the dual write driver must differ from oc10-sql")
+	}
+	f, ok := registry.NewFuncs[driver]
+	if !ok {
+		return nil, errors.Errorf("sql: dual write driver not found: %s", driver)
+	}
+	return f(c)
+}
+
+// mirrorRef translates the given reference into one understood by the secondary manager.
+// As share IDs differ between the two, references by ID are resolved to the share key.
+// It returns nil if dual writes are disabled or the share can't be resolved.
+func (m *mgr) mirrorRef(ctx context.Context, ref *collaboration.ShareReference) *collaboration.ShareReference {
+	if m.secondary == nil {
+		return nil
+	}
+	if ref.GetKey() != nil {
+		return ref
+	}
+
+	s, err := m.GetShare(ctx, ref)
+	if err != nil {
+		m.logMirrorError(ctx, err, "resolve share reference")
+		return nil
+	}
+	return &collaboration.ShareReference{
+		Spec: &collaboration.ShareReference_Key{
+			Key: &collaboration.ShareKey{
+				Owner:      s.Owner,
+				ResourceId: s.ResourceId,
+				Grantee:    s.Grantee,
+			},
+		},
+	}
+}
+
+// mirrorReceivedShareUpdate applies the update of a received share to the secondary manager.
+func (m *mgr) mirrorReceivedShareUpdate(ctx context.Context, rs *collaboration.ReceivedShare, fieldMask *field_mask.FieldMask) {
+	if m.secondary == nil {
+		return
+	}
+
+	mirrored, err := m.secondary.GetReceivedShare(ctx, &collaboration.ShareReference{
+		Spec: &collaboration.ShareReference_Key{
+			Key: &collaboration.ShareKey{
+				Owner:      rs.Share.Owner,
+				ResourceId: rs.Share.ResourceId,
+				Grantee:    rs.Share.Grantee,
+			},
+		},
+	})
+	if err != nil {
+		m.logMirrorError(ctx, err, "get received share")
+		return
+	}
+
+	mirrored.State = rs.State
+	if _, err := m.secondary.UpdateReceivedShare(ctx, mirrored, fieldMask); err != nil {
+		m.logMirrorError(ctx, err, "update received share")
+	}
+}
+
+// logMirrorError logs failed dual writes. They don't fail the request, as the
+// oc_share table remains the source of truth; the inconsistencies can be
+// detected afterwards using CheckConsistency.
+func (m *mgr) logMirrorError(ctx context.Context, err error, op string) {
+	appctx.GetLogger(ctx).Error().Err(err).Str("operation", op).Msg("sql: error mirroring write to the dual write share manager")
+}
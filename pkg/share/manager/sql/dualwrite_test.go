@@ -0,0 +1,248 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/cs3org/reva/pkg/share/manager/sql/mocks"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/genproto/protobuf/field_mask"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// mirror records the writes mirrored to the dual write share manager.
+type mirror struct {
+	share.Manager
+	shared   []*collaboration.ShareGrant
+	unshared []*collaboration.ShareReference
+	updated  []*collaboration.ShareReference
+	received []*collaboration.ReceivedShare
+	fail     bool
+}
+
+func (m *mirror) Share(ctx context.Context, md *provider.ResourceInfo, g *collaboration.ShareGrant) (*collaboration.Share, error) {
+	if m.fail {
+		return nil, errtypes.InternalError("mirror unavailable")
+	}
+	m.shared = append(m.shared, g)
+	return &collaboration.Share{}, nil
+}
+
+func (m *mirror) Unshare(ctx context.Context, ref *collaboration.ShareReference) error {
+	m.unshared = append(m.unshared, ref)
+	return nil
+}
+
+func (m *mirror) UpdateShare(ctx context.Context, ref *collaboration.ShareReference, p *collaboration.SharePermissions) (*collaboration.Share, error) {
+	m.updated = append(m.updated, ref)
+	return &collaboration.Share{}, nil
+}
+
+func (m *mirror) GetReceivedShare(ctx context.Context, ref *collaboration.ShareReference) (*collaboration.ReceivedShare, error) {
+	return &collaboration.ReceivedShare{Share: &collaboration.Share{ResourceId: ref.GetKey().GetResourceId()}}, nil
+}
+
+func (m *mirror) UpdateReceivedShare(ctx context.Context, rs *collaboration.ReceivedShare, fieldMask *field_mask.FieldMask) (*collaboration.ReceivedShare, error) {
+	m.received = append(m.received, rs)
+	return rs, nil
+}
+
+var _ = Describe("oc10-sql federated shares and dual writes", func() {
+	var (
+		m          *mgr
+		secondary  *mirror
+		ctx        context.Context
+		testDbFile *os.File
+
+		admin = &userpb.User{
+			Id:       &userpb.UserId{Idp: "idp", OpaqueId: "admin", Type: userpb.UserType_USER_TYPE_PRIMARY},
+			Username: "admin",
+		}
+		einstein = &userpb.User{
+			Id:       &userpb.UserId{Idp: "idp", OpaqueId: "einstein", Type: userpb.UserType_USER_TYPE_PRIMARY},
+			Username: "einstein",
+		}
+		remoteGrant = &collaboration.ShareGrant{
+			Grantee: &provider.Grantee{
+				Type: provider.GranteeType_GRANTEE_TYPE_USER,
+				Id: &provider.Grantee_UserId{UserId: &userpb.UserId{
+					Idp:      "cloud.example.org",
+					OpaqueId: "marie",
+					Type:     userpb.UserType_USER_TYPE_FEDERATED,
+				}},
+			},
+			Permissions: &collaboration.SharePermissions{Permissions: &provider.ResourcePermissions{Stat: true}},
+		}
+		remoteInfo = &provider.ResourceInfo{
+			Id:    &provider.ResourceId{StorageId: "/", OpaqueId: "20"},
+			Owner: admin.Id,
+			Path:  "/remote",
+		}
+		shareRef = &collaboration.ShareReference{Spec: &collaboration.ShareReference_Id{
+			Id: &collaboration.ShareId{OpaqueId: "1"},
+		}}
+	)
+
+	BeforeEach(func() {
+		var err error
+		testDbFile, err = ioutil.TempFile("", "example")
+		Expect(err).ToNot(HaveOccurred())
+		dbData, err := ioutil.ReadFile("test.db")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = testDbFile.Write(dbData)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(testDbFile.Close()).To(Succeed())
+
+		db, err := sql.Open("sqlite3", testDbFile.Name())
+		Expect(err).ToNot(HaveOccurred())
+
+		userConverter := &mocks.UserConverter{}
+		userConverter.On("UserIDToUserName", mock.Anything, mock.Anything).Return(
+			func(_ context.Context, id *userpb.UserId) string { return id.OpaqueId },
+			func(_ context.Context, id *userpb.UserId) error { return nil })
+		userConverter.On("UserNameToUserID", mock.Anything, mock.Anything).Return(
+			func(_ context.Context, username string) *userpb.UserId { return &userpb.UserId{OpaqueId: username} },
+			func(_ context.Context, username string) error { return nil })
+
+		secondary = &mirror{}
+		m = &mgr{
+			driver:          "sqlite3",
+			db:              db,
+			storageMountID:  "abcde",
+			userConverter:   userConverter,
+			federatedShares: true,
+			secondary:       secondary,
+		}
+		ctx = ctxpkg.ContextSetUser(context.Background(), admin)
+	})
+
+	AfterEach(func() {
+		os.Remove(testDbFile.Name())
+	})
+
+	Describe("federated shares", func() {
+		BeforeEach(func() {
+			_, err := m.Share(ctx, remoteInfo, remoteGrant)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("stores the remote user the way ownCloud 10 does", func() {
+			var shareType int
+			var shareWith string
+			Expect(m.db.QueryRow("SELECT share_type, share_with FROM oc_share WHERE item_source='20'").Scan(&shareType, &shareWith)).To(Succeed())
+			Expect(shareType).To(Equal(shareTypeFederated))
+			Expect(shareWith).To(Equal("marie@cloud.example.org"))
+		})
+
+		It("lists the federated shares", func() {
+			shares, err := m.ListShares(ctx, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(shares).To(HaveLen(2))
+			remote := shares[1].Grantee.GetUserId()
+			Expect(remote.Type).To(Equal(userpb.UserType_USER_TYPE_FEDERATED))
+			Expect(remote.Idp).To(Equal("cloud.example.org"))
+			Expect(remote.OpaqueId).To(Equal("marie"))
+		})
+
+		It("applies the filters to the federated shares", func() {
+			shares, err := m.ListShares(ctx, []*collaboration.Filter{share.UserGranteeFilter()})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(shares).To(HaveLen(2))
+
+			shares, err = m.ListShares(ctx, []*collaboration.Filter{share.GroupGranteeFilter()})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(shares).To(BeEmpty())
+
+			shares, err = m.ListShares(ctx, []*collaboration.Filter{share.ResourceIDFilter(remoteInfo.Id)})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(shares).To(HaveLen(1))
+			Expect(shares[0].ResourceId.OpaqueId).To(Equal("20"))
+		})
+
+		It("skips the federated shares unless enabled", func() {
+			m.federatedShares = false
+			shares, err := m.ListShares(ctx, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(shares).To(HaveLen(1))
+
+			shares, err = m.ListShares(ctx, []*collaboration.Filter{share.UserGranteeFilter()})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(shares).To(HaveLen(1))
+
+			shares, err = m.ListShares(ctx, []*collaboration.Filter{share.ResourceIDFilter(remoteInfo.Id)})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(shares).To(BeEmpty())
+
+			ids, err := m.ListSharedResources(ctx, []*provider.ResourceId{remoteInfo.Id})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ids).To(BeEmpty())
+		})
+	})
+
+	Describe("dual writes", func() {
+		It("mirrors the new shares", func() {
+			_, err := m.Share(ctx, remoteInfo, remoteGrant)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secondary.shared).To(HaveLen(1))
+		})
+
+		It("does not fail the request if the mirror fails", func() {
+			secondary.fail = true
+			s, err := m.Share(ctx, remoteInfo, remoteGrant)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s).ToNot(BeNil())
+		})
+
+		It("mirrors the updates and deletions by key", func() {
+			_, err := m.UpdateShare(ctx, shareRef, &collaboration.SharePermissions{Permissions: &provider.ResourcePermissions{Stat: true}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secondary.updated).To(HaveLen(1))
+			Expect(secondary.updated[0].GetKey().GetResourceId().GetOpaqueId()).To(Equal("14"))
+
+			Expect(m.Unshare(ctx, shareRef)).To(Succeed())
+			Expect(secondary.unshared).To(HaveLen(1))
+			Expect(secondary.unshared[0].GetKey().GetResourceId().GetOpaqueId()).To(Equal("14"))
+		})
+
+		It("mirrors the state of the received shares", func() {
+			ctx = ctxpkg.ContextSetUser(context.Background(), einstein)
+			rs, err := m.GetReceivedShare(ctx, shareRef)
+			Expect(err).ToNot(HaveOccurred())
+			rs.State = collaboration.ShareState_SHARE_STATE_ACCEPTED
+			_, err = m.UpdateReceivedShare(ctx, rs, &field_mask.FieldMask{Paths: []string{"state"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secondary.received).To(HaveLen(1))
+			Expect(secondary.received[0].State).To(Equal(collaboration.ShareState_SHARE_STATE_ACCEPTED))
+		})
+	})
+})
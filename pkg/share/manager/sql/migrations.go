@@ -0,0 +1,67 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import "github.com/cs3org/reva/pkg/migrate"
+
+// Schema is the migration component of the ownCloud share tables used by the oc10-sql share manager.
+const Schema = "owncloud_shares"
+
+func init() {
+	// the tables belong to the ownCloud database, so they are only created when missing
+	// and they are left in place when the migration is reverted. The received shares
+	// are joined with the storages of the ownCloud file cache.
+	migrate.Register(Schema, migrate.Migration{
+		Version:     1,
+		Description: "create the share and storage tables",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS oc_share (
+				id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				share_type SMALLINT NOT NULL DEFAULT 0,
+				share_with VARCHAR(255) DEFAULT NULL,
+				uid_owner VARCHAR(64) NOT NULL DEFAULT '',
+				uid_initiator VARCHAR(64) DEFAULT NULL,
+				parent BIGINT DEFAULT NULL,
+				item_type VARCHAR(64) NOT NULL DEFAULT '',
+				item_source VARCHAR(255) DEFAULT NULL,
+				item_target VARCHAR(255) DEFAULT NULL,
+				file_source BIGINT DEFAULT NULL,
+				file_target VARCHAR(512) DEFAULT NULL,
+				permissions SMALLINT NOT NULL DEFAULT 0,
+				stime BIGINT NOT NULL DEFAULT 0,
+				accepted SMALLINT NOT NULL DEFAULT 0,
+				expiration DATETIME DEFAULT NULL,
+				token VARCHAR(32) DEFAULT NULL,
+				mail_send SMALLINT NOT NULL DEFAULT 0,
+				share_name VARCHAR(64) DEFAULT NULL,
+				attributes LONGTEXT DEFAULT NULL,
+				INDEX item_share_type_index (item_type, share_type),
+				INDEX file_source_index (file_source),
+				INDEX token_index (token),
+				INDEX share_with_index (share_with)
+			)`,
+			`CREATE TABLE IF NOT EXISTS oc_storages (
+				numeric_id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				id VARCHAR(64) DEFAULT NULL UNIQUE,
+				available INT NOT NULL DEFAULT 1,
+				last_checked INT DEFAULT NULL
+			)`,
+		},
+	})
+}
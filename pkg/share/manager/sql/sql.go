@@ -32,6 +32,7 @@ import (
 	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/migrate"
 	"github.com/cs3org/reva/pkg/share"
 	"github.com/cs3org/reva/pkg/share/manager/registry"
 	"github.com/cs3org/reva/pkg/utils"
@@ -44,8 +45,9 @@ import (
 )
 
 const (
-	shareTypeUser  = 0
-	shareTypeGroup = 1
+	shareTypeUser      = 0
+	shareTypeGroup     = 1
+	shareTypeFederated = 6
 )
 
 func init() {
@@ -60,13 +62,22 @@ type config struct {
 	DbHost         string `mapstructure:"db_host"`
 	DbPort         int    `mapstructure:"db_port"`
 	DbName         string `mapstructure:"db_name"`
+	// FederatedShares enables reading and writing the federated share rows of the oc_share table.
+	FederatedShares bool `mapstructure:"federated_shares"`
+	// DualWriteDriver is the share manager every write is mirrored to, e.g. while migrating to another schema.
+	DualWriteDriver  string                            `mapstructure:"dual_write_driver"`
+	DualWriteDrivers map[string]map[string]interface{} `mapstructure:"dual_write_drivers"`
+	// SkipMigrations disables applying the pending schema migrations on startup.
+	SkipMigrations bool `mapstructure:"skip_migrations"`
 }
 
 type mgr struct {
-	driver         string
-	db             *sql.DB
-	storageMountID string
-	userConverter  UserConverter
+	driver          string
+	db              *sql.DB
+	storageMountID  string
+	userConverter   UserConverter
+	federatedShares bool
+	secondary       share.Manager
 }
 
 // NewMysql returns a new share manager connection to a mysql database
@@ -82,9 +93,29 @@ func NewMysql(m map[string]interface{}) (share.Manager, error) {
 		return nil, err
 	}
 
+	if !c.SkipMigrations {
+		if err := migrate.Up(context.Background(), db, Schema); err != nil {
+			return nil, err
+		}
+	}
+
 	userConverter := NewGatewayUserConverter(c.GatewayAddr)
 
-	return New("mysql", db, c.StorageMountID, userConverter)
+	var secondary share.Manager
+	if c.DualWriteDriver != "" {
+		if secondary, err = newSecondary(c.DualWriteDriver, c.DualWriteDrivers[c.DualWriteDriver]); err != nil {
+			return nil, err
+		}
+	}
+
+	return &mgr{
+		driver:          "mysql",
+		db:              db,
+		storageMountID:  c.StorageMountID,
+		userConverter:   userConverter,
+		federatedShares: c.FederatedShares,
+		secondary:       secondary,
+	}, nil
 }
 
 // New returns a new Cache instance connecting to the given sql.DB
@@ -168,6 +199,12 @@ func (m *mgr) Share(ctx context.Context, md *provider.ResourceInfo, g *collabora
 		return nil, err
 	}
 
+	if m.secondary != nil {
+		if _, err := m.secondary.Share(ctx, md, g); err != nil {
+			m.logMirrorError(ctx, err, "share")
+		}
+	}
+
 	return &collaboration.Share{
 		Id: &collaboration.ShareId{
 			OpaqueId: strconv.FormatInt(lastID, 10),
@@ -203,6 +240,7 @@ func (m *mgr) GetShare(ctx context.Context, ref *collaboration.ShareReference) (
 
 func (m *mgr) Unshare(ctx context.Context, ref *collaboration.ShareReference) error {
 	uid := ctxpkg.ContextMustGetUser(ctx).Username
+	mirrorRef := m.mirrorRef(ctx, ref)
 	var query string
 	params := []interface{}{}
 	switch {
@@ -238,6 +276,12 @@ func (m *mgr) Unshare(ctx context.Context, ref *collaboration.ShareReference) er
 	if rowCnt == 0 {
 		return errtypes.NotFound(ref.String())
 	}
+
+	if mirrorRef != nil {
+		if err := m.secondary.Unshare(ctx, mirrorRef); err != nil {
+			m.logMirrorError(ctx, err, "unshare")
+		}
+	}
 	return nil
 }
 
@@ -272,6 +316,12 @@ func (m *mgr) UpdateShare(ctx context.Context, ref *collaboration.ShareReference
 		return nil, err
 	}
 
+	if mirrorRef := m.mirrorRef(ctx, ref); mirrorRef != nil {
+		if _, err := m.secondary.UpdateShare(ctx, mirrorRef, p); err != nil {
+			m.logMirrorError(ctx, err, "update share")
+		}
+	}
+
 	return m.GetShare(ctx, ref)
 }
 
@@ -280,22 +330,15 @@ func (m *mgr) ListShares(ctx context.Context, filters []*collaboration.Filter) (
 	query := "select coalesce(uid_owner, '') as uid_owner, coalesce(uid_initiator, '') as uid_initiator, coalesce(share_with, '') as share_with, coalesce(item_source, '') as item_source, id, stime, permissions, share_type FROM oc_share WHERE (uid_owner=? or uid_initiator=?)"
 	params := []interface{}{uid, uid}
 
-	var (
-		filterQuery  string
-		filterParams []interface{}
-		err          error
-	)
-	if len(filters) == 0 {
-		filterQuery += "(share_type=? OR share_type=?)"
-		params = append(params, shareTypeUser)
-		params = append(params, shareTypeGroup)
-	} else {
-		filterQuery, filterParams, err = translateFilters(filters)
-		if err != nil {
-			return nil, err
-		}
-		params = append(params, filterParams...)
+	shareTypes := m.shareTypes()
+	query += " AND (share_type=?" + strings.Repeat(" OR share_type=?", len(shareTypes)-1) + ")"
+	params = append(params, shareTypes...)
+
+	filterQuery, filterParams, err := m.translateFilters(filters)
+	if err != nil {
+		return nil, err
 	}
+	params = append(params, filterParams...)
 
 	if filterQuery != "" {
 		query = fmt.Sprintf("%s AND (%s)", query, filterQuery)
@@ -326,6 +369,48 @@ func (m *mgr) ListShares(ctx context.Context, filters []*collaboration.Filter) (
 	return shares, nil
 }
 
+// ListSharedResources returns the ids of the given resources shared by the
+// user. Only the item sources are read, sparing the conversion of the shares.
+func (m *mgr) ListSharedResources(ctx context.Context, ids []*provider.ResourceId) ([]*provider.ResourceId, error) {
+	if len(ids) == 0 {
+		return []*provider.ResourceId{}, nil
+	}
+	uid := ctxpkg.ContextMustGetUser(ctx).Username
+	shareTypes := m.shareTypes()
+	query := "SELECT DISTINCT coalesce(item_source, '') as item_source FROM oc_share WHERE (uid_owner=? or uid_initiator=?) AND (share_type=?" + strings.Repeat(" OR share_type=?", len(shareTypes)-1) + ")"
+	params := append([]interface{}{uid, uid}, shareTypes...)
+	query += " AND item_source IN (?" + strings.Repeat(",?", len(ids)-1) + ")"
+	for _, id := range ids {
+		params = append(params, id.OpaqueId)
+	}
+
+	rows, err := m.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sources := map[string]struct{}{}
+	for rows.Next() {
+		var source string
+		if err := rows.Scan(&source); err != nil {
+			continue
+		}
+		sources[source] = struct{}{}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	shared := []*provider.ResourceId{}
+	for _, id := range ids {
+		if _, ok := sources[id.OpaqueId]; ok {
+			shared = append(shared, id)
+		}
+	}
+	return shared, nil
+}
+
 // we list the shares that are targeted to the user in context or to the user groups.
 func (m *mgr) ListReceivedShares(ctx context.Context, filters []*collaboration.Filter) ([]*collaboration.ReceivedShare, error) {
 	user := ctxpkg.ContextMustGetUser(ctx)
@@ -349,7 +434,7 @@ func (m *mgr) ListReceivedShares(ctx context.Context, filters []*collaboration.F
 		query += "AND (share_with=?)"
 	}
 
-	filterQuery, filterParams, err := translateFilters(filters)
+	filterQuery, filterParams, err := m.translateFilters(filters)
 	if err != nil {
 		return nil, err
 	}
@@ -437,6 +522,7 @@ func (m *mgr) UpdateReceivedShare(ctx context.Context, share *collaboration.Rece
 		if err != nil {
 			return nil, err
 		}
+		m.mirrorReceivedShareUpdate(ctx, rs, fieldMask)
 	}
 
 	return rs, nil
@@ -532,18 +618,32 @@ func (m *mgr) getReceivedByKey(ctx context.Context, key *collaboration.ShareKey)
 	return m.convertToCS3ReceivedShare(ctx, s, m.storageMountID)
 }
 
-func granteeTypeToShareType(granteeType provider.GranteeType) int {
+// shareTypes returns the types of the rows of the oc_share table listed as shares.
+func (m *mgr) shareTypes() []interface{} {
+	if m.federatedShares {
+		return []interface{}{shareTypeUser, shareTypeGroup, shareTypeFederated}
+	}
+	return []interface{}{shareTypeUser, shareTypeGroup}
+}
+
+// granteeTypeToShareTypes returns the types of the rows of the oc_share table
+// holding shares with the given type of grantee. Remote users are stored in
+// rows of their own type.
+func (m *mgr) granteeTypeToShareTypes(granteeType provider.GranteeType) []interface{} {
 	switch granteeType {
 	case provider.GranteeType_GRANTEE_TYPE_USER:
-		return shareTypeUser
+		if m.federatedShares {
+			return []interface{}{shareTypeUser, shareTypeFederated}
+		}
+		return []interface{}{shareTypeUser}
 	case provider.GranteeType_GRANTEE_TYPE_GROUP:
-		return shareTypeGroup
+		return []interface{}{shareTypeGroup}
 	}
-	return -1
+	return []interface{}{-1}
 }
 
 // translateFilters translates the filters to sql queries
-func translateFilters(filters []*collaboration.Filter) (string, []interface{}, error) {
+func (m *mgr) translateFilters(filters []*collaboration.Filter) (string, []interface{}, error) {
 	var (
 		filterQuery string
 		params      []interface{}
@@ -571,8 +671,9 @@ func translateFilters(filters []*collaboration.Filter) (string, []interface{}, e
 		case collaboration.Filter_TYPE_GRANTEE_TYPE:
 			filterQuery += "("
 			for i, f := range filters {
-				filterQuery += "share_type=?"
-				params = append(params, granteeTypeToShareType(f.GetGranteeType()))
+				shareTypes := m.granteeTypeToShareTypes(f.GetGranteeType())
+				filterQuery += "share_type=?" + strings.Repeat(" OR share_type=?", len(shareTypes)-1)
+				params = append(params, shareTypes...)
 
 				if i != len(filters)-1 {
 					filterQuery += " OR "
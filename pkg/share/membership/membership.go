@@ -0,0 +1,190 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package membership keeps track of the groups of the users receiving shares,
+// so that the changes of the group memberships in the user provider take
+// effect on the group shares within a bounded interval instead of when the
+// tokens carrying the groups of the users expire.
+package membership
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+)
+
+// idleTimeout is the time after which the users who have not been seen are
+// no longer reconciled. Their groups are fetched again on their next request.
+const idleTimeout = 24 * time.Hour
+
+// FetchFunc returns the current groups of a user from the user provider.
+type FetchFunc func(ctx context.Context, uid *userpb.UserId) ([]string, error)
+
+// ChangeFunc is called with the groups a user has joined and left.
+type ChangeFunc func(uid *userpb.UserId, added, removed []string)
+
+type entry struct {
+	id       *userpb.UserId
+	groups   []string
+	fetched  time.Time
+	lastSeen time.Time
+}
+
+// Resolver returns the groups of the users, fetched at most interval ago.
+type Resolver struct {
+	interval time.Duration
+	fetch    FetchFunc
+	onChange ChangeFunc
+
+	mutex   sync.Mutex
+	entries map[string]*entry
+	now     func() time.Time
+}
+
+// NewResolver returns a resolver refreshing the groups of the users every
+// interval. onChange may be nil.
+func NewResolver(interval time.Duration, fetch FetchFunc, onChange ChangeFunc) *Resolver {
+	return &Resolver{
+		interval: interval,
+		fetch:    fetch,
+		onChange: onChange,
+		entries:  map[string]*entry{},
+		now:      time.Now,
+	}
+}
+
+func key(uid *userpb.UserId) string {
+	return uid.GetIdp() + "!" + uid.GetOpaqueId()
+}
+
+// Groups returns the current groups of the user. The groups the user came
+// with, e.g. the ones in the token, are returned if the user provider can't
+// be reached.
+func (r *Resolver) Groups(ctx context.Context, u *userpb.User) []string {
+	now := r.now()
+	k := key(u.Id)
+
+	r.mutex.Lock()
+	fallback := u.Groups
+	if e, ok := r.entries[k]; ok {
+		e.lastSeen = now
+		if now.Sub(e.fetched) < r.interval {
+			groups := e.groups
+			r.mutex.Unlock()
+			return groups
+		}
+		fallback = e.groups
+	}
+	r.mutex.Unlock()
+
+	groups, err := r.fetch(ctx, u.Id)
+	if err != nil {
+		appctx.GetLogger(ctx).Warn().Err(err).Str("user", u.Id.GetOpaqueId()).Msg("membership: error fetching the groups of the user")
+		return fallback
+	}
+	r.update(u.Id, groups, now)
+	return groups
+}
+
+// update stores the groups of a user and reports the changes to the ones
+// known before.
+func (r *Resolver) update(uid *userpb.UserId, groups []string, now time.Time) {
+	r.mutex.Lock()
+	e, ok := r.entries[key(uid)]
+	if !ok {
+		// the first fetch is the reference, there is nothing to compare it to
+		r.entries[key(uid)] = &entry{id: uid, groups: groups, fetched: now, lastSeen: now}
+		r.mutex.Unlock()
+		return
+	}
+	added, removed := Diff(e.groups, groups)
+	e.groups, e.fetched = groups, now
+	r.mutex.Unlock()
+
+	if r.onChange != nil && (len(added) > 0 || len(removed) > 0) {
+		r.onChange(uid, added, removed)
+	}
+}
+
+// Reconcile fetches the groups of all the users seen recently, so that the
+// changes are reported even when the users are not active, and forgets the
+// others.
+func (r *Resolver) Reconcile(ctx context.Context) {
+	now := r.now()
+	r.mutex.Lock()
+	ids := make([]*userpb.UserId, 0, len(r.entries))
+	for k, e := range r.entries {
+		if now.Sub(e.lastSeen) > idleTimeout {
+			delete(r.entries, k)
+			continue
+		}
+		ids = append(ids, e.id)
+	}
+	r.mutex.Unlock()
+
+	for _, uid := range ids {
+		groups, err := r.fetch(ctx, uid)
+		if err != nil {
+			appctx.GetLogger(ctx).Warn().Err(err).Str("user", uid.GetOpaqueId()).Msg("membership: error reconciling the groups of the user")
+			continue
+		}
+		r.update(uid, groups, now)
+	}
+}
+
+// Run reconciles the groups every interval until the context is done.
+func (r *Resolver) Run(ctx context.Context) {
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			r.Reconcile(ctx)
+		}
+	}
+}
+
+// Diff returns the groups which are only in updated and the ones which are
+// only in previous, both sorted.
+func Diff(previous, updated []string) (added, removed []string) {
+	old := make(map[string]bool, len(previous))
+	for _, g := range previous {
+		old[g] = true
+	}
+	current := make(map[string]bool, len(updated))
+	for _, g := range updated {
+		current[g] = true
+		if !old[g] {
+			added = append(added, g)
+		}
+	}
+	for _, g := range previous {
+		if !current[g] {
+			removed = append(removed, g)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
@@ -0,0 +1,109 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package membership
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+)
+
+type change struct {
+	added, removed []string
+}
+
+func TestResolver(t *testing.T) {
+	ctx := context.Background()
+	u := &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "einstein"}, Groups: []string{"token-group"}}
+
+	current := []string{"physics"}
+	var fetchErr error
+	fetches := 0
+	fetch := func(ctx context.Context, uid *userpb.UserId) ([]string, error) {
+		fetches++
+		return current, fetchErr
+	}
+	var changes []change
+	r := NewResolver(time.Minute, fetch, func(uid *userpb.UserId, added, removed []string) {
+		changes = append(changes, change{added, removed})
+	})
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+
+	if groups := r.Groups(ctx, u); !reflect.DeepEqual(groups, []string{"physics"}) {
+		t.Errorf("expected the groups of the user provider, got %v", groups)
+	}
+
+	// within the interval the groups are not fetched again
+	current = []string{"physics", "sailing"}
+	if groups := r.Groups(ctx, u); !reflect.DeepEqual(groups, []string{"physics"}) || fetches != 1 {
+		t.Errorf("expected the cached groups, got %v after %d fetches", groups, fetches)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if groups := r.Groups(ctx, u); !reflect.DeepEqual(groups, []string{"physics", "sailing"}) {
+		t.Errorf("expected the refreshed groups, got %v", groups)
+	}
+	if len(changes) != 1 || !reflect.DeepEqual(changes[0], change{[]string{"sailing"}, nil}) {
+		t.Errorf("expected the new group to be reported, got %v", changes)
+	}
+
+	// the changes are reported by the reconciliation without requests of the user
+	current = []string{"sailing"}
+	r.Reconcile(ctx)
+	if len(changes) != 2 || !reflect.DeepEqual(changes[1], change{nil, []string{"physics"}}) {
+		t.Errorf("expected the removed group to be reported, got %v", changes)
+	}
+
+	// the last known groups are used while the user provider is unreachable
+	now = now.Add(2 * time.Minute)
+	fetchErr = errors.New("unreachable")
+	if groups := r.Groups(ctx, u); !reflect.DeepEqual(groups, []string{"sailing"}) {
+		t.Errorf("expected the last known groups, got %v", groups)
+	}
+
+	// idle users are forgotten
+	now = now.Add(idleTimeout + time.Minute)
+	fetchErr = nil
+	r.Reconcile(ctx)
+	if len(r.entries) != 0 {
+		t.Errorf("expected the idle user to be forgotten")
+	}
+}
+
+func TestResolverFallback(t *testing.T) {
+	u := &userpb.User{Id: &userpb.UserId{OpaqueId: "marie"}, Groups: []string{"token-group"}}
+	r := NewResolver(time.Minute, func(ctx context.Context, uid *userpb.UserId) ([]string, error) {
+		return nil, errors.New("unreachable")
+	}, nil)
+	if groups := r.Groups(context.Background(), u); !reflect.DeepEqual(groups, []string{"token-group"}) {
+		t.Errorf("expected the groups of the token, got %v", groups)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	added, removed := Diff([]string{"a", "b", "c"}, []string{"d", "b", "a"})
+	if !reflect.DeepEqual(added, []string{"d"}) || !reflect.DeepEqual(removed, []string{"c"}) {
+		t.Errorf("unexpected diff: added %v, removed %v", added, removed)
+	}
+}
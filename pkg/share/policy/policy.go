@@ -0,0 +1,155 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package policy implements the sharing policies space managers set on a
+// space. They are enforced by the share providers when a share or a public
+// link is created on a resource inside the space.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
+	"github.com/cs3org/reva/pkg/affiliation"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+// OpaqueKey is the opaque entry holding the policy of a space. It is used in
+// the opaque of a storage space to read or update the policy, and as
+// arbitrary metadata key to get the policy of the space containing a resource
+// in the opaque of its resource info.
+const OpaqueKey = "share_policy"
+
+// Policy restricts the shares created on resources inside a space.
+type Policy struct {
+	// DenyPublicLinks forbids creating public links.
+	DenyPublicLinks bool `json:"deny_public_links,omitempty"`
+	// MaxExpiration is the maximum number of days a public link may be valid.
+	// When set, public links must expire.
+	MaxExpiration int `json:"max_expiration,omitempty"`
+	// AllowedRoles lists the roles shares and public links may grant,
+	// e.g. viewer, editor, uploader or manager. An empty list allows all roles.
+	AllowedRoles []string `json:"allowed_roles,omitempty"`
+	// ShareAffiliations lists the affiliations, e.g. staff, a user must hold
+	// to share. An empty list allows all users.
+	ShareAffiliations []string `json:"share_affiliations,omitempty"`
+	// PublicLinkAffiliations lists the affiliations a user must hold to
+	// create public links. An empty list allows all users.
+	PublicLinkAffiliations []string `json:"public_link_affiliations,omitempty"`
+}
+
+// Decode parses a policy. An empty value yields a nil policy.
+func Decode(v []byte) (*Policy, error) {
+	if len(v) == 0 {
+		return nil, nil
+	}
+	p := &Policy{}
+	if err := json.Unmarshal(v, p); err != nil {
+		return nil, errtypes.BadRequest("invalid share policy: " + err.Error())
+	}
+	if p.MaxExpiration < 0 {
+		return nil, errtypes.BadRequest("invalid share policy: max_expiration must not be negative")
+	}
+	for _, r := range p.AllowedRoles {
+		switch r {
+		case conversions.RoleViewer, conversions.RoleEditor, conversions.RoleFileEditor, conversions.RoleUploader, conversions.RoleCoowner, conversions.RoleManager:
+		default:
+			return nil, errtypes.BadRequest("invalid share policy: unknown role " + r)
+		}
+	}
+	return p, nil
+}
+
+// FromOpaque returns the policy in the given opaque, nil if there is none.
+func FromOpaque(o *types.Opaque) (*Policy, error) {
+	e, ok := o.GetMap()[OpaqueKey]
+	if !ok {
+		return nil, nil
+	}
+	return Decode(e.Value)
+}
+
+// AddToOpaque encodes a raw policy into the given opaque and returns it.
+func AddToOpaque(o *types.Opaque, v []byte) *types.Opaque {
+	if o == nil {
+		o = &types.Opaque{}
+	}
+	if o.Map == nil {
+		o.Map = map[string]*types.OpaqueEntry{}
+	}
+	o.Map[OpaqueKey] = &types.OpaqueEntry{Decoder: "json", Value: v}
+	return o
+}
+
+// CheckShare returns an error if the share grant created by the given user
+// violates the policy.
+func (p *Policy) CheckShare(g *collaboration.ShareGrant, u *userpb.User) error {
+	if p == nil {
+		return nil
+	}
+	if !affiliation.Has(u, p.ShareAffiliations) {
+		return errtypes.PermissionDenied("sharing in this space requires one of the affiliations " + strings.Join(p.ShareAffiliations, ", "))
+	}
+	return p.checkRole(g.GetPermissions().GetPermissions())
+}
+
+// CheckPublicShare returns an error if the public link grant created by the
+// given user violates the policy.
+func (p *Policy) CheckPublicShare(g *link.Grant, u *userpb.User, now time.Time) error {
+	if p == nil {
+		return nil
+	}
+	if p.DenyPublicLinks {
+		return errtypes.PermissionDenied("public links are not allowed in this space")
+	}
+	if !affiliation.Has(u, p.PublicLinkAffiliations) {
+		return errtypes.PermissionDenied("public links in this space require one of the affiliations " + strings.Join(p.PublicLinkAffiliations, ", "))
+	}
+	if p.MaxExpiration > 0 {
+		if g.GetExpiration() == nil {
+			return errtypes.BadRequest(fmt.Sprintf("public links in this space must expire within %d days", p.MaxExpiration))
+		}
+		if utils.TSToTime(g.GetExpiration()).After(now.AddDate(0, 0, p.MaxExpiration)) {
+			return errtypes.BadRequest(fmt.Sprintf("public links in this space must expire within %d days", p.MaxExpiration))
+		}
+	}
+	return p.checkRole(g.GetPermissions().GetPermissions())
+}
+
+func (p *Policy) checkRole(perms *provider.ResourcePermissions) error {
+	if len(p.AllowedRoles) == 0 {
+		return nil
+	}
+	role := conversions.RoleFromResourcePermissions(perms).Name
+	for _, r := range p.AllowedRoles {
+		// managers and co-owners hold the same permissions
+		if r == role || (r == conversions.RoleManager && role == conversions.RoleCoowner) {
+			return nil
+		}
+	}
+	return errtypes.PermissionDenied("the role " + role + " is not allowed in this space")
+}
@@ -0,0 +1,126 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package policy
+
+import (
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
+	"github.com/cs3org/reva/pkg/affiliation"
+)
+
+func affiliated(affiliations ...string) *userpb.User {
+	u := &userpb.User{}
+	affiliation.Set(u, affiliations, "")
+	return u
+}
+
+func linkGrant(r *conversions.Role, exp *time.Time) *link.Grant {
+	g := &link.Grant{Permissions: &link.PublicSharePermissions{Permissions: r.CS3ResourcePermissions()}}
+	if exp != nil {
+		g.Expiration = &types.Timestamp{Seconds: uint64(exp.Unix())}
+	}
+	return g
+}
+
+func TestDecode(t *testing.T) {
+	p, err := Decode(nil)
+	if p != nil || err != nil {
+		t.Errorf("an empty policy must decode to nil, got %v, %v", p, err)
+	}
+	for _, v := range []string{`{`, `{"max_expiration":-1}`, `{"allowed_roles":["admin"]}`} {
+		if _, err := Decode([]byte(v)); err == nil {
+			t.Errorf("expected %s to be refused", v)
+		}
+	}
+	p, err = Decode([]byte(`{"deny_public_links":true,"max_expiration":7,"allowed_roles":["viewer"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.DenyPublicLinks || p.MaxExpiration != 7 || len(p.AllowedRoles) != 1 {
+		t.Errorf("unexpected policy %+v", p)
+	}
+}
+
+func TestFromOpaque(t *testing.T) {
+	if p, err := FromOpaque(nil); p != nil || err != nil {
+		t.Errorf("a missing opaque must have no policy, got %v, %v", p, err)
+	}
+	o := AddToOpaque(nil, []byte(`{"deny_public_links":true}`))
+	p, err := FromOpaque(o)
+	if err != nil || p == nil || !p.DenyPublicLinks {
+		t.Errorf("unexpected policy %+v, %v", p, err)
+	}
+}
+
+func TestCheckPublicShare(t *testing.T) {
+	now := time.Now()
+	soon, late := now.AddDate(0, 0, 3), now.AddDate(0, 0, 30)
+	staff, student := affiliated("staff"), affiliated("student")
+
+	tests := []struct {
+		name    string
+		policy  *Policy
+		grant   *link.Grant
+		user    *userpb.User
+		allowed bool
+	}{
+		{"no policy", nil, linkGrant(conversions.NewEditorRole(), nil), staff, true},
+		{"denied links", &Policy{DenyPublicLinks: true}, linkGrant(conversions.NewViewerRole(), nil), staff, false},
+		{"missing expiry", &Policy{MaxExpiration: 7}, linkGrant(conversions.NewViewerRole(), nil), staff, false},
+		{"expiry too late", &Policy{MaxExpiration: 7}, linkGrant(conversions.NewViewerRole(), &late), staff, false},
+		{"expiry in time", &Policy{MaxExpiration: 7}, linkGrant(conversions.NewViewerRole(), &soon), staff, true},
+		{"allowed role", &Policy{AllowedRoles: []string{"viewer", "uploader"}}, linkGrant(conversions.NewUploaderRole(), nil), staff, true},
+		{"forbidden role", &Policy{AllowedRoles: []string{"viewer"}}, linkGrant(conversions.NewEditorRole(), nil), staff, false},
+		{"allowed affiliation", &Policy{PublicLinkAffiliations: []string{"staff"}}, linkGrant(conversions.NewViewerRole(), nil), staff, true},
+		{"missing affiliation", &Policy{PublicLinkAffiliations: []string{"staff"}}, linkGrant(conversions.NewViewerRole(), nil), student, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.policy.CheckPublicShare(tt.grant, tt.user, now); (err == nil) != tt.allowed {
+			t.Errorf("%s: expected allowed to be %t, got error %v", tt.name, tt.allowed, err)
+		}
+	}
+}
+
+func TestCheckShare(t *testing.T) {
+	grant := func(r *conversions.Role) *collaboration.ShareGrant {
+		return &collaboration.ShareGrant{Permissions: &collaboration.SharePermissions{Permissions: r.CS3ResourcePermissions()}}
+	}
+	p := &Policy{DenyPublicLinks: true, AllowedRoles: []string{"editor", "manager"}, ShareAffiliations: []string{"staff", "faculty"}}
+	staff := affiliated("staff")
+
+	if err := p.CheckShare(grant(conversions.NewEditorRole()), staff); err != nil {
+		t.Errorf("editor shares must be allowed: %v", err)
+	}
+	if err := p.CheckShare(grant(conversions.NewManagerRole()), staff); err != nil {
+		t.Errorf("manager shares must be allowed: %v", err)
+	}
+	if err := p.CheckShare(grant(conversions.NewViewerRole()), staff); err == nil {
+		t.Error("viewer shares must be refused")
+	}
+	if err := p.CheckShare(grant(conversions.NewEditorRole()), affiliated("student")); err == nil {
+		t.Error("shares by students must be refused")
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package portable defines a deployment independent format for user shares
+// and public links, used to migrate them between reva instances. Users,
+// groups and resources are remapped on import through a Mapping.
+package portable
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// Version is the version of the format written by Encode.
+const Version = 1
+
+// Document holds the shares and public links of a user or storage.
+type Document struct {
+	Version     int           `json:"version"`
+	Shares      []*Share      `json:"shares"`
+	PublicLinks []*PublicLink `json:"public_links"`
+}
+
+// Identity identifies a user or a group.
+type Identity struct {
+	Idp      string `json:"idp"`
+	OpaqueID string `json:"opaque_id"`
+	Type     string `json:"type,omitempty"`
+}
+
+// Resource identifies a shared resource. The path is used to find the
+// resource on import when its id is not mapped.
+type Resource struct {
+	StorageID string `json:"storage_id"`
+	OpaqueID  string `json:"opaque_id"`
+	Path      string `json:"path"`
+}
+
+// Share is a share with a user or a group.
+type Share struct {
+	Resource    Resource                      `json:"resource"`
+	Owner       Identity                      `json:"owner"`
+	Creator     Identity                      `json:"creator"`
+	GranteeType string                        `json:"grantee_type"`
+	Grantee     Identity                      `json:"grantee"`
+	Permissions *provider.ResourcePermissions `json:"permissions"`
+	Ctime       uint64                        `json:"ctime"`
+}
+
+// PublicLink is a public link. Passwords are stored hashed, so they cannot be
+// exported and password protected links get a new password on import.
+type PublicLink struct {
+	Resource          Resource                      `json:"resource"`
+	Owner             Identity                      `json:"owner"`
+	Creator           Identity                      `json:"creator"`
+	Token             string                        `json:"token"`
+	DisplayName       string                        `json:"display_name"`
+	Permissions       *provider.ResourcePermissions `json:"permissions"`
+	PasswordProtected bool                          `json:"password_protected"`
+	Expiration        uint64                        `json:"expiration,omitempty"`
+	Ctime             uint64                        `json:"ctime"`
+}
+
+// Decode reads a document, rejecting unknown versions.
+func Decode(r io.Reader) (*Document, error) {
+	d := &Document{}
+	if err := json.NewDecoder(r).Decode(d); err != nil {
+		return nil, errors.Wrap(err, "portable: error decoding document")
+	}
+	if d.Version != Version {
+		return nil, fmt.Errorf("portable: unsupported version %d", d.Version)
+	}
+	return d, nil
+}
+
+// Encode writes the document.
+func (d *Document) Encode(w io.Writer) error {
+	d.Version = Version
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// FromShare converts a share of the resource at the given path.
+func FromShare(s *collaboration.Share, path string) *Share {
+	ps := &Share{
+		Resource:    fromResourceID(s.ResourceId, path),
+		Owner:       fromUserID(s.Owner),
+		Creator:     fromUserID(s.Creator),
+		Permissions: s.GetPermissions().GetPermissions(),
+		Ctime:       s.GetCtime().GetSeconds(),
+	}
+	switch s.GetGrantee().GetType() {
+	case provider.GranteeType_GRANTEE_TYPE_USER:
+		ps.GranteeType = "user"
+		ps.Grantee = fromUserID(s.Grantee.GetUserId())
+	case provider.GranteeType_GRANTEE_TYPE_GROUP:
+		ps.GranteeType = "group"
+		ps.Grantee = Identity{Idp: s.Grantee.GetGroupId().GetIdp(), OpaqueID: s.Grantee.GetGroupId().GetOpaqueId()}
+	}
+	return ps
+}
+
+// FromPublicShare converts a public link to the resource at the given path.
+func FromPublicShare(l *link.PublicShare, path string) *PublicLink {
+	return &PublicLink{
+		Resource:          fromResourceID(l.ResourceId, path),
+		Owner:             fromUserID(l.Owner),
+		Creator:           fromUserID(l.Creator),
+		Token:             l.Token,
+		DisplayName:       l.DisplayName,
+		Permissions:       l.GetPermissions().GetPermissions(),
+		PasswordProtected: l.PasswordProtected,
+		Expiration:        l.GetExpiration().GetSeconds(),
+		Ctime:             l.GetCtime().GetSeconds(),
+	}
+}
+
+func fromUserID(id *userpb.UserId) Identity {
+	return Identity{Idp: id.GetIdp(), OpaqueID: id.GetOpaqueId(), Type: utils.UserTypeToString(id.GetType())}
+}
+
+func fromResourceID(id *provider.ResourceId, path string) Resource {
+	return Resource{StorageID: id.GetStorageId(), OpaqueID: id.GetOpaqueId(), Path: path}
+}
+
+// Mapping translates the users, groups and resources of the source deployment
+// to the ones of the destination. Users and groups are keyed by opaque id and
+// resources by "storage_id:opaque_id". Unmapped entries are kept as they are.
+type Mapping struct {
+	Idp       string            `json:"idp"`
+	Users     map[string]string `json:"users"`
+	Groups    map[string]string `json:"groups"`
+	Resources map[string]string `json:"resources"`
+	Paths     map[string]string `json:"paths"`
+}
+
+// ReadMapping reads a mapping file. An empty name returns an empty mapping.
+func ReadMapping(name string) (*Mapping, error) {
+	m := &Mapping{}
+	if name == "" {
+		return m, nil
+	}
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "portable: error reading mapping file")
+	}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, errors.Wrap(err, "portable: error decoding mapping file")
+	}
+	return m, nil
+}
+
+// UserID returns the id of a user in the destination deployment.
+func (m *Mapping) UserID(i Identity) *userpb.UserId {
+	id := &userpb.UserId{Idp: m.idp(i.Idp), OpaqueId: i.OpaqueID, Type: utils.UserTypeMap(i.Type)}
+	if v, ok := m.Users[i.OpaqueID]; ok {
+		id.OpaqueId = v
+	}
+	return id
+}
+
+// GroupID returns the id of a group in the destination deployment.
+func (m *Mapping) GroupID(i Identity) *grouppb.GroupId {
+	id := &grouppb.GroupId{Idp: m.idp(i.Idp), OpaqueId: i.OpaqueID}
+	if v, ok := m.Groups[i.OpaqueID]; ok {
+		id.OpaqueId = v
+	}
+	return id
+}
+
+// Reference returns a reference to a resource in the destination deployment,
+// by id if it is mapped and by path otherwise.
+func (m *Mapping) Reference(r Resource) (*provider.Reference, error) {
+	if v, ok := m.Resources[r.StorageID+":"+r.OpaqueID]; ok {
+		parts := strings.SplitN(v, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("portable: invalid resource id %q in mapping", v)
+		}
+		return &provider.Reference{ResourceId: &provider.ResourceId{StorageId: parts[0], OpaqueId: parts[1]}}, nil
+	}
+	if r.Path == "" {
+		return nil, fmt.Errorf("portable: resource %s:%s is neither mapped nor has a path", r.StorageID, r.OpaqueID)
+	}
+	return &provider.Reference{Path: m.path(r.Path)}, nil
+}
+
+func (m *Mapping) idp(idp string) string {
+	if m.Idp != "" {
+		return m.Idp
+	}
+	return idp
+}
+
+// path replaces the longest mapped prefix of a path.
+func (m *Mapping) path(p string) string {
+	var match string
+	for prefix := range m.Paths {
+		if (p == prefix || strings.HasPrefix(p, strings.TrimSuffix(prefix, "/")+"/")) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	if match == "" {
+		return p
+	}
+	return m.Paths[match] + strings.TrimPrefix(p, match)
+}
+
+// Grant returns the grant re-creating the share in the destination deployment.
+func (m *Mapping) Grant(s *Share) (*collaboration.ShareGrant, error) {
+	g := &collaboration.ShareGrant{
+		Permissions: &collaboration.SharePermissions{Permissions: s.Permissions},
+		Grantee:     &provider.Grantee{},
+	}
+	switch s.GranteeType {
+	case "user":
+		g.Grantee.Type = provider.GranteeType_GRANTEE_TYPE_USER
+		g.Grantee.Id = &provider.Grantee_UserId{UserId: m.UserID(s.Grantee)}
+	case "group":
+		g.Grantee.Type = provider.GranteeType_GRANTEE_TYPE_GROUP
+		g.Grantee.Id = &provider.Grantee_GroupId{GroupId: m.GroupID(s.Grantee)}
+	default:
+		return nil, fmt.Errorf("portable: unsupported grantee type %q", s.GranteeType)
+	}
+	return g, nil
+}
+
+// PublicGrant returns the grant re-creating a public link in the destination
+// deployment, protected with the given password if the original one was.
+func PublicGrant(l *PublicLink, password string) *link.Grant {
+	g := &link.Grant{
+		Permissions: &link.PublicSharePermissions{Permissions: l.Permissions},
+	}
+	if l.PasswordProtected {
+		g.Password = password
+	}
+	if l.Expiration != 0 {
+		g.Expiration = &types.Timestamp{Seconds: l.Expiration}
+	}
+	return g
+}
@@ -0,0 +1,84 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package portable
+
+import (
+	"bytes"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+func TestRoundTrip(t *testing.T) {
+	s := &collaboration.Share{
+		ResourceId: &provider.ResourceId{StorageId: "old-storage", OpaqueId: "123"},
+		Owner:      &userpb.UserId{Idp: "https://old", OpaqueId: "einstein", Type: userpb.UserType_USER_TYPE_PRIMARY},
+		Creator:    &userpb.UserId{Idp: "https://old", OpaqueId: "einstein", Type: userpb.UserType_USER_TYPE_PRIMARY},
+		Grantee: &provider.Grantee{
+			Type: provider.GranteeType_GRANTEE_TYPE_USER,
+			Id:   &provider.Grantee_UserId{UserId: &userpb.UserId{Idp: "https://old", OpaqueId: "marie", Type: userpb.UserType_USER_TYPE_PRIMARY}},
+		},
+		Permissions: &collaboration.SharePermissions{Permissions: &provider.ResourcePermissions{Stat: true}},
+	}
+
+	var buf bytes.Buffer
+	doc := &Document{Shares: []*Share{FromShare(s, "/home/einstein/docs")}}
+	if err := doc.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Mapping{
+		Idp:   "https://new",
+		Users: map[string]string{"marie": "curie"},
+		Paths: map[string]string{"/home/einstein": "/home/e/einstein"},
+	}
+	g, err := m.Grant(doc.Shares[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := g.Grantee.GetUserId(); id.Idp != "https://new" || id.OpaqueId != "curie" || id.Type != userpb.UserType_USER_TYPE_PRIMARY {
+		t.Errorf("unexpected grantee %v", id)
+	}
+	if !g.Permissions.Permissions.Stat {
+		t.Error("permissions were lost")
+	}
+
+	ref, err := m.Reference(doc.Shares[0].Resource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.Path != "/home/e/einstein/docs" {
+		t.Errorf("unexpected path %q", ref.Path)
+	}
+
+	m.Resources = map[string]string{"old-storage:123": "new-storage:456"}
+	ref, err = m.Reference(doc.Shares[0].Resource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.ResourceId.StorageId != "new-storage" || ref.ResourceId.OpaqueId != "456" {
+		t.Errorf("unexpected resource id %v", ref.ResourceId)
+	}
+}
@@ -59,6 +59,61 @@ type Manager interface {
 	UpdateReceivedShare(ctx context.Context, share *collaboration.ReceivedShare, fieldMask *field_mask.FieldMask) (*collaboration.ReceivedShare, error)
 }
 
+// OpaqueResourceIDsOnly marks the requests listing shares which only need to
+// know which of the filtered resources are shared. The shares in the response
+// then only carry their resource id.
+const OpaqueResourceIDsOnly = "resource_ids_only"
+
+// SharedResourcesLister is implemented by the managers able to tell which of
+// many resources are shared in a single query, without loading the shares.
+type SharedResourcesLister interface {
+	// ListSharedResources returns the ids of the given resources shared by the user.
+	ListSharedResources(ctx context.Context, ids []*provider.ResourceId) ([]*provider.ResourceId, error)
+}
+
+// ListSharedResources returns the ids of the given resources shared by the
+// user, falling back to listing the shares if the manager can't batch it.
+func ListSharedResources(ctx context.Context, m Manager, ids []*provider.ResourceId) ([]*provider.ResourceId, error) {
+	if len(ids) == 0 {
+		return []*provider.ResourceId{}, nil
+	}
+	if l, ok := m.(SharedResourcesLister); ok {
+		return l.ListSharedResources(ctx, ids)
+	}
+
+	filters := make([]*collaboration.Filter, 0, len(ids))
+	for _, id := range ids {
+		filters = append(filters, ResourceIDFilter(id))
+	}
+	shares, err := m.ListShares(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	shared := []*provider.ResourceId{}
+	for _, id := range ids {
+		for _, s := range shares {
+			if utils.ResourceIDEqual(s.ResourceId, id) {
+				shared = append(shared, id)
+				break
+			}
+		}
+	}
+	return shared, nil
+}
+
+// ResourceIDsOf returns the resources the filters select, if all of them are
+// filters by resource id.
+func ResourceIDsOf(filters []*collaboration.Filter) ([]*provider.ResourceId, bool) {
+	ids := make([]*provider.ResourceId, 0, len(filters))
+	for _, f := range filters {
+		if f.Type != collaboration.Filter_TYPE_RESOURCE_ID {
+			return nil, false
+		}
+		ids = append(ids, f.GetResourceId())
+	}
+	return ids, true
+}
+
 // GroupGranteeFilter is an abstraction for creating filter by grantee type group.
 func GroupGranteeFilter() *collaboration.Filter {
 	return &collaboration.Filter{
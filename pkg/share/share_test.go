@@ -19,6 +19,7 @@
 package share
 
 import (
+	"context"
 	"testing"
 
 	groupv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
@@ -211,3 +212,48 @@ func TestGroupFiltersByType(t *testing.T) {
 		}
 	}
 }
+
+func TestResourceIDsOf(t *testing.T) {
+	id := &provider.ResourceId{StorageId: "storage", OpaqueId: "a"}
+	ids, ok := ResourceIDsOf([]*collaboration.Filter{ResourceIDFilter(id)})
+	if !ok || len(ids) != 1 || ids[0] != id {
+		t.Errorf("expected the resource id of the filter, got %v", ids)
+	}
+	if _, ok := ResourceIDsOf([]*collaboration.Filter{ResourceIDFilter(id), UserGranteeFilter()}); ok {
+		t.Error("expected filters of other types not to be accepted")
+	}
+}
+
+type listingManager struct {
+	Manager
+	shares []*collaboration.Share
+}
+
+func (m *listingManager) ListShares(ctx context.Context, filters []*collaboration.Filter) ([]*collaboration.Share, error) {
+	shares := []*collaboration.Share{}
+	for _, s := range m.shares {
+		if MatchesFilters(s, filters) {
+			shares = append(shares, s)
+		}
+	}
+	return shares, nil
+}
+
+func TestListSharedResources(t *testing.T) {
+	a := &provider.ResourceId{StorageId: "storage", OpaqueId: "a"}
+	b := &provider.ResourceId{StorageId: "storage", OpaqueId: "b"}
+	c := &provider.ResourceId{StorageId: "storage", OpaqueId: "c"}
+	m := &listingManager{shares: []*collaboration.Share{
+		{ResourceId: a},
+		{ResourceId: a},
+		{ResourceId: c},
+	}}
+
+	shared, err := ListSharedResources(context.Background(), m, []*provider.ResourceId{a, b, c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shared) != 2 || shared[0] != a || shared[1] != c {
+		t.Errorf("expected the resources a and c to be shared once, got %v", shared)
+	}
+}
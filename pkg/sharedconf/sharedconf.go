@@ -28,10 +28,13 @@ import (
 var sharedConf = &conf{}
 
 type conf struct {
-	JWTSecret             string `mapstructure:"jwt_secret"`
-	GatewaySVC            string `mapstructure:"gatewaysvc"`
-	DataGateway           string `mapstructure:"datagateway"`
-	SkipUserGroupsInToken bool   `mapstructure:"skip_user_groups_in_token"`
+	JWTSecret             string                 `mapstructure:"jwt_secret"`
+	GatewaySVC            string                 `mapstructure:"gatewaysvc"`
+	DataGateway           string                 `mapstructure:"datagateway"`
+	SkipUserGroupsInToken bool                   `mapstructure:"skip_user_groups_in_token"`
+	Tenancy               map[string]interface{} `mapstructure:"tenancy"`
+	Cache                 map[string]interface{} `mapstructure:"cache"`
+	Lease                 map[string]interface{} `mapstructure:"lease"`
 }
 
 // Decode decodes the configuration.
@@ -92,3 +95,18 @@ func GetDataGateway(val string) string {
 func SkipUserGroupsInToken() bool {
 	return sharedConf.SkipUserGroupsInToken
 }
+
+// GetTenancy returns the multi-tenancy configuration shared by all services.
+func GetTenancy() map[string]interface{} {
+	return sharedConf.Tenancy
+}
+
+// GetCache returns the configuration of the key-value cache shared by all drivers.
+func GetCache() map[string]interface{} {
+	return sharedConf.Cache
+}
+
+// GetLease returns the configuration of the leases coordinating the background jobs of the replicas.
+func GetLease() map[string]interface{} {
+	return sharedConf.Lease
+}
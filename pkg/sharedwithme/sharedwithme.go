@@ -0,0 +1,68 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package sharedwithme aggregates the shares received by a user with the
+// information of the shared resources.
+package sharedwithme
+
+import (
+	"context"
+	"sync"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/sharedwithme/proto"
+)
+
+// StatFunc returns the information of a shared resource, or the status
+// explaining why it is not available.
+type StatFunc func(ctx context.Context, id *provider.ResourceId) (*provider.ResourceInfo, *rpc.Status)
+
+// Enrich stats the resources of the given shares with at most workers
+// concurrent calls and returns the results in the order of the shares.
+func Enrich(ctx context.Context, shares []*collaboration.ReceivedShare, workers int, stat StatFunc) []*proto.SharedWithMe {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	items := make([]*proto.SharedWithMe, len(shares))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(shares); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				info, st := stat(ctx, shares[i].GetShare().GetResourceId())
+				items[i] = &proto.SharedWithMe{
+					ReceivedShare: shares[i],
+					ResourceInfo:  info,
+					StatStatus:    st,
+				}
+			}
+		}()
+	}
+	for i := range shares {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return items
+}
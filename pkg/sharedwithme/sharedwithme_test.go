@@ -0,0 +1,85 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sharedwithme
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+func TestEnrich(t *testing.T) {
+	shares := make([]*collaboration.ReceivedShare, 20)
+	for i := range shares {
+		shares[i] = &collaboration.ReceivedShare{
+			Share: &collaboration.Share{ResourceId: &provider.ResourceId{StorageId: "s", OpaqueId: fmt.Sprint(i)}},
+		}
+	}
+
+	var running, max int32
+	stat := func(ctx context.Context, id *provider.ResourceId) (*provider.ResourceInfo, *rpc.Status) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		if id.OpaqueId == "7" {
+			return nil, &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}
+		}
+		return &provider.ResourceInfo{Id: id, Path: "/" + id.OpaqueId}, &rpc.Status{Code: rpc.Code_CODE_OK}
+	}
+
+	items := Enrich(context.Background(), shares, 4, stat)
+
+	if max > 4 {
+		t.Errorf("expected at most 4 concurrent stats, got %d", max)
+	}
+	if len(items) != len(shares) {
+		t.Fatalf("expected %d items, got %d", len(shares), len(items))
+	}
+	for i, item := range items {
+		if item.ReceivedShare != shares[i] {
+			t.Fatalf("item %d does not match its share", i)
+		}
+		if i == 7 {
+			if item.ResourceInfo != nil || item.StatStatus.Code != rpc.Code_CODE_NOT_FOUND {
+				t.Errorf("expected the failed stat to be reported, got %+v", item)
+			}
+			continue
+		}
+		if item.ResourceInfo.Path != fmt.Sprintf("/%d", i) || item.StatStatus.Code != rpc.Code_CODE_OK {
+			t.Errorf("unexpected item %d: %+v", i, item)
+		}
+	}
+
+	if items := Enrich(context.Background(), nil, 4, stat); len(items) != 0 {
+		t.Errorf("expected no items, got %d", len(items))
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2018-2020 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package apikeys
+
+const tplJavaScript = `
+function createKey() {
+	const formData = new FormData(document.getElementById("form-create"));
+	if (formData.getTrimmed("name") == "") {
+		setState(STATE_ERROR, "Please enter a name for the key.", "form-create", "name", true);
+		return;
+	}
+
+	setState(STATE_STATUS, "Issuing API key... this should only take a moment.", "form-create", null, false);
+
+	var xhr = new XMLHttpRequest();
+    xhr.open("POST", "{{getServerAddress}}/apikeys-create?invoker=user");
+    xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
+
+	xhr.onload = function() {
+		var resp = JSON.parse(this.responseText);
+		if (this.status == 200) {
+			showKey("A new key has been issued", resp.data.key);
+		} else {
+			setState(STATE_ERROR, "An error occurred while trying to issue the API key:<br><em>" + resp.error + "</em>", "form-create", null, true);
+		}
+	}
+
+	var postData = {
+		"name": formData.getTrimmed("name"),
+		"scope": formData.get("scope"),
+		"expires": formData.get("expires")
+	};
+
+    xhr.send(JSON.stringify(postData));
+}
+
+function rotateKey(id, name) {
+	if (!confirm("Rotating the key " + name + " will immediately invalidate its current value. Continue?")) {
+		return;
+	}
+
+	setState(STATE_STATUS, "Rotating API key... this should only take a moment.", "form", null, false);
+
+	var xhr = new XMLHttpRequest();
+    xhr.open("POST", "{{getServerAddress}}/apikeys-rotate?invoker=user&id=" + encodeURIComponent(id));
+    xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
+
+	xhr.onload = function() {
+		var resp = JSON.parse(this.responseText);
+		if (this.status == 200) {
+			showKey("The key " + name + " has been rotated", resp.data.key);
+		} else {
+			setState(STATE_ERROR, "An error occurred while trying to rotate the API key:<br><em>" + resp.error + "</em>", "form", null, true);
+		}
+	}
+
+    xhr.send("{}");
+}
+
+function revokeKey(id, name) {
+	if (!confirm("Revoke the key " + name + "? Services using it will no longer be able to access your sites.")) {
+		return;
+	}
+
+	setState(STATE_STATUS, "Revoking API key... this should only take a moment.", "form", null, false);
+
+	var xhr = new XMLHttpRequest();
+    xhr.open("POST", "{{getServerAddress}}/apikeys-revoke?invoker=user&id=" + encodeURIComponent(id));
+    xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
+
+	xhr.onload = function() {
+		if (this.status == 200) {
+			window.location.reload();
+		} else {
+			var resp = JSON.parse(this.responseText);
+			setState(STATE_ERROR, "An error occurred while trying to revoke the API key:<br><em>" + resp.error + "</em>", "form", null, true);
+		}
+	}
+
+    xhr.send("{}");
+}
+
+function showKey(message, key) {
+	document.getElementById("new-key").textContent = key;
+	document.getElementById("new-key-message").textContent = message + ":";
+	document.getElementById("new-key-box").style.display = "block";
+	setState(STATE_SUCCESS, "Pass the key in the <code>X-API-Key</code> header of your requests. <strong>Copy it now, as it will not be shown again!</strong>", "form-create", null, true);
+}
+`
+
+const tplStyleSheet = `
+html * {
+	font-family: arial !important;
+}
+
+table {
+	width: 100%;
+	border-collapse: collapse;
+}
+
+th, td {
+	text-align: left;
+	padding: 0.25em;
+	border-bottom: 1px solid #ddd;
+}
+
+.mandatory {
+	color: red;
+	font-weight: bold;
+}
+
+.expired {
+	color: red;
+}
+`
+
+const tplBody = `
+<div>
+	<p>API keys let other services, like monitoring systems or deployment tools, access the sites of your operator without using the credentials of an account. <em>The keys belong to your operator and not to your account.</em></p>
+	<ul style="margin-top: 0em;">
+		<li>Keys with the <em>monitoring</em> scope can only read the data of your sites.</li>
+		<li>Keys with the <em>site-config</em> scope can also change the configuration of your sites, including the test users and site keys.</li>
+	</ul>
+</div>
+<div id="new-key-box" style="display: none;">
+	<p><span id="new-key-message"></span><br><code id="new-key"></code></p>
+	<p><a href="{{getServerAddress}}/account/?path=api-keys">Refresh</a> the list of keys.</p>
+</div>
+<div>&nbsp;</div>
+<div>
+	<form id="form" method="POST" class="box" style="width: 100%;">
+		<h3>Issued keys</h3>
+		<hr>
+		{{if .Operator.APIKeys}}
+		<table>
+			<tr>
+				<th>Name</th>
+				<th>Scope</th>
+				<th>Created</th>
+				<th>Expires</th>
+				<th>Last used</th>
+				<th></th>
+			</tr>
+			{{range .Operator.APIKeys}}
+			<tr>
+				<td><strong>{{.Name}}</strong><br><span style="font-size: 80%;">{{.ID}}; issued by {{.CreatedBy}}</span></td>
+				<td>{{.Scope}}</td>
+				<td>{{.DateCreated.Format "Jan 02, 2006"}}{{if not .DateRotated.IsZero}}<br><span style="font-size: 80%;">rotated {{.DateRotated.Format "Jan 02, 2006"}}</span>{{end}}</td>
+				<td>{{if .DateExpires.IsZero}}Never{{else if .IsExpired}}<span class="expired">Expired</span>{{else}}{{.DateExpires.Format "Jan 02, 2006"}}{{end}}</td>
+				<td>{{if .LastUsed.IsZero}}Never{{else}}{{.LastUsed.Format "Jan 02, 2006 15:04"}} ({{.UseCount}} uses){{end}}</td>
+				<td style="text-align: right;">
+					<button type="button" onClick="rotateKey('{{.ID}}', '{{.Name}}');">Rotate</button>
+					<button type="button" onClick="revokeKey('{{.ID}}', '{{.Name}}');">Revoke</button>
+				</td>
+			</tr>
+			{{end}}
+		</table>
+		{{else}}
+		<p><em>Your operator hasn't issued any API keys yet.</em></p>
+		{{end}}
+	</form>
+</div>
+<div>&nbsp;</div>
+<div>
+	<form id="form-create" method="POST" class="box container-inline" style="width: 100%;" onSubmit="createKey(); return false;">
+		<div style="grid-row: 1; grid-column: 1 / span 2;">
+			<h3>Issue a new key</h3>
+			<hr>
+		</div>
+
+		<div style="grid-row: 2;"><label for="name">Name: <span class="mandatory">*</span></label></div>
+		<div style="grid-row: 3;"><input type="text" id="name" name="name" placeholder="e.g., Monitoring of our sites"/></div>
+		<div style="grid-row: 2;"><label for="scope">Scope: <span class="mandatory">*</span></label></div>
+		<div style="grid-row: 3;">
+			<select id="scope" name="scope">
+				<option value="monitoring" selected>monitoring (read-only)</option>
+				<option value="site-config">site-config (read and configure)</option>
+			</select>
+		</div>
+
+		<div style="grid-row: 4;"><label for="expires">Expires on:</label></div>
+		<div style="grid-row: 5;"><input type="date" id="expires" name="expires"/></div>
+
+		<div style="grid-row: 6; align-self: center;">
+			Fields marked with <span class="mandatory">*</span> are mandatory. Keys without an expiry date remain valid until revoked.
+		</div>
+		<div style="grid-row: 6; grid-column: 2; text-align: right;">
+			<button type="reset">Reset</button>
+			<button type="submit" style="font-weight: bold;">Issue key</button>
+		</div>
+	</form>
+</div>
+<div>
+	<p>Go <a href="{{getServerAddress}}/account/?path=manage">back</a> to the main account page.</p>
+</div>
+`
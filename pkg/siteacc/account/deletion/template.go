@@ -0,0 +1,88 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package deletion
+
+const tplJavaScript = `
+function handleExport() {
+	window.location.assign("{{getServerAddress}}/export-account?format=zip");
+}
+
+function handleDelete() {
+	const formData = new FormData(document.querySelector("form"));
+	if (!formData.get("confirm")) {
+		setState(STATE_ERROR, "Please confirm that you want to delete your account.", "form", "confirm", true);
+		return;
+	}
+
+	var xhr = new XMLHttpRequest();
+    xhr.open("POST", "{{getServerAddress}}/request-deletion");
+    xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
+
+	setState(STATE_STATUS, "Requesting the deletion of your account...", "form", null, false);
+
+	xhr.onload = function() {
+		if (this.status == 200) {
+			var resp = JSON.parse(this.responseText);
+			setState(STATE_SUCCESS, "We have sent you an email to confirm the deletion of your account. Please visit the link it contains within " + resp.data.confirmationTimeout + " hours; your account remains unchanged until then.", "form", null, true);
+		} else {
+			var resp = JSON.parse(this.responseText);
+			setState(STATE_ERROR, "An error occurred while requesting the deletion of your account:<br><em>" + resp.error + "</em>", "form", null, true);
+		}
+	}
+
+	xhr.send("{}");
+}
+`
+
+const tplStyleSheet = `
+html * {
+	font-family: arial !important;
+}
+button {
+	min-width: 170px;
+}
+`
+
+const tplBody = `
+<div>
+	<p>On this page, you can delete your ScienceMesh Site Administrator Account.</p>
+	<p style="margin-bottom: 0em;">Please note the following:</p>
+	<ul style="margin-top: 0em;">
+		<li>You need to confirm the deletion using the link sent to your email address; your account will then be disabled, and you will be logged out.</li>
+		<li>Your account will only be deleted permanently after a cooling-off period; until then, you can cancel the deletion using the link sent to your email address.</li>
+		<li>You can export your account data, including your operator and site associations and your recent activity, now or at any time during the cooling-off period.</li>
+	</ul>
+</div>
+<div>&nbsp;</div>
+<div>
+	<form id="form" method="POST" class="box" style="width: 100%;" onSubmit="handleDelete(); return false;">
+		<div>
+			<input type="checkbox" id="confirm" name="confirm" value="yes">
+			<label for="confirm">I want to delete my account <em>{{.Account.Email}}</em></label>
+		</div>
+		<div style="margin-top: 0.5em;">
+			<button type="button" onClick="handleExport();">Export account data</button>
+			<button type="submit" style="float: right; font-weight: bold;">Delete account</button>
+		</div>
+	</form>
+</div>
+<div>
+	<p>Go <a href="{{getServerAddress}}/account/?path=manage">back</a> to the main account page.</p>
+</div>
+`
@@ -67,7 +67,12 @@ function handleAction(action) {
 			setState(STATE_SUCCESS, "Your account was successfully updated!", "form", null, true);
 		} else {
 			var resp = JSON.parse(this.responseText);
-			setState(STATE_ERROR, "An error occurred while trying to update your account:<br><em>" + resp.error + "</em>", "form", null, true);
+			if (resp.violations) {
+				var rules = resp.violations.map(function(violation) { return "<li>" + violation.message + "</li>"; });
+				setState(STATE_ERROR, "The password does not fulfil the password policy:<ul>" + rules.join("") + "</ul>", "form", "password", true);
+			} else {
+				setState(STATE_ERROR, "An error occurred while trying to update your account:<br><em>" + resp.error + "</em>", "form", null, true);
+			}
 		}
 	}
 
@@ -136,10 +141,9 @@ const tplBody = `
 		<div style="grid-row: 11; font-style: italic; font-size: 0.8em;">
 			The password must fulfil the following criteria:
 			<ul style="margin-top: 0em;">
-				<li>Must be at least 8 characters long</li>
-				<li>Must contain at least 1 lowercase letter</li>
-				<li>Must contain at least 1 uppercase letter</li>
-				<li>Must contain at least 1 digit</li>
+				{{range getPasswordRequirements}}
+				<li>{{.}}</li>
+				{{end}}
 			</ul>
 		</div>
 
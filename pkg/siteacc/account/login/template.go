@@ -48,11 +48,18 @@ function handleAction(action) {
     xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
 
 	xhr.onload = function() {
+		var resp = JSON.parse(this.responseText);
 		if (this.status == 200) {
+			if (resp.data && resp.data.twoFactor) {
+				// The login needs to be completed using the second factor
+				document.getElementById("form").style.display = "none";
+				document.getElementById("form-2fa").style.display = "grid";
+				setState(STATE_SUCCESS, "Please enter the code shown by your authenticator app.", "form-2fa", "code", true);
+				return;
+			}
 			setState(STATE_SUCCESS, "Your login was successful! Redirecting...");
 			window.location.replace("{{getServerAddress}}/account/?path=manage");
 		} else {
-			var resp = JSON.parse(this.responseText);
 			setState(STATE_ERROR, "An error occurred while trying to login your account:<br><em>" + resp.error + "</em>", "form", null, true);
 		}
 	}
@@ -67,6 +74,53 @@ function handleAction(action) {
     xhr.send(JSON.stringify(postData));
 }
 
+function handleVerifyCode() {
+	const formData = new FormData(document.getElementById("form-2fa"));
+	if (formData.getTrimmed("code") == "") {
+		setState(STATE_ERROR, "Please enter the code shown by your authenticator app.", "form-2fa", "code", true);
+		return;
+	}
+
+	setState(STATE_STATUS, "Verifying code... this should only take a moment.", "form-2fa", null, false);
+
+	var xhr = new XMLHttpRequest();
+    xhr.open("POST", "{{getServerAddress}}/verify-2fa");
+    xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
+
+	xhr.onload = function() {
+		if (this.status == 200) {
+			setState(STATE_SUCCESS, "Your login was successful! Redirecting...");
+			window.location.replace("{{getServerAddress}}/account/?path=manage");
+		} else {
+			var resp = JSON.parse(this.responseText);
+			setState(STATE_ERROR, "An error occurred while trying to verify your code:<br><em>" + resp.error + "</em>", "form-2fa", "code", true);
+		}
+	}
+
+	var postData = {
+        "code": formData.getTrimmed("code")
+    };
+
+    xhr.send(JSON.stringify(postData));
+}
+
+window.addEventListener("load", function() {
+	{{if eq .Params.Login "2fa"}}
+	// A login through the OpenID Connect provider needs to be completed using the second factor
+	document.getElementById("form").style.display = "none";
+	document.getElementById("form-2fa").style.display = "grid";
+	setState(STATE_SUCCESS, "Please enter the code shown by your authenticator app.", "form-2fa", "code", true);
+	{{else if eq .Params.Verified "true"}}
+	setState(STATE_SUCCESS, "Your email address has been verified. You can now log in.", "form", null, true);
+	{{else if .Params.Deleted}}
+	setState(STATE_SUCCESS, "Your account has been disabled and will be permanently deleted on {{.Params.Deleted}}. Check your inbox for instructions on how to export your data or cancel the deletion until then.", "form", null, true);
+	{{else if .Params.Error}}
+	var errMsg = document.createElement("em");
+	errMsg.textContent = "{{.Params.Error}}";
+	setState(STATE_ERROR, "An error occurred while trying to login your account:<br>" + errMsg.outerHTML, "form", null, true);
+	{{end}}
+});
+
 function handleResetPassword() {
 	const formData = new FormData(document.querySelector("form"));
 	if (!verifyForm(formData, false)) {
@@ -130,6 +184,18 @@ const tplBody = `
 			<button type="submit" style="font-weight: bold;">Login</button>
 		</div>	
 	</form>	
+	{{if isOIDCEnabled}}
+	<div style="text-align: center; padding-top: 10px;">
+		<a href="{{getServerAddress}}/oidc-login"><button type="button" style="font-weight: bold;">Login with {{getOIDCName}}</button></a>
+	</div>
+	{{end}}
+	<form id="form-2fa" method="POST" class="box container-inline" style="width: 100%; display: none;" onSubmit="handleVerifyCode(); return false;">
+		<div style="grid-row: 1;"><label for="code">Two-factor code: <span class="mandatory">*</span></label></div>
+		<div style="grid-row: 2;"><input type="text" id="code" name="code" autocomplete="one-time-code" inputmode="numeric"/></div>
+		<div style="grid-row: 3; grid-column: 2; text-align: right;">
+			<button type="submit" style="font-weight: bold;">Verify</button>
+		</div>
+	</form>
 </div>
 <div>
 	<p>Don't' have an account yet? Register <a href="{{getServerAddress}}/account/?path=register">here</a>.</p>
@@ -24,6 +24,16 @@ function handleAccountSettings() {
 	window.location.replace("{{getServerAddress}}/account/?path=settings");
 }
 
+function handleNotifications() {
+	setState(STATE_STATUS, "Redirecting to the notifications...");
+	window.location.replace("{{getServerAddress}}/account/?path=notifications");
+}
+
+function handleTwoFactor() {
+	setState(STATE_STATUS, "Redirecting to the two-factor authentication...");
+	window.location.replace("{{getServerAddress}}/account/?path=two-factor");
+}
+
 function handleEditAccount() {
 	setState(STATE_STATUS, "Redirecting to the account editor...");
 	window.location.replace("{{getServerAddress}}/account/?path=edit");
@@ -34,11 +44,25 @@ function handleSitesSettings() {
 	window.location.replace("{{getServerAddress}}/account/?path=sites");
 }
 
+function handleAPIKeys() {
+	setState(STATE_STATUS, "Redirecting to the API keys...");
+	window.location.replace("{{getServerAddress}}/account/?path=api-keys");
+}
+
 function handleRequestAccess(scope) {
 	setState(STATE_STATUS, "Redirecting to the contact form...");		
 	window.location.replace("{{getServerAddress}}/account/?path=contact&subject=" + encodeURIComponent("Request " + scope + " access"));
 }
 
+function handleExportAccount() {
+	window.location.assign("{{getServerAddress}}/export-account?format=zip");
+}
+
+function handleDeleteAccount() {
+	setState(STATE_STATUS, "Redirecting to the account deletion...");
+	window.location.replace("{{getServerAddress}}/account/?path=delete");
+}
+
 function handleLogout() {
 	var xhr = new XMLHttpRequest();
     xhr.open("GET", "{{getServerAddress}}/logout");
@@ -95,6 +119,7 @@ const tplBody = `
 	<ul style="margin-top: 0em;">	
 		<li>Sites access: <em>{{if .Account.Data.SitesAccess}}Granted{{else}}Not granted{{end}}</em></li>
 		<li>GOCDB access: <em>{{if .Account.Data.GOCDBAccess}}Granted{{else}}Not granted{{end}}</em></li>	
+		<li>Two-factor authentication: <em>{{if .Account.TwoFactor.Enabled}}Enabled{{else}}Disabled{{end}}</em></li>
 	</ul>
 </div>
 <div>
@@ -102,10 +127,15 @@ const tplBody = `
 		<div>
 			<button type="button" onClick="handleAccountSettings();">Account settings</button>
 			<button type="button" onClick="handleEditAccount();">Edit account</button>
+			<button type="button" onClick="handleTwoFactor();">Two-factor authentication</button>
+			<button type="button" onClick="handleNotifications();">Notifications{{with .Account.Notifications.Messages}} ({{len .}}){{end}}</button>
 			<span style="width: 25px;">&nbsp;</span>
 			
-			{{if .Account.Data.SitesAccess}}
+			{{if or .Account.Data.SitesAccess .ManagedSites}}
 			<button type="button" onClick="handleSitesSettings();">Sites settings</button>
+			{{if .Account.Data.SitesAccess}}
+			<button type="button" onClick="handleAPIKeys();">API keys</button>
+			{{end}}
 			<span style="width: 25px;">&nbsp;</span>
 			{{end}}	
 
@@ -114,6 +144,8 @@ const tplBody = `
 		<div style="margin-top: 0.5em;">
 			<button type="button" onClick="handleRequestAccess('Sites');" {{if .Account.Data.SitesAccess}}disabled{{end}}>Request Sites access</button>
 			<button type="button" onClick="handleRequestAccess('GOCDB');" {{if .Account.Data.GOCDBAccess}}disabled{{end}}>Request GOCDB access</button>	
+			<button type="button" onClick="handleDeleteAccount();" style="float: right;">Delete account</button>
+			<button type="button" onClick="handleExportAccount();" style="float: right;">Export my data</button>
 		</div>
 	</form>
 </div>
@@ -0,0 +1,166 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package notifications
+
+const tplJavaScript = `
+function getMutedTypes(formData, channel) {
+	var types = [];
+	for (const type of ["site_added", "site_removed", "site_unhealthy"]) {
+		if (formData.get(channel + "_" + type) !== "on") {
+			types.push(type);
+		}
+	}
+	return types;
+}
+
+function handleAction(action) {
+	const formData = new FormData(document.querySelector("form"));
+
+	setState(STATE_STATUS, "Configuring notifications... this should only take a moment.", "form", null, false);
+
+	var xhr = new XMLHttpRequest();
+    xhr.open("POST", "{{getServerAddress}}/" + action);
+    xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
+
+	xhr.onload = function() {
+		if (this.status == 200) {
+			setState(STATE_SUCCESS, "Your notification settings were successfully saved!", "form", null, true);
+		} else {
+			var resp = JSON.parse(this.responseText);
+			setState(STATE_ERROR, "An error occurred while trying to save your notification settings:<br><em>" + resp.error + "</em>", "form", null, true);
+		}
+	}
+
+	var postData = {
+		"notifications": {
+			"preferences": {
+				"mutedEmails": getMutedTypes(formData, "email"),
+				"mutedPanel": getMutedTypes(formData, "panel")
+			}
+		}
+    };
+
+    xhr.send(JSON.stringify(postData));
+}
+
+function handleClear() {
+	var xhr = new XMLHttpRequest();
+    xhr.open("POST", "{{getServerAddress}}/clear-notifications");
+    xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
+
+	setState(STATE_STATUS, "Clearing notifications...");
+
+	xhr.onload = function() {
+		if (this.status == 200) {
+			window.location.reload();
+		} else {
+			var resp = JSON.parse(this.responseText);
+			setState(STATE_ERROR, "An error occurred while clearing your notifications:<br><em>" + resp.error + "</em>");
+		}
+	}
+
+    xhr.send("{}");
+}
+`
+
+const tplStyleSheet = `
+html * {
+	font-family: arial !important;
+}
+
+input[type="checkbox"] {
+	width: auto;
+}
+
+.notification {
+	margin-bottom: 0.5em;
+}
+
+.notification-date {
+	font-size: 80%;
+	color: gray;
+}
+`
+
+const tplBody = `
+<div>
+	<p>Below are the most recent notifications about your sites.</p>
+</div>
+<div class="box" style="width: 100%;">
+	{{with .Account.Notifications.Recent}}
+	{{range .}}
+	<div class="notification">
+		<div class="notification-date">{{.Date.Format "2006-01-02 15:04"}}</div>
+		<div>{{.Message}}</div>
+	</div>
+	{{end}}
+	<div style="text-align: right;"><button type="button" onClick="handleClear();">Clear notifications</button></div>
+	{{else}}
+	<em>There are no notifications.</em>
+	{{end}}
+</div>
+<div>&nbsp;</div>
+<div>
+	<form id="form" method="POST" class="box container-inline" style="width: 100%;" onSubmit="handleAction('configure-notifications'); return false;">
+		<div style="grid-row: 1; grid-column: 1 / span 2;">
+			<h3>Notification preferences</h3>
+			<hr>
+		</div>
+
+		{{$prefs := .Account.Notifications.Preferences}}
+		<div style="grid-row: 2; grid-column: 1 / span 2;"><strong>A site of yours has been added to the mesh:</strong></div>
+		<div style="grid-row: 3;">
+			<input type="checkbox" id="email_site_added" name="email_site_added" value="on" {{if $prefs.WantsEmail "site_added"}}checked{{end}}/>
+			<label for="email_site_added" style="font-weight: normal;">Send an email</label>
+		</div>
+		<div style="grid-row: 3;">
+			<input type="checkbox" id="panel_site_added" name="panel_site_added" value="on" {{if $prefs.WantsPanel "site_added"}}checked{{end}}/>
+			<label for="panel_site_added" style="font-weight: normal;">Show in the account panel</label>
+		</div>
+
+		<div style="grid-row: 4; grid-column: 1 / span 2;"><strong>A site of yours has been removed from the mesh:</strong></div>
+		<div style="grid-row: 5;">
+			<input type="checkbox" id="email_site_removed" name="email_site_removed" value="on" {{if $prefs.WantsEmail "site_removed"}}checked{{end}}/>
+			<label for="email_site_removed" style="font-weight: normal;">Send an email</label>
+		</div>
+		<div style="grid-row: 5;">
+			<input type="checkbox" id="panel_site_removed" name="panel_site_removed" value="on" {{if $prefs.WantsPanel "site_removed"}}checked{{end}}/>
+			<label for="panel_site_removed" style="font-weight: normal;">Show in the account panel</label>
+		</div>
+
+		<div style="grid-row: 6; grid-column: 1 / span 2;"><strong>A site of yours has been flagged as unhealthy:</strong></div>
+		<div style="grid-row: 7;">
+			<input type="checkbox" id="email_site_unhealthy" name="email_site_unhealthy" value="on" {{if $prefs.WantsEmail "site_unhealthy"}}checked{{end}}/>
+			<label for="email_site_unhealthy" style="font-weight: normal;">Send an email</label>
+		</div>
+		<div style="grid-row: 7;">
+			<input type="checkbox" id="panel_site_unhealthy" name="panel_site_unhealthy" value="on" {{if $prefs.WantsPanel "site_unhealthy"}}checked{{end}}/>
+			<label for="panel_site_unhealthy" style="font-weight: normal;">Show in the account panel</label>
+		</div>
+
+		<div style="grid-row: 8; grid-column: 2; text-align: right;">
+			<button type="reset">Reset</button>
+			<button type="submit" style="font-weight: bold;">Save</button>
+		</div>
+	</form>
+</div>
+<div>
+	<p>Go <a href="{{getServerAddress}}/account/?path=manage">back</a> to the main account page.</p>
+</div>
+`
@@ -23,15 +23,23 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/cs3org/reva/pkg/siteacc/account/apikeys"
 	"github.com/cs3org/reva/pkg/siteacc/account/contact"
+	"github.com/cs3org/reva/pkg/siteacc/account/deletion"
 	"github.com/cs3org/reva/pkg/siteacc/account/edit"
 	"github.com/cs3org/reva/pkg/siteacc/account/login"
 	"github.com/cs3org/reva/pkg/siteacc/account/manage"
+	"github.com/cs3org/reva/pkg/siteacc/account/notifications"
 	"github.com/cs3org/reva/pkg/siteacc/account/registration"
+	"github.com/cs3org/reva/pkg/siteacc/account/restore"
 	"github.com/cs3org/reva/pkg/siteacc/account/settings"
+	"github.com/cs3org/reva/pkg/siteacc/account/siteedit"
 	"github.com/cs3org/reva/pkg/siteacc/account/sites"
+	"github.com/cs3org/reva/pkg/siteacc/account/siteusage"
+	"github.com/cs3org/reva/pkg/siteacc/account/twofactor"
 	"github.com/cs3org/reva/pkg/siteacc/config"
 	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/cs3org/reva/pkg/siteacc/email"
 	"github.com/cs3org/reva/pkg/siteacc/html"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
@@ -47,13 +55,20 @@ type Panel struct {
 }
 
 const (
-	templateLogin        = "login"
-	templateManage       = "manage"
-	templateSettings     = "settings"
-	templateEdit         = "edit"
-	templateSites        = "sites"
-	templateContact      = "contact"
-	templateRegistration = "register"
+	templateLogin         = "login"
+	templateManage        = "manage"
+	templateSettings      = "settings"
+	templateEdit          = "edit"
+	templateSites         = "sites"
+	templateSiteEdit      = "site-edit"
+	templateSiteUsage     = "site-usage"
+	templateAPIKeys       = "api-keys"
+	templateContact       = "contact"
+	templateRegistration  = "register"
+	templateDeletion      = "delete"
+	templateRestore       = "cancel-deletion"
+	templateNotifications = "notifications"
+	templateTwoFactor     = "two-factor"
 )
 
 func (panel *Panel) initialize(conf *config.Configuration, log *zerolog.Logger) error {
@@ -90,6 +105,18 @@ func (panel *Panel) initialize(conf *config.Configuration, log *zerolog.Logger)
 		return errors.Wrap(err, "unable to create the sites template")
 	}
 
+	if err := panel.htmlPanel.AddTemplate(templateSiteEdit, &siteedit.PanelTemplate{}); err != nil {
+		return errors.Wrap(err, "unable to create the site editing template")
+	}
+
+	if err := panel.htmlPanel.AddTemplate(templateSiteUsage, &siteusage.PanelTemplate{}); err != nil {
+		return errors.Wrap(err, "unable to create the site usage template")
+	}
+
+	if err := panel.htmlPanel.AddTemplate(templateAPIKeys, &apikeys.PanelTemplate{}); err != nil {
+		return errors.Wrap(err, "unable to create the API keys template")
+	}
+
 	if err := panel.htmlPanel.AddTemplate(templateContact, &contact.PanelTemplate{}); err != nil {
 		return errors.Wrap(err, "unable to create the contact template")
 	}
@@ -98,12 +125,28 @@ func (panel *Panel) initialize(conf *config.Configuration, log *zerolog.Logger)
 		return errors.Wrap(err, "unable to create the registration template")
 	}
 
+	if err := panel.htmlPanel.AddTemplate(templateNotifications, &notifications.PanelTemplate{}); err != nil {
+		return errors.Wrap(err, "unable to create the notifications template")
+	}
+
+	if err := panel.htmlPanel.AddTemplate(templateTwoFactor, &twofactor.PanelTemplate{}); err != nil {
+		return errors.Wrap(err, "unable to create the two-factor authentication template")
+	}
+
+	if err := panel.htmlPanel.AddTemplate(templateDeletion, &deletion.PanelTemplate{}); err != nil {
+		return errors.Wrap(err, "unable to create the account deletion template")
+	}
+
+	if err := panel.htmlPanel.AddTemplate(templateRestore, &restore.PanelTemplate{}); err != nil {
+		return errors.Wrap(err, "unable to create the account restoration template")
+	}
+
 	return nil
 }
 
 // GetActiveTemplate returns the name of the active template.
 func (panel *Panel) GetActiveTemplate(session *html.Session, path string) string {
-	validPaths := []string{templateLogin, templateManage, templateSettings, templateEdit, templateSites, templateContact, templateRegistration}
+	validPaths := []string{templateLogin, templateManage, templateSettings, templateEdit, templateSites, templateSiteEdit, templateSiteUsage, templateAPIKeys, templateContact, templateRegistration, templateNotifications, templateTwoFactor, templateDeletion, templateRestore}
 	template := templateLogin
 
 	// Only allow valid template paths; redirect to the login page otherwise
@@ -119,16 +162,36 @@ func (panel *Panel) GetActiveTemplate(session *html.Session, path string) string
 
 // PreExecute is called before the actual template is being executed.
 func (panel *Panel) PreExecute(session *html.Session, path string, w http.ResponseWriter, r *http.Request) (html.ExecutionResult, error) {
-	protectedPaths := []string{templateManage, templateSettings, templateEdit, templateSites, templateContact}
+	protectedPaths := []string{templateManage, templateSettings, templateEdit, templateSites, templateSiteEdit, templateSiteUsage, templateAPIKeys, templateContact, templateNotifications, templateTwoFactor, templateDeletion}
+
+	// Users whose account has been disabled in the meantime are logged out
+	if user := session.LoggedInUser(); user != nil && user.Account.IsDisabled() {
+		session.LogoutUser()
+	}
 
 	if user := session.LoggedInUser(); user != nil {
 		switch path {
-		case templateSites:
-			// If the logged in user doesn't have sites access, redirect him back to the main account page
+		case templateSites, templateSiteEdit, templateSiteUsage:
+			// If the logged in user neither has sites access nor manages any sites of other operators, redirect them back to the main account page
+			if !user.Account.Data.SitesAccess && len(user.ManagedSites) == 0 {
+				return panel.redirect(templateManage, w, r), nil
+			}
+
+			// If the operator requires two-factor authentication, the user needs to enable it first
+			if user.Account.RequiresTwoFactor(user.Operator) && !user.Account.TwoFactor.Enabled {
+				return panel.redirect(templateTwoFactor, w, r), nil
+			}
+
+		case templateAPIKeys:
+			// API keys grant access to all sites of the operator, so only users with sites access may manage them
 			if !user.Account.Data.SitesAccess {
 				return panel.redirect(templateManage, w, r), nil
 			}
 
+			if user.Account.RequiresTwoFactor(user.Operator) && !user.Account.TwoFactor.Enabled {
+				return panel.redirect(templateTwoFactor, w, r), nil
+			}
+
 		case templateLogin:
 		case templateRegistration:
 			// If a user is logged in and tries to login or register again, redirect to the main account page
@@ -154,7 +217,7 @@ func (panel *Panel) Execute(w http.ResponseWriter, r *http.Request, session *htm
 			flatValues[strings.Title(k)] = v[0]
 		}
 
-		availOps, err := data.QueryAvailableOperators(panel.conf.Mentix.URL, panel.conf.Mentix.DataEndpoint)
+		availOps, err := data.QueryAvailableOperators(panel.conf.Mentix.URL, panel.conf.Mentix.DataEndpoint, &panel.conf.Mentix.Signing)
 		if err != nil {
 			return errors.Wrap(err, "unable to query available operators")
 		}
@@ -164,18 +227,24 @@ func (panel *Panel) Execute(w http.ResponseWriter, r *http.Request, session *htm
 			Account  *data.Account
 			Params   map[string]string
 
+			// ManagedSites holds the sites of other operators the user has been granted management rights over.
+			ManagedSites []*data.Site
+
 			Operators []data.OperatorInformation
 			Sites     map[string]string
 			Titles    []string
+			Languages []string
 		}
 
 		tplData := TemplateData{
-			Operator:  nil,
-			Account:   nil,
-			Params:    flatValues,
-			Operators: availOps,
-			Sites:     make(map[string]string, 10),
-			Titles:    []string{"Mr", "Mrs", "Ms", "Prof", "Dr"},
+			Operator:     nil,
+			Account:      nil,
+			Params:       flatValues,
+			Operators:    availOps,
+			Sites:        make(map[string]string, 10),
+			Titles:       []string{"Mr", "Mrs", "Ms", "Prof", "Dr"},
+			Languages:    email.AvailableLanguages(*panel.conf),
+			ManagedSites: []*data.Site{},
 		}
 		if user := session.LoggedInUser(); user != nil {
 			availSites, err := panel.fetchAvailableSites(user.Operator)
@@ -183,9 +252,19 @@ func (panel *Panel) Execute(w http.ResponseWriter, r *http.Request, session *htm
 				return errors.Wrap(err, "unable to query available sites")
 			}
 
-			tplData.Operator = panel.cloneUserOperator(user.Operator, availSites)
+			tplData.Operator = panel.cloneUserOperator(user.Operator, availSites, user.Account.Data.SitesAccess)
 			tplData.Account = user.Account
 			tplData.Sites = availSites
+
+			// Sites managed on behalf of other operators are listed separately
+			for _, op := range user.ManagedSites {
+				for _, site := range op.Sites {
+					site = site.Clone(false)
+					panel.decryptCredentials(site)
+					tplData.ManagedSites = append(tplData.ManagedSites, site)
+					tplData.Sites[site.ID] = panel.fetchSiteName(site.ID)
+				}
+			}
 		}
 		return tplData
 	}
@@ -211,29 +290,30 @@ func (panel *Panel) redirect(path string, w http.ResponseWriter, r *http.Request
 }
 
 func (panel *Panel) fetchAvailableSites(op *data.Operator) (map[string]string, error) {
-	ids, err := data.QueryOperatorSites(op.ID, panel.conf.Mentix.URL, panel.conf.Mentix.DataEndpoint)
+	ids, err := data.QueryOperatorSites(op.ID, panel.conf.Mentix.URL, panel.conf.Mentix.DataEndpoint, &panel.conf.Mentix.Signing)
 	if err != nil {
 		return nil, err
 	}
 	sites := make(map[string]string, 10)
 	for _, id := range ids {
-		if siteName, _ := data.QuerySiteName(id, true, panel.conf.Mentix.URL, panel.conf.Mentix.DataEndpoint); err == nil {
-			sites[id] = siteName
-		} else {
-			sites[id] = id
-		}
+		sites[id] = panel.fetchSiteName(id)
 	}
 	return sites, nil
 }
 
-func (panel *Panel) cloneUserOperator(op *data.Operator, sites map[string]string) *data.Operator {
-	// Clone the user's operator and decrypt all credentials for the panel
-	opClone := op.Clone(false)
-	for _, site := range opClone.Sites {
-		id, secret, err := site.Config.TestClientCredentials.Get(panel.conf.Security.CredentialsPassphrase)
-		if err == nil {
-			site.Config.TestClientCredentials.ID = id
-			site.Config.TestClientCredentials.Secret = secret
+func (panel *Panel) fetchSiteName(id string) string {
+	if siteName, err := data.QuerySiteName(id, true, panel.conf.Mentix.URL, panel.conf.Mentix.DataEndpoint, &panel.conf.Mentix.Signing); err == nil {
+		return siteName
+	}
+	return id
+}
+
+func (panel *Panel) cloneUserOperator(op *data.Operator, sites map[string]string, decryptCredentials bool) *data.Operator {
+	// Clone the user's operator; the credentials are only decrypted for users allowed to access all sites of the operator
+	opClone := op.Clone(!decryptCredentials)
+	if decryptCredentials {
+		for _, site := range opClone.Sites {
+			panel.decryptCredentials(site)
 		}
 	}
 
@@ -257,6 +337,19 @@ func (panel *Panel) cloneUserOperator(op *data.Operator, sites map[string]string
 	return opClone
 }
 
+func (panel *Panel) decryptCredentials(site *data.Site) {
+	id, secret, err := site.Config.TestClientCredentials.Get(panel.conf.Security.CredentialsPassphrase)
+	if err == nil {
+		site.Config.TestClientCredentials.ID = id
+		site.Config.TestClientCredentials.Secret = secret
+	}
+}
+
+// SetAnnouncementsProvider sets the provider of the announcements shown on top of the panel.
+func (panel *Panel) SetAnnouncementsProvider(provider html.AnnouncementsProvider) {
+	panel.htmlPanel.SetAnnouncementsProvider(provider)
+}
+
 // NewPanel creates a new account panel.
 func NewPanel(conf *config.Configuration, log *zerolog.Logger) (*Panel, error) {
 	form := &Panel{}
@@ -83,7 +83,12 @@ function handleAction(action) {
 			}, 3000);
 		} else {
 			var resp = JSON.parse(this.responseText);
-			setState(STATE_ERROR, "An error occurred while trying to register your account:<br><em>" + resp.error + "</em>", "form", null, true);
+			if (resp.violations) {
+				var rules = resp.violations.map(function(violation) { return "<li>" + violation.message + "</li>"; });
+				setState(STATE_ERROR, "The password does not fulfil the password policy:<ul>" + rules.join("") + "</ul>", "form", "password", true);
+			} else {
+				setState(STATE_ERROR, "An error occurred while trying to register your account:<br><em>" + resp.error + "</em>", "form", null, true);
+			}
 		}
 	}
 
@@ -99,7 +104,9 @@ function handleAction(action) {
 			"value": formData.get("password")
 		}
     };
-
+{{with getCaptcha}}
+	postData["captcha"] = formData.get("{{.ResponseField}}");
+{{end}}
     xhr.send(JSON.stringify(postData));
 }
 `
@@ -164,13 +171,19 @@ const tplBody = `
 		<div style="grid-row: 13; font-style: italic; font-size: 0.8em;">
 			The password must fulfil the following criteria:
 			<ul style="margin-top: 0em;">
-				<li>Must be at least 8 characters long</li>
-				<li>Must contain at least 1 lowercase letter</li>
-				<li>Must contain at least 1 uppercase letter</li>
-				<li>Must contain at least 1 digit</li>
+				{{range getPasswordRequirements}}
+				<li>{{.}}</li>
+				{{end}}
 			</ul>
 		</div>
 
+		{{with getCaptcha}}
+		<div style="grid-row: 13; grid-column: 2; justify-self: end; align-self: center;">
+			<script src="{{.Script}}" async defer></script>
+			<div class="{{.Class}}" data-sitekey="{{.SiteKey}}"></div>
+		</div>
+		{{end}}
+
 		<div style="grid-row: 14; align-self: center;">
 			Fields marked with <span class="mandatory">*</span> are mandatory.
 		</div>
@@ -0,0 +1,102 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package restore
+
+const tplJavaScript = `
+function getDeletionData() {
+	return {
+		"email": "{{.Params.Email}}",
+		"token": "{{.Params.Token}}"
+	};
+}
+
+function handleExport() {
+	var xhr = new XMLHttpRequest();
+    xhr.open("POST", "{{getServerAddress}}/export-account?format=zip");
+    xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
+
+	setState(STATE_STATUS, "Exporting your account data...", "form", null, false);
+
+	xhr.responseType = "blob";
+
+	xhr.onload = function() {
+		if (this.status == 200) {
+			var link = document.createElement("a");
+			link.href = URL.createObjectURL(this.response);
+			link.download = "sciencemesh-account.zip";
+			link.click();
+			URL.revokeObjectURL(link.href);
+
+			setState(STATE_SUCCESS, "Your account data has been exported.", "form", null, true);
+		} else {
+			this.response.text().then(function(text) {
+				var errMsg = document.createElement("em");
+				errMsg.textContent = text;
+				setState(STATE_ERROR, "An error occurred while exporting your account data:<br>" + errMsg.outerHTML, "form", null, true);
+			});
+		}
+	}
+
+	xhr.send(JSON.stringify(getDeletionData()));
+}
+
+function handleCancel() {
+	var xhr = new XMLHttpRequest();
+    xhr.open("POST", "{{getServerAddress}}/cancel-deletion");
+    xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
+
+	setState(STATE_STATUS, "Canceling the deletion of your account...", "form", null, false);
+
+	xhr.onload = function() {
+		if (this.status == 200) {
+			setState(STATE_SUCCESS, "The deletion of your account has been canceled! You can now <a href=\"{{getServerAddress}}/account/?path=login\">log in</a> again.");
+		} else {
+			var resp = JSON.parse(this.responseText);
+			setState(STATE_ERROR, "An error occurred while canceling the deletion of your account:<br><em>" + resp.error + "</em>", "form", null, true);
+		}
+	}
+
+	xhr.send(JSON.stringify(getDeletionData()));
+}
+`
+
+const tplStyleSheet = `
+html * {
+	font-family: arial !important;
+}
+button {
+	min-width: 170px;
+}
+`
+
+const tplBody = `
+<div>
+	<p>The account <em>{{.Params.Email}}</em> has been disabled and is scheduled for deletion.</p>
+	<p>Until the account is deleted permanently, you can still export its data or cancel the deletion.</p>
+</div>
+<div>&nbsp;</div>
+<div>
+	<form id="form" method="POST" class="box" style="width: 100%;" onSubmit="handleCancel(); return false;">
+		<div>
+			<button type="button" onClick="handleExport();">Export account data</button>
+			<button type="submit" style="float: right; font-weight: bold;">Cancel deletion</button>
+		</div>
+	</form>
+</div>
+`
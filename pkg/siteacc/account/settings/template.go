@@ -46,7 +46,8 @@ function handleAction(action) {
 
 	var postData = {
 		"settings": {
-			"receiveAlerts": (formData.get("rcvAlerts") === "on")
+			"receiveAlerts": (formData.get("rcvAlerts") === "on"),
+			"language": formData.get("language")
 		}
     };
 
@@ -81,7 +82,22 @@ const tplBody = `
 			<label for="rcvAlerts" style="font-weight: normal;">Receive email notifications about sites alerts <em>(mandatory; always on)</em></label>
 		</div>
 
-		<div style="grid-row: 3; grid-column: 2; text-align: right;">
+		<div style="grid-row: 3; grid-column: 1 / span 2;">
+			<h3>Language settings</h3>
+			<hr>
+		</div>
+
+		<div style="grid-row: 4; grid-column: 1;">
+			<label for="language">Language of emails:</label>
+			<select id="language" name="language">
+				<option value="">Default</option>
+				{{range .Languages}}
+				<option value="{{.}}" {{if eq . $.Account.Settings.Language}}selected{{end}}>{{.}}</option>
+				{{end}}
+			</select>
+		</div>
+
+		<div style="grid-row: 5; grid-column: 2; text-align: right;">
 			<button type="reset">Reset</button>
 			<button type="submit" style="font-weight: bold;">Save</button>
 		</div>
@@ -0,0 +1,203 @@
+// Copyright 2018-2020 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package siteedit
+
+const tplJavaScript = `
+var baseData = null;
+
+function siteURL(action, dryRun) {
+	var url = "{{getServerAddress}}/" + action + "?invoker=user&site=" + encodeURIComponent("{{.Params.Site}}");
+	if (dryRun) {
+		url += "&dryrun=true";
+	}
+	return url;
+}
+
+function escapeHTML(text) {
+	var div = document.createElement("div");
+	div.innerText = text;
+	return div.innerHTML;
+}
+
+function loadSiteData() {
+	setState(STATE_STATUS, "Loading the site data... this should only take a moment.", "form", null, false);
+
+	var xhr = new XMLHttpRequest();
+	xhr.open("GET", siteURL("site-data", false));
+
+	xhr.onload = function() {
+		var resp = JSON.parse(this.responseText);
+		if (this.status == 200) {
+			baseData = resp.data.site;
+			renderSiteData(baseData);
+			setState(STATE_NONE, "", "form", null, true);
+		} else {
+			setState(STATE_ERROR, "An error occurred while trying to load the site data:<br><em>" + resp.error + "</em>", "form", null, false);
+		}
+	}
+
+	xhr.send();
+}
+
+function renderSiteData(siteData) {
+	var props = "";
+	Object.keys(siteData.properties || {}).sort().forEach(function(key) {
+		props += "<tr><td><input type='text' class='prop-key' value='" + escapeHTML(key) + "' readonly/></td>";
+		props += "<td><input type='text' class='prop-value' value='" + escapeHTML(siteData.properties[key]) + "'/></td></tr>";
+	});
+	props += "<tr><td><input type='text' class='prop-key' placeholder='New property'/></td>";
+	props += "<td><input type='text' class='prop-value' placeholder='Value'/></td></tr>";
+	document.getElementById("properties").innerHTML = props;
+
+	var endpoints = "";
+	(siteData.endpoints || []).forEach(function(ep, index) {
+		var name = ep.name ? ep.service + " (" + ep.name + ")" : ep.service;
+		endpoints += "<tr><td><em>" + escapeHTML(name) + "</em></td>";
+		endpoints += "<td><input type='text' class='ep-url' data-index='" + index + "' value='" + escapeHTML(ep.url) + "'/></td>";
+		endpoints += "<td><input type='checkbox' class='ep-monitored' data-index='" + index + "'" + (ep.isMonitored ? " checked" : "") + "/></td></tr>";
+	});
+	document.getElementById("endpoints").innerHTML = endpoints;
+}
+
+function collectSiteData() {
+	var siteData = {"properties": {}, "endpoints": []};
+
+	var keys = document.querySelectorAll(".prop-key");
+	var values = document.querySelectorAll(".prop-value");
+	for (var i = 0; i < keys.length; i++) {
+		var key = keys[i].value.trim();
+		if (key != "") {
+			siteData.properties[key] = values[i].value.trim();
+		}
+	}
+
+	(baseData.endpoints || []).forEach(function(ep, index) {
+		siteData.endpoints.push({
+			"service": ep.service,
+			"name": ep.name,
+			"url": document.querySelector(".ep-url[data-index='" + index + "']").value.trim(),
+			"isMonitored": document.querySelector(".ep-monitored[data-index='" + index + "']").checked
+		});
+	});
+
+	return siteData;
+}
+
+function formatChanges(changes) {
+	var text = "<ul>";
+	changes.forEach(function(change) {
+		text += "<li><strong>" + escapeHTML(change.key) + "</strong>: <em>" + escapeHTML(change.oldValue) + "</em> &rarr; <em>" + escapeHTML(change.newValue) + "</em></li>";
+	});
+	return text + "</ul>";
+}
+
+function handleAction(dryRun) {
+	if (baseData == null) {
+		return;
+	}
+
+	if (dryRun) {
+		setState(STATE_STATUS, "Previewing changes... this should only take a moment.", "form", null, false);
+	} else {
+		setState(STATE_STATUS, "Saving changes... this should only take a moment.", "form", null, false);
+	}
+
+	var xhr = new XMLHttpRequest();
+	xhr.open("POST", siteURL("site-update", dryRun));
+	xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
+
+	xhr.onload = function() {
+		var resp = JSON.parse(this.responseText);
+		if (this.status != 200) {
+			setState(STATE_ERROR, "An error occurred while trying to update the site:<br><em>" + resp.error + "</em>", "form", null, true);
+			return;
+		}
+
+		var update = resp.data.update;
+		if (update.conflicts && update.conflicts.length > 0) {
+			setState(STATE_ERROR, "The site has been modified in the meantime; the following values conflict with your changes:" + formatChanges(update.conflicts) + "Please reload the page and apply your changes again.", "form", null, true);
+		} else if (!update.changes || update.changes.length == 0) {
+			setState(STATE_SUCCESS, "There are no changes to save.", "form", null, true);
+		} else if (update.dryRun) {
+			setState(STATE_SUCCESS, "The following changes will be made:" + formatChanges(update.changes), "form", null, true);
+		} else {
+			setState(STATE_SUCCESS, "Your site was successfully updated! It may take a while until the changes are visible everywhere." + formatChanges(update.changes), "form", null, true);
+		}
+	}
+
+	var postData = {
+		"base": baseData,
+		"data": collectSiteData()
+	};
+
+	xhr.send(JSON.stringify(postData));
+}
+
+window.addEventListener("load", loadSiteData);
+`
+
+const tplStyleSheet = `
+html * {
+	font-family: arial !important;
+}
+
+table {
+	width: 100%;
+}
+
+input[type="checkbox"] {
+	width: auto;
+}
+
+input[readonly] {
+	background: #eee;
+}
+`
+
+const tplBody = `
+<div>
+	<p>Edit the site <strong>{{index .Sites .Params.Site}}</strong> ({{.Params.Site}}) below. <em>Changes are written back to the GOCDB and affect the entire site.</em></p>
+</div>
+<div>&nbsp;</div>
+<div>
+	<form id="form" method="POST" class="box" style="width: 100%;" onSubmit="handleAction(false); return false;">
+		<h3>Properties</h3>
+		<table>
+			<thead><tr><th>Name</th><th>Value</th></tr></thead>
+			<tbody id="properties"></tbody>
+		</table>
+
+		<h3>Endpoints</h3>
+		<table>
+			<thead><tr><th>Service</th><th>URL</th><th>Monitored</th></tr></thead>
+			<tbody id="endpoints"></tbody>
+		</table>
+
+		<div>&nbsp;</div>
+		<div style="text-align: right;">
+			<button type="button" onClick="renderSiteData(baseData);">Reset</button>
+			<button type="button" onClick="handleAction(true);">Preview changes</button>
+			<button type="submit" style="font-weight: bold;">Save</button>
+		</div>
+	</form>
+</div>
+<div>
+	<p>Go <a href="{{getServerAddress}}/account/?path=sites">back</a> to the sites page.</p>
+</div>
+`
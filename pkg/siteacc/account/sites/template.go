@@ -76,6 +76,138 @@ function handleAction(action) {
 
     xhr.send(JSON.stringify(postData));
 }
+
+function issueSiteKey(site, rotate, form) {
+	form = form || "form";
+	if (rotate && !confirm("Rotating the key will immediately invalidate the current key of this site. Continue?")) {
+		return;
+	}
+
+	setState(STATE_STATUS, "Issuing site key... this should only take a moment.", form, null, false);
+
+	var xhr = new XMLHttpRequest();
+    xhr.open("POST", "{{getServerAddress}}/issue-site-key?invoker=user&site=" + encodeURIComponent(site));
+    xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
+
+	xhr.onload = function() {
+		var resp = JSON.parse(this.responseText);
+		if (this.status == 200) {
+			setState(STATE_SUCCESS, "A new key has been issued for site " + site + ":<br><code>" + resp.data.key + "</code><br>Configure it in your IOP instance to push metrics. <strong>Copy it now, as it will not be shown again!</strong>", form, null, true);
+		} else {
+			setState(STATE_ERROR, "An error occurred while trying to issue the site key:<br><em>" + resp.error + "</em>", form, null, true);
+		}
+	}
+
+    xhr.send("{}");
+}
+
+function importSites(dryRun) {
+	const file = document.getElementById("sites-file").files[0];
+	if (!file) {
+		setState(STATE_ERROR, "Please select a file to upload.", "form-bulk", "sites-file", true);
+		return;
+	}
+	const format = file.name.toLowerCase().endsWith(".json") ? "json" : "csv";
+
+	setState(STATE_STATUS, (dryRun ? "Validating" : "Importing") + " the sites... this should only take a moment.", "form-bulk", null, false);
+
+	var reader = new FileReader();
+	reader.onload = function() {
+		var xhr = new XMLHttpRequest();
+		xhr.open("POST", "{{getServerAddress}}/sites-import?invoker=user&format=" + format + (dryRun ? "&dryrun=true" : ""));
+		xhr.setRequestHeader('Content-Type', format == "json" ? 'application/json; charset=UTF-8' : 'text/csv; charset=UTF-8');
+
+		xhr.onload = function() {
+			var resp = JSON.parse(this.responseText);
+			if (this.status != 200) {
+				setState(STATE_ERROR, "An error occurred while trying to import the sites:<br><em>" + escapeHTML(resp.error) + "</em>", "form-bulk", null, true);
+				return;
+			}
+
+			var errors = resp.data.results.filter(function(result) { return result.error; }).map(function(result) {
+				return escapeHTML((result.site || "?") + ": " + result.error);
+			});
+			if (!resp.data.valid) {
+				setState(STATE_ERROR, "The file contains errors; no sites have been changed:<br><em>" + errors.join("<br>") + "</em>", "form-bulk", null, true);
+			} else if (resp.data.dryRun) {
+				setState(STATE_SUCCESS, "The file is valid; importing it would configure " + resp.data.results.length + " site(s).", "form-bulk", null, true);
+			} else {
+				setState(STATE_SUCCESS, resp.data.imported + " site(s) have been configured successfully!", "form-bulk", null, true);
+			}
+		}
+
+		xhr.send(reader.result);
+	}
+	reader.readAsText(file);
+}
+
+function escapeHTML(text) {
+	var elem = document.createElement("span");
+	elem.textContent = text;
+	return elem.innerHTML;
+}
+
+function grantSiteManagement(site, email, grant) {
+	if (email == "") {
+		setState(STATE_ERROR, "Please enter the email address of the account to grant the management rights to.", "form", "manager-" + site, true);
+		return;
+	}
+	if (!grant && !confirm("Revoke the management rights of " + email + " over site " + site + "?")) {
+		return;
+	}
+
+	setState(STATE_STATUS, "Changing the site management rights... this should only take a moment.", "form", null, false);
+
+	var xhr = new XMLHttpRequest();
+    xhr.open("POST", "{{getServerAddress}}/grant-site-management?invoker=user&site=" + encodeURIComponent(site) + "&status=" + grant);
+    xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
+
+	xhr.onload = function() {
+		if (this.status == 200) {
+			window.location.reload();
+		} else {
+			var resp = JSON.parse(this.responseText);
+			setState(STATE_ERROR, "An error occurred while trying to change the site management rights:<br><em>" + resp.error + "</em>", "form", null, true);
+		}
+	}
+
+    xhr.send(JSON.stringify({"email": email}));
+}
+
+function configureManagedSite(site) {
+	const formData = new FormData(document.getElementById("form-" + site));
+	if (formData.getTrimmed("clientID") == "" || formData.get("secret") == "") {
+		setState(STATE_ERROR, "Please enter the name and password of the test user for site " + site + ".", "form-" + site, null, true);
+		return;
+	}
+
+	setState(STATE_STATUS, "Configuring site... this should only take a moment.", "form-" + site, null, false);
+
+	var xhr = new XMLHttpRequest();
+    xhr.open("POST", "{{getServerAddress}}/site-configure?invoker=user&site=" + encodeURIComponent(site));
+    xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
+
+	xhr.onload = function() {
+		if (this.status == 200) {
+			setState(STATE_SUCCESS, "The site was successfully configured!", "form-" + site, null, true);
+		} else {
+			var resp = JSON.parse(this.responseText);
+			setState(STATE_ERROR, "An error occurred while trying to configure the site:<br><em>" + resp.error + "</em>", "form-" + site, null, true);
+		}
+	}
+
+	var postData = {
+		"id": site,
+		"config": {
+			"testClientCredentials": {
+				"id": formData.getTrimmed("clientID"),
+				"secret": formData.get("secret")
+			}
+		}
+	};
+
+    xhr.send(JSON.stringify(postData));
+}
 `
 
 const tplStyleSheet = `
@@ -91,12 +223,31 @@ input[type="checkbox"] {
 	color: red;
 	font-weight: bold;
 }
+
+.health {
+	padding: 1px 6px;
+	border-radius: 4px;
+	font-size: 0.8em;
+	font-weight: bold;
+	text-transform: uppercase;
+	color: white;
+	background: gray;
+}
+
+.health-ok {
+	background: green;
+}
+
+.health-unreachable, .health-login-failed {
+	background: red;
+}
 `
 
 const tplBody = `
 <div>
 	<p>Configure your ScienceMesh Sites below. <em>These settings affect the entire sites and not just your account.</em></p>
 </div>
+{{if .Account.Data.SitesAccess}}
 <div>&nbsp;</div>
 <div>
 	<form id="form" method="POST" class="box container-inline" style="width: 100%;" onSubmit="handleAction('sites-configure?invoker=user'); return false;">
@@ -108,7 +259,8 @@ const tplBody = `
 
 		{{$row := 2}}{{$parent := .}}
 		{{range $index, $elem := .Operator.Sites}}
-			<div style="grid-row: {{$row}};"><em><strong>{{index $parent.Sites .ID}}</strong> ({{.ID}})</em></div>
+			<div style="grid-row: {{$row}};"><em><strong>{{index $parent.Sites .ID}}</strong> ({{.ID}})</em>{{if .Health}} <span class="health health-{{.Health.Status}}" title="{{if .Health.Message}}{{.Health.Message}} &ndash; {{end}}checked {{.Health.LastChecked.Format "Jan 02, 2006 15:04"}}">{{.Health.Status}}</span>{{end}}</div>
+			<div style="grid-row: {{$row}}; text-align: right;"><a href="{{getServerAddress}}/account/?path=site-usage&site={{.ID}}">Usage</a> | <a href="{{getServerAddress}}/account/?path=site-edit&site={{.ID}}">Edit site</a></div>
 
 			{{$clientID := print "clientID-" .ID}}
 			<div style="grid-row: {{add $row 1}};"><label for="{{$clientID}}">User name: <span class="mandatory">*</span></label></div>
@@ -117,9 +269,35 @@ const tplBody = `
 			<div style="grid-row: {{add $row 1}};"><label for="{{$secret}}">Password: <span class="mandatory">*</span></label></div>
 			<div style="grid-row: {{add $row 2}};"><input type="password" id="{{$secret}}" name="{{$secret}}" placeholder="Password" value="{{.Config.TestClientCredentials.Secret}}"/></div>
 	
-			<div style="grid-row: {{add $row 3}};">&nbsp;</div>
+			<div style="grid-row: {{add $row 3}}; grid-column: 1 / span 2;">
+				<label>Site key:</label>
+				{{if .Key}}
+				<em>Issued {{.Key.DateIssued.Format "Jan 02, 2006 15:04"}}; last used {{if .Key.LastSeen.IsZero}}never{{else}}{{.Key.LastSeen.Format "Jan 02, 2006 15:04"}}{{end}}</em>
+				<button type="button" onClick="issueSiteKey('{{.ID}}', true);" style="float: right;">Rotate key</button>
+				{{else}}
+				<em>No key issued yet</em>
+				<button type="button" onClick="issueSiteKey('{{.ID}}', false);" style="float: right;">Issue key</button>
+				{{end}}
+			</div>
+
+			<div style="grid-row: {{add $row 4}}; grid-column: 1 / span 2;">
+				<label>Co-managers:</label>
+				{{$siteID := .ID}}
+				{{range .Managers}}
+				<div>{{.}} <button type="button" onClick="grantSiteManagement('{{$siteID}}', '{{.}}', false);" style="float: right;">Revoke</button></div>
+				{{else}}
+				<em>Only the accounts of your operator with Sites access can manage this site</em>
+				{{end}}
+				{{$manager := print "manager-" .ID}}
+				<div>
+					<input type="email" id="{{$manager}}" name="{{$manager}}" placeholder="Email address of a registered account" style="width: 70%;"/>
+					<button type="button" onClick="grantSiteManagement('{{.ID}}', document.getElementById('{{$manager}}').value.trim(), true);" style="float: right;">Grant management</button>
+				</div>
+			</div>
+
+			<div style="grid-row: {{add $row 5}};">&nbsp;</div>
 			
-			{{$row = add $row 4}}
+			{{$row = add $row 6}}
 		{{end}}
 
 		<div style="grid-row: {{add $row 1}}; align-self: center;">
@@ -131,6 +309,67 @@ const tplBody = `
 		</div>
 	</form>
 </div>
+<div>&nbsp;</div>
+<div>
+	<form id="form-bulk" method="POST" class="box container-inline" style="width: 100%;" onSubmit="importSites(false); return false;">
+		<div style="grid-row: 1; grid-column: 1 / span 2;">
+			<h3>Bulk configuration</h3>
+			<p>Download the configuration of all your sites, edit it and upload it again to configure many sites at once. Only the sites listed in the uploaded file are changed; use <em>Validate</em> to check the file without applying it. <em>The files contain the passwords of the test users, so keep them safe!</em></p>
+			<hr>
+		</div>
+
+		<div style="grid-row: 2; grid-column: 1 / span 2;">
+			<label>Download:</label>
+			<a href="{{getServerAddress}}/sites-export?invoker=user&format=csv">CSV</a> | <a href="{{getServerAddress}}/sites-export?invoker=user&format=json">JSON</a>
+		</div>
+
+		<div style="grid-row: 3;"><label for="sites-file">Upload (CSV or JSON):</label></div>
+		<div style="grid-row: 4; grid-column: 1 / span 2;"><input type="file" id="sites-file" name="sites-file" accept=".csv,.json,text/csv,application/json"/></div>
+
+		<div style="grid-row: 5; grid-column: 2; text-align: right;">
+			<button type="button" onClick="importSites(true);">Validate</button>
+			<button type="submit" style="font-weight: bold;">Import</button>
+		</div>
+	</form>
+</div>
+{{end}}
+{{if .ManagedSites}}
+<div>&nbsp;</div>
+<div>
+	<h3>Sites managed for other operators</h3>
+	<p>The operators of the following sites have granted you the rights to manage them.</p>
+</div>
+{{$parent := .}}
+{{range .ManagedSites}}
+<div>
+	<form id="form-{{.ID}}" method="POST" class="box container-inline" style="width: 100%;" onSubmit="configureManagedSite('{{.ID}}'); return false;">
+		<div style="grid-row: 1;"><em><strong>{{index $parent.Sites .ID}}</strong> ({{.ID}})</em>{{if .Health}} <span class="health health-{{.Health.Status}}" title="{{if .Health.Message}}{{.Health.Message}} &ndash; {{end}}checked {{.Health.LastChecked.Format "Jan 02, 2006 15:04"}}">{{.Health.Status}}</span>{{end}}</div>
+		<div style="grid-row: 1; text-align: right;"><a href="{{getServerAddress}}/account/?path=site-usage&site={{.ID}}">Usage</a> | <a href="{{getServerAddress}}/account/?path=site-edit&site={{.ID}}">Edit site</a></div>
+
+		<div style="grid-row: 2;"><label for="clientID-{{.ID}}">User name: <span class="mandatory">*</span></label></div>
+		<div style="grid-row: 3;"><input type="text" id="clientID-{{.ID}}" name="clientID" placeholder="User name" value="{{.Config.TestClientCredentials.ID}}"/></div>
+		<div style="grid-row: 2;"><label for="secret-{{.ID}}">Password: <span class="mandatory">*</span></label></div>
+		<div style="grid-row: 3;"><input type="password" id="secret-{{.ID}}" name="secret" placeholder="Password" value="{{.Config.TestClientCredentials.Secret}}"/></div>
+
+		<div style="grid-row: 4; grid-column: 1 / span 2;">
+			<label>Site key:</label>
+			{{if .Key}}
+			<em>Issued {{.Key.DateIssued.Format "Jan 02, 2006 15:04"}}; last used {{if .Key.LastSeen.IsZero}}never{{else}}{{.Key.LastSeen.Format "Jan 02, 2006 15:04"}}{{end}}</em>
+			<button type="button" onClick="issueSiteKey('{{.ID}}', true, 'form-{{.ID}}');" style="float: right;">Rotate key</button>
+			{{else}}
+			<em>No key issued yet</em>
+			<button type="button" onClick="issueSiteKey('{{.ID}}', false, 'form-{{.ID}}');" style="float: right;">Issue key</button>
+			{{end}}
+		</div>
+
+		<div style="grid-row: 5; grid-column: 2; text-align: right;">
+			<button type="reset">Reset</button>
+			<button type="submit" style="font-weight: bold;">Save</button>
+		</div>
+	</form>
+</div>
+{{end}}
+{{end}}
 <div>
 	<p>Go <a href="{{getServerAddress}}/account/?path=manage">back</a> to the main account page.</p>
 </div>
@@ -0,0 +1,171 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package siteusage
+
+const tplJavaScript = `
+const USAGE_COLUMNS = [
+	{"key": "sharesSent", "title": "Shares sent"},
+	{"key": "sharesReceived", "title": "Shares received"},
+	{"key": "bytesTransferred", "title": "Data transferred", "bytes": true},
+	{"key": "requests", "title": "Requests"},
+	{"key": "errors", "title": "Errors"}
+];
+
+function usageURL(action) {
+	var url = "{{getServerAddress}}/" + action + "?invoker=user&site=" + encodeURIComponent("{{.Params.Site}}");
+	url += "&from=" + encodeURIComponent(document.getElementById("from").value);
+	url += "&to=" + encodeURIComponent(document.getElementById("to").value);
+	return url;
+}
+
+function formatBytes(bytes) {
+	const units = ["B", "KiB", "MiB", "GiB", "TiB", "PiB"];
+	var unit = 0;
+	while (bytes >= 1024 && unit < units.length - 1) {
+		bytes /= 1024;
+		unit++;
+	}
+	return (unit == 0 ? bytes : bytes.toFixed(1)) + " " + units[unit];
+}
+
+function formatValue(column, value) {
+	return column.bytes ? formatBytes(value) : value.toLocaleString();
+}
+
+function loadUsage() {
+	setState(STATE_STATUS, "Loading the site usage... this should only take a moment.", "form", null, false);
+
+	var xhr = new XMLHttpRequest();
+	xhr.open("GET", usageURL("site-usage"));
+
+	xhr.onload = function() {
+		var resp = JSON.parse(this.responseText);
+		if (this.status == 200) {
+			document.getElementById("from").value = resp.data.from;
+			document.getElementById("to").value = resp.data.to;
+			document.getElementById("export").href = usageURL("site-usage-export") + "&format=csv";
+			renderUsage(resp.data.usage || [], resp.data.total);
+			setState(STATE_NONE, "", "form", null, true);
+		} else {
+			setState(STATE_ERROR, "An error occurred while trying to load the site usage:<br><em>" + resp.error + "</em>", "form", null, true);
+		}
+	}
+
+	xhr.send();
+}
+
+function renderUsage(records, total) {
+	var totals = "";
+	USAGE_COLUMNS.forEach(function(column) {
+		totals += "<div class='total'><div class='total-value'>" + formatValue(column, total[column.key]) + "</div><div>" + column.title + "</div></div>";
+	});
+	document.getElementById("totals").innerHTML = totals;
+
+	// The bars show the requests of each day relative to the busiest day, with the share of failed ones
+	var maxRequests = Math.max.apply(null, records.map(function(record) { return record.requests; }).concat([1]));
+	var rows = "";
+	records.forEach(function(record) {
+		var width = Math.round(100 * record.requests / maxRequests);
+		var errorWidth = record.requests > 0 ? Math.round(100 * Math.min(record.errors, record.requests) / record.requests) : 0;
+		rows += "<tr><td>" + record.date + "</td>";
+		USAGE_COLUMNS.forEach(function(column) {
+			rows += "<td class='number'>" + formatValue(column, record[column.key]) + "</td>";
+		});
+		rows += "<td><div class='bar' style='width: " + width + "%;'><div class='bar-errors' style='width: " + errorWidth + "%;'></div></div></td></tr>";
+	});
+	if (records.length == 0) {
+		rows = "<tr><td colspan='" + (USAGE_COLUMNS.length + 2) + "'><em>The site hasn't reported any usage for this period.</em></td></tr>";
+	}
+	document.getElementById("usage").innerHTML = rows;
+}
+
+window.addEventListener("load", loadUsage);
+`
+
+const tplStyleSheet = `
+html * {
+	font-family: arial !important;
+}
+
+table {
+	width: 100%;
+}
+
+input[type="date"] {
+	width: auto;
+}
+
+.number {
+	text-align: right;
+}
+
+.totals {
+	display: flex;
+	justify-content: space-between;
+}
+
+.total {
+	text-align: center;
+}
+
+.total-value {
+	font-size: 1.4em;
+	font-weight: bold;
+}
+
+.bar {
+	height: 1em;
+	min-width: 1px;
+	background: #5b9bd5;
+}
+
+.bar-errors {
+	height: 100%;
+	background: #d9534f;
+}
+`
+
+const tplBody = `
+<div>
+	<p>The API usage of the site <strong>{{index .Sites .Params.Site}}</strong> ({{.Params.Site}}) as reported by the site to the mesh. <em>The usage of the current day may be incomplete.</em></p>
+</div>
+<div>&nbsp;</div>
+<div>
+	<form id="form" method="GET" class="box" style="width: 100%;" onSubmit="loadUsage(); return false;">
+		<div>
+			<label for="from">From:</label> <input type="date" id="from" name="from" value="{{.Params.From}}"/>
+			<label for="to">To:</label> <input type="date" id="to" name="to" value="{{.Params.To}}"/>
+			<button type="submit" style="font-weight: bold;">Show</button>
+			<a id="export" href="#" style="float: right;">Export as CSV</a>
+		</div>
+		<hr>
+
+		<div id="totals" class="totals"></div>
+		<hr>
+
+		<table>
+			<thead><tr><th>Day</th><th>Shares sent</th><th>Shares received</th><th>Data transferred</th><th>Requests</th><th>Errors</th><th>Requests (errors in red)</th></tr></thead>
+			<tbody id="usage"></tbody>
+		</table>
+	</form>
+</div>
+<div>
+	<p>Go <a href="{{getServerAddress}}/account/?path=sites">back</a> to the sites page.</p>
+</div>
+`
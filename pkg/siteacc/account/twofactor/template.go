@@ -0,0 +1,194 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package twofactor
+
+const tplJavaScript = `
+function sendRequest(endpoint, postData, statusMsg, onSuccess) {
+	var xhr = new XMLHttpRequest();
+    xhr.open("POST", "{{getServerAddress}}/" + endpoint);
+    xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
+
+	setState(STATE_STATUS, statusMsg, "form", null, false);
+
+	xhr.onload = function() {
+		var resp = JSON.parse(this.responseText);
+		if (this.status == 200) {
+			onSuccess(resp.data);
+		} else {
+			setState(STATE_ERROR, "An error occurred while trying to configure the two-factor authentication:<br><em>" + resp.error + "</em>", "form", null, true);
+		}
+	}
+
+    xhr.send(JSON.stringify(postData));
+}
+
+function verifyCode(formData) {
+	if (formData.getTrimmed("code") == "") {
+		setState(STATE_ERROR, "Please enter the code shown by your authenticator app.", "form", "code", true);
+		return false;
+	}
+	return true;
+}
+
+function handleEnroll() {
+	sendRequest("enroll-2fa", {}, "Enrolling a new device...", function(data) {
+		document.getElementById("qrcode").src = data.qrCode;
+		document.getElementById("secret").innerHTML = data.secret;
+		document.getElementById("enroll").style.display = "none";
+		document.getElementById("confirm").style.display = "grid";
+		setState(STATE_SUCCESS, "Scan the QR code with your authenticator app and enter the shown code to finish the enrollment.", "form", "code", true);
+	});
+}
+
+function handleEnable() {
+	const formData = new FormData(document.querySelector("form"));
+	if (!verifyCode(formData)) {
+		return;
+	}
+
+	sendRequest("enable-2fa", {"code": formData.getTrimmed("code")}, "Enabling two-factor authentication...", function(data) {
+		setState(STATE_SUCCESS, "Two-factor authentication has been enabled! Reloading...");
+		window.location.reload();
+	});
+}
+
+function handleDisable() {
+	const formData = new FormData(document.querySelector("form"));
+	if (!verifyCode(formData)) {
+		return;
+	}
+
+	sendRequest("disable-2fa", {"code": formData.getTrimmed("code")}, "Disabling two-factor authentication...", function(data) {
+		setState(STATE_SUCCESS, "Two-factor authentication has been disabled! Reloading...");
+		window.location.reload();
+	});
+}
+
+function handleOperatorSettings() {
+	const formData = new FormData(document.getElementById("operator-form"));
+
+	var xhr = new XMLHttpRequest();
+    xhr.open("POST", "{{getServerAddress}}/operator-configure?invoker=user");
+    xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
+
+	setState(STATE_STATUS, "Configuring operator... this should only take a moment.", "operator-form", null, false);
+
+	xhr.onload = function() {
+		if (this.status == 200) {
+			setState(STATE_SUCCESS, "The operator settings were successfully saved!", "operator-form", null, true);
+		} else {
+			var resp = JSON.parse(this.responseText);
+			setState(STATE_ERROR, "An error occurred while trying to configure the operator:<br><em>" + resp.error + "</em>", "operator-form", null, true);
+		}
+	}
+
+	var postData = {
+		"settings": {
+			"requireTwoFactor": (formData.get("requireTwoFactor") === "on")
+		}
+    };
+
+    xhr.send(JSON.stringify(postData));
+}
+`
+
+const tplStyleSheet = `
+html * {
+	font-family: arial !important;
+}
+
+input[type="checkbox"] {
+	width: auto;
+}
+
+.secret {
+	font-family: monospace !important;
+	letter-spacing: 0.1em;
+}
+`
+
+const tplBody = `
+<div>
+	<p>Two-factor authentication protects your ScienceMesh Site Administrator Account by asking for a code generated by an authenticator app on your phone, in addition to your password, whenever you log in.</p>
+	{{if and (.Account.RequiresTwoFactor .Operator) (not .Account.TwoFactor.Enabled)}}
+	<p><strong>Your operator requires you to use two-factor authentication; you can only access your sites after enabling it.</strong></p>
+	{{end}}
+</div>
+<div>&nbsp;</div>
+<div>
+	{{if .Account.TwoFactor.Enabled}}
+	<form id="form" method="POST" class="box container-inline" style="width: 100%;" onSubmit="handleDisable(); return false;">
+		<div style="grid-row: 1; grid-column: 1 / span 2;">
+			<h3>Two-factor authentication is enabled</h3>
+			<hr>
+		</div>
+		<div style="grid-row: 2;"><label for="code">Code from your authenticator app:</label></div>
+		<div style="grid-row: 3;"><input type="text" id="code" name="code" autocomplete="one-time-code" inputmode="numeric"/></div>
+		<div style="grid-row: 4; grid-column: 2; text-align: right;">
+			<button type="submit" style="font-weight: bold;" {{if .Account.RequiresTwoFactor .Operator}}disabled{{end}}>Disable</button>
+		</div>
+	</form>
+	{{else}}
+	<form id="form" method="POST" class="box" style="width: 100%;" onSubmit="handleEnable(); return false;">
+		<div>
+			<h3>Two-factor authentication is disabled</h3>
+			<hr>
+		</div>
+		<div id="enroll">
+			<p>To enable two-factor authentication, enroll your device first; you will need an authenticator app like FreeOTP or Google Authenticator.</p>
+			<div style="text-align: right;"><button type="button" style="font-weight: bold;" onClick="handleEnroll();">Enroll device</button></div>
+		</div>
+		<div id="confirm" class="container-inline" style="display: none;">
+			<div style="grid-row: 1;"><img id="qrcode" alt="QR code"/></div>
+			<div style="grid-row: 1;">
+				<p>Scan the QR code with your authenticator app. If you can't scan it, enter the following secret manually:</p>
+				<p class="secret" id="secret"></p>
+			</div>
+			<div style="grid-row: 2;"><label for="code">Code from your authenticator app:</label></div>
+			<div style="grid-row: 3;"><input type="text" id="code" name="code" autocomplete="one-time-code" inputmode="numeric"/></div>
+			<div style="grid-row: 4; grid-column: 2; text-align: right;">
+				<button type="submit" style="font-weight: bold;">Enable</button>
+			</div>
+		</div>
+	</form>
+	{{end}}
+</div>
+{{if .Account.Data.SitesAccess}}
+<div>&nbsp;</div>
+<div>
+	<form id="operator-form" method="POST" class="box container-inline" style="width: 100%;" onSubmit="handleOperatorSettings(); return false;">
+		<div style="grid-row: 1; grid-column: 1 / span 2;">
+			<h3>Operator settings</h3>
+			<hr>
+		</div>
+		<div style="grid-row: 2; grid-column: 1 / span 2;">
+			<input type="checkbox" id="requireTwoFactor" name="requireTwoFactor" value="on" {{if .Operator.Settings.RequireTwoFactor}}checked{{end}}/>
+			<label for="requireTwoFactor" style="font-weight: normal;">Require two-factor authentication for all accounts of {{getOperatorName .Account.Operator}} with Sites access</label>
+		</div>
+		<div style="grid-row: 3; grid-column: 2; text-align: right;">
+			<button type="reset">Reset</button>
+			<button type="submit" style="font-weight: bold;">Save</button>
+		</div>
+	</form>
+</div>
+{{end}}
+<div>
+	<p>Go <a href="{{getServerAddress}}/account/?path=manage">back</a> to the main account page.</p>
+</div>
+`
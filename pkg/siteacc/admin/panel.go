@@ -21,6 +21,7 @@ package admin
 import (
 	"net/http"
 
+	"github.com/cs3org/reva/pkg/announcements"
 	"github.com/cs3org/reva/pkg/siteacc/config"
 	"github.com/cs3org/reva/pkg/siteacc/data"
 	"github.com/cs3org/reva/pkg/siteacc/html"
@@ -91,20 +92,33 @@ func (panel *Panel) PreExecute(*html.Session, string, http.ResponseWriter, *http
 	return html.ContinueExecution, nil
 }
 
-// Execute generates the HTTP output of the htmlPanel and writes it to the response writer.
-func (panel *Panel) Execute(w http.ResponseWriter, r *http.Request, session *html.Session, accounts *data.Accounts) error {
+// Execute generates the HTTP output of the htmlPanel and writes it to the response writer; anns is nil if announcements are disabled.
+func (panel *Panel) Execute(w http.ResponseWriter, r *http.Request, session *html.Session, accounts *data.Accounts, operators *data.Operators, anns []*announcements.Announcement) error {
 	dataProvider := func(*html.Session) interface{} {
 		type TemplateData struct {
-			Accounts *data.Accounts
+			Accounts      *data.Accounts
+			Operators     *data.Operators
+			Announcements []*announcements.Announcement
+			// AnnouncementsEnabled is false if no announcements registry has been configured
+			AnnouncementsEnabled bool
 		}
 
 		return TemplateData{
-			Accounts: accounts,
+			Accounts:      accounts,
+			Operators:     operators,
+			Announcements: anns,
+
+			AnnouncementsEnabled: anns != nil,
 		}
 	}
 	return panel.htmlPanel.Execute(w, r, session, dataProvider)
 }
 
+// SetAnnouncementsProvider sets the provider of the announcements shown on top of the panel.
+func (panel *Panel) SetAnnouncementsProvider(provider html.AnnouncementsProvider) {
+	panel.htmlPanel.SetAnnouncementsProvider(provider)
+}
+
 // NewPanel creates a new administration panel.
 func NewPanel(conf *config.Configuration, log *zerolog.Logger) (*Panel, error) {
 	panel := &Panel{}
@@ -41,22 +41,106 @@ function handleAction(action, email) {
 
     xhr.send(JSON.stringify(postData));
 }
+
+function handleRemove(email) {
+	if (confirm("Do you really want to permanently delete the account " + email + "?")) {
+		handleAction('remove', email);
+	}
+}
+
+function handlePostAnnouncement() {
+	var form = document.getElementById("announcement-form");
+	var start = form.elements["start"].value;
+	var end = form.elements["end"].value;
+
+	var xhr = new XMLHttpRequest();
+	xhr.open("POST", "{{getServerAddress}}/announcement-post");
+	xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
+
+	setState(STATE_STATUS, "Posting announcement...", "announcement-form", null, false);
+
+	xhr.onload = function() {
+		if (this.status == 200) {
+			setState(STATE_SUCCESS, "Done! Reloading...");
+			location.reload();
+		} else {
+			setState(STATE_ERROR, "An error occurred while posting the announcement: " + this.responseText, "announcement-form", null, true);
+		}
+	}
+
+	var postData = {
+		"title": form.elements["title"].value,
+		"message": form.elements["message"].value,
+		"severity": form.elements["severity"].value,
+		"start": start != "" ? new Date(start).toISOString() : "",
+		"end": end != "" ? new Date(end).toISOString() : "",
+		"email": form.elements["email"].checked,
+	};
+
+	xhr.send(JSON.stringify(postData));
+}
+
+function handleRemoveAnnouncement(id, title) {
+	if (confirm("Do you really want to remove the announcement '" + title + "'?")) {
+		handleAction('announcement-remove?id=' + id, '');
+	}
+}
+
+function filterAccounts() {
+	var search = document.getElementById("search").value.trim().toLowerCase();
+	var filter = document.getElementById("filter").value;
+
+	var shown = 0;
+	document.querySelectorAll("li.account").forEach(function(item) {
+		var visible = item.dataset.search.indexOf(search) != -1 && (filter == "" || item.dataset.status.split(" ").indexOf(filter) != -1);
+		item.style.display = visible ? "" : "none";
+		if (visible) {
+			shown++;
+		}
+	});
+	document.getElementById("shown").innerText = shown;
+}
 `
 
 const tplStyleSheet = `
 html * {
 	font-family: monospace !important;
 }
+.badge {
+	font-weight: bold;
+	color: darkorange;
+}
+.badge-disabled {
+	font-weight: bold;
+	color: red;
+}
 `
 
 const tplBody = `
 <div style="font-size: 14px;">
+	<div>
+		<input type="text" id="search" placeholder="Search by name, email, role or operator..." style="width: 50%;" onInput="filterAccounts();"/>
+		<select id="filter" onChange="filterAccounts();">
+			<option value="">All accounts</option>
+			<option value="pending">Pending approval</option>
+			<option value="unverified">Unverified email</option>
+			<option value="disabled">Disabled</option>
+			<option value="sites">Sites access</option>
+			<option value="no-sites">No Sites access</option>
+		</select>
+		<em>(Showing <span id="shown">{{.Accounts | len}}</span> accounts)</em>
+	</div>
 	<ul>
 	{{range .Accounts}}
-		<li>
+		<li class="account" data-search="{{lower (print .Email " " .FirstName " " .LastName " " .Role " " .Operator)}}" data-status="{{if .PendingApproval}}pending {{end}}{{if not .IsVerified}}unverified {{end}}{{if .IsDisabled}}disabled {{end}}{{if .Data.SitesAccess}}sites{{else}}no-sites{{end}}">
 			<div>
 				<div>
-					<strong>{{.Email}}</strong><br>
+					<strong>{{.Email}}</strong>
+					{{if .PendingApproval}}<span class="badge">[Pending approval]</span>{{end}}
+					{{if not .IsVerified}}<span class="badge">[Unverified email]</span>{{end}}
+					{{if .Suspended}}<span class="badge-disabled">[Disabled]</span>{{end}}
+					{{if .Deletion}}<span class="badge-disabled">[Deletion requested]</span>{{end}}
+					<br>
 					{{.Title}}. {{.FirstName}} {{.LastName}} <em>(Joined: {{.DateCreated.Format "Jan 02, 2006 15:04"}}; Last modified: {{.DateModified.Format "Jan 02, 2006 15:04"}})</em>
 				</div>
 				<div>
@@ -86,6 +170,13 @@ const tplBody = `
 
 			<div>
 				<form method="POST" style="width: 100%;">
+				{{if .PendingApproval}}
+					<button type="button" onClick="handleAction('approve', '{{.Email}}');" style="font-weight: bold;">Approve</button>
+				{{end}}
+				{{if not .IsVerified}}
+					<button type="button" onClick="handleAction('resend-verification', '{{.Email}}');">Resend verification email</button>
+				{{end}}
+
 				{{if .Data.SitesAccess}}
 					<button type="button" onClick="handleAction('grant-sites-access?status=false', '{{.Email}}');">Revoke Sites access</button>
 				{{else}}
@@ -99,7 +190,12 @@ const tplBody = `
 				{{end}}
 
 					<span style="width: 25px;">&nbsp;</span>
-					<button type="button" onClick="handleAction('remove', '{{.Email}}');" style="float: right;">Remove</button>
+					<button type="button" onClick="handleRemove('{{.Email}}');" style="float: right;">Delete</button>
+				{{if .Suspended}}
+					<button type="button" onClick="handleAction('suspend?status=false', '{{.Email}}');" style="float: right;">Enable</button>
+				{{else}}
+					<button type="button" onClick="handleAction('suspend?status=true', '{{.Email}}');" style="float: right;">Disable</button>
+				{{end}}
 				</form>
 			</div>
 			<hr>
@@ -107,4 +203,49 @@ const tplBody = `
 	{{end}}
 	</ul>
 </div>
+{{if .AnnouncementsEnabled}}
+<div style="font-size: 14px;">
+	<h3>Announcements</h3>
+	<ul>
+	{{range .Announcements}}
+		<li>
+			<strong>{{.Title}}</strong> <em>[{{.Severity}}]</em><br>
+			{{.Message}}<br>
+			<em>Shown from {{.Start.Format "Jan 02, 2006 15:04"}}{{if not .End.IsZero}} until {{.End.Format "Jan 02, 2006 15:04"}}{{end}}{{if .Author}}; posted by {{.Author}}{{end}}</em>
+			<button type="button" onClick="handleRemoveAnnouncement('{{.ID}}', '{{.Title}}');">Remove</button>
+		</li>
+	{{end}}
+	</ul>
+	<form id="announcement-form" method="POST" style="width: 100%;">
+		<input type="text" name="title" placeholder="Title" style="width: 50%;"/>
+		<select name="severity">
+			<option value="info">Info</option>
+			<option value="warning">Warning</option>
+			<option value="critical">Critical</option>
+		</select>
+		<br>
+		<textarea name="message" placeholder="Message" rows="3" style="width: 50%;"></textarea>
+		<br>
+		<label>Start: <input type="datetime-local" name="start"/></label>
+		<label>End: <input type="datetime-local" name="end"/></label>
+		<label><input type="checkbox" name="email"/> Send via email</label>
+		<button type="button" onClick="handlePostAnnouncement();">Post announcement</button>
+	</form>
+</div>
+{{end}}
+<div style="font-size: 14px;">
+	<h3>Site keys</h3>
+	<ul>
+	{{range $op := .Operators}}
+		{{range $op.Sites}}
+		{{if .Key}}
+		<li>
+			<strong>{{.ID}}</strong> <em>({{getOperatorName $op.ID}})</em><br>
+			Issued: {{.Key.DateIssued.Format "Jan 02, 2006 15:04"}}; Last seen: {{if .Key.LastSeen.IsZero}}Never{{else}}{{.Key.LastSeen.Format "Jan 02, 2006 15:04"}}{{end}}; Uses: {{.Key.UseCount}}
+		</li>
+		{{end}}
+		{{end}}
+	{{end}}
+	</ul>
+</div>
 `
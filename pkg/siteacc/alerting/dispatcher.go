@@ -67,6 +67,10 @@ func (dispatcher *Dispatcher) DispatchAlerts(alerts *template.Data, accounts dat
 
 		// Dispatch the alert to all accounts configured to receive it
 		for _, account := range accounts {
+			if account.IsDisabled() {
+				continue
+			}
+
 			if strings.EqualFold(account.Operator, opID) /* && account.Settings.ReceiveAlerts */ { // TODO: Uncomment if alert notifications aren't mandatory anymore
 				if err := dispatcher.dispatchAlert(alert, account); err != nil {
 					// Log errors only
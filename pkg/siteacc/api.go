@@ -0,0 +1,840 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package siteacc
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/siteacc/audit"
+	"github.com/cs3org/reva/pkg/siteacc/credentials"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/cs3org/reva/pkg/siteacc/manager"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+)
+
+const (
+	// apiPrefix is the path of the versioned REST API; its requests are authenticated using bearer tokens instead of sessions.
+	apiPrefix = "/api/v1"
+
+	apiDefaultPageSize = 100
+	apiMaxPageSize     = 1000
+
+	// apiScopeAdmin is required by the endpoints that can only be called using one of the configured API tokens.
+	apiScopeAdmin = "admin"
+)
+
+// apiPrincipal is the caller of the REST API, either an administrator using a configured API token or an operator using one of its API keys.
+type apiPrincipal struct {
+	// TokenName is the name of the API token used; it is empty for operator API keys.
+	TokenName string
+
+	Operator string
+	Key      *data.OperatorAPIKey
+}
+
+func (p *apiPrincipal) isAdmin() bool {
+	return p.TokenName != ""
+}
+
+func (p *apiPrincipal) grants(scope string) bool {
+	if p.isAdmin() {
+		return true
+	}
+	return scope != apiScopeAdmin && p.Key.Grants(scope)
+}
+
+func (p *apiPrincipal) canAccessOperator(opID string) bool {
+	return p.isAdmin() || strings.EqualFold(p.Operator, opID)
+}
+
+func (p *apiPrincipal) actor() string {
+	if p.isAdmin() {
+		return "api-token:" + p.TokenName
+	}
+	return fmt.Sprintf("api-key:%v/%v", p.Operator, p.Key.ID)
+}
+
+// apiError is the body of all error responses of the REST API.
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Violations lists the violated rules if a password didn't fulfil the password policy.
+	Violations []credentials.PolicyViolation `json:"violations,omitempty"`
+}
+
+// apiStatusError is an error with the HTTP status and error code it is reported with.
+type apiStatusError struct {
+	status int
+	code   string
+	err    error
+}
+
+func (e *apiStatusError) Error() string {
+	return e.err.Error()
+}
+
+func newAPIError(status int, code string, format string, args ...interface{}) error {
+	return &apiStatusError{status: status, code: code, err: errors.Errorf(format, args...)}
+}
+
+func apiBadRequest(format string, args ...interface{}) error {
+	return newAPIError(http.StatusBadRequest, "invalid_request", format, args...)
+}
+
+func apiNotFound(format string, args ...interface{}) error {
+	return newAPIError(http.StatusNotFound, "not_found", format, args...)
+}
+
+func apiForbidden(format string, args ...interface{}) error {
+	return newAPIError(http.StatusForbidden, "forbidden", format, args...)
+}
+
+func apiConflict(format string, args ...interface{}) error {
+	return newAPIError(http.StatusConflict, "conflict", format, args...)
+}
+
+// apiPage is the body of the responses of all list endpoints.
+type apiPage struct {
+	Items  interface{} `json:"items"`
+	Total  int         `json:"total"`
+	Offset int         `json:"offset"`
+	Limit  int         `json:"limit"`
+}
+
+// apiAccount is the representation of an account in the REST API.
+type apiAccount struct {
+	Email       string `json:"email"`
+	Title       string `json:"title"`
+	FirstName   string `json:"firstName"`
+	LastName    string `json:"lastName"`
+	Operator    string `json:"operator"`
+	Role        string `json:"role"`
+	PhoneNumber string `json:"phoneNumber"`
+
+	SitesAccess bool `json:"sitesAccess"`
+	GOCDBAccess bool `json:"gocdbAccess"`
+
+	TwoFactor       bool `json:"twoFactor"`
+	EmailVerified   bool `json:"emailVerified"`
+	PendingApproval bool `json:"pendingApproval"`
+	Suspended       bool `json:"suspended"`
+	Deleted         bool `json:"deleted"`
+
+	DateCreated  time.Time `json:"dateCreated"`
+	DateModified time.Time `json:"dateModified"`
+}
+
+// apiAccountRequest is the body of the requests creating and updating accounts; the access flags are left untouched if omitted.
+type apiAccountRequest struct {
+	Email       string `json:"email"`
+	Title       string `json:"title"`
+	FirstName   string `json:"firstName"`
+	LastName    string `json:"lastName"`
+	Operator    string `json:"operator"`
+	Role        string `json:"role"`
+	PhoneNumber string `json:"phoneNumber"`
+	Password    string `json:"password"`
+
+	SitesAccess *bool `json:"sitesAccess"`
+	GOCDBAccess *bool `json:"gocdbAccess"`
+}
+
+// apiOperator is the representation of an operator in the REST API.
+type apiOperator struct {
+	ID               string     `json:"id"`
+	RequireTwoFactor bool       `json:"requireTwoFactor"`
+	Sites            []*apiSite `json:"sites"`
+}
+
+// apiSite is the representation of a site in the REST API; credentials and keys are never returned.
+type apiSite struct {
+	ID       string   `json:"id"`
+	Operator string   `json:"operator"`
+	Managers []string `json:"managers"`
+
+	TestClientCredentials bool       `json:"testClientCredentials"`
+	KeyIssued             *time.Time `json:"keyIssued,omitempty"`
+
+	Health *data.SiteHealth `json:"health,omitempty"`
+}
+
+// apiAPIKey is the representation of an operator API key in the REST API; the key itself is only returned when it is issued or rotated.
+type apiAPIKey struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+
+	CreatedBy   string     `json:"createdBy"`
+	DateCreated time.Time  `json:"dateCreated"`
+	DateRotated *time.Time `json:"dateRotated,omitempty"`
+	DateExpires *time.Time `json:"dateExpires,omitempty"`
+	LastUsed    *time.Time `json:"lastUsed,omitempty"`
+	UseCount    int64      `json:"useCount"`
+
+	Key string `json:"key,omitempty"`
+}
+
+type apiHandlerFunc = func(*SiteAccounts, *http.Request, *apiPrincipal) (int, interface{}, error)
+
+// newAPIHandler creates the router of the REST API.
+func (siteacc *SiteAccounts) newAPIHandler() http.Handler {
+	r := chi.NewRouter()
+	r.Route(apiPrefix, func(r chi.Router) {
+		r.Route("/accounts", func(r chi.Router) {
+			r.Get("/", siteacc.apiRoute(apiScopeAdmin, apiListAccounts))
+			r.Post("/", siteacc.apiRoute(apiScopeAdmin, apiCreateAccount))
+			r.Get("/{email}", siteacc.apiRoute(apiScopeAdmin, apiGetAccount))
+			r.Put("/{email}", siteacc.apiRoute(apiScopeAdmin, apiUpdateAccount))
+			r.Delete("/{email}", siteacc.apiRoute(apiScopeAdmin, apiRemoveAccount))
+			r.Post("/{email}/approve", siteacc.apiRoute(apiScopeAdmin, apiApproveAccount))
+			r.Post("/{email}/suspend", siteacc.apiRoute(apiScopeAdmin, apiSuspendAccount(true)))
+			r.Post("/{email}/resume", siteacc.apiRoute(apiScopeAdmin, apiSuspendAccount(false)))
+		})
+		r.Route("/operators", func(r chi.Router) {
+			r.Get("/", siteacc.apiRoute(data.APIKeyScopeMonitoring, apiListOperators))
+			r.Get("/{operator}", siteacc.apiRoute(data.APIKeyScopeMonitoring, apiGetOperator))
+			r.Get("/{operator}/apikeys", siteacc.apiRoute(apiScopeAdmin, apiListAPIKeys))
+			r.Post("/{operator}/apikeys", siteacc.apiRoute(apiScopeAdmin, apiIssueAPIKey))
+			r.Post("/{operator}/apikeys/{key}/rotate", siteacc.apiRoute(apiScopeAdmin, apiRotateAPIKey))
+			r.Delete("/{operator}/apikeys/{key}", siteacc.apiRoute(apiScopeAdmin, apiRevokeAPIKey))
+		})
+		r.Route("/sites", func(r chi.Router) {
+			r.Get("/", siteacc.apiRoute(data.APIKeyScopeMonitoring, apiListSites))
+			r.Get("/{site}", siteacc.apiRoute(data.APIKeyScopeMonitoring, apiGetSite))
+			r.Put("/{site}/credentials", siteacc.apiRoute(data.APIKeyScopeSiteConfig, apiSetSiteCredentials))
+		})
+	})
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, apiNotFound("unknown endpoint %v", r.URL.Path))
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, newAPIError(http.StatusMethodNotAllowed, "method_not_allowed", "method %v not allowed for %v", r.Method, r.URL.Path))
+	})
+	return r
+}
+
+// apiRoute authenticates the requests to an endpoint requiring the given scope before calling its handler.
+func (siteacc *SiteAccounts) apiRoute(scope string, handler apiHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, err := siteacc.authenticateAPIRequest(r)
+		if err != nil {
+			siteacc.log.Warn().Err(err).Str("path", r.URL.Path).Msg("rejected API request")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="siteacc"`)
+			writeAPIError(w, newAPIError(http.StatusUnauthorized, "unauthorized", "%v", err))
+			return
+		}
+		if !principal.grants(scope) {
+			writeAPIError(w, apiForbidden("the credentials lack the %v scope", scope))
+			return
+		}
+
+		status, body, err := handler(siteacc, r, principal)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		if body == nil {
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	}
+}
+
+// authenticateAPIRequest verifies the bearer token of a request, which is either one of the configured API tokens or an operator API key.
+func (siteacc *SiteAccounts) authenticateAPIRequest(r *http.Request) (*apiPrincipal, error) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return nil, errors.Errorf("no bearer token provided")
+	}
+	token := strings.TrimSpace(auth[7:])
+	if token == "" {
+		return nil, errors.Errorf("no bearer token provided")
+	}
+
+	for name, apiToken := range siteacc.conf.API.Tokens {
+		if apiToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(apiToken)) == 1 {
+			return &apiPrincipal{TokenName: name}, nil
+		}
+	}
+
+	op, key, err := siteacc.operatorsManager.VerifyAPIKey(token)
+	if err != nil {
+		return nil, err
+	}
+	return &apiPrincipal{Operator: op.ID, Key: key}, nil
+}
+
+func writeAPIError(w http.ResponseWriter, err error) {
+	status, body := http.StatusInternalServerError, &apiError{Code: "internal_error", Message: err.Error()}
+
+	var statusErr *apiStatusError
+	var policyErr *credentials.PolicyError
+	switch {
+	case errors.As(err, &policyErr):
+		status, body.Code, body.Violations = http.StatusUnprocessableEntity, "password_policy", policyErr.Violations
+	case errors.As(err, &statusErr):
+		status, body.Code = statusErr.status, statusErr.code
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// decodeAPIRequest decodes the JSON body of a request, rejecting unknown fields.
+func decodeAPIRequest(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return apiBadRequest("invalid request body: %v", err)
+	}
+	return nil
+}
+
+// paginate returns the page of the items selected by the offset and limit parameters of the request.
+func paginate(r *http.Request, count int, slice func(from, to int) interface{}) (*apiPage, error) {
+	offset, limit := 0, apiDefaultPageSize
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, apiBadRequest("invalid offset %v", v)
+		}
+		offset = n
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > apiMaxPageSize {
+			return nil, apiBadRequest("invalid limit %v; it must lie between 1 and %v", v, apiMaxPageSize)
+		}
+		limit = n
+	}
+
+	from, to := offset, offset+limit
+	if from > count {
+		from = count
+	}
+	if to > count {
+		to = count
+	}
+	return &apiPage{Items: slice(from, to), Total: count, Offset: offset, Limit: limit}, nil
+}
+
+func urlParam(r *http.Request, key string) string {
+	v := chi.URLParam(r, key)
+	if unescaped, err := url.PathUnescape(v); err == nil {
+		return unescaped
+	}
+	return v
+}
+
+// auditAPI records the audit event of a call to the REST API.
+func (siteacc *SiteAccounts) auditAPI(r *http.Request, principal *apiPrincipal, action string, account string, details string, err error) {
+	if siteacc.auditor == nil {
+		return
+	}
+
+	ip, _ := utils.GetClientIP(r)
+	event := &audit.Event{
+		Action:  action,
+		Actor:   principal.actor(),
+		Account: account,
+		IP:      ip,
+		Success: err == nil,
+		Details: details,
+	}
+	if err != nil {
+		event.Details = joinDetails(event.Details, err.Error())
+	}
+	siteacc.auditor.Record(event)
+}
+
+func newAPIAccount(account *data.Account) *apiAccount {
+	return &apiAccount{
+		Email:           account.Email,
+		Title:           account.Title,
+		FirstName:       account.FirstName,
+		LastName:        account.LastName,
+		Operator:        account.Operator,
+		Role:            account.Role,
+		PhoneNumber:     account.PhoneNumber,
+		SitesAccess:     account.Data.SitesAccess,
+		GOCDBAccess:     account.Data.GOCDBAccess,
+		TwoFactor:       account.TwoFactor.Enabled,
+		EmailVerified:   account.Verification == nil,
+		PendingApproval: account.PendingApproval,
+		Suspended:       account.Suspended,
+		Deleted:         account.Deletion != nil,
+		DateCreated:     account.DateCreated,
+		DateModified:    account.DateModified,
+	}
+}
+
+func newAPISite(opID string, site *data.Site) *apiSite {
+	s := &apiSite{
+		ID:                    site.ID,
+		Operator:              opID,
+		Managers:              append([]string{}, site.Managers...),
+		TestClientCredentials: site.Config.TestClientCredentials.IsValid(),
+		Health:                site.Health,
+	}
+	if site.Key != nil {
+		issued := site.Key.DateIssued
+		s.KeyIssued = &issued
+	}
+	return s
+}
+
+func newAPIOperator(op *data.Operator) *apiOperator {
+	o := &apiOperator{
+		ID:               op.ID,
+		RequireTwoFactor: op.Settings.RequireTwoFactor,
+		Sites:            make([]*apiSite, 0, len(op.Sites)),
+	}
+	for _, site := range op.Sites {
+		o.Sites = append(o.Sites, newAPISite(op.ID, site))
+	}
+	return o
+}
+
+func newAPIAPIKey(apiKey *data.OperatorAPIKey) *apiAPIKey {
+	optionalTime := func(t time.Time) *time.Time {
+		if t.IsZero() {
+			return nil
+		}
+		return &t
+	}
+	return &apiAPIKey{
+		ID:          apiKey.ID,
+		Name:        apiKey.Name,
+		Scope:       apiKey.Scope,
+		CreatedBy:   apiKey.CreatedBy,
+		DateCreated: apiKey.DateCreated,
+		DateRotated: optionalTime(apiKey.DateRotated),
+		DateExpires: optionalTime(apiKey.DateExpires),
+		LastUsed:    optionalTime(apiKey.LastUsed),
+		UseCount:    apiKey.UseCount,
+	}
+}
+
+func (siteacc *SiteAccounts) findAPIAccount(r *http.Request) (*data.Account, error) {
+	email := urlParam(r, "email")
+	account, err := siteacc.AccountsManager().FindAccountEx(manager.FindByEmail, email, true)
+	if err != nil {
+		return nil, apiNotFound("no account with email %v exists", email)
+	}
+	return account, nil
+}
+
+// findAPIOperator returns a clone of the operator given in the request path if the caller may access it; the clone still holds all credentials.
+func (siteacc *SiteAccounts) findAPIOperator(r *http.Request, principal *apiPrincipal) (*data.Operator, error) {
+	opID := urlParam(r, "operator")
+	if !principal.canAccessOperator(opID) {
+		return nil, apiForbidden("no access to operator %v", opID)
+	}
+	for _, op := range siteacc.OperatorsManager().CloneOperators(false) {
+		if strings.EqualFold(op.ID, opID) {
+			return op, nil
+		}
+	}
+	return nil, apiNotFound("no operator with ID %v exists", opID)
+}
+
+// accessibleOperators returns clones of all operators the caller may access, sorted by their IDs; the clones still hold all credentials, so they must never be returned as-is.
+func (siteacc *SiteAccounts) accessibleOperators(principal *apiPrincipal) data.Operators {
+	operators := make(data.Operators, 0)
+	for _, op := range siteacc.OperatorsManager().CloneOperators(false) {
+		if principal.canAccessOperator(op.ID) {
+			operators = append(operators, op)
+		}
+	}
+	sort.Slice(operators, func(i, j int) bool { return operators[i].ID < operators[j].ID })
+	return operators
+}
+
+func apiListAccounts(siteacc *SiteAccounts, r *http.Request, principal *apiPrincipal) (int, interface{}, error) {
+	opID := r.URL.Query().Get("operator")
+	accounts := make([]*apiAccount, 0)
+	for _, account := range siteacc.AccountsManager().CloneAccounts(true) {
+		if opID == "" || strings.EqualFold(account.Operator, opID) {
+			accounts = append(accounts, newAPIAccount(account))
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Email < accounts[j].Email })
+
+	page, err := paginate(r, len(accounts), func(from, to int) interface{} { return accounts[from:to] })
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, page, nil
+}
+
+func apiGetAccount(siteacc *SiteAccounts, r *http.Request, principal *apiPrincipal) (int, interface{}, error) {
+	account, err := siteacc.findAPIAccount(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, newAPIAccount(account), nil
+}
+
+func apiCreateAccount(siteacc *SiteAccounts, r *http.Request, principal *apiPrincipal) (int, interface{}, error) {
+	req := &apiAccountRequest{}
+	if err := decodeAPIRequest(r, req); err != nil {
+		return 0, nil, err
+	}
+	if req.Email == "" {
+		return 0, nil, apiBadRequest("no email provided")
+	}
+	if _, err := siteacc.AccountsManager().FindAccount(manager.FindByEmail, req.Email); err == nil {
+		return 0, nil, apiConflict("an account with email %v already exists", req.Email)
+	}
+
+	account := &data.Account{
+		Email:       req.Email,
+		Title:       req.Title,
+		FirstName:   req.FirstName,
+		LastName:    req.LastName,
+		Operator:    req.Operator,
+		Role:        req.Role,
+		PhoneNumber: req.PhoneNumber,
+		Password:    credentials.Password{Value: req.Password},
+	}
+	if err := siteacc.AccountsManager().CreateAccount(account); err != nil {
+		return 0, nil, wrapAPIError(err, "unable to create account")
+	}
+	if err := siteacc.applyAPIAccess(account, req); err != nil {
+		return 0, nil, err
+	}
+
+	created, err := siteacc.AccountsManager().FindAccountEx(manager.FindByEmail, account.Email, true)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusCreated, newAPIAccount(created), nil
+}
+
+func apiUpdateAccount(siteacc *SiteAccounts, r *http.Request, principal *apiPrincipal) (int, interface{}, error) {
+	account, err := siteacc.findAPIAccount(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	req := &apiAccountRequest{}
+	if err := decodeAPIRequest(r, req); err != nil {
+		return 0, nil, err
+	}
+	if req.Email != "" && !strings.EqualFold(req.Email, account.Email) {
+		return 0, nil, apiBadRequest("the email of an account can't be changed")
+	}
+	if req.Operator != "" && !strings.EqualFold(req.Operator, account.Operator) {
+		return 0, nil, apiBadRequest("the operator of an account can't be changed")
+	}
+
+	accountData := &data.Account{
+		Email:       account.Email,
+		Title:       req.Title,
+		FirstName:   req.FirstName,
+		LastName:    req.LastName,
+		Role:        req.Role,
+		PhoneNumber: req.PhoneNumber,
+		Password:    credentials.Password{Value: req.Password},
+	}
+	err = siteacc.AccountsManager().UpdateAccount(accountData, req.Password != "", false)
+	details := ""
+	if account.Role != req.Role {
+		details = fmt.Sprintf("role %v -> %v", account.Role, req.Role)
+	}
+	siteacc.auditAPI(r, principal, audit.ActionAccountUpdate, account.Email, details, err)
+	if err != nil {
+		return 0, nil, wrapAPIError(err, "unable to update account")
+	}
+	if req.Password != "" {
+		siteacc.auditAPI(r, principal, audit.ActionPasswordChange, account.Email, "", nil)
+	}
+	if err := siteacc.applyAPIAccess(accountData, req); err != nil {
+		return 0, nil, err
+	}
+
+	updated, err := siteacc.AccountsManager().FindAccountEx(manager.FindByEmail, account.Email, true)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, newAPIAccount(updated), nil
+}
+
+// applyAPIAccess grants or revokes the access to the Sites and the GOCDB as requested.
+func (siteacc *SiteAccounts) applyAPIAccess(account *data.Account, req *apiAccountRequest) error {
+	if req.SitesAccess != nil {
+		if err := siteacc.AccountsManager().GrantSitesAccess(account, *req.SitesAccess); err != nil {
+			return wrapAPIError(err, "unable to change the Sites access")
+		}
+	}
+	if req.GOCDBAccess != nil {
+		if err := siteacc.AccountsManager().GrantGOCDBAccess(account, *req.GOCDBAccess); err != nil {
+			return wrapAPIError(err, "unable to change the GOCDB access")
+		}
+	}
+	return nil
+}
+
+func apiRemoveAccount(siteacc *SiteAccounts, r *http.Request, principal *apiPrincipal) (int, interface{}, error) {
+	account, err := siteacc.findAPIAccount(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	err = siteacc.AccountsManager().RemoveAccount(account)
+	siteacc.auditAPI(r, principal, audit.ActionAccountDeletion, account.Email, "", err)
+	if err != nil {
+		return 0, nil, wrapAPIError(err, "unable to remove account")
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+func apiApproveAccount(siteacc *SiteAccounts, r *http.Request, principal *apiPrincipal) (int, interface{}, error) {
+	account, err := siteacc.findAPIAccount(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if !account.PendingApproval {
+		return 0, nil, apiConflict("the account has already been approved")
+	}
+	err = siteacc.AccountsManager().ApproveAccount(account)
+	siteacc.auditAPI(r, principal, audit.ActionStatusChange, account.Email, "approved", err)
+	if err != nil {
+		return 0, nil, wrapAPIError(err, "unable to approve account")
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+func apiSuspendAccount(suspend bool) apiHandlerFunc {
+	return func(siteacc *SiteAccounts, r *http.Request, principal *apiPrincipal) (int, interface{}, error) {
+		account, err := siteacc.findAPIAccount(r)
+		if err != nil {
+			return 0, nil, err
+		}
+		err = siteacc.AccountsManager().SuspendAccount(account, suspend)
+		details := "suspended"
+		if !suspend {
+			details = "resumed"
+		}
+		siteacc.auditAPI(r, principal, audit.ActionStatusChange, account.Email, details, err)
+		if err != nil {
+			return 0, nil, wrapAPIError(err, "unable to change the account status")
+		}
+		return http.StatusNoContent, nil, nil
+	}
+}
+
+func apiListOperators(siteacc *SiteAccounts, r *http.Request, principal *apiPrincipal) (int, interface{}, error) {
+	operators := make([]*apiOperator, 0)
+	for _, op := range siteacc.accessibleOperators(principal) {
+		operators = append(operators, newAPIOperator(op))
+	}
+
+	page, err := paginate(r, len(operators), func(from, to int) interface{} { return operators[from:to] })
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, page, nil
+}
+
+func apiGetOperator(siteacc *SiteAccounts, r *http.Request, principal *apiPrincipal) (int, interface{}, error) {
+	op, err := siteacc.findAPIOperator(r, principal)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, newAPIOperator(op), nil
+}
+
+func apiListSites(siteacc *SiteAccounts, r *http.Request, principal *apiPrincipal) (int, interface{}, error) {
+	opID := r.URL.Query().Get("operator")
+	sites := make([]*apiSite, 0)
+	for _, op := range siteacc.accessibleOperators(principal) {
+		if opID != "" && !strings.EqualFold(op.ID, opID) {
+			continue
+		}
+		for _, site := range op.Sites {
+			sites = append(sites, newAPISite(op.ID, site))
+		}
+	}
+	sort.SliceStable(sites, func(i, j int) bool { return sites[i].ID < sites[j].ID })
+
+	page, err := paginate(r, len(sites), func(from, to int) interface{} { return sites[from:to] })
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, page, nil
+}
+
+// findAPISite returns the site given in the request path along with its operator if the caller may access it.
+func (siteacc *SiteAccounts) findAPISite(r *http.Request, principal *apiPrincipal) (*data.Operator, *data.Site, error) {
+	siteID := urlParam(r, "site")
+	for _, op := range siteacc.OperatorsManager().CloneOperators(false) {
+		if site := op.FindSite(siteID); site != nil {
+			if !principal.canAccessOperator(op.ID) {
+				return nil, nil, apiForbidden("no access to site %v", siteID)
+			}
+			return op, site, nil
+		}
+	}
+	return nil, nil, apiNotFound("no site with ID %v exists", siteID)
+}
+
+func apiGetSite(siteacc *SiteAccounts, r *http.Request, principal *apiPrincipal) (int, interface{}, error) {
+	op, site, err := siteacc.findAPISite(r, principal)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, newAPISite(op.ID, site), nil
+}
+
+func apiSetSiteCredentials(siteacc *SiteAccounts, r *http.Request, principal *apiPrincipal) (int, interface{}, error) {
+	op, site, err := siteacc.findAPISite(r, principal)
+	if err != nil {
+		return 0, nil, err
+	}
+	creds := &struct {
+		ID     string `json:"id"`
+		Secret string `json:"secret"`
+	}{}
+	if err := decodeAPIRequest(r, creds); err != nil {
+		return 0, nil, err
+	}
+	if creds.ID == "" || creds.Secret == "" {
+		return 0, nil, apiBadRequest("both the ID and the secret of the credentials are required")
+	}
+
+	siteData := &data.Site{ID: site.ID}
+	siteData.Config.TestClientCredentials = credentials.Credentials{ID: creds.ID, Secret: creds.Secret}
+	err = siteacc.OperatorsManager().UpdateSite(op.ID, siteData)
+	siteacc.auditAPI(r, principal, audit.ActionTestCredentialsUpdate, "", fmt.Sprintf("site=%v", site.ID), err)
+	if err != nil {
+		return 0, nil, wrapAPIError(err, "unable to update the test client credentials")
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+func apiListAPIKeys(siteacc *SiteAccounts, r *http.Request, principal *apiPrincipal) (int, interface{}, error) {
+	op, err := siteacc.findAPIOperator(r, principal)
+	if err != nil {
+		return 0, nil, err
+	}
+	keys := make([]*apiAPIKey, 0)
+	for _, apiKey := range siteacc.OperatorsManager().CloneAPIKeys(op.ID) {
+		keys = append(keys, newAPIAPIKey(apiKey))
+	}
+
+	page, err := paginate(r, len(keys), func(from, to int) interface{} { return keys[from:to] })
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, page, nil
+}
+
+func apiIssueAPIKey(siteacc *SiteAccounts, r *http.Request, principal *apiPrincipal) (int, interface{}, error) {
+	op, err := siteacc.findAPIOperator(r, principal)
+	if err != nil {
+		return 0, nil, err
+	}
+	req := &struct {
+		Name    string     `json:"name"`
+		Scope   string     `json:"scope"`
+		Expires *time.Time `json:"expires"`
+	}{}
+	if err := decodeAPIRequest(r, req); err != nil {
+		return 0, nil, err
+	}
+	var expires time.Time
+	if req.Expires != nil {
+		expires = *req.Expires
+	}
+
+	// The key is only returned once; only its hash is stored
+	info, apiKey, err := siteacc.OperatorsManager().IssueAPIKey(op.ID, req.Name, req.Scope, expires, principal.actor())
+	siteacc.auditAPI(r, principal, audit.ActionAPIKeyChange, "", fmt.Sprintf("operator=%v", op.ID), err)
+	if err != nil {
+		return 0, nil, newAPIError(http.StatusBadRequest, "invalid_request", "unable to issue API key: %v", err)
+	}
+	key := newAPIAPIKey(info)
+	key.Key = apiKey
+	return http.StatusCreated, key, nil
+}
+
+// findAPIAPIKey returns the API key given in the request path along with its operator.
+func (siteacc *SiteAccounts) findAPIAPIKey(r *http.Request, principal *apiPrincipal) (*data.Operator, *data.OperatorAPIKey, error) {
+	op, err := siteacc.findAPIOperator(r, principal)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyID := urlParam(r, "key")
+	for _, apiKey := range siteacc.OperatorsManager().CloneAPIKeys(op.ID) {
+		if apiKey.ID == keyID {
+			return op, apiKey, nil
+		}
+	}
+	return nil, nil, apiNotFound("no API key with ID %v exists", keyID)
+}
+
+func apiRotateAPIKey(siteacc *SiteAccounts, r *http.Request, principal *apiPrincipal) (int, interface{}, error) {
+	op, info, err := siteacc.findAPIAPIKey(r, principal)
+	if err != nil {
+		return 0, nil, err
+	}
+	apiKey, err := siteacc.OperatorsManager().RotateAPIKey(op.ID, info.ID)
+	siteacc.auditAPI(r, principal, audit.ActionAPIKeyChange, "", fmt.Sprintf("operator=%v; key=%v", op.ID, info.ID), err)
+	if err != nil {
+		return 0, nil, wrapAPIError(err, "unable to rotate API key")
+	}
+	key := newAPIAPIKey(info)
+	key.Key = apiKey
+	return http.StatusOK, key, nil
+}
+
+func apiRevokeAPIKey(siteacc *SiteAccounts, r *http.Request, principal *apiPrincipal) (int, interface{}, error) {
+	op, info, err := siteacc.findAPIAPIKey(r, principal)
+	if err != nil {
+		return 0, nil, err
+	}
+	err = siteacc.OperatorsManager().RevokeAPIKey(op.ID, info.ID)
+	siteacc.auditAPI(r, principal, audit.ActionAPIKeyChange, "", fmt.Sprintf("operator=%v; key=%v", op.ID, info.ID), err)
+	if err != nil {
+		return 0, nil, wrapAPIError(err, "unable to revoke API key")
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+// wrapAPIError turns errors of the managers into bad requests, keeping password policy violations.
+func wrapAPIError(err error, msg string) error {
+	var policyErr *credentials.PolicyError
+	if errors.As(err, &policyErr) {
+		return err
+	}
+	return newAPIError(http.StatusBadRequest, "invalid_request", "%v: %v", msg, err)
+}
@@ -0,0 +1,243 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package siteacc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/credentials"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const testAPIToken = "admin-token"
+
+func newTestAPI(t *testing.T) (*SiteAccounts, http.Handler) {
+	t.Helper()
+
+	log := zerolog.Nop()
+	conf := &config.Configuration{}
+	conf.API.Tokens = map[string]string{"admin": testAPIToken}
+	siteacc := &SiteAccounts{
+		conf: conf,
+		log:  &log,
+		operatorsManager: newTestOperatorsManager(t,
+			&data.Operator{ID: "op2", Sites: []*data.Site{{ID: "site-c"}}},
+			&data.Operator{ID: "op1", Sites: []*data.Site{{ID: "site-b"}, {ID: "site-a"}}},
+		),
+	}
+	return siteacc, siteacc.newAPIHandler()
+}
+
+func issueTestAPIKey(t *testing.T, siteacc *SiteAccounts, opID string, scope string) string {
+	t.Helper()
+
+	_, key, err := siteacc.operatorsManager.IssueAPIKey(opID, scope, scope, time.Time{}, "")
+	if err != nil {
+		t.Fatalf("unable to issue the API key: %v", err)
+	}
+	return key
+}
+
+func apiRequest(handler http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func decodeAPIResponse(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+
+	if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+		t.Fatalf("unable to decode the response: %v", err)
+	}
+}
+
+func expectAPIError(t *testing.T, w *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+
+	if w.Code != status {
+		t.Errorf("expected status %d, got %d: %v", status, w.Code, w.Body.String())
+		return
+	}
+	apiErr := &apiError{}
+	decodeAPIResponse(t, w, apiErr)
+	if apiErr.Code != code {
+		t.Errorf("expected error code %v, got %+v", code, apiErr)
+	}
+}
+
+func TestAPIAuthentication(t *testing.T) {
+	siteacc, handler := newTestAPI(t)
+	monitoringKey := issueTestAPIKey(t, siteacc, "op1", data.APIKeyScopeMonitoring)
+
+	w := apiRequest(handler, "GET", "/api/v1/sites/", "", "")
+	expectAPIError(t, w, http.StatusUnauthorized, "unauthorized")
+	if w.Header().Get("WWW-Authenticate") == "" {
+		t.Error("expected a bearer challenge")
+	}
+	expectAPIError(t, apiRequest(handler, "GET", "/api/v1/sites/", "unknown.secret", ""), http.StatusUnauthorized, "unauthorized")
+
+	// Operator API keys never grant access to the administrative endpoints
+	expectAPIError(t, apiRequest(handler, "GET", "/api/v1/accounts/", monitoringKey, ""), http.StatusForbidden, "forbidden")
+	expectAPIError(t, apiRequest(handler, "GET", "/api/v1/operators/op1/apikeys", monitoringKey, ""), http.StatusForbidden, "forbidden")
+
+	if w := apiRequest(handler, "GET", "/api/v1/sites/", monitoringKey, ""); w.Code != http.StatusOK {
+		t.Errorf("expected the API key to be accepted, got %d", w.Code)
+	}
+	if w := apiRequest(handler, "GET", "/api/v1/sites/", testAPIToken, ""); w.Code != http.StatusOK {
+		t.Errorf("expected the API token to be accepted, got %d", w.Code)
+	}
+
+	expectAPIError(t, apiRequest(handler, "GET", "/api/v1/unknown", testAPIToken, ""), http.StatusNotFound, "not_found")
+	expectAPIError(t, apiRequest(handler, "PATCH", "/api/v1/sites/site-a", testAPIToken, ""), http.StatusMethodNotAllowed, "method_not_allowed")
+}
+
+func TestAPISites(t *testing.T) {
+	siteacc, handler := newTestAPI(t)
+
+	type sitesPage struct {
+		Items []*apiSite `json:"items"`
+		Total int        `json:"total"`
+	}
+
+	page := &sitesPage{}
+	decodeAPIResponse(t, apiRequest(handler, "GET", "/api/v1/sites/?offset=1&limit=1", testAPIToken, ""), page)
+	if page.Total != 3 || len(page.Items) != 1 || page.Items[0].ID != "site-b" || page.Items[0].Operator != "op1" {
+		t.Errorf("expected the second of all sites, got %+v", page)
+	}
+	page = &sitesPage{}
+	decodeAPIResponse(t, apiRequest(handler, "GET", "/api/v1/sites/?offset=5", testAPIToken, ""), page)
+	if page.Total != 3 || len(page.Items) != 0 {
+		t.Errorf("expected an empty page, got %+v", page)
+	}
+	expectAPIError(t, apiRequest(handler, "GET", "/api/v1/sites/?limit=0", testAPIToken, ""), http.StatusBadRequest, "invalid_request")
+	expectAPIError(t, apiRequest(handler, "GET", "/api/v1/sites/?offset=-1", testAPIToken, ""), http.StatusBadRequest, "invalid_request")
+
+	// Operators only see their own sites
+	monitoringKey := issueTestAPIKey(t, siteacc, "op1", data.APIKeyScopeMonitoring)
+	page = &sitesPage{}
+	decodeAPIResponse(t, apiRequest(handler, "GET", "/api/v1/sites/", monitoringKey, ""), page)
+	if page.Total != 2 || page.Items[0].ID != "site-a" || page.Items[1].ID != "site-b" {
+		t.Errorf("expected the sorted sites of op1, got %+v", page)
+	}
+	expectAPIError(t, apiRequest(handler, "GET", "/api/v1/sites/site-c", monitoringKey, ""), http.StatusForbidden, "forbidden")
+	expectAPIError(t, apiRequest(handler, "GET", "/api/v1/sites/site-x", monitoringKey, ""), http.StatusNotFound, "not_found")
+	expectAPIError(t, apiRequest(handler, "GET", "/api/v1/operators/op2", monitoringKey, ""), http.StatusForbidden, "forbidden")
+
+	ops := &struct {
+		Items []*apiOperator `json:"items"`
+	}{}
+	decodeAPIResponse(t, apiRequest(handler, "GET", "/api/v1/operators/", monitoringKey, ""), ops)
+	if len(ops.Items) != 1 || ops.Items[0].ID != "op1" || len(ops.Items[0].Sites) != 2 {
+		t.Errorf("expected only op1, got %+v", ops.Items)
+	}
+
+	// Setting the test client credentials requires the site configuration scope
+	creds := `{"id": "tester", "secret": "secret"}`
+	expectAPIError(t, apiRequest(handler, "PUT", "/api/v1/sites/site-a/credentials", monitoringKey, creds), http.StatusForbidden, "forbidden")
+
+	configKey := issueTestAPIKey(t, siteacc, "op1", data.APIKeyScopeSiteConfig)
+	expectAPIError(t, apiRequest(handler, "PUT", "/api/v1/sites/site-a/credentials", configKey, `{"id": "tester"}`), http.StatusBadRequest, "invalid_request")
+	expectAPIError(t, apiRequest(handler, "PUT", "/api/v1/sites/site-a/credentials", configKey, `{"id": "tester", "secret": "secret", "extra": 1}`), http.StatusBadRequest, "invalid_request")
+	expectAPIError(t, apiRequest(handler, "PUT", "/api/v1/sites/site-c/credentials", configKey, creds), http.StatusForbidden, "forbidden")
+	if w := apiRequest(handler, "PUT", "/api/v1/sites/site-a/credentials", configKey, creds); w.Code != http.StatusNoContent {
+		t.Fatalf("expected the credentials to be set, got %d: %v", w.Code, w.Body.String())
+	}
+
+	site := &apiSite{}
+	decodeAPIResponse(t, apiRequest(handler, "GET", "/api/v1/sites/site-a", configKey, ""), site)
+	if !site.TestClientCredentials {
+		t.Errorf("expected the site to have test client credentials, got %+v", site)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	_, handler := newTestAPI(t)
+
+	w := apiRequest(handler, "POST", "/api/v1/operators/op1/apikeys", testAPIToken, `{"name": "ci", "scope": "monitoring"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected the key to be issued, got %d: %v", w.Code, w.Body.String())
+	}
+	issued := &apiAPIKey{}
+	decodeAPIResponse(t, w, issued)
+	if issued.Key == "" || issued.CreatedBy != "api-token:admin" || issued.DateExpires != nil {
+		t.Errorf("unexpected issued key %+v", issued)
+	}
+	if w := apiRequest(handler, "GET", "/api/v1/sites/", issued.Key, ""); w.Code != http.StatusOK {
+		t.Errorf("expected the issued key to be accepted, got %d", w.Code)
+	}
+
+	expectAPIError(t, apiRequest(handler, "POST", "/api/v1/operators/op1/apikeys", testAPIToken, `{"name": "ci", "scope": "everything"}`), http.StatusBadRequest, "invalid_request")
+	expectAPIError(t, apiRequest(handler, "POST", "/api/v1/operators/op9/apikeys", testAPIToken, `{"name": "ci", "scope": "monitoring"}`), http.StatusNotFound, "not_found")
+
+	keys := &struct {
+		Items []*apiAPIKey `json:"items"`
+	}{}
+	decodeAPIResponse(t, apiRequest(handler, "GET", "/api/v1/operators/op1/apikeys", testAPIToken, ""), keys)
+	if len(keys.Items) != 1 || keys.Items[0].ID != issued.ID || keys.Items[0].Key != "" {
+		t.Errorf("expected the issued key without its secret, got %+v", keys.Items)
+	}
+
+	// Rotating a key immediately invalidates the old one
+	w = apiRequest(handler, "POST", "/api/v1/operators/op1/apikeys/"+issued.ID+"/rotate", testAPIToken, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the key to be rotated, got %d: %v", w.Code, w.Body.String())
+	}
+	rotated := &apiAPIKey{}
+	decodeAPIResponse(t, w, rotated)
+	if rotated.ID != issued.ID || rotated.Key == "" || rotated.Key == issued.Key {
+		t.Errorf("unexpected rotated key %+v", rotated)
+	}
+	expectAPIError(t, apiRequest(handler, "GET", "/api/v1/sites/", issued.Key, ""), http.StatusUnauthorized, "unauthorized")
+
+	if w := apiRequest(handler, "DELETE", "/api/v1/operators/op1/apikeys/"+issued.ID, testAPIToken, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("expected the key to be revoked, got %d: %v", w.Code, w.Body.String())
+	}
+	expectAPIError(t, apiRequest(handler, "GET", "/api/v1/sites/", rotated.Key, ""), http.StatusUnauthorized, "unauthorized")
+	expectAPIError(t, apiRequest(handler, "DELETE", "/api/v1/operators/op1/apikeys/"+issued.ID, testAPIToken, ""), http.StatusNotFound, "not_found")
+}
+
+func TestWriteAPIError(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeAPIError(w, wrapAPIError(credentials.DefaultPasswordPolicy().Verify("short", nil), "unable to set the password"))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	apiErr := &apiError{}
+	decodeAPIResponse(t, w, apiErr)
+	if apiErr.Code != "password_policy" || len(apiErr.Violations) == 0 {
+		t.Errorf("expected the policy violations, got %+v", apiErr)
+	}
+
+	w = httptest.NewRecorder()
+	writeAPIError(w, wrapAPIError(errors.New("operator not found"), "unable to revoke API key"))
+	expectAPIError(t, w, http.StatusBadRequest, "invalid_request")
+}
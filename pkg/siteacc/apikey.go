@@ -0,0 +1,65 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package siteacc
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/cs3org/reva/pkg/siteacc/html"
+	"github.com/pkg/errors"
+)
+
+// apiKeyHeader is the header carrying an operator API key; the Authorization header is left to the authentication of Reva.
+const apiKeyHeader = "X-API-Key"
+
+// apiKeyEndpoints maps all endpoints that can be called using an operator API key to the scope required by the key.
+var apiKeyEndpoints = map[string]string{
+	config.EndpointSiteData:        data.APIKeyScopeMonitoring,
+	config.EndpointSiteUsage:       data.APIKeyScopeMonitoring,
+	config.EndpointSiteUsageExport: data.APIKeyScopeMonitoring,
+
+	config.EndpointSitesExport:   data.APIKeyScopeSiteConfig,
+	config.EndpointSiteConfigure: data.APIKeyScopeSiteConfig,
+	config.EndpointIssueSiteKey:  data.APIKeyScopeSiteConfig,
+}
+
+// getRequestAPIKey returns the operator API key passed with a request or an empty string if there is none.
+func getRequestAPIKey(r *http.Request) string {
+	return strings.TrimSpace(r.Header.Get(apiKeyHeader))
+}
+
+// authenticateAPIKey verifies the API key of a request to the given endpoint, returning a transient session holding the key and its operator.
+func (siteacc *SiteAccounts) authenticateAPIKey(ep endpoint, apiKey string, r *http.Request) (*html.Session, error) {
+	scope, ok := apiKeyEndpoints[ep.Path]
+	if !ok {
+		return nil, errors.Errorf("the endpoint can't be accessed using an API key")
+	}
+
+	op, key, err := siteacc.operatorsManager.VerifyAPIKey(apiKey)
+	if err != nil {
+		return nil, err
+	}
+	if !key.Grants(scope) {
+		return nil, errors.Errorf("the API key lacks the %v scope", scope)
+	}
+	return html.NewAPIKeySession(r, op, key), nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package siteacc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/cs3org/reva/pkg/siteacc/manager"
+	"github.com/rs/zerolog"
+)
+
+func newTestOperatorsManager(t *testing.T, ops ...*data.Operator) *manager.OperatorsManager {
+	t.Helper()
+
+	dir := t.TempDir()
+	conf := &config.Configuration{}
+	conf.Storage.File.OperatorsFile = filepath.Join(dir, "operators.json")
+	conf.Storage.File.AccountsFile = filepath.Join(dir, "accounts.json")
+
+	opsData, _ := json.Marshal(ops)
+	if err := ioutil.WriteFile(conf.Storage.File.OperatorsFile, opsData, 0600); err != nil {
+		t.Fatalf("unable to write the operators: %v", err)
+	}
+
+	log := zerolog.Nop()
+	storage, err := data.NewFileStorage(conf, &log)
+	if err != nil {
+		t.Fatalf("unable to create the storage: %v", err)
+	}
+	mngr, err := manager.NewOperatorsManager(storage, conf, &log)
+	if err != nil {
+		t.Fatalf("unable to create the operators manager: %v", err)
+	}
+	return mngr
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	siteacc := &SiteAccounts{operatorsManager: newTestOperatorsManager(t, &data.Operator{ID: "op"})}
+	_, monitoringKey, err := siteacc.operatorsManager.IssueAPIKey("op", "monitoring", data.APIKeyScopeMonitoring, time.Time{}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, configKey, err := siteacc.operatorsManager.IssueAPIKey("op", "config", data.APIKeyScopeSiteConfig, time.Time{}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		key     string
+		wantErr bool
+	}{
+		{"monitoring", config.EndpointSiteData, monitoringKey, false},
+		{"site config on monitoring endpoint", config.EndpointSiteUsage, configKey, false},
+		{"site config", config.EndpointSitesExport, configKey, false},
+		{"insufficient scope", config.EndpointSiteConfigure, monitoringKey, true},
+		{"endpoint without keys", config.EndpointAccount, configKey, true},
+		{"invalid key", config.EndpointSiteData, "unknown.secret", true},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		session, err := siteacc.authenticateAPIKey(endpoint{Path: tt.path}, tt.key, r)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%v: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil {
+			if user := session.APIKeyUser(); user == nil || user.Operator.ID != "op" || user.Key.Hash != "" {
+				t.Errorf("%v: unexpected API key user %+v", tt.name, user)
+			}
+		}
+	}
+}
+
+func TestGetRequestAPIKey(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	if key := getRequestAPIKey(r); key != "" {
+		t.Errorf("expected no key, got %q", key)
+	}
+	r.Header.Set(apiKeyHeader, " abc.def ")
+	if key := getRequestAPIKey(r); key != "abc.def" {
+		t.Errorf("expected the trimmed key, got %q", key)
+	}
+}
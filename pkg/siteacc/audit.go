@@ -0,0 +1,221 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package siteacc
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/auth/loginguard"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/siteacc/audit"
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/html"
+	"github.com/cs3org/reva/pkg/siteacc/manager"
+)
+
+// auditedEndpoints maps all endpoints whose calls are recorded in the audit log to the respective action.
+var auditedEndpoints = map[string]string{
+	config.EndpointLogin:           audit.ActionLogin,
+	config.EndpointVerifyTwoFactor: audit.ActionLogin,
+
+	config.EndpointUpdate:        audit.ActionAccountUpdate,
+	config.EndpointResetPassword: audit.ActionPasswordReset,
+
+	config.EndpointApprove:          audit.ActionStatusChange,
+	config.EndpointSuspend:          audit.ActionStatusChange,
+	config.EndpointGrantSitesAccess: audit.ActionAccessChange,
+	config.EndpointGrantGOCDBAccess: audit.ActionAccessChange,
+
+	config.EndpointGrantSiteManagement: audit.ActionAccessChange,
+
+	config.EndpointEnableTwoFactor:  audit.ActionTwoFactorChange,
+	config.EndpointDisableTwoFactor: audit.ActionTwoFactorChange,
+
+	config.EndpointSitesConfigure: audit.ActionTestCredentialsUpdate,
+	config.EndpointSiteConfigure:  audit.ActionTestCredentialsUpdate,
+	config.EndpointSitesImport:    audit.ActionTestCredentialsUpdate,
+
+	config.EndpointRemove:          audit.ActionAccountDeletion,
+	config.EndpointRequestDeletion: audit.ActionAccountDeletion,
+	config.EndpointConfirmDeletion: audit.ActionAccountDeletion,
+	config.EndpointCancelDeletion:  audit.ActionAccountDeletion,
+
+	config.EndpointUnlock: audit.ActionUnlock,
+
+	config.EndpointAPIKeysCreate: audit.ActionAPIKeyChange,
+	config.EndpointAPIKeysRotate: audit.ActionAPIKeyChange,
+	config.EndpointAPIKeysRevoke: audit.ActionAPIKeyChange,
+
+	config.EndpointAnnouncementPost:   audit.ActionAnnouncement,
+	config.EndpointAnnouncementRemove: audit.ActionAnnouncement,
+}
+
+// auditRecord collects the information about an audited endpoint call.
+type auditRecord struct {
+	event *audit.Event
+
+	role     string
+	password bool
+}
+
+// beginAudit gathers all information about an endpoint call needed for its audit event before the call is handled; nil is returned if the endpoint isn't audited.
+func (siteacc *SiteAccounts) beginAudit(ep endpoint, r *http.Request, body []byte, session *html.Session) *auditRecord {
+	action, ok := auditedEndpoints[ep.Path]
+	if !ok || siteacc.auditor == nil {
+		return nil
+	}
+
+	record := &auditRecord{
+		event: &audit.Event{
+			Action: action,
+			IP:     session.RemoteAddress,
+		},
+	}
+
+	// The actor is either the logged in user, the API key used or, for the administrative endpoints, the user authenticated by Reva
+	if session.IsUserLoggedIn() {
+		record.event.Actor = session.LoggedInUser().Account.Email
+	} else if user := session.APIKeyUser(); user != nil {
+		record.event.Actor = fmt.Sprintf("api-key:%v/%v", user.Operator.ID, user.Key.ID)
+	} else if user, ok := ctxpkg.ContextGetUser(r.Context()); ok {
+		record.event.Actor = user.Username
+	}
+
+	// Determine the affected account, which is either the invoking user or the one passed in the request; granting site management rights affects the latter
+	if strings.EqualFold(r.URL.Query().Get("invoker"), invokerUser) && session.IsUserLoggedIn() && ep.Path != config.EndpointGrantSiteManagement {
+		record.event.Account = session.LoggedInUser().Account.Email
+	} else if account, err := unmarshalRequestData(body); err == nil && account.Email != "" {
+		record.event.Account = account.Email
+	} else if login := session.PendingLogin(); login != nil {
+		record.event.Account = login.User.Account.Email
+	} else if session.IsUserLoggedIn() {
+		record.event.Account = session.LoggedInUser().Account.Email
+	}
+
+	// Users logging in act on their own behalf
+	if action == audit.ActionLogin && record.event.Actor == "" {
+		record.event.Actor = record.event.Account
+	}
+
+	if ep.Path == config.EndpointUpdate {
+		if account, err := unmarshalRequestData(body); err == nil {
+			record.password = account.Password.Value != ""
+		}
+		if account, err := siteacc.AccountsManager().FindAccount(manager.FindByEmail, record.event.Account); err == nil {
+			record.role = account.Role
+		}
+	}
+
+	// Lifting the lockout of an account affects that account
+	if ep.Path == config.EndpointUnlock {
+		kind, value := r.URL.Query().Get("kind"), r.URL.Query().Get("value")
+		if kind == loginguard.KindAccount {
+			record.event.Account = value
+		}
+		record.event.Details = fmt.Sprintf("%v=%v", kind, value)
+	}
+
+	if id := r.URL.Query().Get("id"); id != "" && action == audit.ActionAPIKeyChange {
+		record.event.Details = fmt.Sprintf("key=%v", id)
+	}
+	if id := r.URL.Query().Get("id"); id != "" && action == audit.ActionAnnouncement {
+		record.event.Details = fmt.Sprintf("announcement=%v", id)
+	}
+	if site := r.URL.Query().Get("site"); site != "" {
+		record.event.Details = fmt.Sprintf("site=%v", site)
+	}
+	if status := r.URL.Query().Get("status"); status != "" {
+		record.event.Details = joinDetails(record.event.Details, fmt.Sprintf("status=%v", status))
+	}
+	if strings.EqualFold(r.URL.Query().Get("dryrun"), "true") {
+		record.event.Details = joinDetails(record.event.Details, "dry run")
+	}
+
+	return record
+}
+
+// finishAudit records the audit event(s) of an endpoint call once it has been handled.
+func (siteacc *SiteAccounts) finishAudit(record *auditRecord, session *html.Session, err error) {
+	if record == nil {
+		return
+	}
+
+	event := record.event
+	event.Success = err == nil
+	if err != nil {
+		event.Details = joinDetails(event.Details, err.Error())
+	}
+
+	// Logins might need to be completed using a second factor
+	if event.Success && event.Action == audit.ActionLogin && !session.IsUserLoggedIn() && session.PendingLogin() != nil {
+		event.Details = joinDetails(event.Details, "awaiting second factor")
+	}
+
+	// Account updates can include password and role changes, which are recorded separately
+	if event.Success && event.Action == audit.ActionAccountUpdate {
+		recorded := false
+		if record.password {
+			changeEvent := *event
+			changeEvent.Action = audit.ActionPasswordChange
+			siteacc.auditor.Record(&changeEvent)
+			recorded = true
+		}
+		if account, err := siteacc.AccountsManager().FindAccount(manager.FindByEmail, event.Account); err == nil && record.role != "" && account.Role != record.role {
+			changeEvent := *event
+			changeEvent.Action = audit.ActionRoleChange
+			changeEvent.Details = joinDetails(event.Details, fmt.Sprintf("%v -> %v", record.role, account.Role))
+			siteacc.auditor.Record(&changeEvent)
+			recorded = true
+		}
+		if recorded {
+			return
+		}
+	}
+
+	siteacc.auditor.Record(event)
+}
+
+// auditOIDCLogin records the audit event of a login through an OpenID Connect provider.
+func (siteacc *SiteAccounts) auditOIDCLogin(email string, session *html.Session, err error) {
+	if siteacc.auditor == nil {
+		return
+	}
+
+	event := &audit.Event{
+		Action:  audit.ActionLogin,
+		Actor:   email,
+		Account: email,
+		IP:      session.RemoteAddress,
+		Success: err == nil,
+		Details: "oidc",
+	}
+	if err != nil {
+		event.Details = joinDetails(event.Details, err.Error())
+	}
+	siteacc.auditor.Record(event)
+}
+
+func joinDetails(details, info string) string {
+	if details == "" {
+		return info
+	}
+	return details + "; " + info
+}
@@ -0,0 +1,206 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package audit
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// ActionLogin is the action of a user logging in.
+	ActionLogin = "login"
+	// ActionPasswordChange is the action of a user changing the password.
+	ActionPasswordChange = "password-change"
+	// ActionPasswordReset is the action of resetting the password of an account.
+	ActionPasswordReset = "password-reset"
+	// ActionAccountUpdate is the action of changing the data of an account.
+	ActionAccountUpdate = "account-update"
+	// ActionRoleChange is the action of changing the role of an account.
+	ActionRoleChange = "role-change"
+	// ActionAccessChange is the action of granting or revoking access to the Sites or the GOCDB.
+	ActionAccessChange = "access-change"
+	// ActionStatusChange is the action of approving, disabling or re-enabling an account.
+	ActionStatusChange = "status-change"
+	// ActionTwoFactorChange is the action of enabling or disabling two-factor authentication.
+	ActionTwoFactorChange = "two-factor-change"
+	// ActionTestCredentialsUpdate is the action of updating the test client credentials of sites.
+	ActionTestCredentialsUpdate = "test-credentials-update"
+	// ActionAccountDeletion is the action of deleting an account or requesting or cancelling its deletion.
+	ActionAccountDeletion = "account-deletion"
+	// ActionUnlock is the action of lifting the lockout of an IP or account.
+	ActionUnlock = "unlock"
+	// ActionAPIKeyChange is the action of issuing, rotating or revoking an API key of an operator.
+	ActionAPIKeyChange = "api-key-change"
+	// ActionAnnouncement is the action of posting or removing an announcement.
+	ActionAnnouncement = "announcement"
+)
+
+// Event holds a single security-relevant action.
+type Event struct {
+	Date    time.Time `json:"date"`
+	Action  string    `json:"action"`
+	Actor   string    `json:"actor"`
+	Account string    `json:"account"`
+	IP      string    `json:"ip"`
+	Success bool      `json:"success"`
+	Details string    `json:"details,omitempty"`
+}
+
+// Sink is the interface of all destinations audit events are written to.
+type Sink interface {
+	// Write writes a single event to the sink.
+	Write(event *Event) error
+	// Close closes the sink.
+	Close() error
+}
+
+// Reader is an optional interface of sinks that are able to read back previously written events.
+type Reader interface {
+	// ReadEvents reads the most recent events, up to the given maximum number.
+	ReadEvents(max int) ([]*Event, error)
+}
+
+// Auditor records audit events, writing them to all configured sinks.
+type Auditor struct {
+	conf *config.Configuration
+	log  *zerolog.Logger
+
+	sinks  []Sink
+	events []*Event
+
+	mutex sync.RWMutex
+}
+
+func (auditor *Auditor) initialize(conf *config.Configuration, log *zerolog.Logger) error {
+	if conf == nil {
+		return errors.Errorf("no configuration provided")
+	}
+	auditor.conf = conf
+
+	if log == nil {
+		return errors.Errorf("no logger provided")
+	}
+	auditor.log = log
+
+	auditor.sinks = make([]Sink, 0, len(conf.Audit.Sinks))
+	for _, name := range conf.Audit.Sinks {
+		sink, err := newSink(strings.ToLower(name), conf)
+		if err != nil {
+			auditor.Close()
+			return errors.Wrapf(err, "unable to create the audit sink %v", name)
+		}
+		auditor.sinks = append(auditor.sinks, sink)
+	}
+
+	// Restore the recent events from the first sink capable of reading them back
+	auditor.events = make([]*Event, 0, conf.Audit.MaxEvents)
+	for _, sink := range auditor.sinks {
+		if reader, ok := sink.(Reader); ok {
+			events, err := reader.ReadEvents(conf.Audit.MaxEvents)
+			if err != nil {
+				// Just warn when not being able to read the events
+				log.Warn().Err(err).Msg("error while reading audit events")
+				continue
+			}
+			auditor.events = append(auditor.events, events...)
+			break
+		}
+	}
+
+	return nil
+}
+
+// Record records the given event; its date is set to the current time if missing.
+func (auditor *Auditor) Record(event *Event) {
+	if event.Date.IsZero() {
+		event.Date = time.Now()
+	}
+
+	auditor.mutex.Lock()
+	defer auditor.mutex.Unlock()
+
+	auditor.events = append(auditor.events, event)
+	if n := len(auditor.events) - auditor.conf.Audit.MaxEvents; n > 0 {
+		auditor.events = append(auditor.events[:0:0], auditor.events[n:]...)
+	}
+
+	for _, sink := range auditor.sinks {
+		if err := sink.Write(event); err != nil {
+			// Just warn when not being able to write the event
+			auditor.log.Warn().Err(err).Str("action", event.Action).Str("account", event.Account).Msg("error while writing audit event")
+		}
+	}
+}
+
+// Events returns the most recent events (newest first) concerning the given account or performed by it; if account is empty, all events are returned. If limit is greater than 0, at most limit events are returned.
+func (auditor *Auditor) Events(account string, limit int) []*Event {
+	auditor.mutex.RLock()
+	defer auditor.mutex.RUnlock()
+
+	events := make([]*Event, 0, 20)
+	for i := len(auditor.events) - 1; i >= 0; i-- {
+		if limit > 0 && len(events) >= limit {
+			break
+		}
+
+		event := auditor.events[i]
+		if account == "" || strings.EqualFold(event.Account, account) || strings.EqualFold(event.Actor, account) {
+			clone := *event
+			events = append(events, &clone)
+		}
+	}
+	return events
+}
+
+// Close closes all sinks.
+func (auditor *Auditor) Close() {
+	for _, sink := range auditor.sinks {
+		if err := sink.Close(); err != nil {
+			auditor.log.Warn().Err(err).Msg("error while closing audit sink")
+		}
+	}
+}
+
+func newSink(name string, conf *config.Configuration) (Sink, error) {
+	switch name {
+	case "file":
+		return newFileSink(conf)
+	case "syslog":
+		return newSyslogSink(conf)
+	case "webhook":
+		return newWebhookSink(conf)
+	}
+
+	return nil, errors.Errorf("unknown audit sink %v", name)
+}
+
+// NewAuditor creates a new auditor instance.
+func NewAuditor(conf *config.Configuration, log *zerolog.Logger) (*Auditor, error) {
+	auditor := &Auditor{}
+	if err := auditor.initialize(conf, log); err != nil {
+		return nil, errors.Wrap(err, "unable to initialize the auditor")
+	}
+	return auditor, nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/rs/zerolog"
+)
+
+func newTestAuditor(t *testing.T, conf *config.Configuration) *Auditor {
+	t.Helper()
+
+	log := zerolog.Nop()
+	auditor, err := NewAuditor(conf, &log)
+	if err != nil {
+		t.Fatalf("unable to create the auditor: %v", err)
+	}
+	return auditor
+}
+
+func TestAuditorEvents(t *testing.T) {
+	conf := &config.Configuration{}
+	conf.Audit.MaxEvents = 3
+	auditor := newTestAuditor(t, conf)
+
+	auditor.Record(&Event{Action: ActionLogin, Actor: "john@example.org", Account: "john@example.org", Success: true})
+	auditor.Record(&Event{Action: ActionRoleChange, Actor: "admin", Account: "john@example.org", Success: true})
+	auditor.Record(&Event{Action: ActionLogin, Actor: "jane@example.org", Account: "jane@example.org"})
+	auditor.Record(&Event{Action: ActionStatusChange, Actor: "admin", Account: "JANE@example.org", Success: true})
+
+	events := auditor.Events("", 0)
+	if len(events) != 3 {
+		t.Fatalf("expected only the 3 most recent events to be kept, got %d", len(events))
+	}
+	if events[0].Action != ActionStatusChange || events[2].Action != ActionRoleChange {
+		t.Errorf("expected the newest events first, got %v, ..., %v", events[0].Action, events[2].Action)
+	}
+	if events[0].Date.IsZero() {
+		t.Error("expected the date of the events to be set")
+	}
+
+	if events := auditor.Events("jane@example.org", 0); len(events) != 2 {
+		t.Errorf("expected 2 events concerning the account, got %d", len(events))
+	}
+	if events := auditor.Events("admin", 1); len(events) != 1 || events[0].Action != ActionStatusChange {
+		t.Errorf("expected the most recent event performed by the actor, got %v", events)
+	}
+
+	// The returned events are copies
+	events[0].Action = "changed"
+	if auditor.Events("", 1)[0].Action != ActionStatusChange {
+		t.Error("expected the recorded events to be unaffected")
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	conf := &config.Configuration{}
+	conf.Audit.Sinks = []string{"file"}
+	conf.Audit.MaxEvents = 2
+	conf.Audit.File.Path = filepath.Join(t.TempDir(), "audit", "events.log")
+
+	auditor := newTestAuditor(t, conf)
+	for i := 0; i < 3; i++ {
+		auditor.Record(&Event{Action: ActionLogin, Account: fmt.Sprintf("user%d@example.org", i)})
+	}
+	auditor.Close()
+
+	// Corrupted lines are skipped when reading the events back
+	data, err := ioutil.ReadFile(conf.Audit.File.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(conf.Audit.File.Path, append(data, []byte("{\"action\":\n")...), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	auditor = newTestAuditor(t, conf)
+	defer auditor.Close()
+	events := auditor.Events("", 0)
+	if len(events) != 2 || events[0].Account != "user2@example.org" || events[1].Account != "user1@example.org" {
+		t.Errorf("expected the most recent events to be restored, got %v", events)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	received := make(chan *Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		event := &Event{}
+		if err := json.NewDecoder(r.Body).Decode(event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- event
+	}))
+	defer server.Close()
+
+	conf := &config.Configuration{}
+	conf.Audit.Webhook.URL = server.URL
+	conf.Audit.Webhook.Token = "secret"
+	sink, err := newWebhookSink(conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := sink.Write(&Event{Action: ActionPasswordReset, Account: "john@example.org"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event := <-received; event.Action != ActionPasswordReset || event.Account != "john@example.org" {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	sink.token = "wrong"
+	if err := sink.Write(&Event{Action: ActionPasswordReset}); err == nil {
+		t.Error("expected an error status of the webhook to fail the write")
+	}
+}
+
+func TestUnknownSink(t *testing.T) {
+	conf := &config.Configuration{}
+	conf.Audit.Sinks = []string{"database"}
+	log := zerolog.Nop()
+	if _, err := NewAuditor(conf, &log); err == nil {
+		t.Error("expected an unknown sink to be rejected")
+	}
+
+	conf.Audit.Sinks = []string{"webhook"}
+	if _, err := NewAuditor(conf, &log); err == nil {
+		t.Error("expected an unconfigured sink to be rejected")
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/pkg/errors"
+)
+
+// fileSink appends events as JSON lines to a file.
+type fileSink struct {
+	path string
+	file *os.File
+
+	mutex sync.Mutex
+}
+
+func (sink *fileSink) Write(event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal the audit event")
+	}
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	if _, err := sink.file.Write(append(data, '\n')); err != nil {
+		return errors.Wrapf(err, "unable to write to the audit file %v", sink.path)
+	}
+	return nil
+}
+
+func (sink *fileSink) ReadEvents(max int) ([]*Event, error) {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	file, err := os.Open(sink.path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open the audit file %v", sink.path)
+	}
+	defer file.Close()
+
+	events := make([]*Event, 0, max)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		event := &Event{}
+		if err := json.Unmarshal(scanner.Bytes(), event); err != nil {
+			// Skip corrupted lines, e.g. caused by a crash while writing
+			continue
+		}
+
+		events = append(events, event)
+		if len(events) > max {
+			events = events[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "unable to read the audit file %v", sink.path)
+	}
+	return events, nil
+}
+
+func (sink *fileSink) Close() error {
+	return sink.file.Close()
+}
+
+func newFileSink(conf *config.Configuration) (*fileSink, error) {
+	path := conf.Audit.File.Path
+	if path == "" {
+		return nil, errors.Errorf("no audit file configured")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.Wrapf(err, "unable to create the directory of the audit file %v", path)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open the audit file %v", path)
+	}
+
+	return &fileSink{
+		path: path,
+		file: file,
+	}, nil
+}
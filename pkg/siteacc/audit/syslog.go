@@ -0,0 +1,62 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package audit
+
+import (
+	"encoding/json"
+	"log/syslog"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/pkg/errors"
+)
+
+// syslogSink writes events as JSON messages to the syslog.
+type syslogSink struct {
+	writer *syslog.Writer
+}
+
+func (sink *syslogSink) Write(event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal the audit event")
+	}
+
+	// Failures are of more interest and thus logged with a higher priority
+	if event.Success {
+		err = sink.writer.Info(string(data))
+	} else {
+		err = sink.writer.Warning(string(data))
+	}
+	return errors.Wrap(err, "unable to write to the syslog")
+}
+
+func (sink *syslogSink) Close() error {
+	return sink.writer.Close()
+}
+
+func newSyslogSink(conf *config.Configuration) (*syslogSink, error) {
+	writer, err := syslog.Dial(conf.Audit.Syslog.Network, conf.Audit.Syslog.Address, syslog.LOG_INFO|syslog.LOG_AUTH, conf.Audit.Syslog.Tag)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to connect to the syslog")
+	}
+	return &syslogSink{writer: writer}, nil
+}
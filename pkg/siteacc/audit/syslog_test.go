@@ -0,0 +1,74 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package audit
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+)
+
+func TestSyslogSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conf := &config.Configuration{}
+	conf.Audit.Syslog.Network = "udp"
+	conf.Audit.Syslog.Address = conn.LocalAddr().String()
+	conf.Audit.Syslog.Tag = "siteacc"
+	sink, err := newSyslogSink(conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer sink.Close()
+
+	read := func() string {
+		buf := make([]byte, 4096)
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("no syslog message received: %v", err)
+		}
+		return string(buf[:n])
+	}
+
+	// The priority is the facility (auth = 4) times 8 plus the severity (info = 6, warning = 4)
+	if err := sink.Write(&Event{Action: ActionLogin, Account: "john@example.org", Success: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg := read(); !strings.HasPrefix(msg, "<38>") || !strings.Contains(msg, "siteacc") || !strings.Contains(msg, `"account":"john@example.org"`) {
+		t.Errorf("unexpected message %q", msg)
+	}
+
+	if err := sink.Write(&Event{Action: ActionLogin, Account: "john@example.org"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg := read(); !strings.HasPrefix(msg, "<36>") {
+		t.Errorf("expected failures to be logged as warnings, got %q", msg)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/pkg/errors"
+)
+
+// webhookSink posts events as JSON to an HTTP endpoint.
+type webhookSink struct {
+	url   string
+	token string
+
+	client *http.Client
+}
+
+func (sink *webhookSink) Write(event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal the audit event")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sink.url, bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "unable to create the webhook request")
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	if sink.token != "" {
+		req.Header.Set("Authorization", "Bearer "+sink.token)
+	}
+
+	resp, err := sink.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "unable to call the webhook")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("the webhook returned %v", resp.Status)
+	}
+	return nil
+}
+
+func (sink *webhookSink) Close() error {
+	return nil
+}
+
+func newWebhookSink(conf *config.Configuration) (*webhookSink, error) {
+	if conf.Audit.Webhook.URL == "" {
+		return nil, errors.Errorf("no webhook URL configured")
+	}
+	return &webhookSink{
+		url:    conf.Audit.Webhook.URL,
+		token:  conf.Audit.Webhook.Token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
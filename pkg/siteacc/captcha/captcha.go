@@ -0,0 +1,136 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package captcha
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/pkg/errors"
+)
+
+// Verifier is the hook point used to verify the CAPTCHA solved by a client, e.g. on the registration form.
+type Verifier interface {
+	// Verify checks the response of the CAPTCHA widget submitted by a client.
+	Verify(response, remoteIP string) error
+}
+
+// Widget holds what a form needs to embed the CAPTCHA of the configured provider.
+type Widget struct {
+	Script  string
+	Class   string
+	SiteKey string
+	// ResponseField is the form field the widget stores its response in.
+	ResponseField string
+}
+
+type provider struct {
+	verifyURL string
+	widget    Widget
+}
+
+// The supported providers all use the same verification protocol.
+var providers = map[string]provider{
+	"hcaptcha": {
+		verifyURL: "https://hcaptcha.com/siteverify",
+		widget:    Widget{Script: "https://js.hcaptcha.com/1/api.js", Class: "h-captcha", ResponseField: "h-captcha-response"},
+	},
+	"recaptcha": {
+		verifyURL: "https://www.google.com/recaptcha/api/siteverify",
+		widget:    Widget{Script: "https://www.google.com/recaptcha/api.js", Class: "g-recaptcha", ResponseField: "g-recaptcha-response"},
+	},
+	"turnstile": {
+		verifyURL: "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+		widget:    Widget{Script: "https://challenges.cloudflare.com/turnstile/v0/api.js", Class: "cf-turnstile", ResponseField: "cf-turnstile-response"},
+	},
+}
+
+type siteVerifier struct {
+	verifyURL string
+	secret    string
+	client    *http.Client
+}
+
+func (v *siteVerifier) Verify(response, remoteIP string) error {
+	if strings.TrimSpace(response) == "" {
+		return errors.Errorf("the CAPTCHA has not been solved")
+	}
+
+	resp, err := v.client.PostForm(v.verifyURL, url.Values{
+		"secret":   {v.secret},
+		"response": {response},
+		"remoteip": {remoteIP},
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to verify the CAPTCHA")
+	}
+	defer resp.Body.Close()
+
+	result := struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return errors.Wrap(err, "unable to decode the CAPTCHA verification")
+	}
+	if !result.Success {
+		return errors.Errorf("the CAPTCHA verification failed (%v)", strings.Join(result.ErrorCodes, ", "))
+	}
+	return nil
+}
+
+// GetWidget returns the widget of the configured provider, or nil if no CAPTCHA has been configured.
+func GetWidget(conf *config.Configuration) *Widget {
+	p, ok := providers[conf.Security.Captcha.Provider]
+	if !ok {
+		return nil
+	}
+	widget := p.widget
+	widget.SiteKey = conf.Security.Captcha.SiteKey
+	return &widget
+}
+
+// New creates a new verifier for the configured provider; nil is returned if no CAPTCHA has been configured.
+func New(conf *config.Configuration) (Verifier, error) {
+	name := conf.Security.Captcha.Provider
+	if name == "" {
+		return nil, nil
+	}
+	p, ok := providers[name]
+	if !ok {
+		return nil, errors.Errorf("unknown CAPTCHA provider %v", name)
+	}
+	if conf.Security.Captcha.SiteKey == "" || conf.Security.Captcha.Secret == "" {
+		return nil, errors.Errorf("no site key or secret configured for the CAPTCHA")
+	}
+
+	verifyURL := p.verifyURL
+	if conf.Security.Captcha.VerifyURL != "" {
+		verifyURL = conf.Security.Captcha.VerifyURL
+	}
+	return &siteVerifier{
+		verifyURL: verifyURL,
+		secret:    conf.Security.Captcha.Secret,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
@@ -21,7 +21,12 @@ package config
 import (
 	"strings"
 
+	"github.com/cs3org/reva/pkg/auth/loginguard"
+	"github.com/cs3org/reva/pkg/mentix/key"
+	"github.com/cs3org/reva/pkg/rhttp/problem"
+	"github.com/cs3org/reva/pkg/siteacc/credentials"
 	"github.com/cs3org/reva/pkg/smtpclient"
+	"github.com/cs3org/reva/pkg/utils"
 )
 
 // Configuration holds the general service configuration.
@@ -30,6 +35,23 @@ type Configuration struct {
 
 	Security struct {
 		CredentialsPassphrase string `mapstructure:"creds_passphrase"`
+
+		// PasswordPolicy defines the requirements the passwords of accounts need to fulfil.
+		PasswordPolicy credentials.PasswordPolicy `mapstructure:"password_policy"`
+
+		// LoginGuard limits the failed logins per IP and per account, locking them out temporarily.
+		LoginGuard loginguard.Config `mapstructure:"login_guard"`
+		// RegistrationGuard limits the registrations per IP and per email address; every registration counts as an attempt.
+		RegistrationGuard loginguard.Config `mapstructure:"registration_guard"`
+
+		Captcha struct {
+			// Provider is either hcaptcha, recaptcha or turnstile; the registration form isn't protected by a CAPTCHA if empty.
+			Provider string `mapstructure:"provider"`
+			SiteKey  string `mapstructure:"site_key"`
+			Secret   string `mapstructure:"secret"`
+			// VerifyURL overrides the verification endpoint of the provider.
+			VerifyURL string `mapstructure:"verify_url"`
+		} `mapstructure:"captcha"`
 	} `mapstructure:"security"`
 
 	Storage struct {
@@ -39,17 +61,46 @@ type Configuration struct {
 			OperatorsFile string `mapstructure:"operators_file"`
 			AccountsFile  string `mapstructure:"accounts_file"`
 		} `mapstructure:"file"`
+
+		SQL struct {
+			// Engine is either mysql, the default, sqlite or postgres.
+			Engine   string `mapstructure:"db_engine"`
+			Username string `mapstructure:"db_username"`
+			Password string `mapstructure:"db_password"`
+			Host     string `mapstructure:"db_host"`
+			Port     int    `mapstructure:"db_port"`
+			Name     string `mapstructure:"db_name"`
+			// Path is the file of the sqlite database.
+			Path string `mapstructure:"db_path"`
+		} `mapstructure:"sql"`
 	} `mapstructure:"storage"`
 
 	Email struct {
 		SMTP              *smtpclient.SMTPCredentials `mapstructure:"smtp"`
 		NotificationsMail string                      `mapstructure:"notifications_mail"`
+
+		// TemplatesDirectory holds templates overriding the built-in emails, organized in one subdirectory per language.
+		TemplatesDirectory string `mapstructure:"templates_dir"`
+		// DefaultLanguage is the language of emails sent to accounts that haven't chosen one.
+		DefaultLanguage string `mapstructure:"default_language"`
 	} `mapstructure:"email"`
 
 	Mentix struct {
 		URL                      string `mapstructure:"url"`
 		DataEndpoint             string `mapstructure:"data_endpoint"`
 		SiteRegistrationEndpoint string `mapstructure:"sitereg_endpoint"`
+		SiteUpdateEndpoint       string `mapstructure:"siteupdate_endpoint"`
+		// UsageEndpoint is the endpoint of the Mentix usage exporter, providing the API usage reported by the sites.
+		UsageEndpoint string `mapstructure:"usage_endpoint"`
+
+		// Signing holds the keys used to sign requests sent to Mentix and to verify the requests received from it.
+		Signing key.SigningConfig `mapstructure:"signing"`
+
+		// Cache configures the caching of the operators and sites queried from Mentix; a negative TTL disables it.
+		Cache struct {
+			TTL      int `mapstructure:"ttl"`
+			MaxStale int `mapstructure:"max_stale"`
+		} `mapstructure:"cache"`
 	} `mapstructure:"mentix"`
 
 	Webserver struct {
@@ -58,14 +109,145 @@ type Configuration struct {
 		SessionTimeout      int  `mapstructure:"session_timeout"`
 		VerifyRemoteAddress bool `mapstructure:"verify_remote_address"`
 		LogSessions         bool `mapstructure:"log_sessions"`
+
+		// SlidingSessions restarts the timeout of a session on every request, so that sessions only expire after a period of inactivity.
+		SlidingSessions bool `mapstructure:"sliding_sessions"`
+		// SessionCleanupInterval is the number of seconds between two removals of the expired sessions.
+		SessionCleanupInterval int `mapstructure:"session_cleanup_interval"`
+
+		// SessionStore is the store of the sessions: memory or redis.
+		SessionStore string `mapstructure:"session_store"`
+		Redis        struct {
+			Address  string `mapstructure:"address"`
+			Username string `mapstructure:"username"`
+			Password string `mapstructure:"password"`
+			// Prefix is prepended to the keys of the sessions, allowing several services to share a Redis instance.
+			Prefix string `mapstructure:"prefix"`
+		} `mapstructure:"redis"`
 	} `mapstructure:"webserver"`
 
+	OIDC struct {
+		// Issuer is the URL of the OpenID Connect provider; logging in through OIDC is disabled if empty.
+		Issuer       string   `mapstructure:"issuer"`
+		ClientID     string   `mapstructure:"client_id"`
+		ClientSecret string   `mapstructure:"client_secret"`
+		Scopes       []string `mapstructure:"scopes"`
+		// Name is the name of the provider shown on the login page.
+		Name string `mapstructure:"name"`
+
+		// AutoProvision creates an account for users logging in for the first time.
+		AutoProvision bool `mapstructure:"auto_provision"`
+		// OperatorClaim is the claim holding the operator of new accounts; DefaultOperator is used if it is missing.
+		OperatorClaim   string `mapstructure:"operator_claim"`
+		DefaultOperator string `mapstructure:"default_operator"`
+		DefaultRole     string `mapstructure:"default_role"`
+	} `mapstructure:"oidc"`
+
+	Accounts struct {
+		// DeletionCoolingOff is the number of days a deleted account is kept disabled before being purged.
+		DeletionCoolingOff int `mapstructure:"deletion_cooling_off"`
+		// DeletionConfirmationTimeout is the number of hours the owner of an account has to confirm its deletion using the link sent via email.
+		DeletionConfirmationTimeout int `mapstructure:"deletion_confirmation_timeout"`
+		// VerifyEmail requires users to verify the email address of new accounts before logging in.
+		VerifyEmail bool `mapstructure:"verify_email"`
+		// RequireApproval requires new accounts to be approved by an administrator before logging in.
+		RequireApproval bool `mapstructure:"require_approval"`
+	} `mapstructure:"accounts"`
+
 	GOCDB struct {
 		URL      string `mapstructure:"url"`
 		WriteURL string `mapstructure:"write_url"`
 
 		APIKey string `mapstructure:"apikey"`
 	} `mapstructure:"gocdb"`
+
+	Contacts struct {
+		// Source is the source contact data is imported from (ldap or rest); the import is disabled if empty.
+		Source string `mapstructure:"source"`
+		// Interval is the number of minutes between two imports; if 0, contacts are only imported through the API.
+		Interval int `mapstructure:"interval"`
+		// DryRun only reports the changes of the periodic imports without applying them.
+		DryRun bool `mapstructure:"dry_run"`
+		// Conflicts defines how contact data differing from the data of an existing account is handled: source, account or skip.
+		Conflicts string `mapstructure:"conflicts"`
+
+		LDAP struct {
+			utils.LDAPConn `mapstructure:",squash"`
+			BaseDN         string `mapstructure:"base_dn"`
+			Filter         string `mapstructure:"filter"`
+		} `mapstructure:"ldap"`
+
+		REST struct {
+			URL   string `mapstructure:"url"`
+			Token string `mapstructure:"token"`
+		} `mapstructure:"rest"`
+
+		// Mapping holds the names of the attributes (or JSON fields) holding the contact data.
+		Mapping struct {
+			ID          string `mapstructure:"id"`
+			Email       string `mapstructure:"email"`
+			FirstName   string `mapstructure:"first_name"`
+			LastName    string `mapstructure:"last_name"`
+			PhoneNumber string `mapstructure:"phone_number"`
+			Operator    string `mapstructure:"operator"`
+			Role        string `mapstructure:"role"`
+		} `mapstructure:"mapping"`
+
+		// DefaultOperator and DefaultRole are used for new accounts if the contact data lacks them.
+		DefaultOperator string `mapstructure:"default_operator"`
+		DefaultRole     string `mapstructure:"default_role"`
+	} `mapstructure:"contacts"`
+
+	Health struct {
+		// Interval is the number of minutes between two health checks of the sites; the sites aren't checked if 0.
+		Interval int `mapstructure:"interval"`
+		// Timeout is the number of seconds a single request of a health check may take.
+		Timeout int `mapstructure:"timeout"`
+		// WebDAVPath is the path of the WebDAV endpoint relative to the Reva endpoint; it is used for sites that don't register a WebDAV endpoint.
+		WebDAVPath string `mapstructure:"webdav_path"`
+	} `mapstructure:"health"`
+
+	Audit struct {
+		// Sinks are the sinks audit events are written to: file, syslog and webhook.
+		Sinks []string `mapstructure:"sinks"`
+		// MaxEvents is the number of recent events kept for querying.
+		MaxEvents int `mapstructure:"max_events"`
+
+		File struct {
+			Path string `mapstructure:"path"`
+		} `mapstructure:"file"`
+
+		Syslog struct {
+			// Network and Address define a remote syslog server; the local one is used if empty.
+			Network string `mapstructure:"network"`
+			Address string `mapstructure:"address"`
+			Tag     string `mapstructure:"tag"`
+		} `mapstructure:"syslog"`
+
+		Webhook struct {
+			URL   string `mapstructure:"url"`
+			Token string `mapstructure:"token"`
+		} `mapstructure:"webhook"`
+	} `mapstructure:"audit"`
+
+	Announcements struct {
+		// Registry is the announcement registry driver; announcements are disabled if empty. Use a registry shared with the OCS service to show the announcements in the clients as well.
+		Registry   string                            `mapstructure:"registry"`
+		Registries map[string]map[string]interface{} `mapstructure:"registries"`
+	} `mapstructure:"announcements"`
+
+	Metrics struct {
+		// Enabled collects the metrics of the service, which are exposed by the prometheus service.
+		Enabled bool `mapstructure:"enabled"`
+	} `mapstructure:"metrics"`
+
+	// Errors configures the error pages and problem details returned to the clients.
+	Errors problem.Config `mapstructure:"errors"`
+
+	API struct {
+		// Tokens maps names to the bearer tokens granting full access to the REST API; operators can also use their API keys.
+		Tokens map[string]string `mapstructure:"tokens"`
+	} `mapstructure:"api"`
 }
 
 // Cleanup cleans up certain settings, normalizing them.
@@ -80,8 +262,133 @@ func (cfg *Configuration) Cleanup() {
 		cfg.GOCDB.URL += "/"
 	}
 
+	// Request the claims needed to identify users by default; the openid scope is always required
+	if len(cfg.OIDC.Scopes) == 0 {
+		cfg.OIDC.Scopes = []string{"openid", "email", "profile"}
+	} else if !contains(cfg.OIDC.Scopes, "openid") {
+		cfg.OIDC.Scopes = append([]string{"openid"}, cfg.OIDC.Scopes...)
+	}
+	if cfg.OIDC.Name == "" {
+		cfg.OIDC.Name = "Single Sign-On"
+	}
+	if cfg.OIDC.DefaultRole == "" {
+		cfg.OIDC.DefaultRole = "Site administrator"
+	}
+
+	// Cache the Mentix data for 5 minutes and serve it for up to a day while Mentix is unavailable by default
+	if cfg.Mentix.Cache.TTL == 0 {
+		cfg.Mentix.Cache.TTL = 300
+	}
+	if cfg.Mentix.Cache.MaxStale <= 0 {
+		cfg.Mentix.Cache.MaxStale = 86400
+	}
+
+	// Remove the expired sessions every minute by default
+	if cfg.Webserver.SessionCleanupInterval <= 0 {
+		cfg.Webserver.SessionCleanupInterval = 60
+	}
+	if cfg.Webserver.Redis.Address == "" {
+		cfg.Webserver.Redis.Address = "localhost:6379"
+	}
+
+	// Keep deleted accounts for a month by default
+	if cfg.Accounts.DeletionCoolingOff <= 0 {
+		cfg.Accounts.DeletionCoolingOff = 30
+	}
+	if cfg.Accounts.DeletionConfirmationTimeout <= 0 {
+		cfg.Accounts.DeletionConfirmationTimeout = 24
+	}
+
 	// Ensure the GOCDB Write URL ends with a slash
 	if cfg.GOCDB.WriteURL != "" && !strings.HasSuffix(cfg.GOCDB.WriteURL, "/") {
 		cfg.GOCDB.WriteURL += "/"
 	}
+
+	if cfg.Email.DefaultLanguage == "" {
+		cfg.Email.DefaultLanguage = "en"
+	}
+
+	cfg.Security.Captcha.Provider = strings.ToLower(cfg.Security.Captcha.Provider)
+
+	// Passwords need to fulfil the default policy unless configured otherwise
+	policy := &cfg.Security.PasswordPolicy
+	if policy.MinLength <= 0 {
+		policy.MinLength = credentials.DefaultPasswordPolicy().MinLength
+	}
+	if policy.CharacterClasses == nil {
+		policy.CharacterClasses = credentials.DefaultPasswordPolicy().CharacterClasses
+	}
+	if policy.Pwned.URL == "" {
+		policy.Pwned.URL = "https://api.pwnedpasswords.com/range/"
+	}
+	if policy.Pwned.Timeout <= 0 {
+		policy.Pwned.Timeout = 5
+	}
+
+	cfg.cleanupContacts()
+
+	// Give up on a health check request after 10 seconds by default
+	if cfg.Health.Timeout <= 0 {
+		cfg.Health.Timeout = 10
+	}
+	if cfg.Health.WebDAVPath == "" {
+		cfg.Health.WebDAVPath = "remote.php/webdav/"
+	}
+
+	if cfg.Audit.MaxEvents <= 0 {
+		cfg.Audit.MaxEvents = 1000
+	}
+	if cfg.Audit.Syslog.Tag == "" {
+		cfg.Audit.Syslog.Tag = "siteacc"
+	}
+}
+
+func (cfg *Configuration) cleanupContacts() {
+	contacts := &cfg.Contacts
+	contacts.Source = strings.ToLower(contacts.Source)
+	if contacts.Conflicts == "" {
+		contacts.Conflicts = "source"
+	}
+	if contacts.DefaultRole == "" {
+		contacts.DefaultRole = "Site administrator"
+	}
+
+	// Default to the common LDAP attributes respectively to the JSON fields named like the account data
+	mapping := &contacts.Mapping
+	if contacts.Source == "ldap" {
+		if contacts.LDAP.Port == 0 {
+			contacts.LDAP.Port = 636
+		}
+		if contacts.LDAP.Filter == "" {
+			contacts.LDAP.Filter = "(objectClass=person)"
+		}
+		setDefault(&mapping.ID, "uid")
+		setDefault(&mapping.Email, "mail")
+		setDefault(&mapping.FirstName, "givenName")
+		setDefault(&mapping.LastName, "sn")
+		setDefault(&mapping.PhoneNumber, "telephoneNumber")
+	} else {
+		setDefault(&mapping.ID, "id")
+		setDefault(&mapping.Email, "email")
+		setDefault(&mapping.FirstName, "firstName")
+		setDefault(&mapping.LastName, "lastName")
+		setDefault(&mapping.PhoneNumber, "phoneNumber")
+		setDefault(&mapping.Operator, "operator")
+		setDefault(&mapping.Role, "role")
+	}
+}
+
+func setDefault(value *string, def string) {
+	if *value == "" {
+		*value = def
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
@@ -37,15 +37,82 @@ const (
 	EndpointConfigure = "/configure"
 	// EndpointRemove is the endpoint path for account removal.
 	EndpointRemove = "/remove"
+	// EndpointApprove is the endpoint path for approving new accounts.
+	EndpointApprove = "/approve"
+	// EndpointSuspend is the endpoint path for disabling or re-enabling accounts.
+	EndpointSuspend = "/suspend"
+
+	// EndpointVerifyEmail is the endpoint path users verify the email address of their account through.
+	EndpointVerifyEmail = "/verify-email"
+	// EndpointResendVerification is the endpoint path for resending the email address verification.
+	EndpointResendVerification = "/resend-verification"
+	// EndpointImportContacts is the endpoint path for importing contacts from the configured source.
+	EndpointImportContacts = "/import-contacts"
+	// EndpointAuditEvents is the endpoint path for querying recent audit events.
+	EndpointAuditEvents = "/audit-events"
+	// EndpointLockouts is the endpoint path for listing the IPs and accounts locked out after too many failed attempts.
+	EndpointLockouts = "/lockouts"
+	// EndpointUnlock is the endpoint path for lifting the lockout of an IP or account.
+	EndpointUnlock = "/unlock"
+
+	// EndpointAnnouncements is the endpoint path for listing all announcements.
+	EndpointAnnouncements = "/announcements"
+	// EndpointAnnouncementPost is the endpoint path for posting a new announcement.
+	EndpointAnnouncementPost = "/announcement-post"
+	// EndpointAnnouncementRemove is the endpoint path for removing an announcement.
+	EndpointAnnouncementRemove = "/announcement-remove"
 
 	// EndpointSiteGet is the endpoint path for retrieving site data.
 	EndpointSiteGet = "/site-get"
 
+	// EndpointSiteData is the endpoint path for retrieving the GOCDB data of a site.
+	EndpointSiteData = "/site-data"
+	// EndpointSiteUpdate is the endpoint path for writing changes of a site back to the GOCDB.
+	EndpointSiteUpdate = "/site-update"
+	// EndpointSiteUsage is the endpoint path for retrieving the API usage of a site.
+	EndpointSiteUsage = "/site-usage"
+	// EndpointSiteUsageExport is the endpoint path for exporting the API usage of a site.
+	EndpointSiteUsageExport = "/site-usage-export"
+
+	// EndpointIssueSiteKey is the endpoint path for issuing (and rotating) site keys.
+	EndpointIssueSiteKey = "/issue-site-key"
+	// EndpointSiteConfigure is the endpoint path for configuring a single site.
+	EndpointSiteConfigure = "/site-configure"
+	// EndpointGrantSiteManagement is the endpoint path for granting or revoking the management rights over a site.
+	EndpointGrantSiteManagement = "/grant-site-management"
+	// EndpointSitesExport is the endpoint path for exporting the configurations of all sites of an operator.
+	EndpointSitesExport = "/sites-export"
+	// EndpointSitesImport is the endpoint path for importing the configurations of multiple sites at once.
+	EndpointSitesImport = "/sites-import"
+	// EndpointVerifySiteKey is the endpoint path for site key validation.
+	EndpointVerifySiteKey = "/verify-site-key"
+	// EndpointSitesHealth is the endpoint path for retrieving the results of the latest site health checks.
+	EndpointSitesHealth = "/sites-health"
+	// EndpointCheckSitesHealth is the endpoint path for checking the health of all sites immediately.
+	EndpointCheckSitesHealth = "/check-sites-health"
+
+	// EndpointAPIKeysList is the endpoint path for listing the API keys of an operator.
+	EndpointAPIKeysList = "/apikeys-list"
+	// EndpointAPIKeysCreate is the endpoint path for issuing a new API key of an operator.
+	EndpointAPIKeysCreate = "/apikeys-create"
+	// EndpointAPIKeysRotate is the endpoint path for rotating an API key of an operator.
+	EndpointAPIKeysRotate = "/apikeys-rotate"
+	// EndpointAPIKeysRevoke is the endpoint path for revoking an API key of an operator.
+	EndpointAPIKeysRevoke = "/apikeys-revoke"
+
 	// EndpointSitesConfigure is the endpoint path for sites configuration.
 	EndpointSitesConfigure = "/sites-configure"
+	// EndpointOperatorConfigure is the endpoint path for operator configuration.
+	EndpointOperatorConfigure = "/operator-configure"
 
 	// EndpointLogin is the endpoint path for (internal) user login.
 	EndpointLogin = "/login"
+	// EndpointVerifyTwoFactor is the endpoint path for completing a user login using the second factor.
+	EndpointVerifyTwoFactor = "/verify-2fa"
+	// EndpointOIDCLogin is the endpoint path for starting a user login through an OpenID Connect provider.
+	EndpointOIDCLogin = "/oidc-login"
+	// EndpointOIDCCallback is the endpoint path the OpenID Connect provider redirects users back to.
+	EndpointOIDCCallback = "/oidc-callback"
 	// EndpointLogout is the endpoint path for (internal) user logout.
 	EndpointLogout = "/logout"
 	// EndpointResetPassword is the endpoint path for resetting user passwords
@@ -53,6 +120,27 @@ const (
 	// EndpointContact is the endpoint path for sending contact emails
 	EndpointContact = "/contact"
 
+	// EndpointRequestDeletion is the endpoint path for requesting the deletion of the own account.
+	EndpointRequestDeletion = "/request-deletion"
+	// EndpointConfirmDeletion is the endpoint path users confirm the deletion of their account through.
+	EndpointConfirmDeletion = "/confirm-deletion"
+	// EndpointCancelDeletion is the endpoint path for canceling a requested account deletion.
+	EndpointCancelDeletion = "/cancel-deletion"
+	// EndpointExportAccount is the endpoint path for exporting the own account data.
+	EndpointExportAccount = "/export-account"
+
+	// EndpointEnrollTwoFactor is the endpoint path for enrolling a device for two-factor authentication.
+	EndpointEnrollTwoFactor = "/enroll-2fa"
+	// EndpointEnableTwoFactor is the endpoint path for enabling two-factor authentication.
+	EndpointEnableTwoFactor = "/enable-2fa"
+	// EndpointDisableTwoFactor is the endpoint path for disabling two-factor authentication.
+	EndpointDisableTwoFactor = "/disable-2fa"
+
+	// EndpointConfigureNotifications is the endpoint path for configuring the notification preferences.
+	EndpointConfigureNotifications = "/configure-notifications"
+	// EndpointClearNotifications is the endpoint path for clearing all panel notifications.
+	EndpointClearNotifications = "/clear-notifications"
+
 	// EndpointVerifyUserToken is the endpoint path for user token validation.
 	EndpointVerifyUserToken = "/verify-user-token"
 
@@ -63,4 +151,6 @@ const (
 
 	// EndpointDispatchAlert is the endpoint path for dispatching alerts from Prometheus.
 	EndpointDispatchAlert = "/dispatch-alert"
+	// EndpointDispatchSiteEvents is the endpoint path for dispatching site events sent by Mentix.
+	EndpointDispatchSiteEvents = "/dispatch-site-events"
 )
@@ -0,0 +1,93 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package contacts
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/pkg/errors"
+)
+
+// Contact holds the contact data of a person as provided by an external source.
+type Contact struct {
+	ID          string `json:"id"`
+	Email       string `json:"email"`
+	FirstName   string `json:"firstName"`
+	LastName    string `json:"lastName"`
+	PhoneNumber string `json:"phoneNumber"`
+	Operator    string `json:"operator"`
+	Role        string `json:"role"`
+}
+
+// Source is the interface of all external sources contact data is imported from.
+type Source interface {
+	// Contacts retrieves all contacts currently provided by the source.
+	Contacts(ctx context.Context) ([]*Contact, error)
+}
+
+func (contact *Contact) cleanup() {
+	contact.ID = strings.TrimSpace(contact.ID)
+	contact.Email = strings.TrimSpace(contact.Email)
+	contact.FirstName = strings.TrimSpace(contact.FirstName)
+	contact.LastName = strings.TrimSpace(contact.LastName)
+	contact.PhoneNumber = strings.TrimSpace(contact.PhoneNumber)
+	contact.Operator = strings.TrimSpace(contact.Operator)
+	contact.Role = strings.TrimSpace(contact.Role)
+}
+
+// newContact creates a contact from generic values, using the configured mapping to look up the contact data.
+func newContact(conf *config.Configuration, value func(name string) string) *Contact {
+	mapping := conf.Contacts.Mapping
+	lookup := func(name string) string {
+		if name == "" {
+			return ""
+		}
+		return value(name)
+	}
+
+	contact := &Contact{
+		ID:          lookup(mapping.ID),
+		Email:       lookup(mapping.Email),
+		FirstName:   lookup(mapping.FirstName),
+		LastName:    lookup(mapping.LastName),
+		PhoneNumber: lookup(mapping.PhoneNumber),
+		Operator:    lookup(mapping.Operator),
+		Role:        lookup(mapping.Role),
+	}
+	contact.cleanup()
+	return contact
+}
+
+func newSource(conf *config.Configuration) (Source, error) {
+	switch conf.Contacts.Source {
+	case "ldap":
+		return newLDAPSource(conf)
+	case "rest":
+		return newRESTSource(conf)
+	}
+
+	return nil, errors.Errorf("unknown contacts source %v", conf.Contacts.Source)
+}
+
+func (contact *Contact) String() string {
+	return fmt.Sprintf("%v (%v)", contact.Email, contact.ID)
+}
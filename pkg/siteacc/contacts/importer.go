@@ -0,0 +1,291 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package contacts
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/cs3org/reva/pkg/siteacc/manager"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// ConflictsPreferSource overwrites the account data with the contact data.
+	ConflictsPreferSource = "source"
+	// ConflictsPreferAccount keeps the account data and only fills in missing data.
+	ConflictsPreferAccount = "account"
+	// ConflictsSkip reports differing data as a conflict without changing the account.
+	ConflictsSkip = "skip"
+)
+
+// Report holds the outcome of a single contacts import.
+type Report struct {
+	Date   time.Time `json:"date"`
+	DryRun bool      `json:"dryRun"`
+
+	Created   []string `json:"created"`
+	Updated   []string `json:"updated"`
+	Conflicts []string `json:"conflicts"`
+	Errors    []string `json:"errors"`
+}
+
+// Importer imports contacts from an external source into the site accounts.
+type Importer struct {
+	conf *config.Configuration
+	log  *zerolog.Logger
+
+	source          Source
+	accountsManager *manager.AccountsManager
+
+	mutex    sync.Mutex
+	stopChan chan struct{}
+}
+
+func (importer *Importer) initialize(conf *config.Configuration, log *zerolog.Logger, amngr *manager.AccountsManager) error {
+	if conf == nil {
+		return errors.Errorf("no configuration provided")
+	}
+	importer.conf = conf
+
+	if log == nil {
+		return errors.Errorf("no logger provided")
+	}
+	importer.log = log
+
+	if amngr == nil {
+		return errors.Errorf("no accounts manager provided")
+	}
+	importer.accountsManager = amngr
+
+	switch conf.Contacts.Conflicts {
+	case ConflictsPreferSource, ConflictsPreferAccount, ConflictsSkip:
+	default:
+		return errors.Errorf("unknown conflicts policy %v", conf.Contacts.Conflicts)
+	}
+
+	source, err := newSource(conf)
+	if err != nil {
+		return errors.Wrap(err, "unable to create the contacts source")
+	}
+	importer.source = source
+
+	return nil
+}
+
+// Start starts the periodic import of contacts; nothing happens if no interval has been configured.
+func (importer *Importer) Start() {
+	if importer.conf.Contacts.Interval <= 0 || importer.stopChan != nil {
+		return
+	}
+
+	importer.stopChan = make(chan struct{})
+	go func(stop <-chan struct{}) {
+		ticker := time.NewTicker(time.Duration(importer.conf.Contacts.Interval) * time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				// Pick up any changes made by other instances sharing the same storage first
+				importer.accountsManager.ReloadAccounts()
+				if _, err := importer.Run(context.Background(), importer.conf.Contacts.DryRun); err != nil {
+					importer.log.Err(err).Msg("error while importing contacts")
+				}
+
+			case <-stop:
+				return
+			}
+		}
+	}(importer.stopChan)
+}
+
+// Stop stops the periodic import of contacts.
+func (importer *Importer) Stop() {
+	if importer.stopChan != nil {
+		close(importer.stopChan)
+		importer.stopChan = nil
+	}
+}
+
+// Run imports all contacts from the source; if dryRun is set, the changes are only reported but not applied.
+func (importer *Importer) Run(ctx context.Context, dryRun bool) (*Report, error) {
+	// Never run multiple imports at once
+	importer.mutex.Lock()
+	defer importer.mutex.Unlock()
+
+	contacts, err := importer.source.Contacts(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve the contacts")
+	}
+
+	report := &Report{
+		Date:      time.Now(),
+		DryRun:    dryRun,
+		Created:   []string{},
+		Updated:   []string{},
+		Conflicts: []string{},
+		Errors:    []string{},
+	}
+
+	accounts := importer.accountsManager.CloneAccounts(true)
+	accountsByID := make(map[string]*data.Account, len(accounts))
+	accountsByEmail := make(map[string]*data.Account, len(accounts))
+	for _, account := range accounts {
+		if account.ExternalID != "" {
+			accountsByID[account.ExternalID] = account
+		}
+		accountsByEmail[strings.ToLower(account.Email)] = account
+	}
+
+	seenIDs := make(map[string]bool, len(contacts))
+	seenEmails := make(map[string]bool, len(contacts))
+	for _, contact := range contacts {
+		if contact.ID == "" || contact.Email == "" {
+			report.Errors = append(report.Errors, errors.Errorf("%v: contact lacks an ID or email address", contact).Error())
+			continue
+		}
+
+		email := strings.ToLower(contact.Email)
+		if seenIDs[contact.ID] || seenEmails[email] {
+			report.Conflicts = append(report.Conflicts, errors.Errorf("%v: contact appears multiple times in the source", contact).Error())
+			continue
+		}
+		seenIDs[contact.ID] = true
+		seenEmails[email] = true
+
+		account, ok := accountsByID[contact.ID]
+		if !ok {
+			account = accountsByEmail[email]
+		}
+
+		if account == nil {
+			importer.createAccount(contact, report, dryRun)
+		} else {
+			importer.updateAccount(account, contact, accountsByEmail, report, dryRun)
+		}
+	}
+
+	importer.log.Info().Bool("dry-run", dryRun).Int("contacts", len(contacts)).Int("created", len(report.Created)).Int("updated", len(report.Updated)).Int("conflicts", len(report.Conflicts)).Int("errors", len(report.Errors)).Msg("imported contacts")
+	return report, nil
+}
+
+func (importer *Importer) createAccount(contact *Contact, report *Report, dryRun bool) {
+	accountData := &data.Account{
+		Email:       contact.Email,
+		FirstName:   contact.FirstName,
+		LastName:    contact.LastName,
+		PhoneNumber: contact.PhoneNumber,
+		Operator:    contact.Operator,
+		Role:        contact.Role,
+		ExternalID:  contact.ID,
+	}
+	if accountData.Operator == "" {
+		accountData.Operator = importer.conf.Contacts.DefaultOperator
+	}
+	if accountData.Role == "" {
+		accountData.Role = importer.conf.Contacts.DefaultRole
+	}
+
+	if !dryRun {
+		if err := importer.accountsManager.ImportAccount(accountData); err != nil {
+			report.Errors = append(report.Errors, errors.Wrapf(err, "%v", contact).Error())
+			return
+		}
+	}
+	report.Created = append(report.Created, contact.String())
+}
+
+func (importer *Importer) updateAccount(account *data.Account, contact *Contact, accountsByEmail map[string]*data.Account, report *Report, dryRun bool) {
+	if account.ExternalID != "" && account.ExternalID != contact.ID {
+		report.Conflicts = append(report.Conflicts, errors.Errorf("%v: account %v is linked to contact %v", contact, account.Email, account.ExternalID).Error())
+		return
+	}
+
+	accountData := &data.Account{
+		Email:       account.Email,
+		FirstName:   account.FirstName,
+		LastName:    account.LastName,
+		PhoneNumber: account.PhoneNumber,
+		ExternalID:  contact.ID,
+	}
+	differs := !strings.EqualFold(account.Email, contact.Email) || account.FirstName != contact.FirstName || account.LastName != contact.LastName || account.PhoneNumber != contact.PhoneNumber
+
+	switch importer.conf.Contacts.Conflicts {
+	case ConflictsPreferSource:
+		accountData.Email = contact.Email
+		overwrite := func(value *string, other string) {
+			if other != "" {
+				*value = other
+			}
+		}
+		overwrite(&accountData.FirstName, contact.FirstName)
+		overwrite(&accountData.LastName, contact.LastName)
+		overwrite(&accountData.PhoneNumber, contact.PhoneNumber)
+
+	case ConflictsPreferAccount:
+		fill := func(value *string, other string) {
+			if *value == "" {
+				*value = other
+			}
+		}
+		fill(&accountData.FirstName, contact.FirstName)
+		fill(&accountData.LastName, contact.LastName)
+		fill(&accountData.PhoneNumber, contact.PhoneNumber)
+
+	case ConflictsSkip:
+		if differs {
+			report.Conflicts = append(report.Conflicts, errors.Errorf("%v: contact data differs from account %v", contact, account.Email).Error())
+			return
+		}
+	}
+
+	if !strings.EqualFold(account.Email, accountData.Email) {
+		if other, ok := accountsByEmail[strings.ToLower(accountData.Email)]; ok && other != account {
+			report.Conflicts = append(report.Conflicts, errors.Errorf("%v: email address of account %v is already used by another account", contact, account.Email).Error())
+			return
+		}
+	}
+
+	if accountData.Email == account.Email && accountData.FirstName == account.FirstName && accountData.LastName == account.LastName && accountData.PhoneNumber == account.PhoneNumber && accountData.ExternalID == account.ExternalID {
+		return
+	}
+
+	if !dryRun {
+		if err := importer.accountsManager.SyncAccount(account.Email, accountData); err != nil {
+			report.Errors = append(report.Errors, errors.Wrapf(err, "%v", contact).Error())
+			return
+		}
+	}
+	report.Updated = append(report.Updated, contact.String())
+}
+
+// NewImporter creates a new contacts importer instance.
+func NewImporter(conf *config.Configuration, log *zerolog.Logger, amngr *manager.AccountsManager) (*Importer, error) {
+	importer := &Importer{}
+	if err := importer.initialize(conf, log, amngr); err != nil {
+		return nil, errors.Wrap(err, "unable to initialize the contacts importer")
+	}
+	return importer, nil
+}
@@ -0,0 +1,212 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package contacts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/cs3org/reva/pkg/siteacc/manager"
+	"github.com/rs/zerolog"
+)
+
+type memStorage struct {
+	operators data.Operators
+	accounts  data.Accounts
+}
+
+func (s *memStorage) ReadOperators() (*data.Operators, error) { return &s.operators, nil }
+func (s *memStorage) WriteOperators(ops *data.Operators) error {
+	s.operators = *ops
+	return nil
+}
+func (s *memStorage) OperatorAdded(*data.Operator)   {}
+func (s *memStorage) OperatorUpdated(*data.Operator) {}
+func (s *memStorage) OperatorRemoved(*data.Operator) {}
+
+func (s *memStorage) ReadAccounts() (*data.Accounts, error) { return &s.accounts, nil }
+func (s *memStorage) WriteAccounts(accounts *data.Accounts) error {
+	s.accounts = append(data.Accounts{}, *accounts...)
+	return nil
+}
+func (s *memStorage) AccountAdded(*data.Account)   {}
+func (s *memStorage) AccountUpdated(*data.Account) {}
+func (s *memStorage) AccountRemoved(*data.Account) {}
+
+type staticSource []*Contact
+
+func (source staticSource) Contacts(context.Context) ([]*Contact, error) {
+	return source, nil
+}
+
+func newTestImporter(t *testing.T, conflicts string, contacts []*Contact, accounts ...*data.Account) (*Importer, *manager.AccountsManager) {
+	t.Helper()
+
+	conf := &config.Configuration{}
+	conf.Contacts.Conflicts = conflicts
+	conf.Contacts.DefaultOperator = "op"
+	conf.Contacts.DefaultRole = "Member"
+	log := zerolog.Nop()
+
+	amngr, err := manager.NewAccountsManager(&memStorage{accounts: accounts}, conf, &log)
+	if err != nil {
+		t.Fatalf("unable to create the accounts manager: %v", err)
+	}
+	return &Importer{conf: conf, log: &log, source: staticSource(contacts), accountsManager: amngr}, amngr
+}
+
+func newTestContact(id, email string) *Contact {
+	return &Contact{ID: id, Email: email, FirstName: "John", LastName: "Doe"}
+}
+
+func newTestAccount(email string) *data.Account {
+	return &data.Account{Email: email, FirstName: "John", LastName: "Doe", Operator: "op", Role: "Admin"}
+}
+
+func findAccount(t *testing.T, amngr *manager.AccountsManager, email string) *data.Account {
+	t.Helper()
+
+	account, err := amngr.FindAccount(manager.FindByEmail, email)
+	if err != nil {
+		t.Fatalf("expected an account %v: %v", email, err)
+	}
+	return account
+}
+
+func TestImporterRun(t *testing.T) {
+	updated := newTestContact("2", "jane@example.org")
+	updated.PhoneNumber = "+41 22 767 6111"
+	contacts := []*Contact{
+		newTestContact("1", "john@example.org"),
+		updated,
+		newTestContact("", "noid@example.org"),
+		newTestContact("3", "JOHN@example.org"),
+	}
+	importer, amngr := newTestImporter(t, ConflictsPreferSource, contacts, newTestAccount("jane@example.org"))
+
+	report, err := importer.Run(context.Background(), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Created) != 1 || len(report.Updated) != 1 || len(report.Errors) != 1 || len(report.Conflicts) != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	created := findAccount(t, amngr, "john@example.org")
+	if created.ExternalID != "1" || !created.PendingApproval || created.Operator != "op" || created.Role != "Member" {
+		t.Errorf("expected an imported account awaiting its approval, got %+v", created)
+	}
+	if account := findAccount(t, amngr, "jane@example.org"); account.ExternalID != "2" || account.PhoneNumber != "+41 22 767 6111" {
+		t.Errorf("expected the existing account to be linked and updated, got %+v", account)
+	}
+
+	// Importing the same contacts again changes nothing
+	report, err = importer.Run(context.Background(), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Created) != 0 || len(report.Updated) != 0 {
+		t.Errorf("expected nothing to change, got %+v", report)
+	}
+}
+
+func TestImporterDryRun(t *testing.T) {
+	importer, amngr := newTestImporter(t, ConflictsPreferSource, []*Contact{newTestContact("1", "john@example.org")})
+
+	report, err := importer.Run(context.Background(), true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !report.DryRun || len(report.Created) != 1 {
+		t.Errorf("expected the account creation to be reported, got %+v", report)
+	}
+	if _, err := amngr.FindAccount(manager.FindByEmail, "john@example.org"); err == nil {
+		t.Error("expected a dry run not to create any accounts")
+	}
+}
+
+func TestImporterConflicts(t *testing.T) {
+	contact := newTestContact("1", "john@example.org")
+	contact.FirstName = "Johnny"
+	contact.PhoneNumber = "+41 22 767 6111"
+
+	tests := []struct {
+		conflicts string
+		firstName string
+		phone     string
+		conflict  bool
+	}{
+		{ConflictsPreferSource, "Johnny", "+41 22 767 6111", false},
+		{ConflictsPreferAccount, "John", "+41 22 767 6111", false},
+		{ConflictsSkip, "John", "", true},
+	}
+
+	for _, tt := range tests {
+		importer, amngr := newTestImporter(t, tt.conflicts, []*Contact{contact}, newTestAccount("john@example.org"))
+		report, err := importer.Run(context.Background(), false)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.conflicts, err)
+		}
+		if (len(report.Conflicts) == 1) != tt.conflict {
+			t.Errorf("%s: unexpected conflicts %v", tt.conflicts, report.Conflicts)
+		}
+
+		account := findAccount(t, amngr, "john@example.org")
+		if account.FirstName != tt.firstName || account.PhoneNumber != tt.phone {
+			t.Errorf("%s: expected %q and %q, got %q and %q", tt.conflicts, tt.firstName, tt.phone, account.FirstName, account.PhoneNumber)
+		}
+	}
+}
+
+func TestImporterLinkedAccount(t *testing.T) {
+	linked := newTestAccount("john@example.org")
+	linked.ExternalID = "other"
+	importer, _ := newTestImporter(t, ConflictsPreferSource, []*Contact{newTestContact("1", "john@example.org")}, linked)
+
+	report, err := importer.Run(context.Background(), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Conflicts) != 1 || len(report.Updated) != 0 {
+		t.Errorf("expected an account linked to another contact to be reported as a conflict, got %+v", report)
+	}
+}
+
+func TestNewImporterConflictsPolicy(t *testing.T) {
+	conf := &config.Configuration{}
+	conf.Contacts.Source = "rest"
+	conf.Contacts.REST.URL = "http://localhost/contacts"
+	conf.Contacts.Conflicts = "merge"
+	log := zerolog.Nop()
+
+	amngr, err := manager.NewAccountsManager(&memStorage{}, conf, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewImporter(conf, &log, amngr); err == nil {
+		t.Error("expected an unknown conflicts policy to be rejected")
+	}
+
+	conf.Contacts.Conflicts = ConflictsSkip
+	if _, err := NewImporter(conf, &log, amngr); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package contacts
+
+import (
+	"context"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+)
+
+type ldapSource struct {
+	conf *config.Configuration
+}
+
+// Contacts retrieves all entries matching the configured filter from the LDAP server.
+func (source *ldapSource) Contacts(ctx context.Context) ([]*Contact, error) {
+	conf := &source.conf.Contacts
+	conn, err := utils.GetLDAPConnection(&conf.LDAP.LDAPConn)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to connect to the LDAP server")
+	}
+	defer conn.Close()
+
+	searchRequest := ldap.NewSearchRequest(
+		conf.LDAP.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		conf.LDAP.Filter,
+		source.attributes(),
+		nil,
+	)
+	// Directories usually limit the size of the results, so page through them
+	sr, err := conn.SearchWithPaging(searchRequest, 500)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to search the LDAP server")
+	}
+
+	contacts := make([]*Contact, 0, len(sr.Entries))
+	for _, entry := range sr.Entries {
+		contacts = append(contacts, newContact(source.conf, entry.GetEqualFoldAttributeValue))
+	}
+	return contacts, nil
+}
+
+func (source *ldapSource) attributes() []string {
+	mapping := source.conf.Contacts.Mapping
+	attrs := make([]string, 0, 7)
+	for _, attr := range []string{mapping.ID, mapping.Email, mapping.FirstName, mapping.LastName, mapping.PhoneNumber, mapping.Operator, mapping.Role} {
+		if attr != "" {
+			attrs = append(attrs, attr)
+		}
+	}
+	return attrs
+}
+
+func newLDAPSource(conf *config.Configuration) (*ldapSource, error) {
+	if conf.Contacts.LDAP.Hostname == "" {
+		return nil, errors.Errorf("no LDAP server configured")
+	}
+	if conf.Contacts.LDAP.BaseDN == "" {
+		return nil, errors.Errorf("no LDAP base DN configured")
+	}
+	return &ldapSource{conf: conf}, nil
+}
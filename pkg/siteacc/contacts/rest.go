@@ -0,0 +1,90 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package contacts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/pkg/errors"
+)
+
+type restSource struct {
+	conf   *config.Configuration
+	client *http.Client
+}
+
+// Contacts retrieves all contacts from the configured endpoint, which needs to return a JSON array of objects.
+func (source *restSource) Contacts(ctx context.Context) ([]*Contact, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.conf.Contacts.REST.URL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create the contacts request")
+	}
+	req.Header.Set("Accept", "application/json")
+	if token := source.conf.Contacts.REST.Token; token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := source.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to query the contacts endpoint")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("the contacts endpoint returned %v", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the contacts")
+	}
+
+	entries := make([]map[string]interface{}, 0)
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, errors.Wrap(err, "invalid contacts data")
+	}
+
+	contacts := make([]*Contact, 0, len(entries))
+	for _, entry := range entries {
+		entry := entry
+		contacts = append(contacts, newContact(source.conf, func(name string) string {
+			if v, ok := entry[name]; ok && v != nil {
+				return fmt.Sprint(v)
+			}
+			return ""
+		}))
+	}
+	return contacts, nil
+}
+
+func newRESTSource(conf *config.Configuration) (*restSource, error) {
+	if conf.Contacts.REST.URL == "" {
+		return nil, errors.Errorf("no contacts URL configured")
+	}
+	return &restSource{
+		conf:   conf,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
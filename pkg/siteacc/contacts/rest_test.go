@@ -0,0 +1,86 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package contacts
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+)
+
+func TestRESTSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`[
+			{"uid": 42, "mail": " john@example.org ", "givenName": "John", "sn": "Doe", "phone": null},
+			{"uid": "43", "mail": "jane@example.org"}
+		]`))
+	}))
+	defer server.Close()
+
+	conf := &config.Configuration{}
+	conf.Contacts.REST.URL = server.URL
+	conf.Contacts.REST.Token = "secret"
+	conf.Contacts.Mapping.ID = "uid"
+	conf.Contacts.Mapping.Email = "mail"
+	conf.Contacts.Mapping.FirstName = "givenName"
+	conf.Contacts.Mapping.LastName = "sn"
+	conf.Contacts.Mapping.PhoneNumber = "phone"
+
+	source, err := newRESTSource(conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	contacts, err := source.Contacts(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(contacts) != 2 {
+		t.Fatalf("expected 2 contacts, got %d", len(contacts))
+	}
+	expected := Contact{ID: "42", Email: "john@example.org", FirstName: "John", LastName: "Doe"}
+	if *contacts[0] != expected {
+		t.Errorf("expected %+v, got %+v", expected, *contacts[0])
+	}
+	if contacts[1].ID != "43" || contacts[1].FirstName != "" {
+		t.Errorf("unexpected contact %+v", contacts[1])
+	}
+
+	conf.Contacts.REST.Token = "wrong"
+	if _, err := source.Contacts(context.Background()); err == nil {
+		t.Error("expected an error status to fail the retrieval")
+	}
+}
+
+func TestNewSource(t *testing.T) {
+	conf := &config.Configuration{}
+	for _, name := range []string{"", "csv", "rest", "ldap"} {
+		conf.Contacts.Source = name
+		if _, err := newSource(conf); err == nil {
+			t.Errorf("%q: expected an unconfigured source to be rejected", name)
+		}
+	}
+}
@@ -28,22 +28,37 @@ import (
 // Password holds a hash password alongside its salt value.
 type Password struct {
 	Value string `json:"value"`
-}
 
-const (
-	passwordMinLength = 8
-)
+	// History holds the hashes of the passwords used before the current one, the most recent first.
+	History []string `json:"history,omitempty"`
+}
 
-// Set sets a new password by hashing the plaintext version using bcrypt.
-func (password *Password) Set(pwd string) error {
-	if err := VerifyPassword(pwd); err != nil {
-		return errors.Wrap(err, "invalid password")
+// Set sets a new password by hashing the plaintext version using bcrypt; if a policy is given, the password needs to fulfil it.
+// Passwords generated by the service itself are set without a policy.
+func (password *Password) Set(pwd string, policy *PasswordPolicy) error {
+	if policy != nil {
+		if err := policy.Verify(pwd, password.recentHashes(policy.History)); err != nil {
+			return err
+		}
 	}
 
 	pwdData, err := bcrypt.GenerateFromPassword([]byte(pwd), bcrypt.DefaultCost)
 	if err != nil {
 		return errors.Wrap(err, "unable to generate password hash")
 	}
+
+	// Keep the hashes of the previous passwords to prevent their reuse; only as many as required by the policy are kept
+	if password.IsValid() {
+		password.History = append([]string{password.Value}, password.History...)
+	}
+	if policy != nil {
+		if keep := policy.History - 1; keep <= 0 {
+			password.History = nil
+		} else if len(password.History) > keep {
+			password.History = password.History[:keep]
+		}
+	}
+
 	password.Value = string(pwdData)
 	return nil
 }
@@ -62,22 +77,22 @@ func (password *Password) IsValid() bool {
 // Clear resets the password.
 func (password *Password) Clear() {
 	password.Value = ""
+	password.History = nil
 }
 
-// VerifyPassword checks whether the given password abides to the enforced password strength.
-func VerifyPassword(pwd string) error {
-	if len(pwd) < passwordMinLength {
-		return errors.Errorf("the password must be at least 8 characters long")
-	}
-	if !strings.ContainsAny(pwd, "abcdefghijklmnopqrstuvwxyz") {
-		return errors.Errorf("the password must contain at least one lowercase letter")
+func (password *Password) recentHashes(count int) []string {
+	if count <= 0 || !password.IsValid() {
+		return nil
 	}
-	if !strings.ContainsAny(pwd, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") {
-		return errors.Errorf("the password must contain at least one uppercase letter")
-	}
-	if !strings.ContainsAny(pwd, "0123456789") {
-		return errors.Errorf("the password must contain at least one digit")
+
+	hashes := append([]string{password.Value}, password.History...)
+	if len(hashes) > count {
+		hashes = hashes[:count]
 	}
+	return hashes
+}
 
-	return nil
+// VerifyPassword checks whether the given password abides to the default password policy.
+func VerifyPassword(pwd string) error {
+	return DefaultPasswordPolicy().Verify(pwd, nil)
 }
@@ -0,0 +1,105 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package credentials
+
+import (
+	"testing"
+)
+
+func TestPasswordSet(t *testing.T) {
+	password := &Password{}
+	if err := password.Set("Secret123", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !password.IsValid() || !password.Compare("Secret123") || password.Compare("secret123") {
+		t.Errorf("expected the password to be hashed, got %+v", password)
+	}
+	if len(password.History) != 0 {
+		t.Errorf("expected no history for the first password, got %v", password.History)
+	}
+
+	// Without a policy, the password isn't verified and the history isn't trimmed
+	for _, pwd := range []string{"a", "b", "c"} {
+		if err := password.Set(pwd, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(password.History) != 3 {
+		t.Errorf("expected 3 previous passwords, got %d", len(password.History))
+	}
+
+	password.Clear()
+	if password.IsValid() || password.History != nil {
+		t.Errorf("expected the password to be cleared, got %+v", password)
+	}
+}
+
+func TestPasswordHistory(t *testing.T) {
+	policy := &PasswordPolicy{History: 2}
+	password := &Password{}
+	for _, pwd := range []string{"first", "second"} {
+		if err := password.Set(pwd, policy); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := password.Set("second", policy); !isViolation(err, PolicyRuleHistory) {
+		t.Errorf("expected the current password to be rejected, got %v", err)
+	}
+	if err := password.Set("first", policy); !isViolation(err, PolicyRuleHistory) {
+		t.Errorf("expected the previous password to be rejected, got %v", err)
+	}
+	if !password.Compare("second") {
+		t.Error("expected a rejected password not to replace the current one")
+	}
+
+	// Only the hashes required by the policy are kept
+	if err := password.Set("third", policy); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(password.History) != 1 {
+		t.Errorf("expected 1 previous password, got %d", len(password.History))
+	}
+	if err := password.Set("first", policy); err != nil {
+		t.Errorf("expected a password older than the history to be accepted, got %v", err)
+	}
+
+	// Disabling the history drops all previous hashes
+	if err := password.Set("fourth", &PasswordPolicy{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if password.History != nil {
+		t.Errorf("expected no previous passwords, got %v", password.History)
+	}
+}
+
+func TestVerifyPassword(t *testing.T) {
+	tests := map[string]bool{
+		"Secret123":  true,
+		"Sec123":     false,
+		"secret123":  false,
+		"SECRET123":  false,
+		"SecretWord": false,
+	}
+	for pwd, valid := range tests {
+		if err := VerifyPassword(pwd); (err == nil) != valid {
+			t.Errorf("%v: expected valid=%v, got %v", pwd, valid, err)
+		}
+	}
+}
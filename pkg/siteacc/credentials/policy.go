@@ -0,0 +1,184 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package credentials
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// CharacterClassLowercase requires passwords to contain a lowercase letter.
+	CharacterClassLowercase = "lowercase"
+	// CharacterClassUppercase requires passwords to contain an uppercase letter.
+	CharacterClassUppercase = "uppercase"
+	// CharacterClassDigit requires passwords to contain a digit.
+	CharacterClassDigit = "digit"
+	// CharacterClassSymbol requires passwords to contain a character that is neither a letter nor a digit.
+	CharacterClassSymbol = "symbol"
+)
+
+const (
+	// PolicyRuleLength is the rule violated by passwords that are too short.
+	PolicyRuleLength = "length"
+	// PolicyRuleHistory is the rule violated by passwords that have been used before.
+	PolicyRuleHistory = "history"
+	// PolicyRulePwned is the rule violated by passwords that appeared in a data breach.
+	PolicyRulePwned = "pwned"
+)
+
+// PasswordPolicy defines the requirements passwords of accounts need to fulfil.
+type PasswordPolicy struct {
+	// MinLength is the minimum number of characters of a password.
+	MinLength int `mapstructure:"min_length"`
+	// CharacterClasses are the classes passwords need to contain a character of: lowercase, uppercase, digit and symbol.
+	CharacterClasses []string `mapstructure:"character_classes"`
+	// History is the number of recent passwords of an account, including the current one, that can't be reused.
+	History int `mapstructure:"history"`
+
+	Pwned struct {
+		// Enabled rejects passwords that appeared in a data breach, checked using the range API of HaveIBeenPwned.
+		Enabled bool `mapstructure:"enabled"`
+		// URL is the URL of the range API; the first five characters of the SHA-1 hash of a password are appended to it.
+		URL string `mapstructure:"url"`
+		// Timeout is the number of seconds a check may take; passwords are accepted if the API can't be reached.
+		Timeout int `mapstructure:"timeout"`
+	} `mapstructure:"pwned"`
+}
+
+// PolicyViolation describes a single rule of the password policy that a password violates.
+type PolicyViolation struct {
+	// Rule is either one of the character classes or one of the other policy rules.
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyError is returned if a password violates the password policy; it lists all violated rules.
+type PolicyError struct {
+	Violations []PolicyViolation
+}
+
+func (err *PolicyError) Error() string {
+	messages := make([]string, 0, len(err.Violations))
+	for _, violation := range err.Violations {
+		messages = append(messages, violation.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+var characterClasses = map[string]struct {
+	matches     func(rune) bool
+	description string
+}{
+	CharacterClassLowercase: {unicode.IsLower, "lowercase letter"},
+	CharacterClassUppercase: {unicode.IsUpper, "uppercase letter"},
+	CharacterClassDigit:     {unicode.IsDigit, "digit"},
+	CharacterClassSymbol: {func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+	}, "symbol"},
+}
+
+// Validate checks whether the policy is valid.
+func (policy *PasswordPolicy) Validate() error {
+	for _, class := range policy.CharacterClasses {
+		if _, ok := characterClasses[class]; !ok {
+			return errors.Errorf("unknown character class %v", class)
+		}
+	}
+	if policy.Pwned.Enabled && policy.Pwned.URL == "" {
+		return errors.Errorf("no URL of the pwned passwords API configured")
+	}
+	return nil
+}
+
+// Requirements returns a human-readable description of all requirements passwords need to fulfil.
+func (policy *PasswordPolicy) Requirements() []string {
+	requirements := []string{fmt.Sprintf("Must be at least %v characters long", policy.MinLength)}
+	for _, class := range policy.CharacterClasses {
+		if cc, ok := characterClasses[class]; ok {
+			requirements = append(requirements, fmt.Sprintf("Must contain at least 1 %v", cc.description))
+		}
+	}
+	if policy.History > 0 {
+		requirements = append(requirements, fmt.Sprintf("Must not be one of your last %v passwords", policy.History))
+	}
+	if policy.Pwned.Enabled {
+		requirements = append(requirements, "Must not have appeared in a known data breach")
+	}
+	return requirements
+}
+
+// Verify checks whether the given password fulfils the policy; the password is also compared against the given hashes of previous passwords.
+// If the password violates the policy, a PolicyError listing all violated rules is returned.
+func (policy *PasswordPolicy) Verify(pwd string, previous []string) error {
+	var violations []PolicyViolation
+
+	if len([]rune(pwd)) < policy.MinLength {
+		violations = append(violations, PolicyViolation{
+			Rule:    PolicyRuleLength,
+			Message: fmt.Sprintf("the password must be at least %v characters long", policy.MinLength),
+		})
+	}
+
+	for _, class := range policy.CharacterClasses {
+		if cc, ok := characterClasses[class]; ok && strings.IndexFunc(pwd, cc.matches) == -1 {
+			violations = append(violations, PolicyViolation{
+				Rule:    class,
+				Message: fmt.Sprintf("the password must contain at least one %v", cc.description),
+			})
+		}
+	}
+
+	for _, hash := range previous {
+		if hash != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(pwd)) == nil {
+			violations = append(violations, PolicyViolation{
+				Rule:    PolicyRuleHistory,
+				Message: "the password has been used before",
+			})
+			break
+		}
+	}
+
+	// Only query the breached passwords if the password is acceptable otherwise
+	if len(violations) == 0 && policy.Pwned.Enabled {
+		if pwned, _ := isPwnedPassword(pwd, policy.Pwned.URL, policy.Pwned.Timeout); pwned {
+			violations = append(violations, PolicyViolation{
+				Rule:    PolicyRulePwned,
+				Message: "the password has appeared in a data breach and must not be used",
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// DefaultPasswordPolicy returns the policy used if none has been configured.
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{
+		MinLength:        8,
+		CharacterClasses: []string{CharacterClassLowercase, CharacterClassUppercase, CharacterClassDigit},
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package credentials
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func isViolation(err error, rule string) bool {
+	policyErr, ok := err.(*PolicyError)
+	if !ok {
+		return false
+	}
+	for _, violation := range policyErr.Violations {
+		if violation.Rule == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// newPwnedServer serves the range API for the given breached passwords; the padding entries have a count of 0.
+func newPwnedServer(t *testing.T, breached ...string) *httptest.Server {
+	suffixes := make(map[string][]string)
+	for _, pwd := range breached {
+		hash := sha1.Sum([]byte(pwd))
+		digest := strings.ToUpper(hex.EncodeToString(hash[:]))
+		suffixes[digest[:5]] = append(suffixes[digest[:5]], digest[5:])
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Add-Padding") != "true" {
+			t.Errorf("expected padding to be requested")
+		}
+		prefix := strings.TrimPrefix(r.URL.Path, "/range/")
+		if len(prefix) != 5 {
+			t.Errorf("expected only the hash prefix to be sent, got %v", prefix)
+		}
+		for _, suffix := range suffixes[prefix] {
+			fmt.Fprintf(w, "%v:42\r\n", suffix)
+		}
+		fmt.Fprintf(w, "%v:0\r\n", strings.Repeat("0", 35))
+	}))
+}
+
+func TestPolicyVerify(t *testing.T) {
+	policy := &PasswordPolicy{
+		MinLength:        8,
+		CharacterClasses: []string{CharacterClassLowercase, CharacterClassUppercase, CharacterClassDigit, CharacterClassSymbol},
+	}
+
+	if err := policy.Verify("Sécret-123", nil); err != nil {
+		t.Errorf("expected the password to be accepted, got %v", err)
+	}
+
+	err := policy.Verify("abc", nil)
+	for _, rule := range []string{PolicyRuleLength, CharacterClassUppercase, CharacterClassDigit, CharacterClassSymbol} {
+		if !isViolation(err, rule) {
+			t.Errorf("expected rule %v to be violated, got %v", rule, err)
+		}
+	}
+	if isViolation(err, CharacterClassLowercase) {
+		t.Errorf("expected the lowercase rule not to be violated, got %v", err)
+	}
+	if len(strings.Split(err.Error(), "; ")) != 4 {
+		t.Errorf("expected the error to list all violations, got %v", err)
+	}
+
+	// Spaces don't count as symbols
+	if err := policy.Verify("Secret 123", nil); !isViolation(err, CharacterClassSymbol) {
+		t.Errorf("expected the symbol rule to be violated, got %v", err)
+	}
+}
+
+func TestPolicyPwned(t *testing.T) {
+	server := newPwnedServer(t, "Password123")
+	defer server.Close()
+
+	policy := &PasswordPolicy{MinLength: 8}
+	policy.Pwned.Enabled = true
+	policy.Pwned.URL = server.URL + "/range/"
+	policy.Pwned.Timeout = 5
+
+	if err := policy.Verify("Password123", nil); !isViolation(err, PolicyRulePwned) {
+		t.Errorf("expected a breached password to be rejected, got %v", err)
+	}
+	if err := policy.Verify("Unbreached-Password", nil); err != nil {
+		t.Errorf("expected the password to be accepted, got %v", err)
+	}
+
+	// Passwords are accepted if the API fails
+	server.Close()
+	if err := policy.Verify("Password123", nil); err != nil {
+		t.Errorf("expected the password to be accepted if the API is unavailable, got %v", err)
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	policy := DefaultPasswordPolicy()
+	if err := policy.Validate(); err != nil {
+		t.Errorf("expected the default policy to be valid, got %v", err)
+	}
+	if reqs := policy.Requirements(); len(reqs) != 4 {
+		t.Errorf("expected 4 requirements, got %v", reqs)
+	}
+
+	policy.CharacterClasses = append(policy.CharacterClasses, "emoji")
+	if err := policy.Validate(); err == nil {
+		t.Error("expected an unknown character class to be rejected")
+	}
+
+	policy = DefaultPasswordPolicy()
+	policy.Pwned.Enabled = true
+	if err := policy.Validate(); err == nil {
+		t.Error("expected the pwned check to require a URL")
+	}
+}
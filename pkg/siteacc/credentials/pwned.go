@@ -0,0 +1,66 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package credentials
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// isPwnedPassword checks whether the password appeared in a data breach using the k-anonymity range API of HaveIBeenPwned.
+// Only the first five characters of the SHA-1 hash of the password are sent; the remainder is compared against the returned suffixes locally.
+func isPwnedPassword(pwd string, apiURL string, timeout int) (bool, error) {
+	hash := sha1.Sum([]byte(pwd))
+	digest := strings.ToUpper(hex.EncodeToString(hash[:]))
+	prefix, suffix := digest[:5], digest[5:]
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(apiURL, "/")+"/"+prefix, nil)
+	if err != nil {
+		return false, errors.Wrap(err, "unable to create the range request")
+	}
+	// Padding hides the number of suffixes sharing the prefix from observers of the response size
+	req.Header.Set("Add-Padding", "true")
+
+	client := &http.Client{Timeout: time.Duration(timeout) * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, errors.Wrap(err, "unable to query the pwned passwords API")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, errors.Errorf("the pwned passwords API responded with %v", resp.Status)
+	}
+
+	// Every line has the form SUFFIX:COUNT; padding entries have a count of 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.SplitN(strings.TrimSpace(scanner.Text()), ":", 2)
+		if len(fields) == 2 && strings.EqualFold(fields[0], suffix) && strings.TrimSpace(fields[1]) != "0" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,139 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package credentials
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/siteacc/credentials/crypto"
+	"github.com/pkg/errors"
+)
+
+// TOTP holds the settings of the time-based one-time passwords (RFC 6238) used as a second authentication factor.
+type TOTP struct {
+	// Secret is the shared secret, encrypted using the credentials passphrase.
+	Secret  string `json:"secret"`
+	Enabled bool   `json:"enabled"`
+
+	// LastStep is the time step of the last accepted code, preventing codes from being used twice.
+	LastStep int64 `json:"lastStep,omitempty"`
+}
+
+const (
+	totpSecretLength = 20
+	totpDigits       = 6
+	totpPeriod       = 30
+	// totpSkew is the number of time steps a code may deviate from the current one, compensating for clock drifts.
+	totpSkew = 1
+)
+
+// GenerateTOTPSecret generates a new random secret, encoded as base32 as expected by authenticator apps.
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, totpSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", errors.Wrap(err, "unable to generate secret")
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret), nil
+}
+
+// TOTPURI returns the provisioning URI of the given secret, usually presented to authenticator apps as a QR code.
+func TOTPURI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprintf("%v", totpDigits))
+	params.Set("period", fmt.Sprintf("%v", totpPeriod))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// Set encrypts and stores a new secret; the second factor stays disabled until it is enabled explicitly.
+func (totp *TOTP) Set(secret string, passphrase string) error {
+	s, err := crypto.EncodeString(secret, passphrase)
+	if err != nil {
+		return errors.Wrap(err, "unable to encode secret")
+	}
+	totp.Secret = s
+	totp.Enabled = false
+	totp.LastStep = 0
+	return nil
+}
+
+// Verify checks the given code against the stored secret at the given time. Every code is accepted only once.
+func (totp *TOTP) Verify(code string, passphrase string, t time.Time) (bool, error) {
+	if totp.Secret == "" {
+		return false, errors.Errorf("no secret set")
+	}
+	secret, err := crypto.DecodeString(totp.Secret, passphrase)
+	if err != nil {
+		return false, errors.Wrap(err, "unable to decode secret")
+	}
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return false, errors.Wrap(err, "invalid secret")
+	}
+
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	step := t.Unix() / totpPeriod
+	for s := step - totpSkew; s <= step+totpSkew; s++ {
+		if s <= totp.LastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(generateTOTPCode(key, s)), []byte(code)) == 1 {
+			totp.LastStep = s
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// IsSet checks whether a secret has been stored.
+func (totp *TOTP) IsSet() bool {
+	return totp.Secret != ""
+}
+
+// Clear resets the second factor, disabling it.
+func (totp *TOTP) Clear() {
+	totp.Secret = ""
+	totp.Enabled = false
+	totp.LastStep = 0
+}
+
+func generateTOTPCode(key []byte, step int64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	_, _ = mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation as defined by RFC 4226
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
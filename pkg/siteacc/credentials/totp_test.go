@@ -0,0 +1,106 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package credentials
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// The secret of the test vectors of RFC 6238, base32-encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateTOTPCode(t *testing.T) {
+	// The SHA1 test vectors of RFC 6238, truncated to six digits
+	tests := []struct {
+		time     int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	key := []byte("12345678901234567890")
+	for _, tt := range tests {
+		if code := generateTOTPCode(key, tt.time/totpPeriod); code != tt.expected {
+			t.Errorf("time %d: expected %s, got %s", tt.time, tt.expected, code)
+		}
+	}
+}
+
+func TestTOTPVerify(t *testing.T) {
+	totp := &TOTP{}
+	if err := totp.Set(rfcSecret, "passphrase"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !totp.IsSet() || totp.Enabled || totp.Secret == rfcSecret {
+		t.Fatalf("expected an encrypted, disabled secret, got %+v", totp)
+	}
+
+	now := time.Unix(1111111109, 0)
+	if ok, err := totp.Verify("081 804", "passphrase", now); err != nil || !ok {
+		t.Fatalf("expected the code to be accepted, got %v (%v)", ok, err)
+	}
+	if ok, _ := totp.Verify("081804", "passphrase", now); ok {
+		t.Error("expected a code to be accepted only once")
+	}
+
+	// The code of the previous step is still accepted to compensate for clock drifts, but not older ones
+	later := time.Unix(1111111111, 0).Add(totpPeriod * time.Second)
+	if ok, _ := totp.Verify("050471", "passphrase", later); !ok {
+		t.Error("expected the code of the previous step to be accepted")
+	}
+	if ok, _ := totp.Verify("005924", "passphrase", later); ok {
+		t.Error("expected a code of a distant step to be rejected")
+	}
+
+	if _, err := totp.Verify("081804", "other", now); err == nil {
+		t.Error("expected a wrong passphrase to fail")
+	}
+
+	totp.Clear()
+	if _, err := totp.Verify("081804", "passphrase", now); err == nil {
+		t.Error("expected verifying without a secret to fail")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("expected a secret of 32 base32 characters, got %q", secret)
+	}
+
+	u, err := url.Parse(TOTPURI("Science Mesh", "john@example.org", secret))
+	if err != nil {
+		t.Fatalf("invalid URI: %v", err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Science Mesh:john@example.org" {
+		t.Errorf("unexpected URI %v", u)
+	}
+	if q := u.Query(); q.Get("secret") != secret || q.Get("issuer") != "Science Mesh" || q.Get("digits") != "6" || q.Get("period") != "30" {
+		t.Errorf("unexpected parameters %v", q)
+	}
+}
@@ -19,6 +19,7 @@
 package data
 
 import (
+	"regexp"
 	"strings"
 	"time"
 
@@ -38,13 +39,31 @@ type Account struct {
 	Role        string `json:"role"`
 	PhoneNumber string `json:"phoneNumber"`
 
-	Password credentials.Password `json:"password"`
+	Password  credentials.Password `json:"password"`
+	TwoFactor credentials.TOTP     `json:"twoFactor"`
+
+	Identity *AccountIdentity `json:"identity,omitempty"`
+	// ExternalID is the ID of the contact in the external source the account has been imported from.
+	ExternalID string `json:"externalId,omitempty"`
 
 	DateCreated  time.Time `json:"dateCreated"`
 	DateModified time.Time `json:"dateModified"`
 
 	Data     AccountData     `json:"data"`
 	Settings AccountSettings `json:"settings"`
+
+	Notifications AccountNotifications `json:"notifications"`
+
+	Deletion *AccountDeletion `json:"deletion,omitempty"`
+	// DeletionRequest is set while a requested deletion awaits its confirmation by the account owner.
+	DeletionRequest *AccountDeletionRequest `json:"deletionRequest,omitempty"`
+
+	// Verification is set while the email address of the account hasn't been verified.
+	Verification *AccountVerification `json:"verification,omitempty"`
+	// PendingApproval is set while the account awaits its approval by an administrator.
+	PendingApproval bool `json:"pendingApproval,omitempty"`
+	// Suspended is set if the account has been disabled by an administrator.
+	Suspended bool `json:"suspended,omitempty"`
 }
 
 // AccountData holds additional data for a sites account.
@@ -56,13 +75,48 @@ type AccountData struct {
 // AccountSettings holds additional settings for a sites account.
 type AccountSettings struct {
 	ReceiveAlerts bool `json:"receiveAlerts"`
+	// Language is the preferred language of emails sent to the account; the default language is used if empty.
+	Language string `json:"language,omitempty"`
 }
 
+// AccountIdentity links an account to the identity of a user at an OpenID Connect provider.
+type AccountIdentity struct {
+	Issuer  string `json:"issuer"`
+	Subject string `json:"subject"`
+}
+
+// AccountDeletion holds the state of a deletion requested by the account owner.
+type AccountDeletion struct {
+	DateRequested time.Time `json:"dateRequested"`
+	DatePurge     time.Time `json:"datePurge"`
+
+	// Token allows the owner to export the account data and to cancel the deletion while the account is disabled.
+	Token string `json:"token,omitempty"`
+}
+
+// AccountDeletionRequest holds the state of a deletion requested by the account owner which hasn't been confirmed yet.
+type AccountDeletionRequest struct {
+	DateSent time.Time `json:"dateSent"`
+
+	// Token is sent to the email address of the account to confirm the deletion.
+	Token string `json:"token,omitempty"`
+}
+
+// AccountVerification holds the state of the verification of the email address of an account.
+type AccountVerification struct {
+	DateSent time.Time `json:"dateSent"`
+
+	// Token is sent to the email address of the account to verify it.
+	Token string `json:"token,omitempty"`
+}
+
+var languageRegex = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
 // Accounts holds an array of sites accounts.
 type Accounts = []*Account
 
-// Update copies the data of the given account to this account.
-func (acc *Account) Update(other *Account, setPassword bool, copyData bool) error {
+// Update copies the data of the given account to this account; a new password needs to fulfil the given policy.
+func (acc *Account) Update(other *Account, setPassword bool, copyData bool, policy *credentials.PasswordPolicy) error {
 	if err := other.verify(false, false); err != nil {
 		return errors.Wrap(err, "unable to update account data")
 	}
@@ -76,7 +130,7 @@ func (acc *Account) Update(other *Account, setPassword bool, copyData bool) erro
 
 	if setPassword && other.Password.Value != "" {
 		// If a password was provided, use that as the new one
-		if err := acc.UpdatePassword(other.Password.Value); err != nil {
+		if err := acc.UpdatePassword(other.Password.Value, policy); err != nil {
 			return errors.Wrap(err, "unable to update account data")
 		}
 	}
@@ -88,33 +142,119 @@ func (acc *Account) Update(other *Account, setPassword bool, copyData bool) erro
 	return nil
 }
 
+// UpdateContact copies the contact data (email address, names and phone number) of the given account to this account.
+func (acc *Account) UpdateContact(other *Account) error {
+	contact := *acc
+	contact.Email = other.Email
+	contact.FirstName = other.FirstName
+	contact.LastName = other.LastName
+	contact.PhoneNumber = other.PhoneNumber
+	contact.Cleanup()
+
+	if err := contact.verify(false, false); err != nil {
+		return errors.Wrap(err, "unable to update contact data")
+	}
+
+	acc.Email = contact.Email
+	acc.FirstName = contact.FirstName
+	acc.LastName = contact.LastName
+	acc.PhoneNumber = contact.PhoneNumber
+	acc.ExternalID = other.ExternalID
+
+	return nil
+}
+
 // Configure copies the settings of the given account to this account.
 func (acc *Account) Configure(other *Account) error {
+	language := NormalizeLanguage(other.Settings.Language)
+	if other.Settings.Language != "" && language == "" {
+		return errors.Errorf("invalid language: %v", other.Settings.Language)
+	}
+
 	// Simply copy the stored settings
 	acc.Settings = other.Settings
+	acc.Settings.Language = language
+
+	return nil
+}
 
+// ConfigureNotifications copies the notification preferences of the given account to this account.
+func (acc *Account) ConfigureNotifications(other *Account) error {
+	acc.Notifications.Preferences = other.Notifications.Clone().Preferences
 	return nil
 }
 
-// UpdatePassword assigns a new password to the account, hashing it first.
-func (acc *Account) UpdatePassword(pwd string) error {
-	if err := acc.Password.Set(pwd); err != nil {
+// UpdatePassword assigns a new password to the account, hashing it first; if a policy is given, the password needs to fulfil it.
+func (acc *Account) UpdatePassword(pwd string, policy *credentials.PasswordPolicy) error {
+	if err := acc.Password.Set(pwd, policy); err != nil {
 		return errors.Wrap(err, "unable to update the user password")
 	}
 	return nil
 }
 
-// Clone creates a copy of the account; if erasePassword is set to true, the password will be cleared in the cloned object.
+// Clone creates a copy of the account; if erasePassword is set to true, the password (as well as the two-factor secret and the deletion, deletion request and verification tokens) will be cleared in the cloned object.
 func (acc *Account) Clone(erasePassword bool) *Account {
 	clone := *acc
+	clone.Notifications = acc.Notifications.Clone()
+
+	if acc.Deletion != nil {
+		deletion := *acc.Deletion
+		clone.Deletion = &deletion
+	}
+
+	if acc.Identity != nil {
+		identity := *acc.Identity
+		clone.Identity = &identity
+	}
+
+	if acc.DeletionRequest != nil {
+		request := *acc.DeletionRequest
+		clone.DeletionRequest = &request
+	}
+
+	if acc.Verification != nil {
+		verification := *acc.Verification
+		clone.Verification = &verification
+	}
 
 	if erasePassword {
 		clone.Password.Clear()
+		clone.TwoFactor.Secret = ""
+
+		if clone.Deletion != nil {
+			clone.Deletion.Token = ""
+		}
+		if clone.DeletionRequest != nil {
+			clone.DeletionRequest.Token = ""
+		}
+		if clone.Verification != nil {
+			clone.Verification.Token = ""
+		}
 	}
 
 	return &clone
 }
 
+// IsDisabled tells whether the account was disabled, either by an administrator or because its owner requested its deletion.
+func (acc *Account) IsDisabled() bool {
+	return acc.Deletion != nil || acc.Suspended
+}
+
+// IsVerified tells whether the email address of the account has been verified.
+func (acc *Account) IsVerified() bool {
+	return acc.Verification == nil
+}
+
+// IsDue tells whether the cooling-off period of a requested deletion is over.
+func (acc *Account) IsDue(now time.Time) bool {
+	return acc.Deletion != nil && now.After(acc.Deletion.DatePurge)
+}
+
+// RequiresTwoFactor tells whether the given operator of the account requires it to use two-factor authentication; this only applies to accounts with Sites access.
+func (acc *Account) RequiresTwoFactor(op *Operator) bool {
+	return acc.Data.SitesAccess && op != nil && op.Settings.RequireTwoFactor
+}
+
 // CheckScopeAccess checks whether the user can access the specified scope.
 func (acc *Account) CheckScopeAccess(scope string) bool {
 	hasAccess := false
@@ -144,6 +284,15 @@ func (acc *Account) Cleanup() {
 	acc.PhoneNumber = strings.TrimSpace(acc.PhoneNumber)
 }
 
+// NormalizeLanguage converts the given language tag (e.g., de_CH) into its normalized form (de-ch); an empty string is returned for invalid tags.
+func NormalizeLanguage(language string) string {
+	language = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(language), "_", "-"))
+	if !languageRegex.MatchString(language) {
+		return ""
+	}
+	return language
+}
+
 func (acc *Account) verify(isNewAccount, verifyPassword bool) error {
 	if acc.Email == "" {
 		return errors.Errorf("no email address provided")
@@ -186,8 +335,8 @@ func (acc *Account) verify(isNewAccount, verifyPassword bool) error {
 	return nil
 }
 
-// NewAccount creates a new sites account.
-func NewAccount(email string, title, firstName, lastName string, operator, role string, phoneNumber string, password string) (*Account, error) {
+// NewAccount creates a new sites account; if a policy is given, the password needs to fulfil it.
+func NewAccount(email string, title, firstName, lastName string, operator, role string, phoneNumber string, password string, policy *credentials.PasswordPolicy) (*Account, error) {
 	t := time.Now()
 
 	acc := &Account{
@@ -210,7 +359,7 @@ func NewAccount(email string, title, firstName, lastName string, operator, role
 	}
 
 	// Set the user password, which also makes sure that the given password is strong enough
-	if err := acc.UpdatePassword(password); err != nil {
+	if err := acc.UpdatePassword(password, policy); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,69 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAccountIsDue(t *testing.T) {
+	now := time.Now()
+
+	acc := &Account{}
+	if acc.IsDisabled() || acc.IsDue(now) {
+		t.Error("expected an account without a deletion to be neither disabled nor due")
+	}
+
+	acc.Deletion = &AccountDeletion{DateRequested: now, DatePurge: now.Add(time.Hour)}
+	if !acc.IsDisabled() {
+		t.Error("expected an account scheduled for deletion to be disabled")
+	}
+	if acc.IsDue(now) {
+		t.Error("expected the account not to be due during the cooling-off period")
+	}
+	if !acc.IsDue(now.Add(2 * time.Hour)) {
+		t.Error("expected the account to be due after the cooling-off period")
+	}
+}
+
+func TestAccountCloneErasesDeletionTokens(t *testing.T) {
+	acc := &Account{
+		Email:           "john@example.org",
+		Deletion:        &AccountDeletion{Token: "deletion"},
+		DeletionRequest: &AccountDeletionRequest{Token: "request"},
+	}
+
+	clone := acc.Clone(true)
+	if clone.Deletion.Token != "" || clone.DeletionRequest.Token != "" {
+		t.Errorf("expected the tokens to be erased, got %+v and %+v", clone.Deletion, clone.DeletionRequest)
+	}
+	if acc.Deletion.Token != "deletion" || acc.DeletionRequest.Token != "request" {
+		t.Error("expected the tokens of the original account to be kept")
+	}
+
+	clone = acc.Clone(false)
+	if clone.Deletion.Token != "deletion" {
+		t.Errorf("expected the token to be kept, got %q", clone.Deletion.Token)
+	}
+	clone.Deletion.Token = "changed"
+	if acc.Deletion.Token != "deletion" {
+		t.Error("expected the clone not to share the deletion with the original account")
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2018-2022 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package data
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// APIKeyScopeMonitoring grants read-only access to the data of the sites of an operator.
+	APIKeyScopeMonitoring = "monitoring"
+	// APIKeyScopeSiteConfig additionally grants access to the configuration of the sites of an operator.
+	APIKeyScopeSiteConfig = "site-config"
+)
+
+// OperatorAPIKey holds an API key issued by an operator, letting other services access the sites of the operator without user credentials.
+type OperatorAPIKey struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+
+	// Hash is the SHA256 hash of the key; the key itself is only shown once when it is issued or rotated.
+	Hash string `json:"hash"`
+
+	CreatedBy   string    `json:"createdBy"`
+	DateCreated time.Time `json:"dateCreated"`
+	DateRotated time.Time `json:"dateRotated"`
+	// DateExpires is the time after which the key can no longer be used; keys with no expiry date never expire.
+	DateExpires time.Time `json:"dateExpires"`
+
+	LastUsed time.Time `json:"lastUsed"`
+	UseCount int64     `json:"useCount"`
+}
+
+// IsExpired checks whether the key has expired.
+func (apiKey *OperatorAPIKey) IsExpired() bool {
+	return !apiKey.DateExpires.IsZero() && time.Now().After(apiKey.DateExpires)
+}
+
+// Grants checks whether the scope of the key includes the given scope.
+func (apiKey *OperatorAPIKey) Grants(scope string) bool {
+	switch apiKey.Scope {
+	case APIKeyScopeSiteConfig:
+		return scope == APIKeyScopeSiteConfig || scope == APIKeyScopeMonitoring
+	case APIKeyScopeMonitoring:
+		return scope == APIKeyScopeMonitoring
+	}
+	return false
+}
+
+// Clone creates a copy of the key; if eraseCredentials is set to true, the key hash will be cleared in the cloned object.
+func (apiKey *OperatorAPIKey) Clone(eraseCredentials bool) *OperatorAPIKey {
+	clone := *apiKey
+	if eraseCredentials {
+		clone.Hash = ""
+	}
+	return &clone
+}
+
+// IsValidAPIKeyScope checks whether the given scope is a known API key scope.
+func IsValidAPIKeyScope(scope string) bool {
+	return scope == APIKeyScopeMonitoring || scope == APIKeyScopeSiteConfig
+}
+
+// SplitAPIKey splits an API key into the ID of the key and its secret.
+func SplitAPIKey(apiKey string) (string, string, error) {
+	parts := strings.SplitN(apiKey, ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", errors.Errorf("malformed API key")
+	}
+	return parts[0], parts[1], nil
+}
+
+// IssueAPIKey generates a new API key with the given name and scope; the generated key is returned along with its information.
+func (op *Operator) IssueAPIKey(name string, scope string, expires time.Time, createdBy string) (*OperatorAPIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", errors.Errorf("no key name provided")
+	}
+	if !IsValidAPIKeyScope(scope) {
+		return nil, "", errors.Errorf("unknown key scope %v", scope)
+	}
+	if !expires.IsZero() && expires.Before(time.Now()) {
+		return nil, "", errors.Errorf("the expiry date lies in the past")
+	}
+
+	id, err := generateAPIKeyPart(8, hex.EncodeToString)
+	if err != nil {
+		return nil, "", err
+	}
+	apiKey := &OperatorAPIKey{
+		ID:          id,
+		Name:        name,
+		Scope:       scope,
+		CreatedBy:   createdBy,
+		DateCreated: time.Now(),
+		DateExpires: expires,
+	}
+	key, err := apiKey.generate()
+	if err != nil {
+		return nil, "", err
+	}
+
+	op.APIKeys = append(op.APIKeys, apiKey)
+	return apiKey, key, nil
+}
+
+// RotateAPIKey replaces the specified key by a new one, keeping all of its other information; the new key is returned.
+func (op *Operator) RotateAPIKey(id string) (*OperatorAPIKey, string, error) {
+	apiKey := op.FindAPIKey(id)
+	if apiKey == nil {
+		return nil, "", errors.Errorf("no API key with ID %v exists", id)
+	}
+
+	key, err := apiKey.generate()
+	if err != nil {
+		return nil, "", err
+	}
+	apiKey.DateRotated = time.Now()
+	return apiKey, key, nil
+}
+
+// RevokeAPIKey removes the specified key.
+func (op *Operator) RevokeAPIKey(id string) error {
+	for i, apiKey := range op.APIKeys {
+		if apiKey.ID == id {
+			op.APIKeys = append(op.APIKeys[:i], op.APIKeys[i+1:]...)
+			return nil
+		}
+	}
+	return errors.Errorf("no API key with ID %v exists", id)
+}
+
+// FindAPIKey returns the API key specified by the ID if one exists.
+func (op *Operator) FindAPIKey(id string) *OperatorAPIKey {
+	for _, apiKey := range op.APIKeys {
+		if apiKey.ID == id {
+			return apiKey
+		}
+	}
+	return nil
+}
+
+// VerifyAPIKey checks whether the given key has been issued by the operator and is still valid; if so, its usage information is updated.
+func (op *Operator) VerifyAPIKey(key string) (*OperatorAPIKey, error) {
+	id, _, err := SplitAPIKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey := op.FindAPIKey(id)
+	if apiKey == nil || subtle.ConstantTimeCompare([]byte(hashAPIKey(key)), []byte(apiKey.Hash)) != 1 {
+		return nil, errors.Errorf("the API key is invalid")
+	}
+	if apiKey.IsExpired() {
+		return nil, errors.Errorf("the API key has expired")
+	}
+
+	apiKey.LastUsed = time.Now()
+	apiKey.UseCount++
+	return apiKey, nil
+}
+
+func (apiKey *OperatorAPIKey) generate() (string, error) {
+	secret, err := generateAPIKeyPart(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", err
+	}
+	key := apiKey.ID + "." + secret
+	apiKey.Hash = hashAPIKey(key)
+	return key, nil
+}
+
+func generateAPIKeyPart(size int, encode func([]byte) string) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.Wrap(err, "unable to generate the API key")
+	}
+	return encode(buf), nil
+}
+
+func hashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,158 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package data
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIssueAndVerifyAPIKey(t *testing.T) {
+	op := &Operator{ID: "op"}
+
+	apiKey, key, err := op.IssueAPIKey(" monitoring ", APIKeyScopeMonitoring, time.Time{}, "john@example.org")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if apiKey.Name != "monitoring" || apiKey.CreatedBy != "john@example.org" || len(op.APIKeys) != 1 {
+		t.Errorf("unexpected key information %+v", apiKey)
+	}
+	if !strings.HasPrefix(key, apiKey.ID+".") || strings.Contains(apiKey.Hash, key) {
+		t.Errorf("unexpected key %v for %+v", key, apiKey)
+	}
+
+	verified, err := op.VerifyAPIKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if verified != apiKey || apiKey.UseCount != 1 || apiKey.LastUsed.IsZero() {
+		t.Errorf("expected the usage to be recorded, got %+v", apiKey)
+	}
+
+	for _, invalid := range []string{"", "nodot", apiKey.ID + ".wrong", "unknown." + strings.SplitN(key, ".", 2)[1]} {
+		if _, err := op.VerifyAPIKey(invalid); err == nil {
+			t.Errorf("expected key %q to be rejected", invalid)
+		}
+	}
+	if apiKey.UseCount != 1 {
+		t.Errorf("expected rejected keys not to be counted, got %d", apiKey.UseCount)
+	}
+}
+
+func TestIssueAPIKeyInvalid(t *testing.T) {
+	op := &Operator{ID: "op"}
+
+	if _, _, err := op.IssueAPIKey(" ", APIKeyScopeMonitoring, time.Time{}, ""); err == nil {
+		t.Error("expected an error for an empty name")
+	}
+	if _, _, err := op.IssueAPIKey("key", "admin", time.Time{}, ""); err == nil {
+		t.Error("expected an error for an unknown scope")
+	}
+	if _, _, err := op.IssueAPIKey("key", APIKeyScopeMonitoring, time.Now().Add(-time.Hour), ""); err == nil {
+		t.Error("expected an error for an expiry date in the past")
+	}
+	if len(op.APIKeys) != 0 {
+		t.Errorf("expected no keys to be issued, got %d", len(op.APIKeys))
+	}
+}
+
+func TestRotateAndRevokeAPIKey(t *testing.T) {
+	op := &Operator{ID: "op"}
+	apiKey, oldKey, _ := op.IssueAPIKey("key", APIKeyScopeSiteConfig, time.Time{}, "")
+
+	_, newKey, err := op.RotateAPIKey(apiKey.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if apiKey.DateRotated.IsZero() {
+		t.Error("expected the rotation date to be set")
+	}
+	if _, err := op.VerifyAPIKey(oldKey); err == nil {
+		t.Error("expected the old key to be rejected")
+	}
+	if _, err := op.VerifyAPIKey(newKey); err != nil {
+		t.Errorf("expected the new key to be accepted, got %v", err)
+	}
+	if _, _, err := op.RotateAPIKey("unknown"); err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+
+	if err := op.RevokeAPIKey(apiKey.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := op.VerifyAPIKey(newKey); err == nil {
+		t.Error("expected a revoked key to be rejected")
+	}
+	if err := op.RevokeAPIKey(apiKey.ID); err == nil {
+		t.Error("expected an error when revoking a key twice")
+	}
+}
+
+func TestExpiredAPIKey(t *testing.T) {
+	op := &Operator{ID: "op"}
+	apiKey, key, _ := op.IssueAPIKey("key", APIKeyScopeMonitoring, time.Now().Add(time.Hour), "")
+
+	if apiKey.IsExpired() {
+		t.Error("expected the key not to have expired yet")
+	}
+	apiKey.DateExpires = time.Now().Add(-time.Minute)
+	if !apiKey.IsExpired() {
+		t.Error("expected the key to have expired")
+	}
+	if _, err := op.VerifyAPIKey(key); err == nil {
+		t.Error("expected an expired key to be rejected")
+	}
+}
+
+func TestAPIKeyGrants(t *testing.T) {
+	tests := []struct {
+		keyScope string
+		scope    string
+		want     bool
+	}{
+		{APIKeyScopeMonitoring, APIKeyScopeMonitoring, true},
+		{APIKeyScopeMonitoring, APIKeyScopeSiteConfig, false},
+		{APIKeyScopeSiteConfig, APIKeyScopeMonitoring, true},
+		{APIKeyScopeSiteConfig, APIKeyScopeSiteConfig, true},
+		{"unknown", APIKeyScopeMonitoring, false},
+	}
+
+	for _, tt := range tests {
+		apiKey := &OperatorAPIKey{Scope: tt.keyScope}
+		if got := apiKey.Grants(tt.scope); got != tt.want {
+			t.Errorf("%v.Grants(%v) = %v, want %v", tt.keyScope, tt.scope, got, tt.want)
+		}
+	}
+}
+
+func TestOperatorCloneErasesAPIKeyHashes(t *testing.T) {
+	op := &Operator{ID: "op"}
+	_, _, _ = op.IssueAPIKey("key", APIKeyScopeMonitoring, time.Time{}, "")
+
+	if clone := op.Clone(true); len(clone.APIKeys) != 1 || clone.APIKeys[0].Hash != "" {
+		t.Errorf("expected the key hash to be erased, got %+v", clone.APIKeys)
+	}
+	if clone := op.Clone(false); clone.APIKeys[0].Hash != op.APIKeys[0].Hash {
+		t.Error("expected the key hash to be kept")
+	}
+	if op.APIKeys[0].Hash == "" {
+		t.Error("expected the original key hash to be kept")
+	}
+}
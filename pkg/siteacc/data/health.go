@@ -0,0 +1,101 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package data
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/mentix/key"
+	"github.com/cs3org/reva/pkg/mentix/meshdata"
+)
+
+const (
+	// HealthStatusOK is the status of sites that are reachable and accept the login of their test user.
+	HealthStatusOK = "ok"
+	// HealthStatusUnreachable is the status of sites whose Reva endpoint can't be reached.
+	HealthStatusUnreachable = "unreachable"
+	// HealthStatusLoginFailed is the status of sites that reject the login of their test user.
+	HealthStatusLoginFailed = "login-failed"
+	// HealthStatusUnknown is the status of sites that couldn't be checked, e.g. because no Reva endpoint is known.
+	HealthStatusUnknown = "unknown"
+)
+
+// SiteHealth holds the result of the latest health check of a site.
+type SiteHealth struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+
+	// Endpoint is the URL of the Reva endpoint that has been checked.
+	Endpoint string `json:"endpoint,omitempty"`
+	// RTT is the round-trip time of the connectivity check in milliseconds.
+	RTT float64 `json:"rtt"`
+
+	LastChecked time.Time `json:"lastChecked"`
+	LastHealthy time.Time `json:"lastHealthy"`
+}
+
+// SiteEndpoints holds the endpoints of a site used by the health checks.
+type SiteEndpoints struct {
+	// Revad is the URL of the main Reva endpoint.
+	Revad string
+	// WebDAV is the URL of the WebDAV endpoint; it is empty if the site doesn't register one.
+	WebDAV string
+}
+
+// IsHealthy checks whether the site passed its latest health check.
+func (health *SiteHealth) IsHealthy() bool {
+	return health.Status == HealthStatusOK
+}
+
+// Clone creates a copy of the site health.
+func (health *SiteHealth) Clone() *SiteHealth {
+	clone := *health
+	return &clone
+}
+
+// QuerySiteEndpoints uses Mentix to query the endpoints of all sites used by the health checks; the endpoints are mapped by the site IDs.
+func QuerySiteEndpoints(mentixHost, dataEndpoint string, signing *key.SigningConfig) (map[string]*SiteEndpoints, error) {
+	meshData, err := queryMeshData(mentixHost, dataEndpoint, signing)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make(map[string]*SiteEndpoints)
+	for _, op := range meshData.Operators {
+		for _, site := range op.Sites {
+			for _, service := range site.Services {
+				if service.ServiceEndpoint == nil || service.Type == nil || !strings.EqualFold(service.Type.Name, meshdata.EndpointRevad) {
+					continue
+				}
+
+				siteEndpoints := &SiteEndpoints{Revad: service.URL}
+				for _, endpoint := range service.AdditionalEndpoints {
+					if endpoint.Type != nil && strings.EqualFold(endpoint.Type.Name, meshdata.EndpointWebdav) {
+						siteEndpoints.WebDAV = endpoint.URL
+						break
+					}
+				}
+				endpoints[site.ID] = siteEndpoints
+				break
+			}
+		}
+	}
+	return endpoints, nil
+}
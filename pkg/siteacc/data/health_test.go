@@ -0,0 +1,73 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package data
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestQuerySiteEndpoints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"Operators": [{"ID": "op", "Sites": [
+			{"ID": "site1", "Services": [
+				{"Type": {"Name": "GOCDB"}, "URL": "https://gocdb.example.org"},
+				{"Type": {"Name": "revad"}, "URL": "https://site1.example.org", "AdditionalEndpoints": [
+					{"Type": {"Name": "METRICS"}, "URL": "https://site1.example.org/metrics"},
+					{"Type": {"Name": "WEBDAV"}, "URL": "https://site1.example.org/dav"}
+				]}
+			]},
+			{"ID": "site2", "Services": [{"Type": {"Name": "REVAD"}, "URL": "https://site2.example.org"}]},
+			{"ID": "site3", "Services": [{"Type": {"Name": "GOCDB"}, "URL": "https://gocdb.example.org"}]}
+		]}]}`))
+	}))
+	defer server.Close()
+
+	endpoints, err := QuerySiteEndpoints(server.URL, "/sites", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(endpoints) != 2 {
+		t.Fatalf("expected the endpoints of the sites running Reva, got %+v", endpoints)
+	}
+	if e := endpoints["site1"]; e.Revad != "https://site1.example.org" || e.WebDAV != "https://site1.example.org/dav" {
+		t.Errorf("unexpected endpoints of site1 %+v", e)
+	}
+	if e := endpoints["site2"]; e.Revad != "https://site2.example.org" || e.WebDAV != "" {
+		t.Errorf("unexpected endpoints of site2 %+v", e)
+	}
+}
+
+func TestSiteHealthClone(t *testing.T) {
+	site := &Site{ID: "site", Health: &SiteHealth{Status: HealthStatusOK, LastChecked: time.Now()}}
+	if !site.Health.IsHealthy() {
+		t.Error("expected the site to be healthy")
+	}
+
+	clone := site.Clone(true)
+	clone.Health.Status = HealthStatusUnreachable
+	if site.Health.Status != HealthStatusOK {
+		t.Error("expected the health of the clone to be independent")
+	}
+	if clone.Health.IsHealthy() {
+		t.Error("expected an unreachable site not to be healthy")
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package data
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/mentix/key"
+	"github.com/rs/zerolog"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+const (
+	cacheResultHit   = "hit"
+	cacheResultStale = "stale"
+	cacheResultMiss  = "miss"
+)
+
+var (
+	mentixCacheLookups = stats.Int64("siteacc_mentix_cache_lookups", "The number of lookups of Mentix data in the cache", stats.UnitDimensionless)
+	mentixErrors       = stats.Int64("siteacc_mentix_errors", "The number of failed queries to Mentix", stats.UnitDimensionless)
+	cacheResultKey     = tag.MustNewKey("result")
+	registerCacheViews sync.Once
+
+	activeCache      *MentixCache
+	activeCacheMutex sync.RWMutex
+)
+
+// MentixCache caches the operators and sites queried from Mentix, so that pages are rendered without waiting for Mentix
+// and keep working while it is down. Data older than the TTL is still served while it is refreshed in the background;
+// data older than the maximum staleness is discarded.
+type MentixCache struct {
+	ttl      time.Duration
+	maxStale time.Duration
+	log      *zerolog.Logger
+
+	entries map[mentixSource]*mentixCacheEntry
+	mutex   sync.Mutex
+
+	stopChan chan struct{}
+}
+
+type mentixSource struct {
+	host     string
+	endpoint string
+}
+
+type mentixCacheEntry struct {
+	operators []OperatorInformation
+	fetched   time.Time
+	signing   *key.SigningConfig
+
+	refreshing bool
+}
+
+// EnableMentixCache makes all Mentix queries of the operators and sites go through a cache with the given TTL and maximum
+// staleness; the cached data is refreshed periodically in the background until the cache is stopped.
+func EnableMentixCache(ttl, maxStale time.Duration, log *zerolog.Logger) *MentixCache {
+	registerCacheViews.Do(func() {
+		_ = view.Register(&view.View{
+			Name:        mentixCacheLookups.Name(),
+			Description: mentixCacheLookups.Description(),
+			Measure:     mentixCacheLookups,
+			TagKeys:     []tag.Key{cacheResultKey},
+			Aggregation: view.Count(),
+		})
+		_ = view.Register(&view.View{
+			Name:        mentixErrors.Name(),
+			Description: mentixErrors.Description(),
+			Measure:     mentixErrors,
+			Aggregation: view.Count(),
+		})
+	})
+
+	cache := &MentixCache{
+		ttl:      ttl,
+		maxStale: maxStale,
+		log:      log,
+		entries:  make(map[mentixSource]*mentixCacheEntry),
+		stopChan: make(chan struct{}),
+	}
+	go cache.refreshPeriodically()
+
+	activeCacheMutex.Lock()
+	defer activeCacheMutex.Unlock()
+	if activeCache != nil {
+		activeCache.stop()
+	}
+	activeCache = cache
+	return cache
+}
+
+// Stop stops the background refresh; the Mentix queries bypass the cache afterwards.
+func (cache *MentixCache) Stop() {
+	activeCacheMutex.Lock()
+	defer activeCacheMutex.Unlock()
+	if activeCache == cache {
+		activeCache = nil
+		cache.stop()
+	}
+}
+
+func (cache *MentixCache) stop() {
+	close(cache.stopChan)
+}
+
+func activeMentixCache() *MentixCache {
+	activeCacheMutex.RLock()
+	defer activeCacheMutex.RUnlock()
+	return activeCache
+}
+
+func (cache *MentixCache) getOperators(mentixHost, dataEndpoint string, signing *key.SigningConfig) ([]OperatorInformation, error) {
+	source := mentixSource{host: mentixHost, endpoint: dataEndpoint}
+
+	cache.mutex.Lock()
+	entry, ok := cache.entries[source]
+	if ok && time.Since(entry.fetched) < cache.ttl {
+		cache.mutex.Unlock()
+		recordCacheLookup(cacheResultHit)
+		return entry.operators, nil
+	}
+	if ok && time.Since(entry.fetched) < cache.maxStale {
+		// Serve the stale data while it is being refreshed
+		if !entry.refreshing {
+			entry.refreshing = true
+			go cache.refresh(source, signing)
+		}
+		cache.mutex.Unlock()
+		recordCacheLookup(cacheResultStale)
+		return entry.operators, nil
+	}
+	cache.mutex.Unlock()
+
+	recordCacheLookup(cacheResultMiss)
+	return cache.refresh(source, signing)
+}
+
+// refresh queries the operators from Mentix and stores them; if the query fails, the cached data is kept.
+func (cache *MentixCache) refresh(source mentixSource, signing *key.SigningConfig) ([]OperatorInformation, error) {
+	operators, err := fetchAvailableOperators(source.host, source.endpoint, signing)
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry, ok := cache.entries[source]
+	if !ok {
+		entry = &mentixCacheEntry{}
+	}
+	entry.refreshing = false
+	entry.signing = signing
+
+	if err != nil {
+		stats.Record(context.Background(), mentixErrors.M(1))
+		cache.log.Warn().Err(err).Str("host", source.host).Msg("unable to refresh the operators cached from Mentix")
+		return nil, err
+	}
+
+	entry.operators = operators
+	entry.fetched = time.Now()
+	cache.entries[source] = entry
+	return operators, nil
+}
+
+func (cache *MentixCache) refreshPeriodically() {
+	ticker := time.NewTicker(cache.ttl)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cache.stopChan:
+			return
+		case <-ticker.C:
+		}
+
+		cache.mutex.Lock()
+		sources := make(map[mentixSource]*key.SigningConfig, len(cache.entries))
+		for source, entry := range cache.entries {
+			if !entry.refreshing {
+				entry.refreshing = true
+				sources[source] = entry.signing
+			}
+		}
+		cache.mutex.Unlock()
+
+		for source, signing := range sources {
+			_, _ = cache.refresh(source, signing)
+		}
+	}
+}
+
+func recordCacheLookup(result string) {
+	if ctx, err := tag.New(context.Background(), tag.Insert(cacheResultKey, result)); err == nil {
+		stats.Record(ctx, mentixCacheLookups.M(1))
+	}
+}
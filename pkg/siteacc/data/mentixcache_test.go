@@ -0,0 +1,151 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package data
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type mentixServer struct {
+	*httptest.Server
+
+	requests int32
+	failing  int32
+}
+
+func newMentixServer() *mentixServer {
+	server := &mentixServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&server.requests, 1)
+		if atomic.LoadInt32(&server.failing) != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"Operators": [{"ID": "op2", "Name": "Zeta"}, {"ID": "op1", "Name": "Alpha", "Sites": [{"ID": "site1"}]}]}`))
+	}))
+	return server
+}
+
+func (server *mentixServer) requestCount() int {
+	return int(atomic.LoadInt32(&server.requests))
+}
+
+func newTestMentixCache(ttl, maxStale time.Duration) *MentixCache {
+	log := zerolog.Nop()
+	return &MentixCache{
+		ttl:      ttl,
+		maxStale: maxStale,
+		log:      &log,
+		entries:  make(map[mentixSource]*mentixCacheEntry),
+	}
+}
+
+func (cache *MentixCache) age(d time.Duration) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	for _, entry := range cache.entries {
+		entry.fetched = entry.fetched.Add(-d)
+	}
+}
+
+func TestMentixCacheLookups(t *testing.T) {
+	server := newMentixServer()
+	defer server.Close()
+	cache := newTestMentixCache(time.Minute, time.Hour)
+
+	ops, err := cache.getOperators(server.URL, "/ops", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ops) != 2 || ops[0].ID != "op1" || ops[0].Sites[0].ID != "site1" {
+		t.Fatalf("expected the operators sorted by name, got %+v", ops)
+	}
+
+	if _, err := cache.getOperators(server.URL, "/ops", nil); err != nil || server.requestCount() != 1 {
+		t.Errorf("expected fresh data to be served from the cache, got %d requests (%v)", server.requestCount(), err)
+	}
+	if _, err := cache.getOperators(server.URL, "/other", nil); err != nil || server.requestCount() != 2 {
+		t.Errorf("expected every endpoint to be cached separately, got %d requests (%v)", server.requestCount(), err)
+	}
+
+	// Stale data is served right away while it is refreshed in the background
+	cache.age(2 * time.Minute)
+	if ops, err := cache.getOperators(server.URL, "/ops", nil); err != nil || len(ops) != 2 {
+		t.Fatalf("expected the stale data to be served, got %+v (%v)", ops, err)
+	}
+	for i := 0; i < 100 && server.requestCount() < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if server.requestCount() != 3 {
+		t.Errorf("expected the stale data to be refreshed, got %d requests", server.requestCount())
+	}
+}
+
+func TestMentixCacheOutage(t *testing.T) {
+	server := newMentixServer()
+	defer server.Close()
+	cache := newTestMentixCache(time.Minute, time.Hour)
+
+	if _, err := cache.getOperators(server.URL, "/ops", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	atomic.StoreInt32(&server.failing, 1)
+
+	// A failed refresh keeps the cached data
+	source := mentixSource{host: server.URL, endpoint: "/ops"}
+	if _, err := cache.refresh(source, nil); err == nil {
+		t.Fatal("expected the refresh to fail")
+	}
+	cache.age(2 * time.Minute)
+	if ops, err := cache.getOperators(server.URL, "/ops", nil); err != nil || len(ops) != 2 {
+		t.Errorf("expected the cached data to be served while Mentix is down, got %+v (%v)", ops, err)
+	}
+
+	// Data exceeding the maximum staleness is discarded
+	cache.age(2 * time.Hour)
+	if _, err := cache.getOperators(server.URL, "/ops", nil); err == nil {
+		t.Error("expected the query to fail once the data is too old")
+	}
+}
+
+func TestEnableMentixCache(t *testing.T) {
+	log := zerolog.Nop()
+
+	first := EnableMentixCache(time.Minute, time.Hour, &log)
+	second := EnableMentixCache(time.Minute, time.Hour, &log)
+	if activeMentixCache() != second {
+		t.Fatal("expected the latest cache to be active")
+	}
+
+	// Stopping a replaced cache has no effect
+	first.Stop()
+	if activeMentixCache() != second {
+		t.Error("expected the latest cache to stay active")
+	}
+	second.Stop()
+	if activeMentixCache() != nil {
+		t.Error("expected the queries to bypass the cache once it is stopped")
+	}
+}
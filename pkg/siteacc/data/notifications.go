@@ -0,0 +1,117 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package data
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/mentix/exchangers/exporters/webhook"
+)
+
+const (
+	// NotificationSiteAdded is the notification type for sites that entered the mesh.
+	NotificationSiteAdded = webhook.EventSiteAdded
+	// NotificationSiteRemoved is the notification type for sites that left the mesh.
+	NotificationSiteRemoved = webhook.EventSiteRemoved
+	// NotificationSiteUnhealthy is the notification type for sites that have been flagged as unhealthy.
+	NotificationSiteUnhealthy = webhook.EventSiteUnhealthy
+)
+
+const (
+	maxNotificationMessages = 50
+)
+
+// Notification holds a single notification shown in the account panel.
+type Notification struct {
+	Type     string    `json:"type"`
+	SiteID   string    `json:"siteID"`
+	SiteName string    `json:"siteName"`
+	Message  string    `json:"message"`
+	Date     time.Time `json:"date"`
+}
+
+// NotificationPreferences holds the notification types an account does not want to receive; all notifications are enabled by default.
+type NotificationPreferences struct {
+	MutedEmails []string `json:"mutedEmails"`
+	MutedPanel  []string `json:"mutedPanel"`
+}
+
+// AccountNotifications holds the notification preferences and the panel notifications of an account.
+type AccountNotifications struct {
+	Preferences NotificationPreferences `json:"preferences"`
+	Messages    []*Notification         `json:"messages"`
+}
+
+// WantsEmail checks whether notifications of the given type should be sent via email.
+func (prefs NotificationPreferences) WantsEmail(notificationType string) bool {
+	return !isNotificationTypeListed(prefs.MutedEmails, notificationType)
+}
+
+// WantsPanel checks whether notifications of the given type should be shown in the account panel.
+func (prefs NotificationPreferences) WantsPanel(notificationType string) bool {
+	return !isNotificationTypeListed(prefs.MutedPanel, notificationType)
+}
+
+// Add adds a new panel notification, dropping the oldest ones if too many notifications are stored.
+func (notifications *AccountNotifications) Add(notification *Notification) {
+	notifications.Messages = append(notifications.Messages, notification)
+	if n := len(notifications.Messages); n > maxNotificationMessages {
+		notifications.Messages = append([]*Notification{}, notifications.Messages[n-maxNotificationMessages:]...)
+	}
+}
+
+// Recent returns the panel notifications, starting with the most recent one.
+func (notifications AccountNotifications) Recent() []*Notification {
+	recent := make([]*Notification, 0, len(notifications.Messages))
+	for i := len(notifications.Messages) - 1; i >= 0; i-- {
+		recent = append(recent, notifications.Messages[i])
+	}
+	return recent
+}
+
+// Clear removes all panel notifications.
+func (notifications *AccountNotifications) Clear() {
+	notifications.Messages = nil
+}
+
+// Clone creates a deep copy of the notifications.
+func (notifications *AccountNotifications) Clone() AccountNotifications {
+	clone := AccountNotifications{
+		Preferences: NotificationPreferences{
+			MutedEmails: append([]string{}, notifications.Preferences.MutedEmails...),
+			MutedPanel:  append([]string{}, notifications.Preferences.MutedPanel...),
+		},
+		Messages: make([]*Notification, 0, len(notifications.Messages)),
+	}
+	for _, msg := range notifications.Messages {
+		msgClone := *msg
+		clone.Messages = append(clone.Messages, &msgClone)
+	}
+	return clone
+}
+
+func isNotificationTypeListed(types []string, notificationType string) bool {
+	for _, t := range types {
+		if strings.EqualFold(t, notificationType) {
+			return true
+		}
+	}
+	return false
+}
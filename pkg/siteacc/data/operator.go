@@ -19,6 +19,8 @@
 package data
 
 import (
+	"strings"
+
 	"github.com/pkg/errors"
 )
 
@@ -26,7 +28,17 @@ import (
 type Operator struct {
 	ID string `json:"id"`
 
-	Sites []*Site `json:"sites"`
+	Sites    []*Site          `json:"sites"`
+	Settings OperatorSettings `json:"settings"`
+
+	// APIKeys holds the keys issued by the operator to let other services access its sites.
+	APIKeys []*OperatorAPIKey `json:"apiKeys,omitempty"`
+}
+
+// OperatorSettings holds the settings an operator applies to all of its accounts.
+type OperatorSettings struct {
+	// RequireTwoFactor enforces two-factor authentication for all accounts with Sites access.
+	RequireTwoFactor bool `json:"requireTwoFactor"`
 }
 
 // Operators holds an array of operators.
@@ -35,22 +47,49 @@ type Operators = []*Operator
 // Update copies the data of the given operator to this operator.
 func (op *Operator) Update(other *Operator, credsPassphrase string) error {
 	// Clear currently stored sites and clone over the new ones
-	op.Sites = make([]*Site, 0, len(other.Sites))
+	sites := make([]*Site, 0, len(other.Sites))
 	for _, otherSite := range other.Sites {
 		site := otherSite.Clone(true)
 		if err := site.Update(otherSite, credsPassphrase); err != nil {
 			return errors.Wrapf(err, "unable to update site %v", site.ID)
 		}
-		op.Sites = append(op.Sites, site)
+
+		// Site keys and managers can't be set through updates, so keep the ones already issued
+		site.Key = nil
+		site.Managers = nil
+		if oldSite := op.FindSite(site.ID); oldSite != nil {
+			site.Key = oldSite.Key
+			site.Managers = oldSite.Managers
+		}
+
+		sites = append(sites, site)
 	}
+	op.Sites = sites
+	return nil
+}
+
+// Configure copies the settings of the given operator to this operator.
+func (op *Operator) Configure(other *Operator) error {
+	op.Settings = other.Settings
 	return nil
 }
 
-// Clone creates a copy of the operator; if eraseCredentials is set to true, the (test user) credentials will be cleared in the cloned object.
+// FindSite returns the site of the operator specified by the ID if one exists.
+func (op *Operator) FindSite(id string) *Site {
+	for _, site := range op.Sites {
+		if strings.EqualFold(site.ID, id) {
+			return site
+		}
+	}
+	return nil
+}
+
+// Clone creates a copy of the operator; if eraseCredentials is set to true, the (test user) credentials and key hashes will be cleared in the cloned object.
 func (op *Operator) Clone(eraseCredentials bool) *Operator {
 	clone := &Operator{
-		ID:    op.ID,
-		Sites: []*Site{},
+		ID:       op.ID,
+		Sites:    []*Site{},
+		Settings: op.Settings,
 	}
 
 	// Clone sites
@@ -58,6 +97,11 @@ func (op *Operator) Clone(eraseCredentials bool) *Operator {
 		clone.Sites = append(clone.Sites, site.Clone(eraseCredentials))
 	}
 
+	// Clone API keys
+	for _, apiKey := range op.APIKeys {
+		clone.APIKeys = append(clone.APIKeys, apiKey.Clone(eraseCredentials))
+	}
+
 	return clone
 }
 
@@ -21,8 +21,11 @@ package data
 import (
 	"encoding/json"
 	"sort"
+	"time"
 
+	"github.com/cs3org/reva/pkg/mentix/key"
 	"github.com/cs3org/reva/pkg/mentix/utils/network"
+	"github.com/cs3org/reva/pkg/siteacc/metrics"
 	"github.com/pkg/errors"
 )
 
@@ -34,14 +37,29 @@ type OperatorInformation struct {
 	Sites []SiteInformation
 }
 
-// QueryAvailableOperators uses Mentix to query a list of all available operators and sites.
-func QueryAvailableOperators(mentixHost, dataEndpoint string) ([]OperatorInformation, error) {
+// QueryAvailableOperators uses Mentix to query a list of all available operators and sites; if signing is enabled, the request is signed.
+// If the Mentix cache is enabled, the operators are served from it.
+func QueryAvailableOperators(mentixHost, dataEndpoint string, signing *key.SigningConfig) ([]OperatorInformation, error) {
+	if cache := activeMentixCache(); cache != nil {
+		return cache.getOperators(mentixHost, dataEndpoint, signing)
+	}
+	return fetchAvailableOperators(mentixHost, dataEndpoint, signing)
+}
+
+func fetchAvailableOperators(mentixHost, dataEndpoint string, signing *key.SigningConfig) ([]OperatorInformation, error) {
 	mentixURL, err := network.GenerateURL(mentixHost, dataEndpoint, network.URLParams{})
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to generate Mentix URL")
 	}
 
-	data, err := network.ReadEndpoint(mentixURL, nil, true)
+	signer, err := key.NewRequestSigner(signing)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create the request signer")
+	}
+
+	start := time.Now()
+	data, err := network.ReadSignedEndpoint(mentixURL, signer, true)
+	metrics.RecordMentixQuery(start, err)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to read the Mentix endpoint")
 	}
@@ -62,8 +80,8 @@ func QueryAvailableOperators(mentixHost, dataEndpoint string) ([]OperatorInforma
 }
 
 // QueryOperatorName uses Mentix to query the name of an operator given by its ID.
-func QueryOperatorName(opID string, mentixHost, dataEndpoint string) (string, error) {
-	ops, err := QueryAvailableOperators(mentixHost, dataEndpoint)
+func QueryOperatorName(opID string, mentixHost, dataEndpoint string, signing *key.SigningConfig) (string, error) {
+	ops, err := QueryAvailableOperators(mentixHost, dataEndpoint, signing)
 	if err != nil {
 		return "", err
 	}
@@ -78,8 +96,8 @@ func QueryOperatorName(opID string, mentixHost, dataEndpoint string) (string, er
 }
 
 // QueryOperatorSites uses Mentix to query the sites associated with the specified operator.
-func QueryOperatorSites(opID string, mentixHost, dataEndpoint string) ([]string, error) {
-	ops, err := QueryAvailableOperators(mentixHost, dataEndpoint)
+func QueryOperatorSites(opID string, mentixHost, dataEndpoint string, signing *key.SigningConfig) ([]string, error) {
+	ops, err := QueryAvailableOperators(mentixHost, dataEndpoint, signing)
 	if err != nil {
 		return []string{}, err
 	}
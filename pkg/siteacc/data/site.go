@@ -19,6 +19,13 @@
 package data
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/mentix/key"
 	"github.com/cs3org/reva/pkg/siteacc/credentials"
 	"github.com/pkg/errors"
 )
@@ -28,6 +35,24 @@ type Site struct {
 	ID string `json:"id"`
 
 	Config SiteConfiguration `json:"config"`
+
+	Key *SiteKey `json:"key,omitempty"`
+
+	// Managers holds the email addresses of the accounts of other operators granted management rights over this site.
+	Managers []string `json:"managers,omitempty"`
+
+	// Health holds the result of the latest health check performed using the test client credentials.
+	Health *SiteHealth `json:"health,omitempty"`
+}
+
+// SiteKey holds the API key used by the IOP instance of a site to push metrics, together with its usage information.
+type SiteKey struct {
+	// Hash is the SHA256 hash of the key; the key itself is only shown once when it is issued.
+	Hash string `json:"hash"`
+
+	DateIssued time.Time `json:"dateIssued"`
+	LastSeen   time.Time `json:"lastSeen"`
+	UseCount   int64     `json:"useCount"`
 }
 
 // SiteConfiguration stores the global configuration of a sites.
@@ -55,12 +80,84 @@ func (site *Site) UpdateTestClientCredentials(id, secret string, passphrase stri
 	return nil
 }
 
-// Clone creates a copy of the sites; if eraseCredentials is set to true, the (test user) credentials will be cleared in the cloned object.
+// IssueKey generates a new site key, replacing any previously issued one; the generated key is returned.
+func (site *Site) IssueKey() (key.APIKey, error) {
+	apiKey, err := key.GenerateAPIKey(key.SaltFromSiteID(site.ID), key.FlagScienceMesh)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to generate the site key")
+	}
+
+	site.Key = &SiteKey{
+		Hash:       hashSiteKey(apiKey),
+		DateIssued: time.Now(),
+	}
+	return apiKey, nil
+}
+
+// VerifyKey checks whether the given key is the one issued for the site; if so, its usage information is updated.
+func (site *Site) VerifyKey(apiKey key.APIKey) error {
+	if site.Key == nil {
+		return errors.Errorf("no key has been issued for site %v", site.ID)
+	}
+
+	if err := key.VerifyAPIKey(apiKey, key.SaltFromSiteID(site.ID)); err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSiteKey(apiKey)), []byte(site.Key.Hash)) != 1 {
+		return errors.Errorf("the site key is invalid")
+	}
+
+	site.Key.LastSeen = time.Now()
+	site.Key.UseCount++
+	return nil
+}
+
+// IsManagedBy checks whether the account with the given email address has been granted management rights over the site.
+func (site *Site) IsManagedBy(email string) bool {
+	for _, manager := range site.Managers {
+		if strings.EqualFold(manager, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// GrantManagement grants or revokes the management rights of the account with the given email address over the site.
+func (site *Site) GrantManagement(email string, grant bool) {
+	managers := make([]string, 0, len(site.Managers)+1)
+	for _, manager := range site.Managers {
+		if !strings.EqualFold(manager, email) {
+			managers = append(managers, manager)
+		}
+	}
+	if grant {
+		managers = append(managers, email)
+	}
+	site.Managers = managers
+}
+
+// Clone creates a copy of the sites; if eraseCredentials is set to true, the (test user) credentials and the key hash will be cleared in the cloned object.
 func (site *Site) Clone(eraseCredentials bool) *Site {
 	clone := *site
 
+	if site.Key != nil {
+		siteKey := *site.Key
+		clone.Key = &siteKey
+	}
+
+	clone.Managers = append([]string{}, site.Managers...)
+
+	if site.Health != nil {
+		clone.Health = site.Health.Clone()
+	}
+
 	if eraseCredentials {
 		clone.Config.TestClientCredentials.Clear()
+
+		if clone.Key != nil {
+			clone.Key.Hash = ""
+		}
 	}
 
 	return &clone
@@ -76,3 +173,8 @@ func NewSite(id string) (*Site, error) {
 	}
 	return site, nil
 }
+
+func hashSiteKey(apiKey key.APIKey) string {
+	hash := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,65 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package data
+
+import "testing"
+
+func TestSiteManagement(t *testing.T) {
+	site := &Site{ID: "site"}
+
+	site.GrantManagement("john@example.org", true)
+	site.GrantManagement("JOHN@example.org", true)
+	site.GrantManagement("jane@example.org", true)
+	if len(site.Managers) != 2 {
+		t.Fatalf("expected every account to be granted the rights only once, got %v", site.Managers)
+	}
+	if !site.IsManagedBy("John@Example.org") || !site.IsManagedBy("jane@example.org") {
+		t.Errorf("expected the accounts to manage the site, got %v", site.Managers)
+	}
+
+	site.GrantManagement("john@example.org", false)
+	if site.IsManagedBy("john@example.org") || !site.IsManagedBy("jane@example.org") {
+		t.Errorf("expected only the rights of the account to be revoked, got %v", site.Managers)
+	}
+
+	clone := site.Clone(true)
+	clone.GrantManagement("john@example.org", true)
+	if site.IsManagedBy("john@example.org") {
+		t.Error("expected the clone not to share its managers with the site")
+	}
+}
+
+func TestOperatorUpdateKeepsManagers(t *testing.T) {
+	op := &Operator{ID: "op", Sites: []*Site{{ID: "site", Managers: []string{"john@example.org"}}}}
+
+	other := &Operator{ID: "op", Sites: []*Site{
+		{ID: "site", Managers: []string{"mallory@example.org"}},
+		{ID: "new", Managers: []string{"mallory@example.org"}},
+	}}
+	if err := op.Update(other, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if site := op.FindSite("site"); !site.IsManagedBy("john@example.org") || site.IsManagedBy("mallory@example.org") {
+		t.Errorf("expected the managers of the site to be kept, got %v", site.Managers)
+	}
+	if site := op.FindSite("new"); len(site.Managers) != 0 {
+		t.Errorf("expected managers not to be set through updates, got %v", site.Managers)
+	}
+}
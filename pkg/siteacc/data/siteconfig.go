@@ -0,0 +1,157 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package data
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// SiteConfigFormatCSV is the CSV format of exported site configurations.
+	SiteConfigFormatCSV = "csv"
+	// SiteConfigFormatJSON is the JSON format of exported site configurations.
+	SiteConfigFormatJSON = "json"
+)
+
+var siteConfigColumns = []string{"id", "name", "test_client_id", "test_client_secret"}
+
+// SiteConfigRecord holds the (decrypted) configuration of a single site used for bulk exports and imports.
+type SiteConfigRecord struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+
+	TestClientID     string `json:"testClientId"`
+	TestClientSecret string `json:"testClientSecret"`
+}
+
+// WriteSiteConfigRecords writes the given site configurations in the specified format.
+func WriteSiteConfigRecords(w io.Writer, records []*SiteConfigRecord, format string) error {
+	switch strings.ToLower(format) {
+	case SiteConfigFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(siteConfigColumns); err != nil {
+			return errors.Wrap(err, "unable to write the CSV header")
+		}
+		for _, record := range records {
+			if err := writer.Write([]string{record.ID, record.Name, record.TestClientID, record.TestClientSecret}); err != nil {
+				return errors.Wrapf(err, "unable to write the configuration of site %v", record.ID)
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+
+	case SiteConfigFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		return enc.Encode(records)
+	}
+
+	return errors.Errorf("unsupported format %v", format)
+}
+
+// ReadSiteConfigRecords reads site configurations in the specified format; CSV data needs to start with a header naming the columns.
+func ReadSiteConfigRecords(r io.Reader, format string) ([]*SiteConfigRecord, error) {
+	switch strings.ToLower(format) {
+	case SiteConfigFormatCSV:
+		return readSiteConfigCSV(r)
+
+	case SiteConfigFormatJSON:
+		records := make([]*SiteConfigRecord, 0)
+		dec := json.NewDecoder(r)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&records); err != nil {
+			return nil, errors.Wrap(err, "invalid JSON data")
+		}
+		for _, record := range records {
+			record.cleanup()
+		}
+		return records, nil
+	}
+
+	return nil, errors.Errorf("unsupported format %v", format)
+}
+
+func readSiteConfigCSV(r io.Reader) ([]*SiteConfigRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the CSV header")
+	}
+
+	// Columns may appear in any order, but all of them must be known
+	columns := make(map[string]int, len(header))
+	for i, col := range header {
+		col = strings.ToLower(strings.TrimSpace(col))
+		if !containsString(siteConfigColumns, col) {
+			return nil, errors.Errorf("unknown CSV column %v", col)
+		}
+		columns[col] = i
+	}
+	if _, ok := columns["id"]; !ok {
+		return nil, errors.Errorf("the CSV data lacks the id column")
+	}
+
+	records := make([]*SiteConfigRecord, 0)
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrap(err, "invalid CSV data")
+		}
+
+		value := func(col string) string {
+			if i, ok := columns[col]; ok && i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+		record := &SiteConfigRecord{
+			ID:               value("id"),
+			Name:             value("name"),
+			TestClientID:     value("test_client_id"),
+			TestClientSecret: value("test_client_secret"),
+		}
+		record.cleanup()
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func (record *SiteConfigRecord) cleanup() {
+	record.ID = strings.TrimSpace(record.ID)
+	record.Name = strings.TrimSpace(record.Name)
+	record.TestClientID = strings.TrimSpace(record.TestClientID)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package data
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSiteConfigRecordsRoundTrip(t *testing.T) {
+	records := []*SiteConfigRecord{
+		{ID: "site1", Name: "Site 1", TestClientID: "client", TestClientSecret: "with, comma \"quoted\""},
+		{ID: "site2", TestClientID: "other", TestClientSecret: " padded "},
+	}
+
+	for _, format := range []string{SiteConfigFormatCSV, SiteConfigFormatJSON, "CSV"} {
+		var buf bytes.Buffer
+		if err := WriteSiteConfigRecords(&buf, records, format); err != nil {
+			t.Fatalf("%s: unexpected error: %v", format, err)
+		}
+		read, err := ReadSiteConfigRecords(&buf, format)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", format, err)
+		}
+
+		if len(read) != len(records) {
+			t.Fatalf("%s: expected %d records, got %d", format, len(records), len(read))
+		}
+		for i := range records {
+			if *read[i] != *records[i] {
+				t.Errorf("%s: expected %+v, got %+v", format, *records[i], *read[i])
+			}
+		}
+	}
+}
+
+func TestReadSiteConfigCSV(t *testing.T) {
+	data := "test_client_secret, ID ,test_client_id\nsecret, site1 , client\nother,site2,\n"
+	records, err := ReadSiteConfigRecords(strings.NewReader(data), SiteConfigFormatCSV)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	expected := SiteConfigRecord{ID: "site1", TestClientID: "client", TestClientSecret: "secret"}
+	if *records[0] != expected {
+		t.Errorf("expected the columns to be mapped by their header, got %+v", *records[0])
+	}
+	if records[1].ID != "site2" || records[1].TestClientID != "" {
+		t.Errorf("expected missing columns to be empty, got %+v", *records[1])
+	}
+}
+
+func TestReadInvalidSiteConfigRecords(t *testing.T) {
+	tests := []struct {
+		data   string
+		format string
+	}{
+		{"id,name,password\nsite1,Site,secret\n", SiteConfigFormatCSV},
+		{"name,test_client_id\nSite,client\n", SiteConfigFormatCSV},
+		{"", SiteConfigFormatCSV},
+		{`[{"id": "site1", "password": "secret"}]`, SiteConfigFormatJSON},
+		{`{"id": "site1"}`, SiteConfigFormatJSON},
+		{"id\nsite1\n", "xml"},
+	}
+
+	for _, tt := range tests {
+		if _, err := ReadSiteConfigRecords(strings.NewReader(tt.data), tt.format); err == nil {
+			t.Errorf("expected %q (%s) to be rejected", tt.data, tt.format)
+		}
+	}
+
+	if err := WriteSiteConfigRecords(&bytes.Buffer{}, nil, "xml"); err == nil {
+		t.Error("expected an unsupported format to be rejected")
+	}
+}
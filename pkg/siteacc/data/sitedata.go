@@ -0,0 +1,124 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cs3org/reva/pkg/mentix/exchangers/importers/siteupdate"
+	"github.com/cs3org/reva/pkg/mentix/key"
+	"github.com/cs3org/reva/pkg/mentix/meshdata"
+	"github.com/cs3org/reva/pkg/mentix/utils/network"
+	"github.com/cs3org/reva/pkg/siteacc/metrics"
+	"github.com/pkg/errors"
+)
+
+// QuerySiteData uses Mentix to query the updatable data (properties and endpoints) of a site.
+func QuerySiteData(siteID string, mentixHost, dataEndpoint string, signing *key.SigningConfig) (*siteupdate.SiteData, error) {
+	meshData, err := queryMeshData(mentixHost, dataEndpoint, signing)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range meshData.Operators {
+		if site := op.FindSite(siteID); site != nil {
+			return siteupdate.GetSiteData(site), nil
+		}
+	}
+
+	return nil, errors.Errorf("no site with ID %v found", siteID)
+}
+
+func queryMeshData(mentixHost, dataEndpoint string, signing *key.SigningConfig) (*meshdata.MeshData, error) {
+	mentixURL, err := network.GenerateURL(mentixHost, dataEndpoint, network.URLParams{})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to generate Mentix URL")
+	}
+
+	signer, err := key.NewRequestSigner(signing)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create the request signer")
+	}
+
+	start := time.Now()
+	data, err := network.ReadSignedEndpoint(mentixURL, signer, true)
+	metrics.RecordMentixQuery(start, err)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the Mentix endpoint")
+	}
+
+	meshData := &meshdata.MeshData{}
+	if err := json.Unmarshal(data, meshData); err != nil {
+		return nil, errors.Wrap(err, "error while decoding the JSON data")
+	}
+	return meshData, nil
+}
+
+// UpdateSiteData uses Mentix to write changes made to a site back to the GOCDB; if dryRun is set, the changes are only previewed.
+// If the changes conflict with changes made in the meantime, the conflicts are returned in the response.
+func UpdateSiteData(req *siteupdate.Request, dryRun bool, mentixHost, siteUpdateEndpoint string, signing *key.SigningConfig) (*siteupdate.Response, error) {
+	mentixURL, err := network.GenerateURL(mentixHost, siteUpdateEndpoint, network.URLParams{"action": "update", "dryrun": strconv.FormatBool(dryRun)})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to generate Mentix URL")
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal the site update")
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, mentixURL.String(), bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create HTTP request")
+	}
+	httpReq.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	signer, err := key.NewRequestSigner(signing)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create the request signer")
+	}
+	if err := signer.Sign(httpReq, data); err != nil {
+		return nil, errors.Wrap(err, "unable to sign the site update")
+	}
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(httpReq)
+	metrics.RecordMentixQuery(start, err)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to send the site update to Mentix")
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusConflict {
+		return nil, errors.Errorf("unable to update the site: %v", string(body))
+	}
+
+	updateResp := &siteupdate.Response{}
+	if err := json.Unmarshal(body, updateResp); err != nil {
+		return nil, errors.Wrap(err, "error while decoding the JSON data")
+	}
+	return updateResp, nil
+}
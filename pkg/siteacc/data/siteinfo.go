@@ -19,6 +19,7 @@
 package data
 
 import (
+	"github.com/cs3org/reva/pkg/mentix/key"
 	"github.com/pkg/errors"
 )
 
@@ -30,8 +31,8 @@ type SiteInformation struct {
 }
 
 // QuerySiteName uses Mentix to query the name of a sites given by its ID.
-func QuerySiteName(siteID string, fullName bool, mentixHost, dataEndpoint string) (string, error) {
-	ops, err := QueryAvailableOperators(mentixHost, dataEndpoint)
+func QuerySiteName(siteID string, fullName bool, mentixHost, dataEndpoint string, signing *key.SigningConfig) (string, error) {
+	ops, err := QueryAvailableOperators(mentixHost, dataEndpoint, signing)
 	if err != nil {
 		return "", err
 	}
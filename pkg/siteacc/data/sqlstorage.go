@@ -0,0 +1,399 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sync"
+
+	"github.com/cs3org/reva/pkg/migrate"
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/sqldb"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// SQLSchema is the migration component of the site accounts tables.
+const SQLSchema = "siteacc"
+
+const (
+	operatorsTable = "siteacc_operators"
+	accountsTable  = "siteacc_accounts"
+
+	operatorsRevision = "operators"
+	accountsRevision  = "accounts"
+)
+
+func init() {
+	migrate.Register(SQLSchema, migrate.Migration{
+		Version:     1,
+		Description: "create the operators and accounts tables",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS siteacc_operators (
+				id VARCHAR(255) NOT NULL PRIMARY KEY,
+				data LONGTEXT NOT NULL
+			)`,
+			`CREATE TABLE IF NOT EXISTS siteacc_accounts (
+				email VARCHAR(255) NOT NULL PRIMARY KEY,
+				data LONGTEXT NOT NULL
+			)`,
+		},
+		Down: []string{
+			"DROP TABLE IF EXISTS siteacc_accounts",
+			"DROP TABLE IF EXISTS siteacc_operators",
+		},
+		PostgresUp: []string{
+			`CREATE TABLE IF NOT EXISTS siteacc_operators (
+				id VARCHAR(255) NOT NULL PRIMARY KEY,
+				data TEXT NOT NULL
+			)`,
+			`CREATE TABLE IF NOT EXISTS siteacc_accounts (
+				email VARCHAR(255) NOT NULL PRIMARY KEY,
+				data TEXT NOT NULL
+			)`,
+		},
+	}, migrate.Migration{
+		Version:     2,
+		Description: "track the revisions of the operators and accounts",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS siteacc_revisions (
+				name VARCHAR(64) NOT NULL PRIMARY KEY,
+				revision BIGINT NOT NULL
+			)`,
+			"INSERT INTO siteacc_revisions (name, revision) VALUES ('operators', 0), ('accounts', 0)",
+		},
+		Down: []string{
+			"DROP TABLE IF EXISTS siteacc_revisions",
+		},
+	})
+}
+
+// SQLStorage implements a storage backed by a MySQL, PostgreSQL or SQLite database.
+// Operators and accounts are written one by one as they are modified, so that several service instances can share the same database.
+type SQLStorage struct {
+	SharedStorage
+
+	conf *config.Configuration
+	log  *zerolog.Logger
+
+	db *sql.DB
+
+	// revisions holds the revisions of the data last read by this instance
+	revisions map[string]int64
+	mutex     sync.Mutex
+}
+
+func (storage *SQLStorage) initialize(conf *config.Configuration, log *zerolog.Logger) error {
+	if conf == nil {
+		return errors.Errorf("no configuration provided")
+	}
+	storage.conf = conf
+
+	db, err := sqldb.Open(sqldb.Config{
+		Engine:   conf.Storage.SQL.Engine,
+		Username: conf.Storage.SQL.Username,
+		Password: conf.Storage.SQL.Password,
+		Host:     conf.Storage.SQL.Host,
+		Port:     conf.Storage.SQL.Port,
+		Name:     conf.Storage.SQL.Name,
+		Path:     conf.Storage.SQL.Path,
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to open the database")
+	}
+
+	return storage.setup(db, log)
+}
+
+func (storage *SQLStorage) setup(db *sql.DB, log *zerolog.Logger) error {
+	if log == nil {
+		return errors.Errorf("no logger provided")
+	}
+	storage.log = log
+
+	if err := migrate.Up(context.Background(), db, SQLSchema); err != nil {
+		return errors.Wrap(err, "unable to migrate the database")
+	}
+	storage.db = db
+	storage.revisions = make(map[string]int64)
+
+	return nil
+}
+
+func (storage *SQLStorage) query(query string) string {
+	return sqldb.Rebind(storage.db, query)
+}
+
+// rowQuerier is implemented by both databases and transactions.
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func (storage *SQLStorage) readRevision(q rowQuerier, name string) (int64, error) {
+	var revision int64
+	if err := q.QueryRow(storage.query("SELECT revision FROM siteacc_revisions WHERE name=?"), name).Scan(&revision); err != nil {
+		return 0, errors.Wrapf(err, "unable to read the revision of the %v", name)
+	}
+	return revision, nil
+}
+
+func (storage *SQLStorage) readData(table, revisionName string, newObj func() interface{}, add func(obj interface{})) error {
+	// The revision is read first; data modified in the meantime thus only causes another reload
+	revision, err := storage.readRevision(storage.db, revisionName)
+	if err != nil {
+		return err
+	}
+
+	rows, err := storage.db.Query("SELECT data FROM " + table)
+	if err != nil {
+		return errors.Wrapf(err, "unable to query table %v", table)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return errors.Wrapf(err, "unable to read table %v", table)
+		}
+		obj := newObj()
+		if err := json.Unmarshal([]byte(data), obj); err != nil {
+			return errors.Wrapf(err, "invalid data in table %v", table)
+		}
+		add(obj)
+	}
+	if err := rows.Err(); err != nil {
+		return errors.Wrapf(err, "unable to read table %v", table)
+	}
+
+	storage.mutex.Lock()
+	storage.revisions[revisionName] = revision
+	storage.mutex.Unlock()
+	return nil
+}
+
+// ReadOperators reads all stored operators into the given data object.
+func (storage *SQLStorage) ReadOperators() (*Operators, error) {
+	operators := &Operators{}
+	err := storage.readData(operatorsTable, operatorsRevision, func() interface{} { return &Operator{} }, func(obj interface{}) {
+		*operators = append(*operators, obj.(*Operator))
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading operators")
+	}
+	return operators, nil
+}
+
+// ReadAccounts reads all stored accounts into the given data object.
+func (storage *SQLStorage) ReadAccounts() (*Accounts, error) {
+	accounts := &Accounts{}
+	err := storage.readData(accountsTable, accountsRevision, func() interface{} { return &Account{} }, func(obj interface{}) {
+		*accounts = append(*accounts, obj.(*Account))
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading accounts")
+	}
+	return accounts, nil
+}
+
+func (storage *SQLStorage) modified(revisionName string) (bool, error) {
+	revision, err := storage.readRevision(storage.db, revisionName)
+	if err != nil {
+		return false, err
+	}
+
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	return revision != storage.revisions[revisionName], nil
+}
+
+// OperatorsModified reports whether the stored operators have been modified by another instance since they were last read.
+func (storage *SQLStorage) OperatorsModified() (bool, error) {
+	return storage.modified(operatorsRevision)
+}
+
+// AccountsModified reports whether the stored accounts have been modified by another instance since they were last read.
+func (storage *SQLStorage) AccountsModified() (bool, error) {
+	return storage.modified(accountsRevision)
+}
+
+func (storage *SQLStorage) upsertQuery(table, keyColumn string) string {
+	query := "INSERT INTO " + table + " (" + keyColumn + ", data) VALUES (?, ?) ON DUPLICATE KEY UPDATE data=VALUES(data)"
+	if engine := sqldb.Engine(storage.db); engine == sqldb.SQLite || engine == sqldb.Postgres {
+		query = "INSERT INTO " + table + " (" + keyColumn + ", data) VALUES (?, ?) ON CONFLICT(" + keyColumn + ") DO UPDATE SET data=excluded.data"
+	}
+	return storage.query(query)
+}
+
+// modify runs the given modification in a transaction which also advances the revision of the data.
+// Concurrent modifications are serialized by the lock the database holds on the revision row until the transaction ends.
+func (storage *SQLStorage) modify(revisionName string, modification func(tx *sql.Tx) error) error {
+	tx, err := storage.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "unable to begin a transaction")
+	}
+
+	if _, err := tx.Exec(storage.query("UPDATE siteacc_revisions SET revision=revision+1 WHERE name=?"), revisionName); err != nil {
+		_ = tx.Rollback()
+		return errors.Wrapf(err, "unable to update the revision of the %v", revisionName)
+	}
+	if err := modification(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	revision, err := storage.readRevision(tx, revisionName)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "unable to commit the transaction")
+	}
+
+	// If nobody else modified the data since it was last read, this instance is still up-to-date
+	storage.mutex.Lock()
+	if revision == storage.revisions[revisionName]+1 {
+		storage.revisions[revisionName] = revision
+	}
+	storage.mutex.Unlock()
+	return nil
+}
+
+func (storage *SQLStorage) writeObject(table, keyColumn, revisionName, key string, obj interface{}) error {
+	data, _ := json.Marshal(obj)
+	return storage.modify(revisionName, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(storage.upsertQuery(table, keyColumn), key, string(data)); err != nil {
+			return errors.Wrapf(err, "unable to write table %v", table)
+		}
+		return nil
+	})
+}
+
+func (storage *SQLStorage) removeObject(table, keyColumn, revisionName, key string) error {
+	return storage.modify(revisionName, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(storage.query("DELETE FROM "+table+" WHERE "+keyColumn+"=?"), key); err != nil {
+			return errors.Wrapf(err, "unable to delete from table %v", table)
+		}
+		return nil
+	})
+}
+
+// WriteOperators writes all stored operators from the given data object.
+func (storage *SQLStorage) WriteOperators(ops *Operators) error {
+	// Simply skip this action; all data is saved solely in the OperatorXXX callbacks
+	return nil
+}
+
+// WriteAccounts writes all stored accounts from the given data object.
+func (storage *SQLStorage) WriteAccounts(accounts *Accounts) error {
+	// Simply skip this action; all data is saved solely in the AccountXXX callbacks
+	return nil
+}
+
+// OperatorAdded is called when an operator has been added.
+func (storage *SQLStorage) OperatorAdded(op *Operator) {
+	if err := storage.writeObject(operatorsTable, "id", operatorsRevision, op.ID, op); err != nil {
+		storage.log.Error().Err(err).Str("operator", op.ID).Msg("error while adding operator")
+	}
+}
+
+// OperatorUpdated is called when an operator has been updated.
+func (storage *SQLStorage) OperatorUpdated(op *Operator) {
+	if err := storage.writeObject(operatorsTable, "id", operatorsRevision, op.ID, op); err != nil {
+		storage.log.Error().Err(err).Str("operator", op.ID).Msg("error while updating operator")
+	}
+}
+
+// OperatorRemoved is called when an operator has been removed.
+func (storage *SQLStorage) OperatorRemoved(op *Operator) {
+	if err := storage.removeObject(operatorsTable, "id", operatorsRevision, op.ID); err != nil {
+		storage.log.Error().Err(err).Str("operator", op.ID).Msg("error while removing operator")
+	}
+}
+
+// AccountAdded is called when an account has been added.
+func (storage *SQLStorage) AccountAdded(account *Account) {
+	if err := storage.writeObject(accountsTable, "email", accountsRevision, account.Email, account); err != nil {
+		storage.log.Error().Err(err).Str("account", account.Email).Msg("error while adding account")
+	}
+}
+
+// AccountUpdated is called when an account has been updated.
+func (storage *SQLStorage) AccountUpdated(account *Account) {
+	if err := storage.writeObject(accountsTable, "email", accountsRevision, account.Email, account); err != nil {
+		storage.log.Error().Err(err).Str("account", account.Email).Msg("error while updating account")
+	}
+}
+
+// AccountRemoved is called when an account has been removed.
+func (storage *SQLStorage) AccountRemoved(account *Account) {
+	if err := storage.removeObject(accountsTable, "email", accountsRevision, account.Email); err != nil {
+		storage.log.Error().Err(err).Str("account", account.Email).Msg("error while removing account")
+	}
+}
+
+// Import writes the given operators and accounts, e.g. read from a file storage, to the database; existing entries with the same ID or email are replaced.
+func (storage *SQLStorage) Import(ops *Operators, accounts *Accounts) error {
+	err := storage.modify(operatorsRevision, func(tx *sql.Tx) error {
+		for _, op := range *ops {
+			data, _ := json.Marshal(op)
+			if _, err := tx.Exec(storage.upsertQuery(operatorsTable, "id"), op.ID, string(data)); err != nil {
+				return errors.Wrapf(err, "unable to import operator %v", op.ID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "error importing operators")
+	}
+
+	err = storage.modify(accountsRevision, func(tx *sql.Tx) error {
+		for _, account := range *accounts {
+			data, _ := json.Marshal(account)
+			if _, err := tx.Exec(storage.upsertQuery(accountsTable, "email"), account.Email, string(data)); err != nil {
+				return errors.Wrapf(err, "unable to import account %v", account.Email)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "error importing accounts")
+	}
+
+	return nil
+}
+
+// NewSQLStorage creates a new SQL storage.
+func NewSQLStorage(conf *config.Configuration, log *zerolog.Logger) (*SQLStorage, error) {
+	storage := &SQLStorage{}
+	if err := storage.initialize(conf, log); err != nil {
+		return nil, errors.Wrap(err, "unable to initialize the SQL storage")
+	}
+	return storage, nil
+}
+
+// NewSQLStorageWithDB creates a new SQL storage using an already opened database.
+func NewSQLStorageWithDB(db *sql.DB, log *zerolog.Logger) (*SQLStorage, error) {
+	storage := &SQLStorage{}
+	if err := storage.setup(db, log); err != nil {
+		return nil, errors.Wrap(err, "unable to initialize the SQL storage")
+	}
+	return storage, nil
+}
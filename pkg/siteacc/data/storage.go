@@ -44,3 +44,14 @@ type Storage interface {
 	// AccountRemoved is called when an account has been removed.
 	AccountRemoved(account *Account)
 }
+
+// SharedStorage is implemented by storages which can be used by several service instances at the same time.
+// As the stored data might then be modified by other instances, it needs to be reloaded whenever it has changed.
+type SharedStorage interface {
+	Storage
+
+	// OperatorsModified reports whether the stored operators have been modified by another instance since they were last read.
+	OperatorsModified() (bool, error)
+	// AccountsModified reports whether the stored accounts have been modified by another instance since they were last read.
+	AccountsModified() (bool, error)
+}
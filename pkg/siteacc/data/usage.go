@@ -0,0 +1,144 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package data
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/mentix/key"
+	"github.com/cs3org/reva/pkg/mentix/usage"
+	"github.com/cs3org/reva/pkg/mentix/utils/network"
+	"github.com/cs3org/reva/pkg/siteacc/metrics"
+	"github.com/pkg/errors"
+)
+
+const (
+	// UsageFormatCSV is the CSV format of exported site usage.
+	UsageFormatCSV = "csv"
+	// UsageFormatJSON is the JSON format of exported site usage.
+	UsageFormatJSON = "json"
+
+	// MaxUsageRange is the maximum number of days whose usage can be queried at once.
+	MaxUsageRange = 366
+)
+
+var usageColumns = []string{"site", "date", "shares_sent", "shares_received", "bytes_transferred", "requests", "errors"}
+
+// QuerySiteUsage uses Mentix to query the daily API usage of a site between the two days (inclusive).
+func QuerySiteUsage(siteID, from, to string, mentixHost, usageEndpoint string, signing *key.SigningConfig) ([]*usage.Record, error) {
+	mentixURL, err := network.GenerateURL(mentixHost, usageEndpoint, network.URLParams{"action": "site", "id": siteID, "from": from, "to": to})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to generate Mentix URL")
+	}
+
+	signer, err := key.NewRequestSigner(signing)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create the request signer")
+	}
+
+	start := time.Now()
+	data, err := network.ReadSignedEndpoint(mentixURL, signer, true)
+	metrics.RecordMentixQuery(start, err)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the Mentix endpoint")
+	}
+
+	records := make([]*usage.Record, 0)
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, errors.Wrap(err, "error while decoding the JSON data")
+	}
+	return records, nil
+}
+
+// ParseUsageRange parses the days of a usage query; if no days are given, the last 30 days are used.
+func ParseUsageRange(from, to string) (string, string, error) {
+	toDate := time.Now()
+	if to != "" {
+		date, err := time.Parse(usage.DateLayout, to)
+		if err != nil {
+			return "", "", errors.Errorf("invalid end date %v", to)
+		}
+		toDate = date
+	}
+
+	fromDate := toDate.AddDate(0, 0, -29)
+	if from != "" {
+		date, err := time.Parse(usage.DateLayout, from)
+		if err != nil {
+			return "", "", errors.Errorf("invalid start date %v", from)
+		}
+		fromDate = date
+	}
+
+	if fromDate.After(toDate) {
+		return "", "", errors.Errorf("the start date is after the end date")
+	}
+	if toDate.Sub(fromDate) >= MaxUsageRange*24*time.Hour {
+		return "", "", errors.Errorf("the usage of at most %v days can be queried at once", MaxUsageRange)
+	}
+	return fromDate.Format(usage.DateLayout), toDate.Format(usage.DateLayout), nil
+}
+
+// SumUsage sums up the usage of all given records.
+func SumUsage(records []*usage.Record) usage.Counters {
+	total := usage.Counters{}
+	for _, record := range records {
+		total.Add(record.Counters)
+	}
+	return total
+}
+
+// WriteUsageRecords writes the given usage records in the specified format.
+func WriteUsageRecords(w io.Writer, records []*usage.Record, format string) error {
+	switch strings.ToLower(format) {
+	case UsageFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(usageColumns); err != nil {
+			return errors.Wrap(err, "unable to write the CSV header")
+		}
+		for _, record := range records {
+			row := []string{
+				record.Site,
+				record.Date,
+				strconv.FormatInt(record.SharesSent, 10),
+				strconv.FormatInt(record.SharesReceived, 10),
+				strconv.FormatInt(record.BytesTransferred, 10),
+				strconv.FormatInt(record.Requests, 10),
+				strconv.FormatInt(record.Errors, 10),
+			}
+			if err := writer.Write(row); err != nil {
+				return errors.Wrapf(err, "unable to write the usage of %v", record.Date)
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+
+	case UsageFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		return enc.Encode(records)
+	}
+
+	return errors.Errorf("unsupported format %v", format)
+}
@@ -20,12 +20,13 @@ package email
 
 import (
 	"bytes"
+	htmltemplate "html/template"
 	"strings"
 	"text/template"
 
 	"github.com/cs3org/reva/pkg/siteacc/config"
 	"github.com/cs3org/reva/pkg/siteacc/data"
-	"github.com/cs3org/reva/pkg/smtpclient"
+	"github.com/cs3org/reva/pkg/siteacc/metrics"
 	"github.com/pkg/errors"
 )
 
@@ -52,58 +53,112 @@ func getEmailData(account *data.Account, conf config.Configuration, params map[s
 
 // SendAccountCreated sends an email about account creation.
 func SendAccountCreated(account *data.Account, recipients []string, params map[string]string, conf config.Configuration) error {
-	return send(recipients, "ScienceMesh: Site Administrator Account created", accountCreatedTemplate, getEmailData(account, conf, params), conf.Email.SMTP)
+	return send(recipients, accountCreatedMessage, account, getEmailData(account, conf, params), conf)
+}
+
+// SendAccountVerification sends an email asking the user to verify the email address of the account.
+func SendAccountVerification(account *data.Account, recipients []string, params map[string]string, conf config.Configuration) error {
+	return send(recipients, accountVerificationMessage, account, getEmailData(account, conf, params), conf)
+}
+
+// SendAccountApproved sends an email about the approval of an account.
+func SendAccountApproved(account *data.Account, recipients []string, params map[string]string, conf config.Configuration) error {
+	return send(recipients, accountApprovedMessage, account, getEmailData(account, conf, params), conf)
 }
 
 // SendSitesAccessGranted sends an email about granted Sites access.
 func SendSitesAccessGranted(account *data.Account, recipients []string, params map[string]string, conf config.Configuration) error {
-	return send(recipients, "ScienceMesh: Sites access granted", sitesAccessGrantedTemplate, getEmailData(account, conf, params), conf.Email.SMTP)
+	return send(recipients, sitesAccessGrantedMessage, account, getEmailData(account, conf, params), conf)
+}
+
+// SendSiteManagementGranted sends an email about granted management rights over a site.
+func SendSiteManagementGranted(account *data.Account, recipients []string, params map[string]string, conf config.Configuration) error {
+	return send(recipients, siteManagementGrantedMessage, account, getEmailData(account, conf, params), conf)
 }
 
 // SendGOCDBAccessGranted sends an email about granted GOCDB access.
 func SendGOCDBAccessGranted(account *data.Account, recipients []string, params map[string]string, conf config.Configuration) error {
-	return send(recipients, "ScienceMesh: GOCDB access granted", gocdbAccessGrantedTemplate, getEmailData(account, conf, params), conf.Email.SMTP)
+	return send(recipients, gocdbAccessGrantedMessage, account, getEmailData(account, conf, params), conf)
 }
 
 // SendPasswordReset sends an email containing the user's new password.
 func SendPasswordReset(account *data.Account, recipients []string, params map[string]string, conf config.Configuration) error {
-	return send(recipients, "ScienceMesh: Password reset", passwordResetTemplate, getEmailData(account, conf, params), conf.Email.SMTP)
+	return send(recipients, passwordResetMessage, account, getEmailData(account, conf, params), conf)
+}
+
+// SendAccountDeletionConfirm sends an email asking the account owner to confirm the deletion of the account.
+func SendAccountDeletionConfirm(account *data.Account, recipients []string, params map[string]string, conf config.Configuration) error {
+	return send(recipients, accountDeletionConfirmMessage, account, getEmailData(account, conf, params), conf)
+}
+
+// SendAccountDeletionRequested sends an email about a requested account deletion.
+func SendAccountDeletionRequested(account *data.Account, recipients []string, params map[string]string, conf config.Configuration) error {
+	return send(recipients, accountDeletionRequestedMessage, account, getEmailData(account, conf, params), conf)
+}
+
+// SendAccountDeletionCanceled sends an email about a canceled account deletion.
+func SendAccountDeletionCanceled(account *data.Account, recipients []string, params map[string]string, conf config.Configuration) error {
+	return send(recipients, accountDeletionCanceledMessage, account, getEmailData(account, conf, params), conf)
+}
+
+// SendSiteNotification sends a notification about a change of a site within the mesh.
+func SendSiteNotification(account *data.Account, recipients []string, params map[string]string, conf config.Configuration) error {
+	return send(recipients, siteNotificationMessage, account, getEmailData(account, conf, params), conf)
+}
+
+// SendAnnouncement sends an announcement posted by the ScienceMesh admins.
+func SendAnnouncement(account *data.Account, recipients []string, params map[string]string, conf config.Configuration) error {
+	return send(recipients, announcementMessage, account, getEmailData(account, conf, params), conf)
 }
 
 // SendContactForm sends a generic contact form to the ScienceMesh admins.
 func SendContactForm(account *data.Account, recipients []string, params map[string]string, conf config.Configuration) error {
-	return send(recipients, "ScienceMesh: Contact form", contactFormTemplate, getEmailData(account, conf, params), conf.Email.SMTP)
+	return send(recipients, contactFormMessage, account, getEmailData(account, conf, params), conf)
 }
 
 // SendAlertNotification sends an alert via email.
 func SendAlertNotification(account *data.Account, recipients []string, params map[string]string, conf config.Configuration) error {
-	subject := params["Summary"]
-	tpl := alertFiringNotificationTemplate
+	msg := alertFiringNotificationMessage
 	if strings.EqualFold(params["Status"], "resolved") {
-		tpl = alertResolvedNotificationTemplate
-		subject += " [RESOLVED]"
+		msg = alertResolvedNotificationMessage
 	}
-	return send(recipients, "ScienceMesh Alert: "+subject, tpl, getEmailData(account, conf, params), conf.Email.SMTP)
+	return send(recipients, msg, account, getEmailData(account, conf, params), conf)
 }
 
-func send(recipients []string, subject string, bodyTemplate string, data interface{}, smtp *smtpclient.SMTPCredentials) error {
+func send(recipients []string, msg message, account *data.Account, tplData interface{}, conf config.Configuration) error {
 	// Do not fail if no SMTP client or recipient is given
+	smtp := conf.Email.SMTP
 	if smtp == nil {
 		return nil
 	}
 
-	tpl := template.New("email")
-	prepareEmailTemplate(tpl)
+	language := ""
+	if account != nil {
+		language = account.Settings.Language
+	}
+	content, err := loadMessage(msg, language, conf)
+	if err != nil {
+		return errors.Wrapf(err, "error while loading email template %v", msg.name)
+	}
 
-	if _, err := tpl.Parse(bodyTemplate); err != nil {
-		return errors.Wrap(err, "error while parsing email template")
+	subject, err := executeTextTemplate(content.subject, tplData)
+	if err != nil {
+		return errors.Wrap(err, "error while executing email subject template")
 	}
+	subject = strings.TrimSpace(subject)
 
-	var body bytes.Buffer
-	if err := tpl.Execute(&body, data); err != nil {
+	body, err := executeTextTemplate(content.body, tplData)
+	if err != nil {
 		return errors.Wrap(err, "error while executing email template")
 	}
 
+	htmlBody := ""
+	if content.htmlBody != "" {
+		if htmlBody, err = executeHTMLTemplate(content.htmlBody, tplData); err != nil {
+			return errors.Wrap(err, "error while executing HTML email template")
+		}
+	}
+
 	for _, recipient := range recipients {
 		if len(recipient) == 0 {
 			continue
@@ -111,16 +166,44 @@ func send(recipients []string, subject string, bodyTemplate string, data interfa
 
 		// Send the mail w/o blocking the main thread
 		go func(recipient string) {
-			_ = smtp.SendMail(recipient, subject, body.String())
+			if err := smtp.SendMultipartMail(recipient, subject, body, htmlBody); err != nil {
+				metrics.RecordEmailFailure(msg.name)
+			}
 		}(recipient)
 	}
 
 	return nil
 }
 
-func prepareEmailTemplate(tpl *template.Template) {
-	// Add some custom helper functions to the template
-	tpl.Funcs(template.FuncMap{
+func executeTextTemplate(text string, data interface{}) (string, error) {
+	tpl := template.New("email").Funcs(getTemplateFunctions())
+	if _, err := tpl.Parse(text); err != nil {
+		return "", errors.Wrap(err, "error while parsing email template")
+	}
+
+	var output bytes.Buffer
+	if err := tpl.Execute(&output, data); err != nil {
+		return "", err
+	}
+	return output.String(), nil
+}
+
+func executeHTMLTemplate(text string, data interface{}) (string, error) {
+	tpl := htmltemplate.New("email").Funcs(htmltemplate.FuncMap(getTemplateFunctions()))
+	if _, err := tpl.Parse(text); err != nil {
+		return "", errors.Wrap(err, "error while parsing HTML email template")
+	}
+
+	var output bytes.Buffer
+	if err := tpl.Execute(&output, data); err != nil {
+		return "", err
+	}
+	return output.String(), nil
+}
+
+func getTemplateFunctions() template.FuncMap {
+	// Add some custom helper functions to the templates
+	return template.FuncMap{
 		"indent": func(n int, s string) string {
 			lines := make([]string, 0, 10)
 			for _, line := range strings.Split(s, "\n") {
@@ -130,5 +213,5 @@ func prepareEmailTemplate(tpl *template.Template) {
 			}
 			return strings.Join(lines, "\n")
 		},
-	})
+	}
 }
@@ -0,0 +1,179 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package email
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/pkg/errors"
+)
+
+// message describes an email; operators can override its subject and bodies by placing template files named after the message into the templates directory.
+type message struct {
+	name    string
+	subject string
+	body    string
+}
+
+// messageContent holds the templates used to compose an email.
+type messageContent struct {
+	subject  string
+	body     string
+	htmlBody string
+}
+
+const (
+	subjectExtension  = ".subject"
+	bodyExtension     = ".txt"
+	htmlBodyExtension = ".html"
+)
+
+var (
+	accountCreatedMessage            = message{"account-created", "ScienceMesh: Site Administrator Account created", accountCreatedTemplate}
+	accountVerificationMessage       = message{"account-verification", "ScienceMesh: Verify your email address", accountVerificationTemplate}
+	accountApprovedMessage           = message{"account-approved", "ScienceMesh: Site Administrator Account approved", accountApprovedTemplate}
+	sitesAccessGrantedMessage        = message{"sites-access-granted", "ScienceMesh: Sites access granted", sitesAccessGrantedTemplate}
+	siteManagementGrantedMessage     = message{"site-management-granted", "ScienceMesh: Site management rights granted", siteManagementGrantedTemplate}
+	gocdbAccessGrantedMessage        = message{"gocdb-access-granted", "ScienceMesh: GOCDB access granted", gocdbAccessGrantedTemplate}
+	passwordResetMessage             = message{"password-reset", "ScienceMesh: Password reset", passwordResetTemplate}
+	accountDeletionConfirmMessage    = message{"account-deletion-confirm", "ScienceMesh: Confirm the deletion of your account", accountDeletionConfirmTemplate}
+	accountDeletionRequestedMessage  = message{"account-deletion-requested", "ScienceMesh: Account deletion requested", accountDeletionRequestedTemplate}
+	accountDeletionCanceledMessage   = message{"account-deletion-canceled", "ScienceMesh: Account deletion canceled", accountDeletionCanceledTemplate}
+	siteNotificationMessage          = message{"site-notification", "ScienceMesh: {{.Params.Subject}}", siteNotificationTemplate}
+	contactFormMessage               = message{"contact-form", "ScienceMesh: Contact form", contactFormTemplate}
+	announcementMessage              = message{"announcement", "ScienceMesh: {{.Params.Title}}", announcementTemplate}
+	alertFiringNotificationMessage   = message{"alert-firing", "ScienceMesh Alert: {{.Params.Summary}}", alertFiringNotificationTemplate}
+	alertResolvedNotificationMessage = message{"alert-resolved", "ScienceMesh Alert: {{.Params.Summary}} [RESOLVED]", alertResolvedNotificationTemplate}
+)
+
+// loadMessage loads the templates of a message in the given language from the templates directory.
+// The first language providing a body for the message is used, falling back to the base language (e.g., de for de-ch), the default language and finally the built-in templates.
+func loadMessage(msg message, language string, conf config.Configuration) (*messageContent, error) {
+	content := &messageContent{
+		subject: msg.subject,
+		body:    msg.body,
+	}
+
+	if conf.Email.TemplatesDirectory == "" {
+		return content, nil
+	}
+
+	for _, locale := range getLocales(language, conf.Email.DefaultLanguage) {
+		dir := filepath.Join(conf.Email.TemplatesDirectory, locale)
+
+		body, bodyFound, err := readTemplateFile(dir, msg.name+bodyExtension)
+		if err != nil {
+			return nil, err
+		}
+		htmlBody, htmlBodyFound, err := readTemplateFile(dir, msg.name+htmlBodyExtension)
+		if err != nil {
+			return nil, err
+		}
+		if !bodyFound && !htmlBodyFound {
+			continue
+		}
+
+		if bodyFound {
+			content.body = body
+		}
+		content.htmlBody = htmlBody
+
+		subject, subjectFound, err := readTemplateFile(dir, msg.name+subjectExtension)
+		if err != nil {
+			return nil, err
+		}
+		if subjectFound {
+			content.subject = subject
+		}
+		break
+	}
+
+	return content, nil
+}
+
+func readTemplateFile(dir string, name string) (string, bool, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		return "", false, errors.Wrapf(err, "unable to read template file %v", name)
+	}
+	return string(content), true, nil
+}
+
+// getLocales returns the locales to look for templates in, ordered by their priority.
+func getLocales(languages ...string) []string {
+	locales := make([]string, 0, 4)
+	for _, language := range languages {
+		// Invalid languages are skipped, which also keeps them from pointing outside of the templates directory
+		locale := data.NormalizeLanguage(language)
+		for locale != "" {
+			if !containsLocale(locales, locale) {
+				locales = append(locales, locale)
+			}
+
+			if idx := strings.LastIndex(locale, "-"); idx != -1 {
+				locale = locale[:idx]
+			} else {
+				locale = ""
+			}
+		}
+	}
+	return locales
+}
+
+func containsLocale(locales []string, locale string) bool {
+	for _, l := range locales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// AvailableLanguages returns all languages emails can be sent in; these are the default language and all languages found in the templates directory.
+func AvailableLanguages(conf config.Configuration) []string {
+	languages := make([]string, 0, 5)
+	if language := data.NormalizeLanguage(conf.Email.DefaultLanguage); language != "" {
+		languages = append(languages, language)
+	}
+
+	if conf.Email.TemplatesDirectory != "" {
+		if entries, err := ioutil.ReadDir(conf.Email.TemplatesDirectory); err == nil {
+			for _, entry := range entries {
+				if !entry.IsDir() {
+					continue
+				}
+				if language := data.NormalizeLanguage(entry.Name()); language == entry.Name() && !containsLocale(languages, language) {
+					languages = append(languages, language)
+				}
+			}
+		}
+	}
+
+	sort.Strings(languages)
+	return languages
+}
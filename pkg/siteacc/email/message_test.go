@@ -0,0 +1,173 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package email
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+)
+
+func writeTemplates(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGetLocales(t *testing.T) {
+	tests := []struct {
+		languages []string
+		expected  []string
+	}{
+		{[]string{"de_CH", "en"}, []string{"de-ch", "de", "en"}},
+		{[]string{"en-gb", "en"}, []string{"en-gb", "en"}},
+		{[]string{"", "en"}, []string{"en"}},
+		{[]string{"../../etc", "fr"}, []string{"fr"}},
+	}
+
+	for _, tt := range tests {
+		if locales := getLocales(tt.languages...); fmt.Sprint(locales) != fmt.Sprint(tt.expected) {
+			t.Errorf("%v: expected %v, got %v", tt.languages, tt.expected, locales)
+		}
+	}
+}
+
+func TestLoadMessage(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"de/password-reset.subject": "Passwort zurückgesetzt",
+		"de/password-reset.txt":     "Hallo {{.Account.FirstName}}",
+		"de-ch/password-reset.html": "<p>Grüezi {{.Account.FirstName}}</p>",
+		"en/password-reset.txt":     "Hello {{.Account.FirstName}}",
+	})
+	conf := config.Configuration{}
+	conf.Email.TemplatesDirectory = dir
+	conf.Email.DefaultLanguage = "en"
+
+	tests := []struct {
+		language string
+		subject  string
+		body     string
+		htmlBody string
+	}{
+		// Only the HTML body is overridden for de-ch, so the built-in subject and text body are used
+		{"de-ch", passwordResetMessage.subject, passwordResetMessage.body, "<p>Grüezi {{.Account.FirstName}}</p>"},
+		{"de", "Passwort zurückgesetzt", "Hallo {{.Account.FirstName}}", ""},
+		{"fr", passwordResetMessage.subject, "Hello {{.Account.FirstName}}", ""},
+		{"", passwordResetMessage.subject, "Hello {{.Account.FirstName}}", ""},
+	}
+
+	for _, tt := range tests {
+		content, err := loadMessage(passwordResetMessage, tt.language, conf)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tt.language, err)
+		}
+		if content.subject != tt.subject || content.body != tt.body || content.htmlBody != tt.htmlBody {
+			t.Errorf("%q: unexpected content %+v", tt.language, content)
+		}
+	}
+
+	// Messages without templates use the built-in ones
+	content, err := loadMessage(contactFormMessage, "de", conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content.subject != contactFormMessage.subject || content.body != contactFormMessage.body {
+		t.Errorf("expected the built-in templates, got %+v", content)
+	}
+}
+
+func TestAvailableLanguages(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"de/password-reset.txt":    "",
+		"fr-ch/password-reset.txt": "",
+		"Invalid_Dir/readme.txt":   "",
+		"readme.txt":               "",
+	})
+	conf := config.Configuration{}
+	conf.Email.TemplatesDirectory = dir
+	conf.Email.DefaultLanguage = "en"
+
+	if languages := AvailableLanguages(conf); fmt.Sprint(languages) != "[de en fr-ch]" {
+		t.Errorf("unexpected languages %v", languages)
+	}
+
+	conf.Email.TemplatesDirectory = ""
+	if languages := AvailableLanguages(conf); fmt.Sprint(languages) != "[en]" {
+		t.Errorf("expected only the default language without templates, got %v", languages)
+	}
+}
+
+func TestExecuteTemplates(t *testing.T) {
+	tplData := getEmailData(&data.Account{FirstName: "<John>"}, config.Configuration{}, map[string]string{"Message": "a\nb"})
+
+	text, err := executeTextTemplate("Hello {{.Account.FirstName}}\n{{indent 2 .Params.Message}}", tplData)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text != "Hello <John>\n  a\n  b" {
+		t.Errorf("unexpected text %q", text)
+	}
+
+	html, err := executeHTMLTemplate("<p>Hello {{.Account.FirstName}}</p>", tplData)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(html, "&lt;John&gt;") {
+		t.Errorf("expected the HTML body to be escaped, got %q", html)
+	}
+
+	if _, err := executeTextTemplate("{{.Unknown", tplData); err == nil {
+		t.Error("expected an invalid template to fail")
+	}
+}
+
+func TestAccountDeletionConfirmTemplate(t *testing.T) {
+	conf := config.Configuration{}
+	conf.Webserver.URL = "https://accounts.example.org/"
+	params := map[string]string{"Token": "a+b", "Timeout": "24", "CoolingOff": "30"}
+	tplData := getEmailData(&data.Account{Email: "john+doe@example.org"}, conf, params)
+
+	text, err := executeTextTemplate(accountDeletionConfirmMessage.body, tplData)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	link := "https://accounts.example.org/confirm-deletion?email=john%2Bdoe%40example.org&token=a%2Bb"
+	if !strings.Contains(text, link) {
+		t.Errorf("expected the confirmation link %v, got %q", link, text)
+	}
+	if !strings.Contains(text, "within 24 hours") || !strings.Contains(text, "after 30 days") {
+		t.Errorf("expected the timeout and cooling-off period, got %q", text)
+	}
+}
@@ -32,6 +32,30 @@ Kind regards,
 The ScienceMesh Team
 `
 
+const accountVerificationTemplate = `
+Dear {{.Account.FirstName}} {{.Account.LastName}},
+
+Please verify the email address of your ScienceMesh Site Administrator Account by visiting the following page:
+{{.AccountsAddress}}verify-email?email={{urlquery .Account.Email}}&token={{urlquery .Params.Token}}
+
+You will be able to log in to your account once its email address has been verified.
+
+Kind regards,
+The ScienceMesh Team
+`
+
+const accountApprovedTemplate = `
+Dear {{.Account.FirstName}} {{.Account.LastName}},
+
+Your ScienceMesh Site Administrator Account has been approved by an administrator.
+
+Log in to your account by visiting the user account panel:
+{{.AccountsAddress}}
+
+Kind regards,
+The ScienceMesh Team
+`
+
 const sitesAccessGrantedTemplate = `
 Dear {{.Account.FirstName}} {{.Account.LastName}},
 
@@ -44,6 +68,18 @@ Kind regards,
 The ScienceMesh Team
 `
 
+const siteManagementGrantedTemplate = `
+Dear {{.Account.FirstName}} {{.Account.LastName}},
+
+{{.Params.GrantedBy}} has granted you the rights to manage the site {{if .Params.SiteName}}{{.Params.SiteName}} ({{.Params.Site}}){{else}}{{.Params.Site}}{{end}}.
+
+Log in to your account to access the configuration of this site:
+{{.AccountsAddress}} 
+
+Kind regards,
+The ScienceMesh Team
+`
+
 const gocdbAccessGrantedTemplate = `
 Dear {{.Account.FirstName}} {{.Account.LastName}},
 
@@ -68,6 +104,72 @@ Kind regards,
 The ScienceMesh Team
 `
 
+const accountDeletionConfirmTemplate = `
+Dear {{.Account.FirstName}} {{.Account.LastName}},
+
+We have received your request to delete your ScienceMesh Site Administrator Account.
+
+Please confirm the deletion within {{.Params.Timeout}} hours by visiting the following page:
+{{.AccountsAddress}}confirm-deletion?email={{urlquery .Account.Email}}&token={{urlquery .Params.Token}}
+
+Your account will then be disabled and permanently deleted after {{.Params.CoolingOff}} days.
+
+If you did not request the deletion of your account, you can ignore this email; your account remains unchanged.
+
+Kind regards,
+The ScienceMesh Team
+`
+
+const accountDeletionRequestedTemplate = `
+Dear {{.Account.FirstName}} {{.Account.LastName}},
+
+You have confirmed the deletion of your ScienceMesh Site Administrator Account.
+
+Your account has been disabled and will be permanently deleted in {{.Params.CoolingOff}} days (on {{.Params.DatePurge}}).
+Until then, you can still export your account data or cancel the deletion by visiting the following page:
+{{.AccountsAddress}}account/?path=cancel-deletion&email={{urlquery .Account.Email}}&token={{urlquery .Params.Token}}
+
+If you did not request the deletion of your account, please cancel it using the link above and contact us immediately.
+
+Kind regards,
+The ScienceMesh Team
+`
+
+const accountDeletionCanceledTemplate = `
+Dear {{.Account.FirstName}} {{.Account.LastName}},
+
+The deletion of your ScienceMesh Site Administrator Account has been canceled, and your account has been re-enabled.
+
+Log in to your account by visiting the user account panel:
+{{.AccountsAddress}}
+
+Kind regards,
+The ScienceMesh Team
+`
+
+const siteNotificationTemplate = `
+Dear {{.Account.FirstName}} {{.Account.LastName}},
+
+{{.Params.Message}}
+
+You can change which notifications you receive in the notification settings of your account:
+{{.AccountsAddress}}account/?path=notifications
+
+Kind regards,
+The ScienceMesh Team
+`
+
+const announcementTemplate = `
+Dear {{.Account.FirstName}} {{.Account.LastName}},
+
+{{.Params.Message}}
+{{if .Params.Period}}
+This announcement applies {{.Params.Period}}.
+{{end}}
+Kind regards,
+The ScienceMesh Team
+`
+
 const contactFormTemplate = `
 {{.Account.FirstName}} {{.Account.LastName}} ({{.Account.Email}}) has sent the following message:
 
@@ -19,23 +19,44 @@
 package siteacc
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/cs3org/reva/pkg/announcements"
+	"github.com/cs3org/reva/pkg/auth/loginguard"
+	"github.com/cs3org/reva/pkg/mentix/exchangers/exporters/webhook"
+	"github.com/cs3org/reva/pkg/mentix/exchangers/importers/siteupdate"
+	"github.com/cs3org/reva/pkg/mentix/usage"
 	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/credentials"
 	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/cs3org/reva/pkg/siteacc/email"
 	"github.com/cs3org/reva/pkg/siteacc/html"
+	"github.com/cs3org/reva/pkg/siteacc/html/qrcode"
 	"github.com/cs3org/reva/pkg/siteacc/manager"
+	"github.com/cs3org/reva/pkg/siteacc/metrics"
 	"github.com/pkg/errors"
 	"github.com/prometheus/alertmanager/template"
 )
 
 const (
 	invokerUser = "user"
+
+	// twoFactorIssuer is the issuer shown by authenticator apps.
+	twoFactorIssuer = "ScienceMesh"
+
+	// oidcLoginTimeout is the time users have to login at the OpenID Connect provider.
+	oidcLoginTimeout = 10 * time.Minute
 )
 
 type methodCallback = func(*SiteAccounts, url.Values, []byte, *html.Session) (interface{}, error)
@@ -62,6 +83,13 @@ func createMethodCallbacks(cbGet methodCallback, cbPost methodCallback) map[stri
 	return callbacks
 }
 
+// signedEndpoints are the endpoints called by Mentix; if signing keys have been configured, requests to these need to be signed.
+var signedEndpoints = map[string]bool{
+	config.EndpointVerifySiteKey:      true,
+	config.EndpointDispatchSiteEvents: true,
+	config.EndpointSitesHealth:        true,
+}
+
 func getEndpoints() []endpoint {
 	endpoints := []endpoint{
 		// Form/panel endpoints
@@ -74,22 +102,69 @@ func getEndpoints() []endpoint {
 		{config.EndpointUpdate, callMethodEndpoint, createMethodCallbacks(nil, handleUpdate), false},
 		{config.EndpointConfigure, callMethodEndpoint, createMethodCallbacks(nil, handleConfigure), false},
 		{config.EndpointRemove, callMethodEndpoint, createMethodCallbacks(nil, handleRemove), false},
+		// Account lifecycle endpoints
+		{config.EndpointApprove, callMethodEndpoint, createMethodCallbacks(nil, handleApprove), false},
+		{config.EndpointSuspend, callMethodEndpoint, createMethodCallbacks(nil, handleSuspend), false},
+		{config.EndpointResendVerification, callMethodEndpoint, createMethodCallbacks(nil, handleResendVerification), false},
+		{config.EndpointVerifyEmail, callVerifyEmailEndpoint, nil, true},
+		{config.EndpointImportContacts, callMethodEndpoint, createMethodCallbacks(nil, handleImportContacts), false},
+		{config.EndpointAuditEvents, callMethodEndpoint, createMethodCallbacks(handleAuditEvents, nil), false},
+		{config.EndpointLockouts, callMethodEndpoint, createMethodCallbacks(handleLockouts, nil), false},
+		{config.EndpointUnlock, callMethodEndpoint, createMethodCallbacks(nil, handleUnlock), false},
+		// Announcement endpoints
+		{config.EndpointAnnouncements, callMethodEndpoint, createMethodCallbacks(handleAnnouncements, nil), false},
+		{config.EndpointAnnouncementPost, callMethodEndpoint, createMethodCallbacks(nil, handleAnnouncementPost), false},
+		{config.EndpointAnnouncementRemove, callMethodEndpoint, createMethodCallbacks(nil, handleAnnouncementRemove), false},
 		// Site endpoints
 		{config.EndpointSiteGet, callMethodEndpoint, createMethodCallbacks(handleSiteGet, nil), false},
+		{config.EndpointSiteData, callMethodEndpoint, createMethodCallbacks(handleSiteData, nil), true},
+		{config.EndpointSiteUpdate, callMethodEndpoint, createMethodCallbacks(nil, handleSiteUpdate), false},
+		{config.EndpointSiteUsage, callMethodEndpoint, createMethodCallbacks(handleSiteUsage, nil), true},
+		{config.EndpointSiteUsageExport, callSiteUsageExportEndpoint, nil, true},
+		{config.EndpointIssueSiteKey, callMethodEndpoint, createMethodCallbacks(nil, handleIssueSiteKey), true},
+		{config.EndpointSiteConfigure, callMethodEndpoint, createMethodCallbacks(nil, handleSiteConfigure), true},
+		{config.EndpointSitesExport, callSitesExportEndpoint, nil, true},
+		{config.EndpointSitesImport, callMethodEndpoint, createMethodCallbacks(nil, handleSitesImport), true},
+		{config.EndpointGrantSiteManagement, callMethodEndpoint, createMethodCallbacks(nil, handleGrantSiteManagement), true},
+		{config.EndpointSitesHealth, callMethodEndpoint, createMethodCallbacks(handleSitesHealth, nil), true},
+		{config.EndpointCheckSitesHealth, callMethodEndpoint, createMethodCallbacks(nil, handleCheckSitesHealth), false},
+		// API key endpoints
+		{config.EndpointAPIKeysList, callMethodEndpoint, createMethodCallbacks(handleAPIKeysList, nil), true},
+		{config.EndpointAPIKeysCreate, callMethodEndpoint, createMethodCallbacks(nil, handleAPIKeysCreate), true},
+		{config.EndpointAPIKeysRotate, callMethodEndpoint, createMethodCallbacks(nil, handleAPIKeysRotate), true},
+		{config.EndpointAPIKeysRevoke, callMethodEndpoint, createMethodCallbacks(nil, handleAPIKeysRevoke), true},
 		// Sites endpoints
 		{config.EndpointSitesConfigure, callMethodEndpoint, createMethodCallbacks(nil, handleSitesConfigure), false},
+		{config.EndpointOperatorConfigure, callMethodEndpoint, createMethodCallbacks(nil, handleOperatorConfigure), false},
 		// Login endpoints
 		{config.EndpointLogin, callMethodEndpoint, createMethodCallbacks(nil, handleLogin), true},
+		{config.EndpointVerifyTwoFactor, callMethodEndpoint, createMethodCallbacks(nil, handleVerifyTwoFactor), true},
+		{config.EndpointOIDCLogin, callOIDCLoginEndpoint, nil, true},
+		{config.EndpointOIDCCallback, callOIDCCallbackEndpoint, nil, true},
 		{config.EndpointLogout, callMethodEndpoint, createMethodCallbacks(handleLogout, nil), true},
 		{config.EndpointResetPassword, callMethodEndpoint, createMethodCallbacks(nil, handleResetPassword), true},
 		{config.EndpointContact, callMethodEndpoint, createMethodCallbacks(nil, handleContact), true},
+		// Two-factor authentication endpoints
+		{config.EndpointEnrollTwoFactor, callMethodEndpoint, createMethodCallbacks(nil, handleEnrollTwoFactor), true},
+		{config.EndpointEnableTwoFactor, callMethodEndpoint, createMethodCallbacks(nil, handleEnableTwoFactor), true},
+		{config.EndpointDisableTwoFactor, callMethodEndpoint, createMethodCallbacks(nil, handleDisableTwoFactor), true},
+		// Notification endpoints
+		{config.EndpointConfigureNotifications, callMethodEndpoint, createMethodCallbacks(nil, handleConfigureNotifications), true},
+		{config.EndpointClearNotifications, callMethodEndpoint, createMethodCallbacks(nil, handleClearNotifications), true},
+		// Account deletion endpoints
+		{config.EndpointRequestDeletion, callMethodEndpoint, createMethodCallbacks(nil, handleRequestDeletion), true},
+		{config.EndpointConfirmDeletion, callConfirmDeletionEndpoint, nil, true},
+		{config.EndpointCancelDeletion, callMethodEndpoint, createMethodCallbacks(nil, handleCancelDeletion), true},
+		{config.EndpointExportAccount, callExportAccountEndpoint, nil, true},
 		// Authentication endpoints
 		{config.EndpointVerifyUserToken, callMethodEndpoint, createMethodCallbacks(handleVerifyUserToken, nil), true},
+		{config.EndpointVerifySiteKey, callMethodEndpoint, createMethodCallbacks(nil, handleVerifySiteKey), true},
 		// Access management endpoints
 		{config.EndpointGrantSitesAccess, callMethodEndpoint, createMethodCallbacks(nil, handleGrantSitesAccess), false},
 		{config.EndpointGrantGOCDBAccess, callMethodEndpoint, createMethodCallbacks(nil, handleGrantGOCDBAccess), false},
 		// Alerting endpoints
 		{config.EndpointDispatchAlert, callMethodEndpoint, createMethodCallbacks(nil, handleDispatchAlert), false},
+		{config.EndpointDispatchSiteEvents, callMethodEndpoint, createMethodCallbacks(nil, handleDispatchSiteEvents), false},
 	}
 
 	return endpoints
@@ -97,16 +172,123 @@ func getEndpoints() []endpoint {
 
 func callAdministrationEndpoint(siteacc *SiteAccounts, ep endpoint, w http.ResponseWriter, r *http.Request, session *html.Session) {
 	if err := siteacc.ShowAdministrationPanel(w, r, session); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_, _ = w.Write([]byte(fmt.Sprintf("Unable to show the administration panel: %v", err)))
+		siteacc.log.Err(err).Msg("unable to show the administration panel")
+		siteacc.writeError(w, r, http.StatusInternalServerError, "Unable to show the administration panel.")
 	}
 }
 
 func callAccountEndpoint(siteacc *SiteAccounts, ep endpoint, w http.ResponseWriter, r *http.Request, session *html.Session) {
 	if err := siteacc.ShowAccountPanel(w, r, session); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_, _ = w.Write([]byte(fmt.Sprintf("Unable to show the account panel: %v", err)))
+		siteacc.log.Err(err).Msg("unable to show the account panel")
+		siteacc.writeError(w, r, http.StatusInternalServerError, "Unable to show the account panel.")
+	}
+}
+
+func callOIDCLoginEndpoint(siteacc *SiteAccounts, ep endpoint, w http.ResponseWriter, r *http.Request, session *html.Session) {
+	if !siteacc.OIDCManager().Enabled() {
+		siteacc.writeError(w, r, http.StatusBadRequest, "Logging in through OpenID Connect is not enabled.")
+		return
+	}
+
+	// State and nonce tie the response of the provider to this session
+	var nonce, authURL string
+	state, err := generateRandomString()
+	if err == nil {
+		nonce, err = generateRandomString()
+	}
+	if err == nil {
+		authURL, err = siteacc.OIDCManager().AuthCodeURL(state, nonce)
+	}
+	if err != nil {
+		siteacc.log.Err(err).Msg("unable to start the OIDC login")
+		siteacc.writeError(w, r, http.StatusInternalServerError, "Unable to start the login.")
+		return
+	}
+	session.BeginOIDCLogin(state, nonce, r.URL.Query().Get("scope"), oidcLoginTimeout)
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func callOIDCCallbackEndpoint(siteacc *SiteAccounts, ep endpoint, w http.ResponseWriter, r *http.Request, session *html.Session) {
+	serverAddress := strings.TrimRight(siteacc.conf.Webserver.URL, "/")
+	redirectError := func(err error) {
+		http.Redirect(w, r, serverAddress+"/account/?path=login&error="+url.QueryEscape(err.Error()), http.StatusFound)
+	}
+
+	values := r.URL.Query()
+	login := session.TakeOIDCLogin(values.Get("state"))
+	if login == nil {
+		redirectError(errors.Errorf("the login is invalid or has expired; please try again"))
+		return
+	}
+	if errMsg := values.Get("error"); errMsg != "" {
+		if desc := values.Get("error_description"); desc != "" {
+			errMsg = desc
+		}
+		redirectError(errors.Errorf("the provider denied the login: %v", errMsg))
+		return
+	}
+
+	identity, err := siteacc.OIDCManager().Exchange(r.Context(), values.Get("code"), login.Nonce)
+	if err != nil {
+		siteacc.log.Err(err).Msg("OIDC login failed")
+		redirectError(errors.Wrap(err, "unable to verify your identity"))
+		return
+	}
+
+	_, twoFactor, err := siteacc.UsersManager().LoginOIDCUser(identity, login.Scope, session)
+	siteacc.auditOIDCLogin(identity.Email, session, err)
+	if err != nil || !twoFactor {
+		metrics.RecordLogin(metrics.MethodOIDC, err)
+	}
+	if err != nil {
+		redirectError(errors.Wrap(err, "unable to login user"))
+		return
+	}
+
+	// If two-factor authentication is enabled, the login page asks for the second factor
+	if twoFactor {
+		http.Redirect(w, r, serverAddress+"/account/?path=login&login=2fa", http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, serverAddress+"/account/?path=manage", http.StatusFound)
+}
+
+func callVerifyEmailEndpoint(siteacc *SiteAccounts, ep endpoint, w http.ResponseWriter, r *http.Request, session *html.Session) {
+	serverAddress := strings.TrimRight(siteacc.conf.Webserver.URL, "/")
+
+	values := r.URL.Query()
+	if err := siteacc.AccountsManager().VerifyEmail(values.Get("email"), values.Get("token")); err != nil {
+		http.Redirect(w, r, serverAddress+"/account/?path=login&error="+url.QueryEscape(fmt.Sprintf("unable to verify your email address: %v", err)), http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, serverAddress+"/account/?path=login&verified=true", http.StatusFound)
+}
+
+func callConfirmDeletionEndpoint(siteacc *SiteAccounts, ep endpoint, w http.ResponseWriter, r *http.Request, session *html.Session) {
+	serverAddress := strings.TrimRight(siteacc.conf.Webserver.URL, "/")
+
+	values := r.URL.Query()
+	auditRecord := siteacc.beginAudit(ep, r, nil, session)
+	if auditRecord != nil {
+		auditRecord.event.Account = values.Get("email")
+		auditRecord.event.Details = "confirmed"
+	}
+	purgeDate, err := siteacc.AccountsManager().ConfirmDeletion(values.Get("email"), values.Get("token"))
+	siteacc.finishAudit(auditRecord, session, err)
+	if err != nil {
+		http.Redirect(w, r, serverAddress+"/account/?path=login&error="+url.QueryEscape(fmt.Sprintf("unable to confirm the deletion of your account: %v", err)), http.StatusFound)
+		return
+	}
+
+	// The account is disabled now, so the user needs to be logged out
+	if session.IsUserLoggedIn() && strings.EqualFold(session.LoggedInUser().Account.Email, values.Get("email")) {
+		siteacc.UsersManager().LogoutUser(session)
 	}
+
+	http.Redirect(w, r, serverAddress+"/account/?path=login&deleted="+url.QueryEscape(purgeDate.Format("2006-01-02")), http.StatusFound)
 }
 
 func callMethodEndpoint(siteacc *SiteAccounts, ep endpoint, w http.ResponseWriter, r *http.Request, session *html.Session) {
@@ -115,6 +297,9 @@ func callMethodEndpoint(siteacc *SiteAccounts, ep endpoint, w http.ResponseWrite
 		Success bool        `json:"success"`
 		Error   string      `json:"error,omitempty"`
 		Data    interface{} `json:"data,omitempty"`
+
+		// Violations lists the violated rules if a password didn't fulfil the password policy.
+		Violations []credentials.PolicyViolation `json:"violations,omitempty"`
 	}
 
 	// The default response is an unknown requestHandler (for the specified method)
@@ -130,7 +315,11 @@ func callMethodEndpoint(siteacc *SiteAccounts, ep endpoint, w http.ResponseWrite
 			if method == r.Method {
 				body, _ := ioutil.ReadAll(r.Body)
 
-				if respData, err := cb(siteacc, r.URL.Query(), body, session); err == nil {
+				auditRecord := siteacc.beginAudit(ep, r, body, session)
+				respData, err := cb(siteacc, r.URL.Query(), body, session)
+				siteacc.finishAudit(auditRecord, session, err)
+
+				if err == nil {
 					resp.Success = true
 					resp.Error = ""
 					resp.Data = respData
@@ -138,6 +327,11 @@ func callMethodEndpoint(siteacc *SiteAccounts, ep endpoint, w http.ResponseWrite
 					resp.Success = false
 					resp.Error = fmt.Sprintf("%v", err)
 					resp.Data = nil
+
+					var policyErr *credentials.PolicyError
+					if errors.As(err, &policyErr) {
+						resp.Violations = policyErr.Violations
+					}
 				}
 			}
 		}
@@ -173,8 +367,27 @@ func handleCreate(siteacc *SiteAccounts, values url.Values, body []byte, session
 		return nil, err
 	}
 
+	// Every registration counts as an attempt, limiting how many accounts can be registered from an IP or for an email address
+	if err := checkAttempt(siteacc.registrationGuard, session, account.Email); err != nil {
+		return nil, err
+	}
+	failAttempt(siteacc.registrationGuard, session, account.Email)
+
+	if siteacc.captchaVerifier != nil {
+		captchaData := struct {
+			Captcha string `json:"captcha"`
+		}{}
+		_ = json.Unmarshal(body, &captchaData)
+		if err := siteacc.captchaVerifier.Verify(captchaData.Captcha, session.RemoteAddress); err != nil {
+			metrics.RecordRegistration(err)
+			return nil, err
+		}
+	}
+
 	// Create a new account through the accounts manager
-	if err := siteacc.AccountsManager().CreateAccount(account); err != nil {
+	err = siteacc.AccountsManager().CreateAccount(account)
+	metrics.RecordRegistration(err)
+	if err != nil {
 		return nil, errors.Wrap(err, "unable to create account")
 	}
 
@@ -235,210 +448,1191 @@ func handleRemove(siteacc *SiteAccounts, values url.Values, body []byte, session
 	return nil, nil
 }
 
-func handleSiteGet(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
-	siteID := values.Get("site")
-	if siteID == "" {
-		return nil, errors.Errorf("no site specified")
+func handleApprove(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	account, err := unmarshalRequestData(body)
+	if err != nil {
+		return nil, err
 	}
-	_, site := siteacc.OperatorsManager().FindSite(siteID)
-	if site == nil {
-		return nil, errors.Errorf("no site with ID %v exists", siteID)
+
+	// Approve the account through the accounts manager
+	if err := siteacc.AccountsManager().ApproveAccount(account); err != nil {
+		return nil, errors.Wrap(err, "unable to approve account")
 	}
-	return map[string]interface{}{"site": site.Clone(false)}, nil
+
+	return nil, nil
 }
 
-func handleSitesConfigure(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
-	email, _, err := processInvoker(siteacc, values, session)
-	if err != nil {
-		return nil, err
-	}
-	account, err := siteacc.AccountsManager().FindAccount(manager.FindByEmail, email)
+func handleSuspend(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	account, err := unmarshalRequestData(body)
 	if err != nil {
 		return nil, err
 	}
 
-	sitesData := &[]*data.Site{}
-	if err := json.Unmarshal(body, sitesData); err != nil {
-		return nil, errors.Wrap(err, "invalid form data")
-	}
+	var suspend bool
+	switch val := strings.ToLower(values.Get("status")); val {
+	case "true":
+		suspend = true
 
-	// Configure the sites through the operators manager
-	opData := &data.Operator{
-		ID:    account.Operator,
-		Sites: *sitesData,
+	case "false":
+		suspend = false
+
+	case "":
+		return nil, errors.Errorf("no suspension status provided")
+
+	default:
+		return nil, errors.Errorf("unsupported suspension status %v", val)
 	}
-	if err := siteacc.OperatorsManager().UpdateOperator(opData); err != nil {
-		return nil, errors.Wrap(err, "unable to configure operator")
+
+	// Disable or re-enable the account through the accounts manager
+	if err := siteacc.AccountsManager().SuspendAccount(account, suspend); err != nil {
+		return nil, errors.Wrap(err, "unable to change the suspension status of the account")
 	}
 
 	return nil, nil
 }
 
-func handleLogin(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+func handleResendVerification(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
 	account, err := unmarshalRequestData(body)
 	if err != nil {
 		return nil, err
 	}
 
-	// Login the user through the users manager
-	token, err := siteacc.UsersManager().LoginUser(account.Email, account.Password.Value, values.Get("scope"), session)
-	if err != nil {
-		return nil, errors.Wrap(err, "unable to login user")
+	// Send a new verification email through the accounts manager
+	if err := siteacc.AccountsManager().ResendVerification(account); err != nil {
+		return nil, errors.Wrap(err, "unable to resend the verification email")
 	}
 
-	return token, nil
-}
-
-func handleLogout(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
-	// Logout the user through the users manager
-	siteacc.UsersManager().LogoutUser(session)
 	return nil, nil
 }
 
-func handleResetPassword(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
-	account, err := unmarshalRequestData(body)
+func handleImportContacts(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	importer := siteacc.ContactsImporter()
+	if importer == nil {
+		return nil, errors.Errorf("no contacts source configured")
+	}
+
+	dryRun := strings.EqualFold(values.Get("dryrun"), "true")
+
+	// Import the contacts now, regardless of any periodic imports
+	report, err := importer.Run(context.Background(), dryRun)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(err, "unable to import contacts")
 	}
+	return map[string]interface{}{"report": report}, nil
+}
 
-	// Reset the password through the users manager
-	if err := siteacc.AccountsManager().ResetPassword(account.Email); err != nil {
-		return nil, errors.Wrap(err, "unable to reset password")
+func handleAuditEvents(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	limit := 0
+	if val := values.Get("limit"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 0 {
+			return nil, errors.Errorf("invalid limit %v", val)
+		}
+		limit = n
 	}
 
-	return nil, nil
+	return map[string]interface{}{"events": siteacc.Auditor().Events(values.Get("account"), limit)}, nil
 }
 
-func handleContact(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
-	if !session.IsUserLoggedIn() {
-		return nil, errors.Errorf("no user is currently logged in")
+func handleLockouts(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	lockouts := []*loginguard.Block{}
+	for _, guard := range siteacc.guards() {
+		lockouts = append(lockouts, guard.Blocks()...)
 	}
+	return map[string]interface{}{"lockouts": lockouts}, nil
+}
 
-	type jsonData struct {
-		Subject string `json:"subject"`
-		Message string `json:"message"`
+func handleUnlock(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	key := loginguard.Key{Kind: values.Get("kind"), Value: values.Get("value")}
+	if key.Kind != loginguard.KindIP && key.Kind != loginguard.KindAccount {
+		return nil, errors.Errorf("invalid kind %v", key.Kind)
 	}
-	contactData := &jsonData{}
-	if err := json.Unmarshal(body, contactData); err != nil {
-		return nil, errors.Wrap(err, "invalid form data")
+	if key.Kind == loginguard.KindAccount {
+		key.Value = strings.ToLower(key.Value)
 	}
 
-	// Send an email through the accounts manager
-	siteacc.AccountsManager().SendContactForm(session.LoggedInUser().Account, strings.TrimSpace(contactData.Subject), strings.TrimSpace(contactData.Message))
+	// The lockout is lifted in all guards, both for logins and registrations
+	unlocked := false
+	for _, guard := range siteacc.guards() {
+		if guard.Clear(key) {
+			unlocked = true
+		}
+	}
+	if !unlocked {
+		return nil, errors.Errorf("no failed attempts recorded for %v %v", key.Kind, key.Value)
+	}
 	return nil, nil
 }
 
-func handleVerifyUserToken(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
-	token := values.Get("token")
-	if token == "" {
-		return nil, errors.Errorf("no token specified")
+func handleAnnouncements(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	list, err := siteacc.AnnouncementsManager().List()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list the announcements")
 	}
+	return map[string]interface{}{"announcements": list}, nil
+}
 
-	user := values.Get("user")
-	if user == "" {
-		return nil, errors.Errorf("no user specified")
+func handleAnnouncementPost(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	annData := &struct {
+		Title    string `json:"title"`
+		Message  string `json:"message"`
+		Severity string `json:"severity"`
+		Start    string `json:"start"`
+		End      string `json:"end"`
+		Email    bool   `json:"email"`
+	}{}
+	if err := json.Unmarshal(body, annData); err != nil {
+		return nil, errors.Wrap(err, "invalid form data")
 	}
 
-	// Verify the user token using the users manager
-	newToken, err := siteacc.UsersManager().VerifyUserToken(token, user, values.Get("scope"))
+	// Start and end are given in RFC 3339 format; without a start, the announcement is shown right away, without an end, until it is removed
+	var start, end time.Time
+	for _, t := range []struct {
+		value string
+		time  *time.Time
+	}{{annData.Start, &start}, {annData.End, &end}} {
+		if t.value == "" {
+			continue
+		}
+		v, err := time.Parse(time.RFC3339, t.value)
+		if err != nil {
+			return nil, errors.Errorf("invalid date %v", t.value)
+		}
+		*t.time = v
+	}
+
+	author := ""
+	if session.IsUserLoggedIn() {
+		author = session.LoggedInUser().Account.Email
+	}
+	a, err := announcements.New(annData.Title, annData.Message, annData.Severity, start, end, author)
 	if err != nil {
-		return nil, errors.Wrap(err, "token verification failed")
+		return nil, err
+	}
+	if err := siteacc.AnnouncementsManager().Post(a); err != nil {
+		return nil, errors.Wrap(err, "unable to post the announcement")
 	}
 
-	return newToken, nil
+	// Emails are sent in the background, as there might be many accounts to notify
+	if annData.Email {
+		go siteacc.AccountsManager().SendAnnouncement(a)
+	}
+	return map[string]interface{}{"announcement": a}, nil
 }
 
-func handleDispatchAlert(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
-	alertsData := &template.Data{}
-	if err := json.Unmarshal(body, alertsData); err != nil {
-		return nil, errors.Wrap(err, "unable to unmarshal the alerts data")
+func handleAnnouncementRemove(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	if values.Get("id") == "" {
+		return nil, errors.Errorf("no announcement specified")
 	}
 
-	// Dispatch the alerts using the alerts dispatcher
-	if err := siteacc.AlertsDispatcher().DispatchAlerts(alertsData, siteacc.AccountsManager().CloneAccounts(true)); err != nil {
-		return nil, errors.Wrap(err, "error while dispatching the alerts")
+	if err := siteacc.AnnouncementsManager().Remove(values.Get("id")); err != nil {
+		return nil, errors.Wrap(err, "unable to remove the announcement")
 	}
-
 	return nil, nil
 }
 
-func handleGrantSitesAccess(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
-	return handleGrantAccess((*manager.AccountsManager).GrantSitesAccess, siteacc, values, body, session)
+func handleSiteGet(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	siteID := values.Get("site")
+	if siteID == "" {
+		return nil, errors.Errorf("no site specified")
+	}
+	_, site := siteacc.OperatorsManager().FindSite(siteID)
+	if site == nil {
+		return nil, errors.Errorf("no site with ID %v exists", siteID)
+	}
+	return map[string]interface{}{"site": site.Clone(false)}, nil
 }
 
-func handleGrantGOCDBAccess(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
-	return handleGrantAccess((*manager.AccountsManager).GrantGOCDBAccess, siteacc, values, body, session)
+func handleSiteData(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	_, siteID, err := checkSiteAccess(siteacc, values, session)
+	if err != nil {
+		return nil, err
+	}
+
+	// Query the current site data using Mentix
+	siteData, err := data.QuerySiteData(siteID, siteacc.conf.Mentix.URL, siteacc.conf.Mentix.DataEndpoint, &siteacc.conf.Mentix.Signing)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to query the site data")
+	}
+	return map[string]interface{}{"site": siteData}, nil
 }
 
-func handleGrantAccess(accessSetter accessSetterCallback, siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
-	account, err := unmarshalRequestData(body)
+func handleSiteUpdate(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	_, siteID, err := checkSiteAccess(siteacc, values, session)
 	if err != nil {
 		return nil, err
 	}
 
-	if val := values.Get("status"); len(val) > 0 {
-		var grantAccess bool
-		switch strings.ToLower(val) {
-		case "true":
-			grantAccess = true
+	req := &siteupdate.Request{}
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, errors.Wrap(err, "invalid form data")
+	}
+	req.SiteID = siteID
 
-		case "false":
-			grantAccess = false
+	dryRun := strings.EqualFold(values.Get("dryrun"), "true")
 
-		default:
-			return nil, errors.Errorf("unsupported access status %v", val[0])
-		}
+	// Write the changes back to the GOCDB using Mentix
+	resp, err := data.UpdateSiteData(req, dryRun, siteacc.conf.Mentix.URL, siteacc.conf.Mentix.SiteUpdateEndpoint, &siteacc.conf.Mentix.Signing)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to update the site")
+	}
+	return map[string]interface{}{"update": resp}, nil
+}
 
-		// Grant access to the account through the accounts manager
-		if err := accessSetter(siteacc.AccountsManager(), account, grantAccess); err != nil {
-			return nil, errors.Wrap(err, "unable to change the access status of the account")
-		}
-	} else {
-		return nil, errors.Errorf("no access status provided")
+func handleSiteUsage(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	siteID, from, to, records, err := querySiteUsage(siteacc, values, session)
+	if err != nil {
+		return nil, err
 	}
+	return map[string]interface{}{"site": siteID, "from": from, "to": to, "usage": records, "total": data.SumUsage(records)}, nil
+}
 
-	return nil, nil
+func callSiteUsageExportEndpoint(siteacc *SiteAccounts, ep endpoint, w http.ResponseWriter, r *http.Request, session *html.Session) {
+	values := r.URL.Query()
+	format := strings.ToLower(values.Get("format"))
+	if format == "" {
+		format = data.UsageFormatCSV
+	}
+	if format != data.UsageFormatCSV && format != data.UsageFormatJSON {
+		siteacc.writeError(w, r, http.StatusBadRequest, fmt.Sprintf("Unsupported format %v", format))
+		return
+	}
+
+	siteID, from, to, records, err := querySiteUsage(siteacc, values, session)
+	if err != nil {
+		siteacc.writeError(w, r, http.StatusBadRequest, fmt.Sprintf("Unable to export the site usage: %v", err))
+		return
+	}
+
+	// The usage is offered as a file download
+	contentType := "text/csv; charset=UTF-8"
+	if format == data.UsageFormatJSON {
+		contentType = "application/json; charset=UTF-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"usage-%v-%v-%v.%v\"", url.PathEscape(siteID), from, to, format))
+	w.Header().Set("Cache-Control", "no-store")
+	if err := data.WriteUsageRecords(w, records, format); err != nil {
+		siteacc.log.Err(err).Msg("error while exporting the site usage")
+	}
 }
 
-func unmarshalRequestData(body []byte) (*data.Account, error) {
-	account := &data.Account{}
-	if err := json.Unmarshal(body, account); err != nil {
-		return nil, errors.Wrap(err, "invalid account data")
+func querySiteUsage(siteacc *SiteAccounts, values url.Values, session *html.Session) (string, string, string, []*usage.Record, error) {
+	_, siteID, err := checkSiteAccess(siteacc, values, session)
+	if err != nil {
+		return "", "", "", nil, err
 	}
-	account.Cleanup()
-	return account, nil
+
+	from, to, err := data.ParseUsageRange(values.Get("from"), values.Get("to"))
+	if err != nil {
+		return "", "", "", nil, err
+	}
+
+	// The usage is reported by the sites to Mentix
+	records, err := data.QuerySiteUsage(siteID, from, to, siteacc.conf.Mentix.URL, siteacc.conf.Mentix.UsageEndpoint, &siteacc.conf.Mentix.Signing)
+	if err != nil {
+		return "", "", "", nil, errors.Wrap(err, "unable to query the site usage")
+	}
+	return siteID, from, to, records, nil
 }
 
-func findAccount(siteacc *SiteAccounts, by string, value string) (*data.Account, error) {
-	if len(by) == 0 && len(value) == 0 {
-		return nil, errors.Errorf("missing search criteria")
+func handleIssueSiteKey(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	opID, siteID, err := checkSiteAccess(siteacc, values, session)
+	if err != nil {
+		return nil, err
 	}
 
-	// Find the account using the accounts manager
-	account, err := siteacc.AccountsManager().FindAccount(by, value)
+	// The key is only returned once; only its hash is stored
+	apiKey, err := siteacc.OperatorsManager().IssueSiteKey(opID, siteID)
 	if err != nil {
-		return nil, errors.Wrap(err, "user not found")
+		return nil, errors.Wrap(err, "unable to issue site key")
 	}
-	return account, nil
+	return map[string]interface{}{"key": apiKey}, nil
 }
 
-func processInvoker(siteacc *SiteAccounts, values url.Values, session *html.Session) (string, bool, error) {
-	var email string
-	var invokedByUser bool
+func handleAPIKeysList(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	account, err := checkSitesAccess(siteacc, values, session)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"keys": siteacc.OperatorsManager().CloneAPIKeys(account.Operator)}, nil
+}
 
-	switch strings.ToLower(values.Get("invoker")) {
-	case invokerUser:
-		// If this endpoint was called by the user, set the account email from the stored session
-		if !session.IsUserLoggedIn() {
-			return "", false, errors.Errorf("no user is currently logged in")
-		}
+func handleAPIKeysCreate(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	account, err := checkSitesAccess(siteacc, values, session)
+	if err != nil {
+		return nil, err
+	}
 
-		email = session.LoggedInUser().Account.Email
-		invokedByUser = true
+	keyData := &struct {
+		Name    string `json:"name"`
+		Scope   string `json:"scope"`
+		Expires string `json:"expires"`
+	}{}
+	if err := json.Unmarshal(body, keyData); err != nil {
+		return nil, errors.Wrap(err, "invalid form data")
+	}
 
-	default:
-		return "", false, errors.Errorf("no invoker provided")
+	// Keys expire at the end of the given day; without an expiry date, they remain valid until revoked
+	var expires time.Time
+	if keyData.Expires != "" {
+		date, err := time.Parse("2006-01-02", keyData.Expires)
+		if err != nil {
+			return nil, errors.Errorf("invalid expiry date %v", keyData.Expires)
+		}
+		expires = date.Add(24 * time.Hour)
 	}
 
-	return email, invokedByUser, nil
+	// The key is only returned once; only its hash is stored
+	info, apiKey, err := siteacc.OperatorsManager().IssueAPIKey(account.Operator, keyData.Name, keyData.Scope, expires, account.Email)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to issue API key")
+	}
+	return map[string]interface{}{"key": apiKey, "info": info}, nil
+}
+
+func handleAPIKeysRotate(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	account, err := checkSitesAccess(siteacc, values, session)
+	if err != nil {
+		return nil, err
+	}
+	if values.Get("id") == "" {
+		return nil, errors.Errorf("no key specified")
+	}
+
+	apiKey, err := siteacc.OperatorsManager().RotateAPIKey(account.Operator, values.Get("id"))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to rotate API key")
+	}
+	return map[string]interface{}{"key": apiKey}, nil
+}
+
+func handleAPIKeysRevoke(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	account, err := checkSitesAccess(siteacc, values, session)
+	if err != nil {
+		return nil, err
+	}
+	if values.Get("id") == "" {
+		return nil, errors.Errorf("no key specified")
+	}
+
+	if err := siteacc.OperatorsManager().RevokeAPIKey(account.Operator, values.Get("id")); err != nil {
+		return nil, errors.Wrap(err, "unable to revoke API key")
+	}
+	return nil, nil
+}
+
+func handleSitesConfigure(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	account, err := checkSitesAccess(siteacc, values, session)
+	if err != nil {
+		return nil, err
+	}
+
+	sitesData := &[]*data.Site{}
+	if err := json.Unmarshal(body, sitesData); err != nil {
+		return nil, errors.Wrap(err, "invalid form data")
+	}
+
+	// Configure the sites through the operators manager
+	opData := &data.Operator{
+		ID:    account.Operator,
+		Sites: *sitesData,
+	}
+	if err := siteacc.OperatorsManager().UpdateOperator(opData); err != nil {
+		return nil, errors.Wrap(err, "unable to configure operator")
+	}
+
+	return nil, nil
+}
+
+func callSitesExportEndpoint(siteacc *SiteAccounts, ep endpoint, w http.ResponseWriter, r *http.Request, session *html.Session) {
+	values := r.URL.Query()
+	format := strings.ToLower(values.Get("format"))
+	if format == "" {
+		format = data.SiteConfigFormatCSV
+	}
+
+	records, err := exportSiteConfigs(siteacc, values, session, format)
+	if err != nil {
+		siteacc.writeError(w, r, http.StatusBadRequest, fmt.Sprintf("Unable to export the sites: %v", err))
+		return
+	}
+
+	// The configurations are offered as a file download
+	contentType := "text/csv; charset=UTF-8"
+	if format == data.SiteConfigFormatJSON {
+		contentType = "application/json; charset=UTF-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"sites.%v\"", format))
+	w.Header().Set("Cache-Control", "no-store")
+	if err := data.WriteSiteConfigRecords(w, records, format); err != nil {
+		siteacc.log.Err(err).Msg("error while exporting the sites")
+	}
+}
+
+func exportSiteConfigs(siteacc *SiteAccounts, values url.Values, session *html.Session, format string) ([]*data.SiteConfigRecord, error) {
+	if format != data.SiteConfigFormatCSV && format != data.SiteConfigFormatJSON {
+		return nil, errors.Errorf("unsupported format %v", format)
+	}
+
+	opID, err := checkSitesOperator(siteacc, values, session)
+	if err != nil {
+		return nil, err
+	}
+
+	sites, err := data.QueryOperatorSites(opID, siteacc.conf.Mentix.URL, siteacc.conf.Mentix.DataEndpoint, &siteacc.conf.Mentix.Signing)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to query the sites of the operator")
+	}
+	op, err := siteacc.OperatorsManager().GetOperator(opID, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get the operator")
+	}
+
+	// Export all sites of the operator, including the ones not configured yet
+	records := make([]*data.SiteConfigRecord, 0, len(sites))
+	for _, siteID := range sites {
+		record := &data.SiteConfigRecord{ID: siteID}
+		record.Name, _ = data.QuerySiteName(siteID, true, siteacc.conf.Mentix.URL, siteacc.conf.Mentix.DataEndpoint, &siteacc.conf.Mentix.Signing)
+		if site := op.FindSite(siteID); site != nil && site.Config.TestClientCredentials.IsValid() {
+			id, secret, err := site.Config.TestClientCredentials.Get(siteacc.conf.Security.CredentialsPassphrase)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to decrypt the test user of site %v", siteID)
+			}
+			record.TestClientID = id
+			record.TestClientSecret = secret
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func handleSitesHealth(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	health := siteacc.OperatorsManager().CloneSitesHealth()
+	if siteID := values.Get("site"); siteID != "" {
+		siteHealth, ok := health[siteID]
+		if !ok {
+			return nil, errors.Errorf("no health information about site %v available", siteID)
+		}
+		health = map[string]*data.SiteHealth{siteID: siteHealth}
+	}
+	return map[string]interface{}{"health": health}, nil
+}
+
+func handleCheckSitesHealth(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	if siteacc.HealthProber() == nil {
+		return nil, errors.Errorf("no site health checks have been configured")
+	}
+
+	health, err := siteacc.HealthProber().Run(context.Background())
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to check the health of the sites")
+	}
+	return map[string]interface{}{"health": health}, nil
+}
+
+func handleSitesImport(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	account, err := checkSitesAccess(siteacc, values, session)
+	if err != nil {
+		return nil, err
+	}
+
+	format := values.Get("format")
+	if format == "" {
+		format = data.SiteConfigFormatCSV
+	}
+	records, err := data.ReadSiteConfigRecords(bytes.NewReader(body), format)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the site configurations")
+	}
+
+	sites, err := data.QueryOperatorSites(account.Operator, siteacc.conf.Mentix.URL, siteacc.conf.Mentix.DataEndpoint, &siteacc.conf.Mentix.Signing)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to query the sites of the operator")
+	}
+
+	type importResult struct {
+		Site  string `json:"site"`
+		Error string `json:"error,omitempty"`
+	}
+
+	// Validate all records first; nothing is imported if any of them is invalid
+	results := make([]*importResult, 0, len(records))
+	siteData := make([]*data.Site, 0, len(records))
+	seen := make(map[string]bool, len(records))
+	valid := true
+	for _, record := range records {
+		result := &importResult{Site: record.ID}
+		results = append(results, result)
+
+		siteID := ""
+		for _, site := range sites {
+			if strings.EqualFold(site, record.ID) {
+				siteID = site
+				break
+			}
+		}
+
+		switch {
+		case record.ID == "":
+			result.Error = "no site specified"
+		case siteID == "":
+			result.Error = fmt.Sprintf("site %v does not belong to your operator", record.ID)
+		case seen[strings.ToLower(siteID)]:
+			result.Error = fmt.Sprintf("site %v is listed multiple times", siteID)
+		case record.TestClientID == "" || record.TestClientSecret == "":
+			result.Error = "the name or password of the test user is missing"
+		}
+		if result.Error != "" {
+			valid = false
+			continue
+		}
+
+		seen[strings.ToLower(siteID)] = true
+		site, _ := data.NewSite(siteID)
+		site.Config.TestClientCredentials.ID = record.TestClientID
+		site.Config.TestClientCredentials.Secret = record.TestClientSecret
+		siteData = append(siteData, site)
+	}
+
+	dryRun := strings.EqualFold(values.Get("dryrun"), "true")
+	imported := 0
+	if valid && !dryRun {
+		for _, site := range siteData {
+			if err := siteacc.OperatorsManager().UpdateSite(account.Operator, site); err != nil {
+				return nil, errors.Wrapf(err, "unable to import site %v after importing %v sites", site.ID, imported)
+			}
+			imported++
+		}
+	}
+
+	return map[string]interface{}{"valid": valid, "dryRun": dryRun, "imported": imported, "results": results}, nil
+}
+
+func handleSiteConfigure(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	opID, siteID, err := checkSiteAccess(siteacc, values, session)
+	if err != nil {
+		return nil, err
+	}
+
+	siteData := &data.Site{}
+	if err := json.Unmarshal(body, siteData); err != nil {
+		return nil, errors.Wrap(err, "invalid form data")
+	}
+	siteData.ID = siteID
+
+	// Configure the single site through the operators manager
+	if err := siteacc.OperatorsManager().UpdateSite(opID, siteData); err != nil {
+		return nil, errors.Wrap(err, "unable to configure site")
+	}
+
+	return nil, nil
+}
+
+func handleGrantSiteManagement(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	name, _, err := processInvoker(siteacc, values, session)
+	if err != nil {
+		return nil, err
+	}
+	account, err := siteacc.AccountsManager().FindAccount(manager.FindByEmail, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only accounts with access to all sites of the operator may delegate the management of a site
+	siteID, err := checkOperatorSiteAccess(siteacc, account, values.Get("site"))
+	if err != nil {
+		return nil, err
+	}
+
+	managerData, err := unmarshalRequestData(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var grant bool
+	switch val := strings.ToLower(values.Get("status")); val {
+	case "true":
+		grant = true
+
+	case "false":
+		grant = false
+
+	case "":
+		return nil, errors.Errorf("no management status provided")
+
+	default:
+		return nil, errors.Errorf("unsupported management status %v", val)
+	}
+
+	if grant {
+		if strings.EqualFold(managerData.Email, account.Email) {
+			return nil, errors.Errorf("you cannot grant management rights to yourself")
+		}
+
+		// Management rights can only be granted to registered accounts
+		managerAccount, err := siteacc.AccountsManager().FindAccount(manager.FindByEmail, managerData.Email)
+		if err != nil {
+			return nil, errors.Errorf("no account with the email address %v exists", managerData.Email)
+		}
+		if managerAccount.IsDisabled() {
+			return nil, errors.Errorf("the account %v is disabled", managerAccount.Email)
+		}
+		managerData = managerAccount
+	}
+
+	if err := siteacc.OperatorsManager().GrantSiteManagement(account.Operator, siteID, managerData.Email, grant); err != nil {
+		return nil, errors.Wrap(err, "unable to change the management rights of the site")
+	}
+
+	if grant {
+		siteName, _ := data.QuerySiteName(siteID, true, siteacc.conf.Mentix.URL, siteacc.conf.Mentix.DataEndpoint, &siteacc.conf.Mentix.Signing)
+		params := map[string]string{
+			"Site":      siteID,
+			"SiteName":  siteName,
+			"GrantedBy": account.Email,
+		}
+		_ = email.SendSiteManagementGranted(managerData, []string{managerData.Email}, params, *siteacc.conf)
+	}
+
+	return nil, nil
+}
+
+func handleOperatorConfigure(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	email, _, err := processInvoker(siteacc, values, session)
+	if err != nil {
+		return nil, err
+	}
+	account, err := siteacc.AccountsManager().FindAccount(manager.FindByEmail, email)
+	if err != nil {
+		return nil, err
+	}
+	if !account.Data.SitesAccess {
+		return nil, errors.Errorf("no sites access granted")
+	}
+
+	opData := &data.Operator{}
+	if err := json.Unmarshal(body, opData); err != nil {
+		return nil, errors.Wrap(err, "invalid form data")
+	}
+	opData.ID = account.Operator
+
+	// Operator admins requiring two-factor authentication must not lock themselves out of the sites
+	if opData.Settings.RequireTwoFactor && !account.TwoFactor.Enabled {
+		return nil, errors.Errorf("two-factor authentication must be enabled for your own account first")
+	}
+
+	// Configure the operator through the operators manager
+	if err := siteacc.OperatorsManager().ConfigureOperator(opData); err != nil {
+		return nil, errors.Wrap(err, "unable to configure operator")
+	}
+
+	return nil, nil
+}
+
+func handleLogin(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	account, err := unmarshalRequestData(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkAttempt(siteacc.loginGuard, session, account.Email); err != nil {
+		return nil, err
+	}
+
+	// Login the user through the users manager
+	token, twoFactor, err := siteacc.UsersManager().LoginUser(account.Email, account.Password.Value, values.Get("scope"), session)
+	if err != nil {
+		failAttempt(siteacc.loginGuard, session, account.Email)
+		metrics.RecordLogin(metrics.MethodPassword, err)
+		return nil, errors.Wrap(err, "unable to login user")
+	}
+
+	// If two-factor authentication is enabled, the login needs to be completed using the second factor; only then the failed attempts are reset
+	if twoFactor {
+		return map[string]interface{}{"twoFactor": true}, nil
+	}
+	succeedAttempt(siteacc.loginGuard, account.Email)
+	metrics.RecordLogin(metrics.MethodPassword, nil)
+
+	return token, nil
+}
+
+func handleVerifyTwoFactor(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	codeData, err := unmarshalTwoFactorData(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Guessing the second factor counts against the same limits as guessing the password
+	email := ""
+	if login := session.PendingLogin(); login != nil {
+		email = login.User.Account.Email
+	}
+	if err := checkAttempt(siteacc.loginGuard, session, email); err != nil {
+		return nil, err
+	}
+
+	// Complete the pending login through the users manager
+	token, err := siteacc.UsersManager().VerifyLogin(codeData.Code, session)
+	metrics.RecordLogin(metrics.MethodTwoFactor, err)
+	if err != nil {
+		failAttempt(siteacc.loginGuard, session, email)
+		return nil, errors.Wrap(err, "unable to login user")
+	}
+	succeedAttempt(siteacc.loginGuard, email)
+
+	return token, nil
+}
+
+func handleEnrollTwoFactor(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	if !session.IsUserLoggedIn() {
+		return nil, errors.Errorf("no user is currently logged in")
+	}
+	account := session.LoggedInUser().Account
+
+	// Generate a new secret through the accounts manager; the secret is only returned once
+	secret, err := siteacc.AccountsManager().EnrollTwoFactor(account)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to enroll two-factor authentication")
+	}
+
+	uri := credentials.TOTPURI(twoFactorIssuer, account.Email, secret)
+	qrCode, err := qrcode.DataURI(uri, 4)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to generate the QR code")
+	}
+
+	return map[string]interface{}{"secret": secret, "uri": uri, "qrCode": qrCode}, nil
+}
+
+func handleEnableTwoFactor(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	if !session.IsUserLoggedIn() {
+		return nil, errors.Errorf("no user is currently logged in")
+	}
+
+	codeData, err := unmarshalTwoFactorData(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Enable two-factor authentication through the accounts manager
+	if err := siteacc.AccountsManager().EnableTwoFactor(session.LoggedInUser().Account, codeData.Code); err != nil {
+		return nil, errors.Wrap(err, "unable to enable two-factor authentication")
+	}
+
+	return nil, nil
+}
+
+func handleDisableTwoFactor(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	if !session.IsUserLoggedIn() {
+		return nil, errors.Errorf("no user is currently logged in")
+	}
+
+	codeData, err := unmarshalTwoFactorData(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Disable two-factor authentication through the accounts manager
+	user := session.LoggedInUser()
+	if err := siteacc.AccountsManager().DisableTwoFactor(user.Account, user.Operator, codeData.Code); err != nil {
+		return nil, errors.Wrap(err, "unable to disable two-factor authentication")
+	}
+
+	return nil, nil
+}
+
+func handleLogout(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	// Logout the user through the users manager
+	siteacc.UsersManager().LogoutUser(session)
+	return nil, nil
+}
+
+func handleResetPassword(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	account, err := unmarshalRequestData(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reset the password through the users manager
+	if err := siteacc.AccountsManager().ResetPassword(account.Email); err != nil {
+		return nil, errors.Wrap(err, "unable to reset password")
+	}
+
+	return nil, nil
+}
+
+func handleContact(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	if !session.IsUserLoggedIn() {
+		return nil, errors.Errorf("no user is currently logged in")
+	}
+
+	type jsonData struct {
+		Subject string `json:"subject"`
+		Message string `json:"message"`
+	}
+	contactData := &jsonData{}
+	if err := json.Unmarshal(body, contactData); err != nil {
+		return nil, errors.Wrap(err, "invalid form data")
+	}
+
+	// Send an email through the accounts manager
+	siteacc.AccountsManager().SendContactForm(session.LoggedInUser().Account, strings.TrimSpace(contactData.Subject), strings.TrimSpace(contactData.Message))
+	return nil, nil
+}
+
+func handleConfigureNotifications(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	if !session.IsUserLoggedIn() {
+		return nil, errors.Errorf("no user is currently logged in")
+	}
+
+	account, err := unmarshalRequestData(body)
+	if err != nil {
+		return nil, err
+	}
+	account.Email = session.LoggedInUser().Account.Email
+
+	// Configure the notification preferences through the accounts manager
+	if err := siteacc.AccountsManager().ConfigureNotifications(account); err != nil {
+		return nil, errors.Wrap(err, "unable to configure notifications")
+	}
+
+	return nil, nil
+}
+
+func handleClearNotifications(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	if !session.IsUserLoggedIn() {
+		return nil, errors.Errorf("no user is currently logged in")
+	}
+
+	// Clear the panel notifications through the accounts manager
+	if err := siteacc.AccountsManager().ClearNotifications(session.LoggedInUser().Account); err != nil {
+		return nil, errors.Wrap(err, "unable to clear notifications")
+	}
+
+	return nil, nil
+}
+
+func handleRequestDeletion(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	if !session.IsUserLoggedIn() {
+		return nil, errors.Errorf("no user is currently logged in")
+	}
+
+	// The account is only disabled and scheduled for deletion once the owner has confirmed the deletion using the link sent via email
+	if err := siteacc.AccountsManager().RequestDeletion(session.LoggedInUser().Account); err != nil {
+		return nil, errors.Wrap(err, "unable to request the account deletion")
+	}
+
+	return map[string]interface{}{"confirmationTimeout": siteacc.conf.Accounts.DeletionConfirmationTimeout}, nil
+}
+
+func handleCancelDeletion(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	deletionData, err := unmarshalDeletionData(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-enable the account through the accounts manager
+	if err := siteacc.AccountsManager().CancelDeletion(deletionData.Email, deletionData.Token); err != nil {
+		return nil, errors.Wrap(err, "unable to cancel the account deletion")
+	}
+
+	return nil, nil
+}
+
+func handleVerifyUserToken(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	token := values.Get("token")
+	if token == "" {
+		return nil, errors.Errorf("no token specified")
+	}
+
+	user := values.Get("user")
+	if user == "" {
+		return nil, errors.Errorf("no user specified")
+	}
+
+	// Verify the user token using the users manager
+	newToken, err := siteacc.UsersManager().VerifyUserToken(token, user, values.Get("scope"))
+	if err != nil {
+		return nil, errors.Wrap(err, "token verification failed")
+	}
+
+	return newToken, nil
+}
+
+func handleVerifySiteKey(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	keyData := &struct {
+		Site string `json:"site"`
+		Key  string `json:"key"`
+	}{}
+	if err := json.Unmarshal(body, keyData); err != nil {
+		return nil, errors.Wrap(err, "invalid form data")
+	}
+
+	if keyData.Site == "" {
+		return nil, errors.Errorf("no site specified")
+	}
+	if keyData.Key == "" {
+		return nil, errors.Errorf("no key specified")
+	}
+
+	// Verify the site key using the operators manager
+	if err := siteacc.OperatorsManager().VerifySiteKey(keyData.Site, keyData.Key); err != nil {
+		return nil, errors.Wrap(err, "key verification failed")
+	}
+
+	return nil, nil
+}
+
+func handleDispatchAlert(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	alertsData := &template.Data{}
+	if err := json.Unmarshal(body, alertsData); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal the alerts data")
+	}
+
+	// Dispatch the alerts using the alerts dispatcher
+	if err := siteacc.AlertsDispatcher().DispatchAlerts(alertsData, siteacc.AccountsManager().CloneAccounts(true)); err != nil {
+		return nil, errors.Wrap(err, "error while dispatching the alerts")
+	}
+
+	return nil, nil
+}
+
+func handleDispatchSiteEvents(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	payload := &webhook.Payload{}
+	if err := json.Unmarshal(body, payload); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal the site events")
+	}
+
+	// Notify the affected accounts through the accounts manager
+	siteacc.AccountsManager().DispatchSiteEvents(payload.Events)
+
+	return nil, nil
+}
+
+func handleGrantSitesAccess(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	return handleGrantAccess((*manager.AccountsManager).GrantSitesAccess, siteacc, values, body, session)
+}
+
+func handleGrantGOCDBAccess(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	return handleGrantAccess((*manager.AccountsManager).GrantGOCDBAccess, siteacc, values, body, session)
+}
+
+func handleGrantAccess(accessSetter accessSetterCallback, siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	account, err := unmarshalRequestData(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if val := values.Get("status"); len(val) > 0 {
+		var grantAccess bool
+		switch strings.ToLower(val) {
+		case "true":
+			grantAccess = true
+
+		case "false":
+			grantAccess = false
+
+		default:
+			return nil, errors.Errorf("unsupported access status %v", val[0])
+		}
+
+		// Grant access to the account through the accounts manager
+		if err := accessSetter(siteacc.AccountsManager(), account, grantAccess); err != nil {
+			return nil, errors.Wrap(err, "unable to change the access status of the account")
+		}
+	} else {
+		return nil, errors.Errorf("no access status provided")
+	}
+
+	return nil, nil
+}
+
+func unmarshalRequestData(body []byte) (*data.Account, error) {
+	account := &data.Account{}
+	if err := json.Unmarshal(body, account); err != nil {
+		return nil, errors.Wrap(err, "invalid account data")
+	}
+	account.Cleanup()
+	return account, nil
+}
+
+type deletionData struct {
+	Email string `json:"email"`
+	Token string `json:"token"`
+}
+
+func unmarshalDeletionData(body []byte) (*deletionData, error) {
+	delData := &deletionData{}
+	if err := json.Unmarshal(body, delData); err != nil {
+		return nil, errors.Wrap(err, "invalid deletion data")
+	}
+	delData.Email = strings.TrimSpace(delData.Email)
+	delData.Token = strings.TrimSpace(delData.Token)
+	return delData, nil
+}
+
+type twoFactorData struct {
+	Code string `json:"code"`
+}
+
+func unmarshalTwoFactorData(body []byte) (*twoFactorData, error) {
+	codeData := &twoFactorData{}
+	if err := json.Unmarshal(body, codeData); err != nil {
+		return nil, errors.Wrap(err, "invalid two-factor data")
+	}
+	codeData.Code = strings.TrimSpace(codeData.Code)
+	if codeData.Code == "" {
+		return nil, errors.Errorf("no code provided")
+	}
+	return codeData, nil
+}
+
+func findAccount(siteacc *SiteAccounts, by string, value string) (*data.Account, error) {
+	if len(by) == 0 && len(value) == 0 {
+		return nil, errors.Errorf("missing search criteria")
+	}
+
+	// Find the account using the accounts manager
+	account, err := siteacc.AccountsManager().FindAccount(by, value)
+	if err != nil {
+		return nil, errors.Wrap(err, "user not found")
+	}
+	return account, nil
+}
+
+func checkSitesAccess(siteacc *SiteAccounts, values url.Values, session *html.Session) (*data.Account, error) {
+	email, _, err := processInvoker(siteacc, values, session)
+	if err != nil {
+		return nil, err
+	}
+	account, err := siteacc.AccountsManager().FindAccount(manager.FindByEmail, email)
+	if err != nil {
+		return nil, err
+	}
+	if !account.Data.SitesAccess {
+		return nil, errors.Errorf("no sites access granted")
+	}
+	if err := checkTwoFactorRequirement(siteacc, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func checkSiteAccess(siteacc *SiteAccounts, values url.Values, session *html.Session) (string, string, error) {
+	siteID := values.Get("site")
+	if siteID == "" {
+		return "", "", errors.Errorf("no site specified")
+	}
+
+	// Requests authenticated with an API key may access all sites of the operator that issued the key
+	if user := session.APIKeyUser(); user != nil {
+		site, err := findOperatorSite(siteacc, user.Operator.ID, siteID)
+		if err != nil {
+			return "", "", err
+		}
+		return user.Operator.ID, site, nil
+	}
+
+	email, _, err := processInvoker(siteacc, values, session)
+	if err != nil {
+		return "", "", err
+	}
+	account, err := siteacc.AccountsManager().FindAccount(manager.FindByEmail, email)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Sites of other operators may only be accessed if their operator has granted management rights to the account
+	if op, site := siteacc.OperatorsManager().FindManagedSite(siteID, account.Email); site != nil {
+		if op.Settings.RequireTwoFactor && !account.TwoFactor.Enabled {
+			return "", "", errors.Errorf("two-factor authentication is required by the operator of site %v", site.ID)
+		}
+		return op.ID, site.ID, nil
+	}
+
+	site, err := checkOperatorSiteAccess(siteacc, account, siteID)
+	if err != nil {
+		return "", "", err
+	}
+	return account.Operator, site, nil
+}
+
+func checkOperatorSiteAccess(siteacc *SiteAccounts, account *data.Account, siteID string) (string, error) {
+	if siteID == "" {
+		return "", errors.Errorf("no site specified")
+	}
+	if !account.Data.SitesAccess {
+		return "", errors.Errorf("no sites access granted")
+	}
+	if err := checkTwoFactorRequirement(siteacc, account); err != nil {
+		return "", err
+	}
+
+	// Only sites belonging to the operator of the account may be accessed
+	return findOperatorSite(siteacc, account.Operator, siteID)
+}
+
+// checkSitesOperator returns the operator whose sites may be accessed, either through an API key or by an account with sites access.
+func checkSitesOperator(siteacc *SiteAccounts, values url.Values, session *html.Session) (string, error) {
+	if user := session.APIKeyUser(); user != nil {
+		return user.Operator.ID, nil
+	}
+
+	account, err := checkSitesAccess(siteacc, values, session)
+	if err != nil {
+		return "", err
+	}
+	return account.Operator, nil
+}
+
+func findOperatorSite(siteacc *SiteAccounts, opID string, siteID string) (string, error) {
+	sites, err := data.QueryOperatorSites(opID, siteacc.conf.Mentix.URL, siteacc.conf.Mentix.DataEndpoint, &siteacc.conf.Mentix.Signing)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to query the sites of the operator")
+	}
+	for _, site := range sites {
+		if strings.EqualFold(site, siteID) {
+			return site, nil
+		}
+	}
+	return "", errors.Errorf("site %v does not belong to your operator", siteID)
+}
+
+func checkTwoFactorRequirement(siteacc *SiteAccounts, account *data.Account) error {
+	if account.RequiresTwoFactor(siteacc.OperatorsManager().FindOperator(account.Operator)) && !account.TwoFactor.Enabled {
+		return errors.Errorf("two-factor authentication is required by your operator")
+	}
+	return nil
+}
+
+func processInvoker(siteacc *SiteAccounts, values url.Values, session *html.Session) (string, bool, error) {
+	var email string
+	var invokedByUser bool
+
+	switch strings.ToLower(values.Get("invoker")) {
+	case invokerUser:
+		// If this endpoint was called by the user, set the account email from the stored session
+		if !session.IsUserLoggedIn() {
+			return "", false, errors.Errorf("no user is currently logged in")
+		}
+
+		email = session.LoggedInUser().Account.Email
+		invokedByUser = true
+
+	default:
+		return "", false, errors.Errorf("no invoker provided")
+	}
+
+	return email, invokedByUser, nil
+}
+
+func generateRandomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.Wrap(err, "unable to generate random data")
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
 }
@@ -0,0 +1,162 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package siteacc
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/siteacc/audit"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/cs3org/reva/pkg/siteacc/html"
+	"github.com/cs3org/reva/pkg/siteacc/manager"
+	"github.com/pkg/errors"
+)
+
+const (
+	exportFormatZIP  = "zip"
+	exportFormatJSON = "json"
+)
+
+// accountExport holds all data stored about an account.
+type accountExport struct {
+	DateExported time.Time `json:"dateExported"`
+
+	Account *data.Account `json:"account"`
+
+	Operator struct {
+		ID    string   `json:"id"`
+		Name  string   `json:"name"`
+		Sites []string `json:"sites"`
+	} `json:"operator"`
+	// ManagedSites holds the sites of other operators the account has been granted management rights over.
+	ManagedSites []string `json:"managedSites"`
+
+	// AuditEvents holds the recent audit events affecting the account or triggered by it.
+	AuditEvents []*audit.Event `json:"auditEvents"`
+}
+
+func callExportAccountEndpoint(siteacc *SiteAccounts, ep endpoint, w http.ResponseWriter, r *http.Request, session *html.Session) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = exportFormatZIP
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	export, err := exportAccount(siteacc, format, body, session)
+	if err != nil {
+		siteacc.writeError(w, r, http.StatusBadRequest, fmt.Sprintf("Unable to export the account: %v", err))
+		return
+	}
+
+	// The data is offered as a file download
+	contentType := "application/zip"
+	if format == exportFormatJSON {
+		contentType = "application/json; charset=UTF-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"sciencemesh-account.%v\"", format))
+	w.Header().Set("Cache-Control", "no-store")
+	if err := writeAccountExport(w, export, format); err != nil {
+		siteacc.log.Err(err).Msg("error while exporting the account")
+	}
+}
+
+func exportAccount(siteacc *SiteAccounts, format string, body []byte, session *html.Session) (*accountExport, error) {
+	if format != exportFormatZIP && format != exportFormatJSON {
+		return nil, errors.Errorf("unsupported format %v", format)
+	}
+
+	// Logged in users can always export their own account; disabled accounts require the deletion token
+	var account *data.Account
+	if session.IsUserLoggedIn() {
+		acc, err := siteacc.AccountsManager().FindAccount(manager.FindByEmail, session.LoggedInUser().Account.Email)
+		if err != nil {
+			return nil, err
+		}
+		account = acc.Clone(true)
+	} else {
+		deletionData, err := unmarshalDeletionData(body)
+		if err != nil {
+			return nil, err
+		}
+		if account, err = siteacc.AccountsManager().ExportAccount(deletionData.Email, deletionData.Token); err != nil {
+			return nil, err
+		}
+	}
+
+	export := &accountExport{
+		DateExported: time.Now(),
+		Account:      account,
+		ManagedSites: []string{},
+		AuditEvents:  siteacc.Auditor().Events(account.Email, 0),
+	}
+
+	mentix := &siteacc.conf.Mentix
+	export.Operator.ID = account.Operator
+	export.Operator.Name, _ = data.QueryOperatorName(account.Operator, mentix.URL, mentix.DataEndpoint, &mentix.Signing)
+	if sites, err := data.QueryOperatorSites(account.Operator, mentix.URL, mentix.DataEndpoint, &mentix.Signing); err == nil {
+		export.Operator.Sites = sites
+	}
+	for _, op := range siteacc.OperatorsManager().CloneManagedSites(account.Email, true) {
+		for _, site := range op.Sites {
+			export.ManagedSites = append(export.ManagedSites, site.ID)
+		}
+	}
+
+	return export, nil
+}
+
+// writeAccountExport writes the exported data either as a single JSON document or as a ZIP archive holding one JSON file per part.
+func writeAccountExport(w io.Writer, export *accountExport, format string) error {
+	if format == exportFormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		return enc.Encode(export)
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"account.json", export.Account},
+		{"operator.json", map[string]interface{}{"operator": export.Operator, "managedSites": export.ManagedSites}},
+		{"audit.json", export.AuditEvents},
+	}
+
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: export.DateExported})
+		if err != nil {
+			return errors.Wrapf(err, "unable to add %v to the archive", file.name)
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "\t")
+		if err := enc.Encode(file.data); err != nil {
+			return errors.Wrapf(err, "unable to write %v", file.name)
+		}
+	}
+	return archive.Close()
+}
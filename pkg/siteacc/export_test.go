@@ -0,0 +1,113 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package siteacc
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/siteacc/audit"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/cs3org/reva/pkg/siteacc/html"
+)
+
+func newTestAccountExport() *accountExport {
+	export := &accountExport{
+		DateExported: time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC),
+		Account:      &data.Account{Email: "john@example.org", FirstName: "John", Operator: "op"},
+		ManagedSites: []string{"site3"},
+		AuditEvents:  []*audit.Event{{Action: audit.ActionLogin, Account: "john@example.org", Success: true}},
+	}
+	export.Operator.ID = "op"
+	export.Operator.Name = "Operator"
+	export.Operator.Sites = []string{"site1", "site2"}
+	return export
+}
+
+func TestWriteAccountExportJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeAccountExport(&buf, newTestAccountExport(), exportFormatJSON); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	export := &accountExport{}
+	if err := json.Unmarshal(buf.Bytes(), export); err != nil {
+		t.Fatalf("invalid export: %v", err)
+	}
+	if export.Account.Email != "john@example.org" || export.Operator.Name != "Operator" || len(export.Operator.Sites) != 2 {
+		t.Errorf("unexpected export %+v", export)
+	}
+	if len(export.ManagedSites) != 1 || len(export.AuditEvents) != 1 {
+		t.Errorf("expected the managed sites and audit events, got %+v", export)
+	}
+}
+
+func TestWriteAccountExportZIP(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeAccountExport(&buf, newTestAccountExport(), exportFormatZIP); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("invalid archive: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, file := range archive.File {
+		f, err := file.Open()
+		if err != nil {
+			t.Fatalf("unable to open %v: %v", file.Name, err)
+		}
+		files[file.Name], _ = ioutil.ReadAll(f)
+		_ = f.Close()
+	}
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %d", len(files))
+	}
+
+	account := &data.Account{}
+	if err := json.Unmarshal(files["account.json"], account); err != nil || account.Email != "john@example.org" {
+		t.Errorf("unexpected account.json: %s", files["account.json"])
+	}
+	operator := struct {
+		Operator struct {
+			ID string `json:"id"`
+		} `json:"operator"`
+		ManagedSites []string `json:"managedSites"`
+	}{}
+	if err := json.Unmarshal(files["operator.json"], &operator); err != nil || operator.Operator.ID != "op" || len(operator.ManagedSites) != 1 {
+		t.Errorf("unexpected operator.json: %s", files["operator.json"])
+	}
+	var events []*audit.Event
+	if err := json.Unmarshal(files["audit.json"], &events); err != nil || len(events) != 1 {
+		t.Errorf("unexpected audit.json: %s", files["audit.json"])
+	}
+}
+
+func TestExportAccountUnsupportedFormat(t *testing.T) {
+	session := html.NewSession("test_session", time.Hour, httptest.NewRequest("GET", "/", nil))
+	if _, err := exportAccount(&SiteAccounts{}, "csv", nil, session); err == nil {
+		t.Error("expected an unsupported format to be rejected")
+	}
+}
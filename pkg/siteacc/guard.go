@@ -0,0 +1,92 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package siteacc
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/auth/loginguard"
+	"github.com/cs3org/reva/pkg/siteacc/html"
+	"github.com/pkg/errors"
+)
+
+// The guards are shared within the process under these names, so their lockouts are also listed (and can be lifted) through the loginguard service.
+const (
+	loginGuardName        = "siteacc-login"
+	registrationGuardName = "siteacc-registration"
+)
+
+func (siteacc *SiteAccounts) createGuards() error {
+	for name, target := range map[string]**loginguard.Guard{loginGuardName: &siteacc.loginGuard, registrationGuardName: &siteacc.registrationGuard} {
+		conf := &siteacc.conf.Security.LoginGuard
+		if name == registrationGuardName {
+			conf = &siteacc.conf.Security.RegistrationGuard
+		}
+		if !conf.Enabled {
+			continue
+		}
+
+		guard, err := loginguard.Shared(name, conf)
+		if err != nil {
+			return errors.Wrapf(err, "unable to create the %v guard", name)
+		}
+		*target = guard
+	}
+	return nil
+}
+
+// guards returns all enabled guards.
+func (siteacc *SiteAccounts) guards() []*loginguard.Guard {
+	guards := make([]*loginguard.Guard, 0, 2)
+	for _, guard := range []*loginguard.Guard{siteacc.loginGuard, siteacc.registrationGuard} {
+		if guard != nil {
+			guards = append(guards, guard)
+		}
+	}
+	return guards
+}
+
+// checkAttempt rejects attempts from locked out IPs and for locked out accounts; other attempts are delayed based on the previous failures.
+func checkAttempt(guard *loginguard.Guard, session *html.Session, account string) error {
+	if guard == nil {
+		return nil
+	}
+
+	account = strings.ToLower(account)
+	if retryAfter, blocked := guard.Blocked(session.RemoteAddress, account); blocked {
+		return errors.Errorf("too many failed attempts, please try again in %v", retryAfter.Round(time.Second))
+	}
+	time.Sleep(guard.Delay(session.RemoteAddress, account))
+	return nil
+}
+
+// failAttempt registers a failed attempt, locking out the IP or account if too many attempts have failed.
+func failAttempt(guard *loginguard.Guard, session *html.Session, account string) {
+	if guard != nil && account != "" {
+		guard.Fail(session.RemoteAddress, strings.ToLower(account))
+	}
+}
+
+// succeedAttempt resets the failed attempts of the account.
+func succeedAttempt(guard *loginguard.Guard, account string) {
+	if guard != nil {
+		guard.Succeed(strings.ToLower(account))
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/mentix/utils/network"
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/cs3org/reva/pkg/siteacc/manager"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// Prober periodically checks the health of all sites using their test client credentials.
+type Prober struct {
+	conf *config.Configuration
+	log  *zerolog.Logger
+
+	operatorsManager *manager.OperatorsManager
+
+	client *http.Client
+
+	mutex    sync.Mutex
+	stopChan chan struct{}
+}
+
+func (prober *Prober) initialize(conf *config.Configuration, log *zerolog.Logger, omngr *manager.OperatorsManager) error {
+	if conf == nil {
+		return errors.Errorf("no configuration provided")
+	}
+	prober.conf = conf
+
+	if log == nil {
+		return errors.Errorf("no logger provided")
+	}
+	prober.log = log
+
+	if omngr == nil {
+		return errors.Errorf("no operators manager provided")
+	}
+	prober.operatorsManager = omngr
+
+	prober.client = &http.Client{Timeout: time.Duration(conf.Health.Timeout) * time.Second}
+
+	return nil
+}
+
+// Start starts the periodic health checks if an interval has been configured.
+func (prober *Prober) Start() {
+	if prober.conf.Health.Interval <= 0 || prober.stopChan != nil {
+		return
+	}
+
+	prober.stopChan = make(chan struct{})
+	go func(stop <-chan struct{}) {
+		ticker := time.NewTicker(time.Duration(prober.conf.Health.Interval) * time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				// Pick up any changes made by other instances sharing the same storage first
+				prober.operatorsManager.ReloadOperators()
+				if _, err := prober.Run(context.Background()); err != nil {
+					prober.log.Err(err).Msg("error while checking the health of the sites")
+				}
+
+			case <-stop:
+				return
+			}
+		}
+	}(prober.stopChan)
+}
+
+// Stop stops the periodic health checks.
+func (prober *Prober) Stop() {
+	if prober.stopChan != nil {
+		close(prober.stopChan)
+		prober.stopChan = nil
+	}
+}
+
+// Run checks the health of all sites with test client credentials and stores the results; the results are also returned, mapped by the site IDs.
+func (prober *Prober) Run(ctx context.Context) (map[string]*data.SiteHealth, error) {
+	// Never run multiple checks at once
+	prober.mutex.Lock()
+	defer prober.mutex.Unlock()
+
+	endpoints, err := data.QuerySiteEndpoints(prober.conf.Mentix.URL, prober.conf.Mentix.DataEndpoint, &prober.conf.Mentix.Signing)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to query the site endpoints")
+	}
+
+	results := make(map[string]*data.SiteHealth)
+	for _, op := range prober.operatorsManager.CloneOperators(false) {
+		for _, site := range op.Sites {
+			if !site.Config.TestClientCredentials.IsValid() {
+				continue
+			}
+
+			health := prober.checkSite(ctx, site, endpoints[site.ID])
+			if health.IsHealthy() {
+				health.LastHealthy = health.LastChecked
+			} else if site.Health != nil {
+				health.LastHealthy = site.Health.LastHealthy
+			}
+			results[site.ID] = health
+		}
+	}
+	prober.operatorsManager.UpdateSitesHealth(results)
+
+	healthy := 0
+	for _, health := range results {
+		if health.IsHealthy() {
+			healthy++
+		}
+	}
+	prober.log.Info().Int("sites", len(results)).Int("healthy", healthy).Msg("checked the health of the sites")
+	return results, nil
+}
+
+func (prober *Prober) checkSite(ctx context.Context, site *data.Site, endpoints *data.SiteEndpoints) *data.SiteHealth {
+	health := &data.SiteHealth{
+		Status:      data.HealthStatusUnknown,
+		LastChecked: time.Now(),
+	}
+
+	if endpoints == nil || endpoints.Revad == "" {
+		health.Message = "no Reva endpoint is registered for the site"
+		return health
+	}
+	health.Endpoint = endpoints.Revad
+
+	user, password, err := site.Config.TestClientCredentials.Get(prober.conf.Security.CredentialsPassphrase)
+	if err != nil {
+		health.Message = "unable to decrypt the test client credentials"
+		return health
+	}
+
+	// Any response that isn't a server error means that the endpoint is reachable
+	start := time.Now()
+	status, err := prober.request(ctx, http.MethodHead, endpoints.Revad, nil)
+	if err != nil || status >= http.StatusInternalServerError {
+		health.Status = data.HealthStatusUnreachable
+		health.Message = describeFailure(status, err)
+		return health
+	}
+	health.RTT = float64(time.Since(start).Microseconds()) / 1000.0
+
+	// The test user logs in by listing its home directory through WebDAV
+	webdavURL := endpoints.WebDAV
+	if webdavURL == "" {
+		u, err := network.GenerateURL(endpoints.Revad, prober.conf.Health.WebDAVPath, network.URLParams{})
+		if err != nil {
+			health.Message = err.Error()
+			return health
+		}
+		webdavURL = u.String()
+	}
+
+	status, err = prober.request(ctx, "PROPFIND", webdavURL, &network.BasicAuth{User: user, Password: password})
+	switch {
+	case err != nil || status >= http.StatusInternalServerError:
+		health.Status = data.HealthStatusUnreachable
+		health.Message = describeFailure(status, err)
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		health.Status = data.HealthStatusLoginFailed
+		health.Message = fmt.Sprintf("the test user %v was rejected", user)
+	case status >= http.StatusBadRequest:
+		health.Status = data.HealthStatusLoginFailed
+		health.Message = describeFailure(status, nil)
+	default:
+		health.Status = data.HealthStatusOK
+	}
+	return health
+}
+
+func (prober *Prober) request(ctx context.Context, method, endpoint string, auth *network.BasicAuth) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+	if auth != nil {
+		req.SetBasicAuth(auth.User, auth.Password)
+	}
+	if method == "PROPFIND" {
+		req.Header.Set("Depth", "0")
+	}
+
+	resp, err := prober.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func describeFailure(status int, err error) string {
+	if err != nil {
+		// Strip the request details added by the HTTP client
+		msg := err.Error()
+		if idx := strings.LastIndex(msg, ": "); idx != -1 {
+			msg = msg[idx+2:]
+		}
+		return msg
+	}
+	return fmt.Sprintf("the endpoint responded with %v %v", status, http.StatusText(status))
+}
+
+// NewProber creates a new site health prober.
+func NewProber(conf *config.Configuration, log *zerolog.Logger, omngr *manager.OperatorsManager) (*Prober, error) {
+	prober := &Prober{}
+	if err := prober.initialize(conf, log, omngr); err != nil {
+		return nil, errors.Wrap(err, "unable to initialize the site health prober")
+	}
+	return prober, nil
+}
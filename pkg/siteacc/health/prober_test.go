@@ -0,0 +1,205 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/cs3org/reva/pkg/siteacc/manager"
+	"github.com/rs/zerolog"
+)
+
+const testPassphrase = "passphrase"
+
+// newRevaServer serves the requests of the health checks; the test user is only accepted with the right password.
+func newRevaServer(down *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(down) != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		switch {
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusOK)
+		case r.Method == "PROPFIND" && strings.HasPrefix(r.URL.Path, "/remote.php/webdav"):
+			if user, password, ok := r.BasicAuth(); !ok || user != "tester" || password != "secret" || r.Header.Get("Depth") != "0" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusMultiStatus)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func newMentixServer(endpoints map[string]string) *httptest.Server {
+	var sites []string
+	for id, url := range endpoints {
+		sites = append(sites, fmt.Sprintf(`{"ID": %q, "Services": [{"Type": {"Name": "REVAD"}, "URL": %q}]}`, id, url))
+	}
+	meshData := fmt.Sprintf(`{"Operators": [{"ID": "op", "Sites": [%v]}]}`, strings.Join(sites, ","))
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(meshData))
+	}))
+}
+
+func newTestSite(t *testing.T, id, user, password string) *data.Site {
+	site := &data.Site{ID: id}
+	if user != "" {
+		if err := site.Config.TestClientCredentials.Set(user, password, testPassphrase); err != nil {
+			t.Fatalf("unable to set the credentials: %v", err)
+		}
+	}
+	return site
+}
+
+func newTestProber(t *testing.T, mentixURL string, sites ...*data.Site) (*Prober, *manager.OperatorsManager) {
+	t.Helper()
+
+	dir := t.TempDir()
+	conf := &config.Configuration{}
+	conf.Storage.File.OperatorsFile = filepath.Join(dir, "operators.json")
+	conf.Storage.File.AccountsFile = filepath.Join(dir, "accounts.json")
+	conf.Mentix.URL = mentixURL
+	conf.Mentix.DataEndpoint = "/sites"
+	conf.Security.CredentialsPassphrase = testPassphrase
+	conf.Health.Timeout = 5
+	conf.Health.WebDAVPath = "remote.php/webdav/"
+
+	opsData, _ := json.Marshal([]*data.Operator{{ID: "op", Sites: sites}})
+	if err := ioutil.WriteFile(conf.Storage.File.OperatorsFile, opsData, 0600); err != nil {
+		t.Fatalf("unable to write the operators: %v", err)
+	}
+
+	log := zerolog.Nop()
+	storage, err := data.NewFileStorage(conf, &log)
+	if err != nil {
+		t.Fatalf("unable to create the storage: %v", err)
+	}
+	omngr, err := manager.NewOperatorsManager(storage, conf, &log)
+	if err != nil {
+		t.Fatalf("unable to create the operators manager: %v", err)
+	}
+	prober, err := NewProber(conf, &log, omngr)
+	if err != nil {
+		t.Fatalf("unable to create the prober: %v", err)
+	}
+	return prober, omngr
+}
+
+func TestProberRun(t *testing.T) {
+	var down int32
+	reva := newRevaServer(&down)
+	defer reva.Close()
+	gone := httptest.NewServer(http.NotFoundHandler())
+	gone.Close()
+
+	mentix := newMentixServer(map[string]string{
+		"healthy":     reva.URL,
+		"rejected":    reva.URL,
+		"unreachable": gone.URL,
+		"skipped":     reva.URL,
+	})
+	defer mentix.Close()
+
+	prober, omngr := newTestProber(t, mentix.URL,
+		newTestSite(t, "healthy", "tester", "secret"),
+		newTestSite(t, "rejected", "tester", "wrong"),
+		newTestSite(t, "unreachable", "tester", "secret"),
+		newTestSite(t, "unregistered", "tester", "secret"),
+		newTestSite(t, "skipped", "", ""),
+	)
+
+	results, err := prober.Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"healthy":      data.HealthStatusOK,
+		"rejected":     data.HealthStatusLoginFailed,
+		"unreachable":  data.HealthStatusUnreachable,
+		"unregistered": data.HealthStatusUnknown,
+	}
+	if len(results) != len(expected) {
+		t.Errorf("expected only the sites with test client credentials to be checked, got %v", results)
+	}
+	for id, status := range expected {
+		if health := results[id]; health == nil || health.Status != status {
+			t.Errorf("%v: expected status %v, got %+v", id, status, health)
+		}
+	}
+
+	healthy := results["healthy"]
+	if healthy.Endpoint != reva.URL || !healthy.LastHealthy.Equal(healthy.LastChecked) || healthy.RTT < 0 {
+		t.Errorf("unexpected result of the healthy site %+v", healthy)
+	}
+	if !results["rejected"].LastHealthy.IsZero() {
+		t.Error("expected a site that has never been healthy to have no last healthy time")
+	}
+	if len(omngr.CloneSitesHealth()) != len(expected) {
+		t.Errorf("expected the results to be stored, got %v", omngr.CloneSitesHealth())
+	}
+
+	// A site going down keeps the time it was last healthy
+	atomic.StoreInt32(&down, 1)
+	results, err = prober.Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if health := results["healthy"]; health.Status != data.HealthStatusUnreachable || !health.LastHealthy.Equal(healthy.LastHealthy) {
+		t.Errorf("expected the last healthy time to be kept, got %+v", health)
+	}
+}
+
+func TestProberMentixFailure(t *testing.T) {
+	mentix := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer mentix.Close()
+
+	prober, omngr := newTestProber(t, mentix.URL, newTestSite(t, "site", "tester", "secret"))
+	if _, err := prober.Run(context.Background()); err == nil {
+		t.Error("expected an error if Mentix can't be queried")
+	}
+	if len(omngr.CloneSitesHealth()) != 0 {
+		t.Error("expected no results to be stored")
+	}
+}
+
+func TestDescribeFailure(t *testing.T) {
+	if msg := describeFailure(http.StatusBadGateway, nil); msg != "the endpoint responded with 502 Bad Gateway" {
+		t.Errorf("unexpected message %q", msg)
+	}
+	if msg := describeFailure(0, fmt.Errorf(`Head "http://example.org": dial tcp: connection refused`)); msg != "connection refused" {
+		t.Errorf("expected the request details to be stripped, got %q", msg)
+	}
+}
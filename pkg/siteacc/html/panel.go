@@ -23,9 +23,13 @@ import (
 	"html/template"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/cs3org/reva/pkg/announcements"
+	"github.com/cs3org/reva/pkg/siteacc/captcha"
 	"github.com/cs3org/reva/pkg/siteacc/config"
 	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/cs3org/reva/pkg/siteacc/metrics"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
@@ -33,6 +37,9 @@ import (
 // TemplateID is the type for template identifiers.
 type TemplateID = string
 
+// AnnouncementsProvider returns the announcements shown on top of the panel.
+type AnnouncementsProvider = func() []*announcements.Announcement
+
 // Panel provides basic HTML panel functionality.
 type Panel struct {
 	conf *config.Configuration
@@ -40,7 +47,8 @@ type Panel struct {
 
 	name string
 
-	provider PanelProvider
+	provider      PanelProvider
+	announcements AnnouncementsProvider
 
 	templates map[TemplateID]*template.Template
 }
@@ -114,8 +122,15 @@ func (panel *Panel) AddTemplate(name TemplateID, provider ContentProvider) error
 	return nil
 }
 
+// SetAnnouncementsProvider sets the provider of the announcements shown on top of the panel.
+func (panel *Panel) SetAnnouncementsProvider(provider AnnouncementsProvider) {
+	panel.announcements = provider
+}
+
 // Execute generates the HTTP output of the panel and writes it to the response writer.
 func (panel *Panel) Execute(w http.ResponseWriter, r *http.Request, session *Session, dataProvider PanelDataProvider) error {
+	defer metrics.RecordPanelRender(panel.name, time.Now())
+
 	// Get the path query parameter; the panel provider may use this to determine the template to use
 	path := r.URL.Query().Get(pathParameterName)
 
@@ -150,19 +165,38 @@ func (panel *Panel) prepareTemplate(tpl *template.Template) {
 		"add": func(x, y int) int {
 			return x + y
 		},
+		"lower": strings.ToLower,
 		"getServerAddress": func() string {
 			return strings.TrimRight(panel.conf.Webserver.URL, "/")
 		},
+		"isOIDCEnabled": func() bool {
+			return panel.conf.OIDC.Issuer != ""
+		},
+		"getOIDCName": func() string {
+			return panel.conf.OIDC.Name
+		},
+		"getAnnouncements": func() []*announcements.Announcement {
+			if panel.announcements == nil {
+				return nil
+			}
+			return panel.announcements()
+		},
+		"getCaptcha": func() *captcha.Widget {
+			return captcha.GetWidget(panel.conf)
+		},
+		"getPasswordRequirements": func() []string {
+			return panel.conf.Security.PasswordPolicy.Requirements()
+		},
 		"getOperatorName": func(opID string) string {
-			opName, _ := data.QueryOperatorName(opID, panel.conf.Mentix.URL, panel.conf.Mentix.DataEndpoint)
+			opName, _ := data.QueryOperatorName(opID, panel.conf.Mentix.URL, panel.conf.Mentix.DataEndpoint, &panel.conf.Mentix.Signing)
 			return opName
 		},
 		"getOperatorSites": func(opID string, fullNames bool) string {
-			sites, _ := data.QueryOperatorSites(opID, panel.conf.Mentix.URL, panel.conf.Mentix.DataEndpoint)
+			sites, _ := data.QueryOperatorSites(opID, panel.conf.Mentix.URL, panel.conf.Mentix.DataEndpoint, &panel.conf.Mentix.Signing)
 			for i, s := range sites {
-				longName, _ := data.QuerySiteName(s, true, panel.conf.Mentix.URL, panel.conf.Mentix.DataEndpoint)
+				longName, _ := data.QuerySiteName(s, true, panel.conf.Mentix.URL, panel.conf.Mentix.DataEndpoint, &panel.conf.Mentix.Signing)
 				if fullNames {
-					shortName, _ := data.QuerySiteName(s, false, panel.conf.Mentix.URL, panel.conf.Mentix.DataEndpoint)
+					shortName, _ := data.QuerySiteName(s, false, panel.conf.Mentix.URL, panel.conf.Mentix.DataEndpoint, &panel.conf.Mentix.Signing)
 					sites[i] = fmt.Sprintf("%v (%v)", longName, shortName)
 				} else {
 					sites[i] = longName
@@ -0,0 +1,511 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package qrcode renders short texts, like the provisioning URIs of authenticator apps, as QR codes.
+// Only the byte mode and the error correction level M are supported, for texts of up to 213 bytes.
+package qrcode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+
+	"github.com/pkg/errors"
+)
+
+// QRCode is a matrix of modules; true denotes a dark module.
+type QRCode struct {
+	Size    int
+	Modules [][]bool
+
+	isFunction [][]bool
+}
+
+type versionInfo struct {
+	totalCodewords int
+	numBlocks      int
+	eccPerBlock    int
+	alignment      []int
+}
+
+// The block structure of the versions 1 to 10 at the error correction level M.
+var versions = []versionInfo{
+	{26, 1, 10, nil},
+	{44, 1, 16, []int{6, 18}},
+	{70, 1, 26, []int{6, 22}},
+	{100, 2, 18, []int{6, 26}},
+	{134, 2, 24, []int{6, 30}},
+	{172, 4, 16, []int{6, 34}},
+	{196, 4, 18, []int{6, 22, 38}},
+	{242, 4, 22, []int{6, 24, 42}},
+	{292, 5, 22, []int{6, 26, 46}},
+	{346, 5, 26, []int{6, 28, 50}},
+}
+
+const (
+	// The format bits of the error correction level M.
+	eccLevelM = 0
+
+	penaltyN1 = 3
+	penaltyN2 = 3
+	penaltyN3 = 40
+	penaltyN4 = 10
+)
+
+// Encode encodes the given text as a QR code, choosing the smallest fitting version and the mask with the lowest penalty.
+func Encode(text string) (*QRCode, error) {
+	data := []byte(text)
+	for i, v := range versions {
+		version := i + 1
+		countBits := 8
+		if version >= 10 {
+			countBits = 16
+		}
+		capacity := (v.totalCodewords - v.numBlocks*v.eccPerBlock) * 8
+		if 4+countBits+len(data)*8 > capacity {
+			continue
+		}
+
+		codewords := addECCAndInterleave(encodeData(data, countBits, capacity), v)
+
+		var best *QRCode
+		bestPenalty := -1
+		for mask := 0; mask < 8; mask++ {
+			qr := newQRCode(version, v)
+			qr.drawCodewords(codewords)
+			qr.applyMask(mask)
+			qr.drawFormatBits(mask)
+			if p := qr.penalty(); bestPenalty < 0 || p < bestPenalty {
+				best, bestPenalty = qr, p
+			}
+		}
+		return best, nil
+	}
+	return nil, errors.Errorf("text too long to be encoded as a QR code")
+}
+
+// PNG renders the QR code as a PNG image, using the given number of pixels per module and a quiet zone of four modules.
+func (qr *QRCode) PNG(scale int) ([]byte, error) {
+	if scale < 1 {
+		scale = 1
+	}
+	border := 4
+	dim := (qr.Size + 2*border) * scale
+	img := image.NewGray(image.Rect(0, 0, dim, dim))
+	for y := 0; y < dim; y++ {
+		for x := 0; x < dim; x++ {
+			mx, my := x/scale-border, y/scale-border
+			c := color.Gray{Y: 255}
+			if mx >= 0 && my >= 0 && mx < qr.Size && my < qr.Size && qr.Modules[my][mx] {
+				c = color.Gray{Y: 0}
+			}
+			img.SetGray(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, errors.Wrap(err, "unable to encode the QR code")
+	}
+	return buf.Bytes(), nil
+}
+
+// DataURI encodes the given text as a QR code and returns it as a PNG data URI, ready to be used as the source of an image.
+func DataURI(text string, scale int) (string, error) {
+	qr, err := Encode(text)
+	if err != nil {
+		return "", err
+	}
+	img, err := qr.PNG(scale)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(img), nil
+}
+
+func encodeData(data []byte, countBits int, capacity int) []byte {
+	var bb bitBuffer
+	bb.append(0x4, 4) // Byte mode
+	bb.append(len(data), countBits)
+	for _, b := range data {
+		bb.append(int(b), 8)
+	}
+
+	// Add the terminator and pad up to a byte boundary
+	if term := capacity - len(bb); term < 4 {
+		bb.append(0, term)
+	} else {
+		bb.append(0, 4)
+	}
+	if rem := len(bb) % 8; rem != 0 {
+		bb.append(0, 8-rem)
+	}
+
+	// Fill the remaining capacity with the alternating pad bytes
+	for pad := 0xEC; len(bb) < capacity; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+	return bb.bytes()
+}
+
+func addECCAndInterleave(data []byte, v versionInfo) []byte {
+	numShortBlocks := v.numBlocks - v.totalCodewords%v.numBlocks
+	shortBlockLen := v.totalCodewords / v.numBlocks
+	divisor := reedSolomonDivisor(v.eccPerBlock)
+
+	blocks := make([][]byte, 0, v.numBlocks)
+	k := 0
+	for i := 0; i < v.numBlocks; i++ {
+		n := shortBlockLen - v.eccPerBlock
+		if i >= numShortBlocks {
+			n++
+		}
+		dat := append([]byte{}, data[k:k+n]...)
+		k += n
+		ecc := reedSolomonRemainder(dat, divisor)
+		if i < numShortBlocks {
+			// Placeholder to align the short blocks with the long ones; skipped when interleaving
+			dat = append(dat, 0)
+		}
+		blocks = append(blocks, append(dat, ecc...))
+	}
+
+	result := make([]byte, 0, v.totalCodewords)
+	for i := range blocks[0] {
+		for j, block := range blocks {
+			if i != shortBlockLen-v.eccPerBlock || j >= numShortBlocks {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func reedSolomonRemainder(data []byte, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range divisor {
+			result[i] ^= gfMultiply(coef, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies two elements of GF(2^8) modulo the polynomial 0x11D.
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>uint(i))&1) * int(x)
+	}
+	return byte(z)
+}
+
+func newQRCode(version int, v versionInfo) *QRCode {
+	size := version*4 + 17
+	qr := &QRCode{
+		Size:       size,
+		Modules:    make([][]bool, size),
+		isFunction: make([][]bool, size),
+	}
+	for i := 0; i < size; i++ {
+		qr.Modules[i] = make([]bool, size)
+		qr.isFunction[i] = make([]bool, size)
+	}
+
+	// Timing patterns
+	for i := 0; i < size; i++ {
+		qr.setFunctionModule(6, i, i%2 == 0)
+		qr.setFunctionModule(i, 6, i%2 == 0)
+	}
+
+	// Finder patterns, including their separators
+	qr.drawFinderPattern(3, 3)
+	qr.drawFinderPattern(size-4, 3)
+	qr.drawFinderPattern(3, size-4)
+
+	// Alignment patterns, except for the ones overlapping the finder patterns
+	last := len(v.alignment) - 1
+	for i, x := range v.alignment {
+		for j, y := range v.alignment {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					qr.setFunctionModule(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve the format areas; the actual bits are drawn once the mask is known
+	qr.drawFormatBits(0)
+
+	// Version information
+	if version >= 7 {
+		rem := version
+		for i := 0; i < 12; i++ {
+			rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+		}
+		bits := version<<12 | rem
+		for i := 0; i < 18; i++ {
+			bit := (bits>>uint(i))&1 != 0
+			a, b := size-11+i%3, i/3
+			qr.setFunctionModule(a, b, bit)
+			qr.setFunctionModule(b, a, bit)
+		}
+	}
+
+	return qr
+}
+
+func (qr *QRCode) setFunctionModule(x, y int, dark bool) {
+	qr.Modules[y][x] = dark
+	qr.isFunction[y][x] = true
+}
+
+func (qr *QRCode) drawFinderPattern(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx >= 0 && xx < qr.Size && yy >= 0 && yy < qr.Size {
+				dist := max(abs(dx), abs(dy))
+				qr.setFunctionModule(xx, yy, dist != 2 && dist != 4)
+			}
+		}
+	}
+}
+
+func (qr *QRCode) drawFormatBits(mask int) {
+	data := eccLevelM<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>uint(i))&1 != 0 }
+
+	// First copy, around the top left finder pattern
+	for i := 0; i <= 5; i++ {
+		qr.setFunctionModule(8, i, bit(i))
+	}
+	qr.setFunctionModule(8, 7, bit(6))
+	qr.setFunctionModule(8, 8, bit(7))
+	qr.setFunctionModule(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		qr.setFunctionModule(14-i, 8, bit(i))
+	}
+
+	// Second copy, split between the other two finder patterns
+	for i := 0; i < 8; i++ {
+		qr.setFunctionModule(qr.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		qr.setFunctionModule(8, qr.Size-15+i, bit(i))
+	}
+	qr.setFunctionModule(8, qr.Size-8, true) // Always dark
+}
+
+func (qr *QRCode) drawCodewords(data []byte) {
+	i := 0
+	for right := qr.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			// Skip the vertical timing pattern
+			right = 5
+		}
+		for vert := 0; vert < qr.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					// Upwards
+					y = qr.Size - 1 - vert
+				}
+				if !qr.isFunction[y][x] && i < len(data)*8 {
+					qr.Modules[y][x] = (data[i>>3]>>uint(7-i&7))&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (qr *QRCode) applyMask(mask int) {
+	for y := 0; y < qr.Size; y++ {
+		for x := 0; x < qr.Size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !qr.isFunction[y][x] {
+				qr.Modules[y][x] = !qr.Modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty computes the penalty score of the masked QR code as defined by the specification.
+func (qr *QRCode) penalty() int {
+	result := 0
+	at := func(x, y int, transpose bool) bool {
+		if transpose {
+			return qr.Modules[x][y]
+		}
+		return qr.Modules[y][x]
+	}
+
+	for _, transpose := range []bool{false, true} {
+		for y := 0; y < qr.Size; y++ {
+			// Runs of five or more modules of the same color
+			run := 1
+			for x := 1; x < qr.Size; x++ {
+				if at(x, y, transpose) == at(x-1, y, transpose) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					result += penaltyN1 + run - 5
+				}
+				run = 1
+			}
+			if run >= 5 {
+				result += penaltyN1 + run - 5
+			}
+
+			// Patterns looking like finder patterns
+			for x := 0; x+11 <= qr.Size; x++ {
+				if matchesFinderLike(func(i int) bool { return at(x+i, y, transpose) }) {
+					result += penaltyN3
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < qr.Size; y++ {
+		for x := 0; x < qr.Size; x++ {
+			if qr.Modules[y][x] {
+				dark++
+			}
+			// Blocks of 2x2 modules of the same color
+			if x+1 < qr.Size && y+1 < qr.Size {
+				c := qr.Modules[y][x]
+				if c == qr.Modules[y][x+1] && c == qr.Modules[y+1][x] && c == qr.Modules[y+1][x+1] {
+					result += penaltyN2
+				}
+			}
+		}
+	}
+
+	// Deviation of the proportion of dark modules from 50%
+	total := qr.Size * qr.Size
+	k := abs(dark*100/total-50) / 5
+	result += k * penaltyN4
+
+	return result
+}
+
+var finderLikePatterns = [][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+func matchesFinderLike(at func(int) bool) bool {
+	for _, pattern := range finderLikePatterns {
+		matches := true
+		for i, dark := range pattern {
+			if at(i) != dark {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+type bitBuffer []bool
+
+func (bb *bitBuffer) append(val int, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*bb = append(*bb, (val>>uint(i))&1 != 0)
+	}
+}
+
+func (bb bitBuffer) bytes() []byte {
+	result := make([]byte, (len(bb)+7)/8)
+	for i, bit := range bb {
+		if bit {
+			result[i>>3] |= 1 << uint(7-i&7)
+		}
+	}
+	return result
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,124 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package qrcode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestEncodeVersion(t *testing.T) {
+	tests := []struct {
+		length  int
+		version int
+	}{
+		{1, 1},
+		{14, 1},
+		{15, 2},
+		{100, 6},
+		{213, 10},
+	}
+
+	for _, tt := range tests {
+		qr, err := Encode(strings.Repeat("a", tt.length))
+		if err != nil {
+			t.Fatalf("%d bytes: unexpected error: %v", tt.length, err)
+		}
+		if size := 17 + 4*tt.version; qr.Size != size || len(qr.Modules) != size {
+			t.Errorf("%d bytes: expected version %d of size %d, got %d", tt.length, tt.version, size, qr.Size)
+		}
+	}
+
+	if _, err := Encode(strings.Repeat("a", 214)); err == nil {
+		t.Error("expected a too long text to be rejected")
+	}
+}
+
+func TestEncodePatterns(t *testing.T) {
+	qr, err := Encode("otpauth://totp/Science%20Mesh:john@example.org?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The finder patterns in three of the corners
+	for _, corner := range [][2]int{{0, 0}, {qr.Size - 7, 0}, {0, qr.Size - 7}} {
+		for dy := 0; dy < 7; dy++ {
+			for dx := 0; dx < 7; dx++ {
+				ring := max(abs(dx-3), abs(dy-3))
+				if dark := qr.Modules[corner[1]+dy][corner[0]+dx]; dark != (ring != 2) {
+					t.Fatalf("invalid finder pattern at %v", corner)
+				}
+			}
+		}
+	}
+
+	// The timing patterns alternate between dark and light modules
+	for i := 8; i < qr.Size-8; i++ {
+		if qr.Modules[6][i] != (i%2 == 0) || qr.Modules[i][6] != (i%2 == 0) {
+			t.Fatalf("invalid timing pattern at %d", i)
+		}
+	}
+
+	// The dark module next to the lower left finder pattern
+	if !qr.Modules[qr.Size-8][8] {
+		t.Error("expected the dark module to be set")
+	}
+}
+
+func TestReedSolomon(t *testing.T) {
+	data := []byte{0x40, 0xd2, 0x75, 0x47, 0x76, 0x17, 0x32, 0x06, 0x27, 0x26, 0x96, 0xc6, 0xc6, 0x96, 0x70, 0xec}
+	divisor := reedSolomonDivisor(10)
+	ecc := reedSolomonRemainder(data, divisor)
+	if len(ecc) != 10 {
+		t.Fatalf("expected 10 error correction codewords, got %d", len(ecc))
+	}
+
+	// A codeword made of the data and its error correction is divisible by the generator polynomial
+	for _, b := range reedSolomonRemainder(append(data, ecc...), divisor) {
+		if b != 0 {
+			t.Fatalf("expected the codeword to be divisible by the generator, got %v", ecc)
+		}
+	}
+}
+
+func TestDataURI(t *testing.T) {
+	uri, err := DataURI("hello", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(uri, "data:image/png;base64,") {
+		t.Fatalf("unexpected data URI %q", uri)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(uri, "data:image/png;base64,"))
+	if err != nil {
+		t.Fatalf("invalid base64 data: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid PNG: %v", err)
+	}
+	// Version 1 has 21 modules, surrounded by a quiet zone of four modules
+	if b := img.Bounds(); b.Dx() != (21+8)*3 || b.Dy() != (21+8)*3 {
+		t.Errorf("unexpected image size %v", b)
+	}
+}
@@ -19,12 +19,14 @@
 package html
 
 import (
+	"crypto/subtle"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
 	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/cs3org/reva/pkg/utils"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 )
@@ -40,6 +42,9 @@ type Session struct {
 	Data map[string]interface{}
 
 	loggedInUser *SessionUser
+	pendingLogin *PendingLogin
+	oidcLogin    *OIDCLogin
+	apiKeyUser   *APIKeyUser
 
 	expirationTime time.Time
 	halflifeTime   time.Time
@@ -51,14 +56,38 @@ type Session struct {
 type SessionUser struct {
 	Account  *data.Account
 	Operator *data.Operator
+
+	// ManagedSites holds the sites of other operators the user has been granted management rights over.
+	ManagedSites data.Operators
+}
+
+// APIKeyUser holds information about the operator API key a request has been authenticated with.
+type APIKeyUser struct {
+	Operator *data.Operator
+	Key      *data.OperatorAPIKey
+}
+
+// PendingLogin holds a login awaiting the second authentication factor.
+type PendingLogin struct {
+	User  *SessionUser
+	Scope string
+
+	Attempts   int
+	Expiration time.Time
+}
+
+// OIDCLogin holds a login through an OpenID Connect provider awaiting the user to be redirected back.
+type OIDCLogin struct {
+	State string
+	Nonce string
+	Scope string
+
+	Expiration time.Time
 }
 
 func getRemoteAddress(r *http.Request) string {
-	// Remove the port number from the remote address
-	remoteAddress := ""
-	if address := strings.Split(r.RemoteAddr, ":"); len(address) == 2 {
-		remoteAddress = address[0]
-	}
+	// Behind trusted proxies, this is the address of the client reported by them
+	remoteAddress, _ := utils.GetClientIP(r)
 	return remoteAddress
 }
 
@@ -73,11 +102,59 @@ func (sess *Session) LoginUser(acc *data.Account, op *data.Operator) {
 		Account:  acc,
 		Operator: op,
 	}
+	sess.pendingLogin = nil
 }
 
 // LogoutUser logs out the currently logged in user.
 func (sess *Session) LogoutUser() {
 	sess.loggedInUser = nil
+	sess.pendingLogin = nil
+}
+
+// BeginPendingLogin stores a login that needs to be completed using a second authentication factor within the given timeout.
+func (sess *Session) BeginPendingLogin(acc *data.Account, op *data.Operator, scope string, timeout time.Duration) {
+	sess.pendingLogin = &PendingLogin{
+		User: &SessionUser{
+			Account:  acc,
+			Operator: op,
+		},
+		Scope:      scope,
+		Expiration: time.Now().Add(timeout),
+	}
+}
+
+// PendingLogin retrieves the login awaiting the second authentication factor or nil if there is none (or it has expired).
+func (sess *Session) PendingLogin() *PendingLogin {
+	if sess.pendingLogin != nil && time.Now().After(sess.pendingLogin.Expiration) {
+		sess.pendingLogin = nil
+	}
+	return sess.pendingLogin
+}
+
+// ClearPendingLogin discards the login awaiting the second authentication factor.
+func (sess *Session) ClearPendingLogin() {
+	sess.pendingLogin = nil
+}
+
+// BeginOIDCLogin stores a login through an OpenID Connect provider that needs to be completed within the given timeout.
+func (sess *Session) BeginOIDCLogin(state, nonce, scope string, timeout time.Duration) {
+	sess.oidcLogin = &OIDCLogin{
+		State:      state,
+		Nonce:      nonce,
+		Scope:      scope,
+		Expiration: time.Now().Add(timeout),
+	}
+}
+
+// TakeOIDCLogin retrieves and discards the login through an OpenID Connect provider matching the given state; nil is returned if there is none (or it has expired).
+func (sess *Session) TakeOIDCLogin(state string) *OIDCLogin {
+	login := sess.oidcLogin
+	sess.oidcLogin = nil
+
+	if login == nil || state == "" || subtle.ConstantTimeCompare([]byte(login.State), []byte(state)) != 1 || time.Now().After(login.Expiration) {
+		return nil
+	}
+	return login
 }
 
 // IsUserLoggedIn tells whether a user is currently logged in.
@@ -85,6 +162,11 @@ func (sess *Session) IsUserLoggedIn() bool {
 	return sess.loggedInUser != nil
 }
 
+// APIKeyUser retrieves the API key the request of the session has been authenticated with or nil if none was used.
+func (sess *Session) APIKeyUser() *APIKeyUser {
+	return sess.apiKeyUser
+}
+
 // Save stores the session ID in a cookie using a response writer.
 func (sess *Session) Save(cookiePath string, w http.ResponseWriter) {
 	fullURL, _ := url.Parse(cookiePath)
@@ -128,6 +210,11 @@ func (sess *Session) HasExpired() bool {
 	return time.Now().After(sess.expirationTime)
 }
 
+// Extend restarts the timeout of the session, so that it only expires after a period of inactivity.
+func (sess *Session) Extend() {
+	sess.expirationTime = time.Now().Add(sess.Timeout)
+}
+
 // NewSession creates a new session, giving it a random ID.
 func NewSession(name string, timeout time.Duration, r *http.Request) *Session {
 	session := &Session{
@@ -144,3 +231,13 @@ func NewSession(name string, timeout time.Duration, r *http.Request) *Session {
 	}
 	return session
 }
+
+// NewAPIKeySession creates a transient session for a request authenticated with an API key; such sessions are never stored.
+func NewAPIKeySession(r *http.Request, op *data.Operator, key *data.OperatorAPIKey) *Session {
+	session := NewSession("", 0, r)
+	session.apiKeyUser = &APIKeyUser{
+		Operator: op,
+		Key:      key,
+	}
+	return session
+}
@@ -0,0 +1,61 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package html
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTakeOIDCLogin(t *testing.T) {
+	tests := []struct {
+		name    string
+		begin   bool
+		timeout time.Duration
+		state   string
+		want    bool
+	}{
+		{name: "matching state", begin: true, timeout: time.Minute, state: "state", want: true},
+		{name: "state mismatch", begin: true, timeout: time.Minute, state: "other", want: false},
+		{name: "missing state", begin: true, timeout: time.Minute, state: "", want: false},
+		{name: "expired login", begin: true, timeout: -time.Second, state: "state", want: false},
+		{name: "no login", state: "state", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newTestSession(time.Hour)
+			if tt.begin {
+				session.BeginOIDCLogin("state", "nonce", "scope", tt.timeout)
+			}
+
+			login := session.TakeOIDCLogin(tt.state)
+			if got := login != nil; got != tt.want {
+				t.Fatalf("TakeOIDCLogin(%q) = %v, want a login: %v", tt.state, login, tt.want)
+			}
+			if login != nil && (login.Nonce != "nonce" || login.Scope != "scope") {
+				t.Errorf("TakeOIDCLogin(%q) = %+v, want the begun login", tt.state, login)
+			}
+
+			// A login can only be taken once, even with the right state after a failed attempt
+			if login := session.TakeOIDCLogin("state"); login != nil {
+				t.Errorf("TakeOIDCLogin(state) = %+v after taking the login, want nil", login)
+			}
+		})
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/metrics"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
@@ -34,14 +35,16 @@ type SessionManager struct {
 	conf *config.Configuration
 	log  *zerolog.Logger
 
-	sessions map[string]*Session
+	store SessionStore
 
 	sessionName string
 
 	mutex sync.Mutex
+
+	stopChan chan struct{}
 }
 
-func (mngr *SessionManager) initialize(name string, conf *config.Configuration, log *zerolog.Logger) error {
+func (mngr *SessionManager) initialize(name string, conf *config.Configuration, resolver SessionUserResolver, log *zerolog.Logger) error {
 	if name == "" {
 		return errors.Errorf("no session name provided")
 	}
@@ -57,7 +60,14 @@ func (mngr *SessionManager) initialize(name string, conf *config.Configuration,
 	}
 	mngr.log = log
 
-	mngr.sessions = make(map[string]*Session, 100)
+	store, err := NewSessionStore(name, conf, resolver)
+	if err != nil {
+		return errors.Wrap(err, "unable to create the session store")
+	}
+	mngr.store = store
+
+	mngr.stopChan = make(chan struct{})
+	go mngr.purgePeriodically()
 
 	return nil
 }
@@ -73,7 +83,10 @@ func (mngr *SessionManager) HandleRequest(w http.ResponseWriter, r *http.Request
 	// Try to get the session ID from the request; if none has been set yet, a new one will be assigned
 	cookie, err := r.Cookie(mngr.sessionName)
 	if err == nil {
-		session = mngr.findSession(cookie.Value)
+		session, err = mngr.store.Get(cookie.Value)
+		if err != nil {
+			sessionErr = errors.Wrap(err, "unable to load the session")
+		}
 		if session != nil {
 			mngr.logSessionInfo(session, r, "existing session found")
 
@@ -114,42 +127,61 @@ func (mngr *SessionManager) HandleRequest(w http.ResponseWriter, r *http.Request
 		session = mngr.createSession(r)
 
 		mngr.logSessionInfo(session, r, "assigned new session")
+	} else if mngr.conf.Webserver.SlidingSessions {
+		// Every request of the client restarts the timeout of its session
+		session.Extend()
 	}
 
 	// Store the session ID on the client side
 	session.Save(mngr.conf.Webserver.URL, w)
 
+	if err := mngr.store.Put(session); err != nil && sessionErr == nil {
+		sessionErr = errors.Wrap(err, "unable to store the session")
+	}
+
 	return session, sessionErr
 }
 
-// PurgeSessions removes any expired sessions.
-func (mngr *SessionManager) PurgeSessions() {
-	mngr.mutex.Lock()
-	defer mngr.mutex.Unlock()
-
-	var expiredSessions []string
-	for id, session := range mngr.sessions {
-		if session.HasExpired() {
-			expiredSessions = append(expiredSessions, id)
-		}
+// SaveSession stores the changes made to a session while handling a request.
+func (mngr *SessionManager) SaveSession(session *Session) error {
+	if err := mngr.store.Put(session); err != nil {
+		return errors.Wrap(err, "unable to store the session")
 	}
+	return nil
+}
 
-	for _, id := range expiredSessions {
-		delete(mngr.sessions, id)
+// PurgeSessions removes any expired sessions.
+func (mngr *SessionManager) PurgeSessions() {
+	count, err := mngr.store.Purge()
+	if err != nil {
+		mngr.log.Err(err).Msg("unable to purge the expired sessions")
+		return
 	}
+	metrics.RecordActiveSessions(count)
 }
 
-func (mngr *SessionManager) createSession(r *http.Request) *Session {
-	session := NewSession(mngr.sessionName, time.Duration(mngr.conf.Webserver.SessionTimeout)*time.Second, r)
-	mngr.sessions[session.ID] = session
-	return session
+// Close stops the periodic removal of expired sessions and closes the session store.
+func (mngr *SessionManager) Close() error {
+	close(mngr.stopChan)
+	return mngr.store.Close()
 }
 
-func (mngr *SessionManager) findSession(id string) *Session {
-	if session, ok := mngr.sessions[id]; ok {
-		return session
+func (mngr *SessionManager) purgePeriodically() {
+	ticker := time.NewTicker(time.Duration(mngr.conf.Webserver.SessionCleanupInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-mngr.stopChan:
+			return
+		case <-ticker.C:
+			mngr.PurgeSessions()
+		}
 	}
-	return nil
+}
+
+func (mngr *SessionManager) createSession(r *http.Request) *Session {
+	return NewSession(mngr.sessionName, time.Duration(mngr.conf.Webserver.SessionTimeout)*time.Second, r)
 }
 
 func (mngr *SessionManager) migrateSession(session *Session, r *http.Request) (*Session, error) {
@@ -164,9 +196,13 @@ func (mngr *SessionManager) migrateSession(session *Session, r *http.Request) (*
 	} else {
 		sessionNew.LogoutUser()
 	}
+	sessionNew.pendingLogin = session.pendingLogin
+	sessionNew.oidcLogin = session.oidcLogin
 
 	// Delete the old session
-	delete(mngr.sessions, session.ID)
+	if err := mngr.store.Delete(session.ID); err != nil {
+		return nil, err
+	}
 
 	return sessionNew, nil
 }
@@ -181,10 +217,10 @@ func (mngr *SessionManager) logSessionInfo(session *Session, r *http.Request, in
 	}
 }
 
-// NewSessionManager creates a new session manager.
-func NewSessionManager(name string, conf *config.Configuration, log *zerolog.Logger) (*SessionManager, error) {
+// NewSessionManager creates a new session manager; the resolver is used to restore the users of sessions kept outside of the process.
+func NewSessionManager(name string, conf *config.Configuration, resolver SessionUserResolver, log *zerolog.Logger) (*SessionManager, error) {
 	mngr := &SessionManager{}
-	if err := mngr.initialize(name, conf, log); err != nil {
+	if err := mngr.initialize(name, conf, resolver, log); err != nil {
 		return nil, errors.Wrap(err, "unable to initialize the session manager")
 	}
 	return mngr, nil
@@ -0,0 +1,112 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package html
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/pkg/errors"
+)
+
+// The available session stores.
+const (
+	SessionStoreMemory = "memory"
+	SessionStoreRedis  = "redis"
+)
+
+// SessionStore stores the HTML sessions.
+type SessionStore interface {
+	// Get returns the session with the given ID or nil if there is none.
+	Get(id string) (*Session, error)
+	// Put stores the session, replacing any previously stored version.
+	Put(session *Session) error
+	// Delete removes the session with the given ID.
+	Delete(id string) error
+	// Purge removes all expired sessions and returns the number of remaining ones.
+	Purge() (int, error)
+	// Close releases all resources held by the store.
+	Close() error
+}
+
+// SessionUserResolver looks up the account and operator of a user by the email of the account; stores keeping the
+// sessions outside of the process only store the email and use this to restore the users.
+type SessionUserResolver func(email string) (*data.Account, *data.Operator, error)
+
+// NewSessionStore creates the session store configured for the given session name.
+func NewSessionStore(name string, conf *config.Configuration, resolver SessionUserResolver) (SessionStore, error) {
+	switch strings.ToLower(conf.Webserver.SessionStore) {
+	case "", SessionStoreMemory:
+		return newMemorySessionStore(), nil
+	case SessionStoreRedis:
+		return newRedisSessionStore(name, conf, resolver)
+	default:
+		return nil, errors.Errorf("unknown session store %v", conf.Webserver.SessionStore)
+	}
+}
+
+// memorySessionStore keeps the sessions in memory, so they are lost on restart and not shared among instances.
+type memorySessionStore struct {
+	sessions map[string]*Session
+	mutex    sync.Mutex
+}
+
+func newMemorySessionStore() *memorySessionStore {
+	return &memorySessionStore{
+		sessions: make(map[string]*Session, 100),
+	}
+}
+
+func (store *memorySessionStore) Get(id string) (*Session, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.sessions[id], nil
+}
+
+func (store *memorySessionStore) Put(session *Session) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.sessions[session.ID] = session
+	return nil
+}
+
+func (store *memorySessionStore) Delete(id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.sessions, id)
+	return nil
+}
+
+func (store *memorySessionStore) Purge() (int, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	for id, session := range store.sessions {
+		if session.HasExpired() {
+			delete(store.sessions, id)
+		}
+	}
+	return len(store.sessions), nil
+}
+
+func (store *memorySessionStore) Close() error {
+	return nil
+}
@@ -0,0 +1,244 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package html
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+)
+
+// redisSessionStore keeps the sessions in Redis, so that they survive restarts and are shared among instances.
+// Only the email of the logged in user is stored; the account itself is looked up anew whenever a session is loaded.
+type redisSessionStore struct {
+	pool     *redis.Pool
+	prefix   string
+	name     string
+	resolver SessionUserResolver
+}
+
+// storedSession is the representation of a session in Redis.
+type storedSession struct {
+	ID            string
+	MigrationID   string
+	RemoteAddress string
+	CreationTime  time.Time
+	Timeout       time.Duration
+
+	Data map[string]interface{}
+
+	User         string
+	PendingLogin *storedPendingLogin
+	OIDCLogin    *OIDCLogin
+
+	ExpirationTime time.Time
+	HalflifeTime   time.Time
+}
+
+type storedPendingLogin struct {
+	User  string
+	Scope string
+
+	Attempts   int
+	Expiration time.Time
+}
+
+func newRedisSessionStore(name string, conf *config.Configuration, resolver SessionUserResolver) (*redisSessionStore, error) {
+	if resolver == nil {
+		return nil, errors.Errorf("no session user resolver provided")
+	}
+
+	redisConf := conf.Webserver.Redis
+	pool := &redis.Pool{
+		MaxIdle:     50,
+		MaxActive:   1000,
+		IdleTimeout: 240 * time.Second,
+
+		Dial: func() (redis.Conn, error) {
+			var opts []redis.DialOption
+			if redisConf.Username != "" {
+				opts = append(opts, redis.DialUsername(redisConf.Username))
+			}
+			if redisConf.Password != "" {
+				opts = append(opts, redis.DialPassword(redisConf.Password))
+			}
+			return redis.Dial("tcp", redisConf.Address, opts...)
+		},
+
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+
+	return &redisSessionStore{
+		pool:     pool,
+		prefix:   redisConf.Prefix + name + ":",
+		name:     name,
+		resolver: resolver,
+	}, nil
+}
+
+func (store *redisSessionStore) Get(id string) (*Session, error) {
+	conn := store.pool.Get()
+	defer conn.Close()
+
+	value, err := redis.Bytes(conn.Do("GET", store.prefix+id))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the session from Redis")
+	}
+
+	stored := &storedSession{}
+	if err := json.Unmarshal(value, stored); err != nil {
+		return nil, errors.Wrap(err, "unable to decode the session")
+	}
+	return store.restore(stored), nil
+}
+
+func (store *redisSessionStore) Put(session *Session) error {
+	// The session is removed by Redis once it has expired
+	ttl := time.Until(session.expirationTime)
+	if ttl <= 0 {
+		return store.Delete(session.ID)
+	}
+
+	value, err := json.Marshal(store.store(session))
+	if err != nil {
+		return errors.Wrap(err, "unable to encode the session")
+	}
+
+	conn := store.pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("SET", store.prefix+session.ID, value, "PX", ttl.Milliseconds()+1); err != nil {
+		return errors.Wrap(err, "unable to write the session to Redis")
+	}
+	return nil
+}
+
+func (store *redisSessionStore) Delete(id string) error {
+	conn := store.pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("DEL", store.prefix+id); err != nil {
+		return errors.Wrap(err, "unable to delete the session from Redis")
+	}
+	return nil
+}
+
+// Purge only counts the sessions, as Redis removes the expired ones by itself.
+func (store *redisSessionStore) Purge() (int, error) {
+	conn := store.pool.Get()
+	defer conn.Close()
+
+	count := 0
+	cursor := 0
+	for {
+		values, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", store.prefix+"*", "COUNT", 1000))
+		if err != nil {
+			return 0, errors.Wrap(err, "unable to scan the sessions in Redis")
+		}
+
+		if len(values) != 2 {
+			return 0, errors.Errorf("unexpected reply to the scan of the sessions in Redis")
+		}
+		if cursor, err = redis.Int(values[0], nil); err != nil {
+			return 0, errors.Wrap(err, "unable to scan the sessions in Redis")
+		}
+		keys, err := redis.Strings(values[1], nil)
+		if err != nil {
+			return 0, errors.Wrap(err, "unable to scan the sessions in Redis")
+		}
+		count += len(keys)
+
+		if cursor == 0 {
+			return count, nil
+		}
+	}
+}
+
+func (store *redisSessionStore) Close() error {
+	return store.pool.Close()
+}
+
+func (store *redisSessionStore) store(session *Session) *storedSession {
+	stored := &storedSession{
+		ID:             session.ID,
+		MigrationID:    session.MigrationID,
+		RemoteAddress:  session.RemoteAddress,
+		CreationTime:   session.CreationTime,
+		Timeout:        session.Timeout,
+		Data:           session.Data,
+		OIDCLogin:      session.oidcLogin,
+		ExpirationTime: session.expirationTime,
+		HalflifeTime:   session.halflifeTime,
+	}
+	if user := session.loggedInUser; user != nil && user.Account != nil {
+		stored.User = user.Account.Email
+	}
+	if login := session.pendingLogin; login != nil && login.User != nil && login.User.Account != nil {
+		stored.PendingLogin = &storedPendingLogin{
+			User:       login.User.Account.Email,
+			Scope:      login.Scope,
+			Attempts:   login.Attempts,
+			Expiration: login.Expiration,
+		}
+	}
+	return stored
+}
+
+// restore recreates a stored session; users whose account can no longer be found are logged out.
+func (store *redisSessionStore) restore(stored *storedSession) *Session {
+	session := &Session{
+		ID:                stored.ID,
+		MigrationID:       stored.MigrationID,
+		RemoteAddress:     stored.RemoteAddress,
+		CreationTime:      stored.CreationTime,
+		Timeout:           stored.Timeout,
+		Data:              stored.Data,
+		oidcLogin:         stored.OIDCLogin,
+		expirationTime:    stored.ExpirationTime,
+		halflifeTime:      stored.HalflifeTime,
+		sessionCookieName: store.name,
+	}
+	if session.Data == nil {
+		session.Data = make(map[string]interface{}, 10)
+	}
+
+	if stored.User != "" {
+		if acc, op, err := store.resolver(stored.User); err == nil {
+			session.loggedInUser = &SessionUser{Account: acc, Operator: op}
+		}
+	}
+	if login := stored.PendingLogin; login != nil {
+		if acc, op, err := store.resolver(login.User); err == nil {
+			session.pendingLogin = &PendingLogin{
+				User:       &SessionUser{Account: acc, Operator: op},
+				Scope:      login.Scope,
+				Attempts:   login.Attempts,
+				Expiration: login.Expiration,
+			}
+		}
+	}
+	return session
+}
@@ -0,0 +1,184 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package html
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/pkg/errors"
+)
+
+func newTestSession(timeout time.Duration) *Session {
+	return NewSession("test_session", timeout, httptest.NewRequest("GET", "/", nil))
+}
+
+func TestMemorySessionStore(t *testing.T) {
+	store := newMemorySessionStore()
+	active := newTestSession(time.Hour)
+	expired := newTestSession(-time.Second)
+
+	for _, session := range []*Session{active, expired} {
+		if err := store.Put(session); err != nil {
+			t.Fatalf("Put: unexpected error: %v", err)
+		}
+	}
+
+	if session, _ := store.Get(active.ID); session != active {
+		t.Errorf("Get(%v) = %v, want the stored session", active.ID, session)
+	}
+	if session, _ := store.Get("unknown"); session != nil {
+		t.Errorf("Get(unknown) = %v, want nil", session)
+	}
+
+	count, err := store.Purge()
+	if err != nil {
+		t.Fatalf("Purge: unexpected error: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Purge() = %d, want 1", count)
+	}
+	if session, _ := store.Get(expired.ID); session != nil {
+		t.Errorf("expired session survived the purge")
+	}
+
+	if err := store.Delete(active.ID); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+	if session, _ := store.Get(active.ID); session != nil {
+		t.Errorf("deleted session is still stored")
+	}
+}
+
+func TestNewSessionStore(t *testing.T) {
+	resolver := func(email string) (*data.Account, *data.Operator, error) {
+		return nil, nil, errors.Errorf("no user %v", email)
+	}
+
+	tests := []struct {
+		store    string
+		resolver SessionUserResolver
+		wantErr  bool
+	}{
+		{store: "", resolver: nil},
+		{store: "Memory", resolver: nil},
+		{store: "redis", resolver: resolver},
+		{store: "redis", resolver: nil, wantErr: true},
+		{store: "memcached", resolver: resolver, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		conf := &config.Configuration{}
+		conf.Webserver.SessionStore = tt.store
+		store, err := NewSessionStore("test_session", conf, tt.resolver)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewSessionStore(%q): error = %v, wantErr %v", tt.store, err, tt.wantErr)
+			continue
+		}
+		if store != nil {
+			_ = store.Close()
+		}
+	}
+}
+
+func TestRedisSessionSerialization(t *testing.T) {
+	acc := &data.Account{Email: "john@example.com", Operator: "op"}
+	op := &data.Operator{ID: "op"}
+	resolver := func(email string) (*data.Account, *data.Operator, error) {
+		if email != acc.Email {
+			return nil, nil, errors.Errorf("no user %v", email)
+		}
+		return acc, op, nil
+	}
+
+	conf := &config.Configuration{}
+	conf.Webserver.Redis.Prefix = "reva:"
+	store, err := newRedisSessionStore("test_session", conf, resolver)
+	if err != nil {
+		t.Fatalf("newRedisSessionStore: unexpected error: %v", err)
+	}
+	defer store.Close()
+	if store.prefix != "reva:test_session:" {
+		t.Errorf("prefix = %q, want %q", store.prefix, "reva:test_session:")
+	}
+
+	roundTrip := func(session *Session) *Session {
+		value, err := json.Marshal(store.store(session))
+		if err != nil {
+			t.Fatalf("unable to encode the session: %v", err)
+		}
+		stored := &storedSession{}
+		if err := json.Unmarshal(value, stored); err != nil {
+			t.Fatalf("unable to decode the session: %v", err)
+		}
+		return store.restore(stored)
+	}
+
+	session := newTestSession(time.Hour)
+	session.Data["lang"] = "de"
+	session.LoginUser(acc, op)
+	session.BeginOIDCLogin("state", "nonce", "profile", time.Minute)
+
+	restored := roundTrip(session)
+	if restored.ID != session.ID || restored.RemoteAddress != session.RemoteAddress || restored.Timeout != session.Timeout {
+		t.Errorf("restored session %+v does not match %+v", restored, session)
+	}
+	if !restored.expirationTime.Equal(session.expirationTime) || !restored.halflifeTime.Equal(session.halflifeTime) {
+		t.Errorf("session times were not restored")
+	}
+	if restored.sessionCookieName != "test_session" {
+		t.Errorf("sessionCookieName = %q, want %q", restored.sessionCookieName, "test_session")
+	}
+	if restored.Data["lang"] != "de" {
+		t.Errorf("Data[lang] = %v, want de", restored.Data["lang"])
+	}
+	if user := restored.LoggedInUser(); user == nil || user.Account != acc || user.Operator != op {
+		t.Errorf("LoggedInUser() = %+v, want the resolved user", user)
+	}
+	if login := restored.TakeOIDCLogin("state"); login == nil || login.Nonce != "nonce" {
+		t.Errorf("TakeOIDCLogin() = %+v, want the stored login", login)
+	}
+
+	// A pending login is restored along with its attempts
+	pending := newTestSession(time.Hour)
+	pending.BeginPendingLogin(acc, op, "login", time.Minute)
+	pending.pendingLogin.Attempts = 2
+	restored = roundTrip(pending)
+	if restored.IsUserLoggedIn() {
+		t.Errorf("session with a pending login is logged in")
+	}
+	if login := restored.PendingLogin(); login == nil || login.User.Account != acc || login.Scope != "login" || login.Attempts != 2 {
+		t.Errorf("PendingLogin() = %+v, want the stored login", login)
+	}
+
+	// Users that can no longer be resolved are logged out
+	gone := newTestSession(time.Hour)
+	gone.LoginUser(&data.Account{Email: "gone@example.com"}, op)
+	restored = roundTrip(gone)
+	if restored.IsUserLoggedIn() {
+		t.Errorf("session of an unknown user is still logged in")
+	}
+	if restored.Data == nil {
+		t.Errorf("Data of the restored session is nil")
+	}
+}
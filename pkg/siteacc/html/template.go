@@ -140,6 +140,18 @@ const panelTemplate = `
 			border-color: #F20000;
 			background: #F4D0D0;
 		}
+		.announcement-info {
+			border-color: #2A7AB0;
+			background: #D4E8F5;
+		}
+		.announcement-warning {
+			border-color: #F7B22A;
+			background: #FFEABF;
+		}
+		.announcement-critical {
+			border-color: #F20000;
+			background: #F4D0D0;
+		}
 		.visible {
 			display: block;
 		}
@@ -156,6 +168,13 @@ const panelTemplate = `
 <div class="container">
 	<div><h1>$(CAPTION)</h1></div>
 	
+	{{range getAnnouncements}}
+	<div class="box announcement-{{.Severity}}">
+		<strong>{{.Title}}</strong><br>
+		{{.Message}}
+	</div>
+	{{end}}
+
 	$(CONTENT_BODY)
 	
 	<div id="status" class="box status hidden">
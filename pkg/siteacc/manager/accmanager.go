@@ -19,11 +19,16 @@
 package manager
 
 import (
+	"crypto/subtle"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/cs3org/reva/pkg/announcements"
+	"github.com/cs3org/reva/pkg/mentix/exchangers/exporters/webhook"
 	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/credentials"
 	"github.com/cs3org/reva/pkg/siteacc/data"
 	"github.com/cs3org/reva/pkg/siteacc/email"
 	"github.com/cs3org/reva/pkg/siteacc/manager/gocdb"
@@ -38,6 +43,11 @@ const (
 	FindByEmail = "email"
 )
 
+const (
+	deletionTokenLength     = 32
+	verificationTokenLength = 32
+)
+
 // AccountsManager is responsible for all sites account related tasks.
 type AccountsManager struct {
 	conf *config.Configuration
@@ -103,6 +113,44 @@ func (mngr *AccountsManager) writeAllAccounts() {
 	}
 }
 
+// ReloadAccounts reloads all accounts if they have been modified by another service instance sharing the same storage.
+func (mngr *AccountsManager) ReloadAccounts() {
+	shared, ok := mngr.storage.(data.SharedStorage)
+	if !ok {
+		return
+	}
+	if modified, err := shared.AccountsModified(); err != nil {
+		mngr.log.Warn().Err(err).Msg("error while checking for modified accounts")
+		return
+	} else if !modified {
+		return
+	}
+
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	accounts, err := mngr.storage.ReadAccounts()
+	if err != nil {
+		mngr.log.Warn().Err(err).Msg("error while reloading accounts")
+		return
+	}
+
+	// Existing accounts are updated in place, as they are also referenced by the sessions of logged in users
+	current := make(map[string]*data.Account, len(mngr.accounts))
+	for _, account := range mngr.accounts {
+		current[strings.ToLower(account.Email)] = account
+	}
+	reloaded := make(data.Accounts, 0, len(*accounts))
+	for _, account := range *accounts {
+		if existing, ok := current[strings.ToLower(account.Email)]; ok {
+			*existing = *account
+			account = existing
+		}
+		reloaded = append(reloaded, account)
+	}
+	mngr.accounts = reloaded
+}
+
 func (mngr *AccountsManager) findAccount(by string, value string) (*data.Account, error) {
 	if len(value) == 0 {
 		return nil, errors.Errorf("no search value specified")
@@ -135,6 +183,10 @@ func (mngr *AccountsManager) findAccountByPredicate(predicate func(*data.Account
 
 // CreateAccount creates a new account; if an account with the same email address already exists, an error is returned.
 func (mngr *AccountsManager) CreateAccount(accountData *data.Account) error {
+	return mngr.createAccount(accountData, &mngr.conf.Security.PasswordPolicy)
+}
+
+func (mngr *AccountsManager) createAccount(accountData *data.Account, policy *credentials.PasswordPolicy) error {
 	mngr.mutex.Lock()
 	defer mngr.mutex.Unlock()
 
@@ -143,12 +195,18 @@ func (mngr *AccountsManager) CreateAccount(accountData *data.Account) error {
 		return errors.Errorf("an account with the specified email address already exists")
 	}
 
-	if account, err := data.NewAccount(accountData.Email, accountData.Title, accountData.FirstName, accountData.LastName, accountData.Operator, accountData.Role, accountData.PhoneNumber, accountData.Password.Value); err == nil {
+	if account, err := data.NewAccount(accountData.Email, accountData.Title, accountData.FirstName, accountData.LastName, accountData.Operator, accountData.Role, accountData.PhoneNumber, accountData.Password.Value, policy); err == nil {
+		if mngr.conf.Accounts.VerifyEmail {
+			account.Verification = newAccountVerification()
+		}
+		account.PendingApproval = mngr.conf.Accounts.RequireApproval
+
 		mngr.accounts = append(mngr.accounts, account)
 		mngr.storage.AccountAdded(account)
 		mngr.writeAllAccounts()
 
 		mngr.sendEmail(account, nil, email.SendAccountCreated)
+		mngr.sendVerification(account)
 		mngr.callListeners(account, AccountsListener.AccountCreated)
 	} else {
 		return errors.Wrap(err, "error while creating account")
@@ -157,8 +215,75 @@ func (mngr *AccountsManager) CreateAccount(accountData *data.Account) error {
 	return nil
 }
 
+// ImportAccount creates an account for a contact imported from an external source. The account awaits its approval by an administrator and gets a random password, which its owner needs to reset.
+func (mngr *AccountsManager) ImportAccount(accountData *data.Account) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	if account, _ := mngr.findAccount(FindByEmail, accountData.Email); account != nil {
+		return errors.Errorf("an account with the specified email address already exists")
+	}
+
+	pwd := password.MustGenerate(defaultPasswordLength, 2, 0, false, true)
+	if account, err := data.NewAccount(accountData.Email, accountData.Title, accountData.FirstName, accountData.LastName, accountData.Operator, accountData.Role, accountData.PhoneNumber, pwd, nil); err == nil {
+		account.ExternalID = accountData.ExternalID
+		account.PendingApproval = true
+
+		mngr.accounts = append(mngr.accounts, account)
+		mngr.storage.AccountAdded(account)
+		mngr.writeAllAccounts()
+
+		mngr.callListeners(account, AccountsListener.AccountCreated)
+	} else {
+		return errors.Wrap(err, "error while importing account")
+	}
+
+	return nil
+}
+
+// SyncAccount updates the contact data of the account identified by name with the data imported from an external source; this might change the email address of the account.
+func (mngr *AccountsManager) SyncAccount(name string, accountData *data.Account) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	account, err := mngr.findAccount(FindByEmail, name)
+	if err != nil {
+		return errors.Wrap(err, "user to sync not found")
+	}
+
+	emailChanged := !strings.EqualFold(account.Email, accountData.Email)
+	if emailChanged {
+		if other, _ := mngr.findAccount(FindByEmail, accountData.Email); other != nil {
+			return errors.Errorf("an account with the email address %v already exists", accountData.Email)
+		}
+	}
+
+	previous := account.Clone(false)
+	if err := account.UpdateContact(accountData); err != nil {
+		return errors.Wrap(err, "error while syncing account")
+	}
+	account.DateModified = time.Now()
+
+	// Accounts are stored by their email address, so a changed address requires storing the account anew
+	if emailChanged {
+		mngr.storage.AccountRemoved(previous)
+		mngr.storage.AccountAdded(account)
+	} else {
+		mngr.storage.AccountUpdated(account)
+	}
+	mngr.writeAllAccounts()
+
+	mngr.callListeners(account, AccountsListener.AccountUpdated)
+
+	return nil
+}
+
 // UpdateAccount updates the account identified by the account email; if no such account exists, an error is returned.
 func (mngr *AccountsManager) UpdateAccount(accountData *data.Account, setPassword bool, copyData bool) error {
+	return mngr.updateAccount(accountData, setPassword, copyData, &mngr.conf.Security.PasswordPolicy)
+}
+
+func (mngr *AccountsManager) updateAccount(accountData *data.Account, setPassword bool, copyData bool, policy *credentials.PasswordPolicy) error {
 	mngr.mutex.Lock()
 	defer mngr.mutex.Unlock()
 
@@ -167,7 +292,7 @@ func (mngr *AccountsManager) UpdateAccount(accountData *data.Account, setPasswor
 		return errors.Wrap(err, "user to update not found")
 	}
 
-	if err := account.Update(accountData, setPassword, copyData); err == nil {
+	if err := account.Update(accountData, setPassword, copyData, policy); err == nil {
 		account.DateModified = time.Now()
 
 		mngr.storage.AccountUpdated(account)
@@ -214,7 +339,8 @@ func (mngr *AccountsManager) ResetPassword(name string) error {
 	accountUpd := account.Clone(true)
 	accountUpd.Password.Value = password.MustGenerate(defaultPasswordLength, 2, 0, false, true)
 
-	err = mngr.UpdateAccount(accountUpd, true, false)
+	// Generated passwords don't need to fulfil the password policy
+	err = mngr.updateAccount(accountUpd, true, false, nil)
 	if err == nil {
 		mngr.sendEmail(accountUpd, nil, email.SendPasswordReset)
 	}
@@ -222,6 +348,72 @@ func (mngr *AccountsManager) ResetPassword(name string) error {
 	return err
 }
 
+// FindOIDCAccount finds the account of a user authenticated by an OpenID Connect provider. Accounts are matched by the identity linked to them or else by their email address, provided that it has been verified by the provider; the identity is then linked to the account.
+// If no account matches and auto-provisioning is enabled, a new account is created. The account is not cloned, as it is used for logging in the user.
+func (mngr *AccountsManager) FindOIDCAccount(identity *OIDCIdentity) (*data.Account, error) {
+	if account, err := mngr.linkOIDCAccount(identity); account != nil || err != nil {
+		return account, err
+	}
+
+	if !identity.EmailVerified {
+		return nil, errors.Errorf("the email address of your identity has not been verified by the provider")
+	}
+	if !mngr.conf.OIDC.AutoProvision {
+		return nil, errors.Errorf("no account with the email address %v exists; please register first", identity.Email)
+	}
+
+	// Provision a new account; its random password can be reset by the user to also login using the password
+	accountData := &data.Account{
+		Email:     identity.Email,
+		FirstName: identity.FirstName,
+		LastName:  identity.LastName,
+		Operator:  identity.Operator,
+		Role:      mngr.conf.OIDC.DefaultRole,
+	}
+	accountData.Password.Value = password.MustGenerate(defaultPasswordLength, 2, 0, false, true)
+	if err := mngr.createAccount(accountData, nil); err != nil {
+		return nil, errors.Wrap(err, "unable to create an account for your identity")
+	}
+
+	account, err := mngr.linkOIDCAccount(identity)
+	if account == nil && err == nil {
+		err = errors.Errorf("the created account could not be found")
+	}
+	return account, err
+}
+
+func (mngr *AccountsManager) linkOIDCAccount(identity *OIDCIdentity) (*data.Account, error) {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	if account := mngr.findAccountByPredicate(func(account *data.Account) bool {
+		return account.Identity != nil && *account.Identity == identity.AccountIdentity
+	}); account != nil {
+		return account, nil
+	}
+
+	if !identity.EmailVerified || identity.Email == "" {
+		return nil, nil
+	}
+	account, _ := mngr.findAccount(FindByEmail, identity.Email)
+	if account == nil {
+		return nil, nil
+	}
+	if account.Identity != nil {
+		return nil, errors.Errorf("the account is already linked to another identity")
+	}
+
+	identityData := identity.AccountIdentity
+	account.Identity = &identityData
+	account.Verification = nil // The email address has been verified by the provider
+	account.DateModified = time.Now()
+
+	mngr.storage.AccountUpdated(account)
+	mngr.writeAllAccounts()
+
+	return account, nil
+}
+
 // FindAccount is used to find an account by various criteria. The account is cloned to prevent data changes.
 func (mngr *AccountsManager) FindAccount(by string, value string) (*data.Account, error) {
 	return mngr.FindAccountEx(by, value, true)
@@ -289,11 +481,500 @@ func (mngr *AccountsManager) RemoveAccount(accountData *data.Account) error {
 	return errors.Errorf("no account with the specified email exists")
 }
 
+// ConfigureNotifications sets the notification preferences of the account identified by the account email; if no such account exists, an error is returned.
+func (mngr *AccountsManager) ConfigureNotifications(accountData *data.Account) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	account, err := mngr.findAccount(FindByEmail, accountData.Email)
+	if err != nil {
+		return errors.Wrap(err, "user to configure not found")
+	}
+
+	if err := account.ConfigureNotifications(accountData); err == nil {
+		account.DateModified = time.Now()
+
+		mngr.storage.AccountUpdated(account)
+		mngr.writeAllAccounts()
+	} else {
+		return errors.Wrap(err, "error while configuring notifications")
+	}
+
+	return nil
+}
+
+// ClearNotifications removes all panel notifications of the account identified by the account email.
+func (mngr *AccountsManager) ClearNotifications(accountData *data.Account) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	account, err := mngr.findAccount(FindByEmail, accountData.Email)
+	if err != nil {
+		return errors.Wrap(err, "user not found")
+	}
+
+	account.Notifications.Clear()
+
+	mngr.storage.AccountUpdated(account)
+	mngr.writeAllAccounts()
+
+	return nil
+}
+
+// EnrollTwoFactor generates a new two-factor secret for the account identified by the account email and returns it; it needs to be confirmed through EnableTwoFactor before being used.
+func (mngr *AccountsManager) EnrollTwoFactor(accountData *data.Account) (string, error) {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	account, err := mngr.findAccount(FindByEmail, accountData.Email)
+	if err != nil {
+		return "", errors.Wrap(err, "user not found")
+	}
+
+	if account.TwoFactor.Enabled {
+		return "", errors.Errorf("two-factor authentication is already enabled")
+	}
+
+	secret, err := credentials.GenerateTOTPSecret()
+	if err != nil {
+		return "", errors.Wrap(err, "unable to enroll two-factor authentication")
+	}
+	if err := account.TwoFactor.Set(secret, mngr.conf.Security.CredentialsPassphrase); err != nil {
+		return "", errors.Wrap(err, "unable to enroll two-factor authentication")
+	}
+	account.DateModified = time.Now()
+
+	mngr.storage.AccountUpdated(account)
+	mngr.writeAllAccounts()
+
+	return secret, nil
+}
+
+// EnableTwoFactor enables two-factor authentication for the account identified by the account email, provided that the code matches the enrolled secret.
+func (mngr *AccountsManager) EnableTwoFactor(accountData *data.Account, code string) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	account, err := mngr.findAccount(FindByEmail, accountData.Email)
+	if err != nil {
+		return errors.Wrap(err, "user not found")
+	}
+
+	if account.TwoFactor.Enabled {
+		return errors.Errorf("two-factor authentication is already enabled")
+	}
+	if !account.TwoFactor.IsSet() {
+		return errors.Errorf("no device has been enrolled")
+	}
+	if err := mngr.verifyTwoFactor(account, code); err != nil {
+		return err
+	}
+
+	account.TwoFactor.Enabled = true
+	account.DateModified = time.Now()
+
+	mngr.storage.AccountUpdated(account)
+	mngr.writeAllAccounts()
+
+	mngr.callListeners(account, AccountsListener.AccountUpdated)
+
+	return nil
+}
+
+// DisableTwoFactor disables two-factor authentication for the account identified by the account email; a valid code must be provided.
+func (mngr *AccountsManager) DisableTwoFactor(accountData *data.Account, op *data.Operator, code string) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	account, err := mngr.findAccount(FindByEmail, accountData.Email)
+	if err != nil {
+		return errors.Wrap(err, "user not found")
+	}
+
+	if !account.TwoFactor.Enabled {
+		return errors.Errorf("two-factor authentication is not enabled")
+	}
+	if account.RequiresTwoFactor(op) {
+		return errors.Errorf("two-factor authentication is required by your operator")
+	}
+	if err := mngr.verifyTwoFactor(account, code); err != nil {
+		return err
+	}
+
+	account.TwoFactor.Clear()
+	account.DateModified = time.Now()
+
+	mngr.storage.AccountUpdated(account)
+	mngr.writeAllAccounts()
+
+	mngr.callListeners(account, AccountsListener.AccountUpdated)
+
+	return nil
+}
+
+// VerifyTwoFactor checks the given code against the enabled second factor of the account identified by the account email.
+func (mngr *AccountsManager) VerifyTwoFactor(accountData *data.Account, code string) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	account, err := mngr.findAccount(FindByEmail, accountData.Email)
+	if err != nil {
+		return errors.Wrap(err, "user not found")
+	}
+
+	if !account.TwoFactor.Enabled {
+		return errors.Errorf("two-factor authentication is not enabled")
+	}
+	return mngr.verifyTwoFactor(account, code)
+}
+
+// DispatchSiteEvents notifies all accounts belonging to the operators of the affected sites via email and the account panel, respecting their notification preferences.
+func (mngr *AccountsManager) DispatchSiteEvents(events []*webhook.SiteEvent) {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	for _, event := range events {
+		notification := &data.Notification{
+			Type:     event.Type,
+			SiteID:   event.SiteID,
+			SiteName: event.SiteName,
+			Date:     event.Timestamp,
+		}
+		var subject string
+		switch event.Type {
+		case data.NotificationSiteAdded:
+			subject = "Site added"
+			notification.Message = fmt.Sprintf("Your site %v has been added to the ScienceMesh.", event.SiteName)
+
+		case data.NotificationSiteRemoved:
+			subject = "Site removed"
+			notification.Message = fmt.Sprintf("Your site %v has been removed from the ScienceMesh.", event.SiteName)
+
+		case data.NotificationSiteUnhealthy:
+			subject = "Site unhealthy"
+			notification.Message = fmt.Sprintf("Your site %v has been flagged as unhealthy.", event.SiteName)
+			if event.Reason != "" {
+				notification.Message = fmt.Sprintf("Your site %v has been flagged as unhealthy: %v.", event.SiteName, event.Reason)
+			}
+
+		default:
+			mngr.log.Warn().Str("type", event.Type).Str("site", event.SiteID).Msg("ignoring unknown site event")
+			continue
+		}
+		if notification.Date.IsZero() {
+			notification.Date = time.Now()
+		}
+
+		for _, account := range mngr.accounts {
+			if account.IsDisabled() || !strings.EqualFold(account.Operator, event.OperatorID) {
+				continue
+			}
+
+			if account.Notifications.Preferences.WantsPanel(event.Type) {
+				msg := *notification
+				account.Notifications.Add(&msg)
+				mngr.storage.AccountUpdated(account)
+			}
+
+			if account.Notifications.Preferences.WantsEmail(event.Type) {
+				params := map[string]string{"Subject": subject + ": " + event.SiteName, "Message": notification.Message}
+				_ = email.SendSiteNotification(account, []string{account.Email}, params, *mngr.conf)
+			}
+		}
+	}
+
+	mngr.writeAllAccounts()
+}
+
+// SendAnnouncement emails an announcement to all accounts which aren't disabled.
+func (mngr *AccountsManager) SendAnnouncement(a *announcements.Announcement) {
+	mngr.mutex.RLock()
+	defer mngr.mutex.RUnlock()
+
+	params := map[string]string{"Title": a.Title, "Message": a.Message}
+	const layout = "Jan 02, 2006 15:04 MST"
+	if a.End.IsZero() {
+		params["Period"] = "from " + a.Start.Format(layout)
+	} else {
+		params["Period"] = fmt.Sprintf("from %v until %v", a.Start.Format(layout), a.End.Format(layout))
+	}
+
+	for _, account := range mngr.accounts {
+		if account.IsDisabled() {
+			continue
+		}
+		if err := email.SendAnnouncement(account, []string{account.Email}, params, *mngr.conf); err != nil {
+			mngr.log.Warn().Err(err).Str("account", account.Email).Msg("error while sending the announcement")
+		}
+	}
+}
+
+// RequestDeletion sends a link to confirm the deletion of the account identified by the account email to its owner; requesting the deletion again sends a new link.
+func (mngr *AccountsManager) RequestDeletion(accountData *data.Account) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	account, err := mngr.findAccount(FindByEmail, accountData.Email)
+	if err != nil {
+		return errors.Wrap(err, "user to delete not found")
+	}
+
+	if account.Deletion != nil {
+		return errors.Errorf("the deletion of this account has already been requested")
+	}
+
+	account.DeletionRequest = &data.AccountDeletionRequest{
+		DateSent: time.Now(),
+		Token:    password.MustGenerate(deletionTokenLength, 10, 0, false, true),
+	}
+
+	mngr.storage.AccountUpdated(account)
+	mngr.writeAllAccounts()
+
+	params := map[string]string{
+		"Token":      account.DeletionRequest.Token,
+		"Timeout":    fmt.Sprintf("%v", mngr.conf.Accounts.DeletionConfirmationTimeout),
+		"CoolingOff": fmt.Sprintf("%v", mngr.conf.Accounts.DeletionCoolingOff),
+	}
+	if err := email.SendAccountDeletionConfirm(account, []string{account.Email}, params, *mngr.conf); err != nil {
+		return errors.Wrap(err, "unable to send the confirmation email")
+	}
+
+	return nil
+}
+
+// ConfirmDeletion disables the account identified by the account email and schedules it for deletion after the configured cooling-off period; the token sent to the account owner must be provided.
+func (mngr *AccountsManager) ConfirmDeletion(name string, token string) (time.Time, error) {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	account, err := mngr.findAccount(FindByEmail, name)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "no account with the specified email exists")
+	}
+
+	request := account.DeletionRequest
+	if request == nil || request.Token == "" || subtle.ConstantTimeCompare([]byte(request.Token), []byte(token)) != 1 {
+		return time.Time{}, errors.Errorf("invalid confirmation token")
+	}
+	now := time.Now()
+	if now.After(request.DateSent.Add(time.Duration(mngr.conf.Accounts.DeletionConfirmationTimeout) * time.Hour)) {
+		return time.Time{}, errors.Errorf("the confirmation link has expired; please request the deletion again")
+	}
+
+	account.DeletionRequest = nil
+	account.Deletion = &data.AccountDeletion{
+		DateRequested: now,
+		DatePurge:     now.AddDate(0, 0, mngr.conf.Accounts.DeletionCoolingOff),
+		Token:         password.MustGenerate(deletionTokenLength, 10, 0, false, true),
+	}
+	account.DateModified = now
+
+	mngr.storage.AccountUpdated(account)
+	mngr.writeAllAccounts()
+
+	params := map[string]string{
+		"Token":      account.Deletion.Token,
+		"DatePurge":  account.Deletion.DatePurge.Format("2006-01-02"),
+		"CoolingOff": fmt.Sprintf("%v", mngr.conf.Accounts.DeletionCoolingOff),
+	}
+	_ = email.SendAccountDeletionRequested(account, []string{account.Email}, params, *mngr.conf)
+
+	mngr.callListeners(account, AccountsListener.AccountUpdated)
+
+	return account.Deletion.DatePurge, nil
+}
+
+// CancelDeletion re-enables an account that is scheduled for deletion; the token sent to the account owner must be provided.
+func (mngr *AccountsManager) CancelDeletion(name string, token string) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	account, err := mngr.findDeletedAccount(name, token)
+	if err != nil {
+		return err
+	}
+
+	account.Deletion = nil
+	account.DateModified = time.Now()
+
+	mngr.storage.AccountUpdated(account)
+	mngr.writeAllAccounts()
+
+	mngr.sendEmail(account, nil, email.SendAccountDeletionCanceled)
+	mngr.callListeners(account, AccountsListener.AccountUpdated)
+
+	return nil
+}
+
+// ExportAccount exports the data of an account that is scheduled for deletion; the token sent to the account owner must be provided.
+func (mngr *AccountsManager) ExportAccount(name string, token string) (*data.Account, error) {
+	mngr.mutex.RLock()
+	defer mngr.mutex.RUnlock()
+
+	account, err := mngr.findDeletedAccount(name, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return account.Clone(true), nil
+}
+
+// PurgeDeletedAccounts removes all accounts whose cooling-off period is over.
+func (mngr *AccountsManager) PurgeDeletedAccounts() {
+	now := time.Now()
+
+	// Most of the time, nothing needs to be purged, so check this first using a read lock only
+	mngr.mutex.RLock()
+	due := mngr.findAccountByPredicate(func(account *data.Account) bool { return account.IsDue(now) })
+	mngr.mutex.RUnlock()
+
+	if due == nil {
+		return
+	}
+
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	accounts := make(data.Accounts, 0, len(mngr.accounts))
+	purged := make(data.Accounts, 0)
+	for _, account := range mngr.accounts {
+		if account.IsDue(now) {
+			purged = append(purged, account)
+		} else {
+			accounts = append(accounts, account)
+		}
+	}
+
+	if len(purged) == 0 {
+		return
+	}
+
+	mngr.accounts = accounts
+	for _, account := range purged {
+		mngr.storage.AccountRemoved(account)
+	}
+	mngr.writeAllAccounts()
+
+	for _, account := range purged {
+		mngr.log.Info().Str("email", account.Email).Msg("purged deleted account")
+		mngr.callListeners(account, AccountsListener.AccountRemoved)
+	}
+}
+
+// VerifyEmail marks the email address of the account identified by the account email as verified; the token sent to this address must be provided.
+func (mngr *AccountsManager) VerifyEmail(name string, token string) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	account, err := mngr.findAccount(FindByEmail, name)
+	if err != nil {
+		return errors.Wrap(err, "no account with the specified email exists")
+	}
+
+	if account.IsVerified() {
+		return nil
+	}
+	if account.Verification.Token == "" || subtle.ConstantTimeCompare([]byte(account.Verification.Token), []byte(token)) != 1 {
+		return errors.Errorf("invalid verification token")
+	}
+
+	account.Verification = nil
+	account.DateModified = time.Now()
+
+	mngr.storage.AccountUpdated(account)
+	mngr.writeAllAccounts()
+
+	mngr.callListeners(account, AccountsListener.AccountUpdated)
+
+	return nil
+}
+
+// ResendVerification sends a new verification email to the account identified by the account email.
+func (mngr *AccountsManager) ResendVerification(accountData *data.Account) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	account, err := mngr.findAccount(FindByEmail, accountData.Email)
+	if err != nil {
+		return errors.Wrap(err, "user to verify not found")
+	}
+
+	if account.IsVerified() {
+		return errors.Errorf("the email address of this account has already been verified")
+	}
+
+	// Issue a new token, so that only the latest email can be used
+	account.Verification = newAccountVerification()
+
+	mngr.storage.AccountUpdated(account)
+	mngr.writeAllAccounts()
+
+	mngr.sendVerification(account)
+
+	return nil
+}
+
+// ApproveAccount approves the account identified by the account email, allowing its owner to log in.
+func (mngr *AccountsManager) ApproveAccount(accountData *data.Account) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	account, err := mngr.findAccount(FindByEmail, accountData.Email)
+	if err != nil {
+		return errors.Wrap(err, "user to approve not found")
+	}
+
+	if !account.PendingApproval {
+		return errors.Errorf("the account has already been approved")
+	}
+
+	account.PendingApproval = false
+	account.DateModified = time.Now()
+
+	mngr.storage.AccountUpdated(account)
+	mngr.writeAllAccounts()
+
+	mngr.sendEmail(account, nil, email.SendAccountApproved)
+	mngr.callListeners(account, AccountsListener.AccountUpdated)
+
+	return nil
+}
+
+// SuspendAccount disables or re-enables the account identified by the account email.
+func (mngr *AccountsManager) SuspendAccount(accountData *data.Account, suspend bool) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	account, err := mngr.findAccount(FindByEmail, accountData.Email)
+	if err != nil {
+		return errors.Wrap(err, "user to suspend not found")
+	}
+
+	account.Suspended = suspend
+	account.DateModified = time.Now()
+
+	mngr.storage.AccountUpdated(account)
+	mngr.writeAllAccounts()
+
+	mngr.callListeners(account, AccountsListener.AccountUpdated)
+
+	return nil
+}
+
 // SendContactForm sends a generic email to the ScienceMesh admins.
 func (mngr *AccountsManager) SendContactForm(account *data.Account, subject, message string) {
 	mngr.sendEmail(account, map[string]string{"Subject": subject, "Message": message}, email.SendContactForm)
 }
 
+// AddListener registers an additional listener notified about accounts events.
+func (mngr *AccountsManager) AddListener(listener AccountsListener) {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	mngr.accountsListeners = append(mngr.accountsListeners, listener)
+}
+
 // CloneAccounts retrieves all accounts currently stored by cloning the data, thus avoiding race conflicts and making outside modifications impossible.
 func (mngr *AccountsManager) CloneAccounts(erasePasswords bool) data.Accounts {
 	mngr.mutex.RLock()
@@ -307,6 +988,35 @@ func (mngr *AccountsManager) CloneAccounts(erasePasswords bool) data.Accounts {
 	return clones
 }
 
+func (mngr *AccountsManager) findDeletedAccount(name string, token string) (*data.Account, error) {
+	account, err := mngr.findAccount(FindByEmail, name)
+	if err != nil {
+		return nil, errors.Wrap(err, "no account with the specified email exists")
+	}
+
+	if account.Deletion == nil || account.Deletion.Token == "" || subtle.ConstantTimeCompare([]byte(account.Deletion.Token), []byte(token)) != 1 {
+		return nil, errors.Errorf("invalid deletion token")
+	}
+
+	return account, nil
+}
+
+func (mngr *AccountsManager) verifyTwoFactor(account *data.Account, code string) error {
+	valid, err := account.TwoFactor.Verify(code, mngr.conf.Security.CredentialsPassphrase, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "unable to verify the two-factor code")
+	}
+	if !valid {
+		return errors.Errorf("invalid two-factor code")
+	}
+
+	// The step of the accepted code needs to be persisted, so that it can't be used again
+	mngr.storage.AccountUpdated(account)
+	mngr.writeAllAccounts()
+
+	return nil
+}
+
 func (mngr *AccountsManager) grantAccess(account *data.Account, accessFlag *bool, grantAccess bool, emailFunc email.SendFunction) error {
 	accessOld := *accessFlag
 	*accessFlag = grantAccess
@@ -329,10 +1039,24 @@ func (mngr *AccountsManager) callListeners(account *data.Account, cb AccountsLis
 	}
 }
 
+// sendVerification sends the verification token of the account to its email address only.
+func (mngr *AccountsManager) sendVerification(account *data.Account) {
+	if account.Verification != nil {
+		_ = email.SendAccountVerification(account, []string{account.Email}, map[string]string{"Token": account.Verification.Token}, *mngr.conf)
+	}
+}
+
 func (mngr *AccountsManager) sendEmail(account *data.Account, params map[string]string, sendFunc email.SendFunction) {
 	_ = sendFunc(account, []string{account.Email, mngr.conf.Email.NotificationsMail}, params, *mngr.conf)
 }
 
+func newAccountVerification() *data.AccountVerification {
+	return &data.AccountVerification{
+		DateSent: time.Now(),
+		Token:    password.MustGenerate(verificationTokenLength, 10, 0, false, true),
+	}
+}
+
 // NewAccountsManager creates a new accounts manager instance.
 func NewAccountsManager(storage data.Storage, conf *config.Configuration, log *zerolog.Logger) (*AccountsManager, error) {
 	mngr := &AccountsManager{}
@@ -0,0 +1,319 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/rs/zerolog"
+)
+
+type memStorage struct {
+	operators data.Operators
+	accounts  data.Accounts
+
+	writes int
+}
+
+func (s *memStorage) ReadOperators() (*data.Operators, error) { return &s.operators, nil }
+func (s *memStorage) WriteOperators(ops *data.Operators) error {
+	s.operators = *ops
+	return nil
+}
+func (s *memStorage) OperatorAdded(*data.Operator)   {}
+func (s *memStorage) OperatorUpdated(*data.Operator) {}
+func (s *memStorage) OperatorRemoved(*data.Operator) {}
+
+func (s *memStorage) ReadAccounts() (*data.Accounts, error) { return &s.accounts, nil }
+func (s *memStorage) WriteAccounts(accounts *data.Accounts) error {
+	s.accounts = append(data.Accounts{}, *accounts...)
+	s.writes++
+	return nil
+}
+func (s *memStorage) AccountAdded(*data.Account)   {}
+func (s *memStorage) AccountUpdated(*data.Account) {}
+func (s *memStorage) AccountRemoved(*data.Account) {}
+
+type recordingListener struct {
+	updated []string
+	removed []string
+}
+
+func (l *recordingListener) AccountCreated(*data.Account) {}
+func (l *recordingListener) AccountUpdated(account *data.Account) {
+	l.updated = append(l.updated, account.Email)
+}
+func (l *recordingListener) AccountRemoved(account *data.Account) {
+	l.removed = append(l.removed, account.Email)
+}
+
+func newTestAccount(email string) *data.Account {
+	return &data.Account{
+		Email:     email,
+		FirstName: "John",
+		LastName:  "Doe",
+		Operator:  "op",
+		Role:      "Admin",
+	}
+}
+
+// newTestAccountsManager creates an accounts manager holding the given accounts; the GOCDB listener is replaced by a recording one.
+func newTestAccountsManager(t *testing.T, accounts ...*data.Account) (*AccountsManager, *memStorage, *recordingListener) {
+	t.Helper()
+
+	conf := &config.Configuration{}
+	conf.Accounts.DeletionCoolingOff = 30
+	conf.Accounts.DeletionConfirmationTimeout = 24
+	log := zerolog.Nop()
+
+	storage := &memStorage{accounts: accounts}
+	mngr, err := NewAccountsManager(storage, conf, &log)
+	if err != nil {
+		t.Fatalf("unable to create the accounts manager: %v", err)
+	}
+
+	listener := &recordingListener{}
+	mngr.accountsListeners = []AccountsListener{listener}
+	return mngr, storage, listener
+}
+
+func requestAndConfirmDeletion(t *testing.T, mngr *AccountsManager, email string) time.Time {
+	t.Helper()
+
+	if err := mngr.RequestDeletion(&data.Account{Email: email}); err != nil {
+		t.Fatalf("unexpected error requesting the deletion: %v", err)
+	}
+	account, _ := mngr.findAccount(FindByEmail, email)
+	if account.DeletionRequest == nil || account.DeletionRequest.Token == "" {
+		t.Fatalf("expected a deletion request with a token, got %+v", account.DeletionRequest)
+	}
+	if account.IsDisabled() {
+		t.Fatal("expected the account to stay enabled until the deletion is confirmed")
+	}
+
+	datePurge, err := mngr.ConfirmDeletion(email, account.DeletionRequest.Token)
+	if err != nil {
+		t.Fatalf("unexpected error confirming the deletion: %v", err)
+	}
+	return datePurge
+}
+
+func TestConfirmDeletion(t *testing.T) {
+	mngr, _, listener := newTestAccountsManager(t, newTestAccount("john@example.org"))
+
+	datePurge := requestAndConfirmDeletion(t, mngr, "john@example.org")
+	if days := time.Until(datePurge).Hours() / 24; days < 29 || days > 30 {
+		t.Errorf("expected the account to be purged in 30 days, got %v", datePurge)
+	}
+
+	account, _ := mngr.findAccount(FindByEmail, "john@example.org")
+	if !account.IsDisabled() || account.DeletionRequest != nil {
+		t.Errorf("expected the account to be disabled and the request to be consumed, got %+v", account)
+	}
+	if account.Deletion.Token == "" {
+		t.Error("expected a token to cancel the deletion")
+	}
+	if len(listener.updated) != 1 {
+		t.Errorf("expected the listeners to be notified once, got %v", listener.updated)
+	}
+
+	if err := mngr.RequestDeletion(&data.Account{Email: "john@example.org"}); err == nil {
+		t.Error("expected requesting the deletion of a disabled account to fail")
+	}
+}
+
+func TestConfirmDeletionInvalidToken(t *testing.T) {
+	mngr, _, _ := newTestAccountsManager(t, newTestAccount("john@example.org"))
+
+	if _, err := mngr.ConfirmDeletion("john@example.org", "token"); err == nil {
+		t.Error("expected confirming a deletion that wasn't requested to fail")
+	}
+
+	if err := mngr.RequestDeletion(&data.Account{Email: "john@example.org"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := mngr.ConfirmDeletion("john@example.org", "wrong"); err == nil {
+		t.Error("expected an invalid token to be rejected")
+	}
+
+	account, _ := mngr.findAccount(FindByEmail, "john@example.org")
+	account.DeletionRequest.DateSent = time.Now().Add(-25 * time.Hour)
+	if _, err := mngr.ConfirmDeletion("john@example.org", account.DeletionRequest.Token); err == nil {
+		t.Error("expected an expired confirmation link to be rejected")
+	}
+	if account.IsDisabled() {
+		t.Error("expected the account to stay enabled")
+	}
+}
+
+func TestExportAndCancelDeletion(t *testing.T) {
+	mngr, _, _ := newTestAccountsManager(t, newTestAccount("john@example.org"))
+	requestAndConfirmDeletion(t, mngr, "john@example.org")
+
+	account, _ := mngr.findAccount(FindByEmail, "john@example.org")
+	token := account.Deletion.Token
+
+	if _, err := mngr.ExportAccount("john@example.org", "wrong"); err == nil {
+		t.Error("expected the export to require the deletion token")
+	}
+	exported, err := mngr.ExportAccount("john@example.org", token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exported.Email != "john@example.org" || exported.Deletion.Token != "" {
+		t.Errorf("expected the exported account without its token, got %+v", exported)
+	}
+
+	if err := mngr.CancelDeletion("john@example.org", "wrong"); err == nil {
+		t.Error("expected the cancellation to require the deletion token")
+	}
+	if err := mngr.CancelDeletion("john@example.org", token); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if account.IsDisabled() {
+		t.Error("expected the account to be enabled again")
+	}
+	if _, err := mngr.ExportAccount("john@example.org", token); err == nil {
+		t.Error("expected the token to be invalid once the deletion was canceled")
+	}
+}
+
+func TestPurgeDeletedAccounts(t *testing.T) {
+	due := newTestAccount("due@example.org")
+	due.Deletion = &data.AccountDeletion{DatePurge: time.Now().Add(-time.Hour)}
+	pending := newTestAccount("pending@example.org")
+	pending.Deletion = &data.AccountDeletion{DatePurge: time.Now().Add(time.Hour)}
+	mngr, storage, listener := newTestAccountsManager(t, due, pending, newTestAccount("john@example.org"))
+
+	mngr.PurgeDeletedAccounts()
+
+	if _, err := mngr.findAccount(FindByEmail, "due@example.org"); err == nil {
+		t.Error("expected the due account to be purged")
+	}
+	if len(mngr.accounts) != 2 || len(storage.accounts) != 2 {
+		t.Errorf("expected 2 remaining accounts, got %d (stored: %d)", len(mngr.accounts), len(storage.accounts))
+	}
+	if len(listener.removed) != 1 || listener.removed[0] != "due@example.org" {
+		t.Errorf("expected the listeners to be notified about the purged account, got %v", listener.removed)
+	}
+
+	writes := storage.writes
+	mngr.PurgeDeletedAccounts()
+	if storage.writes != writes {
+		t.Error("expected nothing to be written if no account is due")
+	}
+}
+
+func newTestOIDCIdentity(email string, verified bool) *OIDCIdentity {
+	return &OIDCIdentity{
+		AccountIdentity: data.AccountIdentity{Issuer: "https://idp.example.org", Subject: "john-sub"},
+		Email:           email,
+		EmailVerified:   verified,
+		FirstName:       "John",
+		LastName:        "Doe",
+		Operator:        "op",
+	}
+}
+
+func TestFindOIDCAccountLinksVerifiedEmail(t *testing.T) {
+	unverified := newTestAccount("john@example.org")
+	unverified.Verification = &data.AccountVerification{Token: "token"}
+	mngr, storage, _ := newTestAccountsManager(t, unverified)
+
+	if account, err := mngr.FindOIDCAccount(newTestOIDCIdentity("john@example.org", false)); err == nil {
+		t.Fatalf("expected an unverified email address to be rejected, got %+v", account)
+	}
+	if account, _ := mngr.findAccount(FindByEmail, "john@example.org"); account.Identity != nil {
+		t.Fatalf("expected the account not to be linked to an identity with an unverified email address, got %+v", account.Identity)
+	}
+
+	identity := newTestOIDCIdentity("john@example.org", true)
+	account, err := mngr.FindOIDCAccount(identity)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if account.Identity == nil || *account.Identity != identity.AccountIdentity {
+		t.Errorf("expected the account to be linked to the identity, got %+v", account.Identity)
+	}
+	if account.Verification != nil {
+		t.Error("expected the email address to be considered verified")
+	}
+	if len(storage.accounts) != 1 || storage.accounts[0].Identity == nil {
+		t.Error("expected the link to be stored")
+	}
+
+	// Once linked, the account is found by the identity regardless of the email address
+	identity.Email = "albert@example.org"
+	identity.EmailVerified = false
+	if found, err := mngr.FindOIDCAccount(identity); err != nil || found != account {
+		t.Errorf("expected the linked account to be found by its identity, got %+v (error: %v)", found, err)
+	}
+}
+
+func TestFindOIDCAccountLinkedToOtherIdentity(t *testing.T) {
+	linked := newTestAccount("john@example.org")
+	linked.Identity = &data.AccountIdentity{Issuer: "https://idp.example.org", Subject: "other-sub"}
+	mngr, _, _ := newTestAccountsManager(t, linked)
+	mngr.conf.OIDC.AutoProvision = true
+
+	if account, err := mngr.FindOIDCAccount(newTestOIDCIdentity("john@example.org", true)); err == nil {
+		t.Fatalf("expected an account linked to another identity to be refused, got %+v", account)
+	}
+	if account, _ := mngr.findAccount(FindByEmail, "john@example.org"); account.Identity.Subject != "other-sub" {
+		t.Errorf("expected the account to stay linked to its identity, got %+v", account.Identity)
+	}
+	if len(mngr.accounts) != 1 {
+		t.Errorf("expected no account to be provisioned, got %d accounts", len(mngr.accounts))
+	}
+}
+
+func TestFindOIDCAccountAutoProvision(t *testing.T) {
+	mngr, _, _ := newTestAccountsManager(t)
+	mngr.conf.OIDC.DefaultRole = "Site administrator"
+
+	identity := newTestOIDCIdentity("john@example.org", true)
+	if account, err := mngr.FindOIDCAccount(identity); err == nil {
+		t.Fatalf("expected no account to be provisioned if auto-provisioning is disabled, got %+v", account)
+	}
+	if len(mngr.accounts) != 0 {
+		t.Fatalf("expected no account to be created, got %d accounts", len(mngr.accounts))
+	}
+
+	mngr.conf.OIDC.AutoProvision = true
+	if account, err := mngr.FindOIDCAccount(newTestOIDCIdentity("john@example.org", false)); err == nil {
+		t.Fatalf("expected no account to be provisioned for an unverified email address, got %+v", account)
+	}
+
+	account, err := mngr.FindOIDCAccount(identity)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if account.Email != "john@example.org" || account.FirstName != "John" || account.LastName != "Doe" || account.Operator != "op" || account.Role != "Site administrator" {
+		t.Errorf("expected the account to be provisioned from the identity, got %+v", account)
+	}
+	if account.Identity == nil || *account.Identity != identity.AccountIdentity {
+		t.Errorf("expected the provisioned account to be linked to the identity, got %+v", account.Identity)
+	}
+	if len(mngr.accounts) != 1 {
+		t.Errorf("expected 1 account, got %d", len(mngr.accounts))
+	}
+}
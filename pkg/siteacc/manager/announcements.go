@@ -0,0 +1,119 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package manager
+
+import (
+	"context"
+	"time"
+
+	"github.com/cs3org/reva/pkg/announcements"
+	"github.com/cs3org/reva/pkg/announcements/registry"
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// AnnouncementsManager is responsible for the announcements broadcast to all users.
+type AnnouncementsManager struct {
+	conf *config.Configuration
+	log  *zerolog.Logger
+
+	registry announcements.Registry
+}
+
+func (mngr *AnnouncementsManager) initialize(conf *config.Configuration, log *zerolog.Logger) error {
+	if conf == nil {
+		return errors.Errorf("no configuration provided")
+	}
+	mngr.conf = conf
+
+	if log == nil {
+		return errors.Errorf("no logger provided")
+	}
+	mngr.log = log
+
+	// Announcements are only available if a registry has been configured
+	if conf.Announcements.Registry != "" {
+		f, ok := registry.NewFuncs[conf.Announcements.Registry]
+		if !ok {
+			return errors.Errorf("unknown announcements registry %v", conf.Announcements.Registry)
+		}
+		reg, err := f(conf.Announcements.Registries[conf.Announcements.Registry])
+		if err != nil {
+			return errors.Wrap(err, "unable to create the announcements registry")
+		}
+		mngr.registry = reg
+	}
+
+	return nil
+}
+
+// Enabled returns whether announcements are available.
+func (mngr *AnnouncementsManager) Enabled() bool {
+	return mngr.registry != nil
+}
+
+// List returns all announcements, including the scheduled and expired ones.
+func (mngr *AnnouncementsManager) List() ([]*announcements.Announcement, error) {
+	if !mngr.Enabled() {
+		return nil, errors.Errorf("announcements are not enabled")
+	}
+	return mngr.registry.ListAnnouncements(context.Background())
+}
+
+// Active returns the announcements to show right now; errors are only logged, as the announcements are shown on every panel.
+func (mngr *AnnouncementsManager) Active() []*announcements.Announcement {
+	if !mngr.Enabled() {
+		return nil
+	}
+	list, err := announcements.Active(context.Background(), mngr.registry, time.Now())
+	if err != nil {
+		mngr.log.Warn().Err(err).Msg("error while reading the announcements")
+		return nil
+	}
+	return list
+}
+
+// Post stores a new announcement.
+func (mngr *AnnouncementsManager) Post(a *announcements.Announcement) error {
+	if !mngr.Enabled() {
+		return errors.Errorf("announcements are not enabled")
+	}
+	if err := a.Validate(); err != nil {
+		return err
+	}
+	return mngr.registry.StoreAnnouncement(context.Background(), a)
+}
+
+// Remove removes the announcement with the given ID.
+func (mngr *AnnouncementsManager) Remove(id string) error {
+	if !mngr.Enabled() {
+		return errors.Errorf("announcements are not enabled")
+	}
+	return mngr.registry.DeleteAnnouncement(context.Background(), id)
+}
+
+// NewAnnouncementsManager creates a new announcements manager instance.
+func NewAnnouncementsManager(conf *config.Configuration, log *zerolog.Logger) (*AnnouncementsManager, error) {
+	mngr := &AnnouncementsManager{}
+	if err := mngr.initialize(conf, log); err != nil {
+		return nil, errors.Wrap(err, "unable to initialize the announcements manager")
+	}
+	return mngr, nil
+}
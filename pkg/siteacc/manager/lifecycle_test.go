@@ -0,0 +1,154 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package manager
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/siteacc/credentials"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+)
+
+func createTestAccount(t *testing.T, mngr *AccountsManager, email string) *data.Account {
+	t.Helper()
+
+	accountData := newTestAccount(email)
+	accountData.Password = credentials.Password{Value: "secret"}
+	if err := mngr.CreateAccount(accountData); err != nil {
+		t.Fatalf("unexpected error creating the account: %v", err)
+	}
+	account, err := mngr.findAccount(FindByEmail, email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return account
+}
+
+func TestCreateAccountLifecycle(t *testing.T) {
+	mngr, _, _ := newTestAccountsManager(t)
+
+	account := createTestAccount(t, mngr, "john@example.org")
+	if !account.IsVerified() || account.PendingApproval {
+		t.Errorf("expected new accounts to be usable right away by default, got %+v", account)
+	}
+
+	mngr.conf.Accounts.VerifyEmail = true
+	mngr.conf.Accounts.RequireApproval = true
+	account = createTestAccount(t, mngr, "jane@example.org")
+	if account.IsVerified() || account.Verification.Token == "" {
+		t.Errorf("expected the account to await the verification of its email address, got %+v", account.Verification)
+	}
+	if !account.PendingApproval {
+		t.Error("expected the account to await its approval")
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	mngr, _, listener := newTestAccountsManager(t)
+	mngr.conf.Accounts.VerifyEmail = true
+	account := createTestAccount(t, mngr, "john@example.org")
+	token := account.Verification.Token
+
+	if err := mngr.ResendVerification(&data.Account{Email: "john@example.org"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if account.Verification.Token == token {
+		t.Fatal("expected a new token to be issued")
+	}
+	if err := mngr.VerifyEmail("john@example.org", token); err == nil {
+		t.Error("expected the token of an earlier email to be rejected")
+	}
+
+	if err := mngr.VerifyEmail("john@example.org", account.Verification.Token); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !account.IsVerified() {
+		t.Error("expected the email address to be verified")
+	}
+	if len(listener.updated) != 1 {
+		t.Errorf("expected the listeners to be notified once, got %v", listener.updated)
+	}
+
+	// Verifying twice is harmless, but there is nothing to resend anymore
+	if err := mngr.VerifyEmail("john@example.org", "any"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := mngr.ResendVerification(&data.Account{Email: "john@example.org"}); err == nil {
+		t.Error("expected resending the verification of a verified account to fail")
+	}
+}
+
+func TestApproveAndSuspendAccount(t *testing.T) {
+	account := newTestAccount("john@example.org")
+	account.PendingApproval = true
+	mngr, _, listener := newTestAccountsManager(t, account)
+
+	if err := mngr.ApproveAccount(&data.Account{Email: "john@example.org"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if account.PendingApproval {
+		t.Error("expected the account to be approved")
+	}
+	if err := mngr.ApproveAccount(&data.Account{Email: "john@example.org"}); err == nil {
+		t.Error("expected approving an account twice to fail")
+	}
+
+	if err := mngr.SuspendAccount(&data.Account{Email: "john@example.org"}, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !account.Suspended || !account.IsDisabled() {
+		t.Error("expected the account to be suspended")
+	}
+	if err := mngr.SuspendAccount(&data.Account{Email: "john@example.org"}, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if account.IsDisabled() {
+		t.Error("expected the account to be enabled again")
+	}
+	if len(listener.updated) != 3 {
+		t.Errorf("expected the listeners to be notified of every change, got %v", listener.updated)
+	}
+
+	if err := mngr.SuspendAccount(&data.Account{Email: "jane@example.org"}, true); err == nil {
+		t.Error("expected suspending an unknown account to fail")
+	}
+}
+
+func TestAuthorizeLoginLifecycle(t *testing.T) {
+	mngr := &UsersManager{}
+
+	tests := map[string]struct {
+		modify   func(*data.Account)
+		expected string
+	}{
+		"deletion":     {func(a *data.Account) { a.Deletion = &data.AccountDeletion{} }, "deletion request"},
+		"suspended":    {func(a *data.Account) { a.Suspended = true }, "disabled by an administrator"},
+		"unverified":   {func(a *data.Account) { a.Verification = &data.AccountVerification{} }, "hasn't been verified"},
+		"not approved": {func(a *data.Account) { a.PendingApproval = true }, "hasn't been approved"},
+	}
+
+	for name, tt := range tests {
+		account := newTestAccount("john@example.org")
+		tt.modify(account)
+		if _, _, err := mngr.authorizeLogin(account, data.ScopeDefault, nil); err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected the login to be refused with %q, got %v", name, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package manager
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc"
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"golang.org/x/oauth2"
+)
+
+// OIDCIdentity holds the information about a user authenticated by an OpenID Connect provider.
+type OIDCIdentity struct {
+	data.AccountIdentity
+
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+	Operator      string
+}
+
+// OIDCManager is responsible for logging in users through an external OpenID Connect provider.
+type OIDCManager struct {
+	conf *config.Configuration
+	log  *zerolog.Logger
+
+	client   *http.Client
+	provider *oidc.Provider
+
+	mutex sync.Mutex
+}
+
+func (mngr *OIDCManager) initialize(conf *config.Configuration, log *zerolog.Logger) error {
+	if conf == nil {
+		return errors.Errorf("no configuration provided")
+	}
+	mngr.conf = conf
+
+	if log == nil {
+		return errors.Errorf("no logger provided")
+	}
+	mngr.log = log
+
+	mngr.client = &http.Client{Timeout: 10 * time.Second}
+
+	return nil
+}
+
+// Enabled tells whether logging in through an OpenID Connect provider has been configured.
+func (mngr *OIDCManager) Enabled() bool {
+	return mngr.conf.OIDC.Issuer != ""
+}
+
+// AuthCodeURL returns the URL of the provider to redirect the user to; state and nonce need to be stored in the session of the user.
+func (mngr *OIDCManager) AuthCodeURL(state, nonce string) (string, error) {
+	provider, err := mngr.getProvider()
+	if err != nil {
+		return "", err
+	}
+	return mngr.oauth2Config(provider).AuthCodeURL(state, oidc.Nonce(nonce)), nil
+}
+
+// Exchange redeems the authorization code the provider redirected the user back with and verifies the received ID token, returning the identity of the user.
+func (mngr *OIDCManager) Exchange(ctx context.Context, code, nonce string) (*OIDCIdentity, error) {
+	provider, err := mngr.getProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = oidc.ClientContext(ctx, mngr.client)
+	token, err := mngr.oauth2Config(provider).Exchange(ctx, code)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to redeem the authorization code")
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.Errorf("no ID token received from the provider")
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: mngr.conf.OIDC.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid ID token")
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.Errorf("the ID token doesn't belong to this login")
+	}
+
+	claims := map[string]interface{}{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, errors.Wrap(err, "unable to read the claims of the ID token")
+	}
+	// Providers may only hand out the email address through the user info endpoint
+	if _, ok := claims["email"]; !ok {
+		if userInfo, err := provider.UserInfo(ctx, oauth2.StaticTokenSource(token)); err == nil {
+			_ = userInfo.Claims(&claims)
+		} else {
+			mngr.log.Warn().Err(err).Msg("unable to query the OIDC user info")
+		}
+	}
+
+	return mngr.newIdentity(idToken.Issuer, idToken.Subject, claims), nil
+}
+
+func (mngr *OIDCManager) newIdentity(issuer, subject string, claims map[string]interface{}) *OIDCIdentity {
+	identity := &OIDCIdentity{
+		AccountIdentity: data.AccountIdentity{
+			Issuer:  issuer,
+			Subject: subject,
+		},
+		Email:     claimString(claims, "email"),
+		FirstName: claimString(claims, "given_name"),
+		LastName:  claimString(claims, "family_name"),
+		Operator:  mngr.conf.OIDC.DefaultOperator,
+	}
+
+	// Some providers send the verification status as a string
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = strings.EqualFold(verified, "true")
+	}
+
+	if identity.FirstName == "" && identity.LastName == "" {
+		if names := strings.Fields(claimString(claims, "name")); len(names) > 0 {
+			identity.FirstName = strings.Join(names[:len(names)-1], " ")
+			identity.LastName = names[len(names)-1]
+		}
+	}
+
+	if mngr.conf.OIDC.OperatorClaim != "" {
+		if op := claimString(claims, mngr.conf.OIDC.OperatorClaim); op != "" {
+			identity.Operator = op
+		}
+	}
+
+	return identity
+}
+
+func (mngr *OIDCManager) getProvider() (*oidc.Provider, error) {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	if mngr.provider != nil {
+		return mngr.provider, nil
+	}
+
+	if !mngr.Enabled() {
+		return nil, errors.Errorf("no OpenID Connect provider configured")
+	}
+
+	// The provider keeps the context to fetch its keys later on, so it must not be bound to a request
+	provider, err := oidc.NewProvider(oidc.ClientContext(context.Background(), mngr.client), mngr.conf.OIDC.Issuer)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to discover the OpenID Connect provider")
+	}
+	mngr.provider = provider
+
+	return mngr.provider, nil
+}
+
+func (mngr *OIDCManager) oauth2Config(provider *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     mngr.conf.OIDC.ClientID,
+		ClientSecret: mngr.conf.OIDC.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  strings.TrimRight(mngr.conf.Webserver.URL, "/") + config.EndpointOIDCCallback,
+		Scopes:       mngr.conf.OIDC.Scopes,
+	}
+}
+
+func claimString(claims map[string]interface{}, name string) string {
+	if v, ok := claims[name]; ok && v != nil {
+		return strings.TrimSpace(fmt.Sprint(v))
+	}
+	return ""
+}
+
+// NewOIDCManager creates a new OpenID Connect manager instance.
+func NewOIDCManager(conf *config.Configuration, log *zerolog.Logger) (*OIDCManager, error) {
+	mngr := &OIDCManager{}
+	if err := mngr.initialize(conf, log); err != nil {
+		return nil, errors.Wrap(err, "unable to initialize the OIDC manager")
+	}
+	return mngr, nil
+}
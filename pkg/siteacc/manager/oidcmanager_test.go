@@ -0,0 +1,197 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package manager
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/golang-jwt/jwt"
+	"github.com/rs/zerolog"
+)
+
+const testClientID = "siteacc"
+
+// testOIDCProvider is a minimal OpenID Connect provider issuing ID tokens with the configured nonce and claims.
+type testOIDCProvider struct {
+	*httptest.Server
+
+	key      *rsa.PrivateKey
+	nonce    string
+	claims   map[string]interface{}
+	userInfo map[string]interface{}
+}
+
+func newTestOIDCProvider(t *testing.T) *testOIDCProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &testOIDCProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"issuer":                                p.URL,
+			"authorization_endpoint":                p.URL + "/auth",
+			"token_endpoint":                        p.URL + "/token",
+			"userinfo_endpoint":                     p.URL + "/userinfo",
+			"jwks_uri":                              p.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": "test",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		claims := jwt.MapClaims{
+			"iss":   p.URL,
+			"sub":   "einstein-sub",
+			"aud":   testClientID,
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"nonce": p.nonce,
+		}
+		for k, v := range p.claims {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test"
+		idToken, err := token.SignedString(key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   60,
+			"id_token":     idToken,
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		info := map[string]interface{}{"sub": "einstein-sub"}
+		for k, v := range p.userInfo {
+			info[k] = v
+		}
+		writeJSON(w, info)
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func newTestOIDCManager(t *testing.T, issuer string) *OIDCManager {
+	t.Helper()
+
+	conf := &config.Configuration{}
+	conf.Webserver.URL = "https://siteacc.example.org"
+	conf.OIDC.Issuer = issuer
+	conf.OIDC.ClientID = testClientID
+	conf.OIDC.ClientSecret = "secret"
+	conf.OIDC.Scopes = []string{"openid", "email", "profile"}
+	conf.OIDC.OperatorClaim = "site"
+	conf.OIDC.DefaultOperator = "op"
+	log := zerolog.Nop()
+
+	mngr, err := NewOIDCManager(conf, &log)
+	if err != nil {
+		t.Fatalf("unable to create the OIDC manager: %v", err)
+	}
+	return mngr
+}
+
+func TestOIDCExchange(t *testing.T) {
+	provider := newTestOIDCProvider(t)
+	provider.nonce = "nonce"
+	provider.claims = map[string]interface{}{
+		"email":          "einstein@example.org",
+		"email_verified": "true",
+		"name":           "Albert Einstein",
+		"site":           "CERN",
+	}
+	mngr := newTestOIDCManager(t, provider.URL)
+
+	identity, err := mngr.Exchange(context.Background(), "code", "nonce")
+	if err != nil {
+		t.Fatalf("Exchange: unexpected error: %v", err)
+	}
+	if identity.Issuer != provider.URL || identity.Subject != "einstein-sub" {
+		t.Errorf("Exchange() = %v/%v, want %v/einstein-sub", identity.Issuer, identity.Subject, provider.URL)
+	}
+	if identity.Email != "einstein@example.org" || !identity.EmailVerified {
+		t.Errorf("Exchange() = %v (verified: %v), want a verified einstein@example.org", identity.Email, identity.EmailVerified)
+	}
+	if identity.FirstName != "Albert" || identity.LastName != "Einstein" || identity.Operator != "CERN" {
+		t.Errorf("Exchange() = %v %v of %v, want Albert Einstein of CERN", identity.FirstName, identity.LastName, identity.Operator)
+	}
+}
+
+func TestOIDCExchangeNonceMismatch(t *testing.T) {
+	provider := newTestOIDCProvider(t)
+	provider.nonce = "other-login"
+	mngr := newTestOIDCManager(t, provider.URL)
+
+	if identity, err := mngr.Exchange(context.Background(), "code", "nonce"); err == nil {
+		t.Errorf("Exchange() = %+v, want an error for a nonce mismatch", identity)
+	}
+}
+
+func TestOIDCExchangeUserInfo(t *testing.T) {
+	provider := newTestOIDCProvider(t)
+	provider.nonce = "nonce"
+	provider.userInfo = map[string]interface{}{"email": "einstein@example.org", "email_verified": false}
+	mngr := newTestOIDCManager(t, provider.URL)
+
+	identity, err := mngr.Exchange(context.Background(), "code", "nonce")
+	if err != nil {
+		t.Fatalf("Exchange: unexpected error: %v", err)
+	}
+	if identity.Email != "einstein@example.org" || identity.EmailVerified {
+		t.Errorf("Exchange() = %v (verified: %v), want an unverified einstein@example.org from the user info", identity.Email, identity.EmailVerified)
+	}
+	if identity.Operator != "op" {
+		t.Errorf("Exchange() operator = %v, want the default operator", identity.Operator)
+	}
+}
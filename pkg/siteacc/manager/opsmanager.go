@@ -21,7 +21,9 @@ package manager
 import (
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/cs3org/reva/pkg/mentix/key"
 	"github.com/cs3org/reva/pkg/siteacc/config"
 	"github.com/cs3org/reva/pkg/siteacc/data"
 
@@ -79,6 +81,44 @@ func (mngr *OperatorsManager) writeAllOperators() {
 	}
 }
 
+// ReloadOperators reloads all operators if they have been modified by another service instance sharing the same storage.
+func (mngr *OperatorsManager) ReloadOperators() {
+	shared, ok := mngr.storage.(data.SharedStorage)
+	if !ok {
+		return
+	}
+	if modified, err := shared.OperatorsModified(); err != nil {
+		mngr.log.Warn().Err(err).Msg("error while checking for modified operators")
+		return
+	} else if !modified {
+		return
+	}
+
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	ops, err := mngr.storage.ReadOperators()
+	if err != nil {
+		mngr.log.Warn().Err(err).Msg("error while reloading operators")
+		return
+	}
+
+	// Existing operators are updated in place, as they are also referenced by the sessions of logged in users
+	current := make(map[string]*data.Operator, len(mngr.operators))
+	for _, op := range mngr.operators {
+		current[strings.ToLower(op.ID)] = op
+	}
+	reloaded := make(data.Operators, 0, len(*ops))
+	for _, op := range *ops {
+		if existing, ok := current[strings.ToLower(op.ID)]; ok {
+			*existing = *op
+			op = existing
+		}
+		reloaded = append(reloaded, op)
+	}
+	mngr.operators = reloaded
+}
+
 // GetOperator retrieves the operator with the given ID, creating it first if necessary.
 func (mngr *OperatorsManager) GetOperator(id string, clone bool) (*data.Operator, error) {
 	mngr.mutex.RLock()
@@ -134,6 +174,309 @@ func (mngr *OperatorsManager) UpdateOperator(opData *data.Operator) error {
 	return nil
 }
 
+// ConfigureOperator configures the operator identified by the ID; if no such operator exists, one will be created first.
+func (mngr *OperatorsManager) ConfigureOperator(opData *data.Operator) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	op, err := mngr.getOperator(opData.ID)
+	if err != nil {
+		return errors.Wrap(err, "operator to configure not found")
+	}
+
+	if err := op.Configure(opData); err == nil {
+		mngr.storage.OperatorUpdated(op)
+		mngr.writeAllOperators()
+	} else {
+		return errors.Wrap(err, "error while configuring operator")
+	}
+
+	return nil
+}
+
+// IssueSiteKey issues a new key for the specified site of an operator, replacing (and thus revoking) any previously issued key.
+func (mngr *OperatorsManager) IssueSiteKey(opID string, siteID string) (key.APIKey, error) {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	op, site, err := mngr.getSite(opID, siteID)
+	if err != nil {
+		return "", err
+	}
+
+	apiKey, err := site.IssueKey()
+	if err != nil {
+		return "", err
+	}
+
+	mngr.storage.OperatorUpdated(op)
+	mngr.writeAllOperators()
+
+	return apiKey, nil
+}
+
+// VerifySiteKey checks whether the given key has been issued for the specified site, updating its usage information.
+func (mngr *OperatorsManager) VerifySiteKey(siteID string, apiKey key.APIKey) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	op, site := mngr.FindSite(siteID)
+	if site == nil {
+		return errors.Errorf("no site with ID %v exists", siteID)
+	}
+
+	if err := site.VerifyKey(apiKey); err != nil {
+		return err
+	}
+
+	mngr.storage.OperatorUpdated(op)
+	mngr.writeAllOperators()
+
+	return nil
+}
+
+// IssueAPIKey issues a new API key with the given name and scope for an operator; the generated key is returned along with a clone of its information.
+func (mngr *OperatorsManager) IssueAPIKey(opID string, name string, scope string, expires time.Time, createdBy string) (*data.OperatorAPIKey, string, error) {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	op, err := mngr.getOperator(opID)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "operator not found")
+	}
+
+	apiKey, key, err := op.IssueAPIKey(name, scope, expires, createdBy)
+	if err != nil {
+		return nil, "", err
+	}
+
+	mngr.storage.OperatorUpdated(op)
+	mngr.writeAllOperators()
+
+	return apiKey.Clone(true), key, nil
+}
+
+// RotateAPIKey replaces the specified API key of an operator by a new one, immediately invalidating the old one; the new key is returned.
+func (mngr *OperatorsManager) RotateAPIKey(opID string, id string) (string, error) {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	op, err := mngr.findOperator(opID)
+	if err != nil {
+		return "", err
+	}
+
+	_, key, err := op.RotateAPIKey(id)
+	if err != nil {
+		return "", err
+	}
+
+	mngr.storage.OperatorUpdated(op)
+	mngr.writeAllOperators()
+
+	return key, nil
+}
+
+// RevokeAPIKey removes the specified API key of an operator.
+func (mngr *OperatorsManager) RevokeAPIKey(opID string, id string) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	op, err := mngr.findOperator(opID)
+	if err != nil {
+		return err
+	}
+
+	if err := op.RevokeAPIKey(id); err != nil {
+		return err
+	}
+
+	mngr.storage.OperatorUpdated(op)
+	mngr.writeAllOperators()
+
+	return nil
+}
+
+// CloneAPIKeys retrieves clones of all API keys issued by an operator; the key hashes are always erased.
+func (mngr *OperatorsManager) CloneAPIKeys(opID string) []*data.OperatorAPIKey {
+	mngr.mutex.RLock()
+	defer mngr.mutex.RUnlock()
+
+	clones := make([]*data.OperatorAPIKey, 0)
+	if op, _ := mngr.findOperator(opID); op != nil {
+		for _, apiKey := range op.APIKeys {
+			clones = append(clones, apiKey.Clone(true))
+		}
+	}
+	return clones
+}
+
+// VerifyAPIKey checks whether the given API key has been issued by any operator and is still valid, updating its usage information; clones of the operator and the key are returned.
+func (mngr *OperatorsManager) VerifyAPIKey(key string) (*data.Operator, *data.OperatorAPIKey, error) {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	id, _, err := data.SplitAPIKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	op := mngr.findOperatorByPredicate(func(op *data.Operator) bool { return op.FindAPIKey(id) != nil })
+	if op == nil {
+		return nil, nil, errors.Errorf("the API key is invalid")
+	}
+
+	apiKey, err := op.VerifyAPIKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mngr.storage.OperatorUpdated(op)
+	mngr.writeAllOperators()
+
+	return op.Clone(true), apiKey.Clone(true), nil
+}
+
+// UpdateSite updates the settings of a single site of an operator; if the site hasn't been configured yet, it will be added to the operator.
+func (mngr *OperatorsManager) UpdateSite(opID string, siteData *data.Site) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	op, site, err := mngr.getSite(opID, siteData.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := site.Update(siteData, mngr.conf.Security.CredentialsPassphrase); err != nil {
+		return errors.Wrapf(err, "error while updating site %v", site.ID)
+	}
+
+	mngr.storage.OperatorUpdated(op)
+	mngr.writeAllOperators()
+
+	return nil
+}
+
+// GrantSiteManagement grants or revokes the management rights over the specified site of an operator to the account with the given email address.
+func (mngr *OperatorsManager) GrantSiteManagement(opID string, siteID string, email string, grant bool) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	op, site, err := mngr.getSite(opID, siteID)
+	if err != nil {
+		return err
+	}
+
+	site.GrantManagement(email, grant)
+
+	mngr.storage.OperatorUpdated(op)
+	mngr.writeAllOperators()
+
+	return nil
+}
+
+// RevokeSiteManagement revokes all management rights granted to the account with the given email address.
+func (mngr *OperatorsManager) RevokeSiteManagement(email string) {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	for _, op := range mngr.operators {
+		modified := false
+		for _, site := range op.Sites {
+			if site.IsManagedBy(email) {
+				site.GrantManagement(email, false)
+				modified = true
+			}
+		}
+
+		if modified {
+			mngr.storage.OperatorUpdated(op)
+			mngr.writeAllOperators()
+		}
+	}
+}
+
+// UpdateSitesHealth stores the results of the latest health checks; the results are mapped by the site IDs.
+func (mngr *OperatorsManager) UpdateSitesHealth(health map[string]*data.SiteHealth) {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	modified := false
+	for _, op := range mngr.operators {
+		opModified := false
+		for _, site := range op.Sites {
+			if siteHealth, ok := health[site.ID]; ok {
+				site.Health = siteHealth.Clone()
+				opModified = true
+			}
+		}
+
+		if opModified {
+			mngr.storage.OperatorUpdated(op)
+			modified = true
+		}
+	}
+
+	if modified {
+		mngr.writeAllOperators()
+	}
+}
+
+// CloneSitesHealth retrieves the results of the latest health checks of all sites; the results are mapped by the site IDs.
+func (mngr *OperatorsManager) CloneSitesHealth() map[string]*data.SiteHealth {
+	mngr.mutex.RLock()
+	defer mngr.mutex.RUnlock()
+
+	health := make(map[string]*data.SiteHealth)
+	for _, op := range mngr.operators {
+		for _, site := range op.Sites {
+			if site.Health != nil {
+				health[site.ID] = site.Health.Clone()
+			}
+		}
+	}
+	return health
+}
+
+// FindManagedSite returns clones of the specified site and its operator if the account with the given email address has been granted management rights over it.
+func (mngr *OperatorsManager) FindManagedSite(siteID string, email string) (*data.Operator, *data.Site) {
+	mngr.mutex.RLock()
+	defer mngr.mutex.RUnlock()
+
+	if op, site := mngr.FindSite(siteID); site != nil && site.IsManagedBy(email) {
+		return op.Clone(true), site.Clone(true)
+	}
+	return nil, nil
+}
+
+// CloneManagedSites retrieves all operators with sites the account with the given email address has been granted management rights over; only these sites are included in the cloned operators.
+func (mngr *OperatorsManager) CloneManagedSites(email string, eraseCredentials bool) data.Operators {
+	mngr.mutex.RLock()
+	defer mngr.mutex.RUnlock()
+
+	clones := make(data.Operators, 0)
+	for _, op := range mngr.operators {
+		var clone *data.Operator
+		for _, site := range op.Sites {
+			if !site.IsManagedBy(email) {
+				continue
+			}
+
+			if clone == nil {
+				clone = &data.Operator{
+					ID:       op.ID,
+					Sites:    []*data.Site{},
+					Settings: op.Settings,
+				}
+				clones = append(clones, clone)
+			}
+			clone.Sites = append(clone.Sites, site.Clone(eraseCredentials))
+		}
+	}
+
+	return clones
+}
+
 // CloneOperators retrieves all operators currently stored by cloning the data, thus avoiding race conflicts and making outside modifications impossible.
 func (mngr *OperatorsManager) CloneOperators(eraseCredentials bool) data.Operators {
 	mngr.mutex.RLock()
@@ -155,6 +498,24 @@ func (mngr *OperatorsManager) getOperator(id string) (*data.Operator, error) {
 	return op, err
 }
 
+func (mngr *OperatorsManager) getSite(opID string, siteID string) (*data.Operator, *data.Site, error) {
+	op, err := mngr.getOperator(opID)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "operator not found")
+	}
+
+	site := op.FindSite(siteID)
+	if site == nil {
+		// The site hasn't been configured yet, so add it to the operator
+		if site, err = data.NewSite(siteID); err != nil {
+			return nil, nil, errors.Wrap(err, "unable to create site")
+		}
+		op.Sites = append(op.Sites, site)
+	}
+
+	return op, site, nil
+}
+
 func (mngr *OperatorsManager) createOperator(id string) (*data.Operator, error) {
 	op, err := data.NewOperator(id)
 	if err != nil {
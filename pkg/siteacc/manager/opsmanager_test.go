@@ -0,0 +1,152 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/rs/zerolog"
+)
+
+func newTestOperatorsManager(t *testing.T, ops ...*data.Operator) (*OperatorsManager, *memStorage) {
+	t.Helper()
+
+	log := zerolog.Nop()
+	storage := &memStorage{operators: ops}
+	mngr, err := NewOperatorsManager(storage, &config.Configuration{}, &log)
+	if err != nil {
+		t.Fatalf("unable to create the operators manager: %v", err)
+	}
+	return mngr, storage
+}
+
+func TestSiteManagementRights(t *testing.T) {
+	mngr, storage := newTestOperatorsManager(t,
+		&data.Operator{ID: "op1", Sites: []*data.Site{{ID: "site1"}, {ID: "site2"}}},
+		&data.Operator{ID: "op2", Sites: []*data.Site{{ID: "site3"}}},
+	)
+
+	for _, grant := range [][2]string{{"op1", "site1"}, {"op2", "site3"}} {
+		if err := mngr.GrantSiteManagement(grant[0], grant[1], "john@example.org", true); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if storage.operators[0].FindSite("site1").Managers == nil {
+		t.Error("expected the rights to be stored")
+	}
+
+	if op, site := mngr.FindManagedSite("site1", "john@example.org"); op == nil || op.ID != "op1" || site.ID != "site1" {
+		t.Errorf("expected the managed site to be found, got %v and %v", op, site)
+	}
+	if op, site := mngr.FindManagedSite("site2", "john@example.org"); op != nil || site != nil {
+		t.Error("expected sites without rights not to be found")
+	}
+
+	ops := mngr.CloneManagedSites("john@example.org", true)
+	if len(ops) != 2 || len(ops[0].Sites) != 1 || ops[0].Sites[0].ID != "site1" || ops[1].Sites[0].ID != "site3" {
+		t.Errorf("expected only the managed sites to be cloned, got %+v", ops)
+	}
+	if ops := mngr.CloneManagedSites("jane@example.org", true); len(ops) != 0 {
+		t.Errorf("expected no sites for other accounts, got %+v", ops)
+	}
+
+	if err := mngr.GrantSiteManagement("op1", "site1", "john@example.org", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, site := mngr.FindManagedSite("site1", "john@example.org"); site != nil {
+		t.Error("expected the rights to be revoked")
+	}
+}
+
+func TestSiteManagementListener(t *testing.T) {
+	mngr, _ := newTestOperatorsManager(t, &data.Operator{ID: "op", Sites: []*data.Site{
+		{ID: "site1", Managers: []string{"john@example.org", "jane@example.org"}},
+		{ID: "site2", Managers: []string{"JOHN@example.org"}},
+	}})
+
+	listener := NewSiteManagementListener(mngr)
+	listener.AccountUpdated(newTestAccount("john@example.org"))
+	if _, site := mngr.FindManagedSite("site2", "john@example.org"); site == nil {
+		t.Fatal("expected updates not to revoke any rights")
+	}
+
+	listener.AccountRemoved(newTestAccount("john@example.org"))
+	for _, id := range []string{"site1", "site2"} {
+		if _, site := mngr.FindManagedSite(id, "john@example.org"); site != nil {
+			t.Errorf("expected the rights over %v to be revoked", id)
+		}
+	}
+	if _, site := mngr.FindManagedSite("site1", "jane@example.org"); site == nil {
+		t.Error("expected the rights of other accounts to be kept")
+	}
+}
+
+func TestOperatorAPIKeys(t *testing.T) {
+	mngr, storage := newTestOperatorsManager(t, &data.Operator{ID: "op1"}, &data.Operator{ID: "op2"})
+
+	apiKey, key, err := mngr.IssueAPIKey("op2", "monitoring", data.APIKeyScopeMonitoring, time.Time{}, "john@example.org")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if apiKey.Hash != "" {
+		t.Error("expected the key hash not to be returned")
+	}
+	if len(storage.operators[1].APIKeys) != 1 || storage.operators[1].APIKeys[0].Hash == "" {
+		t.Error("expected the key to be stored")
+	}
+
+	op, verified, err := mngr.VerifyAPIKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if op.ID != "op2" || verified.ID != apiKey.ID || verified.UseCount != 1 || verified.Hash != "" {
+		t.Errorf("unexpected verification result %+v and %+v", op, verified)
+	}
+	if _, _, err := mngr.VerifyAPIKey("unknown.secret"); err == nil {
+		t.Error("expected an unknown key to be rejected")
+	}
+
+	if keys := mngr.CloneAPIKeys("op2"); len(keys) != 1 || keys[0].Hash != "" {
+		t.Errorf("expected the keys to be cloned without hashes, got %+v", keys)
+	}
+	if keys := mngr.CloneAPIKeys("op1"); len(keys) != 0 {
+		t.Errorf("expected no keys for other operators, got %+v", keys)
+	}
+
+	newKey, err := mngr.RotateAPIKey("op2", apiKey.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := mngr.VerifyAPIKey(key); err == nil {
+		t.Error("expected the rotated key to be rejected")
+	}
+	if _, err := mngr.RotateAPIKey("op1", apiKey.ID); err == nil {
+		t.Error("expected keys of other operators not to be rotated")
+	}
+
+	if err := mngr.RevokeAPIKey("op2", apiKey.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := mngr.VerifyAPIKey(newKey); err == nil {
+		t.Error("expected the revoked key to be rejected")
+	}
+}
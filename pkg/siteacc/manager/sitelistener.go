@@ -0,0 +1,45 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package manager
+
+import "github.com/cs3org/reva/pkg/siteacc/data"
+
+// SiteManagementListener revokes the site management rights of removed accounts.
+type SiteManagementListener struct {
+	opsManager *OperatorsManager
+}
+
+// AccountCreated is called whenever an account was created.
+func (listener *SiteManagementListener) AccountCreated(account *data.Account) {
+}
+
+// AccountUpdated is called whenever an account was updated.
+func (listener *SiteManagementListener) AccountUpdated(account *data.Account) {
+}
+
+// AccountRemoved is called whenever an account was removed.
+func (listener *SiteManagementListener) AccountRemoved(account *data.Account) {
+	// Rights granted to a removed account must not pass on to a new account registered with the same email address
+	listener.opsManager.RevokeSiteManagement(account.Email)
+}
+
+// NewSiteManagementListener creates a new site management listener.
+func NewSiteManagementListener(opsManager *OperatorsManager) *SiteManagementListener {
+	return &SiteManagementListener{opsManager: opsManager}
+}
@@ -20,8 +20,10 @@ package manager
 
 import (
 	"strings"
+	"time"
 
 	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/data"
 	"github.com/cs3org/reva/pkg/siteacc/html"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
@@ -38,6 +40,11 @@ type UsersManager struct {
 
 const (
 	defaultPasswordLength = 12
+
+	// twoFactorLoginTimeout is the time users have to provide their second factor after entering their password.
+	twoFactorLoginTimeout = 5 * time.Minute
+	// twoFactorMaxAttempts is the number of invalid codes after which a pending login is discarded.
+	twoFactorMaxAttempts = 5
 )
 
 func (mngr *UsersManager) initialize(conf *config.Configuration, log *zerolog.Logger, opsManager *OperatorsManager, accountsManager *AccountsManager) error {
@@ -65,28 +72,95 @@ func (mngr *UsersManager) initialize(conf *config.Configuration, log *zerolog.Lo
 }
 
 // LoginUser tries to login a given username/password pair. On success, the corresponding user account is stored in the session and a user token is returned.
-func (mngr *UsersManager) LoginUser(name, password string, scope string, session *html.Session) (string, error) {
+// If the account uses two-factor authentication, the login is kept pending in the session instead, until it is completed through VerifyLogin; in this case, no token is returned and twoFactor is set.
+func (mngr *UsersManager) LoginUser(name, password string, scope string, session *html.Session) (token string, twoFactor bool, err error) {
 	account, err := mngr.accountsManager.FindAccountEx(FindByEmail, name, false)
 	if err != nil {
-		return "", errors.Wrap(err, "no account with the specified email exists")
+		return "", false, errors.Wrap(err, "no account with the specified email exists")
 	}
 
 	// Verify the provided password
 	if !account.Password.Compare(password) {
-		return "", errors.Errorf("invalid password")
+		return "", false, errors.Errorf("invalid password")
+	}
+
+	return mngr.authorizeLogin(account, scope, session)
+}
+
+// LoginOIDCUser logs in a user authenticated by an OpenID Connect provider. The account of the user is looked up (or provisioned) using the provided identity; the login then proceeds as in LoginUser.
+func (mngr *UsersManager) LoginOIDCUser(identity *OIDCIdentity, scope string, session *html.Session) (token string, twoFactor bool, err error) {
+	account, err := mngr.accountsManager.FindOIDCAccount(identity)
+	if err != nil {
+		return "", false, err
+	}
+
+	return mngr.authorizeLogin(account, scope, session)
+}
+
+func (mngr *UsersManager) authorizeLogin(account *data.Account, scope string, session *html.Session) (string, bool, error) {
+	// Accounts scheduled for deletion or suspended by an administrator are disabled
+	if account.Deletion != nil {
+		return "", false, errors.Errorf("the account has been disabled due to a deletion request")
+	}
+	if account.Suspended {
+		return "", false, errors.Errorf("the account has been disabled by an administrator")
+	}
+
+	// New accounts might need to be verified and approved first
+	if !account.IsVerified() {
+		return "", false, errors.Errorf("the email address of the account hasn't been verified yet; please check your inbox")
+	}
+	if account.PendingApproval {
+		return "", false, errors.Errorf("the account hasn't been approved by an administrator yet")
 	}
 
 	// Check if the user has access to the specified scope
 	if !account.CheckScopeAccess(scope) {
-		return "", errors.Errorf("no access to the specified scope granted")
+		return "", false, errors.Errorf("no access to the specified scope granted")
 	}
 
 	// Get the sites the account belongs to
 	op, err := mngr.operatorsManager.GetOperator(account.Operator, false)
 	if err != nil {
-		return "", errors.Wrap(err, "no operator with the specified ID exists")
+		return "", false, errors.Wrap(err, "no operator with the specified ID exists")
 	}
 
+	// Accounts required to use two-factor authentication may only access the sites once they have enabled it
+	if strings.EqualFold(scope, data.ScopeSites) && account.RequiresTwoFactor(op) && !account.TwoFactor.Enabled {
+		return "", false, errors.Errorf("two-factor authentication is required by your operator")
+	}
+
+	// With two-factor authentication, the user is only logged in after providing a valid code
+	if account.TwoFactor.Enabled {
+		session.BeginPendingLogin(account, op, scope, twoFactorLoginTimeout)
+		return "", true, nil
+	}
+
+	token, err := mngr.loginUser(account, op, scope, session)
+	return token, false, err
+}
+
+// VerifyLogin completes the login pending in the session using the code of the second factor. On success, a user token is returned.
+func (mngr *UsersManager) VerifyLogin(code string, session *html.Session) (string, error) {
+	pending := session.PendingLogin()
+	if pending == nil {
+		return "", errors.Errorf("no login is pending or it has expired")
+	}
+
+	if err := mngr.accountsManager.VerifyTwoFactor(pending.User.Account, code); err != nil {
+		// Discard the pending login after too many invalid codes, so that the password needs to be provided again
+		pending.Attempts++
+		if pending.Attempts >= twoFactorMaxAttempts {
+			session.ClearPendingLogin()
+			return "", errors.Errorf("too many invalid codes; please login again")
+		}
+		return "", err
+	}
+
+	return mngr.loginUser(pending.User.Account, pending.User.Operator, pending.Scope, session)
+}
+
+func (mngr *UsersManager) loginUser(account *data.Account, op *data.Operator, scope string, session *html.Session) (string, error) {
 	// Store the user account in the session
 	session.LoginUser(account, op)
 
@@ -121,9 +195,15 @@ func (mngr *UsersManager) VerifyUserToken(token string, user string, scope strin
 	// Check if the user account actually exists and has proper scope access
 	if strings.EqualFold(scope, utoken.Scope) {
 		if acc, err := mngr.accountsManager.FindAccount(FindByEmail, utoken.User); err == nil {
+			if acc.IsDisabled() {
+				return "", errors.Errorf("account disabled")
+			}
 			if !acc.CheckScopeAccess(scope) {
 				return "", errors.Errorf("no scope access")
 			}
+			if strings.EqualFold(scope, data.ScopeSites) && acc.RequiresTwoFactor(mngr.operatorsManager.FindOperator(acc.Operator)) && !acc.TwoFactor.Enabled {
+				return "", errors.Errorf("two-factor authentication required")
+			}
 		} else {
 			return "", errors.Errorf("invalid email")
 		}
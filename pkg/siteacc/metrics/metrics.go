@@ -0,0 +1,159 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package metrics records the operational metrics of the site accounts service. The metrics are only collected once
+// the views have been registered; they are then exposed by the prometheus service of Reva.
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// The login methods.
+const (
+	MethodPassword  = "password"
+	MethodTwoFactor = "2fa"
+	MethodOIDC      = "oidc"
+)
+
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+var (
+	activeSessions      = stats.Int64("siteacc_active_sessions", "The number of active sessions", stats.UnitDimensionless)
+	logins              = stats.Int64("siteacc_logins", "The number of logins", stats.UnitDimensionless)
+	registrations       = stats.Int64("siteacc_registrations", "The number of account registrations", stats.UnitDimensionless)
+	emailFailures       = stats.Int64("siteacc_email_send_failures", "The number of emails that could not be sent", stats.UnitDimensionless)
+	mentixQueryDuration = stats.Float64("siteacc_mentix_query_duration", "The duration of the queries to Mentix", stats.UnitMilliseconds)
+	panelRenderDuration = stats.Float64("siteacc_panel_render_duration", "The duration of the rendering of the panels", stats.UnitMilliseconds)
+
+	methodKey = tag.MustNewKey("method")
+	resultKey = tag.MustNewKey("result")
+	emailKey  = tag.MustNewKey("email")
+	panelKey  = tag.MustNewKey("panel")
+
+	durationDistribution = view.Distribution(5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000)
+
+	registerViews sync.Once
+	registerErr   error
+)
+
+// Register registers the views of all metrics, so that they are collected and exported.
+func Register() error {
+	registerViews.Do(func() {
+		registerErr = view.Register(
+			&view.View{
+				Name:        activeSessions.Name(),
+				Description: activeSessions.Description(),
+				Measure:     activeSessions,
+				Aggregation: view.LastValue(),
+			},
+			&view.View{
+				Name:        logins.Name(),
+				Description: logins.Description(),
+				Measure:     logins,
+				TagKeys:     []tag.Key{methodKey, resultKey},
+				Aggregation: view.Count(),
+			},
+			&view.View{
+				Name:        registrations.Name(),
+				Description: registrations.Description(),
+				Measure:     registrations,
+				TagKeys:     []tag.Key{resultKey},
+				Aggregation: view.Count(),
+			},
+			&view.View{
+				Name:        emailFailures.Name(),
+				Description: emailFailures.Description(),
+				Measure:     emailFailures,
+				TagKeys:     []tag.Key{emailKey},
+				Aggregation: view.Count(),
+			},
+			&view.View{
+				Name:        mentixQueryDuration.Name(),
+				Description: mentixQueryDuration.Description(),
+				Measure:     mentixQueryDuration,
+				TagKeys:     []tag.Key{resultKey},
+				Aggregation: durationDistribution,
+			},
+			&view.View{
+				Name:        panelRenderDuration.Name(),
+				Description: panelRenderDuration.Description(),
+				Measure:     panelRenderDuration,
+				TagKeys:     []tag.Key{panelKey},
+				Aggregation: durationDistribution,
+			},
+		)
+	})
+	return registerErr
+}
+
+// RecordActiveSessions records the current number of sessions.
+func RecordActiveSessions(count int) {
+	stats.Record(context.Background(), activeSessions.M(int64(count)))
+}
+
+// RecordLogin records a login through the given method.
+func RecordLogin(method string, err error) {
+	record(logins.M(1), tag.Insert(methodKey, method), tag.Insert(resultKey, result(err)))
+}
+
+// RecordRegistration records the registration of an account.
+func RecordRegistration(err error) {
+	record(registrations.M(1), tag.Insert(resultKey, result(err)))
+}
+
+// RecordEmailFailure records an email that could not be sent.
+func RecordEmailFailure(email string) {
+	record(emailFailures.M(1), tag.Insert(emailKey, email))
+}
+
+// RecordMentixQuery records the duration of a query to Mentix started at the given time.
+func RecordMentixQuery(start time.Time, err error) {
+	record(mentixQueryDuration.M(milliseconds(start)), tag.Insert(resultKey, result(err)))
+}
+
+// RecordPanelRender records the duration of the rendering of a panel started at the given time.
+func RecordPanelRender(panel string, start time.Time) {
+	record(panelRenderDuration.M(milliseconds(start)), tag.Insert(panelKey, panel))
+}
+
+func record(m stats.Measurement, mutators ...tag.Mutator) {
+	if ctx, err := tag.New(context.Background(), mutators...); err == nil {
+		stats.Record(ctx, m)
+	}
+}
+
+func result(err error) string {
+	if err != nil {
+		return resultFailure
+	}
+	return resultSuccess
+}
+
+func milliseconds(start time.Time) float64 {
+	return float64(time.Since(start)) / float64(time.Millisecond)
+}
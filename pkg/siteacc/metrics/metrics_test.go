@@ -0,0 +1,103 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+func retrieveRow(t *testing.T, name string, tags ...tag.Tag) view.AggregationData {
+	t.Helper()
+
+	rows, err := view.RetrieveData(name)
+	if err != nil {
+		t.Fatalf("unable to retrieve the data of %v: %v", name, err)
+	}
+	for _, row := range rows {
+		if len(row.Tags) != len(tags) {
+			continue
+		}
+		matches := true
+		for i, tg := range tags {
+			if row.Tags[i] != tg {
+				matches = false
+			}
+		}
+		if matches {
+			return row.Data
+		}
+	}
+	return nil
+}
+
+func TestRecordLogins(t *testing.T) {
+	if err := Register(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Register(); err != nil {
+		t.Fatalf("expected registering twice to be harmless: %v", err)
+	}
+
+	RecordLogin(MethodPassword, nil)
+	RecordLogin(MethodPassword, nil)
+	RecordLogin(MethodPassword, errors.New("invalid password"))
+	RecordLogin(MethodOIDC, nil)
+
+	tests := []struct {
+		method string
+		result string
+		count  int64
+	}{
+		{MethodPassword, resultSuccess, 2},
+		{MethodPassword, resultFailure, 1},
+		{MethodOIDC, resultSuccess, 1},
+	}
+	for _, tt := range tests {
+		data := retrieveRow(t, logins.Name(), tag.Tag{Key: methodKey, Value: tt.method}, tag.Tag{Key: resultKey, Value: tt.result})
+		if count, ok := data.(*view.CountData); !ok || count.Value != tt.count {
+			t.Errorf("%s/%s: expected %d logins, got %v", tt.method, tt.result, tt.count, data)
+		}
+	}
+}
+
+func TestRecordActiveSessionsAndDurations(t *testing.T) {
+	if err := Register(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	RecordActiveSessions(5)
+	RecordActiveSessions(3)
+	if data, ok := retrieveRow(t, activeSessions.Name()).(*view.LastValueData); !ok || data.Value != 3 {
+		t.Errorf("expected the last number of sessions, got %v", data)
+	}
+
+	RecordPanelRender("admin", time.Now().Add(-30*time.Millisecond))
+	data, ok := retrieveRow(t, panelRenderDuration.Name(), tag.Tag{Key: panelKey, Value: "admin"}).(*view.DistributionData)
+	if !ok || data.Count != 1 {
+		t.Fatalf("expected a single rendering to be recorded, got %v", data)
+	}
+	if data.Min < 30 {
+		t.Errorf("expected the duration in milliseconds, got %v", data.Min)
+	}
+}
@@ -21,14 +21,25 @@ package siteacc
 import (
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/cs3org/reva/pkg/announcements"
+	"github.com/cs3org/reva/pkg/auth/loginguard"
+	"github.com/cs3org/reva/pkg/mentix/key"
+	"github.com/cs3org/reva/pkg/rhttp/problem"
 	accpanel "github.com/cs3org/reva/pkg/siteacc/account"
 	"github.com/cs3org/reva/pkg/siteacc/admin"
 	"github.com/cs3org/reva/pkg/siteacc/alerting"
+	"github.com/cs3org/reva/pkg/siteacc/audit"
+	"github.com/cs3org/reva/pkg/siteacc/captcha"
 	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/contacts"
 	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/cs3org/reva/pkg/siteacc/health"
 	"github.com/cs3org/reva/pkg/siteacc/html"
 	"github.com/cs3org/reva/pkg/siteacc/manager"
+	"github.com/cs3org/reva/pkg/siteacc/metrics"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
@@ -45,9 +56,28 @@ type SiteAccounts struct {
 	operatorsManager *manager.OperatorsManager
 	accountsManager  *manager.AccountsManager
 	usersManager     *manager.UsersManager
+	oidcManager      *manager.OIDCManager
+
+	announcementsManager *manager.AnnouncementsManager
 
 	alertsDispatcher *alerting.Dispatcher
 
+	contactsImporter *contacts.Importer
+
+	healthProber *health.Prober
+
+	auditor *audit.Auditor
+
+	api      http.Handler
+	problems *problem.Renderer
+
+	requestVerifier *key.RequestVerifier
+	mentixCache     *data.MentixCache
+
+	loginGuard        *loginguard.Guard
+	registrationGuard *loginguard.Guard
+	captchaVerifier   captcha.Verifier
+
 	adminPanel   *admin.Panel
 	accountPanel *accpanel.Panel
 }
@@ -63,12 +93,16 @@ func (siteacc *SiteAccounts) initialize(conf *config.Configuration, log *zerolog
 	}
 	siteacc.log = log
 
-	// Create the session mananger
-	sessions, err := html.NewSessionManager("siteacc_session", conf, log)
-	if err != nil {
-		return errors.Wrap(err, "error while creating the session manager")
+	// The metrics are only collected if enabled
+	if conf.Metrics.Enabled {
+		if err := metrics.Register(); err != nil {
+			return errors.Wrap(err, "error while registering the metrics")
+		}
+	}
+
+	if err := conf.Security.PasswordPolicy.Validate(); err != nil {
+		return errors.Wrap(err, "invalid password policy")
 	}
-	siteacc.sessions = sessions
 
 	// Create the central storage
 	storage, err := siteacc.createStorage(conf.Storage.Driver)
@@ -91,6 +125,9 @@ func (siteacc *SiteAccounts) initialize(conf *config.Configuration, log *zerolog
 	}
 	siteacc.accountsManager = amngr
 
+	// Rights to manage sites of other operators are revoked when the account is removed
+	amngr.AddListener(manager.NewSiteManagementListener(omngr))
+
 	// Create the users manager instance
 	umngr, err := manager.NewUsersManager(conf, log, siteacc.operatorsManager, siteacc.accountsManager)
 	if err != nil {
@@ -98,6 +135,27 @@ func (siteacc *SiteAccounts) initialize(conf *config.Configuration, log *zerolog
 	}
 	siteacc.usersManager = umngr
 
+	// Create the session mananger; sessions kept outside of the process are restored using the managers
+	sessions, err := html.NewSessionManager("siteacc_session", conf, siteacc.resolveSessionUser, log)
+	if err != nil {
+		return errors.Wrap(err, "error while creating the session manager")
+	}
+	siteacc.sessions = sessions
+
+	// Create the OIDC manager instance
+	oidcmngr, err := manager.NewOIDCManager(conf, log)
+	if err != nil {
+		return errors.Wrap(err, "error creating the OIDC manager")
+	}
+	siteacc.oidcManager = oidcmngr
+
+	// Create the announcements manager instance
+	anmngr, err := manager.NewAnnouncementsManager(conf, log)
+	if err != nil {
+		return errors.Wrap(err, "error creating the announcements manager")
+	}
+	siteacc.announcementsManager = anmngr
+
 	// Create the alerts dispatcher instance
 	dispatcher, err := alerting.NewDispatcher(conf, log)
 	if err != nil {
@@ -105,8 +163,66 @@ func (siteacc *SiteAccounts) initialize(conf *config.Configuration, log *zerolog
 	}
 	siteacc.alertsDispatcher = dispatcher
 
+	// Create the auditor instance
+	auditor, err := audit.NewAuditor(conf, log)
+	if err != nil {
+		return errors.Wrap(err, "error creating the auditor")
+	}
+	siteacc.auditor = auditor
+
+	// The REST API is served by its own router and authenticated using bearer tokens
+	siteacc.api = siteacc.newAPIHandler()
+	siteacc.problems = problem.NewRenderer(&conf.Errors)
+
+	// Requests sent by Mentix need to be signed if signing keys have been configured
+	if conf.Mentix.Signing.IsEnabled() {
+		verifier, err := key.NewRequestVerifier(&conf.Mentix.Signing)
+		if err != nil {
+			return errors.Wrap(err, "error creating the request verifier")
+		}
+		siteacc.requestVerifier = verifier
+	}
+
+	// The data queried from Mentix is cached unless disabled
+	if conf.Mentix.Cache.TTL > 0 {
+		ttl := time.Duration(conf.Mentix.Cache.TTL) * time.Second
+		maxStale := time.Duration(conf.Mentix.Cache.MaxStale) * time.Second
+		siteacc.mentixCache = data.EnableMentixCache(ttl, maxStale, log)
+	}
+
+	// Logins and registrations are protected against brute-force attacks if configured
+	if err := siteacc.createGuards(); err != nil {
+		return err
+	}
+	verifier, err := captcha.New(conf)
+	if err != nil {
+		return errors.Wrap(err, "error creating the CAPTCHA verifier")
+	}
+	siteacc.captchaVerifier = verifier
+
+	// Create the contacts importer instance if an import source has been configured
+	if conf.Contacts.Source != "" {
+		importer, err := contacts.NewImporter(conf, log, siteacc.accountsManager)
+		if err != nil {
+			return errors.Wrap(err, "error creating the contacts importer")
+		}
+		importer.Start()
+		siteacc.contactsImporter = importer
+	}
+
+	// Create the site health prober instance if periodic checks have been configured
+	if conf.Health.Interval > 0 {
+		prober, err := health.NewProber(conf, log, siteacc.operatorsManager)
+		if err != nil {
+			return errors.Wrap(err, "error creating the site health prober")
+		}
+		prober.Start()
+		siteacc.healthProber = prober
+	}
+
 	// Create the admin panel
 	if pnl, err := admin.NewPanel(conf, log); err == nil {
+		pnl.SetAnnouncementsProvider(anmngr.Active)
 		siteacc.adminPanel = pnl
 	} else {
 		return errors.Wrap(err, "unable to create the administration panel")
@@ -114,6 +230,7 @@ func (siteacc *SiteAccounts) initialize(conf *config.Configuration, log *zerolog
 
 	// Create the account panel
 	if pnl, err := accpanel.NewPanel(conf, log); err == nil {
+		pnl.SetAnnouncementsProvider(anmngr.Active)
 		siteacc.accountPanel = pnl
 	} else {
 		return errors.Wrap(err, "unable to create the account panel")
@@ -127,16 +244,48 @@ func (siteacc *SiteAccounts) RequestHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer r.Body.Close()
 
+		// Pick up any changes made by other instances sharing the same storage
+		siteacc.operatorsManager.ReloadOperators()
+		siteacc.accountsManager.ReloadAccounts()
+
+		// Requests to the REST API don't use any sessions
+		if strings.HasPrefix(r.URL.Path, apiPrefix+"/") {
+			siteacc.api.ServeHTTP(w, r)
+			return
+		}
+
 		// Get the active session for the request (or create a new one); a valid session object will always be returned
-		siteacc.sessions.PurgeSessions() // Remove expired sessions first
-		session, err := siteacc.sessions.HandleRequest(w, r)
-		if err != nil {
-			siteacc.log.Err(err).Msg("an error occurred while handling sessions")
+		// Requests authenticated with an API key don't use any session but get a transient one once the key has been verified
+		siteacc.accountsManager.PurgeDeletedAccounts() // Remove accounts whose deletion cooling-off period is over
+		var session *html.Session
+		apiKey := getRequestAPIKey(r)
+		if apiKey == "" {
+			var err error
+			if session, err = siteacc.sessions.HandleRequest(w, r); err != nil {
+				siteacc.log.Err(err).Msg("an error occurred while handling sessions")
+			}
 		}
 
 		epHandled := false
 		for _, ep := range getEndpoints() {
 			if ep.Path == r.URL.Path {
+				if err := siteacc.verifyRequest(ep, r); err != nil {
+					siteacc.log.Warn().Err(err).Str("path", r.URL.Path).Msg("rejected unverified request")
+					siteacc.writeError(w, r, http.StatusUnauthorized, fmt.Sprintf("Request verification failed: %v", err))
+					epHandled = true
+					break
+				}
+
+				if apiKey != "" {
+					var err error
+					if session, err = siteacc.authenticateAPIKey(ep, apiKey, r); err != nil {
+						siteacc.log.Warn().Err(err).Str("path", r.URL.Path).Msg("rejected API key")
+						siteacc.writeError(w, r, http.StatusUnauthorized, fmt.Sprintf("API key authentication failed: %v", err))
+						epHandled = true
+						break
+					}
+				}
+
 				ep.Handler(siteacc, ep, w, r, session)
 				epHandled = true
 				break
@@ -144,21 +293,59 @@ func (siteacc *SiteAccounts) RequestHandler() http.Handler {
 		}
 
 		if !epHandled {
-			w.WriteHeader(http.StatusBadRequest)
-			_, _ = w.Write([]byte(fmt.Sprintf("Unknown endpoint %v", r.URL.Path)))
+			siteacc.writeError(w, r, http.StatusNotFound, fmt.Sprintf("Unknown endpoint %v", r.URL.Path))
+		}
+
+		// Keep the changes made to the session while handling the request, like logins and logouts
+		if apiKey == "" {
+			if err := siteacc.sessions.SaveSession(session); err != nil {
+				siteacc.log.Err(err).Msg("an error occurred while saving the session")
+			}
 		}
 	})
 }
 
+func (siteacc *SiteAccounts) resolveSessionUser(email string) (*data.Account, *data.Operator, error) {
+	account, err := siteacc.accountsManager.FindAccountEx(manager.FindByEmail, email, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	op, err := siteacc.operatorsManager.GetOperator(account.Operator, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return account, op, nil
+}
+
+func (siteacc *SiteAccounts) verifyRequest(ep endpoint, r *http.Request) error {
+	if siteacc.requestVerifier == nil || !signedEndpoints[ep.Path] {
+		return nil
+	}
+	return siteacc.requestVerifier.Verify(r)
+}
+
 // ShowAdministrationPanel writes the administration panel HTTP output directly to the response writer.
 func (siteacc *SiteAccounts) ShowAdministrationPanel(w http.ResponseWriter, r *http.Request, session *html.Session) error {
 	// The admin panel only shows the stored accounts and offers actions through links, so let it use cloned data
 	accounts := siteacc.accountsManager.CloneAccounts(true)
-	return siteacc.adminPanel.Execute(w, r, session, &accounts)
+	operators := siteacc.operatorsManager.CloneOperators(true)
+	var anns []*announcements.Announcement
+	if siteacc.announcementsManager.Enabled() {
+		list, err := siteacc.announcementsManager.List()
+		if err != nil {
+			return errors.Wrap(err, "unable to list the announcements")
+		}
+		anns = append(make([]*announcements.Announcement, 0, len(list)), list...)
+	}
+	return siteacc.adminPanel.Execute(w, r, session, &accounts, &operators, anns)
 }
 
 // ShowAccountPanel writes the account panel HTTP output directly to the response writer.
 func (siteacc *SiteAccounts) ShowAccountPanel(w http.ResponseWriter, r *http.Request, session *html.Session) error {
+	// Management rights over sites of other operators can be granted or revoked at any time, so always look them up anew
+	if user := session.LoggedInUser(); user != nil {
+		user.ManagedSites = siteacc.operatorsManager.CloneManagedSites(user.Account.Email, false)
+	}
 	return siteacc.accountPanel.Execute(w, r, session)
 }
 
@@ -177,11 +364,58 @@ func (siteacc *SiteAccounts) UsersManager() *manager.UsersManager {
 	return siteacc.usersManager
 }
 
+// OIDCManager returns the central OpenID Connect manager instance.
+func (siteacc *SiteAccounts) OIDCManager() *manager.OIDCManager {
+	return siteacc.oidcManager
+}
+
+// AnnouncementsManager returns the central announcements manager instance.
+func (siteacc *SiteAccounts) AnnouncementsManager() *manager.AnnouncementsManager {
+	return siteacc.announcementsManager
+}
+
 // AlertsDispatcher returns the central alerts dispatcher instance.
 func (siteacc *SiteAccounts) AlertsDispatcher() *alerting.Dispatcher {
 	return siteacc.alertsDispatcher
 }
 
+// ContactsImporter returns the central contacts importer instance; this is nil if no contacts source has been configured.
+func (siteacc *SiteAccounts) ContactsImporter() *contacts.Importer {
+	return siteacc.contactsImporter
+}
+
+// HealthProber returns the central site health prober instance; this is nil if no health checks have been configured.
+func (siteacc *SiteAccounts) HealthProber() *health.Prober {
+	return siteacc.healthProber
+}
+
+// Auditor returns the central auditor instance.
+func (siteacc *SiteAccounts) Auditor() *audit.Auditor {
+	return siteacc.auditor
+}
+
+// Close stops all background tasks of the service and closes the audit sinks.
+func (siteacc *SiteAccounts) Close() {
+	if siteacc.contactsImporter != nil {
+		siteacc.contactsImporter.Stop()
+	}
+	if siteacc.healthProber != nil {
+		siteacc.healthProber.Stop()
+	}
+	if siteacc.mentixCache != nil {
+		siteacc.mentixCache.Stop()
+	}
+	if err := siteacc.sessions.Close(); err != nil {
+		siteacc.log.Err(err).Msg("an error occurred while closing the session store")
+	}
+	siteacc.auditor.Close()
+}
+
+// writeError writes an error page for browsers or problem details for all other clients.
+func (siteacc *SiteAccounts) writeError(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	siteacc.problems.Write(w, r, status, detail)
+}
+
 // GetPublicEndpoints returns a list of all public endpoints.
 func (siteacc *SiteAccounts) GetPublicEndpoints() []string {
 	// TODO: Only for local testing!
@@ -193,12 +427,17 @@ func (siteacc *SiteAccounts) GetPublicEndpoints() []string {
 			endpoints = append(endpoints, ep.Path)
 		}
 	}
+	// The REST API authenticates its requests itself
+	endpoints = append(endpoints, apiPrefix+"/")
 	return endpoints
 }
 
 func (siteacc *SiteAccounts) createStorage(driver string) (data.Storage, error) {
-	if driver == "file" {
+	switch driver {
+	case "file":
 		return data.NewFileStorage(siteacc.conf, siteacc.log)
+	case "sql":
+		return data.NewSQLStorage(siteacc.conf, siteacc.log)
 	}
 
 	return nil, errors.Errorf("unknown storage driver %v", driver)
@@ -0,0 +1,89 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package siteacc
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/cs3org/reva/pkg/apispec"
+	"github.com/go-chi/chi/v5"
+)
+
+// Operations describes the endpoints of the site accounts service, both the
+// ones used by its web interface and the ones of the REST API.
+func Operations() []apispec.Operation {
+	var ops []apispec.Operation
+	for _, ep := range getEndpoints() {
+		methods := []string{http.MethodGet}
+		if ep.MethodCallbacks != nil {
+			methods = methods[:0]
+			for method := range ep.MethodCallbacks {
+				methods = append(methods, method)
+			}
+			sort.Strings(methods)
+		}
+
+		for _, method := range methods {
+			op := apispec.Operation{Method: method, Path: ep.Path, Public: ep.IsPublic}
+			if ep.MethodCallbacks != nil {
+				// the method endpoints answer with a standardized JSON response
+				op.Responses = []apispec.Response{
+					{Status: http.StatusOK, ContentType: "application/json"},
+					{Status: http.StatusBadRequest, ContentType: "application/json"},
+				}
+				if method == http.MethodPost {
+					op.RequestType = "application/json"
+				}
+			}
+			if _, ok := apiKeyEndpoints[ep.Path]; ok {
+				op.Security = []string{apispec.SchemeAPIKey}
+			}
+			ops = append(ops, op)
+		}
+	}
+
+	// the handlers of the REST API are only created, never called, to list its routes
+	_ = chi.Walk((&SiteAccounts{}).newAPIHandler().(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		op := apispec.Operation{
+			Method: method,
+			Path:   route,
+			Responses: []apispec.Response{
+				{Status: http.StatusOK, ContentType: "application/json"},
+				{Status: http.StatusUnauthorized, ContentType: "application/json"},
+				{Status: http.StatusForbidden, ContentType: "application/json"},
+			},
+			Public:   true,
+			Security: []string{apispec.SchemeBearer},
+		}
+		if method == http.MethodPost || method == http.MethodPut {
+			op.RequestType = "application/json"
+		}
+		if method == http.MethodPost {
+			op.Responses = append(op.Responses, apispec.Response{Status: http.StatusCreated, ContentType: "application/json"})
+		}
+		if method == http.MethodDelete {
+			op.Responses[0].Status = http.StatusNoContent
+			op.Responses[0].ContentType = ""
+		}
+		ops = append(ops, op)
+		return nil
+	})
+	return ops
+}
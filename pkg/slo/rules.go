@@ -0,0 +1,145 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package slo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// burnRateMetric is the burn rate series as exported by the prometheus service.
+const burnRateMetric = "revad_slo_burn_rate"
+
+// alert is one of the multiwindow, multi-burn-rate alerts: it fires when the
+// given fraction of the error budget is consumed over the long window, and the
+// short window shows that it is still being consumed.
+type alert struct {
+	severity string
+	budget   float64
+	long     string
+	short    string
+}
+
+var alerts = []alert{
+	{severity: "page", budget: 0.02, long: "1h", short: "5m"},
+	{severity: "page", budget: 0.05, long: "6h", short: "30m"},
+	{severity: "ticket", budget: 0.10, long: "1d", short: "2h"},
+	{severity: "ticket", budget: 0.10, long: "3d", short: "6h"},
+}
+
+// RuleFile is a Prometheus rule file.
+type RuleFile struct {
+	Groups []RuleGroup `yaml:"groups"`
+}
+
+// RuleGroup is a group of Prometheus rules.
+type RuleGroup struct {
+	Name  string `yaml:"name"`
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule is a Prometheus alerting rule.
+type Rule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// Rules returns the alerting rules watching the burn rates of the objectives,
+// with a group per service.
+func Rules(c *Config) (*RuleFile, error) {
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+
+	f := &RuleFile{Groups: []RuleGroup{}}
+	for _, o := range c.Objectives {
+		g := RuleGroup{Name: "reva-slo-" + o.Service}
+		indicators := o.indicators()
+		slis := make([]string, 0, len(indicators))
+		for sli := range indicators {
+			slis = append(slis, sli)
+		}
+		sort.Strings(slis)
+
+		for _, sli := range slis {
+			for _, a := range alerts {
+				threshold := burnRateThreshold(a, o.Window)
+				g.Rules = append(g.Rules, Rule{
+					Alert: "RevaErrorBudgetBurn",
+					Expr: fmt.Sprintf("%s > %s\nand\n%s > %s",
+						burnRateExpr(o.Service, sli, a.long), formatFloat(threshold),
+						burnRateExpr(o.Service, sli, a.short), formatFloat(threshold)),
+					Labels: map[string]string{
+						"service":  o.Service,
+						"sli":      sli,
+						"severity": a.severity,
+						"window":   a.long,
+					},
+					Annotations: map[string]string{
+						"summary": fmt.Sprintf("The %s error budget of %s is burning too fast", sli, o.Service),
+						"description": fmt.Sprintf("%s%% of the %d-day %s error budget of %s were consumed in the last %s.",
+							formatFloat(a.budget*100), o.Window, sli, o.Service, a.long),
+					},
+				})
+			}
+		}
+		f.Groups = append(f.Groups, g)
+	}
+	return f, nil
+}
+
+// GenerateRules returns the Prometheus rule file watching the burn rates of the objectives.
+func GenerateRules(c *Config) ([]byte, error) {
+	f, err := Rules(c)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(f)
+}
+
+// burnRateThreshold returns the burn rate at which the budget of the alert is
+// consumed over its long window, given the period of the objective in days.
+func burnRateThreshold(a alert, days int) float64 {
+	period := time.Duration(days) * 24 * time.Hour
+	t := a.budget * float64(period) / float64(windowDuration(a.long))
+	return math.Round(t*1000) / 1000
+}
+
+func windowDuration(name string) time.Duration {
+	for _, w := range Windows {
+		if w.Name == name {
+			return w.Duration
+		}
+	}
+	panic("slo: unknown window " + name)
+}
+
+func burnRateExpr(service, sli, window string) string {
+	return fmt.Sprintf("max by (service, sli) (%s{service=%q,sli=%q,window=%q})", burnRateMetric, service, sli, window)
+}
+
+func formatFloat(f float64) string {
+	return fmt.Sprintf("%g", f)
+}
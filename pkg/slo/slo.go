@@ -0,0 +1,225 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package slo tracks the service level objectives of the services. The
+// availability and latency indicators are computed from the requests served,
+// the rates at which the error budgets are consumed are exposed as metrics and
+// the Prometheus alerting rules watching them can be generated from the same
+// objectives.
+package slo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// The service level indicators.
+const (
+	// SLIAvailability is the ratio of the requests which do not fail.
+	SLIAvailability = "availability"
+	// SLILatency is the ratio of the successful requests served below the latency threshold.
+	SLILatency = "latency"
+)
+
+// Window is a window the burn rates are computed over.
+type Window struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Windows are the windows the burn rates are computed over, the longest one
+// determines how long the requests are remembered.
+var Windows = []Window{
+	{Name: "5m", Duration: 5 * time.Minute},
+	{Name: "30m", Duration: 30 * time.Minute},
+	{Name: "1h", Duration: time.Hour},
+	{Name: "2h", Duration: 2 * time.Hour},
+	{Name: "6h", Duration: 6 * time.Hour},
+	{Name: "1d", Duration: 24 * time.Hour},
+	{Name: "3d", Duration: 72 * time.Hour},
+}
+
+var (
+	sloEvents   = stats.Int64("slo_events", "The number of requests counted in the service level indicators", stats.UnitDimensionless)
+	sloBurnRate = stats.Float64("slo_burn_rate", "The rate at which the error budget of a service level objective is consumed", stats.UnitDimensionless)
+	serviceKey  = tag.MustNewKey("service")
+	sliKey      = tag.MustNewKey("sli")
+	resultKey   = tag.MustNewKey("result")
+	windowKey   = tag.MustNewKey("window")
+
+	registerViews sync.Once
+	trackers      = map[string]*tracker{}
+)
+
+// Config holds the service level objectives.
+type Config struct {
+	// Objectives are the objectives of the services, at most one per service.
+	Objectives []*Objective `mapstructure:"objectives"`
+	// Interval is the number of seconds between two updates of the burn rates.
+	Interval int `mapstructure:"interval"`
+}
+
+// Objective holds the service level objectives of a service.
+type Objective struct {
+	// Service is the name of the service, e.g. gateway, ocdav or dataprovider.
+	Service string `mapstructure:"service"`
+	// Availability is the percentage of the requests which must not fail, e.g. 99.9.
+	Availability float64 `mapstructure:"availability"`
+	// Latency is the number of milliseconds above which a request is slow.
+	Latency int `mapstructure:"latency"`
+	// LatencyTarget is the percentage of the successful requests which must not be slow.
+	LatencyTarget float64 `mapstructure:"latency_target"`
+	// Window is the number of days the objectives are defined over.
+	Window int `mapstructure:"window"`
+}
+
+// Enabled returns whether objectives are configured.
+func (c *Config) Enabled() bool {
+	return len(c.Objectives) > 0
+}
+
+func (c *Config) init() error {
+	if c.Interval <= 0 {
+		c.Interval = 30
+	}
+
+	services := map[string]bool{}
+	for _, o := range c.Objectives {
+		if o.Service == "" {
+			return errors.New("slo: objective without service")
+		}
+		if services[o.Service] {
+			return fmt.Errorf("slo: duplicate objectives for service %s", o.Service)
+		}
+		services[o.Service] = true
+
+		if o.Availability == 0 && o.LatencyTarget == 0 {
+			return fmt.Errorf("slo: no availability or latency target for service %s", o.Service)
+		}
+		if o.Availability < 0 || o.Availability >= 100 {
+			return fmt.Errorf("slo: invalid availability target %v for service %s", o.Availability, o.Service)
+		}
+		if o.LatencyTarget < 0 || o.LatencyTarget >= 100 {
+			return fmt.Errorf("slo: invalid latency target %v for service %s", o.LatencyTarget, o.Service)
+		}
+		if o.LatencyTarget > 0 && o.Latency <= 0 {
+			return fmt.Errorf("slo: no latency threshold for service %s", o.Service)
+		}
+		if o.Window <= 0 {
+			o.Window = 30
+		}
+	}
+	return nil
+}
+
+// indicators returns the indicators the objective has a target for, and the
+// targets as ratios.
+func (o *Objective) indicators() map[string]float64 {
+	sli := map[string]float64{}
+	if o.Availability > 0 {
+		sli[SLIAvailability] = o.Availability / 100
+	}
+	if o.LatencyTarget > 0 {
+		sli[SLILatency] = o.LatencyTarget / 100
+	}
+	return sli
+}
+
+// Configure starts tracking the configured objectives. It has to be called
+// before the requests are served.
+func Configure(c *Config) error {
+	if err := c.init(); err != nil {
+		return err
+	}
+
+	registerViews.Do(func() {
+		_ = view.Register(&view.View{
+			Name:        sloEvents.Name(),
+			Description: sloEvents.Description(),
+			Measure:     sloEvents,
+			TagKeys:     []tag.Key{serviceKey, sliKey, resultKey},
+			Aggregation: view.Count(),
+		}, &view.View{
+			Name:        sloBurnRate.Name(),
+			Description: sloBurnRate.Description(),
+			Measure:     sloBurnRate,
+			TagKeys:     []tag.Key{serviceKey, sliKey, windowKey},
+			Aggregation: view.LastValue(),
+		})
+	})
+
+	for _, o := range c.Objectives {
+		trackers[o.Service] = newTracker(o)
+	}
+	go update(time.Duration(c.Interval) * time.Second)
+	return nil
+}
+
+// Enabled returns whether objectives are tracked.
+func Enabled() bool {
+	return len(trackers) > 0
+}
+
+// Tracked returns whether the objectives of the given service are tracked.
+func Tracked(service string) bool {
+	_, ok := trackers[service]
+	return ok
+}
+
+// Record counts a request served by the given service in its indicators.
+// Requests of services without objectives are ignored.
+func Record(service string, failed bool, duration time.Duration) {
+	t, ok := trackers[service]
+	if !ok {
+		return
+	}
+	for sli, bad := range t.record(time.Now(), failed, duration) {
+		result := "good"
+		if bad {
+			result = "bad"
+		}
+		if ctx, err := tag.New(context.Background(), tag.Insert(serviceKey, service), tag.Insert(sliKey, sli), tag.Insert(resultKey, result)); err == nil {
+			stats.Record(ctx, sloEvents.M(1))
+		}
+	}
+}
+
+// update periodically records the burn rates of all the tracked objectives.
+func update(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		for service, t := range trackers {
+			for sli, rates := range t.burnRates(now) {
+				for i, w := range Windows {
+					if ctx, err := tag.New(context.Background(), tag.Insert(serviceKey, service), tag.Insert(sliKey, sli), tag.Insert(windowKey, w.Name)); err == nil {
+						stats.Record(ctx, sloBurnRate.M(rates[i]))
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package slo
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBurnRates(t *testing.T) {
+	tr := newTracker(&Objective{Service: "gateway", Availability: 99, Latency: 100, LatencyTarget: 90})
+	now := time.Unix(1700000000, 0)
+
+	// an hour ago: 100 requests, 10 of them failing
+	for n := 0; n < 100; n++ {
+		tr.record(now.Add(-time.Hour+time.Second), n < 10, time.Millisecond)
+	}
+	// now: 100 requests, 2 failing and 20 slow ones
+	for n := 0; n < 100; n++ {
+		d := time.Millisecond
+		if n >= 80 {
+			d = time.Second
+		}
+		tr.record(now, n < 2, d)
+	}
+
+	rates := tr.burnRates(now)
+	expect := func(sli, window string, rate float64) {
+		for i, w := range Windows {
+			if w.Name == window {
+				if math.Abs(rates[sli][i]-rate) > 1e-9 {
+					t.Errorf("expected %s burn rate over %s to be %v, got %v", sli, window, rate, rates[sli][i])
+				}
+				return
+			}
+		}
+		t.Fatalf("unknown window %s", window)
+	}
+	expect(SLIAvailability, "5m", 2)
+	expect(SLIAvailability, "1h", 2)
+	expect(SLIAvailability, "2h", 6)
+	// the failed requests are not counted, the 20 slow ones out of 98 are
+	expect(SLILatency, "5m", 20.0/98/0.1)
+	expect(SLILatency, "1d", 20.0/188/0.1)
+
+	// the buckets of past periods are not counted again
+	later := now.Add(72 * time.Hour)
+	tr.record(later, false, time.Millisecond)
+	if r := tr.burnRates(later)[SLIAvailability]; r[len(r)-1] != 0 {
+		t.Errorf("expected the old requests to be forgotten, got a burn rate of %v", r[len(r)-1])
+	}
+}
+
+func TestRules(t *testing.T) {
+	c := &Config{Objectives: []*Objective{
+		{Service: "ocdav", Availability: 99.9},
+		{Service: "dataprovider", Latency: 500, LatencyTarget: 99, Window: 7},
+	}}
+	f, err := Rules(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Groups) != 2 || len(f.Groups[0].Rules) != len(alerts) || len(f.Groups[1].Rules) != len(alerts) {
+		t.Fatalf("unexpected groups %+v", f.Groups)
+	}
+
+	r := f.Groups[0].Rules[0]
+	expr := `max by (service, sli) (revad_slo_burn_rate{service="ocdav",sli="availability",window="1h"}) > 14.4
+and
+max by (service, sli) (revad_slo_burn_rate{service="ocdav",sli="availability",window="5m"}) > 14.4`
+	if r.Expr != expr {
+		t.Errorf("unexpected expression %s", r.Expr)
+	}
+	if r.Labels["severity"] != "page" || r.Labels["sli"] != SLIAvailability {
+		t.Errorf("unexpected labels %v", r.Labels)
+	}
+
+	// the thresholds scale with the period of the objective
+	r = f.Groups[1].Rules[3]
+	if r.Labels["sli"] != SLILatency || !strings.HasSuffix(r.Expr, " > 0.233") {
+		t.Errorf("unexpected rule %+v", r)
+	}
+}
+
+func TestInvalidObjectives(t *testing.T) {
+	for _, o := range []*Objective{
+		{Availability: 99},
+		{Service: "gateway"},
+		{Service: "gateway", Availability: 100},
+		{Service: "gateway", LatencyTarget: 99},
+	} {
+		if _, err := Rules(&Config{Objectives: []*Objective{o}}); err == nil {
+			t.Errorf("expected objective %+v to be rejected", o)
+		}
+	}
+	dup := []*Objective{{Service: "gateway", Availability: 99}, {Service: "gateway", Availability: 99.9}}
+	if _, err := Rules(&Config{Objectives: dup}); err == nil {
+		t.Error("expected duplicate objectives to be rejected")
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package slo
+
+import (
+	"sync"
+	"time"
+)
+
+// bucket counts the requests of one minute.
+type bucket struct {
+	minute int64
+	total  int64
+	bad    int64
+}
+
+// indicator remembers the requests per minute over the longest window.
+type indicator struct {
+	target  float64
+	buckets []bucket
+}
+
+func newIndicator(target float64) *indicator {
+	longest := Windows[len(Windows)-1].Duration
+	return &indicator{target: target, buckets: make([]bucket, int(longest/time.Minute))}
+}
+
+func (i *indicator) add(now time.Time, bad bool) {
+	m := now.Unix() / 60
+	b := &i.buckets[m%int64(len(i.buckets))]
+	if b.minute != m {
+		*b = bucket{minute: m}
+	}
+	b.total++
+	if bad {
+		b.bad++
+	}
+}
+
+// burnRate returns the ratio of the bad requests over the window, including
+// the current minute, to the one allowed by the target. A burn rate of 1
+// consumes exactly the error budget over the period of the objective.
+func (i *indicator) burnRate(now time.Time, window time.Duration) float64 {
+	m := now.Unix() / 60
+	var total, bad int64
+	for n := int64(0); n < int64(window/time.Minute) && n < int64(len(i.buckets)); n++ {
+		if b := i.buckets[(m-n)%int64(len(i.buckets))]; b.minute == m-n {
+			total += b.total
+			bad += b.bad
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(bad) / float64(total) / (1 - i.target)
+}
+
+// tracker tracks the indicators of the objectives of a service.
+type tracker struct {
+	latency    time.Duration
+	mu         sync.Mutex
+	indicators map[string]*indicator
+}
+
+func newTracker(o *Objective) *tracker {
+	t := &tracker{latency: time.Duration(o.Latency) * time.Millisecond, indicators: map[string]*indicator{}}
+	for sli, target := range o.indicators() {
+		t.indicators[sli] = newIndicator(target)
+	}
+	return t
+}
+
+// record counts a request in the indicators and returns whether it was
+// bad for each of them. Failed requests are not counted for the latency.
+func (t *tracker) record(now time.Time, failed bool, duration time.Duration) map[string]bool {
+	res := make(map[string]bool, len(t.indicators))
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if i, ok := t.indicators[SLIAvailability]; ok {
+		i.add(now, failed)
+		res[SLIAvailability] = failed
+	}
+	if i, ok := t.indicators[SLILatency]; ok && !failed {
+		slow := duration > t.latency
+		i.add(now, slow)
+		res[SLILatency] = slow
+	}
+	return res
+}
+
+// burnRates returns the burn rates of the indicators over the Windows.
+func (t *tracker) burnRates(now time.Time) map[string][]float64 {
+	res := make(map[string][]float64, len(t.indicators))
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for sli, i := range t.indicators {
+		rates := make([]float64, len(Windows))
+		for n, w := range Windows {
+			rates[n] = i.burnRate(now, w.Duration)
+		}
+		res[sli] = rates
+	}
+	return res
+}
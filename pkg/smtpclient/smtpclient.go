@@ -22,6 +22,7 @@ import (
 	"bytes"
 	"encoding/base64"
 	"fmt"
+	"mime"
 	"net/smtp"
 	"os"
 	"strings"
@@ -64,23 +65,39 @@ func NewSMTPCredentials(c *SMTPCredentials) *SMTPCredentials {
 
 // SendMail allows sending mails using a set of client credentials.
 func (creds *SMTPCredentials) SendMail(recipient, subject, body string) error {
+	return creds.SendMultipartMail(recipient, subject, body, "")
+}
+
+// SendMultipartMail sends a mail consisting of a plaintext and an HTML body; if the HTML body is empty, a plaintext mail is sent.
+func (creds *SMTPCredentials) SendMultipartMail(recipient, subject, textBody, htmlBody string) error {
 
 	headers := map[string]string{
-		"From":                      creds.SenderMail,
-		"To":                        recipient,
-		"Subject":                   subject,
-		"Date":                      time.Now().Format(time.RFC1123Z),
-		"Message-ID":                uuid.New().String(),
-		"MIME-Version":              "1.0",
-		"Content-Type":              "text/plain; charset=\"utf-8\"",
-		"Content-Transfer-Encoding": "base64",
+		"From":         creds.SenderMail,
+		"To":           recipient,
+		"Subject":      mime.QEncoding.Encode("utf-8", subject),
+		"Date":         time.Now().Format(time.RFC1123Z),
+		"Message-ID":   uuid.New().String(),
+		"MIME-Version": "1.0",
+	}
+
+	content := ""
+	if htmlBody == "" {
+		headers["Content-Type"] = "text/plain; charset=\"utf-8\""
+		headers["Content-Transfer-Encoding"] = "base64"
+		content = base64.StdEncoding.EncodeToString([]byte(textBody))
+	} else {
+		boundary := uuid.New().String()
+		headers["Content-Type"] = fmt.Sprintf("multipart/alternative; boundary=\"%s\"", boundary)
+		content += encodeMessagePart(boundary, "text/plain", textBody)
+		content += encodeMessagePart(boundary, "text/html", htmlBody)
+		content += fmt.Sprintf("--%s--\r\n", boundary)
 	}
 
 	message := ""
 	for k, v := range headers {
 		message += fmt.Sprintf("%s: %s\r\n", k, v)
 	}
-	message += "\r\n" + base64.StdEncoding.EncodeToString([]byte(body))
+	message += "\r\n" + content
 
 	if creds.DisableAuth {
 		return creds.sendMailSMTP(recipient, subject, message)
@@ -88,6 +105,20 @@ func (creds *SMTPCredentials) SendMail(recipient, subject, body string) error {
 	return creds.sendMailAuthSMTP(recipient, subject, message)
 }
 
+func encodeMessagePart(boundary, contentType, body string) string {
+	part := fmt.Sprintf("--%s\r\n", boundary)
+	part += fmt.Sprintf("Content-Type: %s; charset=\"utf-8\"\r\n", contentType)
+	part += "Content-Transfer-Encoding: base64\r\n\r\n"
+
+	// Lines of base64 encoded parts must not exceed 76 characters
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		part += encoded[:76] + "\r\n"
+		encoded = encoded[76:]
+	}
+	return part + encoded + "\r\n"
+}
+
 func (creds *SMTPCredentials) sendMailAuthSMTP(recipient, subject, message string) error {
 
 	auth := smtp.PlainAuth("", creds.SenderLogin, creds.SenderPassword, creds.SMTPServer)
@@ -0,0 +1,57 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package smtpclient
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestEncodeMessagePart(t *testing.T) {
+	body := strings.Repeat("Grüezi mitenand! ", 20)
+	part := encodeMessagePart("boundary", "text/html", body)
+
+	lines := strings.Split(strings.TrimSuffix(part, "\r\n"), "\r\n")
+	if lines[0] != "--boundary" || lines[1] != "Content-Type: text/html; charset=\"utf-8\"" || lines[2] != "Content-Transfer-Encoding: base64" || lines[3] != "" {
+		t.Fatalf("unexpected part headers %q", lines[:4])
+	}
+
+	encoded := ""
+	for _, line := range lines[4:] {
+		if len(line) > 76 {
+			t.Errorf("expected lines of at most 76 characters, got %d", len(line))
+		}
+		encoded += line
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("invalid base64 data: %v", err)
+	}
+	if string(decoded) != body {
+		t.Errorf("expected %q, got %q", body, decoded)
+	}
+}
+
+func TestNewSMTPCredentials(t *testing.T) {
+	creds := NewSMTPCredentials(&SMTPCredentials{SenderMail: "noreply@example.org", DisableAuth: true})
+	if creds.SMTPPort != 587 || creds.LocalName != "example.org" || creds.SenderLogin != "noreply@example.org" {
+		t.Errorf("unexpected defaults %+v", creds)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package spaceprovisioner
+
+import (
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Op is the kind of change to apply to the grants of a space.
+type Op string
+
+const (
+	// OpAdd adds the grant of a new member.
+	OpAdd Op = "add"
+	// OpUpdate changes the permissions of an existing member.
+	OpUpdate Op = "update"
+	// OpRemove removes the grant of a former member.
+	OpRemove Op = "remove"
+)
+
+// Change is a grant to add, update or remove on a space.
+type Change struct {
+	Op    Op
+	Grant *provider.Grant
+}
+
+// Plan computes the changes bringing the user grants of a space in line with
+// the members and managers of its group. Managers get the manager permissions
+// even if they are not members of the group themselves. Group grants and the
+// grant of the given user, who runs the provisioning, are left untouched.
+func Plan(members, managers []*userpb.UserId, memberPerms, managerPerms *provider.ResourcePermissions, grants []*provider.Grant, keep *userpb.UserId) []Change {
+	desired := make(map[string]*provider.Grant, len(members)+len(managers))
+	for _, m := range members {
+		desired[userKey(m)] = userGrant(m, memberPerms)
+	}
+	for _, m := range managers {
+		desired[userKey(m)] = userGrant(m, managerPerms)
+	}
+	if keep != nil {
+		delete(desired, userKey(keep))
+	}
+
+	changes := []Change{}
+	current := make(map[string]struct{}, len(grants))
+	for _, g := range grants {
+		u := g.GetGrantee().GetUserId()
+		if u == nil || (keep != nil && userKey(u) == userKey(keep)) {
+			continue
+		}
+		current[userKey(u)] = struct{}{}
+		d, ok := desired[userKey(u)]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Op: OpRemove, Grant: g})
+		case !proto.Equal(d.Permissions, g.Permissions):
+			changes = append(changes, Change{Op: OpUpdate, Grant: d})
+		}
+	}
+	// add in a stable order, members first
+	for _, list := range [][]*userpb.UserId{members, managers} {
+		for _, m := range list {
+			k := userKey(m)
+			if _, ok := current[k]; ok {
+				continue
+			}
+			if d, ok := desired[k]; ok {
+				changes = append(changes, Change{Op: OpAdd, Grant: d})
+				current[k] = struct{}{}
+			}
+		}
+	}
+	return changes
+}
+
+func userGrant(u *userpb.UserId, perms *provider.ResourcePermissions) *provider.Grant {
+	return &provider.Grant{
+		Grantee: &provider.Grantee{
+			Type: provider.GranteeType_GRANTEE_TYPE_USER,
+			Id:   &provider.Grantee_UserId{UserId: u},
+		},
+		Permissions: perms,
+	}
+}
+
+func userKey(u *userpb.UserId) string {
+	return u.GetIdp() + ":" + u.GetOpaqueId()
+}
@@ -0,0 +1,63 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package spaceprovisioner
+
+import (
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
+)
+
+func TestPlan(t *testing.T) {
+	user := func(id string) *userpb.UserId { return &userpb.UserId{Idp: "https://idp", OpaqueId: id} }
+	editor := conversions.NewEditorRole().CS3ResourcePermissions()
+	manager := conversions.NewManagerRole().CS3ResourcePermissions()
+
+	service, einstein, marie, richard, moss := user("service"), user("einstein"), user("marie"), user("richard"), user("moss")
+	grants := []*provider.Grant{
+		userGrant(service, manager),
+		userGrant(einstein, editor),
+		userGrant(marie, editor),
+		userGrant(moss, editor),
+		{Grantee: &provider.Grantee{Type: provider.GranteeType_GRANTEE_TYPE_GROUP}, Permissions: editor},
+	}
+
+	// richard joined, moss left and marie became a manager
+	changes := Plan([]*userpb.UserId{einstein, marie, richard}, []*userpb.UserId{marie}, editor, manager, grants, service)
+
+	expected := map[string]Op{"marie": OpUpdate, "moss": OpRemove, "richard": OpAdd}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %v", len(expected), changes)
+	}
+	for _, c := range changes {
+		id := c.Grant.Grantee.GetUserId().GetOpaqueId()
+		if expected[id] != c.Op {
+			t.Errorf("unexpected change %s for %s", c.Op, id)
+		}
+		if id == "marie" && c.Grant.Permissions != manager {
+			t.Error("managers must get the manager permissions")
+		}
+	}
+
+	if changes := Plan([]*userpb.UserId{einstein, marie, moss}, nil, editor, manager, grants, service); len(changes) != 0 {
+		t.Errorf("expected no changes for a space in sync, got %v", changes)
+	}
+}
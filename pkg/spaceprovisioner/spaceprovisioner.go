@@ -0,0 +1,422 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package spaceprovisioner creates a project space for each group matching a
+// pattern and keeps the members of the spaces in sync with the groups.
+package spaceprovisioner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	registry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
+	"github.com/cs3org/reva/pkg/affiliation"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/pkg/errors"
+)
+
+// Config configures which groups get a project space and how their members are granted access.
+type Config struct {
+	GroupFilter    string `mapstructure:"group_filter" docs:";The filter used to look up the candidate groups."`
+	GroupPattern   string `mapstructure:"group_pattern" docs:"^project-.+$;The regular expression the names of the groups getting a project space must match."`
+	ManagersSuffix string `mapstructure:"managers_suffix" docs:"-admins;The suffix of the subgroup whose members manage the space of a group, e.g. project-x-admins for project-x."`
+	MemberRole     string `mapstructure:"member_role" docs:"editor;The role granted to the members of a group on its space."`
+	Quota          uint64 `mapstructure:"quota" docs:"0;The quota in bytes of new spaces. 0 uses the default of the storage."`
+	StateFile      string `mapstructure:"state_file" docs:"/var/tmp/reva/spaceprovisioner.json;The file recording the spaces provisioned for the groups."`
+
+	MemberAffiliations []string `mapstructure:"member_affiliations" docs:";The affiliations, e.g. staff, members must hold to be granted access. Managers are always granted access. Empty grants all members."`
+}
+
+func (c *Config) init() {
+	if c.GroupPattern == "" {
+		c.GroupPattern = "^project-.+$"
+	}
+	if c.ManagersSuffix == "" {
+		c.ManagersSuffix = "-admins"
+	}
+	if c.MemberRole == "" {
+		c.MemberRole = conversions.RoleEditor
+	}
+	if c.StateFile == "" {
+		c.StateFile = "/var/tmp/reva/spaceprovisioner.json"
+	}
+}
+
+// Space records the project space provisioned for a group.
+type Space struct {
+	Group   *grouppb.GroupId     `json:"group"`
+	SpaceID string               `json:"space_id"`
+	Root    *provider.ResourceId `json:"root"`
+	Name    string               `json:"name"`
+}
+
+// Report sums up a provisioning run.
+type Report struct {
+	Groups        int `json:"groups"`
+	Created       int `json:"created"`
+	Renamed       int `json:"renamed"`
+	GrantsAdded   int `json:"grants_added"`
+	GrantsUpdated int `json:"grants_updated"`
+	GrantsRemoved int `json:"grants_removed"`
+	Errors        int `json:"errors"`
+}
+
+// Provisioner creates and updates the project spaces of the groups.
+type Provisioner struct {
+	conf        *Config
+	pattern     *regexp.Regexp
+	memberPerms *provider.ResourcePermissions
+	gatewaySvc  string
+	registrySvc string
+
+	// runs are serialized, as they share the state file
+	mu sync.Mutex
+}
+
+// New returns a provisioner using the given gateway and storage registry.
+func New(c *Config, gatewaySvc, registrySvc string) (*Provisioner, error) {
+	c.init()
+	pattern, err := regexp.Compile(c.GroupPattern)
+	if err != nil {
+		return nil, errors.Wrap(err, "spaceprovisioner: invalid group pattern")
+	}
+	role := conversions.RoleFromName(c.MemberRole)
+	if role.Name == conversions.RoleUnknown {
+		return nil, fmt.Errorf("spaceprovisioner: unknown member role %s", c.MemberRole)
+	}
+	return &Provisioner{
+		conf:        c,
+		pattern:     pattern,
+		memberPerms: role.CS3ResourcePermissions(),
+		gatewaySvc:  gatewaySvc,
+		registrySvc: registrySvc,
+	}, nil
+}
+
+// Matches tells whether a group gets a project space. The managers subgroups never do.
+func (p *Provisioner) Matches(groupName string) bool {
+	return p.pattern.MatchString(groupName) && !strings.HasSuffix(groupName, p.conf.ManagersSuffix)
+}
+
+// Spaces returns the spaces provisioned so far.
+func (p *Provisioner) Spaces() ([]*Space, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Space, 0, len(state))
+	for _, s := range state {
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// Run provisions the spaces of all matching groups. The context must be
+// authenticated as the service user, who creates the spaces and therefore
+// manages all of them. Errors of single groups are logged and counted, so
+// that one broken group does not block the others.
+func (p *Provisioner) Run(ctx context.Context) (*Report, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	log := appctx.GetLogger(ctx)
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(p.gatewaySvc))
+	if err != nil {
+		return nil, err
+	}
+	state, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := client.FindGroups(ctx, &grouppb.FindGroupsRequest{Filter: p.conf.GroupFilter})
+	switch {
+	case err != nil:
+		return nil, err
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, errtypes.InternalError(res.Status.Message)
+	}
+	byName := make(map[string]*grouppb.Group, len(res.Groups))
+	for _, g := range res.Groups {
+		byName[g.GroupName] = g
+	}
+
+	report := &Report{}
+	for _, g := range res.Groups {
+		if !p.Matches(g.GroupName) {
+			continue
+		}
+		report.Groups++
+		if err := p.provision(ctx, client, state, g, byName, report); err != nil {
+			report.Errors++
+			log.Error().Err(err).Str("group", g.GroupName).Msg("spaceprovisioner: error provisioning space")
+		}
+		// save after every group so that created spaces are never forgotten
+		if err := p.save(state); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func (p *Provisioner) provision(ctx context.Context, client gateway.GatewayAPIClient, state map[string]*Space, g *grouppb.Group, byName map[string]*grouppb.Group, report *Report) error {
+	log := appctx.GetLogger(ctx)
+	name := g.DisplayName
+	if name == "" {
+		name = g.GroupName
+	}
+
+	space, ok := state[groupKey(g.Id)]
+	if !ok {
+		s, err := p.createSpace(ctx, client, g, name)
+		if err != nil {
+			return err
+		}
+		space = s
+		state[groupKey(g.Id)] = space
+		report.Created++
+		log.Info().Str("group", g.GroupName).Str("space", space.SpaceID).Msg("spaceprovisioner: space created")
+	} else if space.Name != name {
+		res, err := client.UpdateStorageSpace(ctx, &provider.UpdateStorageSpaceRequest{
+			StorageSpace: &provider.StorageSpace{Id: &provider.StorageSpaceId{OpaqueId: space.SpaceID}, Root: space.Root, Name: name},
+		})
+		switch {
+		case err != nil:
+			return err
+		case res.Status.Code != rpc.Code_CODE_OK:
+			return errtypes.InternalError(res.Status.Message)
+		}
+		space.Name = name
+		report.Renamed++
+	}
+
+	members, err := p.affiliated(ctx, client, g.Members)
+	if err != nil {
+		return err
+	}
+	managers, err := p.managers(ctx, client, g, byName)
+	if err != nil {
+		return err
+	}
+	return p.syncGrants(ctx, space, members, managers, report)
+}
+
+// affiliated returns the given members holding one of the configured affiliations.
+func (p *Provisioner) affiliated(ctx context.Context, client gateway.GatewayAPIClient, members []*userpb.UserId) ([]*userpb.UserId, error) {
+	if len(p.conf.MemberAffiliations) == 0 {
+		return members, nil
+	}
+	var ids []*userpb.UserId
+	for _, m := range members {
+		res, err := client.GetUser(ctx, &userpb.GetUserRequest{UserId: m, SkipFetchingUserGroups: true})
+		switch {
+		case err != nil:
+			return nil, err
+		case res.Status.Code == rpc.Code_CODE_NOT_FOUND:
+			continue
+		case res.Status.Code != rpc.Code_CODE_OK:
+			return nil, errtypes.InternalError(res.Status.Message)
+		}
+		if affiliation.Has(res.User, p.conf.MemberAffiliations) {
+			ids = append(ids, m)
+		}
+	}
+	return ids, nil
+}
+
+// managers returns the members of the managers subgroup of the given group, if any.
+func (p *Provisioner) managers(ctx context.Context, client gateway.GatewayAPIClient, g *grouppb.Group, byName map[string]*grouppb.Group) ([]*userpb.UserId, error) {
+	if m, ok := byName[g.GroupName+p.conf.ManagersSuffix]; ok {
+		return m.Members, nil
+	}
+	// the filter might not cover the subgroups
+	res, err := client.GetGroupByClaim(ctx, &grouppb.GetGroupByClaimRequest{Claim: "group_name", Value: g.GroupName + p.conf.ManagersSuffix})
+	switch {
+	case err != nil:
+		return nil, err
+	case res.Status.Code == rpc.Code_CODE_NOT_FOUND:
+		return nil, nil
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, errtypes.InternalError(res.Status.Message)
+	}
+	return res.Group.Members, nil
+}
+
+func (p *Provisioner) createSpace(ctx context.Context, client gateway.GatewayAPIClient, g *grouppb.Group, name string) (*Space, error) {
+	req := &provider.CreateStorageSpaceRequest{Type: "project", Name: name}
+	if p.conf.Quota > 0 {
+		req.Quota = &provider.Quota{QuotaMaxBytes: p.conf.Quota}
+	}
+	res, err := client.CreateStorageSpace(ctx, req)
+	switch {
+	case err != nil:
+		return nil, err
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, errtypes.InternalError(res.Status.Message)
+	}
+
+	// the storage does not know its mount point and leaves the root out, look it up
+	created := res.StorageSpace.GetId().GetOpaqueId()
+	lres, err := client.ListStorageSpaces(ctx, &provider.ListStorageSpacesRequest{
+		Filters: []*provider.ListStorageSpacesRequest_Filter{
+			{
+				Type: provider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE,
+				Term: &provider.ListStorageSpacesRequest_Filter_SpaceType{SpaceType: "project"},
+			},
+		},
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case lres.Status.Code != rpc.Code_CODE_OK:
+		return nil, errtypes.InternalError(lres.Status.Message)
+	}
+	for _, s := range lres.StorageSpaces {
+		id := s.GetId().GetOpaqueId()
+		if s.Root != nil && (id == created || strings.HasSuffix(id, "!"+created)) {
+			return &Space{Group: g.Id, SpaceID: id, Root: s.Root, Name: name}, nil
+		}
+	}
+	return nil, fmt.Errorf("created space %s not found", created)
+}
+
+func (p *Provisioner) syncGrants(ctx context.Context, space *Space, members, managers []*userpb.UserId, report *Report) error {
+	ref := &provider.Reference{ResourceId: space.Root}
+	c, err := p.storageProvider(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	lres, err := c.ListGrants(ctx, &provider.ListGrantsRequest{Ref: ref})
+	switch {
+	case err != nil:
+		return err
+	case lres.Status.Code != rpc.Code_CODE_OK:
+		return errtypes.InternalError(lres.Status.Message)
+	}
+
+	var self *userpb.UserId
+	if u, ok := ctxpkg.ContextGetUser(ctx); ok {
+		self = u.Id
+	}
+	managerPerms := conversions.NewManagerRole().CS3ResourcePermissions()
+	for _, change := range Plan(members, managers, p.memberPerms, managerPerms, lres.Grants, self) {
+		var st *rpc.Status
+		switch change.Op {
+		case OpAdd:
+			res, err := c.AddGrant(ctx, &provider.AddGrantRequest{Ref: ref, Grant: change.Grant})
+			if err != nil {
+				return err
+			}
+			st = res.Status
+		case OpUpdate:
+			res, err := c.UpdateGrant(ctx, &provider.UpdateGrantRequest{Ref: ref, Grant: change.Grant})
+			if err != nil {
+				return err
+			}
+			st = res.Status
+		case OpRemove:
+			res, err := c.RemoveGrant(ctx, &provider.RemoveGrantRequest{Ref: ref, Grant: change.Grant})
+			if err != nil {
+				return err
+			}
+			st = res.Status
+		}
+		if st.Code != rpc.Code_CODE_OK {
+			return errtypes.InternalError(fmt.Sprintf("error applying %s grant for %s: %s", change.Op, change.Grant.Grantee.GetUserId().GetOpaqueId(), st.Message))
+		}
+		switch change.Op {
+		case OpAdd:
+			report.GrantsAdded++
+		case OpUpdate:
+			report.GrantsUpdated++
+		case OpRemove:
+			report.GrantsRemoved++
+		}
+	}
+	return nil
+}
+
+// storageProvider returns a client of the provider of the space. Grants are not
+// exposed by the gateway and are set on the provider directly.
+func (p *Provisioner) storageProvider(ctx context.Context, ref *provider.Reference) (provider.ProviderAPIClient, error) {
+	c, err := pool.GetStorageRegistryClient(pool.Endpoint(p.registrySvc))
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting storage registry client")
+	}
+	res, err := c.GetStorageProviders(ctx, &registry.GetStorageProvidersRequest{Ref: ref})
+	switch {
+	case err != nil:
+		return nil, err
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, errtypes.InternalError(res.Status.Message)
+	case len(res.Providers) == 0:
+		return nil, errtypes.NotFound("storage provider of space")
+	}
+	return pool.GetStorageProviderServiceClient(pool.Endpoint(res.Providers[0].Address))
+}
+
+func (p *Provisioner) load() (map[string]*Space, error) {
+	state := map[string]*Space{}
+	data, err := ioutil.ReadFile(p.conf.StateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, errors.Wrapf(err, "error reading the file %s", p.conf.StateFile)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, errors.Wrapf(err, "error parsing the file %s", p.conf.StateFile)
+		}
+	}
+	return state, nil
+}
+
+func (p *Provisioner) save(state map[string]*Space) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return errors.Wrap(err, "error encoding the provisioned spaces")
+	}
+	if err := ioutil.WriteFile(p.conf.StateFile, data, 0600); err != nil {
+		return errors.Wrapf(err, "error writing the file %s", p.conf.StateFile)
+	}
+	return nil
+}
+
+func groupKey(g *grouppb.GroupId) string {
+	return g.GetIdp() + ":" + g.GetOpaqueId()
+}
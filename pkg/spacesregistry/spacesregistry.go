@@ -0,0 +1,192 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package spacesregistry composes the view of a user on all their spaces: the
+// personal space, the accepted shares, the project spaces and the accepted OCM
+// shares. The views are cached and dropped when events change them.
+package spacesregistry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/spacesregistry/proto"
+	"github.com/pkg/errors"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// The types of the spaces.
+const (
+	TypePersonal = "personal"
+	TypeShare    = "share"
+	TypeProject  = "project"
+	TypeOCM      = "ocm"
+)
+
+// Events lists the events which change the views of the users.
+var Events = []events.Unmarshaller{
+	events.ShareCreated{},
+	events.ShareRemoved{},
+	events.ReceivedShareUpdated{},
+	events.ReceivedOCMShareUpdated{},
+	events.SpaceCreated{},
+}
+
+// Source lists the spaces of one type of the user in the context.
+type Source func(ctx context.Context) ([]*proto.Space, error)
+
+type view struct {
+	spaces  []*proto.Space
+	expires time.Time
+}
+
+// Registry composes and caches the spaces of the users.
+type Registry struct {
+	types   []string
+	sources map[string]Source
+	ttl     time.Duration
+
+	mu    sync.Mutex
+	views map[string]*view
+	// gen changes whenever views are dropped, so that a view composed
+	// concurrently is not cached with outdated spaces.
+	gen uint64
+}
+
+// New returns a registry composing the spaces of the given types, in this
+// order, from their sources.
+func New(sources map[string]Source, types []string, ttl time.Duration) (*Registry, error) {
+	for _, t := range types {
+		if _, ok := sources[t]; !ok {
+			return nil, errtypes.NotSupported("spaces registry: unknown space type " + t)
+		}
+	}
+	return &Registry{
+		types:   types,
+		sources: sources,
+		ttl:     ttl,
+		views:   map[string]*view{},
+	}, nil
+}
+
+// UserKey identifies the view of a user.
+func UserKey(id *user.UserId) string {
+	return id.GetIdp() + "!" + id.GetOpaqueId()
+}
+
+// List returns the spaces of the given types of a user, or all of them if no
+// type is given. The cached view of the user is composed again if it expired
+// or if refresh is set.
+func (r *Registry) List(ctx context.Context, userKey string, types []string, refresh bool) ([]*proto.Space, error) {
+	r.mu.Lock()
+	v, ok := r.views[userKey]
+	gen := r.gen
+	r.mu.Unlock()
+
+	if refresh || !ok || time.Now().After(v.expires) {
+		spaces, err := r.compose(ctx)
+		if err != nil {
+			return nil, err
+		}
+		v = &view{spaces: spaces, expires: time.Now().Add(r.ttl)}
+
+		r.mu.Lock()
+		if r.gen == gen {
+			r.views[userKey] = v
+		}
+		r.mu.Unlock()
+	}
+
+	wanted := map[string]bool{}
+	for _, t := range types {
+		wanted[t] = true
+	}
+	spaces := make([]*proto.Space, 0, len(v.spaces))
+	for _, s := range v.spaces {
+		if len(wanted) == 0 || wanted[s.Type] {
+			spaces = append(spaces, pb.Clone(s).(*proto.Space))
+		}
+	}
+	return spaces, nil
+}
+
+// compose lists the spaces of all types concurrently.
+func (r *Registry) compose(ctx context.Context) ([]*proto.Space, error) {
+	results := make([][]*proto.Space, len(r.types))
+	errs := make([]error, len(r.types))
+	var wg sync.WaitGroup
+	for i, t := range r.types {
+		wg.Add(1)
+		go func(i int, src Source) {
+			defer wg.Done()
+			results[i], errs[i] = src(ctx)
+		}(i, r.sources[t])
+	}
+	wg.Wait()
+
+	spaces := []*proto.Space{}
+	for i := range r.types {
+		if errs[i] != nil {
+			return nil, errors.Wrapf(errs[i], "spaces registry: error listing the %s spaces", r.types[i])
+		}
+		for _, s := range results[i] {
+			s.Type = r.types[i]
+			spaces = append(spaces, s)
+		}
+	}
+	return spaces, nil
+}
+
+// Invalidate drops the cached view of a user.
+func (r *Registry) Invalidate(userKey string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.views, userKey)
+	r.gen++
+}
+
+// InvalidateAll drops the cached views of all users.
+func (r *Registry) InvalidateAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.views = map[string]*view{}
+	r.gen++
+}
+
+// HandleEvent drops the views changed by an event. The views of all users are
+// dropped if the affected users are not known, e.g. the members of a group.
+func (r *Registry) HandleEvent(ev interface{}) {
+	switch e := ev.(type) {
+	case events.ShareCreated:
+		if e.GranteeUserID != nil {
+			r.Invalidate(UserKey(e.GranteeUserID))
+		} else {
+			r.InvalidateAll()
+		}
+	case events.ReceivedShareUpdated:
+		r.Invalidate(UserKey(e.Executant))
+	case events.ReceivedOCMShareUpdated:
+		r.Invalidate(UserKey(e.Executant))
+	case events.ShareRemoved, events.SpaceCreated:
+		r.InvalidateAll()
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package spacesregistry
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/spacesregistry/proto"
+)
+
+func newTestRegistry(t *testing.T, calls *int32, fail *atomic.Value) *Registry {
+	src := func(name string) Source {
+		return func(ctx context.Context) ([]*proto.Space, error) {
+			atomic.AddInt32(calls, 1)
+			if err, ok := fail.Load().(error); ok && err != nil {
+				return nil, err
+			}
+			return []*proto.Space{{Id: name, Name: name}}, nil
+		}
+	}
+	r, err := New(map[string]Source{
+		TypePersonal: src("home"),
+		TypeShare:    src("share"),
+		TypeProject:  src("project"),
+	}, []string{TypePersonal, TypeShare, TypeProject}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func ids(spaces []*proto.Space) []string {
+	res := []string{}
+	for _, s := range spaces {
+		res = append(res, s.Type+":"+s.Id)
+	}
+	return res
+}
+
+func TestList(t *testing.T) {
+	var calls int32
+	fail := &atomic.Value{}
+	r := newTestRegistry(t, &calls, fail)
+	ctx := context.Background()
+
+	spaces, err := r.List(ctx, "einstein", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ids(spaces), []string{"personal:home", "share:share", "project:project"}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	// the view is cached, and the cached spaces are not modified by the callers
+	spaces[0].Name = "changed"
+	spaces, err = r.List(ctx, "einstein", []string{TypeShare}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected the cached view to be used, got %d calls", calls)
+	}
+	if got := ids(spaces); len(got) != 1 || got[0] != "share:share" {
+		t.Fatalf("expected only the share, got %v", got)
+	}
+	spaces, _ = r.List(ctx, "einstein", []string{TypePersonal}, false)
+	if spaces[0].Name != "home" {
+		t.Fatal("the cached view must not be modified")
+	}
+
+	// a refresh composes the view again
+	if _, err := r.List(ctx, "einstein", nil, true); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 6 {
+		t.Fatalf("expected the view to be composed again, got %d calls", calls)
+	}
+
+	// a failing source fails the whole call
+	fail.Store(errors.New("unavailable"))
+	if _, err := r.List(ctx, "marie", nil, false); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestUnknownType(t *testing.T) {
+	if _, err := New(map[string]Source{}, []string{TypeOCM}, time.Minute); err == nil {
+		t.Fatal("expected an error for a type without source")
+	}
+}
+
+func TestHandleEvent(t *testing.T) {
+	var calls int32
+	fail := &atomic.Value{}
+	r := newTestRegistry(t, &calls, fail)
+	ctx := context.Background()
+	einstein := &user.UserId{Idp: "cernbox.cern.ch", OpaqueId: "einstein"}
+	marie := &user.UserId{Idp: "cernbox.cern.ch", OpaqueId: "marie"}
+
+	list := func(u *user.UserId) {
+		if _, err := r.List(ctx, UserKey(u), nil, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list(einstein)
+	list(marie)
+
+	// only the view of the user who accepted a share is dropped
+	r.HandleEvent(events.ReceivedShareUpdated{Executant: einstein})
+	calls = 0
+	list(einstein)
+	list(marie)
+	if calls != 3 {
+		t.Fatalf("expected only the view of einstein to be composed again, got %d calls", calls)
+	}
+
+	// the grantees of a removed share are not known
+	r.HandleEvent(events.ShareRemoved{Executant: einstein})
+	calls = 0
+	list(einstein)
+	list(marie)
+	if calls != 6 {
+		t.Fatalf("expected all views to be composed again, got %d calls", calls)
+	}
+}
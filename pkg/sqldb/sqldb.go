@@ -0,0 +1,193 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package sqldb opens the database connections of the SQL backed drivers. Besides
+// MySQL it supports SQLite, which gives single node deployments durable storage
+// without running a database server, and PostgreSQL for the drivers writing their
+// queries through Rebind.
+package sqldb
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	// Provides mysql drivers
+	_ "github.com/go-sql-driver/mysql"
+	// Provides postgres drivers
+	_ "github.com/lib/pq"
+	// Provides sqlite drivers
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// The supported database engines.
+const (
+	MySQL    = "mysql"
+	SQLite   = "sqlite"
+	Postgres = "postgres"
+)
+
+// busyTimeout is the number of milliseconds SQLite waits for the lock held by another connection.
+const busyTimeout = 5000
+
+// Config holds the connection settings of a driver.
+type Config struct {
+	// Engine is either mysql, the default, sqlite or postgres.
+	Engine   string
+	Username string
+	Password string
+	Host     string
+	Port     int
+	Name     string
+	// Path is the file holding the SQLite database.
+	Path string
+}
+
+var (
+	// the drivers of a process share the connections to the same database, so
+	// that SQLite writes are serialized by a single connection pool.
+	pool    = map[string]*sql.DB{}
+	engines = map[*sql.DB]string{}
+	mutex   sync.Mutex
+)
+
+// Open returns the connection pool to the configured database, creating it if needed.
+func Open(c Config) (*sql.DB, error) {
+	engine, driver, dsn, err := c.dsn()
+	if err != nil {
+		return nil, err
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	key := driver + ":" + dsn
+	if db, ok := pool[key]; ok {
+		return db, nil
+	}
+
+	if engine == SQLite {
+		if err := os.MkdirAll(filepath.Dir(c.Path), 0700); err != nil {
+			return nil, errors.Wrap(err, "sqldb: error creating the database directory")
+		}
+	}
+
+	db, err := open(driver, dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "sqldb: error opening the database")
+	}
+	pool[key] = db
+	engines[db] = engine
+	return db, nil
+}
+
+// OpenDSN opens a connection pool to a MySQL or PostgreSQL database using the data source name of its driver.
+func OpenDSN(engine, dsn string) (*sql.DB, error) {
+	var driver string
+	switch engine {
+	case "", MySQL:
+		engine, driver = MySQL, "mysql"
+	case Postgres:
+		driver = "postgres"
+	default:
+		return nil, fmt.Errorf("sqldb: unsupported engine %s for a data source name", engine)
+	}
+
+	db, err := open(driver, dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "sqldb: error opening the database")
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	engines[db] = engine
+	return db, nil
+}
+
+// Engine returns the engine of a connection pool opened with Open; other pools are assumed to be MySQL.
+func Engine(db *sql.DB) string {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if engine, ok := engines[db]; ok {
+		return engine
+	}
+	return MySQL
+}
+
+// Rebind rewrites the ? placeholders of a query to the syntax of the engine of the connection pool.
+// Queries must not contain literal question marks.
+func Rebind(db *sql.DB, query string) string {
+	return RebindEngine(Engine(db), query)
+}
+
+// RebindEngine rewrites the ? placeholders of a query to the syntax of the given engine.
+func RebindEngine(engine, query string) string {
+	if engine != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (c Config) dsn() (engine, driver, dsn string, err error) {
+	switch c.Engine {
+	case "", MySQL:
+		return MySQL, "mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", c.Username, c.Password, c.Host, c.Port, c.Name), nil
+	case SQLite:
+		if c.Path == "" {
+			return "", "", "", errors.New("sqldb: the path of the sqlite database is required")
+		}
+		// the write-ahead log lets readers proceed while a connection writes.
+		// SQLite allows a single writer, the other connections wait for its
+		// lock up to the busy timeout. Transactions take the lock when they
+		// start, so they never fail upgrading a read lock.
+		params := url.Values{}
+		params.Set("_journal_mode", "WAL")
+		params.Set("_busy_timeout", fmt.Sprint(busyTimeout))
+		params.Set("_txlock", "immediate")
+		params.Set("_foreign_keys", "1")
+		return SQLite, "sqlite3", "file:" + c.Path + "?" + params.Encode(), nil
+	case Postgres:
+		u := url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(c.Username, c.Password),
+			Host:   fmt.Sprintf("%s:%d", c.Host, c.Port),
+			Path:   "/" + c.Name,
+		}
+		return Postgres, "postgres", u.String(), nil
+	default:
+		return "", "", "", fmt.Errorf("sqldb: unsupported engine %s", c.Engine)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sqldb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDSN(t *testing.T) {
+	engine, driver, dsn, err := Config{Username: "reva", Password: "secret", Host: "localhost", Port: 3306, Name: "reva"}.dsn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if engine != MySQL || driver != "mysql" || dsn != "reva:secret@tcp(localhost:3306)/reva" {
+		t.Fatalf("unexpected mysql data source %s %s %s", engine, driver, dsn)
+	}
+
+	engine, driver, dsn, err = Config{Engine: SQLite, Path: "/var/lib/reva/reva.db"}.dsn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if engine != SQLite || driver != "sqlite3" || !strings.HasPrefix(dsn, "file:/var/lib/reva/reva.db?") {
+		t.Fatalf("unexpected sqlite data source %s %s %s", engine, driver, dsn)
+	}
+	for _, param := range []string{"_journal_mode=WAL", "_busy_timeout=5000", "_txlock=immediate"} {
+		if !strings.Contains(dsn, param) {
+			t.Errorf("expected %s in the sqlite data source %s", param, dsn)
+		}
+	}
+
+	if _, _, _, err := (Config{Engine: SQLite}).dsn(); err == nil {
+		t.Error("expected an error for an sqlite database without path")
+	}
+	engine, driver, dsn, err = Config{Engine: Postgres, Username: "reva", Password: "s3cr:t", Host: "db", Port: 5432, Name: "reva"}.dsn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if engine != Postgres || driver != "postgres" || dsn != "postgres://reva:s3cr%3At@db:5432/reva" {
+		t.Fatalf("unexpected postgres data source %s %s %s", engine, driver, dsn)
+	}
+
+	if _, _, _, err := (Config{Engine: "oracle"}).dsn(); err == nil {
+		t.Error("expected an error for an unsupported engine")
+	}
+}
+
+func TestRebind(t *testing.T) {
+	query := "UPDATE t SET data=? WHERE id=? AND revision=?"
+	if q := RebindEngine(MySQL, query); q != query {
+		t.Errorf("expected the mysql query to be unchanged, got %s", q)
+	}
+	if q := RebindEngine(SQLite, query); q != query {
+		t.Errorf("expected the sqlite query to be unchanged, got %s", q)
+	}
+	if q := RebindEngine(Postgres, query); q != "UPDATE t SET data=$1 WHERE id=$2 AND revision=$3" {
+		t.Errorf("unexpected postgres query %s", q)
+	}
+}
@@ -21,6 +21,7 @@ package localhome
 import (
 	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/cs3org/reva/pkg/storage/utils/encryption"
 	"github.com/cs3org/reva/pkg/storage/utils/localfs"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
@@ -31,9 +32,13 @@ func init() {
 }
 
 type config struct {
-	Root        string `mapstructure:"root" docs:"/var/tmp/reva/;Path of root directory for user storage."`
-	ShareFolder string `mapstructure:"share_folder" docs:"/MyShares;Path for storing share references."`
-	UserLayout  string `mapstructure:"user_layout" docs:"{{.Username}};Template for user home directories"`
+	Root          string            `mapstructure:"root" docs:"/var/tmp/reva/;Path of root directory for user storage."`
+	ShareFolder   string            `mapstructure:"share_folder" docs:"/MyShares;Path for storing share references."`
+	UserLayout    string            `mapstructure:"user_layout" docs:"{{.Username}};Template for user home directories"`
+	Encryption    encryption.Config `mapstructure:"encryption" docs:"url:pkg/storage/utils/encryption/keys.go"`
+	RecycleMaxAge int               `mapstructure:"recycle_max_age" docs:"0;Number of days the deleted files are kept in the trash bin, 0 keeps them forever."`
+	MaxVersions   int               `mapstructure:"max_versions" docs:"0;Number of versions kept per file, 0 keeps all of them."`
+	Symlinks      string            `mapstructure:"symlinks" docs:"follow;How the symlinks are handled: follow them inside the mount root, expose them as links or reject them."`
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -54,9 +59,13 @@ func New(m map[string]interface{}) (storage.FS, error) {
 	}
 
 	conf := localfs.Config{
-		Root:        c.Root,
-		ShareFolder: c.ShareFolder,
-		UserLayout:  c.UserLayout,
+		Root:          c.Root,
+		ShareFolder:   c.ShareFolder,
+		Encryption:    c.Encryption,
+		RecycleMaxAge: c.RecycleMaxAge,
+		MaxVersions:   c.MaxVersions,
+		Symlinks:      c.Symlinks,
+		UserLayout:    c.UserLayout,
 	}
 	return localfs.NewLocalFS(&conf)
 }
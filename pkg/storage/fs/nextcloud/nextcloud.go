@@ -133,7 +133,7 @@ func (nc *StorageDriver) doUpload(ctx context.Context, filePath string, r io.Rea
 	// See https://github.com/pondersource/nc-sciencemesh/issues/5
 	// url := nc.endPoint + "~" + user.Username + "/files/" + filePath
 	url := nc.endPoint + "~" + user.Username + "/api/storage/Upload/" + filePath
-	req, err := http.NewRequest(http.MethodPut, url, r)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, r)
 	if err != nil {
 		panic(err)
 	}
@@ -142,6 +142,7 @@ func (nc *StorageDriver) doUpload(ctx context.Context, filePath string, r io.Rea
 	// set the request header Content-Type for the upload
 	// FIXME: get the actual content type from somewhere
 	req.Header.Set("Content-Type", "text/plain")
+	ctxpkg.AddRequestIDHeader(ctx, req.Header)
 	resp, err := nc.client.Do(req)
 	if err != nil {
 		panic(err)
@@ -160,11 +161,12 @@ func (nc *StorageDriver) doDownload(ctx context.Context, filePath string) (io.Re
 	// See https://github.com/pondersource/nc-sciencemesh/issues/5
 	// url := nc.endPoint + "~" + user.Username + "/files/" + filePath
 	url := nc.endPoint + "~" + user.Username + "/api/storage/Download/" + filePath
-	req, err := http.NewRequest(http.MethodGet, url, strings.NewReader(""))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, strings.NewReader(""))
 	if err != nil {
 		panic(err)
 	}
 
+	ctxpkg.AddRequestIDHeader(ctx, req.Header)
 	resp, err := nc.client.Do(req)
 	if err != nil {
 		panic(err)
@@ -183,12 +185,13 @@ func (nc *StorageDriver) doDownloadRevision(ctx context.Context, filePath string
 	}
 	// See https://github.com/pondersource/nc-sciencemesh/issues/5
 	url := nc.endPoint + "~" + user.Username + "/api/storage/DownloadRevision/" + url.QueryEscape(key) + "/" + filePath
-	req, err := http.NewRequest(http.MethodGet, url, strings.NewReader(""))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, strings.NewReader(""))
 	if err != nil {
 		panic(err)
 	}
 	req.Header.Set("X-Reva-Secret", nc.sharedSecret)
 
+	ctxpkg.AddRequestIDHeader(ctx, req.Header)
 	resp, err := nc.client.Do(req)
 	if err != nil {
 		panic(err)
@@ -210,13 +213,14 @@ func (nc *StorageDriver) do(ctx context.Context, a Action) (int, []byte, error)
 	// for discussion of user.Username vs user.Id.OpaqueId
 	url := nc.endPoint + "~" + user.Id.OpaqueId + "/api/storage/" + a.verb
 	log.Info().Msgf("nc.do req %s %s", url, a.argS)
-	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(a.argS))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(a.argS))
 	if err != nil {
 		return 0, nil, err
 	}
 	req.Header.Set("X-Reva-Secret", nc.sharedSecret)
 
 	req.Header.Set("Content-Type", "application/json")
+	ctxpkg.AddRequestIDHeader(ctx, req.Header)
 	resp, err := nc.client.Do(req)
 	if err != nil {
 		return 0, nil, err
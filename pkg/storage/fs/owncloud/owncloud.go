@@ -49,6 +49,7 @@ import (
 	"github.com/cs3org/reva/pkg/storage/utils/ace"
 	"github.com/cs3org/reva/pkg/storage/utils/chunking"
 	"github.com/cs3org/reva/pkg/storage/utils/templates"
+	"github.com/cs3org/reva/pkg/utils"
 	"github.com/gomodule/redigo/redis"
 	"github.com/google/uuid"
 	"github.com/mitchellh/mapstructure"
@@ -1226,7 +1227,7 @@ func (fs *ocfs) CreateReference(ctx context.Context, sp string, targetURI *url.U
 
 func (fs *ocfs) setMtime(ctx context.Context, ip string, mtime string) error {
 	log := appctx.GetLogger(ctx)
-	if mt, err := parseMTime(mtime); err == nil {
+	if mt, err := utils.ParseMTime(mtime); err == nil {
 		// updating mtime also updates atime
 		if err := os.Chtimes(ip, mt, mt); err != nil {
 			log.Error().Err(err).
@@ -1376,17 +1377,6 @@ func (fs *ocfs) SetArbitraryMetadata(ctx context.Context, ref *provider.Referenc
 	}
 }
 
-func parseMTime(v string) (t time.Time, err error) {
-	p := strings.SplitN(v, ".", 2)
-	var sec, nsec int64
-	if sec, err = strconv.ParseInt(p[0], 10, 64); err == nil {
-		if len(p) > 1 {
-			nsec, err = strconv.ParseInt(p[1], 10, 64)
-		}
-	}
-	return time.Unix(sec, nsec), err
-}
-
 func (fs *ocfs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) (err error) {
 	log := appctx.GetLogger(ctx)
 
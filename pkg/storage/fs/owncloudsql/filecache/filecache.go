@@ -501,11 +501,11 @@ func (c *Cache) Move(storage interface{}, sourcePath, targetPath string) error {
 		return err
 	}
 
-	childRows, err := tx.Query("SELECT fileid, path FROM oc_filecache WHERE parent = ?", source.ID)
+	// rewrite the paths of all descendants, keeping their file ids and parents
+	childRows, err := tx.Query("SELECT fileid, path FROM oc_filecache WHERE storage = ? AND path LIKE ? ESCAPE '!'", storageID, escapeLike(sourcePath)+"/%")
 	if err != nil {
 		return err
 	}
-	defer childRows.Close()
 	children := map[int]string{}
 	for childRows.Next() {
 		var (
@@ -514,15 +514,26 @@ func (c *Cache) Move(storage interface{}, sourcePath, targetPath string) error {
 		)
 		err = childRows.Scan(&id, &path)
 		if err != nil {
+			childRows.Close()
 			return err
 		}
 
 		children[id] = path
 	}
+	childRows.Close()
+	if err = childRows.Err(); err != nil {
+		return err
+	}
+
+	childStmt, err := tx.Prepare("UPDATE oc_filecache SET path=?, name=?, path_hash=? WHERE storage = ? AND fileid=?")
+	if err != nil {
+		return err
+	}
+	defer childStmt.Close()
 	for id, path := range children {
-		path = strings.ReplaceAll(path, sourcePath, targetPath)
+		path = targetPath + strings.TrimPrefix(path, sourcePath)
 		phashBytes = md5.Sum([]byte(path))
-		_, err = stmt.Exec(source.ID, path, filepath.Base(path), hex.EncodeToString(phashBytes[:]), storageID, id)
+		_, err = childStmt.Exec(path, filepath.Base(path), hex.EncodeToString(phashBytes[:]), storageID, id)
 		if err != nil {
 			return err
 		}
@@ -663,6 +674,38 @@ func (c *Cache) SetEtag(storage interface{}, path, etag string) error {
 	return err
 }
 
+// Propagate updates the etag and mtime of the specified item after a change below it.
+// The mtime is never decreased. The size of directories is recalculated from the sizes
+// of their children, like ownCloud 10 does, so that legacy clients keep seeing
+// consistent quota and folder sizes.
+func (c *Cache) Propagate(storage interface{}, path, etag string, mtime int, isDir bool) error {
+	storageID, err := toIntID(storage)
+	if err != nil {
+		return err
+	}
+	source, err := c.Get(storageID, path)
+	if err != nil {
+		return errors.Wrap(err, "could not find source")
+	}
+	if source.MTime > mtime {
+		mtime = source.MTime
+	}
+
+	if !isDir {
+		_, err = c.db.Exec("UPDATE oc_filecache SET etag=?, mtime=? WHERE storage = ? AND fileid=?", etag, mtime, storageID, source.ID)
+		return err
+	}
+
+	// unknown sizes are stored as negative values and must not be aggregated
+	var size int
+	row := c.db.QueryRow("SELECT COALESCE(SUM(size), 0) FROM oc_filecache WHERE parent = ? AND size >= 0", source.ID)
+	if err = row.Scan(&size); err != nil {
+		return err
+	}
+	_, err = c.db.Exec("UPDATE oc_filecache SET etag=?, mtime=?, size=? WHERE storage = ? AND fileid=?", etag, mtime, size, storageID, source.ID)
+	return err
+}
+
 func (c *Cache) insertMimetype(tx *sql.Tx, mimetype string) error {
 	insertPart := func(v string) error {
 		stmt, err := tx.Prepare("INSERT INTO oc_mimetypes(mimetype) VALUES(?)")
@@ -686,6 +729,11 @@ func (c *Cache) insertMimetype(tx *sql.Tx, mimetype string) error {
 	return insertPart(mimetype)
 }
 
+// escapeLike escapes the wildcards of a LIKE pattern using ! as escape character
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
 func toIntID(rid interface{}) (int, error) {
 	switch t := rid.(type) {
 	case int:
@@ -390,6 +390,63 @@ var _ = Describe("Filecache", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(newEntry.Path).To(Equal("files/Foo/Portugal.jpg"))
 		})
+
+		It("keeps the file ids", func() {
+			oldEntry, err := cache.Get(1, "files/Photos/Portugal.jpg")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = cache.Move(1, "files/Photos", "files/Foo")
+			Expect(err).ToNot(HaveOccurred())
+
+			newEntry, err := cache.Get(1, "files/Foo/Portugal.jpg")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(newEntry.ID).To(Equal(oldEntry.ID))
+		})
+	})
+
+	Describe("Propagate", func() {
+		It("updates the etag and mtime", func() {
+			entry, err := cache.Get(1, "files/Photos/Portugal.jpg")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = cache.Propagate(1, "files/Photos/Portugal.jpg", "foo", entry.MTime+10, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			updated, err := cache.Get(1, "files/Photos/Portugal.jpg")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updated.Etag).To(Equal("foo"))
+			Expect(updated.MTime).To(Equal(entry.MTime + 10))
+		})
+
+		It("does not decrease the mtime", func() {
+			entry, err := cache.Get(1, "files/Photos/Portugal.jpg")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = cache.Propagate(1, "files/Photos/Portugal.jpg", "foo", entry.MTime-10, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			updated, err := cache.Get(1, "files/Photos/Portugal.jpg")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updated.MTime).To(Equal(entry.MTime))
+		})
+
+		It("aggregates the size of directories", func() {
+			children, err := cache.List(1, "files/Photos/")
+			Expect(err).ToNot(HaveOccurred())
+			size := 0
+			for _, c := range children {
+				if c.Size > 0 {
+					size += c.Size
+				}
+			}
+
+			err = cache.Propagate(1, "files/Photos", "foo", 0, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			updated, err := cache.Get(1, "files/Photos")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updated.Size).To(Equal(size))
+		})
 	})
 
 	Describe("SetEtag", func() {
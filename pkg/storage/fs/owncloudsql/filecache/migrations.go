@@ -0,0 +1,85 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package filecache
+
+import (
+	"context"
+
+	"github.com/cs3org/reva/pkg/migrate"
+)
+
+// Component is the migration component of the ownCloud file cache tables used by the owncloudsql storage driver.
+const Component = "owncloud_filecache"
+
+func init() {
+	// the tables belong to the ownCloud database, so they are only created when missing
+	// and they are left in place when the migration is reverted
+	migrate.Register(Component, migrate.Migration{
+		Version:     1,
+		Description: "create the storage, file cache, mime type and trash tables",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS oc_storages (
+				numeric_id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				id VARCHAR(64) DEFAULT NULL UNIQUE,
+				available INT NOT NULL DEFAULT 1,
+				last_checked INT DEFAULT NULL
+			)`,
+			`CREATE TABLE IF NOT EXISTS oc_mimetypes (
+				id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				mimetype VARCHAR(255) NOT NULL DEFAULT '' UNIQUE
+			)`,
+			`CREATE TABLE IF NOT EXISTS oc_filecache (
+				fileid BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				storage INT NOT NULL DEFAULT 0,
+				path VARCHAR(4000) DEFAULT NULL,
+				path_hash VARCHAR(32) NOT NULL DEFAULT '',
+				parent BIGINT NOT NULL DEFAULT 0,
+				name VARCHAR(250) DEFAULT NULL,
+				mimetype INT NOT NULL DEFAULT 0,
+				mimepart INT NOT NULL DEFAULT 0,
+				size BIGINT NOT NULL DEFAULT 0,
+				mtime INT NOT NULL DEFAULT 0,
+				storage_mtime INT NOT NULL DEFAULT 0,
+				encrypted INT NOT NULL DEFAULT 0,
+				unencrypted_size BIGINT NOT NULL DEFAULT 0,
+				etag VARCHAR(40) DEFAULT NULL,
+				permissions INT DEFAULT 0,
+				checksum VARCHAR(255) DEFAULT NULL,
+				UNIQUE INDEX fs_storage_path_hash (storage, path_hash),
+				INDEX fs_parent_name_hash (parent, name)
+			)`,
+			`CREATE TABLE IF NOT EXISTS oc_files_trash (
+				auto_id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				id VARCHAR(250) NOT NULL DEFAULT '',
+				user VARCHAR(64) NOT NULL DEFAULT '',
+				timestamp VARCHAR(12) NOT NULL DEFAULT '',
+				location VARCHAR(512) NOT NULL DEFAULT '',
+				type VARCHAR(4) DEFAULT NULL,
+				mime VARCHAR(255) DEFAULT NULL,
+				INDEX id_index (id),
+				INDEX user_index (user)
+			)`,
+		},
+	})
+}
+
+// InitSchema applies all pending migrations of the tables.
+func (c *Cache) InitSchema(ctx context.Context) error {
+	return migrate.Up(ctx, c.db, Component)
+}
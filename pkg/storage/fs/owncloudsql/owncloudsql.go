@@ -54,6 +54,7 @@ import (
 	"github.com/cs3org/reva/pkg/storage/fs/registry"
 	"github.com/cs3org/reva/pkg/storage/utils/chunking"
 	"github.com/cs3org/reva/pkg/storage/utils/templates"
+	"github.com/cs3org/reva/pkg/utils"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"github.com/pkg/xattr"
@@ -118,6 +119,8 @@ type config struct {
 	DbHost                   string `mapstructure:"dbhost"`
 	DbPort                   int    `mapstructure:"dbport"`
 	DbName                   string `mapstructure:"dbname"`
+	// SkipMigrations disables applying the pending schema migrations on startup.
+	SkipMigrations bool `mapstructure:"skip_migrations"`
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -181,6 +184,11 @@ func New(m map[string]interface{}) (storage.FS, error) {
 	if err != nil {
 		return nil, err
 	}
+	if !c.SkipMigrations {
+		if err := filecache.InitSchema(context.Background()); err != nil {
+			return nil, err
+		}
+	}
 
 	return &owncloudsqlfs{
 		c:            c,
@@ -777,7 +785,7 @@ func (fs *owncloudsqlfs) CreateReference(ctx context.Context, sp string, targetU
 
 func (fs *owncloudsqlfs) setMtime(ctx context.Context, ip string, mtime string) error {
 	log := appctx.GetLogger(ctx)
-	if mt, err := parseMTime(mtime); err == nil {
+	if mt, err := utils.ParseMTime(mtime); err == nil {
 		// updating mtime also updates atime
 		if err := os.Chtimes(ip, mt, mt); err != nil {
 			log.Error().Err(err).
@@ -927,17 +935,6 @@ func (fs *owncloudsqlfs) SetArbitraryMetadata(ctx context.Context, ref *provider
 	}
 }
 
-func parseMTime(v string) (t time.Time, err error) {
-	p := strings.SplitN(v, ".", 2)
-	var sec, nsec int64
-	if sec, err = strconv.ParseInt(p[0], 10, 64); err == nil {
-		if len(p) > 1 {
-			nsec, err = strconv.ParseInt(p[1], 10, 64)
-		}
-	}
-	return time.Unix(sec, nsec), err
-}
-
 func (fs *owncloudsqlfs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) (err error) {
 	log := appctx.GetLogger(ctx)
 
@@ -1889,8 +1886,9 @@ func (fs *owncloudsqlfs) propagate(ctx context.Context, leafPath string) error {
 		return err
 	}
 
+	// the root folders are updated as well, as legacy clients rely on their etag and size
 	currentPath := filepath.Clean(leafPath)
-	for currentPath != root && currentPath != versionsRoot {
+	for {
 		appctx.GetLogger(ctx).Debug().
 			Str("leafPath", leafPath).
 			Str("currentPath", currentPath).
@@ -1914,16 +1912,20 @@ func (fs *owncloudsqlfs) propagate(ctx context.Context, leafPath string) error {
 			return err
 		}
 		etag := calcEtag(ctx, fi)
-		if err := fs.filecache.SetEtag(storageID, fs.toDatabasePath(currentPath), etag); err != nil {
+		if err := fs.filecache.Propagate(storageID, fs.toDatabasePath(currentPath), etag, int(fi.ModTime().Unix()), fi.IsDir()); err != nil {
 			appctx.GetLogger(ctx).Error().
 				Err(err).
 				Str("leafPath", leafPath).
 				Str("currentPath", currentPath).
-				Msg("could not set etag")
+				Msg("could not propagate etag, mtime and size")
 			return err
 		}
 
-		currentPath = filepath.Dir(currentPath)
+		parent := filepath.Dir(currentPath)
+		if currentPath == root || currentPath == versionsRoot || parent == currentPath {
+			break
+		}
+		currentPath = parent
 	}
 	return nil
 }
@@ -0,0 +1,189 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/storage/utils/movejournal"
+	"github.com/pkg/errors"
+)
+
+// journalStore keeps the entries of the move journal as objects in the
+// bucket, so that all the instances share them.
+type journalStore struct {
+	fs *s3FS
+}
+
+func (s *journalStore) key(id string) string {
+	return s.fs.config.JournalPrefix + "/" + id + ".json"
+}
+
+func (s *journalStore) List(ctx context.Context) ([]*movejournal.Entry, error) {
+	var entries []*movejournal.Entry
+	err := s.fs.listKeys(ctx, s.fs.config.JournalPrefix+"/", func(keys []string) error {
+		for _, k := range keys {
+			out, err := s.fs.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+				Bucket: aws.String(s.fs.config.Bucket),
+				Key:    aws.String(k),
+			})
+			if err != nil {
+				if isNotFound(err) {
+					// completed in the meantime
+					continue
+				}
+				return errors.Wrap(err, "s3fs: error reading journal entry "+k)
+			}
+			e := &movejournal.Entry{}
+			err = json.NewDecoder(out.Body).Decode(e)
+			out.Body.Close()
+			if err != nil {
+				appctx.GetLogger(ctx).Error().Err(err).Str("key", k).Msg("s3fs: skipping invalid journal entry")
+				continue
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+func (s *journalStore) Save(ctx context.Context, e *movejournal.Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = s.fs.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.fs.config.Bucket),
+		Key:         aws.String(s.key(e.ID)),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+func (s *journalStore) Delete(ctx context.Context, id string) error {
+	return s.fs.deleteKey(ctx, s.key(id))
+}
+
+// journalBackend gives the move journal access to the objects of the bucket.
+type journalBackend struct {
+	fs *s3FS
+}
+
+func (b *journalBackend) List(ctx context.Context, prefix string, fn func(keys []string) error) error {
+	return b.fs.listKeys(ctx, prefix, fn)
+}
+
+func (b *journalBackend) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := b.fs.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(b.fs.config.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "s3fs: error checking "+key)
+	}
+	return true, nil
+}
+
+func (b *journalBackend) Copy(ctx context.Context, src, dst string) error {
+	return b.fs.copyObject(ctx, src, "", dst)
+}
+
+func (b *journalBackend) Delete(ctx context.Context, key string) error {
+	return b.fs.deleteKey(ctx, key)
+}
+
+// listKeys calls fn with the keys found below prefix, one page at a time.
+func (fs *s3FS) listKeys(ctx context.Context, prefix string, fn func(keys []string) error) error {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(fs.config.Bucket),
+		Prefix: aws.String(prefix),
+	}
+	isTruncated := true
+
+	for isTruncated {
+		output, err := fs.client.ListObjectsV2WithContext(ctx, input)
+		if err != nil {
+			return errors.Wrap(err, "s3fs: error listing "+prefix)
+		}
+
+		keys := make([]string, 0, len(output.Contents))
+		for _, o := range output.Contents {
+			keys = append(keys, *o.Key)
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+
+		input.ContinuationToken = output.NextContinuationToken
+		isTruncated = *output.IsTruncated
+	}
+	return nil
+}
+
+func (fs *s3FS) deleteKey(ctx context.Context, key string) error {
+	_, err := fs.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(fs.config.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil && !isNotFound(err) {
+		return errors.Wrap(err, "s3fs: error deleting "+key)
+	}
+	return nil
+}
+
+// checkMoves recovers the interrupted moves involving fn. Failures are only
+// logged, the tree is still accessible in its current state.
+func (fs *s3FS) checkMoves(ctx context.Context, fn string) {
+	if err := fs.journal.Check(ctx, fn); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("fn", fn).Msg("s3fs: error recovering interrupted moves")
+	}
+}
+
+// ListInterruptedMoves returns the moves which have been interrupted.
+func (fs *s3FS) ListInterruptedMoves(ctx context.Context) ([]*movejournal.Entry, error) {
+	return fs.journal.Interrupted(ctx)
+}
+
+// RepairMove resumes or rolls back an interrupted move.
+func (fs *s3FS) RepairMove(ctx context.Context, id string, rollback bool) error {
+	return fs.journal.Repair(ctx, id, rollback)
+}
+
+func isNotFound(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchKey, s3.ErrCodeNoSuchUpload, "NoSuchVersion", "NotFound":
+			return true
+		}
+	}
+	return false
+}
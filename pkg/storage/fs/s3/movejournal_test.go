@@ -0,0 +1,76 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/cs3org/reva/pkg/storage/utils/movejournal"
+)
+
+func TestJournalStore(t *testing.T) {
+	f, srv := newFakeS3(t)
+	fs := newTestFS(t, srv, nil)
+	store := &journalStore{fs: fs}
+	ctx := context.Background()
+
+	if key := store.key("m1"); key != ".reva-move-journal/m1.json" {
+		t.Errorf("unexpected key %v", key)
+	}
+
+	if err := store.Save(ctx, &movejournal.Entry{ID: "m1", Source: "/data/a", Target: "/data/b"}); err != nil {
+		t.Fatal(err)
+	}
+	e := &movejournal.Entry{}
+	if err := json.Unmarshal([]byte(f.objects[".reva-move-journal/m1.json"]), e); err != nil || e.Source != "/data/a" {
+		t.Errorf("unexpected stored entry %q", f.objects[".reva-move-journal/m1.json"])
+	}
+
+	// Invalid entries are skipped
+	f.objects[".reva-move-journal/m2.json"] = "{"
+	entries, err := store.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].ID != "m1" || entries[0].Target != "/data/b" {
+		t.Errorf("expected only the valid entry, got %+v", entries)
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{awserr.New("NoSuchKey", "", nil), true},
+		{awserr.New("NoSuchVersion", "", nil), true},
+		{awserr.New("NotFound", "", nil), true},
+		{awserr.New("AccessDenied", "", nil), false},
+		{errors.New("NoSuchKey"), false},
+	}
+	for _, tt := range tests {
+		if isNotFound(tt.err) != tt.expected {
+			t.Errorf("isNotFound(%v) = %v, expected %v", tt.err, !tt.expected, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+)
+
+// markerKey returns the key of the empty object representing the folder fn.
+// It holds the modification time propagated to the folder.
+func markerKey(fn string) string {
+	return strings.TrimSuffix(fn, "/") + "/"
+}
+
+// formatMTime formats a modification time the way utils.ParseMTime parses it.
+func formatMTime(t time.Time) string {
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
+
+// calcEtag returns a hash of the path and the modification time of a folder.
+func calcEtag(fn string, mtime *types.Timestamp) string {
+	h := md5.New()
+	fmt.Fprintf(h, "%s:%d.%d", fn, mtime.GetSeconds(), mtime.GetNanos())
+	return fmt.Sprintf(`"%x"`, h.Sum(nil))
+}
+
+// propagate sets the modification time of the folders above fn to now, which
+// changes their etags as well. Failures are only logged, the change itself
+// has been applied already.
+func (fs *s3FS) propagate(ctx context.Context, fn string) {
+	if !fs.config.TreeTimeAccounting {
+		return
+	}
+	log := appctx.GetLogger(ctx)
+
+	mtime := aws.String(formatMTime(time.Now()))
+	root := fs.addRoot("/")
+	for dir := path.Dir(fn); strings.HasPrefix(dir, root); dir = path.Dir(dir) {
+		_, err := fs.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+			Bucket:        aws.String(fs.config.Bucket),
+			Key:           aws.String(markerKey(dir)),
+			ContentType:   aws.String("application/octet-stream"),
+			ContentLength: aws.Int64(0),
+			Metadata:      map[string]*string{mtimeMetadataKey: mtime},
+		})
+		if err != nil {
+			log.Error().Err(err).Str("fn", fn).Str("dir", dir).Msg("s3fs: error propagating the modification time")
+			return
+		}
+		if dir == root || dir == "/" {
+			return
+		}
+	}
+}
+
+// normalizePrefix returns the metadata of a folder found in a listing. The
+// modification time propagated to the folder needs an additional request, so
+// it is only looked up when the tree time accounting is enabled.
+func (fs *s3FS) normalizePrefix(ctx context.Context, p *s3.CommonPrefix) *provider.ResourceInfo {
+	if fs.config.TreeTimeAccounting {
+		marker, err := fs.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(fs.config.Bucket),
+			Key:    p.Prefix,
+		})
+		if err == nil {
+			return fs.normalizeHead(ctx, marker, *p.Prefix)
+		}
+	}
+	return fs.normalizeCommonPrefix(ctx, p)
+}
@@ -0,0 +1,77 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package s3
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+func TestPropagate(t *testing.T) {
+	f, srv := newFakeS3(t)
+	fs := newTestFS(t, srv, map[string]interface{}{"treetime_accounting": true})
+
+	before := time.Now()
+	fs.propagate(context.Background(), "/data/a/b/file.txt")
+
+	expected := []string{"data/a/b/", "data/a/", "data/"}
+	if len(f.puts) != len(expected) {
+		t.Fatalf("expected the markers %v, got %v", expected, f.puts)
+	}
+	for i, put := range f.puts {
+		if put[0] != expected[i] {
+			t.Errorf("expected marker %v, got %v", expected[i], put[0])
+		}
+		mtime, err := utils.ParseMTime(put[1])
+		if err != nil {
+			t.Fatalf("invalid modification time %q: %v", put[1], err)
+		}
+		if mtime.Before(before.Truncate(time.Second)) {
+			t.Errorf("expected the current time, got %v", mtime)
+		}
+	}
+}
+
+func TestPropagateDisabled(t *testing.T) {
+	f, srv := newFakeS3(t)
+	fs := newTestFS(t, srv, nil)
+
+	fs.propagate(context.Background(), "/data/a/file.txt")
+	if len(f.puts) != 0 {
+		t.Errorf("expected no markers to be written, got %v", f.puts)
+	}
+}
+
+func TestCalcEtag(t *testing.T) {
+	mtime := &types.Timestamp{Seconds: 123, Nanos: 5}
+	etag := calcEtag("/data/a", mtime)
+	if etag != calcEtag("/data/a", &types.Timestamp{Seconds: 123, Nanos: 5}) {
+		t.Error("expected the etag to be stable")
+	}
+	if etag == calcEtag("/data/b", mtime) || etag == calcEtag("/data/a", &types.Timestamp{Seconds: 123, Nanos: 6}) {
+		t.Error("expected the etag to depend on the path and the modification time")
+	}
+	if markerKey("/data/a") != "/data/a/" || markerKey("/data/a/") != "/data/a/" {
+		t.Errorf("unexpected marker keys %v and %v", markerKey("/data/a"), markerKey("/data/a/"))
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package s3
+
+import (
+	"context"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/pkg/errors"
+)
+
+// The revisions of a file are the noncurrent versions of its object, the
+// revision keys are the version ids. They only exist when the bucket has
+// versioning enabled.
+
+func (fs *s3FS) ListRevisions(ctx context.Context, ref *provider.Reference) ([]*provider.FileVersion, error) {
+	if !fs.config.Versioning {
+		return nil, errtypes.NotSupported("list revisions")
+	}
+
+	fn, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return nil, errors.Wrap(err, "error resolving ref")
+	}
+
+	revisions := []*provider.FileVersion{}
+	err = fs.client.ListObjectVersionsPagesWithContext(ctx, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(fs.config.Bucket),
+		Prefix: aws.String(fn),
+	}, func(out *s3.ListObjectVersionsOutput, last bool) bool {
+		for _, v := range out.Versions {
+			// the prefix also matches the siblings whose name starts with the same name
+			if aws.StringValue(v.Key) != fn || aws.BoolValue(v.IsLatest) {
+				continue
+			}
+			revisions = append(revisions, &provider.FileVersion{
+				Key:   aws.StringValue(v.VersionId),
+				Size:  uint64(aws.Int64Value(v.Size)),
+				Mtime: uint64(aws.TimeValue(v.LastModified).Unix()),
+				Etag:  aws.StringValue(v.ETag),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "s3fs: error listing versions of "+fn)
+	}
+	return revisions, nil
+}
+
+func (fs *s3FS) DownloadRevision(ctx context.Context, ref *provider.Reference, revisionKey string) (io.ReadCloser, error) {
+	if !fs.config.Versioning {
+		return nil, errtypes.NotSupported("download revision")
+	}
+
+	fn, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return nil, errors.Wrap(err, "error resolving ref")
+	}
+
+	r, err := fs.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket:    aws.String(fs.config.Bucket),
+		Key:       aws.String(fn),
+		VersionId: aws.String(revisionKey),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, errtypes.NotFound(fn + "@" + revisionKey)
+		}
+		return nil, errors.Wrap(err, "s3fs: error downloading revision "+revisionKey+" of "+fn)
+	}
+	return r.Body, nil
+}
+
+// RestoreRevision copies the version over the object, which keeps the current
+// content as a revision.
+func (fs *s3FS) RestoreRevision(ctx context.Context, ref *provider.Reference, revisionKey string) error {
+	if !fs.config.Versioning {
+		return errtypes.NotSupported("restore revision")
+	}
+
+	fn, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return errors.Wrap(err, "error resolving ref")
+	}
+
+	if err := fs.copyObject(ctx, fn, revisionKey, fn); err != nil {
+		return err
+	}
+	fs.propagate(ctx, fn)
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package s3
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+const testVersions = `<ListVersionsResult>
+	<Name>bucket</Name>
+	<IsTruncated>false</IsTruncated>
+	<Version><Key>/data/file.txt</Key><VersionId>v2</VersionId><IsLatest>true</IsLatest><Size>5</Size></Version>
+	<Version><Key>/data/file.txt</Key><VersionId>v1</VersionId><IsLatest>false</IsLatest><Size>3</Size><ETag>"e1"</ETag><LastModified>2021-10-01T12:00:00.000Z</LastModified></Version>
+	<Version><Key>/data/file.txt.bak</Key><VersionId>v3</VersionId><IsLatest>false</IsLatest><Size>4</Size></Version>
+</ListVersionsResult>`
+
+func TestRevisionsNotSupported(t *testing.T) {
+	_, srv := newFakeS3(t)
+	fs := newTestFS(t, srv, nil)
+	ctx := context.Background()
+	ref := &provider.Reference{Path: "/file.txt"}
+
+	if _, err := fs.ListRevisions(ctx, ref); !isNotSupported(err) {
+		t.Errorf("expected listing the revisions not to be supported, got %v", err)
+	}
+	if _, err := fs.DownloadRevision(ctx, ref, "v1"); !isNotSupported(err) {
+		t.Errorf("expected downloading a revision not to be supported, got %v", err)
+	}
+	if err := fs.RestoreRevision(ctx, ref, "v1"); !isNotSupported(err) {
+		t.Errorf("expected restoring a revision not to be supported, got %v", err)
+	}
+}
+
+func TestListRevisions(t *testing.T) {
+	f, srv := newFakeS3(t)
+	f.versions = testVersions
+	fs := newTestFS(t, srv, map[string]interface{}{"versioning": true})
+
+	revisions, err := fs.ListRevisions(context.Background(), &provider.Reference{Path: "/file.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Neither the current version nor the versions of the siblings are revisions
+	if len(revisions) != 1 {
+		t.Fatalf("expected a single revision, got %v", revisions)
+	}
+	if r := revisions[0]; r.Key != "v1" || r.Size != 3 || r.Etag != `"e1"` || r.Mtime != 1633089600 {
+		t.Errorf("unexpected revision %+v", r)
+	}
+}
+
+func TestDownloadRevision(t *testing.T) {
+	f, srv := newFakeS3(t)
+	f.objects["data/file.txt@v1"] = "old"
+	fs := newTestFS(t, srv, map[string]interface{}{"versioning": true})
+	ctx := context.Background()
+	ref := &provider.Reference{Path: "/file.txt"}
+
+	r, err := fs.DownloadRevision(ctx, ref, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if data, _ := ioutil.ReadAll(r); string(data) != "old" {
+		t.Errorf("expected the content of the revision, got %q", data)
+	}
+
+	if _, err := fs.DownloadRevision(ctx, ref, "v9"); err == nil {
+		t.Error("expected an unknown revision to fail")
+	} else if _, ok := err.(errtypes.IsNotFound); !ok {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func isNotSupported(err error) bool {
+	_, ok := err.(errtypes.IsNotSupported)
+	return ok
+}
@@ -41,6 +41,8 @@ import (
 	"github.com/cs3org/reva/pkg/mime"
 	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/cs3org/reva/pkg/storage/utils/movejournal"
+	"github.com/cs3org/reva/pkg/utils"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 )
@@ -56,6 +58,24 @@ type config struct {
 	Endpoint  string `mapstructure:"endpoint"`
 	Bucket    string `mapstructure:"bucket"`
 	Prefix    string `mapstructure:"prefix"`
+
+	// JournalPrefix is the prefix of the entries of the move journal, which
+	// must be outside of the storage prefix.
+	JournalPrefix string             `mapstructure:"journal_prefix"`
+	MoveJournal   movejournal.Config `mapstructure:"move_journal"`
+
+	// UploadPrefix is the prefix of the state of the ongoing uploads, which
+	// must be outside of the storage prefix as well.
+	UploadPrefix string `mapstructure:"upload_prefix"`
+	// PartSize is the size of the parts of the multipart uploads.
+	PartSize int64 `mapstructure:"part_size"`
+
+	// TreeTimeAccounting propagates the changes to the modification time and
+	// the etag of the parent folders, so that sync clients notice them.
+	TreeTimeAccounting bool `mapstructure:"treetime_accounting"`
+	// Versioning maps the revisions of the files to the versions of the
+	// objects. The bucket needs to have versioning enabled.
+	Versioning bool `mapstructure:"versioning"`
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -64,6 +84,18 @@ func parseConfig(m map[string]interface{}) (*config, error) {
 		err = errors.Wrap(err, "error decoding conf")
 		return nil, err
 	}
+	if c.JournalPrefix == "" {
+		c.JournalPrefix = ".reva-move-journal"
+	}
+	if c.UploadPrefix == "" {
+		c.UploadPrefix = ".reva-uploads"
+	}
+	if c.PartSize == 0 {
+		c.PartSize = defaultPartSize
+	}
+	if c.PartSize < minPartSize {
+		return nil, fmt.Errorf("s3fs: part_size must be at least %d bytes", minPartSize)
+	}
 	return c, nil
 }
 
@@ -102,13 +134,41 @@ func New(m map[string]interface{}) (storage.FS, error) {
 
 	s3Client := s3.New(sess)
 
-	return &s3FS{client: s3Client, config: c}, nil
+	fs := &s3FS{client: s3Client, config: c}
+	fs.journal, err = movejournal.New(&c.MoveJournal, &journalStore{fs: fs}, &journalBackend{fs: fs})
+	if err != nil {
+		return nil, err
+	}
+	return fs, nil
 }
 
 func (fs *s3FS) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// Warmup checks that the bucket exists and can be accessed, and that it keeps
+// the versions of the objects when they are mapped to revisions.
+func (fs *s3FS) Warmup(ctx context.Context) error {
+	_, err := fs.client.HeadBucketWithContext(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(fs.config.Bucket),
+	})
+	if err != nil {
+		return errors.Wrap(err, "s3: error accessing bucket "+fs.config.Bucket)
+	}
+	if fs.config.Versioning {
+		out, err := fs.client.GetBucketVersioningWithContext(ctx, &s3.GetBucketVersioningInput{
+			Bucket: aws.String(fs.config.Bucket),
+		})
+		if err != nil {
+			return errors.Wrap(err, "s3: error reading the versioning of bucket "+fs.config.Bucket)
+		}
+		if aws.StringValue(out.Status) != s3.BucketVersioningStatusEnabled {
+			return errors.New("s3: versioning is not enabled on bucket " + fs.config.Bucket)
+		}
+	}
+	return nil
+}
+
 func (fs *s3FS) addRoot(p string) string {
 	np := path.Join(fs.config.Prefix, p)
 	return np
@@ -116,12 +176,15 @@ func (fs *s3FS) addRoot(p string) string {
 
 func (fs *s3FS) resolve(ctx context.Context, ref *provider.Reference) (string, error) {
 	if strings.HasPrefix(ref.Path, "/") {
-		return fs.addRoot(ref.GetPath()), nil
+		fn := fs.addRoot(ref.GetPath())
+		fs.checkMoves(ctx, fn)
+		return fn, nil
 	}
 
 	if ref.ResourceId != nil && ref.ResourceId.OpaqueId != "" {
 		fn := path.Join("/", strings.TrimPrefix(ref.ResourceId.OpaqueId, "fileid-"))
 		fn = fs.addRoot(fn)
+		fs.checkMoves(ctx, fn)
 		return fn, nil
 	}
 
@@ -138,8 +201,9 @@ func (fs *s3FS) removeRoot(np string) string {
 }
 
 type s3FS struct {
-	client *s3.S3
-	config *config
+	client  *s3.S3
+	config  *config
+	journal *movejournal.Journal
 }
 
 // permissionSet returns the permission set for the current user
@@ -155,13 +219,13 @@ func (fs *s3FS) permissionSet(ctx context.Context) *provider.ResourcePermissions
 		InitiateFileDownload: true,
 		InitiateFileUpload:   true,
 		ListContainer:        true,
-		ListFileVersions:     true,
+		ListFileVersions:     fs.config.Versioning,
 		ListGrants:           true,
 		ListRecycle:          true,
 		Move:                 true,
 		PurgeRecycle:         true,
 		RemoveGrant:          true,
-		RestoreFileVersion:   true,
+		RestoreFileVersion:   fs.config.Versioning,
 		RestoreRecycleItem:   true,
 		Stat:                 true,
 		UpdateGrant:          true,
@@ -196,12 +260,13 @@ func getResourceType(isDir bool) provider.ResourceType {
 	if isDir {
 		return provider.ResourceType_RESOURCE_TYPE_CONTAINER
 	}
-	return provider.ResourceType_RESOURCE_TYPE_CONTAINER
+	return provider.ResourceType_RESOURCE_TYPE_FILE
 }
 
 func (fs *s3FS) normalizeHead(ctx context.Context, o *s3.HeadObjectOutput, fn string) *provider.ResourceInfo {
-	fn = fs.removeRoot(path.Join("/", fn))
+	// folders are represented by the marker objects ending in /
 	isDir := strings.HasSuffix(fn, "/")
+	fn = fs.removeRoot(path.Join("/", fn))
 	md := &provider.ResourceInfo{
 		Id:            &provider.ResourceId{OpaqueId: "fileid-" + strings.TrimPrefix(fn, "/")},
 		Path:          fn,
@@ -214,6 +279,20 @@ func (fs *s3FS) normalizeHead(ctx context.Context, o *s3.HeadObjectOutput, fn st
 			Seconds: uint64(o.LastModified.Unix()),
 		},
 	}
+	// prefer the modification time set by clients, see SetArbitraryMetadata
+	if v, ok := o.Metadata[mtimeMetadataKey]; ok && v != nil {
+		if mtime, err := utils.ParseMTime(*v); err == nil {
+			md.Mtime = &types.Timestamp{
+				Seconds: uint64(mtime.Unix()),
+				Nanos:   uint32(mtime.Nanosecond()),
+			}
+		}
+	}
+	if isDir {
+		// the content of the marker never changes, derive the etag from the
+		// modification time propagated to the folder instead
+		md.Etag = calcEtag(fn, md.Mtime)
+	}
 	appctx.GetLogger(ctx).Debug().
 		Interface("head", o).
 		Interface("metadata", md).
@@ -272,8 +351,42 @@ func (fs *s3FS) GetQuota(ctx context.Context, ref *provider.Reference) (uint64,
 	return 0, 0, nil
 }
 
+// mtimeMetadataKey is the object metadata holding the modification time set by
+// clients. It is only returned by HEAD requests, so listings still report the
+// time the object was last written.
+const mtimeMetadataKey = "Mtime"
+
+// SetArbitraryMetadata only supports setting the modification time of objects.
 func (fs *s3FS) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
-	return errtypes.NotSupported("s3: operation not supported")
+	mtime, ok := md.Metadata["mtime"]
+	if !ok || len(md.Metadata) > 1 {
+		return errtypes.NotSupported("s3: operation not supported")
+	}
+	if _, err := utils.ParseMTime(mtime); err != nil {
+		return errtypes.BadRequest("s3: invalid mtime " + mtime)
+	}
+
+	fn, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return errors.Wrap(err, "error resolving ref")
+	}
+
+	// objects are immutable, so copy the object onto itself replacing its metadata
+	_, err = fs.client.CopyObject(&s3.CopyObjectInput{
+		Bucket:            aws.String(fs.config.Bucket),
+		CopySource:        aws.String(fs.copySource(fn, "")),
+		Key:               aws.String(fn),
+		Metadata:          map[string]*string{mtimeMetadataKey: aws.String(mtime)},
+		MetadataDirective: aws.String(s3.MetadataDirectiveReplace),
+	})
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchBucket, s3.ErrCodeNoSuchKey:
+			return errtypes.NotFound(fn)
+		}
+		return err
+	}
+	return nil
 }
 
 func (fs *s3FS) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
@@ -318,16 +431,15 @@ func (fs *s3FS) CreateDir(ctx context.Context, ref *provider.Reference) error {
 
 	fn, err := fs.resolve(ctx, ref)
 	if err != nil {
-		return nil
+		return errors.Wrap(err, "error resolving ref")
 	}
 
-	fn = fs.addRoot(fn) + "/" // append / to indicate folder // TODO only if fn does not end in /
-
 	input := &s3.PutObjectInput{
 		Bucket:        aws.String(fs.config.Bucket),
-		Key:           aws.String(fn),
+		Key:           aws.String(markerKey(fn)),
 		ContentType:   aws.String("application/octet-stream"),
 		ContentLength: aws.Int64(0),
+		Metadata:      map[string]*string{mtimeMetadataKey: aws.String(formatMTime(time.Now()))},
 	}
 
 	result, err := fs.client.PutObject(input)
@@ -342,7 +454,8 @@ func (fs *s3FS) CreateDir(ctx context.Context, ref *provider.Reference) error {
 		return errors.Wrap(err, "s3fs: error creating dir "+ref.Path)
 	}
 
-	log.Debug().Interface("result", result) // todo cache etag?
+	log.Debug().Interface("result", result)
+	fs.propagate(ctx, fn)
 	return nil
 }
 
@@ -384,6 +497,7 @@ func (fs *s3FS) Delete(ctx context.Context, ref *provider.Reference) error {
 			return err
 		}
 		// ok, we are done
+		fs.propagate(ctx, fn)
 		return nil
 	}
 
@@ -405,6 +519,7 @@ func (fs *s3FS) Delete(ctx context.Context, ref *provider.Reference) error {
 	}
 
 	log.Debug().Interface("result", result)
+	fs.propagate(ctx, fn)
 	return nil
 }
 
@@ -414,25 +529,11 @@ func (fs *s3FS) CreateStorageSpace(ctx context.Context, req *provider.CreateStor
 }
 
 func (fs *s3FS) moveObject(ctx context.Context, oldKey string, newKey string) error {
-
-	// Copy
-	// TODO double check CopyObject can deal with >5GB files.
-	// Docs say we need to use multipart upload: https://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectCOPY.html
-	_, err := fs.client.CopyObject(&s3.CopyObjectInput{
-		Bucket:     aws.String(fs.config.Bucket),
-		CopySource: aws.String("/" + fs.config.Bucket + oldKey),
-		Key:        aws.String(newKey),
-	})
-	if aerr, ok := err.(awserr.Error); ok {
-		if aerr.Code() == s3.ErrCodeNoSuchBucket {
-			return errtypes.NotFound(oldKey)
-		}
+	if err := fs.copyObject(ctx, oldKey, "", newKey); err != nil {
 		return err
 	}
-	// TODO cache etag and mtime?
 
-	// Delete
-	_, err = fs.client.DeleteObject(&s3.DeleteObjectInput{
+	_, err := fs.client.DeleteObject(&s3.DeleteObjectInput{
 		Bucket: aws.String(fs.config.Bucket),
 		Key:    aws.String(oldKey),
 	})
@@ -476,35 +577,13 @@ func (fs *s3FS) Move(ctx context.Context, oldRef, newRef *provider.Reference) er
 			}
 		}
 
-		// move directory
-		input := &s3.ListObjectsV2Input{
-			Bucket: aws.String(fs.config.Bucket),
-			Prefix: aws.String(fn + "/"),
-		}
-		isTruncated := true
-
-		for isTruncated {
-			output, err := fs.client.ListObjectsV2(input)
-			if err != nil {
-				return errors.Wrap(err, "s3FS: error listing "+fn)
-			}
-
-			for _, o := range output.Contents {
-				log.Debug().
-					Interface("object", *o).
-					Str("fn", fn).
-					Msg("found Object")
-
-				err := fs.moveObject(ctx, *o.Key, strings.Replace(*o.Key, fn+"/", newName+"/", 1))
-				if err != nil {
-					return err
-				}
-			}
-
-			input.ContinuationToken = output.NextContinuationToken
-			isTruncated = *output.IsTruncated
+		// move directory, through the journal so that the move can be
+		// recovered if it is interrupted
+		if err := fs.journal.Move(ctx, fn, newName); err != nil {
+			return err
 		}
-		// ok, we are done
+		fs.propagate(ctx, fn)
+		fs.propagate(ctx, newName)
 		return nil
 	}
 
@@ -513,6 +592,8 @@ func (fs *s3FS) Move(ctx context.Context, oldRef, newRef *provider.Reference) er
 	if err != nil {
 		return err
 	}
+	fs.propagate(ctx, fn)
+	fs.propagate(ctx, newName)
 	return nil
 }
 
@@ -543,6 +624,17 @@ func (fs *s3FS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []str
 				return nil, errtypes.NotFound(fn)
 			}
 		}
+
+		// then the marker of the folder
+		marker, err := fs.client.HeadObject(&s3.HeadObjectInput{
+			Bucket: aws.String(fs.config.Bucket),
+			Key:    aws.String(markerKey(fn)),
+		})
+		if err == nil {
+			return fs.normalizeHead(ctx, marker, markerKey(fn)), nil
+		}
+
+		// folders implied by the keys of their content have no marker
 		log.Debug().
 			Str("fn", fn).
 			Msg("trying to list prefix")
@@ -601,10 +693,14 @@ func (fs *s3FS) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys
 		}
 
 		for i := range output.CommonPrefixes {
-			finfos = append(finfos, fs.normalizeCommonPrefix(ctx, output.CommonPrefixes[i]))
+			finfos = append(finfos, fs.normalizePrefix(ctx, output.CommonPrefixes[i]))
 		}
 
 		for i := range output.Contents {
+			if *output.Contents[i].Key == *input.Prefix {
+				// the marker of the folder itself
+				continue
+			}
 			finfos = append(finfos, fs.normalizeObject(ctx, output.Contents[i], *output.Contents[i].Key))
 		}
 
@@ -615,36 +711,6 @@ func (fs *s3FS) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys
 	return finfos, nil
 }
 
-func (fs *s3FS) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
-	log := appctx.GetLogger(ctx)
-
-	fn, err := fs.resolve(ctx, ref)
-	if err != nil {
-		return errors.Wrap(err, "error resolving ref")
-	}
-
-	upParams := &s3manager.UploadInput{
-		Bucket: aws.String(fs.config.Bucket),
-		Key:    aws.String(fn),
-		Body:   r,
-	}
-	uploader := s3manager.NewUploaderWithClient(fs.client)
-	result, err := uploader.Upload(upParams)
-
-	if err != nil {
-		log.Error().Err(err)
-		if aerr, ok := err.(awserr.Error); ok {
-			if aerr.Code() == s3.ErrCodeNoSuchBucket {
-				return errtypes.NotFound(fn)
-			}
-		}
-		return errors.Wrap(err, "s3fs: error creating object "+fn)
-	}
-
-	log.Debug().Interface("result", result) // todo cache etag?
-	return nil
-}
-
 func (fs *s3FS) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
 	log := appctx.GetLogger(ctx)
 
@@ -674,18 +740,6 @@ func (fs *s3FS) Download(ctx context.Context, ref *provider.Reference) (io.ReadC
 	return r.Body, nil
 }
 
-func (fs *s3FS) ListRevisions(ctx context.Context, ref *provider.Reference) ([]*provider.FileVersion, error) {
-	return nil, errtypes.NotSupported("list revisions")
-}
-
-func (fs *s3FS) DownloadRevision(ctx context.Context, ref *provider.Reference, revisionKey string) (io.ReadCloser, error) {
-	return nil, errtypes.NotSupported("download revision")
-}
-
-func (fs *s3FS) RestoreRevision(ctx context.Context, ref *provider.Reference, revisionKey string) error {
-	return errtypes.NotSupported("restore revision")
-}
-
 func (fs *s3FS) PurgeRecycleItem(ctx context.Context, kbasePath, key, relativePath string) error {
 	return errtypes.NotSupported("purge recycle item")
 }
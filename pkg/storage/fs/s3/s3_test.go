@@ -0,0 +1,169 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package s3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+// fakeS3 serves the few requests of the S3 API used by the tests from memory.
+type fakeS3 struct {
+	mutex sync.Mutex
+	// objects maps the keys to the contents; versions are stored as key@versionId.
+	objects map[string]string
+	// versions is returned when the versions of the objects are listed.
+	versions string
+	// puts records the keys written, along with their modification time metadata.
+	puts [][2]string
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{objects: map[string]string{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	key := strings.TrimLeft(strings.TrimPrefix(r.URL.Path, "/bucket"), "/")
+	query := r.URL.Query()
+	switch {
+	case key == "" && query.Get("list-type") == "2":
+		f.list(w, strings.TrimLeft(query.Get("prefix"), "/"))
+	case key == "" && query["versions"] != nil:
+		fmt.Fprint(w, f.versions)
+	case r.Method == http.MethodPut:
+		body, _ := ioutil.ReadAll(r.Body)
+		f.objects[key] = string(body)
+		f.puts = append(f.puts, [2]string{key, r.Header.Get("X-Amz-Meta-Mtime")})
+	case r.Method == http.MethodGet:
+		if v := query.Get("versionId"); v != "" {
+			key += "@" + v
+		}
+		data, ok := f.objects[key]
+		if !ok {
+			code := "NoSuchKey"
+			if query.Get("versionId") != "" {
+				code = "NoSuchVersion"
+			}
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "<Error><Code>%s</Code><Message>not found</Message></Error>", code)
+			return
+		}
+		fmt.Fprint(w, data)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix string) {
+	type content struct {
+		Key string
+	}
+	result := struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		Name        string
+		KeyCount    int
+		IsTruncated bool
+		Contents    []content
+	}{Name: "bucket"}
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) && !strings.Contains(key, "@") {
+			result.Contents = append(result.Contents, content{Key: key})
+		}
+	}
+	sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+	result.KeyCount = len(result.Contents)
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+func newTestFS(t *testing.T, srv *httptest.Server, conf map[string]interface{}) *s3FS {
+	t.Helper()
+
+	m := map[string]interface{}{
+		"endpoint":     srv.URL,
+		"bucket":       "bucket",
+		"prefix":       "/data",
+		"access_key":   "key",
+		"secret_key":   "secret",
+		"move_journal": map[string]interface{}{"on_access": "none"},
+	}
+	for k, v := range conf {
+		m[k] = v
+	}
+	fs, err := New(m)
+	if err != nil {
+		t.Fatalf("unable to create the fs: %v", err)
+	}
+	return fs.(*s3FS)
+}
+
+func TestParseConfig(t *testing.T) {
+	c, err := parseConfig(map[string]interface{}{"bucket": "bucket"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.JournalPrefix != ".reva-move-journal" || c.UploadPrefix != ".reva-uploads" || c.PartSize != defaultPartSize {
+		t.Errorf("expected the defaults, got %+v", c)
+	}
+	if _, err := parseConfig(map[string]interface{}{"part_size": minPartSize - 1}); err == nil {
+		t.Error("expected parts smaller than the minimum size to be rejected")
+	}
+}
+
+func TestCopySource(t *testing.T) {
+	fs := &s3FS{config: &config{Bucket: "bucket"}}
+	tests := []struct {
+		key, versionID, expected string
+	}{
+		{"a/b.txt", "", "bucket/a/b.txt"},
+		{"a/c+d & e.txt", "", "bucket/a/c%2Bd%20%26%20e.txt"},
+		{"a/ü?.txt", "v1+2", "bucket/a/%C3%BC%3F.txt?versionId=v1%2B2"},
+	}
+	for _, tt := range tests {
+		if s := fs.copySource(tt.key, tt.versionID); s != tt.expected {
+			t.Errorf("copySource(%q, %q) = %q, expected %q", tt.key, tt.versionID, s, tt.expected)
+		}
+	}
+}
+
+func TestFormatMTime(t *testing.T) {
+	mtime := time.Unix(123, 5)
+	parsed, err := utils.ParseMTime(formatMTime(mtime))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.Equal(mtime) {
+		t.Errorf("expected %v, got %v", mtime, parsed)
+	}
+}
@@ -19,13 +19,601 @@
 package s3
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"strings"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/mime"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	tusd "github.com/tus/tusd/pkg/handler"
 )
 
+const (
+	// minPartSize is the minimum size of the parts of a multipart upload,
+	// except for the last one.
+	minPartSize = 5 * 1024 * 1024
+	// maxParts is the maximum number of parts of a multipart upload.
+	maxParts = 10000
+	// maxCopySize is the size of the largest object a single request can
+	// copy, bigger ones are copied part by part.
+	maxCopySize = 5 * 1024 * 1024 * 1024
+
+	defaultPartSize = 16 * 1024 * 1024
+)
+
+// The uploads are multipart uploads of the destination object, so that the
+// object is replaced atomically once all the parts have been uploaded. Their
+// state is kept in the bucket below the upload prefix, so that any instance
+// can continue them:
+// - <id>.info holds the tusd.FileInfo of the upload
+// - <id>.part holds the end of the data written so far, which is smaller than
+//   the minimum size of a part and gets prepended to the next chunk
+// The uploaded parts are listed from the multipart upload itself.
+
+func (fs *s3FS) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	upload, err := fs.GetUpload(ctx, ref.GetPath())
+	if err != nil {
+		return errors.Wrap(err, "s3fs: error retrieving upload")
+	}
+
+	uploadInfo := upload.(*fileUpload)
+
+	if _, err := uploadInfo.WriteChunk(ctx, 0, r); err != nil {
+		return errors.Wrap(err, "s3fs: error writing the parts of the upload")
+	}
+
+	return uploadInfo.FinishUpload(ctx)
+}
+
 // InitiateUpload returns upload ids corresponding to different protocols it supports
 func (fs *s3FS) InitiateUpload(ctx context.Context, ref *provider.Reference, uploadLength int64, metadata map[string]string) (map[string]string, error) {
-	return nil, errtypes.NotSupported("op not supported")
+	fn, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return nil, errors.Wrap(err, "s3fs: error resolving reference")
+	}
+	np := fs.removeRoot(fn)
+
+	info := tusd.FileInfo{
+		MetaData: tusd.MetaData{
+			"filename": path.Base(np),
+			"dir":      path.Dir(np),
+		},
+		Size: uploadLength,
+	}
+
+	if metadata != nil {
+		if metadata["mtime"] != "" {
+			info.MetaData["mtime"] = metadata["mtime"]
+		}
+		if _, ok := metadata["sizedeferred"]; ok {
+			info.SizeIsDeferred = true
+		}
+	}
+
+	upload, err := fs.NewUpload(ctx, info)
+	if err != nil {
+		return nil, err
+	}
+
+	info, _ = upload.GetInfo(ctx)
+
+	return map[string]string{
+		"simple": info.ID,
+		"tus":    info.ID,
+	}, nil
+}
+
+// UseIn tells the tus upload middleware which extensions it supports.
+func (fs *s3FS) UseIn(composer *tusd.StoreComposer) {
+	composer.UseCore(fs)
+	composer.UseTerminater(fs)
+	composer.UseLengthDeferrer(fs)
+}
+
+// NewUpload starts the multipart upload of the object at the dir and filename
+// given in the metadata of the upload.
+func (fs *s3FS) NewUpload(ctx context.Context, info tusd.FileInfo) (tusd.Upload, error) {
+	log := appctx.GetLogger(ctx)
+	log.Debug().Interface("info", info).Msg("s3fs: NewUpload")
+
+	if info.MetaData["filename"] == "" {
+		return nil, errors.New("s3fs: missing filename in metadata")
+	}
+	if info.MetaData["dir"] == "" {
+		return nil, errors.New("s3fs: missing dir in metadata")
+	}
+	key := fs.addRoot(path.Join("/", info.MetaData["dir"], info.MetaData["filename"]))
+
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(fs.config.Bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(mime.Detect(false, key)),
+	}
+	if mtime := info.MetaData["mtime"]; mtime != "" {
+		if _, err := utils.ParseMTime(mtime); err != nil {
+			return nil, errtypes.BadRequest("s3fs: invalid mtime " + mtime)
+		}
+		// the object keeps the modification time of the client
+		input.Metadata = map[string]*string{mtimeMetadataKey: aws.String(mtime)}
+	}
+	out, err := fs.client.CreateMultipartUploadWithContext(ctx, input)
+	if err != nil {
+		return nil, errors.Wrap(err, "s3fs: error creating multipart upload for "+key)
+	}
+
+	info.ID = uuid.New().String()
+	info.Storage = map[string]string{
+		"Type":              "S3Store",
+		"Bucket":            fs.config.Bucket,
+		"Key":               key,
+		"MultipartUploadID": aws.StringValue(out.UploadId),
+	}
+
+	upload := &fileUpload{
+		info: info,
+		fs:   fs,
+	}
+	if err := upload.writeInfo(ctx); err != nil {
+		_ = upload.abort(ctx)
+		return nil, err
+	}
+	return upload, nil
+}
+
+func (fs *s3FS) uploadKey(id, ext string) string {
+	return path.Join(fs.config.UploadPrefix, path.Join("/", id)) + ext
+}
+
+// GetUpload returns the Upload for the given upload id
+func (fs *s3FS) GetUpload(ctx context.Context, id string) (tusd.Upload, error) {
+	out, err := fs.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(fs.config.Bucket),
+		Key:    aws.String(fs.uploadKey(id, ".info")),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, tusd.ErrNotFound
+		}
+		return nil, errors.Wrap(err, "s3fs: error reading upload "+id)
+	}
+	defer out.Body.Close()
+
+	upload := &fileUpload{fs: fs}
+	if err := json.NewDecoder(out.Body).Decode(&upload.info); err != nil {
+		return nil, errors.Wrap(err, "s3fs: error decoding upload "+id)
+	}
+
+	// the offset is the size of the parts uploaded so far and of the
+	// incomplete part
+	err = fs.client.ListPartsPagesWithContext(ctx, &s3.ListPartsInput{
+		Bucket:   aws.String(fs.config.Bucket),
+		Key:      aws.String(upload.info.Storage["Key"]),
+		UploadId: aws.String(upload.info.Storage["MultipartUploadID"]),
+	}, func(out *s3.ListPartsOutput, last bool) bool {
+		upload.parts = append(upload.parts, out.Parts...)
+		return true
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, tusd.ErrNotFound
+		}
+		return nil, errors.Wrap(err, "s3fs: error listing the parts of upload "+id)
+	}
+	upload.info.Offset = 0
+	for _, p := range upload.parts {
+		upload.info.Offset += aws.Int64Value(p.Size)
+	}
+
+	head, err := fs.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.config.Bucket),
+		Key:    aws.String(fs.uploadKey(id, ".part")),
+	})
+	switch {
+	case err == nil:
+		upload.info.Offset += aws.Int64Value(head.ContentLength)
+	case !isNotFound(err):
+		return nil, errors.Wrap(err, "s3fs: error reading the incomplete part of upload "+id)
+	}
+
+	return upload, nil
+}
+
+type fileUpload struct {
+	// info stores the current information about the upload
+	info tusd.FileInfo
+	// parts are the parts uploaded so far, ordered by number
+	parts []*s3.Part
+	fs    *s3FS
+}
+
+// GetInfo returns the FileInfo
+func (upload *fileUpload) GetInfo(ctx context.Context) (tusd.FileInfo, error) {
+	return upload.info, nil
+}
+
+// GetReader returns an io.Reader for the upload. The parts of an ongoing
+// multipart upload cannot be read, so only finished uploads can be.
+func (upload *fileUpload) GetReader(ctx context.Context) (io.Reader, error) {
+	if upload.info.SizeIsDeferred || upload.info.Offset != upload.info.Size {
+		return nil, errors.New("s3fs: cannot read an unfinished upload")
+	}
+	out, err := upload.fs.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(upload.fs.config.Bucket),
+		Key:    aws.String(upload.info.Storage["Key"]),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "s3fs: error reading "+upload.info.Storage["Key"])
+	}
+	return out.Body, nil
+}
+
+// WriteChunk uploads the stream from the reader as parts of the multipart
+// upload. The data which does not fill a part is kept aside until the next
+// chunk, unless it is the end of the upload.
+func (upload *fileUpload) WriteChunk(ctx context.Context, offset int64, src io.Reader) (int64, error) {
+	incomplete, err := upload.readIncompletePart(ctx)
+	if err != nil {
+		return 0, err
+	}
+	r := io.MultiReader(bytes.NewReader(incomplete), src)
+
+	// the incomplete part is accounted in the offset already
+	written := -int64(len(incomplete))
+	stored := func() int64 {
+		if written < 0 {
+			return 0
+		}
+		return written
+	}
+
+	for {
+		buf := &bytes.Buffer{}
+		n, err := io.CopyN(buf, r, upload.fs.config.PartSize)
+		// If the HTTP PATCH request gets interrupted in the middle (e.g. because
+		// the user wants to pause the upload), Go's net/http returns an io.ErrUnexpectedEOF.
+		// The data read so far is stored all the same.
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return stored(), err
+		}
+		if n == 0 {
+			break
+		}
+		last := n < upload.fs.config.PartSize
+
+		if last && !upload.completes(written+n) {
+			// only the last part may be smaller than the minimum size
+			if err := upload.writeIncompletePart(ctx, buf.Bytes()); err != nil {
+				return stored(), err
+			}
+		} else {
+			if err := upload.uploadPart(ctx, buf.Bytes()); err != nil {
+				return stored(), err
+			}
+			if incomplete != nil {
+				if err := upload.fs.deleteKey(ctx, upload.fs.uploadKey(upload.info.ID, ".part")); err != nil {
+					return stored(), err
+				}
+				incomplete = nil
+			}
+		}
+		written += n
+
+		if last {
+			break
+		}
+	}
+
+	upload.info.Offset += written
+	return written, nil
+}
+
+// completes tells whether writing n more bytes completes the upload.
+func (upload *fileUpload) completes(n int64) bool {
+	return !upload.info.SizeIsDeferred && upload.info.Offset+n == upload.info.Size
+}
+
+func (upload *fileUpload) readIncompletePart(ctx context.Context) ([]byte, error) {
+	out, err := upload.fs.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(upload.fs.config.Bucket),
+		Key:    aws.String(upload.fs.uploadKey(upload.info.ID, ".part")),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "s3fs: error reading the incomplete part of upload "+upload.info.ID)
+	}
+	defer out.Body.Close()
+	return ioutil.ReadAll(out.Body)
+}
+
+func (upload *fileUpload) writeIncompletePart(ctx context.Context, data []byte) error {
+	_, err := upload.fs.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(upload.fs.config.Bucket),
+		Key:    aws.String(upload.fs.uploadKey(upload.info.ID, ".part")),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		return errors.Wrap(err, "s3fs: error writing the incomplete part of upload "+upload.info.ID)
+	}
+	return nil
+}
+
+func (upload *fileUpload) uploadPart(ctx context.Context, data []byte) error {
+	if len(upload.parts) == maxParts {
+		return errtypes.InsufficientStorage(fmt.Sprintf("s3fs: upload %s exceeds %d parts", upload.info.ID, maxParts))
+	}
+	number := aws.Int64(int64(len(upload.parts) + 1))
+	out, err := upload.fs.client.UploadPartWithContext(ctx, &s3.UploadPartInput{
+		Bucket:     aws.String(upload.fs.config.Bucket),
+		Key:        aws.String(upload.info.Storage["Key"]),
+		UploadId:   aws.String(upload.info.Storage["MultipartUploadID"]),
+		PartNumber: number,
+		Body:       bytes.NewReader(data),
+	})
+	if err != nil {
+		return errors.Wrap(err, "s3fs: error uploading a part of upload "+upload.info.ID)
+	}
+	upload.parts = append(upload.parts, &s3.Part{
+		PartNumber: number,
+		ETag:       out.ETag,
+		Size:       aws.Int64(int64(len(data))),
+	})
+	return nil
+}
+
+// writeInfo updates the entire information. Everything will be overwritten.
+func (upload *fileUpload) writeInfo(ctx context.Context) error {
+	data, err := json.Marshal(upload.info)
+	if err != nil {
+		return err
+	}
+	_, err = upload.fs.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(upload.fs.config.Bucket),
+		Key:    aws.String(upload.fs.uploadKey(upload.info.ID, ".info")),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		return errors.Wrap(err, "s3fs: error writing upload "+upload.info.ID)
+	}
+	return nil
+}
+
+// FinishUpload uploads the incomplete part as the last one and completes the
+// multipart upload, which replaces the object.
+func (upload *fileUpload) FinishUpload(ctx context.Context) error {
+	fs := upload.fs
+	key := upload.info.Storage["Key"]
+
+	incomplete, err := upload.readIncompletePart(ctx)
+	if err != nil {
+		return err
+	}
+	if incomplete != nil {
+		if err := upload.uploadPart(ctx, incomplete); err != nil {
+			return err
+		}
+	}
+
+	if len(upload.parts) == 0 {
+		// multipart uploads need at least one part, write empty files directly
+		if err := upload.abort(ctx); err != nil {
+			return err
+		}
+		input := &s3.PutObjectInput{
+			Bucket:        aws.String(fs.config.Bucket),
+			Key:           aws.String(key),
+			ContentType:   aws.String(mime.Detect(false, key)),
+			ContentLength: aws.Int64(0),
+		}
+		if mtime := upload.info.MetaData["mtime"]; mtime != "" {
+			input.Metadata = map[string]*string{mtimeMetadataKey: aws.String(mtime)}
+		}
+		if _, err := fs.client.PutObjectWithContext(ctx, input); err != nil {
+			return errors.Wrap(err, "s3fs: error creating object "+key)
+		}
+	} else {
+		parts := make([]*s3.CompletedPart, 0, len(upload.parts))
+		for _, p := range upload.parts {
+			parts = append(parts, &s3.CompletedPart{
+				ETag:       p.ETag,
+				PartNumber: p.PartNumber,
+			})
+		}
+		_, err := fs.client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(fs.config.Bucket),
+			Key:             aws.String(key),
+			UploadId:        aws.String(upload.info.Storage["MultipartUploadID"]),
+			MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+		})
+		if err != nil {
+			return errors.Wrap(err, "s3fs: error completing upload of "+key)
+		}
+	}
+	fs.propagate(ctx, key)
+
+	// the upload is done, failing to clean up its state is only logged
+	log := appctx.GetLogger(ctx)
+	for _, ext := range []string{".part", ".info"} {
+		if err := fs.deleteKey(ctx, fs.uploadKey(upload.info.ID, ext)); err != nil {
+			log.Error().Err(err).Interface("info", upload.info).Msg("s3fs: could not delete upload state")
+		}
+	}
+	return nil
+}
+
+// abort aborts the multipart upload, which deletes the parts uploaded so far.
+func (upload *fileUpload) abort(ctx context.Context) error {
+	_, err := upload.fs.client.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(upload.fs.config.Bucket),
+		Key:      aws.String(upload.info.Storage["Key"]),
+		UploadId: aws.String(upload.info.Storage["MultipartUploadID"]),
+	})
+	if err != nil && !isNotFound(err) {
+		return errors.Wrap(err, "s3fs: error aborting upload "+upload.info.ID)
+	}
+	return nil
+}
+
+// To implement the termination extension as specified in https://tus.io/protocols/resumable-upload.html#termination
+// - the storage needs to implement AsTerminatableUpload
+// - the upload needs to implement Terminate
+
+// AsTerminatableUpload returns a TerminatableUpload
+func (fs *s3FS) AsTerminatableUpload(upload tusd.Upload) tusd.TerminatableUpload {
+	return upload.(*fileUpload)
+}
+
+// Terminate terminates the upload
+func (upload *fileUpload) Terminate(ctx context.Context) error {
+	if err := upload.abort(ctx); err != nil {
+		return err
+	}
+	for _, ext := range []string{".part", ".info"} {
+		if err := upload.fs.deleteKey(ctx, upload.fs.uploadKey(upload.info.ID, ext)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// To implement the creation-defer-length extension as specified in https://tus.io/protocols/resumable-upload.html#creation
+// - the storage needs to implement AsLengthDeclarableUpload
+// - the upload needs to implement DeclareLength
+
+// AsLengthDeclarableUpload returns a LengthDeclarableUpload
+func (fs *s3FS) AsLengthDeclarableUpload(upload tusd.Upload) tusd.LengthDeclarableUpload {
+	return upload.(*fileUpload)
+}
+
+// DeclareLength updates the upload length information
+func (upload *fileUpload) DeclareLength(ctx context.Context, length int64) error {
+	upload.info.Size = length
+	upload.info.SizeIsDeferred = false
+	return upload.writeInfo(ctx)
+}
+
+// copySource returns the url encoded source of a copy of key. Every segment is
+// escaped, including the characters like "+" which are valid in a path but would
+// be decoded differently by S3.
+func (fs *s3FS) copySource(key, versionID string) string {
+	segments := strings.Split(fs.config.Bucket+"/"+key, "/")
+	for i, s := range segments {
+		segments[i] = strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+	}
+	source := strings.Join(segments, "/")
+	if versionID != "" {
+		source += "?versionId=" + url.QueryEscape(versionID)
+	}
+	return source
+}
+
+// copyObject copies the object src, or its version when versionID is set, to
+// dst. Objects bigger than what a single request can copy are copied part by
+// part.
+func (fs *s3FS) copyObject(ctx context.Context, src, versionID, dst string) error {
+	source := fs.copySource(src, versionID)
+	head := &s3.HeadObjectInput{
+		Bucket: aws.String(fs.config.Bucket),
+		Key:    aws.String(src),
+	}
+	if versionID != "" {
+		head.VersionId = aws.String(versionID)
+	}
+
+	o, err := fs.client.HeadObjectWithContext(ctx, head)
+	if err != nil {
+		if isNotFound(err) {
+			return errtypes.NotFound(src)
+		}
+		return errors.Wrap(err, "s3fs: error copying "+src)
+	}
+
+	size := aws.Int64Value(o.ContentLength)
+	if size <= maxCopySize {
+		_, err := fs.client.CopyObjectWithContext(ctx, &s3.CopyObjectInput{
+			Bucket:     aws.String(fs.config.Bucket),
+			CopySource: aws.String(source),
+			Key:        aws.String(dst),
+		})
+		if err != nil {
+			return errors.Wrap(err, "s3fs: error copying "+src)
+		}
+		return nil
+	}
+
+	out, err := fs.client.CreateMultipartUploadWithContext(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(fs.config.Bucket),
+		Key:         aws.String(dst),
+		ContentType: o.ContentType,
+		Metadata:    o.Metadata,
+	})
+	if err != nil {
+		return errors.Wrap(err, "s3fs: error creating multipart copy of "+src)
+	}
+	upload := &fileUpload{
+		info: tusd.FileInfo{
+			ID: "copy of " + src,
+			Storage: map[string]string{
+				"Key":               dst,
+				"MultipartUploadID": aws.StringValue(out.UploadId),
+			},
+		},
+		fs: fs,
+	}
+
+	// the parts may need to be bigger than usual to stay within the limit
+	partSize := fs.config.PartSize
+	if least := (size + maxParts - 1) / maxParts; partSize < least {
+		partSize = least
+	}
+
+	var parts []*s3.CompletedPart
+	for start, number := int64(0), int64(1); start < size; start, number = start+partSize, number+1 {
+		end := start + partSize
+		if end > size {
+			end = size
+		}
+		res, err := fs.client.UploadPartCopyWithContext(ctx, &s3.UploadPartCopyInput{
+			Bucket:          aws.String(fs.config.Bucket),
+			Key:             aws.String(dst),
+			UploadId:        out.UploadId,
+			PartNumber:      aws.Int64(number),
+			CopySource:      aws.String(source),
+			CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", start, end-1)),
+		})
+		if err != nil {
+			_ = upload.abort(ctx)
+			return errors.Wrap(err, "s3fs: error copying a part of "+src)
+		}
+		parts = append(parts, &s3.CompletedPart{
+			ETag:       res.CopyPartResult.ETag,
+			PartNumber: aws.Int64(number),
+		})
+	}
+
+	_, err = fs.client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(fs.config.Bucket),
+		Key:             aws.String(dst),
+		UploadId:        out.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		_ = upload.abort(ctx)
+		return errors.Wrap(err, "s3fs: error completing copy of "+src)
+	}
+	return nil
 }
@@ -0,0 +1,73 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import (
+	"context"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+// Quota holds the quota of a storage, i.e. of a space or of the home of a user.
+type Quota struct {
+	// Total is the number of bytes that may be used; it is 0 if the storage doesn't know its quota.
+	Total uint64
+	// Used is the number of bytes in use.
+	Used uint64
+	// Enforced is set if the storage rejects writes exceeding the quota itself, like EOS does through its quota nodes.
+	Enforced bool
+}
+
+// IsKnown reports whether the storage knows its quota.
+func (q *Quota) IsKnown() bool {
+	return q.Total > 0
+}
+
+// Free returns the number of bytes that can still be written; it is 0 if the quota has been exceeded.
+func (q *Quota) Free() uint64 {
+	if q.Used >= q.Total {
+		return 0
+	}
+	return q.Total - q.Used
+}
+
+// Allows reports whether size more bytes can be written; storages that don't know their quota allow any size.
+func (q *Quota) Allows(size uint64) bool {
+	return !q.IsKnown() || size <= q.Free()
+}
+
+// QuotaReporter is implemented by storage drivers that compute their quota themselves and enforce it on writes,
+// like EOS with its native quota or decomposedfs with the quota of its spaces.
+type QuotaReporter interface {
+	GetQuotaInfo(ctx context.Context, ref *provider.Reference) (*Quota, error)
+}
+
+// QuotaOf returns the quota of the storage holding the reference. The quota of drivers not implementing
+// QuotaReporter is taken from their GetQuota method and considered not to be enforced by them.
+func QuotaOf(ctx context.Context, fs FS, ref *provider.Reference) (*Quota, error) {
+	if r, ok := fs.(QuotaReporter); ok {
+		return r.GetQuotaInfo(ctx, ref)
+	}
+
+	total, used, err := fs.GetQuota(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &Quota{Total: total, Used: used}, nil
+}
@@ -0,0 +1,46 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import "testing"
+
+func TestQuota(t *testing.T) {
+	tests := []struct {
+		name    string
+		quota   Quota
+		size    uint64
+		free    uint64
+		allowed bool
+	}{
+		{"unknown", Quota{Used: 10}, 100, 0, true},
+		{"within", Quota{Total: 100, Used: 40}, 60, 60, true},
+		{"exceeding", Quota{Total: 100, Used: 40}, 61, 60, false},
+		{"exceeded", Quota{Total: 100, Used: 120}, 1, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if free := tt.quota.Free(); free != tt.free {
+				t.Errorf("Free() = %d, expected %d", free, tt.free)
+			}
+			if allowed := tt.quota.Allows(tt.size); allowed != tt.allowed {
+				t.Errorf("Allows(%d) = %v, expected %v", tt.size, allowed, tt.allowed)
+			}
+		})
+	}
+}
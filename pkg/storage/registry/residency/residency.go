@@ -0,0 +1,191 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package residency places the homes and spaces of the users on the mounts
+// required by data-residency rules, based on the country and the department
+// of the users. Homes created before the rules were enabled are pinned to
+// their mount in a placements file until they are migrated.
+package residency
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/affiliation"
+	"github.com/pkg/errors"
+)
+
+// reloadInterval is the minimum time between two checks for changes of the placements file.
+const reloadInterval = 10 * time.Second
+
+// Rule places the users of the given countries and departments on the given mounts.
+type Rule struct {
+	// Countries are the ISO 3166 codes of the countries of the users, empty matches every country.
+	Countries []string `mapstructure:"countries"`
+	// Departments are the departments of the users, empty matches every department.
+	Departments []string `mapstructure:"departments"`
+	// Mounts maps the mounts of the storage registry, e.g. /home, to the address of the provider to use.
+	Mounts map[string]string `mapstructure:"mounts"`
+}
+
+// Matches returns whether the rule applies to the user.
+func (r *Rule) Matches(u *userpb.User) bool {
+	return matches(r.Countries, affiliation.CountryOf(u)) && matches(r.Departments, affiliation.DepartmentOf(u))
+}
+
+func matches(allowed []string, v string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(a, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Address returns the address of the provider the first matching rule places the mount of the user on.
+func Address(rules []Rule, u *userpb.User, mount string) (string, bool) {
+	for i := range rules {
+		if addr, ok := rules[i].Mounts[mount]; ok && rules[i].Matches(u) {
+			return addr, true
+		}
+	}
+	return "", false
+}
+
+// Key returns the key of the user in the placements file.
+func Key(userID *userpb.UserId) string {
+	return userID.GetIdp() + "!" + userID.GetOpaqueId()
+}
+
+// Placements records the providers the mounts of some users are pinned to, regardless of the rules.
+// The file is reloaded when modified by the rebalance tool.
+type Placements struct {
+	file string
+
+	mu      sync.RWMutex
+	m       map[string]map[string]string // addresses by mount by user
+	modTime time.Time
+	checked time.Time
+}
+
+// NewPlacements returns the placements recorded in the given file, which may not exist yet.
+func NewPlacements(file string) (*Placements, error) {
+	p := &Placements{file: file, m: map[string]map[string]string{}}
+	if err := p.load(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Placements) load() error {
+	info, err := os.Stat(p.file)
+	if os.IsNotExist(err) {
+		p.m, p.modTime = map[string]map[string]string{}, time.Time{}
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "residency: error reading placements")
+	}
+	if info.ModTime().Equal(p.modTime) {
+		return nil
+	}
+	data, err := ioutil.ReadFile(p.file)
+	if err != nil {
+		return errors.Wrap(err, "residency: error reading placements")
+	}
+	m := map[string]map[string]string{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return errors.Wrap(err, "residency: error decoding placements")
+	}
+	p.m, p.modTime = m, info.ModTime()
+	return nil
+}
+
+// Get returns the address of the provider the mount of the user is pinned to.
+func (p *Placements) Get(userID *userpb.UserId, mount string) (string, bool) {
+	p.mu.RLock()
+	stale := time.Since(p.checked) >= reloadInterval
+	p.mu.RUnlock()
+	if stale {
+		p.mu.Lock()
+		if time.Since(p.checked) >= reloadInterval {
+			// the previous placements are kept if the file is being rewritten
+			_ = p.load()
+			p.checked = time.Now()
+		}
+		p.mu.Unlock()
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	addr, ok := p.m[Key(userID)][mount]
+	return addr, ok
+}
+
+// Set pins the mount of the user to the given provider, or unpins it if the address is empty.
+func (p *Placements) Set(userID *userpb.UserId, mount, addr string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	k := Key(userID)
+	if addr == "" {
+		delete(p.m[k], mount)
+		if len(p.m[k]) == 0 {
+			delete(p.m, k)
+		}
+		return
+	}
+	if p.m[k] == nil {
+		p.m[k] = map[string]string{}
+	}
+	p.m[k][mount] = addr
+}
+
+// Save writes the placements to the file, replacing it atomically.
+func (p *Placements) Save() error {
+	p.mu.RLock()
+	data, err := json.MarshalIndent(p.m, "", "  ")
+	p.mu.RUnlock()
+	if err != nil {
+		return errors.Wrap(err, "residency: error encoding placements")
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(p.file), ".placements-")
+	if err != nil {
+		return errors.Wrap(err, "residency: error writing placements")
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "residency: error writing placements")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "residency: error writing placements")
+	}
+	if err := os.Rename(tmp.Name(), p.file); err != nil {
+		return errors.Wrap(err, "residency: error writing placements")
+	}
+	return nil
+}
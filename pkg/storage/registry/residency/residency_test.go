@@ -0,0 +1,91 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package residency
+
+import (
+	"path/filepath"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/affiliation"
+)
+
+func TestAddress(t *testing.T) {
+	rules := []Rule{
+		{Countries: []string{"ch"}, Departments: []string{"IT"}, Mounts: map[string]string{"/home": "home-ch-it"}},
+		{Countries: []string{"CH"}, Mounts: map[string]string{"/home": "home-ch", "/users": "spaces-ch"}},
+		{Departments: []string{"HR"}, Mounts: map[string]string{"/home": "home-hr"}},
+	}
+	user := func(country, department string) *userpb.User {
+		u := &userpb.User{Id: &userpb.UserId{OpaqueId: "einstein"}}
+		affiliation.Set(u, nil, department)
+		affiliation.SetCountry(u, country)
+		return u
+	}
+
+	tests := []struct {
+		u     *userpb.User
+		mount string
+		want  string
+	}{
+		{user("CH", "IT"), "/home", "home-ch-it"},
+		{user("CH", "IT"), "/users", "spaces-ch"},
+		{user("CH", ""), "/home", "home-ch"},
+		{user("FR", "HR"), "/home", "home-hr"},
+		{user("FR", "HR"), "/users", ""},
+		{user("", ""), "/home", ""},
+	}
+	for _, tt := range tests {
+		got, ok := Address(rules, tt.u, tt.mount)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("Address(%s, %s, %s) = %s, %t, want %s", affiliation.CountryOf(tt.u), affiliation.DepartmentOf(tt.u), tt.mount, got, ok, tt.want)
+		}
+	}
+}
+
+func TestPlacements(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "placements.json")
+	einstein := &userpb.UserId{Idp: "idp", OpaqueId: "einstein"}
+
+	p, err := NewPlacements(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.Get(einstein, "/home"); ok {
+		t.Fatal("expected no placement before the file exists")
+	}
+
+	p.Set(einstein, "/home", "home-00")
+	p.Set(einstein, "/users", "spaces-00")
+	p.Set(einstein, "/users", "")
+	if err := p.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := NewPlacements(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr, ok := loaded.Get(einstein, "/home"); !ok || addr != "home-00" {
+		t.Fatalf("expected /home to be pinned to home-00, got %s", addr)
+	}
+	if _, ok := loaded.Get(einstein, "/users"); ok {
+		t.Fatal("expected /users to be unpinned")
+	}
+}
@@ -31,7 +31,9 @@ import (
 	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/registry/registry"
+	"github.com/cs3org/reva/pkg/storage/registry/residency"
 	"github.com/cs3org/reva/pkg/storage/utils/templates"
+	"github.com/cs3org/reva/pkg/tenant"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 )
@@ -46,11 +48,19 @@ type rule struct {
 	Mapping string            `mapstructure:"mapping"`
 	Address string            `mapstructure:"address"`
 	Aliases map[string]string `mapstructure:"aliases"`
+	// Tenants restricts the rule to the users of the given tenants.
+	Tenants []string `mapstructure:"tenants"`
 }
 
 type config struct {
 	Rules        map[string]rule `mapstructure:"rules"`
 	HomeProvider string          `mapstructure:"home_provider"`
+	// Residency places the mounts of the users, e.g. their homes, on the providers required by
+	// data-residency rules. The first matching rule applies.
+	Residency []residency.Rule `mapstructure:"residency"`
+	// PlacementsFile pins the mounts of the users created before the residency rules were enabled
+	// to their provider, until they are migrated by the rebalance-homes tool.
+	PlacementsFile string `mapstructure:"placements_file"`
 }
 
 func (c *config) init() {
@@ -86,14 +96,39 @@ func New(m map[string]interface{}) (storage.Registry, error) {
 		return nil, err
 	}
 	c.init()
-	return &reg{c: c}, nil
+	tenants, err := tenant.New(sharedconf.GetTenancy())
+	if err != nil {
+		return nil, err
+	}
+	r := &reg{c: c, tenants: tenants}
+	if len(c.Residency) > 0 && c.PlacementsFile != "" {
+		if r.placements, err = residency.NewPlacements(c.PlacementsFile); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
 }
 
 type reg struct {
-	c *config
+	c          *config
+	tenants    *tenant.Manager
+	placements *residency.Placements
+}
+
+// applies returns whether the rule applies to the tenant of the current user.
+func (b *reg) applies(ctx context.Context, r rule) bool {
+	if len(r.Tenants) == 0 || !b.tenants.Enabled() {
+		return true
+	}
+	u, _ := ctxpkg.ContextGetUser(ctx)
+	return contains(r.Tenants, b.tenants.Of(u))
 }
 
-func getProviderAddr(ctx context.Context, r rule) string {
+func (b *reg) getProviderAddr(ctx context.Context, r rule) string {
+	if !b.applies(ctx, r) {
+		return ""
+	}
+
 	addr := r.Address
 	if addr == "" {
 		if u, ok := ctxpkg.ContextGetUser(ctx); ok {
@@ -108,10 +143,31 @@ func getProviderAddr(ctx context.Context, r rule) string {
 	return addr
 }
 
+// getMountAddr returns the address of the provider serving the mount for the current user, which
+// is chosen by the residency rules for the mounts they cover.
+func (b *reg) getMountAddr(ctx context.Context, mount string, r rule) string {
+	if !b.applies(ctx, r) {
+		return ""
+	}
+	if len(b.c.Residency) > 0 {
+		if u, ok := ctxpkg.ContextGetUser(ctx); ok {
+			if b.placements != nil {
+				if addr, ok := b.placements.Get(u.Id, mount); ok {
+					return addr
+				}
+			}
+			if addr, ok := residency.Address(b.c.Residency, u, mount); ok {
+				return addr
+			}
+		}
+	}
+	return b.getProviderAddr(ctx, r)
+}
+
 func (b *reg) ListProviders(ctx context.Context) ([]*registrypb.ProviderInfo, error) {
 	providers := []*registrypb.ProviderInfo{}
 	for k, v := range b.c.Rules {
-		if addr := getProviderAddr(ctx, v); addr != "" {
+		if addr := b.getMountAddr(ctx, k, v); addr != "" {
 			combs := generateRegexCombinations(k)
 			for _, c := range combs {
 				providers = append(providers, &registrypb.ProviderInfo{
@@ -128,7 +184,7 @@ func (b *reg) ListProviders(ctx context.Context) ([]*registrypb.ProviderInfo, er
 func (b *reg) GetHome(ctx context.Context) (*registrypb.ProviderInfo, error) {
 	// Assume that HomeProvider is not a regexp
 	if r, ok := b.c.Rules[b.c.HomeProvider]; ok {
-		if addr := getProviderAddr(ctx, r); addr != "" {
+		if addr := b.getMountAddr(ctx, b.c.HomeProvider, r); addr != "" {
 			return &registrypb.ProviderInfo{
 				ProviderPath: b.c.HomeProvider,
 				Address:      addr,
@@ -147,7 +203,10 @@ func (b *reg) FindProviders(ctx context.Context, ref *provider.Reference) ([]*re
 	if ref.ResourceId != nil {
 		if ref.ResourceId.StorageId != "" {
 			for prefix, rule := range b.c.Rules {
-				addr := getProviderAddr(ctx, rule)
+				if !b.applies(ctx, rule) {
+					continue
+				}
+				addr := b.getProviderAddr(ctx, rule)
 				r, err := regexp.Compile("^" + prefix + "$")
 				if err != nil {
 					continue
@@ -174,7 +233,10 @@ func (b *reg) FindProviders(ctx context.Context, ref *provider.Reference) ([]*re
 	if fn != "" {
 		for prefix, rule := range b.c.Rules {
 
-			addr := getProviderAddr(ctx, rule)
+			if !b.applies(ctx, rule) {
+				continue
+			}
+			addr := b.getMountAddr(ctx, prefix, rule)
 			r, err := regexp.Compile("^" + prefix)
 			if err != nil {
 				continue
@@ -213,6 +275,15 @@ func (b *reg) FindProviders(ctx context.Context, ref *provider.Reference) ([]*re
 	return nil, errtypes.NotFound("storage provider not found for ref " + ref.String())
 }
 
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
 func generateRegexCombinations(rex string) []string {
 	m := bracketRegex.FindString(rex)
 	r := strings.Trim(strings.Trim(m, "["), "]")
@@ -24,6 +24,7 @@ import (
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	registrypb "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
+	"github.com/cs3org/reva/pkg/affiliation"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/storage/registry/static"
 
@@ -239,3 +240,52 @@ var _ = Describe("Static", func() {
 		})
 	})
 })
+
+var _ = Describe("Static with residency rules", func() {
+
+	handler, err := static.New(map[string]interface{}{
+		"home_provider": "/home",
+		"rules": map[string]interface{}{
+			"/home": map[string]interface{}{
+				"address": "home-00",
+			},
+			"/eos/project": map[string]interface{}{
+				"address": "project-00",
+			},
+		},
+		"residency": []map[string]interface{}{
+			{
+				"countries": []string{"CH"},
+				"mounts": map[string]string{
+					"/home": "home-ch",
+				},
+			},
+		},
+	})
+	Expect(err).ToNot(HaveOccurred())
+
+	swiss := &userpb.User{Id: &userpb.UserId{OpaqueId: "einstein"}}
+	affiliation.SetCountry(swiss, "ch")
+	ctxSwiss := ctxpkg.ContextSetUser(context.Background(), swiss)
+	ctxOther := ctxpkg.ContextSetUser(context.Background(), &userpb.User{Id: &userpb.UserId{OpaqueId: "marie"}})
+
+	It("places the home of the users matching a rule on its provider", func() {
+		home, err := handler.GetHome(ctxSwiss)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(home.Address).To(Equal("home-ch"))
+
+		providers, err := handler.FindProviders(ctxSwiss, &provider.Reference{Path: "/home/Documents"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(providers).To(Equal([]*registrypb.ProviderInfo{{ProviderPath: "/home", Address: "home-ch"}}))
+	})
+
+	It("keeps the default provider for the other users and mounts", func() {
+		home, err := handler.GetHome(ctxOther)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(home.Address).To(Equal("home-00"))
+
+		providers, err := handler.FindProviders(ctxSwiss, &provider.Reference{Path: "/eos/project/pqr"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(providers).To(Equal([]*registrypb.ProviderInfo{{ProviderPath: "/eos/project", Address: "project-00"}}))
+	})
+})
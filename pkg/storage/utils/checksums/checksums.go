@@ -0,0 +1,191 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package checksums computes the checksums of the files whose driver doesn't
+// store any, so that the clients can verify their transfers. As computing a
+// checksum means reading the whole file, the checksums are only computed when
+// explicitly requested and kept in memory until the file changes.
+package checksums
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/adler32"
+	"io"
+
+	"github.com/bluele/gcache"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/deletejob"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/composable"
+	"github.com/pkg/errors"
+)
+
+// MetadataKey is the metadata key requesting the checksums, as sent by the
+// WebDAV service when the oc:checksums property is requested.
+const MetadataKey = "http://owncloud.org/ns/checksums"
+
+// Config configures the checksums computed on the fly.
+type Config struct {
+	Algorithm string `mapstructure:"algorithm" docs:";Algorithm of the checksums computed for the files the driver doesn't provide one for: md5, sha1 or adler32. No checksum is computed if empty."`
+	MaxSize   uint64 `mapstructure:"max_size" docs:"104857600;Size in bytes above which no checksum is computed."`
+	CacheSize int    `mapstructure:"cache_size" docs:"10000;Number of computed checksums kept in memory."`
+}
+
+// Enabled returns whether checksums are computed.
+func (c *Config) Enabled() bool {
+	return c.Algorithm != ""
+}
+
+func (c *Config) init() {
+	if c.MaxSize == 0 {
+		c.MaxSize = 100 * 1024 * 1024
+	}
+	if c.CacheSize <= 0 {
+		c.CacheSize = 10000
+	}
+}
+
+var algorithms = map[string]struct {
+	t   provider.ResourceChecksumType
+	new func() hash.Hash
+}{
+	"md5":     {provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_MD5, md5.New},
+	"sha1":    {provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_SHA1, sha1.New},
+	"adler32": {provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_ADLER32, func() hash.Hash { return adler32.New() }},
+}
+
+type fs struct {
+	storage.FS
+	c     *Config
+	t     provider.ResourceChecksumType
+	new   func() hash.Hash
+	cache gcache.Cache
+}
+
+// New returns a storage.FS adding the checksums to the metadata of the files
+// the given one doesn't provide any for, when requested with MetadataKey.
+func New(next storage.FS, c *Config) (storage.FS, error) {
+	c.init()
+	algo, ok := algorithms[c.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("checksums: unsupported algorithm %q", c.Algorithm)
+	}
+	s := &fs{
+		FS:    next,
+		c:     c,
+		t:     algo.t,
+		new:   algo.new,
+		cache: gcache.New(c.CacheSize).LRU().Build(),
+	}
+	return composable.Wrap(s, next), nil
+}
+
+// DeleteRecursive is forwarded to the driver, which deletes the tree at once.
+func (s *fs) DeleteRecursive(ctx context.Context, ref *provider.Reference, progress func(total, deleted uint64)) error {
+	return deletejob.DeleteRecursive(ctx, s.FS, ref, progress)
+}
+
+func (s *fs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	info, err := s.FS.GetMD(ctx, ref, mdKeys)
+	if err != nil || !requested(mdKeys) {
+		return info, err
+	}
+	s.addChecksum(ctx, info)
+	return info, nil
+}
+
+func (s *fs) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	infos, err := s.FS.ListFolder(ctx, ref, mdKeys)
+	if err != nil || !requested(mdKeys) {
+		return infos, err
+	}
+	for _, info := range infos {
+		s.addChecksum(ctx, info)
+	}
+	return infos, nil
+}
+
+// addChecksum sets the checksum of the file if the driver didn't. Failures are
+// only logged, the metadata is still valid without a checksum.
+func (s *fs) addChecksum(ctx context.Context, info *provider.ResourceInfo) {
+	if info == nil || info.Type != provider.ResourceType_RESOURCE_TYPE_FILE || hasChecksum(info) || info.Size > s.c.MaxSize {
+		return
+	}
+
+	// the etag changes with the content, so the cached checksums of the previous versions aren't hit anymore
+	key := fmt.Sprintf("%s:%s:%s", info.GetId().GetStorageId(), info.GetId().GetOpaqueId(), info.Etag)
+	if info.GetId().GetOpaqueId() == "" {
+		key = fmt.Sprintf("%s:%s", info.Path, info.Etag)
+	}
+	if sum, err := s.cache.Get(key); err == nil {
+		info.Checksum = &provider.ResourceChecksum{Type: s.t, Sum: sum.(string)}
+		return
+	}
+
+	sum, err := s.compute(ctx, info)
+	if err != nil {
+		appctx.GetLogger(ctx).Warn().Err(err).Str("path", info.Path).Msg("checksums: error computing the checksum")
+		return
+	}
+	_ = s.cache.Set(key, sum)
+	info.Checksum = &provider.ResourceChecksum{Type: s.t, Sum: sum}
+}
+
+func (s *fs) compute(ctx context.Context, info *provider.ResourceInfo) (string, error) {
+	ref := &provider.Reference{Path: info.Path}
+	if info.Path == "" {
+		ref = &provider.Reference{ResourceId: info.Id}
+	}
+	r, err := s.FS.Download(ctx, ref)
+	if err != nil {
+		return "", errors.Wrap(err, "checksums: error downloading the file")
+	}
+	defer r.Close()
+
+	h := s.new()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", errors.Wrap(err, "checksums: error reading the file")
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func requested(mdKeys []string) bool {
+	for _, k := range mdKeys {
+		if k == MetadataKey {
+			return true
+		}
+	}
+	return false
+}
+
+func hasChecksum(info *provider.ResourceInfo) bool {
+	if info.Checksum == nil || info.Checksum.Sum == "" {
+		return false
+	}
+	switch info.Checksum.Type {
+	case provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_INVALID, provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_UNSET:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package checksums
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+type testFS struct {
+	storage.FS
+	info      *provider.ResourceInfo
+	content   []byte
+	downloads int
+}
+
+func (t *testFS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	info := *t.info
+	return &info, nil
+}
+
+func (t *testFS) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	t.downloads++
+	return ioutil.NopCloser(bytes.NewReader(t.content)), nil
+}
+
+func newTestFS(t *testing.T, c *Config) (*testFS, storage.FS) {
+	next := &testFS{
+		info: &provider.ResourceInfo{
+			Type: provider.ResourceType_RESOURCE_TYPE_FILE,
+			Id:   &provider.ResourceId{StorageId: "storage", OpaqueId: "file"},
+			Path: "/file.txt",
+			Etag: "1",
+			Size: 5,
+		},
+		content: []byte("hello"),
+	}
+	fs, err := New(next, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return next, fs
+}
+
+func TestChecksumComputed(t *testing.T) {
+	next, fs := newTestFS(t, &Config{Algorithm: "adler32"})
+	ref := &provider.Reference{Path: "/file.txt"}
+
+	info, err := fs.GetMD(context.Background(), ref, []string{MetadataKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Checksum == nil || info.Checksum.Type != provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_ADLER32 || info.Checksum.Sum != "062c0215" {
+		t.Fatalf("unexpected checksum %v", info.Checksum)
+	}
+
+	if _, err := fs.GetMD(context.Background(), ref, []string{MetadataKey}); err != nil {
+		t.Fatal(err)
+	}
+	if next.downloads != 1 {
+		t.Fatalf("expected the checksum to be cached, got %d downloads", next.downloads)
+	}
+
+	next.info.Etag = "2"
+	if _, err := fs.GetMD(context.Background(), ref, []string{MetadataKey}); err != nil {
+		t.Fatal(err)
+	}
+	if next.downloads != 2 {
+		t.Fatalf("expected the checksum to be computed again for the new version, got %d downloads", next.downloads)
+	}
+}
+
+func TestChecksumSkipped(t *testing.T) {
+	ref := &provider.Reference{Path: "/file.txt"}
+
+	next, fs := newTestFS(t, &Config{Algorithm: "md5"})
+	info, err := fs.GetMD(context.Background(), ref, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Checksum != nil || next.downloads != 0 {
+		t.Fatal("expected no checksum when not requested")
+	}
+
+	next.info.Checksum = &provider.ResourceChecksum{Type: provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_ADLER32, Sum: "abc"}
+	info, err = fs.GetMD(context.Background(), ref, []string{MetadataKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Checksum.Sum != "abc" || next.downloads != 0 {
+		t.Fatal("expected the checksum of the driver to be kept")
+	}
+
+	next, fs = newTestFS(t, &Config{Algorithm: "md5", MaxSize: 4})
+	info, err = fs.GetMD(context.Background(), ref, []string{MetadataKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Checksum != nil || next.downloads != 0 {
+		t.Fatal("expected no checksum for files larger than the maximum size")
+	}
+}
+
+func TestUnsupportedAlgorithm(t *testing.T) {
+	if _, err := New(&testFS{}, &Config{Algorithm: "crc32"}); err == nil {
+		t.Fatal("expected an error for an unsupported algorithm")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package composable keeps the support of the tus protocol of the drivers
+// decorated by the storage wrappers. The data provider only serves tus
+// uploads for the drivers with a UseIn method, which the wrappers would
+// otherwise hide.
+package composable
+
+import (
+	"github.com/cs3org/reva/pkg/deletejob"
+	"github.com/cs3org/reva/pkg/storage"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+// Wrapper is a storage wrapper, which forwards the recursive deletions to the
+// driver it wraps.
+type Wrapper interface {
+	storage.FS
+	deletejob.RecursiveDeleter
+}
+
+type composer interface {
+	UseIn(composer *tusd.StoreComposer)
+}
+
+type fs struct {
+	Wrapper
+	next  composer
+	store func(composer *tusd.StoreComposer)
+}
+
+// UseIn lets the wrapped driver set up the tus data store, and then the
+// wrapper decorate it.
+func (f *fs) UseIn(composer *tusd.StoreComposer) {
+	f.next.UseIn(composer)
+	if f.store != nil {
+		f.store(composer)
+	}
+}
+
+// reportingFS keeps the quota reported by the wrapper.
+type reportingFS struct {
+	*fs
+	storage.QuotaReporter
+}
+
+// Wrap returns the wrapper of next, which supports the tus protocol if next
+// does.
+func Wrap(wrapper Wrapper, next storage.FS) storage.FS {
+	return WrapStore(wrapper, next, nil)
+}
+
+// WrapStore is like Wrap for wrappers decorating the tus data store set up by
+// next, e.g. to check the uploads before they are finished.
+func WrapStore(wrapper Wrapper, next storage.FS, store func(composer *tusd.StoreComposer)) storage.FS {
+	n, ok := next.(composer)
+	if !ok {
+		return wrapper
+	}
+	f := &fs{Wrapper: wrapper, next: n, store: store}
+	if r, ok := wrapper.(storage.QuotaReporter); ok {
+		return reportingFS{fs: f, QuotaReporter: r}
+	}
+	return f
+}
@@ -0,0 +1,99 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package composable
+
+import (
+	"context"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/storage"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+type driver struct {
+	storage.FS
+	used bool
+}
+
+type tusDriver struct {
+	driver
+}
+
+func (d *tusDriver) UseIn(composer *tusd.StoreComposer) {
+	d.used = true
+}
+
+type wrapper struct {
+	storage.FS
+}
+
+func (w *wrapper) DeleteRecursive(ctx context.Context, ref *provider.Reference, progress func(total, deleted uint64)) error {
+	return nil
+}
+
+type reportingWrapper struct {
+	wrapper
+}
+
+func (w *reportingWrapper) GetQuotaInfo(ctx context.Context, ref *provider.Reference) (*storage.Quota, error) {
+	return &storage.Quota{Total: 1}, nil
+}
+
+func TestWrapWithoutTus(t *testing.T) {
+	next := &driver{}
+	w := &wrapper{FS: next}
+	if fs := Wrap(w, next); fs != w {
+		t.Errorf("expected the wrapper of a driver without tus support to be returned as is, got %T", fs)
+	}
+}
+
+func TestWrapStore(t *testing.T) {
+	next := &tusDriver{}
+	stored := false
+	fs := WrapStore(&wrapper{FS: next}, next, func(composer *tusd.StoreComposer) {
+		if !next.used {
+			t.Error("expected the driver to set up the data store first")
+		}
+		stored = true
+	})
+	c, ok := fs.(interface{ UseIn(*tusd.StoreComposer) })
+	if !ok {
+		t.Fatal("expected the wrapper to support the tus protocol")
+	}
+	c.UseIn(tusd.NewStoreComposer())
+	if !next.used || !stored {
+		t.Error("expected both the driver and the wrapper to set up the data store")
+	}
+	if _, ok := fs.(storage.QuotaReporter); ok {
+		t.Error("expected the quota not to be reported by a wrapper without GetQuotaInfo")
+	}
+}
+
+func TestWrapKeepsQuotaReporter(t *testing.T) {
+	next := &tusDriver{}
+	fs := Wrap(&reportingWrapper{wrapper{FS: next}}, next)
+	r, ok := fs.(storage.QuotaReporter)
+	if !ok {
+		t.Fatal("expected the quota reported by the wrapper to be kept")
+	}
+	if q, _ := r.GetQuotaInfo(context.Background(), nil); q.Total != 1 {
+		t.Errorf("expected the quota of the wrapper, got %+v", q)
+	}
+}
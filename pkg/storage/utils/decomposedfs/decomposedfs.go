@@ -32,12 +32,17 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/asim/go-micro/plugins/events/nats/v4"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/events/server"
+	"github.com/cs3org/reva/pkg/kvcache"
 	"github.com/cs3org/reva/pkg/logger"
 	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/cs3org/reva/pkg/storage"
@@ -87,6 +92,8 @@ type Decomposedfs struct {
 	o            *options.Options
 	p            PermissionsChecker
 	chunkHandler *chunking.ChunkHandler
+	usageCache   *kvcache.Cache
+	publisher    events.Publisher
 }
 
 // NewDefault returns an instance with default components
@@ -126,13 +133,24 @@ func New(o *options.Options, lu *Lookup, p PermissionsChecker, tp Tree) (storage
 		return nil, errors.Wrap(err, "could not setup tree")
 	}
 
-	return &Decomposedfs{
+	fs := &Decomposedfs{
 		tp:           tp,
 		lu:           lu,
 		o:            o,
 		p:            p,
 		chunkHandler: chunking.NewChunkHandler(filepath.Join(o.Root, "uploads")),
-	}, nil
+		usageCache:   kvcache.Namespace("decomposedfs_usage", time.Duration(o.QuotaPolicy.UsageCacheTTL)*time.Second),
+	}
+
+	if o.SoftQuota.NatsAddress != "" {
+		stream, err := server.NewNatsStream(nats.Address(o.SoftQuota.NatsAddress), nats.ClusterID(o.SoftQuota.NatsClusterID))
+		if err != nil {
+			return nil, errors.Wrap(err, "error connecting to the event stream")
+		}
+		fs.publisher = stream
+	}
+
+	return fs, nil
 }
 
 // Shutdown shuts down the storage
@@ -195,7 +213,30 @@ func (fs *Decomposedfs) GetQuota(ctx context.Context, ref *provider.Reference) (
 		}
 	}
 
-	return total, ri.Size, nil
+	inUse = ri.Size
+	if fs.o.QuotaPolicy.Enabled() {
+		// trashed items and old versions may count towards the quota
+		spaceRoot := n.SpaceRoot
+		if spaceRoot == nil {
+			spaceRoot = n
+		}
+		if u, err := fs.spaceUsage(ctx, spaceRoot); err == nil {
+			inUse += u.inQuota(&fs.o.QuotaPolicy)
+		} else {
+			appctx.GetLogger(ctx).Error().Err(err).Str("node", n.ID).Msg("could not compute the usage of trash and versions")
+		}
+	}
+
+	return total, inUse, nil
+}
+
+// GetQuotaInfo returns the quota of the space holding the reference, which is checked when uploads are initiated and finished.
+func (fs *Decomposedfs) GetQuotaInfo(ctx context.Context, ref *provider.Reference) (*storage.Quota, error) {
+	total, used, err := fs.GetQuota(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &storage.Quota{Total: total, Used: used, Enforced: true}, nil
 }
 
 // CreateHome creates a new home node for the given user
@@ -412,25 +453,36 @@ func (fs *Decomposedfs) Move(ctx context.Context, oldRef, newRef *provider.Refer
 
 // GetMD returns the metadata for the specified resource
 func (fs *Decomposedfs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (ri *provider.ResourceInfo, err error) {
-	var node *node.Node
-	if node, err = fs.lu.NodeFromResource(ctx, ref); err != nil {
+	var n *node.Node
+	if n, err = fs.lu.NodeFromResource(ctx, ref); err != nil {
 		return
 	}
 
-	if !node.Exists {
-		err = errtypes.NotFound(filepath.Join(node.ParentID, node.Name))
+	if !n.Exists {
+		err = errtypes.NotFound(filepath.Join(n.ParentID, n.Name))
 		return
 	}
 
-	rp, err := fs.p.AssemblePermissions(ctx, node)
+	rp, err := fs.p.AssemblePermissions(ctx, n)
 	switch {
 	case err != nil:
 		return nil, errtypes.InternalError(err.Error())
 	case !rp.Stat:
-		return nil, errtypes.PermissionDenied(node.ID)
+		return nil, errtypes.PermissionDenied(n.ID)
 	}
 
-	return node.AsResourceInfo(ctx, &rp, mdKeys, utils.IsRelativeReference(ref))
+	ri, err = n.AsResourceInfo(ctx, &rp, mdKeys, utils.IsRelativeReference(ref))
+	if err != nil {
+		return nil, err
+	}
+
+	if fs.o.QuotaPolicy.Enabled() && includesKey(mdKeys, node.QuotaKey) && node.IsSpaceRoot(n) {
+		fs.addUsageToOpaque(ctx, n, ri)
+	}
+	if wantsSharePolicy(mdKeys) {
+		fs.addSharePolicyToOpaque(ctx, n, ri)
+	}
+	return ri, nil
 }
 
 // ListFolder returns a list of resources in the specified folder
@@ -0,0 +1,183 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package decomposedfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
+	"github.com/cs3org/reva/pkg/storage/utils/fsck"
+	"github.com/pkg/errors"
+	"github.com/pkg/xattr"
+)
+
+// artifactGracePeriod protects the artifacts of the operations in progress:
+// a node is moved to the trash after its trash entry is created.
+const artifactGracePeriod = time.Hour
+
+// The prefixes of the ids of the orphaned artifacts.
+const (
+	artifactTrashEntry  = "trash-entry:"
+	artifactTrashedNode = "trashed-node:"
+	artifactRevision    = "revision:"
+)
+
+// ListOrphanedArtifacts returns the trash entries whose node doesn't exist,
+// the trashed nodes without a trash entry and the revisions of the nodes
+// which don't exist anymore, e.g. because they were purged from the trash.
+func (fs *Decomposedfs) ListOrphanedArtifacts(ctx context.Context) ([]*fsck.Artifact, error) {
+	nodes, err := readDirNames(filepath.Join(fs.o.Root, "nodes"))
+	if err != nil {
+		return nil, errors.Wrap(err, "decomposedfs: error listing nodes")
+	}
+	names := make(map[string]bool, len(nodes))
+	ids := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		names[n] = true
+		if !strings.Contains(n, ".REV.") {
+			ids[strings.SplitN(n, ".T.", 2)[0]] = true
+		}
+	}
+
+	artifacts := []*fsck.Artifact{}
+	referenced := map[string]bool{}
+	owners, err := readDirNames(filepath.Join(fs.o.Root, "trash"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "decomposedfs: error listing trash")
+	}
+	for _, owner := range owners {
+		entries, err := readDirNames(filepath.Join(fs.o.Root, "trash", owner))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			link, err := os.Readlink(filepath.Join(fs.o.Root, "trash", owner, e))
+			if err != nil {
+				continue
+			}
+			target := filepath.Base(link)
+			referenced[target] = true
+			if !names[target] && settled(target, ".T.") {
+				artifacts = append(artifacts, &fsck.Artifact{
+					ID:     artifactTrashEntry + filepath.Join(owner, e),
+					Detail: "trash entry of missing node " + target,
+				})
+			}
+		}
+	}
+
+	for _, n := range nodes {
+		switch {
+		case strings.Contains(n, ".T."):
+			if !referenced[n] && settled(n, ".T.") {
+				artifacts = append(artifacts, &fsck.Artifact{
+					ID:     artifactTrashedNode + n,
+					Detail: "trashed node without trash entry",
+				})
+			}
+		case strings.Contains(n, ".REV."):
+			if id := strings.SplitN(n, ".REV.", 2)[0]; !ids[id] && settled(n, ".REV.") {
+				artifacts = append(artifacts, &fsck.Artifact{
+					ID:     artifactRevision + n,
+					Detail: "revision of missing node " + id,
+				})
+			}
+		}
+	}
+	return artifacts, nil
+}
+
+// PurgeArtifact deletes an orphaned artifact, after checking it is still orphaned.
+func (fs *Decomposedfs) PurgeArtifact(ctx context.Context, a *fsck.Artifact) error {
+	orphans, err := fs.ListOrphanedArtifacts(ctx)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, o := range orphans {
+		if o.ID == a.ID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return errtypes.NotFound(a.ID)
+	}
+
+	switch {
+	case strings.HasPrefix(a.ID, artifactTrashEntry):
+		return os.Remove(filepath.Join(fs.o.Root, "trash", strings.TrimPrefix(a.ID, artifactTrashEntry)))
+	case strings.HasPrefix(a.ID, artifactTrashedNode):
+		return fs.purgeNode(fs.lu.InternalPath(strings.TrimPrefix(a.ID, artifactTrashedNode)))
+	case strings.HasPrefix(a.ID, artifactRevision):
+		return fs.purgeNode(fs.lu.InternalPath(strings.TrimPrefix(a.ID, artifactRevision)))
+	}
+	return errtypes.BadRequest("decomposedfs: unknown artifact " + a.ID)
+}
+
+// purgeNode deletes a node and its blob, if it is a file.
+func (fs *Decomposedfs) purgeNode(nodePath string) error {
+	if blobID, err := xattr.Get(nodePath, xattrs.BlobIDAttr); err == nil && len(blobID) > 0 {
+		if err := fs.tp.DeleteBlob(string(blobID)); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(nodePath)
+}
+
+// RepairTreeSize sets the tree size of a folder and propagates it to its parents.
+func (fs *Decomposedfs) RepairTreeSize(ctx context.Context, ref *provider.Reference, size uint64) error {
+	n, err := fs.lu.NodeFromResource(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if !n.Exists {
+		return errtypes.NotFound(filepath.Join(n.ParentID, n.Name))
+	}
+	if err := n.SetTreeSize(size); err != nil {
+		return err
+	}
+	return fs.tp.Propagate(ctx, n)
+}
+
+// settled returns whether the time following the separator in the name of
+// an artifact is older than the grace period.
+func settled(name, sep string) bool {
+	parts := strings.SplitN(name, sep, 2)
+	if len(parts) != 2 {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339Nano, parts[1])
+	return err == nil && time.Since(t) > artifactGracePeriod
+}
+
+func readDirNames(dir string) ([]string, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Readdirnames(0)
+}
@@ -406,7 +406,7 @@ func calculateEtag(nodeID string, tmTime time.Time) (string, error) {
 // SetMtime sets the mtime and atime of a node
 func (n *Node) SetMtime(ctx context.Context, mtime string) error {
 	sublog := appctx.GetLogger(ctx).With().Interface("node", n).Logger()
-	if mt, err := parseMTime(mtime); err == nil {
+	if mt, err := utils.ParseMTime(mtime); err == nil {
 		nodePath := n.lu.InternalPath(n.ID)
 		// updating mtime also updates atime
 		if err := os.Chtimes(nodePath, mt, mt); err != nil {
@@ -928,17 +928,6 @@ func (n *Node) hasUserShares(ctx context.Context) bool {
 	return false
 }
 
-func parseMTime(v string) (t time.Time, err error) {
-	p := strings.SplitN(v, ".", 2)
-	var sec, nsec int64
-	if sec, err = strconv.ParseInt(p[0], 10, 64); err == nil {
-		if len(p) > 1 {
-			nsec, err = strconv.ParseInt(p[1], 10, 64)
-		}
-	}
-	return time.Unix(sec, nsec), err
-}
-
 // FindStorageSpaceRoot calls n.Parent() and climbs the tree
 // until it finds the space root node and adds it to the node
 func (n *Node) FindStorageSpaceRoot() error {
@@ -970,6 +959,11 @@ func IsSpaceRoot(r *Node) bool {
 
 // CheckQuota checks if both disk space and available quota are sufficient
 var CheckQuota = func(spaceRoot *Node, fileSize uint64) (quotaSufficient bool, err error) {
+	return CheckQuotaLimit(spaceRoot, fileSize, func(quota uint64) uint64 { return quota })
+}
+
+// CheckQuotaLimit checks if both disk space and the limit derived from the quota of the space are sufficient
+var CheckQuotaLimit = func(spaceRoot *Node, fileSize uint64, limit func(quota uint64) uint64) (quotaSufficient bool, err error) {
 	used, _ := spaceRoot.GetTreeSize()
 	if !enoughDiskSpace(spaceRoot.InternalPath(), fileSize) {
 		return false, errtypes.InsufficientStorage("disk full")
@@ -981,6 +975,7 @@ var CheckQuota = func(spaceRoot *Node, fileSize uint64) (quotaSufficient bool, e
 		return true, nil
 	}
 	total, _ = strconv.ParseUint(string(quotaByte), 10, 64)
+	total = limit(total)
 	// if total is smaller than used, total-used could overflow and be bigger than fileSize
 	if fileSize > total-used || total < used {
 		return false, errtypes.InsufficientStorage("quota exceeded")
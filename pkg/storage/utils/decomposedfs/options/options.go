@@ -55,6 +55,66 @@ type Options struct {
 	OwnerType string `mapstructure:"owner_type"`
 
 	GatewayAddr string `mapstructure:"gateway_addr"`
+
+	// QuotaPolicy configures how trashed items and old versions are accounted
+	QuotaPolicy QuotaPolicy `mapstructure:"quota_policy"`
+
+	// SoftQuota configures the overage tolerated above the quota of a space
+	SoftQuota SoftQuota `mapstructure:"soft_quota"`
+}
+
+// The ways trashed items and old versions can be accounted
+const (
+	// QuotaAccountingNone does not account them at all
+	QuotaAccountingNone = "none"
+	// QuotaAccountingQuota counts them towards the quota of the space
+	QuotaAccountingQuota = "quota"
+	// QuotaAccountingBudget tracks them against a separate budget
+	QuotaAccountingBudget = "budget"
+)
+
+// QuotaPolicy defines how trashed items and old versions are accounted and when they are purged automatically.
+type QuotaPolicy struct {
+	// Trash is the accounting of trashed items, one of none, quota or budget
+	Trash string `mapstructure:"trash"`
+	// TrashBudget is the size in bytes trashed items may use when accounted against a budget
+	TrashBudget uint64 `mapstructure:"trash_budget"`
+	// Versions is the accounting of old versions, one of none, quota or budget
+	Versions string `mapstructure:"versions"`
+	// VersionsBudget is the size in bytes old versions may use when accounted against a budget
+	VersionsBudget uint64 `mapstructure:"versions_budget"`
+	// PurgeThreshold is the usage in percent of the quota or budget above which the oldest
+	// trashed items and versions are purged automatically. 0 disables the purge.
+	PurgeThreshold uint64 `mapstructure:"purge_threshold"`
+	// UsageCacheTTL is the time in seconds the computed usage of trash and versions is cached for
+	UsageCacheTTL int `mapstructure:"usage_cache_ttl"`
+}
+
+// Enabled returns whether trashed items or old versions are accounted.
+func (p *QuotaPolicy) Enabled() bool {
+	return p.Trash != QuotaAccountingNone || p.Versions != QuotaAccountingNone
+}
+
+// SoftQuota turns the quota of a space into a soft limit which may be exceeded by a percentage for a limited time.
+type SoftQuota struct {
+	// Overage is the usage in percent of the quota tolerated above it. 0 disables the soft quota.
+	Overage uint64 `mapstructure:"overage"`
+	// GracePeriod is the time in seconds a space may stay above its quota. Afterwards only writes
+	// bringing the usage back below the quota succeed. 0 tolerates the overage indefinitely.
+	GracePeriod int `mapstructure:"grace_period"`
+	// NatsAddress and NatsClusterID configure the event stream the warnings are published to
+	NatsAddress   string `mapstructure:"nats_address"`
+	NatsClusterID string `mapstructure:"nats_clusterid"`
+}
+
+// Enabled returns whether an overage is tolerated.
+func (q *SoftQuota) Enabled() bool {
+	return q.Overage > 0
+}
+
+// HardLimit returns the limit a space with the given quota may not exceed.
+func (q *SoftQuota) HardLimit(quota uint64) uint64 {
+	return quota + quota*q.Overage/100
 }
 
 // New returns a new Options instance for the given configuration
@@ -80,5 +140,23 @@ func New(m map[string]interface{}) (*Options, error) {
 	// c.DataDirectory should never end in / unless it is the root
 	o.Root = filepath.Clean(o.Root)
 
+	if o.QuotaPolicy.Trash == "" {
+		o.QuotaPolicy.Trash = QuotaAccountingNone
+	}
+	if o.QuotaPolicy.Versions == "" {
+		o.QuotaPolicy.Versions = QuotaAccountingNone
+	}
+	for _, a := range []string{o.QuotaPolicy.Trash, o.QuotaPolicy.Versions} {
+		if a != QuotaAccountingNone && a != QuotaAccountingQuota && a != QuotaAccountingBudget {
+			return nil, errors.New("invalid quota accounting: " + a)
+		}
+	}
+	if o.QuotaPolicy.UsageCacheTTL == 0 {
+		o.QuotaPolicy.UsageCacheTTL = 60
+	}
+	if o.SoftQuota.GracePeriod < 0 {
+		return nil, errors.New("invalid soft quota grace period")
+	}
+
 	return o, nil
 }
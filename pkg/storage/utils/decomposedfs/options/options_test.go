@@ -45,6 +45,38 @@ var _ = Describe("Options", func() {
 		It("sets defaults", func() {
 			Expect(len(o.ShareFolder) > 0).To(BeTrue())
 			Expect(len(o.UserLayout) > 0).To(BeTrue())
+			Expect(o.QuotaPolicy.Trash).To(Equal(options.QuotaAccountingNone))
+			Expect(o.QuotaPolicy.Versions).To(Equal(options.QuotaAccountingNone))
+			Expect(o.QuotaPolicy.Enabled()).To(BeFalse())
+			Expect(o.SoftQuota.Enabled()).To(BeFalse())
+		})
+
+		Context("with a quota policy", func() {
+			BeforeEach(func() {
+				config["quota_policy"] = map[string]interface{}{
+					"trash": "quota",
+				}
+			})
+
+			It("enables the accounting", func() {
+				Expect(o.QuotaPolicy.Trash).To(Equal(options.QuotaAccountingQuota))
+				Expect(o.QuotaPolicy.Enabled()).To(BeTrue())
+			})
+		})
+
+		Context("with a soft quota", func() {
+			BeforeEach(func() {
+				config["soft_quota"] = map[string]interface{}{
+					"overage":      10,
+					"grace_period": 3600,
+				}
+			})
+
+			It("tolerates the overage", func() {
+				Expect(o.SoftQuota.Enabled()).To(BeTrue())
+				Expect(o.SoftQuota.GracePeriod).To(Equal(3600))
+				Expect(o.SoftQuota.HardLimit(1000)).To(Equal(uint64(1100)))
+			})
 		})
 
 		Context("with unclean root path configuration", func() {
@@ -0,0 +1,398 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package decomposedfs
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/options"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/tree"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
+	"github.com/pkg/xattr"
+)
+
+// Trashed items and old versions are not part of the tree of a space, so they are not included in its treesize.
+// Depending on the quota policy they are counted towards the quota of the space or tracked against separate
+// budgets. Their usage is computed by walking the trash of the space owner and the revisions of the files in
+// the space, which is why it is cached for a short time.
+
+// usageItem is a trashed item or an old version which can be purged to free space.
+type usageItem struct {
+	trash bool
+	// path is the trash link or the revision file
+	path string
+	// nodePath is the path of the trashed node
+	nodePath string
+	size     uint64
+	mtime    time.Time
+	purged   bool
+}
+
+// usage is the space used by the trashed items and old versions of a space.
+type usage struct {
+	trash    uint64
+	versions uint64
+	items    []*usageItem
+}
+
+// inQuota returns the part of the usage which counts towards the quota of the space.
+func (u *usage) inQuota(p *options.QuotaPolicy) uint64 {
+	var size uint64
+	if p.Trash == options.QuotaAccountingQuota {
+		size += u.trash
+	}
+	if p.Versions == options.QuotaAccountingQuota {
+		size += u.versions
+	}
+	return size
+}
+
+// usageJSON is the representation of a usage in the cache.
+type usageJSON struct {
+	Trash    uint64          `json:"trash"`
+	Versions uint64          `json:"versions"`
+	Items    []usageItemJSON `json:"items"`
+}
+
+type usageItemJSON struct {
+	Trash    bool      `json:"trash"`
+	Path     string    `json:"path"`
+	NodePath string    `json:"node_path"`
+	Size     uint64    `json:"size"`
+	MTime    time.Time `json:"mtime"`
+}
+
+// MarshalJSON encodes the usage for the cache.
+func (u *usage) MarshalJSON() ([]byte, error) {
+	j := usageJSON{Trash: u.trash, Versions: u.versions, Items: make([]usageItemJSON, 0, len(u.items))}
+	for _, i := range u.items {
+		if !i.purged {
+			j.Items = append(j.Items, usageItemJSON{Trash: i.trash, Path: i.path, NodePath: i.nodePath, Size: i.size, MTime: i.mtime})
+		}
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes a usage read from the cache.
+func (u *usage) UnmarshalJSON(data []byte) error {
+	j := usageJSON{}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	u.trash, u.versions = j.Trash, j.Versions
+	u.items = make([]*usageItem, 0, len(j.Items))
+	for _, i := range j.Items {
+		u.items = append(u.items, &usageItem{trash: i.Trash, path: i.Path, nodePath: i.NodePath, size: i.Size, mtime: i.MTime})
+	}
+	return nil
+}
+
+// spaceUsage returns the possibly cached usage of trashed items and old versions of the space.
+func (fs *Decomposedfs) spaceUsage(ctx context.Context, spaceRoot *node.Node) (*usage, error) {
+	u := &usage{}
+	if fs.usageCache.Get(spaceRoot.ID, u) {
+		return u, nil
+	}
+	u, err := fs.computeUsage(ctx, spaceRoot)
+	if err != nil {
+		return nil, err
+	}
+	_ = fs.usageCache.Set(spaceRoot.ID, u)
+	return u, nil
+}
+
+func (fs *Decomposedfs) computeUsage(ctx context.Context, spaceRoot *node.Node) (*usage, error) {
+	u := &usage{}
+	if fs.o.QuotaPolicy.Trash != options.QuotaAccountingNone {
+		if err := fs.collectTrash(spaceRoot, u); err != nil {
+			return nil, err
+		}
+	}
+	if fs.o.QuotaPolicy.Versions != options.QuotaAccountingNone {
+		if err := fs.collectVersions(spaceRoot.InternalPath(), u); err != nil {
+			return nil, err
+		}
+	}
+	// the oldest items are purged first
+	sort.Slice(u.items, func(i, j int) bool {
+		return u.items[i].mtime.Before(u.items[j].mtime)
+	})
+	return u, nil
+}
+
+func (fs *Decomposedfs) collectTrash(spaceRoot *node.Node, u *usage) error {
+	o, err := spaceRoot.Owner()
+	if err != nil {
+		return err
+	}
+	if o.OpaqueId == "" {
+		o.OpaqueId = "root"
+	}
+	trashRoot := filepath.Join(fs.o.Root, "trash", o.OpaqueId)
+	entries, err := os.ReadDir(trashRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, e := range entries {
+		trashItem := filepath.Join(trashRoot, e.Name())
+		link, err := os.Readlink(trashItem)
+		if err != nil {
+			continue
+		}
+		parts := strings.SplitN(filepath.Base(link), ".T.", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		nodePath := fs.lu.InternalPath(filepath.Base(link))
+		size, err := itemSize(nodePath)
+		if err != nil {
+			continue
+		}
+		mtime, _ := time.Parse(time.RFC3339Nano, parts[1])
+		u.trash += size
+		u.items = append(u.items, &usageItem{trash: true, path: trashItem, nodePath: nodePath, size: size, mtime: mtime})
+	}
+	return nil
+}
+
+func (fs *Decomposedfs) collectVersions(dirPath string, u *usage) error {
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		// the entries of a directory node are links to the child nodes
+		link, err := os.Readlink(filepath.Join(dirPath, e.Name()))
+		if err != nil {
+			continue
+		}
+		childPath := fs.lu.InternalPath(filepath.Base(link))
+		fi, err := os.Stat(childPath)
+		if err != nil {
+			continue
+		}
+		if fi.IsDir() {
+			if err := fs.collectVersions(childPath, u); err != nil {
+				return err
+			}
+			continue
+		}
+
+		revisions, err := filepath.Glob(childPath + ".REV.*")
+		if err != nil {
+			continue
+		}
+		for _, r := range revisions {
+			blobSize, err := node.ReadBlobSizeAttr(r)
+			if err != nil {
+				continue
+			}
+			parts := strings.SplitN(filepath.Base(r), ".REV.", 2)
+			mtime, _ := time.Parse(time.RFC3339Nano, parts[1])
+			u.versions += uint64(blobSize)
+			u.items = append(u.items, &usageItem{path: r, size: uint64(blobSize), mtime: mtime})
+		}
+	}
+	return nil
+}
+
+// itemSize returns the size of a trashed node, which is its treesize for directories.
+func itemSize(nodePath string) (uint64, error) {
+	fi, err := os.Stat(nodePath)
+	if err != nil {
+		return 0, err
+	}
+	if fi.IsDir() {
+		b, err := xattr.Get(nodePath, xattrs.TreesizeAttr)
+		if err != nil {
+			// treesize accounting is disabled
+			return 0, nil
+		}
+		return strconv.ParseUint(string(b), 10, 64)
+	}
+	size, err := node.ReadBlobSizeAttr(nodePath)
+	return uint64(size), err
+}
+
+// checkQuota checks if a file of the given size fits into the space, taking the trashed items and old
+// versions into account according to the quota policy. They are purged first if the usage exceeds the
+// purge threshold.
+func (fs *Decomposedfs) checkQuota(ctx context.Context, spaceRoot *node.Node, fileSize uint64) (bool, error) {
+	p := &fs.o.QuotaPolicy
+	if !p.Enabled() {
+		return fs.checkLimits(ctx, spaceRoot, fileSize)
+	}
+
+	u, err := fs.spaceUsage(ctx, spaceRoot)
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("spaceRoot", spaceRoot.ID).Msg("could not compute the usage of trash and versions")
+		return fs.checkLimits(ctx, spaceRoot, fileSize)
+	}
+
+	if p.PurgeThreshold > 0 && fs.overThreshold(spaceRoot, u, fileSize) {
+		// the cached usage is updated with the purged items instead of being computed again,
+		// so that a space staying above the threshold isn't walked on every upload; items
+		// which have vanished in the meantime simply fail to be purged
+		fs.purgeOverThreshold(ctx, spaceRoot, u, fileSize)
+		_ = fs.usageCache.Set(spaceRoot.ID, u)
+	}
+
+	return fs.checkLimits(ctx, spaceRoot, fileSize+u.inQuota(p))
+}
+
+// quotaPressure returns a function telling whether the usage of the space exceeds the purge threshold of its quota.
+func (fs *Decomposedfs) quotaPressure(spaceRoot *node.Node, u *usage, fileSize uint64) func() bool {
+	quotaBytes, err := xattr.Get(spaceRoot.InternalPath(), xattrs.QuotaAttr)
+	if err != nil {
+		// no quota, no pressure
+		return func() bool { return false }
+	}
+	quota, err := strconv.ParseUint(string(quotaBytes), 10, 64)
+	if err != nil || quota == 0 {
+		return func() bool { return false }
+	}
+	used, _ := spaceRoot.GetTreeSize()
+	limit := quota * fs.o.QuotaPolicy.PurgeThreshold / 100
+	return func() bool {
+		return used+fileSize+u.inQuota(&fs.o.QuotaPolicy) > limit
+	}
+}
+
+func (fs *Decomposedfs) overThreshold(spaceRoot *node.Node, u *usage, fileSize uint64) bool {
+	p := &fs.o.QuotaPolicy
+	if fs.quotaPressure(spaceRoot, u, fileSize)() {
+		return true
+	}
+	if p.Trash == options.QuotaAccountingBudget && p.TrashBudget > 0 && u.trash > p.TrashBudget*p.PurgeThreshold/100 {
+		return true
+	}
+	return p.Versions == options.QuotaAccountingBudget && p.VersionsBudget > 0 && u.versions > p.VersionsBudget*p.PurgeThreshold/100
+}
+
+func (fs *Decomposedfs) purgeOverThreshold(ctx context.Context, spaceRoot *node.Node, u *usage, fileSize uint64) {
+	p := &fs.o.QuotaPolicy
+
+	fs.purgeOldest(ctx, u, func(i *usageItem) bool {
+		if i.trash {
+			return p.Trash == options.QuotaAccountingQuota
+		}
+		return p.Versions == options.QuotaAccountingQuota
+	}, fs.quotaPressure(spaceRoot, u, fileSize))
+
+	if p.Trash == options.QuotaAccountingBudget && p.TrashBudget > 0 {
+		fs.purgeOldest(ctx, u, func(i *usageItem) bool { return i.trash }, func() bool {
+			return u.trash > p.TrashBudget*p.PurgeThreshold/100
+		})
+	}
+	if p.Versions == options.QuotaAccountingBudget && p.VersionsBudget > 0 {
+		fs.purgeOldest(ctx, u, func(i *usageItem) bool { return !i.trash }, func() bool {
+			return u.versions > p.VersionsBudget*p.PurgeThreshold/100
+		})
+	}
+}
+
+// purgeOldest purges the oldest items accepted by the filter as long as over returns true.
+func (fs *Decomposedfs) purgeOldest(ctx context.Context, u *usage, filter func(*usageItem) bool, over func() bool) {
+	log := appctx.GetLogger(ctx)
+	for _, item := range u.items {
+		if !over() {
+			return
+		}
+		if item.purged || !filter(item) {
+			continue
+		}
+		if err := fs.purgeItem(ctx, item); err != nil {
+			log.Error().Err(err).Str("path", item.path).Msg("could not purge item to free space")
+			continue
+		}
+		log.Info().Str("path", item.path).Uint64("size", item.size).Msg("purged item to free space")
+		item.purged = true
+		if item.trash {
+			u.trash -= item.size
+		} else {
+			u.versions -= item.size
+		}
+	}
+}
+
+func (fs *Decomposedfs) purgeItem(ctx context.Context, item *usageItem) error {
+	if item.trash {
+		// the trash item is purged like the users do it, including the children of a folder
+		ctx = tree.ContextWithTrashOwner(ctx, filepath.Base(filepath.Dir(item.path)))
+		_, purge, err := fs.tp.PurgeRecycleItemFunc(ctx, filepath.Base(item.path), "")
+		if err != nil {
+			return err
+		}
+		return purge()
+	}
+
+	blobID, _ := xattr.Get(item.path, xattrs.BlobIDAttr)
+	if err := os.Remove(item.path); err != nil {
+		return err
+	}
+	if len(blobID) > 0 {
+		return fs.tp.DeleteBlob(string(blobID))
+	}
+	return nil
+}
+
+// addUsageToOpaque reports the usage of trashed items and old versions of a space in the opaque of its resource info.
+func (fs *Decomposedfs) addUsageToOpaque(ctx context.Context, spaceRoot *node.Node, ri *provider.ResourceInfo) {
+	u, err := fs.spaceUsage(ctx, spaceRoot)
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("spaceRoot", spaceRoot.ID).Msg("could not compute the usage of trash and versions")
+		return
+	}
+	if ri.Opaque == nil {
+		ri.Opaque = &types.Opaque{Map: map[string]*types.OpaqueEntry{}}
+	} else if ri.Opaque.Map == nil {
+		ri.Opaque.Map = map[string]*types.OpaqueEntry{}
+	}
+	if fs.o.QuotaPolicy.Trash != options.QuotaAccountingNone {
+		ri.Opaque.Map["trash_size"] = &types.OpaqueEntry{Decoder: "plain", Value: []byte(strconv.FormatUint(u.trash, 10))}
+	}
+	if fs.o.QuotaPolicy.Versions != options.QuotaAccountingNone {
+		ri.Opaque.Map["versions_size"] = &types.OpaqueEntry{Decoder: "plain", Value: []byte(strconv.FormatUint(u.versions, 10))}
+	}
+}
+
+func includesKey(mdKeys []string, key string) bool {
+	for _, k := range mdKeys {
+		if k == key || k == "*" {
+			return true
+		}
+	}
+	return false
+}
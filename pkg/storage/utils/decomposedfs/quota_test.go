@@ -0,0 +1,201 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package decomposedfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/kvcache"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/options"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/tree"
+	treemocks "github.com/cs3org/reva/pkg/storage/utils/decomposedfs/tree/mocks"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
+	"github.com/google/uuid"
+	"github.com/pkg/xattr"
+	"github.com/stretchr/testify/mock"
+)
+
+var testOwner = &userpb.UserId{Idp: "idp", OpaqueId: "einstein", Type: userpb.UserType_USER_TYPE_PRIMARY}
+
+// newQuotaTestFS returns a storage with an empty space of the given quota; a zero quota leaves it unlimited.
+func newQuotaTestFS(t *testing.T, conf map[string]interface{}, quota uint64) (*Decomposedfs, *node.Node, *treemocks.Blobstore) {
+	conf["root"] = t.TempDir()
+	o, err := options.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lu := &Lookup{Options: o}
+	bs := &treemocks.Blobstore{}
+	bs.On("Delete", mock.AnythingOfType("string")).Return(nil)
+	fs := &Decomposedfs{
+		o:          o,
+		lu:         lu,
+		tp:         tree.New(o.Root, true, true, lu, bs),
+		usageCache: kvcache.Namespace("decomposedfs_usage_test", 0),
+	}
+
+	spaceRoot := newTestNode(t, lu, uuid.New().String(), "", "", true)
+	if err := xattr.Set(spaceRoot.InternalPath(), xattrs.TreesizeAttr, []byte("0")); err != nil {
+		t.Fatal(err)
+	}
+	if quota > 0 {
+		if err := xattr.Set(spaceRoot.InternalPath(), xattrs.QuotaAttr, []byte(strconv.FormatUint(quota, 10))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fs, spaceRoot, bs
+}
+
+// newTestNode creates a node below the given parent, a folder or a file with the given blob.
+func newTestNode(t *testing.T, lu *Lookup, id, parentID, blobID string, folder bool) *node.Node {
+	n := node.New(id, parentID, id, 0, blobID, testOwner, lu)
+	if err := os.MkdirAll(filepath.Join(lu.InternalRoot(), "nodes"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	if folder {
+		err = os.Mkdir(n.InternalPath(), 0700)
+	} else {
+		err = os.WriteFile(n.InternalPath(), nil, 0600)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.WriteMetadata(testOwner); err != nil {
+		t.Fatal(err)
+	}
+	if parentID != "" {
+		if err := os.Symlink("../"+id, filepath.Join(lu.InternalPath(parentID), id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return n
+}
+
+// trash moves a node to the trash of the test owner the way the tree does it.
+func trash(t *testing.T, fs *Decomposedfs, n *node.Node, deletionTime string) string {
+	trashDir := filepath.Join(fs.o.Root, "trash", testOwner.OpaqueId)
+	if err := os.MkdirAll(trashDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := xattr.Set(n.InternalPath(), xattrs.TrashOriginAttr, []byte("/"+n.Name)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(n.InternalPath(), n.InternalPath()+".T."+deletionTime); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(trashDir, n.ID)
+	if err := os.Symlink("../../nodes/"+n.ID+".T."+deletionTime, link); err != nil {
+		t.Fatal(err)
+	}
+	return link
+}
+
+func addRevision(t *testing.T, n *node.Node, mtime, blobID string, size int) string {
+	r := n.InternalPath() + ".REV." + mtime
+	if err := os.WriteFile(r, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := xattr.Set(r, xattrs.BlobIDAttr, []byte(blobID)); err != nil {
+		t.Fatal(err)
+	}
+	if err := xattr.Set(r, xattrs.BlobsizeAttr, []byte(strconv.Itoa(size))); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func notExists(p string) bool {
+	_, err := os.Lstat(p)
+	return os.IsNotExist(err)
+}
+
+func TestPurgeTrashedFolder(t *testing.T) {
+	fs, spaceRoot, bs := newQuotaTestFS(t, map[string]interface{}{
+		"quota_policy": map[string]interface{}{"trash": "quota", "purge_threshold": 50},
+	}, 1000)
+
+	folder := newTestNode(t, fs.lu, "folder", spaceRoot.ID, "", true)
+	child := newTestNode(t, fs.lu, "child", folder.ID, "child-blob", false)
+	if err := xattr.Set(folder.InternalPath(), xattrs.TreesizeAttr, []byte("800")); err != nil {
+		t.Fatal(err)
+	}
+	link := trash(t, fs, folder, "2022-01-01T00:00:00Z")
+
+	ok, err := fs.checkQuota(context.Background(), spaceRoot, 100)
+	if err != nil || !ok {
+		t.Fatalf("expected the upload to fit once the trash is purged, got %v", err)
+	}
+	if !notExists(link) || !notExists(folder.InternalPath()+".T.2022-01-01T00:00:00Z") {
+		t.Error("expected the trashed folder to be purged")
+	}
+	if !notExists(child.InternalPath()) {
+		t.Error("expected the child of the trashed folder to be purged")
+	}
+	bs.AssertCalled(t, "Delete", "child-blob")
+}
+
+func TestPurgeOldestVersionsOverBudget(t *testing.T) {
+	fs, spaceRoot, bs := newQuotaTestFS(t, map[string]interface{}{
+		"quota_policy": map[string]interface{}{"versions": "budget", "versions_budget": 1000, "purge_threshold": 100},
+	}, 0)
+
+	folder := newTestNode(t, fs.lu, "folder", spaceRoot.ID, "", true)
+	file := newTestNode(t, fs.lu, "file", folder.ID, "file-blob", false)
+	older := addRevision(t, file, "2022-01-01T00:00:00Z", "older-blob", 600)
+	newer := addRevision(t, file, "2022-02-01T00:00:00Z", "newer-blob", 600)
+
+	if ok, err := fs.checkQuota(context.Background(), spaceRoot, 100); err != nil || !ok {
+		t.Fatalf("expected the upload to be accepted, got %v", err)
+	}
+	if !notExists(older) {
+		t.Error("expected the oldest version to be purged")
+	}
+	if notExists(newer) {
+		t.Error("expected the newest version to be kept, as the budget is no longer exceeded")
+	}
+	bs.AssertCalled(t, "Delete", "older-blob")
+	bs.AssertNotCalled(t, "Delete", "newer-blob")
+}
+
+func TestPurgeBelowThreshold(t *testing.T) {
+	fs, spaceRoot, bs := newQuotaTestFS(t, map[string]interface{}{
+		"quota_policy": map[string]interface{}{"trash": "quota", "purge_threshold": 90},
+	}, 10000)
+
+	file := newTestNode(t, fs.lu, "file", spaceRoot.ID, "file-blob", false)
+	if err := xattr.Set(file.InternalPath(), xattrs.BlobsizeAttr, []byte("500")); err != nil {
+		t.Fatal(err)
+	}
+	link := trash(t, fs, file, "2022-01-01T00:00:00Z")
+
+	if ok, err := fs.checkQuota(context.Background(), spaceRoot, 100); err != nil || !ok {
+		t.Fatalf("expected the upload to be accepted, got %v", err)
+	}
+	if notExists(link) {
+		t.Error("expected nothing to be purged below the threshold")
+	}
+	bs.AssertNotCalled(t, "Delete", mock.AnythingOfType("string"))
+}
@@ -32,7 +32,9 @@ import (
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/tree"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
+	"github.com/cs3org/reva/pkg/utils"
 	"github.com/pkg/errors"
 	"github.com/pkg/xattr"
 )
@@ -51,14 +53,25 @@ func (fs *Decomposedfs) ListRecycle(ctx context.Context, basePath, key, relative
 
 	items := make([]*provider.RecycleItem, 0)
 
+	space, err := fs.recycleSpace(ctx, func(rp *provider.ResourcePermissions) bool {
+		return rp.ListRecycle
+	})
+	if err != nil {
+		return items, err
+	}
+
+	trashRoot := fs.getRecycleRoot(ctx)
+	switch {
+	case space != nil:
+		trashRoot = fs.spaceTrashRoot(space)
 	// TODO how do we check if the storage allows listing the recycle for the current user? check owner of the root of the storage?
 	// use permissions ReadUserPermissions?
-	if fs.o.EnableHome {
+	case fs.o.EnableHome:
 		if !node.OwnerPermissions().ListContainer {
 			log.Debug().Msg("owner not allowed to list trash")
 			return items, errtypes.PermissionDenied("owner not allowed to list trash")
 		}
-	} else {
+	default:
 		if !node.NoPermissions().ListContainer {
 			log.Debug().Msg("default permissions prevent listing trash")
 			return items, errtypes.PermissionDenied("default permissions prevent listing trash")
@@ -66,10 +79,9 @@ func (fs *Decomposedfs) ListRecycle(ctx context.Context, basePath, key, relative
 	}
 
 	if key == "" && relativePath == "/" {
-		return fs.listTrashRoot(ctx)
+		return fs.listTrashRoot(ctx, trashRoot, space)
 	}
 
-	trashRoot := fs.getRecycleRoot(ctx)
 	f, err := os.Open(filepath.Join(trashRoot, key, relativePath))
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -84,12 +96,15 @@ func (fs *Decomposedfs) ListRecycle(ctx context.Context, basePath, key, relative
 		log.Error().Err(err).Str("trashRoot", trashRoot).Msg("error reading trash link, skipping")
 		return nil, err
 	}
+	if space != nil && !fs.deletedInSpace(ctx, parentNode, space) {
+		return items, errtypes.NotFound(key)
+	}
 
 	if md, err := f.Stat(); err != nil {
 		return nil, err
 	} else if !md.IsDir() {
 		// this is the case when we want to directly list a file in the trashbin
-		item, err := fs.createTrashItem(ctx, parentNode, filepath.Dir(relativePath), filepath.Join(trashRoot, key, relativePath))
+		item, err := fs.createTrashItem(ctx, trashRoot, space, parentNode, filepath.Dir(relativePath), filepath.Join(trashRoot, key, relativePath))
 		if err != nil {
 			return items, err
 		}
@@ -102,16 +117,15 @@ func (fs *Decomposedfs) ListRecycle(ctx context.Context, basePath, key, relative
 		return nil, err
 	}
 	for i := range names {
-		if item, err := fs.createTrashItem(ctx, parentNode, relativePath, filepath.Join(trashRoot, key, relativePath, names[i])); err == nil {
+		if item, err := fs.createTrashItem(ctx, trashRoot, space, parentNode, relativePath, filepath.Join(trashRoot, key, relativePath, names[i])); err == nil {
 			items = append(items, item)
 		}
 	}
 	return items, nil
 }
 
-func (fs *Decomposedfs) createTrashItem(ctx context.Context, parentNode, intermediatePath, itemPath string) (*provider.RecycleItem, error) {
+func (fs *Decomposedfs) createTrashItem(ctx context.Context, trashRoot string, space *node.Node, parentNode, intermediatePath, itemPath string) (*provider.RecycleItem, error) {
 	log := appctx.GetLogger(ctx)
-	trashRoot := fs.getRecycleRoot(ctx)
 	trashnode, err := os.Readlink(itemPath)
 	if err != nil {
 		log.Error().Err(err).Str("trashRoot", trashRoot).Msg("error reading trash link, skipping")
@@ -152,6 +166,10 @@ func (fs *Decomposedfs) createTrashItem(ctx context.Context, parentNode, interme
 		log.Error().Err(err).Str("trashRoot", trashRoot).Str("link", trashnode).Msg("could not read origin path, skipping")
 		return nil, err
 	}
+	if space != nil {
+		// the managers of a space see the items deleted by all of its members
+		return item, fs.relativeToSpace(ctx, item, space)
+	}
 	// TODO filter results by permission ... on the original parent? or the trashed node?
 	// if it were on the original parent it would be possible to see files that were trashed before the current user got access
 	// so -> check the trash node itself
@@ -174,11 +192,10 @@ func (fs *Decomposedfs) createTrashItem(ctx context.Context, parentNode, interme
 	return item, nil
 }
 
-func (fs *Decomposedfs) listTrashRoot(ctx context.Context) ([]*provider.RecycleItem, error) {
+func (fs *Decomposedfs) listTrashRoot(ctx context.Context, trashRoot string, space *node.Node) ([]*provider.RecycleItem, error) {
 	log := appctx.GetLogger(ctx)
 	items := make([]*provider.RecycleItem, 0)
 
-	trashRoot := fs.getRecycleRoot(ctx)
 	f, err := os.Open(trashRoot)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -204,6 +221,9 @@ func (fs *Decomposedfs) listTrashRoot(ctx context.Context) ([]*provider.RecycleI
 			log.Error().Err(err).Str("trashRoot", trashRoot).Str("name", names[i]).Str("trashnode", trashnode).Interface("parts", parts).Msg("malformed trash link, skipping")
 			continue
 		}
+		if space != nil && !fs.deletedInSpace(ctx, trashnode, space) {
+			continue
+		}
 
 		nodePath := fs.lu.InternalPath(filepath.Base(trashnode))
 		md, err := os.Stat(nodePath)
@@ -234,6 +254,15 @@ func (fs *Decomposedfs) listTrashRoot(ctx context.Context) ([]*provider.RecycleI
 			log.Error().Err(err).Str("trashRoot", trashRoot).Str("name", names[i]).Str("link", trashnode).Msg("could not read origin path, skipping")
 			continue
 		}
+		if space != nil {
+			// the managers of a space see the items deleted by all of its members
+			if err := fs.relativeToSpace(ctx, item, space); err != nil {
+				log.Error().Err(err).Str("trashRoot", trashRoot).Str("name", names[i]).Msg("could not make origin path relative to the space, skipping")
+				continue
+			}
+			items = append(items, item)
+			continue
+		}
 		// TODO filter results by permission ... on the original parent? or the trashed node?
 		// if it were on the original parent it would be possible to see files that were trashed before the current user got access
 		// so -> check the trash node itself
@@ -262,7 +291,29 @@ func (fs *Decomposedfs) RestoreRecycleItem(ctx context.Context, basePath, key, r
 	if restoreRef == nil {
 		restoreRef = &provider.Reference{}
 	}
-	rn, p, restoreFunc, err := fs.tp.RestoreRecycleItemFunc(ctx, key, relativePath, restoreRef.Path)
+	space, err := fs.recycleSpace(ctx, func(rp *provider.ResourcePermissions) bool {
+		return rp.RestoreRecycleItem
+	})
+	if err != nil {
+		return err
+	}
+	if space != nil {
+		if ctx, err = fs.spaceRecycleContext(ctx, space, key); err != nil {
+			return err
+		}
+	}
+	restorePath := restoreRef.Path
+	if restoreRef.ResourceId != nil {
+		// restoring to a location relative to a space
+		target, err := fs.lu.NodeFromResource(ctx, restoreRef)
+		if err != nil {
+			return err
+		}
+		if restorePath, err = fs.lu.Path(ctx, target); err != nil {
+			return err
+		}
+	}
+	rn, p, restoreFunc, err := fs.tp.RestoreRecycleItemFunc(ctx, key, relativePath, restorePath)
 	if err != nil {
 		return err
 	}
@@ -289,11 +340,28 @@ func (fs *Decomposedfs) RestoreRecycleItem(ctx context.Context, basePath, key, r
 	}
 
 	// Run the restore func
-	return restoreFunc()
+	if err := restoreFunc(); err != nil {
+		return err
+	}
+	if space != nil {
+		fs.auditSpaceRecycle(ctx, space, "restored", key, relativePath)
+	}
+	return nil
 }
 
 // PurgeRecycleItem purges the specified item
 func (fs *Decomposedfs) PurgeRecycleItem(ctx context.Context, basePath, key, relativePath string) error {
+	space, err := fs.recycleSpace(ctx, func(rp *provider.ResourcePermissions) bool {
+		return rp.PurgeRecycle
+	})
+	if err != nil {
+		return err
+	}
+	if space != nil {
+		if ctx, err = fs.spaceRecycleContext(ctx, space, key); err != nil {
+			return err
+		}
+	}
 	rn, purgeFunc, err := fs.tp.PurgeRecycleItemFunc(ctx, key, relativePath)
 	if err != nil {
 		return err
@@ -311,11 +379,31 @@ func (fs *Decomposedfs) PurgeRecycleItem(ctx context.Context, basePath, key, rel
 	}
 
 	// Run the purge func
-	return purgeFunc()
+	if err := purgeFunc(); err != nil {
+		return err
+	}
+	if space != nil {
+		fs.auditSpaceRecycle(ctx, space, "purged", key, relativePath)
+	}
+	return nil
 }
 
 // EmptyRecycle empties the trash
 func (fs *Decomposedfs) EmptyRecycle(ctx context.Context) error {
+	if _, ok := appctx.ContextGetRecycleSpace(ctx); ok {
+		// only purge the items deleted in the space, not the whole trash of its owner
+		items, err := fs.ListRecycle(ctx, "", "", "/")
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := fs.PurgeRecycleItem(ctx, "", item.Key, ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	u, ok := ctxpkg.ContextGetUser(ctx)
 	// TODO what permission should we check? we could check the root node of the user? or the owner permissions on his home root node?
 	// The current impl will wipe your own trash. or when no user provided the trash of 'root'
@@ -342,3 +430,100 @@ func (fs *Decomposedfs) getRecycleRoot(ctx context.Context) string {
 	}
 	return filepath.Join(fs.o.Root, "trash", "root")
 }
+
+// recycleSpace returns the root of the space whose trash is accessed, nil
+// when the trash of the current user is accessed. Only the managers of a
+// space, who may also manage its grants, can access the items deleted by all
+// of its members.
+func (fs *Decomposedfs) recycleSpace(ctx context.Context, check func(*provider.ResourcePermissions) bool) (*node.Node, error) {
+	id, ok := appctx.ContextGetRecycleSpace(ctx)
+	if !ok {
+		return nil, nil
+	}
+	space, err := fs.lu.NodeFromID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !space.Exists || !node.IsSpaceRoot(space) {
+		return nil, errtypes.NotFound(id.OpaqueId)
+	}
+	ok, err = fs.p.HasPermission(ctx, space, func(rp *provider.ResourcePermissions) bool {
+		return rp.AddGrant && check(rp)
+	})
+	switch {
+	case err != nil:
+		return nil, errtypes.InternalError(err.Error())
+	case !ok:
+		return nil, errtypes.PermissionDenied(id.OpaqueId)
+	}
+	return space, nil
+}
+
+// spaceTrashRoot returns the trash holding the items deleted in a space. Nodes
+// inherit the owner of their parent, so they end up in the trash of the owner
+// of the space, whoever deleted them.
+func (fs *Decomposedfs) spaceTrashRoot(space *node.Node) string {
+	return filepath.Join(fs.o.Root, "trash", spaceTrashOwner(space))
+}
+
+func spaceTrashOwner(space *node.Node) string {
+	if o, err := space.Owner(); err == nil && o.OpaqueId != "" {
+		return o.OpaqueId
+	}
+	// fall back to root trash, see Tree.Delete()
+	return "root"
+}
+
+// spaceRecycleContext makes the tree access the trash of the space, after
+// checking that the item with the given key was deleted in the space.
+func (fs *Decomposedfs) spaceRecycleContext(ctx context.Context, space *node.Node, key string) (context.Context, error) {
+	link, err := os.Readlink(filepath.Join(fs.spaceTrashRoot(space), key))
+	if err != nil || !fs.deletedInSpace(ctx, link, space) {
+		return nil, errtypes.NotFound(key)
+	}
+	return tree.ContextWithTrashOwner(ctx, spaceTrashOwner(space)), nil
+}
+
+// deletedInSpace tells whether the trashed node a trash link points to was
+// deleted from inside the given space.
+func (fs *Decomposedfs) deletedInSpace(ctx context.Context, link string, space *node.Node) bool {
+	parentID, err := xattr.Get(fs.lu.InternalPath(filepath.Base(link)), xattrs.ParentidAttr)
+	if err != nil {
+		return false
+	}
+	if string(parentID) == space.ID {
+		return true
+	}
+	parent, err := fs.lu.NodeFromID(ctx, &provider.ResourceId{OpaqueId: string(parentID)})
+	if err != nil || !parent.Exists {
+		return false
+	}
+	return parent.SpaceRoot != nil && parent.SpaceRoot.ID == space.ID
+}
+
+// relativeToSpace turns the origin of a trash item into a reference relative to the root of the space.
+func (fs *Decomposedfs) relativeToSpace(ctx context.Context, item *provider.RecycleItem, space *node.Node) error {
+	spacePath, err := fs.lu.Path(ctx, space)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(spacePath, item.Ref.Path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return errtypes.InternalError("origin " + item.Ref.Path + " is not inside the space")
+	}
+	item.Ref = &provider.Reference{
+		ResourceId: &provider.ResourceId{OpaqueId: space.ID},
+		Path:       utils.MakeRelativePath(rel),
+	}
+	return nil
+}
+
+func (fs *Decomposedfs) auditSpaceRecycle(ctx context.Context, space *node.Node, action, key, relativePath string) {
+	u := ctxpkg.ContextMustGetUser(ctx)
+	appctx.GetLogger(ctx).Info().
+		Str("space", space.ID).
+		Str("user", u.Username).
+		Str("key", path.Join(key, relativePath)).
+		Str("action", action).
+		Msg("audit: trash item of space " + action + " by space manager")
+}
@@ -0,0 +1,108 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package decomposedfs_test
+
+import (
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
+	helpers "github.com/cs3org/reva/pkg/storage/utils/decomposedfs/testhelpers"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Recycle of a space", func() {
+	var (
+		env   *helpers.TestEnv
+		space *node.Node
+	)
+
+	JustBeforeEach(func() {
+		var err error
+		env, err = helpers.NewTestEnv()
+		Expect(err).ToNot(HaveOccurred())
+		space, err = env.Lookup.HomeNode(env.Ctx)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		if env != nil {
+			env.Cleanup()
+		}
+	})
+
+	spaceRef := func() *provider.ResourceId {
+		return &provider.ResourceId{OpaqueId: space.ID}
+	}
+	isSpace := func(n *node.Node) bool { return n.ID == space.ID }
+
+	Context("with the permissions of a space manager", func() {
+		JustBeforeEach(func() {
+			env.Permissions.On("HasPermission", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+			Expect(env.Fs.Delete(env.Ctx, &provider.Reference{Path: "/dir1"})).To(Succeed())
+		})
+
+		It("lists the items deleted in the space relative to it", func() {
+			ctx := appctx.ContextSetRecycleSpace(env.Ctx, spaceRef())
+			items, err := env.Fs.ListRecycle(ctx, "", "", "/")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(items).To(HaveLen(1))
+			Expect(items[0].Ref.ResourceId.OpaqueId).To(Equal(space.ID))
+			Expect(items[0].Ref.Path).To(Equal("./dir1"))
+		})
+
+		It("restores the items deleted in the space", func() {
+			ctx := appctx.ContextSetRecycleSpace(env.Ctx, spaceRef())
+			items, err := env.Fs.ListRecycle(ctx, "", "", "/")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(items).To(HaveLen(1))
+
+			Expect(env.Fs.RestoreRecycleItem(ctx, "", items[0].Key, "", nil)).To(Succeed())
+			n, err := env.Lookup.NodeFromPath(env.Ctx, "/dir1", false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n.Exists).To(BeTrue())
+
+			items, err = env.Fs.ListRecycle(ctx, "", "", "/")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(items).To(BeEmpty())
+		})
+
+		It("rejects the keys of items which were not deleted in the space", func() {
+			ctx := appctx.ContextSetRecycleSpace(env.Ctx, spaceRef())
+			err := env.Fs.PurgeRecycleItem(ctx, "", "unknown-key", "")
+			Expect(err).To(MatchError(ContainSubstring("unknown-key")))
+		})
+	})
+
+	Context("without the permission to manage the space", func() {
+		JustBeforeEach(func() {
+			env.Permissions.On("HasPermission", mock.Anything, mock.MatchedBy(isSpace), mock.Anything).Return(false, nil)
+			env.Permissions.On("HasPermission", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+			Expect(env.Fs.Delete(env.Ctx, &provider.Reference{Path: "/dir1"})).To(Succeed())
+		})
+
+		It("denies access to the trash of the space", func() {
+			ctx := appctx.ContextSetRecycleSpace(env.Ctx, spaceRef())
+			_, err := env.Fs.ListRecycle(ctx, "", "", "/")
+			Expect(err).To(MatchError(ContainSubstring("permission denied")))
+		})
+	})
+})
@@ -0,0 +1,91 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package decomposedfs
+
+import (
+	"context"
+
+	v1beta11 "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/share/policy"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
+	"github.com/pkg/xattr"
+)
+
+// The share policy of a space is kept as json on the space root. Space managers set it with the
+// policy.OpaqueKey entry of the space opaque when updating the space, an empty value removes it.
+// The share providers get it in the opaque of the resource info when the key is requested on a stat.
+
+// setSharePolicy validates and stores the policy of a space, it returns a status when the update is refused.
+func (fs *Decomposedfs) setSharePolicy(ctx context.Context, spaceRoot *node.Node, v []byte) (*v1beta11.Status, error) {
+	ok, err := fs.p.HasPermission(ctx, spaceRoot, func(rp *provider.ResourcePermissions) bool {
+		// only managers can change the policy
+		return rp.AddGrant && rp.RemoveGrant
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case !ok:
+		return &v1beta11.Status{Code: v1beta11.Code_CODE_PERMISSION_DENIED, Message: "only space managers can change the share policy"}, nil
+	}
+
+	if len(v) == 0 {
+		_ = xattr.Remove(spaceRoot.InternalPath(), xattrs.SpaceSharePolicyAttr)
+		return nil, nil
+	}
+	if _, err := policy.Decode(v); err != nil {
+		return &v1beta11.Status{Code: v1beta11.Code_CODE_INVALID_ARGUMENT, Message: err.Error()}, nil
+	}
+	return nil, spaceRoot.SetMetadata(xattrs.SpaceSharePolicyAttr, string(v))
+}
+
+// addSharePolicyToOpaque adds the policy of the space containing the node to the opaque of its resource info.
+func (fs *Decomposedfs) addSharePolicyToOpaque(ctx context.Context, n *node.Node, ri *provider.ResourceInfo) {
+	if n.SpaceRoot == nil {
+		if err := n.FindStorageSpaceRoot(); err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Str("node", n.ID).Msg("could not find the space root")
+			return
+		}
+	}
+	if n.SpaceRoot == nil {
+		return
+	}
+	if v, err := xattr.Get(n.SpaceRoot.InternalPath(), xattrs.SpaceSharePolicyAttr); err == nil {
+		ri.Opaque = policy.AddToOpaque(ri.Opaque, v)
+	}
+}
+
+func wantsSharePolicy(mdKeys []string) bool {
+	for _, k := range mdKeys {
+		// not included in "*", finding the space root may walk up the whole tree
+		if k == policy.OpaqueKey {
+			return true
+		}
+	}
+	return false
+}
+
+// addSharePolicyToSpace adds the policy to the opaque of a listed space.
+func addSharePolicyToSpace(nodePath string, space *provider.StorageSpace) {
+	if v, err := xattr.Get(nodePath, xattrs.SpaceSharePolicyAttr); err == nil {
+		space.Opaque = policy.AddToOpaque(space.Opaque, v)
+	}
+}
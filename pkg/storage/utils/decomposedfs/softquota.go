@@ -0,0 +1,123 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package decomposedfs
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
+	"github.com/pkg/xattr"
+)
+
+// With a soft quota the quota of a space may be exceeded by the configured overage. The time the usage
+// first exceeded the quota is recorded on the space root, once the grace period has passed since then
+// the quota is enforced again. The record is removed as soon as a write fits into the quota again.
+
+// checkLimits checks if the given size fits into the space, tolerating the overage of the soft quota.
+func (fs *Decomposedfs) checkLimits(ctx context.Context, spaceRoot *node.Node, size uint64) (bool, error) {
+	sq := &fs.o.SoftQuota
+	if !sq.Enabled() {
+		return node.CheckQuota(spaceRoot, size)
+	}
+
+	quotaBytes, err := xattr.Get(spaceRoot.InternalPath(), xattrs.QuotaAttr)
+	if err != nil {
+		// no quota, nothing to tolerate
+		return node.CheckQuota(spaceRoot, size)
+	}
+	quota, err := strconv.ParseUint(string(quotaBytes), 10, 64)
+	if err != nil || quota == 0 {
+		return node.CheckQuota(spaceRoot, size)
+	}
+
+	used, _ := spaceRoot.GetTreeSize()
+	if used+size <= quota {
+		if ok, err := node.CheckQuota(spaceRoot, size); !ok {
+			return ok, err
+		}
+		// the space is back within its quota, a later overage starts a new grace period
+		_ = xattr.Remove(spaceRoot.InternalPath(), xattrs.QuotaExceededAttr)
+		return true, nil
+	}
+
+	if ok, err := node.CheckQuotaLimit(spaceRoot, size, sq.HardLimit); !ok {
+		return ok, err
+	}
+
+	since, exceeded := quotaExceededSince(spaceRoot)
+	if !exceeded {
+		since = time.Now()
+		if err := xattr.Set(spaceRoot.InternalPath(), xattrs.QuotaExceededAttr, []byte(since.UTC().Format(time.RFC3339Nano))); err != nil {
+			return false, err
+		}
+		fs.warnQuotaExceeded(ctx, spaceRoot, used+size, quota, since)
+		return true, nil
+	}
+
+	if sq.GracePeriod > 0 && time.Since(since) > time.Duration(sq.GracePeriod)*time.Second {
+		return false, errtypes.InsufficientStorage("quota exceeded, grace period expired")
+	}
+	return true, nil
+}
+
+// quotaExceededSince returns the time the usage of the space exceeded its quota.
+func quotaExceededSince(spaceRoot *node.Node) (time.Time, bool) {
+	b, err := xattr.Get(spaceRoot.InternalPath(), xattrs.QuotaExceededAttr)
+	if err != nil {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, string(b))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func (fs *Decomposedfs) warnQuotaExceeded(ctx context.Context, spaceRoot *node.Node, used, quota uint64, since time.Time) {
+	log := appctx.GetLogger(ctx)
+	sq := &fs.o.SoftQuota
+	log.Warn().Str("spaceRoot", spaceRoot.ID).Uint64("used", used).Uint64("quota", quota).Msg("space exceeded its soft quota")
+
+	if fs.publisher == nil {
+		return
+	}
+	ev := events.SoftQuotaExceeded{
+		SpaceID:   spaceRoot.ID,
+		Used:      used,
+		Quota:     quota,
+		HardLimit: sq.HardLimit(quota),
+	}
+	if o, err := spaceRoot.Owner(); err == nil {
+		ev.SpaceOwner = o
+	}
+	if sq.GracePeriod > 0 {
+		until := since.Add(time.Duration(sq.GracePeriod) * time.Second)
+		ev.GraceUntil = &types.Timestamp{Seconds: uint64(until.Unix()), Nanos: uint32(until.Nanosecond())}
+	}
+	if err := events.Publish(fs.publisher, ev); err != nil {
+		log.Error().Err(err).Str("spaceRoot", spaceRoot.ID).Msg("could not publish soft quota warning")
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package decomposedfs
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
+	"github.com/pkg/xattr"
+	microevents "go-micro.dev/v4/events"
+)
+
+type recordingPublisher struct {
+	events []interface{}
+}
+
+func (p *recordingPublisher) Publish(_ string, ev interface{}, _ ...microevents.PublishOption) error {
+	p.events = append(p.events, ev)
+	return nil
+}
+
+// newSoftQuotaTestFS returns a storage tolerating 10% over the quota of 1000 bytes of its space,
+// which already uses the given size.
+func newSoftQuotaTestFS(t *testing.T, gracePeriod int, used uint64) (*Decomposedfs, *node.Node, *recordingPublisher) {
+	fs, spaceRoot, _ := newQuotaTestFS(t, map[string]interface{}{
+		"soft_quota": map[string]interface{}{"overage": 10, "grace_period": gracePeriod},
+	}, 1000)
+	if err := xattr.Set(spaceRoot.InternalPath(), xattrs.TreesizeAttr, []byte(strconv.FormatUint(used, 10))); err != nil {
+		t.Fatal(err)
+	}
+	p := &recordingPublisher{}
+	fs.publisher = p
+	return fs, spaceRoot, p
+}
+
+func setQuotaExceeded(t *testing.T, spaceRoot *node.Node, since time.Time) {
+	if err := xattr.Set(spaceRoot.InternalPath(), xattrs.QuotaExceededAttr, []byte(since.UTC().Format(time.RFC3339Nano))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSoftQuotaOverage(t *testing.T) {
+	fs, spaceRoot, p := newSoftQuotaTestFS(t, 3600, 950)
+
+	ok, err := fs.checkLimits(context.Background(), spaceRoot, 100)
+	if err != nil || !ok {
+		t.Fatalf("expected the overage to be tolerated, got %v", err)
+	}
+	since, exceeded := quotaExceededSince(spaceRoot)
+	if !exceeded || time.Since(since) > time.Minute {
+		t.Errorf("expected the time the quota was exceeded to be recorded, got %v", since)
+	}
+	if len(p.events) != 1 {
+		t.Fatalf("expected one warning, got %d", len(p.events))
+	}
+	ev := p.events[0].(events.SoftQuotaExceeded)
+	if ev.Used != 1050 || ev.Quota != 1000 || ev.HardLimit != 1100 || ev.GraceUntil == nil {
+		t.Errorf("unexpected warning %+v", ev)
+	}
+
+	// the grace period started with the first overage
+	if ok, err := fs.checkLimits(context.Background(), spaceRoot, 100); err != nil || !ok {
+		t.Fatalf("expected the overage to be tolerated during the grace period, got %v", err)
+	}
+	if again, _ := quotaExceededSince(spaceRoot); !again.Equal(since) {
+		t.Errorf("expected the start of the grace period to be kept, got %v instead of %v", again, since)
+	}
+	if len(p.events) != 1 {
+		t.Errorf("expected the warning to be sent once, got %d", len(p.events))
+	}
+}
+
+func TestSoftQuotaHardLimit(t *testing.T) {
+	fs, spaceRoot, p := newSoftQuotaTestFS(t, 3600, 950)
+
+	ok, err := fs.checkLimits(context.Background(), spaceRoot, 200)
+	if ok {
+		t.Fatal("expected the write exceeding the hard limit to be rejected")
+	}
+	if _, is := err.(errtypes.InsufficientStorage); !is {
+		t.Errorf("expected an insufficient storage error, got %v", err)
+	}
+	if _, exceeded := quotaExceededSince(spaceRoot); exceeded {
+		t.Error("expected no grace period to start for a rejected write")
+	}
+	if len(p.events) != 0 {
+		t.Errorf("expected no warning, got %d", len(p.events))
+	}
+}
+
+func TestSoftQuotaGracePeriodExpired(t *testing.T) {
+	fs, spaceRoot, _ := newSoftQuotaTestFS(t, 3600, 1050)
+	setQuotaExceeded(t, spaceRoot, time.Now().Add(-2*time.Hour))
+
+	ok, err := fs.checkLimits(context.Background(), spaceRoot, 10)
+	if ok {
+		t.Fatal("expected the overage to be rejected once the grace period expired")
+	}
+	if _, is := err.(errtypes.InsufficientStorage); !is {
+		t.Errorf("expected an insufficient storage error, got %v", err)
+	}
+}
+
+func TestSoftQuotaWithoutGracePeriod(t *testing.T) {
+	fs, spaceRoot, _ := newSoftQuotaTestFS(t, 0, 1050)
+	setQuotaExceeded(t, spaceRoot, time.Now().Add(-24*time.Hour))
+
+	if ok, err := fs.checkLimits(context.Background(), spaceRoot, 10); err != nil || !ok {
+		t.Fatalf("expected the overage to be tolerated indefinitely, got %v", err)
+	}
+}
+
+func TestSoftQuotaBackWithinQuota(t *testing.T) {
+	fs, spaceRoot, _ := newSoftQuotaTestFS(t, 3600, 500)
+	setQuotaExceeded(t, spaceRoot, time.Now().Add(-2*time.Hour))
+
+	if ok, err := fs.checkLimits(context.Background(), spaceRoot, 100); err != nil || !ok {
+		t.Fatalf("expected the write fitting into the quota to be accepted, got %v", err)
+	}
+	if _, exceeded := quotaExceededSince(spaceRoot); exceeded {
+		t.Error("expected the record of the overage to be removed")
+	}
+}
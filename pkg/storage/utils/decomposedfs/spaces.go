@@ -36,6 +36,7 @@ import (
 	"github.com/cs3org/reva/pkg/appctx"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/share/policy"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
 	"github.com/cs3org/reva/pkg/utils"
@@ -302,6 +303,16 @@ func (fs *Decomposedfs) UpdateStorageSpace(ctx context.Context, req *provider.Up
 	}
 	space.Owner = u
 
+	if e, ok := space.GetOpaque().GetMap()[policy.OpaqueKey]; ok {
+		st, err := fs.setSharePolicy(ctx, node, e.Value)
+		if err != nil {
+			return nil, err
+		}
+		if st != nil {
+			return &provider.UpdateStorageSpaceResponse{Status: st}, nil
+		}
+	}
+
 	if space.Name != "" {
 		if err := node.SetMetadata(xattrs.SpaceNameAttr, space.Name); err != nil {
 			return nil, err
@@ -430,5 +441,7 @@ func (fs *Decomposedfs) storageSpaceFromNode(ctx context.Context, node *node.Nod
 		}
 	}
 
+	addSharePolicyToSpace(nodePath, space)
+
 	return space, nil
 }
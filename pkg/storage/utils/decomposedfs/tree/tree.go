@@ -545,19 +545,11 @@ func (t *Tree) PurgeRecycleItemFunc(ctx context.Context, key string, path string
 	}
 
 	fn := func() error {
-		if err := os.RemoveAll(deletedNodePath); err != nil {
+		if err := t.purgeNode(deletedNodePath); err != nil {
 			log.Error().Err(err).Str("deletedNodePath", deletedNodePath).Msg("error deleting trash node")
 			return err
 		}
 
-		// delete blob from blobstore
-		if rn.BlobID != "" {
-			if err = t.DeleteBlob(rn.BlobID); err != nil {
-				log.Error().Err(err).Str("trashItem", trashItem).Msg("error deleting trash item blob")
-				return err
-			}
-		}
-
 		// delete item link in trash
 		if err = os.Remove(trashItem); err != nil {
 			log.Error().Err(err).Str("trashItem", trashItem).Msg("error deleting trash item")
@@ -570,6 +562,54 @@ func (t *Tree) PurgeRecycleItemFunc(ctx context.Context, key string, path string
 	return rn, fn, nil
 }
 
+// purgeNode removes a trashed node for good. The child nodes of a folder are
+// purged as well, and the blobs and revisions of the files are deleted.
+func (t *Tree) purgeNode(nodePath string) error {
+	fi, err := os.Stat(nodePath)
+	if err != nil {
+		return err
+	}
+
+	if fi.IsDir() {
+		entries, err := os.ReadDir(nodePath)
+		if err != nil {
+			return err
+		}
+		// the entries of a folder node are links to its child nodes
+		for _, e := range entries {
+			link, err := os.Readlink(filepath.Join(nodePath, e.Name()))
+			if err != nil {
+				continue
+			}
+			if err := t.purgeNode(t.lookup.InternalPath(filepath.Base(link))); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	} else {
+		// the revisions are named after the id of the node, without the suffix of a trashed node
+		id := strings.SplitN(filepath.Base(nodePath), ".T.", 2)[0]
+		revisions, _ := filepath.Glob(t.lookup.InternalPath(id) + ".REV.*")
+		for _, r := range revisions {
+			if err := t.removeWithBlob(r); err != nil {
+				return err
+			}
+		}
+	}
+	return t.removeWithBlob(nodePath)
+}
+
+// removeWithBlob removes a node or revision and the blob it references.
+func (t *Tree) removeWithBlob(path string) error {
+	blobID, _ := xattr.Get(path, xattrs.BlobIDAttr)
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+	if len(blobID) > 0 {
+		return t.DeleteBlob(string(blobID))
+	}
+	return nil
+}
+
 // Propagate propagates changes to the root of the tree
 func (t *Tree) Propagate(ctx context.Context, n *node.Node) (err error) {
 	sublog := appctx.GetLogger(ctx).With().Interface("node", n).Logger()
@@ -777,13 +817,29 @@ func (t *Tree) createNode(n *node.Node, owner *userpb.UserId) (err error) {
 }
 
 // TODO refactor the returned params into Node properties? would make all the path transformations go away...
+type trashOwnerKey struct{}
+
+// ContextWithTrashOwner makes the recycle functions access the trash of the
+// given owner instead of the one of the current user, e.g. the trash holding
+// the items deleted in a space.
+func ContextWithTrashOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, trashOwnerKey{}, owner)
+}
+
+func trashOwner(ctx context.Context) string {
+	if owner, ok := ctx.Value(trashOwnerKey{}).(string); ok {
+		return owner
+	}
+	return ctxpkg.ContextMustGetUser(ctx).Id.OpaqueId
+}
+
 func (t *Tree) readRecycleItem(ctx context.Context, key, path string) (n *node.Node, trashItem string, deletedNodePath string, origin string, err error) {
 	if key == "" {
 		return nil, "", "", "", errtypes.InternalError("key is empty")
 	}
 
-	u := ctxpkg.ContextMustGetUser(ctx)
-	trashItem = filepath.Join(t.lookup.InternalRoot(), "trash", u.Id.OpaqueId, key, path)
+	trashOwnerID := trashOwner(ctx)
+	trashItem = filepath.Join(t.lookup.InternalRoot(), "trash", trashOwnerID, key, path)
 
 	var link string
 	link, err = os.Readlink(trashItem)
@@ -851,7 +907,7 @@ func (t *Tree) readRecycleItem(ctx context.Context, key, path string) (n *node.N
 
 	deletedNodeRootPath := deletedNodePath
 	if path != "" && path != "/" {
-		trashItemRoot := filepath.Join(t.lookup.InternalRoot(), "trash", u.Id.OpaqueId, key)
+		trashItemRoot := filepath.Join(t.lookup.InternalRoot(), "trash", trashOwnerID, key)
 		var rootLink string
 		rootLink, err = os.Readlink(trashItemRoot)
 		if err != nil {
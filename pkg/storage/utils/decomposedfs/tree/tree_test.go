@@ -220,6 +220,56 @@ var _ = Describe("Tree", func() {
 		})
 	})
 
+	Context("with a directory containing files", func() {
+		var (
+			dir, file1, subdir, file2 *node.Node
+			revision                  string
+		)
+
+		JustBeforeEach(func() {
+			var err error
+			dir, err = env.Lookup.NodeFromPath(env.Ctx, "dir1", false)
+			Expect(err).ToNot(HaveOccurred())
+			file1, err = env.Lookup.NodeFromPath(env.Ctx, "dir1/file1", false)
+			Expect(err).ToNot(HaveOccurred())
+			subdir, err = env.Lookup.NodeFromPath(env.Ctx, "dir1/subdir1", false)
+			Expect(err).ToNot(HaveOccurred())
+			file2, err = env.Lookup.NodeFromPath(env.Ctx, "dir1/subdir1/file2", false)
+			Expect(err).ToNot(HaveOccurred())
+
+			// an old version of file1
+			revision = file1.InternalPath() + ".REV.2022-01-01T00:00:00Z"
+			Expect(os.WriteFile(revision, nil, 0600)).To(Succeed())
+			Expect(xattr.Set(revision, xattrs.BlobIDAttr, []byte("file1-rev-blobid"))).To(Succeed())
+
+			env.Blobstore.On("Delete", mock.AnythingOfType("string")).Return(nil)
+			Expect(t.Delete(env.Ctx, dir)).To(Succeed())
+		})
+
+		Describe("PurgeRecycleItemFunc", func() {
+			JustBeforeEach(func() {
+				_, purgeFunc, err := t.PurgeRecycleItemFunc(env.Ctx, dir.ID, "")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(purgeFunc()).To(Succeed())
+			})
+
+			It("removes the child nodes", func() {
+				for _, n := range []*node.Node{file1, subdir, file2} {
+					_, err := os.Stat(n.InternalPath())
+					Expect(os.IsNotExist(err)).To(BeTrue())
+				}
+				_, err := os.Stat(revision)
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+
+			It("deletes the blobs of the files and their revisions", func() {
+				env.Blobstore.AssertCalled(GinkgoT(), "Delete", "file1-blobid")
+				env.Blobstore.AssertCalled(GinkgoT(), "Delete", "file2-blobid")
+				env.Blobstore.AssertCalled(GinkgoT(), "Delete", "file1-rev-blobid")
+			})
+		})
+	})
+
 	Describe("Propagate", func() {
 		var dir *node.Node
 
@@ -51,9 +51,12 @@ import (
 
 var defaultFilePerm = os.FileMode(0664)
 
+// verifiedChecksums is implemented by readers that verified the checksums sent by the client
+type verifiedChecksums interface {
+	Verified() map[string]string
+}
+
 // Upload uploads data to the given resource
-// TODO Upload (and InitiateUpload) needs a way to receive the expected checksum.
-// Maybe in metadata as 'checksum' => 'sha1 aeosvp45w5xaeoe' = lowercase, space separated?
 func (fs *Decomposedfs) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) (err error) {
 	upload, err := fs.GetUpload(ctx, ref.GetPath())
 	if err != nil {
@@ -93,6 +96,17 @@ func (fs *Decomposedfs) Upload(ctx context.Context, ref *provider.Reference, r i
 		return errors.Wrap(err, "Decomposedfs: error writing to binary file")
 	}
 
+	// keep the checksum the data transfer verified so FinishUpload checks and persists it,
+	// assembled chunks are read from disk and carry none
+	if v, ok := r.(verifiedChecksums); ok && uploadInfo.info.MetaData["checksum"] == "" {
+		for _, algo := range []string{"sha1", "md5", "adler32"} {
+			if sum, ok := v.Verified()[algo]; ok {
+				uploadInfo.info.MetaData["checksum"] = algo + " " + sum
+				break
+			}
+		}
+	}
+
 	return uploadInfo.FinishUpload(ctx)
 }
 
@@ -149,7 +163,7 @@ func (fs *Decomposedfs) InitiateUpload(ctx context.Context, ref *provider.Refere
 
 	log.Debug().Interface("info", info).Interface("node", n).Interface("metadata", metadata).Msg("Decomposedfs: resolved filename")
 
-	_, err = node.CheckQuota(n.SpaceRoot, uint64(info.Size))
+	_, err = fs.checkQuota(ctx, n.SpaceRoot, uint64(info.Size))
 	if err != nil {
 		return nil, err
 	}
@@ -466,7 +480,7 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) (err error) {
 	)
 	n.SpaceRoot = node.New(upload.info.Storage["SpaceRoot"], "", "", 0, "", nil, upload.fs.lu)
 
-	_, err = node.CheckQuota(n.SpaceRoot, uint64(fi.Size()))
+	_, err = upload.fs.checkQuota(upload.ctx, n.SpaceRoot, uint64(fi.Size()))
 	if err != nil {
 		return err
 	}
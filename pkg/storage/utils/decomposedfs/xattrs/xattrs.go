@@ -78,9 +78,15 @@ const (
 	// the quota for the storage space / tree, regardless who accesses it
 	QuotaAttr string = OcisPrefix + "quota"
 
+	// the time the usage of a storage space exceeded its soft quota, stored as RFC3339Nano
+	QuotaExceededAttr string = OcisPrefix + "quota.exceeded"
+
 	// the name given to a storage space. It should not contain any semantics as its only purpose is to be read.
 	SpaceNameAttr string = OcisPrefix + "space.name"
 
+	// the sharing policy space managers set on a storage space, stored as json
+	SpaceSharePolicyAttr string = OcisPrefix + "space.share_policy"
+
 	UserAcePrefix  string = "u:"
 	GroupAcePrefix string = "g:"
 )
@@ -0,0 +1,305 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package encryption encrypts the content of files at rest. Every file is
+// encrypted with its own random data key, which is stored in the header of
+// the file wrapped by a master key or a KMS. The content is split in segments
+// sealed with AES-256-GCM, so that it can be read from any offset.
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	// segmentSize is the size of the plaintext segments sealed separately.
+	segmentSize = 64 * 1024
+	// the segments are followed by the GCM tag
+	sealedSegmentSize = segmentSize + 16
+	keySize           = 32
+)
+
+// magic starts the header of the encrypted files, which then contains the
+// length of the wrapped data key as a big endian uint16 and the key itself.
+var magic = []byte("REVAENC1")
+
+// ErrNotEncrypted is returned when reading a file which isn't encrypted.
+var ErrNotEncrypted = errors.New("encryption: file is not encrypted")
+
+// Encrypter encrypts and decrypts the content of files.
+type Encrypter struct {
+	keys KeyWrapper
+}
+
+// New returns an Encrypter wrapping the data keys of the files as configured.
+func New(c *Config) (*Encrypter, error) {
+	keys, err := c.keyWrapper()
+	if err != nil {
+		return nil, err
+	}
+	return &Encrypter{keys: keys}, nil
+}
+
+// Encrypt writes the encrypted content of src to dst.
+func (e *Encrypter) Encrypt(ctx context.Context, dst io.Writer, src io.Reader) error {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("encryption: error generating the data key: %w", err)
+	}
+	wrapped, err := e.keys.Wrap(ctx, key)
+	if err != nil {
+		return fmt.Errorf("encryption: error wrapping the data key: %w", err)
+	}
+	if len(wrapped) > 0xffff {
+		return fmt.Errorf("encryption: wrapped data key too long: %d bytes", len(wrapped))
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, len(magic)+2, len(magic)+2+len(wrapped))
+	copy(header, magic)
+	binary.BigEndian.PutUint16(header[len(magic):], uint16(len(wrapped)))
+	if _, err := dst.Write(append(header, wrapped...)); err != nil {
+		return err
+	}
+
+	// a segment is only known to be the last one once the next read returns nothing
+	br := bufio.NewReaderSize(src, segmentSize)
+	plain := make([]byte, segmentSize)
+	sealed := make([]byte, 0, sealedSegmentSize)
+	for i := uint64(0); ; i++ {
+		n, err := io.ReadFull(br, plain)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		last := n < segmentSize
+		if !last {
+			if _, err := br.Peek(1); err == io.EOF {
+				last = true
+			} else if err != nil {
+				return err
+			}
+		}
+		sealed = aead.Seal(sealed[:0], nonce(i, last), plain[:n], nil)
+		if _, err := dst.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// Reader reads the decrypted content of a file from any offset.
+type Reader struct {
+	r       io.ReaderAt
+	aead    cipher.AEAD
+	offset  int64 // of the first segment in the file
+	size    int64 // of the plaintext
+	pos     int64
+	segment int64 // index of the decrypted segment in buf, -1 if none
+	buf     []byte
+}
+
+// NewReader returns a Reader decrypting the content of the encrypted file of
+// the given size, or ErrNotEncrypted.
+func (e *Encrypter) NewReader(ctx context.Context, r io.ReaderAt, size int64) (*Reader, error) {
+	wrapped, offset, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	plainSize, err := plaintextSize(size - offset)
+	if err != nil {
+		return nil, err
+	}
+	key, err := e.keys.Unwrap(ctx, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: error unwrapping the data key: %w", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		r:       r,
+		aead:    aead,
+		offset:  offset,
+		size:    plainSize,
+		segment: -1,
+		buf:     make([]byte, 0, sealedSegmentSize),
+	}, nil
+}
+
+// Size returns the size of the decrypted content.
+func (r *Reader) Size() int64 {
+	return r.size
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+	seg := r.pos / segmentSize
+	if seg != r.segment {
+		if err := r.load(seg); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf[r.pos-seg*segmentSize:])
+	r.pos += int64(n)
+	return n, nil
+}
+
+// Seek implements io.Seeker, which allows serving range requests.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("encryption: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("encryption: negative position")
+	}
+	r.pos = offset
+	return offset, nil
+}
+
+func (r *Reader) load(seg int64) error {
+	segments := segmentCount(r.size)
+	start := r.offset + seg*sealedSegmentSize
+	length := int64(sealedSegmentSize)
+	last := seg == segments-1
+	if last {
+		length = r.size - seg*segmentSize + 16
+	}
+
+	sealed := make([]byte, length)
+	if _, err := r.r.ReadAt(sealed, start); err != nil && err != io.EOF {
+		return err
+	}
+	plain, err := r.aead.Open(r.buf[:0], nonce(uint64(seg), last), sealed, nil)
+	if err != nil {
+		return fmt.Errorf("encryption: error decrypting segment %d: %w", seg, err)
+	}
+	r.buf, r.segment = plain, seg
+	return nil
+}
+
+// PlaintextSize returns the size of the decrypted content of the encrypted
+// file of the given size, or ErrNotEncrypted. The data key isn't unwrapped.
+func PlaintextSize(r io.ReaderAt, size int64) (int64, error) {
+	_, offset, err := readHeader(r)
+	if err != nil {
+		return 0, err
+	}
+	return plaintextSize(size - offset)
+}
+
+// FileSize returns the size of the decrypted content of the file at the given
+// path, or its size when it isn't encrypted.
+func FileSize(fn string) (int64, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size, err := PlaintextSize(f, fi.Size())
+	if err == ErrNotEncrypted {
+		return fi.Size(), nil
+	}
+	return size, err
+}
+
+func readHeader(r io.ReaderAt) ([]byte, int64, error) {
+	header := make([]byte, len(magic)+2)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		if err == io.EOF {
+			return nil, 0, ErrNotEncrypted
+		}
+		return nil, 0, err
+	}
+	if !bytes.Equal(header[:len(magic)], magic) {
+		return nil, 0, ErrNotEncrypted
+	}
+	wrapped := make([]byte, binary.BigEndian.Uint16(header[len(magic):]))
+	if _, err := r.ReadAt(wrapped, int64(len(header))); err != nil {
+		return nil, 0, fmt.Errorf("encryption: error reading the data key: %w", err)
+	}
+	return wrapped, int64(len(header) + len(wrapped)), nil
+}
+
+// plaintextSize returns the size of the plaintext sealed in the given number of bytes.
+func plaintextSize(sealed int64) (int64, error) {
+	full, rest := sealed/sealedSegmentSize, sealed%sealedSegmentSize
+	switch {
+	case rest == 0 && full > 0:
+		return full * segmentSize, nil
+	case rest >= 16:
+		return full*segmentSize + rest - 16, nil
+	default:
+		return 0, errors.New("encryption: truncated file")
+	}
+}
+
+// segmentCount returns the number of segments of the given plaintext size,
+// empty files having a single empty one.
+func segmentCount(size int64) int64 {
+	if size == 0 {
+		return 1
+	}
+	return (size + segmentSize - 1) / segmentSize
+}
+
+// nonce returns the nonce of the segment, which is unique as every file has its
+// own data key. Flagging the last segment prevents truncating the file.
+func nonce(seg uint64, last bool) []byte {
+	n := make([]byte, 12)
+	if last {
+		n[0] = 1
+	}
+	binary.BigEndian.PutUint64(n[4:], seg)
+	return n
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: invalid key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,166 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestEncrypter(t *testing.T) *Encrypter {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	e, err := New(&Config{MasterKey: base64.StdEncoding.EncodeToString(key)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func encrypt(t *testing.T, e *Encrypter, plain []byte) []byte {
+	var sealed bytes.Buffer
+	if err := e.Encrypt(context.Background(), &sealed, bytes.NewReader(plain)); err != nil {
+		t.Fatal(err)
+	}
+	return sealed.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	e := newTestEncrypter(t)
+	for _, size := range []int{0, 1, segmentSize - 1, segmentSize, 2*segmentSize + 5} {
+		plain := make([]byte, size)
+		if _, err := rand.Read(plain); err != nil {
+			t.Fatal(err)
+		}
+		sealed := encrypt(t, e, plain)
+
+		if s, err := PlaintextSize(bytes.NewReader(sealed), int64(len(sealed))); err != nil || s != int64(size) {
+			t.Fatalf("size %d: unexpected plaintext size %d, %v", size, s, err)
+		}
+		r, err := e.NewReader(context.Background(), bytes.NewReader(sealed), int64(len(sealed)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Fatalf("size %d: decrypted content differs", size)
+		}
+
+		if size > 10 {
+			off := int64(size - 10)
+			if _, err := r.Seek(off, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ioutil.ReadAll(r)
+			if err != nil || !bytes.Equal(got, plain[off:]) {
+				t.Fatalf("size %d: unexpected content after seeking to %d: %v", size, off, err)
+			}
+		}
+	}
+}
+
+func TestTampered(t *testing.T) {
+	e := newTestEncrypter(t)
+	sealed := encrypt(t, e, bytes.Repeat([]byte("a"), 2*segmentSize))
+
+	modified := append([]byte{}, sealed...)
+	modified[len(modified)-20] ^= 1
+	r, err := e.NewReader(context.Background(), bytes.NewReader(modified), int64(len(modified)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(r); err == nil {
+		t.Fatal("expected modified content not to be decrypted")
+	}
+
+	// dropping the last segment leaves a valid segment that wasn't sealed as the last one
+	truncated := sealed[:len(sealed)-sealedSegmentSize]
+	r, err = e.NewReader(context.Background(), bytes.NewReader(truncated), int64(len(truncated)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(r); err == nil {
+		t.Fatal("expected truncated content not to be decrypted")
+	}
+}
+
+func TestNotEncrypted(t *testing.T) {
+	e := newTestEncrypter(t)
+	plain := []byte("plain content")
+	if _, err := e.NewReader(context.Background(), bytes.NewReader(plain), int64(len(plain))); err != ErrNotEncrypted {
+		t.Fatalf("expected ErrNotEncrypted, got %v", err)
+	}
+	if _, err := PlaintextSize(bytes.NewReader(nil), 0); err != ErrNotEncrypted {
+		t.Fatalf("expected ErrNotEncrypted for an empty file, got %v", err)
+	}
+}
+
+func TestTransitKMS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var req map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/files":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"ciphertext": "vault:v1:" + req["plaintext"]}})
+		case "/v1/transit/decrypt/files":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"plaintext": strings.TrimPrefix(req["ciphertext"], "vault:v1:")}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	e, err := New(&Config{KMSURL: srv.URL, KMSKey: "files", KMSToken: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := []byte("hello")
+	sealed := encrypt(t, e, plain)
+	r, err := e.NewReader(context.Background(), bytes.NewReader(sealed), int64(len(sealed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ioutil.ReadAll(r); err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("unexpected content %q: %v", got, err)
+	}
+}
+
+func TestInvalidMasterKey(t *testing.T) {
+	if _, err := New(&Config{MasterKey: base64.StdEncoding.EncodeToString([]byte("short"))}); err == nil {
+		t.Fatal("expected an error for a short master key")
+	}
+}
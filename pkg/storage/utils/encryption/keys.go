@@ -0,0 +1,168 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/rhttp"
+)
+
+// Config configures the encryption of the files at rest.
+type Config struct {
+	MasterKey     string `mapstructure:"master_key" docs:";Base64 encoded 256 bits key wrapping the data keys of the files. The files are not encrypted if neither a master key nor a KMS is configured."`
+	MasterKeyFile string `mapstructure:"master_key_file" docs:";File containing the base64 encoded master key, instead of master_key."`
+	KMSURL        string `mapstructure:"kms_url" docs:";URL of a KMS with a Vault compatible transit API wrapping the data keys, instead of the master key."`
+	KMSKey        string `mapstructure:"kms_key" docs:";Name of the transit key of the KMS."`
+	KMSToken      string `mapstructure:"kms_token" docs:";Token authenticating to the KMS."`
+}
+
+// Enabled returns whether the files are encrypted.
+func (c *Config) Enabled() bool {
+	return c.MasterKey != "" || c.MasterKeyFile != "" || c.KMSURL != ""
+}
+
+func (c *Config) keyWrapper() (KeyWrapper, error) {
+	if c.KMSURL != "" {
+		if c.KMSKey == "" {
+			return nil, errors.New("encryption: kms_key is required with kms_url")
+		}
+		return &transitKMS{
+			url:    strings.TrimSuffix(c.KMSURL, "/") + "/v1/transit",
+			key:    c.KMSKey,
+			token:  c.KMSToken,
+			client: rhttp.GetHTTPClient(rhttp.Timeout(10 * time.Second)),
+		}, nil
+	}
+
+	encoded := c.MasterKey
+	if c.MasterKeyFile != "" {
+		b, err := ioutil.ReadFile(c.MasterKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("encryption: error reading the master key file: %w", err)
+		}
+		encoded = string(bytes.TrimSpace(b))
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != keySize {
+		return nil, errors.New("encryption: the master key must be 32 base64 encoded bytes")
+	}
+	return &masterKey{key: key}, nil
+}
+
+// KeyWrapper wraps the data keys of the files so that they can be stored along
+// with the encrypted content.
+type KeyWrapper interface {
+	Wrap(ctx context.Context, key []byte) ([]byte, error)
+	Unwrap(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// masterKey wraps the data keys with AES-256-GCM, prefixing them with the nonce.
+type masterKey struct {
+	key []byte
+}
+
+func (m *masterKey) Wrap(ctx context.Context, key []byte) ([]byte, error) {
+	aead, err := newAEAD(m.key)
+	if err != nil {
+		return nil, err
+	}
+	n := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(n); err != nil {
+		return nil, err
+	}
+	return aead.Seal(n, n, key, nil), nil
+}
+
+func (m *masterKey) Unwrap(ctx context.Context, wrapped []byte) ([]byte, error) {
+	aead, err := newAEAD(m.key)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errors.New("wrapped key too short")
+	}
+	return aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], nil)
+}
+
+// transitKMS wraps the data keys with the transit secrets engine of a Vault
+// compatible KMS, so that the master key never leaves it.
+type transitKMS struct {
+	url    string
+	key    string
+	token  string
+	client *http.Client
+}
+
+func (t *transitKMS) Wrap(ctx context.Context, key []byte) ([]byte, error) {
+	var res struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	if err := t.do(ctx, "encrypt", map[string]string{"plaintext": base64.StdEncoding.EncodeToString(key)}, &res); err != nil {
+		return nil, err
+	}
+	return []byte(res.Data.Ciphertext), nil
+}
+
+func (t *transitKMS) Unwrap(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var res struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := t.do(ctx, "decrypt", map[string]string{"ciphertext": string(wrapped)}, &res); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(res.Data.Plaintext)
+}
+
+func (t *transitKMS) do(ctx context.Context, op string, body interface{}, res interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url+"/"+op+"/"+t.key, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", t.token)
+
+	httpRes, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling the KMS: %w", err)
+	}
+	defer httpRes.Body.Close()
+	if httpRes.StatusCode != http.StatusOK {
+		return fmt.Errorf("KMS %s failed with status %d", op, httpRes.StatusCode)
+	}
+	return json.NewDecoder(httpRes.Body).Decode(res)
+}
@@ -168,4 +168,10 @@ type Config struct {
 	// TokenExpiry stores in seconds the time after which generated tokens will expire
 	// Default is 3600
 	TokenExpiry int
+
+	// StatCacheTTL is the time in seconds the metadata of the resources looked up by path is cached
+	// for per user, in the eos_stat namespace of the shared cache. Writes through this driver invalidate
+	// the entries of the writing user; other users may see changes, like the propagated etags of parent
+	// folders, only after the TTL. 0 disables the cache.
+	StatCacheTTL int `mapstructure:"stat_cache_ttl"`
 }
@@ -47,6 +47,7 @@ import (
 	"github.com/cs3org/reva/pkg/eosclient/eosbinary"
 	"github.com/cs3org/reva/pkg/eosclient/eosgrpc"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/kvcache"
 	"github.com/cs3org/reva/pkg/mime"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
@@ -62,6 +63,11 @@ import (
 
 const (
 	refTargetAttrKey = "reva.target"
+
+	// mtimeAttrKey stores the modification time set by clients, as EOS does
+	// not allow changing it. The value also records the native mtime at the
+	// time it was set, so that it is ignored once the file is written again.
+	mtimeAttrKey = "reva.mtime"
 )
 
 const (
@@ -156,6 +162,7 @@ type eosfs struct {
 	singleUserAuth eosclient.Authorization
 	userIDCache    *ttlcache.Cache
 	tokenCache     gcache.Cache
+	statCache      *kvcache.Cache
 }
 
 // NewEOSFS returns a storage.FS interface implementation that connects to an EOS instance
@@ -225,6 +232,7 @@ func NewEOSFS(c *Config) (storage.FS, error) {
 		chunkHandler: chunking.NewChunkHandler(c.CacheDirectory),
 		userIDCache:  ttlcache.NewCache(),
 		tokenCache:   gcache.New(c.UserIDCacheSize).LFU().Build(),
+		statCache:    kvcache.Namespace("eos_stat", time.Duration(c.StatCacheTTL)*time.Second),
 	}
 
 	eosfs.userIDCache.SetCacheSizeLimit(c.UserIDCacheSize)
@@ -268,6 +276,18 @@ func (fs *eosfs) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// Warmup probes the connection to EOS by statting the namespace.
+func (fs *eosfs) Warmup(ctx context.Context) error {
+	auth, err := fs.getRootAuth(ctx)
+	if err != nil {
+		return errors.Wrap(err, "eosfs: error getting root auth")
+	}
+	if _, err := fs.c.GetFileInfoByPath(ctx, auth, fs.conf.Namespace); err != nil {
+		return errors.Wrap(err, "eosfs: error statting namespace "+fs.conf.Namespace)
+	}
+	return nil
+}
+
 func getUser(ctx context.Context) (*userpb.User, error) {
 	u, ok := ctxpkg.ContextGetUser(ctx)
 	if !ok {
@@ -517,14 +537,22 @@ func (fs *eosfs) SetArbitraryMetadata(ctx context.Context, ref *provider.Referen
 	if err != nil {
 		return err
 	}
+	defer fs.forgetStat(ctx, fn)
 
 	for k, v := range md.Metadata {
 		if k == "" || v == "" {
 			return errtypes.BadRequest(fmt.Sprintf("eosfs: key or value is empty: key:%s, value:%s", k, v))
 		}
 
+		if k == "mtime" {
+			if err := fs.setMtime(ctx, auth, fn, v); err != nil {
+				return err
+			}
+			continue
+		}
+
 		// do not allow to set a lock key attr
-		if k == LockPayloadKey || k == LockExpirationKey || k == LockTypeKey {
+		if k == LockPayloadKey || k == LockExpirationKey || k == LockTypeKey || k == mtimeAttrKey {
 			return errtypes.BadRequest(fmt.Sprintf("eosfs: key %s not allowed", k))
 		}
 
@@ -545,6 +573,53 @@ func (fs *eosfs) SetArbitraryMetadata(ctx context.Context, ref *provider.Referen
 	return nil
 }
 
+// setMtime records the client provided modification time of a file.
+func (fs *eosfs) setMtime(ctx context.Context, auth eosclient.Authorization, fn, mtime string) error {
+	if _, err := utils.ParseMTime(mtime); err != nil {
+		return errtypes.BadRequest("eosfs: invalid mtime " + mtime)
+	}
+
+	eosFileInfo, err := fs.c.GetFileInfoByPath(ctx, auth, fn)
+	if err != nil {
+		return errors.Wrap(err, "eosfs: error getting file info")
+	}
+
+	attr := &eosclient.Attribute{
+		Type: UserAttr,
+		Key:  mtimeAttrKey,
+		Val:  fmt.Sprintf("%s:%d", mtime, eosFileInfo.MTimeSec),
+	}
+	if err := fs.c.SetAttr(ctx, auth, attr, false, false, fn); err != nil {
+		return errors.Wrap(err, "eosfs: error setting mtime")
+	}
+	return nil
+}
+
+// getMtime returns the modification time of a file, preferring the one set by
+// clients as long as the file has not been modified since.
+func getMtime(eosFileInfo *eosclient.FileInfo) *types.Timestamp {
+	native := &types.Timestamp{
+		Seconds: eosFileInfo.MTimeSec,
+		Nanos:   eosFileInfo.MTimeNanos,
+	}
+	v, ok := eosFileInfo.Attrs[mtimeAttrKey]
+	if !ok {
+		return native
+	}
+	i := strings.LastIndex(v, ":")
+	if i < 0 || v[i+1:] != strconv.FormatUint(eosFileInfo.MTimeSec, 10) {
+		return native
+	}
+	mtime, err := utils.ParseMTime(v[:i])
+	if err != nil {
+		return native
+	}
+	return &types.Timestamp{
+		Seconds: uint64(mtime.Unix()),
+		Nanos:   uint32(mtime.Nanosecond()),
+	}
+}
+
 func (fs *eosfs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
 	if len(keys) == 0 {
 		return errtypes.BadRequest("eosfs: no keys set")
@@ -554,6 +629,7 @@ func (fs *eosfs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Refer
 	if err != nil {
 		return err
 	}
+	defer fs.forgetStat(ctx, fn)
 
 	for _, k := range keys {
 		if k == "" {
@@ -686,9 +762,12 @@ func (fs *eosfs) getLock(ctx context.Context, auth eosclient.Authorization, user
 
 	l, err := fs.getLockContent(ctx, auth, path, expiration)
 	if err != nil {
-		if !errors.Is(err, eosclient.AttrNotExistsError) {
+		// a missing lock attribute means the resource is not locked, any
+		// other error must reach the caller so that a lock is never ignored
+		if _, ok := err.(errtypes.NotFound); ok || errors.Is(err, eosclient.AttrNotExistsError) {
 			return nil, errtypes.NotFound("lock not found for ref")
 		}
+		return nil, err
 	}
 	return l, nil
 }
@@ -1230,7 +1309,7 @@ func (fs *eosfs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []st
 	}
 
 	fn = fs.wrap(ctx, p)
-	eosFileInfo, err := fs.c.GetFileInfoByPath(ctx, auth, fn)
+	eosFileInfo, err := fs.getCachedFileInfoByPath(ctx, u, auth, fn)
 	if err != nil {
 		return nil, err
 	}
@@ -1238,6 +1317,43 @@ func (fs *eosfs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []st
 	return fs.convertToResourceInfo(ctx, eosFileInfo)
 }
 
+// statCacheKey returns the key of the cached metadata of a file for a user. The metadata is cached per
+// user, as EOS checks the permissions of the user looking it up.
+func statCacheKey(u *userpb.User, fn string) string {
+	return u.Id.Idp + "!" + u.Id.OpaqueId + "!" + fn
+}
+
+// getCachedFileInfoByPath looks up the metadata of a file in the stat cache before asking EOS.
+func (fs *eosfs) getCachedFileInfoByPath(ctx context.Context, u *userpb.User, auth eosclient.Authorization, fn string) (*eosclient.FileInfo, error) {
+	key := statCacheKey(u, fn)
+	info := &eosclient.FileInfo{}
+	if fs.statCache.Get(key, info) {
+		return info, nil
+	}
+
+	info, err := fs.c.GetFileInfoByPath(ctx, auth, fn)
+	if err != nil {
+		return nil, err
+	}
+	_ = fs.statCache.Set(key, info)
+	return info, nil
+}
+
+// forgetStat removes the cached metadata of the files written by the user in the context.
+func (fs *eosfs) forgetStat(ctx context.Context, fns ...string) {
+	u, err := getUser(ctx)
+	if err != nil {
+		return
+	}
+	keys := make([]string, len(fns))
+	for i, fn := range fns {
+		keys[i] = statCacheKey(u, fn)
+	}
+	if err := fs.statCache.Delete(keys...); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Strs("files", fns).Msg("eosfs: error invalidating the stat cache")
+	}
+}
+
 func (fs *eosfs) getMDShareFolder(ctx context.Context, p string, mdKeys []string) (*provider.ResourceInfo, error) {
 	fn := fs.wrapShadow(ctx, p)
 
@@ -1392,6 +1508,15 @@ func (fs *eosfs) GetQuota(ctx context.Context, ref *provider.Reference) (uint64,
 	return qi.AvailableBytes, qi.UsedBytes, nil
 }
 
+// GetQuotaInfo returns the quota of the user, which EOS enforces itself.
+func (fs *eosfs) GetQuotaInfo(ctx context.Context, ref *provider.Reference) (*storage.Quota, error) {
+	total, used, err := fs.GetQuota(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &storage.Quota{Total: total, Used: used, Enforced: true}, nil
+}
+
 func (fs *eosfs) GetHome(ctx context.Context) (string, error) {
 	if !fs.conf.EnableHome {
 		return "", errtypes.NotSupported("eosfs: get home not supported")
@@ -1581,6 +1706,7 @@ func (fs *eosfs) CreateDir(ctx context.Context, ref *provider.Reference) error {
 	}
 
 	log.Info().Msgf("eosfs: createdir: path=%s", fn)
+	defer fs.forgetStat(ctx, fn)
 	return fs.c.CreateDir(ctx, auth, fn)
 }
 
@@ -1594,6 +1720,7 @@ func (fs *eosfs) TouchFile(ctx context.Context, ref *provider.Reference) error {
 	}
 	log.Info().Msgf("eosfs: touch file: path=%s", fn)
 
+	defer fs.forgetStat(ctx, fn)
 	return fs.c.Touch(ctx, auth, fn)
 }
 
@@ -1666,6 +1793,7 @@ func (fs *eosfs) Delete(ctx context.Context, ref *provider.Reference) error {
 		return err
 	}
 
+	defer fs.forgetStat(ctx, fn)
 	return fs.c.Remove(ctx, auth, fn, false)
 }
 
@@ -1718,6 +1846,7 @@ func (fs *eosfs) Move(ctx context.Context, oldRef, newRef *provider.Reference) e
 		return err
 	}
 
+	defer fs.forgetStat(ctx, oldFn, newFn)
 	return fs.c.Rename(ctx, auth, oldFn, newFn)
 }
 
@@ -2145,7 +2274,7 @@ func (fs *eosfs) convert(ctx context.Context, eosFileInfo *eosclient.FileInfo) (
 	// filter 'sys' attrs and the reserved lock
 	filteredAttrs := make(map[string]string)
 	for k, v := range eosFileInfo.Attrs {
-		if !strings.HasPrefix(k, "sys") {
+		if !strings.HasPrefix(k, "sys") && k != mtimeAttrKey {
 			filteredAttrs[k] = v
 		}
 	}
@@ -2160,10 +2289,7 @@ func (fs *eosfs) convert(ctx context.Context, eosFileInfo *eosclient.FileInfo) (
 		PermissionSet: fs.permissionSet(ctx, eosFileInfo, owner),
 		Checksum:      &xs,
 		Type:          getResourceType(eosFileInfo.IsDir),
-		Mtime: &types.Timestamp{
-			Seconds: eosFileInfo.MTimeSec,
-			Nanos:   eosFileInfo.MTimeNanos,
-		},
+		Mtime:         getMtime(eosFileInfo),
 		Opaque: &types.Opaque{
 			Map: map[string]*types.OpaqueEntry{
 				"eos": {
@@ -75,6 +75,7 @@ func (fs *eosfs) Upload(ctx context.Context, ref *provider.Reference, r io.ReadC
 	if err != nil {
 		return err
 	}
+	defer fs.forgetStat(ctx, fn)
 	return fs.c.Write(ctx, auth, fn, r)
 }
 
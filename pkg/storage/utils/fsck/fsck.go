@@ -0,0 +1,666 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package fsck checks the consistency of the metadata of a storage with its
+// content. A check walks a tree of the storage, comparing the sizes of the
+// folders with the ones of their children and the sizes and checksums of the
+// files with their content, looking for grants to users and groups which
+// don't exist anymore and for the trash and version artifacts the driver left
+// behind. The inconsistencies are collected in a report and the selected
+// kinds are repaired on the way. Checks are rate limited, so that they can
+// run in the background of a production storage.
+package fsck
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/adler32"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/throttle"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// The kinds of inconsistencies.
+const (
+	// KindSize is a folder whose size differs from the sum of the sizes of
+	// its children, or a file whose size differs from the one of its content.
+	KindSize = "size"
+	// KindChecksum is a file whose checksum doesn't match its content.
+	KindChecksum = "checksum"
+	// KindGrant is a grant to a user or a group which doesn't exist.
+	KindGrant = "grant"
+	// KindArtifact is a trash entry or a version left behind by the driver.
+	KindArtifact = "artifact"
+)
+
+// The states of a check.
+const (
+	StateRunning   = "running"
+	StateDone      = "done"
+	StateFailed    = "failed"
+	StateCancelled = "cancelled"
+)
+
+// Config configures the checks.
+type Config struct {
+	Interval   int      `mapstructure:"interval" docs:"0;Seconds between two background checks of the storage. 0 disables them, checks can still be started with the storage-fsck command of the reva CLI."`
+	Root       string   `mapstructure:"root" docs:"/;The folder of the storage checked in the background."`
+	Checks     []string `mapstructure:"checks" docs:"[size,checksum,grant,artifact];The kinds of inconsistencies looked for. Drivers which don't report the size of the folders as the one of their tree should not check sizes."`
+	Repair     []string `mapstructure:"repair" docs:"[];The kinds of inconsistencies repaired by the background checks. Checksum mismatches are only reported."`
+	Rate       int      `mapstructure:"rate" docs:"50;Resources checked per second."`
+	Bandwidth  int      `mapstructure:"bandwidth" docs:"10;MB/s of content read to verify the sizes and checksums of the files."`
+	MaxIssues  int      `mapstructure:"max_issues" docs:"1000;Maximum number of inconsistencies kept in a report."`
+	ReportFile string   `mapstructure:"report_file" docs:";File keeping the report of the last check across restarts."`
+}
+
+// Enabled returns whether the storage is checked in the background.
+func (c *Config) Enabled() bool {
+	return c.Interval > 0
+}
+
+func (c *Config) init() {
+	if c.Root == "" {
+		c.Root = "/"
+	}
+	if len(c.Checks) == 0 {
+		c.Checks = []string{KindSize, KindChecksum, KindGrant, KindArtifact}
+	}
+	if c.Rate <= 0 {
+		c.Rate = 50
+	}
+	if c.Bandwidth <= 0 {
+		c.Bandwidth = 10
+	}
+	if c.MaxIssues <= 0 {
+		c.MaxIssues = 1000
+	}
+}
+
+// Issue is an inconsistency found by a check.
+type Issue struct {
+	Kind       string `json:"kind"`
+	Path       string `json:"path"`
+	Detail     string `json:"detail"`
+	Repairable bool   `json:"repairable"`
+	Repaired   bool   `json:"repaired"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Report is the outcome of a check.
+type Report struct {
+	ID       string    `json:"id"`
+	Root     string    `json:"root"`
+	State    string    `json:"state"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished,omitempty"`
+	// Checked is the number of resources checked so far.
+	Checked uint64 `json:"checked"`
+	// Bytes is the amount of content read so far.
+	Bytes uint64 `json:"bytes"`
+	// Failed is the number of resources which couldn't be checked.
+	Failed    uint64   `json:"failed"`
+	Issues    []*Issue `json:"issues"`
+	Truncated bool     `json:"truncated"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Artifact is a piece of data the driver left behind, e.g. the trash entry of
+// a node which doesn't exist anymore or a version of a purged file.
+type Artifact struct {
+	ID     string
+	Detail string
+}
+
+// ArtifactScanner is implemented by the drivers able to find their orphaned
+// artifacts.
+type ArtifactScanner interface {
+	ListOrphanedArtifacts(ctx context.Context) ([]*Artifact, error)
+	PurgeArtifact(ctx context.Context, a *Artifact) error
+}
+
+// SizeRepairer is implemented by the drivers storing the size of the folders,
+// to set it to the sum of the sizes of their children.
+type SizeRepairer interface {
+	RepairTreeSize(ctx context.Context, ref *provider.Reference, size uint64) error
+}
+
+// GranteeResolver returns whether the grantee of a grant exists.
+type GranteeResolver func(ctx context.Context, g *provider.Grantee) (bool, error)
+
+// Checker runs the checks of a storage, one at a time.
+type Checker struct {
+	c       *Config
+	fs      storage.FS
+	driver  storage.FS
+	resolve GranteeResolver
+	ops     *throttle.Limiter
+	bytes   *throttle.Limiter
+
+	mu      sync.Mutex
+	current *Report
+	last    *Report
+	cancel  context.CancelFunc
+	quit    chan struct{}
+}
+
+// NewChecker returns a checker walking fs. The optional interfaces of this
+// package are looked up on driver, the driver wrapped by fs, if any. The
+// grants are only checked with a resolver. The background checks are started
+// when enabled, until Close is called.
+func NewChecker(fs, driver storage.FS, c *Config, resolve GranteeResolver) *Checker {
+	c.init()
+	if driver == nil {
+		driver = fs
+	}
+	ch := &Checker{
+		c:       c,
+		fs:      fs,
+		driver:  driver,
+		resolve: resolve,
+		ops:     throttle.NewLimiter(float64(c.Rate)),
+		bytes:   throttle.NewLimiter(float64(c.Bandwidth) * 1024 * 1024),
+		quit:    make(chan struct{}),
+	}
+	ch.last = ch.loadReport()
+	if c.Enabled() {
+		go ch.loop()
+	}
+	return ch
+}
+
+// Close stops the background checks and cancels the running check.
+func (ch *Checker) Close() {
+	close(ch.quit)
+	ch.Cancel()
+}
+
+func (ch *Checker) loop() {
+	ticker := time.NewTicker(time.Duration(ch.c.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ch.quit:
+			return
+		case <-ticker.C:
+			_, err := ch.Start(context.Background(), &provider.Reference{Path: ch.c.Root}, ch.c.Checks, ch.c.Repair)
+			if err != nil {
+				appctx.GetLogger(context.Background()).Warn().Err(err).Msg("fsck: background check not started")
+			}
+		}
+	}
+}
+
+// Start checks the tree at ref in the background, looking for the given
+// kinds of inconsistencies and repairing the ones of the kinds in repair.
+// The check runs on behalf of the user of ctx, but is not cancelled when ctx is.
+func (ch *Checker) Start(ctx context.Context, ref *provider.Reference, checks, repair []string) (*Report, error) {
+	if len(checks) == 0 {
+		checks = ch.c.Checks
+	}
+	kinds, err := kindSet(checks)
+	if err != nil {
+		return nil, err
+	}
+	fixes, err := kindSet(repair)
+	if err != nil {
+		return nil, err
+	}
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.current != nil {
+		return nil, errtypes.AlreadyExists("fsck: check " + ch.current.ID + " is running")
+	}
+
+	root := ref.GetPath()
+	if root == "" {
+		root = ref.GetResourceId().String()
+	}
+	r := &Report{
+		ID:      uuid.New().String(),
+		Root:    root,
+		State:   StateRunning,
+		Started: time.Now(),
+		Issues:  []*Issue{},
+	}
+	rctx, cancel := context.WithCancel(detach(ctx))
+	ch.current, ch.cancel = r, cancel
+
+	go ch.run(rctx, r, ref, kinds, fixes)
+	return ch.copyReport(r), nil
+}
+
+// Cancel cancels the running check, if any.
+func (ch *Checker) Cancel() bool {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.cancel == nil {
+		return false
+	}
+	ch.cancel()
+	return true
+}
+
+// Report returns the report of the running check, or the one of the last
+// check if none is running, nil if there is none.
+func (ch *Checker) Report() *Report {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.current != nil {
+		return ch.copyReport(ch.current)
+	}
+	if ch.last != nil {
+		return ch.copyReport(ch.last)
+	}
+	return nil
+}
+
+// copyReport returns a copy of r which is not modified by the running check.
+// The issues are never modified once added to a report.
+func (ch *Checker) copyReport(r *Report) *Report {
+	c := *r
+	c.Issues = append([]*Issue(nil), r.Issues...)
+	return &c
+}
+
+func (ch *Checker) run(ctx context.Context, r *Report, ref *provider.Reference, kinds, fixes map[string]bool) {
+	log := appctx.GetLogger(ctx).With().Str("pkg", "fsck").Str("check", r.ID).Logger()
+	log.Info().Str("root", r.Root).Msg("fsck: check started")
+
+	// when the mount is throttled, the check must not slow down the users
+	ctx = throttle.ContextWithClass(ctx, throttle.Bulk)
+	err := ch.walk(ctx, r, ref, kinds, fixes)
+	if err == nil && kinds[KindArtifact] {
+		err = ch.checkArtifacts(ctx, r, fixes[KindArtifact])
+	}
+
+	ch.mu.Lock()
+	r.Finished = time.Now()
+	switch {
+	case err == nil:
+		r.State = StateDone
+	case ctx.Err() != nil:
+		r.State = StateCancelled
+	default:
+		r.State = StateFailed
+		r.Error = err.Error()
+	}
+	ch.current, ch.cancel, ch.last = nil, nil, r
+	ch.mu.Unlock()
+
+	ch.saveReport(r)
+	log.Info().Str("state", r.State).Uint64("checked", r.Checked).Int("issues", len(r.Issues)).Msg("fsck: check finished")
+}
+
+func (ch *Checker) walk(ctx context.Context, r *Report, ref *provider.Reference, kinds, fixes map[string]bool) error {
+	info, err := ch.fs.GetMD(ctx, ref, nil)
+	if err != nil {
+		return errors.Wrap(err, "fsck: error getting the metadata of the root")
+	}
+	return ch.check(ctx, r, info, kinds, fixes)
+}
+
+// check checks a resource and its children, if it is a folder. Failures to
+// check a resource are only counted, the check goes on with the next one.
+func (ch *Checker) check(ctx context.Context, r *Report, info *provider.ResourceInfo, kinds, fixes map[string]bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ch.ops.Wait(ctx, 1); err != nil {
+		return err
+	}
+	log := appctx.GetLogger(ctx)
+
+	if kinds[KindGrant] && ch.resolve != nil {
+		if err := ch.checkGrants(ctx, r, info, fixes[KindGrant]); err != nil {
+			log.Debug().Err(err).Str("path", info.Path).Msg("fsck: error checking grants")
+			ch.failed(r)
+		}
+	}
+
+	switch info.Type {
+	case provider.ResourceType_RESOURCE_TYPE_FILE:
+		if kinds[KindSize] || kinds[KindChecksum] {
+			if err := ch.checkContent(ctx, r, info, kinds); err != nil {
+				log.Debug().Err(err).Str("path", info.Path).Msg("fsck: error checking content")
+				ch.failed(r)
+			}
+		}
+		ch.checked(r)
+		return ctx.Err()
+
+	case provider.ResourceType_RESOURCE_TYPE_CONTAINER:
+		children, err := ch.fs.ListFolder(ctx, refOf(info), nil)
+		if err != nil {
+			log.Debug().Err(err).Str("path", info.Path).Msg("fsck: error listing folder")
+			ch.failed(r)
+			return ctx.Err()
+		}
+		if kinds[KindSize] {
+			ch.checkTreeSize(ctx, r, info, children, fixes[KindSize])
+		}
+		ch.checked(r)
+
+		for _, child := range children {
+			if err := ch.check(ctx, r, child, kinds, fixes); err != nil {
+				return err
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+func (ch *Checker) checkTreeSize(ctx context.Context, r *Report, info *provider.ResourceInfo, children []*provider.ResourceInfo, repair bool) {
+	var sum uint64
+	for _, c := range children {
+		sum += c.Size
+	}
+	if sum == info.Size {
+		return
+	}
+
+	issue := &Issue{
+		Kind:   KindSize,
+		Path:   info.Path,
+		Detail: fmt.Sprintf("folder size is %d, its children sum up to %d", info.Size, sum),
+	}
+	sr, ok := ch.driver.(SizeRepairer)
+	issue.Repairable = ok
+	if ok && repair {
+		ch.repair(ctx, issue, func() error {
+			return sr.RepairTreeSize(ctx, refOf(info), sum)
+		})
+	}
+	ch.addIssue(r, issue)
+}
+
+func (ch *Checker) checkContent(ctx context.Context, r *Report, info *provider.ResourceInfo, kinds map[string]bool) error {
+	var h hash.Hash
+	if kinds[KindChecksum] {
+		h = newHash(info.GetChecksum())
+	}
+	if h == nil && !kinds[KindSize] {
+		return nil
+	}
+	if h == nil {
+		h = noopHash{}
+	}
+
+	rc, err := ch.fs.Download(ctx, refOf(info))
+	if err != nil {
+		return err
+	}
+	rc = ch.bytes.Reader(ctx, rc)
+	defer rc.Close()
+
+	n, err := io.Copy(h, rc)
+	ch.mu.Lock()
+	r.Bytes += uint64(n)
+	ch.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if kinds[KindSize] && uint64(n) != info.Size {
+		ch.addIssue(r, &Issue{
+			Kind:   KindSize,
+			Path:   info.Path,
+			Detail: fmt.Sprintf("file size is %d, its content has %d bytes", info.Size, n),
+		})
+	}
+	if _, ok := h.(noopHash); !ok {
+		sum := hex.EncodeToString(h.Sum(nil))
+		if !sameChecksum(sum, info.Checksum.Sum) {
+			ch.addIssue(r, &Issue{
+				Kind:   KindChecksum,
+				Path:   info.Path,
+				Detail: fmt.Sprintf("%s checksum is %s, its content has %s", checksumName(info.Checksum.Type), info.Checksum.Sum, sum),
+			})
+		}
+	}
+	return nil
+}
+
+func (ch *Checker) checkGrants(ctx context.Context, r *Report, info *provider.ResourceInfo, repair bool) error {
+	grants, err := ch.fs.ListGrants(ctx, refOf(info))
+	if err != nil {
+		if _, ok := err.(errtypes.NotSupported); ok {
+			return nil
+		}
+		return err
+	}
+	for _, g := range grants {
+		exists, err := ch.resolve(ctx, g.Grantee)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		issue := &Issue{
+			Kind:       KindGrant,
+			Path:       info.Path,
+			Detail:     "grant to unknown " + granteeString(g.Grantee),
+			Repairable: true,
+		}
+		if repair {
+			g := g
+			ch.repair(ctx, issue, func() error {
+				return ch.fs.RemoveGrant(ctx, refOf(info), g)
+			})
+		}
+		ch.addIssue(r, issue)
+	}
+	return nil
+}
+
+func (ch *Checker) checkArtifacts(ctx context.Context, r *Report, repair bool) error {
+	as, ok := ch.driver.(ArtifactScanner)
+	if !ok {
+		return nil
+	}
+	artifacts, err := as.ListOrphanedArtifacts(ctx)
+	if err != nil {
+		if _, ok := err.(errtypes.NotSupported); ok {
+			return nil
+		}
+		return errors.Wrap(err, "fsck: error listing the orphaned artifacts")
+	}
+	for _, a := range artifacts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		issue := &Issue{
+			Kind:       KindArtifact,
+			Path:       a.ID,
+			Detail:     a.Detail,
+			Repairable: true,
+		}
+		if repair {
+			a := a
+			ch.repair(ctx, issue, func() error {
+				return as.PurgeArtifact(ctx, a)
+			})
+		}
+		ch.addIssue(r, issue)
+	}
+	return nil
+}
+
+// repair runs fn at the rate of the check and records its outcome in the issue.
+func (ch *Checker) repair(ctx context.Context, issue *Issue, fn func() error) {
+	err := ch.ops.Wait(ctx, 1)
+	if err == nil {
+		err = fn()
+	}
+	if err != nil {
+		issue.Error = err.Error()
+		return
+	}
+	issue.Repaired = true
+}
+
+func (ch *Checker) addIssue(r *Report, issue *Issue) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if len(r.Issues) >= ch.c.MaxIssues {
+		r.Truncated = true
+		return
+	}
+	r.Issues = append(r.Issues, issue)
+}
+
+func (ch *Checker) checked(r *Report) {
+	ch.mu.Lock()
+	r.Checked++
+	ch.mu.Unlock()
+}
+
+func (ch *Checker) failed(r *Report) {
+	ch.mu.Lock()
+	r.Failed++
+	ch.mu.Unlock()
+}
+
+func (ch *Checker) loadReport() *Report {
+	if ch.c.ReportFile == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(ch.c.ReportFile)
+	if err != nil {
+		return nil
+	}
+	r := &Report{}
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil
+	}
+	return r
+}
+
+func (ch *Checker) saveReport(r *Report) {
+	if ch.c.ReportFile == "" {
+		return
+	}
+	b, err := json.Marshal(r)
+	if err == nil {
+		// the previous report is kept if the new one can't be written entirely
+		tmp := ch.c.ReportFile + ".tmp"
+		if err = ioutil.WriteFile(tmp, b, 0600); err == nil {
+			err = os.Rename(tmp, ch.c.ReportFile)
+		}
+	}
+	if err != nil {
+		appctx.GetLogger(context.Background()).Error().Err(err).Str("file", ch.c.ReportFile).Msg("fsck: error saving report")
+	}
+}
+
+// detach returns a context carrying the user, the token and the logger of ctx,
+// which is not cancelled with it.
+func detach(ctx context.Context) context.Context {
+	dctx := appctx.WithLogger(context.Background(), appctx.GetLogger(ctx))
+	if u, ok := ctxpkg.ContextGetUser(ctx); ok {
+		dctx = ctxpkg.ContextSetUser(dctx, u)
+	}
+	if t, ok := ctxpkg.ContextGetToken(ctx); ok {
+		dctx = ctxpkg.ContextSetToken(dctx, t)
+		dctx = metadata.AppendToOutgoingContext(dctx, ctxpkg.TokenHeader, t)
+	}
+	return dctx
+}
+
+func kindSet(kinds []string) (map[string]bool, error) {
+	set := make(map[string]bool, len(kinds))
+	for _, k := range kinds {
+		switch k {
+		case KindSize, KindChecksum, KindGrant, KindArtifact:
+			set[k] = true
+		default:
+			return nil, errtypes.BadRequest("fsck: unknown kind of inconsistency: " + k)
+		}
+	}
+	return set, nil
+}
+
+func refOf(info *provider.ResourceInfo) *provider.Reference {
+	if info.Path != "" {
+		return &provider.Reference{Path: info.Path}
+	}
+	return &provider.Reference{ResourceId: info.Id}
+}
+
+func newHash(xs *provider.ResourceChecksum) hash.Hash {
+	if xs.GetSum() == "" {
+		return nil
+	}
+	switch xs.Type {
+	case provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_ADLER32:
+		return adler32.New()
+	case provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_MD5:
+		return md5.New()
+	case provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_SHA1:
+		return sha1.New()
+	}
+	return nil
+}
+
+func checksumName(t provider.ResourceChecksumType) string {
+	return strings.ToLower(strings.TrimPrefix(t.String(), "RESOURCE_CHECKSUM_TYPE_"))
+}
+
+// sameChecksum compares hex encoded checksums, which some drivers store
+// in upper case or without the leading zeros.
+func sameChecksum(a, b string) bool {
+	return strings.TrimLeft(strings.ToLower(a), "0") == strings.TrimLeft(strings.ToLower(b), "0")
+}
+
+// noopHash counts the content of the files whose checksum isn't checked.
+type noopHash struct{}
+
+func (noopHash) Write(p []byte) (int, error) { return len(p), nil }
+func (noopHash) Sum(b []byte) []byte         { return b }
+func (noopHash) Reset()                      {}
+func (noopHash) Size() int                   { return 0 }
+func (noopHash) BlockSize() int              { return 1 }
+
+func granteeString(g *provider.Grantee) string {
+	if id := g.GetUserId(); id != nil {
+		return "user " + id.OpaqueId + "@" + id.Idp
+	}
+	if id := g.GetGroupId(); id != nil {
+		return "group " + id.OpaqueId + "@" + id.Idp
+	}
+	return "grantee"
+}
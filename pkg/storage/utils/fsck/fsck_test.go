@@ -0,0 +1,270 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package fsck
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+type testFS struct {
+	storage.FS
+	infos     map[string]*provider.ResourceInfo
+	content   map[string][]byte
+	grants    map[string][]*provider.Grant
+	artifacts []*Artifact
+	purged    []string
+	block     chan struct{}
+}
+
+func newTestFS() *testFS {
+	t := &testFS{
+		infos:   map[string]*provider.ResourceInfo{},
+		content: map[string][]byte{},
+		grants:  map[string][]*provider.Grant{},
+	}
+	t.infos["/"] = &provider.ResourceInfo{Type: provider.ResourceType_RESOURCE_TYPE_CONTAINER, Path: "/", Size: 10}
+	t.infos["/a"] = &provider.ResourceInfo{Type: provider.ResourceType_RESOURCE_TYPE_CONTAINER, Path: "/a", Size: 5}
+	t.addFile("/a/hello.txt", "hello", "062c0215")
+	t.addFile("/world.txt", "world", "06a60229")
+	return t
+}
+
+func (t *testFS) addFile(p, content, adler32 string) {
+	t.content[p] = []byte(content)
+	t.infos[p] = &provider.ResourceInfo{
+		Type:     provider.ResourceType_RESOURCE_TYPE_FILE,
+		Path:     p,
+		Size:     uint64(len(content)),
+		Checksum: &provider.ResourceChecksum{Type: provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_ADLER32, Sum: adler32},
+	}
+}
+
+func (t *testFS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	info, ok := t.infos[ref.Path]
+	if !ok {
+		return nil, errtypes.NotFound(ref.Path)
+	}
+	return info, nil
+}
+
+func (t *testFS) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	if t.block != nil {
+		<-t.block
+	}
+	var children []*provider.ResourceInfo
+	for p, info := range t.infos {
+		if p != "/" && path.Dir(p) == ref.Path {
+			children = append(children, info)
+		}
+	}
+	return children, nil
+}
+
+func (t *testFS) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(t.content[ref.Path])), nil
+}
+
+func (t *testFS) ListGrants(ctx context.Context, ref *provider.Reference) ([]*provider.Grant, error) {
+	return t.grants[ref.Path], nil
+}
+
+func (t *testFS) RemoveGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	var grants []*provider.Grant
+	for _, x := range t.grants[ref.Path] {
+		if x != g {
+			grants = append(grants, x)
+		}
+	}
+	t.grants[ref.Path] = grants
+	return nil
+}
+
+func (t *testFS) RepairTreeSize(ctx context.Context, ref *provider.Reference, size uint64) error {
+	t.infos[ref.Path].Size = size
+	return nil
+}
+
+func (t *testFS) ListOrphanedArtifacts(ctx context.Context) ([]*Artifact, error) {
+	return t.artifacts, nil
+}
+
+func (t *testFS) PurgeArtifact(ctx context.Context, a *Artifact) error {
+	t.purged = append(t.purged, a.ID)
+	return nil
+}
+
+func userGrant(id string) *provider.Grant {
+	return &provider.Grant{Grantee: &provider.Grantee{
+		Type:   provider.GranteeType_GRANTEE_TYPE_USER,
+		UserId: &userpb.UserId{OpaqueId: id, Idp: "idp"},
+	}}
+}
+
+func knownUsers(ctx context.Context, g *provider.Grantee) (bool, error) {
+	return g.GetUserId().OpaqueId == "einstein", nil
+}
+
+func wait(t *testing.T, ch *Checker) *Report {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if r := ch.Report(); r != nil && r.State != StateRunning {
+			return r
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("check did not finish")
+	return nil
+}
+
+func issuesOf(r *Report, kind string) []*Issue {
+	var issues []*Issue
+	for _, i := range r.Issues {
+		if i.Kind == kind {
+			issues = append(issues, i)
+		}
+	}
+	return issues
+}
+
+func TestConsistentTree(t *testing.T) {
+	fs := newTestFS()
+	ch := NewChecker(fs, nil, &Config{Rate: 1000}, knownUsers)
+	defer ch.Close()
+
+	if _, err := ch.Start(context.Background(), &provider.Reference{Path: "/"}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	r := wait(t, ch)
+	if r.State != StateDone || len(r.Issues) != 0 {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	if r.Checked != 4 || r.Bytes != 10 {
+		t.Fatalf("expected 4 resources and 10 bytes checked, got %d and %d", r.Checked, r.Bytes)
+	}
+}
+
+func TestInconsistenciesFound(t *testing.T) {
+	fs := newTestFS()
+	fs.infos["/"].Size = 12
+	fs.infos["/world.txt"].Checksum.Sum = "deadbeef"
+	fs.infos["/a/hello.txt"].Size = 4
+	fs.grants["/a"] = []*provider.Grant{userGrant("einstein"), userGrant("ghost")}
+	fs.artifacts = []*Artifact{{ID: "revision:x", Detail: "version of a purged node"}}
+	ch := NewChecker(fs, nil, &Config{Rate: 1000}, knownUsers)
+	defer ch.Close()
+
+	if _, err := ch.Start(context.Background(), &provider.Reference{Path: "/"}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	r := wait(t, ch)
+	if r.State != StateDone {
+		t.Fatalf("unexpected state %s: %s", r.State, r.Error)
+	}
+	// the size of the file and the one of its folder differ
+	if sizes := issuesOf(r, KindSize); len(sizes) != 3 {
+		t.Fatalf("expected 3 size issues, got %d", len(sizes))
+	}
+	if xs := issuesOf(r, KindChecksum); len(xs) != 1 || xs[0].Path != "/world.txt" {
+		t.Fatalf("unexpected checksum issues %+v", xs)
+	}
+	if gs := issuesOf(r, KindGrant); len(gs) != 1 || !strings.Contains(gs[0].Detail, "ghost") || gs[0].Repaired {
+		t.Fatalf("unexpected grant issues %+v", gs)
+	}
+	if as := issuesOf(r, KindArtifact); len(as) != 1 || as[0].Path != "revision:x" {
+		t.Fatalf("unexpected artifact issues %+v", as)
+	}
+	if len(fs.grants["/a"]) != 2 || len(fs.purged) != 0 || fs.infos["/"].Size != 12 {
+		t.Fatal("expected nothing to be repaired")
+	}
+}
+
+func TestInconsistenciesRepaired(t *testing.T) {
+	fs := newTestFS()
+	fs.infos["/"].Size = 12
+	fs.grants["/a"] = []*provider.Grant{userGrant("einstein"), userGrant("ghost")}
+	fs.artifacts = []*Artifact{{ID: "revision:x"}}
+	ch := NewChecker(fs, nil, &Config{Rate: 1000}, knownUsers)
+	defer ch.Close()
+
+	if _, err := ch.Start(context.Background(), &provider.Reference{Path: "/"}, nil, []string{KindSize, KindGrant, KindArtifact}); err != nil {
+		t.Fatal(err)
+	}
+	r := wait(t, ch)
+	for _, i := range r.Issues {
+		if !i.Repaired {
+			t.Fatalf("expected %+v to be repaired", i)
+		}
+	}
+	if len(r.Issues) != 3 {
+		t.Fatalf("expected 3 issues, got %d", len(r.Issues))
+	}
+	if fs.infos["/"].Size != 10 || len(fs.grants["/a"]) != 1 || len(fs.purged) != 1 {
+		t.Fatal("expected the inconsistencies to be repaired")
+	}
+}
+
+func TestSingleCheck(t *testing.T) {
+	fs := newTestFS()
+	fs.block = make(chan struct{})
+	ch := NewChecker(fs, nil, &Config{Rate: 1000}, nil)
+	defer ch.Close()
+
+	ref := &provider.Reference{Path: "/"}
+	if _, err := ch.Start(context.Background(), ref, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ch.Start(context.Background(), ref, nil, nil); err == nil {
+		t.Fatal("expected a second check to be rejected")
+	}
+	if _, err := ch.Start(context.Background(), ref, []string{"bogus"}, nil); err == nil {
+		t.Fatal("expected unknown kinds to be rejected")
+	}
+
+	if !ch.Cancel() {
+		t.Fatal("expected the check to be cancelled")
+	}
+	close(fs.block)
+	if r := wait(t, ch); r.State != StateCancelled {
+		t.Fatalf("expected the check to be cancelled, got %s", r.State)
+	}
+	if ch.Cancel() {
+		t.Fatal("expected no check to be running")
+	}
+}
+
+func TestSameChecksum(t *testing.T) {
+	if !sameChecksum("062c0215", "62C0215") {
+		t.Fatal("expected the checksums to match")
+	}
+	if sameChecksum("062c0215", "062c0216") {
+		t.Fatal("expected the checksums to differ")
+	}
+}
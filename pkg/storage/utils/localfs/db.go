@@ -23,55 +23,20 @@ import (
 	"database/sql"
 	"path"
 
+	"github.com/cs3org/reva/pkg/migrate"
+	"github.com/cs3org/reva/pkg/sqldb"
 	"github.com/pkg/errors"
-
-	// Provides sqlite drivers
-	_ "github.com/mattn/go-sqlite3"
 )
 
 func initializeDB(root, dbName string) (*sql.DB, error) {
-	dbPath := path.Join(root, dbName)
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sqldb.Open(sqldb.Config{Engine: sqldb.SQLite, Path: path.Join(root, dbName)})
 	if err != nil {
 		return nil, errors.Wrap(err, "localfs: error opening DB connection")
 	}
 
-	stmt, err := db.Prepare("CREATE TABLE IF NOT EXISTS recycled_entries (key TEXT PRIMARY KEY, path TEXT)")
-	if err != nil {
-		return nil, errors.Wrap(err, "localfs: error preparing statement")
-	}
-	_, err = stmt.Exec()
-	if err != nil {
-		return nil, errors.Wrap(err, "localfs: error executing create statement")
-	}
-
-	stmt, err = db.Prepare("CREATE TABLE IF NOT EXISTS user_interaction (resource TEXT, grantee TEXT, role TEXT DEFAULT '', favorite INTEGER DEFAULT 0, PRIMARY KEY (resource, grantee))")
-	if err != nil {
-		return nil, errors.Wrap(err, "localfs: error preparing statement")
-	}
-	_, err = stmt.Exec()
-	if err != nil {
-		return nil, errors.Wrap(err, "localfs: error executing create statement")
-	}
-
-	stmt, err = db.Prepare("CREATE TABLE IF NOT EXISTS metadata (resource TEXT, key TEXT, value TEXT, PRIMARY KEY (resource, key))")
-	if err != nil {
-		return nil, errors.Wrap(err, "localfs: error preparing statement")
-	}
-	_, err = stmt.Exec()
-	if err != nil {
-		return nil, errors.Wrap(err, "localfs: error executing create statement")
-	}
-
-	stmt, err = db.Prepare("CREATE TABLE IF NOT EXISTS share_references (resource TEXT PRIMARY KEY, target TEXT)")
-	if err != nil {
-		return nil, errors.Wrap(err, "localfs: error preparing statement")
+	if err := migrate.Up(context.Background(), db, Schema); err != nil {
+		return nil, errors.Wrap(err, "localfs: error migrating the database")
 	}
-	_, err = stmt.Exec()
-	if err != nil {
-		return nil, errors.Wrap(err, "localfs: error executing create statement")
-	}
-
 	return db, nil
 }
 
@@ -217,6 +182,39 @@ func (fs *localfs) getReferenceEntry(ctx context.Context, resource string) (stri
 	return target, nil
 }
 
+func (fs *localfs) addToLocksDB(ctx context.Context, resource, lock string) error {
+	stmt, err := fs.db.Prepare("INSERT INTO locks (resource, lock) VALUES (?, ?) ON CONFLICT(resource) DO UPDATE SET lock=?")
+	if err != nil {
+		return errors.Wrap(err, "localfs: error preparing statement")
+	}
+	_, err = stmt.Exec(resource, lock, lock)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error executing insert statement")
+	}
+	return nil
+}
+
+func (fs *localfs) getLockEntry(ctx context.Context, resource string) (string, error) {
+	var lock string
+	err := fs.db.QueryRow("SELECT lock FROM locks WHERE resource=?", resource).Scan(&lock)
+	if err != nil {
+		return "", err
+	}
+	return lock, nil
+}
+
+func (fs *localfs) removeFromLocksDB(ctx context.Context, resource string) error {
+	stmt, err := fs.db.Prepare("DELETE FROM locks WHERE resource=?")
+	if err != nil {
+		return errors.Wrap(err, "localfs: error preparing statement")
+	}
+	_, err = stmt.Exec(resource)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error executing delete statement")
+	}
+	return nil
+}
+
 func (fs *localfs) copyMD(s string, t string) (err error) {
 	stmt, err := fs.db.Prepare("UPDATE user_interaction SET resource=? WHERE resource=?")
 	if err != nil {
@@ -244,5 +242,14 @@ func (fs *localfs) copyMD(s string, t string) (err error) {
 	if err != nil {
 		return errors.Wrap(err, "localfs: error executing delete statement")
 	}
+
+	stmt, err = fs.db.Prepare("UPDATE locks SET resource=? WHERE resource=?")
+	if err != nil {
+		return errors.Wrap(err, "localfs: error preparing statement")
+	}
+	_, err = stmt.Exec(t, s)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error executing delete statement")
+	}
 	return nil
 }
@@ -0,0 +1,107 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package localfs
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/storage/utils/encryption"
+	"github.com/pkg/errors"
+)
+
+// decryptedFile reads the decrypted content of a file, seeking for range requests.
+type decryptedFile struct {
+	*encryption.Reader
+	f *os.File
+}
+
+func (d *decryptedFile) Close() error {
+	return d.f.Close()
+}
+
+// openContent opens the file at the internal path fn, decrypting its content
+// if it is encrypted. The files written before the encryption was enabled are
+// returned as they are.
+func (fs *localfs) openContent(ctx context.Context, fn string) (io.ReadCloser, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	if fs.encrypter == nil {
+		return f, nil
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r, err := fs.encrypter.NewReader(ctx, f, fi.Size())
+	switch {
+	case err == encryption.ErrNotEncrypted:
+		return f, nil
+	case err != nil:
+		f.Close()
+		return nil, errors.Wrap(err, "localfs: error decrypting "+fn)
+	}
+	return &decryptedFile{Reader: r, f: f}, nil
+}
+
+// contentSize returns the size of the decrypted content of the file at the internal path fn.
+func (fs *localfs) contentSize(fn string, fi os.FileInfo) uint64 {
+	if fs.encrypter == nil || fi.IsDir() {
+		return uint64(fi.Size())
+	}
+	size, err := encryption.FileSize(fn)
+	if err != nil {
+		return uint64(fi.Size())
+	}
+	return uint64(size)
+}
+
+// encryptFile replaces the plaintext file at src with its encrypted content at dst.
+func (fs *localfs) encryptFile(ctx context.Context, src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, defaultFilePerm)
+	if err != nil {
+		return err
+	}
+	if err := fs.encrypter.Encrypt(ctx, out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return errors.Wrap(err, "localfs: error encrypting "+src)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+
+	if err := os.Remove(src); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("path", src).Msg("localfs: error removing the plaintext upload")
+	}
+	return nil
+}
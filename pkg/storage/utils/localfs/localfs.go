@@ -21,12 +21,14 @@ package localfs
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/url"
 	"os"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -35,12 +37,14 @@ import (
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/mime"
 	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/utils/acl"
 	"github.com/cs3org/reva/pkg/storage/utils/chunking"
+	"github.com/cs3org/reva/pkg/storage/utils/encryption"
 	"github.com/cs3org/reva/pkg/storage/utils/grants"
 	"github.com/cs3org/reva/pkg/storage/utils/templates"
 	"github.com/cs3org/reva/pkg/utils"
@@ -49,17 +53,25 @@ import (
 
 // Config holds the configuration details for the local fs.
 type Config struct {
-	Root                string `mapstructure:"root"`
-	DisableHome         bool   `mapstructure:"disable_home"`
-	UserLayout          string `mapstructure:"user_layout"`
-	ShareFolder         string `mapstructure:"share_folder"`
-	DataTransfersFolder string `mapstructure:"data_transfers_folder"`
-	Uploads             string `mapstructure:"uploads"`
-	DataDirectory       string `mapstructure:"data_directory"`
-	RecycleBin          string `mapstructure:"recycle_bin"`
-	Versions            string `mapstructure:"versions"`
-	Shadow              string `mapstructure:"shadow"`
-	References          string `mapstructure:"references"`
+	Root                string            `mapstructure:"root"`
+	DisableHome         bool              `mapstructure:"disable_home"`
+	UserLayout          string            `mapstructure:"user_layout"`
+	ShareFolder         string            `mapstructure:"share_folder"`
+	DataTransfersFolder string            `mapstructure:"data_transfers_folder"`
+	Uploads             string            `mapstructure:"uploads"`
+	DataDirectory       string            `mapstructure:"data_directory"`
+	RecycleBin          string            `mapstructure:"recycle_bin"`
+	Versions            string            `mapstructure:"versions"`
+	Shadow              string            `mapstructure:"shadow"`
+	References          string            `mapstructure:"references"`
+	Encryption          encryption.Config `mapstructure:"encryption"`
+	// RecycleMaxAge is the number of days the deleted files are kept, 0 keeps them forever.
+	RecycleMaxAge int `mapstructure:"recycle_max_age"`
+	// MaxVersions is the number of versions kept per file, 0 keeps all of them.
+	MaxVersions int `mapstructure:"max_versions"`
+	// Symlinks is the policy for the symlinks found in the storage. They are either followed as long as
+	// they point inside the mount root, exposed as link resources or rejected.
+	Symlinks string `mapstructure:"symlinks"`
 }
 
 func (c *Config) init() {
@@ -79,6 +91,10 @@ func (c *Config) init() {
 		c.DataTransfersFolder = "/DataTransfers"
 	}
 
+	if c.Symlinks == "" {
+		c.Symlinks = symlinksFollow
+	}
+
 	// ensure share folder always starts with slash
 	c.ShareFolder = path.Join("/", c.ShareFolder)
 
@@ -96,6 +112,7 @@ type localfs struct {
 	conf         *Config
 	db           *sql.DB
 	chunkHandler *chunking.ChunkHandler
+	encrypter    *encryption.Encrypter // nil if the files are not encrypted
 }
 
 // NewLocalFS returns a storage.FS interface implementation that controls then
@@ -103,6 +120,10 @@ type localfs struct {
 func NewLocalFS(c *Config) (storage.FS, error) {
 	c.init()
 
+	if err := validateSymlinksPolicy(c.Symlinks); err != nil {
+		return nil, err
+	}
+
 	// create namespaces if they do not exist
 	namespaces := []string{c.DataDirectory, c.Uploads, c.Shadow, c.References, c.RecycleBin, c.Versions}
 	for _, v := range namespaces {
@@ -116,6 +137,14 @@ func NewLocalFS(c *Config) (storage.FS, error) {
 		dbName = "localhomefs.db"
 	}
 
+	var encrypter *encryption.Encrypter
+	if c.Encryption.Enabled() {
+		var err error
+		if encrypter, err = encryption.New(&c.Encryption); err != nil {
+			return nil, errors.Wrap(err, "localfs: error configuring the encryption")
+		}
+	}
+
 	db, err := initializeDB(c.Root, dbName)
 	if err != nil {
 		return nil, errors.Wrap(err, "localfs: error initializing db")
@@ -125,14 +154,13 @@ func NewLocalFS(c *Config) (storage.FS, error) {
 		conf:         c,
 		db:           db,
 		chunkHandler: chunking.NewChunkHandler(c.Uploads),
+		encrypter:    encrypter,
 	}, nil
 }
 
+// Shutdown leaves the database open, as its connection pool is shared by the drivers
+// configured with the same root.
 func (fs *localfs) Shutdown(ctx context.Context) error {
-	err := fs.db.Close()
-	if err != nil {
-		return errors.Wrap(err, "localfs: error closing db connection")
-	}
 	return nil
 }
 
@@ -356,7 +384,7 @@ func (fs *localfs) normalize(ctx context.Context, fi os.FileInfo, fn string, mdK
 		Type:          getResourceType(fi.IsDir()),
 		Etag:          calcEtag(ctx, fi),
 		MimeType:      mime.Detect(fi.IsDir(), fp),
-		Size:          uint64(fi.Size()),
+		Size:          fs.contentSize(fn, fi),
 		PermissionSet: fs.permissionSet(ctx, owner.Id),
 		Mtime: &types.Timestamp{
 			Seconds: uint64(fi.ModTime().Unix()),
@@ -365,6 +393,14 @@ func (fs *localfs) normalize(ctx context.Context, fi os.FileInfo, fn string, mdK
 		ArbitraryMetadata: metadata,
 	}
 
+	if isSymlink(fi) {
+		// only exposed symlinks are normalized as such, the others are stated through
+		md.Type = provider.ResourceType_RESOURCE_TYPE_SYMLINK
+		md.MimeType = "inode/symlink"
+		md.Size = 0
+		md.Target = fs.symlinkTarget(ctx, fn)
+	}
+
 	return md, nil
 }
 
@@ -586,6 +622,9 @@ func (fs *localfs) SetArbitraryMetadata(ctx context.Context, ref *provider.Refer
 		np = fs.wrap(ctx, np)
 	}
 
+	if err := fs.checkPath(ctx, np); err != nil {
+		return err
+	}
 	fi, err := os.Stat(np)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -596,7 +635,7 @@ func (fs *localfs) SetArbitraryMetadata(ctx context.Context, ref *provider.Refer
 
 	if md.Metadata != nil {
 		if val, ok := md.Metadata["mtime"]; ok {
-			if mtime, err := parseMTime(val); err == nil {
+			if mtime, err := utils.ParseMTime(val); err == nil {
 				// updating mtime also updates atime
 				if err := os.Chtimes(np, mtime, mtime); err != nil {
 					return errors.Wrap(err, "could not set mtime")
@@ -645,17 +684,6 @@ func (fs *localfs) SetArbitraryMetadata(ctx context.Context, ref *provider.Refer
 	return fs.propagate(ctx, np)
 }
 
-func parseMTime(v string) (t time.Time, err error) {
-	p := strings.SplitN(v, ".", 2)
-	var sec, nsec int64
-	if sec, err = strconv.ParseInt(p[0], 10, 64); err == nil {
-		if len(p) > 1 {
-			nsec, err = strconv.ParseInt(p[1], 10, 64)
-		}
-	}
-	return time.Unix(sec, nsec), err
-}
-
 func (fs *localfs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
 
 	np, err := fs.resolve(ctx, ref)
@@ -673,6 +701,9 @@ func (fs *localfs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Ref
 		np = fs.wrap(ctx, np)
 	}
 
+	if err := fs.checkPath(ctx, np); err != nil {
+		return err
+	}
 	_, err = os.Stat(np)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -711,24 +742,161 @@ func (fs *localfs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Ref
 	return fs.propagate(ctx, np)
 }
 
+// resolveLockPath returns the internal path of the resource a lock is set on.
+func (fs *localfs) resolveLockPath(ctx context.Context, ref *provider.Reference) (string, error) {
+	np, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return "", errors.Wrap(err, "localfs: error resolving ref")
+	}
+
+	if fs.isShareFolderRoot(ctx, np) {
+		return "", errtypes.PermissionDenied("localfs: cannot lock the virtual share folder")
+	}
+
+	if fs.isShareFolderChild(ctx, np) {
+		np = fs.wrapReferences(ctx, np)
+	} else {
+		np = fs.wrap(ctx, np)
+	}
+
+	if err := fs.checkPath(ctx, np); err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(np); err != nil {
+		if os.IsNotExist(err) {
+			return "", errtypes.NotFound(fs.unwrap(ctx, np))
+		}
+		return "", errors.Wrap(err, "localfs: error stating "+np)
+	}
+	return np, nil
+}
+
+func (fs *localfs) getLock(ctx context.Context, np string) (*provider.Lock, error) {
+	entry, err := fs.getLockEntry(ctx, np)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errtypes.NotFound("lock not found for ref")
+		}
+		return nil, errors.Wrap(err, "localfs: error reading lock from DB")
+	}
+
+	l := new(provider.Lock)
+	if err := json.Unmarshal([]byte(entry), l); err != nil {
+		return nil, errors.Wrap(err, "localfs: error decoding lock")
+	}
+
+	if l.Expiration != nil && time.Unix(int64(l.Expiration.Seconds), 0).Before(time.Now()) {
+		// the previous lock expired
+		if err := fs.removeFromLocksDB(ctx, np); err != nil {
+			return nil, err
+		}
+		return nil, errtypes.NotFound("lock not found for ref")
+	}
+	return l, nil
+}
+
+func (fs *localfs) setLock(ctx context.Context, np string, lock *provider.Lock) error {
+	data, err := json.Marshal(lock)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error encoding lock")
+	}
+	if err := fs.addToLocksDB(ctx, np, string(data)); err != nil {
+		return errors.Wrap(err, "localfs: error adding entry to DB")
+	}
+	return nil
+}
+
 // GetLock returns an existing lock on the given reference
 func (fs *localfs) GetLock(ctx context.Context, ref *provider.Reference) (*provider.Lock, error) {
-	return nil, errtypes.NotSupported("unimplemented")
+	np, err := fs.resolveLockPath(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return fs.getLock(ctx, np)
 }
 
 // SetLock puts a lock on the given reference
 func (fs *localfs) SetLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
-	return errtypes.NotSupported("unimplemented")
+	if lock.Type == provider.LockType_LOCK_TYPE_SHARED {
+		return errtypes.NotSupported("shared lock not yet implemented")
+	}
+
+	np, err := fs.resolveLockPath(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	_, err = fs.getLock(ctx, np)
+	switch err.(type) {
+	case nil:
+		return errtypes.BadRequest("resource already locked")
+	case errtypes.NotFound:
+	default:
+		return err
+	}
+
+	return fs.setLock(ctx, np, lock)
 }
 
 // RefreshLock refreshes an existing lock on the given reference
 func (fs *localfs) RefreshLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
-	return errtypes.NotSupported("unimplemented")
+	if lock.Type == provider.LockType_LOCK_TYPE_SHARED {
+		return errtypes.NotSupported("shared lock not yet implemented")
+	}
+
+	np, err := fs.resolveLockPath(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	oldLock, err := fs.getLock(ctx, np)
+	if err != nil {
+		if _, ok := err.(errtypes.NotFound); ok {
+			return errtypes.BadRequest("file was not locked")
+		}
+		return err
+	}
+
+	if oldLock.LockId != lock.LockId {
+		return errtypes.BadRequest("lock id does not match")
+	}
+	if !sameLockHolder(oldLock, lock) {
+		return errtypes.BadRequest("caller does not hold the lock")
+	}
+
+	return fs.setLock(ctx, np, lock)
 }
 
 // Unlock removes an existing lock from the given reference
 func (fs *localfs) Unlock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
-	return errtypes.NotSupported("unimplemented")
+	np, err := fs.resolveLockPath(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	oldLock, err := fs.getLock(ctx, np)
+	if err != nil {
+		if _, ok := err.(errtypes.NotFound); ok {
+			return errtypes.BadRequest("file was not locked")
+		}
+		return err
+	}
+
+	if oldLock.LockId != lock.LockId {
+		return errtypes.BadRequest("lock id does not match")
+	}
+	if !sameLockHolder(oldLock, lock) {
+		return errtypes.BadRequest("caller does not hold the lock")
+	}
+
+	return fs.removeFromLocksDB(ctx, np)
+}
+
+func sameLockHolder(l1, l2 *provider.Lock) bool {
+	if (l1.User != nil || l2.User != nil) && !utils.UserEqual(l1.User, l2.User) {
+		return false
+	}
+	return l1.AppName == l2.AppName
 }
 
 func (fs *localfs) GetHome(ctx context.Context) (string, error) {
@@ -788,7 +956,10 @@ func (fs *localfs) CreateDir(ctx context.Context, ref *provider.Reference) error
 	}
 
 	fn = fs.wrap(ctx, fn)
-	if _, err := os.Stat(fn); err == nil {
+	if err := fs.checkParent(ctx, fn); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(fn); err == nil {
 		return errtypes.AlreadyExists(fn)
 	}
 	err = os.Mkdir(fn, 0700)
@@ -824,7 +995,11 @@ func (fs *localfs) Delete(ctx context.Context, ref *provider.Reference) error {
 		fp = fs.wrap(ctx, fn)
 	}
 
-	_, err = os.Stat(fp)
+	// a symlink is deleted itself, whatever it points to
+	if err := fs.checkParent(ctx, fp); err != nil {
+		return err
+	}
+	_, err = os.Lstat(fp)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return errtypes.NotFound(fn)
@@ -842,6 +1017,11 @@ func (fs *localfs) Delete(ctx context.Context, ref *provider.Reference) error {
 		return errors.Wrap(err, "localfs: error adding entry to DB")
 	}
 
+	// a restored file must not come back locked
+	if err := fs.removeFromLocksDB(ctx, fp); err != nil {
+		return err
+	}
+
 	return fs.propagate(ctx, path.Dir(fp))
 }
 
@@ -863,6 +1043,12 @@ func (fs *localfs) Move(ctx context.Context, oldRef, newRef *provider.Reference)
 	oldName = fs.wrap(ctx, oldName)
 	newName = fs.wrap(ctx, newName)
 
+	for _, np := range []string{oldName, newName} {
+		if err := fs.checkParent(ctx, np); err != nil {
+			return err
+		}
+	}
+
 	if err := os.Rename(oldName, newName); err != nil {
 		return errors.Wrap(err, "localfs: error moving "+oldName+" to "+newName)
 	}
@@ -871,6 +1057,10 @@ func (fs *localfs) Move(ctx context.Context, oldRef, newRef *provider.Reference)
 		return errors.Wrap(err, "localfs: error copying metadata")
 	}
 
+	if err := fs.moveVersions(ctx, oldName, newName); err != nil {
+		return err
+	}
+
 	if err := fs.propagate(ctx, newName); err != nil {
 		return err
 	}
@@ -929,8 +1119,11 @@ func (fs *localfs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []
 	}
 
 	fn = fs.wrap(ctx, fn)
-	md, err := os.Stat(fn)
+	md, err := fs.stat(ctx, fn)
 	if err != nil {
+		if _, ok := err.(errtypes.PermissionDenied); ok {
+			return nil, err
+		}
 		if os.IsNotExist(err) {
 			return nil, errtypes.NotFound(fn)
 		}
@@ -992,6 +1185,9 @@ func (fs *localfs) ListFolder(ctx context.Context, ref *provider.Reference, mdKe
 func (fs *localfs) listFolder(ctx context.Context, fn string, mdKeys []string) ([]*provider.ResourceInfo, error) {
 
 	fn = fs.wrap(ctx, fn)
+	if err := fs.checkPath(ctx, fn); err != nil {
+		return nil, err
+	}
 
 	mds, err := ioutil.ReadDir(fn)
 	if err != nil {
@@ -1003,7 +1199,14 @@ func (fs *localfs) listFolder(ctx context.Context, fn string, mdKeys []string) (
 
 	finfos := []*provider.ResourceInfo{}
 	for _, md := range mds {
-		info, err := fs.normalize(ctx, md, path.Join(fn, md.Name()), mdKeys)
+		np := path.Join(fn, md.Name())
+		if isSymlink(md) {
+			// the symlinks which can not be followed are left out
+			if md, err = fs.statSymlink(ctx, np, md); err != nil {
+				continue
+			}
+		}
+		info, err := fs.normalize(ctx, md, np, mdKeys)
 		if err == nil {
 			finfos = append(finfos, info)
 		}
@@ -1050,9 +1253,12 @@ func (fs *localfs) Download(ctx context.Context, ref *provider.Reference) (io.Re
 	}
 
 	fn = fs.wrap(ctx, fn)
-	r, err := os.Open(fn)
+	if err := fs.checkPath(ctx, fn); err != nil {
+		return nil, err
+	}
+	r, err := fs.openContent(ctx, fn)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if os.IsNotExist(errors.Cause(err)) {
 			return nil, errtypes.NotFound(fn)
 		}
 		return nil, errors.Wrap(err, "localfs: error reading "+fn)
@@ -1075,6 +1281,65 @@ func (fs *localfs) archiveRevision(ctx context.Context, np string) error {
 	return nil
 }
 
+// pruneRevisions removes the oldest versions exceeding the configured maximum.
+func (fs *localfs) pruneRevisions(versionsDir string) error {
+	if fs.conf.MaxVersions <= 0 {
+		return nil
+	}
+	mds, err := ioutil.ReadDir(versionsDir)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error reading "+versionsDir)
+	}
+	var versions []int
+	for _, md := range mds {
+		if v, err := strconv.Atoi(strings.TrimPrefix(md.Name(), "v")); err == nil && !md.IsDir() {
+			versions = append(versions, v)
+		}
+	}
+	sort.Ints(versions)
+	for len(versions) > fs.conf.MaxVersions {
+		vp := path.Join(versionsDir, fmt.Sprintf("v%d", versions[0]))
+		if err := os.Remove(vp); err != nil {
+			return errors.Wrap(err, "localfs: error removing version "+vp)
+		}
+		versions = versions[1:]
+	}
+	return nil
+}
+
+// moveVersions moves the versions of a file or the ones of the files in a
+// folder along with them, as they are kept by path.
+func (fs *localfs) moveVersions(ctx context.Context, oldName, newName string) error {
+	oldVersions := fs.wrapVersions(ctx, fs.unwrap(ctx, oldName))
+	newVersions := fs.wrapVersions(ctx, fs.unwrap(ctx, newName))
+	if _, err := os.Stat(oldVersions); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrap(err, "localfs: error stating "+oldVersions)
+	}
+	if err := os.MkdirAll(path.Dir(newVersions), 0700); err != nil {
+		return errors.Wrap(err, "localfs: error creating file versions dir "+path.Dir(newVersions))
+	}
+	// the versions of a file previously at the destination go with it
+	if err := os.RemoveAll(newVersions); err != nil {
+		return errors.Wrap(err, "localfs: error removing "+newVersions)
+	}
+	if err := os.Rename(oldVersions, newVersions); err != nil {
+		return errors.Wrap(err, "localfs: error moving versions from "+oldVersions+" to "+newVersions)
+	}
+	return nil
+}
+
+// versionPath returns the path of a version from its key, the version
+// timestamp.
+func (fs *localfs) versionPath(ctx context.Context, np, revisionKey string) (string, error) {
+	if _, err := strconv.ParseUint(revisionKey, 10, 64); err != nil {
+		return "", errtypes.BadRequest("localfs: invalid revision key " + revisionKey)
+	}
+	return path.Join(fs.wrapVersions(ctx, np), "v"+revisionKey), nil
+}
+
 func (fs *localfs) ListRevisions(ctx context.Context, ref *provider.Reference) ([]*provider.FileVersion, error) {
 	np, err := fs.resolve(ctx, ref)
 	if err != nil {
@@ -1089,21 +1354,28 @@ func (fs *localfs) ListRevisions(ctx context.Context, ref *provider.Reference) (
 	revisions := []*provider.FileVersion{}
 	mds, err := ioutil.ReadDir(versionsDir)
 	if err != nil {
+		if os.IsNotExist(err) {
+			// the file has never been overwritten
+			return revisions, nil
+		}
 		return nil, errors.Wrap(err, "localfs: error reading"+versionsDir)
 	}
 
 	for i := range mds {
-		// versions resemble v12345678
+		// versions resemble v12345678, the time of the overwrite in milliseconds
+		if mds[i].IsDir() || !strings.HasPrefix(mds[i].Name(), "v") {
+			continue
+		}
 		version := mds[i].Name()[1:]
 
-		mtime, err := strconv.Atoi(version)
+		mtime, err := strconv.ParseUint(version, 10, 64)
 		if err != nil {
 			continue
 		}
 		revisions = append(revisions, &provider.FileVersion{
 			Key:   version,
-			Size:  uint64(mds[i].Size()),
-			Mtime: uint64(mtime),
+			Size:  fs.contentSize(path.Join(versionsDir, mds[i].Name()), mds[i]),
+			Mtime: mtime / 1000,
 			Etag:  calcEtag(ctx, mds[i]),
 		})
 	}
@@ -1120,12 +1392,14 @@ func (fs *localfs) DownloadRevision(ctx context.Context, ref *provider.Reference
 		return nil, errtypes.PermissionDenied("localfs: cannot download revisions under the virtual share folder")
 	}
 
-	versionsDir := fs.wrapVersions(ctx, np)
-	vp := path.Join(versionsDir, revisionKey)
+	vp, err := fs.versionPath(ctx, np, revisionKey)
+	if err != nil {
+		return nil, err
+	}
 
-	r, err := os.Open(vp)
+	r, err := fs.openContent(ctx, vp)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if os.IsNotExist(errors.Cause(err)) {
 			return nil, errtypes.NotFound(vp)
 		}
 		return nil, errors.Wrap(err, "localfs: error reading "+vp)
@@ -1144,8 +1418,10 @@ func (fs *localfs) RestoreRevision(ctx context.Context, ref *provider.Reference,
 		return errtypes.PermissionDenied("localfs: cannot restore revisions under the virtual share folder")
 	}
 
-	versionsDir := fs.wrapVersions(ctx, np)
-	vp := path.Join(versionsDir, revisionKey)
+	vp, err := fs.versionPath(ctx, np, revisionKey)
+	if err != nil {
+		return err
+	}
 	np = fs.wrap(ctx, np)
 
 	// check revision exists
@@ -1169,21 +1445,76 @@ func (fs *localfs) RestoreRevision(ctx context.Context, ref *provider.Reference,
 		return errors.Wrap(err, "localfs: error renaming from "+vp+" to "+np)
 	}
 
+	if err := fs.pruneRevisions(path.Dir(vp)); err != nil {
+		return err
+	}
+
 	return fs.propagate(ctx, np)
 }
 
+// recycleItemPath returns the internal path of a deleted item or of a file in
+// a deleted folder.
+func (fs *localfs) recycleItemPath(ctx context.Context, key, relativePath string) (string, error) {
+	if _, ok := deletionTime(key); !ok {
+		return "", errtypes.BadRequest("localfs: invalid trash item key " + key)
+	}
+	return path.Join(fs.wrapRecycleBin(ctx, key), path.Clean("/"+relativePath)), nil
+}
+
+// deletionTime returns the time in milliseconds a trash item was deleted at,
+// trash items are named like filename.txt.d12345678.
+func deletionTime(key string) (uint64, bool) {
+	suffix := path.Ext(key)
+	if len(suffix) == 0 || !strings.HasPrefix(suffix, ".d") {
+		return 0, false
+	}
+	t, err := strconv.ParseUint(suffix[2:], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return t, true
+}
+
+func isItemRoot(relativePath string) bool {
+	return path.Clean("/"+relativePath) == "/"
+}
+
 func (fs *localfs) PurgeRecycleItem(ctx context.Context, basePath, key, relativePath string) error {
-	rp := fs.wrapRecycleBin(ctx, key)
+	rp, err := fs.recycleItemPath(ctx, key, relativePath)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(rp); err != nil {
+		if os.IsNotExist(err) {
+			return errtypes.NotFound(path.Join(key, relativePath))
+		}
+		return errors.Wrap(err, "localfs: error stating "+rp)
+	}
 
-	if err := os.Remove(rp); err != nil {
+	if err := os.RemoveAll(rp); err != nil {
 		return errors.Wrap(err, "localfs: error deleting recycle item")
 	}
+	if isItemRoot(relativePath) {
+		if err := fs.removeFromRecycledDB(ctx, key); err != nil {
+			return errors.Wrap(err, "localfs: error removing entry from DB")
+		}
+	}
 	return nil
 }
 
 func (fs *localfs) EmptyRecycle(ctx context.Context) error {
 	rp := fs.wrapRecycleBin(ctx, "/")
 
+	mds, err := ioutil.ReadDir(rp)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error listing deleted files")
+	}
+	for _, md := range mds {
+		if err := fs.removeFromRecycledDB(ctx, md.Name()); err != nil {
+			return errors.Wrap(err, "localfs: error removing entry from DB")
+		}
+	}
+
 	if err := os.RemoveAll(rp); err != nil {
 		return errors.Wrap(err, "localfs: error deleting recycle files")
 	}
@@ -1193,64 +1524,103 @@ func (fs *localfs) EmptyRecycle(ctx context.Context) error {
 	return nil
 }
 
-func (fs *localfs) convertToRecycleItem(ctx context.Context, rp string, md os.FileInfo) *provider.RecycleItem {
-	// trashbin items have filename.txt.d12345678
-	suffix := path.Ext(md.Name())
-	if len(suffix) == 0 || !strings.HasPrefix(suffix, ".d") {
-		return nil
+// purgeExpiredRecycleItems removes the items deleted before the configured
+// retention period.
+func (fs *localfs) purgeExpiredRecycleItems(ctx context.Context, rp string, mds []os.FileInfo) []os.FileInfo {
+	if fs.conf.RecycleMaxAge <= 0 {
+		return mds
 	}
+	log := appctx.GetLogger(ctx)
+	limit := uint64(time.Now().Add(-time.Duration(fs.conf.RecycleMaxAge)*24*time.Hour).UnixNano() / int64(time.Millisecond))
 
-	trashtime := suffix[2:]
-	ttime, err := strconv.Atoi(trashtime)
-	if err != nil {
+	kept := mds[:0]
+	for _, md := range mds {
+		if t, ok := deletionTime(md.Name()); !ok || t >= limit {
+			kept = append(kept, md)
+			continue
+		}
+		if err := os.RemoveAll(path.Join(rp, md.Name())); err != nil {
+			log.Error().Err(err).Str("key", md.Name()).Msg("localfs: error purging expired recycle item")
+			kept = append(kept, md)
+			continue
+		}
+		if err := fs.removeFromRecycledDB(ctx, md.Name()); err != nil {
+			log.Error().Err(err).Str("key", md.Name()).Msg("localfs: error removing expired recycle item from DB")
+		}
+	}
+	return kept
+}
+
+func (fs *localfs) convertToRecycleItem(ctx context.Context, rp, key, relativePath string, md os.FileInfo) *provider.RecycleItem {
+	ttime, ok := deletionTime(key)
+	if !ok {
 		return nil
 	}
 
-	filePath, err := fs.getRecycledEntry(ctx, md.Name())
+	filePath, err := fs.getRecycledEntry(ctx, key)
 	if err != nil {
 		return nil
 	}
 
 	return &provider.RecycleItem{
 		Type: getResourceType(md.IsDir()),
-		Key:  md.Name(),
-		Ref:  &provider.Reference{Path: filePath},
-		Size: uint64(md.Size()),
+		Key:  path.Join(key, relativePath),
+		Ref:  &provider.Reference{Path: path.Join(filePath, relativePath)},
+		Size: fs.contentSize(path.Join(rp, md.Name()), md),
 		DeletionTime: &types.Timestamp{
-			Seconds: uint64(ttime),
+			Seconds: ttime / 1000,
 		},
 	}
 }
 
+// ListRecycle lists the deleted items or, given the key of a deleted folder,
+// the files in it. The expired items are purged when listing the trash.
 func (fs *localfs) ListRecycle(ctx context.Context, basePath, key, relativePath string) ([]*provider.RecycleItem, error) {
-
 	rp := fs.wrapRecycleBin(ctx, "/")
+	if key != "" {
+		var err error
+		if rp, err = fs.recycleItemPath(ctx, key, relativePath); err != nil {
+			return nil, err
+		}
+	}
 
 	mds, err := ioutil.ReadDir(rp)
 	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errtypes.NotFound(path.Join(key, relativePath))
+		}
 		return nil, errors.Wrap(err, "localfs: error listing deleted files")
 	}
 	items := []*provider.RecycleItem{}
-	for i := range mds {
-		ri := fs.convertToRecycleItem(ctx, rp, mds[i])
-		if ri != nil {
+	if key == "" {
+		for _, md := range fs.purgeExpiredRecycleItems(ctx, rp, mds) {
+			if ri := fs.convertToRecycleItem(ctx, rp, md.Name(), "", md); ri != nil {
+				items = append(items, ri)
+			}
+		}
+		return items, nil
+	}
+	for _, md := range mds {
+		if ri := fs.convertToRecycleItem(ctx, rp, key, path.Join(relativePath, md.Name()), md); ri != nil {
 			items = append(items, ri)
 		}
 	}
 	return items, nil
 }
 
+// RestoreRecycleItem restores a deleted item or a file in a deleted folder,
+// to its original location unless a restore reference is given.
 func (fs *localfs) RestoreRecycleItem(ctx context.Context, basePath, key, relativePath string, restoreRef *provider.Reference) error {
-
-	suffix := path.Ext(key)
-	if len(suffix) == 0 || !strings.HasPrefix(suffix, ".d") {
-		return errors.New("localfs: invalid trash item suffix")
+	rp, err := fs.recycleItemPath(ctx, key, relativePath)
+	if err != nil {
+		return err
 	}
 
 	filePath, err := fs.getRecycledEntry(ctx, key)
 	if err != nil {
 		return errors.Wrap(err, "localfs: invalid key")
 	}
+	filePath = path.Join(filePath, relativePath)
 
 	var localRestorePath string
 	switch {
@@ -1263,24 +1633,24 @@ func (fs *localfs) RestoreRecycleItem(ctx context.Context, basePath, key, relati
 	}
 
 	if _, err = os.Stat(localRestorePath); err == nil {
-		return errors.New("localfs: can't restore - file already exists at original path")
+		return errtypes.AlreadyExists("localfs: can't restore - file already exists at original path")
 	}
 
-	rp := fs.wrapRecycleBin(ctx, key)
 	if _, err = os.Stat(rp); err != nil {
 		if os.IsNotExist(err) {
-			return errtypes.NotFound(key)
+			return errtypes.NotFound(path.Join(key, relativePath))
 		}
 		return errors.Wrap(err, "localfs: error stating "+rp)
 	}
 
 	if err := os.Rename(rp, localRestorePath); err != nil {
-		return errors.Wrap(err, "ocfs: could not restore item")
+		return errors.Wrap(err, "localfs: could not restore item")
 	}
 
-	err = fs.removeFromRecycledDB(ctx, key)
-	if err != nil {
-		return errors.Wrap(err, "localfs: error adding entry to DB")
+	if isItemRoot(relativePath) {
+		if err := fs.removeFromRecycledDB(ctx, key); err != nil {
+			return errors.Wrap(err, "localfs: error removing entry from DB")
+		}
 	}
 
 	return fs.propagate(ctx, localRestorePath)
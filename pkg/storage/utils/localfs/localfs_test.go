@@ -0,0 +1,316 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package localfs
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+func newTestFS(t *testing.T, c *Config) (*localfs, context.Context) {
+	t.Helper()
+
+	c.Root = t.TempDir()
+	c.DisableHome = true
+	fs, err := NewLocalFS(c)
+	if err != nil {
+		t.Fatalf("unable to create the fs: %v", err)
+	}
+	u := &userpb.User{Id: &userpb.UserId{OpaqueId: "einstein"}, Username: "einstein"}
+	return fs.(*localfs), ctxpkg.ContextSetUser(context.Background(), u)
+}
+
+func writeFile(t *testing.T, fs *localfs, ctx context.Context, fn, content string) {
+	t.Helper()
+
+	np := fs.wrap(ctx, fn)
+	if err := os.MkdirAll(path.Dir(np), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(np, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, fs *localfs, ctx context.Context, fn string) string {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(fs.wrap(ctx, fn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestDeletionTime(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected uint64
+		ok       bool
+	}{
+		{"file.txt.d1633089600000", 1633089600000, true},
+		{"folder.d5", 5, true},
+		{"file.txt", 0, false},
+		{"file.txt.dnow", 0, false},
+		{"file", 0, false},
+	}
+	for _, tt := range tests {
+		if d, ok := deletionTime(tt.key); d != tt.expected || ok != tt.ok {
+			t.Errorf("deletionTime(%q) = %d, %v, expected %d, %v", tt.key, d, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestTrash(t *testing.T) {
+	fs, ctx := newTestFS(t, &Config{})
+	writeFile(t, fs, ctx, "/folder/a.txt", "a")
+	writeFile(t, fs, ctx, "/folder/b.txt", "b")
+
+	if err := fs.Delete(ctx, &provider.Reference{Path: "/folder"}); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := fs.ListRecycle(ctx, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Ref.Path != "/folder" || items[0].Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		t.Fatalf("expected the deleted folder, got %v", items)
+	}
+	key := items[0].Key
+	if d := time.Since(time.Unix(int64(items[0].DeletionTime.Seconds), 0)); d < 0 || d > time.Minute {
+		t.Errorf("expected the deletion time to be now, got %v", items[0].DeletionTime)
+	}
+
+	// The files in a deleted folder can be listed, restored and purged one by one
+	items, err = fs.ListRecycle(ctx, "", key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Key != key+"/a.txt" || items[0].Ref.Path != "/folder/a.txt" {
+		t.Fatalf("expected the files of the deleted folder, got %v", items)
+	}
+
+	if err := fs.RestoreRecycleItem(ctx, "", key, "a.txt", &provider.Reference{Path: "/a.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if content := readFile(t, fs, ctx, "/a.txt"); content != "a" {
+		t.Errorf("expected the restored content, got %q", content)
+	}
+	if err := fs.PurgeRecycleItem(ctx, "", key, "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := fs.ListRecycle(ctx, "", key, ""); len(items) != 0 {
+		t.Errorf("expected the deleted folder to be empty, got %v", items)
+	}
+	if items, _ := fs.ListRecycle(ctx, "", "", ""); len(items) != 1 {
+		t.Errorf("expected the deleted folder to be kept, got %v", items)
+	}
+
+	if err := fs.PurgeRecycleItem(ctx, "", key, ""); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := fs.ListRecycle(ctx, "", "", ""); len(items) != 0 {
+		t.Errorf("expected the trash to be empty, got %v", items)
+	}
+	if _, err := fs.getRecycledEntry(ctx, key); err == nil {
+		t.Error("expected the entry of the purged folder to be removed")
+	}
+
+	if err := fs.PurgeRecycleItem(ctx, "", key, ""); !isNotFound(err) {
+		t.Errorf("expected purging twice to fail with not found, got %v", err)
+	}
+	if err := fs.PurgeRecycleItem(ctx, "", "../data", ""); err == nil {
+		t.Error("expected an invalid key to be rejected")
+	}
+}
+
+func TestRestoreRecycleItemExists(t *testing.T) {
+	fs, ctx := newTestFS(t, &Config{})
+	writeFile(t, fs, ctx, "/file.txt", "old")
+	if err := fs.Delete(ctx, &provider.Reference{Path: "/file.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fs, ctx, "/file.txt", "new")
+
+	items, err := fs.ListRecycle(ctx, "", "", "")
+	if err != nil || len(items) != 1 {
+		t.Fatalf("expected the deleted file, got %v, %v", items, err)
+	}
+	if err := fs.RestoreRecycleItem(ctx, "", items[0].Key, "", nil); err == nil {
+		t.Error("expected the restore over an existing file to fail")
+	} else if _, ok := err.(errtypes.IsAlreadyExists); !ok {
+		t.Errorf("expected an already exists error, got %v", err)
+	}
+}
+
+func TestRecycleMaxAge(t *testing.T) {
+	fs, ctx := newTestFS(t, &Config{RecycleMaxAge: 1})
+
+	old := fmt.Sprintf("old.txt.d%d", time.Now().Add(-48*time.Hour).UnixNano()/int64(time.Millisecond))
+	if err := ioutil.WriteFile(fs.wrapRecycleBin(ctx, old), []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.addToRecycledDB(ctx, old, "/old.txt"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fs, ctx, "/recent.txt", "recent")
+	if err := fs.Delete(ctx, &provider.Reference{Path: "/recent.txt"}); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := fs.ListRecycle(ctx, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Ref.Path != "/recent.txt" {
+		t.Errorf("expected only the recently deleted file, got %v", items)
+	}
+	if _, err := os.Stat(fs.wrapRecycleBin(ctx, old)); !os.IsNotExist(err) {
+		t.Error("expected the expired item to be purged")
+	}
+}
+
+func TestPruneRevisions(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"v1000", "v3000", "v2000", "notes"} {
+		if err := ioutil.WriteFile(path.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fs := &localfs{conf: &Config{}}
+	if err := fs.pruneRevisions(dir); err != nil {
+		t.Fatal(err)
+	}
+	if mds, _ := ioutil.ReadDir(dir); len(mds) != 4 {
+		t.Errorf("expected all versions to be kept without a maximum, got %d files", len(mds))
+	}
+
+	fs.conf.MaxVersions = 2
+	if err := fs.pruneRevisions(dir); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	mds, _ := ioutil.ReadDir(dir)
+	for _, md := range mds {
+		names = append(names, md.Name())
+	}
+	if strings.Join(names, ",") != "notes,v2000,v3000" {
+		t.Errorf("expected the oldest version to be removed, got %v", names)
+	}
+}
+
+func TestRevisions(t *testing.T) {
+	fs, ctx := newTestFS(t, &Config{})
+	ref := &provider.Reference{Path: "/file.txt"}
+	writeFile(t, fs, ctx, "/file.txt", "current")
+
+	revisions, err := fs.ListRevisions(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 0 {
+		t.Errorf("expected no revisions for a file never overwritten, got %v", revisions)
+	}
+
+	versionsDir := fs.wrapVersions(ctx, "/file.txt")
+	if err := os.MkdirAll(versionsDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(versionsDir, "v1633089600000"), []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, err = fs.ListRevisions(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 || revisions[0].Key != "1633089600000" || revisions[0].Mtime != 1633089600 || revisions[0].Size != 3 {
+		t.Fatalf("unexpected revisions %v", revisions)
+	}
+
+	r, err := fs.DownloadRevision(ctx, ref, "1633089600000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(r)
+	r.Close()
+	if string(data) != "old" {
+		t.Errorf("expected the content of the revision, got %q", data)
+	}
+	if _, err := fs.DownloadRevision(ctx, ref, "../../data/file.txt"); err == nil {
+		t.Error("expected an invalid revision key to be rejected")
+	} else if _, ok := err.(errtypes.IsBadRequest); !ok {
+		t.Errorf("expected a bad request error, got %v", err)
+	}
+
+	if err := fs.RestoreRevision(ctx, ref, "1633089600000"); err != nil {
+		t.Fatal(err)
+	}
+	if content := readFile(t, fs, ctx, "/file.txt"); content != "old" {
+		t.Errorf("expected the restored content, got %q", content)
+	}
+	revisions, _ = fs.ListRevisions(ctx, ref)
+	if len(revisions) != 1 || revisions[0].Key == "1633089600000" {
+		t.Errorf("expected the overwritten content to be kept as a revision, got %v", revisions)
+	}
+}
+
+func TestMoveVersions(t *testing.T) {
+	fs, ctx := newTestFS(t, &Config{})
+	writeFile(t, fs, ctx, "/file.txt", "current")
+	versionsDir := fs.wrapVersions(ctx, "/file.txt")
+	if err := os.MkdirAll(versionsDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(versionsDir, "v1000"), []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.Move(ctx, &provider.Reference{Path: "/file.txt"}, &provider.Reference{Path: "/moved.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := fs.ListRevisions(ctx, &provider.Reference{Path: "/moved.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 || revisions[0].Key != "1000" {
+		t.Errorf("expected the versions to be moved along, got %v", revisions)
+	}
+	if _, err := os.Stat(versionsDir); !os.IsNotExist(err) {
+		t.Error("expected no versions to be left at the old path")
+	}
+}
+
+func isNotFound(err error) bool {
+	_, ok := err.(errtypes.IsNotFound)
+	return ok
+}
@@ -0,0 +1,45 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package localfs
+
+import "github.com/cs3org/reva/pkg/migrate"
+
+// Schema is the migration component of the SQLite database kept by the local storage drivers.
+const Schema = "localfs"
+
+func init() {
+	migrate.Register(Schema, migrate.Migration{
+		Version:     1,
+		Description: "create the recycle, interaction, metadata, share reference and lock tables",
+		Up: []string{
+			"CREATE TABLE IF NOT EXISTS recycled_entries (key TEXT PRIMARY KEY, path TEXT)",
+			"CREATE TABLE IF NOT EXISTS user_interaction (resource TEXT, grantee TEXT, role TEXT DEFAULT '', favorite INTEGER DEFAULT 0, PRIMARY KEY (resource, grantee))",
+			"CREATE TABLE IF NOT EXISTS metadata (resource TEXT, key TEXT, value TEXT, PRIMARY KEY (resource, key))",
+			"CREATE TABLE IF NOT EXISTS share_references (resource TEXT PRIMARY KEY, target TEXT)",
+			"CREATE TABLE IF NOT EXISTS locks (resource TEXT PRIMARY KEY, lock TEXT)",
+		},
+		Down: []string{
+			"DROP TABLE IF EXISTS locks",
+			"DROP TABLE IF EXISTS share_references",
+			"DROP TABLE IF EXISTS metadata",
+			"DROP TABLE IF EXISTS user_interaction",
+			"DROP TABLE IF EXISTS recycled_entries",
+		},
+	})
+}
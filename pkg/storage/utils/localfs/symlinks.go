@@ -0,0 +1,152 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package localfs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// The policies for the symlinks found in the storage, scientific datasets commonly contain them.
+const (
+	// symlinksFollow serves the symlinks as the resources they point to, if inside the mount root.
+	symlinksFollow = "follow"
+	// symlinksExpose serves the symlinks as link resources carrying their target. The symlinks
+	// pointing inside the mount root can still be traversed.
+	symlinksExpose = "expose"
+	// symlinksReject hides the symlinks and refuses to traverse them.
+	symlinksReject = "reject"
+)
+
+func validateSymlinksPolicy(p string) error {
+	switch p {
+	case symlinksFollow, symlinksExpose, symlinksReject:
+		return nil
+	default:
+		return fmt.Errorf("localfs: invalid symlinks policy %q", p)
+	}
+}
+
+func isSymlink(fi os.FileInfo) bool {
+	return fi.Mode()&os.ModeSymlink != 0
+}
+
+// realRoot returns the mount root of the user of the context with its own symlinks evaluated.
+func (fs *localfs) realRoot(ctx context.Context) (string, error) {
+	return filepath.EvalSymlinks(fs.wrap(ctx, "/"))
+}
+
+// resolveSymlink returns the internal path the symlink at np points to and whether it lies in the mount root.
+func (fs *localfs) resolveSymlink(ctx context.Context, np string) (string, bool) {
+	root, err := fs.realRoot(ctx)
+	if err != nil {
+		return "", false
+	}
+	target, err := filepath.EvalSymlinks(np)
+	if err != nil {
+		return "", false
+	}
+	return target, target == root || strings.HasPrefix(target, root+string(filepath.Separator))
+}
+
+// symlinkTarget returns the path of the target of the symlink at np relative to the mount root, as seen
+// by the users. The targets outside of the mount root are not disclosed.
+func (fs *localfs) symlinkTarget(ctx context.Context, np string) string {
+	target, inside := fs.resolveSymlink(ctx, np)
+	if !inside {
+		return ""
+	}
+	root, err := fs.realRoot(ctx)
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(root, target)
+	if err != nil {
+		return ""
+	}
+	return filepath.Join("/", rel)
+}
+
+// checkPath makes sure that the symlinks met on the way to the internal path np, np included, can be
+// traversed: with the reject policy no symlink can, otherwise only those pointing inside the mount root.
+// The part of the path which does not exist yet is not checked.
+func (fs *localfs) checkPath(ctx context.Context, np string) error {
+	root := fs.wrap(ctx, "/")
+	rel, err := filepath.Rel(root, np)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		// the virtual folders live outside of the data directory
+		return nil
+	}
+
+	cur := root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		cur = filepath.Join(cur, part)
+		fi, err := os.Lstat(cur)
+		if err != nil {
+			return nil
+		}
+		if !isSymlink(fi) {
+			continue
+		}
+		if fs.conf.Symlinks == symlinksReject {
+			return errtypes.PermissionDenied("localfs: symlinks are not allowed: " + fs.unwrap(ctx, cur))
+		}
+		if _, inside := fs.resolveSymlink(ctx, cur); !inside {
+			return errtypes.PermissionDenied("localfs: symlink points outside of the mount: " + fs.unwrap(ctx, cur))
+		}
+	}
+	return nil
+}
+
+// checkParent makes sure that the parent of the internal path np can be traversed, for the operations
+// acting on a symlink itself rather than on its target.
+func (fs *localfs) checkParent(ctx context.Context, np string) error {
+	return fs.checkPath(ctx, filepath.Dir(np))
+}
+
+// stat returns the file info of the internal path np according to the symlinks policy: symlinks are
+// either followed inside the mount root, returned as such or refused.
+func (fs *localfs) stat(ctx context.Context, np string) (os.FileInfo, error) {
+	if err := fs.checkParent(ctx, np); err != nil {
+		return nil, err
+	}
+	fi, err := os.Lstat(np)
+	if err != nil || !isSymlink(fi) {
+		return fi, err
+	}
+	return fs.statSymlink(ctx, np, fi)
+}
+
+func (fs *localfs) statSymlink(ctx context.Context, np string, fi os.FileInfo) (os.FileInfo, error) {
+	switch fs.conf.Symlinks {
+	case symlinksExpose:
+		return fi, nil
+	case symlinksReject:
+		return nil, errtypes.PermissionDenied("localfs: symlinks are not allowed: " + fs.unwrap(ctx, np))
+	}
+	if _, inside := fs.resolveSymlink(ctx, np); !inside {
+		return nil, errtypes.PermissionDenied("localfs: symlink points outside of the mount: " + fs.unwrap(ctx, np))
+	}
+	return os.Stat(np)
+}
@@ -0,0 +1,168 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package localfs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// newSymlinksFS creates a storage holding a folder and two symlinks, one pointing to the folder and one
+// pointing outside of the mount root.
+func newSymlinksFS(t *testing.T, policy string) (*localfs, context.Context) {
+	t.Helper()
+
+	fs, ctx := newTestFS(t, &Config{Symlinks: policy})
+	writeFile(t, fs, ctx, "/folder/file.txt", "inside")
+
+	outside := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(outside, "secret.txt"), []byte("outside"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(fs.wrap(ctx, "/folder"), fs.wrap(ctx, "/inside")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, fs.wrap(ctx, "/outside")); err != nil {
+		t.Fatal(err)
+	}
+	return fs, ctx
+}
+
+func listNames(t *testing.T, fs *localfs, ctx context.Context, fn string) map[string]*provider.ResourceInfo {
+	t.Helper()
+
+	infos, err := fs.ListFolder(ctx, &provider.Reference{Path: fn}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]*provider.ResourceInfo{}
+	for _, info := range infos {
+		names[filepath.Base(info.Path)] = info
+	}
+	return names
+}
+
+func isPermissionDenied(err error) bool {
+	_, ok := err.(errtypes.IsPermissionDenied)
+	return ok
+}
+
+func TestSymlinksPolicy(t *testing.T) {
+	for _, p := range []string{symlinksFollow, symlinksExpose, symlinksReject} {
+		if err := validateSymlinksPolicy(p); err != nil {
+			t.Errorf("expected policy %q to be valid, got %v", p, err)
+		}
+	}
+	if _, err := NewLocalFS(&Config{Root: t.TempDir(), Symlinks: "ignore"}); err == nil {
+		t.Error("expected an invalid policy to be rejected")
+	}
+}
+
+func TestSymlinksFollow(t *testing.T) {
+	fs, ctx := newSymlinksFS(t, symlinksFollow)
+
+	md, err := fs.GetMD(ctx, &provider.Reference{Path: "/inside"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		t.Errorf("expected the symlink to be followed, got %v", md.Type)
+	}
+	if _, err := fs.GetMD(ctx, &provider.Reference{Path: "/outside"}, nil); !isPermissionDenied(err) {
+		t.Errorf("expected the symlink pointing outside to be refused, got %v", err)
+	}
+
+	names := listNames(t, fs, ctx, "/")
+	if names["inside"] == nil || names["inside"].Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER || names["outside"] != nil {
+		t.Errorf("expected only the symlink pointing inside to be listed, got %v", names)
+	}
+
+	r, err := fs.Download(ctx, &provider.Reference{Path: "/inside/file.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(r)
+	r.Close()
+	if string(data) != "inside" {
+		t.Errorf("expected the content of the target, got %q", data)
+	}
+	if _, err := fs.Download(ctx, &provider.Reference{Path: "/outside/secret.txt"}); !isPermissionDenied(err) {
+		t.Errorf("expected the download through the symlink pointing outside to be refused, got %v", err)
+	}
+}
+
+func TestSymlinksExpose(t *testing.T) {
+	fs, ctx := newSymlinksFS(t, symlinksExpose)
+
+	md, err := fs.GetMD(ctx, &provider.Reference{Path: "/inside"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Type != provider.ResourceType_RESOURCE_TYPE_SYMLINK || md.Target != "/folder" || md.MimeType != "inode/symlink" {
+		t.Errorf("expected the symlink to be exposed with its target, got %v", md)
+	}
+	md, err = fs.GetMD(ctx, &provider.Reference{Path: "/outside"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Type != provider.ResourceType_RESOURCE_TYPE_SYMLINK || md.Target != "" {
+		t.Errorf("expected the target outside of the mount not to be disclosed, got %v", md)
+	}
+
+	if names := listNames(t, fs, ctx, "/"); names["inside"] == nil || names["outside"] == nil {
+		t.Errorf("expected both symlinks to be listed, got %v", names)
+	}
+	if names := listNames(t, fs, ctx, "/inside"); names["file.txt"] == nil {
+		t.Errorf("expected the symlink pointing inside to be traversed, got %v", names)
+	}
+	if _, err := fs.ListFolder(ctx, &provider.Reference{Path: "/outside"}, nil); !isPermissionDenied(err) {
+		t.Errorf("expected the symlink pointing outside not to be traversed, got %v", err)
+	}
+
+	// Deleting a symlink leaves its target alone
+	if err := fs.Delete(ctx, &provider.Reference{Path: "/inside"}); err != nil {
+		t.Fatal(err)
+	}
+	if content := readFile(t, fs, ctx, "/folder/file.txt"); content != "inside" {
+		t.Errorf("expected the target to be kept, got %q", content)
+	}
+}
+
+func TestSymlinksReject(t *testing.T) {
+	fs, ctx := newSymlinksFS(t, symlinksReject)
+
+	if _, err := fs.GetMD(ctx, &provider.Reference{Path: "/inside"}, nil); !isPermissionDenied(err) {
+		t.Errorf("expected the symlink to be refused, got %v", err)
+	}
+	if names := listNames(t, fs, ctx, "/"); names["inside"] != nil || names["outside"] != nil || names["folder"] == nil {
+		t.Errorf("expected the symlinks to be hidden, got %v", names)
+	}
+	if _, err := fs.ListFolder(ctx, &provider.Reference{Path: "/inside"}, nil); !isPermissionDenied(err) {
+		t.Errorf("expected the symlink not to be traversed, got %v", err)
+	}
+	if err := fs.CreateDir(ctx, &provider.Reference{Path: "/inside/new"}); !isPermissionDenied(err) {
+		t.Errorf("expected no folder to be created through the symlink, got %v", err)
+	}
+}
@@ -153,6 +153,9 @@ func (fs *localfs) NewUpload(ctx context.Context, info tusd.FileInfo) (upload tu
 	info.MetaData["dir"] = filepath.Clean(info.MetaData["dir"])
 
 	np := fs.wrap(ctx, filepath.Join(info.MetaData["dir"], info.MetaData["filename"]))
+	if err := fs.checkParent(ctx, np); err != nil {
+		return nil, err
+	}
 
 	log.Debug().Interface("info", info).Msg("localfs: resolved filename")
 
@@ -327,9 +330,22 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) error {
 		if err := upload.fs.archiveRevision(upload.ctx, np); err != nil {
 			return err
 		}
+		versionsDir := upload.fs.wrapVersions(upload.ctx, upload.fs.unwrap(upload.ctx, np))
+		if err := upload.fs.pruneRevisions(versionsDir); err != nil {
+			return err
+		}
+	}
+
+	binPath := upload.binPath
+	if upload.fs.encrypter != nil {
+		// encrypt next to the upload and rename, so that the file is replaced atomically
+		binPath += ".enc"
+		if err := upload.fs.encryptFile(upload.ctx, upload.binPath, binPath); err != nil {
+			return err
+		}
 	}
 
-	err := os.Rename(upload.binPath, np)
+	err := os.Rename(binPath, np)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,186 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package mdbackup implements the archive format used to back up and
+// restore the metadata of a storage: the grants and arbitrary metadata of
+// every resource below a path and the definitions of its storage spaces.
+//
+// An archive is a gzip compressed stream of newline delimited JSON records.
+// The first record is a header, every following one describes either a
+// resource or a storage space. Proto messages are encoded with protojson so
+// that archives stay readable and independent of the storage driver.
+package mdbackup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// Version is the version of the archive format written by this package.
+const Version = 1
+
+const (
+	kindHeader   = "header"
+	kindResource = "resource"
+	kindSpace    = "space"
+)
+
+// Resource holds the metadata of a single file or folder.
+type Resource struct {
+	Path     string
+	Metadata map[string]string
+	Grants   []*provider.Grant
+}
+
+// Entry is a record read from an archive, exactly one of its fields is set.
+type Entry struct {
+	Resource *Resource
+	Space    *provider.StorageSpace
+}
+
+type record struct {
+	Kind     string            `json:"kind"`
+	Version  int               `json:"version,omitempty"`
+	Root     string            `json:"root,omitempty"`
+	Path     string            `json:"path,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Grants   []json.RawMessage `json:"grants,omitempty"`
+	Space    json.RawMessage   `json:"space,omitempty"`
+}
+
+// Writer writes an archive.
+type Writer struct {
+	gz  *gzip.Writer
+	enc *json.Encoder
+}
+
+// NewWriter returns a Writer writing the archive of the metadata below root to w.
+// Callers must call Close to flush the archive.
+func NewWriter(w io.Writer, root string) (*Writer, error) {
+	gz := gzip.NewWriter(w)
+	aw := &Writer{gz: gz, enc: json.NewEncoder(gz)}
+	if err := aw.enc.Encode(&record{Kind: kindHeader, Version: Version, Root: root}); err != nil {
+		return nil, errors.Wrap(err, "mdbackup: error writing header")
+	}
+	return aw, nil
+}
+
+// WriteResource adds the metadata of a resource to the archive.
+func (w *Writer) WriteResource(r *Resource) error {
+	rec := &record{Kind: kindResource, Path: r.Path, Metadata: r.Metadata}
+	for _, g := range r.Grants {
+		b, err := utils.MarshalProtoV1ToJSON(g)
+		if err != nil {
+			return errors.Wrapf(err, "mdbackup: error encoding grant of %s", r.Path)
+		}
+		rec.Grants = append(rec.Grants, b)
+	}
+	return errors.Wrap(w.enc.Encode(rec), "mdbackup: error writing resource")
+}
+
+// WriteSpace adds the definition of a storage space to the archive.
+func (w *Writer) WriteSpace(s *provider.StorageSpace) error {
+	b, err := utils.MarshalProtoV1ToJSON(s)
+	if err != nil {
+		return errors.Wrap(err, "mdbackup: error encoding storage space")
+	}
+	return errors.Wrap(w.enc.Encode(&record{Kind: kindSpace, Space: b}), "mdbackup: error writing storage space")
+}
+
+// Close flushes the archive. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	return w.gz.Close()
+}
+
+// Reader reads an archive.
+type Reader struct {
+	gz   *gzip.Reader
+	dec  *json.Decoder
+	root string
+}
+
+// NewReader returns a Reader for the archive read from r.
+func NewReader(r io.Reader) (*Reader, error) {
+	gz, err := gzip.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return nil, errors.Wrap(err, "mdbackup: not an archive")
+	}
+	ar := &Reader{gz: gz, dec: json.NewDecoder(gz)}
+
+	var h record
+	if err := ar.dec.Decode(&h); err != nil {
+		return nil, errors.Wrap(err, "mdbackup: error reading header")
+	}
+	if h.Kind != kindHeader {
+		return nil, errors.New("mdbackup: archive has no header")
+	}
+	if h.Version > Version {
+		return nil, fmt.Errorf("mdbackup: unsupported archive version %d", h.Version)
+	}
+	ar.root = h.Root
+	return ar, nil
+}
+
+// Root returns the path the archive was taken from.
+func (r *Reader) Root() string {
+	return r.root
+}
+
+// Next returns the next entry of the archive, or io.EOF once all entries have been read.
+func (r *Reader) Next() (*Entry, error) {
+	var rec record
+	if err := r.dec.Decode(&rec); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, errors.Wrap(err, "mdbackup: error reading record")
+	}
+
+	switch rec.Kind {
+	case kindResource:
+		res := &Resource{Path: rec.Path, Metadata: rec.Metadata}
+		for _, b := range rec.Grants {
+			g := &provider.Grant{}
+			if err := utils.UnmarshalJSONToProtoV1(b, g); err != nil {
+				return nil, errors.Wrapf(err, "mdbackup: error decoding grant of %s", rec.Path)
+			}
+			res.Grants = append(res.Grants, g)
+		}
+		return &Entry{Resource: res}, nil
+	case kindSpace:
+		s := &provider.StorageSpace{}
+		if err := utils.UnmarshalJSONToProtoV1(rec.Space, s); err != nil {
+			return nil, errors.Wrap(err, "mdbackup: error decoding storage space")
+		}
+		return &Entry{Space: s}, nil
+	default:
+		return nil, fmt.Errorf("mdbackup: unknown record kind %q", rec.Kind)
+	}
+}
+
+// Close releases the resources of the reader. It does not close the underlying reader.
+func (r *Reader) Close() error {
+	return r.gz.Close()
+}
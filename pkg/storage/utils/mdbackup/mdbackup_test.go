@@ -0,0 +1,96 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package mdbackup
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+func TestRoundTrip(t *testing.T) {
+	grant := &provider.Grant{
+		Grantee: &provider.Grantee{
+			Type: provider.GranteeType_GRANTEE_TYPE_USER,
+			Id:   &provider.Grantee_UserId{UserId: &userpb.UserId{Idp: "https://idp", OpaqueId: "marie"}},
+		},
+		Permissions: &provider.ResourcePermissions{Stat: true, InitiateFileDownload: true},
+	}
+	space := &provider.StorageSpace{
+		Id:        &provider.StorageSpaceId{OpaqueId: "1234"},
+		Name:      "project",
+		SpaceType: "project",
+		Quota:     &provider.Quota{QuotaMaxBytes: 1000},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "/home/einstein")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteResource(&Resource{Path: "/home/einstein/docs", Metadata: map[string]string{"color": "red"}, Grants: []*provider.Grant{grant}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteSpace(space); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Root() != "/home/einstein" {
+		t.Fatalf("unexpected root %q", r.Root())
+	}
+
+	e, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Resource == nil || e.Resource.Path != "/home/einstein/docs" || e.Resource.Metadata["color"] != "red" {
+		t.Fatalf("unexpected resource %+v", e.Resource)
+	}
+	if len(e.Resource.Grants) != 1 || e.Resource.Grants[0].Grantee.GetUserId().OpaqueId != "marie" || !e.Resource.Grants[0].Permissions.InitiateFileDownload {
+		t.Fatalf("unexpected grants %+v", e.Resource.Grants)
+	}
+
+	e, err = r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Space == nil || e.Space.Id.OpaqueId != "1234" || e.Space.Quota.QuotaMaxBytes != 1000 {
+		t.Fatalf("unexpected space %+v", e.Space)
+	}
+
+	if _, err := r.Next(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestNotAnArchive(t *testing.T) {
+	if _, err := NewReader(bytes.NewBufferString(`{"kind":"header"}`)); err == nil {
+		t.Fatal("expected an error reading a plain JSON stream")
+	}
+}
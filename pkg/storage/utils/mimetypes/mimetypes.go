@@ -0,0 +1,232 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package mimetypes wraps a storage driver to detect the MIME type of the
+// files from their content and to apply MIME type rules per extension, so
+// that the WebDAV clients, the app providers and the previews all see the
+// same type.
+package mimetypes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/deletejob"
+	"github.com/cs3org/reva/pkg/mime"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/composable"
+	"github.com/pkg/errors"
+)
+
+// MetadataKey is the arbitrary metadata key the detected MIME type is stored under.
+const MetadataKey = "reva.mimetype"
+
+// RuleDetect is the rule detecting the MIME type of the files with an extension from their content.
+const RuleDetect = "detect"
+
+// Config configures the MIME types of the files.
+type Config struct {
+	Detect bool              `mapstructure:"detect" docs:"false;Whether to detect the MIME type of the files with an unknown extension from their content."`
+	Rules  map[string]string `mapstructure:"rules" docs:"nil;MIME type rules per file extension, like .log = \"text/plain\": the MIME type of the files with the extension, or detect to always detect it from their content."`
+}
+
+// Enabled returns whether the MIME types are detected from the content or
+// overridden by rules.
+func (c *Config) Enabled() bool {
+	return c.Detect || len(c.Rules) > 0
+}
+
+type fs struct {
+	storage.FS
+	detect bool
+	rules  map[string]string
+}
+
+// New returns a storage.FS setting the MIME type of the files of the given
+// one according to the rules and their content. The type detected for a file
+// is stored in its metadata when it is uploaded, or when it is first listed
+// for the uploads finished by the driver, like the tus ones.
+func New(next storage.FS, c *Config) (storage.FS, error) {
+	rules := make(map[string]string, len(c.Rules))
+	for ext, r := range c.Rules {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if r != RuleDetect && !strings.Contains(r, "/") {
+			return nil, fmt.Errorf("mimetypes: invalid rule for %s: %s", ext, r)
+		}
+		rules[strings.ToLower(ext)] = r
+	}
+
+	m := &fs{FS: next, detect: c.Detect, rules: rules}
+	return composable.Wrap(m, next), nil
+}
+
+// DeleteRecursive is forwarded to the driver, so that it still deletes trees at once.
+func (m *fs) DeleteRecursive(ctx context.Context, ref *provider.Reference, progress func(total, deleted uint64)) error {
+	return deletejob.DeleteRecursive(ctx, m.FS, ref, progress)
+}
+
+// needsContent returns whether the MIME type of the file has to be detected
+// from its content, or the one to use otherwise.
+func (m *fs) needsContent(fn string) (bool, string) {
+	ext := strings.ToLower(path.Ext(fn))
+	if r, ok := m.rules[ext]; ok {
+		if r == RuleDetect {
+			return true, ""
+		}
+		return false, r
+	}
+	t := mime.Detect(false, fn)
+	return m.detect && mime.IsGeneric(t), t
+}
+
+func (m *fs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	ri, err := m.FS.GetMD(ctx, ref, withMetadataKey(mdKeys))
+	if err != nil {
+		return nil, err
+	}
+	m.setMimeType(ctx, ri, mdKeys)
+	return ri, nil
+}
+
+func (m *fs) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	infos, err := m.FS.ListFolder(ctx, ref, withMetadataKey(mdKeys))
+	if err != nil {
+		return nil, err
+	}
+	for _, ri := range infos {
+		m.setMimeType(ctx, ri, mdKeys)
+	}
+	return infos, nil
+}
+
+func (m *fs) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	if err := m.FS.Upload(ctx, ref, r); err != nil {
+		return err
+	}
+	// the type of the previous content would still be stored otherwise
+	ri, err := m.FS.GetMD(ctx, ref, []string{MetadataKey})
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Interface("ref", ref).Msg("mimetypes: error reading the metadata of the uploaded file")
+		return nil
+	}
+	m.setMimeType(ctx, ri, nil)
+	return nil
+}
+
+// setMimeType sets the MIME type of the file according to the rules, reading
+// the stored type or detecting it from the content when needed. Detection
+// failures are only logged, leaving the type derived from the extension.
+func (m *fs) setMimeType(ctx context.Context, ri *provider.ResourceInfo, mdKeys []string) {
+	if ri == nil || ri.Type != provider.ResourceType_RESOURCE_TYPE_FILE {
+		return
+	}
+	stored, hasStored := ri.GetArbitraryMetadata().GetMetadata()[MetadataKey]
+	if hasStored && !requested(mdKeys, MetadataKey) {
+		delete(ri.ArbitraryMetadata.Metadata, MetadataKey)
+	}
+
+	sniff, t := m.needsContent(ri.Path)
+	if !sniff {
+		ri.MimeType = t
+		return
+	}
+	if t, ok := decodeStored(stored, ri); ok {
+		ri.MimeType = t
+		return
+	}
+
+	t, err := m.detectContent(ctx, ri)
+	if err != nil {
+		appctx.GetLogger(ctx).Warn().Err(err).Str("path", ri.Path).Msg("mimetypes: error detecting the MIME type")
+		return
+	}
+	if !mime.IsGeneric(t) {
+		ri.MimeType = t
+	}
+	err = m.FS.SetArbitraryMetadata(ctx, refOf(ri), &provider.ArbitraryMetadata{
+		Metadata: map[string]string{MetadataKey: encodeStored(t, ri)},
+	})
+	if err != nil {
+		appctx.GetLogger(ctx).Warn().Err(err).Str("path", ri.Path).Msg("mimetypes: error storing the MIME type")
+	}
+}
+
+func (m *fs) detectContent(ctx context.Context, ri *provider.ResourceInfo) (string, error) {
+	r, err := m.FS.Download(ctx, refOf(ri))
+	if err != nil {
+		return "", errors.Wrap(err, "mimetypes: error downloading the file")
+	}
+	defer r.Close()
+
+	head := make([]byte, mime.SniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", errors.Wrap(err, "mimetypes: error reading the file")
+	}
+	return mime.DetectContent(head[:n]), nil
+}
+
+// encodeStored returns the stored value of the detected MIME type, which is
+// only valid as long as the size and the modification time of the file match.
+func encodeStored(t string, ri *provider.ResourceInfo) string {
+	return fmt.Sprintf("%d:%d:%s", ri.Size, ri.GetMtime().GetSeconds(), t)
+}
+
+func decodeStored(v string, ri *provider.ResourceInfo) (string, bool) {
+	parts := strings.SplitN(v, ":", 3)
+	if len(parts) != 3 || parts[0] != strconv.FormatUint(ri.Size, 10) || parts[1] != strconv.FormatUint(ri.GetMtime().GetSeconds(), 10) {
+		return "", false
+	}
+	if mime.IsGeneric(parts[2]) {
+		return mime.Detect(false, ri.Path), true
+	}
+	return parts[2], true
+}
+
+func refOf(ri *provider.ResourceInfo) *provider.Reference {
+	if ri.Path != "" {
+		return &provider.Reference{Path: ri.Path}
+	}
+	return &provider.Reference{ResourceId: ri.Id}
+}
+
+// withMetadataKey adds MetadataKey to the keys requested from the driver,
+// unless they are all returned anyway.
+func withMetadataKey(mdKeys []string) []string {
+	if len(mdKeys) == 0 || requested(mdKeys, "*") || requested(mdKeys, MetadataKey) {
+		return mdKeys
+	}
+	return append(append([]string{}, mdKeys...), MetadataKey)
+}
+
+func requested(mdKeys []string, key string) bool {
+	for _, k := range mdKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
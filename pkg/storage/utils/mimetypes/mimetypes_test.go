@@ -0,0 +1,128 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package mimetypes
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+var png = []byte("\x89PNG\x0D\x0A\x1A\x0A\x00\x00\x00\x0DIHDR")
+
+type testFS struct {
+	storage.FS
+	files     map[string][]byte
+	metadata  map[string]string
+	downloads int
+}
+
+func (t *testFS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	md := map[string]string{}
+	if v, ok := t.metadata[ref.Path]; ok {
+		md[MetadataKey] = v
+	}
+	return &provider.ResourceInfo{
+		Type:              provider.ResourceType_RESOURCE_TYPE_FILE,
+		Path:              ref.Path,
+		Size:              uint64(len(t.files[ref.Path])),
+		Mtime:             &types.Timestamp{Seconds: 1},
+		MimeType:          "application/octet-stream",
+		ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: md},
+	}, nil
+}
+
+func (t *testFS) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	t.downloads++
+	return ioutil.NopCloser(bytes.NewReader(t.files[ref.Path])), nil
+}
+
+func (t *testFS) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	t.metadata[ref.Path] = md.Metadata[MetadataKey]
+	return nil
+}
+
+func TestMimeTypes(t *testing.T) {
+	next := &testFS{
+		files: map[string][]byte{
+			"/image":      png,
+			"/image.txt":  png,
+			"/notes.log":  []byte("hello"),
+			"/report.pdf": png,
+		},
+		metadata: map[string]string{},
+	}
+	fs, err := New(next, &Config{
+		Detect: true,
+		Rules:  map[string]string{"txt": RuleDetect, ".LOG": "text/plain"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path      string
+		mimeType  string
+		downloads int
+	}{
+		{"/image", "image/png", 1},
+		{"/image", "image/png", 0}, // stored
+		{"/image.txt", "image/png", 1},
+		{"/notes.log", "text/plain", 0},
+		{"/report.pdf", "application/pdf", 0},
+	}
+	for _, tt := range tests {
+		next.downloads = 0
+		ri, err := fs.GetMD(context.Background(), &provider.Reference{Path: tt.path}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ri.MimeType != tt.mimeType {
+			t.Errorf("%s: expected %s, got %s", tt.path, tt.mimeType, ri.MimeType)
+		}
+		if next.downloads != tt.downloads {
+			t.Errorf("%s: expected %d downloads, got %d", tt.path, tt.downloads, next.downloads)
+		}
+		if _, ok := ri.ArbitraryMetadata.Metadata[MetadataKey]; ok {
+			t.Errorf("%s: expected the stored MIME type to be hidden", tt.path)
+		}
+	}
+
+	// the stored type is outdated when the content changes
+	next.files["/image"] = []byte("%PDF-1.4")
+	ri, err := fs.GetMD(context.Background(), &provider.Reference{Path: "/image"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ri.MimeType != "application/pdf" {
+		t.Errorf("expected the MIME type of the new content, got %s", ri.MimeType)
+	}
+}
+
+func TestInvalidRule(t *testing.T) {
+	if _, err := New(&testFS{}, &Config{Rules: map[string]string{".txt": "text"}}); err == nil {
+		t.Fatal("expected an error for an invalid rule")
+	}
+}
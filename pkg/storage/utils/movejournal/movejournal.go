@@ -0,0 +1,367 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package movejournal makes the moves of folder trees recoverable on object
+// stores, where a move is a copy and a delete of every object of the tree.
+// Each move is recorded in a journal, along with the objects moved so far, so
+// that a move interrupted by a crash or a network failure can be resumed or
+// rolled back instead of leaving the tree split between both locations.
+package movejournal
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// The actions taken on the interrupted moves of an accessed tree.
+const (
+	ActionResume   = "resume"
+	ActionRollback = "rollback"
+	ActionNone     = "none"
+)
+
+// Entry is the journal entry of a move. The keys are relative to the source
+// and target prefixes.
+type Entry struct {
+	ID      string    `json:"id"`
+	Source  string    `json:"source"`
+	Target  string    `json:"target"`
+	Started time.Time `json:"started"`
+	// Updated is refreshed after every batch; a move whose entry wasn't
+	// updated for a while has been interrupted.
+	Updated time.Time `json:"updated"`
+	Moved   []string  `json:"moved,omitempty"`
+	// Pending holds the keys of the batch being moved.
+	Pending []string `json:"pending,omitempty"`
+}
+
+// Store persists the journal entries. It must be shared by all the instances
+// accessing the storage, typically by keeping the entries in the bucket.
+type Store interface {
+	List(ctx context.Context) ([]*Entry, error)
+	Save(ctx context.Context, e *Entry) error
+	Delete(ctx context.Context, id string) error
+}
+
+// Backend gives access to the objects of the storage.
+type Backend interface {
+	// List calls fn with the keys of the objects below prefix, in batches.
+	List(ctx context.Context, prefix string, fn func(keys []string) error) error
+	Exists(ctx context.Context, key string) (bool, error)
+	Copy(ctx context.Context, src, dst string) error
+	Delete(ctx context.Context, key string) error
+}
+
+// Repairer is implemented by the drivers journaling their moves, to let the
+// operators list and repair the interrupted moves.
+type Repairer interface {
+	ListInterruptedMoves(ctx context.Context) ([]*Entry, error)
+	RepairMove(ctx context.Context, id string, rollback bool) error
+}
+
+// Config configures the journal.
+type Config struct {
+	OnAccess        string `mapstructure:"on_access" docs:"resume;What to do with the interrupted moves of a tree when it is accessed: resume, rollback or none."`
+	StaleTimeout    int    `mapstructure:"stale_timeout" docs:"300;Seconds after which a move whose entry hasn't been updated is considered interrupted."`
+	RefreshInterval int    `mapstructure:"refresh_interval" docs:"30;Seconds during which the list of the journal entries is cached."`
+}
+
+func (c *Config) init() {
+	if c.OnAccess == "" {
+		c.OnAccess = ActionResume
+	}
+	if c.StaleTimeout <= 0 {
+		c.StaleTimeout = 300
+	}
+	if c.RefreshInterval <= 0 {
+		c.RefreshInterval = 30
+	}
+}
+
+// Journal runs the moves and recovers the interrupted ones.
+type Journal struct {
+	c       *Config
+	store   Store
+	backend Backend
+
+	mu      sync.Mutex
+	entries []*Entry
+	loaded  time.Time
+	// the moves run by this instance, which are never recovered
+	running map[string]bool
+}
+
+// New returns a journal keeping its entries in store.
+func New(c *Config, store Store, backend Backend) (*Journal, error) {
+	c.init()
+	switch c.OnAccess {
+	case ActionResume, ActionRollback, ActionNone:
+	default:
+		return nil, errors.Errorf("movejournal: unknown action %q", c.OnAccess)
+	}
+	return &Journal{c: c, store: store, backend: backend, running: map[string]bool{}}, nil
+}
+
+// Move moves the tree at source to target, both given without a trailing
+// slash. If the move fails, its entry is kept so that it can be recovered.
+func (j *Journal) Move(ctx context.Context, source, target string) error {
+	now := time.Now()
+	e := &Entry{ID: uuid.New().String(), Source: source, Target: target, Started: now, Updated: now}
+	if err := j.store.Save(ctx, e); err != nil {
+		return errors.Wrap(err, "movejournal: error saving the journal entry")
+	}
+	j.claim(e)
+	defer j.release(e.ID)
+
+	return j.resume(ctx, e)
+}
+
+// Check recovers the interrupted moves involving the tree at key, which is
+// either one of the trees moved or one of their ancestors.
+func (j *Journal) Check(ctx context.Context, key string) error {
+	if j.c.OnAccess == ActionNone {
+		return nil
+	}
+	entries, err := j.cached(ctx)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !involves(e, key) || !j.interrupted(e) {
+			continue
+		}
+		appctx.GetLogger(ctx).Info().Str("id", e.ID).Str("source", e.Source).Str("target", e.Target).
+			Str("action", j.c.OnAccess).Msg("movejournal: recovering interrupted move")
+		if err := j.recover(ctx, e, j.c.OnAccess == ActionRollback); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Interrupted returns the interrupted moves.
+func (j *Journal) Interrupted(ctx context.Context) ([]*Entry, error) {
+	entries, err := j.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var interrupted []*Entry
+	for _, e := range entries {
+		if j.interrupted(e) {
+			interrupted = append(interrupted, e)
+		}
+	}
+	return interrupted, nil
+}
+
+// Repair resumes or rolls back an interrupted move.
+func (j *Journal) Repair(ctx context.Context, id string, rollback bool) error {
+	entries, err := j.refresh(ctx)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.ID != id {
+			continue
+		}
+		if !j.interrupted(e) {
+			return errtypes.BadRequest("movejournal: move " + id + " is still running")
+		}
+		return j.recover(ctx, e, rollback)
+	}
+	return errtypes.NotFound("movejournal: move " + id)
+}
+
+func (j *Journal) recover(ctx context.Context, e *Entry, rollback bool) error {
+	if !j.claim(e) {
+		// recovered concurrently by another request
+		return nil
+	}
+	defer j.release(e.ID)
+
+	// let the other instances know that the move is being taken care of
+	e.Updated = time.Now()
+	if err := j.store.Save(ctx, e); err != nil {
+		return errors.Wrap(err, "movejournal: error saving the journal entry")
+	}
+	if rollback {
+		return j.rollback(ctx, e)
+	}
+	return j.resume(ctx, e)
+}
+
+func (j *Journal) resume(ctx context.Context, e *Entry) error {
+	// the batch being moved when the move was interrupted comes first
+	if len(e.Pending) > 0 {
+		if err := j.moveBatch(ctx, e, e.Pending); err != nil {
+			return err
+		}
+	}
+	prefix := e.Source + "/"
+	err := j.backend.List(ctx, prefix, func(keys []string) error {
+		rel := make([]string, 0, len(keys))
+		for _, k := range keys {
+			rel = append(rel, strings.TrimPrefix(k, prefix))
+		}
+		return j.moveBatch(ctx, e, rel)
+	})
+	if err != nil {
+		return errors.Wrap(err, "movejournal: error moving "+e.Source)
+	}
+	return j.remove(ctx, e.ID)
+}
+
+func (j *Journal) moveBatch(ctx context.Context, e *Entry, keys []string) error {
+	e.Pending = keys
+	e.Updated = time.Now()
+	if err := j.store.Save(ctx, e); err != nil {
+		return errors.Wrap(err, "movejournal: error saving the journal entry")
+	}
+	for _, k := range keys {
+		src, dst := e.Source+"/"+k, e.Target+"/"+k
+		exists, err := j.backend.Exists(ctx, src)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			// moved before the interruption
+			continue
+		}
+		if err := j.backend.Copy(ctx, src, dst); err != nil {
+			return err
+		}
+		if err := j.backend.Delete(ctx, src); err != nil {
+			return err
+		}
+	}
+	e.Moved = append(e.Moved, keys...)
+	e.Pending = nil
+	e.Updated = time.Now()
+	if err := j.store.Save(ctx, e); err != nil {
+		return errors.Wrap(err, "movejournal: error saving the journal entry")
+	}
+	return nil
+}
+
+func (j *Journal) rollback(ctx context.Context, e *Entry) error {
+	for _, keys := range [][]string{e.Pending, e.Moved} {
+		for _, k := range keys {
+			src, dst := e.Source+"/"+k, e.Target+"/"+k
+			exists, err := j.backend.Exists(ctx, dst)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				continue
+			}
+			// the source is still there if the move was interrupted between the copy and the delete
+			if exists, err = j.backend.Exists(ctx, src); err != nil {
+				return err
+			}
+			if !exists {
+				if err := j.backend.Copy(ctx, dst, src); err != nil {
+					return err
+				}
+			}
+			if err := j.backend.Delete(ctx, dst); err != nil {
+				return err
+			}
+		}
+	}
+	return j.remove(ctx, e.ID)
+}
+
+func (j *Journal) remove(ctx context.Context, id string) error {
+	if err := j.store.Delete(ctx, id); err != nil {
+		return errors.Wrap(err, "movejournal: error deleting the journal entry")
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i, e := range j.entries {
+		if e.ID == id {
+			j.entries = append(j.entries[:i:i], j.entries[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// claim marks a move as run by this instance, it returns false if it already is.
+func (j *Journal) claim(e *Entry) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.running[e.ID] {
+		return false
+	}
+	j.running[e.ID] = true
+	return true
+}
+
+func (j *Journal) release(id string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.running, id)
+}
+
+func (j *Journal) interrupted(e *Entry) bool {
+	j.mu.Lock()
+	running := j.running[e.ID]
+	j.mu.Unlock()
+	return !running && time.Since(e.Updated) > time.Duration(j.c.StaleTimeout)*time.Second
+}
+
+func (j *Journal) cached(ctx context.Context) ([]*Entry, error) {
+	j.mu.Lock()
+	if time.Since(j.loaded) < time.Duration(j.c.RefreshInterval)*time.Second {
+		entries := j.entries
+		j.mu.Unlock()
+		return entries, nil
+	}
+	j.mu.Unlock()
+	return j.refresh(ctx)
+}
+
+func (j *Journal) refresh(ctx context.Context) ([]*Entry, error) {
+	entries, err := j.store.List(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "movejournal: error listing the journal entries")
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries, j.loaded = entries, time.Now()
+	return entries, nil
+}
+
+// involves returns whether key is one of the trees of a move or one of their
+// ancestors, whose listing would show the tree split between both locations.
+func involves(e *Entry, key string) bool {
+	key = strings.TrimSuffix(key, "/")
+	for _, p := range []string{e.Source, e.Target} {
+		if key == p || strings.HasPrefix(p, key+"/") || strings.HasPrefix(key, p+"/") || key == "" {
+			return true
+		}
+	}
+	return false
+}
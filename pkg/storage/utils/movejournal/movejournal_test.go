@@ -0,0 +1,246 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package movejournal
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+type memStore struct {
+	entries map[string]Entry
+}
+
+func (s *memStore) List(ctx context.Context) ([]*Entry, error) {
+	var entries []*Entry
+	for _, e := range s.entries {
+		e := e
+		entries = append(entries, &e)
+	}
+	return entries, nil
+}
+
+func (s *memStore) Save(ctx context.Context, e *Entry) error {
+	s.entries[e.ID] = *e
+	return nil
+}
+
+func (s *memStore) Delete(ctx context.Context, id string) error {
+	delete(s.entries, id)
+	return nil
+}
+
+type memBackend struct {
+	objects map[string]string
+	// the number of copies after which the copies fail, -1 for never
+	failAfter int
+}
+
+func (b *memBackend) List(ctx context.Context, prefix string, fn func(keys []string) error) error {
+	var keys []string
+	for k := range b.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for len(keys) > 0 {
+		n := 2
+		if n > len(keys) {
+			n = len(keys)
+		}
+		if err := fn(keys[:n]); err != nil {
+			return err
+		}
+		keys = keys[n:]
+	}
+	return nil
+}
+
+func (b *memBackend) Exists(ctx context.Context, key string) (bool, error) {
+	_, ok := b.objects[key]
+	return ok, nil
+}
+
+func (b *memBackend) Copy(ctx context.Context, src, dst string) error {
+	if b.failAfter == 0 {
+		return errors.New("connection lost")
+	}
+	b.failAfter--
+	b.objects[dst] = b.objects[src]
+	return nil
+}
+
+func (b *memBackend) Delete(ctx context.Context, key string) error {
+	delete(b.objects, key)
+	return nil
+}
+
+func (b *memBackend) keys() []string {
+	var keys []string
+	for k := range b.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func newTree() *memBackend {
+	return &memBackend{
+		objects: map[string]string{
+			"root/a/1":   "1",
+			"root/a/2":   "2",
+			"root/a/b/3": "3",
+			"root/a/b/4": "4",
+			"root/a/c/5": "5",
+			"root/other": "x",
+		},
+		failAfter: -1,
+	}
+}
+
+func newJournal(t *testing.T, c *Config, backend Backend) (*Journal, *memStore) {
+	store := &memStore{entries: map[string]Entry{}}
+	j, err := New(c, store, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j, store
+}
+
+// interrupt runs a move failing after the given number of copies, and ages its entry.
+func interrupt(t *testing.T, j *Journal, store *memStore, backend *memBackend, copies int) {
+	backend.failAfter = copies
+	if err := j.Move(context.Background(), "root/a", "root/z"); err == nil {
+		t.Fatal("expected the move to fail")
+	}
+	backend.failAfter = -1
+	for id, e := range store.entries {
+		e.Updated = e.Updated.Add(-time.Hour)
+		store.entries[id] = e
+	}
+	j.loaded = time.Time{}
+}
+
+func TestMove(t *testing.T) {
+	backend := newTree()
+	j, store := newJournal(t, &Config{}, backend)
+
+	if err := j.Move(context.Background(), "root/a", "root/z"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"root/other", "root/z/1", "root/z/2", "root/z/b/3", "root/z/b/4", "root/z/c/5"}
+	if keys := backend.keys(); !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("got %v, expected %v", keys, expected)
+	}
+	if len(store.entries) != 0 {
+		t.Fatalf("expected the journal entry to be removed, got %v", store.entries)
+	}
+}
+
+func TestCheckResumes(t *testing.T) {
+	backend := newTree()
+	j, store := newJournal(t, &Config{}, backend)
+	interrupt(t, j, store, backend, 3)
+
+	// unrelated trees are left alone
+	if err := j.Check(context.Background(), "root/other"); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.entries) != 1 {
+		t.Fatal("expected the move not to be recovered")
+	}
+
+	if err := j.Check(context.Background(), "root/z/b"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"root/other", "root/z/1", "root/z/2", "root/z/b/3", "root/z/b/4", "root/z/c/5"}
+	if keys := backend.keys(); !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("got %v, expected %v", keys, expected)
+	}
+	if len(store.entries) != 0 {
+		t.Fatalf("expected the journal entry to be removed, got %v", store.entries)
+	}
+}
+
+func TestCheckRollsBack(t *testing.T) {
+	backend := newTree()
+	j, store := newJournal(t, &Config{OnAccess: ActionRollback}, backend)
+	interrupt(t, j, store, backend, 3)
+
+	if err := j.Check(context.Background(), "root"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"root/a/1", "root/a/2", "root/a/b/3", "root/a/b/4", "root/a/c/5", "root/other"}
+	if keys := backend.keys(); !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("got %v, expected %v", keys, expected)
+	}
+	if len(store.entries) != 0 {
+		t.Fatalf("expected the journal entry to be removed, got %v", store.entries)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	backend := newTree()
+	j, store := newJournal(t, &Config{OnAccess: ActionNone}, backend)
+	interrupt(t, j, store, backend, 1)
+
+	if err := j.Check(context.Background(), "root/a"); err != nil || len(store.entries) != 1 {
+		t.Fatal("expected the move not to be recovered on access")
+	}
+
+	entries, err := j.Interrupted(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Source != "root/a" || entries[0].Target != "root/z" {
+		t.Fatalf("unexpected interrupted moves %v", entries)
+	}
+	if err := j.Repair(context.Background(), "unknown", false); err == nil {
+		t.Fatal("expected an error repairing an unknown move")
+	}
+	if err := j.Repair(context.Background(), entries[0].ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.entries) != 0 || len(backend.keys()) != 6 {
+		t.Fatalf("expected the move to be completed, got %v", backend.keys())
+	}
+}
+
+func TestRunningMovesAreNotRecovered(t *testing.T) {
+	backend := newTree()
+	j, store := newJournal(t, &Config{}, backend)
+	e := &Entry{ID: "running", Source: "root/a", Target: "root/z", Updated: time.Now()}
+	_ = store.Save(context.Background(), e)
+
+	if err := j.Check(context.Background(), "root/a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Repair(context.Background(), "running", true); err == nil {
+		t.Fatal("expected an error repairing a running move")
+	}
+	if len(store.entries) != 1 || len(backend.keys()) != 6 {
+		t.Fatal("expected the running move to be left alone")
+	}
+}
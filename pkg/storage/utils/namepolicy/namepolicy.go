@@ -0,0 +1,191 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package namepolicy enforces configurable restrictions on the names of
+// files and folders, so that resources which the backing storage or the
+// clients cannot handle are rejected before they are created.
+package namepolicy
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// Violation codes reported to clients, so they can show an actionable message.
+const (
+	CodeForbiddenCharacter = "forbidden_character"
+	CodeNameTooLong        = "name_too_long"
+	CodePathTooLong        = "path_too_long"
+	CodeReservedName       = "reserved_name"
+)
+
+// windowsForbiddenCharacters cannot be used in file names on Windows.
+const windowsForbiddenCharacters = `<>:"/\|?*`
+
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// Rule restricts the names of the resources below a path prefix.
+// Lengths are measured in bytes, as that is what file systems limit.
+type Rule struct {
+	Prefix              string   `mapstructure:"prefix" docs:";Only paths below this prefix are checked, e.g. the mount point of a storage. Empty for all paths."`
+	ForbiddenCharacters string   `mapstructure:"forbidden_characters" docs:";Characters that must not appear in a name."`
+	MaxNameLength       int      `mapstructure:"max_name_length" docs:"0;Maximum length of a single path component; 0 for no limit."`
+	MaxPathLength       int      `mapstructure:"max_path_length" docs:"0;Maximum length of the full path; 0 for no limit."`
+	WindowsCompatible   bool     `mapstructure:"windows_compatible" docs:"false;Rejects names Windows clients cannot sync: reserved device names, trailing dots or spaces and the characters <>:\"|?*."`
+	ReservedNames       []string `mapstructure:"reserved_names" docs:";Additional names which are not allowed, compared case-insensitively."`
+}
+
+// Config holds the filename policy: a rule applied to all paths and any
+// number of additional rules for the paths served by specific storages.
+type Config struct {
+	Rule     `mapstructure:",squash"`
+	Storages []Rule `mapstructure:"storages"`
+}
+
+// Violation describes why a name was rejected.
+type Violation struct {
+	Code    string
+	Message string
+}
+
+func (v *Violation) Error() string {
+	return v.Code + ": " + v.Message
+}
+
+// Policy checks names against the configured rules.
+type Policy struct {
+	rules []Rule
+}
+
+// New returns a policy for the given configuration.
+func New(c *Config) *Policy {
+	p := &Policy{}
+	if c == nil {
+		return p
+	}
+	p.rules = append(p.rules, c.Rule)
+	p.rules = append(p.rules, c.Storages...)
+	return p
+}
+
+// PathDependent returns whether a rule depends on the full path, i.e. applies
+// below a prefix or limits the length of the path. Relative paths have to be
+// resolved before they are checked against such rules.
+func (p *Policy) PathDependent() bool {
+	if p == nil {
+		return false
+	}
+	for i := range p.rules {
+		if p.rules[i].Prefix != "" || p.rules[i].MaxPathLength > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Check validates the last component of fn, and the length of fn itself,
+// against all rules whose prefix matches. Relative paths are only checked
+// against the rules without a prefix and not against the path length, see
+// PathDependent. A nil error means fn is allowed.
+func (p *Policy) Check(fn string) error {
+	if p == nil {
+		return nil
+	}
+	name := path.Base(fn)
+	for i := range p.rules {
+		r := &p.rules[i]
+		if !r.matches(fn) {
+			continue
+		}
+		if v := r.check(fn, name); v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+func (r *Rule) matches(fn string) bool {
+	if r.Prefix == "" {
+		return true
+	}
+	if !path.IsAbs(fn) {
+		return false
+	}
+	prefix := path.Clean(r.Prefix)
+	return fn == prefix || strings.HasPrefix(fn, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+func (r *Rule) check(fn, name string) *Violation {
+	if r.MaxPathLength > 0 && path.IsAbs(fn) && len(fn) > r.MaxPathLength {
+		return &Violation{
+			Code:    CodePathTooLong,
+			Message: fmt.Sprintf("the path must not be longer than %d bytes", r.MaxPathLength),
+		}
+	}
+	if r.MaxNameLength > 0 && len(name) > r.MaxNameLength {
+		return &Violation{
+			Code:    CodeNameTooLong,
+			Message: fmt.Sprintf("the name must not be longer than %d bytes", r.MaxNameLength),
+		}
+	}
+
+	forbidden := r.ForbiddenCharacters
+	if r.WindowsCompatible {
+		forbidden += windowsForbiddenCharacters
+	}
+	for _, c := range name {
+		if strings.ContainsRune(forbidden, c) || (r.WindowsCompatible && unicode.IsControl(c)) {
+			return &Violation{
+				Code:    CodeForbiddenCharacter,
+				Message: fmt.Sprintf("the name must not contain %q", c),
+			}
+		}
+	}
+
+	for _, reserved := range r.ReservedNames {
+		if strings.EqualFold(name, reserved) {
+			return &Violation{
+				Code:    CodeReservedName,
+				Message: fmt.Sprintf("%q is a reserved name", name),
+			}
+		}
+	}
+	if r.WindowsCompatible {
+		if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+			return &Violation{
+				Code:    CodeReservedName,
+				Message: "the name must not end with a dot or a space",
+			}
+		}
+		// device names are reserved regardless of the extension, e.g. "nul.txt"
+		base := strings.ToUpper(strings.TrimRight(strings.SplitN(name, ".", 2)[0], " "))
+		if windowsReservedNames[base] {
+			return &Violation{
+				Code:    CodeReservedName,
+				Message: fmt.Sprintf("%q is reserved on Windows", name),
+			}
+		}
+	}
+	return nil
+}
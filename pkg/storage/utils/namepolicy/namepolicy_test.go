@@ -0,0 +1,84 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package namepolicy
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	p := New(&Config{
+		Rule: Rule{
+			MaxNameLength:     10,
+			WindowsCompatible: true,
+		},
+		Storages: []Rule{
+			{Prefix: "/eos", ForbiddenCharacters: "#", MaxPathLength: 16},
+		},
+	})
+
+	tests := map[string]string{
+		"/home/file.txt":      "",
+		"/home/averylongname": CodeNameTooLong,
+		"/home/a:b":           CodeForbiddenCharacter,
+		"/home/a\tb":          CodeForbiddenCharacter,
+		"/home/con":           CodeReservedName,
+		"/home/NUL.txt":       CodeReservedName,
+		"/home/name.":         CodeReservedName,
+		"/home/console":       "",
+		"/home/a#b":           "",
+		"/eos/a#b":            CodeForbiddenCharacter,
+		"/eos/abc/def/ghi":    "",
+		"/eos/abc/def/ghij":   CodePathTooLong,
+		"/eosx/abc/def/ghij":  "",
+		"./a#b":               "",
+		"./aux":               CodeReservedName,
+	}
+
+	for fn, code := range tests {
+		err := p.Check(fn)
+		if code == "" {
+			if err != nil {
+				t.Errorf("%s: expected no violation, got %v", fn, err)
+			}
+			continue
+		}
+		var v *Violation
+		if !errors.As(err, &v) || v.Code != code {
+			t.Errorf("%s: expected %s, got %v", fn, code, err)
+		}
+	}
+}
+
+func TestPathDependent(t *testing.T) {
+	var nilPolicy *Policy
+	if nilPolicy.PathDependent() {
+		t.Error("expected a nil policy not to depend on the path")
+	}
+	if New(&Config{Rule: Rule{MaxNameLength: 10, WindowsCompatible: true}}).PathDependent() {
+		t.Error("expected the name rules not to depend on the path")
+	}
+	if !New(&Config{Rule: Rule{MaxPathLength: 10}}).PathDependent() {
+		t.Error("expected the path length to depend on the path")
+	}
+	if !New(&Config{Storages: []Rule{{Prefix: "/eos", ForbiddenCharacters: "#"}}}).PathDependent() {
+		t.Error("expected the rules of a prefix to depend on the path")
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package normalize wraps a storage driver to store file names in Unicode
+// normalization form C and to detect names which only differ in case from
+// existing ones, as clients on case-insensitive file systems cannot sync them.
+// Being a wrapper, it behaves the same regardless of the backing driver.
+package normalize
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/deletejob"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Ways of handling case-insensitive collisions.
+const (
+	// CollisionsAllow creates the resource as requested.
+	CollisionsAllow = ""
+	// CollisionsReject fails the request with an already exists error.
+	CollisionsReject = "reject"
+	// CollisionsRename creates the resource under a new name, e.g. "Report (2).txt".
+	CollisionsRename = "rename"
+)
+
+// maxRenameAttempts bounds the search for a free name when auto-renaming.
+const maxRenameAttempts = 100
+
+// Config configures the wrapper.
+type Config struct {
+	NFC        bool   `mapstructure:"nfc" docs:"false;Whether to normalize the names of new resources to NFC."`
+	Collisions string `mapstructure:"case_collisions" docs:";How to handle names only differing in case from an existing one on create and rename: empty to allow them, reject or rename."`
+}
+
+// Enabled returns whether the names are normalized or checked for collisions
+// differing only in case.
+func (c *Config) Enabled() bool {
+	return c.NFC || c.Collisions != CollisionsAllow
+}
+
+type fs struct {
+	storage.FS
+	c *Config
+}
+
+// New returns a storage.FS normalizing the names passed to the given one.
+func New(next storage.FS, c *Config) (storage.FS, error) {
+	switch c.Collisions {
+	case CollisionsAllow, CollisionsReject, CollisionsRename:
+	default:
+		return nil, fmt.Errorf("normalize: unknown case collision handling %q", c.Collisions)
+	}
+	return &fs{FS: next, c: c}, nil
+}
+
+// normalizeRef returns a copy of the reference with its path normalized to NFC.
+func (n *fs) normalizeRef(ref *provider.Reference) *provider.Reference {
+	if !n.c.NFC || ref == nil || ref.Path == "" || norm.NFC.IsNormalString(ref.Path) {
+		return ref
+	}
+	return &provider.Reference{
+		ResourceId: ref.ResourceId,
+		Path:       norm.NFC.String(ref.Path),
+	}
+}
+
+// lookup calls fn with the reference as given and, if nothing is found there,
+// with the normalized reference, so resources can be found regardless of the
+// normalization form used by the client.
+func (n *fs) lookup(ref *provider.Reference, fn func(*provider.Reference) error) error {
+	err := fn(ref)
+	if _, ok := err.(errtypes.IsNotFound); ok {
+		if nref := n.normalizeRef(ref); nref != ref {
+			return fn(nref)
+		}
+	}
+	return err
+}
+
+// resolve returns the reference a new resource should be created at, handling
+// collisions with existing names. The skip id allows renaming a resource to a
+// name only differing in case.
+func (n *fs) resolve(ctx context.Context, ref *provider.Reference, skip *provider.ResourceId) (*provider.Reference, error) {
+	ref = n.normalizeRef(ref)
+	if n.c.Collisions == CollisionsAllow || ref == nil || ref.Path == "" {
+		return ref, nil
+	}
+
+	dir, name := path.Split(ref.Path)
+	parent := &provider.Reference{ResourceId: ref.ResourceId, Path: path.Clean(dir)}
+	if ref.ResourceId != nil && !strings.HasPrefix(parent.Path, ".") {
+		parent.Path = "./" + parent.Path
+	}
+	infos, err := n.FS.ListFolder(ctx, parent, []string{})
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			return ref, nil
+		}
+		return nil, errors.Wrap(err, "normalize: error listing parent folder")
+	}
+
+	taken := make(map[string]bool, len(infos))
+	var collision bool
+	for _, info := range infos {
+		existing := norm.NFC.String(path.Base(info.Path))
+		if existing == name || (skip != nil && utils.ResourceIDEqual(info.Id, skip)) {
+			// exact matches are handled by the driver itself
+			continue
+		}
+		taken[strings.ToLower(existing)] = true
+		if strings.EqualFold(existing, name) {
+			collision = true
+		}
+	}
+	if !collision {
+		return ref, nil
+	}
+
+	if n.c.Collisions == CollisionsReject {
+		return nil, errtypes.AlreadyExists(fmt.Sprintf("%s differs only in case from an existing name", name))
+	}
+
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 2; i < maxRenameAttempts; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if !taken[strings.ToLower(candidate)] {
+			return &provider.Reference{ResourceId: ref.ResourceId, Path: dir + candidate}, nil
+		}
+	}
+	return nil, errtypes.AlreadyExists(fmt.Sprintf("no free name found for %s", name))
+}
+
+func (n *fs) CreateDir(ctx context.Context, ref *provider.Reference) error {
+	ref, err := n.resolve(ctx, ref, nil)
+	if err != nil {
+		return err
+	}
+	return n.FS.CreateDir(ctx, ref)
+}
+
+func (n *fs) TouchFile(ctx context.Context, ref *provider.Reference) error {
+	ref, err := n.resolve(ctx, ref, nil)
+	if err != nil {
+		return err
+	}
+	return n.FS.TouchFile(ctx, ref)
+}
+
+func (n *fs) InitiateUpload(ctx context.Context, ref *provider.Reference, uploadLength int64, metadata map[string]string) (map[string]string, error) {
+	ref, err := n.resolve(ctx, ref, nil)
+	if err != nil {
+		return nil, err
+	}
+	return n.FS.InitiateUpload(ctx, ref, uploadLength, metadata)
+}
+
+func (n *fs) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	ref, err := n.resolve(ctx, ref, nil)
+	if err != nil {
+		return err
+	}
+	return n.FS.Upload(ctx, ref, r)
+}
+
+func (n *fs) Move(ctx context.Context, oldRef, newRef *provider.Reference) error {
+	var skip *provider.ResourceId
+	if n.c.Collisions != CollisionsAllow {
+		err := n.lookup(oldRef, func(ref *provider.Reference) error {
+			info, err := n.FS.GetMD(ctx, ref, []string{})
+			if err == nil {
+				oldRef, skip = ref, info.Id
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	newRef, err := n.resolve(ctx, newRef, skip)
+	if err != nil {
+		return err
+	}
+	return n.FS.Move(ctx, oldRef, newRef)
+}
+
+func (n *fs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (ri *provider.ResourceInfo, err error) {
+	err = n.lookup(ref, func(ref *provider.Reference) error {
+		ri, err = n.FS.GetMD(ctx, ref, mdKeys)
+		return err
+	})
+	return ri, err
+}
+
+func (n *fs) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) (infos []*provider.ResourceInfo, err error) {
+	err = n.lookup(ref, func(ref *provider.Reference) error {
+		infos, err = n.FS.ListFolder(ctx, ref, mdKeys)
+		return err
+	})
+	return infos, err
+}
+
+func (n *fs) Download(ctx context.Context, ref *provider.Reference) (rc io.ReadCloser, err error) {
+	err = n.lookup(ref, func(ref *provider.Reference) error {
+		rc, err = n.FS.Download(ctx, ref)
+		return err
+	})
+	return rc, err
+}
+
+func (n *fs) Delete(ctx context.Context, ref *provider.Reference) error {
+	return n.lookup(ref, func(ref *provider.Reference) error {
+		return n.FS.Delete(ctx, ref)
+	})
+}
+
+// DeleteRecursive lets the driver delete a tree at once, finding it like Delete.
+func (n *fs) DeleteRecursive(ctx context.Context, ref *provider.Reference, progress func(total, deleted uint64)) error {
+	return n.lookup(ref, func(ref *provider.Reference) error {
+		return deletejob.DeleteRecursive(ctx, n.FS, ref, progress)
+	})
+}
@@ -0,0 +1,100 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package normalize
+
+import (
+	"context"
+	"path"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+// memFS keeps the names of a single folder in memory.
+type memFS struct {
+	storage.FS
+	names map[string]bool
+}
+
+func (m *memFS) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	infos := []*provider.ResourceInfo{}
+	for name := range m.names {
+		infos = append(infos, &provider.ResourceInfo{
+			Id:   &provider.ResourceId{OpaqueId: name},
+			Path: path.Join(ref.Path, name),
+		})
+	}
+	return infos, nil
+}
+
+func (m *memFS) CreateDir(ctx context.Context, ref *provider.Reference) error {
+	name := path.Base(ref.Path)
+	if m.names[name] {
+		return errtypes.AlreadyExists(name)
+	}
+	m.names[name] = true
+	return nil
+}
+
+func TestCreateDir(t *testing.T) {
+	tests := []struct {
+		conf     Config
+		create   string
+		expected string
+		err      bool
+	}{
+		{Config{NFC: true}, "/Cafe\u0301", "Caf\u00e9", false},
+		{Config{Collisions: CollisionsReject}, "/readme", "", true},
+		{Config{Collisions: CollisionsReject}, "/other", "other", false},
+		{Config{Collisions: CollisionsRename}, "/README", "README (2)", false},
+		{Config{Collisions: CollisionsRename}, "/Docs.tar", "Docs (3).tar", false},
+		{Config{NFC: true, Collisions: CollisionsReject}, "/CAFE\u0301", "", true},
+	}
+
+	for _, tt := range tests {
+		m := &memFS{names: map[string]bool{"Readme": true, "docs.tar": true, "docs (2).tar": true, "caf\u00e9": true}}
+		fs, err := New(m, &tt.conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = fs.CreateDir(context.Background(), &provider.Reference{Path: tt.create})
+		if tt.err {
+			if _, ok := err.(errtypes.IsAlreadyExists); !ok {
+				t.Errorf("%s: expected already exists error, got %v", tt.create, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.create, err)
+			continue
+		}
+		if !m.names[tt.expected] {
+			t.Errorf("%s: expected %q to be created, got %v", tt.create, tt.expected, m.names)
+		}
+	}
+}
+
+func TestNewRejectsUnknownCollisionHandling(t *testing.T) {
+	if _, err := New(&memFS{}, &Config{Collisions: "ignore"}); err == nil {
+		t.Error("expected an error for an unknown collision handling")
+	}
+}
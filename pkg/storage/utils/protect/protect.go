@@ -0,0 +1,226 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package protect wraps a storage driver to let the managers of a space
+// protect folders against deletion. Protected resources can neither be
+// deleted nor moved, unless the user has been granted the override permission.
+package protect
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	permissions "github.com/cs3org/go-cs3apis/cs3/permissions/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/deletejob"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/composable"
+	"github.com/pkg/errors"
+)
+
+// MetadataKey is the arbitrary metadata flagging a resource as protected. It
+// matches the oc:protected WebDAV property, so that it can be set through PROPPATCH.
+const MetadataKey = "http://owncloud.org/ns/protected"
+
+// Config configures the protection of folders.
+type Config struct {
+	Folders            bool   `mapstructure:"folders" docs:"false;Whether the managers of a space can protect its folders against deletion and moves."`
+	OverridePermission string `mapstructure:"override_permission" docs:"delete-protected-resources;The permission allowing users to delete and move protected resources nevertheless."`
+	GatewaySvc         string `mapstructure:"gatewaysvc" docs:";The gateway the override permission is checked through."`
+}
+
+// Enabled returns whether the folders flagged by the space managers are
+// protected.
+func (c *Config) Enabled() bool {
+	return c.Folders
+}
+
+// IsProtected returns whether the given resource has been protected.
+func IsProtected(ri *provider.ResourceInfo) bool {
+	v, ok := ri.GetArbitraryMetadata().GetMetadata()[MetadataKey]
+	if !ok {
+		return false
+	}
+	protected, err := strconv.ParseBool(v)
+	return err == nil && protected
+}
+
+type fs struct {
+	storage.FS
+	canOverride func(context.Context) (bool, error)
+}
+
+// New returns a storage.FS rejecting the deletion and the moves of the
+// protected resources of the given one.
+func New(next storage.FS, c *Config) (storage.FS, error) {
+	if c.OverridePermission == "" {
+		c.OverridePermission = "delete-protected-resources"
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+
+	p := &fs{FS: next}
+	p.canOverride = func(ctx context.Context) (bool, error) {
+		return checkPermission(ctx, c.GatewaySvc, c.OverridePermission)
+	}
+	return composable.Wrap(p, next), nil
+}
+
+// GetMD always reads the protection of the resource, as clients need it to
+// know whether the resource can be deleted.
+func (p *fs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	return p.FS.GetMD(ctx, ref, withMetadataKey(mdKeys))
+}
+
+func (p *fs) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	return p.FS.ListFolder(ctx, ref, withMetadataKey(mdKeys))
+}
+
+func (p *fs) Delete(ctx context.Context, ref *provider.Reference) error {
+	if err := p.check(ctx, "delete", ref); err != nil {
+		return err
+	}
+	return p.FS.Delete(ctx, ref)
+}
+
+// DeleteRecursive lets the driver delete a tree at once, unless it is protected.
+func (p *fs) DeleteRecursive(ctx context.Context, ref *provider.Reference, progress func(total, deleted uint64)) error {
+	if err := p.check(ctx, "delete", ref); err != nil {
+		return err
+	}
+	return deletejob.DeleteRecursive(ctx, p.FS, ref, progress)
+}
+
+func (p *fs) Move(ctx context.Context, oldRef, newRef *provider.Reference) error {
+	if err := p.check(ctx, "move", oldRef); err != nil {
+		return err
+	}
+	return p.FS.Move(ctx, oldRef, newRef)
+}
+
+func (p *fs) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	if v, ok := md.GetMetadata()[MetadataKey]; ok {
+		protected, err := strconv.ParseBool(v)
+		if err != nil {
+			return errtypes.BadRequest(fmt.Sprintf("invalid protection %q", v))
+		}
+		if err := p.checkManager(ctx, ref, protected); err != nil {
+			return err
+		}
+	}
+	return p.FS.SetArbitraryMetadata(ctx, ref, md)
+}
+
+func (p *fs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
+	for _, k := range keys {
+		if k == MetadataKey {
+			if err := p.checkManager(ctx, ref, false); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return p.FS.UnsetArbitraryMetadata(ctx, ref, keys)
+}
+
+// checkManager rejects changing the protection of the referenced resource
+// unless the user manages its grants, as the managers of a space do. Only
+// folders can be protected.
+func (p *fs) checkManager(ctx context.Context, ref *provider.Reference, protect bool) error {
+	info, err := p.FS.GetMD(ctx, ref, []string{MetadataKey})
+	if err != nil {
+		return err
+	}
+	if protect && info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		return errtypes.BadRequest("only folders can be protected")
+	}
+	if rp := info.PermissionSet; rp == nil || !rp.AddGrant || !rp.RemoveGrant {
+		return errtypes.PermissionDenied(fmt.Sprintf("only the managers of the space can change the protection of %s", info.Path))
+	}
+	return nil
+}
+
+// check rejects the operation on the referenced resource if it is protected
+// and the user lacks the override permission.
+func (p *fs) check(ctx context.Context, op string, ref *provider.Reference) error {
+	info, err := p.FS.GetMD(ctx, ref, []string{MetadataKey})
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			// nothing to protect, the driver reports the error
+			return nil
+		}
+		return err
+	}
+	if !IsProtected(info) {
+		return nil
+	}
+
+	override, err := p.canOverride(ctx)
+	if err != nil {
+		return errors.Wrap(err, "protect: error checking the override permission")
+	}
+	log := appctx.GetLogger(ctx)
+	if override {
+		log.Info().Str("operation", op).Str("path", info.Path).Msg("protect: protection overridden")
+		return nil
+	}
+	log.Debug().Str("operation", op).Str("path", info.Path).Msg("protect: operation rejected")
+	return errtypes.PermissionDenied(fmt.Sprintf("%s is protected against %s", info.Path, op))
+}
+
+func withMetadataKey(mdKeys []string) []string {
+	// no keys return all of them
+	if len(mdKeys) == 0 {
+		return mdKeys
+	}
+	for _, k := range mdKeys {
+		if k == MetadataKey || k == "*" {
+			return mdKeys
+		}
+	}
+	return append(mdKeys[:len(mdKeys):len(mdKeys)], MetadataKey)
+}
+
+func checkPermission(ctx context.Context, gatewaySvc, permission string) (bool, error) {
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		return false, nil
+	}
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(gatewaySvc))
+	if err != nil {
+		return false, err
+	}
+	res, err := client.CheckPermission(ctx, &permissions.CheckPermissionRequest{
+		Permission: permission,
+		SubjectRef: &permissions.SubjectReference{
+			Spec: &permissions.SubjectReference_UserId{
+				UserId: u.Id,
+			},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.Status.Code == rpc.Code_CODE_OK, nil
+}
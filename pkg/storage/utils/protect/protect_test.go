@@ -0,0 +1,172 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package protect
+
+import (
+	"context"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+// memFS keeps the metadata of a few folders in memory.
+type memFS struct {
+	storage.FS
+	infos   map[string]*provider.ResourceInfo
+	deleted []string
+}
+
+func (m *memFS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	ri, ok := m.infos[ref.Path]
+	if !ok {
+		return nil, errtypes.NotFound(ref.Path)
+	}
+	return ri, nil
+}
+
+func (m *memFS) Delete(ctx context.Context, ref *provider.Reference) error {
+	m.deleted = append(m.deleted, ref.Path)
+	return nil
+}
+
+func (m *memFS) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	m.infos[ref.Path].ArbitraryMetadata = md
+	return nil
+}
+
+func newMemFS() *memFS {
+	return &memFS{infos: map[string]*provider.ResourceInfo{
+		"/projects": {
+			Path:              "/projects",
+			Type:              provider.ResourceType_RESOURCE_TYPE_CONTAINER,
+			PermissionSet:     &provider.ResourcePermissions{Delete: true},
+			ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: map[string]string{MetadataKey: "1"}},
+		},
+		"/scratch": {
+			Path:          "/scratch",
+			Type:          provider.ResourceType_RESOURCE_TYPE_CONTAINER,
+			PermissionSet: &provider.ResourcePermissions{Delete: true, AddGrant: true, RemoveGrant: true},
+		},
+		"/notes.txt": {
+			Path:          "/notes.txt",
+			Type:          provider.ResourceType_RESOURCE_TYPE_FILE,
+			PermissionSet: &provider.ResourcePermissions{Delete: true, AddGrant: true, RemoveGrant: true},
+		},
+	}}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		path     string
+		override bool
+		err      bool
+	}{
+		{"/projects", false, true},
+		{"/projects", true, false},
+		{"/scratch", false, false},
+		{"/missing", false, false},
+	}
+
+	for _, tt := range tests {
+		m := newMemFS()
+		override := tt.override
+		p := &fs{FS: m, canOverride: func(context.Context) (bool, error) { return override, nil }}
+
+		err := p.Delete(context.Background(), &provider.Reference{Path: tt.path})
+		if tt.err {
+			if _, ok := err.(errtypes.PermissionDenied); !ok {
+				t.Errorf("deleting %s: expected permission denied, got %v", tt.path, err)
+			}
+			if len(m.deleted) != 0 {
+				t.Errorf("deleting %s: the resource was deleted", tt.path)
+			}
+		} else if err != nil || len(m.deleted) != 1 {
+			t.Errorf("deleting %s: unexpected error %v", tt.path, err)
+		}
+	}
+}
+
+func TestDeleteRecursive(t *testing.T) {
+	m := newMemFS()
+	p := &fs{FS: m, canOverride: func(context.Context) (bool, error) { return false, nil }}
+	progress := func(total, deleted uint64) {}
+
+	err := p.DeleteRecursive(context.Background(), &provider.Reference{Path: "/projects"}, progress)
+	if _, ok := err.(errtypes.PermissionDenied); !ok || len(m.deleted) != 0 {
+		t.Errorf("expected the protected folder to be kept, got %v", err)
+	}
+	// the driver has no DeleteRecursive, the folder is deleted at once
+	if err := p.DeleteRecursive(context.Background(), &provider.Reference{Path: "/scratch"}, progress); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.deleted) != 1 || m.deleted[0] != "/scratch" {
+		t.Errorf("expected the folder to be deleted, got %v", m.deleted)
+	}
+}
+
+func TestSetProtection(t *testing.T) {
+	tests := []struct {
+		path  string
+		value string
+		err   error
+	}{
+		{"/scratch", "true", nil},
+		{"/scratch", "maybe", errtypes.BadRequest("")},
+		{"/notes.txt", "1", errtypes.BadRequest("")},
+		{"/notes.txt", "0", nil},
+		{"/projects", "0", errtypes.PermissionDenied("")},
+	}
+
+	for _, tt := range tests {
+		m := newMemFS()
+		p := &fs{FS: m}
+
+		md := &provider.ArbitraryMetadata{Metadata: map[string]string{MetadataKey: tt.value}}
+		err := p.SetArbitraryMetadata(context.Background(), &provider.Reference{Path: tt.path}, md)
+		switch tt.err.(type) {
+		case nil:
+			if err != nil {
+				t.Errorf("protecting %s with %s: unexpected error %v", tt.path, tt.value, err)
+			}
+		case errtypes.BadRequest:
+			if _, ok := err.(errtypes.BadRequest); !ok {
+				t.Errorf("protecting %s with %s: expected bad request, got %v", tt.path, tt.value, err)
+			}
+		case errtypes.PermissionDenied:
+			if _, ok := err.(errtypes.PermissionDenied); !ok {
+				t.Errorf("protecting %s with %s: expected permission denied, got %v", tt.path, tt.value, err)
+			}
+		}
+	}
+}
+
+func TestWithMetadataKey(t *testing.T) {
+	if keys := withMetadataKey(nil); len(keys) != 0 {
+		t.Errorf("expected all keys to be requested, got %v", keys)
+	}
+	if keys := withMetadataKey([]string{"*"}); len(keys) != 1 {
+		t.Errorf("expected all keys to be requested, got %v", keys)
+	}
+	keys := withMetadataKey([]string{"http://owncloud.org/ns/favorite"})
+	if len(keys) != 2 || keys[1] != MetadataKey {
+		t.Errorf("expected the protection to be requested, got %v", keys)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package quarantine wraps a storage driver to surface the virus scan status
+// of its files and to block the downloads of the infected ones.
+package quarantine
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/antivirus"
+	_ "github.com/cs3org/reva/pkg/antivirus/manager/loader" // Load the scan result managers
+	"github.com/cs3org/reva/pkg/antivirus/manager/registry"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/deletejob"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/composable"
+	"github.com/pkg/errors"
+)
+
+// Config configures the quarantine of infected files.
+type Config struct {
+	Driver  string                            `mapstructure:"driver" docs:";The manager the scan results are read from. Empty disables the quarantine."`
+	Drivers map[string]map[string]interface{} `mapstructure:"drivers" docs:"url:pkg/antivirus/manager/json/json.go;The configuration of the scan result managers."`
+}
+
+// Enabled returns whether the scan status of the files is read from a
+// quarantine manager.
+func (c *Config) Enabled() bool {
+	return c.Driver != ""
+}
+
+type fs struct {
+	storage.FS
+	results antivirus.Manager
+}
+
+// New returns a storage.FS adding the scan status to the files of the given
+// one and refusing to serve the content of the infected ones.
+func New(next storage.FS, c *Config) (storage.FS, error) {
+	f, ok := registry.NewFuncs[c.Driver]
+	if !ok {
+		return nil, fmt.Errorf("quarantine: scan result manager not found: %s", c.Driver)
+	}
+	results, err := f(c.Drivers[c.Driver])
+	if err != nil {
+		return nil, errors.Wrap(err, "quarantine: error creating the scan result manager")
+	}
+
+	q := &fs{FS: next, results: results}
+	return composable.Wrap(q, next), nil
+}
+
+// DeleteRecursive lets the driver delete a tree at once, infected files included.
+func (q *fs) DeleteRecursive(ctx context.Context, ref *provider.Reference, progress func(total, deleted uint64)) error {
+	return deletejob.DeleteRecursive(ctx, q.FS, ref, progress)
+}
+
+func (q *fs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	ri, err := q.FS.GetMD(ctx, ref, mdKeys)
+	if err != nil || ri.Type != provider.ResourceType_RESOURCE_TYPE_FILE {
+		return ri, err
+	}
+	r, err := q.results.GetResult(ctx, ri.Id)
+	switch err.(type) {
+	case nil:
+		ri.Opaque = antivirus.AddToOpaque(ri.Opaque, r)
+	case errtypes.IsNotFound:
+		// the file has not been scanned
+	default:
+		// the status is informative, the download check reports the error
+		appctx.GetLogger(ctx).Error().Err(err).Interface("id", ri.Id).Msg("quarantine: error reading scan result")
+	}
+	return ri, nil
+}
+
+func (q *fs) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	infos, err := q.FS.ListFolder(ctx, ref, mdKeys)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]*provider.ResourceId, 0, len(infos))
+	for _, ri := range infos {
+		if ri.Type == provider.ResourceType_RESOURCE_TYPE_FILE {
+			ids = append(ids, ri.Id)
+		}
+	}
+	if len(ids) == 0 {
+		return infos, nil
+	}
+	results, err := q.results.GetResults(ctx, ids)
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("quarantine: error reading scan results")
+		return infos, nil
+	}
+	for _, ri := range infos {
+		if r, ok := results[antivirus.ID(ri.Id)]; ok && ri.Type == provider.ResourceType_RESOURCE_TYPE_FILE {
+			ri.Opaque = antivirus.AddToOpaque(ri.Opaque, r)
+		}
+	}
+	return infos, nil
+}
+
+func (q *fs) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	ri, err := q.FS.GetMD(ctx, ref, nil)
+	if err != nil {
+		return nil, err
+	}
+	r, err := q.results.GetResult(ctx, ri.Id)
+	switch err.(type) {
+	case nil:
+		if r.Status == antivirus.StatusInfected {
+			appctx.GetLogger(ctx).Warn().Interface("id", ri.Id).Str("virus", r.Virus).Msg("quarantine: download of infected file blocked")
+			return nil, errtypes.Infected(r.Virus)
+		}
+	case errtypes.IsNotFound:
+		// the file has not been scanned
+	default:
+		return nil, errors.Wrap(err, "quarantine: error reading scan result")
+	}
+	return q.FS.Download(ctx, ref)
+}
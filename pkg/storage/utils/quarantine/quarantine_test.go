@@ -0,0 +1,181 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package quarantine
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/antivirus"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+// memFS serves a folder with a few files from memory.
+type memFS struct {
+	storage.FS
+	infos []*provider.ResourceInfo
+}
+
+func (m *memFS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	for _, ri := range m.infos {
+		if ri.Path == ref.Path {
+			clone := *ri
+			return &clone, nil
+		}
+	}
+	return nil, errtypes.NotFound(ref.Path)
+}
+
+func (m *memFS) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	infos := make([]*provider.ResourceInfo, 0, len(m.infos))
+	for _, ri := range m.infos {
+		clone := *ri
+		infos = append(infos, &clone)
+	}
+	return infos, nil
+}
+
+func (m *memFS) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader("content")), nil
+}
+
+// memResults keeps the scan results in memory and records the batch lookups.
+type memResults struct {
+	antivirus.Manager
+	results map[string]*antivirus.Result
+	lookups [][]*provider.ResourceId
+}
+
+func (m *memResults) GetResult(ctx context.Context, id *provider.ResourceId) (*antivirus.Result, error) {
+	if r, ok := m.results[antivirus.ID(id)]; ok {
+		return r, nil
+	}
+	return nil, errtypes.NotFound(antivirus.ID(id))
+}
+
+func (m *memResults) GetResults(ctx context.Context, ids []*provider.ResourceId) (map[string]*antivirus.Result, error) {
+	m.lookups = append(m.lookups, ids)
+	found := map[string]*antivirus.Result{}
+	for _, id := range ids {
+		if r, ok := m.results[antivirus.ID(id)]; ok {
+			found[r.ID()] = r
+		}
+	}
+	return found, nil
+}
+
+func file(name string) *provider.ResourceInfo {
+	return &provider.ResourceInfo{
+		Id:   &provider.ResourceId{StorageId: "storage", OpaqueId: name},
+		Path: "/" + name,
+		Type: provider.ResourceType_RESOURCE_TYPE_FILE,
+	}
+}
+
+func newTestFS() (*fs, *memResults) {
+	folder := &provider.ResourceInfo{
+		Id:   &provider.ResourceId{StorageId: "storage", OpaqueId: "folder"},
+		Path: "/folder",
+		Type: provider.ResourceType_RESOURCE_TYPE_CONTAINER,
+	}
+	results := &memResults{results: map[string]*antivirus.Result{}}
+	for _, r := range []*antivirus.Result{
+		{ResourceID: &provider.ResourceId{StorageId: "storage", OpaqueId: "infected"}, Status: antivirus.StatusInfected, Virus: "Eicar-Test-Signature"},
+		{ResourceID: &provider.ResourceId{StorageId: "storage", OpaqueId: "clean"}, Status: antivirus.StatusClean},
+		// results of files elsewhere are not looked at when listing the folder
+		{ResourceID: &provider.ResourceId{StorageId: "storage", OpaqueId: "other"}, Status: antivirus.StatusInfected},
+	} {
+		results.results[r.ID()] = r
+	}
+	next := &memFS{infos: []*provider.ResourceInfo{file("infected"), file("clean"), file("unscanned"), folder}}
+	return &fs{FS: next, results: results}, results
+}
+
+func TestListFolder(t *testing.T) {
+	q, results := newTestFS()
+
+	infos, err := q.ListFolder(context.Background(), &provider.Reference{Path: "/"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]antivirus.Status{
+		"/infected":  antivirus.StatusInfected,
+		"/clean":     antivirus.StatusClean,
+		"/unscanned": "",
+		"/folder":    "",
+	}
+	for _, ri := range infos {
+		if status := antivirus.StatusFromResourceInfo(ri); status != expected[ri.Path] {
+			t.Errorf("expected status %q for %s, got %q", expected[ri.Path], ri.Path, status)
+		}
+	}
+	if len(results.lookups) != 1 {
+		t.Fatalf("expected a single batch lookup, got %d", len(results.lookups))
+	}
+	if len(results.lookups[0]) != 3 {
+		t.Errorf("expected the results of the 3 files to be looked up, got %d", len(results.lookups[0]))
+	}
+}
+
+func TestGetMD(t *testing.T) {
+	q, _ := newTestFS()
+
+	ri, err := q.GetMD(context.Background(), &provider.Reference{Path: "/infected"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if antivirus.StatusFromResourceInfo(ri) != antivirus.StatusInfected || antivirus.VirusFromResourceInfo(ri) != "Eicar-Test-Signature" {
+		t.Errorf("unexpected scan status in %v", ri.Opaque)
+	}
+
+	ri, err = q.GetMD(context.Background(), &provider.Reference{Path: "/unscanned"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := antivirus.StatusFromResourceInfo(ri); status != "" {
+		t.Errorf("expected no scan status for an unscanned file, got %q", status)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	q, _ := newTestFS()
+
+	for _, tt := range []struct {
+		path    string
+		blocked bool
+	}{
+		{"/infected", true},
+		{"/clean", false},
+		{"/unscanned", false},
+	} {
+		r, err := q.Download(context.Background(), &provider.Reference{Path: tt.path})
+		if _, infected := err.(errtypes.IsInfected); tt.blocked != infected {
+			t.Errorf("unexpected error downloading %s: %v", tt.path, err)
+		}
+		if !tt.blocked && err == nil {
+			r.Close()
+		}
+	}
+}
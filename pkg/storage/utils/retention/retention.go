@@ -0,0 +1,359 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package retention wraps a storage driver to enforce retention policies on
+// folders: until the retention date of a folder, the resources in its tree
+// can be added but neither modified, moved nor deleted. The retention can
+// only be set by privileged users and extended, not shortened.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	permissions "github.com/cs3org/go-cs3apis/cs3/permissions/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/deletejob"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/composable"
+	"github.com/pkg/errors"
+)
+
+// MetadataKey is the arbitrary metadata holding the retention date of a
+// folder in RFC 3339 format. It matches the oc:retention-until WebDAV
+// property, so that it can be set through PROPPATCH. The resources returned
+// with the key requested carry the retention inherited from their parents.
+const MetadataKey = "http://owncloud.org/ns/retention-until"
+
+// Config configures the retention of folders.
+type Config struct {
+	Folders       bool   `mapstructure:"folders" docs:"false;Whether folders can be put under retention, making their content write-once until the retention date."`
+	SetPermission string `mapstructure:"set_permission" docs:"set-retention;The permission allowing users to set the retention of folders."`
+	GatewaySvc    string `mapstructure:"gatewaysvc" docs:";The gateway the permission is checked through."`
+}
+
+// Enabled returns whether the retention periods set on folders are
+// enforced.
+func (c *Config) Enabled() bool {
+	return c.Folders
+}
+
+// RetainedUntil returns the date until which the given resource is retained,
+// if the retention is still active.
+func RetainedUntil(ri *provider.ResourceInfo) (time.Time, bool) {
+	until, ok := parse(ri.GetArbitraryMetadata().GetMetadata()[MetadataKey])
+	return until, ok && until.After(time.Now())
+}
+
+func parse(v string) (time.Time, bool) {
+	if v == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	return t, err == nil
+}
+
+type fs struct {
+	storage.FS
+	canSet func(context.Context) (bool, error)
+}
+
+// New returns a storage.FS rejecting the modifications of the resources of
+// the given one under an active retention.
+func New(next storage.FS, c *Config) (storage.FS, error) {
+	if c.SetPermission == "" {
+		c.SetPermission = "set-retention"
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+
+	r := &fs{FS: next}
+	r.canSet = func(ctx context.Context) (bool, error) {
+		return checkPermission(ctx, c.GatewaySvc, c.SetPermission)
+	}
+	return composable.Wrap(r, next), nil
+}
+
+// GetMD adds the retention inherited from the parents of the resource when
+// it is requested, which costs a lookup per parent.
+func (r *fs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	info, err := r.FS.GetMD(ctx, ref, withMetadataKey(mdKeys))
+	if err != nil || !requested(mdKeys) {
+		return info, err
+	}
+	until, err := r.inherited(ctx, path.Dir(info.Path))
+	if err != nil {
+		return nil, err
+	}
+	setRetention(info, until)
+	return info, nil
+}
+
+func (r *fs) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	infos, err := r.FS.ListFolder(ctx, ref, withMetadataKey(mdKeys))
+	if err != nil || !requested(mdKeys) || len(infos) == 0 {
+		return infos, err
+	}
+	// the children inherit the retention of the listed folder
+	until, err := r.inherited(ctx, path.Dir(infos[0].Path))
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		setRetention(info, until)
+	}
+	return infos, nil
+}
+
+func (r *fs) Delete(ctx context.Context, ref *provider.Reference) error {
+	if err := r.check(ctx, "delete", ref); err != nil {
+		return err
+	}
+	return r.FS.Delete(ctx, ref)
+}
+
+// DeleteRecursive lets the driver delete a tree at once, unless it is retained.
+func (r *fs) DeleteRecursive(ctx context.Context, ref *provider.Reference, progress func(total, deleted uint64)) error {
+	if err := r.check(ctx, "delete", ref); err != nil {
+		return err
+	}
+	return deletejob.DeleteRecursive(ctx, r.FS, ref, progress)
+}
+
+func (r *fs) Move(ctx context.Context, oldRef, newRef *provider.Reference) error {
+	if err := r.check(ctx, "move", oldRef); err != nil {
+		return err
+	}
+	// moving into a retained folder adds a resource, overwriting one modifies it
+	if err := r.check(ctx, "move", newRef); err != nil {
+		return err
+	}
+	return r.FS.Move(ctx, oldRef, newRef)
+}
+
+func (r *fs) TouchFile(ctx context.Context, ref *provider.Reference) error {
+	if err := r.check(ctx, "touch_file", ref); err != nil {
+		return err
+	}
+	return r.FS.TouchFile(ctx, ref)
+}
+
+func (r *fs) InitiateUpload(ctx context.Context, ref *provider.Reference, uploadLength int64, metadata map[string]string) (map[string]string, error) {
+	if err := r.check(ctx, "upload", ref); err != nil {
+		return nil, err
+	}
+	return r.FS.InitiateUpload(ctx, ref, uploadLength, metadata)
+}
+
+func (r *fs) Upload(ctx context.Context, ref *provider.Reference, rc io.ReadCloser) error {
+	if err := r.check(ctx, "upload", ref); err != nil {
+		return err
+	}
+	return r.FS.Upload(ctx, ref, rc)
+}
+
+func (r *fs) RestoreRevision(ctx context.Context, ref *provider.Reference, key string) error {
+	if err := r.check(ctx, "restore_revision", ref); err != nil {
+		return err
+	}
+	return r.FS.RestoreRevision(ctx, ref, key)
+}
+
+func (r *fs) RestoreRecycleItem(ctx context.Context, basePath, key, relativePath string, restoreRef *provider.Reference) error {
+	if restoreRef != nil {
+		if err := r.check(ctx, "restore_recycle_item", restoreRef); err != nil {
+			return err
+		}
+	}
+	return r.FS.RestoreRecycleItem(ctx, basePath, key, relativePath, restoreRef)
+}
+
+func (r *fs) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	if v, ok := md.GetMetadata()[MetadataKey]; ok {
+		until, valid := parse(v)
+		if !valid {
+			return errtypes.BadRequest(fmt.Sprintf("invalid retention date %q, expected RFC 3339", v))
+		}
+		if err := r.checkSet(ctx, ref, until); err != nil {
+			return err
+		}
+	}
+	return r.FS.SetArbitraryMetadata(ctx, ref, md)
+}
+
+func (r *fs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
+	for _, k := range keys {
+		if k == MetadataKey {
+			if err := r.checkSet(ctx, ref, time.Time{}); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return r.FS.UnsetArbitraryMetadata(ctx, ref, keys)
+}
+
+// checkSet rejects changing the retention of the referenced folder unless the
+// user has the permission to, and only allows extending an active retention.
+func (r *fs) checkSet(ctx context.Context, ref *provider.Reference, until time.Time) error {
+	info, err := r.FS.GetMD(ctx, ref, []string{MetadataKey})
+	if err != nil {
+		return err
+	}
+	if info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		return errtypes.BadRequest("only folders can be put under retention")
+	}
+
+	allowed, err := r.canSet(ctx)
+	if err != nil {
+		return errors.Wrap(err, "retention: error checking the permission")
+	}
+	if !allowed {
+		return errtypes.PermissionDenied(fmt.Sprintf("not allowed to change the retention of %s", info.Path))
+	}
+	if current, ok := RetainedUntil(info); ok && until.Before(current) {
+		return errtypes.PermissionDenied(fmt.Sprintf("the retention of %s cannot be shortened before %s", info.Path, current.Format(time.RFC3339)))
+	}
+
+	log := appctx.GetLogger(ctx)
+	if until.IsZero() {
+		log.Info().Str("path", info.Path).Msg("retention: retention removed")
+	} else {
+		log.Info().Str("path", info.Path).Time("until", until).Msg("retention: retention set")
+	}
+	return nil
+}
+
+// check rejects the operation on the referenced resource if it is under an
+// active retention, its own or the one of its parents. Creating a resource
+// which doesn't exist yet is always allowed.
+func (r *fs) check(ctx context.Context, op string, ref *provider.Reference) error {
+	info, err := r.FS.GetMD(ctx, ref, []string{MetadataKey})
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			// new resources can be added, the driver reports the missing ones otherwise
+			return nil
+		}
+		return err
+	}
+
+	until, ok := RetainedUntil(info)
+	if !ok {
+		if until, err = r.inherited(ctx, path.Dir(info.Path)); err != nil {
+			return err
+		}
+	}
+	if until.IsZero() {
+		return nil
+	}
+	appctx.GetLogger(ctx).Debug().Str("operation", op).Str("path", info.Path).Time("until", until).Msg("retention: operation rejected")
+	return errtypes.PermissionDenied(fmt.Sprintf("%s is retained until %s and cannot be modified", info.Path, until.Format(time.RFC3339)))
+}
+
+// inherited returns the latest active retention of the folder at the given
+// path and its parents, or the zero time if there is none.
+func (r *fs) inherited(ctx context.Context, p string) (time.Time, error) {
+	var latest time.Time
+	for {
+		info, err := r.FS.GetMD(ctx, &provider.Reference{Path: p}, []string{MetadataKey})
+		if err != nil {
+			if _, ok := err.(errtypes.IsNotFound); ok {
+				// above the root of the storage
+				return latest, nil
+			}
+			if _, ok := err.(errtypes.IsPermissionDenied); ok {
+				// the parents the user has no access to cannot be checked
+				return latest, nil
+			}
+			return time.Time{}, err
+		}
+		if until, ok := RetainedUntil(info); ok && until.After(latest) {
+			latest = until
+		}
+		if p == "/" || p == "." || p == "" {
+			return latest, nil
+		}
+		p = path.Dir(p)
+	}
+}
+
+// setRetention reports the given inherited retention in the metadata of the
+// resource, unless its own lasts longer.
+func setRetention(info *provider.ResourceInfo, inherited time.Time) {
+	if inherited.IsZero() {
+		return
+	}
+	if own, ok := RetainedUntil(info); ok && own.After(inherited) {
+		return
+	}
+	if info.ArbitraryMetadata == nil {
+		info.ArbitraryMetadata = &provider.ArbitraryMetadata{}
+	}
+	if info.ArbitraryMetadata.Metadata == nil {
+		info.ArbitraryMetadata.Metadata = map[string]string{}
+	}
+	info.ArbitraryMetadata.Metadata[MetadataKey] = inherited.UTC().Format(time.RFC3339)
+}
+
+func requested(mdKeys []string) bool {
+	for _, k := range mdKeys {
+		if k == MetadataKey || k == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func withMetadataKey(mdKeys []string) []string {
+	// no keys return all of them
+	if len(mdKeys) == 0 || requested(mdKeys) {
+		return mdKeys
+	}
+	return append(mdKeys[:len(mdKeys):len(mdKeys)], MetadataKey)
+}
+
+func checkPermission(ctx context.Context, gatewaySvc, permission string) (bool, error) {
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		return false, nil
+	}
+	client, err := pool.GetGatewayServiceClient(pool.Endpoint(gatewaySvc))
+	if err != nil {
+		return false, err
+	}
+	res, err := client.CheckPermission(ctx, &permissions.CheckPermissionRequest{
+		Permission: permission,
+		SubjectRef: &permissions.SubjectReference{
+			Spec: &permissions.SubjectReference_UserId{
+				UserId: u.Id,
+			},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.Status.Code == rpc.Code_CODE_OK, nil
+}
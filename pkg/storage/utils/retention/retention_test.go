@@ -0,0 +1,167 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package retention
+
+import (
+	"context"
+	"io"
+	"path"
+	"testing"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+type testFS struct {
+	storage.FS
+	// the metadata of the resources by path, folders end with a slash
+	resources map[string]map[string]string
+}
+
+func (t *testFS) lookup(p string) (string, map[string]string, bool) {
+	if md, ok := t.resources[p]; ok {
+		return p, md, true
+	}
+	if md, ok := t.resources[path.Join(p)+"/"]; ok {
+		return path.Join(p) + "/", md, true
+	}
+	return "", nil, false
+}
+
+func (t *testFS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	k, md, ok := t.lookup(ref.Path)
+	if !ok {
+		return nil, errtypes.NotFound(ref.Path)
+	}
+	info := &provider.ResourceInfo{
+		Type:              provider.ResourceType_RESOURCE_TYPE_FILE,
+		Path:              path.Clean(ref.Path),
+		ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: map[string]string{}},
+	}
+	if k[len(k)-1] == '/' {
+		info.Type = provider.ResourceType_RESOURCE_TYPE_CONTAINER
+	}
+	for k, v := range md {
+		info.ArbitraryMetadata.Metadata[k] = v
+	}
+	return info, nil
+}
+
+func (t *testFS) Delete(ctx context.Context, ref *provider.Reference) error {
+	return nil
+}
+
+func (t *testFS) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	return nil
+}
+
+func (t *testFS) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	_, current, _ := t.lookup(ref.Path)
+	for k, v := range md.Metadata {
+		current[k] = v
+	}
+	return nil
+}
+
+func newTestFS(until time.Time, allowed bool) (*testFS, *fs) {
+	next := &testFS{resources: map[string]map[string]string{
+		"/":                 {},
+		"/archive/":         {MetadataKey: until.Format(time.RFC3339)},
+		"/archive/data/":    {},
+		"/archive/data/a":   {},
+		"/scratch/":         {},
+		"/scratch/notes.md": {},
+	}}
+	return next, &fs{FS: next, canSet: func(context.Context) (bool, error) { return allowed, nil }}
+}
+
+func isPermissionDenied(err error) bool {
+	_, ok := err.(errtypes.IsPermissionDenied)
+	return ok
+}
+
+func TestEnforcement(t *testing.T) {
+	ctx := context.Background()
+	_, r := newTestFS(time.Now().Add(time.Hour), false)
+
+	if err := r.Delete(ctx, &provider.Reference{Path: "/archive/data/a"}); !isPermissionDenied(err) {
+		t.Errorf("expected the deletion of a retained file to be rejected, got %v", err)
+	}
+	if err := r.Delete(ctx, &provider.Reference{Path: "/archive"}); !isPermissionDenied(err) {
+		t.Errorf("expected the deletion of a retained folder to be rejected, got %v", err)
+	}
+	if err := r.Upload(ctx, &provider.Reference{Path: "/archive/data/a"}, nil); !isPermissionDenied(err) {
+		t.Errorf("expected the overwrite of a retained file to be rejected, got %v", err)
+	}
+	if err := r.Upload(ctx, &provider.Reference{Path: "/archive/data/b"}, nil); err != nil {
+		t.Errorf("expected adding a file to a retained folder to be allowed, got %v", err)
+	}
+	if err := r.Delete(ctx, &provider.Reference{Path: "/scratch/notes.md"}); err != nil {
+		t.Errorf("expected the deletion of a file which isn't retained to be allowed, got %v", err)
+	}
+
+	info, err := r.GetMD(ctx, &provider.Reference{Path: "/archive/data/a"}, []string{MetadataKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := RetainedUntil(info); !ok {
+		t.Error("expected the retention of the parent to be inherited")
+	}
+}
+
+func TestExpired(t *testing.T) {
+	_, r := newTestFS(time.Now().Add(-time.Hour), false)
+	if err := r.Delete(context.Background(), &provider.Reference{Path: "/archive/data/a"}); err != nil {
+		t.Errorf("expected the deletion to be allowed once the retention expired, got %v", err)
+	}
+}
+
+func TestSetRetention(t *testing.T) {
+	ctx := context.Background()
+	until := time.Now().Add(time.Hour).Truncate(time.Second)
+	set := func(r *fs, p string, t time.Time) error {
+		return r.SetArbitraryMetadata(ctx, &provider.Reference{Path: p}, &provider.ArbitraryMetadata{
+			Metadata: map[string]string{MetadataKey: t.Format(time.RFC3339)},
+		})
+	}
+
+	_, r := newTestFS(until, false)
+	if err := set(r, "/scratch", until); !isPermissionDenied(err) {
+		t.Errorf("expected setting the retention without the permission to be rejected, got %v", err)
+	}
+
+	next, r := newTestFS(until, true)
+	if err := set(r, "/archive", until.Add(-time.Minute)); !isPermissionDenied(err) {
+		t.Errorf("expected shortening the retention to be rejected, got %v", err)
+	}
+	if err := r.UnsetArbitraryMetadata(ctx, &provider.Reference{Path: "/archive"}, []string{MetadataKey}); !isPermissionDenied(err) {
+		t.Errorf("expected removing an active retention to be rejected, got %v", err)
+	}
+	if err := set(r, "/scratch/notes.md", until); err == nil {
+		t.Error("expected files not to be put under retention")
+	}
+	if err := set(r, "/archive", until.Add(time.Hour)); err != nil {
+		t.Errorf("expected extending the retention to be allowed, got %v", err)
+	}
+	if got := next.resources["/archive/"][MetadataKey]; got != until.Add(time.Hour).Format(time.RFC3339) {
+		t.Errorf("unexpected retention %s", got)
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package slowlog wraps a storage driver to record the calls taking longer
+// than a threshold, with their arguments, the RPC they were made for and the
+// stack of the caller, to a log of their own.
+package slowlog
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"runtime/debug"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/deletejob"
+	"github.com/cs3org/reva/pkg/logger"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/composable"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+)
+
+// Config configures the slow operation log.
+type Config struct {
+	Threshold int    `mapstructure:"threshold" docs:"0;Milliseconds above which a call to the driver is logged. 0 disables the log."`
+	Output    string `mapstructure:"output" docs:"stderr;Where the slow calls are logged: stderr, stdout or the path of a file."`
+	Mode      string `mapstructure:"mode" docs:"json;The format of the log: json or console."`
+}
+
+// Enabled returns whether the slow calls are logged, i.e. whether a
+// threshold is configured.
+func (c *Config) Enabled() bool {
+	return c.Threshold > 0
+}
+
+type fs struct {
+	storage.FS
+	threshold time.Duration
+	log       *zerolog.Logger
+}
+
+// New returns a storage.FS logging the slow calls to the given one.
+func New(next storage.FS, c *Config) (storage.FS, error) {
+	var w io.Writer
+	switch c.Output {
+	case "", "stderr":
+		w = os.Stderr
+	case "stdout":
+		w = os.Stdout
+	default:
+		f, err := os.OpenFile(c.Output, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, errors.Wrap(err, "slowlog: error opening "+c.Output)
+		}
+		w = f
+	}
+
+	s := &fs{
+		FS:        next,
+		threshold: time.Duration(c.Threshold) * time.Millisecond,
+		log:       logger.New(logger.WithWriter(w, logger.Mode(c.Mode))),
+	}
+	return composable.Wrap(s, next), nil
+}
+
+// observe logs the call started at start if it took too long; args are pairs
+// of names and values describing the arguments.
+func (s *fs) observe(ctx context.Context, op string, start time.Time, err *error, args ...interface{}) {
+	d := time.Since(start)
+	if d < s.threshold {
+		return
+	}
+
+	ev := s.log.Warn().Str("op", op).Dur("duration", d)
+	for i := 0; i+1 < len(args); i += 2 {
+		ev = ev.Interface(args[i].(string), args[i+1])
+	}
+	if method, ok := grpc.Method(ctx); ok {
+		ev = ev.Str("rpc", method)
+	}
+	if id, ok := ctxpkg.ContextGetRequestID(ctx); ok {
+		ev = ev.Str("request_id", id)
+	}
+	if u, ok := ctxpkg.ContextGetUser(ctx); ok {
+		ev = ev.Str("user", u.GetId().GetOpaqueId())
+	}
+	if err != nil && *err != nil {
+		ev = ev.Err(*err)
+	}
+	ev.Str("stack", string(debug.Stack())).Msg("slow storage operation")
+}
+
+func (s *fs) GetHome(ctx context.Context) (home string, err error) {
+	defer s.observe(ctx, "GetHome", time.Now(), &err)
+	return s.FS.GetHome(ctx)
+}
+
+func (s *fs) CreateHome(ctx context.Context) (err error) {
+	defer s.observe(ctx, "CreateHome", time.Now(), &err)
+	return s.FS.CreateHome(ctx)
+}
+
+func (s *fs) CreateDir(ctx context.Context, ref *provider.Reference) (err error) {
+	defer s.observe(ctx, "CreateDir", time.Now(), &err, "ref", ref)
+	return s.FS.CreateDir(ctx, ref)
+}
+
+func (s *fs) TouchFile(ctx context.Context, ref *provider.Reference) (err error) {
+	defer s.observe(ctx, "TouchFile", time.Now(), &err, "ref", ref)
+	return s.FS.TouchFile(ctx, ref)
+}
+
+func (s *fs) Delete(ctx context.Context, ref *provider.Reference) (err error) {
+	defer s.observe(ctx, "Delete", time.Now(), &err, "ref", ref)
+	return s.FS.Delete(ctx, ref)
+}
+
+func (s *fs) DeleteRecursive(ctx context.Context, ref *provider.Reference, progress func(total, deleted uint64)) (err error) {
+	defer s.observe(ctx, "DeleteRecursive", time.Now(), &err, "ref", ref)
+	return deletejob.DeleteRecursive(ctx, s.FS, ref, progress)
+}
+
+func (s *fs) Move(ctx context.Context, oldRef, newRef *provider.Reference) (err error) {
+	defer s.observe(ctx, "Move", time.Now(), &err, "old_ref", oldRef, "new_ref", newRef)
+	return s.FS.Move(ctx, oldRef, newRef)
+}
+
+func (s *fs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (info *provider.ResourceInfo, err error) {
+	defer s.observe(ctx, "GetMD", time.Now(), &err, "ref", ref, "md_keys", mdKeys)
+	return s.FS.GetMD(ctx, ref, mdKeys)
+}
+
+func (s *fs) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) (infos []*provider.ResourceInfo, err error) {
+	start := time.Now()
+	defer func() {
+		s.observe(ctx, "ListFolder", start, &err, "ref", ref, "md_keys", mdKeys, "entries", len(infos))
+	}()
+	return s.FS.ListFolder(ctx, ref, mdKeys)
+}
+
+func (s *fs) InitiateUpload(ctx context.Context, ref *provider.Reference, uploadLength int64, metadata map[string]string) (ids map[string]string, err error) {
+	defer s.observe(ctx, "InitiateUpload", time.Now(), &err, "ref", ref, "upload_length", uploadLength, "metadata", metadata)
+	return s.FS.InitiateUpload(ctx, ref, uploadLength, metadata)
+}
+
+func (s *fs) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) (err error) {
+	defer s.observe(ctx, "Upload", time.Now(), &err, "ref", ref)
+	return s.FS.Upload(ctx, ref, r)
+}
+
+// Download only measures the opening of the content, not its transfer.
+func (s *fs) Download(ctx context.Context, ref *provider.Reference) (r io.ReadCloser, err error) {
+	defer s.observe(ctx, "Download", time.Now(), &err, "ref", ref)
+	return s.FS.Download(ctx, ref)
+}
+
+func (s *fs) ListRevisions(ctx context.Context, ref *provider.Reference) (revisions []*provider.FileVersion, err error) {
+	defer s.observe(ctx, "ListRevisions", time.Now(), &err, "ref", ref)
+	return s.FS.ListRevisions(ctx, ref)
+}
+
+func (s *fs) DownloadRevision(ctx context.Context, ref *provider.Reference, key string) (r io.ReadCloser, err error) {
+	defer s.observe(ctx, "DownloadRevision", time.Now(), &err, "ref", ref, "key", key)
+	return s.FS.DownloadRevision(ctx, ref, key)
+}
+
+func (s *fs) RestoreRevision(ctx context.Context, ref *provider.Reference, key string) (err error) {
+	defer s.observe(ctx, "RestoreRevision", time.Now(), &err, "ref", ref, "key", key)
+	return s.FS.RestoreRevision(ctx, ref, key)
+}
+
+func (s *fs) ListRecycle(ctx context.Context, basePath, key, relativePath string) (items []*provider.RecycleItem, err error) {
+	defer s.observe(ctx, "ListRecycle", time.Now(), &err, "base_path", basePath, "key", key, "relative_path", relativePath)
+	return s.FS.ListRecycle(ctx, basePath, key, relativePath)
+}
+
+func (s *fs) RestoreRecycleItem(ctx context.Context, basePath, key, relativePath string, restoreRef *provider.Reference) (err error) {
+	defer s.observe(ctx, "RestoreRecycleItem", time.Now(), &err, "base_path", basePath, "key", key, "relative_path", relativePath, "restore_ref", restoreRef)
+	return s.FS.RestoreRecycleItem(ctx, basePath, key, relativePath, restoreRef)
+}
+
+func (s *fs) PurgeRecycleItem(ctx context.Context, basePath, key, relativePath string) (err error) {
+	defer s.observe(ctx, "PurgeRecycleItem", time.Now(), &err, "base_path", basePath, "key", key, "relative_path", relativePath)
+	return s.FS.PurgeRecycleItem(ctx, basePath, key, relativePath)
+}
+
+func (s *fs) EmptyRecycle(ctx context.Context) (err error) {
+	defer s.observe(ctx, "EmptyRecycle", time.Now(), &err)
+	return s.FS.EmptyRecycle(ctx)
+}
+
+func (s *fs) GetPathByID(ctx context.Context, id *provider.ResourceId) (p string, err error) {
+	defer s.observe(ctx, "GetPathByID", time.Now(), &err, "id", id)
+	return s.FS.GetPathByID(ctx, id)
+}
+
+func (s *fs) AddGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) (err error) {
+	defer s.observe(ctx, "AddGrant", time.Now(), &err, "ref", ref, "grant", g)
+	return s.FS.AddGrant(ctx, ref, g)
+}
+
+func (s *fs) DenyGrant(ctx context.Context, ref *provider.Reference, g *provider.Grantee) (err error) {
+	defer s.observe(ctx, "DenyGrant", time.Now(), &err, "ref", ref, "grantee", g)
+	return s.FS.DenyGrant(ctx, ref, g)
+}
+
+func (s *fs) RemoveGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) (err error) {
+	defer s.observe(ctx, "RemoveGrant", time.Now(), &err, "ref", ref, "grant", g)
+	return s.FS.RemoveGrant(ctx, ref, g)
+}
+
+func (s *fs) UpdateGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) (err error) {
+	defer s.observe(ctx, "UpdateGrant", time.Now(), &err, "ref", ref, "grant", g)
+	return s.FS.UpdateGrant(ctx, ref, g)
+}
+
+func (s *fs) ListGrants(ctx context.Context, ref *provider.Reference) (grants []*provider.Grant, err error) {
+	defer s.observe(ctx, "ListGrants", time.Now(), &err, "ref", ref)
+	return s.FS.ListGrants(ctx, ref)
+}
+
+func (s *fs) GetQuota(ctx context.Context, ref *provider.Reference) (total uint64, used uint64, err error) {
+	defer s.observe(ctx, "GetQuota", time.Now(), &err, "ref", ref)
+	return s.FS.GetQuota(ctx, ref)
+}
+
+func (s *fs) GetQuotaInfo(ctx context.Context, ref *provider.Reference) (quota *storage.Quota, err error) {
+	defer s.observe(ctx, "GetQuotaInfo", time.Now(), &err, "ref", ref)
+	return storage.QuotaOf(ctx, s.FS, ref)
+}
+
+func (s *fs) CreateReference(ctx context.Context, path string, targetURI *url.URL) (err error) {
+	defer s.observe(ctx, "CreateReference", time.Now(), &err, "path", path, "target_uri", targetURI)
+	return s.FS.CreateReference(ctx, path, targetURI)
+}
+
+func (s *fs) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) (err error) {
+	defer s.observe(ctx, "SetArbitraryMetadata", time.Now(), &err, "ref", ref, "metadata", md)
+	return s.FS.SetArbitraryMetadata(ctx, ref, md)
+}
+
+func (s *fs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) (err error) {
+	defer s.observe(ctx, "UnsetArbitraryMetadata", time.Now(), &err, "ref", ref, "keys", keys)
+	return s.FS.UnsetArbitraryMetadata(ctx, ref, keys)
+}
+
+func (s *fs) SetLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) (err error) {
+	defer s.observe(ctx, "SetLock", time.Now(), &err, "ref", ref, "lock", lock)
+	return s.FS.SetLock(ctx, ref, lock)
+}
+
+func (s *fs) GetLock(ctx context.Context, ref *provider.Reference) (lock *provider.Lock, err error) {
+	defer s.observe(ctx, "GetLock", time.Now(), &err, "ref", ref)
+	return s.FS.GetLock(ctx, ref)
+}
+
+func (s *fs) RefreshLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) (err error) {
+	defer s.observe(ctx, "RefreshLock", time.Now(), &err, "ref", ref, "lock", lock)
+	return s.FS.RefreshLock(ctx, ref, lock)
+}
+
+func (s *fs) Unlock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) (err error) {
+	defer s.observe(ctx, "Unlock", time.Now(), &err, "ref", ref, "lock", lock)
+	return s.FS.Unlock(ctx, ref, lock)
+}
+
+func (s *fs) ListStorageSpaces(ctx context.Context, filter []*provider.ListStorageSpacesRequest_Filter) (spaces []*provider.StorageSpace, err error) {
+	defer s.observe(ctx, "ListStorageSpaces", time.Now(), &err, "filter", filter)
+	return s.FS.ListStorageSpaces(ctx, filter)
+}
+
+func (s *fs) CreateStorageSpace(ctx context.Context, req *provider.CreateStorageSpaceRequest) (res *provider.CreateStorageSpaceResponse, err error) {
+	defer s.observe(ctx, "CreateStorageSpace", time.Now(), &err, "request", req)
+	return s.FS.CreateStorageSpace(ctx, req)
+}
+
+func (s *fs) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorageSpaceRequest) (res *provider.UpdateStorageSpaceResponse, err error) {
+	defer s.observe(ctx, "UpdateStorageSpace", time.Now(), &err, "request", req)
+	return s.FS.UpdateStorageSpace(ctx, req)
+}
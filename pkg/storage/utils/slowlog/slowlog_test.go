@@ -0,0 +1,106 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package slowlog
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+// sleepyFS takes the given time to stat a resource and fails to delete it.
+type sleepyFS struct {
+	storage.FS
+	delay time.Duration
+}
+
+func (s *sleepyFS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	time.Sleep(s.delay)
+	return &provider.ResourceInfo{Path: ref.Path}, nil
+}
+
+func (s *sleepyFS) Delete(ctx context.Context, ref *provider.Reference) error {
+	time.Sleep(s.delay)
+	return errtypes.PermissionDenied(ref.Path)
+}
+
+func TestSlowLog(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "slow.log")
+	next := &sleepyFS{delay: 20 * time.Millisecond}
+	fs, err := New(next, &Config{Threshold: 10, Output: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := ctxpkg.ContextSetUser(context.Background(), &userpb.User{Id: &userpb.UserId{OpaqueId: "einstein"}})
+	ctx = ctxpkg.ContextSetRequestID(ctx, "req-1")
+	ref := &provider.Reference{Path: "/slow"}
+	if _, err := fs.GetMD(ctx, ref, []string{"key"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Delete(ctx, ref); err == nil {
+		t.Fatal("expected the error of the driver")
+	}
+
+	// fast calls are not logged
+	next.delay = 0
+	if _, err := fs.GetMD(ctx, ref, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 slow calls, got %d: %s", len(lines), data)
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["op"] != "GetMD" || entry["user"] != "einstein" || entry["request_id"] != "req-1" {
+		t.Errorf("unexpected entry %v", entry)
+	}
+	if !strings.Contains(lines[0], `"/slow"`) || !strings.Contains(lines[0], `"key"`) {
+		t.Errorf("expected the arguments to be logged: %s", lines[0])
+	}
+	if stack, _ := entry["stack"].(string); !strings.Contains(stack, "TestSlowLog") {
+		t.Errorf("expected the stack of the caller to be logged: %v", entry["stack"])
+	}
+
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["op"] != "Delete" || entry["error"] == nil {
+		t.Errorf("expected the failed delete to be logged with its error, got %v", entry)
+	}
+}
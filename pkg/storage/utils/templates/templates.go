@@ -41,6 +41,8 @@ import (
 type UserData struct {
 	*userpb.User
 	Email EmailData
+	// Vars contains additional placeholders, e.g. {{.Vars.project}}
+	Vars map[string]string
 }
 
 // EmailData contains mail data
@@ -53,8 +55,15 @@ type EmailData struct {
 
 // WithUser generates a layout based on user data.
 func WithUser(u *userpb.User, tpl string) string {
+	return WithUserAndVars(u, tpl, nil)
+}
+
+// WithUserAndVars generates a layout based on user data and additional
+// variables, which are accessible using {{.Vars.<name>}}.
+func WithUserAndVars(u *userpb.User, tpl string, vars map[string]string) string {
 	tpl = clean(tpl)
 	ut := newUserData(u)
+	ut.Vars = vars
 	// compile given template tpl
 	t, err := template.New("tpl").Funcs(sprig.TxtFuncMap()).Parse(tpl)
 	if err != nil {
@@ -69,6 +78,12 @@ func WithUser(u *userpb.User, tpl string) string {
 	return b.String()
 }
 
+// Validate checks whether the given template can be parsed.
+func Validate(tpl string) error {
+	_, err := template.New("tpl").Funcs(sprig.TxtFuncMap()).Parse(clean(tpl))
+	return err
+}
+
 func newUserData(u *userpb.User) *UserData {
 	usernameSplit := strings.Split(u.Username, "@")
 	if len(usernameSplit) == 1 {
@@ -101,6 +101,26 @@ func TestLayout(t *testing.T) {
 	}
 }
 
+func TestLayoutWithVars(t *testing.T) {
+	user := &userpb.User{
+		Username: "alabasta",
+	}
+	vars := map[string]string{"project": "cernbox"}
+	got := WithUserAndVars(user, "/eos/project/{{substr 0 1 .Vars.project}}/{{.Vars.project}}", vars)
+	if expected := "/eos/project/c/cernbox"; expected != got {
+		t.Fatal("expected: " + expected + " got: " + got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate("{{substr 0 1 .Username}}/{{.Username}}"); err != nil {
+		t.Fatalf("expected valid template, got error: %v", err)
+	}
+	if err := Validate("{{ bad layout syntax"); err == nil {
+		t.Fatal("expected an error for an invalid template")
+	}
+}
+
 func TestLayoutPanic(t *testing.T) {
 	assertPanic(t, testBadLayout)
 }
@@ -0,0 +1,305 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package throttle
+
+import (
+	"context"
+	"io"
+	"net/url"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/deletejob"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/composable"
+)
+
+type fs struct {
+	storage.FS
+	limits *limits
+}
+
+// New returns a storage.FS limiting the rate of the calls to the given one.
+// The content transferred through the data provider is limited by Handler.
+func New(next storage.FS, c *Config) (storage.FS, error) {
+	s := &fs{
+		FS:     next,
+		limits: limitsOf(c),
+	}
+	return composable.Wrap(s, next), nil
+}
+
+func (s *fs) GetHome(ctx context.Context) (string, error) {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return "", err
+	}
+	return s.FS.GetHome(ctx)
+}
+
+func (s *fs) CreateHome(ctx context.Context) error {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return err
+	}
+	return s.FS.CreateHome(ctx)
+}
+
+func (s *fs) CreateDir(ctx context.Context, ref *provider.Reference) error {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return err
+	}
+	return s.FS.CreateDir(ctx, ref)
+}
+
+func (s *fs) TouchFile(ctx context.Context, ref *provider.Reference) error {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return err
+	}
+	return s.FS.TouchFile(ctx, ref)
+}
+
+func (s *fs) Delete(ctx context.Context, ref *provider.Reference) error {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return err
+	}
+	return s.FS.Delete(ctx, ref)
+}
+
+// DeleteRecursive counts as a single interactive operation, like Delete.
+func (s *fs) DeleteRecursive(ctx context.Context, ref *provider.Reference, progress func(total, deleted uint64)) error {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return err
+	}
+	return deletejob.DeleteRecursive(ctx, s.FS, ref, progress)
+}
+
+func (s *fs) Move(ctx context.Context, oldRef, newRef *provider.Reference) error {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return err
+	}
+	return s.FS.Move(ctx, oldRef, newRef)
+}
+
+func (s *fs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return nil, err
+	}
+	return s.FS.GetMD(ctx, ref, mdKeys)
+}
+
+func (s *fs) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return nil, err
+	}
+	return s.FS.ListFolder(ctx, ref, mdKeys)
+}
+
+func (s *fs) InitiateUpload(ctx context.Context, ref *provider.Reference, uploadLength int64, metadata map[string]string) (map[string]string, error) {
+	if err := s.limits.op(ctx, Bulk); err != nil {
+		return nil, err
+	}
+	return s.FS.InitiateUpload(ctx, ref, uploadLength, metadata)
+}
+
+func (s *fs) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	if err := s.limits.op(ctx, Bulk); err != nil {
+		return err
+	}
+	return s.FS.Upload(ctx, ref, r)
+}
+
+func (s *fs) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	if err := s.limits.op(ctx, Bulk); err != nil {
+		return nil, err
+	}
+	return s.FS.Download(ctx, ref)
+}
+
+func (s *fs) ListRevisions(ctx context.Context, ref *provider.Reference) ([]*provider.FileVersion, error) {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return nil, err
+	}
+	return s.FS.ListRevisions(ctx, ref)
+}
+
+func (s *fs) DownloadRevision(ctx context.Context, ref *provider.Reference, key string) (io.ReadCloser, error) {
+	if err := s.limits.op(ctx, Bulk); err != nil {
+		return nil, err
+	}
+	return s.FS.DownloadRevision(ctx, ref, key)
+}
+
+func (s *fs) RestoreRevision(ctx context.Context, ref *provider.Reference, key string) error {
+	if err := s.limits.op(ctx, Bulk); err != nil {
+		return err
+	}
+	return s.FS.RestoreRevision(ctx, ref, key)
+}
+
+func (s *fs) ListRecycle(ctx context.Context, basePath, key, relativePath string) ([]*provider.RecycleItem, error) {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return nil, err
+	}
+	return s.FS.ListRecycle(ctx, basePath, key, relativePath)
+}
+
+func (s *fs) RestoreRecycleItem(ctx context.Context, basePath, key, relativePath string, restoreRef *provider.Reference) error {
+	if err := s.limits.op(ctx, Bulk); err != nil {
+		return err
+	}
+	return s.FS.RestoreRecycleItem(ctx, basePath, key, relativePath, restoreRef)
+}
+
+func (s *fs) PurgeRecycleItem(ctx context.Context, basePath, key, relativePath string) error {
+	if err := s.limits.op(ctx, Bulk); err != nil {
+		return err
+	}
+	return s.FS.PurgeRecycleItem(ctx, basePath, key, relativePath)
+}
+
+func (s *fs) EmptyRecycle(ctx context.Context) error {
+	if err := s.limits.op(ctx, Bulk); err != nil {
+		return err
+	}
+	return s.FS.EmptyRecycle(ctx)
+}
+
+func (s *fs) GetPathByID(ctx context.Context, id *provider.ResourceId) (string, error) {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return "", err
+	}
+	return s.FS.GetPathByID(ctx, id)
+}
+
+func (s *fs) AddGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return err
+	}
+	return s.FS.AddGrant(ctx, ref, g)
+}
+
+func (s *fs) DenyGrant(ctx context.Context, ref *provider.Reference, g *provider.Grantee) error {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return err
+	}
+	return s.FS.DenyGrant(ctx, ref, g)
+}
+
+func (s *fs) RemoveGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return err
+	}
+	return s.FS.RemoveGrant(ctx, ref, g)
+}
+
+func (s *fs) UpdateGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return err
+	}
+	return s.FS.UpdateGrant(ctx, ref, g)
+}
+
+func (s *fs) ListGrants(ctx context.Context, ref *provider.Reference) ([]*provider.Grant, error) {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return nil, err
+	}
+	return s.FS.ListGrants(ctx, ref)
+}
+
+func (s *fs) GetQuota(ctx context.Context, ref *provider.Reference) (uint64, uint64, error) {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return 0, 0, err
+	}
+	return s.FS.GetQuota(ctx, ref)
+}
+
+func (s *fs) GetQuotaInfo(ctx context.Context, ref *provider.Reference) (*storage.Quota, error) {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return nil, err
+	}
+	return storage.QuotaOf(ctx, s.FS, ref)
+}
+
+func (s *fs) CreateReference(ctx context.Context, path string, targetURI *url.URL) error {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return err
+	}
+	return s.FS.CreateReference(ctx, path, targetURI)
+}
+
+func (s *fs) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return err
+	}
+	return s.FS.SetArbitraryMetadata(ctx, ref, md)
+}
+
+func (s *fs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return err
+	}
+	return s.FS.UnsetArbitraryMetadata(ctx, ref, keys)
+}
+
+func (s *fs) SetLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return err
+	}
+	return s.FS.SetLock(ctx, ref, lock)
+}
+
+func (s *fs) GetLock(ctx context.Context, ref *provider.Reference) (*provider.Lock, error) {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return nil, err
+	}
+	return s.FS.GetLock(ctx, ref)
+}
+
+func (s *fs) RefreshLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return err
+	}
+	return s.FS.RefreshLock(ctx, ref, lock)
+}
+
+func (s *fs) Unlock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return err
+	}
+	return s.FS.Unlock(ctx, ref, lock)
+}
+
+func (s *fs) ListStorageSpaces(ctx context.Context, filter []*provider.ListStorageSpacesRequest_Filter) ([]*provider.StorageSpace, error) {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return nil, err
+	}
+	return s.FS.ListStorageSpaces(ctx, filter)
+}
+
+func (s *fs) CreateStorageSpace(ctx context.Context, req *provider.CreateStorageSpaceRequest) (*provider.CreateStorageSpaceResponse, error) {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return nil, err
+	}
+	return s.FS.CreateStorageSpace(ctx, req)
+}
+
+func (s *fs) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorageSpaceRequest) (*provider.UpdateStorageSpaceResponse, error) {
+	if err := s.limits.op(ctx, Interactive); err != nil {
+		return nil, err
+	}
+	return s.FS.UpdateStorageSpace(ctx, req)
+}
@@ -0,0 +1,297 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package throttle limits the rate of the operations and the bandwidth of the
+// transfers of a storage mount, so that a few heavy users can't saturate a
+// shared backend. The interactive operations, like stat and list, have a share
+// of the rate the bulk ones can't use, so they stay responsive during transfers.
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Config configures the limits of a storage mount.
+type Config struct {
+	// Mount names the limits, so that the services of the same mount running in
+	// one process share them.
+	Mount     string `mapstructure:"mount" docs:";Name under which the limits are shared by the services of a mount. Defaults to the mount id if set."`
+	IOPS      int    `mapstructure:"iops" docs:"0;Operations per second sent to the driver. 0 means unlimited."`
+	Bandwidth int    `mapstructure:"bandwidth" docs:"0;MB/s of the uploaded and downloaded content. 0 means unlimited."`
+	Reserved  int    `mapstructure:"reserved" docs:"20;Percentage of the operations per second only the interactive operations can use."`
+}
+
+// Enabled returns whether the configuration requires throttling the mount.
+func (c *Config) Enabled() bool {
+	return c.IOPS > 0 || c.Bandwidth > 0
+}
+
+func (c *Config) init() {
+	if c.Reserved <= 0 {
+		c.Reserved = 20
+	}
+	if c.Reserved > 90 {
+		c.Reserved = 90
+	}
+}
+
+// Class is the priority class of an operation.
+type Class int
+
+const (
+	// Interactive operations are the ones users wait for, like stat and list.
+	Interactive Class = iota
+	// Bulk operations move the content of the files.
+	Bulk
+)
+
+type classKey struct{}
+
+// ContextWithClass overrides the priority class of the operations run with
+// the returned context.
+func ContextWithClass(ctx context.Context, c Class) context.Context {
+	return context.WithValue(ctx, classKey{}, c)
+}
+
+func classOf(ctx context.Context, def Class) Class {
+	if c, ok := ctx.Value(classKey{}).(Class); ok {
+		return c
+	}
+	return def
+}
+
+// bucket is a token bucket holding one second of its rate. The bulk
+// operations can't take the tokens kept in reserve for the interactive ones.
+type bucket struct {
+	rate    float64
+	burst   float64
+	reserve float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate float64, reserved int) *bucket {
+	return &bucket{
+		rate:    rate,
+		burst:   rate,
+		reserve: rate * float64(reserved) / 100,
+		tokens:  rate,
+		last:    time.Now(),
+	}
+}
+
+// wait blocks until n tokens are available to the class and takes them.
+func (b *bucket) wait(ctx context.Context, n float64, c Class) error {
+	for {
+		d := b.take(n, c)
+		if d == 0 {
+			return nil
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// take takes n tokens if available to the class, otherwise it returns how
+// long it takes for them to be.
+func (b *bucket) take(n float64, c Class) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	floor := 0.0
+	if c == Bulk {
+		floor = b.reserve
+	}
+	// more than the bucket holds is granted once it is full
+	n = math.Min(n, b.burst-floor)
+	if b.tokens-n >= floor {
+		b.tokens -= n
+		return 0
+	}
+	d := time.Duration((n + floor - b.tokens) / b.rate * float64(time.Second))
+	if d <= 0 {
+		d = time.Millisecond
+	}
+	return d
+}
+
+// limits are the buckets of a mount, nil if not limited.
+type limits struct {
+	ops   *bucket
+	bytes *bucket
+}
+
+var (
+	mu     sync.Mutex
+	mounts = map[string]*limits{}
+)
+
+// limitsOf returns the limits of the mount, shared by the configurations
+// naming the same mount with the same limits.
+func limitsOf(c *Config) *limits {
+	c.init()
+	l := &limits{}
+	if c.IOPS > 0 {
+		l.ops = newBucket(float64(c.IOPS), c.Reserved)
+	}
+	if c.Bandwidth > 0 {
+		// the bandwidth is only used by bulk transfers, nothing is reserved
+		l.bytes = newBucket(float64(c.Bandwidth)*1024*1024, 0)
+	}
+	if c.Mount == "" {
+		return l
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	key := fmt.Sprintf("%s|%d|%d|%d", c.Mount, c.IOPS, c.Bandwidth, c.Reserved)
+	if shared, ok := mounts[key]; ok {
+		return shared
+	}
+	mounts[key] = l
+	return l
+}
+
+// op waits for the rate of the operations to allow one more.
+func (l *limits) op(ctx context.Context, def Class) error {
+	if l.ops == nil {
+		return nil
+	}
+	return l.ops.wait(ctx, 1, classOf(ctx, def))
+}
+
+// Limiter limits the rate of a background task, e.g. a consistency check,
+// independently of the limits of the mount.
+type Limiter struct {
+	b *bucket
+}
+
+// NewLimiter returns a limiter allowing rate events per second. The nil
+// limiter returned when the rate is not positive never blocks.
+func NewLimiter(rate float64) *Limiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &Limiter{b: newBucket(rate, 0)}
+}
+
+// Wait blocks until n more events are allowed.
+func (l *Limiter) Wait(ctx context.Context, n float64) error {
+	if l == nil {
+		return nil
+	}
+	return l.b.wait(ctx, n, Bulk)
+}
+
+// Reader limits the bytes per second read from r to the rate of the limiter.
+func (l *Limiter) Reader(ctx context.Context, r io.ReadCloser) io.ReadCloser {
+	if l == nil {
+		return r
+	}
+	return &reader{ReadCloser: r, ctx: ctx, b: l.b}
+}
+
+// Handler wraps the HTTP handler of the data transfers of a mount, limiting
+// the bandwidth of the uploaded and downloaded content.
+func Handler(c *Config, h http.Handler) http.Handler {
+	l := limitsOf(c)
+	if l.bytes == nil {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if r.Body != nil {
+			r.Body = &reader{ReadCloser: r.Body, ctx: ctx, b: l.bytes}
+		}
+		h.ServeHTTP(&writer{ResponseWriter: w, ctx: ctx, b: l.bytes}, r)
+	})
+}
+
+// chunk bounds the bytes transferred at once, so that a transfer waits for
+// its share of the bandwidth in small steps.
+const chunk = 32 * 1024
+
+type reader struct {
+	io.ReadCloser
+	ctx context.Context
+	b   *bucket
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if len(p) > chunk {
+		p = p[:chunk]
+	}
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		if werr := r.b.wait(r.ctx, float64(n), Bulk); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+type writer struct {
+	http.ResponseWriter
+	ctx context.Context
+	b   *bucket
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := len(p)
+		if n > chunk {
+			n = chunk
+		}
+		if err := w.b.wait(w.ctx, float64(n), Bulk); err != nil {
+			return written, err
+		}
+		m, err := w.ResponseWriter.Write(p[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Flush lets the wrapped handlers stream their responses.
+func (w *writer) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package throttle
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBucketReserve(t *testing.T) {
+	b := newBucket(10, 20)
+
+	for i := 0; i < 8; i++ {
+		if d := b.take(1, Bulk); d != 0 {
+			t.Fatalf("expected bulk operation %d to run right away, got a wait of %s", i, d)
+		}
+	}
+	if d := b.take(1, Bulk); d == 0 {
+		t.Fatal("expected the bulk operations not to take the reserve")
+	}
+	for i := 0; i < 2; i++ {
+		if d := b.take(1, Interactive); d != 0 {
+			t.Fatalf("expected interactive operation %d to use the reserve, got a wait of %s", i, d)
+		}
+	}
+	if d := b.take(1, Interactive); d == 0 {
+		t.Fatal("expected the bucket to be empty")
+	}
+}
+
+func TestBucketWaitCanceled(t *testing.T) {
+	b := newBucket(1, 20)
+	if err := b.wait(context.Background(), 1, Interactive); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.wait(ctx, 1, Interactive); err == nil {
+		t.Fatal("expected the wait to end with the context")
+	}
+}
+
+func TestContextClass(t *testing.T) {
+	ctx := ContextWithClass(context.Background(), Bulk)
+	if classOf(ctx, Interactive) != Bulk {
+		t.Error("expected the class of the context to override the default one")
+	}
+	if classOf(context.Background(), Interactive) != Interactive {
+		t.Error("expected the default class")
+	}
+}
+
+func TestLimitsShared(t *testing.T) {
+	c := Config{Mount: "shared", IOPS: 100}
+	a, b := c, c
+	if limitsOf(&a) != limitsOf(&b) {
+		t.Error("expected the limits of a mount to be shared")
+	}
+	other := Config{Mount: "other", IOPS: 100}
+	if limitsOf(&other) == limitsOf(&a) {
+		t.Error("expected the mounts to have their own limits")
+	}
+	unnamed, unnamed2 := Config{IOPS: 100}, Config{IOPS: 100}
+	if limitsOf(&unnamed) == limitsOf(&unnamed2) {
+		t.Error("expected unnamed limits not to be shared")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), 3*chunk)
+	h := Handler(&Config{Bandwidth: 1}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write(data)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/file", bytes.NewReader(body)))
+	if !bytes.Equal(w.Body.Bytes(), body) {
+		t.Errorf("expected the content to be transferred unchanged, got %d bytes", w.Body.Len())
+	}
+
+	if _, ok := Handler(&Config{IOPS: 10}, nopHandler{}).(nopHandler); !ok {
+		t.Error("expected the handler not to be wrapped without a bandwidth limit")
+	}
+}
+
+type nopHandler struct{}
+
+func (nopHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {}
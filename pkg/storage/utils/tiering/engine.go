@@ -0,0 +1,265 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package tiering
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	tx "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/lease"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// pollInterval is the time between two checks of the status of a recall.
+const pollInterval = time.Second
+
+// migration is a running copy of a cold file to the target remote.
+type migration struct {
+	txID     string
+	ref      *provider.Reference
+	location string
+	size     uint64
+	mtime    uint64
+}
+
+func (t *fs) run() {
+	ticker := time.NewTicker(time.Duration(t.c.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.quit:
+			return
+		case <-ticker.C:
+			// Replicas serving the same storage must not migrate the same files
+			_, _ = lease.Run(context.Background(), "tiering:"+strings.Join(t.c.Roots, ","), func(ctx context.Context, _ int64) error {
+				t.scan(ctx)
+				return nil
+			})
+		}
+	}
+}
+
+// scan stubs the files whose migration completed and starts migrating the
+// files which became cold since the last scan.
+func (t *fs) scan(ctx context.Context) {
+	log := appctx.GetLogger(ctx).With().Str("pkg", "tiering").Logger()
+	t.completeMigrations(ctx, log)
+
+	coldBefore := time.Now().Add(-time.Duration(t.c.ColdAfter) * 24 * time.Hour)
+	for _, root := range t.c.Roots {
+		t.walk(ctx, log, &provider.Reference{Path: root}, coldBefore)
+	}
+}
+
+func (t *fs) walk(ctx context.Context, log zerolog.Logger, ref *provider.Reference, coldBefore time.Time) {
+	infos, err := t.FS.ListFolder(ctx, ref, []string{})
+	if err != nil {
+		log.Error().Err(err).Interface("ref", ref).Msg("error listing folder")
+		return
+	}
+	for _, info := range infos {
+		if info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			t.walk(ctx, log, &provider.Reference{Path: info.Path}, coldBefore)
+			continue
+		}
+		if info.Type != provider.ResourceType_RESOURCE_TYPE_FILE || !t.isCold(info, coldBefore) {
+			continue
+		}
+		if err := t.migrate(ctx, info); err != nil {
+			log.Error().Err(err).Str("path", info.Path).Msg("error migrating cold file")
+		}
+	}
+}
+
+// isCold returns whether a file has neither been modified nor accessed since
+// the given time and is not already stubbed or being migrated.
+func (t *fs) isCold(info *provider.ResourceInfo, coldBefore time.Time) bool {
+	if _, ok := stubLocation(info); ok || info.Size == 0 || info.Size < t.c.MinSize {
+		return false
+	}
+	if info.Mtime != nil && int64(info.Mtime.Seconds) >= coldBefore.Unix() {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.pending[key(info.Id)]; ok {
+		return false
+	}
+	accessed, ok := t.accessed[key(info.Id)]
+	return !ok || accessed.Before(coldBefore)
+}
+
+func (t *fs) migrate(ctx context.Context, info *provider.ResourceInfo) error {
+	location := path.Join(t.c.TargetPath, info.Id.GetStorageId(), info.Id.GetOpaqueId())
+	txInfo, err := t.tx.StartTransfer(ctx, t.c.SourceRemote, info.Path, t.c.SourceToken, t.c.TargetRemote, location, t.c.TargetToken)
+	if err != nil {
+		return errors.Wrap(err, "tiering: error starting transfer")
+	}
+
+	t.mu.Lock()
+	t.pending[key(info.Id)] = &migration{
+		txID:     txInfo.GetId().GetOpaqueId(),
+		ref:      &provider.Reference{Path: info.Path},
+		location: location,
+		size:     info.Size,
+		mtime:    info.GetMtime().GetSeconds(),
+	}
+	t.mu.Unlock()
+	return nil
+}
+
+// completeMigrations replaces the files whose content has been copied by
+// stubs, unless they have been modified in the meantime.
+func (t *fs) completeMigrations(ctx context.Context, log zerolog.Logger) {
+	t.mu.Lock()
+	pending := make(map[string]*migration, len(t.pending))
+	for k, m := range t.pending {
+		pending[k] = m
+	}
+	t.mu.Unlock()
+
+	for k, m := range pending {
+		done, err := t.transferDone(ctx, m.txID)
+		if err == nil && !done {
+			continue
+		}
+		if err == nil {
+			err = t.stub(ctx, m)
+		}
+		if err != nil {
+			log.Error().Err(err).Str("location", m.location).Msg("migration failed")
+		}
+		t.mu.Lock()
+		delete(t.pending, k)
+		t.mu.Unlock()
+	}
+}
+
+func (t *fs) stub(ctx context.Context, m *migration) error {
+	info, err := t.FS.GetMD(ctx, m.ref, stubKeys)
+	if err != nil {
+		return err
+	}
+	if info.GetMtime().GetSeconds() != m.mtime || info.Size != m.size {
+		return errors.New("tiering: file modified during migration")
+	}
+
+	if err := t.FS.Upload(ctx, m.ref, ioutil.NopCloser(bytes.NewReader(nil))); err != nil {
+		return errors.Wrap(err, "tiering: error truncating file")
+	}
+	return t.FS.SetArbitraryMetadata(ctx, m.ref, &provider.ArbitraryMetadata{
+		Metadata: map[string]string{
+			LocationKey: m.location,
+			SizeKey:     strconv.FormatUint(m.size, 10),
+			MtimeKey:    strconv.FormatUint(m.mtime, 10),
+		},
+	})
+}
+
+// recall copies the content of a stub back, waiting at most the configured
+// timeout. Concurrent downloads of the same stub wait for the same recall.
+func (t *fs) recall(ctx context.Context, info *provider.ResourceInfo, location string) error {
+	k := key(info.Id)
+	t.mu.Lock()
+	done, running := t.recalls[k]
+	if !running {
+		done = make(chan struct{})
+		t.recalls[k] = done
+	}
+	t.mu.Unlock()
+
+	if !running {
+		// the recall outlives the request, as clients retry after a timeout
+		go t.doRecall(context.Background(), info, location, done)
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Duration(t.c.RecallTimeout) * time.Second):
+		return errtypes.InternalError("tiering: recall of " + info.Path + " in progress, retry later")
+	}
+}
+
+func (t *fs) doRecall(ctx context.Context, info *provider.ResourceInfo, location string, done chan struct{}) {
+	log := appctx.GetLogger(ctx).With().Str("pkg", "tiering").Str("path", info.Path).Logger()
+	defer func() {
+		t.mu.Lock()
+		delete(t.recalls, key(info.Id))
+		t.mu.Unlock()
+		close(done)
+	}()
+
+	txInfo, err := t.tx.StartTransfer(ctx, t.c.TargetRemote, location, t.c.TargetToken, t.c.SourceRemote, info.Path, t.c.SourceToken)
+	if err != nil {
+		log.Error().Err(err).Msg("error starting recall")
+		return
+	}
+	for {
+		finished, err := t.transferDone(ctx, txInfo.GetId().GetOpaqueId())
+		if err != nil {
+			log.Error().Err(err).Msg("recall failed")
+			return
+		}
+		if finished {
+			break
+		}
+		time.Sleep(pollInterval)
+	}
+
+	ref := &provider.Reference{Path: info.Path}
+	md := info.GetArbitraryMetadata().GetMetadata()
+	if mtime := md[MtimeKey]; mtime != "" {
+		if err := t.FS.SetArbitraryMetadata(ctx, ref, &provider.ArbitraryMetadata{Metadata: map[string]string{"mtime": mtime}}); err != nil {
+			log.Error().Err(err).Msg("error restoring mtime")
+		}
+	}
+	if err := t.FS.UnsetArbitraryMetadata(ctx, ref, stubKeys); err != nil {
+		log.Error().Err(err).Msg("error removing stub metadata")
+	}
+}
+
+// transferDone returns whether a transfer completed, or an error if it
+// will not complete.
+func (t *fs) transferDone(ctx context.Context, txID string) (bool, error) {
+	txInfo, err := t.tx.GetTransferStatus(ctx, txID)
+	if err != nil {
+		return false, err
+	}
+	switch txInfo.GetStatus() {
+	case tx.Status_STATUS_TRANSFER_COMPLETE:
+		return true, nil
+	case tx.Status_STATUS_TRANSFER_FAILED, tx.Status_STATUS_TRANSFER_CANCELLED, tx.Status_STATUS_TRANSFER_EXPIRED, tx.Status_STATUS_INVALID:
+		return false, errors.Errorf("tiering: transfer %s ended with status %s", txID, txInfo.GetStatus())
+	default:
+		return false, nil
+	}
+}
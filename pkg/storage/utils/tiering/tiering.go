@@ -0,0 +1,235 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package tiering wraps a storage driver to move files which have not been
+// accessed for a while to a cheaper storage, e.g. a mount backed by S3
+// Glacier. The content is copied with a datatx job and the file is replaced
+// by an empty stub remembering where the content went. Downloading a stub
+// transparently recalls the content first.
+package tiering
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/datatx"
+	txregistry "github.com/cs3org/reva/pkg/datatx/manager/registry"
+	"github.com/cs3org/reva/pkg/deletejob"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/composable"
+	"github.com/pkg/errors"
+)
+
+// Keys of the arbitrary metadata stored on stubs.
+const (
+	// LocationKey holds the path of the content in the target remote.
+	LocationKey = "reva.tiering.location"
+	// SizeKey holds the size of the content.
+	SizeKey = "reva.tiering.size"
+	// MtimeKey holds the modification time of the content.
+	MtimeKey = "reva.tiering.mtime"
+)
+
+var stubKeys = []string{LocationKey, SizeKey, MtimeKey}
+
+// Config configures the tiering policy.
+type Config struct {
+	ColdAfter     int                               `mapstructure:"cold_after" docs:"0;The number of days after which files that have not been accessed are moved. 0 disables tiering."`
+	Interval      int                               `mapstructure:"interval" docs:"86400;The number of seconds between two scans for cold files."`
+	Roots         []string                          `mapstructure:"roots" docs:"[/];The folders scanned for cold files."`
+	MinSize       uint64                            `mapstructure:"min_size" docs:"0;The minimum size of the files to move, in bytes."`
+	SourceRemote  string                            `mapstructure:"source_remote" docs:";The WebDAV endpoint exposing the root of this storage to the datatx driver."`
+	SourceToken   string                            `mapstructure:"source_token" docs:";The token used by the datatx driver to access the source remote."`
+	TargetRemote  string                            `mapstructure:"target_remote" docs:";The WebDAV endpoint of the cheaper storage."`
+	TargetPath    string                            `mapstructure:"target_path" docs:"/;The folder of the cheaper storage receiving the files."`
+	TargetToken   string                            `mapstructure:"target_token" docs:";The token used by the datatx driver to access the target remote."`
+	RecallTimeout int                               `mapstructure:"recall_timeout" docs:"60;The number of seconds a download waits for the recall of a file before failing."`
+	TxDriver      string                            `mapstructure:"txdriver" docs:"rclone;The datatx driver moving the files."`
+	TxDrivers     map[string]map[string]interface{} `mapstructure:"txdrivers" docs:"url:pkg/datatx/manager/rclone/rclone.go;The configuration of the datatx drivers."`
+}
+
+// Init sets the defaults.
+func (c *Config) Init() {
+	if c.Interval == 0 {
+		c.Interval = 86400
+	}
+	if len(c.Roots) == 0 {
+		c.Roots = []string{"/"}
+	}
+	if c.TargetPath == "" {
+		c.TargetPath = "/"
+	}
+	if c.RecallTimeout == 0 {
+		c.RecallTimeout = 60
+	}
+	if c.TxDriver == "" {
+		c.TxDriver = "rclone"
+	}
+}
+
+// Enabled returns whether the files are moved to the cold storage at all,
+// i.e. whether they get cold after some time.
+func (c *Config) Enabled() bool {
+	return c.ColdAfter > 0
+}
+
+type fs struct {
+	storage.FS
+	c  *Config
+	tx datatx.Manager
+
+	mu       sync.Mutex
+	accessed map[string]time.Time     // last access of files by id
+	pending  map[string]*migration    // running migrations by id
+	recalls  map[string]chan struct{} // running recalls by id, closed when done
+	quit     chan struct{}
+}
+
+// New returns a storage.FS moving the cold files of the given one and starts
+// the periodic scan for them, which is stopped on Shutdown.
+func New(next storage.FS, c *Config) (storage.FS, error) {
+	c.Init()
+	if c.SourceRemote == "" || c.TargetRemote == "" {
+		return nil, errors.New("tiering: source_remote and target_remote must be configured")
+	}
+	f, ok := txregistry.NewFuncs[c.TxDriver]
+	if !ok {
+		return nil, fmt.Errorf("tiering: datatx driver not found: %s", c.TxDriver)
+	}
+	tx, err := f(c.TxDrivers[c.TxDriver])
+	if err != nil {
+		return nil, errors.Wrap(err, "tiering: error creating datatx driver")
+	}
+
+	t := &fs{
+		FS:       next,
+		c:        c,
+		tx:       tx,
+		accessed: map[string]time.Time{},
+		pending:  map[string]*migration{},
+		recalls:  map[string]chan struct{}{},
+		quit:     make(chan struct{}),
+	}
+	go t.run()
+
+	return composable.Wrap(t, next), nil
+}
+
+// DeleteRecursive is forwarded to the driver, the stubs of the tree are deleted like any file.
+func (t *fs) DeleteRecursive(ctx context.Context, ref *provider.Reference, progress func(total, deleted uint64)) error {
+	return deletejob.DeleteRecursive(ctx, t.FS, ref, progress)
+}
+
+// GetMD reports the size and modification time of the content of stubs.
+func (t *fs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	info, err := t.FS.GetMD(ctx, ref, withStubKeys(mdKeys))
+	if err != nil {
+		return nil, err
+	}
+	presentStub(info)
+	return info, nil
+}
+
+// ListFolder reports the size and modification time of the content of stubs.
+func (t *fs) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	infos, err := t.FS.ListFolder(ctx, ref, withStubKeys(mdKeys))
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		presentStub(info)
+	}
+	return infos, nil
+}
+
+// Download records the access and recalls the content of stubs.
+func (t *fs) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	info, err := t.FS.GetMD(ctx, ref, stubKeys)
+	if err != nil {
+		return nil, err
+	}
+	t.touch(info)
+
+	if location, ok := stubLocation(info); ok {
+		if err := t.recall(ctx, info, location); err != nil {
+			return nil, err
+		}
+	}
+	return t.FS.Download(ctx, ref)
+}
+
+// Shutdown stops the scan for cold files.
+func (t *fs) Shutdown(ctx context.Context) error {
+	close(t.quit)
+	return t.FS.Shutdown(ctx)
+}
+
+func (t *fs) touch(info *provider.ResourceInfo) {
+	t.mu.Lock()
+	t.accessed[key(info.Id)] = time.Now()
+	t.mu.Unlock()
+}
+
+// withStubKeys adds the stub keys to a list of requested metadata keys. An
+// empty list already requests all metadata.
+func withStubKeys(mdKeys []string) []string {
+	if len(mdKeys) == 0 {
+		return mdKeys
+	}
+	return append(append([]string{}, mdKeys...), stubKeys...)
+}
+
+func stubLocation(info *provider.ResourceInfo) (string, bool) {
+	md := info.GetArbitraryMetadata().GetMetadata()
+	location, ok := md[LocationKey]
+	return location, ok && location != ""
+}
+
+// presentStub makes a stub look like the file it replaces.
+func presentStub(info *provider.ResourceInfo) {
+	if _, ok := stubLocation(info); !ok {
+		return
+	}
+	md := info.ArbitraryMetadata.Metadata
+	if size, err := strconv.ParseUint(md[SizeKey], 10, 64); err == nil {
+		info.Size = size
+	}
+	if mtime, err := strconv.ParseUint(md[MtimeKey], 10, 64); err == nil {
+		info.Mtime = &types.Timestamp{Seconds: mtime}
+	}
+	if info.Opaque == nil {
+		info.Opaque = &types.Opaque{}
+	}
+	if info.Opaque.Map == nil {
+		info.Opaque.Map = map[string]*types.OpaqueEntry{}
+	}
+	info.Opaque.Map["tiering"] = &types.OpaqueEntry{Decoder: "plain", Value: []byte("cold")}
+	for _, k := range stubKeys {
+		delete(md, k)
+	}
+}
+
+func key(id *provider.ResourceId) string {
+	return id.GetStorageId() + "!" + id.GetOpaqueId()
+}
@@ -0,0 +1,88 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package tiering
+
+import (
+	"testing"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+func TestIsCold(t *testing.T) {
+	now := time.Now()
+	coldBefore := now.Add(-24 * time.Hour)
+	old := &types.Timestamp{Seconds: uint64(now.Add(-48 * time.Hour).Unix())}
+	recent := &types.Timestamp{Seconds: uint64(now.Unix())}
+
+	f := &fs{
+		c:        &Config{MinSize: 10},
+		accessed: map[string]time.Time{"s!accessed": now},
+		pending:  map[string]*migration{"s!pending": {}},
+	}
+
+	tests := []struct {
+		name string
+		info *provider.ResourceInfo
+		want bool
+	}{
+		{"cold", &provider.ResourceInfo{Id: &provider.ResourceId{StorageId: "s", OpaqueId: "cold"}, Size: 100, Mtime: old}, true},
+		{"modified", &provider.ResourceInfo{Id: &provider.ResourceId{StorageId: "s", OpaqueId: "modified"}, Size: 100, Mtime: recent}, false},
+		{"accessed", &provider.ResourceInfo{Id: &provider.ResourceId{StorageId: "s", OpaqueId: "accessed"}, Size: 100, Mtime: old}, false},
+		{"pending", &provider.ResourceInfo{Id: &provider.ResourceId{StorageId: "s", OpaqueId: "pending"}, Size: 100, Mtime: old}, false},
+		{"small", &provider.ResourceInfo{Id: &provider.ResourceId{StorageId: "s", OpaqueId: "small"}, Size: 5, Mtime: old}, false},
+		{"stub", &provider.ResourceInfo{
+			Id:                &provider.ResourceId{StorageId: "s", OpaqueId: "stub"},
+			Size:              100,
+			Mtime:             old,
+			ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: map[string]string{LocationKey: "/glacier/s/stub"}},
+		}, false},
+	}
+	for _, tt := range tests {
+		if got := f.isCold(tt.info, coldBefore); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestPresentStub(t *testing.T) {
+	info := &provider.ResourceInfo{
+		ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: map[string]string{
+			LocationKey: "/glacier/s/1",
+			SizeKey:     "1024",
+			MtimeKey:    "1600000000",
+			"other":     "kept",
+		}},
+	}
+	presentStub(info)
+
+	if info.Size != 1024 || info.Mtime.Seconds != 1600000000 {
+		t.Errorf("unexpected size %d or mtime %d", info.Size, info.Mtime.Seconds)
+	}
+	if string(info.Opaque.Map["tiering"].Value) != "cold" {
+		t.Error("stubs must be flagged as cold")
+	}
+	if _, ok := info.ArbitraryMetadata.Metadata[LocationKey]; ok {
+		t.Error("stub metadata must not be exposed")
+	}
+	if info.ArbitraryMetadata.Metadata["other"] != "kept" {
+		t.Error("other metadata must be kept")
+	}
+}
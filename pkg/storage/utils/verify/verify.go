@@ -0,0 +1,206 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package verify recomputes the checksums of files on demand and compares
+// them to the stored ones and to the ones supplied by the clients, so that
+// the data stewards can verify the integrity of the data kept in the long
+// term.
+package verify
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/adler32"
+	"io"
+	"strings"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/throttle"
+)
+
+// The verdicts of a verification.
+const (
+	// VerdictOK is a content matching all the checksums it was compared to.
+	VerdictOK = "ok"
+	// VerdictMismatch is a content not matching one of the checksums.
+	VerdictMismatch = "mismatch"
+	// VerdictUnverified is a content there was no checksum to compare to.
+	VerdictUnverified = "unverified"
+)
+
+// ProgressInterval is the minimum interval between two progress reports.
+var ProgressInterval = time.Second
+
+var algorithms = map[string]struct {
+	t   provider.ResourceChecksumType
+	new func() hash.Hash
+}{
+	"md5":     {provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_MD5, md5.New},
+	"sha1":    {provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_SHA1, sha1.New},
+	"adler32": {provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_ADLER32, func() hash.Hash { return adler32.New() }},
+}
+
+// Result is the outcome of the verification of a file.
+type Result struct {
+	Path          string
+	Algorithm     string
+	Computed      string
+	Stored        string
+	Expected      string
+	StoredMatch   bool
+	ExpectedMatch bool
+	Verdict       string
+	Bytes         uint64
+}
+
+// Progress is called with the amount of content read and the size of the
+// file while verifying it. Returning an error aborts the verification.
+type Progress func(read, size uint64) error
+
+// Verify reads the content of the referenced file, computes its checksum with
+// the given algorithm, or the one of the stored checksum if empty, and
+// compares it to the stored checksum and to the expected one if not empty.
+// The expected checksum may be prefixed with its algorithm, as in sha1:<sum>.
+func Verify(ctx context.Context, fs storage.FS, ref *provider.Reference, algorithm, expected string, progress Progress) (*Result, error) {
+	algorithm, expected, err := parseExpected(strings.ToLower(algorithm), expected)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := fs.GetMD(ctx, ref, nil)
+	if err != nil {
+		return nil, err
+	}
+	if info.Type != provider.ResourceType_RESOURCE_TYPE_FILE {
+		return nil, errtypes.BadRequest("verify: only the checksums of files can be verified")
+	}
+
+	stored := info.GetChecksum()
+	if algorithm == "" {
+		algorithm = nameOf(stored.GetType())
+		if stored.GetSum() == "" || algorithm == "" {
+			return nil, errtypes.BadRequest("verify: the file has no stored checksum, an algorithm is required")
+		}
+	}
+	algo, ok := algorithms[algorithm]
+	if !ok {
+		return nil, errtypes.BadRequest(fmt.Sprintf("verify: unsupported algorithm %q", algorithm))
+	}
+
+	// verifying must not slow down the users of a throttled mount
+	ctx = throttle.ContextWithClass(ctx, throttle.Bulk)
+	rc, err := fs.Download(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	h := algo.new()
+	pr := &progressReader{r: rc, size: info.Size, progress: progress, last: time.Now()}
+	n, err := io.Copy(h, pr)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Result{
+		Path:      info.Path,
+		Algorithm: algorithm,
+		Computed:  hex.EncodeToString(h.Sum(nil)),
+		Expected:  expected,
+		Bytes:     uint64(n),
+	}
+	if stored.GetType() == algo.t {
+		r.Stored = stored.GetSum()
+	}
+
+	compared := false
+	r.Verdict = VerdictOK
+	if r.Stored != "" {
+		compared = true
+		if r.StoredMatch = sameChecksum(r.Computed, r.Stored); !r.StoredMatch {
+			r.Verdict = VerdictMismatch
+		}
+	}
+	if r.Expected != "" {
+		compared = true
+		if r.ExpectedMatch = sameChecksum(r.Computed, r.Expected); !r.ExpectedMatch {
+			r.Verdict = VerdictMismatch
+		}
+	}
+	if !compared {
+		r.Verdict = VerdictUnverified
+	}
+	return r, nil
+}
+
+// parseExpected splits the algorithm prefix off the expected checksum.
+func parseExpected(algorithm, expected string) (string, string, error) {
+	i := strings.Index(expected, ":")
+	if i < 0 {
+		return algorithm, expected, nil
+	}
+	prefix := strings.ToLower(expected[:i])
+	if algorithm != "" && algorithm != prefix {
+		return "", "", errtypes.BadRequest(fmt.Sprintf("verify: the expected checksum is a %s one, not %s", prefix, algorithm))
+	}
+	return prefix, expected[i+1:], nil
+}
+
+func nameOf(t provider.ResourceChecksumType) string {
+	for name, algo := range algorithms {
+		if algo.t == t {
+			return name
+		}
+	}
+	return ""
+}
+
+// sameChecksum compares hex encoded checksums, which some drivers store
+// in upper case or without the leading zeros.
+func sameChecksum(a, b string) bool {
+	return strings.TrimLeft(strings.ToLower(a), "0") == strings.TrimLeft(strings.ToLower(b), "0")
+}
+
+// progressReader reports the progress of the reading at most once per
+// ProgressInterval.
+type progressReader struct {
+	r        io.Reader
+	size     uint64
+	read     uint64
+	progress Progress
+	last     time.Time
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += uint64(n)
+	if p.progress != nil && time.Since(p.last) >= ProgressInterval {
+		p.last = time.Now()
+		if perr := p.progress(p.read, p.size); perr != nil {
+			return n, perr
+		}
+	}
+	return n, err
+}
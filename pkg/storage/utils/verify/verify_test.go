@@ -0,0 +1,185 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+type testFS struct {
+	storage.FS
+	infos   map[string]*provider.ResourceInfo
+	content map[string][]byte
+}
+
+func newTestFS() *testFS {
+	t := &testFS{
+		infos: map[string]*provider.ResourceInfo{
+			"/dir": {Type: provider.ResourceType_RESOURCE_TYPE_CONTAINER, Path: "/dir"},
+		},
+		content: map[string][]byte{},
+	}
+	t.addFile("/hello.txt", "hello", &provider.ResourceChecksum{Type: provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_ADLER32, Sum: "62C0215"})
+	t.addFile("/corrupt.txt", "hellO", &provider.ResourceChecksum{Type: provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_ADLER32, Sum: "062c0215"})
+	t.addFile("/plain.txt", "hello", nil)
+	return t
+}
+
+func (t *testFS) addFile(p, content string, xs *provider.ResourceChecksum) {
+	t.content[p] = []byte(content)
+	t.infos[p] = &provider.ResourceInfo{
+		Type:     provider.ResourceType_RESOURCE_TYPE_FILE,
+		Path:     p,
+		Size:     uint64(len(content)),
+		Checksum: xs,
+	}
+}
+
+func (t *testFS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	info, ok := t.infos[ref.Path]
+	if !ok {
+		return nil, errtypes.NotFound(ref.Path)
+	}
+	return info, nil
+}
+
+func (t *testFS) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(t.content[ref.Path])), nil
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		algorithm string
+		expected  string
+		result    Result
+	}{
+		{
+			name:   "stored checksum",
+			path:   "/hello.txt",
+			result: Result{Path: "/hello.txt", Algorithm: "adler32", Computed: "062c0215", Stored: "62C0215", StoredMatch: true, Verdict: VerdictOK, Bytes: 5},
+		},
+		{
+			name:   "corrupted content",
+			path:   "/corrupt.txt",
+			result: Result{Path: "/corrupt.txt", Algorithm: "adler32", Computed: "060c01f5", Stored: "062c0215", Verdict: VerdictMismatch, Bytes: 5},
+		},
+		{
+			name:      "other algorithm than the stored one",
+			path:      "/hello.txt",
+			algorithm: "MD5",
+			result:    Result{Path: "/hello.txt", Algorithm: "md5", Computed: "5d41402abc4b2a76b9719d911017c592", Verdict: VerdictUnverified, Bytes: 5},
+		},
+		{
+			name:     "expected checksum with algorithm",
+			path:     "/plain.txt",
+			expected: "SHA1:aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d",
+			result:   Result{Path: "/plain.txt", Algorithm: "sha1", Computed: "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d", Expected: "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d", ExpectedMatch: true, Verdict: VerdictOK, Bytes: 5},
+		},
+		{
+			name:     "stored matching, expected not",
+			path:     "/hello.txt",
+			expected: "00000001",
+			result:   Result{Path: "/hello.txt", Algorithm: "adler32", Computed: "062c0215", Stored: "62C0215", Expected: "00000001", StoredMatch: true, Verdict: VerdictMismatch, Bytes: 5},
+		},
+	}
+
+	fs := newTestFS()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Verify(context.Background(), fs, &provider.Reference{Path: tt.path}, tt.algorithm, tt.expected, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *r != tt.result {
+				t.Errorf("got %+v, expected %+v", *r, tt.result)
+			}
+		})
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		algorithm string
+		expected  string
+	}{
+		{name: "folder", path: "/dir"},
+		{name: "no stored checksum", path: "/plain.txt"},
+		{name: "unsupported algorithm", path: "/hello.txt", algorithm: "crc32"},
+		{name: "conflicting algorithms", path: "/hello.txt", algorithm: "md5", expected: "sha1:aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"},
+	}
+
+	fs := newTestFS()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Verify(context.Background(), fs, &provider.Reference{Path: tt.path}, tt.algorithm, tt.expected, nil)
+			if _, ok := err.(errtypes.BadRequest); !ok {
+				t.Errorf("expected a bad request error, got %v", err)
+			}
+		})
+	}
+
+	if _, err := Verify(context.Background(), fs, &provider.Reference{Path: "/missing"}, "", "", nil); err == nil {
+		t.Error("expected an error verifying a missing file")
+	}
+}
+
+func TestVerifyProgress(t *testing.T) {
+	defer func(d time.Duration) { ProgressInterval = d }(ProgressInterval)
+	ProgressInterval = 0
+
+	fs := newTestFS()
+	fs.addFile("/big.bin", string(make([]byte, 100000)), nil)
+
+	var last, size uint64
+	_, err := Verify(context.Background(), fs, &provider.Reference{Path: "/big.bin"}, "md5", "", func(read, s uint64) error {
+		if read < last {
+			t.Errorf("progress went back from %d to %d", last, read)
+		}
+		last, size = read, s
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != 100000 || size != 100000 {
+		t.Errorf("expected the progress to reach 100000 of 100000, got %d of %d", last, size)
+	}
+
+	stop := errors.New("stop")
+	_, err = Verify(context.Background(), fs, &provider.Reference{Path: "/big.bin"}, "md5", "", func(read, s uint64) error {
+		return stop
+	})
+	if err != stop {
+		t.Errorf("expected the verification to be aborted, got %v", err)
+	}
+}
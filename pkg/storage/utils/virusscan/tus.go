@@ -0,0 +1,190 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package virusscan
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/pkg/errors"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+// wrap makes the tus data store of the driver scan the uploads before they
+// are finished. The extensions of the driver expect its own uploads, so they
+// are wrapped to unwrap the uploads handed to them.
+func (s *fs) wrap(composer *tusd.StoreComposer) {
+	st := &store{DataStore: composer.Core, fs: s}
+	if composer.UsesTerminater {
+		st.terminater = composer.Terminater
+		composer.UseTerminater(terminater{composer.Terminater})
+	}
+	if composer.UsesConcater {
+		composer.UseConcater(concater{ConcaterDataStore: composer.Concater, store: st})
+	}
+	if composer.UsesLengthDeferrer {
+		composer.UseLengthDeferrer(lengthDeferrer{composer.LengthDeferrer})
+	}
+	composer.UseCore(st)
+}
+
+type store struct {
+	tusd.DataStore
+	fs         *fs
+	terminater tusd.TerminaterDataStore
+}
+
+func (st *store) NewUpload(ctx context.Context, info tusd.FileInfo) (tusd.Upload, error) {
+	u, err := st.DataStore.NewUpload(ctx, info)
+	if err != nil {
+		return nil, err
+	}
+	return &upload{Upload: u, store: st}, nil
+}
+
+func (st *store) GetUpload(ctx context.Context, id string) (tusd.Upload, error) {
+	u, err := st.DataStore.GetUpload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &upload{Upload: u, store: st}, nil
+}
+
+// check scans the content of an upload and takes the configured action if
+// it is infected. Clean and flagged uploads are finished by finish.
+func (st *store) check(ctx context.Context, u tusd.Upload, content func() (io.Reader, error), finish func() error) error {
+	info, err := u.GetInfo(ctx)
+	if err != nil {
+		return err
+	}
+	r, err := content()
+	if err != nil {
+		return err
+	}
+	res, err := st.fs.scanner.Scan(ctx, r)
+	if c, ok := r.(io.Closer); ok {
+		c.Close()
+	}
+	if err != nil {
+		return errors.Wrap(err, "virusscan: error scanning upload")
+	}
+	if !res.Infected {
+		return finish()
+	}
+
+	err = st.fs.infected(ctx, res, info, content, finish)
+	if _, ok := err.(errtypes.IsInfected); ok {
+		if st.terminater != nil {
+			st.fs.discard(ctx, st.terminater, u)
+		}
+		return tusd.NewHTTPError(err, http.StatusForbidden)
+	}
+	return err
+}
+
+// upload is scanned before it is finished.
+type upload struct {
+	tusd.Upload
+	store *store
+}
+
+func (u *upload) FinishUpload(ctx context.Context) error {
+	return u.store.check(ctx, u.Upload, func() (io.Reader, error) {
+		return u.Upload.GetReader(ctx)
+	}, func() error {
+		return u.Upload.FinishUpload(ctx)
+	})
+}
+
+func unwrap(u tusd.Upload) tusd.Upload {
+	if w, ok := u.(*upload); ok {
+		return w.Upload
+	}
+	return u
+}
+
+type terminater struct {
+	tusd.TerminaterDataStore
+}
+
+func (t terminater) AsTerminatableUpload(u tusd.Upload) tusd.TerminatableUpload {
+	return t.TerminaterDataStore.AsTerminatableUpload(unwrap(u))
+}
+
+type lengthDeferrer struct {
+	tusd.LengthDeferrerDataStore
+}
+
+func (l lengthDeferrer) AsLengthDeclarableUpload(u tusd.Upload) tusd.LengthDeclarableUpload {
+	return l.LengthDeferrerDataStore.AsLengthDeclarableUpload(unwrap(u))
+}
+
+// concater scans the concatenation of partial uploads, as a virus may be
+// split across them.
+type concater struct {
+	tusd.ConcaterDataStore
+	store *store
+}
+
+func (c concater) AsConcatableUpload(u tusd.Upload) tusd.ConcatableUpload {
+	return concatable{upload: unwrap(u), concater: c}
+}
+
+type concatable struct {
+	upload   tusd.Upload
+	concater concater
+}
+
+func (c concatable) ConcatUploads(ctx context.Context, partials []tusd.Upload) error {
+	unwrapped := make([]tusd.Upload, 0, len(partials))
+	for _, p := range partials {
+		unwrapped = append(unwrapped, unwrap(p))
+	}
+	content := func() (io.Reader, error) {
+		readers := make([]io.Reader, 0, len(unwrapped))
+		for _, p := range unwrapped {
+			r, err := p.GetReader(ctx)
+			if err != nil {
+				return nil, err
+			}
+			readers = append(readers, r)
+		}
+		return &multiReadCloser{Reader: io.MultiReader(readers...), readers: readers}, nil
+	}
+	return c.concater.store.check(ctx, c.upload, content, func() error {
+		return c.concater.ConcaterDataStore.AsConcatableUpload(c.upload).ConcatUploads(ctx, unwrapped)
+	})
+}
+
+// multiReadCloser closes the readers of all partial uploads.
+type multiReadCloser struct {
+	io.Reader
+	readers []io.Reader
+}
+
+func (m *multiReadCloser) Close() error {
+	for _, r := range m.readers {
+		if c, ok := r.(io.Closer); ok {
+			c.Close()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,354 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package virusscan wraps a storage driver to stream the content of the
+// uploads to a virus scanner before they are finished, and to reject,
+// quarantine or flag the infected ones.
+package virusscan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/antivirus"
+	"github.com/cs3org/reva/pkg/antivirus/scanner"
+	_ "github.com/cs3org/reva/pkg/antivirus/scanner/loader" // Load the virus scanners
+	"github.com/cs3org/reva/pkg/antivirus/scanner/registry"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/deletejob"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/composable"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+// The actions taken on infected uploads.
+const (
+	// ActionReject discards the upload.
+	ActionReject = "reject"
+	// ActionQuarantine moves the content of the upload to the quarantine
+	// folder, out of reach of the users, and discards the upload.
+	ActionQuarantine = "quarantine"
+	// ActionFlag finishes the upload and marks the file as infected in its
+	// arbitrary metadata.
+	ActionFlag = "flag"
+)
+
+// Config configures the scan of the uploads.
+type Config struct {
+	Scanner          string                            `mapstructure:"scanner" docs:";The engine the uploads are scanned with, clamd or icap. Empty disables the scan."`
+	Scanners         map[string]map[string]interface{} `mapstructure:"scanners" docs:"url:pkg/antivirus/scanner/clamd/clamd.go;The configuration of the virus scanners."`
+	Action           string                            `mapstructure:"action" docs:"reject;What happens to infected uploads: reject, quarantine or flag."`
+	QuarantineFolder string                            `mapstructure:"quarantine_folder" docs:"/var/tmp/reva/quarantine;The local folder the content of quarantined uploads is moved to."`
+}
+
+// Enabled returns whether the uploads are scanned, which requires a
+// scanner.
+func (c *Config) Enabled() bool {
+	return c.Scanner != ""
+}
+
+func (c *Config) init() {
+	if c.Action == "" {
+		c.Action = ActionReject
+	}
+	if c.QuarantineFolder == "" {
+		c.QuarantineFolder = "/var/tmp/reva/quarantine"
+	}
+}
+
+type fs struct {
+	storage.FS
+	c       *Config
+	scanner scanner.Scanner
+}
+
+// New returns a storage.FS scanning the uploads to the given one.
+func New(next storage.FS, c *Config) (storage.FS, error) {
+	c.init()
+	switch c.Action {
+	case ActionReject, ActionQuarantine, ActionFlag:
+	default:
+		return nil, fmt.Errorf("virusscan: unknown action: %s", c.Action)
+	}
+	f, ok := registry.NewFuncs[c.Scanner]
+	if !ok {
+		return nil, fmt.Errorf("virusscan: scanner not found: %s", c.Scanner)
+	}
+	sc, err := f(c.Scanners[c.Scanner])
+	if err != nil {
+		return nil, errors.Wrap(err, "virusscan: error creating the scanner")
+	}
+
+	s := &fs{FS: next, c: c, scanner: sc}
+	return composable.WrapStore(s, next, s.wrap), nil
+}
+
+// DeleteRecursive is forwarded to the driver, deleting doesn't involve the scanner.
+func (s *fs) DeleteRecursive(ctx context.Context, ref *provider.Reference, progress func(total, deleted uint64)) error {
+	return deletejob.DeleteRecursive(ctx, s.FS, ref, progress)
+}
+
+// Upload spools the content to a temporary file while streaming it to the
+// scanner, and only hands it to the driver once it was found clean.
+func (s *fs) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	f, res, err := s.spool(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	finish := func() error {
+		return s.FS.Upload(ctx, ref, ioutil.NopCloser(f))
+	}
+	if !res.Infected {
+		return finish()
+	}
+
+	upload, info := s.getUpload(ctx, ref)
+	content := func() (io.Reader, error) {
+		_, err := f.Seek(0, io.SeekStart)
+		return f, err
+	}
+	err = s.infected(ctx, res, info, content, finish)
+	if _, ok := err.(errtypes.IsInfected); ok && upload != nil {
+		s.discard(ctx, s.FS, upload)
+	}
+	return err
+}
+
+// spool copies the content to a temporary file positioned at its start,
+// scanning it on the way.
+func (s *fs) spool(ctx context.Context, r io.Reader) (*os.File, *scanner.Result, error) {
+	f, err := ioutil.TempFile("", "reva-virusscan-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+
+	pr, pw := io.Pipe()
+	type verdict struct {
+		res *scanner.Result
+		err error
+	}
+	verdicts := make(chan verdict, 1)
+	go func() {
+		res, err := s.scanner.Scan(ctx, pr)
+		// the scanner may stop reading early, e.g. after finding a virus
+		pr.Close()
+		verdicts <- verdict{res, err}
+	}()
+
+	_, err = io.Copy(f, io.TeeReader(r, &lenientWriter{w: pw}))
+	pw.CloseWithError(err)
+	v := <-verdicts
+	switch {
+	case err != nil:
+		cleanup()
+		return nil, nil, err
+	case v.err != nil:
+		cleanup()
+		return nil, nil, errors.Wrap(v.err, "virusscan: error scanning upload")
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return f, v.res, nil
+}
+
+// lenientWriter feeds the scanner without failing the spooling when the
+// scanner stops reading.
+type lenientWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (l *lenientWriter) Write(p []byte) (int, error) {
+	if l.err == nil {
+		_, l.err = l.w.Write(p)
+	}
+	return len(p), nil
+}
+
+// infected takes the configured action on an infected upload. The upload is
+// finished by finish when it is flagged, otherwise an Infected error is
+// returned and the caller discards it.
+func (s *fs) infected(ctx context.Context, res *scanner.Result, info tusd.FileInfo, content func() (io.Reader, error), finish func() error) error {
+	log := appctx.GetLogger(ctx).With().Str("upload", info.ID).Str("virus", res.Virus).Str("action", s.c.Action).Logger()
+	log.Warn().Msg("virusscan: virus found in upload")
+
+	switch s.c.Action {
+	case ActionFlag:
+		if err := finish(); err != nil {
+			return err
+		}
+		if err := s.flag(ctx, res, info); err != nil {
+			// the file is stored, it is up to the admins to find it
+			log.Error().Err(err).Msg("virusscan: error flagging infected file")
+		}
+		return nil
+	case ActionQuarantine:
+		if err := s.quarantine(res, info, content); err != nil {
+			log.Error().Err(err).Msg("virusscan: error quarantining infected upload")
+		}
+	}
+	return errtypes.Infected(res.Virus)
+}
+
+// flag sets the scan status and the virus in the arbitrary metadata of the
+// uploaded file, on behalf of the user who uploaded it.
+func (s *fs) flag(ctx context.Context, res *scanner.Result, info tusd.FileInfo) error {
+	ref := fileRef(info)
+	if ref == nil {
+		return errors.New("virusscan: unknown target of upload " + info.ID)
+	}
+	if _, ok := ctxpkg.ContextGetUser(ctx); !ok && info.Storage["UserId"] != "" {
+		ctx = ctxpkg.ContextSetUser(ctx, &userpb.User{
+			Id: &userpb.UserId{
+				Idp:      info.Storage["Idp"],
+				OpaqueId: info.Storage["UserId"],
+				Type:     utils.UserTypeMap(info.Storage["UserType"]),
+			},
+			Username: info.Storage["UserName"],
+		})
+	}
+	return s.FS.SetArbitraryMetadata(ctx, ref, &provider.ArbitraryMetadata{
+		Metadata: map[string]string{
+			antivirus.StatusOpaqueKey: string(antivirus.StatusInfected),
+			antivirus.VirusOpaqueKey:  res.Virus,
+		},
+	})
+}
+
+// quarantined describes the content of an upload in the quarantine folder.
+type quarantined struct {
+	UploadID      string            `json:"upload_id"`
+	Virus         string            `json:"virus,omitempty"`
+	UserID        string            `json:"user_id,omitempty"`
+	MetaData      map[string]string `json:"metadata,omitempty"`
+	QuarantinedAt int64             `json:"quarantined_at"`
+}
+
+// quarantine copies the content of the upload to the quarantine folder,
+// along with a description of the upload.
+func (s *fs) quarantine(res *scanner.Result, info tusd.FileInfo, content func() (io.Reader, error)) error {
+	if err := os.MkdirAll(s.c.QuarantineFolder, 0700); err != nil {
+		return err
+	}
+	name := info.ID
+	if name == "" {
+		name = fmt.Sprintf("upload-%d", time.Now().UnixNano())
+	}
+	name = filepath.Join(s.c.QuarantineFolder, filepath.Base(name))
+
+	r, err := content()
+	if err != nil {
+		return err
+	}
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	desc, err := json.Marshal(quarantined{
+		UploadID:      info.ID,
+		Virus:         res.Virus,
+		UserID:        info.Storage["UserId"],
+		MetaData:      info.MetaData,
+		QuarantinedAt: time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name+".json", desc, 0600)
+}
+
+// getUpload returns the pending upload a reference points to, if the driver
+// exposes its uploads.
+func (s *fs) getUpload(ctx context.Context, ref *provider.Reference) (tusd.Upload, tusd.FileInfo) {
+	info := tusd.FileInfo{ID: path.Base(ref.GetPath())}
+	g, ok := s.FS.(interface {
+		GetUpload(ctx context.Context, id string) (tusd.Upload, error)
+	})
+	if !ok {
+		return nil, info
+	}
+	upload, err := g.GetUpload(ctx, ref.GetPath())
+	if err != nil {
+		return nil, info
+	}
+	if i, err := upload.GetInfo(ctx); err == nil {
+		info = i
+	}
+	return upload, info
+}
+
+// discard terminates a rejected upload, if the data store supports it.
+func (s *fs) discard(ctx context.Context, store interface{}, upload tusd.Upload) {
+	t, ok := store.(tusd.TerminaterDataStore)
+	if !ok {
+		return
+	}
+	if err := t.AsTerminatableUpload(upload).Terminate(ctx); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("virusscan: error discarding rejected upload")
+	}
+}
+
+// fileRef returns a reference to the file an upload is written to. Drivers
+// keep the target node in the storage info, the others rely on the metadata
+// sent by the clients.
+func fileRef(info tusd.FileInfo) *provider.Reference {
+	if parent := info.Storage["NodeParentId"]; parent != "" {
+		return &provider.Reference{
+			ResourceId: &provider.ResourceId{StorageId: info.Storage["SpaceRoot"], OpaqueId: parent},
+			Path:       utils.MakeRelativePath(info.Storage["NodeName"]),
+		}
+	}
+	if info.MetaData["filename"] != "" {
+		return &provider.Reference{Path: path.Join("/", info.MetaData["dir"], info.MetaData["filename"])}
+	}
+	return nil
+}
@@ -0,0 +1,421 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package warmup wraps a storage driver to create it and run its warmup checks
+// lazily or in the background, so that the storage provider starts without
+// waiting for the backend; the calls fail until the driver is ready.
+package warmup
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"sync"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/readiness"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/fsck"
+)
+
+type fs struct {
+	init  *readiness.Initializer
+	newFS func() (storage.FS, error)
+
+	mu sync.Mutex
+	fs storage.FS
+}
+
+// New returns a storage.FS creating the driver with newFS and running its
+// warmup checks, if it implements readiness.Warmer, as configured by c. The
+// state of the driver is reported under the given name.
+func New(name string, newFS func() (storage.FS, error), c *readiness.Config) (storage.FS, error) {
+	w := &fs{newFS: newFS}
+	init, err := readiness.NewInitializer(name, c, w.warmup)
+	if err != nil {
+		return nil, err
+	}
+	w.init = init
+	if err := init.Start(); err != nil {
+		init.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// warmup creates the driver, unless a previous attempt already did, and runs
+// its checks.
+func (w *fs) warmup(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.fs == nil {
+		fs, err := w.newFS()
+		if err != nil {
+			return err
+		}
+		w.fs = fs
+	}
+	if warmer, ok := w.fs.(readiness.Warmer); ok {
+		return warmer.Warmup(ctx)
+	}
+	return nil
+}
+
+func (w *fs) get(ctx context.Context) (storage.FS, error) {
+	if err := w.init.Ensure(ctx); err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.fs, nil
+}
+
+func (w *fs) Shutdown(ctx context.Context) error {
+	w.init.Close()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.fs == nil {
+		return nil
+	}
+	return w.fs.Shutdown(ctx)
+}
+
+func (w *fs) GetHome(ctx context.Context) (string, error) {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fs.GetHome(ctx)
+}
+
+func (w *fs) CreateHome(ctx context.Context) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.CreateHome(ctx)
+}
+
+func (w *fs) CreateDir(ctx context.Context, ref *provider.Reference) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.CreateDir(ctx, ref)
+}
+
+func (w *fs) TouchFile(ctx context.Context, ref *provider.Reference) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.TouchFile(ctx, ref)
+}
+
+func (w *fs) Delete(ctx context.Context, ref *provider.Reference) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.Delete(ctx, ref)
+}
+
+func (w *fs) Move(ctx context.Context, oldRef, newRef *provider.Reference) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.Move(ctx, oldRef, newRef)
+}
+
+func (w *fs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fs.GetMD(ctx, ref, mdKeys)
+}
+
+func (w *fs) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ListFolder(ctx, ref, mdKeys)
+}
+
+func (w *fs) InitiateUpload(ctx context.Context, ref *provider.Reference, uploadLength int64, metadata map[string]string) (map[string]string, error) {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fs.InitiateUpload(ctx, ref, uploadLength, metadata)
+}
+
+func (w *fs) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.Upload(ctx, ref, r)
+}
+
+func (w *fs) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Download(ctx, ref)
+}
+
+func (w *fs) ListRevisions(ctx context.Context, ref *provider.Reference) ([]*provider.FileVersion, error) {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ListRevisions(ctx, ref)
+}
+
+func (w *fs) DownloadRevision(ctx context.Context, ref *provider.Reference, key string) (io.ReadCloser, error) {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fs.DownloadRevision(ctx, ref, key)
+}
+
+func (w *fs) RestoreRevision(ctx context.Context, ref *provider.Reference, key string) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.RestoreRevision(ctx, ref, key)
+}
+
+func (w *fs) ListRecycle(ctx context.Context, basePath, key, relativePath string) ([]*provider.RecycleItem, error) {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ListRecycle(ctx, basePath, key, relativePath)
+}
+
+func (w *fs) RestoreRecycleItem(ctx context.Context, basePath, key, relativePath string, restoreRef *provider.Reference) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.RestoreRecycleItem(ctx, basePath, key, relativePath, restoreRef)
+}
+
+func (w *fs) PurgeRecycleItem(ctx context.Context, basePath, key, relativePath string) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.PurgeRecycleItem(ctx, basePath, key, relativePath)
+}
+
+func (w *fs) EmptyRecycle(ctx context.Context) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.EmptyRecycle(ctx)
+}
+
+func (w *fs) GetPathByID(ctx context.Context, id *provider.ResourceId) (string, error) {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fs.GetPathByID(ctx, id)
+}
+
+func (w *fs) AddGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.AddGrant(ctx, ref, g)
+}
+
+func (w *fs) DenyGrant(ctx context.Context, ref *provider.Reference, g *provider.Grantee) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.DenyGrant(ctx, ref, g)
+}
+
+func (w *fs) RemoveGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.RemoveGrant(ctx, ref, g)
+}
+
+func (w *fs) UpdateGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.UpdateGrant(ctx, ref, g)
+}
+
+func (w *fs) ListGrants(ctx context.Context, ref *provider.Reference) ([]*provider.Grant, error) {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ListGrants(ctx, ref)
+}
+
+func (w *fs) GetQuota(ctx context.Context, ref *provider.Reference) (uint64, uint64, error) {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	return fs.GetQuota(ctx, ref)
+}
+
+func (w *fs) GetQuotaInfo(ctx context.Context, ref *provider.Reference) (*storage.Quota, error) {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return storage.QuotaOf(ctx, fs, ref)
+}
+
+func (w *fs) CreateReference(ctx context.Context, path string, targetURI *url.URL) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.CreateReference(ctx, path, targetURI)
+}
+
+func (w *fs) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.SetArbitraryMetadata(ctx, ref, md)
+}
+
+func (w *fs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.UnsetArbitraryMetadata(ctx, ref, keys)
+}
+
+func (w *fs) SetLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.SetLock(ctx, ref, lock)
+}
+
+func (w *fs) GetLock(ctx context.Context, ref *provider.Reference) (*provider.Lock, error) {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fs.GetLock(ctx, ref)
+}
+
+func (w *fs) RefreshLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.RefreshLock(ctx, ref, lock)
+}
+
+func (w *fs) Unlock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	return fs.Unlock(ctx, ref, lock)
+}
+
+func (w *fs) ListStorageSpaces(ctx context.Context, filter []*provider.ListStorageSpacesRequest_Filter) ([]*provider.StorageSpace, error) {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ListStorageSpaces(ctx, filter)
+}
+
+func (w *fs) CreateStorageSpace(ctx context.Context, req *provider.CreateStorageSpaceRequest) (*provider.CreateStorageSpaceResponse, error) {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fs.CreateStorageSpace(ctx, req)
+}
+
+func (w *fs) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorageSpaceRequest) (*provider.UpdateStorageSpaceResponse, error) {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fs.UpdateStorageSpace(ctx, req)
+}
+
+func (w *fs) ListOrphanedArtifacts(ctx context.Context) ([]*fsck.Artifact, error) {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	as, ok := fs.(fsck.ArtifactScanner)
+	if !ok {
+		return nil, errtypes.NotSupported("warmup: the driver doesn't find its orphaned artifacts")
+	}
+	return as.ListOrphanedArtifacts(ctx)
+}
+
+func (w *fs) PurgeArtifact(ctx context.Context, a *fsck.Artifact) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	as, ok := fs.(fsck.ArtifactScanner)
+	if !ok {
+		return errtypes.NotSupported("warmup: the driver doesn't find its orphaned artifacts")
+	}
+	return as.PurgeArtifact(ctx, a)
+}
+
+func (w *fs) RepairTreeSize(ctx context.Context, ref *provider.Reference, size uint64) error {
+	fs, err := w.get(ctx)
+	if err != nil {
+		return err
+	}
+	sr, ok := fs.(fsck.SizeRepairer)
+	if !ok {
+		return errtypes.NotSupported("warmup: the driver doesn't store the size of the folders")
+	}
+	return sr.RepairTreeSize(ctx, ref, size)
+}
@@ -0,0 +1,95 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package warmup
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/readiness"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+// flakyFS fails its warmup checks until up is set.
+type flakyFS struct {
+	storage.FS
+	up bool
+}
+
+func (f *flakyFS) Warmup(ctx context.Context) error {
+	if !f.up {
+		return errors.New("backend down")
+	}
+	return nil
+}
+
+func (f *flakyFS) GetHome(ctx context.Context) (string, error) {
+	return "/home", nil
+}
+
+func TestLazyWarmup(t *testing.T) {
+	created := 0
+	driver := &flakyFS{}
+	s, err := New("test-mount", func() (storage.FS, error) {
+		created++
+		return driver, nil
+	}, &readiness.Config{Mode: readiness.ModeLazy, RetryInterval: 3600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.(*fs).init.Close()
+
+	if created != 0 {
+		t.Fatal("driver created at startup in lazy mode")
+	}
+	if _, err := s.GetHome(context.Background()); err == nil {
+		t.Fatal("expected the call to fail while the backend is down")
+	}
+	if created != 1 {
+		t.Fatalf("expected the driver to be created once, got %d", created)
+	}
+
+	// the failed attempt is only retried after the retry interval
+	driver.up = true
+	if _, err := s.GetHome(context.Background()); err == nil {
+		t.Fatal("expected the call to fail within the retry interval")
+	}
+}
+
+func TestEagerWarmup(t *testing.T) {
+	s, err := New("test-mount", func() (storage.FS, error) {
+		return &flakyFS{up: true}, nil
+	}, &readiness.Config{Mode: readiness.ModeEager})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.(*fs).init.Close()
+
+	home, err := s.GetHome(context.Background())
+	if err != nil || home != "/home" {
+		t.Fatalf("unexpected result %q, %v", home, err)
+	}
+
+	if _, err := New("test-down", func() (storage.FS, error) {
+		return &flakyFS{}, nil
+	}, &readiness.Config{Mode: readiness.ModeEager}); err == nil {
+		t.Fatal("expected the eager warmup to fail")
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package worm wraps a storage driver to enforce legal holds: the resources
+// covered by an active hold become write-once, read-many. Every rejected
+// modification is recorded with the hold it violated.
+package worm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/deletejob"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/legalhold"
+	_ "github.com/cs3org/reva/pkg/legalhold/manager/loader" // Load the legal hold managers
+	"github.com/cs3org/reva/pkg/legalhold/manager/registry"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/composable"
+	"github.com/pkg/errors"
+)
+
+// Config configures the enforcement of legal holds.
+type Config struct {
+	Driver  string                            `mapstructure:"driver" docs:";The legal hold manager the holds are read from. Empty disables the enforcement."`
+	Drivers map[string]map[string]interface{} `mapstructure:"drivers" docs:"url:pkg/legalhold/manager/json/json.go;The configuration of the legal hold managers."`
+}
+
+// Enabled returns whether the legal holds are enforced, which requires a
+// manager to read them from.
+func (c *Config) Enabled() bool {
+	return c.Driver != ""
+}
+
+type fs struct {
+	storage.FS
+	holds legalhold.Manager
+}
+
+// New returns a storage.FS rejecting the modifications of the resources of
+// the given one which are under an active legal hold.
+func New(next storage.FS, c *Config) (storage.FS, error) {
+	f, ok := registry.NewFuncs[c.Driver]
+	if !ok {
+		return nil, fmt.Errorf("worm: legal hold manager not found: %s", c.Driver)
+	}
+	holds, err := f(c.Drivers[c.Driver])
+	if err != nil {
+		return nil, errors.Wrap(err, "worm: error creating the legal hold manager")
+	}
+
+	w := &fs{FS: next, holds: holds}
+	return composable.Wrap(w, next), nil
+}
+
+func (w *fs) CreateDir(ctx context.Context, ref *provider.Reference) error {
+	if err := w.check(ctx, "create_dir", ref); err != nil {
+		return err
+	}
+	return w.FS.CreateDir(ctx, ref)
+}
+
+func (w *fs) TouchFile(ctx context.Context, ref *provider.Reference) error {
+	if err := w.check(ctx, "touch_file", ref); err != nil {
+		return err
+	}
+	return w.FS.TouchFile(ctx, ref)
+}
+
+func (w *fs) Delete(ctx context.Context, ref *provider.Reference) error {
+	if err := w.check(ctx, "delete", ref); err != nil {
+		return err
+	}
+	return w.FS.Delete(ctx, ref)
+}
+
+// DeleteRecursive lets the driver delete a tree at once, unless it is under a legal hold.
+func (w *fs) DeleteRecursive(ctx context.Context, ref *provider.Reference, progress func(total, deleted uint64)) error {
+	if err := w.check(ctx, "delete", ref); err != nil {
+		return err
+	}
+	return deletejob.DeleteRecursive(ctx, w.FS, ref, progress)
+}
+
+func (w *fs) Move(ctx context.Context, oldRef, newRef *provider.Reference) error {
+	if err := w.check(ctx, "move", oldRef); err != nil {
+		return err
+	}
+	if err := w.check(ctx, "move", newRef); err != nil {
+		return err
+	}
+	return w.FS.Move(ctx, oldRef, newRef)
+}
+
+func (w *fs) InitiateUpload(ctx context.Context, ref *provider.Reference, uploadLength int64, metadata map[string]string) (map[string]string, error) {
+	if err := w.check(ctx, "upload", ref); err != nil {
+		return nil, err
+	}
+	return w.FS.InitiateUpload(ctx, ref, uploadLength, metadata)
+}
+
+func (w *fs) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	if err := w.check(ctx, "upload", ref); err != nil {
+		return err
+	}
+	return w.FS.Upload(ctx, ref, r)
+}
+
+func (w *fs) RestoreRevision(ctx context.Context, ref *provider.Reference, key string) error {
+	if err := w.check(ctx, "restore_revision", ref); err != nil {
+		return err
+	}
+	return w.FS.RestoreRevision(ctx, ref, key)
+}
+
+func (w *fs) RestoreRecycleItem(ctx context.Context, basePath, key, relativePath string, restoreRef *provider.Reference) error {
+	if restoreRef != nil {
+		if err := w.check(ctx, "restore_recycle_item", restoreRef); err != nil {
+			return err
+		}
+	}
+	return w.FS.RestoreRecycleItem(ctx, basePath, key, relativePath, restoreRef)
+}
+
+// PurgeRecycleItem protects the trash of the held trees, as purging it
+// destroys what was deleted before the hold was placed.
+func (w *fs) PurgeRecycleItem(ctx context.Context, basePath, key, relativePath string) error {
+	if err := w.check(ctx, "purge_recycle_item", &provider.Reference{Path: basePath}); err != nil {
+		return err
+	}
+	return w.FS.PurgeRecycleItem(ctx, basePath, key, relativePath)
+}
+
+// EmptyRecycle empties the trash of the current user, which is protected
+// by the holds placed on the user.
+func (w *fs) EmptyRecycle(ctx context.Context) error {
+	if u, ok := ctxpkg.ContextGetUser(ctx); ok {
+		holds, err := w.activeHolds(ctx)
+		if err != nil {
+			return err
+		}
+		for _, h := range holds {
+			if h.Type == legalhold.TypeUser && h.Covers(u.Id, "") {
+				return w.reject(ctx, h, "empty_recycle", "")
+			}
+		}
+	}
+	return w.FS.EmptyRecycle(ctx)
+}
+
+func (w *fs) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	if err := w.check(ctx, "set_arbitrary_metadata", ref); err != nil {
+		return err
+	}
+	return w.FS.SetArbitraryMetadata(ctx, ref, md)
+}
+
+func (w *fs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
+	if err := w.check(ctx, "unset_arbitrary_metadata", ref); err != nil {
+		return err
+	}
+	return w.FS.UnsetArbitraryMetadata(ctx, ref, keys)
+}
+
+func (w *fs) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorageSpaceRequest) (*provider.UpdateStorageSpaceResponse, error) {
+	holds, err := w.activeHolds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range holds {
+		if h.Type == legalhold.TypeSpace && h.SpaceID == req.GetStorageSpace().GetId().GetOpaqueId() {
+			return nil, w.reject(ctx, h, "update_storage_space", "")
+		}
+	}
+	return w.FS.UpdateStorageSpace(ctx, req)
+}
+
+func (w *fs) activeHolds(ctx context.Context) ([]*legalhold.Hold, error) {
+	holds, err := w.holds.ListHolds(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "worm: error listing the legal holds")
+	}
+	active := holds[:0]
+	for _, h := range holds {
+		if h.Active() {
+			active = append(active, h)
+		}
+	}
+	return active, nil
+}
+
+// check rejects the modification of the referenced resource if it is under
+// an active hold. Resources which do not exist yet are checked through
+// their parent, which they would be added to.
+func (w *fs) check(ctx context.Context, op string, ref *provider.Reference) error {
+	holds, err := w.activeHolds(ctx)
+	if err != nil || len(holds) == 0 {
+		return err
+	}
+
+	info, err := w.FS.GetMD(ctx, ref, nil)
+	if _, ok := err.(errtypes.IsNotFound); ok && ref.Path != "" {
+		parent := &provider.Reference{ResourceId: ref.ResourceId, Path: path.Dir(ref.Path)}
+		info, err = w.FS.GetMD(ctx, parent, nil)
+	}
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			// nothing to protect, the driver reports the error
+			return nil
+		}
+		return err
+	}
+
+	for _, h := range holds {
+		covered := h.Covers(info.Owner, info.Path)
+		if h.Type == legalhold.TypeSpace {
+			root, err := w.spaceRoot(ctx, h.SpaceID)
+			if err != nil {
+				return err
+			}
+			covered = legalhold.IsBelow(info.Path, root)
+		}
+		if covered {
+			return w.reject(ctx, h, op, info.Path)
+		}
+	}
+	return nil
+}
+
+// spaceRoot returns the path of the root of a space, or an empty path if
+// the space does not belong to this storage.
+func (w *fs) spaceRoot(ctx context.Context, id string) (string, error) {
+	spaces, err := w.FS.ListStorageSpaces(ctx, []*provider.ListStorageSpacesRequest_Filter{
+		{
+			Type: provider.ListStorageSpacesRequest_Filter_TYPE_ID,
+			Term: &provider.ListStorageSpacesRequest_Filter_Id{Id: &provider.StorageSpaceId{OpaqueId: id}},
+		},
+	})
+	if err != nil {
+		if _, ok := err.(errtypes.NotSupported); ok {
+			return "", nil
+		}
+		return "", err
+	}
+	for _, s := range spaces {
+		if s.GetId().GetOpaqueId() == id && s.Root != nil {
+			return w.FS.GetPathByID(ctx, s.Root)
+		}
+	}
+	return "", nil
+}
+
+// reject records the attempted modification and returns the error reported to the client.
+func (w *fs) reject(ctx context.Context, h *legalhold.Hold, op, p string) error {
+	a := &legalhold.Attempt{HoldID: h.ID, Operation: op, Path: p, Time: time.Now().Unix()}
+	if u, ok := ctxpkg.ContextGetUser(ctx); ok {
+		a.User = u.Id
+	}
+
+	log := appctx.GetLogger(ctx)
+	log.Warn().Str("hold", h.ID).Str("operation", op).Str("path", p).Interface("user", a.User).
+		Msg("worm: modification rejected by legal hold")
+	if err := w.holds.RecordAttempt(ctx, a); err != nil {
+		log.Error().Err(err).Str("hold", h.ID).Msg("worm: error recording rejected modification")
+	}
+	return errtypes.PermissionDenied(strings.TrimSpace(fmt.Sprintf("legal hold %s forbids %s %s", h.ID, op, p)))
+}
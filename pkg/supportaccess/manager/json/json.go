@@ -0,0 +1,160 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/supportaccess"
+	"github.com/cs3org/reva/pkg/supportaccess/manager/registry"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("json", New)
+}
+
+type config struct {
+	File string `mapstructure:"file"`
+}
+
+func (c *config) init() {
+	if c.File == "" {
+		c.File = "/var/tmp/reva/supportaccess.json"
+	}
+}
+
+type manager struct {
+	sync.Mutex
+	file string
+}
+
+// New returns a support access manager storing the requests in a JSON file. The file is
+// read on every access, as it is shared between the HTTP service handling the requests
+// and the auth provider issuing the tokens.
+func New(m map[string]interface{}) (supportaccess.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	c.init()
+
+	mgr := &manager{file: c.File}
+	if _, err := os.Stat(c.File); os.IsNotExist(err) {
+		if err := mgr.save(map[string]*supportaccess.Request{}); err != nil {
+			return nil, err
+		}
+	}
+	return mgr, nil
+}
+
+func (m *manager) load() (map[string]*supportaccess.Request, error) {
+	data, err := ioutil.ReadFile(m.file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading the file %s", m.file)
+	}
+	requests := map[string]*supportaccess.Request{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &requests); err != nil {
+			return nil, errors.Wrapf(err, "error parsing the file %s", m.file)
+		}
+	}
+	return requests, nil
+}
+
+func (m *manager) save(requests map[string]*supportaccess.Request) error {
+	data, err := json.Marshal(requests)
+	if err != nil {
+		return errors.Wrap(err, "error encoding the support access requests")
+	}
+	if err := ioutil.WriteFile(m.file, data, 0600); err != nil {
+		return errors.Wrapf(err, "error writing the file %s", m.file)
+	}
+	return nil
+}
+
+func (m *manager) CreateRequest(ctx context.Context, r *supportaccess.Request) error {
+	m.Lock()
+	defer m.Unlock()
+
+	requests, err := m.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := requests[r.ID]; ok {
+		return errtypes.AlreadyExists(r.ID)
+	}
+	requests[r.ID] = r
+	return m.save(requests)
+}
+
+func (m *manager) GetRequest(ctx context.Context, id string) (*supportaccess.Request, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	requests, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	r, ok := requests[id]
+	if !ok {
+		return nil, errtypes.NotFound(id)
+	}
+	return r, nil
+}
+
+func (m *manager) ListRequests(ctx context.Context, u *userpb.UserId) ([]*supportaccess.Request, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	requests, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	list := []*supportaccess.Request{}
+	for _, r := range requests {
+		if utils.UserEqual(r.Admin, u) || utils.UserEqual(r.User, u) {
+			list = append(list, r)
+		}
+	}
+	return list, nil
+}
+
+func (m *manager) UpdateRequest(ctx context.Context, r *supportaccess.Request) error {
+	m.Lock()
+	defer m.Unlock()
+
+	requests, err := m.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := requests[r.ID]; !ok {
+		return errtypes.NotFound(r.ID)
+	}
+	requests[r.ID] = r
+	return m.save(requests)
+}
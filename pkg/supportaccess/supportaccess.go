@@ -0,0 +1,95 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package supportaccess
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+)
+
+// State is the state of a support access request.
+type State string
+
+const (
+	// StatePending means that the user has not given consent yet.
+	StatePending State = "pending"
+	// StateApproved means that the access was granted.
+	StateApproved State = "approved"
+	// StateDenied means that the user refused to give access.
+	StateDenied State = "denied"
+	// StateRevoked means that the access was ended before its expiration.
+	StateRevoked State = "revoked"
+)
+
+// Request is a request of an admin to temporarily access the account of a user.
+type Request struct {
+	ID     string         `json:"id"`
+	Admin  *userpb.UserId `json:"admin"`
+	User   *userpb.UserId `json:"user"`
+	Reason string         `json:"reason"`
+	// Duration is the time in seconds the access is granted for once approved.
+	Duration  int64  `json:"duration"`
+	State     State  `json:"state"`
+	CreatedAt int64  `json:"created_at"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+	Secret    string `json:"secret,omitempty"`
+}
+
+// Approve grants the access for the requested duration, starting now.
+func (r *Request) Approve() {
+	r.State = StateApproved
+	r.ExpiresAt = time.Now().Add(time.Duration(r.Duration) * time.Second).Unix()
+}
+
+// Active checks whether the access is currently granted.
+func (r *Request) Active() bool {
+	return r.State == StateApproved && time.Now().Unix() < r.ExpiresAt
+}
+
+// SetSecret stores a hash of the secret used to obtain tokens for the request.
+func (r *Request) SetSecret(secret string) {
+	r.Secret = hashSecret(secret)
+}
+
+// VerifySecret checks the given secret against the one stored for the request.
+func (r *Request) VerifySecret(secret string) bool {
+	return r.Secret != "" && subtle.ConstantTimeCompare([]byte(r.Secret), []byte(hashSecret(secret))) == 1
+}
+
+func hashSecret(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+// Manager is the interface to implement to store support access requests.
+type Manager interface {
+	// CreateRequest stores a new request.
+	CreateRequest(ctx context.Context, r *Request) error
+	// GetRequest returns the request with the given ID.
+	GetRequest(ctx context.Context, id string) (*Request, error)
+	// ListRequests returns the requests made by or concerning the given user.
+	ListRequests(ctx context.Context, u *userpb.UserId) ([]*Request, error)
+	// UpdateRequest updates an existing request.
+	UpdateRequest(ctx context.Context, r *Request) error
+}
@@ -0,0 +1,55 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package supportaccess
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRequest(t *testing.T) {
+	r := &Request{State: StatePending, Duration: 60}
+	if r.Active() {
+		t.Fatal("pending request must not be active")
+	}
+
+	r.Approve()
+	if !r.Active() {
+		t.Fatal("approved request must be active")
+	}
+	if r.ExpiresAt < time.Now().Add(59*time.Second).Unix() {
+		t.Fatalf("unexpected expiration %d", r.ExpiresAt)
+	}
+
+	if r.VerifySecret("") {
+		t.Fatal("no secret must be accepted before one is set")
+	}
+	r.SetSecret("secret")
+	if r.Secret == "secret" {
+		t.Fatal("secret must not be stored in clear")
+	}
+	if !r.VerifySecret("secret") || r.VerifySecret("other") {
+		t.Fatal("secret verification failed")
+	}
+
+	r.ExpiresAt = time.Now().Add(-time.Second).Unix()
+	if r.Active() {
+		t.Fatal("expired request must not be active")
+	}
+}
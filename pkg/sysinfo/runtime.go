@@ -0,0 +1,81 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sysinfo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RuntimeInformation holds information about the running daemon.
+type RuntimeInformation struct {
+	StartTime    time.Time `json:"start_time"`
+	ConfigHash   string    `json:"config_hash"`
+	GRPCServices []string  `json:"grpc_services"`
+	HTTPServices []string  `json:"http_services"`
+}
+
+var (
+	runtimeInfo  = RuntimeInformation{StartTime: time.Now()}
+	runtimeMutex sync.RWMutex
+)
+
+// SetConfig records the hash of the configuration the daemon has been started with.
+// Only the hash is kept, as the configuration usually contains secrets.
+func SetConfig(conf map[string]interface{}) {
+	data, err := json.Marshal(conf)
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(data)
+
+	runtimeMutex.Lock()
+	defer runtimeMutex.Unlock()
+	runtimeInfo.ConfigHash = hex.EncodeToString(sum[:])
+}
+
+// AddGRPCServices records the names of the registered gRPC services.
+func AddGRPCServices(names ...string) {
+	runtimeMutex.Lock()
+	defer runtimeMutex.Unlock()
+	runtimeInfo.GRPCServices = append(runtimeInfo.GRPCServices, names...)
+	sort.Strings(runtimeInfo.GRPCServices)
+}
+
+// AddHTTPServices records the names of the registered HTTP services.
+func AddHTTPServices(names ...string) {
+	runtimeMutex.Lock()
+	defer runtimeMutex.Unlock()
+	runtimeInfo.HTTPServices = append(runtimeInfo.HTTPServices, names...)
+	sort.Strings(runtimeInfo.HTTPServices)
+}
+
+// Runtime returns a copy of the runtime information.
+func Runtime() RuntimeInformation {
+	runtimeMutex.RLock()
+	defer runtimeMutex.RUnlock()
+	info := runtimeInfo
+	info.GRPCServices = append([]string{}, runtimeInfo.GRPCServices...)
+	info.HTTPServices = append([]string{}, runtimeInfo.HTTPServices...)
+	return info
+}
@@ -0,0 +1,206 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package tenant groups users into tenants, so that several institutions can
+// be hosted on the same deployment without seeing each other.
+package tenant
+
+import (
+	"net/http"
+	"strings"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+// OpaqueKey is the key in the user opaque holding the tenant set at login.
+const OpaqueKey = "tenant"
+
+// Config holds the multi-tenancy configuration.
+type Config struct {
+	Claim              string               `mapstructure:"claim" docs:";The IdP claim holding the tenant of a user."`
+	Domains            map[string]string    `mapstructure:"domains" docs:";Maps mail and IdP domains to tenants, used when the tenant is not set at login."`
+	Hosts              map[string]string    `mapstructure:"hosts" docs:";Maps the hosts requests are sent to to tenants, used for anonymous requests."`
+	Default            string               `mapstructure:"default" docs:";The tenant of users that cannot be assigned to any other tenant."`
+	CrossTenantSharing string               `mapstructure:"cross_tenant_sharing" docs:"deny;Whether users can find and share with users of other tenants, either allow or deny."`
+	Trusted            map[string][]string  `mapstructure:"trusted" docs:";The tenants the users of a tenant can share with when cross tenant sharing is denied."`
+	Overrides          map[string]Overrides `mapstructure:"overrides" docs:";The branding and capabilities that differ per tenant."`
+}
+
+// Overrides holds the branding and capabilities presented to the users of a
+// tenant instead of the ones configured for the whole deployment.
+type Overrides struct {
+	Name         string   `mapstructure:"name" docs:";The product name shown by the clients."`
+	Slogan       string   `mapstructure:"slogan" docs:";The slogan shown by the clients."`
+	URL          string   `mapstructure:"url" docs:";The URL of the tenant's homepage."`
+	Logo         string   `mapstructure:"logo" docs:";The URL of the logo shown by the clients."`
+	Color        string   `mapstructure:"color" docs:";The main color used by the clients."`
+	ShareTypes   []string `mapstructure:"share_types" docs:";The enabled share types, any of user, group, public and federated."`
+	DefaultQuota string   `mapstructure:"default_quota" docs:";The default quota of the users, e.g. 10 GB."`
+}
+
+func (c *Config) init() {
+	if c.CrossTenantSharing == "" {
+		c.CrossTenantSharing = "deny"
+	}
+}
+
+// Manager assigns users to tenants and decides which tenants can interact.
+// A nil or disabled manager puts everyone in the same tenant.
+type Manager struct {
+	c *Config
+}
+
+// New returns a new tenant manager for the given configuration.
+func New(m map[string]interface{}) (*Manager, error) {
+	c := &Config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "tenant: error decoding config")
+	}
+	c.init()
+	if c.CrossTenantSharing != "allow" && c.CrossTenantSharing != "deny" {
+		return nil, errors.New("tenant: cross_tenant_sharing must be either allow or deny")
+	}
+	return &Manager{c: c}, nil
+}
+
+// Enabled returns whether users are assigned to tenants at all.
+func (m *Manager) Enabled() bool {
+	return m != nil && (m.c.Claim != "" || len(m.c.Domains) > 0 || len(m.c.Hosts) > 0 || m.c.Default != "")
+}
+
+// Claim returns the IdP claim holding the tenant of a user.
+func (m *Manager) Claim() string {
+	if m == nil {
+		return ""
+	}
+	return m.c.Claim
+}
+
+// Of returns the tenant of a user. The tenant set at login takes precedence,
+// then the domain of the mail address and of the IdP are looked up.
+func (m *Manager) Of(u *userpb.User) string {
+	if !m.Enabled() || u == nil {
+		return ""
+	}
+	if t := Get(u.Opaque); t != "" {
+		return t
+	}
+	return m.lookup(u.Mail, u.GetId().GetIdp())
+}
+
+// OfRequest returns the tenant a request is made for: the one of the
+// authenticated user or, for anonymous requests, the one of the requested host.
+func (m *Manager) OfRequest(r *http.Request) string {
+	if !m.Enabled() {
+		return ""
+	}
+	if u, ok := ctxpkg.ContextGetUser(r.Context()); ok {
+		return m.Of(u)
+	}
+	if t, ok := m.c.Hosts[strings.ToLower(host(r.Host))]; ok {
+		return t
+	}
+	return m.c.Default
+}
+
+// OfGroup returns the tenant of a group, derived like the one of users.
+func (m *Manager) OfGroup(g *grouppb.Group) string {
+	if !m.Enabled() || g == nil {
+		return ""
+	}
+	if t := Get(g.Opaque); t != "" {
+		return t
+	}
+	return m.lookup(g.Mail, g.GetId().GetIdp())
+}
+
+func (m *Manager) lookup(mail, idp string) string {
+	if i := strings.LastIndex(mail, "@"); i >= 0 {
+		if t, ok := m.c.Domains[strings.ToLower(mail[i+1:])]; ok {
+			return t
+		}
+	}
+	if t, ok := m.c.Domains[strings.ToLower(host(idp))]; ok {
+		return t
+	}
+	return m.c.Default
+}
+
+// Overrides returns the overrides configured for a tenant, if any.
+func (m *Manager) Overrides(tenant string) (*Overrides, bool) {
+	if !m.Enabled() {
+		return nil, false
+	}
+	o, ok := m.c.Overrides[tenant]
+	return &o, ok
+}
+
+// CanShare returns whether the users of a tenant can find and share with the
+// users of another tenant.
+func (m *Manager) CanShare(from, to string) bool {
+	if !m.Enabled() || from == to || m.c.CrossTenantSharing == "allow" {
+		return true
+	}
+	for _, t := range m.c.Trusted[from] {
+		if t == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the tenant stored in an opaque, if any.
+func Get(o *types.Opaque) string {
+	if o == nil || o.Map == nil {
+		return ""
+	}
+	if e, ok := o.Map[OpaqueKey]; ok && e.Decoder == "plain" {
+		return string(e.Value)
+	}
+	return ""
+}
+
+// Set stores the tenant of a user in its opaque.
+func Set(u *userpb.User, tenant string) {
+	if u.Opaque == nil {
+		u.Opaque = &types.Opaque{}
+	}
+	if u.Opaque.Map == nil {
+		u.Opaque.Map = map[string]*types.OpaqueEntry{}
+	}
+	u.Opaque.Map[OpaqueKey] = &types.OpaqueEntry{
+		Decoder: "plain",
+		Value:   []byte(tenant),
+	}
+}
+
+// host returns the host of an IdP, which is usually given as an URL.
+func host(idp string) string {
+	if i := strings.Index(idp, "://"); i >= 0 {
+		idp = idp[i+3:]
+	}
+	if i := strings.IndexAny(idp, "/:"); i >= 0 {
+		idp = idp[:i]
+	}
+	return idp
+}
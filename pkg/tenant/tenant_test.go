@@ -0,0 +1,91 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package tenant
+
+import (
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+)
+
+func TestOf(t *testing.T) {
+	m, err := New(map[string]interface{}{
+		"domains": map[string]string{
+			"cern.ch":    "cern",
+			"idp.uni.eu": "uni",
+		},
+		"default": "guests",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claimed := &userpb.User{Mail: "einstein@cern.ch"}
+	Set(claimed, "physics")
+
+	tests := []struct {
+		user *userpb.User
+		want string
+	}{
+		{claimed, "physics"},
+		{&userpb.User{Mail: "einstein@CERN.ch"}, "cern"},
+		{&userpb.User{Id: &userpb.UserId{Idp: "https://idp.uni.eu:8443/realms/main"}}, "uni"},
+		{&userpb.User{Mail: "marie@example.org"}, "guests"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := m.Of(tt.user); got != tt.want {
+			t.Errorf("Of(%v) = %q, want %q", tt.user, got, tt.want)
+		}
+	}
+}
+
+func TestCanShare(t *testing.T) {
+	m, err := New(map[string]interface{}{
+		"default": "a",
+		"trusted": map[string][]string{"a": {"b"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"a", "a", true},
+		{"a", "b", true},
+		{"b", "a", false},
+		{"a", "c", false},
+	}
+	for _, tt := range tests {
+		if got := m.CanShare(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanShare(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	var disabled *Manager
+	if !disabled.CanShare("a", "c") {
+		t.Error("a disabled manager must allow sharing between any tenants")
+	}
+
+	if _, err := New(map[string]interface{}{"cross_tenant_sharing": "sometimes"}); err == nil {
+		t.Error("expected an error for an invalid cross_tenant_sharing policy")
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+func checkStatus(st *rpc.Status, err error, msg string) error {
+	if err != nil {
+		return errors.Wrap(err, "test: "+msg)
+	}
+	if st.Code != rpc.Code_CODE_OK {
+		return errors.Errorf("test: %s: code=%s msg=%q", msg, st.Code, st.Message)
+	}
+	return nil
+}
+
+// Login authenticates the user with the basic auth provider and returns a context
+// carrying the resulting token, to be used for all further calls on behalf of the user.
+func (r *Reva) Login(ctx context.Context, username, password string) (context.Context, error) {
+	client, err := r.Gateway()
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Authenticate(ctx, &gateway.AuthenticateRequest{
+		Type:         "basic",
+		ClientId:     username,
+		ClientSecret: password,
+	})
+	if err := checkStatus(res.GetStatus(), err, "error authenticating "+username); err != nil {
+		return nil, err
+	}
+	ctx = ctxpkg.ContextSetUser(ctx, res.User)
+	ctx = ctxpkg.ContextSetToken(ctx, res.Token)
+	ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.TokenHeader, res.Token)
+	return ctx, nil
+}
+
+// CreateFolder creates a folder at the given path.
+func (r *Reva) CreateFolder(ctx context.Context, path string) error {
+	client, err := r.Gateway()
+	if err != nil {
+		return err
+	}
+	res, err := client.CreateContainer(ctx, &provider.CreateContainerRequest{Ref: &provider.Reference{Path: path}})
+	return checkStatus(res.GetStatus(), err, "error creating folder "+path)
+}
+
+// Upload uploads the content to a file at the given path using the simple protocol.
+func (r *Reva) Upload(ctx context.Context, path string, content []byte) error {
+	client, err := r.Gateway()
+	if err != nil {
+		return err
+	}
+	res, err := client.InitiateFileUpload(ctx, &provider.InitiateFileUploadRequest{
+		Ref: &provider.Reference{Path: path},
+		Opaque: &types.Opaque{
+			Map: map[string]*types.OpaqueEntry{
+				"Upload-Length": {
+					Decoder: "plain",
+					Value:   []byte(strconv.Itoa(len(content))),
+				},
+			},
+		},
+	})
+	if err := checkStatus(res.GetStatus(), err, "error initiating upload of "+path); err != nil {
+		return err
+	}
+
+	for _, p := range res.Protocols {
+		if p.Protocol != "simple" {
+			continue
+		}
+		httpRes, err := r.transfer(ctx, http.MethodPut, p.UploadEndpoint, p.Token, content)
+		if err != nil {
+			return err
+		}
+		drain(httpRes.Body)
+		if httpRes.StatusCode != http.StatusOK {
+			return errors.New("test: upload of " + path + " returned " + httpRes.Status)
+		}
+		return nil
+	}
+	return errors.New("test: no simple upload protocol available for " + path)
+}
+
+// Download returns the content of the file at the given path.
+func (r *Reva) Download(ctx context.Context, path string) ([]byte, error) {
+	client, err := r.Gateway()
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.InitiateFileDownload(ctx, &provider.InitiateFileDownloadRequest{
+		Ref: &provider.Reference{Path: path},
+	})
+	if err := checkStatus(res.GetStatus(), err, "error initiating download of "+path); err != nil {
+		return nil, err
+	}
+
+	for _, p := range res.Protocols {
+		if p.Protocol != "simple" {
+			continue
+		}
+		httpRes, err := r.transfer(ctx, http.MethodGet, p.DownloadEndpoint, p.Token, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer drain(httpRes.Body)
+		if httpRes.StatusCode != http.StatusOK {
+			return nil, errors.New("test: download of " + path + " returned " + httpRes.Status)
+		}
+		return ioutil.ReadAll(httpRes.Body)
+	}
+	return nil, errors.New("test: no simple download protocol available for " + path)
+}
+
+func (r *Reva) transfer(ctx context.Context, method, endpoint, transferToken string, content []byte) (*http.Response, error) {
+	var body io.Reader
+	if content != nil {
+		body = bytes.NewReader(content)
+	}
+	req, err := rhttp.NewRequest(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, errors.Wrap(err, "test: error creating transfer request")
+	}
+	req.Header.Set(datagateway.TokenTransportHeader, transferToken)
+	if tkn, ok := ctxpkg.ContextGetToken(ctx); ok {
+		req.Header.Set(ctxpkg.TokenHeader, tkn)
+	}
+	res, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "test: error sending transfer request")
+	}
+	return res, nil
+}
+
+// drain consumes the response body so connections can be reused.
+func drain(body io.ReadCloser) {
+	_, _ = io.Copy(ioutil.Discard, body)
+	body.Close()
+}
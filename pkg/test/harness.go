@@ -0,0 +1,361 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package test provides a harness running a minimal reva deployment in-process,
+// for downstream projects and integration tests to run scenarios without docker.
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+
+	// load all the drivers and services
+	_ "github.com/cs3org/reva/cmd/revad/runtime"
+	"github.com/cs3org/reva/pkg/rgrpc"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const idp = "http://localhost:20080"
+
+// Account is a user known to the harness.
+type Account struct {
+	Username    string
+	Password    string
+	DisplayName string
+	Mail        string
+	Groups      []string
+}
+
+// DemoAccounts are the accounts used if none are configured.
+var DemoAccounts = []*Account{
+	{Username: "einstein", Password: "relativity", DisplayName: "Albert Einstein", Mail: "einstein@example.org", Groups: []string{"sailing-lovers", "physics-lovers"}},
+	{Username: "marie", Password: "radioactivity", DisplayName: "Marie Curie", Mail: "marie@example.org", Groups: []string{"radium-lovers", "physics-lovers"}},
+	{Username: "richard", Password: "superfluidity", DisplayName: "Richard Feynman", Mail: "richard@example.org", Groups: []string{"quantum-lovers", "physics-lovers"}},
+}
+
+// Options configures the harness.
+type Options struct {
+	// Accounts are the users known to the harness. Defaults to DemoAccounts.
+	Accounts []*Account
+	// Root is the directory holding the storage and the configuration files.
+	// Defaults to a temporary directory removed on Stop.
+	Root string
+	// Logger is the logger used by the services. Defaults to a disabled logger.
+	Logger *zerolog.Logger
+}
+
+// Reva is a running in-process reva deployment, consisting of a gateway,
+// a localhome storage provider, json user, group and auth providers and
+// in-memory share providers, all listening on random local ports.
+type Reva struct {
+	// GatewayAddr is the address of the gRPC server hosting all the services.
+	GatewayAddr string
+	// HTTPAddr is the address of the HTTP server hosting the data services.
+	HTTPAddr string
+	// Root is the directory holding the storage.
+	Root string
+
+	removeRoot bool
+	jwtSecret  string
+	grpc       *rgrpc.Server
+	http       *rhttp.Server
+	httpClient *http.Client
+}
+
+// Start boots a new deployment. It must be stopped with Stop.
+// The configuration is passed to every service rather than set in the process-wide
+// shared configuration, which is left to the process embedding the deployment.
+func Start(opts Options) (*Reva, error) {
+	if opts.Accounts == nil {
+		opts.Accounts = DemoAccounts
+	}
+	logger := zerolog.Nop()
+	if opts.Logger != nil {
+		logger = *opts.Logger
+	}
+
+	r := &Reva{Root: opts.Root, jwtSecret: uuid.NewString(), httpClient: rhttp.GetHTTPClient()}
+	if r.Root == "" {
+		dir, err := ioutil.TempDir("", "reva-test-")
+		if err != nil {
+			return nil, errors.Wrap(err, "test: error creating root directory")
+		}
+		r.Root, r.removeRoot = dir, true
+	}
+
+	grpcListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, errors.Wrap(err, "test: error listening for grpc")
+	}
+	httpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		grpcListener.Close()
+		return nil, errors.Wrap(err, "test: error listening for http")
+	}
+	r.GatewayAddr = grpcListener.Addr().String()
+	r.HTTPAddr = httpListener.Addr().String()
+
+	usersFile, groupsFile, err := r.writeAccounts(opts.Accounts)
+	if err != nil {
+		grpcListener.Close()
+		httpListener.Close()
+		return nil, err
+	}
+
+	grpcConf, httpConf := r.config(usersFile, groupsFile)
+	r.grpc, err = rgrpc.NewServer(grpcConf, logger.With().Str("pkg", "rgrpc").Logger())
+	if err != nil {
+		return nil, errors.Wrap(err, "test: error creating grpc server")
+	}
+	r.http, err = rhttp.New(httpConf, logger.With().Str("pkg", "rhttp").Logger())
+	if err != nil {
+		return nil, errors.Wrap(err, "test: error creating http server")
+	}
+
+	// the listeners are already bound, so clients can connect before the servers are serving
+	go func() {
+		if err := r.grpc.Start(grpcListener); err != nil {
+			logger.Error().Err(err).Msg("test: grpc server stopped")
+		}
+	}()
+	go func() {
+		if err := r.http.Start(httpListener); err != nil {
+			logger.Error().Err(err).Msg("test: http server stopped")
+		}
+	}()
+
+	return r, nil
+}
+
+// Stop stops the servers and removes the temporary root directory.
+func (r *Reva) Stop() error {
+	if err := r.grpc.Stop(); err != nil {
+		return err
+	}
+	if err := r.http.Stop(); err != nil {
+		return err
+	}
+	if r.removeRoot {
+		return os.RemoveAll(r.Root)
+	}
+	return nil
+}
+
+func (r *Reva) dataGatewayURL() string {
+	return fmt.Sprintf("http://%s/datagateway", r.HTTPAddr)
+}
+
+// config returns the configuration of the servers. Every service and interceptor gets the
+// addresses and secrets the shared configuration would otherwise provide.
+func (r *Reva) config(usersFile, groupsFile string) (map[string]interface{}, map[string]interface{}) {
+	storageRoot := filepath.Join(r.Root, "storage")
+	storageDriver := map[string]interface{}{
+		"localhome": map[string]interface{}{
+			"root": storageRoot,
+		},
+	}
+	transferSecret := uuid.NewString()
+	tokenManagers := map[string]interface{}{
+		"jwt": map[string]interface{}{"secret": r.jwtSecret},
+	}
+
+	grpcConf := map[string]interface{}{
+		"address": r.GatewayAddr,
+		"interceptors": map[string]interface{}{
+			"auth": map[string]interface{}{
+				"gateway_addr":   r.GatewayAddr,
+				"token_managers": tokenManagers,
+			},
+		},
+		"services": map[string]interface{}{
+			"gateway": map[string]interface{}{
+				"authregistrysvc":          r.GatewayAddr,
+				"applicationauthsvc":       r.GatewayAddr,
+				"storageregistrysvc":       r.GatewayAddr,
+				"appregistrysvc":           r.GatewayAddr,
+				"preferencessvc":           r.GatewayAddr,
+				"userprovidersvc":          r.GatewayAddr,
+				"groupprovidersvc":         r.GatewayAddr,
+				"usershareprovidersvc":     r.GatewayAddr,
+				"publicshareprovidersvc":   r.GatewayAddr,
+				"ocmshareprovidersvc":      r.GatewayAddr,
+				"ocminvitemanagersvc":      r.GatewayAddr,
+				"ocmproviderauthorizersvc": r.GatewayAddr,
+				"ocmcoresvc":               r.GatewayAddr,
+				"datatx":                   r.GatewayAddr,
+				"datagateway":              r.dataGatewayURL(),
+				"transfer_shared_secret":   transferSecret,
+				"transfer_expires":         60,
+				"token_managers":           tokenManagers,
+			},
+			"authregistry": map[string]interface{}{
+				"driver": "static",
+				"drivers": map[string]interface{}{
+					"static": map[string]interface{}{
+						"rules": map[string]interface{}{
+							"basic":        r.GatewayAddr,
+							"publicshares": r.GatewayAddr,
+						},
+					},
+				},
+			},
+			"authprovider": map[string]interface{}{
+				"auth_manager": "json",
+				"auth_managers": map[string]interface{}{
+					"json": map[string]interface{}{"users": usersFile},
+				},
+			},
+			"userprovider": map[string]interface{}{
+				"driver": "json",
+				"drivers": map[string]interface{}{
+					"json": map[string]interface{}{"users": usersFile},
+				},
+			},
+			"groupprovider": map[string]interface{}{
+				"driver": "json",
+				"drivers": map[string]interface{}{
+					"json": map[string]interface{}{"groups": groupsFile},
+				},
+			},
+			"storageregistry": map[string]interface{}{
+				"driver": "static",
+				"drivers": map[string]interface{}{
+					"static": map[string]interface{}{
+						"home_provider": "/home",
+						"rules": map[string]interface{}{
+							"/home":                                map[string]interface{}{"address": r.GatewayAddr},
+							"123e4567-e89b-12d3-a456-426655440000": map[string]interface{}{"address": r.GatewayAddr},
+						},
+					},
+				},
+			},
+			"storageprovider": map[string]interface{}{
+				"driver":               "localhome",
+				"drivers":              storageDriver,
+				"mount_path":           "/home",
+				"mount_id":             "123e4567-e89b-12d3-a456-426655440000",
+				"tmp_folder":           filepath.Join(r.Root, "tmp"),
+				"data_server_url":      fmt.Sprintf("http://%s/data", r.HTTPAddr),
+				"expose_data_server":   false,
+				"enable_home_creation": true,
+				"gateway_addr":         r.GatewayAddr,
+			},
+			"usershareprovider":   map[string]interface{}{"driver": "memory", "gateway_addr": r.GatewayAddr},
+			"publicshareprovider": map[string]interface{}{"driver": "memory"},
+		},
+	}
+
+	httpConf := map[string]interface{}{
+		"address": r.HTTPAddr,
+		"middlewares": map[string]interface{}{
+			"auth": map[string]interface{}{
+				"gatewaysvc":     r.GatewayAddr,
+				"token_managers": tokenManagers,
+			},
+		},
+		"services": map[string]interface{}{
+			"datagateway": map[string]interface{}{
+				"transfer_shared_secret": transferSecret,
+			},
+			"dataprovider": map[string]interface{}{
+				"driver":                 "localhome",
+				"drivers":                storageDriver,
+				"transfer_shared_secret": transferSecret,
+			},
+		},
+	}
+	return grpcConf, httpConf
+}
+
+func (r *Reva) writeAccounts(accounts []*Account) (string, string, error) {
+	type user struct {
+		ID          *userpb.UserId `json:"id"`
+		Username    string         `json:"username"`
+		Secret      string         `json:"secret"`
+		Mail        string         `json:"mail"`
+		DisplayName string         `json:"display_name"`
+		Groups      []string       `json:"groups"`
+	}
+
+	users := []*user{}
+	groups := map[string]*grouppb.Group{}
+	groupNames := []string{}
+	for _, a := range accounts {
+		id := &userpb.UserId{
+			OpaqueId: uuid.NewSHA1(uuid.NameSpaceURL, []byte(idp+"/"+a.Username)).String(),
+			Idp:      idp,
+			Type:     userpb.UserType_USER_TYPE_PRIMARY,
+		}
+		users = append(users, &user{
+			ID:          id,
+			Username:    a.Username,
+			Secret:      a.Password,
+			Mail:        a.Mail,
+			DisplayName: a.DisplayName,
+			Groups:      a.Groups,
+		})
+		for _, name := range a.Groups {
+			g, ok := groups[name]
+			if !ok {
+				g = &grouppb.Group{
+					Id:          &grouppb.GroupId{OpaqueId: name, Idp: idp},
+					GroupName:   name,
+					DisplayName: name,
+				}
+				groups[name] = g
+				groupNames = append(groupNames, name)
+			}
+			g.Members = append(g.Members, id)
+		}
+	}
+	groupList := make([]*grouppb.Group, 0, len(groupNames))
+	for _, name := range groupNames {
+		groupList = append(groupList, groups[name])
+	}
+
+	usersFile := filepath.Join(r.Root, "users.json")
+	groupsFile := filepath.Join(r.Root, "groups.json")
+	for file, v := range map[string]interface{}{usersFile: users, groupsFile: groupList} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", "", errors.Wrap(err, "test: error marshalling accounts")
+		}
+		if err := ioutil.WriteFile(file, data, 0600); err != nil {
+			return "", "", errors.Wrap(err, "test: error writing accounts")
+		}
+	}
+	return usersFile, groupsFile, nil
+}
+
+// Gateway returns a client of the gateway.
+func (r *Reva) Gateway() (gateway.GatewayAPIClient, error) {
+	return pool.GetGatewayServiceClient(pool.Endpoint(r.GatewayAddr))
+}
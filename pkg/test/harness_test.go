@@ -0,0 +1,64 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/sharedconf"
+)
+
+func TestUploadDownload(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in-process deployment in short mode")
+	}
+
+	secret, gw := sharedconf.GetJWTSecret(""), sharedconf.GetGatewaySVC("")
+	r, err := Start(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sharedconf.GetJWTSecret("") != secret || sharedconf.GetGatewaySVC("") != gw {
+		t.Error("expected the shared configuration of the process to be left alone")
+	}
+	defer func() {
+		if err := r.Stop(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	ctx, err := r.Login(context.Background(), "einstein", "relativity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CreateFolder(ctx, "/home/Documents"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Upload(ctx, "/home/Documents/relativity.md", []byte("E = mc²")); err != nil {
+		t.Fatal(err)
+	}
+	content, err := r.Download(ctx, "/home/Documents/relativity.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "E = mc²" {
+		t.Fatalf("unexpected content %q", content)
+	}
+}
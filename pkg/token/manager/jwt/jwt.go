@@ -123,3 +123,19 @@ func (m *manager) DismantleToken(ctx context.Context, tkn string) (*user.User, m
 
 	return nil, nil, errtypes.InvalidCredentials("invalid token")
 }
+
+// IssuedAt returns the time the given token was issued at.
+func (m *manager) IssuedAt(ctx context.Context, tkn string) (time.Time, error) {
+	token, err := jwt.ParseWithClaims(tkn, &claims{}, func(token *jwt.Token) (interface{}, error) {
+		return []byte(m.conf.Secret), nil
+	})
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "error parsing token")
+	}
+
+	if claims, ok := token.Claims.(*claims); ok && token.Valid {
+		return time.Unix(claims.IssuedAt, 0), nil
+	}
+
+	return time.Time{}, errtypes.InvalidCredentials("invalid token")
+}
@@ -0,0 +1,89 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	"github.com/cs3org/reva/pkg/token/revocation/registry"
+)
+
+func init() {
+	registry.Register("memory", New)
+}
+
+type entry struct {
+	before    time.Time
+	expiresAt time.Time
+}
+
+type store struct {
+	sync.RWMutex
+	tokens map[string]time.Time
+	users  map[string]entry
+}
+
+// shared holds the revocations of all the stores of the process, so that the ones
+// written through the revocation service are seen by the auth interceptors.
+var shared = &store{
+	tokens: map[string]time.Time{},
+	users:  map[string]entry{},
+}
+
+// New returns a revocation store keeping the revocations in memory. The revocations
+// are shared by all the stores of the process but neither with other instances nor
+// persisted, so it is only meant for single-instance deployments and tests.
+func New(m map[string]interface{}) (revocation.Store, error) {
+	return shared, nil
+}
+
+func (s *store) RevokeToken(ctx context.Context, tokenID string, ttl time.Duration) error {
+	s.Lock()
+	defer s.Unlock()
+	s.tokens[tokenID] = time.Now().Add(ttl)
+	return nil
+}
+
+func (s *store) RevokeUser(ctx context.Context, userID *userpb.UserId, before time.Time, ttl time.Duration) error {
+	s.Lock()
+	defer s.Unlock()
+	s.users[revocation.UserKey(userID)] = entry{before: before, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *store) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	s.RLock()
+	defer s.RUnlock()
+	expiresAt, ok := s.tokens[tokenID]
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+func (s *store) GetUserRevocation(ctx context.Context, userID *userpb.UserId) (time.Time, error) {
+	s.RLock()
+	defer s.RUnlock()
+	e, ok := s.users[revocation.UserKey(userID)]
+	if !ok || time.Now().After(e.expiresAt) {
+		return time.Time{}, nil
+	}
+	return e.before, nil
+}
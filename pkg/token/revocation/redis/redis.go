@@ -0,0 +1,129 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	"github.com/cs3org/reva/pkg/token/revocation/registry"
+	"github.com/gomodule/redigo/redis"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("redis", New)
+}
+
+const (
+	tokenPrefix = "revoked-token:"
+	userPrefix  = "revoked-user:"
+)
+
+type config struct {
+	RedisAddress  string `mapstructure:"redis_address"`
+	RedisUsername string `mapstructure:"redis_username"`
+	RedisPassword string `mapstructure:"redis_password"`
+}
+
+type store struct {
+	redisPool *redis.Pool
+}
+
+// New returns a revocation store keeping the revocations in redis, expiring them
+// automatically once they are not needed anymore.
+func New(m map[string]interface{}) (revocation.Store, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+
+	if c.RedisAddress == "" {
+		c.RedisAddress = "localhost:6379"
+	}
+
+	pool := &redis.Pool{
+		MaxIdle:     50,
+		MaxActive:   1000,
+		IdleTimeout: 240 * time.Second,
+
+		Dial: func() (redis.Conn, error) {
+			var opts []redis.DialOption
+			if c.RedisUsername != "" {
+				opts = append(opts, redis.DialUsername(c.RedisUsername))
+			}
+			if c.RedisPassword != "" {
+				opts = append(opts, redis.DialPassword(c.RedisPassword))
+			}
+			return redis.Dial("tcp", c.RedisAddress, opts...)
+		},
+
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+
+	return &store{redisPool: pool}, nil
+}
+
+func (s *store) set(key string, value int64, ttl time.Duration) error {
+	conn := s.redisPool.Get()
+	defer conn.Close()
+	_, err := conn.Do("SET", key, value, "EX", int(ttl.Seconds()))
+	return err
+}
+
+func (s *store) get(key string) (int64, error) {
+	conn := s.redisPool.Get()
+	defer conn.Close()
+	v, err := redis.String(conn.Do("GET", key))
+	if err == redis.ErrNil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
+
+func (s *store) RevokeToken(ctx context.Context, tokenID string, ttl time.Duration) error {
+	return s.set(tokenPrefix+tokenID, time.Now().Unix(), ttl)
+}
+
+func (s *store) RevokeUser(ctx context.Context, userID *userpb.UserId, before time.Time, ttl time.Duration) error {
+	return s.set(userPrefix+revocation.UserKey(userID), before.Unix(), ttl)
+}
+
+func (s *store) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	v, err := s.get(tokenPrefix + tokenID)
+	return v != 0, err
+}
+
+func (s *store) GetUserRevocation(ctx context.Context, userID *userpb.UserId) (time.Time, error) {
+	v, err := s.get(userPrefix + revocation.UserKey(userID))
+	if err != nil || v == 0 {
+		return time.Time{}, err
+	}
+	return time.Unix(v, 0), nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package registry
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cs3org/reva/pkg/token/revocation"
+	"github.com/pkg/errors"
+)
+
+// NewFunc is the function that revocation stores
+// should register at init time.
+type NewFunc func(map[string]interface{}) (revocation.Store, error)
+
+// NewFuncs is a map containing all the registered revocation stores.
+var NewFuncs = map[string]NewFunc{}
+
+// Register registers a new revocation store function.
+// Not safe for concurrent use. Safe for use from package init.
+func Register(name string, f NewFunc) {
+	NewFuncs[name] = f
+}
+
+// NewStore returns the revocation store registered under the given name, created with
+// its entry in conf. No store is returned if the name is empty.
+func NewStore(name string, conf map[string]map[string]interface{}) (revocation.Store, error) {
+	if name == "" {
+		return nil, nil
+	}
+	f, ok := NewFuncs[name]
+	if !ok {
+		return nil, fmt.Errorf("revocation store not found: %s", name)
+	}
+	store, err := f(conf[name])
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating revocation store")
+	}
+	return store, nil
+}
+
+// NewChecker returns a checker of the revocation store registered under the given name,
+// caching the lookups for ttl. No checker is returned if the name is empty.
+func NewChecker(name string, conf map[string]map[string]interface{}, ttl time.Duration) (*revocation.Checker, error) {
+	store, err := NewStore(name, conf)
+	if err != nil || store == nil {
+		return nil, err
+	}
+	return revocation.NewChecker(store, ttl), nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package revocation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/bluele/gcache"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/token"
+)
+
+// Store is the interface to implement shared stores of revoked tokens.
+type Store interface {
+	// RevokeToken revokes the token with the given ID. The revocation is kept for the given duration,
+	// which should be at least the remaining lifetime of the token.
+	RevokeToken(ctx context.Context, tokenID string, ttl time.Duration) error
+	// RevokeUser revokes all the tokens of the user issued before the given time.
+	RevokeUser(ctx context.Context, userID *userpb.UserId, before time.Time, ttl time.Duration) error
+	// IsTokenRevoked checks whether the token with the given ID was revoked.
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+	// GetUserRevocation returns the time before which all the tokens of the user are revoked.
+	// The zero time is returned if the tokens of the user were never revoked.
+	GetUserRevocation(ctx context.Context, userID *userpb.UserId) (time.Time, error)
+}
+
+// TokenID returns the ID under which a token is stored, so that the tokens themselves are never persisted.
+func TokenID(tkn string) string {
+	h := sha256.Sum256([]byte(tkn))
+	return hex.EncodeToString(h[:])
+}
+
+// UserKey returns the key under which the revocations of a user are stored.
+func UserKey(userID *userpb.UserId) string {
+	return userID.Idp + "!" + userID.OpaqueId
+}
+
+// Checker verifies tokens against a revocation store. The lookups are cached locally for
+// a short time, so revocations take effect on all the instances after at most that time.
+type Checker struct {
+	store Store
+	ttl   time.Duration
+	cache gcache.Cache
+}
+
+// NewChecker returns a checker caching the lookups in the given store for ttl.
+func NewChecker(store Store, ttl time.Duration) *Checker {
+	return &Checker{
+		store: store,
+		ttl:   ttl,
+		cache: gcache.New(100000).LRU().Build(),
+	}
+}
+
+// Store returns the store the checker looks the revocations up in.
+func (c *Checker) Store() Store {
+	return c.store
+}
+
+// IsRevoked checks whether the given token of the user was revoked. If the token manager
+// cannot tell when the token was issued, the token is considered revoked as soon as all
+// the tokens of the user have been revoked.
+func (c *Checker) IsRevoked(ctx context.Context, mgr token.Manager, tkn string, u *userpb.User) (bool, error) {
+	revoked, err := c.isTokenRevoked(ctx, TokenID(tkn))
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	if u == nil || u.Id == nil {
+		return false, nil
+	}
+	before, err := c.getUserRevocation(ctx, u.Id)
+	if err != nil || before.IsZero() {
+		return false, err
+	}
+
+	if i, ok := mgr.(token.Inspector); ok {
+		issuedAt, err := i.IssuedAt(ctx, tkn)
+		if err == nil {
+			return !issuedAt.After(before), nil
+		}
+	}
+	return true, nil
+}
+
+func (c *Checker) isTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	key := "token:" + tokenID
+	if v, err := c.cache.Get(key); err == nil {
+		return v.(bool), nil
+	}
+	revoked, err := c.store.IsTokenRevoked(ctx, tokenID)
+	if err != nil {
+		return false, err
+	}
+	_ = c.cache.SetWithExpire(key, revoked, c.ttl)
+	return revoked, nil
+}
+
+func (c *Checker) getUserRevocation(ctx context.Context, userID *userpb.UserId) (time.Time, error) {
+	key := "user:" + UserKey(userID)
+	if v, err := c.cache.Get(key); err == nil {
+		return v.(time.Time), nil
+	}
+	before, err := c.store.GetUserRevocation(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	_ = c.cache.SetWithExpire(key, before, c.ttl)
+	return before, nil
+}
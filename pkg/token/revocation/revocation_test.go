@@ -0,0 +1,100 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package revocation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/token"
+)
+
+type fakeStore struct {
+	tokens map[string]bool
+	users  map[string]time.Time
+	calls  int
+}
+
+func (s *fakeStore) RevokeToken(ctx context.Context, tokenID string, ttl time.Duration) error {
+	s.tokens[tokenID] = true
+	return nil
+}
+
+func (s *fakeStore) RevokeUser(ctx context.Context, userID *userpb.UserId, before time.Time, ttl time.Duration) error {
+	s.users[UserKey(userID)] = before
+	return nil
+}
+
+func (s *fakeStore) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	s.calls++
+	return s.tokens[tokenID], nil
+}
+
+func (s *fakeStore) GetUserRevocation(ctx context.Context, userID *userpb.UserId) (time.Time, error) {
+	return s.users[UserKey(userID)], nil
+}
+
+// fakeManager issues tokens whose value is the time they were issued at.
+type fakeManager struct {
+	token.Manager
+}
+
+func (m *fakeManager) IssuedAt(ctx context.Context, tkn string) (time.Time, error) {
+	return time.Parse(time.RFC3339, tkn)
+}
+
+func TestChecker(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeStore{tokens: map[string]bool{}, users: map[string]time.Time{}}
+	mgr := &fakeManager{}
+	u := &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "einstein"}}
+
+	old := "2021-01-01T10:00:00Z"
+	recent := "2021-01-01T12:00:00Z"
+
+	c := NewChecker(store, time.Minute)
+	if revoked, _ := c.IsRevoked(ctx, mgr, old, u); revoked {
+		t.Fatal("token must not be revoked")
+	}
+
+	_ = store.RevokeToken(ctx, TokenID(old), time.Hour)
+	if revoked, _ := c.IsRevoked(ctx, mgr, old, u); revoked {
+		t.Fatal("cached lookup expected")
+	}
+	if store.calls != 1 {
+		t.Fatalf("expected 1 store lookup, got %d", store.calls)
+	}
+
+	c = NewChecker(store, time.Minute)
+	if revoked, _ := c.IsRevoked(ctx, mgr, old, u); !revoked {
+		t.Fatal("token must be revoked")
+	}
+
+	before, _ := time.Parse(time.RFC3339, "2021-01-01T11:00:00Z")
+	_ = store.RevokeUser(ctx, u.Id, before, time.Hour)
+	c = NewChecker(store, time.Minute)
+	if revoked, _ := c.IsRevoked(ctx, mgr, "2021-01-01T09:00:00Z", u); !revoked {
+		t.Fatal("tokens issued before the user revocation must be revoked")
+	}
+	if revoked, _ := c.IsRevoked(ctx, mgr, recent, u); revoked {
+		t.Fatal("tokens issued after the user revocation must not be revoked")
+	}
+}
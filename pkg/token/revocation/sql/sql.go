@@ -0,0 +1,120 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/migrate"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	"github.com/cs3org/reva/pkg/token/revocation/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+
+	// Provides mysql drivers
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func init() {
+	registry.Register("sql", New)
+}
+
+const (
+	kindToken = "token"
+	kindUser  = "user"
+)
+
+type config struct {
+	DbUsername string `mapstructure:"db_username"`
+	DbPassword string `mapstructure:"db_password"`
+	DbHost     string `mapstructure:"db_host"`
+	DbPort     int    `mapstructure:"db_port"`
+	DbName     string `mapstructure:"db_name"`
+	// SkipMigrations disables applying the pending schema migrations on startup.
+	SkipMigrations bool `mapstructure:"skip_migrations"`
+}
+
+type store struct {
+	db *sql.DB
+}
+
+// New returns a revocation store persisting the revocations in a SQL database.
+func New(m map[string]interface{}) (revocation.Store, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", c.DbUsername, c.DbPassword, c.DbHost, c.DbPort, c.DbName))
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.SkipMigrations {
+		if err := migrate.Up(context.Background(), db, Schema); err != nil {
+			return nil, err
+		}
+	}
+	return &store{db: db}, nil
+}
+
+func (s *store) revoke(ctx context.Context, kind, id string, before time.Time, ttl time.Duration) error {
+	now := time.Now()
+	// expired revocations are not needed anymore
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM token_revocations WHERE expires_at < ?", now.Unix()); err != nil {
+		return err
+	}
+	query := "INSERT INTO token_revocations (kind, id, revoked_before, expires_at) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE revoked_before = VALUES(revoked_before), expires_at = VALUES(expires_at)"
+	_, err := s.db.ExecContext(ctx, query, kind, id, before.Unix(), now.Add(ttl).Unix())
+	return err
+}
+
+func (s *store) lookup(ctx context.Context, kind, id string) (time.Time, error) {
+	var before int64
+	query := "SELECT revoked_before FROM token_revocations WHERE kind = ? AND id = ? AND expires_at >= ?"
+	err := s.db.QueryRowContext(ctx, query, kind, id, time.Now().Unix()).Scan(&before)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(before, 0), nil
+}
+
+func (s *store) RevokeToken(ctx context.Context, tokenID string, ttl time.Duration) error {
+	return s.revoke(ctx, kindToken, tokenID, time.Now(), ttl)
+}
+
+func (s *store) RevokeUser(ctx context.Context, userID *userpb.UserId, before time.Time, ttl time.Duration) error {
+	return s.revoke(ctx, kindUser, revocation.UserKey(userID), before, ttl)
+}
+
+func (s *store) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	t, err := s.lookup(ctx, kindToken, tokenID)
+	return !t.IsZero(), err
+}
+
+func (s *store) GetUserRevocation(ctx context.Context, userID *userpb.UserId) (time.Time, error) {
+	return s.lookup(ctx, kindUser, revocation.UserKey(userID))
+}
@@ -20,6 +20,7 @@ package token
 
 import (
 	"context"
+	"time"
 
 	auth "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
 	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
@@ -30,3 +31,8 @@ type Manager interface {
 	MintToken(ctx context.Context, u *user.User, scope map[string]*auth.Scope) (string, error)
 	DismantleToken(ctx context.Context, token string) (*user.User, map[string]*auth.Scope, error)
 }
+
+// Inspector is implemented by the managers that can report when a token was issued.
+type Inspector interface {
+	IssuedAt(ctx context.Context, token string) (time.Time, error)
+}
@@ -0,0 +1,108 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	var body []byte
+	var path, auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	exp, err := newOTLPExporter(srv.URL, map[string]string{"Authorization": "Bearer secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	_, child := tp.Tracer("test").Start(ctx, "child", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("path", "/a"), attribute.Int("size", 5), attribute.Bool("ok", true)))
+	child.SetStatus(codes.Error, "failed")
+	child.End()
+
+	if path != otlpTracesPath || auth != "Bearer secret" {
+		t.Fatalf("unexpected request to %s with authorization %q", path, auth)
+	}
+	req := &otlpRequest{}
+	if err := json.Unmarshal(body, req); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("unexpected request %s", body)
+	}
+	scope := req.ResourceSpans[0].ScopeSpans[0]
+	if scope.Scope.Name != "test" || len(scope.Spans) != 1 {
+		t.Fatalf("unexpected scope %s", body)
+	}
+
+	span := scope.Spans[0]
+	if span.Name != "child" || span.Kind != int(trace.SpanKindClient) {
+		t.Fatalf("unexpected span %+v", span)
+	}
+	if span.TraceID != parent.SpanContext().TraceID().String() || span.ParentSpanID != parent.SpanContext().SpanID().String() {
+		t.Fatalf("expected the span to be a child of %s, got %+v", parent.SpanContext().SpanID(), span)
+	}
+	if span.Status.Code != otlpStatusError || span.Status.Message != "failed" {
+		t.Fatalf("unexpected status %+v", span.Status)
+	}
+	attrs := map[string]otlpAnyValue{}
+	for _, a := range span.Attributes {
+		attrs[a.Key] = a.Value
+	}
+	if len(attrs) != 3 || *attrs["path"].StringValue != "/a" || *attrs["size"].IntValue != "5" || !*attrs["ok"].BoolValue {
+		t.Fatalf("unexpected attributes %s", body)
+	}
+}
+
+func TestOTLPEndpoint(t *testing.T) {
+	for endpoint, expected := range map[string]string{
+		"":                              "http://localhost:4318/v1/traces",
+		"https://collector:4318/":       "https://collector:4318/v1/traces",
+		"https://collector/custom/path": "https://collector/custom/path",
+	} {
+		exp, err := newOTLPExporter(endpoint, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp.endpoint != expected {
+			t.Fatalf("expected %s for %q, got %s", expected, endpoint, exp.endpoint)
+		}
+	}
+	if _, err := newOTLPExporter("collector:4318", nil); err == nil {
+		t.Fatal("expected an error for an endpoint without scheme")
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"net/url"
 	"strings"
 
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/jaeger"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
@@ -31,6 +32,12 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
+// The exporters of the spans.
+const (
+	ExporterJaeger = "jaeger"
+	ExporterOTLP   = "otlp"
+)
+
 var (
 	// Propagator is the default Reva propagator.
 	Propagator = propagation.NewCompositeTextMapPropagator(propagation.Baggage{}, propagation.TraceContext{})
@@ -41,11 +48,6 @@ var (
 
 // SetTraceProvider sets the TracerProvider at a package level.
 func SetTraceProvider(collectorEndpoint string, agentEndpoint, serviceName string) {
-	// default to 'reva' as service name if not set
-	if serviceName == "" {
-		serviceName = "reva"
-	}
-
 	var exp *jaeger.Exporter
 	var err error
 
@@ -76,13 +78,40 @@ func SetTraceProvider(collectorEndpoint string, agentEndpoint, serviceName strin
 		}
 	}
 
-	Provider = sdktrace.NewTracerProvider(
+	setProvider(exp, serviceName)
+}
+
+// SetOTLPTraceProvider sets the TracerProvider at a package level, exporting
+// the spans to an OpenTelemetry collector with the OTLP/HTTP protocol.
+// The path of the traces is appended to an endpoint without a path.
+func SetOTLPTraceProvider(endpoint string, headers map[string]string, serviceName string) error {
+	exp, err := newOTLPExporter(endpoint, headers)
+	if err != nil {
+		return err
+	}
+	setProvider(exp, serviceName)
+	return nil
+}
+
+// setProvider sets the TracerProvider exporting the spans with exp. It is
+// registered globally as well, together with the propagator, so that the
+// libraries instrumented with OpenTelemetry join the traces of reva.
+func setProvider(exp sdktrace.SpanExporter, serviceName string) {
+	// default to 'reva' as service name if not set
+	if serviceName == "" {
+		serviceName = "reva"
+	}
+
+	tp := sdktrace.NewTracerProvider(
 		sdktrace.WithBatcher(exp),
 		sdktrace.WithResource(resource.NewWithAttributes(
 			semconv.SchemaURL,
 			semconv.ServiceNameKey.String(serviceName),
 		)),
 	)
+	Provider = tp
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(Propagator)
 }
 
 func parseAgentConfig(ae string) (string, string, error) {
@@ -22,8 +22,11 @@ import (
 	"context"
 	"encoding/json"
 	"io/ioutil"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/cs3org/reva/pkg/user"
 	"github.com/cs3org/reva/pkg/user/manager/registry"
@@ -39,7 +42,10 @@ func init() {
 }
 
 type manager struct {
-	users []*userpb.User
+	mutex   sync.RWMutex
+	path    string
+	modTime time.Time
+	users   []*userpb.User
 }
 
 type config struct {
@@ -79,7 +85,19 @@ func (m *manager) Configure(ml map[string]interface{}) error {
 		return err
 	}
 
-	f, err := ioutil.ReadFile(c.Users)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.path = c.Users
+	return m.load()
+}
+
+// load reads the users file. The caller must hold the write lock.
+func (m *manager) load() error {
+	info, err := os.Stat(m.path)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.ReadFile(m.path)
 	if err != nil {
 		return err
 	}
@@ -91,11 +109,32 @@ func (m *manager) Configure(ml map[string]interface{}) error {
 		return err
 	}
 	m.users = users
+	m.modTime = info.ModTime()
 	return nil
 }
 
+// getUsers returns the users, reloading the file when it has been modified
+// so that changes like the group memberships apply without a restart. The
+// users loaded last are kept if the file can't be read.
+func (m *manager) getUsers() []*userpb.User {
+	m.mutex.RLock()
+	users, modTime := m.users, m.modTime
+	m.mutex.RUnlock()
+
+	if info, err := os.Stat(m.path); err != nil || info.ModTime().Equal(modTime) {
+		return users
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if info, err := os.Stat(m.path); err == nil && !info.ModTime().Equal(m.modTime) {
+		_ = m.load()
+	}
+	return m.users
+}
+
 func (m *manager) GetUser(ctx context.Context, uid *userpb.UserId, skipFetchingGroups bool) (*userpb.User, error) {
-	for _, u := range m.users {
+	for _, u := range m.getUsers() {
 		if (u.Id.GetOpaqueId() == uid.OpaqueId || u.Username == uid.OpaqueId) && (uid.Idp == "" || uid.Idp == u.Id.GetIdp()) {
 			user := *u
 			if skipFetchingGroups {
@@ -108,7 +147,7 @@ func (m *manager) GetUser(ctx context.Context, uid *userpb.UserId, skipFetchingG
 }
 
 func (m *manager) GetUserByClaim(ctx context.Context, claim, value string, skipFetchingGroups bool) (*userpb.User, error) {
-	for _, u := range m.users {
+	for _, u := range m.getUsers() {
 		if userClaim, err := extractClaim(u, claim); err == nil && value == userClaim {
 			user := *u
 			if skipFetchingGroups {
@@ -143,7 +182,7 @@ func userContains(u *userpb.User, query string) bool {
 
 func (m *manager) FindUsers(ctx context.Context, query string, skipFetchingGroups bool) ([]*userpb.User, error) {
 	users := []*userpb.User{}
-	for _, u := range m.users {
+	for _, u := range m.getUsers() {
 		if userContains(u, query) {
 			user := *u
 			if skipFetchingGroups {
@@ -22,8 +22,10 @@ import (
 	"context"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	"github.com/cs3org/reva/pkg/errtypes"
@@ -147,3 +149,49 @@ func TestUserManager(t *testing.T) {
 		t.Fatalf("user differ: expected=%v got=%v", "einstein", resUser[0].Username)
 	}
 }
+
+func TestReloadOnChange(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "json_test")
+	if err != nil {
+		t.Fatalf("error while create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempdir)
+
+	path := filepath.Join(tempdir, "users.json")
+	write := func(groups string, mtime time.Time) {
+		userJSON := `[{"id":{"idp":"localhost","opaque_id":"einstein","type":1},"username":"einstein","groups":[` + groups + `]}]`
+		if err := ioutil.WriteFile(path, []byte(userJSON), 0600); err != nil {
+			t.Fatalf("error while writing temp file: %v", err)
+		}
+		// the modification time is set explicitly as it might not change within the resolution of the filesystem
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("error while setting modification time: %v", err)
+		}
+	}
+
+	now := time.Now()
+	write(`"sailing-lovers"`, now.Add(-time.Minute))
+	manager, err := New(map[string]interface{}{"users": path})
+	if err != nil {
+		t.Fatalf("error while getting manager: %v", err)
+	}
+
+	uid := &userpb.UserId{OpaqueId: "einstein"}
+	write(`"sailing-lovers","physics-lovers"`, now)
+	groups, err := manager.GetUserGroups(ctx, uid)
+	if err != nil {
+		t.Fatalf("error while getting groups: %v", err)
+	}
+	if !reflect.DeepEqual(groups, []string{"sailing-lovers", "physics-lovers"}) {
+		t.Fatalf("expected the groups of the modified file, got %v", groups)
+	}
+
+	// the users loaded last are kept while the file is broken
+	if err := ioutil.WriteFile(path, []byte(`[{`), 0600); err != nil {
+		t.Fatalf("error while writing temp file: %v", err)
+	}
+	_ = os.Chtimes(path, now.Add(time.Minute), now.Add(time.Minute))
+	if _, err := manager.GetUser(ctx, uid, true); err != nil {
+		t.Fatalf("expected the previous users to be kept, got %v", err)
+	}
+}
@@ -28,6 +28,7 @@ import (
 
 	"github.com/Masterminds/sprig"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/affiliation"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/user"
@@ -75,6 +76,12 @@ type attributes struct {
 	UIDNumber string `mapstructure:"uidNumber"`
 	// GIDNumber is a numeric id that maps to a filesystem gid, eg. 654321
 	GIDNumber string `mapstructure:"gidNumber"`
+	// Affiliation is the optional multi-valued affiliation of a user, e.g. `eduPersonAffiliation`
+	Affiliation string `mapstructure:"affiliation"`
+	// Department is the optional department of a user, e.g. `departmentNumber`
+	Department string `mapstructure:"department"`
+	// Country is the optional country of a user as an ISO 3166 code, e.g. `c`
+	Country string `mapstructure:"country"`
 }
 
 // Default attributes (Active Directory)
@@ -141,6 +148,17 @@ func (m *manager) Configure(ml map[string]interface{}) error {
 	return nil
 }
 
+// Warmup checks that the LDAP server can be reached and that the bind
+// credentials are valid.
+func (m *manager) Warmup(ctx context.Context) error {
+	l, err := utils.GetLDAPConnection(&m.c.LDAPConn)
+	if err != nil {
+		return errors.Wrap(err, "ldap: error binding to the server")
+	}
+	l.Close()
+	return nil
+}
+
 func (m *manager) GetUser(ctx context.Context, uid *userpb.UserId, skipFetchingGroups bool) (*userpb.User, error) {
 	log := appctx.GetLogger(ctx)
 	l, err := utils.GetLDAPConnection(&m.c.LDAPConn)
@@ -154,7 +172,7 @@ func (m *manager) GetUser(ctx context.Context, uid *userpb.UserId, skipFetchingG
 		m.c.BaseDN,
 		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
 		m.getUserFilter(uid),
-		[]string{m.c.Schema.DN, m.c.Schema.UID, m.c.Schema.CN, m.c.Schema.Mail, m.c.Schema.DisplayName, m.c.Schema.UIDNumber, m.c.Schema.GIDNumber},
+		m.userAttributes(),
 		nil,
 	)
 
@@ -208,6 +226,7 @@ func (m *manager) GetUser(ctx context.Context, uid *userpb.UserId, skipFetchingG
 		GidNumber:   gidNumber,
 		UidNumber:   uidNumber,
 	}
+	m.setAffiliation(u, sr.Entries[0])
 
 	return u, nil
 }
@@ -241,7 +260,7 @@ func (m *manager) GetUserByClaim(ctx context.Context, claim, value string, skipF
 		m.c.BaseDN,
 		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
 		m.getAttributeFilter(claim, value),
-		[]string{m.c.Schema.DN, m.c.Schema.UID, m.c.Schema.CN, m.c.Schema.Mail, m.c.Schema.DisplayName, m.c.Schema.UIDNumber, m.c.Schema.GIDNumber},
+		m.userAttributes(),
 		nil,
 	)
 
@@ -295,6 +314,7 @@ func (m *manager) GetUserByClaim(ctx context.Context, claim, value string, skipF
 		GidNumber:   gidNumber,
 		UidNumber:   uidNumber,
 	}
+	m.setAffiliation(u, sr.Entries[0])
 
 	return u, nil
 
@@ -312,7 +332,7 @@ func (m *manager) FindUsers(ctx context.Context, query string, skipFetchingGroup
 		m.c.BaseDN,
 		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
 		m.getFindFilter(query),
-		[]string{m.c.Schema.DN, m.c.Schema.UID, m.c.Schema.CN, m.c.Schema.Mail, m.c.Schema.DisplayName, m.c.Schema.UIDNumber, m.c.Schema.GIDNumber},
+		m.userAttributes(),
 		nil,
 	)
 
@@ -363,12 +383,43 @@ func (m *manager) FindUsers(ctx context.Context, query string, skipFetchingGroup
 			GidNumber:   gidNumber,
 			UidNumber:   uidNumber,
 		}
+		m.setAffiliation(user, entry)
 		users = append(users, user)
 	}
 
 	return users, nil
 }
 
+func (m *manager) userAttributes() []string {
+	attrs := []string{m.c.Schema.DN, m.c.Schema.UID, m.c.Schema.CN, m.c.Schema.Mail, m.c.Schema.DisplayName, m.c.Schema.UIDNumber, m.c.Schema.GIDNumber}
+	if m.c.Schema.Affiliation != "" {
+		attrs = append(attrs, m.c.Schema.Affiliation)
+	}
+	if m.c.Schema.Department != "" {
+		attrs = append(attrs, m.c.Schema.Department)
+	}
+	if m.c.Schema.Country != "" {
+		attrs = append(attrs, m.c.Schema.Country)
+	}
+	return attrs
+}
+
+// setAffiliation stores the affiliations, the department and the country of an entry in the user opaque.
+func (m *manager) setAffiliation(u *userpb.User, entry *ldap.Entry) {
+	var affiliations []string
+	if m.c.Schema.Affiliation != "" {
+		affiliations = affiliation.Normalize(entry.GetEqualFoldAttributeValues(m.c.Schema.Affiliation))
+	}
+	var department string
+	if m.c.Schema.Department != "" {
+		department = entry.GetEqualFoldAttributeValue(m.c.Schema.Department)
+	}
+	affiliation.Set(u, affiliations, department)
+	if m.c.Schema.Country != "" {
+		affiliation.SetCountry(u, entry.GetEqualFoldAttributeValue(m.c.Schema.Country))
+	}
+}
+
 func (m *manager) GetUserGroups(ctx context.Context, uid *userpb.UserId) ([]string, error) {
 	l, err := utils.GetLDAPConnection(&m.c.LDAPConn)
 	if err != nil {
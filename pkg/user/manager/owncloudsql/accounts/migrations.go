@@ -0,0 +1,68 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package accounts
+
+import (
+	"context"
+
+	"github.com/cs3org/reva/pkg/migrate"
+)
+
+// Component is the migration component of the ownCloud account tables read by the owncloudsql user manager.
+const Component = "owncloud_accounts"
+
+func init() {
+	// the tables belong to the ownCloud database, so they are only created when missing
+	// and they are left in place when the migration is reverted
+	migrate.Register(Component, migrate.Migration{
+		Version:     1,
+		Description: "create the account, group membership and preference tables",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS oc_accounts (
+				id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				email VARCHAR(255) DEFAULT NULL,
+				user_id VARCHAR(255) NOT NULL UNIQUE,
+				lower_user_id VARCHAR(255) NOT NULL UNIQUE,
+				display_name VARCHAR(255) DEFAULT NULL,
+				quota VARCHAR(32) DEFAULT NULL,
+				last_login INT NOT NULL DEFAULT 0,
+				backend VARCHAR(64) NOT NULL,
+				home VARCHAR(1024) NOT NULL,
+				state SMALLINT NOT NULL DEFAULT 0
+			)`,
+			`CREATE TABLE IF NOT EXISTS oc_group_user (
+				gid VARCHAR(64) NOT NULL DEFAULT '',
+				uid VARCHAR(64) NOT NULL DEFAULT '',
+				PRIMARY KEY (gid, uid)
+			)`,
+			`CREATE TABLE IF NOT EXISTS oc_preferences (
+				userid VARCHAR(64) NOT NULL,
+				appid VARCHAR(32) NOT NULL,
+				configkey VARCHAR(64) NOT NULL,
+				configvalue LONGTEXT,
+				PRIMARY KEY (userid, appid, configkey)
+			)`,
+		},
+	})
+}
+
+// InitSchema applies all pending migrations of the tables.
+func (as *Accounts) InitSchema(ctx context.Context) error {
+	return migrate.Up(ctx, as.db, Component)
+}
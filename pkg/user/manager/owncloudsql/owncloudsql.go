@@ -56,6 +56,8 @@ type config struct {
 	JoinUsername       bool   `mapstructure:"join_username"`
 	JoinOwnCloudUUID   bool   `mapstructure:"join_ownclouduuid"`
 	EnableMedialSearch bool   `mapstructure:"enable_medial_search"`
+	// SkipMigrations disables applying the pending schema migrations on startup.
+	SkipMigrations bool `mapstructure:"skip_migrations"`
 }
 
 // NewMysql returns a new user manager connection to an owncloud mysql database
@@ -77,6 +79,12 @@ func NewMysql(m map[string]interface{}) (user.Manager, error) {
 		return nil, err
 	}
 
+	if !mgr.c.SkipMigrations {
+		if err := mgr.db.InitSchema(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
 	return mgr, nil
 }
 
@@ -0,0 +1,365 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/migrate"
+	"github.com/pkg/errors"
+
+	// Provides mysql drivers
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// Component is the migration component of the tables used by the sql user, group and auth managers.
+const Component = "identity"
+
+func init() {
+	migrate.Register(Component, migrate.Migration{
+		Version:     1,
+		Description: "create the user and group tables",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS identity_users (
+				id VARCHAR(64) NOT NULL PRIMARY KEY,
+				username VARCHAR(255) NOT NULL UNIQUE,
+				mail VARCHAR(255) NOT NULL DEFAULT '',
+				display_name VARCHAR(255) NOT NULL DEFAULT '',
+				uid_number BIGINT NOT NULL DEFAULT 0,
+				gid_number BIGINT NOT NULL DEFAULT 0,
+				password_hash VARCHAR(255) NOT NULL DEFAULT '',
+				disabled BOOLEAN NOT NULL DEFAULT FALSE
+			)`,
+			`CREATE TABLE IF NOT EXISTS identity_groups (
+				id VARCHAR(64) NOT NULL PRIMARY KEY,
+				group_name VARCHAR(255) NOT NULL UNIQUE,
+				mail VARCHAR(255) NOT NULL DEFAULT '',
+				display_name VARCHAR(255) NOT NULL DEFAULT '',
+				gid_number BIGINT NOT NULL DEFAULT 0
+			)`,
+			`CREATE TABLE IF NOT EXISTS identity_group_members (
+				group_id VARCHAR(64) NOT NULL,
+				user_id VARCHAR(64) NOT NULL,
+				PRIMARY KEY (group_id, user_id)
+			)`,
+		},
+		Down: []string{
+			"DROP TABLE IF EXISTS identity_group_members",
+			"DROP TABLE IF EXISTS identity_groups",
+			"DROP TABLE IF EXISTS identity_users",
+		},
+	})
+}
+
+// Account is a user stored in the database.
+type Account struct {
+	ID           string
+	Username     string
+	Mail         string
+	DisplayName  string
+	UIDNumber    int64
+	GIDNumber    int64
+	PasswordHash string
+	Disabled     bool
+}
+
+// Group is a group stored in the database.
+type Group struct {
+	ID          string
+	GroupName   string
+	Mail        string
+	DisplayName string
+	GIDNumber   int64
+}
+
+// Accounts provides access to the users and groups stored in the database.
+type Accounts struct {
+	db *sql.DB
+}
+
+// NewMysql returns a new Accounts instance connecting to a MySQL database.
+func NewMysql(dsn string) (*Accounts, error) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "accounts: error connecting to the database")
+	}
+	return New(db), nil
+}
+
+// New returns a new Accounts instance using the given database.
+func New(db *sql.DB) *Accounts {
+	return &Accounts{db: db}
+}
+
+// InitSchema applies all pending migrations of the tables.
+func (a *Accounts) InitSchema(ctx context.Context) error {
+	return migrate.Up(ctx, a.db, Component)
+}
+
+const selectAccount = "SELECT id, username, mail, display_name, uid_number, gid_number, password_hash, disabled FROM identity_users"
+
+func scanAccount(row interface{ Scan(...interface{}) error }) (*Account, error) {
+	a := &Account{}
+	if err := row.Scan(&a.ID, &a.Username, &a.Mail, &a.DisplayName, &a.UIDNumber, &a.GIDNumber, &a.PasswordHash, &a.Disabled); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+var accountClaims = map[string]string{
+	"userid":   "id",
+	"username": "username",
+	"mail":     "mail",
+	"uid":      "uid_number",
+	"gid":      "gid_number",
+}
+
+// GetAccountByClaim returns the account with the given value for the claim.
+func (a *Accounts) GetAccountByClaim(ctx context.Context, claim, value string) (*Account, error) {
+	column, ok := accountClaims[claim]
+	if !ok {
+		return nil, errors.New("accounts: invalid claim " + claim)
+	}
+	acc, err := scanAccount(a.db.QueryRowContext(ctx, selectAccount+" WHERE "+column+" = ?", value))
+	if err == sql.ErrNoRows {
+		return nil, errtypes.NotFound(claim + "=" + value)
+	}
+	return acc, err
+}
+
+// FindAccounts returns the enabled accounts whose username, mail or display name contain the query.
+func (a *Accounts) FindAccounts(ctx context.Context, query string) ([]*Account, error) {
+	like := "%" + escapeLike(query) + "%"
+	rows, err := a.db.QueryContext(ctx, selectAccount+" WHERE disabled = FALSE AND (username LIKE ? OR mail LIKE ? OR display_name LIKE ?)", like, like, like)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []*Account{}
+	for rows.Next() {
+		acc, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, acc)
+	}
+	return accounts, rows.Err()
+}
+
+// GetAccountGroups returns the names of the groups the account is member of.
+func (a *Accounts) GetAccountGroups(ctx context.Context, id string) ([]string, error) {
+	rows, err := a.db.QueryContext(ctx, "SELECT g.group_name FROM identity_groups g JOIN identity_group_members m ON g.id = m.group_id WHERE m.user_id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		groups = append(groups, name)
+	}
+	return groups, rows.Err()
+}
+
+// CreateAccount stores a new account.
+func (a *Accounts) CreateAccount(ctx context.Context, acc *Account) error {
+	_, err := a.db.ExecContext(ctx,
+		"INSERT INTO identity_users (id, username, mail, display_name, uid_number, gid_number, password_hash, disabled) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		acc.ID, acc.Username, acc.Mail, acc.DisplayName, acc.UIDNumber, acc.GIDNumber, acc.PasswordHash, acc.Disabled)
+	return err
+}
+
+// UpdateAccount updates the attributes of an account. The password and the disabled state are changed separately.
+func (a *Accounts) UpdateAccount(ctx context.Context, acc *Account) error {
+	return a.update(ctx, "identity_users", acc.ID,
+		"UPDATE identity_users SET username = ?, mail = ?, display_name = ?, uid_number = ?, gid_number = ? WHERE id = ?",
+		acc.Username, acc.Mail, acc.DisplayName, acc.UIDNumber, acc.GIDNumber, acc.ID)
+}
+
+// SetPasswordHash sets the password hash of an account.
+func (a *Accounts) SetPasswordHash(ctx context.Context, id, hash string) error {
+	return a.update(ctx, "identity_users", id, "UPDATE identity_users SET password_hash = ? WHERE id = ?", hash, id)
+}
+
+// SetDisabled enables or disables an account.
+func (a *Accounts) SetDisabled(ctx context.Context, id string, disabled bool) error {
+	return a.update(ctx, "identity_users", id, "UPDATE identity_users SET disabled = ? WHERE id = ?", disabled, id)
+}
+
+// DeleteAccount removes an account and its group memberships.
+func (a *Accounts) DeleteAccount(ctx context.Context, id string) error {
+	if _, err := a.db.ExecContext(ctx, "DELETE FROM identity_group_members WHERE user_id = ?", id); err != nil {
+		return err
+	}
+	res, err := a.db.ExecContext(ctx, "DELETE FROM identity_users WHERE id = ?", id)
+	return checkAffected(res, err, id)
+}
+
+const selectGroup = "SELECT id, group_name, mail, display_name, gid_number FROM identity_groups"
+
+func scanGroup(row interface{ Scan(...interface{}) error }) (*Group, error) {
+	g := &Group{}
+	if err := row.Scan(&g.ID, &g.GroupName, &g.Mail, &g.DisplayName, &g.GIDNumber); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+var groupClaims = map[string]string{
+	"groupid":      "id",
+	"group_name":   "group_name",
+	"mail":         "mail",
+	"display_name": "display_name",
+	"gid_number":   "gid_number",
+}
+
+// GetGroupByClaim returns the group with the given value for the claim.
+func (a *Accounts) GetGroupByClaim(ctx context.Context, claim, value string) (*Group, error) {
+	column, ok := groupClaims[claim]
+	if !ok {
+		return nil, errors.New("accounts: invalid claim " + claim)
+	}
+	g, err := scanGroup(a.db.QueryRowContext(ctx, selectGroup+" WHERE "+column+" = ?", value))
+	if err == sql.ErrNoRows {
+		return nil, errtypes.NotFound(claim + "=" + value)
+	}
+	return g, err
+}
+
+// FindGroups returns the groups whose name, mail or display name contain the query.
+func (a *Accounts) FindGroups(ctx context.Context, query string) ([]*Group, error) {
+	like := "%" + escapeLike(query) + "%"
+	rows, err := a.db.QueryContext(ctx, selectGroup+" WHERE group_name LIKE ? OR mail LIKE ? OR display_name LIKE ?", like, like, like)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []*Group{}
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// GetMembers returns the ids of the members of a group.
+func (a *Accounts) GetMembers(ctx context.Context, groupID string) ([]string, error) {
+	rows, err := a.db.QueryContext(ctx, "SELECT user_id FROM identity_group_members WHERE group_id = ?", groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		members = append(members, id)
+	}
+	return members, rows.Err()
+}
+
+// HasMember checks whether the user is a member of the group.
+func (a *Accounts) HasMember(ctx context.Context, groupID, userID string) (bool, error) {
+	var n int
+	err := a.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM identity_group_members WHERE group_id = ? AND user_id = ?", groupID, userID).Scan(&n)
+	return n > 0, err
+}
+
+// CreateGroup stores a new group.
+func (a *Accounts) CreateGroup(ctx context.Context, g *Group) error {
+	_, err := a.db.ExecContext(ctx,
+		"INSERT INTO identity_groups (id, group_name, mail, display_name, gid_number) VALUES (?, ?, ?, ?, ?)",
+		g.ID, g.GroupName, g.Mail, g.DisplayName, g.GIDNumber)
+	return err
+}
+
+// UpdateGroup updates the attributes of a group.
+func (a *Accounts) UpdateGroup(ctx context.Context, g *Group) error {
+	return a.update(ctx, "identity_groups", g.ID,
+		"UPDATE identity_groups SET group_name = ?, mail = ?, display_name = ?, gid_number = ? WHERE id = ?",
+		g.GroupName, g.Mail, g.DisplayName, g.GIDNumber, g.ID)
+}
+
+// DeleteGroup removes a group and its memberships.
+func (a *Accounts) DeleteGroup(ctx context.Context, id string) error {
+	if _, err := a.db.ExecContext(ctx, "DELETE FROM identity_group_members WHERE group_id = ?", id); err != nil {
+		return err
+	}
+	res, err := a.db.ExecContext(ctx, "DELETE FROM identity_groups WHERE id = ?", id)
+	return checkAffected(res, err, id)
+}
+
+// AddMember adds the user to the group.
+func (a *Accounts) AddMember(ctx context.Context, groupID, userID string) error {
+	_, err := a.db.ExecContext(ctx, "INSERT IGNORE INTO identity_group_members (group_id, user_id) VALUES (?, ?)", groupID, userID)
+	return err
+}
+
+// RemoveMember removes the user from the group.
+func (a *Accounts) RemoveMember(ctx context.Context, groupID, userID string) error {
+	_, err := a.db.ExecContext(ctx, "DELETE FROM identity_group_members WHERE group_id = ? AND user_id = ?", groupID, userID)
+	return err
+}
+
+// update runs the update statement on an existing row. The number of affected rows can't
+// be used to detect missing rows, as MySQL doesn't count the rows whose values didn't change.
+func (a *Accounts) update(ctx context.Context, table, id, query string, args ...interface{}) error {
+	var n int
+	if err := a.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table+" WHERE id = ?", id).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		return errtypes.NotFound(id)
+	}
+	_, err := a.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func checkAffected(res sql.Result, err error, id string) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errtypes.NotFound(id)
+	}
+	return nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,51 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package accounts
+
+import "testing"
+
+func TestHashAndVerifyPassword(t *testing.T) {
+	for _, algorithm := range []string{HashBcrypt, HashArgon2} {
+		hash, err := HashPassword("relativity", algorithm)
+		if err != nil {
+			t.Fatalf("%s: %v", algorithm, err)
+		}
+
+		ok, err := VerifyPassword(hash, "relativity")
+		if err != nil || !ok {
+			t.Errorf("%s: expected the password to match, got %t, %v", algorithm, ok, err)
+		}
+		ok, err = VerifyPassword(hash, "radioactivity")
+		if err != nil || ok {
+			t.Errorf("%s: expected the password not to match, got %t, %v", algorithm, ok, err)
+		}
+	}
+}
+
+func TestVerifyPasswordInvalidHash(t *testing.T) {
+	if ok, err := VerifyPassword("", "relativity"); ok || err != nil {
+		t.Errorf("expected an empty hash not to match, got %t, %v", ok, err)
+	}
+	if _, err := VerifyPassword("plaintext", "plaintext"); err == nil {
+		t.Error("expected an error for an unknown hash format")
+	}
+	if _, err := VerifyPassword("$argon2id$v=19$m=65536$salt", "relativity"); err == nil {
+		t.Error("expected an error for a malformed argon2id hash")
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/user/manager/registry"
+	"github.com/cs3org/reva/pkg/user/manager/sql/accounts"
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("sql", New)
+}
+
+type config struct {
+	DbUsername   string `mapstructure:"db_username"`
+	DbPassword   string `mapstructure:"db_password"`
+	DbHost       string `mapstructure:"db_host"`
+	DbPort       int    `mapstructure:"db_port"`
+	DbName       string `mapstructure:"db_name"`
+	Idp          string `mapstructure:"idp"`
+	Nobody       int64  `mapstructure:"nobody"`
+	PasswordHash string `mapstructure:"password_hash" docs:"bcrypt;The algorithm used to hash new passwords, bcrypt or argon2id."`
+}
+
+func (c *config) init() {
+	if c.Nobody == 0 {
+		c.Nobody = 99
+	}
+	if c.PasswordHash == "" {
+		c.PasswordHash = accounts.HashBcrypt
+	}
+}
+
+type manager struct {
+	c  *config
+	db *accounts.Accounts
+}
+
+// New returns a user manager storing the users in a SQL database. The users can be
+// provisioned using the user.Writer interface. The tables are created by the migrations of the identity component.
+func New(m map[string]interface{}) (user.Manager, error) {
+	mgr := &manager{}
+	if err := mgr.Configure(m); err != nil {
+		return nil, errors.Wrap(err, "sql: error creating a new manager")
+	}
+
+	db, err := accounts.NewMysql(fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", mgr.c.DbUsername, mgr.c.DbPassword, mgr.c.DbHost, mgr.c.DbPort, mgr.c.DbName))
+	if err != nil {
+		return nil, err
+	}
+	mgr.db = db
+	return mgr, nil
+}
+
+func (m *manager) Configure(ml map[string]interface{}) error {
+	c := &config{}
+	if err := mapstructure.Decode(ml, c); err != nil {
+		return errors.Wrap(err, "error decoding conf")
+	}
+	c.init()
+	m.c = c
+	return nil
+}
+
+func (m *manager) GetUser(ctx context.Context, uid *userpb.UserId, skipFetchingGroups bool) (*userpb.User, error) {
+	a, err := m.db.GetAccountByClaim(ctx, "userid", uid.OpaqueId)
+	if err != nil {
+		return nil, err
+	}
+	return m.convertToCS3User(ctx, a, skipFetchingGroups)
+}
+
+func (m *manager) GetUserByClaim(ctx context.Context, claim, value string, skipFetchingGroups bool) (*userpb.User, error) {
+	a, err := m.db.GetAccountByClaim(ctx, claim, value)
+	if err != nil {
+		return nil, err
+	}
+	return m.convertToCS3User(ctx, a, skipFetchingGroups)
+}
+
+func (m *manager) FindUsers(ctx context.Context, query string, skipFetchingGroups bool) ([]*userpb.User, error) {
+	accounts, err := m.db.FindAccounts(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*userpb.User, 0, len(accounts))
+	for _, a := range accounts {
+		u, err := m.convertToCS3User(ctx, a, skipFetchingGroups)
+		if err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Str("userid", a.ID).Msg("could not convert account, skipping")
+			continue
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+func (m *manager) GetUserGroups(ctx context.Context, uid *userpb.UserId) ([]string, error) {
+	return m.db.GetAccountGroups(ctx, uid.OpaqueId)
+}
+
+func (m *manager) CreateUser(ctx context.Context, u *userpb.User, password string) (*userpb.User, error) {
+	a := m.convertToAccount(u)
+	if a.ID == "" {
+		a.ID = uuid.NewString()
+	}
+	if a.Username == "" {
+		return nil, errors.New("sql: users need a username")
+	}
+	if password != "" {
+		hash, err := accounts.HashPassword(password, m.c.PasswordHash)
+		if err != nil {
+			return nil, err
+		}
+		a.PasswordHash = hash
+	}
+	if err := m.db.CreateAccount(ctx, a); err != nil {
+		return nil, errors.Wrap(err, "sql: error creating user")
+	}
+	return m.convertToCS3User(ctx, a, true)
+}
+
+func (m *manager) UpdateUser(ctx context.Context, u *userpb.User) error {
+	if u.Id == nil || u.Id.OpaqueId == "" {
+		return errors.New("sql: missing user id")
+	}
+	return m.db.UpdateAccount(ctx, m.convertToAccount(u))
+}
+
+func (m *manager) SetPassword(ctx context.Context, uid *userpb.UserId, password string) error {
+	hash, err := accounts.HashPassword(password, m.c.PasswordHash)
+	if err != nil {
+		return err
+	}
+	return m.db.SetPasswordHash(ctx, uid.OpaqueId, hash)
+}
+
+func (m *manager) SetDisabled(ctx context.Context, uid *userpb.UserId, disabled bool) error {
+	return m.db.SetDisabled(ctx, uid.OpaqueId, disabled)
+}
+
+func (m *manager) DeleteUser(ctx context.Context, uid *userpb.UserId) error {
+	return m.db.DeleteAccount(ctx, uid.OpaqueId)
+}
+
+func (m *manager) convertToAccount(u *userpb.User) *accounts.Account {
+	a := &accounts.Account{
+		Username:    u.Username,
+		Mail:        u.Mail,
+		DisplayName: u.DisplayName,
+		UIDNumber:   u.UidNumber,
+		GIDNumber:   u.GidNumber,
+	}
+	if u.Id != nil {
+		a.ID = u.Id.OpaqueId
+	}
+	return a
+}
+
+func (m *manager) convertToCS3User(ctx context.Context, a *accounts.Account, skipFetchingGroups bool) (*userpb.User, error) {
+	u := &userpb.User{
+		Id: &userpb.UserId{
+			Idp:      m.c.Idp,
+			OpaqueId: a.ID,
+			Type:     userpb.UserType_USER_TYPE_PRIMARY,
+		},
+		Username:    a.Username,
+		Mail:        a.Mail,
+		DisplayName: a.DisplayName,
+		UidNumber:   a.UIDNumber,
+		GidNumber:   a.GIDNumber,
+	}
+	if u.UidNumber == 0 {
+		u.UidNumber = m.c.Nobody
+	}
+	if u.GidNumber == 0 {
+		u.GidNumber = m.c.Nobody
+	}
+	if u.DisplayName == "" {
+		u.DisplayName = u.Username
+	}
+	if a.Disabled {
+		u.Opaque = &typespb.Opaque{
+			Map: map[string]*typespb.OpaqueEntry{
+				"disabled": {Decoder: "plain", Value: []byte(strconv.FormatBool(true))},
+			},
+		}
+	}
+
+	if !skipFetchingGroups {
+		var err error
+		if u.Groups, err = m.GetUserGroups(ctx, u.Id); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
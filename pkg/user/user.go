@@ -39,3 +39,17 @@ type Manager interface {
 	// FindUsers returns all the user objects which match a query parameter.
 	FindUsers(ctx context.Context, query string, skipFetchingGroups bool) ([]*userpb.User, error)
 }
+
+// Writer is implemented by user managers able to provision users.
+type Writer interface {
+	// CreateUser creates the user with the given password. If the user has no id, one is assigned.
+	CreateUser(ctx context.Context, u *userpb.User, password string) (*userpb.User, error)
+	// UpdateUser updates the attributes of the user.
+	UpdateUser(ctx context.Context, u *userpb.User) error
+	// SetPassword sets the password of the user.
+	SetPassword(ctx context.Context, uid *userpb.UserId, password string) error
+	// SetDisabled disables or enables the user. Disabled users can't log in.
+	SetDisabled(ctx context.Context, uid *userpb.UserId, disabled bool) error
+	// DeleteUser deletes the user.
+	DeleteUser(ctx context.Context, uid *userpb.UserId) error
+}
@@ -28,6 +28,7 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -36,6 +37,7 @@ import (
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/registry"
 	"github.com/cs3org/reva/pkg/registry/memory"
 	"github.com/golang/protobuf/proto"
@@ -62,8 +64,13 @@ func Skip(source string, prefixes []string) bool {
 	return false
 }
 
-// GetClientIP retrieves the client IP from incoming requests
+// GetClientIP retrieves the client IP from incoming requests; the IP determined
+// by the http server, taking the trusted proxies into account, takes precedence.
 func GetClientIP(r *http.Request) (string, error) {
+	if ip, ok := ctxpkg.ContextGetClientIP(r.Context()); ok {
+		return ip, nil
+	}
+
 	var clientIP string
 	forwarded := r.Header.Get("X-FORWARDED-FOR")
 
@@ -129,6 +136,32 @@ func TSToTime(ts *types.Timestamp) time.Time {
 	return time.Unix(int64(ts.Seconds), int64(ts.Nanos))
 }
 
+// ParseMTime parses a modification time in the "sec[.fraction]" format clients send
+// in the X-OC-Mtime header. The fraction is a decimal fraction of a second, so
+// "123.5" is half a second past 123 and digits beyond nanoseconds are dropped.
+func ParseMTime(v string) (time.Time, error) {
+	p := strings.SplitN(v, ".", 2)
+	sec, err := strconv.ParseInt(p[0], 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var nsec int64
+	if len(p) > 1 {
+		frac := p[1]
+		if frac == "" || strings.Trim(frac, "0123456789") != "" {
+			return time.Time{}, fmt.Errorf("invalid fraction of a second in mtime %q", v)
+		}
+		if len(frac) > 9 {
+			frac = frac[:9]
+		}
+		frac += strings.Repeat("0", 9-len(frac))
+		if nsec, err = strconv.ParseInt(frac, 10, 64); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Unix(sec, nsec), nil
+}
+
 // LaterTS returns the timestamp which occurs later.
 func LaterTS(t1 *types.Timestamp, t2 *types.Timestamp) *types.Timestamp {
 	if TSToUnixNano(t1) > TSToUnixNano(t2) {
@@ -207,3 +207,37 @@ func TestParseStorageSpaceReference(t *testing.T) {
 		}
 	}
 }
+
+func TestParseMTime(t *testing.T) {
+	tests := []struct {
+		in      string
+		sec     int64
+		nsec    int
+		invalid bool
+	}{
+		{in: "123", sec: 123},
+		{in: "123.5", sec: 123, nsec: 500000000},
+		{in: "123.000000005", sec: 123, nsec: 5},
+		{in: "123.1234567899", sec: 123, nsec: 123456789},
+		{in: "", invalid: true},
+		{in: "123.", invalid: true},
+		{in: "123.-5", invalid: true},
+		{in: "abc", invalid: true},
+	}
+	for _, tt := range tests {
+		mtime, err := ParseMTime(tt.in)
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("ParseMTime(%q): expected an error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseMTime(%q): unexpected error %v", tt.in, err)
+			continue
+		}
+		if mtime.Unix() != tt.sec || mtime.Nanosecond() != tt.nsec {
+			t.Errorf("ParseMTime(%q) = %d.%d, expected %d.%d", tt.in, mtime.Unix(), mtime.Nanosecond(), tt.sec, tt.nsec)
+		}
+	}
+}
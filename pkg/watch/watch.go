@@ -0,0 +1,188 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package watch fans out the changes published on the event stream to the
+// clients watching the affected subtrees.
+package watch
+
+import (
+	"path"
+	"strings"
+	"sync"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/cs3org/reva/pkg/watch/proto"
+)
+
+// Change types sent to the watchers.
+const (
+	TypeContainerCreated = "container_created"
+	TypeFileTouched      = "file_touched"
+	TypeItemTrashed      = "item_trashed"
+	TypeItemMoved        = "item_moved"
+	TypeFileUploaded     = "file_uploaded"
+)
+
+// Events lists the events which need to be consumed to serve watchers.
+var Events = []events.Unmarshaller{
+	events.ContainerCreated{},
+	events.FileTouched{},
+	events.ItemTrashed{},
+	events.ItemMoved{},
+	events.FileUploaded{},
+}
+
+// Root is the resource whose subtree is watched.
+type Root struct {
+	Path string
+	ID   *provider.ResourceId
+	// MountPath is the path the storage of the root is mounted at. The uploads are
+	// published by the data providers with the path inside the storage, they are
+	// only matched if it is set.
+	MountPath string
+}
+
+// Contains checks whether ref points into the subtree. As events only carry
+// the reference used by the client, references relative to a resource id can
+// only be matched if the id is the one of the root.
+func (r *Root) Contains(ref *provider.Reference) bool {
+	if ref == nil {
+		return false
+	}
+	if ref.ResourceId != nil {
+		return r.ID != nil && utils.ResourceIDEqual(ref.ResourceId, r.ID)
+	}
+	if r.Path == "" || !path.IsAbs(ref.Path) {
+		return false
+	}
+	p := path.Clean(ref.Path)
+	return r.Path == "/" || p == r.Path || strings.HasPrefix(p, r.Path+"/")
+}
+
+// containsUpload checks whether the uploaded file is in the subtree.
+func (r *Root) containsUpload(e events.FileUploaded) bool {
+	if r.Contains(e.Ref) {
+		return true
+	}
+	if r.MountPath == "" || e.Path == "" || r.ID == nil || e.Ref.GetResourceId().GetStorageId() != r.ID.StorageId {
+		return false
+	}
+	return r.Contains(&provider.Reference{Path: path.Join(r.MountPath, e.Path)})
+}
+
+// Change converts an event to the message sent to the watchers, or returns
+// nil if the event does not describe a change to a resource.
+func Change(ev interface{}) *proto.WatchResponse {
+	switch e := ev.(type) {
+	case events.ContainerCreated:
+		return &proto.WatchResponse{Type: TypeContainerCreated, Ref: e.Ref, Executant: e.Executant}
+	case events.FileTouched:
+		return &proto.WatchResponse{Type: TypeFileTouched, Ref: e.Ref, Executant: e.Executant}
+	case events.ItemTrashed:
+		return &proto.WatchResponse{Type: TypeItemTrashed, Ref: e.Ref, Executant: e.Executant}
+	case events.ItemMoved:
+		return &proto.WatchResponse{Type: TypeItemMoved, Ref: e.Ref, OldRef: e.OldReference, Executant: e.Executant}
+	case events.FileUploaded:
+		return &proto.WatchResponse{Type: TypeFileUploaded, Ref: e.Ref, Executant: e.Executant}
+	}
+	return nil
+}
+
+// Subscription receives the changes to a subtree.
+type Subscription struct {
+	root Root
+	ch   chan *proto.WatchResponse
+}
+
+// C returns the channel the changes are sent to. It is closed when the
+// subscription is cancelled.
+func (s *Subscription) C() <-chan *proto.WatchResponse {
+	return s.ch
+}
+
+// Hub keeps track of the subscriptions and dispatches the changes to them.
+type Hub struct {
+	subscriptions map[*Subscription]struct{}
+	mutex         sync.RWMutex
+}
+
+// NewHub returns a hub without subscriptions.
+func NewHub() *Hub {
+	return &Hub{
+		subscriptions: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe registers a watcher for the given subtree.
+func (h *Hub) Subscribe(root Root, bufferSize int) *Subscription {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if root.Path != "" {
+		root.Path = path.Clean(root.Path)
+	}
+	sub := &Subscription{
+		root: root,
+		ch:   make(chan *proto.WatchResponse, bufferSize),
+	}
+	h.subscriptions[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe cancels the subscription.
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if _, ok := h.subscriptions[sub]; ok {
+		delete(h.subscriptions, sub)
+		close(sub.ch)
+	}
+}
+
+// Dispatch sends the change described by the event to every subscription
+// whose subtree it affects. A move is reported to the watchers of both the
+// source and the destination. Watchers not keeping up miss changes rather
+// than blocking the hub.
+func (h *Hub) Dispatch(ev interface{}) {
+	c := Change(ev)
+	if c == nil {
+		return
+	}
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	uploaded, isUpload := ev.(events.FileUploaded)
+	for sub := range h.subscriptions {
+		switch {
+		case isUpload:
+			if !sub.root.containsUpload(uploaded) {
+				continue
+			}
+		case !sub.root.Contains(c.Ref) && !sub.root.Contains(c.OldRef):
+			continue
+		}
+		select {
+		case sub.ch <- c:
+		default:
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package watch
+
+import (
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/events"
+)
+
+func TestContains(t *testing.T) {
+	id := &provider.ResourceId{StorageId: "s", OpaqueId: "o"}
+	root := Root{Path: "/home/docs", ID: id}
+
+	tests := []struct {
+		ref      *provider.Reference
+		expected bool
+	}{
+		{&provider.Reference{Path: "/home/docs"}, true},
+		{&provider.Reference{Path: "/home/docs/a/b"}, true},
+		{&provider.Reference{Path: "/home/docs2"}, false},
+		{&provider.Reference{Path: "/home"}, false},
+		{&provider.Reference{Path: "./docs/a"}, false},
+		{&provider.Reference{ResourceId: id, Path: "./a"}, true},
+		{&provider.Reference{ResourceId: &provider.ResourceId{StorageId: "s", OpaqueId: "x"}, Path: "./a"}, false},
+		{nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := root.Contains(tt.ref); got != tt.expected {
+			t.Errorf("Contains(%v): expected %v, got %v", tt.ref, tt.expected, got)
+		}
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	h := NewHub()
+	docs := h.Subscribe(Root{Path: "/home/docs/"}, 4)
+	music := h.Subscribe(Root{Path: "/home/music"}, 4)
+
+	h.Dispatch(events.ContainerCreated{Ref: &provider.Reference{Path: "/home/docs/new"}})
+	h.Dispatch(events.ItemMoved{
+		Ref:          &provider.Reference{Path: "/home/music/song"},
+		OldReference: &provider.Reference{Path: "/home/docs/song"},
+	})
+	h.Dispatch(events.ShareCreated{})
+
+	if n := len(docs.C()); n != 2 {
+		t.Errorf("expected 2 changes for docs, got %d", n)
+	}
+	if n := len(music.C()); n != 1 {
+		t.Errorf("expected 1 change for music, got %d", n)
+	}
+	if c := <-music.C(); c.Type != TypeItemMoved || c.OldRef.Path != "/home/docs/song" {
+		t.Errorf("unexpected change %v", c)
+	}
+
+	h.Unsubscribe(docs)
+	if _, ok := <-docs.C(); !ok {
+		t.Error("expected buffered changes to be delivered after unsubscribing")
+	}
+}
+
+func TestDispatchUpload(t *testing.T) {
+	h := NewHub()
+	docs := h.Subscribe(Root{Path: "/home/docs", ID: &provider.ResourceId{StorageId: "s", OpaqueId: "docs"}, MountPath: "/home"}, 4)
+	other := h.Subscribe(Root{Path: "/projects/docs", ID: &provider.ResourceId{StorageId: "p", OpaqueId: "docs"}, MountPath: "/projects"}, 4)
+	unmounted := h.Subscribe(Root{Path: "/home/docs", ID: &provider.ResourceId{StorageId: "s", OpaqueId: "docs"}}, 4)
+
+	uploaded := &provider.Reference{ResourceId: &provider.ResourceId{StorageId: "s", OpaqueId: "file"}, Path: "."}
+	h.Dispatch(events.FileUploaded{Ref: uploaded, Path: "/docs/report.pdf"})
+	h.Dispatch(events.FileUploaded{Ref: uploaded, Path: "/music/song.mp3"})
+
+	if n := len(docs.C()); n != 1 {
+		t.Fatalf("expected 1 change for docs, got %d", n)
+	}
+	if c := <-docs.C(); c.Type != TypeFileUploaded || c.Ref != uploaded {
+		t.Errorf("unexpected change %v", c)
+	}
+	if n := len(other.C()); n != 0 {
+		t.Errorf("expected no change for the docs of another storage, got %d", n)
+	}
+	if n := len(unmounted.C()); n != 0 {
+		t.Errorf("expected no change without the mount path, got %d", n)
+	}
+}
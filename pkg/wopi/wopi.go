@@ -0,0 +1,208 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package wopi contains the helpers shared by the WOPI host implemented in
+// reva: the app provider driver that hands out the WOPI URLs and the HTTP
+// service that answers the WOPI requests of the office applications.
+package wopi
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/mime"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/utils/resourceid"
+	"github.com/pkg/errors"
+)
+
+// The headers defined by the WOPI protocol.
+const (
+	HeaderOverride          = "X-WOPI-Override"
+	HeaderLock              = "X-WOPI-Lock"
+	HeaderOldLock           = "X-WOPI-OldLock"
+	HeaderLockFailureReason = "X-WOPI-LockFailureReason"
+	HeaderItemVersion       = "X-WOPI-ItemVersion"
+)
+
+const (
+	// LockAppName is the app name set on the CS3 locks held by WOPI clients.
+	LockAppName = "wopi"
+	// LockDuration is the validity of a WOPI lock, as mandated by the protocol.
+	LockDuration = 30 * time.Minute
+)
+
+// FileID encodes a resource id into a WOPI file id, which can be safely
+// used as a path segment.
+func FileID(id *provider.ResourceId) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(resourceid.OwnCloudResourceIDWrap(id)))
+}
+
+// ParseFileID decodes a WOPI file id produced by FileID.
+func ParseFileID(fileID string) (*provider.ResourceId, error) {
+	b, err := base64.RawURLEncoding.DecodeString(fileID)
+	if err != nil {
+		return nil, errtypes.BadRequest("wopi: malformed file id")
+	}
+	id := resourceid.OwnCloudResourceIDUnwrap(string(b))
+	if id == nil {
+		return nil, errtypes.BadRequest("wopi: malformed file id")
+	}
+	return id, nil
+}
+
+// SrcURL returns the WOPISrc of a resource, i.e. the URL of the file
+// endpoint of the WOPI host serving it.
+func SrcURL(hostURL string, id *provider.ResourceId) (string, error) {
+	u, err := url.Parse(hostURL)
+	if err != nil {
+		return "", err
+	}
+	u.Path = path.Join(u.Path, "files", FileID(id))
+	return u.String(), nil
+}
+
+// AppURL adds the WOPISrc parameter to an action URL found in the discovery.
+func AppURL(actionURL, wopiSrc string) (string, error) {
+	u, err := url.Parse(actionURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("WOPISrc", wopiSrc)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// GetDiscovery queries the /hosting/discovery endpoint of a WOPI application
+// and returns the action URLs it supports.
+func GetDiscovery(appURL string, insecure bool) (map[string]map[string]string, error) {
+	httpcl := rhttp.GetHTTPClient(
+		rhttp.Timeout(time.Duration(5*int64(time.Second))),
+		rhttp.Insecure(insecure),
+	)
+
+	u, err := url.Parse(appURL)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path.Join(u.Path, "/hosting/discovery")
+
+	res, err := httpcl.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("wopi: discovery of %s returned %s", appURL, res.Status)
+	}
+
+	appURLs, err := ParseDiscovery(res.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing wopi discovery response")
+	}
+	return appURLs, nil
+}
+
+// ParseDiscovery parses a WOPI discovery document and returns the URLs
+// of the view, edit and editnew actions, indexed by action and by file extension.
+func ParseDiscovery(body io.Reader) (map[string]map[string]string, error) {
+	appURLs := make(map[string]map[string]string)
+
+	doc := etree.NewDocument()
+	if _, err := doc.ReadFrom(body); err != nil {
+		return nil, err
+	}
+	root := doc.SelectElement("wopi-discovery")
+	if root == nil {
+		return nil, errors.New("wopi: missing wopi-discovery element")
+	}
+
+	for _, netzone := range root.SelectElements("net-zone") {
+
+		if strings.Contains(netzone.SelectAttrValue("name", ""), "external") {
+			for _, app := range netzone.SelectElements("app") {
+				for _, action := range app.SelectElements("action") {
+					access := action.SelectAttrValue("name", "")
+					if access == "view" || access == "edit" || access == "editnew" {
+						ext := action.SelectAttrValue("ext", "")
+						urlString := action.SelectAttrValue("urlsrc", "")
+
+						if ext == "" || urlString == "" {
+							continue
+						}
+
+						u, err := url.Parse(urlString)
+						if err != nil {
+							// it sucks we cannot log here because this function is run
+							// on init without any context.
+							// TODO(labkode): add logging when we'll have static logging in boot phase.
+							continue
+						}
+
+						// remove any malformed query parameter from discovery urls
+						q := u.Query()
+						for k := range q {
+							if strings.Contains(k, "<") || strings.Contains(k, ">") {
+								q.Del(k)
+							}
+						}
+
+						u.RawQuery = q.Encode()
+
+						if _, ok := appURLs[access]; !ok {
+							appURLs[access] = make(map[string]string)
+						}
+						appURLs[access]["."+ext] = u.String()
+					}
+				}
+			}
+		}
+	}
+	return appURLs, nil
+}
+
+// MimeTypes returns the sorted list of the mime types of the file extensions
+// found in the action URLs.
+func MimeTypes(appURLs map[string]map[string]string) []string {
+	// Initially we store the mime types in a map to avoid duplicates
+	mimeTypesMap := make(map[string]bool)
+	for _, extensions := range appURLs {
+		for ext := range extensions {
+			m := mime.Detect(false, ext)
+			mimeTypesMap[m] = true
+		}
+	}
+
+	mimeTypes := make([]string, 0, len(mimeTypesMap))
+	for m := range mimeTypesMap {
+		mimeTypes = append(mimeTypes, m)
+	}
+	sort.Strings(mimeTypes)
+	return mimeTypes
+}
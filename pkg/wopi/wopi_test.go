@@ -0,0 +1,107 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package wopi
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+const discovery = `<?xml version="1.0" encoding="utf-8"?>
+<wopi-discovery>
+  <net-zone name="external-http">
+    <app name="writer">
+      <action ext="odt" name="edit" urlsrc="https://office.example.org/browser/dist/cool.html?"/>
+      <action ext="odt" name="view" urlsrc="https://office.example.org/browser/dist/cool.html?&lt;ui=UI_LLCC&amp;&gt;"/>
+      <action ext="" name="edit" urlsrc="https://office.example.org/browser/dist/cool.html?"/>
+    </app>
+  </net-zone>
+  <net-zone name="internal-http">
+    <app name="calc">
+      <action ext="ods" name="edit" urlsrc="https://office.example.org/browser/dist/cool.html?"/>
+    </app>
+  </net-zone>
+</wopi-discovery>`
+
+func TestParseDiscovery(t *testing.T) {
+	appURLs, err := ParseDiscovery(strings.NewReader(discovery))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(appURLs) != 2 {
+		t.Fatalf("expected the view and edit actions, got %v", appURLs)
+	}
+	if u := appURLs["edit"][".odt"]; u != "https://office.example.org/browser/dist/cool.html?" {
+		t.Errorf("unexpected edit url: %s", u)
+	}
+	if u := appURLs["view"][".odt"]; strings.ContainsAny(u, "<>") {
+		t.Errorf("malformed query parameters not removed from view url: %s", u)
+	}
+	if _, ok := appURLs["edit"][".ods"]; ok {
+		t.Error("actions of the internal net zone must be ignored")
+	}
+
+	if _, err := ParseDiscovery(strings.NewReader("<html></html>")); err == nil {
+		t.Error("expected an error for a document which is not a discovery")
+	}
+}
+
+func TestFileID(t *testing.T) {
+	id := &provider.ResourceId{StorageId: "storage", OpaqueId: "some/opaque!id"}
+
+	fileID := FileID(id)
+	if strings.ContainsAny(fileID, "/!") {
+		t.Errorf("file id is not safe to use in a path: %s", fileID)
+	}
+	parsed, err := ParseFileID(fileID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !utils.ResourceIDEqual(id, parsed) {
+		t.Errorf("expected %v, got %v", id, parsed)
+	}
+
+	if _, err := ParseFileID("not base64!"); err == nil {
+		t.Error("expected an error for a malformed file id")
+	}
+}
+
+func TestAppURL(t *testing.T) {
+	src, err := SrcURL("https://reva.example.org/wopi/", &provider.ResourceId{StorageId: "storage", OpaqueId: "opaque"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(src, "https://reva.example.org/wopi/files/") {
+		t.Errorf("unexpected WOPISrc: %s", src)
+	}
+
+	appURL, err := AppURL("https://office.example.org/cool.html?lang=en", src)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u, _ := url.Parse(appURL)
+	if u.Query().Get("WOPISrc") != src || u.Query().Get("lang") != "en" {
+		t.Errorf("unexpected app url: %s", appURL)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/share"
+	_ "github.com/cs3org/reva/pkg/share/manager/loader"
+	"github.com/cs3org/reva/pkg/share/manager/registry"
+	"github.com/cs3org/reva/pkg/share/manager/sql"
+)
+
+// The configuration file uses the same settings as the oc10-sql share manager, e.g.
+//
+//	db_host = "localhost"
+//	...
+//	dual_write_driver = "json"
+//	[dual_write_drivers.json]
+//	file = "/var/tmp/reva/shares.json"
+func main() {
+	configFile := flag.String("config", "", "the configuration file of the oc10-sql share manager")
+	users := flag.String("users", "", "comma-separated list of the usernames whose shares are compared")
+	flag.Parse()
+
+	if *configFile == "" || *users == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	c := map[string]interface{}{}
+	if _, err := toml.DecodeFile(*configFile, &c); err != nil {
+		log.Fatal(err)
+	}
+
+	driver, _ := c["dual_write_driver"].(string)
+	if driver == "" {
+		log.Fatal("no dual_write_driver configured")
+	}
+	drivers, _ := c["dual_write_drivers"].(map[string]interface{})
+	driverConf, _ := drivers[driver].(map[string]interface{})
+
+	// the primary manager must not mirror anything while being checked
+	delete(c, "dual_write_driver")
+	primary, err := newManager("oc10-sql", c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	secondary, err := newManager(driver, driverConf)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	failed := false
+	for _, username := range strings.Split(*users, ",") {
+		username = strings.TrimSpace(username)
+		if username == "" {
+			continue
+		}
+		// ownCloud 10 uses the username as user ID
+		ctx := ctxpkg.ContextSetUser(context.Background(), &userpb.User{
+			Id:       &userpb.UserId{OpaqueId: username},
+			Username: username,
+		})
+
+		inconsistencies, err := sql.CheckConsistency(ctx, primary, secondary)
+		if err != nil {
+			log.Printf("error checking shares of %s: %v", username, err)
+			failed = true
+			continue
+		}
+		for _, i := range inconsistencies {
+			fmt.Printf("%s: %s\n", username, i)
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func newManager(driver string, c map[string]interface{}) (share.Manager, error) {
+	f, ok := registry.NewFuncs[driver]
+	if !ok {
+		return nil, fmt.Errorf("share manager not found: %s", driver)
+	}
+	return f(c)
+}
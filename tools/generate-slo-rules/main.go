@@ -0,0 +1,74 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/BurntSushi/toml"
+	"github.com/cs3org/reva/pkg/slo"
+	"github.com/mitchellh/mapstructure"
+)
+
+// The alerting rules are generated from the service level objectives configured
+// in the core section of the revad configuration, e.g.
+//
+//	generate-slo-rules -config /etc/revad/revad.toml -output /etc/prometheus/rules/reva-slo.yaml
+//
+// The rules watch the burn rates exposed by the prometheus service of the revads
+// tracking the objectives.
+func main() {
+	configFile := flag.String("config", "/etc/revad/revad.toml", "the revad configuration file")
+	output := flag.String("output", "", "the rule file to write, the rules are printed if empty")
+	flag.Parse()
+
+	c := struct {
+		Core struct {
+			SLO map[string]interface{} `toml:"slo"`
+		} `toml:"core"`
+	}{}
+	if _, err := toml.DecodeFile(*configFile, &c); err != nil {
+		log.Fatal(err)
+	}
+
+	conf := &slo.Config{}
+	if err := mapstructure.Decode(c.Core.SLO, conf); err != nil {
+		log.Fatal(err)
+	}
+	if !conf.Enabled() {
+		log.Fatalf("no service level objectives configured in %s", *configFile)
+	}
+
+	rules, err := slo.GenerateRules(conf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *output == "" {
+		fmt.Print(string(rules))
+		return
+	}
+	if err := ioutil.WriteFile(*output, rules, 0644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "%d objectives written to %s\n", len(conf.Objectives), *output)
+}
@@ -0,0 +1,163 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/BurntSushi/toml"
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/group"
+	groupsql "github.com/cs3org/reva/pkg/group/manager/sql"
+	"github.com/cs3org/reva/pkg/user"
+	usersql "github.com/cs3org/reva/pkg/user/manager/sql"
+	"github.com/cs3org/reva/pkg/user/manager/sql/accounts"
+)
+
+const usage = `Usage: manage-accounts -config <file> <command> [-flags]
+
+Commands:
+  init-schema     create the tables
+  user-add        create a user
+  user-passwd     set the password of a user
+  user-disable    disable a user
+  user-enable     enable a user
+  user-del        delete a user
+  group-add       create a group
+  group-del       delete a group
+  member-add      add a user to a group
+  member-del      remove a user from a group
+`
+
+// The configuration file uses the same settings as the sql user and group managers, e.g.
+//
+//	db_host = "localhost"
+//	db_port = 3306
+//	...
+func main() {
+	configFile := flag.String("config", "", "the configuration file of the sql user manager")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	c := map[string]interface{}{}
+	if *configFile != "" {
+		if _, err := toml.DecodeFile(*configFile, &c); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	um, err := usersql.New(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	gm, err := groupsql.New(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	users := um.(user.Writer)
+	groups := gm.(group.Writer)
+
+	ctx := context.Background()
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	id := fs.String("id", "", "the id of the user or group")
+	username := fs.String("username", "", "the username")
+	password := fs.String("password", "", "the password")
+	mail := fs.String("mail", "", "the mail address")
+	displayName := fs.String("display-name", "", "the display name")
+	name := fs.String("name", "", "the group name")
+	groupID := fs.String("group", "", "the id of the group")
+	uidNumber := fs.Int64("uid-number", 0, "the uid number")
+	gidNumber := fs.Int64("gid-number", 0, "the gid number")
+	_ = fs.Parse(args)
+
+	uid := func() *userpb.UserId {
+		if *id != "" {
+			return &userpb.UserId{OpaqueId: *id}
+		}
+		u, err := um.GetUserByClaim(ctx, "username", *username, true)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return u.Id
+	}
+
+	switch cmd {
+	case "init-schema":
+		db, err := accounts.NewMysql(fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", c["db_username"], c["db_password"], c["db_host"], c["db_port"], c["db_name"]))
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = db.InitSchema(ctx)
+	case "user-add":
+		var u *userpb.User
+		u, err = users.CreateUser(ctx, &userpb.User{
+			Id:          &userpb.UserId{OpaqueId: *id},
+			Username:    *username,
+			Mail:        *mail,
+			DisplayName: *displayName,
+			UidNumber:   *uidNumber,
+			GidNumber:   *gidNumber,
+		}, *password)
+		if err == nil {
+			fmt.Printf("user %s created with id %s\n", u.Username, u.Id.OpaqueId)
+		}
+	case "user-passwd":
+		err = users.SetPassword(ctx, uid(), *password)
+	case "user-disable":
+		err = users.SetDisabled(ctx, uid(), true)
+	case "user-enable":
+		err = users.SetDisabled(ctx, uid(), false)
+	case "user-del":
+		err = users.DeleteUser(ctx, uid())
+	case "group-add":
+		var g *grouppb.Group
+		g, err = groups.CreateGroup(ctx, &grouppb.Group{
+			Id:          &grouppb.GroupId{OpaqueId: *id},
+			GroupName:   *name,
+			Mail:        *mail,
+			DisplayName: *displayName,
+			GidNumber:   *gidNumber,
+		})
+		if err == nil {
+			fmt.Printf("group %s created with id %s\n", g.GroupName, g.Id.OpaqueId)
+		}
+	case "group-del":
+		err = groups.DeleteGroup(ctx, &grouppb.GroupId{OpaqueId: *groupID})
+	case "member-add":
+		err = groups.AddMember(ctx, &grouppb.GroupId{OpaqueId: *groupID}, uid())
+	case "member-del":
+		err = groups.RemoveMember(ctx, &grouppb.GroupId{OpaqueId: *groupID}, uid())
+	default:
+		flag.Usage()
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/devices"
+	_ "github.com/cs3org/reva/pkg/devices/loader"
+	"github.com/cs3org/reva/pkg/devices/registry"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	_ "github.com/cs3org/reva/pkg/token/revocation/loader"
+	revocationregistry "github.com/cs3org/reva/pkg/token/revocation/registry"
+)
+
+// The configuration files use the same settings as the device registry and the revocation store
+// configured in the auth interceptors, e.g. for the sql implementations
+//
+//	db_host = "localhost"
+//	db_port = 3306
+//	...
+func main() {
+	reg := flag.String("registry", "sql", "the device registry to use")
+	configFile := flag.String("config", "", "the configuration file of the device registry")
+	store := flag.String("revocation-store", "", "the revocation store the tokens of revoked devices are added to")
+	storeConfigFile := flag.String("revocation-config", "", "the configuration file of the revocation store")
+	idp := flag.String("idp", "", "the identity provider of the user")
+	user := flag.String("user", "", "the opaque ID of the user whose devices are listed")
+	revoke := flag.String("revoke", "", "the ID of the device to revoke")
+	flag.Parse()
+
+	if *user == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	f, ok := registry.NewFuncs[*reg]
+	if !ok {
+		log.Fatalf("device registry not found: %s", *reg)
+	}
+	r, err := f(readConfig(*configFile))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var s revocation.Store
+	if *store != "" {
+		f, ok := revocationregistry.NewFuncs[*store]
+		if !ok {
+			log.Fatalf("revocation store not found: %s", *store)
+		}
+		if s, err = f(readConfig(*storeConfigFile)); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	userID := &userpb.UserId{Idp: *idp, OpaqueId: *user}
+	tracker := devices.NewTracker(r, s, 0, 0)
+	if *revoke != "" {
+		if err := tracker.Revoke(ctx, userID, *revoke); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("device %s of user %s revoked\n", *revoke, *user)
+		return
+	}
+
+	list, err := tracker.List(ctx, userID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUSER AGENT\tCREDENTIALS\tAPP PASSWORD\tIP\tFIRST SEEN\tLAST SEEN\tREVOKED")
+	for _, d := range list {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%t\n", d.ID, d.UserAgent, d.CredentialType, d.AppPassword, d.IP,
+			d.FirstSeen.Format(time.RFC3339), d.LastSeen.Format(time.RFC3339), d.Revoked)
+	}
+	_ = w.Flush()
+}
+
+func readConfig(file string) map[string]interface{} {
+	c := map[string]interface{}{}
+	if file != "" {
+		if _, err := toml.DecodeFile(file, &c); err != nil {
+			log.Fatal(err)
+		}
+	}
+	return c
+}
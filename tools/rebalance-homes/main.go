@@ -0,0 +1,226 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	tx "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/datatx"
+	_ "github.com/cs3org/reva/pkg/datatx/manager/loader"
+	txregistry "github.com/cs3org/reva/pkg/datatx/manager/registry"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/registry/residency"
+	"github.com/cs3org/reva/pkg/storage/registry/static"
+	"github.com/cs3org/reva/pkg/storage/utils/templates"
+	"github.com/cs3org/reva/pkg/user"
+	_ "github.com/cs3org/reva/pkg/user/manager/loader"
+	userregistry "github.com/cs3org/reva/pkg/user/manager/registry"
+)
+
+const pollInterval = 5 * time.Second
+
+// remote is the WebDAV endpoint exposing the homes of a storage provider to the datatx driver.
+type remote struct {
+	Endpoint string `toml:"endpoint"`
+	Token    string `toml:"token"`
+	// Path is the template of the path of the homes in the endpoint, e.g. /{{.Username}}.
+	Path string `toml:"path"`
+}
+
+type config struct {
+	// Registry is the configuration of the static storage registry, including the residency rules.
+	Registry     map[string]interface{}            `toml:"registry"`
+	UserManager  string                            `toml:"user_manager"`
+	UserManagers map[string]map[string]interface{} `toml:"user_managers"`
+	TxDriver     string                            `toml:"txdriver"`
+	TxDrivers    map[string]map[string]interface{} `toml:"txdrivers"`
+	// Remotes are the endpoints of the homes by address of the storage providers.
+	Remotes map[string]remote `toml:"remotes"`
+}
+
+// The homes created before the residency rules of the storage registry are enabled must be pinned
+// to their current provider first, then the rules can be enabled and the homes migrated with
+//
+//	rebalance-homes -config rebalance.toml -users einstein,marie -pin
+//	rebalance-homes -config rebalance.toml -users einstein,marie
+//
+// The homes are copied with the datatx driver and unpinned once copied, so that the storage
+// registry routes them to their new provider. Changes made to a home while it is copied are not
+// carried over, and the copy left on the previous provider must be removed by the operator.
+func main() {
+	configFile := flag.String("config", "", "the configuration file")
+	users := flag.String("users", "", "comma-separated list of the usernames whose homes are rebalanced")
+	pin := flag.Bool("pin", false, "pin the homes of the users to their current provider instead of migrating them")
+	dryRun := flag.Bool("dry-run", false, "only print the migrations")
+	flag.Parse()
+
+	if *configFile == "" || *users == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	c := &config{}
+	if _, err := toml.DecodeFile(*configFile, c); err != nil {
+		log.Fatal(err)
+	}
+	file, _ := c.Registry["placements_file"].(string)
+	if file == "" {
+		log.Fatal("no placements_file configured in the registry")
+	}
+	placements, err := residency.NewPlacements(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the homes are routed without the placements to find their target, and without the
+	// residency rules to find where the homes created before the rules are
+	target, err := newRegistry(c.Registry, "placements_file")
+	if err != nil {
+		log.Fatal(err)
+	}
+	previous, err := newRegistry(c.Registry, "placements_file", "residency")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	f, ok := userregistry.NewFuncs[c.UserManager]
+	if !ok {
+		log.Fatalf("user manager not found: %s", c.UserManager)
+	}
+	um, err := f(c.UserManagers[c.UserManager])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var mgr datatx.Manager
+	if !*pin && !*dryRun {
+		g, ok := txregistry.NewFuncs[c.TxDriver]
+		if !ok {
+			log.Fatalf("datatx driver not found: %s", c.TxDriver)
+		}
+		if mgr, err = g(c.TxDrivers[c.TxDriver]); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	failed := false
+	for _, username := range strings.Split(*users, ",") {
+		username = strings.TrimSpace(username)
+		if err := rebalance(ctx, c, um, mgr, placements, target, previous, username, *pin, *dryRun); err != nil {
+			log.Printf("%s: %v", username, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func rebalance(ctx context.Context, c *config, um user.Manager, mgr datatx.Manager, placements *residency.Placements, target, previous storage.Registry, username string, pin, dryRun bool) error {
+	u, err := um.GetUserByClaim(ctx, "username", username, true)
+	if err != nil {
+		return err
+	}
+	ctx = ctxpkg.ContextSetUser(ctx, u)
+
+	prev, err := previous.GetHome(ctx)
+	if err != nil {
+		return err
+	}
+	if pin {
+		placements.Set(u.Id, prev.ProviderPath, prev.Address)
+		fmt.Printf("%s: home pinned to %s\n", username, prev.Address)
+		return placements.Save()
+	}
+
+	cur, ok := placements.Get(u.Id, prev.ProviderPath)
+	if !ok {
+		fmt.Printf("%s: home not pinned, nothing to migrate\n", username)
+		return nil
+	}
+	next, err := target.GetHome(ctx)
+	if err != nil {
+		return err
+	}
+	if cur != next.Address {
+		fmt.Printf("%s: migrating home from %s to %s\n", username, cur, next.Address)
+		if dryRun {
+			return nil
+		}
+		if err := migrate(ctx, c, mgr, u, cur, next.Address); err != nil {
+			return err
+		}
+	}
+	placements.Set(u.Id, prev.ProviderPath, "")
+	fmt.Printf("%s: home unpinned, served by %s\n", username, next.Address)
+	return placements.Save()
+}
+
+func migrate(ctx context.Context, c *config, mgr datatx.Manager, u *userpb.User, from, to string) error {
+	src, ok := c.Remotes[from]
+	if !ok {
+		return fmt.Errorf("no remote configured for %s", from)
+	}
+	dst, ok := c.Remotes[to]
+	if !ok {
+		return fmt.Errorf("no remote configured for %s", to)
+	}
+
+	info, err := mgr.StartTransfer(ctx, src.Endpoint, templates.WithUser(u, src.Path), src.Token, dst.Endpoint, templates.WithUser(u, dst.Path), dst.Token)
+	if err != nil {
+		return err
+	}
+	id := info.GetId().GetOpaqueId()
+	for {
+		info, err := mgr.GetTransferStatus(ctx, id)
+		if err != nil {
+			return err
+		}
+		switch info.GetStatus() {
+		case tx.Status_STATUS_TRANSFER_COMPLETE:
+			return nil
+		case tx.Status_STATUS_TRANSFER_FAILED, tx.Status_STATUS_TRANSFER_CANCELLED, tx.Status_STATUS_TRANSFER_EXPIRED, tx.Status_STATUS_INVALID:
+			return fmt.Errorf("transfer %s ended with status %s", id, info.GetStatus())
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// newRegistry returns a static storage registry using the configuration without the given keys.
+func newRegistry(m map[string]interface{}, without ...string) (storage.Registry, error) {
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	for _, k := range without {
+		delete(c, k)
+	}
+	return static.New(c)
+}
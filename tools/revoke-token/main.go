@@ -0,0 +1,85 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	_ "github.com/cs3org/reva/pkg/token/revocation/loader"
+	"github.com/cs3org/reva/pkg/token/revocation/registry"
+)
+
+// The configuration file uses the same settings as the revocation store configured in the
+// auth interceptors, e.g. for the sql store
+//
+//	db_host = "localhost"
+//	db_port = 3306
+//	...
+func main() {
+	store := flag.String("store", "sql", "the revocation store to use")
+	configFile := flag.String("config", "", "the configuration file of the revocation store")
+	tkn := flag.String("token", "", "the token to revoke")
+	idp := flag.String("idp", "", "the identity provider of the user whose tokens are revoked")
+	user := flag.String("user", "", "the opaque ID of the user whose tokens are revoked")
+	lifetime := flag.Duration("lifetime", 24*time.Hour, "the maximum lifetime of the tokens")
+	flag.Parse()
+
+	if (*tkn == "") == (*user == "") {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	c := map[string]interface{}{}
+	if *configFile != "" {
+		if _, err := toml.DecodeFile(*configFile, &c); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	f, ok := registry.NewFuncs[*store]
+	if !ok {
+		log.Fatalf("revocation store not found: %s", *store)
+	}
+	s, err := f(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if *tkn != "" {
+		if err := s.RevokeToken(ctx, revocation.TokenID(*tkn), *lifetime); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("token revoked")
+		return
+	}
+
+	if err := s.RevokeUser(ctx, &userpb.UserId{Idp: *idp, OpaqueId: *user}, time.Now(), *lifetime); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("all tokens of user %s revoked\n", *user)
+}